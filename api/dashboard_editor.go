@@ -0,0 +1,331 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pmezard/go-difflib/difflib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// YAML 编辑器（类似 kubectl edit）：
+// - GET  /dashboard/api/yaml/:resource/:name?namespace=   获取对象 YAML
+// - POST /dashboard/api/yaml/:resource/:name/validate     校验编辑后的 YAML
+// - POST /dashboard/api/yaml/:resource/:name/diff         返回与当前对象的 unified diff
+// - PUT  /dashboard/api/yaml/:resource/:name?force=       校验并提交更新
+// 集群级资源（nodes）不需要 namespace 参数；namespaced 资源缺省为 default。
+
+// editorFieldManager 是 YAML 编辑器写入时使用的写入者名称
+const editorFieldManager = "k3-dashboard"
+
+// editTarget 是一次编辑请求定位到的对象
+type editTarget struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+// YAMLEditResponse 是编辑器接口的统一响应
+type YAMLEditResponse struct {
+	YAML            string   `json:"yaml,omitempty"`
+	ResourceVersion string   `json:"resourceVersion,omitempty"`
+	Valid           bool     `json:"valid"`
+	Errors          []string `json:"errors,omitempty"`
+	Diff            string   `json:"diff,omitempty"`
+	Changed         bool     `json:"changed"`
+}
+
+func (r DashboardRoutes) setUpEditor() {
+	g := r.fiber.App.Group("/dashboard/api/yaml")
+	g.Get("/:resource/:name", r.handleGetYAML)
+	g.Post("/:resource/:name/validate", r.handleValidateYAML)
+	g.Post("/:resource/:name/diff", r.handleDiffYAML)
+	g.Put("/:resource/:name", r.handleSubmitYAML)
+}
+
+func (r DashboardRoutes) resolveEditTarget(c *fiber.Ctx) (editTarget, error) {
+	gvk, err := apiserver.GVKForResource(c.Params("resource"))
+	if err != nil {
+		return editTarget{}, err
+	}
+	t := editTarget{gvk: gvk, name: c.Params("name")}
+	if !apiserver.IsClusterScoped(gvk.Kind) {
+		t.namespace = c.Query("namespace", metav1.NamespaceDefault)
+	}
+	return t, nil
+}
+
+// editAllowed 检查当前身份能否访问目标对象（namespace 与资源种类）；修改集群级资源（Node、Device）需要 cluster-admin
+func editAllowed(c *fiber.Ctx, t editTarget, write bool) bool {
+	id := webprovider.IdentityFromCtx(c)
+	if !id.AllowsKind(t.gvk.Kind) {
+		return false
+	}
+	if t.namespace == "" {
+		return !write || id.IsClusterAdmin()
+	}
+	return id.AllowsNamespace(t.namespace)
+}
+
+// handleGetYAML 返回对象当前的 YAML（去掉 managedFields，便于编辑）
+func (r DashboardRoutes) handleGetYAML(c *fiber.Ctx) error {
+	t, err := r.resolveEditTarget(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !editAllowed(c, t, false) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: " + t.gvk.Kind + " " + t.namespace + "/" + t.name})
+	}
+	current, err := r.store.Get(t.gvk, t.namespace, t.name)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	data, err := toEditableYAML(current, t.gvk, false)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(YAMLEditResponse{
+		YAML:            string(data),
+		ResourceVersion: resourceVersionOf(current),
+		Valid:           true,
+	})
+}
+
+// handleValidateYAML 只做校验，不写入
+func (r DashboardRoutes) handleValidateYAML(c *fiber.Ctx) error {
+	t, err := r.resolveEditTarget(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !editAllowed(c, t, false) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: " + t.gvk.Kind + " " + t.namespace + "/" + t.name})
+	}
+	edited, errs := r.parseEdited(t, c.Body())
+	if len(errs) == 0 {
+		// 与提交时相同地经过 apiserver 的准入校验（对象不存在时按创建校验）
+		var current runtime.Object
+		if obj, err := r.store.Get(t.gvk, t.namespace, t.name); err == nil {
+			current = obj
+			preserveSystemFields(current, edited)
+		}
+		apiserver.SetDefaults(edited)
+		errs = admissionErrors(r.admission.Admit(t.gvk, edited, current))
+	}
+	return c.JSON(YAMLEditResponse{Valid: len(errs) == 0, Errors: errs})
+}
+
+// handleDiffYAML 计算当前对象与编辑后 YAML 的差异（两边都经过规范化序列化，忽略格式差异）
+func (r DashboardRoutes) handleDiffYAML(c *fiber.Ctx) error {
+	t, err := r.resolveEditTarget(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !editAllowed(c, t, false) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: " + t.gvk.Kind + " " + t.namespace + "/" + t.name})
+	}
+	current, err := r.store.Get(t.gvk, t.namespace, t.name)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	edited, errs := r.parseEdited(t, c.Body())
+	if len(errs) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(YAMLEditResponse{Valid: false, Errors: errs})
+	}
+	preserveSystemFields(current, edited)
+	diff, err := diffObjects(current, edited, t)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(YAMLEditResponse{
+		ResourceVersion: resourceVersionOf(current),
+		Valid:           true,
+		Diff:            diff,
+		Changed:         diff != "",
+	})
+}
+
+// handleSubmitYAML 校验并提交更新；若 YAML 中带有 resourceVersion 且写入时对象已不是这个版本，返回 409
+func (r DashboardRoutes) handleSubmitYAML(c *fiber.Ctx) error {
+	t, err := r.resolveEditTarget(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !editAllowed(c, t, true) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: " + t.gvk.Kind + " " + t.namespace + "/" + t.name})
+	}
+	current, err := r.store.Get(t.gvk, t.namespace, t.name)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	edited, errs := r.parseEdited(t, c.Body())
+	if len(errs) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(YAMLEditResponse{Valid: false, Errors: errs})
+	}
+
+	editedMeta := edited.(metav1.Object)
+	currentRV := resourceVersionOf(current)
+	preserveSystemFields(current, edited)
+	// 与 apiserver 一致地填充默认值，避免删掉默认字段被当成变更
+	apiserver.SetDefaults(edited)
+	// 与 apiserver 的写入经过同一个准入校验（大小限制、Service、Deployment selector 不可修改等）
+	if errs := admissionErrors(r.admission.Admit(t.gvk, edited, current)); len(errs) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(YAMLEditResponse{Valid: false, Errors: errs})
+	}
+
+	diff, err := diffObjects(current, edited, t)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if diff == "" {
+		return c.JSON(YAMLEditResponse{ResourceVersion: currentRV, Valid: true})
+	}
+
+	// YAML 中的 resourceVersion 作为写入的前置条件，由 store 原子地比较：编辑期间对象被修改时返回 409；
+	// 删除了 resourceVersion 的盲写可能覆盖其他写入者的 labels/annotations，需要 force=true
+	rv := editedMeta.GetResourceVersion()
+	if _, err := storage.UpdateAsIf(r.store, t.gvk, edited, editorFieldManager, c.QueryBool("force")); err != nil {
+		var conflict *storage.ManagerConflict
+		if errors.As(err, &conflict) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error(), "conflict": conflict})
+		}
+		if storage.IsResourceVersionConflict(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": fmt.Sprintf("对象已被修改（resourceVersion %s 不是最新版本），请重新加载后再编辑", rv),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	r.logger.Infof("dashboard 编辑已提交: %s %s/%s", t.gvk.Kind, t.namespace, t.name)
+
+	data, err := toEditableYAML(edited, t.gvk, false)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(YAMLEditResponse{
+		YAML:            string(data),
+		ResourceVersion: editedMeta.GetResourceVersion(),
+		Valid:           true,
+		Diff:            diff,
+		Changed:         true,
+	})
+}
+
+// parseEdited 解析并校验编辑后的 YAML，返回所有校验错误（而不是遇到第一个就返回）
+func (r DashboardRoutes) parseEdited(t editTarget, body []byte) (runtime.Object, []string) {
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil, []string{"YAML 内容为空"}
+	}
+	obj, gvk, err := r.parser.ParseYAML(body)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	var errs []string
+	if gvk == nil || *gvk != t.gvk {
+		errs = append(errs, fmt.Sprintf("apiVersion/kind 不匹配: 期望 %s，实际 %v", t.gvk, gvk))
+	}
+	meta, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, append(errs, "对象缺少 metadata")
+	}
+	if meta.GetNamespace() == "" {
+		meta.SetNamespace(t.namespace)
+	}
+	if meta.GetName() != t.name {
+		errs = append(errs, fmt.Sprintf("metadata.name 不允许修改: %q -> %q", t.name, meta.GetName()))
+	}
+	if meta.GetNamespace() != t.namespace {
+		errs = append(errs, fmt.Sprintf("metadata.namespace 不允许修改: %q -> %q", t.namespace, meta.GetNamespace()))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(meta.GetName()) {
+		errs = append(errs, "metadata.name: "+msg)
+	}
+	metaPath := field.NewPath("metadata")
+	fieldErrs := metav1validation.ValidateLabels(meta.GetLabels(), metaPath.Child("labels"))
+	for _, e := range fieldErrs {
+		errs = append(errs, e.Error())
+	}
+	return obj, errs
+}
+
+// admissionErrors 把准入校验的错误展开为编辑器的错误列表（*apiserver.InvalidError 每个字段一条）
+func admissionErrors(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var invalid *apiserver.InvalidError
+	if !errors.As(err, &invalid) {
+		return []string{err.Error()}
+	}
+	errs := make([]string, 0, len(invalid.Causes))
+	for _, cause := range invalid.Causes {
+		errs = append(errs, cause.Error())
+	}
+	return errs
+}
+
+// preserveSystemFields 编辑器不允许修改系统字段：uid / creationTimestamp 沿用当前对象
+func preserveSystemFields(current, edited runtime.Object) {
+	currentMeta, ok1 := current.(metav1.Object)
+	editedMeta, ok2 := edited.(metav1.Object)
+	if !ok1 || !ok2 {
+		return
+	}
+	editedMeta.SetUID(currentMeta.GetUID())
+	editedMeta.SetCreationTimestamp(currentMeta.GetCreationTimestamp())
+}
+
+// diffObjects 返回 current 与 edited 规范化 YAML 之间的 unified diff（无差异返回空字符串）
+func diffObjects(current, edited runtime.Object, t editTarget) (string, error) {
+	before, err := toEditableYAML(current, t.gvk, true)
+	if err != nil {
+		return "", err
+	}
+	after, err := toEditableYAML(edited, t.gvk, true)
+	if err != nil {
+		return "", err
+	}
+	name := t.name
+	if t.namespace != "" {
+		name = t.namespace + "/" + t.name
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: "current/" + name,
+		ToFile:   "edited/" + name,
+		Context:  3,
+	})
+}
+
+// toEditableYAML 序列化为 YAML（去掉 managedFields）；forDiff 时同时去掉 resourceVersion，
+// 它只用于乐观并发校验，不参与 diff
+func toEditableYAML(obj runtime.Object, gvk schema.GroupVersionKind, forDiff bool) ([]byte, error) {
+	cp := obj.DeepCopyObject()
+	cp.GetObjectKind().SetGroupVersionKind(gvk)
+	if meta, ok := cp.(metav1.Object); ok {
+		meta.SetManagedFields(nil)
+		if forDiff {
+			meta.SetResourceVersion("")
+		}
+	}
+	return parser.ToYAML(cp)
+}
+
+func resourceVersionOf(obj runtime.Object) string {
+	if meta, ok := obj.(metav1.Object); ok {
+		return meta.GetResourceVersion()
+	}
+	return ""
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Pod 日志查看：
+// - GET /dashboard/api/pods/:namespace/:name/containers  容器列表（用于选择容器）
+// - GET /dashboard/api/pods/:namespace/:name/logs        日志；follow=true 时以 SSE 推送
+// 查询参数：container、tailLines（默认 500）、sinceSeconds、timestamps、follow，
+// 以及服务端过滤 grep（子串）/ regex=true（正则）/ ignoreCase=true。
+
+const (
+	// defaultLogTailLines dashboard 默认只取最近的日志，避免一次性推送过大的历史
+	defaultLogTailLines = 500
+	// logChunkLines / logChunkInterval 控制 SSE 推送的分块：攒够行数或到达间隔即发送
+	logChunkLines    = 200
+	logChunkInterval = 250 * time.Millisecond
+	// logKeepaliveInterval 没有新日志时发送 SSE 注释，用于发现已断开的客户端
+	logKeepaliveInterval = 15 * time.Second
+	// maxLogLineBytes 单行日志上限（超过时停止读取并返回错误）
+	maxLogLineBytes = 256 * 1024
+)
+
+// LogChunk 是日志推送的一块
+type LogChunk struct {
+	Lines []string `json:"lines"`
+}
+
+func (r DashboardRoutes) setUpLogs() {
+	g := r.fiber.App.Group("/dashboard/api/pods")
+	g.Get("/:namespace/:name/containers", podNamespaceAllowed, r.handlePodContainers)
+	g.Get("/:namespace/:name/logs", podNamespaceAllowed, r.handlePodLogs)
+}
+
+// podNamespaceAllowed 拒绝访问当前身份不可见 namespace 中的 Pod（身份限定了资源种类且不包括 Pod 时同样拒绝）
+func podNamespaceAllowed(c *fiber.Ctx) error {
+	id := webprovider.IdentityFromCtx(c)
+	if !id.AllowsKind("Pod") {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: cannot access Pod"})
+	}
+	if !id.AllowsNamespace(c.Params("namespace")) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: cannot access namespace " + c.Params("namespace")})
+	}
+	return c.Next()
+}
+
+func (r DashboardRoutes) handlePodContainers(c *fiber.Ctx) error {
+	obj, err := r.store.Get(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, c.Params("namespace"), c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "stored object is not a Pod"})
+	}
+	names := make([]string, 0, len(pod.Spec.Containers))
+	for _, ct := range pod.Spec.Containers {
+		names = append(names, ct.Name)
+	}
+	return c.JSON(fiber.Map{"containers": names})
+}
+
+func (r DashboardRoutes) handlePodLogs(c *fiber.Ctx) error {
+	opts, err := apiserver.ParsePodLogOptions(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if opts.TailLines == nil {
+		n := int64(defaultLogTailLines)
+		opts.TailLines = &n
+	}
+	match, err := logLineFilter(c.Query("grep"), c.QueryBool("regex"), c.QueryBool("ignoreCase"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rc, status, err := apiserver.OpenPodLogs(ctx, r.store, r.logs, c.Params("namespace"), c.Params("name"), opts)
+	if err != nil {
+		cancel()
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if !opts.Follow {
+		defer cancel()
+		defer rc.Close()
+		lines := []string{}
+		err := scanLogLines(rc, match, func(line string) bool {
+			lines = append(lines, line)
+			return true
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(LogChunk{Lines: lines})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer rc.Close()
+
+		lineCh := make(chan string, logChunkLines)
+		errCh := make(chan error, 1)
+		go func() {
+			defer close(lineCh)
+			errCh <- scanLogLines(rc, match, func(line string) bool {
+				select {
+				case lineCh <- line:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+		}()
+
+		ticker := time.NewTicker(logChunkInterval)
+		defer ticker.Stop()
+		var pending []string
+		lastWrite := time.Now()
+		flush := func() bool {
+			if len(pending) == 0 {
+				if time.Since(lastWrite) < logKeepaliveInterval {
+					return true
+				}
+				lastWrite = time.Now()
+				if _, err := w.WriteString(": keepalive\n\n"); err != nil {
+					return false
+				}
+				return w.Flush() == nil
+			}
+			err := writeSSEEvent(w, "logs", LogChunk{Lines: pending})
+			pending = nil
+			lastWrite = time.Now()
+			return err == nil
+		}
+
+		for {
+			select {
+			case line, ok := <-lineCh:
+				if !ok {
+					if !flush() {
+						return
+					}
+					end := fiber.Map{"reason": "EOF"}
+					if err := <-errCh; err != nil {
+						end["reason"] = err.Error()
+					}
+					_ = writeSSEEvent(w, "end", end)
+					return
+				}
+				pending = append(pending, line)
+				if len(pending) >= logChunkLines && !flush() {
+					return
+				}
+			case <-ticker.C:
+				if !flush() {
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// logLineFilter 构造服务端过滤函数；pattern 为空时不过滤
+func logLineFilter(pattern string, useRegex, ignoreCase bool) (func(string) bool, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if useRegex {
+		if ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		return re.MatchString, nil
+	}
+	if ignoreCase {
+		lower := strings.ToLower(pattern)
+		return func(line string) bool { return strings.Contains(strings.ToLower(line), lower) }, nil
+	}
+	return func(line string) bool { return strings.Contains(line, pattern) }, nil
+}
+
+// scanLogLines 按行读取日志，match 非空时只保留匹配行；emit 返回 false 时停止
+func scanLogLines(r io.Reader, match func(string) bool, emit func(string) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match != nil && !match(line) {
+			continue
+		}
+		if !emit(line) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// writeSSEEvent 写出一条带事件名的 SSE 消息并 flush
+func writeSSEEvent(w *bufio.Writer, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testLog = "GET /healthz 200\nERROR database timeout\nGET /api 500\nerror: retrying\n"
+
+func TestLogLineFilter(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(testLog, "\n"), "\n")
+	for _, tc := range []struct {
+		name       string
+		pattern    string
+		regex      bool
+		ignoreCase bool
+		want       []string
+	}{
+		{name: "empty pattern keeps every line", want: lines},
+		{name: "substring is case sensitive", pattern: "ERROR", want: []string{"ERROR database timeout"}},
+		{name: "substring ignoring case", pattern: "error", ignoreCase: true, want: []string{"ERROR database timeout", "error: retrying"}},
+		{name: "regex metacharacters are literal without regex", pattern: "GET /.* 500", want: nil},
+		{name: "regex", pattern: `GET /\S* 5\d\d`, regex: true, want: []string{"GET /api 500"}},
+		{name: "regex ignoring case", pattern: "^error", regex: true, ignoreCase: true, want: []string{"ERROR database timeout", "error: retrying"}},
+	} {
+		match, err := logLineFilter(tc.pattern, tc.regex, tc.ignoreCase)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if tc.pattern == "" && match != nil {
+			t.Errorf("%s: filter is not nil", tc.name)
+		}
+		var got []string
+		for _, line := range lines {
+			if match == nil || match(line) {
+				got = append(got, line)
+			}
+		}
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("%s: matched %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := logLineFilter("([a-z", true, false); err == nil || !strings.Contains(err.Error(), "invalid regex") {
+		t.Errorf("invalid regex: %v", err)
+	}
+	// 不使用正则时同样的字符串是普通子串
+	if match, err := logLineFilter("([a-z", false, false); err != nil || !match("x ([a-z y") {
+		t.Errorf("substring with regex metacharacters: %v", err)
+	}
+}
+
+func TestScanLogLines(t *testing.T) {
+	match, _ := logLineFilter("GET", false, false)
+	var got []string
+	err := scanLogLines(strings.NewReader(testLog), match, func(line string) bool {
+		got = append(got, line)
+		return true
+	})
+	if err != nil || strings.Join(got, "|") != "GET /healthz 200|GET /api 500" {
+		t.Errorf("scanLogLines = %q, %v", got, err)
+	}
+
+	// emit 返回 false 时停止
+	got = nil
+	err = scanLogLines(strings.NewReader(testLog), nil, func(line string) bool {
+		got = append(got, line)
+		return len(got) < 2
+	})
+	if err != nil || len(got) != 2 {
+		t.Errorf("stopped scan = %q, %v", got, err)
+	}
+
+	long := strings.Repeat("x", maxLogLineBytes+1) + "\n"
+	if err := scanLogLines(strings.NewReader(long), nil, func(string) bool { return true }); err == nil {
+		t.Error("line over maxLogLineBytes did not fail")
+	}
+}
+
+// fakeLogStreamer 返回固定的日志，记录是否被调用
+type fakeLogStreamer struct {
+	called bool
+}
+
+func (f *fakeLogStreamer) StreamPodLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	f.called = true
+	if opts.TailLines == nil || *opts.TailLines != defaultLogTailLines {
+		return nil, errors.New("tailLines default not applied")
+	}
+	return io.NopCloser(strings.NewReader(testLog)), nil
+}
+
+func TestHandlePodLogsGrep(t *testing.T) {
+	store := storage.NewMemoryStore()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	if err := store.Create(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, pod); err != nil {
+		t.Fatal(err)
+	}
+	logs := &fakeLogStreamer{}
+	r := DashboardRoutes{store: store, logs: logs}
+	app := fiber.New()
+	app.Get("/dashboard/api/pods/:namespace/:name/logs", r.handlePodLogs)
+
+	get := func(query url.Values) (int, []byte) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/dashboard/api/pods/default/web/logs?"+query.Encode(), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	for _, tc := range []struct {
+		query url.Values
+		want  []string
+	}{
+		{url.Values{}, []string{"GET /healthz 200", "ERROR database timeout", "GET /api 500", "error: retrying"}},
+		{url.Values{"grep": {"error"}}, []string{"error: retrying"}},
+		{url.Values{"grep": {"error"}, "ignoreCase": {"true"}}, []string{"ERROR database timeout", "error: retrying"}},
+		{url.Values{"grep": {` 5\d\d$`}, "regex": {"true"}}, []string{"GET /api 500"}},
+	} {
+		code, body := get(tc.query)
+		var chunk LogChunk
+		if code != fiber.StatusOK || json.Unmarshal(body, &chunk) != nil || strings.Join(chunk.Lines, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("%s: HTTP %d: %s, want lines %q", tc.query.Encode(), code, body, tc.want)
+		}
+	}
+
+	// 不合法的正则返回 400，不打开日志
+	logs.called = false
+	code, body := get(url.Values{"grep": {"([a-z"}, "regex": {"true"}})
+	if code != fiber.StatusBadRequest || !strings.Contains(string(body), "invalid regex") {
+		t.Errorf("invalid regex: HTTP %d: %s", code, body)
+	}
+	if logs.called {
+		t.Error("logs were opened for an invalid pattern")
+	}
+}
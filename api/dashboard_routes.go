@@ -1,59 +1,109 @@
 package api
 
 import (
+	"bytes"
 	"context"
-	"fmt"
+	"encoding/json"
+	"html"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 	"github.com/google/uuid"
 	"go.uber.org/fx"
 )
 
+// defaultPodPageSize 分页拉取 Pod 时默认的每页数量
+const defaultPodPageSize = 500
+
 type DashboardRoutes struct {
 	logger logprovider.Logger
 	fiber  webprovider.FiberEngine
 	hub    *ResourceHub
+	store  storage.Store
+	parser *parser.Parser
+	// admission 与 apiserver 共用的准入校验，YAML 编辑器提交前调用
+	admission *apiserver.Admission
+	logs      apiserver.PodLogStreamer
+	// activity 空闲模式的活动记录（未引入 idle.Module 时为 nil）
+	activity apiserver.ActivityTracker
 }
 
 func NewDashboardRoutes(
 	logger logprovider.Logger,
 	fiber webprovider.FiberEngine,
 	hub *ResourceHub,
+	store storage.Store,
+	admission *apiserver.Admission,
+	logs apiserver.PodLogStreamer,
+	activity apiserver.ActivityTracker,
 ) DashboardRoutes {
 	return DashboardRoutes{
-		logger: logger,
-		fiber:  fiber,
-		hub:    hub,
+		logger:    logger,
+		fiber:     fiber,
+		hub:       hub,
+		store:     store,
+		parser:    parser.NewParser(),
+		admission: admission,
+		logs:      logs,
+		activity:  activity,
+	}
+}
+
+// beginSession 开始一个 dashboard 会话，会话期间不会进入空闲模式；返回结束会话的函数
+func (r DashboardRoutes) beginSession() func() {
+	if r.activity == nil {
+		return func() {}
 	}
+	return r.activity.Begin()
 }
 
 func (r DashboardRoutes) SetUp() {
 	dashboardHTML := resolveWebStaticFilePath("dashboard.html")
 
+	// dashboard 的接口与 WebSocket 连接都算作活动，空闲模式下先恢复再处理
+	if r.activity != nil {
+		touch := func(c *fiber.Ctx) error {
+			r.activity.Touch()
+			return c.Next()
+		}
+		r.fiber.App.Use("/dashboard", touch)
+		r.fiber.App.Use("/ws", touch)
+	}
+
 	r.fiber.App.Get("/", func(c *fiber.Ctx) error {
-		return c.SendFile(dashboardHTML)
+		return r.sendDashboard(c, dashboardHTML)
 	})
 	r.fiber.App.Get("/dashboard", func(c *fiber.Ctx) error {
-		return c.SendFile(dashboardHTML)
+		return r.sendDashboard(c, dashboardHTML)
 	})
 
-	// WebSocket endpoint for live resource updates.
+	// WebSocket endpoint for live resource updates：先推送完整快照，之后推送增量（snapshot-delta）。
+	// 参数 namespace、kinds（nodes,pods,devices）、labelSelector 过滤订阅的资源
 	r.fiber.App.Get("/ws/resources", websocket.New(func(c *websocket.Conn) {
-		id := uuid.NewString()
-		ch, unsubscribe := r.hub.Subscribe(id)
+		filter, err := ParseSnapshotFilter(c.Query("namespace"), c.Query("kinds"), c.Query("labelSelector"))
+		if err != nil {
+			payload, _ := json.Marshal(ResourceSnapshot{Type: "snapshot", GeneratedAt: time.Now(), Error: &ErrorDTO{Message: err.Error()}})
+			_ = c.WriteMessage(websocket.TextMessage, payload)
+			return
+		}
+		// 开启认证时 ResourceHub 只推送当前身份可以读取的 namespace 与资源种类
+		ch, snapshot, unsubscribe := r.hub.Subscribe(uuid.NewString(), webprovider.IdentityFromLocals(c.Locals), filter)
 		defer unsubscribe()
+		defer r.beginSession()()
 
 		// Send initial snapshot.
-		if payload, err := r.hub.SnapshotJSON(); err == nil {
-			_ = c.WriteMessage(websocket.TextMessage, payload)
-		} else {
-			_ = c.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"snapshot","generatedAt":"%s","nodes":[],"pods":[],"counts":{"nodes":0,"pods":0},"error":{"message":"%s"}}`, time.Now().Format(time.RFC3339), err.Error())))
+		if payload, err := json.Marshal(snapshot); err == nil {
+			if err := c.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
 		}
 
 		// Keep a reader running so we detect client close quickly.
@@ -73,6 +123,61 @@ func (r DashboardRoutes) SetUp() {
 				return
 			case payload, ok := <-ch:
 				if !ok {
+					// 订阅被关闭（客户端过慢或切换分页模式），断开连接让前端重连获取完整快照
+					return
+				}
+				if err := c.WriteMessage(websocket.TextMessage, payload); err != nil {
+					return
+				}
+			}
+		}
+	}))
+
+	// 分页拉取 Pod（快照进入分页模式时使用）：参数 limit（默认 500）、continue、namespace、labelSelector
+	r.fiber.App.Get("/dashboard/api/pods", func(c *fiber.Ctx) error {
+		filter, err := ParseSnapshotFilter(c.Query("namespace"), SnapshotKindPods, c.Query("labelSelector"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		page, err := r.hub.ListPods(webprovider.IdentityFromCtx(c), filter, c.QueryInt("limit", defaultPodPageSize), c.Query("continue"))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(page)
+	})
+
+	// 拓扑图：GET 返回完整拓扑；WebSocket 先推送完整拓扑，之后推送增量（topology-delta）
+	r.fiber.App.Get("/dashboard/api/topology", func(c *fiber.Ctx) error {
+		return c.JSON(r.hub.Topology(webprovider.IdentityFromCtx(c)))
+	})
+	r.fiber.App.Get("/ws/topology", websocket.New(func(c *websocket.Conn) {
+		ch, graph, unsubscribe := r.hub.SubscribeTopology(uuid.NewString(), webprovider.IdentityFromLocals(c.Locals))
+		defer unsubscribe()
+		defer r.beginSession()()
+
+		if payload, err := json.Marshal(graph); err == nil {
+			if err := c.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		}
+
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			for {
+				if _, _, err := c.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-readDone:
+				return
+			case payload, ok := <-ch:
+				if !ok {
+					// 订阅被关闭（客户端过慢），断开连接让前端重连获取完整拓扑
 					return
 				}
 				if err := c.WriteMessage(websocket.TextMessage, payload); err != nil {
@@ -81,6 +186,28 @@ func (r DashboardRoutes) SetUp() {
 			}
 		}
 	}))
+
+	// YAML 编辑器
+	r.setUpEditor()
+	// Pod 日志
+	r.setUpLogs()
+}
+
+// dashboardBasePathMeta 看板页面中记录路径前缀的 meta 标签，页面中的接口与 WebSocket 地址都以它为前缀
+const dashboardBasePathMeta = `<meta name="k3-base-path" content="" />`
+
+// sendDashboard 返回看板页面，并把 web.base_path 填入页面，使经反向代理以子路径访问时页面中的链接仍然正确
+func (r DashboardRoutes) sendDashboard(c *fiber.Ctx, path string) error {
+	if r.fiber.BasePath == "" {
+		return c.SendFile(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fiber.ErrNotFound
+	}
+	meta := `<meta name="k3-base-path" content="` + html.EscapeString(r.fiber.BasePath) + `" />`
+	c.Type("html", "utf-8")
+	return c.Send(bytes.Replace(data, []byte(dashboardBasePathMeta), []byte(meta), 1))
 }
 
 func resolveWebStaticFilePath(filename string) string {
@@ -107,8 +234,9 @@ func resolveWebStaticFilePath(filename string) string {
 
 // DashboardModule wires hub lifecycle start.
 var DashboardModule = fx.Module("dashboard",
-	fx.Provide(NewResourceHub),
-	fx.Provide(NewDashboardRoutes),
+	fx.Provide(newConfiguredResourceHub),
+	// PodLogStreamer 仅在带容器运行时的进程中存在（one/start 模式），ActivityTracker 仅在引入 idle.Module 的进程中存在
+	fx.Provide(fx.Annotate(NewDashboardRoutes, fx.ParamTags(``, ``, ``, ``, ``, `optional:"true"`, `optional:"true"`))),
 	fx.Invoke(func(lc fx.Lifecycle, hub *ResourceHub) {
 		lc.Append(fx.Hook{
 			OnStart: func(ctx context.Context) error {
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSendDashboardFillsBasePath(t *testing.T) {
+	const page = "../cmd/web/static/dashboard.html"
+	for basePath, want := range map[string]string{
+		"":    dashboardBasePathMeta,
+		"/k3": `<meta name="k3-base-path" content="/k3" />`,
+	} {
+		r := DashboardRoutes{fiber: webprovider.FiberEngine{BasePath: basePath}}
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error { return r.sendDashboard(c, page) })
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatalf("GET /: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != fiber.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("base path %q: HTTP %d, page does not contain %s", basePath, resp.StatusCode, want)
+		}
+	}
+}
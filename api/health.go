@@ -48,6 +48,7 @@ func (r IndexRoutes) SetUp() {
 		index.Get("/health", r.health.CheckFiber)
 		index.Options("/healthz", r.health.CheckFiber)
 		index.Get("/healthz", r.health.CheckFiber)
+		index.Get("/readyz", r.health.Ready)
 	}
 }
 
@@ -1,30 +1,44 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/printers"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type ResourceSnapshot struct {
-	Type        string    `json:"type"` // "snapshot"
-	GeneratedAt time.Time `json:"generatedAt"`
-	Nodes       []NodeDTO `json:"nodes"`
-	Pods        []PodDTO  `json:"pods"`
-	Counts      CountsDTO `json:"counts"`
-	Error       *ErrorDTO `json:"error,omitempty"`
-	Info        *InfoDTO  `json:"info,omitempty"`
+	Type        string      `json:"type"` // "snapshot"
+	GeneratedAt time.Time   `json:"generatedAt"`
+	Nodes       []NodeDTO   `json:"nodes"`
+	Pods        []PodDTO    `json:"pods"`
+	Devices     []DeviceDTO `json:"devices"`
+	Counts      CountsDTO   `json:"counts"`
+	// Paginated 为 true 时 pods 为空（Pod 数超过上限），客户端通过 GET /dashboard/api/pods 分页拉取
+	Paginated bool      `json:"paginated,omitempty"`
+	Error     *ErrorDTO `json:"error,omitempty"`
+	Info      *InfoDTO  `json:"info,omitempty"`
 }
 
 type CountsDTO struct {
-	Nodes int `json:"nodes"`
-	Pods  int `json:"pods"`
+	Nodes   int `json:"nodes"`
+	Pods    int `json:"pods"`
+	Devices int `json:"devices"`
+	// DevicesOnline 在线的局域网设备数
+	DevicesOnline int `json:"devicesOnline"`
 }
 
 type ErrorDTO struct {
@@ -45,49 +59,81 @@ type NodeDTO struct {
 }
 
 type PodDTO struct {
-	Namespace string `json:"namespace"`
-	Name      string `json:"name"`
-	NodeName  string `json:"nodeName,omitempty"`
-	Phase     string `json:"phase"`
-	Ready     bool   `json:"ready"`
-	Restarts  int32  `json:"restarts"`
-	RV        string `json:"resourceVersion"`
-	UID       string `json:"uid"`
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	NodeName  string            `json:"nodeName,omitempty"`
+	Phase     string            `json:"phase"`
+	Ready     bool              `json:"ready"`
+	Restarts  int32             `json:"restarts"`
+	RV        string            `json:"resourceVersion"`
+	UID       string            `json:"uid"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	// ReadyContainers、Status 由 pkg/printers 计算（与 apiserver Table 的 READY、STATUS 列一致）；
+	// AGE 由前端根据 CreatedAt 计算，避免快照随时间变化
+	ReadyContainers string    `json:"readyContainers"`
+	Status          string    `json:"status"`
+	CreatedAt       time.Time `json:"createdAt"`
+	// PodIP、HostIP 为 status.podIP 与 status.hostIP（Pod 启动前为空）
+	PodIP  string `json:"podIP,omitempty"`
+	HostIP string `json:"hostIP,omitempty"`
 }
 
-// ResourceHub watches Store and broadcasts snapshots to subscribers.
+// DeviceDTO 是局域网设备清单（k3.io/v1 Device）中的一台设备
+type DeviceDTO struct {
+	Name        string    `json:"name"`
+	IP          string    `json:"ip"`
+	MAC         string    `json:"mac,omitempty"`
+	Hostname    string    `json:"hostname,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Online      bool      `json:"online"`
+	LastSeen    time.Time `json:"lastSeen"`
+	ObservedBy  string    `json:"observedBy,omitempty"`
+}
+
+// snapshotKinds 是快照关注的资源：拓扑资源加上局域网设备
+var snapshotKinds = append(topologyKinds[:len(topologyKinds):len(topologyKinds)], k3v1.DeviceGVK)
+
+// ResourceHub watches Store and broadcasts snapshot deltas to subscribers.
 type ResourceHub struct {
 	store  storage.Store
 	logger logprovider.Logger
+	// maxObjects 订阅者可见的 Pod 数超过它时改为分页模式，0 或负数表示不限制
+	maxObjects int
+
+	// mu 保护快照订阅者与上一次快照（增量基于它计算）
+	mu           sync.Mutex
+	subs         map[string]*subscriber
+	lastSnapshot *ResourceSnapshot
 
-	mu   sync.RWMutex
-	subs map[string]chan []byte
+	// topoMu 保护拓扑订阅者与上一次拓扑（增量基于它计算）
+	topoMu       sync.Mutex
+	topoSubs     map[string]*subscriber
+	lastTopology *TopologyGraph
 
 	startOnce sync.Once
 }
 
 func NewResourceHub(store storage.Store, logger logprovider.Logger) *ResourceHub {
 	return &ResourceHub{
-		store:  store,
-		logger: logger,
-		subs:   make(map[string]chan []byte),
+		store:      store,
+		logger:     logger,
+		maxObjects: DefaultSnapshotMaxObjects,
+		subs:       make(map[string]*subscriber),
+		topoSubs:   make(map[string]*subscriber),
+	}
+}
+
+// newConfiguredResourceHub 按 web.snapshot_max_objects 创建 ResourceHub（0 使用默认值，负数不限制）
+func newConfiguredResourceHub(store storage.Store, logger logprovider.Logger, cfg config.Config) *ResourceHub {
+	h := NewResourceHub(store, logger)
+	if cfg.Gin.SnapshotMaxObjects != 0 {
+		h.maxObjects = cfg.Gin.SnapshotMaxObjects
 	}
+	return h
 }
 
 func (h *ResourceHub) Start(ctx context.Context) {
 	h.startOnce.Do(func() {
-		nodeGVK := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Node"}
-		podGVK := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
-
-		nodeCh, err := h.store.Watch(nodeGVK, "", "")
-		if err != nil {
-			h.logger.Warnf("ResourceHub: watch nodes failed: %v", err)
-		}
-		podCh, err := h.store.Watch(podGVK, "", "")
-		if err != nil {
-			h.logger.Warnf("ResourceHub: watch pods failed: %v", err)
-		}
-
 		trigger := make(chan struct{}, 1)
 		triggerSend := func() {
 			select {
@@ -96,29 +142,19 @@ func (h *ResourceHub) Start(ctx context.Context) {
 			}
 		}
 
-		// Any store event triggers a debounced snapshot broadcast.
-		if nodeCh != nil {
-			go func() {
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					case _, ok := <-nodeCh:
-						if !ok {
-							return
-						}
-						triggerSend()
-					}
-				}
-			}()
-		}
-		if podCh != nil {
+		// Any store event triggers a debounced snapshot/topology broadcast.
+		for _, gvk := range snapshotKinds {
+			ch, err := h.store.Watch(gvk, "", "")
+			if err != nil {
+				h.logger.Warnf("ResourceHub: watch %s failed: %v", gvk.Kind, err)
+				continue
+			}
 			go func() {
 				for {
 					select {
 					case <-ctx.Done():
 						return
-					case _, ok := <-podCh:
+					case _, ok := <-ch:
 						if !ok {
 							return
 						}
@@ -157,6 +193,7 @@ func (h *ResourceHub) Start(ctx context.Context) {
 					timer = nil
 					timerC = nil
 					h.broadcastSnapshot()
+					h.broadcastTopology()
 				}
 			}
 		}()
@@ -166,43 +203,229 @@ func (h *ResourceHub) Start(ctx context.Context) {
 	})
 }
 
-func (h *ResourceHub) Subscribe(id string) (ch <-chan []byte, unsubscribe func()) {
+// NamespaceFilter 决定订阅者可见的 namespace；nil 表示不受限
+type NamespaceFilter func(namespace string) bool
+
+// WatchAccess 是订阅者身份可见的范围：Namespaces 作用于 namespaced 资源，Kinds 作用于所有资源（Node、Device 也受限）。
+// 零值表示不受限
+type WatchAccess struct {
+	Namespaces NamespaceFilter
+	Kinds      func(kind string) bool
+}
+
+// AccessFor 返回身份可见的范围；id 为 nil（未开启认证）或 cluster-admin 时不受限
+func AccessFor(id *webprovider.Identity) WatchAccess {
+	return WatchAccess{Namespaces: id.NamespaceFilter(), Kinds: id.KindFilter()}
+}
+
+// allows 判断 namespace 下（集群级资源为空）该种类的对象是否可见
+func (a WatchAccess) allows(kind, namespace string) bool {
+	if a.Kinds != nil && !a.Kinds(kind) {
+		return false
+	}
+	return namespace == "" || a.Namespaces == nil || a.Namespaces(namespace)
+}
+
+// unrestricted 表示不受限，可以跳过过滤
+func (a WatchAccess) unrestricted() bool {
+	return a.Namespaces == nil && a.Kinds == nil
+}
+
+// subscriber 是一个 websocket 订阅者
+type subscriber struct {
+	ch     chan []byte
+	access WatchAccess
+	// filter 快照订阅者的过滤条件；paginated 为订阅时是否进入分页模式
+	filter    SnapshotFilter
+	paginated bool
+}
+
+// Subscribe 订阅资源快照；返回的 snapshot 是订阅时刻按 filter 过滤后的完整快照，之后 ch 中只推送 SnapshotDelta。
+// identity 为订阅者的身份（未开启认证时为 nil），快照与增量只包含它可以读取的 namespace 与资源种类。
+// 可见的 Pod 数超过上限时 snapshot 不带 Pod（paginated），增量中也只提示 Pod 有变化。
+// 客户端消费过慢、或可见的 Pod 数跨过上限时 ch 会被关闭，客户端应重新订阅获取完整快照
+func (h *ResourceHub) Subscribe(id string, identity *webprovider.Identity, filter SnapshotFilter) (ch <-chan []byte, snapshot ResourceSnapshot, unsubscribe func()) {
+	filter.access = AccessFor(identity)
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.lastSnapshot == nil {
+		s := h.buildSnapshot()
+		h.lastSnapshot = &s
+	}
+	snapshot = FilterSnapshot(*h.lastSnapshot, filter)
+	paginated := h.exceedsLimit(snapshot.Counts)
+	if paginated {
+		snapshot.Pods = []PodDTO{}
+		snapshot.Paginated = true
+		snapshot.Info = &InfoDTO{Message: fmt.Sprintf("Pod 数超过 %d，请分页查看", h.maxObjects)}
+	}
+
 	c := make(chan []byte, 20)
-	h.subs[id] = c
+	h.subs[id] = &subscriber{ch: c, filter: filter, paginated: paginated}
 
-	return c, func() {
+	return c, snapshot, func() {
 		h.mu.Lock()
 		defer h.mu.Unlock()
 		if existing, ok := h.subs[id]; ok {
 			delete(h.subs, id)
-			close(existing)
+			close(existing.ch)
 		}
 	}
 }
 
-func (h *ResourceHub) broadcastSnapshot() {
-	payload, err := h.SnapshotJSON()
-	if err != nil {
-		h.logger.Warnf("ResourceHub: snapshot failed: %v", err)
+// SubscribeTopology 订阅拓扑增量；返回的 graph 是订阅时刻按 identity 可见范围过滤的完整拓扑，之后 ch 中只推送 TopologyDelta。
+// 客户端消费过慢时 ch 会被关闭（增量不能丢），客户端应重新订阅获取完整拓扑。
+func (h *ResourceHub) SubscribeTopology(id string, identity *webprovider.Identity) (ch <-chan []byte, graph TopologyGraph, unsubscribe func()) {
+	access := AccessFor(identity)
+
+	h.topoMu.Lock()
+	defer h.topoMu.Unlock()
+
+	if h.lastTopology == nil {
+		g := h.BuildTopology()
+		h.lastTopology = &g
+	}
+	c := make(chan []byte, 20)
+	h.topoSubs[id] = &subscriber{ch: c, access: access}
+
+	return c, FilterTopology(*h.lastTopology, access), func() {
+		h.topoMu.Lock()
+		defer h.topoMu.Unlock()
+		if existing, ok := h.topoSubs[id]; ok {
+			delete(h.topoSubs, id)
+			close(existing.ch)
+		}
+	}
+}
+
+func (h *ResourceHub) broadcastTopology() {
+	h.topoMu.Lock()
+	defer h.topoMu.Unlock()
+
+	if len(h.topoSubs) == 0 {
+		// 没有订阅者时不维护增量基线，下次订阅重新构建
+		h.lastTopology = nil
+		return
+	}
+	next := h.BuildTopology()
+	delta := DiffTopology(*h.lastTopology, next)
+	h.lastTopology = &next
+	if delta.Empty() {
 		return
 	}
+	for id, sub := range h.topoSubs {
+		d := FilterTopologyDelta(delta, sub.access)
+		if d.Empty() {
+			continue
+		}
+		payload, err := json.Marshal(d)
+		if err != nil {
+			h.logger.Warnf("ResourceHub: topology delta failed: %v", err)
+			return
+		}
+		select {
+		case sub.ch <- payload:
+		default:
+			// Slow client; deltas cannot be dropped, force a resubscribe.
+			delete(h.topoSubs, id)
+			close(sub.ch)
+		}
+	}
+}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	for id, ch := range h.subs {
+// exceedsLimit 判断可见的 Pod 数是否超过上限（超过时使用分页模式）
+func (h *ResourceHub) exceedsLimit(counts CountsDTO) bool {
+	return h.maxObjects > 0 && counts.Pods > h.maxObjects
+}
+
+// broadcastSnapshot 计算与上一次快照的增量，按订阅者过滤后推送（不再向每个订阅者序列化完整快照）
+func (h *ResourceHub) broadcastSnapshot() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.subs) == 0 {
+		// 没有订阅者时不维护增量基线，下次订阅重新构建
+		h.lastSnapshot = nil
+		return
+	}
+	prev := ResourceSnapshot{}
+	if h.lastSnapshot != nil {
+		prev = *h.lastSnapshot
+	}
+	next := h.buildSnapshot()
+	delta := DiffSnapshot(prev, next)
+	h.lastSnapshot = &next
+	if delta.Empty() {
+		return
+	}
+	for id, sub := range h.subs {
+		d := FilterSnapshotDelta(delta, prev, next, sub.filter, sub.paginated)
+		if h.exceedsLimit(d.Counts) != sub.paginated {
+			// 可见的 Pod 数跨过上限，断开让客户端重新订阅（切换分页模式）
+			delete(h.subs, id)
+			close(sub.ch)
+			continue
+		}
+		if d.Empty() {
+			continue
+		}
+		payload, err := json.Marshal(d)
+		if err != nil {
+			h.logger.Warnf("ResourceHub: snapshot delta failed: %v", err)
+			return
+		}
 		select {
-		case ch <- payload:
+		case sub.ch <- payload:
 		default:
-			// Slow client; drop message.
-			_ = id
+			// Slow client; deltas cannot be dropped, force a resubscribe.
+			delete(h.subs, id)
+			close(sub.ch)
+		}
+	}
+}
+
+// Topology 返回 identity 可见范围内的完整拓扑
+func (h *ResourceHub) Topology(identity *webprovider.Identity) TopologyGraph {
+	return FilterTopology(h.BuildTopology(), AccessFor(identity))
+}
+
+// ListPods 分页列出 identity 与 filter 可见的 Pod（按 namespace/name 排序），limit 小于等于 0 时返回全部。
+// 直接读取 Store（Selector 交给 ListBySelector），不依赖订阅的快照
+func (h *ResourceHub) ListPods(identity *webprovider.Identity, filter SnapshotFilter, limit int, continueToken string) (PodPage, error) {
+	filter.access = AccessFor(identity)
+	podGVK := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	namespace := ""
+	if len(filter.Namespaces) == 1 {
+		for ns := range filter.Namespaces {
+			namespace = ns
+		}
+	}
+	objects, err := h.store.ListBySelector(podGVK, namespace, filter.Selector)
+	if err != nil {
+		return PodPage{}, err
+	}
+	pods := make([]PodDTO, 0, len(objects))
+	for _, obj := range objects {
+		if p, ok := obj.(*corev1.Pod); ok {
+			if dto := podToDTO(p); filter.pod(dto) {
+				pods = append(pods, dto)
+			}
 		}
 	}
+	sortPods(pods)
+	return paginatePods(pods, limit, continueToken), nil
+}
+
+// sortPods 按 namespace/name 排序（与分页的 continue 标记一致）
+func sortPods(pods []PodDTO) {
+	sort.Slice(pods, func(i, j int) bool {
+		return podKey(pods[i].Namespace, pods[i].Name) < podKey(pods[j].Namespace, pods[j].Name)
+	})
 }
 
-func (h *ResourceHub) SnapshotJSON() ([]byte, error) {
+func (h *ResourceHub) buildSnapshot() ResourceSnapshot {
 	snap := ResourceSnapshot{
 		Type:        "snapshot",
 		GeneratedAt: time.Now(),
@@ -234,13 +457,32 @@ func (h *ResourceHub) SnapshotJSON() ([]byte, error) {
 			snap.Pods = append(snap.Pods, podToDTO(p))
 		}
 	}
+	sort.Slice(snap.Nodes, func(i, j int) bool { return snap.Nodes[i].Name < snap.Nodes[j].Name })
+	sortPods(snap.Pods)
 	snap.Counts = CountsDTO{Nodes: len(snap.Nodes), Pods: len(snap.Pods)}
 
-	b, err := json.Marshal(snap)
+	// 设备清单未开启时没有 Device，列出失败也不影响节点和 Pod
+	devices, err := h.store.List(k3v1.DeviceGVK, "")
 	if err != nil {
-		return nil, err
+		h.logger.Debugf("ResourceHub: list devices failed: %v", err)
+	}
+	for _, obj := range devices {
+		if d, ok := obj.(*k3v1.Device); ok {
+			snap.Devices = append(snap.Devices, deviceToDTO(d))
+			if d.Status.Online {
+				snap.Counts.DevicesOnline++
+			}
+		}
 	}
-	return b, nil
+	// 按 IP 排序，IP 相同（或都没有 IP）时按名称
+	sort.Slice(snap.Devices, func(i, j int) bool {
+		if c := bytes.Compare(net.ParseIP(snap.Devices[i].IP).To16(), net.ParseIP(snap.Devices[j].IP).To16()); c != 0 {
+			return c < 0
+		}
+		return snap.Devices[i].Name < snap.Devices[j].Name
+	})
+	snap.Counts.Devices = len(snap.Devices)
+	return snap
 }
 
 func nodeToDTO(n *corev1.Node) NodeDTO {
@@ -261,6 +503,19 @@ func nodeToDTO(n *corev1.Node) NodeDTO {
 	}
 }
 
+func deviceToDTO(d *k3v1.Device) DeviceDTO {
+	return DeviceDTO{
+		Name:        d.Name,
+		IP:          d.Status.IP,
+		MAC:         d.Status.MAC,
+		Hostname:    d.Status.Hostname,
+		Description: d.Spec.Description,
+		Online:      d.Status.Online,
+		LastSeen:    d.Status.LastSeen.Time,
+		ObservedBy:  d.Status.ObservedBy,
+	}
+}
+
 func podToDTO(p *corev1.Pod) PodDTO {
 	ready := false
 	for _, c := range p.Status.Conditions {
@@ -269,18 +524,21 @@ func podToDTO(p *corev1.Pod) PodDTO {
 			break
 		}
 	}
-	var restarts int32
-	for _, cs := range p.Status.ContainerStatuses {
-		restarts += cs.RestartCount
-	}
+	readyContainers, totalContainers := printers.PodReadyContainers(p)
 	return PodDTO{
-		Namespace: p.Namespace,
-		Name:      p.Name,
-		NodeName:  p.Spec.NodeName,
-		Phase:     string(p.Status.Phase),
-		Ready:     ready,
-		Restarts:  restarts,
-		RV:        p.ResourceVersion,
-		UID:       string(p.UID),
+		Namespace:       p.Namespace,
+		Name:            p.Name,
+		NodeName:        p.Spec.NodeName,
+		Phase:           string(p.Status.Phase),
+		Ready:           ready,
+		Restarts:        printers.PodRestarts(p),
+		RV:              p.ResourceVersion,
+		UID:             string(p.UID),
+		Labels:          p.Labels,
+		ReadyContainers: fmt.Sprintf("%d/%d", readyContainers, totalContainers),
+		Status:          printers.PodStatus(p),
+		CreatedAt:       p.CreationTimestamp.Time,
+		PodIP:           p.Status.PodIP,
+		HostIP:          p.Status.HostIP,
 	}
 }
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DefaultSnapshotMaxObjects 是 web.snapshot_max_objects 未设置时快照中 Pod 数的上限，超过后订阅者改为分页拉取 Pod
+const DefaultSnapshotMaxObjects = 2000
+
+// 快照中的资源种类（SnapshotFilter.Kinds 的取值）
+const (
+	SnapshotKindNodes   = "nodes"
+	SnapshotKindPods    = "pods"
+	SnapshotKindDevices = "devices"
+)
+
+// SnapshotDelta 是相对上一次快照的增量（type = "snapshot-delta"）。counts 为订阅者过滤后的最新计数；
+// 分页模式下不推送 Pod 的变化，只用 podsChanged 提示客户端重新拉取当前页
+type SnapshotDelta struct {
+	Type          string      `json:"type"`
+	GeneratedAt   time.Time   `json:"generatedAt"`
+	UpsertNodes   []NodeDTO   `json:"upsertNodes,omitempty"`
+	RemoveNodes   []string    `json:"removeNodes,omitempty"`
+	UpsertPods    []PodDTO    `json:"upsertPods,omitempty"`
+	RemovePods    []string    `json:"removePods,omitempty"`
+	UpsertDevices []DeviceDTO `json:"upsertDevices,omitempty"`
+	RemoveDevices []string    `json:"removeDevices,omitempty"`
+	PodsChanged   bool        `json:"podsChanged,omitempty"`
+	Counts        CountsDTO   `json:"counts"`
+	Error         *ErrorDTO   `json:"error,omitempty"`
+}
+
+// Empty 表示没有任何变化
+func (d SnapshotDelta) Empty() bool {
+	return len(d.UpsertNodes) == 0 && len(d.RemoveNodes) == 0 &&
+		len(d.UpsertPods) == 0 && len(d.RemovePods) == 0 &&
+		len(d.UpsertDevices) == 0 && len(d.RemoveDevices) == 0 && !d.PodsChanged
+}
+
+// PodPage 是分页拉取的一页 Pod（type = "pods-page"），continue 为空表示没有下一页
+type PodPage struct {
+	Type     string   `json:"type"`
+	Items    []PodDTO `json:"items"`
+	Continue string   `json:"continue,omitempty"`
+	Total    int      `json:"total"`
+}
+
+// SnapshotFilter 是订阅者的过滤条件：Namespaces 为订阅者选择的 namespace（只作用于 Pod），
+// Kinds 为订阅的资源种类，Selector 作用于 Node 与 Pod。零值表示不过滤。
+// 身份可见的范围（access）由 ResourceHub 按订阅者身份填写，调用方无法放宽
+type SnapshotFilter struct {
+	Namespaces map[string]bool
+	Kinds      map[string]bool
+	Selector   labels.Selector
+
+	access WatchAccess
+}
+
+// ParseSnapshotFilter 解析订阅参数：namespace、kinds 为逗号分隔的列表，labelSelector 为 Kubernetes 选择器语法
+func ParseSnapshotFilter(namespace, kinds, labelSelector string) (SnapshotFilter, error) {
+	f := SnapshotFilter{}
+	if namespaces := splitList(namespace); len(namespaces) > 0 {
+		f.Namespaces = make(map[string]bool, len(namespaces))
+		for _, ns := range namespaces {
+			f.Namespaces[ns] = true
+		}
+	}
+	if list := splitList(kinds); len(list) > 0 {
+		f.Kinds = make(map[string]bool, len(list))
+		for _, kind := range list {
+			switch kind {
+			case SnapshotKindNodes, SnapshotKindPods, SnapshotKindDevices:
+				f.Kinds[kind] = true
+			default:
+				return f, fmt.Errorf("unsupported kind %q (nodes, pods, devices)", kind)
+			}
+		}
+	}
+	if labelSelector != "" {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return f, fmt.Errorf("invalid labelSelector: %w", err)
+		}
+		f.Selector = selector
+	}
+	return f, nil
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// wants 判断是否订阅了该种类
+func (f SnapshotFilter) wants(kind string) bool {
+	return len(f.Kinds) == 0 || f.Kinds[kind]
+}
+
+// devices 判断局域网设备是否可见（k3.io/v1 Device，集群级资源）
+func (f SnapshotFilter) devices() bool {
+	return f.wants(SnapshotKindDevices) && f.access.allows("Device", "")
+}
+
+// matchesLabels 判断标签是否满足 Selector
+func (f SnapshotFilter) matchesLabels(l map[string]string) bool {
+	return f.Selector == nil || f.Selector.Matches(labels.Set(l))
+}
+
+// node 判断 Node 是否可见（Node 为集群级资源，不受 namespace 限制）
+func (f SnapshotFilter) node(n NodeDTO) bool {
+	return f.wants(SnapshotKindNodes) && f.access.allows("Node", "") && f.matchesLabels(n.Labels)
+}
+
+// pod 判断 Pod 是否可见
+func (f SnapshotFilter) pod(p PodDTO) bool {
+	if !f.wants(SnapshotKindPods) || !f.access.allows("Pod", p.Namespace) {
+		return false
+	}
+	if len(f.Namespaces) > 0 && !f.Namespaces[p.Namespace] {
+		return false
+	}
+	return f.matchesLabels(p.Labels)
+}
+
+// podKey 是 Pod 在增量中的标识（namespace/name）
+func podKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// FilterSnapshot 按 filter 过滤快照并重新计数
+func FilterSnapshot(snap ResourceSnapshot, f SnapshotFilter) ResourceSnapshot {
+	out := snap
+	out.Nodes, out.Pods, out.Devices = nil, nil, nil
+	for _, n := range snap.Nodes {
+		if f.node(n) {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	for _, p := range snap.Pods {
+		if f.pod(p) {
+			out.Pods = append(out.Pods, p)
+		}
+	}
+	if f.devices() {
+		out.Devices = snap.Devices
+	}
+	out.Counts = countSnapshot(snap, f)
+	return out
+}
+
+// countSnapshot 返回 filter 可见的对象数（不复制对象）
+func countSnapshot(snap ResourceSnapshot, f SnapshotFilter) CountsDTO {
+	var counts CountsDTO
+	for _, n := range snap.Nodes {
+		if f.node(n) {
+			counts.Nodes++
+		}
+	}
+	for _, p := range snap.Pods {
+		if f.pod(p) {
+			counts.Pods++
+		}
+	}
+	if f.devices() {
+		counts.Devices = snap.Counts.Devices
+		counts.DevicesOnline = snap.Counts.DevicesOnline
+	}
+	return counts
+}
+
+// DiffSnapshot 计算 prev -> next 的增量（不含 counts，由 FilterSnapshotDelta 按订阅者填写）
+func DiffSnapshot(prev, next ResourceSnapshot) SnapshotDelta {
+	d := SnapshotDelta{Type: "snapshot-delta", GeneratedAt: next.GeneratedAt, Error: next.Error}
+
+	prevNodes := make(map[string]NodeDTO, len(prev.Nodes))
+	for _, n := range prev.Nodes {
+		prevNodes[n.Name] = n
+	}
+	for _, n := range next.Nodes {
+		if old, ok := prevNodes[n.Name]; !ok || !reflect.DeepEqual(old, n) {
+			d.UpsertNodes = append(d.UpsertNodes, n)
+		}
+		delete(prevNodes, n.Name)
+	}
+	for name := range prevNodes {
+		d.RemoveNodes = append(d.RemoveNodes, name)
+	}
+
+	prevPods := make(map[string]PodDTO, len(prev.Pods))
+	for _, p := range prev.Pods {
+		prevPods[podKey(p.Namespace, p.Name)] = p
+	}
+	for _, p := range next.Pods {
+		key := podKey(p.Namespace, p.Name)
+		if old, ok := prevPods[key]; !ok || !reflect.DeepEqual(old, p) {
+			d.UpsertPods = append(d.UpsertPods, p)
+		}
+		delete(prevPods, key)
+	}
+	for key := range prevPods {
+		d.RemovePods = append(d.RemovePods, key)
+	}
+
+	prevDevices := make(map[string]DeviceDTO, len(prev.Devices))
+	for _, dev := range prev.Devices {
+		prevDevices[dev.Name] = dev
+	}
+	for _, dev := range next.Devices {
+		if old, ok := prevDevices[dev.Name]; !ok || old != dev {
+			d.UpsertDevices = append(d.UpsertDevices, dev)
+		}
+		delete(prevDevices, dev.Name)
+	}
+	for name := range prevDevices {
+		d.RemoveDevices = append(d.RemoveDevices, name)
+	}
+
+	sort.Strings(d.RemoveNodes)
+	sort.Strings(d.RemovePods)
+	sort.Strings(d.RemoveDevices)
+	return d
+}
+
+// FilterSnapshotDelta 按订阅者的 filter 过滤增量。prev 用于判断被删除或不再匹配的对象此前是否对订阅者可见：
+// 标签变化后不再匹配 Selector 的对象以删除的形式推送。paginated 为 true 时去掉 Pod 的变化，只设置 podsChanged
+func FilterSnapshotDelta(d SnapshotDelta, prev, next ResourceSnapshot, f SnapshotFilter, paginated bool) SnapshotDelta {
+	out := SnapshotDelta{Type: d.Type, GeneratedAt: d.GeneratedAt, Error: d.Error, Counts: countSnapshot(next, f)}
+
+	prevNodes := make(map[string]bool)
+	for _, n := range prev.Nodes {
+		if f.node(n) {
+			prevNodes[n.Name] = true
+		}
+	}
+	for _, n := range d.UpsertNodes {
+		if f.node(n) {
+			out.UpsertNodes = append(out.UpsertNodes, n)
+		} else if prevNodes[n.Name] {
+			out.RemoveNodes = append(out.RemoveNodes, n.Name)
+		}
+	}
+	for _, name := range d.RemoveNodes {
+		if prevNodes[name] {
+			out.RemoveNodes = append(out.RemoveNodes, name)
+		}
+	}
+
+	prevPods := make(map[string]bool)
+	for _, p := range prev.Pods {
+		if f.pod(p) {
+			prevPods[podKey(p.Namespace, p.Name)] = true
+		}
+	}
+	var upsertPods []PodDTO
+	var removePods []string
+	for _, p := range d.UpsertPods {
+		key := podKey(p.Namespace, p.Name)
+		if f.pod(p) {
+			upsertPods = append(upsertPods, p)
+		} else if prevPods[key] {
+			removePods = append(removePods, key)
+		}
+	}
+	for _, key := range d.RemovePods {
+		if prevPods[key] {
+			removePods = append(removePods, key)
+		}
+	}
+	if paginated {
+		out.PodsChanged = len(upsertPods) > 0 || len(removePods) > 0
+	} else {
+		out.UpsertPods, out.RemovePods = upsertPods, removePods
+	}
+
+	if f.devices() {
+		out.UpsertDevices, out.RemoveDevices = d.UpsertDevices, d.RemoveDevices
+	}
+	sort.Strings(out.RemoveNodes)
+	sort.Strings(out.RemovePods)
+	return out
+}
+
+// paginatePods 返回 continueToken（上一页最后一个 Pod 的 namespace/name）之后最多 limit 个 Pod；pods 需按 namespace/name 排序
+func paginatePods(pods []PodDTO, limit int, continueToken string) PodPage {
+	page := PodPage{Type: "pods-page", Total: len(pods), Items: []PodDTO{}}
+	start := 0
+	if continueToken != "" {
+		start = sort.Search(len(pods), func(i int) bool {
+			return podKey(pods[i].Namespace, pods[i].Name) > continueToken
+		})
+	}
+	end := len(pods)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+		last := pods[end-1]
+		page.Continue = podKey(last.Namespace, last.Name)
+	}
+	page.Items = append(page.Items, pods[start:end]...)
+	return page
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var testPodGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+func newTestHub(t *testing.T) (*ResourceHub, *storage.MemoryStore) {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	return NewResourceHub(store, logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}), store
+}
+
+func createTestPod(t *testing.T, store storage.Store, namespace, name string, labels map[string]string) {
+	t.Helper()
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+	}
+	if err := store.Create(testPodGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotDeltaFilter(t *testing.T) {
+	filter, err := ParseSnapshotFilter("a", "pods", "app=web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := ResourceSnapshot{
+		Nodes: []NodeDTO{{Name: "node-1"}},
+		Pods: []PodDTO{
+			{Namespace: "a", Name: "web-1", Labels: map[string]string{"app": "web"}},
+			{Namespace: "a", Name: "web-2", Labels: map[string]string{"app": "web"}},
+			{Namespace: "b", Name: "web-3", Labels: map[string]string{"app": "web"}},
+		},
+	}
+	next := ResourceSnapshot{
+		Nodes: []NodeDTO{{Name: "node-1", Ready: true}},
+		Pods: []PodDTO{
+			// 标签变化后不再匹配：对订阅者表现为删除
+			{Namespace: "a", Name: "web-1", Labels: map[string]string{"app": "db"}},
+			{Namespace: "a", Name: "web-4", Labels: map[string]string{"app": "web"}},
+			{Namespace: "b", Name: "web-3", Phase: "Running", Labels: map[string]string{"app": "web"}},
+		},
+	}
+
+	delta := DiffSnapshot(prev, next)
+	if len(delta.UpsertNodes) != 1 || len(delta.UpsertPods) != 3 || len(delta.RemovePods) != 1 {
+		t.Fatalf("unexpected delta: %+v", delta)
+	}
+
+	d := FilterSnapshotDelta(delta, prev, next, filter, false)
+	if len(d.UpsertNodes) != 0 {
+		t.Fatalf("nodes were not subscribed, got %+v", d.UpsertNodes)
+	}
+	if len(d.UpsertPods) != 1 || d.UpsertPods[0].Name != "web-4" {
+		t.Fatalf("expected only web-4 upserted, got %+v", d.UpsertPods)
+	}
+	if fmt.Sprint(d.RemovePods) != "[a/web-1 a/web-2]" {
+		t.Fatalf("expected web-1 and web-2 removed, got %v", d.RemovePods)
+	}
+	if d.Counts.Pods != 1 || d.Counts.Nodes != 0 {
+		t.Fatalf("unexpected counts: %+v", d.Counts)
+	}
+
+	// 分页模式下只提示 Pod 有变化
+	d = FilterSnapshotDelta(delta, prev, next, filter, true)
+	if !d.PodsChanged || len(d.UpsertPods) != 0 || len(d.RemovePods) != 0 {
+		t.Fatalf("expected only podsChanged in paginated mode, got %+v", d)
+	}
+
+	if _, err := ParseSnapshotFilter("", "services", ""); err == nil {
+		t.Fatal("expected unsupported kind to be rejected")
+	}
+}
+
+func TestResourceHubDeltas(t *testing.T) {
+	hub, store := newTestHub(t)
+	createTestPod(t, store, "a", "web-1", map[string]string{"app": "web"})
+
+	ch, snap, unsubscribe := hub.Subscribe("s1", nil, SnapshotFilter{Namespaces: map[string]bool{"a": true}})
+	defer unsubscribe()
+	if len(snap.Pods) != 1 || snap.Paginated {
+		t.Fatalf("unexpected initial snapshot: %+v", snap)
+	}
+
+	createTestPod(t, store, "b", "other", nil)
+	hub.broadcastSnapshot()
+	select {
+	case payload := <-ch:
+		t.Fatalf("pods in other namespaces should not be pushed, got %s", payload)
+	default:
+	}
+
+	createTestPod(t, store, "a", "web-2", map[string]string{"app": "web"})
+	hub.broadcastSnapshot()
+	var d SnapshotDelta
+	if err := json.Unmarshal(<-ch, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Type != "snapshot-delta" || len(d.UpsertPods) != 1 || d.UpsertPods[0].Name != "web-2" || d.Counts.Pods != 2 {
+		t.Fatalf("unexpected delta: %+v", d)
+	}
+}
+
+func TestResourceHubIdentityAccess(t *testing.T) {
+	hub, store := newTestHub(t)
+	if err := store.Create(schema.GroupVersionKind{Version: "v1", Kind: "Node"}, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	createTestPod(t, store, "a", "web-1", nil)
+	createTestPod(t, store, "b", "web-2", nil)
+
+	// 只能读取 namespace a 中的 Pod：Node 与其他 namespace 的 Pod 都不推送，订阅参数也无法放宽
+	team := &webprovider.Identity{User: "team-a", Namespaces: []string{"a"}, Kinds: []string{"pod"}}
+	ch, snap, unsubscribe := hub.Subscribe("s1", team, SnapshotFilter{})
+	defer unsubscribe()
+	if len(snap.Nodes) != 0 || len(snap.Pods) != 1 || snap.Pods[0].Name != "web-1" || snap.Counts.Nodes != 0 {
+		t.Fatalf("unexpected snapshot for restricted identity: %+v", snap)
+	}
+
+	createTestPod(t, store, "b", "web-3", nil)
+	hub.broadcastSnapshot()
+	select {
+	case payload := <-ch:
+		t.Fatalf("pods in other namespaces should not be pushed, got %s", payload)
+	default:
+	}
+
+	page, err := hub.ListPods(team, SnapshotFilter{Namespaces: map[string]bool{"b": true}}, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 0 {
+		t.Fatalf("ListPods should not return pods outside the identity's namespaces: %+v", page)
+	}
+
+	// 不包括 Pod 的身份看不到任何 Pod
+	_, snap, unsubscribeNodes := hub.Subscribe("s2", &webprovider.Identity{User: "ops", Namespaces: []string{"*"}, Kinds: []string{"Node"}}, SnapshotFilter{})
+	defer unsubscribeNodes()
+	if len(snap.Nodes) != 1 || len(snap.Pods) != 0 {
+		t.Fatalf("unexpected snapshot for node-only identity: %+v", snap)
+	}
+}
+
+func TestResourceHubPagination(t *testing.T) {
+	hub, store := newTestHub(t)
+	hub.maxObjects = 2
+	for i := 0; i < 3; i++ {
+		createTestPod(t, store, "default", fmt.Sprintf("web-%d", i), map[string]string{"app": "web"})
+	}
+
+	ch, snap, unsubscribe := hub.Subscribe("s1", nil, SnapshotFilter{})
+	defer unsubscribe()
+	if !snap.Paginated || len(snap.Pods) != 0 || snap.Counts.Pods != 3 {
+		t.Fatalf("expected paginated snapshot, got %+v", snap)
+	}
+
+	page, err := hub.ListPods(nil, SnapshotFilter{}, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Continue != "default/web-1" || page.Total != 3 {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	page, err = hub.ListPods(nil, SnapshotFilter{}, 2, page.Continue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Name != "web-2" || page.Continue != "" {
+		t.Fatalf("unexpected last page: %+v", page)
+	}
+
+	// Pod 数降到上限以下时关闭订阅，客户端重新订阅后拿到完整快照
+	if err := store.Delete(testPodGVK, "default", "web-0"); err != nil {
+		t.Fatal(err)
+	}
+	hub.broadcastSnapshot()
+	if _, ok := <-ch; ok {
+		t.Fatal("expected subscription to be closed after crossing the limit")
+	}
+}
+
+func TestResourceHubSnapshotOrder(t *testing.T) {
+	hub, store := newTestHub(t)
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+	for _, name := range []string{"node-c", "node-a", "node-b"} {
+		if err := store.Create(nodeGVK, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createTestPod(t, store, "b", "web", nil)
+	createTestPod(t, store, "a", "web-2", nil)
+	createTestPod(t, store, "a", "web-1", nil)
+
+	snap := hub.buildSnapshot()
+	var nodes, pods []string
+	for _, n := range snap.Nodes {
+		nodes = append(nodes, n.Name)
+	}
+	for _, p := range snap.Pods {
+		pods = append(pods, p.Namespace+"/"+p.Name)
+	}
+	if fmt.Sprint(nodes) != "[node-a node-b node-c]" {
+		t.Fatalf("nodes not sorted: %v", nodes)
+	}
+	if fmt.Sprint(pods) != "[a/web-1 a/web-2 b/web]" {
+		t.Fatalf("pods not sorted: %v", pods)
+	}
+}
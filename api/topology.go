@@ -0,0 +1,336 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// 拓扑图：node ← pod → deployment/statefulset/daemonset，service → pod（即 endpoints）。
+// 节点 ID 形如 "Pod/default/nginx-xxx"、"Node/node-1"，边 ID 为 "<type>:<from>-><to>"。
+
+// 边类型
+const (
+	EdgeRunsOn  = "runs-on"  // Pod -> Node
+	EdgeOwnedBy = "owned-by" // Pod -> Deployment/StatefulSet/DaemonSet
+	EdgeSelects = "selects"  // Service -> Pod（endpoint）
+)
+
+// topologyKinds 是拓扑图关注的资源
+var topologyKinds = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "Node"},
+	{Version: "v1", Kind: "Pod"},
+	{Version: "v1", Kind: "Service"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+}
+
+type TopologyNode struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase,omitempty"`
+	Ready     bool   `json:"ready"`
+	// Replicas 仅工作负载：ready/desired
+	Replicas string `json:"replicas,omitempty"`
+}
+
+type TopologyEdge struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Ready bool   `json:"ready"`
+	Phase string `json:"phase,omitempty"`
+}
+
+// TopologyGraph 是完整拓扑（type = "topology"）
+type TopologyGraph struct {
+	Type        string         `json:"type"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Nodes       []TopologyNode `json:"nodes"`
+	Edges       []TopologyEdge `json:"edges"`
+	Error       *ErrorDTO      `json:"error,omitempty"`
+}
+
+// TopologyDelta 是相对上一次拓扑的增量（type = "topology-delta"）
+type TopologyDelta struct {
+	Type         string         `json:"type"`
+	GeneratedAt  time.Time      `json:"generatedAt"`
+	UpsertNodes  []TopologyNode `json:"upsertNodes,omitempty"`
+	RemoveNodes  []string       `json:"removeNodes,omitempty"`
+	UpsertEdges  []TopologyEdge `json:"upsertEdges,omitempty"`
+	RemoveEdges  []string       `json:"removeEdges,omitempty"`
+	ErrorMessage string         `json:"error,omitempty"`
+}
+
+// Empty 表示没有任何变化
+func (d TopologyDelta) Empty() bool {
+	return len(d.UpsertNodes) == 0 && len(d.RemoveNodes) == 0 && len(d.UpsertEdges) == 0 && len(d.RemoveEdges) == 0
+}
+
+// BuildTopology 从 Store 组装拓扑图
+func (h *ResourceHub) BuildTopology() TopologyGraph {
+	g := TopologyGraph{Type: "topology", GeneratedAt: time.Now(), Nodes: []TopologyNode{}, Edges: []TopologyEdge{}}
+
+	objects := map[string][]runtime.Object{}
+	for _, gvk := range topologyKinds {
+		list, err := h.store.List(gvk, "")
+		if err != nil {
+			msg := "list " + gvk.Kind + " failed: " + err.Error()
+			if g.Error == nil {
+				g.Error = &ErrorDTO{Message: msg}
+			} else {
+				g.Error.Message += "; " + msg
+			}
+			continue
+		}
+		objects[gvk.Kind] = list
+	}
+
+	type workload struct {
+		node     TopologyNode
+		selector labels.Selector
+	}
+	var workloads []workload
+	addWorkload := func(kind string, meta metav1.ObjectMeta, sel *metav1.LabelSelector, ready, desired int32) {
+		n := TopologyNode{
+			ID:        topologyID(kind, meta.Namespace, meta.Name),
+			Kind:      kind,
+			Namespace: meta.Namespace,
+			Name:      meta.Name,
+			Ready:     ready >= desired,
+			Replicas:  formatReplicas(ready, desired),
+		}
+		g.Nodes = append(g.Nodes, n)
+		selector := labels.Nothing()
+		if sel != nil {
+			if s, err := metav1.LabelSelectorAsSelector(sel); err == nil && !s.Empty() {
+				selector = s
+			}
+		}
+		workloads = append(workloads, workload{node: n, selector: selector})
+	}
+
+	for _, obj := range objects["Node"] {
+		if n, ok := obj.(*corev1.Node); ok {
+			dto := nodeToDTO(n)
+			g.Nodes = append(g.Nodes, TopologyNode{ID: topologyID("Node", "", n.Name), Kind: "Node", Name: n.Name, Phase: dto.Phase, Ready: dto.Ready})
+		}
+	}
+	for _, obj := range objects["Deployment"] {
+		if d, ok := obj.(*appsv1.Deployment); ok {
+			addWorkload("Deployment", d.ObjectMeta, d.Spec.Selector, d.Status.ReadyReplicas, replicasOrOne(d.Spec.Replicas))
+		}
+	}
+	for _, obj := range objects["StatefulSet"] {
+		if s, ok := obj.(*appsv1.StatefulSet); ok {
+			addWorkload("StatefulSet", s.ObjectMeta, s.Spec.Selector, s.Status.ReadyReplicas, replicasOrOne(s.Spec.Replicas))
+		}
+	}
+	for _, obj := range objects["DaemonSet"] {
+		if d, ok := obj.(*appsv1.DaemonSet); ok {
+			addWorkload("DaemonSet", d.ObjectMeta, d.Spec.Selector, d.Status.NumberReady, d.Status.DesiredNumberScheduled)
+		}
+	}
+
+	var pods []*corev1.Pod
+	for _, obj := range objects["Pod"] {
+		p, ok := obj.(*corev1.Pod)
+		if !ok {
+			continue
+		}
+		pods = append(pods, p)
+		dto := podToDTO(p)
+		podID := topologyID("Pod", p.Namespace, p.Name)
+		g.Nodes = append(g.Nodes, TopologyNode{ID: podID, Kind: "Pod", Namespace: p.Namespace, Name: p.Name, Phase: dto.Phase, Ready: dto.Ready})
+
+		if p.Spec.NodeName != "" {
+			g.Edges = append(g.Edges, newTopologyEdge(EdgeRunsOn, podID, topologyID("Node", "", p.Spec.NodeName), dto.Ready, dto.Phase))
+		}
+
+		// 优先按 ownerReferences 关联；MySQLStore 可能丢失 ownerReferences，再按 selector 兜底
+		owned := false
+		for _, ref := range p.OwnerReferences {
+			switch ref.Kind {
+			case "Deployment", "StatefulSet", "DaemonSet":
+				g.Edges = append(g.Edges, newTopologyEdge(EdgeOwnedBy, podID, topologyID(ref.Kind, p.Namespace, ref.Name), dto.Ready, dto.Phase))
+				owned = true
+			}
+		}
+		if !owned {
+			for _, w := range workloads {
+				if w.node.Namespace == p.Namespace && w.selector.Matches(labels.Set(p.Labels)) {
+					g.Edges = append(g.Edges, newTopologyEdge(EdgeOwnedBy, podID, w.node.ID, dto.Ready, dto.Phase))
+				}
+			}
+		}
+	}
+
+	for _, obj := range objects["Service"] {
+		svc, ok := obj.(*corev1.Service)
+		if !ok {
+			continue
+		}
+		svcID := topologyID("Service", svc.Namespace, svc.Name)
+		readyEndpoints := 0
+		if len(svc.Spec.Selector) > 0 {
+			selector := labels.SelectorFromSet(svc.Spec.Selector)
+			for _, p := range pods {
+				if p.Namespace != svc.Namespace || !selector.Matches(labels.Set(p.Labels)) {
+					continue
+				}
+				dto := podToDTO(p)
+				if dto.Ready {
+					readyEndpoints++
+				}
+				g.Edges = append(g.Edges, newTopologyEdge(EdgeSelects, svcID, topologyID("Pod", p.Namespace, p.Name), dto.Ready, dto.Phase))
+			}
+		}
+		g.Nodes = append(g.Nodes, TopologyNode{
+			ID:        svcID,
+			Kind:      "Service",
+			Namespace: svc.Namespace,
+			Name:      svc.Name,
+			Phase:     string(svc.Spec.Type),
+			Ready:     readyEndpoints > 0 || len(svc.Spec.Selector) == 0,
+		})
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool { return g.Edges[i].ID < g.Edges[j].ID })
+	return g
+}
+
+// DiffTopology 计算 prev -> next 的增量
+func DiffTopology(prev, next TopologyGraph) TopologyDelta {
+	d := TopologyDelta{Type: "topology-delta", GeneratedAt: next.GeneratedAt}
+	if next.Error != nil {
+		d.ErrorMessage = next.Error.Message
+	}
+
+	prevNodes := make(map[string]TopologyNode, len(prev.Nodes))
+	for _, n := range prev.Nodes {
+		prevNodes[n.ID] = n
+	}
+	for _, n := range next.Nodes {
+		if old, ok := prevNodes[n.ID]; !ok || old != n {
+			d.UpsertNodes = append(d.UpsertNodes, n)
+		}
+		delete(prevNodes, n.ID)
+	}
+	for id := range prevNodes {
+		d.RemoveNodes = append(d.RemoveNodes, id)
+	}
+
+	prevEdges := make(map[string]TopologyEdge, len(prev.Edges))
+	for _, e := range prev.Edges {
+		prevEdges[e.ID] = e
+	}
+	for _, e := range next.Edges {
+		if old, ok := prevEdges[e.ID]; !ok || old != e {
+			d.UpsertEdges = append(d.UpsertEdges, e)
+		}
+		delete(prevEdges, e.ID)
+	}
+	for id := range prevEdges {
+		d.RemoveEdges = append(d.RemoveEdges, id)
+	}
+
+	sort.Strings(d.RemoveNodes)
+	sort.Strings(d.RemoveEdges)
+	return d
+}
+
+// FilterTopology 只保留 access 可见的节点（集群级的 Node 只受资源种类限制），以及两端都可见的边
+func FilterTopology(g TopologyGraph, access WatchAccess) TopologyGraph {
+	if access.unrestricted() {
+		return g
+	}
+	out := g
+	out.Nodes = make([]TopologyNode, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		if access.allows(n.Kind, n.Namespace) {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	out.Edges = make([]TopologyEdge, 0, len(g.Edges))
+	for _, e := range g.Edges {
+		if topologyIDVisible(e.From, access) && topologyIDVisible(e.To, access) {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
+
+// FilterTopologyDelta 按 access 过滤增量
+func FilterTopologyDelta(d TopologyDelta, access WatchAccess) TopologyDelta {
+	if access.unrestricted() {
+		return d
+	}
+	out := TopologyDelta{Type: d.Type, GeneratedAt: d.GeneratedAt, ErrorMessage: d.ErrorMessage}
+	for _, n := range d.UpsertNodes {
+		if access.allows(n.Kind, n.Namespace) {
+			out.UpsertNodes = append(out.UpsertNodes, n)
+		}
+	}
+	for _, id := range d.RemoveNodes {
+		if topologyIDVisible(id, access) {
+			out.RemoveNodes = append(out.RemoveNodes, id)
+		}
+	}
+	for _, e := range d.UpsertEdges {
+		if topologyIDVisible(e.From, access) && topologyIDVisible(e.To, access) {
+			out.UpsertEdges = append(out.UpsertEdges, e)
+		}
+	}
+	for _, id := range d.RemoveEdges {
+		_, rest, _ := strings.Cut(id, ":")
+		from, to, _ := strings.Cut(rest, "->")
+		if topologyIDVisible(from, access) && topologyIDVisible(to, access) {
+			out.RemoveEdges = append(out.RemoveEdges, id)
+		}
+	}
+	return out
+}
+
+// topologyIDVisible 判断节点 ID（Kind/ns/name 或 Kind/name）是否可见
+func topologyIDVisible(id string, access WatchAccess) bool {
+	parts := strings.SplitN(id, "/", 3)
+	if len(parts) < 3 {
+		return access.allows(parts[0], "")
+	}
+	return access.allows(parts[0], parts[1])
+}
+
+func topologyID(kind, namespace, name string) string {
+	if namespace == "" {
+		return kind + "/" + name
+	}
+	return kind + "/" + namespace + "/" + name
+}
+
+func newTopologyEdge(edgeType, from, to string, ready bool, phase string) TopologyEdge {
+	return TopologyEdge{ID: edgeType + ":" + from + "->" + to, Type: edgeType, From: from, To: to, Ready: ready, Phase: phase}
+}
+
+func replicasOrOne(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+func formatReplicas(ready, desired int32) string {
+	return fmt.Sprintf("%d/%d", ready, desired)
+}
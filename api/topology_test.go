@@ -0,0 +1,131 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestBuildTopology(t *testing.T) {
+	store := storage.NewMemoryStore()
+	hub := NewResourceHub(store, logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()})
+
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	svcGVK := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	deployGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	replicas := int32(1)
+	labels := map[string]string{"app": "web"}
+	if err := store.Create(nodeGVK, &corev1.Node{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Node"}, ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(deployGVK, &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(svcGVK, &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Selector: labels},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 没有 ownerReferences 的 Pod 通过 selector 关联到 Deployment
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: labels},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if err := store.Create(podGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+
+	g := hub.BuildTopology()
+	if len(g.Nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %d: %+v", len(g.Nodes), g.Nodes)
+	}
+	want := map[string]bool{
+		"runs-on:Pod/default/web-1->Node/node-1":             true,
+		"owned-by:Pod/default/web-1->Deployment/default/web": true,
+		"selects:Service/default/web->Pod/default/web-1":     true,
+	}
+	if len(g.Edges) != len(want) {
+		t.Fatalf("expected %d edges, got %+v", len(want), g.Edges)
+	}
+	for _, e := range g.Edges {
+		if !want[e.ID] {
+			t.Errorf("unexpected edge %s", e.ID)
+		}
+		if !e.Ready || e.Phase != "Running" {
+			t.Errorf("edge %s should carry pod readiness, got ready=%v phase=%s", e.ID, e.Ready, e.Phase)
+		}
+	}
+
+	// 删除 Pod 后增量只包含被移除的节点与边
+	if err := store.Delete(podGVK, "default", "web-1"); err != nil {
+		t.Fatal(err)
+	}
+	delta := DiffTopology(g, hub.BuildTopology())
+	if len(delta.RemoveNodes) != 1 || delta.RemoveNodes[0] != "Pod/default/web-1" {
+		t.Fatalf("unexpected removed nodes: %v", delta.RemoveNodes)
+	}
+	if len(delta.RemoveEdges) != 3 {
+		t.Fatalf("expected 3 removed edges, got %v", delta.RemoveEdges)
+	}
+	// Service 失去 ready endpoint，状态变化以 upsert 形式下发
+	if len(delta.UpsertNodes) != 1 || delta.UpsertNodes[0].ID != "Service/default/web" || delta.UpsertNodes[0].Ready {
+		t.Fatalf("unexpected upserted nodes: %+v", delta.UpsertNodes)
+	}
+}
+
+func TestFilterTopology(t *testing.T) {
+	g := TopologyGraph{
+		Nodes: []TopologyNode{
+			{ID: "Node/node-1", Kind: "Node", Name: "node-1"},
+			{ID: "Pod/team-a/web-1", Kind: "Pod", Namespace: "team-a", Name: "web-1"},
+			{ID: "Pod/team-b/db-1", Kind: "Pod", Namespace: "team-b", Name: "db-1"},
+		},
+		Edges: []TopologyEdge{
+			{ID: "runs-on:Pod/team-a/web-1->Node/node-1", From: "Pod/team-a/web-1", To: "Node/node-1"},
+			{ID: "runs-on:Pod/team-b/db-1->Node/node-1", From: "Pod/team-b/db-1", To: "Node/node-1"},
+		},
+	}
+	allow := WatchAccess{Namespaces: func(ns string) bool { return ns == "team-a" }}
+
+	got := FilterTopology(g, allow)
+	if len(got.Nodes) != 2 || len(got.Edges) != 1 || got.Edges[0].From != "Pod/team-a/web-1" {
+		t.Fatalf("unexpected filtered graph: %+v", got)
+	}
+
+	delta := FilterTopologyDelta(TopologyDelta{
+		RemoveNodes: []string{"Pod/team-a/web-1", "Pod/team-b/db-1"},
+		RemoveEdges: []string{g.Edges[0].ID, g.Edges[1].ID},
+	}, allow)
+	if len(delta.RemoveNodes) != 1 || len(delta.RemoveEdges) != 1 || delta.RemoveEdges[0] != g.Edges[0].ID {
+		t.Fatalf("unexpected filtered delta: %+v", delta)
+	}
+
+	// 限定资源种类时集群级的 Node 也不可见，连到它的边一并去掉
+	podsOnly := WatchAccess{Kinds: func(kind string) bool { return kind == "Pod" }}
+	got = FilterTopology(g, podsOnly)
+	if len(got.Nodes) != 2 || len(got.Edges) != 0 {
+		t.Fatalf("unexpected graph filtered by kind: %+v", got)
+	}
+}
@@ -0,0 +1,54 @@
+// benchgate 比较 go test -bench 的输出与基线，有基准超过阈值时以非 0 退出（make bench 使用）
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/benchgate"
+)
+
+func main() {
+	baselinePath := flag.String("baseline", "pkg/storage/testdata/bench-baseline.txt", "基线文件（go test -bench -benchmem 的输出）")
+	currentPath := flag.String("current", "-", "本次的基准输出，- 表示标准输入")
+	nsThreshold := flag.Float64("ns-threshold", 40, "ns/op 允许的最大增幅（百分比，0 表示不检查）")
+	allocsThreshold := flag.Float64("allocs-threshold", 10, "allocs/op 允许的最大增幅（百分比，0 表示不检查）")
+	flag.Parse()
+
+	baseline, err := parseFile(*baselinePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "benchgate: 读取基线失败: %v\n", err)
+		os.Exit(2)
+	}
+	current, err := parseFile(*currentPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "benchgate: 读取基准输出失败: %v\n", err)
+		os.Exit(2)
+	}
+	if len(current) == 0 {
+		fmt.Fprintln(os.Stderr, "benchgate: 基准输出中没有结果")
+		os.Exit(2)
+	}
+
+	report := benchgate.Compare(baseline, current, benchgate.Thresholds{NsPercent: *nsThreshold, AllocsPercent: *allocsThreshold})
+	report.Write(os.Stdout)
+	if regressions := report.Regressions(); len(regressions) > 0 {
+		fmt.Fprintf(os.Stderr, "benchgate: %d 个基准超过阈值\n", len(regressions))
+		os.Exit(1)
+	}
+}
+
+func parseFile(path string) (map[string]benchgate.Result, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return benchgate.Parse(r)
+}
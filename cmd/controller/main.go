@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterconfig"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
@@ -22,6 +23,7 @@ func main() {
 	modules := fx.Options(
 		core.CoreModule,
 		controller.Module,
+		clusterconfig.Module,
 		fx.Provide(
 			storage.NewStore,
 		),
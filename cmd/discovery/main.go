@@ -13,6 +13,7 @@ import (
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/discovery"
 	"go.uber.org/fx"
@@ -35,6 +36,7 @@ func main() {
 	healthCheckInterval := fs.Duration("health-check-interval", 10*time.Second, "健康检查间隔")
 	healthCheckTimeout := fs.Duration("health-check-timeout", 3*time.Second, "健康检查超时")
 	deregisterAfter := fs.Duration("deregister-after", 30*time.Second, "服务不健康后多久注销")
+	podHealthChecks := fs.Bool("pod-health-checks", false, "把被 Service 选中的 Pod 与 ExternalName Service 注册到 Consul 并写回检查结果（也可用配置 discovery.consul.pod_health_checks 开启）")
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		os.Exit(2)
@@ -46,7 +48,7 @@ func main() {
 		fx.Provide(
 			bootstrap.ProvideDBContainerHandle,
 			bootstrap.ProvideStore,
-			func() discovery.Settings {
+			func(cfg config.Config) discovery.Settings {
 				return discovery.Settings{
 					ConsulAddress:                      *consulAddr,
 					ConsulToken:                        *consulToken,
@@ -59,6 +61,9 @@ func main() {
 					HealthCheckInterval:                *healthCheckInterval,
 					HealthCheckTimeout:                 *healthCheckTimeout,
 					DeregisterCriticalServiceAfter:     *deregisterAfter,
+					ConsulContainer:                    cfg.Discovery.Consul.Container,
+					ClusterID:                          cfg.Cluster.ID,
+					PodHealthChecks:                    *podHealthChecks || cfg.Discovery.Consul.PodHealthChecks,
 				}
 			},
 			discovery.NewService,
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/cli"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/version"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/discovery"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 离线包（tar.gz）中的文件
+const (
+	bundleManifestFile = "bundle.json"
+	bundleImagesFile   = "images.tar"
+	bundleConfigFile   = "config.yaml"
+	// bundleApplyDir 安装并启动后提交到 apiserver 的 YAML；不能叫 manifests，那是 static_pod_path 的默认目录
+	bundleApplyDir = "apply"
+)
+
+// bundleManifest 是离线包中的 bundle.json
+type bundleManifest struct {
+	Version  string    `json:"version"`
+	Platform string    `json:"platform"`
+	Created  time.Time `json:"created"`
+	Binary   string    `json:"binary"`
+	Images   []string  `json:"images"`
+	Config   bool      `json:"config"`
+	Apply    []string  `json:"apply,omitempty"`
+}
+
+// cmdBundle 离线安装包：create 打包 binary、镜像与 YAML，install 在没有外网的机器上加载并启动
+func cmdBundle(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "用法: k3 bundle create|install [flags]")
+		return 2
+	}
+	switch args[0] {
+	case "create":
+		return cmdBundleCreate(args[1:])
+	case "install":
+		return cmdBundleInstall(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: bundle %s\n", args[0])
+		return 2
+	}
+}
+
+// cmdBundleCreate 把 k3 binary、运行需要的镜像（docker save）、配置与要提交的 YAML 打成一个 tar.gz。
+// 镜像包括 Pod sandbox、按配置在本机自动拉起的存储/Consul 容器、YAML 中引用的镜像以及 --images 指定的镜像，本地没有时先拉取
+func cmdBundleCreate(args []string) int {
+	fs := flag.NewFlagSet("k3 bundle create", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := fs.String("config", "", "打包的配置文件（安装后以它启动节点；为空时 install 需要指定 --config）")
+	output := fs.String("o", "k3-bundle.tar.gz", "输出文件")
+	binary := fs.String("binary", "", "打包的 k3 可执行文件（默认当前运行的 k3）")
+	platform := fs.String("platform", runtime.GOOS+"/"+runtime.GOARCH, "目标平台（os/arch），镜像按该平台拉取；与当前平台不同时需要 --binary")
+	applyPath := fs.String("apply", "", "安装启动后提交到 apiserver 的 YAML 文件或目录（*.yaml/*.yml/*.json）")
+	extraImages := fs.String("images", "", "额外打包的镜像，逗号分隔")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	bin := strings.TrimSpace(*binary)
+	if bin == "" {
+		if *platform != runtime.GOOS+"/"+runtime.GOARCH {
+			fmt.Fprintf(os.Stderr, "目标平台 %s 与当前 k3（%s/%s）不同，请用 --binary 指定对应平台的 k3\n", *platform, runtime.GOOS, runtime.GOARCH)
+			return 2
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无法确定当前 k3 可执行文件，请指定 --binary: %v\n", err)
+			return 1
+		}
+		bin = exe
+	}
+
+	var cfg *config.Config
+	if *cfgPath != "" {
+		applyConfigFlag(*cfgPath)
+		c := config.NewFileConfig()
+		cfg = &c
+	} else {
+		fmt.Println("未指定 --config：离线包中不含配置，安装时需要 --config")
+	}
+	applyFiles, err := manifestFiles(*applyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	images, err := bundleImages(cfg, applyFiles, splitCSV(*extraImages))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		fmt.Fprintln(os.Stderr, "未找到 docker 命令，无法导出镜像")
+		return 1
+	}
+	for _, image := range images {
+		if err := ensureBundleImage(image, *platform); err != nil {
+			fmt.Fprintf(os.Stderr, "准备镜像 %s 失败: %v\n", image, err)
+			return 1
+		}
+	}
+	tmp, err := os.MkdirTemp("", "k3-bundle-*")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+	imagesTar := filepath.Join(tmp, bundleImagesFile)
+	fmt.Printf("导出 %d 个镜像...\n", len(images))
+	if out, err := exec.Command("docker", append([]string{"save", "-o", imagesTar}, images...)...).CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "docker save 失败: %v, 输出: %s\n", err, strings.TrimSpace(string(out)))
+		return 1
+	}
+
+	manifest := bundleManifest{
+		Version:  version.Version,
+		Platform: *platform,
+		Created:  time.Now().UTC(),
+		Binary:   bundleBinaryName(*platform),
+		Images:   images,
+		Config:   cfg != nil,
+	}
+	if *binary != "" {
+		// 其他平台的 binary 可能无法在本机执行，取不到版本时留空
+		manifest.Version = ""
+		if info, err := binaryVersionInfo(bin); err == nil {
+			manifest.Version = info.Version
+		}
+	}
+	files := []bundleFile{
+		{name: manifest.Binary, src: bin, mode: 0o755},
+		{name: bundleImagesFile, src: imagesTar, mode: 0o644},
+	}
+	if cfg != nil {
+		files = append(files, bundleFile{name: bundleConfigFile, src: *cfgPath, mode: 0o600})
+	}
+	for i, f := range applyFiles {
+		// 按原顺序编号，install 按文件名顺序提交
+		name := fmt.Sprintf("%s/%02d-%s", bundleApplyDir, i, filepath.Base(f))
+		manifest.Apply = append(manifest.Apply, name)
+		files = append(files, bundleFile{name: name, src: f, mode: 0o644})
+	}
+	if err := writeBundle(*output, manifest, files); err != nil {
+		fmt.Fprintf(os.Stderr, "写入离线包失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("✅ 离线包已生成: %s（k3 %s，%s，%d 个镜像，%d 个 YAML）\n", *output, manifest.Version, manifest.Platform, len(images), len(applyFiles))
+	for _, image := range images {
+		fmt.Printf("  - %s\n", image)
+	}
+	return 0
+}
+
+// cmdBundleInstall 解压离线包：docker load 镜像、安装 binary 与配置，然后以 k3 run 启动节点，
+// 节点带有 apiserver（role 为 one/master）时等待就绪后提交离线包中的 YAML
+func cmdBundleInstall(args []string) int {
+	fs := flag.NewFlagSet("k3 bundle install", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	file := fs.String("f", "", "离线包路径")
+	dir := fs.String("dir", "k3", "安装目录（binary、config.yaml 与数据目录都在这里）")
+	cfgPath := fs.String("config", "", "启动使用的配置文件（默认安装目录下的 config.yaml：不存在时使用离线包中的配置，已存在时保留）")
+	noStart := fs.Bool("no-start", false, "只安装，不启动节点")
+	force := fs.Bool("force", false, "离线包的平台与本机不同时仍然安装")
+	timeout := fs.Duration("timeout", 5*time.Minute, "等待 apiserver 就绪的超时")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if strings.TrimSpace(*file) == "" {
+		fmt.Fprintln(os.Stderr, "缺少 -f <离线包>")
+		return 2
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// 1. 解压到安装目录下的临时目录（与安装目录同一文件系统）
+	tmp, err := os.MkdirTemp(root, ".bundle-*")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+	manifest, err := extractBundle(*file, tmp)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解压离线包失败: %v\n", err)
+		return 1
+	}
+	local := runtime.GOOS + "/" + runtime.GOARCH
+	if manifest.Platform != local && !*force {
+		fmt.Fprintf(os.Stderr, "离线包的平台为 %s，本机为 %s（--force 强制安装）\n", manifest.Platform, local)
+		return 1
+	}
+	fmt.Printf("安装 k3 %s（%s）到 %s\n", manifest.Version, manifest.Platform, root)
+
+	// 2. 加载镜像
+	if _, err := exec.LookPath("docker"); err != nil {
+		fmt.Fprintln(os.Stderr, "未找到 docker 命令，无法加载镜像")
+		return 1
+	}
+	fmt.Printf("加载 %d 个镜像...\n", len(manifest.Images))
+	if err := runCommand("docker", "load", "-i", filepath.Join(tmp, bundleImagesFile)); err != nil {
+		fmt.Fprintf(os.Stderr, "docker load 失败: %v\n", err)
+		return 1
+	}
+
+	// 3. 安装 binary（旧版本保留为 .prev）与配置
+	bin := filepath.Join(root, manifest.Binary)
+	if err := installBinary(filepath.Join(tmp, manifest.Binary), bin); err != nil {
+		fmt.Fprintf(os.Stderr, "安装 binary 失败: %v\n", err)
+		return 1
+	}
+	cfgFile := strings.TrimSpace(*cfgPath)
+	if cfgFile == "" {
+		cfgFile = filepath.Join(root, bundleConfigFile)
+		if _, err := os.Stat(cfgFile); err == nil {
+			fmt.Printf("保留已有配置 %s\n", cfgFile)
+		} else if !manifest.Config {
+			fmt.Fprintln(os.Stderr, "离线包中没有配置，请用 --config 指定")
+			return 2
+		} else if err := os.Rename(filepath.Join(tmp, bundleConfigFile), cfgFile); err != nil {
+			fmt.Fprintf(os.Stderr, "安装配置失败: %v\n", err)
+			return 1
+		}
+	}
+	if cfgFile, err = filepath.Abs(cfgFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	applyDir := filepath.Join(root, bundleApplyDir)
+	if err := os.RemoveAll(applyDir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(manifest.Apply) > 0 {
+		if err := os.Rename(filepath.Join(tmp, bundleApplyDir), applyDir); err != nil {
+			fmt.Fprintf(os.Stderr, "安装 YAML 失败: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Printf("✅ 已安装 %s（配置 %s）\n", bin, cfgFile)
+
+	if *noStart {
+		fmt.Printf("启动节点: %s run --config %s\n", bin, cfgFile)
+		for _, name := range manifest.Apply {
+			fmt.Printf("提交 YAML: %s apply --config %s -f %s\n", bin, cfgFile, filepath.Join(root, name))
+		}
+		return 0
+	}
+	return startBundledNode(bin, cfgFile, root, manifest.Apply, *timeout)
+}
+
+// startBundledNode 以 k3 run 启动节点并转发退出信号；role 带有 apiserver 时等待就绪后提交 YAML，然后等待节点退出
+func startBundledNode(bin, cfgFile, root string, apply []string, timeout time.Duration) int {
+	cmd := exec.Command(bin, "run", "--config", cfgFile)
+	cmd.Dir = root
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "启动节点失败: %v\n", err)
+		return 1
+	}
+	sigCh := make(chan os.Signal, 1)
+	signalNotify(sigCh)
+	defer signal.Stop(sigCh)
+	go func() {
+		for sig := range sigCh {
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	applyConfigFlag(cfgFile)
+	role := strings.ToLower(strings.TrimSpace(config.NewFileConfig().Role))
+	if len(apply) > 0 {
+		if role == "node" {
+			fmt.Println("role 为 node，本节点没有 apiserver，跳过提交离线包中的 YAML")
+		} else if err := waitReadyOrExit(apiserverBase("")+"/api/readyz", timeout, exited); err != nil {
+			fmt.Fprintf(os.Stderr, "apiserver 未就绪，跳过提交 YAML: %v\n", err)
+		} else {
+			for _, name := range apply {
+				fmt.Printf("提交 %s\n", name)
+				if code := cli.Apply([]string{"--config", cfgFile, "-f", filepath.Join(root, name)}); code != 0 {
+					fmt.Fprintf(os.Stderr, "提交 %s 失败\n", name)
+				}
+			}
+		}
+	}
+
+	err := <-exited
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// waitReadyOrExit 等待 url 返回 200；节点进程提前退出时把退出结果放回 exited 并返回错误
+func waitReadyOrExit(url string, timeout time.Duration, exited chan error) error {
+	ready := make(chan error, 1)
+	go func() { ready <- waitReady(url, timeout) }()
+	select {
+	case err := <-ready:
+		return err
+	case err := <-exited:
+		exited <- err
+		return fmt.Errorf("节点进程已退出: %v", err)
+	}
+}
+
+// bundleImages 汇总需要打包的镜像（去重，保持顺序）：Pod sandbox、按配置在本机自动拉起的存储与 Consul 容器、YAML 中引用的镜像、额外指定的镜像
+func bundleImages(cfg *config.Config, applyFiles []string, extra []string) ([]string, error) {
+	images := []string{controller.PauseImage}
+	if cfg != nil {
+		images = append(images, bootstrap.StorageImages(*cfg)...)
+		// master/one 进程会按需拉起本机的 Consul
+		if !strings.EqualFold(strings.TrimSpace(cfg.Role), "node") {
+			images = append(images, discovery.ConsulImage(cfg.Discovery.Consul.Container))
+		}
+	}
+	p := parser.NewParser()
+	for _, f := range applyFiles {
+		objects, _, err := p.ParseYAMLFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", f, err)
+		}
+		for _, obj := range objects {
+			images = append(images, podSpecImages(obj)...)
+		}
+	}
+	images = append(images, extra...)
+
+	seen := make(map[string]bool, len(images))
+	var out []string
+	for _, image := range images {
+		image = strings.TrimSpace(image)
+		if image == "" || seen[image] {
+			continue
+		}
+		seen[image] = true
+		out = append(out, image)
+	}
+	return out, nil
+}
+
+// podSpecImages 返回工作负载（Pod 与带 Pod 模板的资源）中容器与 init 容器的镜像
+func podSpecImages(obj any) []string {
+	var spec *corev1.PodSpec
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		spec = &o.Spec
+	case *appsv1.Deployment:
+		spec = &o.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		spec = &o.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		spec = &o.Spec.Template.Spec
+	case *appsv1.ReplicaSet:
+		spec = &o.Spec.Template.Spec
+	case *batchv1.Job:
+		spec = &o.Spec.Template.Spec
+	case *batchv1.CronJob:
+		spec = &o.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return nil
+	}
+	var images []string
+	for _, c := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+// manifestFiles 返回 path（文件或目录）下的 YAML/JSON 文件，目录按文件名排序；path 为空时返回 nil
+func manifestFiles(path string) ([]string, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ensureBundleImage 确保本地有 platform 平台的 image，没有时 docker pull
+func ensureBundleImage(image, platform string) error {
+	out, err := exec.Command("docker", "image", "inspect", "--format", "{{.Os}}/{{.Architecture}}", image).Output()
+	if err == nil && strings.TrimSpace(string(out)) == platform {
+		return nil
+	}
+	fmt.Printf("拉取 %s（%s）...\n", image, platform)
+	return runCommand("docker", "pull", "--platform", platform, image)
+}
+
+// runCommand 执行命令，输出直接打印到终端
+func runCommand(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// bundleBinaryName 返回离线包中 k3 binary 的文件名
+func bundleBinaryName(platform string) string {
+	if strings.HasPrefix(platform, "windows/") {
+		return "k3.exe"
+	}
+	return "k3"
+}
+
+// bundleFile 写入离线包的一个文件
+type bundleFile struct {
+	name string
+	src  string
+	mode int64
+}
+
+// writeBundle 写入 tar.gz：bundle.json 在最前面，之后是各个文件
+func writeBundle(output string, manifest bundleManifest, files []bundleFile) (err error) {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := tw.WriteHeader(&tar.Header{Name: bundleManifestFile, Mode: 0o644, Size: int64(len(data)), ModTime: now}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := addBundleFile(tw, f); err != nil {
+			return fmt.Errorf("%s: %w", f.src, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addBundleFile(tw *tar.Writer, f bundleFile) error {
+	in, err := os.Open(f.src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: f.mode, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, in)
+	return err
+}
+
+// extractBundle 把离线包解压到 dir 并返回 bundle.json；只解压普通文件，拒绝指向 dir 之外的路径
+func extractBundle(file, dir string) (*bundleManifest, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var manifest *bundleManifest
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("离线包中的路径无效: %s", hdr.Name)
+		}
+		if hdr.Name == bundleManifestFile {
+			manifest = &bundleManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("解析 %s 失败: %w", bundleManifestFile, err)
+			}
+			continue
+		}
+		dst := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return nil, err
+		}
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return nil, err
+		}
+		if err := out.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("不是 k3 离线包：缺少 %s", bundleManifestFile)
+	}
+	for _, name := range []string{manifest.Binary, bundleImagesFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil || !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("离线包不完整：缺少 %s", name)
+		}
+	}
+	return manifest, nil
+}
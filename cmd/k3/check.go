@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"go.uber.org/zap"
+)
+
+// 检查结果
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+const (
+	// agentPort network/discovery 守护进程的端口（health server、mDNS 广播与 Consul 注册）
+	agentPort = 7946
+	// mdnsGroup mDNS 组播地址
+	mdnsGroup = "224.0.0.251:5353"
+	// mdnsGroup6 IPv6 的 mDNS 组播地址（只有 IPv6 的网卡上通过它发现节点）
+	mdnsGroup6 = "[ff02::fb]:5353"
+)
+
+// checkResult 一项启动前检查的结果
+type checkResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// checkReport k3 check -o json 的输出
+type checkReport struct {
+	// OK 没有 fail 项
+	OK     bool          `json:"ok"`
+	Checks []checkResult `json:"checks"`
+}
+
+// cmdCheck 启动前检查运行环境：容器运行时、端口、cgroup/iptables、数据目录磁盘空间与 mDNS 组播。
+// 有 fail 项时退出码为 1，-o json 输出机器可读的结果
+func cmdCheck(args []string) int {
+	fs := flag.NewFlagSet("k3 check", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := commonFlags(fs)
+	output := fs.String("o", "", "输出格式：json")
+	minFreeGB := fs.Float64("min-free-gb", 2, "数据目录所在磁盘的最小可用空间（GiB），低于它时 fail，低于 2 倍时 warn")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *output != "" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "不支持的输出格式: %s（支持 json）\n", *output)
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+	cfg := config.NewFileConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	checks := []checkResult{checkRuntime(ctx, cfg)}
+	checks = append(checks, checkPorts(cfg)...)
+	checks = append(checks,
+		checkCgroups(),
+		checkIptables(ctx),
+		checkDataDisk(cfg, uint64(*minFreeGB*(1<<30))),
+		checkMulticast(),
+	)
+
+	report := checkReport{OK: true, Checks: checks}
+	for _, c := range checks {
+		if c.Status == checkFail {
+			report.OK = false
+		}
+	}
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHECK\tSTATUS\tMESSAGE")
+		for _, c := range checks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, strings.ToUpper(c.Status), c.Message)
+		}
+		_ = tw.Flush()
+	}
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// checkRuntime 检测容器运行时与版本；没有运行时时 master 只能提供 apiserver 与存储（warn），其他角色无法运行 Pod（fail）
+func checkRuntime(ctx context.Context, cfg config.Config) checkResult {
+	logger := logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}
+	rt, err := controller.NewRuntimeDetector(logger, cfg.Cluster.ID, "").DetectRuntime()
+	if err != nil {
+		status := checkFail
+		if strings.EqualFold(strings.TrimSpace(cfg.Role), "master") {
+			status = checkWarn
+		}
+		return checkResult{Name: "runtime", Status: status, Message: "未找到可用的容器运行时（Docker/Podman/containerd/CRI-O），Pod 与自动拉起的存储容器不可用"}
+	}
+	version, err := controller.RuntimeVersion(ctx, rt)
+	if err != nil {
+		return checkResult{Name: "runtime", Status: checkWarn, Message: fmt.Sprintf("%s 可用，但获取版本失败: %v", rt.Name(), err)}
+	}
+	msg := fmt.Sprintf("%s %s", rt.Name(), version)
+	if osName, arch, err := rt.Platform(ctx); err == nil {
+		msg += fmt.Sprintf("（%s/%s）", osName, arch)
+	}
+	return checkResult{Name: "runtime", Status: checkOK, Message: msg}
+}
+
+// portCheck 一个需要空闲的端口；busy 为端口已被占用时的结果（已经运行的 k3 组件或存储容器也会占用端口）
+type portCheck struct {
+	name string
+	addr string
+	busy string
+	hint string
+}
+
+// checkPorts 检查 web、调试端口、network/discovery 端口与本机自动拉起的存储端口是否空闲
+func checkPorts(cfg config.Config) []checkResult {
+	ports := []portCheck{
+		{name: "port/web", addr: ":" + strconv.Itoa(cfg.Gin.Port), busy: checkFail, hint: "web.port（apiserver 与 dashboard）"},
+	}
+	if cfg.Gin.AdminPort > 0 {
+		ports = append(ports, portCheck{name: "port/admin", addr: ":" + strconv.Itoa(cfg.Gin.AdminPort), busy: checkFail, hint: "web.admin_port"})
+	}
+	ports = append(ports, portCheck{name: "port/agent", addr: ":" + strconv.Itoa(agentPort), busy: checkWarn, hint: "network/discovery 守护进程（已在运行时可以忽略）"})
+	if addr, ok := bootstrap.LocalStorageAddr(cfg); ok {
+		_, port, _ := net.SplitHostPort(addr)
+		ports = append(ports, portCheck{name: "port/storage", addr: ":" + port, busy: checkWarn, hint: cfg.Storage.Type + " 存储（k3 拉起的存储容器已在运行时可以忽略）"})
+	}
+
+	var results []checkResult
+	for _, p := range ports {
+		if p.addr == ":0" {
+			results = append(results, checkResult{Name: p.name, Status: checkFail, Message: p.hint + " 未配置端口"})
+			continue
+		}
+		ln, err := net.Listen("tcp", p.addr)
+		if err != nil {
+			results = append(results, checkResult{Name: p.name, Status: p.busy, Message: fmt.Sprintf("%s 无法监听 %s: %v", p.hint, p.addr, err)})
+			continue
+		}
+		_ = ln.Close()
+		results = append(results, checkResult{Name: p.name, Status: checkOK, Message: fmt.Sprintf("%s %s 空闲", p.hint, p.addr)})
+	}
+	return results
+}
+
+// checkCgroups 检查 cgroup 的 cpu 与 memory 控制器（用于后续的资源限制，缺少时只 warn）
+func checkCgroups() checkResult {
+	if runtime.GOOS != "linux" {
+		return checkResult{Name: "cgroup", Status: checkSkip, Message: runtime.GOOS + " 上由容器运行时的虚拟机提供"}
+	}
+	var version string
+	var controllers []string
+	if data, err := os.ReadFile("/sys/fs/cgroup/cgroup.controllers"); err == nil {
+		version = "v2"
+		controllers = strings.Fields(string(data))
+	} else if data, err := os.ReadFile("/proc/cgroups"); err == nil {
+		version = "v1"
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			// subsys_name hierarchy num_cgroups enabled
+			if len(fields) == 4 && !strings.HasPrefix(fields[0], "#") && fields[3] == "1" {
+				controllers = append(controllers, fields[0])
+			}
+		}
+	} else {
+		return checkResult{Name: "cgroup", Status: checkWarn, Message: "未找到 cgroup（/sys/fs/cgroup/cgroup.controllers 与 /proc/cgroups 都不可读）"}
+	}
+	var missing []string
+	for _, want := range []string{"cpu", "memory"} {
+		found := false
+		for _, c := range controllers {
+			if c == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+	if len(missing) > 0 {
+		return checkResult{Name: "cgroup", Status: checkWarn, Message: fmt.Sprintf("cgroup %s 缺少控制器: %s", version, strings.Join(missing, ", "))}
+	}
+	return checkResult{Name: "cgroup", Status: checkOK, Message: fmt.Sprintf("cgroup %s（%s）", version, strings.Join(controllers, " "))}
+}
+
+// checkIptables 检查 iptables（用于后续的 Service 转发与 NetworkPolicy，缺少时只 warn）
+func checkIptables(ctx context.Context) checkResult {
+	if runtime.GOOS != "linux" {
+		return checkResult{Name: "iptables", Status: checkSkip, Message: runtime.GOOS + " 上不使用 iptables"}
+	}
+	path, err := exec.LookPath("iptables")
+	if err != nil {
+		return checkResult{Name: "iptables", Status: checkWarn, Message: "未找到 iptables 命令"}
+	}
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return checkResult{Name: "iptables", Status: checkWarn, Message: fmt.Sprintf("%s --version 失败: %v", path, err)}
+	}
+	return checkResult{Name: "iptables", Status: checkOK, Message: strings.TrimSpace(string(out))}
+}
+
+// dataDir 返回 k3 的数据目录：自动拉起的存储容器配置了 data_dir 时使用它，否则使用静态 Pod 目录
+func dataDir(cfg config.Config) string {
+	switch strings.ToLower(strings.TrimSpace(cfg.Storage.Type)) {
+	case "mysql":
+		if cfg.Storage.MySQL.Container.DataDir != "" {
+			return cfg.Storage.MySQL.Container.DataDir
+		}
+	case "etcd":
+		if cfg.Storage.Etcd.Container.DataDir != "" {
+			return cfg.Storage.Etcd.Container.DataDir
+		}
+	}
+	return cfg.Storage.StaticPodPath
+}
+
+// checkDataDisk 检查数据目录所在磁盘的可用空间；目录还不存在时检查最近的已存在的上级目录
+func checkDataDisk(cfg config.Config, minFree uint64) checkResult {
+	dir := dataDir(cfg)
+	if dir == "" {
+		return checkResult{Name: "disk", Status: checkSkip, Message: "没有配置数据目录"}
+	}
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return checkResult{Name: "disk", Status: checkWarn, Message: err.Error()}
+	}
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	capacity, used, err := controller.DiskUsage(path)
+	if err != nil {
+		return checkResult{Name: "disk", Status: checkSkip, Message: err.Error()}
+	}
+	free := capacity - used
+	msg := fmt.Sprintf("%s 可用 %.1f GiB / %.1f GiB", dir, float64(free)/(1<<30), float64(capacity)/(1<<30))
+	switch {
+	case free < minFree:
+		return checkResult{Name: "disk", Status: checkFail, Message: msg + fmt.Sprintf("，低于 %.1f GiB", float64(minFree)/(1<<30))}
+	case free < 2*minFree:
+		return checkResult{Name: "disk", Status: checkWarn, Message: msg}
+	}
+	return checkResult{Name: "disk", Status: checkOK, Message: msg}
+}
+
+// checkMulticast 检查 mDNS 组播：需要一个已启用、支持组播的非回环网卡，并能加入 224.0.0.251:5353 或 [ff02::fb]:5353
+func checkMulticast() checkResult {
+	if runtime.GOOS == "windows" {
+		return checkResult{Name: "mdns", Status: checkSkip, Message: "Windows 上不启用 mDNS 发现"}
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return checkResult{Name: "mdns", Status: checkWarn, Message: fmt.Sprintf("列出网卡失败: %v", err)}
+	}
+	var lastErr error
+	for _, g := range []struct{ network, addr string }{{"udp4", mdnsGroup}, {"udp6", mdnsGroup6}} {
+		group, _ := net.ResolveUDPAddr(g.network, g.addr)
+		for i := range ifaces {
+			iface := &ifaces[i]
+			if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+				continue
+			}
+			conn, err := net.ListenMulticastUDP(g.network, iface, group)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			_ = conn.Close()
+			return checkResult{Name: "mdns", Status: checkOK, Message: fmt.Sprintf("可以在 %s 上加入 %s", iface.Name, g.addr)}
+		}
+	}
+	if lastErr == nil {
+		lastErr = errors.New("没有已启用且支持组播的非回环网卡")
+	}
+	return checkResult{Name: "mdns", Status: checkWarn, Message: fmt.Sprintf("mDNS 组播不可用，局域网节点发现将不可用: %v", lastErr)}
+}
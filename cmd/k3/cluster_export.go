@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// exportedDevice 是 `network export` 输出中的一个设备（json/yaml 格式）
+type exportedDevice struct {
+	IP   string `json:"ip"`
+	Name string `json:"name"`
+	MAC  string `json:"mac"`
+}
+
+// networkExport 是 `network export` 的输出，这里只关心设备列表
+type networkExport struct {
+	Devices []exportedDevice `json:"devices"`
+}
+
+// agentInfo 是 network 守护进程 GET /info 的响应（只取需要的字段）
+type agentInfo struct {
+	Node  string   `json:"node"`
+	Port  int      `json:"port"`
+	Addrs []string `json:"addrs"`
+}
+
+// clusterPeer 是探测到运行着 k3 agent 的一台主机
+type clusterPeer struct {
+	Name   string `json:"name"`
+	IP     string `json:"ip"`
+	Role   string `json:"role"`
+	Config string `json:"config"`
+}
+
+// clusterPeerList 写入 <dir>/peers.yaml，记录集群成员以及没有探测到 agent 的设备
+type clusterPeerList struct {
+	ClusterID string           `json:"clusterID"`
+	Master    string           `json:"master"`
+	Storage   string           `json:"storage"`
+	CreatedAt string           `json:"createdAt"`
+	Nodes     []clusterPeer    `json:"nodes"`
+	Skipped   []exportedDevice `json:"skipped,omitempty"`
+}
+
+// fromExportOptions 是 `cluster create --from-export` 的参数
+type fromExportOptions struct {
+	dir          string
+	exportPath   string
+	master       string
+	agentPort    int
+	probeTimeout time.Duration
+	webPort      int
+	storageType  string
+	clusterID    string
+}
+
+// clusterCreateFromExport 读取 `network export` 导出的设备列表，探测哪些主机运行着 k3 agent（network 守护进程的 /info），
+// 为每个 agent 生成节点配置（一个 master，其余为 node，存储统一指向 master）以及 peers.yaml
+func clusterCreateFromExport(opts fromExportOptions) int {
+	data, err := os.ReadFile(opts.exportPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取导出文件失败: %v\n", err)
+		return 1
+	}
+	var export networkExport
+	if err := yaml.Unmarshal(data, &export); err != nil {
+		fmt.Fprintf(os.Stderr, "解析导出文件失败（需要 network export 的 json/yaml 输出）: %v\n", err)
+		return 1
+	}
+	if len(export.Devices) == 0 {
+		fmt.Fprintf(os.Stderr, "导出文件 %s 中没有设备\n", opts.exportPath)
+		return 1
+	}
+
+	fmt.Printf("探测 %d 个设备上的 k3 agent（端口 %d）...\n", len(export.Devices), opts.agentPort)
+	peers, skipped := probeAgents(context.Background(), export.Devices, opts.agentPort, opts.probeTimeout)
+	if len(peers) == 0 {
+		fmt.Fprintln(os.Stderr, "没有探测到运行 k3 agent 的主机，请先在各主机上启动 network 守护进程（go run ./cmd/network）")
+		return 1
+	}
+
+	masterIdx := 0
+	if opts.master != "" {
+		masterIdx = -1
+		for i, p := range peers {
+			if p.Name == opts.master || p.IP == opts.master {
+				masterIdx = i
+				break
+			}
+		}
+		if masterIdx < 0 {
+			fmt.Fprintf(os.Stderr, "--master %s 不在探测到的 agent 中\n", opts.master)
+			return 1
+		}
+	}
+	masterIP := peers[masterIdx].IP
+
+	for i := range peers {
+		p := &peers[i]
+		p.Role = "node"
+		if i == masterIdx {
+			p.Role = "master"
+		}
+		nodeDir := filepath.Join(opts.dir, p.Name)
+		if err := os.MkdirAll(nodeDir, 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "创建目录失败: %v\n", err)
+			return 1
+		}
+		p.Config = filepath.Join(nodeDir, ".config.yaml")
+		content := clusterNodeConfigYAML(p.Role, opts.webPort, opts.storageType, masterIP, opts.clusterID)
+		if err := os.WriteFile(p.Config, []byte(content), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "写入配置失败: %v\n", err)
+			return 1
+		}
+	}
+
+	list := clusterPeerList{
+		ClusterID: opts.clusterID,
+		Master:    peers[masterIdx].Name,
+		Storage:   opts.storageType,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Nodes:     peers,
+		Skipped:   skipped,
+	}
+	out, err := yaml.Marshal(list)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成 peers.yaml 失败: %v\n", err)
+		return 1
+	}
+	peersPath := filepath.Join(opts.dir, "peers.yaml")
+	if err := os.WriteFile(peersPath, out, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "写入 peers.yaml 失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("探测到 %d 个 k3 agent（%d 个设备未运行 agent），配置已生成到 %s/：\n", len(peers), len(skipped), opts.dir)
+	for _, p := range peers {
+		fmt.Printf("  %-7s %-20s %-15s %s\n", p.Role, p.Name, p.IP, p.Config)
+	}
+	fmt.Printf("成员列表: %s\n", peersPath)
+	fmt.Println("把各节点的配置复制到对应主机后启动（先启动 master）：")
+	fmt.Println("  go run ./cmd/k3 run --config <.config.yaml>")
+	return 0
+}
+
+// probeAgents 并发请求每个设备的 http://<ip>:<port>/info，返回运行着 agent 的主机（按节点名排序）和其余设备
+func probeAgents(ctx context.Context, devices []exportedDevice, port int, timeout time.Duration) ([]clusterPeer, []exportedDevice) {
+	client := &http.Client{Timeout: timeout}
+	results := make([]*agentInfo, len(devices))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 32)
+	for i, d := range devices {
+		if net.ParseIP(strings.TrimSpace(d.IP)) == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, ip string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if info, err := fetchAgentInfo(ctx, client, ip, port); err == nil {
+				results[i] = info
+			}
+		}(i, strings.TrimSpace(d.IP))
+	}
+	wg.Wait()
+
+	var peers []clusterPeer
+	var skipped []exportedDevice
+	seen := make(map[string]bool)
+	for i, d := range devices {
+		info := results[i]
+		if info == nil {
+			skipped = append(skipped, d)
+			continue
+		}
+		name := clusterNodeDirName(info.Node, d.IP)
+		// 同一台主机可能以多个 IP 出现在邻居表中，按节点名去重
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		peers = append(peers, clusterPeer{Name: name, IP: strings.TrimSpace(d.IP)})
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	return peers, skipped
+}
+
+// fetchAgentInfo 请求 network 守护进程的 /info
+func fetchAgentInfo(ctx context.Context, client *http.Client, ip string, port int) (*agentInfo, error) {
+	url := "http://" + net.JoinHostPort(ip, strconv.Itoa(port)) + "/info"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s 返回 %s", url, resp.Status)
+	}
+	var info agentInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("%s 不是 k3 agent: %w", url, err)
+	}
+	if strings.TrimSpace(info.Node) == "" {
+		return nil, fmt.Errorf("%s 没有返回节点名", url)
+	}
+	return &info, nil
+}
+
+// clusterNodeDirName 返回节点配置目录名：agent 上报的节点名不能安全地作为目录名时使用 node-<ip>
+func clusterNodeDirName(node, ip string) string {
+	node = strings.TrimSpace(node)
+	if node == "" || node == "." || node == ".." || strings.ContainsAny(node, `/\:`) {
+		return "node-" + strings.NewReplacer(".", "-", ":", "-").Replace(strings.TrimSpace(ip))
+	}
+	return node
+}
+
+// clusterNodeConfigYAML 生成 --from-export 的节点配置：master 运行 apiserver 与存储，node 的存储地址指向 master
+func clusterNodeConfigYAML(role string, port int, storageType, masterIP, clusterID string) string {
+	return fmt.Sprintf(strings.TrimSpace(`
+debug: true
+role: %s
+cluster:
+  id: %s
+web:
+  port: %d
+  cors: true
+log:
+  level: debug
+  path: logs/app.log
+storage:
+  type: %s
+  mysql:
+    host: %s
+    port: 3306
+    user: root
+    password: password
+    database: k3
+    max_open_conns: 100
+    max_idle_conns: 10
+  etcd:
+    endpoints:
+      - http://%s
+    dial_timeout: 5s
+    username: ""
+    password: ""
+jwt:
+  signing_key: secret
+minimum_deviation_distance: 666
+output: console
+cities: []
+`)+"\n", role, clusterID, port, storageType, masterIP, net.JoinHostPort(masterIP, "2379"))
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"sigs.k8s.io/yaml"
+)
+
+// 本机 master 自动拉起的存储地址，agent 的存储配置指向这里
+const (
+	clusterEtcdPort  = 2379
+	clusterMySQLPort = 3306
+)
+
+// clusterRoleAliases 把 --roles 中的角色映射为配置中的 role：server 运行 apiserver 与存储，agent 只运行 controller
+var clusterRoleAliases = map[string]string{
+	"server": "master",
+	"master": "master",
+	"agent":  "node",
+	"node":   "node",
+	"one":    "one",
+}
+
+// clusterNode 是 --roles 生成的一个本机节点
+type clusterNode struct {
+	Name    string // 节点名，同时是配置目录名（server-1、agent-1 ...）
+	Role    string // master / node / one
+	WebPort int
+	Dir     string // 节点目录（绝对路径）
+	Config  string // 节点配置文件（绝对路径）
+}
+
+// rolesOptions 是 `cluster create --roles` 的参数
+type rolesOptions struct {
+	dir         string
+	roles       []string
+	webPort     int
+	storageType string
+	clusterID   string
+	compose     bool
+	image       string
+	systemd     bool
+	binary      string
+}
+
+// parseClusterRoles 解析 --roles（如 server,agent,agent），返回配置中的 role 列表。
+// 有 agent 时必须恰好有一个 server（或 one），agent 的存储与 apiserver 都指向它
+func parseClusterRoles(s string) ([]string, error) {
+	var roles []string
+	servers := 0
+	for _, r := range strings.Split(s, ",") {
+		r = strings.ToLower(strings.TrimSpace(r))
+		role, ok := clusterRoleAliases[r]
+		if !ok {
+			return nil, fmt.Errorf("未知角色 %q（支持 server/agent，也可用 master/node/one）", r)
+		}
+		if role != "node" {
+			servers++
+		}
+		roles = append(roles, role)
+	}
+	if servers != 1 {
+		return nil, fmt.Errorf("--roles 需要恰好一个 server（或 one），当前 %d 个", servers)
+	}
+	return roles, nil
+}
+
+// clusterCreateWithRoles 按 --roles 在本机生成各节点的配置：server 拉起存储（数据目录在节点目录下）并提供 apiserver，
+// agent 的存储与 cluster.server 指向 server；可选生成 docker-compose.yaml 与 systemd unit 用于运行这些节点
+func clusterCreateWithRoles(opts rolesOptions) int {
+	absDir, err := filepath.Abs(opts.dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析目录失败: %v\n", err)
+		return 1
+	}
+
+	nodes := make([]clusterNode, len(opts.roles))
+	counts := make(map[string]int)
+	server := 0
+	for i, role := range opts.roles {
+		prefix := "agent"
+		if role != "node" {
+			prefix = "server"
+			server = i
+		}
+		counts[prefix]++
+		name := fmt.Sprintf("%s-%d", prefix, counts[prefix])
+		nodes[i] = clusterNode{
+			Name:    name,
+			Role:    role,
+			WebPort: opts.webPort + i,
+			Dir:     filepath.Join(absDir, name),
+			Config:  filepath.Join(absDir, name, ".config.yaml"),
+		}
+	}
+	serverURL := fmt.Sprintf("http://127.0.0.1:%d", nodes[server].WebPort)
+
+	for _, n := range nodes {
+		if err := os.MkdirAll(n.Dir, 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "创建目录失败: %v\n", err)
+			return 1
+		}
+		content := clusterRoleConfigYAML(n, opts.storageType, opts.clusterID, serverURL)
+		if err := os.WriteFile(n.Config, []byte(content), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "写入配置失败: %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Printf("已生成 %d 个节点配置到 %s/（集群 ID: %s，存储: %s）：\n", len(nodes), opts.dir, opts.clusterID, opts.storageType)
+	for _, n := range nodes {
+		fmt.Printf("  %-7s %-10s web:%-6d %s\n", n.Role, n.Name, n.WebPort, n.Config)
+	}
+
+	if opts.compose {
+		path := filepath.Join(opts.dir, "docker-compose.yaml")
+		content, err := clusterComposeYAML(nodes, server, opts.clusterID, opts.image)
+		if err == nil {
+			err = os.WriteFile(path, content, 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "写入 docker-compose.yaml 失败: %v\n", err)
+			return 1
+		}
+		fmt.Printf("docker compose: docker compose -f %s up -d（镜像 %s 需要包含 docker CLI）\n", path, opts.image)
+	}
+	if opts.systemd {
+		unitDir := filepath.Join(opts.dir, "systemd")
+		if err := os.MkdirAll(unitDir, 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "创建目录失败: %v\n", err)
+			return 1
+		}
+		for _, n := range nodes {
+			path := filepath.Join(unitDir, clusterUnitName(opts.clusterID, n.Name))
+			content := clusterSystemdUnit(n, nodes[server], opts.clusterID, opts.binary)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "写入 systemd unit 失败: %v\n", err)
+				return 1
+			}
+		}
+		fmt.Printf("systemd: 把 %s/*.service 复制到 /etc/systemd/system/ 后 systemctl daemon-reload && systemctl enable --now <unit>\n", unitDir)
+	}
+
+	fmt.Println("手动启动（先启动 server）：")
+	for _, n := range nodes {
+		fmt.Printf("  go run ./cmd/k3 run --config %s\n", n.Config)
+	}
+	return 0
+}
+
+// clusterRoleConfigYAML 生成 --roles 的节点配置：日志与静态 Pod 目录（默认 manifests）都在节点目录下，
+// server 的存储数据目录为 <节点目录>/data，agent 的存储地址与 cluster.server 指向 server
+func clusterRoleConfigYAML(n clusterNode, storageType, clusterID, serverURL string) string {
+	return fmt.Sprintf(strings.TrimSpace(`
+debug: true
+role: %s
+node_name: %s
+cluster:
+  id: %s
+  server: %s
+web:
+  port: %d
+  cors: true
+log:
+  level: debug
+  path: %s
+storage:
+  type: %s
+  mysql:
+    host: 127.0.0.1
+    port: %d
+    user: root
+    password: password
+    database: k3
+    max_open_conns: 100
+    max_idle_conns: 10
+    data_dir: data/mysql
+  etcd:
+    endpoints:
+      - http://%s
+    dial_timeout: 5s
+    username: ""
+    password: ""
+    data_dir: data/etcd
+jwt:
+  signing_key: secret
+minimum_deviation_distance: 666
+output: console
+cities: []
+`)+"\n", n.Role, n.Name, clusterID, serverURL, n.WebPort, filepath.Join(n.Dir, "logs", "app.log"),
+		storageType, clusterMySQLPort, net.JoinHostPort("127.0.0.1", strconv.Itoa(clusterEtcdPort)))
+}
+
+// composeService 是 docker-compose.yaml 中的一个 k3 节点
+type composeService struct {
+	Image         string            `json:"image"`
+	ContainerName string            `json:"container_name"`
+	NetworkMode   string            `json:"network_mode"`
+	Restart       string            `json:"restart"`
+	Command       []string          `json:"command"`
+	Volumes       []string          `json:"volumes"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	DependsOn     []string          `json:"depends_on,omitempty"`
+}
+
+// clusterComposeYAML 生成 docker-compose.yaml：节点容器使用宿主机网络并挂载 docker.sock，
+// 在宿主机上拉起存储与 Pod 容器；节点目录按原路径挂载，data_dir 等 hostPath 在容器内外一致
+func clusterComposeYAML(nodes []clusterNode, server int, clusterID, image string) ([]byte, error) {
+	services := make(map[string]composeService, len(nodes))
+	for i, n := range nodes {
+		svc := composeService{
+			Image:         image,
+			ContainerName: clusterID + "-" + n.Name,
+			NetworkMode:   "host",
+			Restart:       "unless-stopped",
+			Command:       []string{"run", "--config", n.Config},
+			Volumes: []string{
+				"/var/run/docker.sock:/var/run/docker.sock",
+				n.Dir + ":" + n.Dir,
+			},
+			Labels: map[string]string{controller.LabelClusterID: clusterID},
+		}
+		if i != server {
+			svc.DependsOn = []string{nodes[server].Name}
+		}
+		services[n.Name] = svc
+	}
+	out, err := yaml.Marshal(map[string]any{"services": services})
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("# 由 k3 cluster create --compose 生成（集群 ID: %s）\n", clusterID)
+	return append([]byte(header), out...), nil
+}
+
+// clusterUnitName 返回节点的 systemd unit 文件名
+func clusterUnitName(clusterID, node string) string {
+	return clusterID + "-" + node + ".service"
+}
+
+// clusterSystemdUnit 生成节点的 systemd unit：agent 依赖 server 的 unit，先启动 server
+func clusterSystemdUnit(n, server clusterNode, clusterID, binary string) string {
+	after := "network-online.target docker.service"
+	wants := "network-online.target"
+	if n.Name != server.Name {
+		after += " " + clusterUnitName(clusterID, server.Name)
+		wants += " " + clusterUnitName(clusterID, server.Name)
+	}
+	return fmt.Sprintf(strings.TrimSpace(`
+[Unit]
+Description=k3 %s %s (cluster %s)
+After=%s
+Wants=%s
+
+[Service]
+Type=simple
+WorkingDirectory=%s
+ExecStart=%s run --config %s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`)+"\n", n.Role, n.Name, clusterID, after, wants, n.Dir, binary, n.Config)
+}
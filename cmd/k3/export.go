@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"k8s.io/client-go/dynamic"
+)
+
+// cmdExport 将本地 Store 中的资源 apply 到真实 Kubernetes 集群（import 的反向操作）
+func cmdExport(args []string) int {
+	fs := flag.NewFlagSet("k3 export", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := commonFlags(fs)
+	kubeconfig := fs.String("kubeconfig", "", "目标集群 kubeconfig 路径（默认 $KUBECONFIG 或 ~/.kube/config）")
+	kubeContext := fs.String("context", "", "kubeconfig context（默认 current-context）")
+	kinds := fs.String("kinds", strings.Join(mirror.ExportKinds, ","), "要导出的资源，逗号分隔")
+	namespaces := fs.String("namespaces", "", "只导出这些本地 namespace，逗号分隔（默认全部）")
+	nsMap := fs.String("namespace-map", "", "namespace 映射，例如 dev=staging,default=demo")
+	dryRun := fs.String("dry-run", mirror.DryRunNone, "none/client/server：client 仅打印，server 使用目标集群的服务端 dry-run")
+	includeImported := fs.Bool("include-imported", false, "同时导出 import 模式镜像的对象")
+	timeout := fs.Duration("timeout", 2*time.Minute, "整体超时时间")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+
+	mapping, err := mirror.ParseNamespaceMap(*nsMap)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	cfg := config.NewFileConfig()
+	if cfg.Storage.Type == "memory" {
+		fmt.Fprintln(os.Stderr, "提示：当前 storage 为 memory，新进程中没有数据；请使用 mysql/etcd 配置指向已有集群的存储")
+	}
+	store, err := storage.NewStore(cfg.Storage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "连接本地存储失败: %v\n", err)
+		return 1
+	}
+	if closer, ok := store.(interface{ Close() error }); ok {
+		defer func() { _ = closer.Close() }()
+	}
+
+	var target dynamic.Interface
+	if *dryRun != mirror.DryRunClient {
+		restCfg, source, err := mirror.LoadRESTConfig(*kubeconfig, *kubeContext)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		target, err = dynamic.NewForConfig(restCfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建 Kubernetes 客户端失败: %v\n", err)
+			return 1
+		}
+		fmt.Printf("目标集群: %s\n", source)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var applied, skipped, failed int
+	err = mirror.Export(ctx, store, target, mirror.ExportOptions{
+		Kinds:           splitCSV(*kinds),
+		Namespaces:      splitCSV(*namespaces),
+		NamespaceMap:    mapping,
+		DryRun:          *dryRun,
+		IncludeImported: *includeImported,
+	}, func(r mirror.ExportResult) {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(os.Stderr, "导出失败 %s %s/%s: %v\n", r.Kind, r.Namespace, r.Name, r.Err)
+		case r.Action == "skipped":
+			skipped++
+			fmt.Printf("跳过 %s %s/%s（%s）\n", r.Kind, r.Namespace, r.Name, r.Reason)
+		case r.Action == "dry-run":
+			applied++
+			fmt.Printf("将导出 %s %s/%s (dry-run=%s)\n", r.Kind, r.Namespace, r.Name, *dryRun)
+		default:
+			applied++
+			fmt.Printf("已导出 %s %s/%s\n", r.Kind, r.Namespace, r.Name)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导出失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("完成：导出 %d，跳过 %d，失败 %d\n", applied, skipped, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/api"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/service"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+)
+
+// cmdImport 从真实 Kubernetes 集群单向镜像资源到本地 Store（只读，持续 watch）
+func cmdImport(args []string) int {
+	fs := flag.NewFlagSet("k3 import", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := commonFlags(fs)
+	noBootstrap := bootstrapFlags(fs)
+	kubeconfig := fs.String("kubeconfig", "", "kubeconfig 路径（默认 $KUBECONFIG 或 ~/.kube/config）")
+	kubeContext := fs.String("context", "", "kubeconfig context（默认 current-context）")
+	namespaces := fs.String("namespaces", "", "要镜像的 namespace，逗号分隔（默认全部）")
+	kinds := fs.String("kinds", strings.Join(mirror.DefaultKinds, ","), "要镜像的资源，逗号分隔（支持 pods/services/configmaps/secrets/nodes/deployments/statefulsets/daemonsets）")
+	resync := fs.Duration("resync", 10*time.Minute, "informer 全量同步间隔")
+	withWeb := fs.Bool("web", false, "同时启动 web（dashboard + apiserver），便于离线查看")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+	applyBootstrapFlag(*noBootstrap)
+
+	settings := mirror.Settings{
+		Kubeconfig: *kubeconfig,
+		Context:    *kubeContext,
+		Namespaces: splitCSV(*namespaces),
+		Kinds:      splitCSV(*kinds),
+		Resync:     *resync,
+	}
+
+	modules := fx.Options(
+		core.CoreModule,
+		fx.Provide(
+			bootstrap.ProvideDBContainerHandle,
+			bootstrap.ProvideStore,
+			func() mirror.Settings { return settings },
+			mirror.NewImporter,
+		),
+	)
+	if *withWeb {
+		modules = fx.Options(
+			modules,
+			service.Modules,
+			api.Modules,
+			apiserver.Module,
+			fx.Invoke(StartWebOnly),
+		)
+	}
+
+	app := fxApp(modules, StartImportMode)
+	if err := app.Start(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "启动失败: %v\n", err)
+		return 1
+	}
+	defer func() { _ = app.Stop(context.Background()) }()
+
+	blockUntilSignal()
+	return 0
+}
+
+// splitCSV 解析逗号分隔的参数，忽略空项
+func splitCSV(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// StartImportMode 启动 import 模式：storage + importer
+func StartImportMode(
+	lc fx.Lifecycle,
+	handle *bootstrap.DBContainerHandle,
+	store storage.Store,
+	importer *mirror.Importer,
+	cfg config.Config,
+	l logprovider.Logger,
+) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			l.Infof("Storage 已就绪 (type=%s)", cfg.Storage.Type)
+
+			l.Info("正在启动 Import...")
+			if err := importer.Start(ctx); err != nil {
+				return fmt.Errorf("启动 import 失败: %w", err)
+			}
+			l.Info("Import 启动完成")
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			_ = importer.Stop(ctx)
+			if closer, ok := store.(interface{ Close() error }); ok {
+				_ = closer.Close()
+			}
+			_ = handle.Stop(context.Background())
+			return nil
+		},
+	})
+}
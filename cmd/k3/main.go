@@ -2,35 +2,40 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
-	"os/signal"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxevent"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/api"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/apiproxy"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/cli"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterconfig"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/diag"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/discovery"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/gitops"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/idle"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/notify"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/registry"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/service"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/tenancy"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
-	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
 )
 
 func main() {
@@ -51,9 +56,37 @@ func main() {
 	case "web":
 		os.Exit(cmdWeb(os.Args[2:]))
 	case "apply":
-		os.Exit(cmdApply(os.Args[2:]))
+		os.Exit(cli.Apply(os.Args[2:]))
 	case "cluster":
 		os.Exit(cmdCluster(os.Args[2:]))
+	case "import":
+		os.Exit(cmdImport(os.Args[2:]))
+	case "export":
+		os.Exit(cmdExport(os.Args[2:]))
+	case "rollout":
+		os.Exit(cli.Rollout(os.Args[2:]))
+	case "history":
+		os.Exit(cli.History(os.Args[2:]))
+	case "get":
+		os.Exit(cli.Get(os.Args[2:]))
+	case "wait":
+		os.Exit(cli.Wait(os.Args[2:]))
+	case "explain":
+		os.Exit(cli.Explain(os.Args[2:]))
+	case "seed":
+		os.Exit(cmdSeed(os.Args[2:]))
+	case "top":
+		os.Exit(cmdTop(os.Args[2:]))
+	case "upgrade":
+		os.Exit(cmdUpgrade(os.Args[2:]))
+	case "migrate":
+		os.Exit(cmdMigrate(os.Args[2:]))
+	case "version":
+		os.Exit(cmdVersion(os.Args[2:]))
+	case "bundle":
+		os.Exit(cmdBundle(os.Args[2:]))
+	case "check":
+		os.Exit(cmdCheck(os.Args[2:]))
 	case "-h", "--help", "help":
 		usage()
 		return
@@ -76,11 +109,30 @@ Commands:
   controller            启动 storage + controller
   web                   仅启动 web 模块（假设 storage 已运行）
   apply                 将 Kubernetes YAML/JSON 提交到 apiserver（最小 apply 子集）
-  cluster create        创建 k3 集群配置骨架（多节点配置文件）
+  cluster create        创建 k3 集群配置骨架（多节点配置文件；--roles 按角色生成，--from-export 按 network export 的设备生成）
   cluster clear         删除 k3 集群配置目录以及关联的容器
+  cluster upgrade-nodes 逐个节点封锁、驱逐 Pod（遵守 PDB）、执行升级命令、等待重新加入后解除封锁
+  import                从真实 Kubernetes 集群只读镜像资源到本地 Store（持续 watch）
+  export                将本地 Store 中的资源 apply 到真实 Kubernetes 集群
+  rollout status        等待 Deployment 发布完成（基于 generation/observedGeneration 与副本状态）
+  rollout pause/resume  暂停/恢复 Deployment 的发布（spec.paused：暂停期间模板的变更不发布，仍维持副本数）
+  history               查看对象保留的版本历史：每个版本的时间、操作、写入者以及与上一个版本的差异
+  get                   列出资源（READY/STATUS/RESTARTS/AGE 等列由 apiserver 计算；支持 -n/-A、-l 与 -o wide）
+  wait                  基于 watch 等待资源满足条件（--for=condition=Ready 或 --for=delete，--timeout 超时后退出码为 1）
+  explain               列出资源字段的类型、说明与 k3 的支持程度：哪些字段生效、部分生效或仅保存（例如 explain pod.spec.containers）
+  seed                  提交内置的示例拓扑（--preset demo：3 个节点、Deployment、Service 与各种异常 Pod；--delete 删除）
+  top pods              查看 Pod 的 CPU/内存使用（由容器运行时实时采样；支持排序、按 namespace 汇总与 -w 持续刷新）
+  upgrade               升级 k3：检查版本与存储 schema（拒绝降级）、执行迁移、替换 binary 并按 storage -> controller -> web 重启
+  migrate               对配置中的存储执行待执行的 schema 迁移
+  version               打印 k3 版本与支持的存储 schema 版本
+  bundle create         生成离线安装包：k3 binary、运行需要的镜像（docker save）、配置与安装后提交的 YAML
+  bundle install        安装离线包：docker load 镜像、安装 binary 与配置并启动节点（无需外网）
+  check                 启动前检查运行环境：容器运行时与版本、端口、cgroup/iptables、数据目录磁盘空间与 mDNS 组播（-o json）
 
 Flags:
   --config <path>       指定配置文件路径（默认: ./.config.yaml；也支持环境变量 CONFIG_PATH）
+  --no-bootstrap-containers
+                        run/start/storage/controller/import：不自动拉起本机的 MySQL/etcd 容器（等价于 bootstrap.auto_start_db: false）
 `))
 }
 
@@ -88,6 +140,18 @@ func commonFlags(fs *flag.FlagSet) *string {
 	return fs.String("config", "", "配置文件路径（等价于环境变量 CONFIG_PATH）")
 }
 
+// bootstrapFlags 注册 --no-bootstrap-containers（会连接存储的命令使用）
+func bootstrapFlags(fs *flag.FlagSet) *bool {
+	return fs.Bool("no-bootstrap-containers", false, "不自动拉起本机的 MySQL/etcd 容器（等价于 bootstrap.auto_start_db: false）")
+}
+
+// applyBootstrapFlag 通过环境变量 AUTO_START_DB 覆盖配置中的 bootstrap.auto_start_db
+func applyBootstrapFlag(disabled bool) {
+	if disabled {
+		_ = os.Setenv("AUTO_START_DB", "false")
+	}
+}
+
 func applyConfigFlag(configPath string) {
 	if strings.TrimSpace(configPath) == "" {
 		return
@@ -103,6 +167,8 @@ func newFxLogger() fxevent.Logger {
 func fxApp(modules fx.Option, invoke any) *fx.App {
 	opts := fx.Options(
 		modules,
+		// SIGUSR1 输出诊断快照，SIGUSR2 切换 debug 日志
+		diag.Module,
 	)
 	return fx.New(
 		opts,
@@ -116,10 +182,12 @@ func cmdRun(args []string) int {
 	fs := flag.NewFlagSet("k3 run", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	cfgPath := commonFlags(fs)
+	noBootstrap := bootstrapFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
 	applyConfigFlag(*cfgPath)
+	applyBootstrapFlag(*noBootstrap)
 
 	// 读取配置以获取 role
 	cfg := config.NewFileConfig()
@@ -139,9 +207,12 @@ func cmdRun(args []string) int {
 			fx.Provide(
 				bootstrap.ProvideDBContainerHandle,
 				bootstrap.ProvideStore,
-				func() discovery.Settings {
+				func(cfg config.Config) discovery.Settings {
 					// 使用默认值或从环境变量读取
 					nodeName := os.Getenv("NODE_NAME")
+					if nodeName == "" {
+						nodeName = cfg.NodeName
+					}
 					if nodeName == "" {
 						hostname, _ := os.Hostname()
 						nodeName = hostname
@@ -159,6 +230,9 @@ func cmdRun(args []string) int {
 						HealthCheckTimeout:             3 * time.Second,
 						DeregisterCriticalServiceAfter: 30 * time.Second,
 						AutoStartConsul:                 autoStartConsul,
+						ConsulContainer:                cfg.Discovery.Consul.Container,
+						ClusterID:                      cfg.Cluster.ID,
+						PodHealthChecks:                cfg.Discovery.Consul.PodHealthChecks,
 					}
 				},
 				discovery.NewService,
@@ -166,6 +240,10 @@ func cmdRun(args []string) int {
 			service.Modules,
 			api.Modules,
 			apiserver.Module,
+			notify.Module,
+			gitops.Module,
+			tenancy.Module,
+			clusterconfig.Module,
 		)
 		invokeFunc = StartMasterMode
 
@@ -178,6 +256,9 @@ func cmdRun(args []string) int {
 				bootstrap.ProvideStore,
 			),
 			controller.Module,
+			clusterconfig.Module,
+			registry.Module,
+			apiproxy.Module,
 		)
 		invokeFunc = StartNodeMode
 
@@ -188,9 +269,12 @@ func cmdRun(args []string) int {
 			fx.Provide(
 				bootstrap.ProvideDBContainerHandle,
 				bootstrap.ProvideStore,
-				func() discovery.Settings {
+				func(cfg config.Config) discovery.Settings {
 					// 使用默认值或从环境变量读取
 					nodeName := os.Getenv("NODE_NAME")
+					if nodeName == "" {
+						nodeName = cfg.NodeName
+					}
 					if nodeName == "" {
 						hostname, _ := os.Hostname()
 						nodeName = hostname
@@ -208,14 +292,24 @@ func cmdRun(args []string) int {
 						HealthCheckTimeout:             3 * time.Second,
 						DeregisterCriticalServiceAfter: 30 * time.Second,
 						AutoStartConsul:                 autoStartConsul,
+						ConsulContainer:                cfg.Discovery.Consul.Container,
+						ClusterID:                      cfg.Cluster.ID,
+						PodHealthChecks:                cfg.Discovery.Consul.PodHealthChecks,
 					}
 				},
 				discovery.NewService,
 			),
 			controller.Module,
+			clusterconfig.Module,
+			registry.Module,
+			apiproxy.Module,
 			service.Modules,
 			api.Modules,
 			apiserver.Module,
+			notify.Module,
+			gitops.Module,
+			tenancy.Module,
+			idle.Module,
 		)
 		invokeFunc = StartOneMode
 
@@ -236,6 +330,11 @@ func cmdRun(args []string) int {
 }
 
 // getEnvOrDefault 获取环境变量，如果不存在则返回默认值
+// apiserverBase 返回 apiserver 地址（见 cli.ServerURL）
+func apiserverBase(server string) string {
+	return cli.ServerURL(server)
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -248,10 +347,12 @@ func cmdStartAll(args []string) int {
 	fs := flag.NewFlagSet("k3 start", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	cfgPath := commonFlags(fs)
+	noBootstrap := bootstrapFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
 	applyConfigFlag(*cfgPath)
+	applyBootstrapFlag(*noBootstrap)
 
 	modules := fx.Options(
 		core.CoreModule,
@@ -260,9 +361,16 @@ func cmdStartAll(args []string) int {
 			bootstrap.ProvideStore,
 		),
 		controller.Module,
+		clusterconfig.Module,
+		registry.Module,
+		apiproxy.Module,
 		service.Modules,
 		api.Modules,
 		apiserver.Module,
+		notify.Module,
+		gitops.Module,
+		tenancy.Module,
+		idle.Module,
 	)
 
 	app := fxApp(modules, StartAll)
@@ -281,10 +389,12 @@ func cmdStorage(args []string) int {
 	fs := flag.NewFlagSet("k3 storage", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	cfgPath := commonFlags(fs)
+	noBootstrap := bootstrapFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
 	applyConfigFlag(*cfgPath)
+	applyBootstrapFlag(*noBootstrap)
 
 	modules := fx.Options(
 		core.CoreModule,
@@ -310,10 +420,12 @@ func cmdController(args []string) int {
 	fs := flag.NewFlagSet("k3 controller", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	cfgPath := commonFlags(fs)
+	noBootstrap := bootstrapFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
 	applyConfigFlag(*cfgPath)
+	applyBootstrapFlag(*noBootstrap)
 
 	modules := fx.Options(
 		core.CoreModule,
@@ -322,6 +434,9 @@ func cmdController(args []string) int {
 			bootstrap.ProvideStore,
 		),
 		controller.Module,
+		clusterconfig.Module,
+		registry.Module,
+		apiproxy.Module,
 	)
 
 	app := fxApp(modules, StartControllerOnly)
@@ -366,162 +481,6 @@ func cmdWeb(args []string) int {
 	return 0
 }
 
-func cmdApply(args []string) int {
-	fs := flag.NewFlagSet("k3 apply", flag.ContinueOnError)
-	fs.SetOutput(os.Stderr)
-	cfgPath := commonFlags(fs)
-	file := fs.String("f", "", "要提交的 YAML 文件路径（支持多文档 ---）")
-	server := fs.String("server", "", "apiserver 地址（默认从配置读取，例如 http://localhost:8080）")
-	if err := fs.Parse(args); err != nil {
-		return 2
-	}
-	applyConfigFlag(*cfgPath)
-
-	if strings.TrimSpace(*file) == "" {
-		fmt.Fprintln(os.Stderr, "缺少 -f <file>")
-		return 2
-	}
-
-	cfg := config.NewFileConfig()
-	base := strings.TrimSpace(*server)
-	if base == "" {
-		base = fmt.Sprintf("http://localhost:%d", cfg.Gin.Port)
-	}
-	base = strings.TrimRight(base, "/")
-
-	p := parser.NewParser()
-	objects, gvks, err := p.ParseYAMLFile(*file)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "解析 YAML 失败: %v\n", err)
-		return 1
-	}
-	if len(objects) == 0 {
-		fmt.Fprintln(os.Stderr, "YAML 中没有可提交的资源")
-		return 2
-	}
-
-	client := &http.Client{Timeout: 15 * time.Second}
-	for i, obj := range objects {
-		gvk := gvks[i]
-		if gvk == nil {
-			fmt.Fprintf(os.Stderr, "跳过第 %d 个对象：无法解析 GVK\n", i+1)
-			continue
-		}
-		meta, ok := obj.(metav1.Object)
-		if !ok {
-			fmt.Fprintf(os.Stderr, "跳过第 %d 个对象：不支持的对象类型（无 metadata）\n", i+1)
-			continue
-		}
-
-		path, err := apiPathFor(*gvk, meta.GetNamespace())
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "跳过 %s/%s：%v\n", gvk.Kind, meta.GetName(), err)
-			continue
-		}
-
-		body, err := parser.ToYAML(obj)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "序列化 %s/%s 失败: %v\n", gvk.Kind, meta.GetName(), err)
-			continue
-		}
-
-		req, err := http.NewRequest(http.MethodPost, base+path, strings.NewReader(string(body)))
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "构造请求失败: %v\n", err)
-			return 1
-		}
-		req.Header.Set("Content-Type", "application/yaml")
-
-		resp, err := client.Do(req)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "提交失败 %s/%s: %v\n", gvk.Kind, meta.GetName(), err)
-			return 1
-		}
-		respBody, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-
-		// 如果已存在，则走一次 PUT（最小化“apply”语义）
-		if resp.StatusCode == http.StatusConflict {
-			updateURL := fmt.Sprintf("%s%s/%s", base, path, meta.GetName())
-			req2, err := http.NewRequest(http.MethodPut, updateURL, strings.NewReader(string(body)))
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "构造更新请求失败: %v\n", err)
-				return 1
-			}
-			req2.Header.Set("Content-Type", "application/yaml")
-
-			resp2, err := client.Do(req2)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "更新失败 %s/%s: %v\n", gvk.Kind, meta.GetName(), err)
-				return 1
-			}
-			respBody2, _ := io.ReadAll(resp2.Body)
-			_ = resp2.Body.Close()
-			if resp2.StatusCode < 200 || resp2.StatusCode >= 300 {
-				fmt.Fprintf(os.Stderr, "更新失败 %s/%s: HTTP %d: %s\n", gvk.Kind, meta.GetName(), resp2.StatusCode, strings.TrimSpace(string(respBody2)))
-				return 1
-			}
-			fmt.Printf("已更新 %s %s/%s\n", gvk.Kind, meta.GetNamespace(), meta.GetName())
-			continue
-		}
-
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			fmt.Fprintf(os.Stderr, "提交失败 %s/%s: HTTP %d: %s\n", gvk.Kind, meta.GetName(), resp.StatusCode, strings.TrimSpace(string(respBody)))
-			return 1
-		}
-
-		fmt.Printf("已提交 %s %s/%s\n", gvk.Kind, meta.GetNamespace(), meta.GetName())
-	}
-
-	return 0
-}
-
-func apiPathFor(gvk schema.GroupVersionKind, namespace string) (string, error) {
-	plural, ok := kindToPlural(gvk.Kind)
-	if !ok {
-		return "", fmt.Errorf("unsupported kind: %s", gvk.Kind)
-	}
-
-	// cluster-scoped
-	if gvk.Kind == "Node" && gvk.Group == "" && gvk.Version == "v1" {
-		return fmt.Sprintf("/api/%s/%s", gvk.Version, plural), nil
-	}
-
-	// namespaced (default to "default")
-	ns := strings.TrimSpace(namespace)
-	if ns == "" {
-		ns = "default"
-	}
-
-	if gvk.Group == "" {
-		return fmt.Sprintf("/api/%s/namespaces/%s/%s", gvk.Version, ns, plural), nil
-	}
-	return fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", gvk.Group, gvk.Version, ns, plural), nil
-}
-
-func kindToPlural(kind string) (string, bool) {
-	switch kind {
-	case "Pod":
-		return "pods", true
-	case "Service":
-		return "services", true
-	case "ConfigMap":
-		return "configmaps", true
-	case "Secret":
-		return "secrets", true
-	case "Node":
-		return "nodes", true
-	case "Deployment":
-		return "deployments", true
-	case "StatefulSet":
-		return "statefulsets", true
-	case "DaemonSet":
-		return "daemonsets", true
-	default:
-		return "", false
-	}
-}
-
 func cmdCluster(args []string) int {
 	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, "cluster 需要子命令，例如: cluster create")
@@ -533,6 +492,8 @@ func cmdCluster(args []string) int {
 		return cmdClusterCreate(args[1:])
 	case "clear":
 		return cmdClusterClear(args[1:])
+	case "upgrade-nodes":
+		return cmdClusterUpgradeNodes(args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "未知 cluster 子命令: %s\n", args[0])
 		return 2
@@ -546,10 +507,84 @@ func cmdClusterCreate(args []string) int {
 	dir := fs.String("dir", ".k3", "输出目录")
 	nodes := fs.Int("nodes", 1, "节点数量")
 	webPort := fs.Int("web-port", 8080, "node-1 的 web 端口")
-	storageType := fs.String("storage", "memory", "storage 类型：memory/mysql/etcd")
+	storageType := fs.String("storage", "memory", "storage 类型：memory/mysql/etcd（--from-export 时默认 etcd）")
+	fromExport := fs.String("from-export", "", "network export 导出的设备列表（json/yaml）；指定后探测运行 k3 agent 的主机并按主机生成配置")
+	master := fs.String("master", "", "--from-export 时作为 master 的节点名或 IP（默认按节点名排序的第一个）")
+	agentPort := fs.Int("agent-port", 7946, "--from-export 时探测的 network 守护进程端口（GET /info）")
+	probeTimeout := fs.Duration("probe-timeout", 2*time.Second, "--from-export 时单个设备的探测超时")
+	roles := fs.String("roles", "", "按顺序指定各节点角色，如 server,agent,agent（server=master，agent=node，也可用 one）；指定后忽略 --nodes")
+	compose := fs.Bool("compose", false, "--roles 时同时生成 docker-compose.yaml 运行各节点")
+	image := fs.String("image", "k3:latest", "--compose 时节点容器使用的 k3 镜像（需要包含 docker CLI）")
+	systemd := fs.Bool("systemd", false, "--roles 时同时生成各节点的 systemd unit（<dir>/systemd/）")
+	binary := fs.String("binary", "/usr/local/bin/k3", "--systemd 时 ExecStart 使用的 k3 可执行文件")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
+	clusterID, err := clusterIDForDir(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成集群 ID 失败: %v\n", err)
+		return 1
+	}
+	storageSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "storage" {
+			storageSet = true
+		}
+	})
+	if (*compose || *systemd) && *roles == "" {
+		fmt.Fprintln(os.Stderr, "--compose/--systemd 需要与 --roles 一起使用")
+		return 2
+	}
+	if *roles != "" {
+		nodeRoles, err := parseClusterRoles(*roles)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		storage := *storageType
+		if len(nodeRoles) > 1 {
+			if !storageSet {
+				// agent 与 server 是不同进程，memory 存储无法共享
+				storage = "etcd"
+			}
+			if storage == "memory" {
+				fmt.Fprintln(os.Stderr, "--roles 包含 agent 时 --storage 需要为 etcd 或 mysql")
+				return 2
+			}
+		}
+		return clusterCreateWithRoles(rolesOptions{
+			dir:         *dir,
+			roles:       nodeRoles,
+			webPort:     *webPort,
+			storageType: storage,
+			clusterID:   clusterID,
+			compose:     *compose,
+			image:       *image,
+			systemd:     *systemd,
+			binary:      *binary,
+		})
+	}
+	if *fromExport != "" {
+		storage := *storageType
+		if !storageSet {
+			// memory 存储只在进程内可见，多主机集群需要共享存储
+			storage = "etcd"
+		}
+		if storage == "memory" {
+			fmt.Fprintln(os.Stderr, "--from-export 生成的是多主机集群，--storage 需要为 etcd 或 mysql")
+			return 2
+		}
+		return clusterCreateFromExport(fromExportOptions{
+			dir:          *dir,
+			exportPath:   *fromExport,
+			master:       strings.TrimSpace(*master),
+			agentPort:    *agentPort,
+			probeTimeout: *probeTimeout,
+			webPort:      *webPort,
+			storageType:  storage,
+			clusterID:    clusterID,
+		})
+	}
 	if *nodes <= 0 {
 		fmt.Fprintln(os.Stderr, "--nodes 必须 > 0")
 		return 2
@@ -563,14 +598,14 @@ func cmdClusterCreate(args []string) int {
 		}
 
 		cfgPath := filepath.Join(nodeDir, ".config.yaml")
-		cfgContent := defaultConfigYAML(*webPort+i-1, *storageType)
+		cfgContent := defaultConfigYAML(*webPort+i-1, *storageType, clusterID)
 		if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
 			fmt.Fprintf(os.Stderr, "写入配置失败: %v\n", err)
 			return 1
 		}
 	}
 
-	fmt.Printf("已生成 %d 个节点配置到 %s/\n", *nodes, *dir)
+	fmt.Printf("已生成 %d 个节点配置到 %s/（集群 ID: %s）\n", *nodes, *dir, clusterID)
 	fmt.Println("示例：启动 node-1：")
 	fmt.Printf("  CONFIG_PATH=%s go run ./cmd/k3 start\n", filepath.Join(*dir, "node-1", ".config.yaml"))
 	return 0
@@ -582,6 +617,7 @@ func cmdClusterClear(args []string) int {
 	fs.SetOutput(os.Stderr)
 	dir := fs.String("dir", ".k3", "要删除的集群配置目录")
 	force := fs.Bool("force", false, "不询问确认，直接删除")
+	all := fs.Bool("all", false, "清理本机所有 k3 容器（包括其他集群以及没有集群标签的旧容器），而不只是 --dir 对应集群的容器")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
@@ -598,9 +634,22 @@ func cmdClusterClear(args []string) int {
 		return 1
 	}
 
+	// 只清理 --dir 中节点配置记录的集群的容器；--all 时清理本机所有 k3 容器
+	var clusterIDs []string
+	if !*all {
+		clusterIDs = readClusterIDs(clusterDir)
+	}
+
 	// 确认操作
 	if !*force {
-		fmt.Fprintf(os.Stderr, "警告：将删除目录 %s 及其所有内容，以及关联的 k3 容器\n", clusterDir)
+		target := "本机所有 k3 容器"
+		if !*all {
+			target = fmt.Sprintf("集群 %s 的容器", strings.Join(clusterIDs, ", "))
+			if len(clusterIDs) == 0 {
+				target = "（未找到集群 ID，不清理容器）"
+			}
+		}
+		fmt.Fprintf(os.Stderr, "警告：将删除目录 %s 及其所有内容，以及%s\n", clusterDir, target)
 		fmt.Fprint(os.Stderr, "确认删除？(yes/no): ")
 		var answer string
 		fmt.Scanln(&answer)
@@ -611,8 +660,13 @@ func cmdClusterClear(args []string) int {
 	}
 
 	// 1. 停止并删除关联容器
-	fmt.Println("正在清理关联容器...")
-	containersCleared := clearK3Containers()
+	containersCleared := 0
+	if *all || len(clusterIDs) > 0 {
+		fmt.Println("正在清理关联容器...")
+		containersCleared = clearK3Containers(clusterIDs, *all)
+	} else {
+		fmt.Printf("目录 %s 的节点配置中没有 cluster.id（旧版本 cluster create 生成或目录不存在），跳过容器清理；需要清理本机所有 k3 容器时使用 --all\n", clusterDir)
+	}
 
 	// 2. 删除配置目录
 	fmt.Printf("正在删除配置目录: %s\n", clusterDir)
@@ -625,9 +679,9 @@ func cmdClusterClear(args []string) int {
 	return 0
 }
 
-// clearK3Containers 清理 k3 相关的 Docker 容器
-// 返回清理的容器数量
-func clearK3Containers() int {
+// clearK3Containers 清理 k3 相关的 Docker 容器：默认只清理带有 clusterIDs 中任一 io.k3.cluster.id 标签的容器，
+// all 为 true 时清理本机所有 k3 容器。返回清理的容器数量
+func clearK3Containers(clusterIDs []string, all bool) int {
 	// 检查 docker 是否可用
 	if _, err := exec.LookPath("docker"); err != nil {
 		fmt.Println("未找到 docker 命令，跳过容器清理")
@@ -644,34 +698,34 @@ func clearK3Containers() int {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// 获取所有容器名称（包括已停止的）
-	listCmd := exec.CommandContext(ctx, "docker", "ps", "-a", "--format", "{{.Names}}")
-	output, err := listCmd.Output()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "获取容器列表失败: %v\n", err)
-		return 0
+	// 指定集群的容器：带 io.k3.cluster.id=<id> 标签（Pod 容器、sandbox 以及存储/Consul 容器）
+	var filters []string
+	for _, id := range clusterIDs {
+		filters = append(filters, "label="+controller.LabelClusterID+"="+id)
+	}
+	// --all 时清理所有 k3 相关的容器：
+	// 1. 带 io.k3.pod.name 标签的容器（Pod 容器以及存储容器）
+	// 2. 旧版本创建、没有标签的容器：按名称前缀 k8s_ 匹配（k8s_storage_mysql_mysql、k8s_{namespace}_{pod-name}_{container-name}）
+	if all {
+		filters = []string{"label=" + controller.LabelPodName, "name=^k8s_"}
 	}
 
-	allNames := strings.Split(strings.TrimSpace(string(output)), "\n")
-	cleared := 0
-
-	// k3 相关的容器名称模式
-	// 1. 存储容器：k8s_storage_mysql_mysql, k8s_storage_etcd_etcd
-	// 2. Pod 容器：k8s_{namespace}_{pod-name}_{container-name}
 	matchedContainers := make(map[string]bool)
-
-	for _, name := range allNames {
-		name = strings.TrimSpace(name)
-		if name == "" {
-			continue
+	for _, filter := range filters {
+		output, err := exec.CommandContext(ctx, "docker", "ps", "-a", "--filter", filter, "--format", "{{.Names}}").Output()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "获取容器列表失败: %v\n", err)
+			return 0
 		}
-
-		// 匹配 k3 容器命名规则
-		if strings.HasPrefix(name, "k8s_") {
-			matchedContainers[name] = true
+		for _, name := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if name = strings.TrimSpace(name); name != "" {
+				matchedContainers[name] = true
+			}
 		}
 	}
 
+	cleared := 0
+
 	// 停止并删除匹配的容器
 	for name := range matchedContainers {
 		// 停止容器
@@ -693,10 +747,53 @@ func clearK3Containers() int {
 	return cleared
 }
 
-func defaultConfigYAML(port int, storageType string) string {
+// clusterIDForDir 返回 cluster create 使用的集群 ID：目录中已有节点配置记录了唯一的集群 ID 时沿用
+// （重新生成配置不会让已有容器脱离集群），否则生成新的 ID
+func clusterIDForDir(dir string) (string, error) {
+	if ids := readClusterIDs(dir); len(ids) == 1 {
+		return ids[0], nil
+	}
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "k3-" + hex.EncodeToString(b), nil
+}
+
+// readClusterIDs 读取集群目录下节点配置（<dir>/.config.yaml 与 <dir>/*/.config.yaml）中的 cluster.id，去重后返回
+func readClusterIDs(dir string) []string {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*", ".config.yaml"))
+	paths = append(paths, filepath.Join(dir, ".config.yaml"))
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var cfg struct {
+			Cluster struct {
+				ID string `json:"id"`
+			} `json:"cluster"`
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			continue
+		}
+		if id := strings.TrimSpace(cfg.Cluster.ID); id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func defaultConfigYAML(port int, storageType, clusterID string) string {
 	// 基于项目现有配置结构生成一个最小可运行配置；用户可按需修改。
 	return fmt.Sprintf(strings.TrimSpace(`
 debug: true
+cluster:
+  id: %s
 web:
   port: %d
   cors: true
@@ -724,7 +821,7 @@ jwt:
 minimum_deviation_distance: 666
 output: console
 cities: []
-`)+"\n", port, storageType)
+`)+"\n", clusterID, port, storageType)
 }
 
 func blockUntilSignal() {
@@ -733,11 +830,6 @@ func blockUntilSignal() {
 	<-ch
 }
 
-func signalNotify(ch chan<- os.Signal) {
-	// 使用单独函数，便于后续扩展（如 Windows 信号差异）
-	signal.Notify(ch, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
-}
-
 // StartAll 按顺序启动：storage -> controller -> web。
 func StartAll(
 	lc fx.Lifecycle,
@@ -780,9 +872,7 @@ func StartAll(
 			if closer, ok := store.(interface{ Close() error }); ok {
 				_ = closer.Close()
 			}
-			if handle != nil && handle.Started && handle.Runtime != nil && handle.Pod != nil {
-				_ = handle.Runtime.StopContainer(context.Background(), handle.Pod)
-			}
+			_ = handle.Stop(context.Background())
 			return nil
 		},
 	})
@@ -807,9 +897,7 @@ func StartStorageOnly(
 			if closer, ok := store.(interface{ Close() error }); ok {
 				_ = closer.Close()
 			}
-			if handle != nil && handle.Started && handle.Runtime != nil && handle.Pod != nil {
-				_ = handle.Runtime.StopContainer(context.Background(), handle.Pod)
-			}
+			_ = handle.Stop(context.Background())
 			return nil
 		},
 	})
@@ -910,9 +998,7 @@ func StartMasterMode(
 			if closer, ok := store.(interface{ Close() error }); ok {
 				_ = closer.Close()
 			}
-			if handle != nil && handle.Started && handle.Runtime != nil && handle.Pod != nil {
-				_ = handle.Runtime.StopContainer(context.Background(), handle.Pod)
-			}
+			_ = handle.Stop(context.Background())
 			return nil
 		},
 	})
@@ -947,9 +1033,7 @@ func StartNodeMode(
 			if closer, ok := store.(interface{ Close() error }); ok {
 				_ = closer.Close()
 			}
-			if handle != nil && handle.Started && handle.Runtime != nil && handle.Pod != nil {
-				_ = handle.Runtime.StopContainer(context.Background(), handle.Pod)
-			}
+			_ = handle.Stop(context.Background())
 			return nil
 		},
 	})
@@ -1007,9 +1091,7 @@ func StartOneMode(
 			if closer, ok := store.(interface{ Close() error }); ok {
 				_ = closer.Close()
 			}
-			if handle != nil && handle.Started && handle.Runtime != nil && handle.Pod != nil {
-				_ = handle.Runtime.StopContainer(context.Background(), handle.Pod)
-			}
+			_ = handle.Stop(context.Background())
 			return nil
 		},
 	})
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/cli"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// seedPresets 内置的示例拓扑（seed/<preset>.yaml），按文档顺序提交
+//
+//go:embed seed/*.yaml
+var seedPresets embed.FS
+
+// seedPresetNames 返回内置预设的名称
+func seedPresetNames() []string {
+	entries, _ := seedPresets.ReadDir("seed")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), path.Ext(e.Name())))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cmdSeed 把内置的示例拓扑提交到 apiserver，开发 Dashboard 与控制器时不需要每次手写 YAML
+func cmdSeed(args []string) int {
+	fs := flag.NewFlagSet("k3 seed", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := commonFlags(fs)
+	preset := fs.String("preset", "demo", "预设名称（--list 查看）")
+	server := fs.String("server", "", "apiserver 地址（默认从配置读取，例如 http://localhost:8080）")
+	list := fs.Bool("list", false, "列出内置的预设")
+	del := fs.Bool("delete", false, "删除预设创建的对象（按提交的相反顺序）")
+	dump := fs.Bool("o", false, "只打印预设的 YAML，不提交")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+
+	if *list {
+		for _, name := range seedPresetNames() {
+			fmt.Println(name)
+		}
+		return 0
+	}
+
+	data, err := seedPresets.ReadFile("seed/" + *preset + ".yaml")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "未知预设 %q（可用: %s）\n", *preset, strings.Join(seedPresetNames(), ", "))
+		return 2
+	}
+	if *dump {
+		_, _ = os.Stdout.Write(data)
+		return 0
+	}
+
+	objects, gvks, err := parser.NewParser().ParseYAMLManifest(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析预设 %s 失败: %v\n", *preset, err)
+		return 1
+	}
+
+	base := apiserverBase(*server)
+	if !*del {
+		return cli.ApplyObjects(base, objects, gvks)
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	for i := len(objects) - 1; i >= 0; i-- {
+		meta, ok := objects[i].(metav1.Object)
+		if !ok || gvks[i] == nil {
+			continue
+		}
+		gvk := *gvks[i]
+		p, err := cli.APIPathFor(gvk, meta.GetNamespace())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "跳过 %s/%s：%v\n", gvk.Kind, meta.GetName(), err)
+			continue
+		}
+		req, err := http.NewRequest(http.MethodDelete, base+p+"/"+meta.GetName(), nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "构造请求失败: %v\n", err)
+			return 1
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "删除失败 %s/%s: %v\n", gvk.Kind, meta.GetName(), err)
+			return 1
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			continue
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			fmt.Fprintf(os.Stderr, "删除失败 %s/%s: HTTP %d: %s\n", gvk.Kind, meta.GetName(), resp.StatusCode, strings.TrimSpace(string(body)))
+			return 1
+		}
+		fmt.Printf("已删除 %s %s/%s\n", gvk.Kind, meta.GetNamespace(), meta.GetName())
+	}
+	return 0
+}
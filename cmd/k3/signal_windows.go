@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// signalNotify 订阅退出信号。Windows 没有 SIGQUIT：Ctrl+C/Ctrl+Break 以 os.Interrupt 送达，
+// 关闭控制台窗口、注销和关机（CTRL_CLOSE/LOGOFF/SHUTDOWN_EVENT）以 SIGTERM 送达。
+func signalNotify(ch chan<- os.Signal) {
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/cli"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+)
+
+// cmdTop 查看资源使用
+func cmdTop(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "用法: k3 top pods [-n namespace | -A] [--sort-by cpu|memory|name] [--containers] [--by-namespace] [-w]")
+		return 2
+	}
+	switch args[0] {
+	case "pods", "pod", "po":
+		return cmdTopPods(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: top %s（目前只支持 pods）\n", args[0])
+		return 2
+	}
+}
+
+// cmdTopPods 打印 Pod 的 CPU/内存使用（由节点上的容器运行时实时采样，不依赖 metrics-server）
+func cmdTopPods(args []string) int {
+	fs := flag.NewFlagSet("k3 top pods", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := commonFlags(fs)
+	namespace := fs.String("n", "default", "namespace")
+	allNamespaces := fs.Bool("A", false, "所有 namespace")
+	server := fs.String("server", "", "apiserver 地址（默认从配置读取，例如 http://localhost:8080）")
+	sortBy := fs.String("sort-by", "", "排序：cpu、memory（从大到小）或 name（默认按 namespace/name）")
+	containers := fs.Bool("containers", false, "同时列出每个容器的使用")
+	byNamespace := fs.Bool("by-namespace", false, "按 namespace 汇总")
+	watch := fs.Bool("w", false, "持续刷新")
+	fs.BoolVar(watch, "watch", false, "持续刷新（同 -w）")
+	interval := fs.Duration("interval", 2*time.Second, "持续刷新的间隔")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+
+	switch *sortBy {
+	case "", "cpu", "memory", "name":
+	default:
+		fmt.Fprintf(os.Stderr, "--sort-by 只支持 cpu、memory、name: %s\n", *sortBy)
+		return 2
+	}
+	if *interval < time.Second {
+		*interval = time.Second
+	}
+
+	path := "/apis/k3.io/v1/namespaces/" + *namespace + "/podstats"
+	if *allNamespaces {
+		path = "/apis/k3.io/v1/podstats"
+	}
+	rawURL := apiserverBase(*server) + path
+
+	for {
+		body, err := cli.APIGet(rawURL, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "获取 Pod 资源使用失败: %v\n", err)
+			if !*watch {
+				return 1
+			}
+		} else {
+			var list apiserver.PodStatsList
+			if err := json.Unmarshal(body, &list); err != nil {
+				fmt.Fprintf(os.Stderr, "解析 Pod 资源使用失败: %v\n", err)
+				return 1
+			}
+			if *watch {
+				// 清屏后重新输出（与 watch(1) 相同）
+				fmt.Print("\033[H\033[2J")
+				fmt.Printf("%s  节点 %s  每 %s 刷新（Ctrl+C 退出）\n\n", list.Timestamp.Local().Format("15:04:05"), list.Node, *interval)
+			}
+			printPodStats(&list, *sortBy, *allNamespaces, *containers, *byNamespace)
+		}
+		if !*watch {
+			return 0
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// printPodStats 以表格输出资源使用；有其他节点的 Pod 无法采样时在末尾提示
+func printPodStats(list *apiserver.PodStatsList, sortBy string, allNamespaces, containers, byNamespace bool) {
+	items := list.Items
+	sortPodStats(items, sortBy)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	switch {
+	case byNamespace:
+		type usage struct {
+			namespace   string
+			pods        int
+			cpu, memory int64
+		}
+		index := make(map[string]*usage)
+		var totals []*usage
+		for _, p := range items {
+			u, ok := index[p.Namespace]
+			if !ok {
+				u = &usage{namespace: p.Namespace}
+				index[p.Namespace] = u
+				totals = append(totals, u)
+			}
+			u.pods++
+			u.cpu += p.CPUMillicores
+			u.memory += p.MemoryBytes
+		}
+		sort.SliceStable(totals, func(i, j int) bool {
+			switch sortBy {
+			case "cpu":
+				return totals[i].cpu > totals[j].cpu
+			case "memory":
+				return totals[i].memory > totals[j].memory
+			}
+			return totals[i].namespace < totals[j].namespace
+		})
+		fmt.Fprintln(w, "NAMESPACE\tPODS\tCPU(cores)\tMEMORY(bytes)")
+		for _, u := range totals {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", u.namespace, u.pods, formatMillicores(u.cpu), formatMemory(u.memory))
+		}
+	case containers:
+		if allNamespaces {
+			fmt.Fprint(w, "NAMESPACE\t")
+		}
+		fmt.Fprintln(w, "POD\tNAME\tCPU(cores)\tMEMORY(bytes)")
+		for _, p := range items {
+			for _, c := range p.Containers {
+				if allNamespaces {
+					fmt.Fprintf(w, "%s\t", p.Namespace)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, c.Name, formatMillicores(c.CPUMillicores), formatMemory(c.MemoryBytes))
+			}
+		}
+	default:
+		if allNamespaces {
+			fmt.Fprint(w, "NAMESPACE\t")
+		}
+		fmt.Fprintln(w, "NAME\tCPU(cores)\tMEMORY(bytes)")
+		for _, p := range items {
+			if allNamespaces {
+				fmt.Fprintf(w, "%s\t", p.Namespace)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, formatMillicores(p.CPUMillicores), formatMemory(p.MemoryBytes))
+		}
+	}
+	_ = w.Flush()
+
+	if len(items) == 0 {
+		fmt.Println("没有运行中的 Pod")
+	}
+	if len(list.UnavailableNodes) > 0 {
+		fmt.Fprintf(os.Stderr, "\n注意：节点 %s 上运行中的 Pod 未包含在内（只能采样 apiserver 所在节点 %s）\n", strings.Join(list.UnavailableNodes, ", "), list.Node)
+	}
+}
+
+// sortPodStats 按 --sort-by 排序：cpu/memory 从大到小，其余按 namespace/name
+func sortPodStats(items []apiserver.PodStats, sortBy string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		switch sortBy {
+		case "cpu":
+			if items[i].CPUMillicores != items[j].CPUMillicores {
+				return items[i].CPUMillicores > items[j].CPUMillicores
+			}
+		case "memory":
+			if items[i].MemoryBytes != items[j].MemoryBytes {
+				return items[i].MemoryBytes > items[j].MemoryBytes
+			}
+		}
+		if items[i].Namespace != items[j].Namespace {
+			return items[i].Namespace < items[j].Namespace
+		}
+		return items[i].Name < items[j].Name
+	})
+}
+
+// formatMillicores 按 kubectl top 的格式输出 CPU，例如 12m
+func formatMillicores(m int64) string {
+	return fmt.Sprintf("%dm", m)
+}
+
+// formatMemory 按 kubectl top 的格式输出内存，例如 34Mi
+func formatMemory(b int64) string {
+	switch {
+	case b >= 1<<30:
+		return fmt.Sprintf("%dGi", b>>30)
+	case b >= 1<<20:
+		return fmt.Sprintf("%dMi", b>>20)
+	default:
+		return fmt.Sprintf("%dKi", b>>10)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/version"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+)
+
+// versionInfo 是 `k3 version --json` 的输出，upgrade 用它确认新 binary 支持的 schema 版本
+type versionInfo struct {
+	Version          string `json:"version"`
+	SchemaVersion    int    `json:"schemaVersion"`
+	MinSchemaVersion int    `json:"minSchemaVersion"`
+}
+
+// currentVersionInfo 返回当前 binary 的版本信息
+func currentVersionInfo() versionInfo {
+	return versionInfo{
+		Version:          version.Version,
+		SchemaVersion:    storage.SchemaVersion,
+		MinSchemaVersion: storage.MinSchemaVersion,
+	}
+}
+
+// cmdVersion 打印 k3 版本以及支持的存储 schema 版本
+func cmdVersion(args []string) int {
+	fs := flag.NewFlagSet("k3 version", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	asJSON := fs.Bool("json", false, "以 JSON 输出（k3 upgrade 用它检查新 binary）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	info := currentVersionInfo()
+	if *asJSON {
+		_ = json.NewEncoder(os.Stdout).Encode(info)
+		return 0
+	}
+	fmt.Printf("k3 %s（存储 schema v%d，可从 v%d 起迁移）\n", info.Version, info.SchemaVersion, info.MinSchemaVersion)
+	return 0
+}
+
+// openSchemaMigrator 按配置连接存储；memory 存储没有需要迁移的持久化数据，返回 ok=false
+func openSchemaMigrator(cfg config.Config) (storage.SchemaMigrator, func(), bool, error) {
+	if strings.ToLower(strings.TrimSpace(cfg.Storage.Type)) == "memory" {
+		return nil, nil, false, nil
+	}
+	s, err := storage.NewStore(cfg.Storage)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("连接存储失败: %w", err)
+	}
+	closeFn := func() {
+		if closer, ok := s.(interface{ Close() error }); ok {
+			_ = closer.Close()
+		}
+	}
+	m, ok := s.(storage.SchemaMigrator)
+	if !ok {
+		closeFn()
+		return nil, nil, false, nil
+	}
+	return m, closeFn, true, nil
+}
+
+// cmdMigrate 对配置中的存储执行待执行的 schema 迁移（k3 upgrade 用新 binary 调用）
+func cmdMigrate(args []string) int {
+	fs := flag.NewFlagSet("k3 migrate", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := commonFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "只检查版本并列出待执行的迁移")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+
+	cfg := config.NewFileConfig()
+	m, closeFn, ok, err := openSchemaMigrator(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !ok {
+		fmt.Printf("存储类型 %s 没有持久化数据，无需迁移\n", cfg.Storage.Type)
+		return 0
+	}
+	defer closeFn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	stored, err := m.SchemaVersion(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取存储 schema 版本失败: %v\n", err)
+		return 1
+	}
+	if err := storage.CheckSchemaVersion(stored, storage.MinSchemaVersion, storage.SchemaVersion); err != nil {
+		fmt.Fprintf(os.Stderr, "存储 schema v%d 不能由 k3 %s 使用: %v\n", stored, version.Version, err)
+		return 1
+	}
+	pending := storage.PendingMigrations(stored)
+	fmt.Printf("存储 schema: v%d，k3 %s 使用 v%d，待执行迁移 %d 个\n", stored, version.Version, storage.SchemaVersion, len(pending))
+	for _, p := range pending {
+		fmt.Printf("  v%d: %s\n", p.Version, p.Description)
+	}
+	if *dryRun {
+		return 0
+	}
+	applied, err := m.Migrate(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "迁移失败（已执行 %d 个）: %v\n", len(applied), err)
+		return 1
+	}
+	fmt.Printf("存储 schema 已是 v%d\n", storage.SchemaVersion)
+	return 0
+}
+
+// upgradeComponent 是 upgrade 按顺序重启的一个组件
+type upgradeComponent struct {
+	name string
+	unit string
+}
+
+// cmdUpgrade 升级 k3：检查新 binary 与存储 schema 的版本，拒绝会破坏数据的降级，用新 binary 执行迁移，
+// 替换 binary 后按 storage -> controller -> web 的顺序重启组件
+func cmdUpgrade(args []string) int {
+	fs := flag.NewFlagSet("k3 upgrade", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := commonFlags(fs)
+	binary := fs.String("binary", "", "新版本 k3 可执行文件路径")
+	url := fs.String("url", "", "新版本 k3 可执行文件的下载地址（与 --binary 二选一）")
+	target := fs.String("target", "", "要替换的 k3 可执行文件（默认当前运行的 k3）")
+	dryRun := fs.Bool("dry-run", false, "只做版本检查并列出待执行的迁移，不修改任何东西")
+	storageUnit := fs.String("storage-unit", "", "storage 组件的 systemd unit（按 storage -> controller -> web 的顺序重启）")
+	controllerUnit := fs.String("controller-unit", "", "controller 组件的 systemd unit")
+	webUnit := fs.String("web-unit", "", "web 组件的 systemd unit（重启后等待 /api/readyz）")
+	timeout := fs.Duration("timeout", 2*time.Minute, "每个组件重启后等待就绪的超时")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+
+	if (*binary == "") == (*url == "") {
+		fmt.Fprintln(os.Stderr, "需要 --binary 或 --url 之一")
+		return 2
+	}
+	dst := strings.TrimSpace(*target)
+	if dst == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无法确定当前 k3 可执行文件，请指定 --target: %v\n", err)
+			return 1
+		}
+		dst = exe
+	}
+
+	// 1. 准备新 binary
+	newBin := *binary
+	if *url != "" {
+		downloaded, err := downloadBinary(*url, dst)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "下载失败: %v\n", err)
+			return 1
+		}
+		defer os.Remove(downloaded)
+		newBin = downloaded
+	}
+	next, err := binaryVersionInfo(newBin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无法获取新 binary 的版本信息: %v\n", err)
+		return 1
+	}
+	cur := currentVersionInfo()
+	fmt.Printf("当前 k3 %s（schema v%d）-> 新 k3 %s（schema v%d，可从 v%d 起迁移）\n",
+		cur.Version, cur.SchemaVersion, next.Version, next.SchemaVersion, next.MinSchemaVersion)
+
+	// 2. 预检：存储中的 schema 必须在新 binary 支持的范围内，数据比新 binary 新说明是降级，会破坏数据
+	cfg := config.NewFileConfig()
+	if err := preflightSchema(cfg, next); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// 3. 由新 binary 执行迁移（只有它知道新增的迁移）
+	migrateArgs := []string{"migrate"}
+	if p := os.Getenv("CONFIG_PATH"); p != "" {
+		migrateArgs = append(migrateArgs, "--config", p)
+	}
+	if *dryRun {
+		migrateArgs = append(migrateArgs, "--dry-run")
+	}
+	if err := runBinary(newBin, migrateArgs...); err != nil {
+		fmt.Fprintf(os.Stderr, "存储迁移失败，未替换 binary: %v\n", err)
+		return 1
+	}
+	if *dryRun {
+		fmt.Println("dry-run：未替换 binary，未重启组件")
+		return 0
+	}
+
+	// 4. 替换 binary（旧版本保留为 <target>.prev）
+	if err := installBinary(newBin, dst); err != nil {
+		fmt.Fprintf(os.Stderr, "替换 binary 失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("已安装 %s（旧版本保留为 %s.prev）\n", dst, dst)
+
+	// 5. 按 storage -> controller -> web 的顺序重启
+	components := []upgradeComponent{
+		{name: "storage", unit: *storageUnit},
+		{name: "controller", unit: *controllerUnit},
+		{name: "web", unit: *webUnit},
+	}
+	restarted := 0
+	for _, c := range components {
+		if c.unit == "" {
+			continue
+		}
+		fmt.Printf("重启 %s（%s）...\n", c.name, c.unit)
+		if err := restartUnit(c.unit, *timeout); err != nil {
+			fmt.Fprintf(os.Stderr, "重启 %s 失败: %v\n", c.name, err)
+			return 1
+		}
+		if c.name == "web" {
+			if err := waitReady(apiserverBase("")+"/api/readyz", *timeout); err != nil {
+				fmt.Fprintf(os.Stderr, "web 未就绪: %v\n", err)
+				return 1
+			}
+		}
+		restarted++
+	}
+	if restarted == 0 {
+		fmt.Println("未指定 --storage-unit/--controller-unit/--web-unit，请按 storage -> controller -> web 的顺序手动重启各组件")
+	}
+	fmt.Printf("升级到 k3 %s 完成\n", next.Version)
+	return 0
+}
+
+// preflightSchema 检查存储中的 schema 版本是否在新 binary 支持的范围内
+func preflightSchema(cfg config.Config, next versionInfo) error {
+	m, closeFn, ok, err := openSchemaMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Printf("存储类型 %s 没有持久化数据，跳过 schema 检查\n", cfg.Storage.Type)
+		return nil
+	}
+	defer closeFn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	stored, err := m.SchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("读取存储 schema 版本失败: %w", err)
+	}
+	fmt.Printf("存储 schema: v%d\n", stored)
+	err = storage.CheckSchemaVersion(stored, next.MinSchemaVersion, next.SchemaVersion)
+	switch {
+	case errors.Is(err, storage.ErrSchemaTooNew):
+		return fmt.Errorf("拒绝降级：存储 schema v%d 由更新的 k3 写入，k3 %s 只支持到 v%d，继续会破坏数据", stored, next.Version, next.SchemaVersion)
+	case errors.Is(err, storage.ErrSchemaTooOld):
+		return fmt.Errorf("存储 schema v%d 过旧，k3 %s 只能从 v%d 起迁移，请先升级到中间版本", stored, next.Version, next.MinSchemaVersion)
+	}
+	return err
+}
+
+// binaryVersionInfo 执行 `<bin> version --json` 获取新 binary 的版本信息
+func binaryVersionInfo(bin string) (versionInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "version", "--json").Output()
+	if err != nil {
+		return versionInfo{}, fmt.Errorf("%s version --json: %w（新 binary 需要支持 version 命令）", bin, err)
+	}
+	var info versionInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return versionInfo{}, fmt.Errorf("解析 %s version --json 输出失败: %w", bin, err)
+	}
+	if info.SchemaVersion <= 0 {
+		return versionInfo{}, fmt.Errorf("%s 没有返回 schema 版本", bin)
+	}
+	return info, nil
+}
+
+// runBinary 执行 bin，输出直接打印到终端
+func runBinary(bin string, args ...string) error {
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// downloadBinary 把 url 下载到 target 所在目录的临时文件（与 target 同一文件系统，安装时可以直接 rename）
+func downloadBinary(url, target string) (string, error) {
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s 返回 %s", url, resp.Status)
+	}
+	f, err := os.CreateTemp(filepath.Dir(target), ".k3-download-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := os.Chmod(f.Name(), 0o755); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// installBinary 把 src 复制为 dst：先写入 dst.new，旧的 dst 改名为 dst.prev，再把 dst.new 改名为 dst
+func installBinary(src, dst string) error {
+	absSrc, _ := filepath.Abs(src)
+	absDst, _ := filepath.Abs(dst)
+	if absSrc == absDst {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".new"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		if err := os.Rename(dst, dst+".prev"); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, dst)
+}
+
+// restartUnit 重启 systemd unit 并等待它进入 active 状态
+func restartUnit(unit string, timeout time.Duration) error {
+	if out, err := exec.Command("systemctl", "restart", unit).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl restart %s: %w, 输出: %s", unit, err, strings.TrimSpace(string(out)))
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := exec.Command("systemctl", "is-active", "--quiet", unit).Run(); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s 在 %s 内没有进入 active 状态", unit, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// waitReady 轮询 url 直到返回 200
+func waitReady(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("%s 返回 %s", url, resp.Status)
+		}
+		lastErr = err
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("等待 %s 超时: %w", url, lastErr)
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/client"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// upgradePollInterval upgrade-nodes 轮询节点、Pod 与 Deployment 状态的间隔
+const upgradePollInterval = 2 * time.Second
+
+// nodeUpgrader 逐个升级节点：封锁 -> 驱逐 Pod -> 执行升级命令 -> 等待节点重新加入 -> 解除封锁 -> 等待工作负载恢复
+type nodeUpgrader struct {
+	cs      *client.Clientset
+	base    string
+	http    *http.Client
+	command string
+	timeout time.Duration
+}
+
+// cmdClusterUpgradeNodes 逐个升级集群节点，期间保持工作负载可用：驱逐经过 apiserver 的 eviction 子资源（遵守 PodDisruptionBudget），
+// 同一 Deployment 的副本按 maxUnavailable 依次驱逐；一个节点重新加入集群、被驱逐的工作负载恢复可用之后才升级下一个节点
+func cmdClusterUpgradeNodes(args []string) int {
+	fs := flag.NewFlagSet("k3 cluster upgrade-nodes", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	cfgPath := commonFlags(fs)
+	server := fs.String("server", "", "apiserver 地址（默认从配置读取，例如 http://localhost:8080）")
+	nodesFlag := fs.String("nodes", "", "按顺序升级的节点（逗号分隔，默认所有节点按名称排序）")
+	command := fs.String("command", "", "升级一个节点的命令，{node} 替换为节点名，例如 'ssh {node} sudo k3 upgrade --url <url> --controller-unit k3-controller'")
+	timeout := fs.Duration("timeout", 10*time.Minute, "每个节点驱逐 Pod、重新加入集群与工作负载恢复可用的等待超时")
+	dryRun := fs.Bool("dry-run", false, "只打印升级顺序与每个节点上要驱逐的 Pod，不修改任何东西")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+	if strings.TrimSpace(*command) == "" && !*dryRun {
+		fmt.Fprintln(os.Stderr, "需要 --command（升级单个节点的命令，{node} 替换为节点名）")
+		return 2
+	}
+
+	base := apiserverBase(*server)
+	cs, err := client.NewForConfig(&client.Config{Host: base})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建客户端失败: %v\n", err)
+		return 1
+	}
+	u := &nodeUpgrader{cs: cs, base: base, http: &http.Client{Timeout: 30 * time.Second}, command: *command, timeout: *timeout}
+
+	ctx := context.Background()
+	nodes, err := u.nodeOrder(ctx, *nodesFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for i, name := range nodes {
+		fmt.Printf("[%d/%d] 节点 %s\n", i+1, len(nodes), name)
+		if *dryRun {
+			pods, err := u.podsToEvict(ctx, name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			for _, pod := range pods {
+				fmt.Printf("  将驱逐 pod %s/%s\n", pod.Namespace, pod.Name)
+			}
+			continue
+		}
+		if err := u.upgrade(ctx, name); err != nil {
+			fmt.Fprintf(os.Stderr, "升级节点 %s 失败: %v\n", name, err)
+			fmt.Fprintf(os.Stderr, "节点 %s 保持封锁；排查后用 --nodes %s 继续，或 PATCH spec.unschedulable=false 解除封锁\n",
+				name, strings.Join(nodes[i:], ","))
+			return 1
+		}
+	}
+	if *dryRun {
+		fmt.Println("dry-run：未封锁节点，未驱逐 Pod")
+		return 0
+	}
+	fmt.Printf("%d 个节点升级完成\n", len(nodes))
+	return 0
+}
+
+// nodeOrder 返回升级顺序：--nodes 指定的节点（必须存在），否则所有节点按名称排序
+func (u *nodeUpgrader) nodeOrder(ctx context.Context, nodesFlag string) ([]string, error) {
+	list, err := u.cs.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("列出节点失败: %w", err)
+	}
+	known := make(map[string]bool, len(list.Items))
+	var all []string
+	for _, node := range list.Items {
+		known[node.Name] = true
+		all = append(all, node.Name)
+	}
+	if strings.TrimSpace(nodesFlag) == "" {
+		sort.Strings(all)
+		return all, nil
+	}
+	var nodes []string
+	for _, name := range strings.Split(nodesFlag, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("节点 %s 不存在", name)
+		}
+		nodes = append(nodes, name)
+	}
+	return nodes, nil
+}
+
+// upgrade 升级一个节点；失败时节点保持封锁
+func (u *nodeUpgrader) upgrade(ctx context.Context, name string) error {
+	pods, err := u.podsToEvict(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := u.setUnschedulable(ctx, name, true); err != nil {
+		return fmt.Errorf("封锁节点失败: %w", err)
+	}
+	fmt.Printf("  已封锁 node/%s，驱逐 %d 个 Pod\n", name, len(pods))
+
+	owners := map[types.NamespacedName]bool{}
+	for _, pod := range pods {
+		deployment, err := u.waitDeploymentBudget(ctx, pod)
+		if err != nil {
+			return err
+		}
+		if err := u.evict(ctx, pod); err != nil {
+			return err
+		}
+		if deployment != nil {
+			owners[types.NamespacedName{Namespace: deployment.Namespace, Name: deployment.Name}] = true
+		}
+		fmt.Printf("  已驱逐 pod %s/%s\n", pod.Namespace, pod.Name)
+	}
+
+	fmt.Printf("  执行升级命令...\n")
+	if err := u.runCommand(name); err != nil {
+		return err
+	}
+	// 心跳时间精确到秒，要求命令结束之后的下一秒及以后的心跳，确认是升级后的 agent 上报的
+	finished := time.Now().Truncate(time.Second).Add(time.Second)
+	if err := u.waitRejoined(ctx, name, finished); err != nil {
+		return err
+	}
+	fmt.Printf("  node/%s 已重新加入集群\n", name)
+
+	if err := u.setUnschedulable(ctx, name, false); err != nil {
+		return fmt.Errorf("解除封锁失败: %w", err)
+	}
+	fmt.Printf("  已解除封锁 node/%s\n", name)
+	return u.waitDeploymentsAvailable(ctx, owners)
+}
+
+// podsToEvict 返回节点上需要驱逐的 Pod：跳过已结束的 Pod、static Pod 的 mirror 与 DaemonSet 的 Pod（它们属于节点本身）
+func (u *nodeUpgrader) podsToEvict(ctx context.Context, node string) ([]*corev1.Pod, error) {
+	list, err := u.cs.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("列出 Pod 失败: %w", err)
+	}
+	var pods []*corev1.Pod
+	for i := range list.Items {
+		pod := &list.Items[i]
+		if pod.Spec.NodeName != node || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if _, mirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; mirror {
+			continue
+		}
+		if ref := metav1.GetControllerOf(pod); ref != nil && ref.Kind == "DaemonSet" {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+	return pods, nil
+}
+
+// setUnschedulable 设置节点的 spec.unschedulable（封锁后调度器不再向节点放置新的 Pod）
+func (u *nodeUpgrader) setUnschedulable(ctx context.Context, name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	_, err := u.cs.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// waitDeploymentBudget 等待驱逐 pod 后其 Deployment 的不可用副本不超过 maxUnavailable（默认 25%，向下取整，至少 1），
+// 返回 pod 所属的 Deployment（不属于 Deployment 时为 nil）
+func (u *nodeUpgrader) waitDeploymentBudget(ctx context.Context, pod *corev1.Pod) (*appsv1.Deployment, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil || ref.Kind != "Deployment" {
+		return nil, nil
+	}
+	deployment, err := u.cs.AppsV1().Deployments(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		// Deployment 已被删除，按普通 Pod 驱逐
+		return nil, nil
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	maxUnavailable := intstr.FromString("25%")
+	if ru := deployment.Spec.Strategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil {
+		maxUnavailable = *ru.MaxUnavailable
+	}
+	allowed, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, int(replicas), false)
+	if err != nil {
+		return nil, fmt.Errorf("deployment %s/%s 的 maxUnavailable 无效: %w", deployment.Namespace, deployment.Name, err)
+	}
+	allowed = max(allowed, 1)
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil || deployment.Spec.Selector == nil {
+		return deployment, nil
+	}
+	err = u.poll(ctx, fmt.Sprintf("deployment %s/%s 的可用副本", deployment.Namespace, deployment.Name), func() (bool, error) {
+		list, err := u.cs.CoreV1().Pods(pod.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+		ready := 0
+		for i := range list.Items {
+			if p := &list.Items[i]; p.Name != pod.Name && podIsReady(p) {
+				ready++
+			}
+		}
+		return int(replicas)-ready <= allowed, nil
+	})
+	return deployment, err
+}
+
+// evict 通过 eviction 子资源驱逐 Pod；违反 PodDisruptionBudget（429）时等待后重试，直到超时
+func (u *nodeUpgrader) evict(ctx context.Context, pod *corev1.Pod) error {
+	url := fmt.Sprintf("%s/api/v1/namespaces/%s/pods/%s/eviction", u.base, pod.Namespace, pod.Name)
+	body := fmt.Sprintf(`{"apiVersion":"policy/v1","kind":"Eviction","metadata":{"name":%q,"namespace":%q}}`, pod.Name, pod.Namespace)
+	blocked := false
+	return u.poll(ctx, fmt.Sprintf("驱逐 pod %s/%s", pod.Namespace, pod.Name), func() (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := u.http.Do(req)
+		if err != nil {
+			return false, err
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusCreated, http.StatusNotFound:
+			return true, nil
+		case http.StatusTooManyRequests:
+			if !blocked {
+				fmt.Printf("  pod %s/%s 的驱逐受 PodDisruptionBudget 限制，等待重试...\n", pod.Namespace, pod.Name)
+				blocked = true
+			}
+			return false, nil
+		default:
+			return false, fmt.Errorf("驱逐 pod %s/%s 返回 HTTP %d: %s", pod.Namespace, pod.Name, resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+	})
+}
+
+// runCommand 执行升级命令，{node} 替换为节点名
+func (u *nodeUpgrader) runCommand(node string) error {
+	command := strings.ReplaceAll(u.command, "{node}", node)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("升级命令失败: %w", err)
+	}
+	return nil
+}
+
+// waitRejoined 等待节点重新加入集群：Ready 且心跳不早于 since（升级期间 apiserver 可能短暂不可用，请求失败时继续等待）
+func (u *nodeUpgrader) waitRejoined(ctx context.Context, name string, since time.Time) error {
+	return u.poll(ctx, fmt.Sprintf("node/%s 重新加入集群", name), func() (bool, error) {
+		node, err := u.cs.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady {
+				return cond.Status == corev1.ConditionTrue && !cond.LastHeartbeatTime.Time.Before(since), nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// waitDeploymentsAvailable 等待被驱逐的 Deployment 处理完最新的 generation 且所有副本可用
+func (u *nodeUpgrader) waitDeploymentsAvailable(ctx context.Context, owners map[types.NamespacedName]bool) error {
+	for key := range owners {
+		err := u.poll(ctx, fmt.Sprintf("deployment %s 恢复可用", key), func() (bool, error) {
+			d, err := u.cs.AppsV1().Deployments(key.Namespace).Get(ctx, key.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, nil
+			}
+			replicas := int32(1)
+			if d.Spec.Replicas != nil {
+				replicas = *d.Spec.Replicas
+			}
+			return d.Status.ObservedGeneration >= d.Generation && d.Status.AvailableReplicas >= replicas, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// poll 每隔 upgradePollInterval 检查一次 cond，直到满足、返回错误或超过 --timeout
+func (u *nodeUpgrader) poll(ctx context.Context, desc string, cond func() (bool, error)) error {
+	deadline := time.Now().Add(u.timeout)
+	for {
+		ok, err := cond()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待%s超时（%s）", desc, u.timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(upgradePollInterval):
+		}
+	}
+}
+
+// podIsReady 判断 Pod 的 Ready 条件是否为 True
+func podIsReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
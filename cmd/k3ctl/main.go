@@ -0,0 +1,70 @@
+// k3ctl 是只访问 apiserver 的轻量客户端：与 k3 的客户端子命令相同（见 internal/cli），
+// 不包含 fx、存储与控制器，可以单独安装在运维人员的机器上
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/cli"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/version"
+)
+
+func main() {
+	cli.Program = "k3ctl"
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "apply":
+		os.Exit(cli.Apply(os.Args[2:]))
+	case "get":
+		os.Exit(cli.Get(os.Args[2:]))
+	case "logs":
+		os.Exit(cli.Logs(os.Args[2:]))
+	case "rollout":
+		os.Exit(cli.Rollout(os.Args[2:]))
+	case "history":
+		os.Exit(cli.History(os.Args[2:]))
+	case "wait":
+		os.Exit(cli.Wait(os.Args[2:]))
+	case "config":
+		os.Exit(cli.Config(os.Args[2:]))
+	case "explain":
+		os.Exit(cli.Explain(os.Args[2:]))
+	case "version":
+		fmt.Printf("k3ctl %s\n", version.Version)
+	case "-h", "--help", "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Println(strings.TrimSpace(`
+Usage:
+  k3ctl <command> [flags]
+
+Commands:
+  apply                 将 Kubernetes YAML/JSON 提交到 apiserver（三方合并，与 kubectl apply 相同）
+  get                   列出资源（READY/STATUS/RESTARTS/AGE 等列由 apiserver 计算；支持 -n/-A、-l 与 -o wide）
+  logs                  打印 Pod 中容器的日志（-c 容器、-f 持续输出、--tail、--since）
+  rollout status        等待 Deployment 发布完成（基于 generation/observedGeneration 与副本状态）
+  rollout pause/resume  暂停/恢复 Deployment 的发布
+  history               查看对象保留的版本历史
+  wait                  基于 watch 等待资源满足条件（--for=condition=Ready 或 --for=delete）
+  config view|set|unset 查看与修改客户端配置（~/.k3/k3ctl.yaml 中的 server 与默认 namespace）
+  explain               列出资源字段的类型、说明与 k3 的支持程度（例如 explain pod.spec.containers）
+  version               打印 k3ctl 版本
+
+Flags:
+  --server <url>        apiserver 地址（默认取客户端配置的 server，其次是 k3 配置的 cluster.server）
+  -n <namespace>        namespace（默认取客户端配置的 namespace，否则为 default）
+`))
+}
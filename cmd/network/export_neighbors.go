@@ -8,19 +8,18 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
-	"regexp"
-	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/network"
 	"sigs.k8s.io/yaml"
 )
 
 // export：简化版，不做端口扫描/探测。
 // 仅从系统 ARP/neighbor 表导出（同 WiFi/同局域网里已被系统“学到”的邻居），并输出 IP + 设备名称（best-effort）。
+// 邻居表的读取与解析在 internal/network 中，与 controller 的 LAN inventory 共用。
 
 type exportDevice struct {
 	IP   string `json:"ip" yaml:"ip"`
@@ -85,7 +84,7 @@ func runExport(args []string) int {
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
-	targetCIDRs, err := computeTargetCIDRs([]string(cidrs))
+	targetCIDRs, err := network.TargetSubnets([]string(cidrs))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "export: 计算网段失败: %v\n", err)
 		return 1
@@ -95,7 +94,7 @@ func runExport(args []string) int {
 		return 1
 	}
 
-	neighbors, err := readNeighborTable(ctx)
+	neighbors, err := network.ReadNeighborTable(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "export: 读取邻居表失败: %v\n", err)
 		return 1
@@ -107,12 +106,12 @@ func runExport(args []string) int {
 		if ip4 == nil {
 			continue
 		}
-		if !ipInAnyCIDR(ip4, targetCIDRs) {
+		if !network.InSubnets(ip4, targetCIDRs) {
 			continue
 		}
-		name := normalizeHostToken(n.Name)
+		name := network.NormalizeHostToken(n.Name)
 		if name == "" && *resolveDNS {
-			name = reverseLookup(ctx, ip4.String(), *dnsTimeout)
+			name = network.ReverseLookup(ctx, ip4.String(), *dnsTimeout)
 		}
 		// Fallback: if we still don't have a human name, use MAC as a stable identifier.
 		if strings.TrimSpace(name) == "" {
@@ -127,9 +126,7 @@ func runExport(args []string) int {
 
 	cidrStrings := make([]string, 0, len(targetCIDRs))
 	for _, c := range targetCIDRs {
-		if c.Net != nil {
-			cidrStrings = append(cidrStrings, c.Net.String())
-		}
+		cidrStrings = append(cidrStrings, c.String())
 	}
 
 	res := exportResult{
@@ -184,213 +181,6 @@ func runExport(args []string) int {
 	return 0
 }
 
-type cidrTarget struct {
-	Net    *net.IPNet
-	IfName string
-	IfIP   net.IP
-	Source string // auto|flag
-}
-
-func computeTargetCIDRs(userCIDRs []string) ([]cidrTarget, error) {
-	if len(userCIDRs) > 0 {
-		var out []cidrTarget
-		for _, s := range userCIDRs {
-			_, n, err := net.ParseCIDR(strings.TrimSpace(s))
-			if err != nil {
-				return nil, err
-			}
-			if n == nil || n.IP == nil || n.IP.To4() == nil {
-				continue
-			}
-			out = append(out, cidrTarget{Net: n, Source: "flag"})
-		}
-		return dedupeCIDRs(out), nil
-	}
-	auto, err := localIPv4CIDRs()
-	if err != nil {
-		return nil, err
-	}
-	return dedupeCIDRs(auto), nil
-}
-
-func dedupeCIDRs(in []cidrTarget) []cidrTarget {
-	seen := map[string]struct{}{}
-	out := make([]cidrTarget, 0, len(in))
-	for _, c := range in {
-		if c.Net == nil {
-			continue
-		}
-		k := c.Net.String()
-		if _, ok := seen[k]; ok {
-			continue
-		}
-		seen[k] = struct{}{}
-		out = append(out, c)
-	}
-	return out
-}
-
-func localIPv4CIDRs() ([]cidrTarget, error) {
-	ifaces, err := net.Interfaces()
-	if err != nil {
-		return nil, err
-	}
-	out := []cidrTarget{}
-	for _, iface := range ifaces {
-		if iface.Flags&net.FlagUp == 0 {
-			continue
-		}
-		if iface.Flags&net.FlagLoopback != 0 {
-			continue
-		}
-		addrs, err := iface.Addrs()
-		if err != nil {
-			continue
-		}
-		for _, a := range addrs {
-			ipnet, ok := a.(*net.IPNet)
-			if !ok || ipnet == nil || ipnet.IP == nil {
-				continue
-			}
-			ip4 := ipnet.IP.To4()
-			if ip4 == nil || ip4.IsLoopback() {
-				continue
-			}
-			nip := ip4.Mask(ipnet.Mask)
-			n := &net.IPNet{IP: nip, Mask: ipnet.Mask}
-			out = append(out, cidrTarget{
-				Net:    n,
-				IfName: iface.Name,
-				IfIP:   ip4,
-				Source: "auto",
-			})
-		}
-	}
-	return out, nil
-}
-
-type neighborEntry struct {
-	IP   string
-	Name string // best-effort, may be "?"
-	MAC  string // best-effort
-}
-
-var (
-	arpDarwinRe = regexp.MustCompile(`^(\S+)\s+\((\d+\.\d+\.\d+\.\d+)\)\s+at\s+(.+?)\s+on\s+(\S+)`)
-	ipNeighRe   = regexp.MustCompile(`^(\d+\.\d+\.\d+\.\d+)\s+dev\s+(\S+)\b`)
-	macRe       = regexp.MustCompile(`(?i)([0-9a-f]{2}:){5}[0-9a-f]{2}`)
-)
-
-func readNeighborTable(ctx context.Context) ([]neighborEntry, error) {
-	switch runtime.GOOS {
-	case "darwin":
-		out, err := exec.CommandContext(ctx, "arp", "-a").Output()
-		if err != nil {
-			return nil, err
-		}
-		return parseDarwinARP(string(out)), nil
-	case "linux":
-		out, err := exec.CommandContext(ctx, "sh", "-c", "ip neigh show 2>/dev/null || arp -a 2>/dev/null").Output()
-		if err != nil {
-			return nil, err
-		}
-		return parseLinuxNeighbors(string(out)), nil
-	default:
-		out, err := exec.CommandContext(ctx, "arp", "-a").Output()
-		if err != nil {
-			return nil, err
-		}
-		return parseDarwinARP(string(out)), nil
-	}
-}
-
-func parseDarwinARP(out string) []neighborEntry {
-	lines := strings.Split(out, "\n")
-	entries := make([]neighborEntry, 0, len(lines))
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		m := arpDarwinRe.FindStringSubmatch(line)
-		if len(m) < 4 {
-			continue
-		}
-		host := strings.TrimSpace(m[1])
-		ip := strings.TrimSpace(m[2])
-		hw := strings.ToLower(strings.TrimSpace(m[3]))
-		// Skip unresolved neighbors like: "at (incomplete)".
-		if strings.Contains(hw, "incomplete") {
-			continue
-		}
-		mac := strings.ToLower(macRe.FindString(hw))
-		if net.ParseIP(ip).To4() == nil {
-			continue
-		}
-		entries = append(entries, neighborEntry{IP: ip, Name: host, MAC: mac})
-	}
-	return entries
-}
-
-func parseLinuxNeighbors(out string) []neighborEntry {
-	lines := strings.Split(out, "\n")
-	entries := make([]neighborEntry, 0, len(lines))
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		upper := strings.ToUpper(line)
-		if strings.Contains(upper, " INCOMPLETE") || strings.Contains(upper, " FAILED") {
-			continue
-		}
-		mac := strings.ToLower(macRe.FindString(line))
-		if m := ipNeighRe.FindStringSubmatch(line); len(m) >= 2 {
-			ip := strings.TrimSpace(m[1])
-			if net.ParseIP(ip).To4() == nil {
-				continue
-			}
-			entries = append(entries, neighborEntry{IP: ip, Name: "", MAC: mac})
-			continue
-		}
-		if m := arpDarwinRe.FindStringSubmatch(line); len(m) >= 3 {
-			ip := strings.TrimSpace(m[2])
-			host := strings.TrimSpace(m[1])
-			if net.ParseIP(ip).To4() == nil {
-				continue
-			}
-			entries = append(entries, neighborEntry{IP: ip, Name: host, MAC: mac})
-			continue
-		}
-	}
-	return entries
-}
-
-func normalizeHostToken(s string) string {
-	s = strings.TrimSpace(s)
-	if s == "" || s == "?" {
-		return ""
-	}
-	return strings.TrimSuffix(s, ".")
-}
-
-func reverseLookup(ctx context.Context, ip string, timeout time.Duration) string {
-	if strings.TrimSpace(ip) == "" {
-		return ""
-	}
-	if timeout <= 0 {
-		timeout = 250 * time.Millisecond
-	}
-	cctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-	r := net.Resolver{}
-	names, err := r.LookupAddr(cctx, ip)
-	if err != nil || len(names) == 0 {
-		return ""
-	}
-	return strings.TrimSuffix(strings.TrimSpace(names[0]), ".")
-}
-
 func dedupeDevices(in []exportDevice) []exportDevice {
 	seen := map[string]exportDevice{}
 	for _, d := range in {
@@ -418,39 +208,6 @@ func dedupeDevices(in []exportDevice) []exportDevice {
 	return out
 }
 
-func ipInAnyCIDR(ip net.IP, cidrs []cidrTarget) bool {
-	for _, c := range cidrs {
-		if c.Net != nil && c.Net.Contains(ip) && !isNetworkOrBroadcastIPv4(ip, c.Net) {
-			return true
-		}
-	}
-	return false
-}
-
-func isNetworkOrBroadcastIPv4(ip net.IP, n *net.IPNet) bool {
-	if n == nil || n.IP == nil || n.Mask == nil {
-		return false
-	}
-	ip4 := ip.To4()
-	net4 := n.IP.To4()
-	if ip4 == nil || net4 == nil {
-		return false
-	}
-	ones, bits := n.Mask.Size()
-	if bits != 32 {
-		return false
-	}
-	// /31,/32 don't have meaningful broadcast semantics for our filtering.
-	if ones >= 31 {
-		return false
-	}
-	netU := binary.BigEndian.Uint32(net4.Mask(n.Mask))
-	maskU := binary.BigEndian.Uint32(n.Mask)
-	bcastU := netU | ^maskU
-	u := binary.BigEndian.Uint32(ip4)
-	return u == netU || u == bcastU
-}
-
 func formatCmdField(s string) string {
 	s = strings.TrimSpace(s)
 	if s == "" {
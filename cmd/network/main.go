@@ -14,6 +14,7 @@ import (
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/network"
 	"go.uber.org/fx"
@@ -53,7 +54,7 @@ func runDaemon(args []string) int {
 	service := fs.String("service", "_k3._tcp", "mDNS service name")
 	domain := fs.String("domain", "local.", "mDNS domain (通常为 local.)")
 	nodeName := fs.String("node-name", "", "节点名称（默认 NODE_NAME 或 hostname）")
-	peerTTL := fs.Duration("peer-ttl", 90*time.Second, "peer 过期时间（超过则标记 NotReady）")
+	peerTTL := fs.Duration("peer-ttl", 0, "peer 过期时间（超过则标记 NotReady），为 0 时使用配置 network.peer_ttl（默认 90s）")
 	registerSelf := fs.Bool("register-self", true, "同时把当前节点也注册到 store（若 controller 已上报该节点，则不会覆盖）")
 
 	if err := fs.Parse(args); err != nil {
@@ -66,15 +67,20 @@ func runDaemon(args []string) int {
 		fx.Provide(
 			bootstrap.ProvideDBContainerHandle,
 			bootstrap.ProvideStore,
-			func() network.Settings {
-				return network.Settings{
-					ListenAddr:   *listen,
-					Service:      *service,
-					Domain:       *domain,
-					PeerTTL:      *peerTTL,
-					NodeName:     *nodeName,
-					RegisterSelf: *registerSelf,
+			func(cfg config.Config) (network.Settings, error) {
+				if *peerTTL > 0 {
+					cfg.Network.PeerTTL = peerTTL.String()
 				}
+				s, err := network.SettingsFromConfig(cfg.Network)
+				if err != nil {
+					return s, err
+				}
+				s.ListenAddr = *listen
+				s.Service = *service
+				s.Domain = *domain
+				s.NodeName = *nodeName
+				s.RegisterSelf = *registerSelf
+				return s, nil
 			},
 			network.NewService,
 		),
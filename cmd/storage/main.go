@@ -127,14 +127,15 @@ func StartStorageService(
 				l.Info("存储连接已关闭")
 			}
 
-			// 如果 DB 容器由本进程拉起，则在退出时清理
-			if handle != nil && handle.Started && handle.Runtime != nil && handle.Pod != nil {
+			// 停止 DB 容器监控；如果 DB 容器由本进程拉起，则在退出时清理
+			started := handle != nil && handle.Started && handle.Runtime != nil && handle.Pod != nil
+			if started {
 				l.Infof("清理由本进程拉起的存储后端容器 (runtime=%s)...", handle.Runtime.Name())
-				if err := handle.Runtime.StopContainer(context.Background(), handle.Pod); err != nil {
-					l.Warnf("清理容器失败: %v", err)
-				} else {
-					l.Info("存储后端容器已清理")
-				}
+			}
+			if err := handle.Stop(context.Background()); err != nil {
+				l.Warnf("清理容器失败: %v", err)
+			} else if started {
+				l.Info("存储后端容器已清理")
 			}
 
 			l.Info("存储服务已关闭")
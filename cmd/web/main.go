@@ -11,11 +11,13 @@ import (
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/api"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterconfig"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/idle"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/service"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
@@ -31,9 +33,11 @@ func main() {
 			bootstrap.ProvideStore,
 		),
 		controller.Module,
+		clusterconfig.Module,
 		service.Modules,
 		api.Modules,
 		apiserver.Module,
+		idle.Module,
 	)
 
 	app := fx.New(modules,
@@ -130,9 +134,7 @@ func StartWebStack(
 			if closer, ok := store.(interface{ Close() error }); ok {
 				_ = closer.Close()
 			}
-			if handle != nil && handle.Started && handle.Runtime != nil && handle.Pod != nil {
-				_ = handle.Runtime.StopContainer(context.Background(), handle.Pod)
-			}
+			_ = handle.Stop(context.Background())
 			return nil
 		},
 	})
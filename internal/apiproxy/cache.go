@@ -0,0 +1,163 @@
+package apiproxy
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/client"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// cacheResync informer 重新 List 的周期
+const cacheResync = 10 * time.Minute
+
+// cachedResource 一种由本地 informer 缓存提供读取的资源
+type cachedResource struct {
+	gvk        schema.GroupVersionKind
+	namespaced bool
+	synced     func() bool
+	list       func() []client.Object
+	get        func(namespace, name string) (client.Object, bool)
+}
+
+func newCachedResource[T client.Object, L any](gvk schema.GroupVersionKind, namespaced bool, inf *client.Informer[T, L]) *cachedResource {
+	return &cachedResource{
+		gvk:        gvk,
+		namespaced: namespaced,
+		synced:     inf.HasSynced,
+		list: func() []client.Object {
+			items := inf.List()
+			out := make([]client.Object, 0, len(items))
+			for _, it := range items {
+				out = append(out, it)
+			}
+			return out
+		},
+		get: func(namespace, name string) (client.Object, bool) {
+			return inf.Get(namespace, name)
+		},
+	}
+}
+
+// cache 节点本地的 informer 缓存，覆盖 pkg/client 支持的 core/v1 与 apps/v1 资源
+type cache struct {
+	factory   *client.InformerFactory
+	resources map[string]*cachedResource // 键为 resourceKey(group, version, resource)
+}
+
+func newCache(cs client.Interface) *cache {
+	f := client.NewInformerFactory(cs, cacheResync)
+	core := schema.GroupVersion{Version: "v1"}
+	apps := schema.GroupVersion{Group: "apps", Version: "v1"}
+	c := &cache{factory: f, resources: map[string]*cachedResource{
+		resourceKey("", "v1", "pods"):             newCachedResource(core.WithKind("Pod"), true, f.Pods()),
+		resourceKey("", "v1", "services"):         newCachedResource(core.WithKind("Service"), true, f.Services()),
+		resourceKey("", "v1", "configmaps"):       newCachedResource(core.WithKind("ConfigMap"), true, f.ConfigMaps()),
+		resourceKey("", "v1", "secrets"):          newCachedResource(core.WithKind("Secret"), true, f.Secrets()),
+		resourceKey("", "v1", "nodes"):            newCachedResource(core.WithKind("Node"), false, f.Nodes()),
+		resourceKey("apps", "v1", "deployments"):  newCachedResource(apps.WithKind("Deployment"), true, f.Deployments()),
+		resourceKey("apps", "v1", "statefulsets"): newCachedResource(apps.WithKind("StatefulSet"), true, f.StatefulSets()),
+		resourceKey("apps", "v1", "daemonsets"):   newCachedResource(apps.WithKind("DaemonSet"), true, f.DaemonSets()),
+	}}
+	return c
+}
+
+// lookup 返回请求对应的缓存资源，不在缓存中时返回 nil
+func (c *cache) lookup(p apiPath) *cachedResource {
+	return c.resources[resourceKey(p.group, p.version, p.resource)]
+}
+
+// resourceVersion 返回缓存中对象的 resourceVersion，不在缓存中时返回空
+func (c *cache) resourceVersion(p apiPath) string {
+	res := c.lookup(p)
+	if res == nil || p.name == "" || !res.synced() {
+		return ""
+	}
+	obj, ok := res.get(p.namespace, p.name)
+	if !ok {
+		return ""
+	}
+	return obj.GetResourceVersion()
+}
+
+// synced 返回所有资源的首次 List 是否都已完成
+func (c *cache) synced() bool {
+	for _, res := range c.resources {
+		if !res.synced() {
+			return false
+		}
+	}
+	return true
+}
+
+func resourceKey(group, version, resource string) string {
+	return group + "/" + version + "/" + resource
+}
+
+// apiPath 解析后的 apiserver 请求路径
+type apiPath struct {
+	group       string
+	version     string
+	resource    string
+	namespace   string
+	name        string
+	subresource string
+	watch       bool
+}
+
+// parseAPIPath 解析 /api/v1/... 与 /apis/<group>/<version>/... 形式的资源路径，
+// 支持 watch 前缀、namespaces/<ns> 与子资源；不是资源路径（如 /api/readyz）时返回 false
+func parseAPIPath(path string) (apiPath, bool) {
+	var p apiPath
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(segs) >= 3 && segs[0] == "api":
+		p.version, segs = segs[1], segs[2:]
+	case len(segs) >= 4 && segs[0] == "apis":
+		p.group, p.version, segs = segs[1], segs[2], segs[3:]
+	default:
+		return p, false
+	}
+	if segs[0] == "watch" {
+		p.watch, segs = true, segs[1:]
+	}
+	if len(segs) >= 3 && segs[0] == "namespaces" {
+		p.namespace, segs = segs[1], segs[2:]
+	}
+	if len(segs) == 0 || len(segs) > 3 {
+		return p, false
+	}
+	p.resource = segs[0]
+	if len(segs) > 1 {
+		p.name = segs[1]
+	}
+	if len(segs) > 2 {
+		p.subresource = segs[2]
+	}
+	for _, s := range segs {
+		if s == "" {
+			return p, false
+		}
+	}
+	return p, true
+}
+
+// objectPath 返回对象自身（不含 watch 与子资源）的路径
+func (p apiPath) objectPath() string {
+	var b strings.Builder
+	if p.group == "" {
+		b.WriteString("/api/" + p.version)
+	} else {
+		b.WriteString("/apis/" + p.group + "/" + p.version)
+	}
+	if p.namespace != "" {
+		b.WriteString("/namespaces/" + p.namespace)
+	}
+	b.WriteString("/" + p.resource + "/" + p.name)
+	return b.String()
+}
+
+// objectKey 标识队列中写请求作用的对象
+func (p apiPath) objectKey() string {
+	return resourceKey(p.group, p.version, p.resource) + "/" + p.namespace + "/" + p.name
+}
@@ -0,0 +1,462 @@
+package apiproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/client"
+	"go.uber.org/fx"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	defaultListen         = "127.0.0.1:8081"
+	defaultMaxQueue       = 100
+	defaultQueueTTL       = 5 * time.Minute
+	defaultHealthInterval = 5 * time.Second
+	// requestTimeout 转发写请求、重放与健康检查的超时时间
+	requestTimeout = 30 * time.Second
+	// maxBodySize 写请求体的大小上限
+	maxBodySize = 4 << 20
+
+	// CacheHeader 由本地缓存提供的响应带有该头（值为 hit）
+	CacheHeader = "X-K3-Proxy-Cache"
+	// QueuedHeader 写请求进入队列时响应（202）带有该头，值为队列中的序号
+	QueuedHeader = "X-K3-Proxy-Queued"
+)
+
+// Module 在本节点启动 apiserver 缓存代理（配置 api_proxy.enabled 时）
+var Module = fx.Options(
+	fx.Invoke(func(lc fx.Lifecycle, cfg config.Config, logger logprovider.Logger) error {
+		p, err := NewProxy(logger, cfg.APIProxy, cfg.Cluster.Server)
+		if err != nil || p == nil {
+			return err
+		}
+		lc.Append(fx.Hook{
+			OnStart: p.Start,
+			OnStop:  p.Stop,
+		})
+		return nil
+	}),
+)
+
+// Proxy 节点本地的 apiserver 代理：core/v1 与 apps/v1 常用资源的 GET/LIST 由 informer 缓存提供（apiserver 不可用时继续提供缓存内容），
+// watch 与其他请求转发到 apiserver；apiserver 不可用时写请求进入队列并返回 202，恢复后按顺序重放，
+// 重放前对象的 resourceVersion 与排队时不同或对象已被删除时记为冲突并丢弃，不覆盖别人的修改
+type Proxy struct {
+	logger         logprovider.Logger
+	listen         string
+	upstream       *url.URL
+	queueTTL       time.Duration
+	healthInterval time.Duration
+	client         *http.Client // 写请求、重放与健康检查
+	reverse        *httputil.ReverseProxy
+	cache          *cache
+	queue          *writeQueue
+	httpServer     *http.Server
+
+	healthy atomic.Bool
+	wake    chan struct{} // 有新的排队请求时唤醒重放循环
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewProxy 创建代理，cfg.Enabled 为 false 时返回 nil；cfg.Upstream 为空时使用 clusterServer
+func NewProxy(logger logprovider.Logger, cfg config.APIProxyConfig, clusterServer string) (*Proxy, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	upstream := strings.TrimRight(strings.TrimSpace(cfg.Upstream), "/")
+	if upstream == "" {
+		upstream = strings.TrimRight(strings.TrimSpace(clusterServer), "/")
+	}
+	if upstream == "" {
+		return nil, fmt.Errorf("api_proxy.upstream 与 cluster.server 都为空")
+	}
+	u, err := url.Parse(upstream)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("api_proxy.upstream %q 无效", upstream)
+	}
+
+	p := &Proxy{
+		logger:         logger,
+		listen:         cfg.Listen,
+		upstream:       u,
+		queueTTL:       defaultQueueTTL,
+		healthInterval: defaultHealthInterval,
+		queue:          newWriteQueue(cfg.MaxQueue),
+		wake:           make(chan struct{}, 1),
+		done:           make(chan struct{}),
+	}
+	if p.listen == "" {
+		p.listen = defaultListen
+	}
+	if cfg.MaxQueue <= 0 {
+		p.queue.max = defaultMaxQueue
+	}
+	if cfg.QueueTTL != "" {
+		if p.queueTTL, err = time.ParseDuration(cfg.QueueTTL); err != nil {
+			return nil, fmt.Errorf("api_proxy.queue_ttl 无效: %w", err)
+		}
+	}
+	if cfg.HealthInterval != "" {
+		if p.healthInterval, err = time.ParseDuration(cfg.HealthInterval); err != nil || p.healthInterval <= 0 {
+			return nil, fmt.Errorf("api_proxy.health_interval 无效: %q", cfg.HealthInterval)
+		}
+	}
+
+	// 请求自带 Authorization 时使用请求自己的身份，否则使用 token
+	transport := &bearerTransport{
+		token: cfg.Token,
+		base: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+			MaxIdleConnsPerHost: 8,
+		},
+	}
+	p.client = &http.Client{Transport: transport, Timeout: requestTimeout}
+	p.reverse = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(u)
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if r.Context().Err() != nil {
+				return
+			}
+			p.setHealthy(false, err.Error())
+			writeUnavailable(w, fmt.Sprintf("apiserver 不可用: %v", err))
+		},
+	}
+	// informer 使用不带超时的 client（watch 是长连接）
+	cs, err := client.NewForConfig(&client.Config{Host: upstream, HTTPClient: &http.Client{Transport: transport}})
+	if err != nil {
+		return nil, err
+	}
+	p.cache = newCache(cs)
+
+	p.healthy.Store(true)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.httpServer = &http.Server{Handler: p, ReadHeaderTimeout: 10 * time.Second}
+	return p, nil
+}
+
+// Start 开始监听并启动 informer 与重放循环
+func (p *Proxy) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", p.listen)
+	if err != nil {
+		return fmt.Errorf("apiserver 代理监听 %s 失败: %w", p.listen, err)
+	}
+	p.logger.Infof("apiserver 缓存代理已启动: %s -> %s", ln.Addr(), p.upstream)
+	p.cache.factory.Start(p.ctx.Done())
+	go p.run()
+	go func() {
+		if err := p.httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.logger.Errorf("apiserver 代理服务退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop 停止服务；队列中尚未重放的写请求会丢失
+func (p *Proxy) Stop(ctx context.Context) error {
+	p.cancel()
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+	}
+	if n := p.queue.len(); n > 0 {
+		p.logger.Warnf("apiserver 代理退出时仍有 %d 个写请求没有重放", n)
+	}
+	return p.httpServer.Shutdown(ctx)
+}
+
+// ServeHTTP 处理客户端请求：/proxy/status 返回代理状态，缓存资源的 GET 由缓存提供，写请求在 apiserver 不可用时排队，其余转发
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/proxy/status" {
+		p.handleStatus(w, r)
+		return
+	}
+	target, ok := parseAPIPath(r.URL.Path)
+	switch r.Method {
+	case http.MethodGet:
+		if ok && p.serveFromCache(w, r, target) {
+			return
+		}
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		if ok && !target.watch {
+			p.handleWrite(w, r, target)
+			return
+		}
+	}
+	p.reverse.ServeHTTP(w, r)
+}
+
+// serveFromCache 缓存中有请求的资源且已完成首次同步时由缓存响应并返回 true。
+// watch、子资源、fieldSelector 等缓存无法处理的参数返回 false，转发到 apiserver；
+// 缓存中没有的对象可能刚刚创建，apiserver 可用时同样转发，不可用时返回 404
+func (p *Proxy) serveFromCache(w http.ResponseWriter, r *http.Request, target apiPath) bool {
+	if target.watch || target.subresource != "" {
+		return false
+	}
+	res := p.cache.lookup(target)
+	if res == nil || !res.synced() || (target.namespace != "" && !res.namespaced) {
+		return false
+	}
+	query := r.URL.Query()
+	for key, values := range query {
+		switch key {
+		case "labelSelector":
+		case "resourceVersion":
+			// 只有不要求一致性的读取（resourceVersion 为空或 0）可以使用缓存
+			if v := values[0]; v != "" && v != "0" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	selector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		return false
+	}
+
+	if target.name != "" {
+		if res.namespaced && target.namespace == "" {
+			return false
+		}
+		obj, ok := res.get(target.namespace, target.name)
+		if !ok {
+			if p.healthy.Load() {
+				return false
+			}
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": fmt.Sprintf("resource not found: %s", target.name)})
+			return true
+		}
+		data, err := json.Marshal(withKind(obj, res))
+		if err != nil {
+			return false
+		}
+		w.Header().Set(CacheHeader, "hit")
+		writeJSON(w, http.StatusOK, json.RawMessage(data))
+		return true
+	}
+
+	list := &metav1.List{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"},
+		Items:    []runtime.RawExtension{},
+	}
+	for _, obj := range res.list() {
+		if target.namespace != "" && obj.GetNamespace() != target.namespace {
+			continue
+		}
+		if !selector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		data, err := json.Marshal(withKind(obj, res))
+		if err != nil {
+			continue
+		}
+		list.Items = append(list.Items, runtime.RawExtension{Raw: data})
+	}
+	w.Header().Set(CacheHeader, "hit")
+	writeJSON(w, http.StatusOK, list)
+	return true
+}
+
+// withKind 返回补全了 apiVersion/kind 的对象副本（缓存中的对象可能没有 TypeMeta）
+func withKind(obj client.Object, res *cachedResource) runtime.Object {
+	out := obj.DeepCopyObject()
+	out.GetObjectKind().SetGroupVersionKind(res.gvk)
+	return out
+}
+
+// handleWrite 转发写请求；apiserver 不可用（连接失败或返回 502/503/504）或队列中还有未重放的请求时排队，保证按顺序写入
+func (p *Proxy) handleWrite(w http.ResponseWriter, r *http.Request, target apiPath) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeJSON(w, status, map[string]any{"error": fmt.Sprintf("读取请求体失败: %v", err)})
+		return
+	}
+	header := forwardHeader(r.Header)
+
+	if p.healthy.Load() && p.queue.len() == 0 {
+		resp, err := p.send(r.Context(), r.Method, r.URL.RequestURI(), header, body)
+		if err == nil && !isUnavailable(resp.StatusCode) {
+			defer resp.Body.Close()
+			copyResponse(w, resp)
+			return
+		}
+		if r.Context().Err() != nil {
+			// 客户端已断开，不排队
+			if err == nil {
+				resp.Body.Close()
+			}
+			return
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("apiserver 返回 %d", resp.StatusCode)
+		}
+		p.setHealthy(false, err.Error())
+	}
+
+	pw := &pendingWrite{
+		Method:   r.Method,
+		Path:     r.URL.RequestURI(),
+		QueuedAt: time.Now(),
+		header:   header,
+		body:     body,
+		target:   target,
+	}
+	if r.Method == http.MethodPost {
+		if target.name == "" {
+			pw.target.name = objectMeta(body).Name
+		}
+	} else if target.name != "" {
+		// 请求体中带有 resourceVersion 时以它为准，否则使用缓存中的值
+		if pw.baseRV = objectMeta(body).ResourceVersion; pw.baseRV == "" {
+			pw.baseRV = p.cache.resourceVersion(target)
+		}
+	}
+	if !p.queue.push(pw) {
+		writeUnavailable(w, "apiserver 不可用且写队列已满")
+		return
+	}
+	p.logger.Infof("apiserver 不可用，写请求 #%d %s %s 已排队", pw.ID, pw.Method, pw.Path)
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	w.Header().Set(QueuedHeader, strconv.FormatUint(pw.ID, 10))
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":  "Queued",
+		"id":      pw.ID,
+		"message": "apiserver 不可用，请求已排队，恢复后按顺序重放",
+	})
+}
+
+// send 向 apiserver 发送请求，uri 为路径与查询参数
+func (p *Proxy) send(ctx context.Context, method, uri string, header http.Header, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if len(body) > 0 {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(p.upstream.String(), "/")+uri, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return p.client.Do(req)
+}
+
+// handleStatus 返回 apiserver 是否可用、缓存是否已同步、排队中的写请求与最近的冲突
+func (p *Proxy) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	queued, conflicts := p.queue.snapshot()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"upstream":    p.upstream.String(),
+		"healthy":     p.healthy.Load(),
+		"cacheSynced": p.cache.synced(),
+		"queued":      queued,
+		"conflicts":   conflicts,
+	})
+}
+
+// setHealthy 更新 apiserver 的可用状态，状态变化时记录日志
+func (p *Proxy) setHealthy(healthy bool, reason string) {
+	if p.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		p.logger.Infof("apiserver %s 已恢复", p.upstream)
+	} else {
+		p.logger.Warnf("apiserver %s 不可用: %s", p.upstream, reason)
+	}
+}
+
+// bearerTransport 在请求没有 Authorization 时带上 token
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token != "" && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// hopHeaders 不转发的逐跳请求头
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length"}
+
+func forwardHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, k := range hopHeaders {
+		out.Del(k)
+	}
+	return out
+}
+
+func copyResponse(w http.ResponseWriter, resp *http.Response) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// isUnavailable 判断 apiserver 的响应是否表示暂时不可用（包括存储后端不可用时的 503）
+func isUnavailable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// objectMeta 从请求体中读取 metadata（不是对象时返回空值）
+func objectMeta(body []byte) metav1.ObjectMeta {
+	var obj struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}
+	_ = json.Unmarshal(body, &obj)
+	return obj.Metadata
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeUnavailable(w http.ResponseWriter, message string) {
+	w.Header().Set("Retry-After", "5")
+	writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": message})
+}
@@ -0,0 +1,346 @@
+package apiproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseAPIPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want apiPath
+		ok   bool
+	}{
+		{"/api/v1/pods", apiPath{version: "v1", resource: "pods"}, true},
+		{"/api/v1/namespaces/default/pods/web", apiPath{version: "v1", resource: "pods", namespace: "default", name: "web"}, true},
+		{"/api/v1/namespaces/default/pods/web/status", apiPath{version: "v1", resource: "pods", namespace: "default", name: "web", subresource: "status"}, true},
+		{"/api/v1/watch/namespaces/default/pods", apiPath{version: "v1", resource: "pods", namespace: "default", watch: true}, true},
+		{"/api/v1/nodes/node-1", apiPath{version: "v1", resource: "nodes", name: "node-1"}, true},
+		{"/api/v1/namespaces/default", apiPath{version: "v1", resource: "namespaces", name: "default"}, true},
+		{"/apis/apps/v1/namespaces/default/deployments/web/scale", apiPath{group: "apps", version: "v1", resource: "deployments", namespace: "default", name: "web", subresource: "scale"}, true},
+		{"/api/readyz", apiPath{}, false},
+		{"/apis/apps/v1", apiPath{}, false},
+		{"/api/v1/namespaces/default/pods/web/status/extra", apiPath{}, false},
+		{"/healthz", apiPath{}, false},
+	}
+	for _, c := range cases {
+		got, ok := parseAPIPath(c.path)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("parseAPIPath(%q) = %+v, %v; want %+v, %v", c.path, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+// fakeAPIServer 只保存 default namespace 下 Pod 的 apiserver；down 时所有请求返回 503（模拟与存储后端断开）
+type fakeAPIServer struct {
+	*httptest.Server
+	down atomic.Bool
+
+	mu   sync.Mutex
+	rv   int
+	pods map[string]*corev1.Pod
+}
+
+func newFakeAPIServer(t *testing.T, pods ...*corev1.Pod) *fakeAPIServer {
+	t.Helper()
+	f := &fakeAPIServer{pods: make(map[string]*corev1.Pod)}
+	for _, p := range pods {
+		f.put(p)
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// put 直接写入 Pod（模拟其他客户端的修改），返回新的 resourceVersion
+func (f *fakeAPIServer) put(p *corev1.Pod) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rv++
+	p = p.DeepCopy()
+	p.Namespace = "default"
+	p.ResourceVersion = strconv.Itoa(f.rv)
+	f.pods[p.Name] = p
+	return p.ResourceVersion
+}
+
+func (f *fakeAPIServer) get(name string) *corev1.Pod {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pods[name]
+}
+
+func (f *fakeAPIServer) serve(w http.ResponseWriter, r *http.Request) {
+	if f.down.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "storage unavailable"})
+		return
+	}
+	if r.URL.Path == "/api/readyz" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	target, ok := parseAPIPath(r.URL.Path)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if target.watch {
+		// 不产生事件，保持连接直到客户端断开
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		return
+	}
+	if target.resource != "pods" {
+		writeJSON(w, http.StatusOK, metav1.List{})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if target.name == "" {
+			f.mu.Lock()
+			var items []*corev1.Pod
+			for _, p := range f.pods {
+				items = append(items, p)
+			}
+			f.mu.Unlock()
+			writeJSON(w, http.StatusOK, map[string]any{"items": items})
+			return
+		}
+		if p := f.get(target.name); p != nil {
+			writeJSON(w, http.StatusOK, p)
+			return
+		}
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "resource not found: " + target.name})
+	case http.MethodPost, http.MethodPut:
+		var p corev1.Pod
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &p); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		if r.Method == http.MethodPost && f.get(p.Name) != nil {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "already exists"})
+			return
+		}
+		f.put(&p)
+		writeJSON(w, http.StatusOK, f.get(p.Name))
+	case http.MethodDelete:
+		f.mu.Lock()
+		delete(f.pods, target.name)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func testPod(name string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+	}
+}
+
+func startProxy(t *testing.T, upstream string) (*Proxy, string) {
+	t.Helper()
+	p, err := NewProxy(logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}, config.APIProxyConfig{
+		Enabled:        true,
+		Upstream:       upstream,
+		HealthInterval: "20ms",
+	}, "")
+	if err != nil {
+		t.Fatalf("NewProxy: %v", err)
+	}
+	p.cache.factory.Start(p.ctx.Done())
+	go p.run()
+	srv := httptest.NewServer(p)
+	t.Cleanup(func() {
+		p.cancel()
+		<-p.done
+		srv.Close()
+	})
+	waitFor(t, "cache sync", p.cache.synced)
+	return p, srv.URL
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func do(t *testing.T, method, url string, body any) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = strings.NewReader(string(data))
+	}
+	req, _ := http.NewRequest(method, url, reader)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func listNames(t *testing.T, data []byte) []string {
+	t.Helper()
+	var list struct {
+		Kind  string       `json:"kind"`
+		Items []corev1.Pod `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil || list.Kind != "List" {
+		t.Fatalf("unexpected list %s (err=%v)", data, err)
+	}
+	var names []string
+	for _, p := range list.Items {
+		if p.Kind != "Pod" {
+			t.Errorf("item %s missing kind", p.Name)
+		}
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestProxyServesFromCache(t *testing.T) {
+	upstream := newFakeAPIServer(t, testPod("a", map[string]string{"app": "web"}), testPod("b", nil))
+	p, proxy := startProxy(t, upstream.URL)
+
+	resp, data := do(t, http.MethodGet, proxy+"/api/v1/namespaces/default/pods", nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(CacheHeader) != "hit" {
+		t.Fatalf("expected cached list, got %d %q", resp.StatusCode, resp.Header.Get(CacheHeader))
+	}
+	if names := listNames(t, data); fmt.Sprint(names) != "[a b]" {
+		t.Errorf("unexpected pods %v", names)
+	}
+	_, data = do(t, http.MethodGet, proxy+"/api/v1/pods?labelSelector=app%3Dweb", nil)
+	if names := listNames(t, data); fmt.Sprint(names) != "[a]" {
+		t.Errorf("unexpected pods for selector %v", names)
+	}
+
+	// apiserver 不可用时继续由缓存提供
+	upstream.down.Store(true)
+	resp, data = do(t, http.MethodGet, proxy+"/api/v1/namespaces/default/pods/a", nil)
+	var pod corev1.Pod
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(data, &pod) != nil || pod.Name != "a" || pod.Kind != "Pod" {
+		t.Fatalf("expected cached pod, got %d %s", resp.StatusCode, data)
+	}
+	waitFor(t, "health check", func() bool { return !p.healthy.Load() })
+	resp, _ = do(t, http.MethodGet, proxy+"/api/v1/namespaces/default/pods/missing", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for missing pod while disconnected, got %d", resp.StatusCode)
+	}
+	// 缓存无法处理的请求转发到 apiserver
+	resp, _ = do(t, http.MethodGet, proxy+"/api/v1/pods?fieldSelector=metadata.name%3Da", nil)
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get(CacheHeader) != "" {
+		t.Errorf("expected fieldSelector to be forwarded, got %d", resp.StatusCode)
+	}
+}
+
+func TestProxyQueuesAndReplaysWrites(t *testing.T) {
+	upstream := newFakeAPIServer(t, testPod("a", nil))
+	p, proxy := startProxy(t, upstream.URL)
+
+	upstream.down.Store(true)
+	updated := testPod("a", map[string]string{"version": "2"})
+	resp, _ := do(t, http.MethodPut, proxy+"/api/v1/namespaces/default/pods/a", updated)
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get(QueuedHeader) != "1" {
+		t.Fatalf("expected update to be queued, got %d %q", resp.StatusCode, resp.Header.Get(QueuedHeader))
+	}
+	// 同一对象的第二次修改：第一次重放后 resourceVersion 变化，不应判为冲突
+	updated.Labels["version"] = "3"
+	if resp, _ := do(t, http.MethodPut, proxy+"/api/v1/namespaces/default/pods/a", updated); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected second update to be queued, got %d", resp.StatusCode)
+	}
+	if resp, _ := do(t, http.MethodPost, proxy+"/api/v1/namespaces/default/pods", testPod("c", nil)); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected create to be queued, got %d", resp.StatusCode)
+	}
+	if n := p.queue.len(); n != 3 {
+		t.Fatalf("expected 3 queued writes, got %d", n)
+	}
+
+	upstream.down.Store(false)
+	waitFor(t, "replay", func() bool { return p.queue.len() == 0 })
+	if a := upstream.get("a"); a == nil || a.Labels["version"] != "3" {
+		t.Errorf("expected pod a to be updated twice, got %+v", a)
+	}
+	if upstream.get("c") == nil {
+		t.Error("expected pod c to be created")
+	}
+	if _, conflicts := p.queue.snapshot(); len(conflicts) != 0 {
+		t.Errorf("unexpected conflicts %+v", conflicts)
+	}
+
+	// 恢复后直接转发
+	resp, _ = do(t, http.MethodDelete, proxy+"/api/v1/namespaces/default/pods/c", nil)
+	if resp.StatusCode != http.StatusOK || upstream.get("c") != nil {
+		t.Errorf("expected delete to be forwarded, got %d", resp.StatusCode)
+	}
+}
+
+func TestProxyReplayConflict(t *testing.T) {
+	upstream := newFakeAPIServer(t, testPod("a", nil), testPod("b", nil))
+	p, proxy := startProxy(t, upstream.URL)
+
+	upstream.down.Store(true)
+	if resp, _ := do(t, http.MethodPut, proxy+"/api/v1/namespaces/default/pods/a", testPod("a", map[string]string{"from": "proxy"})); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected update to be queued, got %d", resp.StatusCode)
+	}
+	if resp, _ := do(t, http.MethodDelete, proxy+"/api/v1/namespaces/default/pods/b", nil); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected delete to be queued, got %d", resp.StatusCode)
+	}
+	// 断开期间其他客户端修改了 a、删除了 b
+	upstream.put(testPod("a", map[string]string{"from": "other"}))
+	upstream.mu.Lock()
+	delete(upstream.pods, "b")
+	upstream.mu.Unlock()
+
+	upstream.down.Store(false)
+	waitFor(t, "replay", func() bool { return p.queue.len() == 0 })
+	if a := upstream.get("a"); a.Labels["from"] != "other" {
+		t.Errorf("conflicting update must not overwrite pod a, got %v", a.Labels)
+	}
+
+	resp, data := do(t, http.MethodGet, proxy+"/proxy/status", nil)
+	var status struct {
+		Healthy   bool       `json:"healthy"`
+		Queued    []any      `json:"queued"`
+		Conflicts []Conflict `json:"conflicts"`
+	}
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(data, &status) != nil {
+		t.Fatalf("GET /proxy/status: %d %s", resp.StatusCode, data)
+	}
+	if !status.Healthy || len(status.Queued) != 0 || len(status.Conflicts) != 2 {
+		t.Fatalf("unexpected status %s", data)
+	}
+	if c := status.Conflicts[0]; c.Method != http.MethodPut || !strings.Contains(c.Reason, "被修改") {
+		t.Errorf("unexpected conflict %+v", c)
+	}
+	if c := status.Conflicts[1]; c.Method != http.MethodDelete || !strings.Contains(c.Reason, "已被删除") {
+		t.Errorf("unexpected conflict %+v", c)
+	}
+}
@@ -0,0 +1,124 @@
+package apiproxy
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxConflicts /proxy/status 中保留的最近冲突数
+const maxConflicts = 100
+
+// pendingWrite 与 apiserver 断开期间排队的写请求
+type pendingWrite struct {
+	ID       uint64      `json:"id"`
+	Method   string      `json:"method"`
+	Path     string      `json:"path"`
+	QueuedAt time.Time   `json:"queuedAt"`
+	header   http.Header // 转发时使用的请求头（Authorization、Content-Type 等）
+	body     []byte
+	target   apiPath
+	// baseRV 请求发出时客户端看到的对象 resourceVersion（请求体中的或本地缓存中的），
+	// 重放前与 apiserver 上的当前值比较；为空表示不检查
+	baseRV string
+}
+
+// Conflict 一个没有重放成功的写请求：对象在断开期间被修改或删除、apiserver 拒绝（4xx）或排队超时
+type Conflict struct {
+	ID         uint64    `json:"id"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Reason     string    `json:"reason"`
+	QueuedAt   time.Time `json:"queuedAt"`
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+// writeQueue 按到达顺序保存排队的写请求；只有重放循环会从队首移除
+type writeQueue struct {
+	mu        sync.Mutex
+	max       int
+	nextID    uint64
+	items     []*pendingWrite
+	conflicts []Conflict
+}
+
+func newWriteQueue(max int) *writeQueue {
+	return &writeQueue{max: max}
+}
+
+// push 把写请求加入队尾，队列已满时返回 false
+func (q *writeQueue) push(w *pendingWrite) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) >= q.max {
+		return false
+	}
+	q.nextID++
+	w.ID = q.nextID
+	q.items = append(q.items, w)
+	return true
+}
+
+// peek 返回队首的写请求，队列为空时返回 nil
+func (q *writeQueue) peek() *pendingWrite {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return nil
+	}
+	return q.items[0]
+}
+
+// pop 移除队首的写请求；resourceVersion 非空时（重放成功）把队列中之后作用于同一对象的请求的 baseRV 更新为它，
+// 避免把自己排队的前一个写入误判为冲突
+func (q *writeQueue) pop(w *pendingWrite, resourceVersion string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 || q.items[0] != w {
+		return
+	}
+	q.items = q.items[1:]
+	if resourceVersion == "" {
+		return
+	}
+	key := w.target.objectKey()
+	for _, later := range q.items {
+		if later.target.objectKey() == key {
+			later.baseRV = resourceVersion
+		}
+	}
+}
+
+// conflict 记录一个没有重放成功的写请求
+func (q *writeQueue) conflict(w *pendingWrite, reason string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.conflicts = append(q.conflicts, Conflict{
+		ID:         w.ID,
+		Method:     w.Method,
+		Path:       w.Path,
+		Reason:     reason,
+		QueuedAt:   w.QueuedAt,
+		DetectedAt: time.Now(),
+	})
+	if len(q.conflicts) > maxConflicts {
+		q.conflicts = q.conflicts[len(q.conflicts)-maxConflicts:]
+	}
+}
+
+func (q *writeQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// snapshot 返回排队中的写请求与最近的冲突
+func (q *writeQueue) snapshot() ([]pendingWrite, []Conflict) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := make([]pendingWrite, 0, len(q.items))
+	for _, w := range q.items {
+		items = append(items, *w)
+	}
+	return items, append([]Conflict(nil), q.conflicts...)
+}
@@ -0,0 +1,158 @@
+package apiproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// conflictError 写请求不能重放（对象在断开期间被修改或删除、apiserver 拒绝），应从队列中丢弃
+type conflictError struct {
+	reason string
+}
+
+func (e *conflictError) Error() string {
+	return e.reason
+}
+
+// run 每 healthInterval（或有新的排队请求时）检查 apiserver 是否可用，可用时按顺序重放队列中的写请求
+func (p *Proxy) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.healthInterval)
+	defer ticker.Stop()
+	for {
+		if p.checkHealth() {
+			p.replay()
+		}
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.wake:
+		}
+	}
+}
+
+// checkHealth 通过 GET /api/readyz 检查 apiserver（及其存储后端）是否可用
+func (p *Proxy) checkHealth() bool {
+	resp, err := p.send(p.ctx, http.MethodGet, "/api/readyz", nil, nil)
+	if err != nil {
+		if p.ctx.Err() == nil {
+			p.setHealthy(false, err.Error())
+		}
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		p.setHealthy(false, fmt.Sprintf("readyz 返回 %d", resp.StatusCode))
+		return false
+	}
+	p.setHealthy(true, "")
+	return true
+}
+
+// replay 按顺序重放队列中的写请求，直到队列为空或 apiserver 再次不可用（剩余请求留到下次）
+func (p *Proxy) replay() {
+	for p.ctx.Err() == nil {
+		w := p.queue.peek()
+		if w == nil {
+			return
+		}
+		if p.queueTTL > 0 && time.Since(w.QueuedAt) > p.queueTTL {
+			p.drop(w, fmt.Sprintf("排队超过 %s", p.queueTTL))
+			continue
+		}
+
+		rv, err := p.replayOne(w)
+		var conflict *conflictError
+		switch {
+		case errors.As(err, &conflict):
+			p.drop(w, conflict.reason)
+		case err != nil:
+			p.setHealthy(false, err.Error())
+			return
+		default:
+			p.logger.Infof("写请求 #%d %s %s 已重放", w.ID, w.Method, w.Path)
+			p.queue.pop(w, rv)
+		}
+	}
+}
+
+// replayOne 重放一个写请求，成功时返回对象新的 resourceVersion（响应中没有时为空）。
+// 修改与删除单个对象前先读取 apiserver 上的对象：已被删除、或 resourceVersion 与排队时不同时返回 conflictError
+func (p *Proxy) replayOne(w *pendingWrite) (string, error) {
+	if w.Method != http.MethodPost && w.target.name != "" {
+		if err := p.checkBase(w); err != nil {
+			return "", err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, requestTimeout)
+	defer cancel()
+	resp, err := p.send(ctx, w.Method, w.Path, w.header, w.body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	switch {
+	case isUnavailable(resp.StatusCode):
+		return "", fmt.Errorf("apiserver 返回 %d", resp.StatusCode)
+	case resp.StatusCode == http.StatusConflict:
+		return "", &conflictError{reason: "apiserver 返回冲突: " + errorMessage(data)}
+	case resp.StatusCode >= 400:
+		return "", &conflictError{reason: fmt.Sprintf("apiserver 拒绝（%d）: %s", resp.StatusCode, errorMessage(data))}
+	}
+	return objectMeta(data).ResourceVersion, nil
+}
+
+// checkBase 比较 apiserver 上对象当前的 resourceVersion 与排队时客户端看到的值
+func (p *Proxy) checkBase(w *pendingWrite) error {
+	ctx, cancel := context.WithTimeout(p.ctx, requestTimeout)
+	defer cancel()
+	header := http.Header{}
+	if auth := w.header.Get("Authorization"); auth != "" {
+		header.Set("Authorization", auth)
+	}
+	resp, err := p.send(ctx, http.MethodGet, w.target.objectPath(), header, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return &conflictError{reason: "对象在断开期间已被删除"}
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("读取对象返回 %d", resp.StatusCode)
+	case resp.StatusCode >= 400:
+		return &conflictError{reason: fmt.Sprintf("读取对象失败（%d）: %s", resp.StatusCode, errorMessage(data))}
+	}
+	if current := objectMeta(data).ResourceVersion; w.baseRV != "" && current != w.baseRV {
+		return &conflictError{reason: fmt.Sprintf("对象在断开期间被修改（resourceVersion %s -> %s）", w.baseRV, current)}
+	}
+	return nil
+}
+
+// drop 把写请求从队列中移除并记为冲突
+func (p *Proxy) drop(w *pendingWrite, reason string) {
+	p.logger.Warnf("写请求 #%d %s %s 没有重放: %s", w.ID, w.Method, w.Path, reason)
+	p.queue.conflict(w, reason)
+	p.queue.pop(w, "")
+}
+
+// errorMessage 取 apiserver 错误响应中的 error 字段
+func errorMessage(data []byte) string {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &payload); err == nil && payload.Error != "" {
+		return payload.Error
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,180 @@
+// Package benchgate 比较 go test -bench 的输出与提交在仓库中的基线，找出超过阈值的性能回归（见 make bench）
+package benchgate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result 一个基准多次运行（-count）的结果，取中位数
+type Result struct {
+	Name        string
+	NsPerOp     float64
+	AllocsPerOp float64
+	// Runs 参与计算的运行次数
+	Runs int
+}
+
+// gomaxprocsSuffix 基准名称末尾的 -<GOMAXPROCS>，不同机器上不同，比较时去掉
+var gomaxprocsSuffix = regexp.MustCompile(`-\d+$`)
+
+// Parse 解析 go test -bench -benchmem 的输出，同名基准的多次运行取中位数；非基准行（goos、PASS 等）忽略
+func Parse(r io.Reader) (map[string]Result, error) {
+	samples := map[string][][2]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		name := gomaxprocsSuffix.ReplaceAllString(fields[0], "")
+		ns, allocs := -1.0, -1.0
+		// fields[1] 为迭代次数，之后是 “数值 单位” 对
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("benchgate: %s: 无法解析 %q", name, fields[i])
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				ns = v
+			case "allocs/op":
+				allocs = v
+			}
+		}
+		if ns < 0 {
+			continue
+		}
+		samples[name] = append(samples[name], [2]float64{ns, allocs})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	out := make(map[string]Result, len(samples))
+	for name, runs := range samples {
+		ns := make([]float64, 0, len(runs))
+		allocs := make([]float64, 0, len(runs))
+		for _, r := range runs {
+			ns = append(ns, r[0])
+			allocs = append(allocs, r[1])
+		}
+		out[name] = Result{Name: name, NsPerOp: median(ns), AllocsPerOp: median(allocs), Runs: len(runs)}
+	}
+	return out, nil
+}
+
+func median(v []float64) float64 {
+	sort.Float64s(v)
+	n := len(v)
+	if n%2 == 1 {
+		return v[n/2]
+	}
+	return (v[n/2-1] + v[n/2]) / 2
+}
+
+// Thresholds 允许的最大增幅（百分比）；小于等于 0 时不检查该项
+type Thresholds struct {
+	NsPercent     float64
+	AllocsPercent float64
+}
+
+// Delta 一个基准与基线的比较结果
+type Delta struct {
+	Name            string
+	Baseline        Result
+	Current         Result
+	NsChangePct     float64
+	AllocsChangePct float64
+	Regressed       bool
+	// Reason 回归的原因（Regressed 为 true 时）
+	Reason string
+}
+
+// Report 比较结果：Deltas 按名称排序；Missing 为基线中有、本次没有运行的基准（例如没有设置 K3_TEST_MYSQL_DSN），
+// Added 为基线中没有的新基准，两者都不算回归
+type Report struct {
+	Deltas  []Delta
+	Missing []string
+	Added   []string
+}
+
+// Regressions 返回超过阈值的基准
+func (r Report) Regressions() []Delta {
+	var out []Delta
+	for _, d := range r.Deltas {
+		if d.Regressed {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Compare 按阈值比较 current 与 baseline
+func Compare(baseline, current map[string]Result, th Thresholds) Report {
+	var report Report
+	for name, base := range baseline {
+		cur, ok := current[name]
+		if !ok {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+		d := Delta{Name: name, Baseline: base, Current: cur,
+			NsChangePct: changePct(base.NsPerOp, cur.NsPerOp), AllocsChangePct: changePct(base.AllocsPerOp, cur.AllocsPerOp)}
+		var reasons []string
+		if th.NsPercent > 0 && d.NsChangePct > th.NsPercent {
+			reasons = append(reasons, fmt.Sprintf("ns/op +%.1f%% > %.0f%%", d.NsChangePct, th.NsPercent))
+		}
+		// 没有 -benchmem 的结果中 allocs/op 为 -1，不比较
+		if th.AllocsPercent > 0 && base.AllocsPerOp >= 0 && cur.AllocsPerOp >= 0 && d.AllocsChangePct > th.AllocsPercent {
+			reasons = append(reasons, fmt.Sprintf("allocs/op +%.1f%% > %.0f%%", d.AllocsChangePct, th.AllocsPercent))
+		}
+		d.Regressed = len(reasons) > 0
+		d.Reason = strings.Join(reasons, ", ")
+		report.Deltas = append(report.Deltas, d)
+	}
+	for name := range current {
+		if _, ok := baseline[name]; !ok {
+			report.Added = append(report.Added, name)
+		}
+	}
+	sort.Slice(report.Deltas, func(i, j int) bool { return report.Deltas[i].Name < report.Deltas[j].Name })
+	sort.Strings(report.Missing)
+	sort.Strings(report.Added)
+	return report
+}
+
+// changePct 从 base 到 cur 的变化百分比；base 为 0 时 cur 也为 0 视为没有变化，否则视为无穷大的增幅
+func changePct(base, cur float64) float64 {
+	if base == 0 {
+		if cur <= 0 {
+			return 0
+		}
+		return 100 * cur
+	}
+	return (cur - base) / base * 100
+}
+
+// Write 以表格形式输出比较结果
+func (r Report) Write(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "%-60s %14s %14s %8s %10s %10s %8s\n", "benchmark", "old ns/op", "new ns/op", "delta", "old allocs", "new allocs", "delta")
+	for _, d := range r.Deltas {
+		mark := ""
+		if d.Regressed {
+			mark = "  REGRESSION: " + d.Reason
+		}
+		_, _ = fmt.Fprintf(w, "%-60s %14.0f %14.0f %+7.1f%% %10.0f %10.0f %+7.1f%%%s\n",
+			d.Name, d.Baseline.NsPerOp, d.Current.NsPerOp, d.NsChangePct,
+			d.Baseline.AllocsPerOp, d.Current.AllocsPerOp, d.AllocsChangePct, mark)
+	}
+	for _, name := range r.Added {
+		_, _ = fmt.Fprintf(w, "%-60s (new, not in baseline)\n", name)
+	}
+	for _, name := range r.Missing {
+		_, _ = fmt.Fprintf(w, "%-60s (not run)\n", name)
+	}
+}
@@ -0,0 +1,58 @@
+package benchgate
+
+import (
+	"strings"
+	"testing"
+)
+
+const baselineOutput = `goos: linux
+goarch: amd64
+pkg: github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage
+BenchmarkMemoryStore_Create/1k-8         	   65397	     20000 ns/op	    5712 B/op	      33 allocs/op
+BenchmarkMemoryStore_Create/1k-8         	   65397	     21000 ns/op	    5712 B/op	      33 allocs/op
+BenchmarkMemoryStore_Create/1k-8         	   65397	     90000 ns/op	    5712 B/op	      33 allocs/op
+BenchmarkMemoryStore_List/1k-8           	     271	   4500000 ns/op	 2099280 B/op	    5015 allocs/op
+BenchmarkMySQLStore_List/1k-8            	     100	   9000000 ns/op	 2099280 B/op	    9000 allocs/op
+PASS
+`
+
+func TestParseTakesMedianAndStripsProcs(t *testing.T) {
+	got, err := Parse(strings.NewReader(baselineOutput))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	create, ok := got["BenchmarkMemoryStore_Create/1k"]
+	if !ok {
+		t.Fatalf("missing create benchmark: %v", got)
+	}
+	// 中位数不受偶发的慢运行影响
+	if create.NsPerOp != 21000 || create.AllocsPerOp != 33 || create.Runs != 3 {
+		t.Fatalf("unexpected result: %+v", create)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline, _ := Parse(strings.NewReader(baselineOutput))
+	current, _ := Parse(strings.NewReader(`
+BenchmarkMemoryStore_Create/1k-4   1000   22000 ns/op   5712 B/op   40 allocs/op
+BenchmarkMemoryStore_List/1k-4      300   4600000 ns/op 2099280 B/op 5015 allocs/op
+BenchmarkMemoryStore_Delete/1k-4   1000   1000 ns/op    100 B/op    2 allocs/op
+`))
+	report := Compare(baseline, current, Thresholds{NsPercent: 25, AllocsPercent: 10})
+
+	regressions := report.Regressions()
+	if len(regressions) != 1 || regressions[0].Name != "BenchmarkMemoryStore_Create/1k" || !strings.Contains(regressions[0].Reason, "allocs/op") {
+		t.Fatalf("unexpected regressions: %+v", regressions)
+	}
+	if len(report.Missing) != 1 || report.Missing[0] != "BenchmarkMySQLStore_List/1k" {
+		t.Fatalf("unexpected missing: %v", report.Missing)
+	}
+	if len(report.Added) != 1 || report.Added[0] != "BenchmarkMemoryStore_Delete/1k" {
+		t.Fatalf("unexpected added: %v", report.Added)
+	}
+
+	// 阈值为 0 时不检查
+	if r := Compare(baseline, current, Thresholds{}).Regressions(); len(r) != 0 {
+		t.Fatalf("expected no regressions without thresholds: %+v", r)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -12,67 +13,299 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterinfo"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/version"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 )
 
 // DBContainerHandle 记录由本进程“自动拉起”的数据库容器信息。
 //
 // - Runtime: 当前检测到并用于拉起容器的运行时实现（目前真正可用的是 DockerRuntime）
-// - Pod: 复用已有 runtime 控制器的 “Pod 结构 -> docker run 参数” 映射来描述要运行的容器
+// - Pod: 存储后端的静态 Pod（同时写入 storage.static_pod_path，Store 就绪后由 RuntimeController 接管并维护 mirror Pod）
+// - ReadyAddr: 就绪探测地址（host:port），容器被重启后据此等待服务可连接
 // - Started: 标记容器是否由本进程启动（用于退出时是否清理）
+//
+// Store 创建后会启动监控（见 Supervise），容器异常退出时自动重启；退出时应调用 Stop 而不是直接停止容器。
 type DBContainerHandle struct {
-	Runtime controller.ContainerRuntime
-	Pod     *corev1.Pod
-	Started bool // 是否由本进程拉起
+	Runtime   controller.ContainerRuntime
+	Pod       *corev1.Pod
+	ReadyAddr string
+	Started   bool // 是否由本进程拉起
+
+	supervisor supervisorState
 }
 
 // ProvideDBContainerHandle 会根据配置与当前宿主机容器运行时环境，按需拉起 MySQL/Etcd 容器。
 //
 // 约束（避免误操作）：
 // - 仅当配置指向本机地址（localhost/127.0.0.1/::1）时才会尝试拉起容器
+// - role 为 node 时不拉起（存储由 master 提供）
+// - bootstrap.auto_start_db 为 false（或 --no-bootstrap-containers）时完全跳过：不检测运行时，也不改动静态 Pod manifest
 // - 若容器已在运行则不会重复启动
 // - 若未检测到可用运行时，会降级跳过（允许用户自己提前启动数据库）
+//
+// 存储后端以静态 Pod 方式自托管：容器描述会写入 storage.static_pod_path（storage-etcd.yaml / storage-mysql.yaml），
+// 不再使用的存储 manifest 会被删除，避免切换存储类型后 controller 继续拉起旧的数据库。
 func ProvideDBContainerHandle(cfg config.Config, l logprovider.Logger) (*DBContainerHandle, error) {
+	// 即使地址是本机（cluster create 在同一台机器上生成的 agent），存储也由 master 拉起
+	if strings.EqualFold(strings.TrimSpace(cfg.Role), "node") {
+		syncStorageManifests(cfg, l, nil)
+		return &DBContainerHandle{}, nil
+	}
+	if !cfg.Bootstrap.AutoStartDBEnabled() {
+		l.Infof("bootstrap.auto_start_db 为 false，跳过自动拉起存储容器，直接连接 %s", storageEndpoint(cfg))
+		return &DBContainerHandle{}, nil
+	}
+
 	storageType := strings.ToLower(strings.TrimSpace(cfg.Storage.Type))
 
 	switch storageType {
 	case "mysql":
-		if !isLocalHost(cfg.Storage.MySQL.Host) {
-			return &DBContainerHandle{}, nil
-		}
-		if cfg.Storage.MySQL.Port <= 0 {
+		if !isLocalHost(cfg.Storage.MySQL.Host) || cfg.Storage.MySQL.Port <= 0 {
+			syncStorageManifests(cfg, l, nil)
 			return &DBContainerHandle{}, nil
 		}
 
 		pod := buildMySQLPod(cfg)
 		readyAddr := net.JoinHostPort(cfg.Storage.MySQL.Host, strconv.Itoa(cfg.Storage.MySQL.Port))
-		return ensureContainerRunningAndWait(l, pod, readyAddr)
+		return ensureContainerRunningAndWait(cfg, l, pod, readyAddr)
 
 	case "etcd":
 		endpoint, readyAddr, ok := firstLocalEtcdEndpoint(cfg.Storage.Etcd.Endpoints)
 		if !ok {
+			syncStorageManifests(cfg, l, nil)
 			return &DBContainerHandle{}, nil
 		}
-		pod, err := buildEtcdPodFromEndpoint(endpoint)
+		pod, err := buildEtcdPodFromEndpoint(endpoint, cfg.Storage.Etcd)
 		if err != nil {
 			l.Warnf("Etcd endpoint 解析失败，跳过自动拉起容器: %v", err)
 			return &DBContainerHandle{}, nil
 		}
-		return ensureContainerRunningAndWait(l, pod, readyAddr)
+		return ensureContainerRunningAndWait(cfg, l, pod, readyAddr)
 
 	default:
+		syncStorageManifests(cfg, l, nil)
 		return &DBContainerHandle{}, nil
 	}
 }
 
+// StorageImages 返回本机会自动拉起的存储容器使用的镜像（判断条件与 ProvideDBContainerHandle 相同），
+// k3 bundle create 用它把存储镜像打包进离线包
+func StorageImages(cfg config.Config) []string {
+	if !autoStartDB(cfg) {
+		return nil
+	}
+	var pod *corev1.Pod
+	switch strings.ToLower(strings.TrimSpace(cfg.Storage.Type)) {
+	case "mysql":
+		if isLocalHost(cfg.Storage.MySQL.Host) && cfg.Storage.MySQL.Port > 0 {
+			pod = buildMySQLPod(cfg)
+		}
+	case "etcd":
+		if endpoint, _, ok := firstLocalEtcdEndpoint(cfg.Storage.Etcd.Endpoints); ok {
+			pod, _ = buildEtcdPodFromEndpoint(endpoint, cfg.Storage.Etcd)
+		}
+	}
+	if pod == nil {
+		return nil
+	}
+	var images []string
+	for _, c := range pod.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+// LocalStorageAddr 返回本机会自动拉起的存储容器监听的地址（host:port，判断条件与 ProvideDBContainerHandle 相同），
+// 不会拉起时 ok 为 false；k3 check 用它检查存储端口
+func LocalStorageAddr(cfg config.Config) (addr string, ok bool) {
+	if !autoStartDB(cfg) {
+		return "", false
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Storage.Type)) {
+	case "mysql":
+		if isLocalHost(cfg.Storage.MySQL.Host) && cfg.Storage.MySQL.Port > 0 {
+			return net.JoinHostPort(cfg.Storage.MySQL.Host, strconv.Itoa(cfg.Storage.MySQL.Port)), true
+		}
+	case "etcd":
+		if _, readyAddr, ok := firstLocalEtcdEndpoint(cfg.Storage.Etcd.Endpoints); ok {
+			return readyAddr, true
+		}
+	}
+	return "", false
+}
+
+// autoStartDB 本进程是否会考虑自动拉起存储容器：role 为 node（存储由 master 提供）或关闭了 bootstrap.auto_start_db 时不会
+func autoStartDB(cfg config.Config) bool {
+	return !strings.EqualFold(strings.TrimSpace(cfg.Role), "node") && cfg.Bootstrap.AutoStartDBEnabled()
+}
+
+// storageEndpoint 返回存储后端的连接地址（用于日志与错误信息）
+func storageEndpoint(cfg config.Config) string {
+	switch strings.ToLower(strings.TrimSpace(cfg.Storage.Type)) {
+	case "mysql":
+		return "mysql " + net.JoinHostPort(cfg.Storage.MySQL.Host, strconv.Itoa(cfg.Storage.MySQL.Port))
+	case "etcd":
+		return "etcd " + strings.Join(cfg.Storage.Etcd.Endpoints, ",")
+	default:
+		return cfg.Storage.Type
+	}
+}
+
+// unreachableHint 说明存储后端不可达时应该检查什么：k3 是否会自动拉起数据库取决于配置与运行时
+func unreachableHint(cfg config.Config, handle *DBContainerHandle) string {
+	switch {
+	case strings.EqualFold(strings.TrimSpace(cfg.Role), "node"):
+		return "role 为 node 时存储由 master 提供，请确认 master 的数据库已启动且本机可以访问"
+	case !cfg.Bootstrap.AutoStartDBEnabled():
+		return "bootstrap.auto_start_db 为 false，k3 不会拉起数据库，请确认数据库已自行启动且可以访问"
+	}
+	if _, local := LocalStorageAddr(cfg); !local {
+		return "存储地址不是本机，k3 不会自动拉起数据库，请确认数据库已启动且网络可达"
+	}
+	if handle == nil || handle.Runtime == nil {
+		return "未检测到可用容器运行时，k3 无法自动拉起数据库，请安装 Docker 等容器运行时或自行启动数据库"
+	}
+	return fmt.Sprintf("请检查 %s 中存储容器 %s/%s 的状态与日志", handle.Runtime.Name(), handle.Pod.Namespace, handle.Pod.Name)
+}
+
+// storagePodNames 是 bootstrap 生成的存储静态 Pod（storage namespace）
+var storagePodNames = []string{"mysql", "etcd"}
+
+// syncStorageManifests 把当前使用的存储静态 Pod 写入 static_pod_path，并删除其他存储后端的 manifest；
+// pod 为 nil 表示本机不需要自托管存储。失败只告警：静态 Pod 仍会由 bootstrap 拉起，只是没有 mirror Pod。
+func syncStorageManifests(cfg config.Config, l logprovider.Logger, pod *corev1.Pod) {
+	dir := cfg.Storage.StaticPodPath
+	if dir == "" {
+		return
+	}
+	for _, name := range storagePodNames {
+		if pod != nil && pod.Name == name {
+			continue
+		}
+		path := controller.StaticPodManifestPath(dir, storageNamespace, name)
+		if err := os.Remove(path); err == nil {
+			l.Infof("已删除不再使用的存储静态 Pod: %s", path)
+		}
+	}
+	if pod == nil {
+		return
+	}
+	path, err := controller.WriteStaticPodManifest(dir, pod)
+	if err != nil {
+		l.Warnf("写入存储静态 Pod 失败: %v", err)
+		return
+	}
+	l.Infof("存储静态 Pod: %s", path)
+}
+
 // ProvideStore 创建存储实现。
 //
-// 注意：这里依赖注入了 DBContainerHandle（即使未使用），是为了确保初始化顺序：
-// 先拉起/等待 DB 容器就绪，再 NewStore() 连接数据库。
-func ProvideStore(cfg config.Config, _ *DBContainerHandle, l logprovider.Logger) (storage.Store, error) {
+// 注意：这里依赖注入了 DBContainerHandle，是为了确保初始化顺序：
+// 先拉起/等待 DB 容器就绪，再 NewStore() 连接数据库；Store 创建后开始监控 DB 容器，重启后通知 Store 重连。
+func ProvideStore(cfg config.Config, handle *DBContainerHandle, l logprovider.Logger) (storage.Store, error) {
+	s, err := newStore(cfg, l)
+	if err != nil {
+		return nil, fmt.Errorf("无法连接存储后端 %s: %w（%s）", storageEndpoint(cfg), err, unreachableHint(cfg, handle))
+	}
+	// 写入存储（schema 迁移、加入集群）之前先确认本节点与存储中的集群兼容
+	id := clusterIdentity(cfg)
+	for _, step := range []func() error{
+		func() error { return checkCluster(s, id, unreachableHint(cfg, handle), l) },
+		func() error { return ensureSchema(s, l) },
+		func() error { return joinCluster(s, id, l) },
+	} {
+		if err := step(); err != nil {
+			if closer, ok := s.(interface{ Close() error }); ok {
+				_ = closer.Close()
+			}
+			return nil, err
+		}
+	}
+	if r, ok := s.(*storage.ResilientStore); ok {
+		r.OnStateChange(func(from, to storage.CircuitState, err error) {
+			switch to {
+			case storage.CircuitOpen:
+				l.Warnf("存储后端 %s 不可用，已熔断，后台重连中: %v", cfg.Storage.Type, err)
+			case storage.CircuitHalfOpen:
+				l.Infof("存储后端 %s 已重连，放行请求试探", cfg.Storage.Type)
+			case storage.CircuitClosed:
+				l.Infof("存储后端 %s 已恢复", cfg.Storage.Type)
+			}
+		})
+	}
+	handle.Supervise(l, s)
+	return s, nil
+}
+
+// ensureSchema 启动时检查存储的 schema 版本并执行待执行的迁移：数据由更新的 k3 写入（降级）时拒绝启动，
+// 避免旧代码破坏数据；后端暂时不可达时只告警，由熔断与重连处理，下次启动再迁移
+func ensureSchema(s storage.Store, l logprovider.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	stored, applied, err := storage.EnsureSchema(ctx, s)
+	if err != nil {
+		if storage.IsBackendError(err) {
+			l.Warnf("检查存储 schema 版本失败（后端不可达），跳过迁移: %v", err)
+			return nil
+		}
+		return fmt.Errorf("存储 schema 检查失败: %w", err)
+	}
+	for _, m := range applied {
+		l.Infof("存储 schema 迁移 v%d: %s", m.Version, m.Description)
+	}
+	if len(applied) > 0 {
+		l.Infof("存储 schema 已从 v%d 升级到 v%d", stored, storage.SchemaVersion)
+	}
+	return nil
+}
+
+// clusterIdentity 返回本节点加入集群时的身份（ClusterInfo 中记录的内容）
+func clusterIdentity(cfg config.Config) clusterinfo.Identity {
+	return clusterinfo.Identity{
+		NodeName:       apiserver.SelfNodeName(cfg.NodeName),
+		ClusterID:      cfg.Cluster.ID,
+		StorageBackend: strings.ToLower(strings.TrimSpace(cfg.Storage.Type)),
+		K3Version:      version.Version,
+		SchemaVersion:  storage.SchemaVersion,
+	}
+}
+
+// checkCluster 检查本节点能否加入存储中的集群：集群 ID 不同或 k3 版本相差过大时拒绝启动；
+// 后端暂时不可达时只告警（附带 hint，说明应检查什么）
+func checkCluster(s storage.Store, id clusterinfo.Identity, hint string, l logprovider.Logger) error {
+	err := clusterinfo.Check(s, id, time.Now())
+	if err != nil && storage.IsBackendError(err) {
+		l.Warnf("检查集群身份失败（后端不可达），跳过: %v；%s", err, hint)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("无法加入集群: %w", err)
+	}
+	return nil
+}
+
+// joinCluster 创建或更新 ClusterInfo，记录本节点的版本；后端暂时不可达时只告警
+func joinCluster(s storage.Store, id clusterinfo.Identity, l logprovider.Logger) error {
+	info, err := clusterinfo.Join(s, id, time.Now())
+	if err != nil {
+		if storage.IsBackendError(err) {
+			l.Warnf("记录集群成员失败（后端不可达），跳过: %v", err)
+			return nil
+		}
+		return fmt.Errorf("无法加入集群: %w", err)
+	}
+	l.Infof("已加入集群 %s（创建于 %s，k3 %s，存储 %s）", info.Spec.ClusterUID,
+		info.Spec.CreationTime.Format(time.RFC3339), info.Spec.K3Version, info.Spec.StorageBackend)
+	return nil
+}
+
+// newStore 连接存储后端（MySQL 首次连接失败时重试）
+func newStore(cfg config.Config, l logprovider.Logger) (storage.Store, error) {
 	typ := strings.ToLower(strings.TrimSpace(cfg.Storage.Type))
 	if typ != "mysql" {
 		return storage.NewStore(cfg.Storage)
@@ -104,46 +337,47 @@ func ProvideStore(cfg config.Config, _ *DBContainerHandle, l logprovider.Logger)
 }
 
 // ensureContainerRunningAndWait 负责：
+// - 生成存储静态 Pod 的 manifest
 // - 检测容器运行时
-// - 若目标容器未运行则启动
+// - 若目标容器未运行则启动（Store 还不可用，不经过 RuntimeController）
 // - 等待指定 TCP 端口就绪（表示服务可连接）
 //
 // readyAddr 一般为 "host:port"（如 "127.0.0.1:3306" / "127.0.0.1:2379"）。
-func ensureContainerRunningAndWait(l logprovider.Logger, pod *corev1.Pod, readyAddr string) (*DBContainerHandle, error) {
-	detector := controller.NewRuntimeDetector(l)
+func ensureContainerRunningAndWait(cfg config.Config, l logprovider.Logger, pod *corev1.Pod, readyAddr string) (*DBContainerHandle, error) {
+	controller.PrepareStaticPod(pod)
+	syncStorageManifests(cfg, l, pod)
+
+	// 存储容器在镜像缓存启动之前拉起，直接从镜像仓库拉取
+	detector := controller.NewRuntimeDetector(l, cfg.Cluster.ID, "")
 	runtime, err := detector.DetectRuntime()
 	if err != nil {
 		// best-effort：如果用户本机已经有 MySQL/Etcd 进程在跑，不强制要求运行时
-		l.Warnf("未检测到可用容器运行时，跳过自动拉起容器: %v", err)
+		l.Warnf("未检测到可用容器运行时，跳过自动拉起容器（自行管理数据库时可设置 bootstrap.auto_start_db: false）: %v", err)
 		return &DBContainerHandle{}, nil
 	}
 
-	status, _ := runtime.GetContainerStatus(context.Background(), pod)
-	if status.Running {
-		l.Infof("检测到目标容器已在运行 (runtime=%s, status=%s)，跳过拉起", runtime.Name(), status.Status)
-		return &DBContainerHandle{Runtime: runtime, Pod: pod, Started: false}, nil
-	}
-
-	// 清理同名旧容器（Exited 等），避免 docker run --name 冲突
-	_ = runtime.StopContainer(context.Background(), pod)
-
-	l.Infof("准备通过 %s 拉起容器以提供存储后端...", runtime.Name())
-	if err := runtime.StartContainer(context.Background(), pod); err != nil {
+	l.Infof("确认存储后端静态 Pod %s/%s 在 %s 中运行...", pod.Namespace, pod.Name, runtime.Name())
+	started, err := controller.EnsureStaticPod(context.Background(), runtime, pod)
+	if err != nil {
 		return nil, err
 	}
+	if !started {
+		l.Infof("检测到存储后端容器已在运行 (runtime=%s)，跳过拉起", runtime.Name())
+		return &DBContainerHandle{Runtime: runtime, Pod: pod, ReadyAddr: readyAddr, Started: false}, nil
+	}
 
-	if err := waitForTCP(readyAddr, 60*time.Second); err != nil {
+	if err := waitForTCP(context.Background(), readyAddr, 60*time.Second); err != nil {
 		_ = runtime.StopContainer(context.Background(), pod)
 		return nil, err
 	}
 
 	l.Infof("存储后端容器已就绪: %s", readyAddr)
-	return &DBContainerHandle{Runtime: runtime, Pod: pod, Started: true}, nil
+	return &DBContainerHandle{Runtime: runtime, Pod: pod, ReadyAddr: readyAddr, Started: true}, nil
 }
 
 // waitForTCP 在指定超时时间内轮询探测 addr 的 TCP 连通性。
-// 成功连接并立即关闭即视为“端口就绪”，否则超时返回错误。
-func waitForTCP(addr string, timeout time.Duration) error {
+// 成功连接并立即关闭即视为“端口就绪”，否则超时（或 ctx 结束）返回错误。
+func waitForTCP(ctx context.Context, addr string, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	var lastErr error
 	for time.Now().Before(deadline) {
@@ -153,7 +387,11 @@ func waitForTCP(addr string, timeout time.Duration) error {
 			return nil
 		}
 		lastErr = err
-		time.Sleep(500 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
 	}
 	return fmt.Errorf("等待端口就绪超时: %s: %w", addr, lastErr)
 }
@@ -188,11 +426,15 @@ func firstLocalEtcdEndpoint(endpoints []string) (endpoint string, readyAddr stri
 	return "", "", false
 }
 
-// buildMySQLPod 构造用于拉起 MySQL 容器的 Pod 描述。
+// storageNamespace 是存储后端静态 Pod 及其 mirror Pod 所在的 namespace
+const storageNamespace = "storage"
+
+// buildMySQLPod 构造用于拉起 MySQL 容器的静态 Pod。
 //
 // 说明：
-// - 该 Pod 不会进入 Kubernetes 调度，仅作为 runtime(Docker) 的输入描述
-// - HostPort 使用配置中的 mysql.port，容器镜像固定为 mysql:8.0
+// - 该 Pod 不会进入调度，Store 中只有 RuntimeController 维护的 mirror Pod（mysql-<node>）
+// - HostPort 使用配置中的 mysql.port，镜像默认为 mysql:8.0
+// - storage.mysql 中的 image/image_pull_policy/extra_args/extra_env/data_dir 覆盖内置默认值，data_dir 挂载到 /var/lib/mysql
 func buildMySQLPod(cfg config.Config) *corev1.Pod {
 	mysqlCfg := cfg.Storage.MySQL
 
@@ -215,8 +457,8 @@ func buildMySQLPod(cfg config.Config) *corev1.Pod {
 		)
 	}
 
-	return &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{Namespace: "storage", Name: "mysql"},
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: storageNamespace, Name: "mysql"},
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{
 				{
@@ -233,14 +475,18 @@ func buildMySQLPod(cfg config.Config) *corev1.Pod {
 			},
 		},
 	}
+	controller.ApplyContainerConfig(pod, mysqlCfg.Container, "/var/lib/mysql")
+	return pod
 }
 
-// buildEtcdPodFromEndpoint 从一个 etcd endpoint 构造用于拉起 etcd 容器的 Pod 描述。
+// buildEtcdPodFromEndpoint 从一个 etcd endpoint 构造用于拉起 etcd 容器的静态 Pod。
 //
 // 端口策略：
 // - clientPort: endpoint 端口（默认 2379）
 // - peerPort: 默认 2380；若 clientPort 不是 2379，则 peerPort = clientPort + 1
-func buildEtcdPodFromEndpoint(endpoint string) (*corev1.Pod, error) {
+//
+// 镜像默认为 quay.io/coreos/etcd:v3.5.0，etcdCfg 中的 image 等字段覆盖内置默认值，data_dir 挂载到 /etcd-data。
+func buildEtcdPodFromEndpoint(endpoint string, etcdCfg config.EtcdConfig) (*corev1.Pod, error) {
 	u, err := url.Parse(strings.TrimSpace(endpoint))
 	if err != nil {
 		return nil, err
@@ -274,8 +520,8 @@ func buildEtcdPodFromEndpoint(endpoint string) (*corev1.Pod, error) {
 		"--initial-cluster-state", "new",
 	}
 
-	return &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{Namespace: "storage", Name: "etcd"},
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: storageNamespace, Name: "etcd"},
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{
 				{
@@ -289,5 +535,7 @@ func buildEtcdPodFromEndpoint(endpoint string) (*corev1.Pod, error) {
 				},
 			},
 		},
-	}, nil
+	}
+	controller.ApplyContainerConfig(pod, etcdCfg.Container, "/etcd-data")
+	return pod, nil
 }
@@ -0,0 +1,199 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+)
+
+const (
+	// dbSuperviseInterval 检查 DB 容器状态的间隔
+	dbSuperviseInterval = 10 * time.Second
+	// dbDownThreshold 连续多少次检查到容器未运行才重启，避免与运行时自身的重启策略（--restart always）抢着重建
+	dbDownThreshold = 2
+	// dbRestartBackoffMin/Max 重启失败后的退避时间（指数增长）
+	dbRestartBackoffMin = 2 * time.Second
+	dbRestartBackoffMax = time.Minute
+	// dbReadyTimeout 每次重启后等待端口就绪的时间
+	dbReadyTimeout = 60 * time.Second
+	// dbSupervisorComponent 是记录 Event 时的组件名
+	dbSupervisorComponent = "k3-storage-supervisor"
+)
+
+// supervisorState 记录 DB 容器监控协程
+type supervisorState struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Supervise 启动 DB 容器监控：容器异常退出（被删除、崩溃后没有被运行时拉起）时按指数退避重启，
+// 等待端口就绪后通知 store 重连（实现了 storage.Reconnector 时），并在 Store 中记录一条 Warning Event。
+// 没有自动拉起的容器（远端数据库、memory 存储、没有运行时）时什么也不做；重复调用只启动一次。
+func (h *DBContainerHandle) Supervise(l logprovider.Logger, store storage.Store) {
+	if h == nil || h.Runtime == nil || h.Pod == nil || h.ReadyAddr == "" {
+		return
+	}
+	h.supervisor.mu.Lock()
+	defer h.supervisor.mu.Unlock()
+	if h.supervisor.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.supervisor.cancel = cancel
+	h.supervisor.done = make(chan struct{})
+	go h.supervise(ctx, l, store)
+	l.Infof("已开始监控存储后端容器 %s/%s", h.Pod.Namespace, h.Pod.Name)
+}
+
+// Stop 停止监控；容器由本进程拉起时同时停止容器（进程退出时调用，先停监控避免容器被再次拉起）
+func (h *DBContainerHandle) Stop(ctx context.Context) error {
+	if h == nil {
+		return nil
+	}
+	h.stopSupervisor()
+	if h.Started && h.Runtime != nil && h.Pod != nil {
+		return h.Runtime.StopContainer(ctx, h.Pod)
+	}
+	return nil
+}
+
+// stopSupervisor 停止监控协程并等待其退出；之后可以再次 Supervise
+func (h *DBContainerHandle) stopSupervisor() {
+	h.supervisor.mu.Lock()
+	cancel, done := h.supervisor.cancel, h.supervisor.done
+	h.supervisor.cancel, h.supervisor.done = nil, nil
+	h.supervisor.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Suspendable 容器由本进程拉起时可以被空闲模式暂停（Suspend/Resume）
+func (h *DBContainerHandle) Suspendable() bool {
+	return h != nil && h.Started && h.Runtime != nil && h.Pod != nil && h.ReadyAddr != ""
+}
+
+// Suspend 停止监控与容器（空闲模式，见 internal/idle）；容器不是由本进程拉起时什么也不做
+func (h *DBContainerHandle) Suspend(ctx context.Context, l logprovider.Logger) error {
+	if !h.Suspendable() {
+		return nil
+	}
+	h.stopSupervisor()
+	if err := h.Runtime.StopContainer(ctx, h.Pod); err != nil {
+		return fmt.Errorf("停止存储后端容器 %s/%s 失败: %w", h.Pod.Namespace, h.Pod.Name, err)
+	}
+	l.Infof("空闲模式，已停止存储后端容器 %s/%s", h.Pod.Namespace, h.Pod.Name)
+	return nil
+}
+
+// Resume 重新拉起 Suspend 停止的容器，等待端口就绪后通知 store 重连并恢复监控
+func (h *DBContainerHandle) Resume(ctx context.Context, l logprovider.Logger, store storage.Store) error {
+	if !h.Suspendable() {
+		return nil
+	}
+	since := time.Now()
+	if _, err := controller.EnsureStaticPod(ctx, h.Runtime, h.Pod); err != nil {
+		return fmt.Errorf("拉起存储后端容器 %s/%s 失败: %w", h.Pod.Namespace, h.Pod.Name, err)
+	}
+	if err := waitForTCP(ctx, h.ReadyAddr, dbReadyTimeout); err != nil {
+		return fmt.Errorf("存储后端容器 %s/%s 未就绪: %w", h.Pod.Namespace, h.Pod.Name, err)
+	}
+	if r, ok := store.(storage.Reconnector); ok {
+		if err := r.Reconnect(ctx); err != nil {
+			l.Warnf("存储后端重连失败: %v", err)
+		}
+	}
+	l.Infof("退出空闲模式，存储后端容器 %s/%s 已就绪（耗时 %s）", h.Pod.Namespace, h.Pod.Name, time.Since(since).Round(time.Millisecond))
+	h.Supervise(l, store)
+	return nil
+}
+
+// supervise 周期性检查容器状态，连续 dbDownThreshold 次未运行时重启
+func (h *DBContainerHandle) supervise(ctx context.Context, l logprovider.Logger, store storage.Store) {
+	defer close(h.supervisor.done)
+
+	ticker := time.NewTicker(dbSuperviseInterval)
+	defer ticker.Stop()
+
+	down := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		status, err := h.Runtime.GetContainerStatus(ctx, h.Pod)
+		if err == nil && status.Running {
+			down = 0
+			continue
+		}
+		down++
+		if down < dbDownThreshold {
+			continue
+		}
+		reason := status.Status
+		if err != nil {
+			reason = err.Error()
+		} else if status.Message != "" {
+			reason += ": " + status.Message
+		}
+		h.restart(ctx, l, store, reason)
+		down = 0
+	}
+}
+
+// restart 重启容器直到端口就绪（或 ctx 结束），然后通知 store 重连并记录 Event
+func (h *DBContainerHandle) restart(ctx context.Context, l logprovider.Logger, store storage.Store, reason string) {
+	pod := h.Pod
+	since := time.Now()
+	l.Warnf("存储后端容器 %s/%s 未在运行（%s），开始重启", pod.Namespace, pod.Name, reason)
+
+	backoff := dbRestartBackoffMin
+	attempt := 0
+	for {
+		attempt++
+		_, err := controller.EnsureStaticPod(ctx, h.Runtime, pod)
+		if err == nil {
+			err = waitForTCP(ctx, h.ReadyAddr, dbReadyTimeout)
+		}
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		l.Warnf("重启存储后端容器 %s/%s 失败（第 %d 次），%s 后重试: %v", pod.Namespace, pod.Name, attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, dbRestartBackoffMax)
+	}
+
+	elapsed := time.Since(since).Round(time.Second)
+	l.Infof("存储后端容器 %s/%s 已重启并就绪（第 %d 次尝试，耗时 %s）", pod.Namespace, pod.Name, attempt, elapsed)
+
+	if r, ok := store.(storage.Reconnector); ok {
+		if err := r.Reconnect(ctx); err != nil {
+			l.Warnf("存储后端重连失败: %v", err)
+		} else {
+			l.Info("存储后端已重连")
+		}
+	}
+
+	message := fmt.Sprintf("存储后端容器未在运行（%s），第 %d 次重启后恢复（耗时 %s）", reason, attempt, elapsed)
+	if err := controller.RecordEvent(store, pod, corev1.EventTypeWarning, "StorageRestarted", message, dbSupervisorComponent); err != nil {
+		l.Warnf("记录存储后端重启事件失败: %v", err)
+	}
+}
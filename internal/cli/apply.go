@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/client"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Apply 以 apply 语义提交 YAML/JSON 中的对象（-f，支持多文档 ---）
+func Apply(args []string) int {
+	fs := newFlagSet("apply")
+	cfgPath := commonFlags(fs)
+	file := fs.String("f", "", "要提交的 YAML 文件路径（支持多文档 ---）")
+	server := serverFlag(fs)
+	namespace := fs.String("n", "", "未写 namespace 的对象使用的 namespace（默认取 kubeconfig context 的 namespace，其次是客户端配置，否则为 default）")
+	fs.StringVar(namespace, "namespace", "", "同 -n")
+	kubeconfig := fs.String("kubeconfig", "", "从该 kubeconfig 的当前 context 读取默认 namespace")
+	kubeContext := fs.String("context", "", "从 kubeconfig 的该 context 读取默认 namespace")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+
+	if strings.TrimSpace(*file) == "" {
+		fmt.Fprintln(os.Stderr, "缺少 -f <file>")
+		return 2
+	}
+
+	base := ServerURL(*server)
+
+	p := parser.NewParser()
+	objects, gvks, err := p.ParseYAMLFile(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解析 YAML 失败: %v\n", err)
+		return 1
+	}
+	if len(objects) == 0 {
+		fmt.Fprintln(os.Stderr, "YAML 中没有可提交的资源")
+		return 2
+	}
+
+	ns, err := resolveNamespace(strings.TrimSpace(*namespace), *kubeconfig, *kubeContext)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := defaultNamespaces(objects, gvks, ns, strings.TrimSpace(*namespace) != ""); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	return ApplyObjects(base, objects, gvks)
+}
+
+// ApplyObjects 逐个以 apply 语义提交对象到 apiserver：不存在时创建，已存在时按 last-applied-configuration 注解三方合并，
+// manifest 中删除的字段会从对象中删除。跳过无法识别的对象，遇到请求失败时停止并返回 1
+func ApplyObjects(base string, objects []runtime.Object, gvks []*schema.GroupVersionKind) int {
+	cs, err := client.NewForConfig(&client.Config{Host: base, Timeout: 15 * time.Second})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建客户端失败: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+	for i, obj := range objects {
+		gvk := gvks[i]
+		if gvk == nil {
+			fmt.Fprintf(os.Stderr, "跳过第 %d 个对象：无法解析 GVK\n", i+1)
+			continue
+		}
+		meta, ok := obj.(metav1.Object)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "跳过第 %d 个对象：不支持的对象类型（无 metadata）\n", i+1)
+			continue
+		}
+
+		path, err := APIPathFor(*gvk, meta.GetNamespace())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "跳过 %s/%s：%v\n", gvk.Kind, meta.GetName(), err)
+			continue
+		}
+
+		result, err := cs.Apply(ctx, path, obj)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "提交失败 %s/%s: %v\n", gvk.Kind, meta.GetName(), err)
+			return 1
+		}
+		switch result {
+		case client.ApplyCreated:
+			fmt.Printf("已提交 %s %s/%s\n", gvk.Kind, meta.GetNamespace(), meta.GetName())
+		case client.ApplyConfigured:
+			fmt.Printf("已更新 %s %s/%s\n", gvk.Kind, meta.GetNamespace(), meta.GetName())
+		default:
+			fmt.Printf("未变化 %s %s/%s\n", gvk.Kind, meta.GetNamespace(), meta.GetName())
+		}
+	}
+
+	return 0
+}
+
+// APIPathFor 返回对象所在集合的 API 路径（namespace 级资源未写 namespace 时为 default）
+func APIPathFor(gvk schema.GroupVersionKind, namespace string) (string, error) {
+	plural, ok := resources.ResourceForKind(gvk.Kind)
+	if !ok {
+		return "", fmt.Errorf("unsupported kind: %s", gvk.Kind)
+	}
+
+	// cluster-scoped
+	if resources.IsClusterScopedKind(gvk.Kind) {
+		if gvk.Group == "" {
+			return fmt.Sprintf("/api/%s/%s", gvk.Version, plural), nil
+		}
+		return fmt.Sprintf("/apis/%s/%s/%s", gvk.Group, gvk.Version, plural), nil
+	}
+
+	// namespaced (default to "default")
+	ns := strings.TrimSpace(namespace)
+	if ns == "" {
+		ns = "default"
+	}
+
+	if gvk.Group == "" {
+		return fmt.Sprintf("/api/%s/namespaces/%s/%s", gvk.Version, ns, plural), nil
+	}
+	return fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", gvk.Group, gvk.Version, ns, plural), nil
+}
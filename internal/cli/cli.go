@@ -0,0 +1,72 @@
+// Package cli 实现只访问 apiserver 的客户端子命令（apply、get、logs、rollout、history、wait、config）与 explain。
+// k3 与轻量的 k3ctl 共用这些实现；本包不能引用 fx、存储或控制器（k3ctl 只依赖 HTTP 客户端），
+// 资源名与作用域从 pkg/resources 读取
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+// Program 是出现在用法说明与 flag 错误中的命令名，k3ctl 启动时设置为 k3ctl
+var Program = "k3"
+
+// newFlagSet 创建子命令的 FlagSet（错误输出到 stderr）
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(Program+" "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// commonFlags 注册 --config（k3 配置文件，用于读取 cluster.server 与 web.port）
+func commonFlags(fs *flag.FlagSet) *string {
+	return fs.String("config", "", "k3 配置文件路径（等价于环境变量 CONFIG_PATH），用于读取 apiserver 地址")
+}
+
+func applyConfigFlag(configPath string) {
+	if strings.TrimSpace(configPath) == "" {
+		return
+	}
+	_ = os.Setenv("CONFIG_PATH", configPath)
+}
+
+// serverFlag 注册 --server
+func serverFlag(fs *flag.FlagSet) *string {
+	return fs.String("server", "", "apiserver 地址（默认取客户端配置的 server，其次是 k3 配置，例如 http://localhost:8080）")
+}
+
+// namespaceFlag 注册 -n，默认值为客户端配置的 namespace（未设置时为 default）
+func namespaceFlag(fs *flag.FlagSet, usage string) *string {
+	return fs.String("n", DefaultNamespace(), usage)
+}
+
+// ServerURL 返回 apiserver 地址：--server 优先，其次是客户端配置（k3ctl config set server）的 server，
+// 再次是 k3 配置的 cluster.server，否则使用本机 web.port
+func ServerURL(server string) string {
+	base := strings.TrimSpace(server)
+	if base == "" {
+		if cfg, err := loadClientConfig(); err == nil {
+			base = strings.TrimSpace(cfg.Server)
+		}
+	}
+	if base == "" {
+		cfg := config.NewFileConfig()
+		base = strings.TrimSpace(cfg.Cluster.Server)
+		if base == "" {
+			base = fmt.Sprintf("http://localhost:%d", cfg.Gin.Port)
+		}
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// DefaultNamespace 返回命令默认使用的 namespace：客户端配置的 namespace，未设置时为 default
+func DefaultNamespace() string {
+	if cfg, err := loadClientConfig(); err == nil && cfg.Namespace != "" {
+		return cfg.Namespace
+	}
+	return "default"
+}
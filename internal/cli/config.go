@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// clientConfigEnv 指定客户端配置文件路径的环境变量
+const clientConfigEnv = "K3CTL_CONFIG"
+
+// ClientConfig 是客户端配置（默认 ~/.k3/k3ctl.yaml）：在没有 k3 配置文件的机器（例如运维人员的笔记本）上
+// 记录要访问的 apiserver 与默认 namespace
+type ClientConfig struct {
+	// Server apiserver 地址，例如 http://10.0.0.1:8080
+	Server string `json:"server,omitempty"`
+	// Namespace 命令默认使用的 namespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// clientConfigPath 返回客户端配置文件路径：环境变量 K3CTL_CONFIG 优先，否则为 ~/.k3/k3ctl.yaml
+func clientConfigPath() (string, error) {
+	if path := strings.TrimSpace(os.Getenv(clientConfigEnv)); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("无法确定用户目录: %w", err)
+	}
+	return filepath.Join(home, ".k3", "k3ctl.yaml"), nil
+}
+
+// loadClientConfig 读取客户端配置，文件不存在时返回空配置
+func loadClientConfig() (*ClientConfig, error) {
+	path, err := clientConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ClientConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg ClientConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// saveClientConfig 写入客户端配置（目录不存在时创建）
+func saveClientConfig(cfg *ClientConfig) (string, error) {
+	path, err := clientConfigPath()
+	if err != nil {
+		return "", err
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+// Config 查看与修改客户端配置：config view | config set <server|namespace> <value> | config unset <server|namespace>
+func Config(args []string) int {
+	usage := fmt.Sprintf("用法: %[1]s config view | %[1]s config set <server|namespace> <value> | %[1]s config unset <server|namespace>", Program)
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	cfg, err := loadClientConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取客户端配置失败: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "view":
+		path, _ := clientConfigPath()
+		fmt.Printf("# %s\n", path)
+		data, _ := yaml.Marshal(cfg)
+		fmt.Print(string(data))
+		// 同时打印命令实际使用的地址与 namespace（客户端配置未设置时取自 k3 配置或默认值）
+		fmt.Printf("# 实际使用: server=%s namespace=%s\n", ServerURL(""), DefaultNamespace())
+		return 0
+	case "set", "unset":
+		want := 3
+		if args[0] == "unset" {
+			want = 2
+		}
+		if len(args) != want {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		value := ""
+		if args[0] == "set" {
+			value = strings.TrimSpace(args[2])
+		}
+		switch args[1] {
+		case "server":
+			if value != "" && !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+				fmt.Fprintf(os.Stderr, "server 需要以 http:// 或 https:// 开头: %s\n", value)
+				return 2
+			}
+			cfg.Server = strings.TrimRight(value, "/")
+		case "namespace":
+			cfg.Namespace = value
+		default:
+			fmt.Fprintf(os.Stderr, "未知配置项: %s（支持 server、namespace）\n", args[1])
+			return 2
+		}
+		path, err := saveClientConfig(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "写入客户端配置失败: %v\n", err)
+			return 1
+		}
+		fmt.Printf("已更新 %s\n", path)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: config %s\n%s\n", args[0], usage)
+		return 2
+	}
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestClientConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "k3ctl.yaml")
+	t.Setenv(clientConfigEnv, path)
+
+	// 没有客户端配置时 namespace 为 default
+	if ns := DefaultNamespace(); ns != "default" {
+		t.Fatalf("DefaultNamespace() = %q, want default", ns)
+	}
+
+	if code := Config([]string{"set", "server", "http://10.0.0.1:8080/"}); code != 0 {
+		t.Fatalf("config set server = %d", code)
+	}
+	if code := Config([]string{"set", "namespace", "demo"}); code != 0 {
+		t.Fatalf("config set namespace = %d", code)
+	}
+	if code := Config([]string{"set", "server", "10.0.0.1:8080"}); code != 2 {
+		t.Fatalf("config set server without scheme = %d, want 2", code)
+	}
+	if got := ServerURL(""); got != "http://10.0.0.1:8080" {
+		t.Fatalf("ServerURL() = %q, want the configured server", got)
+	}
+	if got := ServerURL("http://other:8080/"); got != "http://other:8080" {
+		t.Fatalf("ServerURL(flag) = %q, want the flag to win", got)
+	}
+	if ns := DefaultNamespace(); ns != "demo" {
+		t.Fatalf("DefaultNamespace() = %q, want demo", ns)
+	}
+
+	if code := Config([]string{"unset", "namespace"}); code != 0 {
+		t.Fatalf("config unset namespace = %d", code)
+	}
+	cfg, err := loadClientConfig()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Namespace != "" || cfg.Server != "http://10.0.0.1:8080" {
+		t.Fatalf("config after unset = %+v", cfg)
+	}
+}
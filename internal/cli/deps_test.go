@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// k3ctl 只依赖 HTTP 客户端：引入 fx、存储、控制器或 apiserver 会把服务端的依赖带进客户端 binary
+func TestK3ctlDependencies(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	out, err := exec.Command(goBin, "list", "-deps", "../../cmd/k3ctl").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go list: %v\n%s", err, out)
+	}
+	forbidden := []string{
+		"go.uber.org/fx",
+		"github.com/gofiber/fiber",
+		"gorm.io/gorm",
+		"go.etcd.io/etcd",
+		"github.com/docker/docker",
+		"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage",
+		"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver",
+		"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller",
+	}
+	for _, pkg := range strings.Fields(string(out)) {
+		for _, prefix := range forbidden {
+			if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+				t.Errorf("k3ctl depends on %s", pkg)
+			}
+		}
+	}
+}
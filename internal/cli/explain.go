@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/explain"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/resources"
+)
+
+// Explain 列出资源字段的类型、说明与 k3 的支持程度（见 pkg/explain），例如 k3 explain pod.spec.containers。
+// 字段来自编译进 binary 的类型，不访问 apiserver
+func Explain(args []string) int {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "用法: %s explain <resource>[.field...] [--recursive] [--supported]\n", Program)
+		fmt.Fprintf(os.Stderr, "支持的资源: %s\n", strings.Join(resources.Resources(), ", "))
+		return 2
+	}
+	target := args[0]
+
+	fs := newFlagSet("explain")
+	recursive := fs.Bool("recursive", false, "递归列出所有子字段（只输出字段名、类型与支持程度）")
+	supported := fs.Bool("supported", false, "隐藏仅保存（写入后没有效果）的字段")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	resource, rest, _ := strings.Cut(target, ".")
+	_, gvk, err := resolveResource(resource)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "不支持的资源 %s（支持的资源: %s）\n", resource, strings.Join(resources.Resources(), ", "))
+		return 2
+	}
+	var path []string
+	if rest != "" {
+		path = strings.Split(rest, ".")
+	}
+	exp, err := explain.Explain(gvk, path, *recursive)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	printExplanation(os.Stdout, exp, *recursive, *supported)
+	return 0
+}
+
+// printExplanation 以 kubectl explain 的格式输出，每个字段后面附上支持程度
+func printExplanation(w io.Writer, exp *explain.Explanation, recursive, onlySupported bool) {
+	fmt.Fprintf(w, "KIND:     %s\n", exp.GVK.Kind)
+	fmt.Fprintf(w, "VERSION:  %s\n\n", exp.GVK.GroupVersion().String())
+	field := exp.Field
+	if len(exp.Path) > 0 {
+		fmt.Fprintf(w, "FIELD:    %s <%s>\n", field.Name, field.Type)
+	}
+	fmt.Fprintf(w, "SUPPORT:  %s\n\n", supportText(field))
+	fmt.Fprintln(w, "DESCRIPTION:")
+	description := field.Description
+	if description == "" {
+		description = "<empty>"
+	}
+	for _, line := range wrapText(description, 76) {
+		fmt.Fprintln(w, "    "+line)
+	}
+	if len(field.Fields) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nFIELDS:")
+	printFields(w, field.Fields, 1, recursive, onlySupported)
+}
+
+// printFields 输出子字段：递归时只输出字段名、类型与支持程度并按层级缩进，否则附上说明的第一句
+func printFields(w io.Writer, fields []explain.Field, depth int, recursive, onlySupported bool) {
+	indent := strings.Repeat("  ", depth)
+	for _, f := range fields {
+		if onlySupported && f.Support == explain.SupportStored {
+			continue
+		}
+		fmt.Fprintf(w, "%s%s <%s>  [%s]\n", indent, f.Name, f.Type, supportText(f))
+		if recursive {
+			printFields(w, f.Fields, depth+1, true, onlySupported)
+			continue
+		}
+		if summary := firstSentence(f.Description); summary != "" {
+			fmt.Fprintf(w, "%s  %s\n", indent, summary)
+		}
+	}
+}
+
+// supportText 支持程度，有说明时附在括号中
+func supportText(f explain.Field) string {
+	if f.Note == "" {
+		return string(f.Support)
+	}
+	return fmt.Sprintf("%s（%s）", f.Support, f.Note)
+}
+
+// firstSentence 返回说明的第一句（到第一个句号或换行为止）
+func firstSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "\n"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i+1]
+	}
+	if i := strings.Index(s, "。"); i >= 0 {
+		s = s[:i+len("。")]
+	}
+	return strings.TrimSpace(s)
+}
+
+// wrapText 按空格把每段文字折成不超过 width 个字符的行（没有空格的长词与中文不拆分）
+func wrapText(s string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(s), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/explain"
+)
+
+func TestPrintExplanation(t *testing.T) {
+	_, gvk, err := resolveResource("po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, err := explain.Explain(gvk, []string{"spec", "containers"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	printExplanation(&out, exp, false, false)
+	for _, want := range []string{
+		"KIND:     Pod\n",
+		"FIELD:    containers <[]Container>\n",
+		"SUPPORT:  部分生效\n",
+		"  image <string>  [生效]\n    Container image name.\n",
+		"  livenessProbe <Probe>  [仅保存（k3 不执行存活探针）]\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	printExplanation(&out, exp, false, true)
+	if strings.Contains(out.String(), "livenessProbe") || !strings.Contains(out.String(), "  image <string>") {
+		t.Errorf("--supported output:\n%s", out.String())
+	}
+}
+
+func TestFirstSentence(t *testing.T) {
+	for in, want := range map[string]string{
+		"Entrypoint array. Not executed within a shell.": "Entrypoint array.",
+		"设备备注。更多说明":                                      "设备备注。",
+		"first line\nsecond line":                        "first line",
+		"":                                               "",
+	} {
+		if got := firstSentence(in); got != want {
+			t.Errorf("firstSentence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
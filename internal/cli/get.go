@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// tableAccept 请求 apiserver 返回 Table（与 kubectl 相同）
+const tableAccept = "application/json;as=Table;v=v1;g=meta.k8s.io"
+
+// Get 列出资源：列（READY、STATUS、RESTARTS、AGE 等）由 apiserver 计算（见 pkg/printers），CLI 只负责对齐输出
+func Get(args []string) int {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "用法: %s get <resource> [-n namespace | -A] [-l selector] [-o wide]\n", Program)
+		return 2
+	}
+	resource := args[0]
+
+	fs := newFlagSet("get")
+	cfgPath := commonFlags(fs)
+	namespace := namespaceFlag(fs, "namespace")
+	allNamespaces := fs.Bool("A", false, "所有 namespace")
+	selector := fs.String("l", "", "标签选择器，例如 app=web")
+	output := fs.String("o", "", "输出格式：wide 同时输出次要列（例如 Pod 的 IP 与 NODE）")
+	server := serverFlag(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	applyConfigFlag(*cfgPath)
+	if *output != "" && *output != "wide" {
+		fmt.Fprintf(os.Stderr, "-o 只支持 wide: %s\n", *output)
+		return 2
+	}
+
+	ns := *namespace
+	if *allNamespaces {
+		ns = ""
+	}
+	path, _, err := resourceCollectionPath(resource, ns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "不支持的资源 %s: %v\n", resource, err)
+		return 2
+	}
+	rawURL := ServerURL(*server) + path
+	if *selector != "" {
+		rawURL += "?labelSelector=" + url.QueryEscape(*selector)
+	}
+
+	body, err := APIGet(rawURL, tableAccept)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取 %s 失败: %v\n", resource, err)
+		return 1
+	}
+	var table metav1.Table
+	if err := json.Unmarshal(body, &table); err != nil {
+		fmt.Fprintf(os.Stderr, "解析 %s 失败: %v\n", resource, err)
+		return 1
+	}
+	if len(table.Rows) == 0 {
+		if ns == "" {
+			fmt.Fprintln(os.Stderr, "No resources found")
+		} else {
+			fmt.Fprintf(os.Stderr, "No resources found in %s namespace.\n", ns)
+		}
+		return 0
+	}
+	printTable(&table, *output == "wide")
+	return 0
+}
+
+// printTable 以 kubectl 的格式输出 Table：列名大写，priority > 0 的列只在 wide 时输出
+func printTable(table *metav1.Table, wide bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	var columns []int
+	var header []string
+	for i, col := range table.ColumnDefinitions {
+		if col.Priority > 0 && !wide {
+			continue
+		}
+		columns = append(columns, i)
+		header = append(header, strings.ToUpper(col.Name))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range table.Rows {
+		cells := make([]string, 0, len(columns))
+		for _, i := range columns {
+			cell := "<none>"
+			if i < len(row.Cells) && row.Cells[i] != nil {
+				cell = fmt.Sprint(row.Cells[i])
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+}
@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/resources"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// historyResourceAliases 资源参数的简写与单数形式（其余单数形式加 s 得到资源名）
+var historyResourceAliases = map[string]string{
+	"po":                  "pods",
+	"svc":                 "services",
+	"ep":                  "endpoints",
+	"cm":                  "configmaps",
+	"no":                  "nodes",
+	"ev":                  "events",
+	"deploy":              "deployments",
+	"sts":                 "statefulsets",
+	"ds":                  "daemonsets",
+	"pc":                  "priorityclasses",
+	"priorityclass":       "priorityclasses",
+	"pdb":                 "poddisruptionbudgets",
+	"gitrepository":       "gitrepositories",
+	"poddisruptionbudget": "poddisruptionbudgets",
+	"ns":                  "namespaces",
+	"sa":                  "serviceaccounts",
+	"quota":               "resourcequotas",
+	"limits":              "limitranges",
+	"netpol":              "networkpolicies",
+	"networkpolicy":       "networkpolicies",
+}
+
+// historyRevision GET ...?history=true 返回的一个版本（见 apiserver.RevisionList）
+type historyRevision struct {
+	Revision        int64           `json:"revision"`
+	ResourceVersion string          `json:"resourceVersion"`
+	Operation       string          `json:"operation"`
+	Manager         string          `json:"manager"`
+	Timestamp       time.Time       `json:"timestamp"`
+	Object          json.RawMessage `json:"object"`
+}
+
+// History 查看对象保留的版本历史：依次打印每个版本的时间、操作与写入者，以及与上一个版本的差异
+func History(args []string) int {
+	fs := newFlagSet("history")
+	cfgPath := commonFlags(fs)
+	namespace := namespaceFlag(fs, "namespace（集群级资源忽略）")
+	server := serverFlag(fs)
+	revision := fs.Int64("revision", 0, "只打印指定版本的完整对象（YAML）")
+
+	// 支持 `k3 history deployment/web -n demo`（资源参数在 flag 之前）
+	var target string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		target, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if target == "" && fs.NArg() > 0 {
+		target = fs.Arg(0)
+	}
+	applyConfigFlag(*cfgPath)
+
+	kind, name, ok := strings.Cut(target, "/")
+	if !ok || kind == "" || name == "" {
+		fmt.Fprintf(os.Stderr, "用法: %s history <resource>/<name> [-n namespace] [--revision N]，例如 deployment/web\n", Program)
+		return 2
+	}
+	path, display, err := historyPath(kind, *namespace, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	query := url.Values{"history": {"true"}}
+	if *revision > 0 {
+		query = url.Values{"revision": {fmt.Sprint(*revision)}}
+	}
+	body, err := APIGet(ServerURL(*server)+path+"?"+query.Encode(), "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取 %s 的历史失败: %v\n", display, err)
+		return 1
+	}
+
+	if *revision > 0 {
+		out, err := yaml.JSONToYAML(body)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "解析版本 %d 失败: %v\n", *revision, err)
+			return 1
+		}
+		fmt.Print(string(out))
+		return 0
+	}
+
+	var list struct {
+		Items []historyRevision `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		fmt.Fprintf(os.Stderr, "解析历史失败: %v\n", err)
+		return 1
+	}
+	if len(list.Items) == 0 {
+		fmt.Printf("%s 没有保留的版本\n", display)
+		return 0
+	}
+	fmt.Printf("%s：保留 %d 个版本（%d-%d）\n", display, len(list.Items), list.Items[0].Revision, list.Items[len(list.Items)-1].Revision)
+
+	var prev []byte
+	for i, rev := range list.Items {
+		manager := rev.Manager
+		if manager == "" {
+			manager = "未知"
+		}
+		fmt.Printf("\n版本 %d  %s  %s  写入者 %s  resourceVersion %s\n",
+			rev.Revision, rev.Timestamp.Local().Format("2006-01-02 15:04:05"), rev.Operation, manager, rev.ResourceVersion)
+
+		cur, err := historyYAML(rev.Object)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "解析版本 %d 失败: %v\n", rev.Revision, err)
+			return 1
+		}
+		switch {
+		case i == 0:
+			fmt.Println("（最早保留的版本，用 --revision 查看完整对象）")
+		case rev.Operation == "Delete":
+			fmt.Println("（对象已删除）")
+		default:
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(string(prev)),
+				B:        difflib.SplitLines(string(cur)),
+				FromFile: fmt.Sprintf("版本 %d", list.Items[i-1].Revision),
+				ToFile:   fmt.Sprintf("版本 %d", rev.Revision),
+				Context:  3,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "生成差异失败: %v\n", err)
+				return 1
+			}
+			if diff == "" {
+				fmt.Println("（除 resourceVersion 与写入者外没有变化）")
+			} else {
+				fmt.Print(diff)
+			}
+		}
+		prev = cur
+	}
+	return 0
+}
+
+// historyPath 把 <resource>/<name> 解析为 apiserver 上对象的路径，同时返回用于显示的名称
+func historyPath(resource, namespace, name string) (string, string, error) {
+	path, gvk, err := resourceCollectionPath(resource, namespace)
+	if err != nil {
+		return "", "", err
+	}
+	display := strings.ToLower(gvk.Kind) + " " + name
+	if !resources.IsClusterScopedKind(gvk.Kind) {
+		display = strings.ToLower(gvk.Kind) + " " + namespace + "/" + name
+	}
+	return path + "/" + name, display, nil
+}
+
+// resourceCollectionPath 把资源参数（单数、复数或简写）解析为 apiserver 上的集合路径；
+// namespace 为空时返回跨 namespace 的路径，集群级资源忽略 namespace
+func resourceCollectionPath(resource, namespace string) (string, schema.GroupVersionKind, error) {
+	resource, gvk, err := resolveResource(resource)
+	if err != nil {
+		return "", gvk, err
+	}
+
+	path := "/api/" + gvk.Version
+	if gvk.Group != "" {
+		path = "/apis/" + gvk.Group + "/" + gvk.Version
+	}
+	if namespace != "" && !resources.IsClusterScopedKind(gvk.Kind) {
+		path += "/namespaces/" + namespace
+	}
+	return path + "/" + resource, gvk, nil
+}
+
+// resolveResource 把资源参数（单数、复数、简写或 Kind，不区分大小写）解析为资源名与 GVK
+func resolveResource(resource string) (string, schema.GroupVersionKind, error) {
+	resource = strings.ToLower(resource)
+	if alias, ok := historyResourceAliases[resource]; ok {
+		resource = alias
+	} else if !strings.HasSuffix(resource, "s") {
+		resource += "s"
+	}
+	gvk, err := resources.ForResource(resource)
+	return resource, gvk, err
+}
+
+// APIGet 发起 GET 请求（accept 非空时设置 Accept 头），非 2xx 时返回 apiserver 的错误信息
+func APIGet(rawURL, accept string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, e.Error)
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// historyYAML 把对象转换为用于比较的 YAML：去掉每次写入都会变化的 resourceVersion 与 managedFields
+func historyYAML(object []byte) ([]byte, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(object, &obj); err != nil {
+		return nil, err
+	}
+	if meta, ok := obj["metadata"].(map[string]interface{}); ok {
+		delete(meta, "resourceVersion")
+		delete(meta, "managedFields")
+	}
+	return yaml.Marshal(obj)
+}
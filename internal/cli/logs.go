@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Logs 打印 Pod 中容器的日志（GET /api/v1/namespaces/<ns>/pods/<name>/log）；-f 持续输出直到 Ctrl+C 或 Pod 结束
+func Logs(args []string) int {
+	fs := newFlagSet("logs")
+	cfgPath := commonFlags(fs)
+	namespace := namespaceFlag(fs, "namespace")
+	server := serverFlag(fs)
+	container := fs.String("c", "", "容器名（Pod 只有一个容器时可以省略）")
+	follow := fs.Bool("f", false, "持续输出新的日志")
+	tail := fs.Int64("tail", -1, "只输出最后 N 行（-1 表示全部）")
+	since := fs.Duration("since", 0, "只输出最近这段时间的日志，例如 10m")
+	timestamps := fs.Bool("timestamps", false, "每行带上时间戳")
+
+	// 支持 `k3ctl logs web-7d9f -f`（Pod 名在 flag 之前）
+	var target string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		target, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if target == "" && fs.NArg() > 0 {
+		target = fs.Arg(0)
+	}
+	applyConfigFlag(*cfgPath)
+
+	// 与 kubectl 相同，接受 <pod> 与 pod/<pod>
+	name := target
+	if kind, rest, ok := strings.Cut(target, "/"); ok {
+		if kind != "pod" && kind != "pods" && kind != "po" {
+			fmt.Fprintf(os.Stderr, "logs 只支持 Pod: %s\n", target)
+			return 2
+		}
+		name = rest
+	}
+	if name == "" {
+		fmt.Fprintf(os.Stderr, "用法: %s logs <pod> [-c container] [-f] [--tail N] [--since 10m] [-n namespace]\n", Program)
+		return 2
+	}
+
+	query := url.Values{}
+	if *container != "" {
+		query.Set("container", *container)
+	}
+	if *follow {
+		query.Set("follow", "true")
+	}
+	if *timestamps {
+		query.Set("timestamps", "true")
+	}
+	if *tail >= 0 {
+		query.Set("tailLines", strconv.FormatInt(*tail, 10))
+	}
+	if *since > 0 {
+		query.Set("sinceSeconds", strconv.FormatInt(int64((*since+time.Second-1)/time.Second), 10))
+	}
+	rawURL := fmt.Sprintf("%s/api/v1/namespaces/%s/pods/%s/log?%s",
+		ServerURL(*server), url.PathEscape(*namespace), url.PathEscape(name), query.Encode())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取日志失败: %v\n", err)
+		return 1
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取日志失败: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			fmt.Fprintf(os.Stderr, "获取 pod %s/%s 的日志失败: HTTP %d: %s\n", *namespace, name, resp.StatusCode, e.Error)
+		} else {
+			fmt.Fprintf(os.Stderr, "获取 pod %s/%s 的日志失败: HTTP %d: %s\n", *namespace, name, resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return 1
+	}
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "读取日志失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// resolveNamespace 决定 apply 时未写 namespace 的对象使用的 namespace：
+// --namespace 优先；其次是指定了 --kubeconfig/--context 时该 context 的 namespace；最后为客户端配置的 namespace（默认 default）
+func resolveNamespace(flagNamespace, kubeconfig, context string) (string, error) {
+	if flagNamespace != "" {
+		return flagNamespace, nil
+	}
+	if kubeconfig != "" || context != "" {
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		if kubeconfig != "" {
+			rules.ExplicitPath = kubeconfig
+		}
+		loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: context})
+		ns, _, err := loader.Namespace()
+		if err != nil {
+			return "", fmt.Errorf("读取 kubeconfig 的 namespace 失败: %w", err)
+		}
+		if ns != "" {
+			return ns, nil
+		}
+	}
+	return DefaultNamespace(), nil
+}
+
+// defaultNamespaces 为未写 namespace 的 namespace 级对象填上 namespace，集群级对象清空 namespace。
+// explicit 表示 namespace 来自 --namespace：对象中写了不同的 namespace 时报错（与 kubectl 相同）
+func defaultNamespaces(objects []runtime.Object, gvks []*schema.GroupVersionKind, namespace string, explicit bool) error {
+	for i, obj := range objects {
+		meta, ok := obj.(metav1.Object)
+		if !ok || gvks[i] == nil {
+			continue
+		}
+		if resources.IsClusterScopedKind(gvks[i].Kind) {
+			meta.SetNamespace("")
+			continue
+		}
+		switch {
+		case meta.GetNamespace() == "":
+			meta.SetNamespace(namespace)
+		case explicit && meta.GetNamespace() != namespace:
+			return fmt.Errorf("%s/%s 的 namespace %q 与 --namespace %q 不一致", gvks[i].Kind, meta.GetName(), meta.GetNamespace(), namespace)
+		}
+	}
+	return nil
+}
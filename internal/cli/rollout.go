@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/client"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Rollout 查看工作负载的发布状态
+func Rollout(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprintf(os.Stderr, "用法: %s rollout status|pause|resume deployment/<name> [-n namespace]\n", Program)
+		return 2
+	}
+	switch args[0] {
+	case "status":
+		return cmdRolloutStatus(args[1:])
+	case "pause":
+		return cmdRolloutPause(args[1:], true)
+	case "resume":
+		return cmdRolloutPause(args[1:], false)
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: rollout %s\n", args[0])
+		return 2
+	}
+}
+
+// cmdRolloutStatus 等待 Deployment 发布完成：控制器已处理最新 generation，且所有副本已更新并可用
+func cmdRolloutStatus(args []string) int {
+	fs := newFlagSet("rollout status")
+	cfgPath := commonFlags(fs)
+	namespace := namespaceFlag(fs, "namespace")
+	server := serverFlag(fs)
+	watch := fs.Bool("watch", true, "持续等待直到发布完成；false 时只打印一次当前状态")
+	timeout := fs.Duration("timeout", 5*time.Minute, "等待超时时间")
+
+	name, code := parseRolloutTarget(fs, args)
+	if code != 0 {
+		return code
+	}
+	applyConfigFlag(*cfgPath)
+
+	cs, err := client.NewForConfig(&client.Config{Host: ServerURL(*server)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建客户端失败: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var last string
+	for {
+		deployment, err := cs.AppsV1().Deployments(*namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "获取 deployment %s/%s 失败: %v\n", *namespace, name, err)
+			return 1
+		}
+		msg, done, err := deploymentRolloutStatus(deployment)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if msg != last {
+			fmt.Println(msg)
+			last = msg
+		}
+		if done {
+			return 0
+		}
+		if deployment.Spec.Paused && deployment.Status.ObservedGeneration >= deployment.Generation {
+			// 暂停期间不会继续发布，等待没有意义
+			return 1
+		}
+		if !*watch {
+			return 1
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(os.Stderr, "等待超时（%s）\n", *timeout)
+			return 1
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// parseRolloutTarget 解析 deployment/<name> 参数，支持 `k3 rollout status deployment/web -n demo`（资源参数在 flag 之前）；
+// 参数错误时返回非 0 的退出码
+func parseRolloutTarget(fs *flag.FlagSet, args []string) (string, int) {
+	var target string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		target, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", 2
+	}
+	if target == "" && fs.NArg() > 0 {
+		target = fs.Arg(0)
+	}
+
+	kind, name, ok := strings.Cut(target, "/")
+	if !ok || name == "" {
+		fmt.Fprintln(os.Stderr, "缺少资源参数，例如 deployment/web")
+		return "", 2
+	}
+	if kind != "deployment" && kind != "deployments" && kind != "deploy" {
+		fmt.Fprintf(os.Stderr, "暂不支持的资源类型: %s（目前只支持 deployment）\n", kind)
+		return "", 2
+	}
+	return name, 0
+}
+
+// cmdRolloutPause 暂停或恢复 Deployment 的发布（设置 spec.paused）：暂停期间模板的变更不会发布到副本，副本数仍按 spec.replicas 维持
+func cmdRolloutPause(args []string, paused bool) int {
+	verb := "resume"
+	if paused {
+		verb = "pause"
+	}
+	fs := newFlagSet("rollout " + verb)
+	cfgPath := commonFlags(fs)
+	namespace := namespaceFlag(fs, "namespace")
+	server := serverFlag(fs)
+
+	name, code := parseRolloutTarget(fs, args)
+	if code != 0 {
+		return code
+	}
+	applyConfigFlag(*cfgPath)
+
+	cs, err := client.NewForConfig(&client.Config{Host: ServerURL(*server)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建客户端失败: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	deployments := cs.AppsV1().Deployments(*namespace)
+	deployment, err := deployments.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取 deployment %s/%s 失败: %v\n", *namespace, name, err)
+		return 1
+	}
+	if deployment.Spec.Paused == paused {
+		if paused {
+			fmt.Fprintf(os.Stderr, "deployment %s/%s 已经处于暂停状态\n", *namespace, name)
+		} else {
+			fmt.Fprintf(os.Stderr, "deployment %s/%s 没有暂停\n", *namespace, name)
+		}
+		return 1
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"paused":%t}}`, paused)
+	if _, err := deployments.Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		fmt.Fprintf(os.Stderr, "更新 deployment %s/%s 失败: %v\n", *namespace, name, err)
+		return 1
+	}
+	if paused {
+		fmt.Printf("deployment.apps/%s paused\n", name)
+	} else {
+		fmt.Printf("deployment.apps/%s resumed\n", name)
+	}
+	return 0
+}
+
+// deploymentRolloutStatus 返回发布进度说明以及是否已完成（判断条件与 kubectl rollout status 一致）；
+// 发布超过 progressDeadlineSeconds 时返回错误
+func deploymentRolloutStatus(d *appsv1.Deployment) (string, bool, error) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return fmt.Sprintf("等待 deployment %q 的变更被控制器处理（generation %d，已处理 %d）...", d.Name, d.Generation, d.Status.ObservedGeneration), false, nil
+	}
+	for _, cond := range d.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return "", false, fmt.Errorf("deployment %q 发布超过期限（progressDeadlineSeconds）：%d/%d 个副本已更新，%d 个可用",
+				d.Name, d.Status.UpdatedReplicas, d.Status.Replicas, d.Status.AvailableReplicas)
+		}
+	}
+	if d.Spec.Paused {
+		return fmt.Sprintf("deployment %q 已暂停（%d/%d 个副本已更新），执行 %s rollout resume 继续发布", d.Name, d.Status.UpdatedReplicas, d.Status.Replicas, Program), false, nil
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	switch {
+	case d.Status.UpdatedReplicas < replicas:
+		return fmt.Sprintf("等待 deployment %q 发布完成：%d/%d 个副本已更新...", d.Name, d.Status.UpdatedReplicas, replicas), false, nil
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		return fmt.Sprintf("等待 deployment %q 发布完成：%d 个旧副本等待终止...", d.Name, d.Status.Replicas-d.Status.UpdatedReplicas), false, nil
+	case d.Status.Replicas > replicas:
+		return fmt.Sprintf("等待 deployment %q 发布完成：%d 个多余副本等待终止...", d.Name, d.Status.Replicas-replicas), false, nil
+	case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+		return fmt.Sprintf("等待 deployment %q 发布完成：%d/%d 个副本可用...", d.Name, d.Status.AvailableReplicas, d.Status.UpdatedReplicas), false, nil
+	}
+	return fmt.Sprintf("deployment %q 已成功发布", d.Name), true, nil
+}
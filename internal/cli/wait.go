@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// errWaitNotFound 等待条件的对象不存在（与 kubectl wait 相同，不等待对象被创建）
+var errWaitNotFound = errors.New("not found")
+
+// waitCondition 是 --for 的取值：delete，或 condition=<type>[=<status>]（status 默认为 True）
+type waitCondition struct {
+	delete        bool
+	conditionType string
+	status        string
+}
+
+// parseWaitFor 解析 --for
+func parseWaitFor(value string) (waitCondition, error) {
+	if value == "delete" {
+		return waitCondition{delete: true}, nil
+	}
+	rest, ok := strings.CutPrefix(value, "condition=")
+	if !ok || rest == "" {
+		return waitCondition{}, fmt.Errorf("--for 只支持 delete 或 condition=<type>[=<status>]: %q", value)
+	}
+	conditionType, status, ok := strings.Cut(rest, "=")
+	if !ok {
+		status = "True"
+	}
+	if conditionType == "" || status == "" {
+		return waitCondition{}, fmt.Errorf("--for 只支持 delete 或 condition=<type>[=<status>]: %q", value)
+	}
+	return waitCondition{conditionType: conditionType, status: status}, nil
+}
+
+// met 判断对象的 status.conditions 中是否有满足条件的一项（type 与 status 不区分大小写，与 kubectl 相同）
+func (w waitCondition) met(obj map[string]any) bool {
+	status, _ := obj["status"].(map[string]any)
+	conditions, _ := status["conditions"].([]any)
+	for _, c := range conditions {
+		condition, _ := c.(map[string]any)
+		conditionType, _ := condition["type"].(string)
+		conditionStatus, _ := condition["status"].(string)
+		if strings.EqualFold(conditionType, w.conditionType) {
+			return strings.EqualFold(conditionStatus, w.status)
+		}
+	}
+	return false
+}
+
+// Wait 等待资源满足条件：--for=condition=<type>[=<status>] 或 --for=delete。
+// 基于 watch API（sendInitialEvents=true 在同一个请求中先拿到当前对象，再接收之后的变更），不轮询
+func Wait(args []string) int {
+	fs := newFlagSet("wait")
+	cfgPath := commonFlags(fs)
+	namespace := namespaceFlag(fs, "namespace（集群级资源忽略）")
+	server := serverFlag(fs)
+	forValue := fs.String("for", "", "等待的条件：condition=<type>[=<status>] 或 delete")
+	timeout := fs.Duration("timeout", 30*time.Second, "等待超时时间（所有资源共用）")
+
+	// 资源参数与 flag 可以任意交错：`k3 wait --for=delete pod/web --timeout=60s`
+	var targets []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		targets = append(targets, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	applyConfigFlag(*cfgPath)
+
+	usage := "用法: " + Program + " wait --for=condition=<type>[=<status>]|delete <resource>/<name>... [-n namespace] [--timeout 30s]"
+	if len(targets) == 0 || *forValue == "" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	condition, err := parseWaitFor(*forValue)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for _, target := range targets {
+		if kind, name, ok := strings.Cut(target, "/"); !ok || kind == "" || name == "" {
+			fmt.Fprintf(os.Stderr, "资源参数应为 <resource>/<name>: %s\n%s\n", target, usage)
+			return 2
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	base := ServerURL(*server)
+	code := 0
+	for _, target := range targets {
+		kind, name, _ := strings.Cut(target, "/")
+		err := waitForResource(ctx, base, kind, *namespace, name, condition)
+		switch {
+		case err == nil && condition.delete:
+			fmt.Printf("%s deleted\n", target)
+		case err == nil:
+			fmt.Printf("%s condition met\n", target)
+		case errors.Is(err, context.DeadlineExceeded):
+			fmt.Fprintf(os.Stderr, "等待 %s 超时（%s）\n", target, *timeout)
+			code = 1
+		default:
+			fmt.Fprintf(os.Stderr, "等待 %s 失败: %v\n", target, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// waitForResource 监听单个对象直到满足条件；watch 流被 apiserver 关闭（超时或重启）时重新建立
+func waitForResource(ctx context.Context, base, kind, namespace, name string, condition waitCondition) error {
+	path, err := resourceWatchPath(kind, namespace)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("fieldSelector", "metadata.name="+name)
+	query.Set("sendInitialEvents", "true")
+	rawURL := base + path + "?" + query.Encode()
+
+	for {
+		done, err := watchUntil(ctx, rawURL, condition)
+		if done || err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// watchUntil 建立一次 watch 并处理事件：满足条件时返回 true；流正常结束时返回 false，由调用方重新建立
+func watchUntil(ctx context.Context, rawURL string, condition waitCondition) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return false, fmt.Errorf("HTTP %d: %s", resp.StatusCode, e.Error)
+	}
+
+	// exists 在初始事件结束（BOOKMARK）之前记录对象是否存在
+	exists := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		payload, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Type   string         `json:"type"`
+			Object map[string]any `json:"object"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(payload)), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			exists = true
+			if !condition.delete && condition.met(event.Object) {
+				return true, nil
+			}
+		case "DELETED":
+			exists = false
+			if condition.delete {
+				return true, nil
+			}
+		case "BOOKMARK":
+			if !initialEventsEnd(event.Object) || exists {
+				continue
+			}
+			if condition.delete {
+				return true, nil
+			}
+			return false, errWaitNotFound
+		}
+	}
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	return false, scanner.Err()
+}
+
+// initialEventsEnd 判断 BOOKMARK 是否标记初始事件结束
+func initialEventsEnd(obj map[string]any) bool {
+	metadata, _ := obj["metadata"].(map[string]any)
+	annotations, _ := metadata["annotations"].(map[string]any)
+	return annotations[metav1.InitialEventsAnnotationKey] == "true"
+}
+
+// resourceWatchPath 返回资源的 watch 路径（/api/v1/watch/namespaces/<ns>/pods 之类）
+func resourceWatchPath(resource, namespace string) (string, error) {
+	path, gvk, err := resourceCollectionPath(resource, namespace)
+	if err != nil {
+		return "", err
+	}
+	prefix := "/api/" + gvk.Version
+	if gvk.Group != "" {
+		prefix = "/apis/" + gvk.Group + "/" + gvk.Version
+	}
+	return prefix + "/watch" + strings.TrimPrefix(path, prefix), nil
+}
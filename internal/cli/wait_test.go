@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseWaitFor(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  waitCondition
+		err   bool
+	}{
+		{value: "delete", want: waitCondition{delete: true}},
+		{value: "condition=Ready", want: waitCondition{conditionType: "Ready", status: "True"}},
+		{value: "condition=Ready=false", want: waitCondition{conditionType: "Ready", status: "false"}},
+		{value: "condition=Progressing=False", want: waitCondition{conditionType: "Progressing", status: "False"}},
+		{value: "condition=Available=Unknown", want: waitCondition{conditionType: "Available", status: "Unknown"}},
+		{value: "condition=", err: true},
+		{value: "condition==True", err: true},
+		{value: "condition=Ready=", err: true},
+		{value: "Ready", err: true},
+		{value: "deleted", err: true},
+		{value: "", err: true},
+		// 与 kubectl 不同，不支持 jsonpath
+		{value: "jsonpath={.status.phase}=Running", err: true},
+		{value: "jsonpath=", err: true},
+	} {
+		got, err := parseWaitFor(tc.value)
+		if tc.err {
+			if err == nil || !strings.Contains(err.Error(), "--for 只支持 delete 或 condition=") {
+				t.Errorf("parseWaitFor(%q) = %+v, %v; want error", tc.value, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseWaitFor(%q) = %+v, %v; want %+v", tc.value, got, err, tc.want)
+		}
+	}
+}
+
+func TestWaitConditionMet(t *testing.T) {
+	withConditions := func(conditions ...any) map[string]any {
+		return map[string]any{"status": map[string]any{"conditions": conditions}}
+	}
+	condition := func(conditionType, status string) map[string]any {
+		return map[string]any{"type": conditionType, "status": status}
+	}
+	ready := waitCondition{conditionType: "Ready", status: "True"}
+	notReady := waitCondition{conditionType: "Ready", status: "false"}
+
+	for _, tc := range []struct {
+		name      string
+		condition waitCondition
+		obj       map[string]any
+		want      bool
+	}{
+		{"ready", ready, withConditions(condition("Initialized", "True"), condition("Ready", "True")), true},
+		{"not ready yet", ready, withConditions(condition("Initialized", "True"), condition("Ready", "False")), false},
+		{"type and status are case insensitive", waitCondition{conditionType: "ready", status: "true"}, withConditions(condition("Ready", "True")), true},
+		{"waiting for False", notReady, withConditions(condition("Ready", "False")), true},
+		{"waiting for False, still True", notReady, withConditions(condition("Ready", "True")), false},
+		{"condition missing", ready, withConditions(condition("Initialized", "True")), false},
+		{"no conditions", ready, withConditions(), false},
+		{"no status", ready, map[string]any{"metadata": map[string]any{"name": "web"}}, false},
+		{"malformed conditions", ready, map[string]any{"status": map[string]any{"conditions": "Ready"}}, false},
+		{"malformed condition entry", ready, withConditions("Ready", condition("Ready", "True")), true},
+	} {
+		if got := tc.condition.met(tc.obj); got != tc.want {
+			t.Errorf("%s: met = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestInitialEventsEnd(t *testing.T) {
+	bookmark := func(annotations map[string]any) map[string]any {
+		return map[string]any{"metadata": map[string]any{"annotations": annotations}}
+	}
+	if !initialEventsEnd(bookmark(map[string]any{metav1.InitialEventsAnnotationKey: "true"})) {
+		t.Error("initial-events-end bookmark not recognised")
+	}
+	for _, obj := range []map[string]any{
+		bookmark(nil),
+		bookmark(map[string]any{metav1.InitialEventsAnnotationKey: "false"}),
+		{},
+	} {
+		if initialEventsEnd(obj) {
+			t.Errorf("initialEventsEnd(%v) = true", obj)
+		}
+	}
+}
+
+func TestResourceWatchPath(t *testing.T) {
+	for _, tc := range []struct {
+		resource, namespace string
+		want                string
+	}{
+		{"pod", "default", "/api/v1/watch/namespaces/default/pods"},
+		{"pods", "", "/api/v1/watch/pods"},
+		{"deployment", "prod", "/apis/apps/v1/watch/namespaces/prod/deployments"},
+		{"node", "default", "/api/v1/watch/nodes"},
+	} {
+		got, err := resourceWatchPath(tc.resource, tc.namespace)
+		if err != nil || got != tc.want {
+			t.Errorf("resourceWatchPath(%q, %q) = %q, %v; want %q", tc.resource, tc.namespace, got, err, tc.want)
+		}
+	}
+	if _, err := resourceWatchPath("widgets", "default"); err == nil {
+		t.Error("unknown resource did not fail")
+	}
+}
@@ -0,0 +1,196 @@
+package clusterconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/fx"
+)
+
+// rewatchInterval watch 通道关闭（例如存储重连）后重新 watch 的间隔
+const rewatchInterval = 5 * time.Second
+
+// Module 提供 *Watcher 并在进程启动时开始 watch，同时按 spec.logLevel 切换本进程的日志级别。
+// 使用 controller.Module 的进程都需要包含它
+var Module = fx.Options(
+	fx.Provide(NewWatcher),
+	fx.Invoke(func(lc fx.Lifecycle, cfg config.Config, logger logprovider.Logger, w *Watcher) {
+		w.OnChange(func(spec k3v1.ClusterConfigurationSpec) {
+			applyLogLevel(logger, cfg.Log.Level, spec.LogLevel)
+		})
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error { return w.Start() },
+			OnStop:  w.Stop,
+		})
+	}),
+)
+
+// applyLogLevel 切换日志级别：ClusterConfiguration 没有设置时恢复配置文件的 log.level
+func applyLogLevel(logger logprovider.Logger, fileLevel, level string) {
+	if level == "" {
+		level = fileLevel
+	}
+	if err := logprovider.SetLevel(level); err != nil {
+		logger.Warnf("切换日志级别失败: %v", err)
+		return
+	}
+	logger.Infof("日志级别: %s", level)
+}
+
+// Watcher 监听 k3.io/v1 ClusterConfiguration（名称为 cluster），spec 变化（包括删除对象）时
+// 依次调用注册的回调。回调在 watch 协程中串行执行，不应长时间阻塞
+type Watcher struct {
+	store  storage.Store
+	logger logprovider.Logger
+
+	mu       sync.Mutex
+	spec     k3v1.ClusterConfigurationSpec
+	handlers []func(k3v1.ClusterConfigurationSpec)
+
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// NewWatcher 创建 ClusterConfiguration watcher，Start 之前 Current 返回空 spec（全部使用配置文件）
+func NewWatcher(store storage.Store, logger logprovider.Logger) *Watcher {
+	return &Watcher{
+		store:  store,
+		logger: logger,
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// OnChange 注册 spec 变化时的回调（Start 读取到已有对象时也会调用）
+func (w *Watcher) OnChange(fn func(spec k3v1.ClusterConfigurationSpec)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, fn)
+}
+
+// Current 返回当前生效的 spec（没有 ClusterConfiguration 时为空）
+func (w *Watcher) Current() k3v1.ClusterConfigurationSpec {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return *w.spec.DeepCopy()
+}
+
+// Start 读取当前的 ClusterConfiguration 并开始 watch
+func (w *Watcher) Start() error {
+	watchCh, err := w.store.Watch(k3v1.ClusterConfigurationGVK, "", "")
+	if err != nil {
+		close(w.done)
+		return fmt.Errorf("watch ClusterConfiguration 失败: %w", err)
+	}
+	w.resync()
+	go w.run(watchCh)
+	return nil
+}
+
+// Stop 停止 watch
+func (w *Watcher) Stop(ctx context.Context) error {
+	close(w.stopCh)
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (w *Watcher) run(watchCh <-chan storage.ResourceEvent) {
+	defer close(w.done)
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case event, ok := <-watchCh:
+			if !ok {
+				// 通道关闭后重新 watch，并重新读取对象补上期间错过的变化
+				if watchCh = w.rewatch(); watchCh == nil {
+					return
+				}
+				w.resync()
+				continue
+			}
+			w.handleEvent(event)
+		}
+	}
+}
+
+// rewatch 每隔 rewatchInterval 重试 watch，Stop 后返回 nil
+func (w *Watcher) rewatch() <-chan storage.ResourceEvent {
+	for {
+		select {
+		case <-w.stopCh:
+			return nil
+		case <-time.After(rewatchInterval):
+		}
+		watchCh, err := w.store.Watch(k3v1.ClusterConfigurationGVK, "", "")
+		if err == nil {
+			return watchCh
+		}
+		w.logger.Warnf("重新 watch ClusterConfiguration 失败: %v", err)
+	}
+}
+
+func (w *Watcher) handleEvent(event storage.ResourceEvent) {
+	cc, ok := event.Object.(*k3v1.ClusterConfiguration)
+	if !ok || cc.Name != k3v1.ClusterConfigurationName {
+		return
+	}
+	switch event.Type {
+	case storage.EventAdded, storage.EventModified:
+		w.apply(cc.Spec)
+	case storage.EventDeleted:
+		w.apply(k3v1.ClusterConfigurationSpec{})
+	}
+}
+
+// resync 按 Store 中的对象更新 spec（对象不存在时为空）
+func (w *Watcher) resync() {
+	obj, err := w.store.Get(k3v1.ClusterConfigurationGVK, "", k3v1.ClusterConfigurationName)
+	if err != nil {
+		// 存储不可用时保持当前配置，其余错误视为对象不存在
+		if storage.IsBackendError(err) {
+			w.logger.Warnf("读取 ClusterConfiguration 失败: %v", err)
+		} else {
+			w.apply(k3v1.ClusterConfigurationSpec{})
+		}
+		return
+	}
+	if cc, ok := obj.(*k3v1.ClusterConfiguration); ok {
+		w.apply(cc.Spec)
+	}
+}
+
+// apply spec 有变化时更新并调用回调
+func (w *Watcher) apply(spec k3v1.ClusterConfigurationSpec) {
+	w.mu.Lock()
+	if reflect.DeepEqual(normalize(spec), normalize(w.spec)) {
+		w.mu.Unlock()
+		return
+	}
+	w.spec = *spec.DeepCopy()
+	handlers := append([]func(k3v1.ClusterConfigurationSpec){}, w.handlers...)
+	w.mu.Unlock()
+
+	w.logger.Infof("ClusterConfiguration 已变化，应用新的运行时配置")
+	for _, fn := range handlers {
+		fn(*spec.DeepCopy())
+	}
+}
+
+// normalize 把空 map 视为未设置，避免 {} 与 null 被当作变化
+func normalize(spec k3v1.ClusterConfigurationSpec) k3v1.ClusterConfigurationSpec {
+	if len(spec.Controllers) == 0 {
+		spec.Controllers = nil
+	}
+	return spec
+}
@@ -0,0 +1,91 @@
+package clusterconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newClusterConfiguration(name string, spec k3v1.ClusterConfigurationSpec) *k3v1.ClusterConfiguration {
+	return &k3v1.ClusterConfiguration{
+		TypeMeta:   metav1.TypeMeta{APIVersion: k3v1.SchemeGroupVersion.String(), Kind: "ClusterConfiguration"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       spec,
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	store := storage.NewMemoryStore()
+	// 启动前已经存在的对象在 Start 时生效
+	if err := store.Create(k3v1.ClusterConfigurationGVK, newClusterConfiguration(k3v1.ClusterConfigurationName, k3v1.ClusterConfigurationSpec{LogLevel: "debug"})); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	w := NewWatcher(store, logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()})
+	changes := make(chan k3v1.ClusterConfigurationSpec, 10)
+	w.OnChange(func(spec k3v1.ClusterConfigurationSpec) { changes <- spec })
+	if err := w.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer w.Stop(context.Background())
+
+	next := func() k3v1.ClusterConfigurationSpec {
+		t.Helper()
+		select {
+		case spec := <-changes:
+			return spec
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for change")
+			return k3v1.ClusterConfigurationSpec{}
+		}
+	}
+
+	if spec := next(); spec.LogLevel != "debug" {
+		t.Fatalf("initial spec = %+v", spec)
+	}
+
+	// 其他名称的对象被忽略
+	if err := store.Create(k3v1.ClusterConfigurationGVK, newClusterConfiguration("other", k3v1.ClusterConfigurationSpec{LogLevel: "error"})); err != nil {
+		t.Fatalf("create other: %v", err)
+	}
+
+	cc := newClusterConfiguration(k3v1.ClusterConfigurationName, k3v1.ClusterConfigurationSpec{
+		LogLevel:    "debug",
+		Controllers: map[string]bool{"ImageGC": false},
+		Scheduler:   &k3v1.SchedulerPolicy{Strategy: k3v1.SchedulingStrategyBinPack},
+	})
+	if err := store.Update(k3v1.ClusterConfigurationGVK, cc); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	spec := next()
+	if spec.Controllers["ImageGC"] || spec.Scheduler == nil || spec.Scheduler.Strategy != k3v1.SchedulingStrategyBinPack {
+		t.Fatalf("updated spec = %+v", spec)
+	}
+	if got := w.Current(); got.Scheduler == nil || got.Scheduler.Strategy != k3v1.SchedulingStrategyBinPack {
+		t.Fatalf("Current() = %+v", got)
+	}
+
+	// spec 没有变化的更新不触发回调
+	if err := store.Update(k3v1.ClusterConfigurationGVK, cc); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// 删除后恢复为空 spec（全部使用配置文件）
+	if err := store.Delete(k3v1.ClusterConfigurationGVK, "", k3v1.ClusterConfigurationName); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if spec := next(); spec.LogLevel != "" || spec.Controllers != nil || spec.Scheduler != nil {
+		t.Fatalf("spec after delete = %+v", spec)
+	}
+	select {
+	case spec := <-changes:
+		t.Fatalf("unexpected change %+v", spec)
+	default:
+	}
+}
@@ -0,0 +1,181 @@
+// Package clusterinfo 维护共享存储中的集群身份（k3.io/v1 ClusterInfo）：第一个连接到存储的节点创建它，
+// 之后加入的节点在写入存储之前检查集群 ID 与 k3 版本差异
+package clusterinfo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// MaxMinorSkew 加入的节点与集群中其他节点的 k3 版本最多相差的次版本数（主版本必须相同）
+const MaxMinorSkew = 1
+
+// StaleMemberAge 节点对象已不存在、且超过这段时间没有重新加入的成员不再参与版本检查，并从 status 中移除
+const StaleMemberAge = 10 * time.Minute
+
+// ErrIncompatible 节点与共享存储中的集群不兼容（集群 ID 不同或版本相差过大），不能加入
+var ErrIncompatible = errors.New("incompatible with the cluster")
+
+var nodeGVK = schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+
+// Identity 是加入集群的节点
+type Identity struct {
+	// NodeName 节点名
+	NodeName string
+	// ClusterID 配置的 cluster.id，为空时不检查
+	ClusterID string
+	// StorageBackend 存储类型（创建集群时记录）
+	StorageBackend string
+	// K3Version 节点运行的 k3 版本
+	K3Version string
+	// SchemaVersion 节点支持的存储 schema 版本
+	SchemaVersion int
+}
+
+// Get 读取 ClusterInfo，不存在时返回 nil
+func Get(store storage.Store) (*k3v1.ClusterInfo, error) {
+	obj, err := store.Get(k3v1.ClusterInfoGVK, "", k3v1.ClusterInfoName)
+	if err != nil {
+		// Store 没有导出 not found 错误类型，按错误信息判断
+		if strings.Contains(err.Error(), "not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	info, ok := obj.(*k3v1.ClusterInfo)
+	if !ok {
+		return nil, fmt.Errorf("ClusterInfo 类型错误: %T", obj)
+	}
+	return info, nil
+}
+
+// Check 只读检查节点能否加入存储中的集群（还没有 ClusterInfo 时总是可以），不兼容时返回包装 ErrIncompatible 的错误
+func Check(store storage.Store, id Identity, now time.Time) error {
+	info, err := Get(store)
+	if err != nil || info == nil {
+		return err
+	}
+	members, err := activeMembers(store, info, id, now)
+	if err != nil {
+		return err
+	}
+	return checkCompatible(info, id, members)
+}
+
+// Join 加入集群：没有 ClusterInfo 时创建（本节点即创建者），检查兼容性后在 status 中记录本节点的版本
+func Join(store storage.Store, id Identity, now time.Time) (*k3v1.ClusterInfo, error) {
+	info, err := Get(store)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		info = &k3v1.ClusterInfo{
+			TypeMeta:   metav1.TypeMeta{APIVersion: k3v1.SchemeGroupVersion.String(), Kind: "ClusterInfo"},
+			ObjectMeta: metav1.ObjectMeta{Name: k3v1.ClusterInfoName},
+			Spec: k3v1.ClusterInfoSpec{
+				ClusterUID:     uuid.NewString(),
+				ClusterID:      id.ClusterID,
+				CreationTime:   metav1.NewTime(now),
+				K3Version:      id.K3Version,
+				StorageBackend: id.StorageBackend,
+			},
+		}
+		if err := store.Create(k3v1.ClusterInfoGVK, info); err != nil {
+			if !strings.Contains(err.Error(), "already exists") {
+				return nil, fmt.Errorf("创建 ClusterInfo 失败: %w", err)
+			}
+			// 另一个节点同时创建了集群
+			if info, err = Get(store); err != nil || info == nil {
+				return nil, fmt.Errorf("读取 ClusterInfo 失败: %v", err)
+			}
+		}
+	}
+
+	members, err := activeMembers(store, info, id, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCompatible(info, id, members); err != nil {
+		return nil, err
+	}
+	updated := info.DeepCopy()
+	updated.Status.Nodes = append(members, k3v1.ClusterMember{
+		Name:          id.NodeName,
+		K3Version:     id.K3Version,
+		SchemaVersion: id.SchemaVersion,
+		JoinedAt:      metav1.NewTime(now),
+	})
+	sort.Slice(updated.Status.Nodes, func(i, j int) bool { return updated.Status.Nodes[i].Name < updated.Status.Nodes[j].Name })
+	if err := store.Update(k3v1.ClusterInfoGVK, updated); err != nil {
+		return nil, fmt.Errorf("更新 ClusterInfo 失败: %w", err)
+	}
+	return updated, nil
+}
+
+// activeMembers 返回除本节点之外仍然参与版本检查的成员：节点对象存在，或者最近 StaleMemberAge 内加入过
+func activeMembers(store storage.Store, info *k3v1.ClusterInfo, id Identity, now time.Time) ([]k3v1.ClusterMember, error) {
+	objs, err := store.List(nodeGVK, "")
+	if err != nil {
+		return nil, fmt.Errorf("列出节点失败: %w", err)
+	}
+	nodes := make(map[string]bool, len(objs))
+	for _, obj := range objs {
+		if node, ok := obj.(*corev1.Node); ok {
+			nodes[node.Name] = true
+		}
+	}
+	var members []k3v1.ClusterMember
+	for _, m := range info.Status.Nodes {
+		if m.Name == id.NodeName {
+			continue
+		}
+		if !nodes[m.Name] && now.Sub(m.JoinedAt.Time) > StaleMemberAge {
+			continue
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// checkCompatible 检查集群 ID 与版本差异：两边都配置了集群 ID 时必须相同；
+// 与每个成员的 k3 版本主版本相同、次版本相差不超过 MaxMinorSkew（无法解析的版本如 dev 不检查）
+func checkCompatible(info *k3v1.ClusterInfo, id Identity, members []k3v1.ClusterMember) error {
+	if id.ClusterID != "" && info.Spec.ClusterID != "" && id.ClusterID != info.Spec.ClusterID {
+		return fmt.Errorf("%w: 存储属于集群 %s，本节点配置的 cluster.id 为 %s", ErrIncompatible, info.Spec.ClusterID, id.ClusterID)
+	}
+	for _, m := range members {
+		if err := CheckVersionSkew(id.K3Version, m.K3Version); err != nil {
+			return fmt.Errorf("%w: 节点 %s: %v", ErrIncompatible, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// CheckVersionSkew 检查两个 k3 版本能否在同一个集群中运行：主版本相同、次版本相差不超过 MaxMinorSkew；
+// 任一版本无法解析（如开发构建的 dev）时不检查
+func CheckVersionSkew(local, other string) error {
+	a, errA := utilversion.ParseGeneric(local)
+	b, errB := utilversion.ParseGeneric(other)
+	if errA != nil || errB != nil {
+		return nil
+	}
+	skew := int(a.Minor()) - int(b.Minor())
+	if skew < 0 {
+		skew = -skew
+	}
+	if a.Major() != b.Major() || skew > MaxMinorSkew {
+		return fmt.Errorf("k3 %s 与 %s 相差超过 %d 个次版本，先把集群中的节点升级到中间版本", local, other, MaxMinorSkew)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+)
+
+// optionalStopTimeout 按 ClusterConfiguration 停止可选控制器时等待的最长时间
+const optionalStopTimeout = 10 * time.Second
+
+// optionalController 可以由 ClusterConfiguration 开关、调整参数的控制器。
+// 生效配置变化时停止旧实例并按新配置创建新实例（控制器的 Stop 不可逆，不能复用）
+type optionalController struct {
+	name string
+	// settings 返回控制器在 spec 下的生效配置（合并配置文件与 ClusterConfiguration）
+	settings func(spec k3v1.ClusterConfigurationSpec) interface{}
+	// build 按生效配置创建控制器，未开启时返回 nil
+	build func(settings interface{}) (Controller, error)
+
+	applied  interface{}
+	hasApply bool
+	running  Controller
+}
+
+// controllerEnabled 返回 ClusterConfiguration 对控制器的开关，没有设置时为 def
+func controllerEnabled(spec k3v1.ClusterConfigurationSpec, name string, def bool) bool {
+	if enabled, ok := spec.Controllers[name]; ok {
+		return enabled
+	}
+	return def
+}
+
+// registerOptional 注册可选控制器，Start 时按当前 ClusterConfiguration 启动
+func (cm *ControllerManager) registerOptional(name string, settings func(spec k3v1.ClusterConfigurationSpec) interface{}, build func(settings interface{}) (Controller, error)) {
+	cm.optional = append(cm.optional, &optionalController{name: name, settings: settings, build: build})
+}
+
+// registerOptionalControllers 注册调度器、回收器等可选控制器。
+// 默认按配置文件开启，ClusterConfiguration 的 controllers/imageGC 覆盖配置文件
+func (cm *ControllerManager) registerOptionalControllers() {
+	cm.registerOptional("SchedulerController",
+		func(spec k3v1.ClusterConfigurationSpec) interface{} {
+			return controllerEnabled(spec, "SchedulerController", true)
+		},
+		func(settings interface{}) (Controller, error) {
+			if !settings.(bool) {
+				return nil, nil
+			}
+			sc := NewSchedulerController(cm.store, cm.logger)
+			sc.resyncInterval = cm.intervals.ResyncPeriod
+			sc.podOverhead = cm.resources.PodOverhead
+			sc.SetPolicy(cm.clusterConfig.Current().Scheduler)
+			cm.scheduler = sc
+			return sc, nil
+		})
+
+	if cm.runtime != nil {
+		// 孤儿容器回收（依赖容器运行时）
+		cm.registerOptional("ContainerGC",
+			func(spec k3v1.ClusterConfigurationSpec) interface{} {
+				return controllerEnabled(spec, "ContainerGC", true)
+			},
+			func(settings interface{}) (Controller, error) {
+				if !settings.(bool) {
+					return nil, nil
+				}
+				gc := NewContainerGC(cm.store, cm.logger, cm.runtime, cm.nodeName, cm.config.Storage.StaticPodPath)
+				gc.interval = cm.intervals.ContainerGCInterval
+				return gc, nil
+			})
+
+		// 镜像回收（image_gc.high_threshold_percent 或 spec.imageGC.highThresholdPercent 大于 0 时开启）
+		cm.registerOptional("ImageGC",
+			func(spec k3v1.ClusterConfigurationSpec) interface{} {
+				policy := cm.config.ImageGC
+				if spec.ImageGC != nil {
+					policy.HighThresholdPercent = spec.ImageGC.HighThresholdPercent
+					policy.LowThresholdPercent = spec.ImageGC.LowThresholdPercent
+					if spec.ImageGC.Interval != "" {
+						policy.Interval = spec.ImageGC.Interval
+					}
+				}
+				if !controllerEnabled(spec, "ImageGC", true) {
+					policy.HighThresholdPercent = 0
+				}
+				return policy
+			},
+			func(settings interface{}) (Controller, error) {
+				imageGC, err := NewImageGC(cm.logger, cm.runtime, settings.(config.ImageGCConfig))
+				if err != nil || imageGC == nil {
+					return nil, err
+				}
+				return imageGC, nil
+			})
+	}
+
+	// descheduler（descheduler.enabled 或 controllers.DeschedulerController 开启，只驱逐本节点上的 Pod）
+	cm.registerOptional("DeschedulerController",
+		func(spec k3v1.ClusterConfigurationSpec) interface{} {
+			cfg := cm.config.Descheduler
+			cfg.Enabled = controllerEnabled(spec, "DeschedulerController", cfg.Enabled)
+			return cfg
+		},
+		func(settings interface{}) (Controller, error) {
+			descheduler, err := NewDeschedulerController(cm.store, cm.logger, cm.nodeName, settings.(config.DeschedulerConfig))
+			if err != nil || descheduler == nil {
+				return nil, err
+			}
+			return descheduler, nil
+		})
+
+	// 短期对象的 TTL 清理（默认开启：Job 的 ttlSecondsAfterFinished 总是生效，其他对象按 retention 配置）
+	cm.registerOptional("TTLController",
+		func(spec k3v1.ClusterConfigurationSpec) interface{} {
+			if !controllerEnabled(spec, "TTLController", true) {
+				return false
+			}
+			return cm.config.Retention
+		},
+		func(settings interface{}) (Controller, error) {
+			cfg, ok := settings.(config.RetentionConfig)
+			if !ok {
+				return nil, nil
+			}
+			ttl, err := NewTTLController(cm.store, cm.logger, cm.nodeName, cfg)
+			if err != nil {
+				return nil, err
+			}
+			return ttl, nil
+		})
+
+	// 节点压力驱逐（eviction.memory_available 或 eviction.disk_available 配置了阈值时开启）
+	cm.registerOptional("EvictionManager",
+		func(spec k3v1.ClusterConfigurationSpec) interface{} {
+			if !controllerEnabled(spec, "EvictionManager", true) {
+				return false
+			}
+			return cm.config.Eviction
+		},
+		func(settings interface{}) (Controller, error) {
+			cfg, ok := settings.(config.EvictionConfig)
+			if !ok {
+				return nil, nil
+			}
+			parsed, err := cfg.Settings()
+			if err != nil {
+				return nil, err
+			}
+			var podStats func(ctx context.Context) ([]apiserver.PodStats, error)
+			if cm.runtime != nil {
+				podStats = cm.ListPodStats
+			}
+			eviction := NewEvictionManager(cm.store, cm.logger, cm.nodeName, parsed, podStats)
+			if eviction == nil {
+				return nil, nil
+			}
+			return eviction, nil
+		})
+
+	// 局域网设备清单（inventory.enabled 或 controllers.InventoryController 开启，不依赖容器运行时）
+	cm.registerOptional("InventoryController",
+		func(spec k3v1.ClusterConfigurationSpec) interface{} {
+			cfg := cm.config.Inventory
+			cfg.Enabled = controllerEnabled(spec, "InventoryController", cfg.Enabled)
+			return cfg
+		},
+		func(settings interface{}) (Controller, error) {
+			inventory, err := NewInventoryController(cm.store, cm.logger, cm.nodeName, settings.(config.InventoryConfig))
+			if err != nil || inventory == nil {
+				return nil, err
+			}
+			return inventory, nil
+		})
+}
+
+// applyClusterConfiguration 按 ClusterConfiguration 开关可选控制器、更新调度策略。
+// Start 之前只检查控制器名称，Start 时按当前 spec 启动
+func (cm *ControllerManager) applyClusterConfiguration(spec k3v1.ClusterConfigurationSpec) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for name := range spec.Controllers {
+		if !cm.isOptional(name) {
+			cm.logger.Warnf("ClusterConfiguration 中的控制器 %s 不存在或不能开关（可选控制器: %v）", name, cm.optionalNames())
+		}
+	}
+	if !cm.started {
+		return
+	}
+	for _, oc := range cm.optional {
+		cm.reconcileOptional(oc, spec)
+	}
+	if cm.scheduler != nil {
+		cm.scheduler.SetPolicy(spec.Scheduler)
+	}
+}
+
+// reconcileOptional 生效配置变化时重建控制器（调用方持有 cm.mu）
+func (cm *ControllerManager) reconcileOptional(oc *optionalController, spec k3v1.ClusterConfigurationSpec) {
+	settings := oc.settings(spec)
+	if cm.idle && idlePausedControllers[oc.name] {
+		settings = idlePaused{}
+	}
+	if oc.hasApply && reflect.DeepEqual(settings, oc.applied) {
+		return
+	}
+	oc.applied, oc.hasApply = settings, true
+
+	if oc.running != nil {
+		cm.logger.Infof("停止控制器: %s", oc.name)
+		ctx, cancel := context.WithTimeout(context.Background(), optionalStopTimeout)
+		if err := oc.running.Stop(ctx); err != nil {
+			cm.logger.Warnf("停止控制器 %s 失败: %v", oc.name, err)
+		}
+		cancel()
+		if _, ok := oc.running.(*SchedulerController); ok {
+			cm.scheduler = nil
+		}
+		cm.metricsFor(oc.name).setRunning(false)
+		oc.running = nil
+	}
+
+	if _, paused := settings.(idlePaused); paused {
+		cm.logger.Infof("空闲模式，暂停控制器: %s", oc.name)
+		return
+	}
+	c, err := oc.build(settings)
+	if err != nil {
+		cm.logger.Warnf("%s 未开启: %v", oc.name, err)
+		return
+	}
+	if c == nil {
+		return
+	}
+	oc.running = c
+	cm.logger.Infof("启动控制器: %s", c.Name())
+	cm.instrument(oc.name, c)
+	go func() {
+		if err := c.Start(cm.runCtx); err != nil {
+			cm.logger.Error("控制器启动失败: ", c.Name(), " error: ", err.Error())
+		}
+	}()
+}
+
+func (cm *ControllerManager) isOptional(name string) bool {
+	for _, oc := range cm.optional {
+		if oc.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (cm *ControllerManager) optionalNames() []string {
+	names := make([]string, 0, len(cm.optional))
+	for _, oc := range cm.optional {
+		names = append(names, oc.name)
+	}
+	return names
+}
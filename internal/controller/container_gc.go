@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// containerGCInterval 孤儿容器回收的默认周期，由 controller.container_gc_interval 覆盖
+const containerGCInterval = time.Minute
+
+// ContainerGC 节点侧的孤儿容器回收：周期性对比本机上 k3 创建的容器与 Store 中调度到本节点的 Pod，
+// 删除所属 Pod 已不存在的容器（例如进程崩溃导致 Pod 删除时没有调用 StopContainer）
+type ContainerGC struct {
+	store    storage.Store
+	logger   logprovider.Logger
+	runtime  ContainerRuntime
+	nodeName string
+	// staticPodPath 静态 Pod manifest 目录，其中 Pod 的容器即使没有 mirror Pod 也不会被回收
+	staticPodPath string
+	interval      time.Duration
+	stopCh        chan struct{}
+	metrics       *controllerMetrics
+}
+
+// NewContainerGC 创建孤儿容器回收器
+func NewContainerGC(store storage.Store, logger logprovider.Logger, runtime ContainerRuntime, nodeName, staticPodPath string) *ContainerGC {
+	return &ContainerGC{
+		store:         store,
+		logger:        logger,
+		runtime:       runtime,
+		nodeName:      nodeName,
+		staticPodPath: staticPodPath,
+		interval:      containerGCInterval,
+		stopCh:        make(chan struct{}),
+	}
+}
+
+// Name 返回控制器名称
+func (gc *ContainerGC) Name() string {
+	return "ContainerGC"
+}
+
+func (gc *ContainerGC) setMetrics(m *controllerMetrics) {
+	gc.metrics = m
+}
+
+// Start 启动周期回收
+func (gc *ContainerGC) Start(ctx context.Context) error {
+	gc.logger.Infof("启动孤儿容器回收（节点: %s，周期: %s）", gc.nodeName, gc.interval)
+	go func() {
+		ticker := time.NewTicker(gc.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-gc.stopCh:
+				return
+			case <-ticker.C:
+				start := time.Now()
+				err := gc.collect(ctx)
+				if err != nil {
+					gc.logger.Warnf("孤儿容器回收失败: %v", err)
+				}
+				gc.metrics.observe(start, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止周期回收
+func (gc *ContainerGC) Stop(ctx context.Context) error {
+	close(gc.stopCh)
+	return nil
+}
+
+// collect 执行一轮回收
+func (gc *ContainerGC) collect(ctx context.Context) error {
+	// 先列容器再列 Pod：容器总是在 Pod 写入 Store 之后才创建，这样不会误删刚启动的容器
+	containers, err := gc.runtime.ListContainers(ctx)
+	if err != nil {
+		return fmt.Errorf("列出容器失败: %w", err)
+	}
+	if len(containers) == 0 {
+		return nil
+	}
+
+	podGVK := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	objs, err := gc.store.List(podGVK, "")
+	if err != nil {
+		return fmt.Errorf("列出 Pod 失败: %w", err)
+	}
+	var pods []*corev1.Pod
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			pods = append(pods, pod)
+		}
+	}
+	// 静态 Pod 不依赖 Store：mirror Pod 被删除到重建之间，容器仍然属于本节点
+	staticPods, _ := LoadStaticPods(gc.staticPodPath)
+	for _, pod := range staticPods {
+		pod.Spec.NodeName = gc.nodeName
+		pods = append(pods, pod)
+	}
+
+	for _, c := range orphanContainers(containers, pods, gc.nodeName) {
+		gc.logger.Infof("回收孤儿容器: %s (%s)，所属 Pod %s/%s (uid=%s) 已不在本节点", c.Name, c.ID, c.PodNamespace, c.PodName, c.PodUID)
+		if err := gc.runtime.RemoveContainer(ctx, c.ID); err != nil {
+			gc.logger.Warnf("回收孤儿容器 %s 失败: %v", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// orphanContainers 返回所属 Pod（按 UID 匹配）不存在或已不在 nodeName 上的容器。
+// 同名重建的 Pod UID 不同，旧 Pod 的容器同样视为孤儿。
+func orphanContainers(containers []ManagedContainer, pods []*corev1.Pod, nodeName string) []ManagedContainer {
+	live := make(map[types.UID]bool, len(pods))
+	for _, pod := range pods {
+		if pod.Spec.NodeName == nodeName {
+			live[pod.UID] = true
+		}
+	}
+
+	var orphans []ManagedContainer
+	for _, c := range containers {
+		if c.PodUID != "" && !live[c.PodUID] {
+			orphans = append(orphans, c)
+		}
+	}
+	return orphans
+}
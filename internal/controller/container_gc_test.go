@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fakeRuntime 只实现回收用到的方法的容器运行时，其余方法调用时 panic
+type fakeRuntime struct {
+	ContainerRuntime
+	containers []ManagedContainer
+	images     []ImageInfo
+	removed    []string
+}
+
+func (f *fakeRuntime) ListContainers(ctx context.Context) ([]ManagedContainer, error) {
+	return f.containers, nil
+}
+
+func (f *fakeRuntime) RemoveContainer(ctx context.Context, id string) error {
+	f.removed = append(f.removed, id)
+	return nil
+}
+
+func (f *fakeRuntime) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	return f.images, nil
+}
+
+func (f *fakeRuntime) RemoveImage(ctx context.Context, id string) error {
+	f.removed = append(f.removed, id)
+	return nil
+}
+
+// uidPod 创建调度到 node、UID 为 uid 的 Pod
+func uidPod(name, uid, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(uid)},
+		Spec:       corev1.PodSpec{NodeName: node},
+	}
+}
+
+func containerIDs(containers []ManagedContainer) []string {
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func TestOrphanContainers(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		containers []ManagedContainer
+		pods       []*corev1.Pod
+		want       []string
+	}{
+		{
+			name:       "containers of a live pod are kept",
+			containers: []ManagedContainer{{ID: "c1", PodUID: "uid-1", PodName: "web"}},
+			pods:       []*corev1.Pod{uidPod("web", "uid-1", "node-1")},
+		},
+		{
+			name:       "deleted pod",
+			containers: []ManagedContainer{{ID: "c1", PodUID: "uid-1", PodName: "web"}},
+			want:       []string{"c1"},
+		},
+		{
+			name: "pod recreated with the same name",
+			containers: []ManagedContainer{
+				{ID: "old", PodUID: "uid-1", PodName: "web"},
+				{ID: "new", PodUID: "uid-2", PodName: "web"},
+			},
+			pods: []*corev1.Pod{uidPod("web", "uid-2", "node-1")},
+			want: []string{"old"},
+		},
+		{
+			name:       "pod moved to another node",
+			containers: []ManagedContainer{{ID: "c1", PodUID: "uid-1", PodName: "web"}},
+			pods:       []*corev1.Pod{uidPod("web", "uid-1", "node-2")},
+			want:       []string{"c1"},
+		},
+		{
+			name:       "containers without a pod UID are skipped",
+			containers: []ManagedContainer{{ID: "c1", PodName: "web"}},
+		},
+		{
+			name: "sandbox and app containers of one pod",
+			containers: []ManagedContainer{
+				{ID: "sandbox", PodUID: "uid-1", ContainerName: "POD"},
+				{ID: "app", PodUID: "uid-1", ContainerName: "app"},
+				{ID: "live", PodUID: "uid-2", ContainerName: "app"},
+			},
+			pods: []*corev1.Pod{uidPod("db", "uid-2", "node-1")},
+			want: []string{"sandbox", "app"},
+		},
+	} {
+		got := containerIDs(orphanContainers(tc.containers, tc.pods, "node-1"))
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: orphans = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestContainerGCKeepsStaticPods(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `apiVersion: v1
+kind: Pod
+metadata:
+  name: etcd
+  namespace: kube-system
+spec:
+  containers:
+  - name: etcd
+    image: etcd:3.5
+`
+	if err := os.WriteFile(filepath.Join(dir, "kube-system-etcd.yaml"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	static, errs := LoadStaticPods(dir)
+	if len(errs) > 0 || len(static) != 1 {
+		t.Fatalf("LoadStaticPods = %v, %v", static, errs)
+	}
+
+	store := storage.NewMemoryStore()
+	live := uidPod("web", "", "node-1")
+	if err := store.Create(podGVK, live); err != nil || live.UID == "" {
+		t.Fatalf("create pod: uid %q, %v", live.UID, err)
+	}
+	runtime := &fakeRuntime{containers: []ManagedContainer{
+		// mirror Pod 不在 Store 中，静态 Pod 的容器仍然保留
+		{ID: "static", PodUID: static[0].UID, PodNamespace: "kube-system", PodName: "etcd"},
+		{ID: "live", PodUID: live.UID, PodNamespace: "default", PodName: "web"},
+		{ID: "orphan", PodUID: "gone", PodNamespace: "default", PodName: "old"},
+		{ID: "foreign"},
+	}}
+	gc := NewContainerGC(store, testLogger, runtime, "node-1", dir)
+	if err := gc.collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(runtime.removed)
+	if strings.Join(runtime.removed, ",") != "orphan" {
+		t.Fatalf("removed %v, want only the orphan", runtime.removed)
+	}
+}
@@ -0,0 +1,492 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// containerLogRetryInterval 日志流结束（容器重启、运行时重启）后重新连接前的等待时间
+	containerLogRetryInterval = 2 * time.Second
+	// containerLogFollowInterval follow 读取日志文件时检查新内容的间隔
+	containerLogFollowInterval = 250 * time.Millisecond
+	// containerLogStopTimeout 删除 Pod 的日志目录前等待写入结束的时间
+	containerLogStopTimeout = 5 * time.Second
+)
+
+// containerLogs 把容器的 stdout/stderr 写入本节点的日志文件（controller.container_logs）：
+// 每个容器一个 <dir>/<namespace>_<pod>_<uid>/<container>.log，超过大小上限后轮转为 .log.1、.log.2……
+// 每行以运行时给出的 RFC3339 时间戳开头；重新连接（容器重启、k3 重启）后运行时从头输出的日志按时间戳跳过已写入的部分。
+// 容器被删除后 Pod 日志 API 仍从文件读取，Pod 删除时删除其日志目录
+type containerLogs struct {
+	runtime  ContainerRuntime
+	logger   logprovider.Logger
+	settings config.ContainerLogSettings
+
+	// mu 保护 active（按日志文件路径记录正在写入的容器）
+	mu     sync.Mutex
+	active map[string]*logCapture
+}
+
+// logCapture 一个正在写入日志文件的容器
+type logCapture struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newContainerLogs(runtime ContainerRuntime, logger logprovider.Logger, settings config.ContainerLogSettings) *containerLogs {
+	return &containerLogs{
+		runtime:  runtime,
+		logger:   logger,
+		settings: settings,
+		active:   make(map[string]*logCapture),
+	}
+}
+
+// podDir 返回 Pod 的日志目录
+func (cl *containerLogs) podDir(pod *corev1.Pod) string {
+	return filepath.Join(cl.settings.Dir, fmt.Sprintf("%s_%s_%s", pod.Namespace, pod.Name, pod.UID))
+}
+
+// path 返回容器正在写入的日志文件
+func (cl *containerLogs) path(pod *corev1.Pod, container string) string {
+	return filepath.Join(cl.podDir(pod), container+".log")
+}
+
+// rotatedLogPath 返回第 i 个轮转后的日志文件（0 为正在写入的文件）
+func rotatedLogPath(path string, i int) string {
+	if i == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// capture 开始把 Pod 各容器的输出写入日志文件，已经在写入的容器跳过；cl 为 nil（没有配置日志目录）时什么也不做
+func (cl *containerLogs) capture(ctx context.Context, pod *corev1.Pod) {
+	if cl == nil {
+		return
+	}
+	for _, c := range pod.Spec.Containers {
+		path := cl.path(pod, c.Name)
+		cl.mu.Lock()
+		if _, ok := cl.active[path]; ok {
+			cl.mu.Unlock()
+			continue
+		}
+		captureCtx, cancel := context.WithCancel(ctx)
+		lc := &logCapture{cancel: cancel, done: make(chan struct{})}
+		cl.active[path] = lc
+		cl.mu.Unlock()
+		go cl.run(captureCtx, pod.DeepCopy(), c.Name, path, lc)
+	}
+}
+
+// run 持续读取容器的日志写入文件：日志流结束后容器仍在运行（例如运行时重启）时重新连接，容器停止后结束
+func (cl *containerLogs) run(ctx context.Context, pod *corev1.Pod, container, path string, lc *logCapture) {
+	defer func() {
+		cl.mu.Lock()
+		if cl.active[path] == lc {
+			delete(cl.active, path)
+		}
+		cl.mu.Unlock()
+		lc.cancel()
+		close(lc.done)
+	}()
+
+	w, err := openLogFileWriter(path, cl.settings)
+	if err != nil {
+		cl.logger.Warnf("打开容器日志文件 %s 失败: %v", path, err)
+		return
+	}
+	defer w.Close()
+
+	for {
+		rc, err := cl.runtime.ContainerLogs(ctx, pod, &corev1.PodLogOptions{Container: container, Follow: true, Timestamps: true})
+		if err != nil {
+			cl.logger.Debugf("读取 Pod %s/%s 容器 %s 的日志失败: %v", pod.Namespace, pod.Name, container, err)
+		} else {
+			if err := w.copyFrom(rc); err != nil && ctx.Err() == nil {
+				cl.logger.Warnf("写入容器日志文件 %s 失败: %v", path, err)
+			}
+			rc.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(containerLogRetryInterval):
+		}
+		if status, err := cl.runtime.GetContainerStatus(ctx, pod); err != nil || !status.Running {
+			return
+		}
+	}
+}
+
+// capturing 判断日志文件是否仍在写入
+func (cl *containerLogs) capturing(path string) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	_, ok := cl.active[path]
+	return ok
+}
+
+// remove 停止写入 Pod 的日志并删除其日志目录（Pod 已删除）
+func (cl *containerLogs) remove(pod *corev1.Pod) {
+	if cl == nil {
+		return
+	}
+	dir := cl.podDir(pod)
+	cl.stopMatching(func(path string) bool { return filepath.Dir(path) == dir })
+	if err := os.RemoveAll(dir); err != nil {
+		cl.logger.Warnf("删除 Pod %s/%s 的日志目录失败: %v", pod.Namespace, pod.Name, err)
+	}
+}
+
+// prune 删除不属于 keep 中任何 Pod 的日志目录（k3 停止期间被删除的 Pod）
+func (cl *containerLogs) prune(keep map[types.UID]bool) {
+	if cl == nil {
+		return
+	}
+	entries, err := os.ReadDir(cl.settings.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		i := strings.LastIndex(entry.Name(), "_")
+		if !entry.IsDir() || i < 0 || keep[types.UID(entry.Name()[i+1:])] {
+			continue
+		}
+		dir := filepath.Join(cl.settings.Dir, entry.Name())
+		cl.stopMatching(func(path string) bool { return filepath.Dir(path) == dir })
+		if err := os.RemoveAll(dir); err != nil {
+			cl.logger.Warnf("删除日志目录 %s 失败: %v", dir, err)
+		}
+	}
+}
+
+// stop 停止写入所有容器的日志
+func (cl *containerLogs) stop() {
+	if cl == nil {
+		return
+	}
+	cl.stopMatching(func(string) bool { return true })
+}
+
+// stopMatching 停止写入路径满足 match 的日志文件，并等待写入结束
+func (cl *containerLogs) stopMatching(match func(path string) bool) {
+	cl.mu.Lock()
+	var stopped []*logCapture
+	for path, lc := range cl.active {
+		if match(path) {
+			lc.cancel()
+			stopped = append(stopped, lc)
+			delete(cl.active, path)
+		}
+	}
+	cl.mu.Unlock()
+	timeout := time.After(containerLogStopTimeout)
+	for _, lc := range stopped {
+		select {
+		case <-lc.done:
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// open 从日志文件读取容器日志；容器没有日志文件时返回 false，由运行时读取
+func (cl *containerLogs) open(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, bool) {
+	if cl == nil {
+		return nil, false
+	}
+	if opts == nil {
+		opts = &corev1.PodLogOptions{}
+	}
+	container, err := logContainerName(pod, opts.Container)
+	if err != nil {
+		return nil, false
+	}
+	path := cl.path(pod, container)
+	if _, err := os.Stat(path); err != nil {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(cl.stream(ctx, pw, path, opts))
+	}()
+	return &logReader{PipeReader: pr, cancel: cancel}, true
+}
+
+// stream 按 opts（tailLines、sinceSeconds/sinceTime、timestamps、follow）把日志文件写到 w：
+// 先按从旧到新的顺序读取轮转后的文件与正在写入的文件；follow 时继续读取新写入的内容（跟随轮转），直到容器停止或 ctx 取消
+func (cl *containerLogs) stream(ctx context.Context, w io.Writer, path string, opts *corev1.PodLogOptions) error {
+	var since time.Time
+	if opts.SinceSeconds != nil {
+		since = time.Now().Add(-time.Duration(*opts.SinceSeconds) * time.Second)
+	}
+	if opts.SinceTime != nil {
+		since = opts.SinceTime.Time
+	}
+	// tailLines 时先收集最后的 N 行，读完已有的内容后再输出
+	tailing := opts.TailLines != nil
+	var tail [][]byte
+	emit := func(line []byte) error {
+		ts, rest, ok := splitLogTimestamp(line)
+		if ok && ts.Before(since) {
+			return nil
+		}
+		if ok && !opts.Timestamps {
+			line = rest
+		}
+		if tailing {
+			tail = append(tail, line)
+			if n := int(max(*opts.TailLines, 0)); len(tail) > 2*n+1024 {
+				tail = append(tail[:0], tail[len(tail)-n:]...)
+			}
+			return nil
+		}
+		_, err := w.Write(line)
+		return err
+	}
+
+	// 轮转后的文件不再变化，整个读取
+	for i := cl.settings.MaxFiles - 1; i > 0; i-- {
+		data, err := os.ReadFile(rotatedLogPath(path, i))
+		if err != nil {
+			continue
+		}
+		for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+			if len(line) > 0 {
+				if err := emit(line); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	reader := bufio.NewReader(f)
+	var partial []byte
+	// readAvailable 读取 f 中已经写入的完整行
+	readAvailable := func() error {
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				partial = append(partial, line...)
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+			if len(partial) > 0 {
+				line, partial = append(partial, line...), nil
+			}
+			if err := emit(line); err != nil {
+				return err
+			}
+		}
+	}
+	if err := readAvailable(); err != nil {
+		return err
+	}
+	if tailing {
+		tailing = false
+		for _, line := range tail[max(len(tail)-int(max(*opts.TailLines, 0)), 0):] {
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+		}
+		tail = nil
+	}
+	if !opts.Follow {
+		return nil
+	}
+
+	for {
+		// 文件已轮转：读完旧文件剩余的内容后打开新文件
+		if info, err := os.Stat(path); err == nil {
+			if current, err := f.Stat(); err == nil && !os.SameFile(info, current) {
+				if err := readAvailable(); err != nil {
+					return err
+				}
+				next, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				f.Close()
+				f, partial = next, nil
+				reader.Reset(f)
+			}
+		}
+		if err := readAvailable(); err != nil {
+			return err
+		}
+		if !cl.capturing(path) {
+			// 容器已停止：读完最后写入的内容后结束
+			return readAvailable()
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(containerLogFollowInterval):
+		}
+	}
+}
+
+// splitLogTimestamp 拆分日志行开头的 RFC3339 时间戳
+func splitLogTimestamp(line []byte) (time.Time, []byte, bool) {
+	i := bytes.IndexByte(line, ' ')
+	if i <= 0 {
+		return time.Time{}, line, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, string(line[:i]))
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return ts, line[i+1:], true
+}
+
+// logFileWriter 写入一个容器的日志文件，超过大小上限时轮转
+type logFileWriter struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	f        *os.File
+	size     int64
+	// last 已写入的最后一行的时间戳，重新连接后不晚于它的行已经写入过
+	last time.Time
+}
+
+// openLogFileWriter 打开（或创建）日志文件，追加写入
+func openLogFileWriter(path string, settings config.ContainerLogSettings) (*logFileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w := &logFileWriter{path: path, maxSize: settings.MaxSize, maxFiles: settings.MaxFiles, f: f, size: info.Size()}
+	for i := 0; i < 2 && w.last.IsZero(); i++ {
+		w.last = lastLogTimestamp(rotatedLogPath(path, i))
+	}
+	return w, nil
+}
+
+// lastLogTimestamp 返回日志文件最后一行的时间戳，读取失败时为零值
+func lastLogTimestamp(path string) time.Time {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return time.Time{}
+	}
+	offset := max(info.Size()-64*1024, 0)
+	data := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+		return time.Time{}
+	}
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	ts, _, _ := splitLogTimestamp(lines[len(lines)-1])
+	return ts
+}
+
+// copyFrom 把日志流按行写入文件
+func (w *logFileWriter) copyFrom(r io.Reader) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if werr := w.writeLine(line); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeLine 写入一行：没有时间戳的行（运行时不支持 timestamps）以当前时间补上，不晚于已写入的最后一行的跳过
+func (w *logFileWriter) writeLine(line []byte) error {
+	if line[len(line)-1] != '\n' {
+		line = append(line, '\n')
+	}
+	ts, _, ok := splitLogTimestamp(line)
+	if ok {
+		if !ts.After(w.last) {
+			return nil
+		}
+	} else {
+		ts = time.Now().UTC()
+		if !ts.After(w.last) {
+			ts = w.last.Add(time.Nanosecond)
+		}
+		line = append([]byte(ts.Format(time.RFC3339Nano)+" "), line...)
+	}
+	if w.size > 0 && w.size+int64(len(line)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := w.f.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	w.last = ts
+	return nil
+}
+
+// rotate 把 .log.(n-1) 依次改名为 .log.n（最旧的文件被覆盖），正在写入的文件改名为 .log.1 后重新创建
+func (w *logFileWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	for i := w.maxFiles - 1; i > 0; i-- {
+		if err := os.Rename(rotatedLogPath(w.path, i-1), rotatedLogPath(w.path, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	w.f, w.size = f, 0
+	return nil
+}
+
+// Close 关闭日志文件
+func (w *logFileWriter) Close() error {
+	return w.f.Close()
+}
@@ -2,31 +2,59 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	// deploymentPausedReason spec.paused 为 true 时 Progressing 条件的原因
+	deploymentPausedReason = "DeploymentPaused"
+	// deploymentResumedReason 恢复发布后 Progressing 条件的原因
+	deploymentResumedReason = "DeploymentResumed"
+	// deploymentUpdatedReason 发布有进展（副本被更新、变为就绪或旧副本被删除）时 Progressing 条件的原因。
+	// 没有 ReplicaSet，原因沿用 Kubernetes 的名称，kubectl 等工具可以直接识别
+	deploymentUpdatedReason = "ReplicaSetUpdated"
+	// deploymentAvailableReason 发布完成时 Progressing 条件的原因
+	deploymentAvailableReason = "NewReplicaSetAvailable"
+	// deploymentTimedOutReason spec.progressDeadlineSeconds 内没有进展时 Progressing 条件的原因
+	deploymentTimedOutReason = "ProgressDeadlineExceeded"
 )
 
 // DeploymentController 管理 Deployment 资源
 type DeploymentController struct {
-	store  storage.Store
-	logger logprovider.Logger
-	stopCh chan struct{}
+	store   storage.Store
+	logger  logprovider.Logger
+	stopCh  chan struct{}
+	metrics *controllerMetrics
+
+	// progressTimers 各 Deployment 发布期限到期时重新检查状态的定时器（没有事件时也能发现超时）
+	timersMu       sync.Mutex
+	progressTimers map[types.NamespacedName]*time.Timer
 }
 
 // NewDeploymentController 创建 Deployment 控制器
 func NewDeploymentController(store storage.Store, logger logprovider.Logger) *DeploymentController {
 	return &DeploymentController{
-		store:  store,
-		logger: logger,
-		stopCh: make(chan struct{}),
+		store:          store,
+		logger:         logger,
+		stopCh:         make(chan struct{}),
+		progressTimers: make(map[types.NamespacedName]*time.Timer),
 	}
 }
 
@@ -35,6 +63,10 @@ func (dc *DeploymentController) Name() string {
 	return "DeploymentController"
 }
 
+func (dc *DeploymentController) setMetrics(m *controllerMetrics) {
+	dc.metrics = m
+}
+
 // Start 启动 Deployment 控制器
 func (dc *DeploymentController) Start(ctx context.Context) error {
 	dc.logger.Info("启动 Deployment 控制器...")
@@ -52,8 +84,17 @@ func (dc *DeploymentController) Start(ctx context.Context) error {
 	}
 
 	// 启动处理循环
+	dc.metrics.watch("deployments", watchCh)
 	go dc.processDeployments(ctx, watchCh)
 
+	// Pod 状态变化时刷新所属 Deployment 的 status（只更新状态，不触发扩缩容）
+	podCh, err := dc.store.Watch(schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}, "", "")
+	if err != nil {
+		return fmt.Errorf("无法监听 Pod 资源: %w", err)
+	}
+	dc.metrics.watch("pods", podCh)
+	go dc.processPods(ctx, podCh)
+
 	// 处理现有的 Deployment
 	if err := dc.syncExistingDeployments(ctx); err != nil {
 		dc.logger.Error("同步现有 Deployment 失败: ", err.Error())
@@ -66,6 +107,12 @@ func (dc *DeploymentController) Start(ctx context.Context) error {
 func (dc *DeploymentController) Stop(ctx context.Context) error {
 	dc.logger.Info("停止 Deployment 控制器...")
 	close(dc.stopCh)
+	dc.timersMu.Lock()
+	for key, timer := range dc.progressTimers {
+		timer.Stop()
+		delete(dc.progressTimers, key)
+	}
+	dc.timersMu.Unlock()
 	return nil
 }
 
@@ -106,6 +153,7 @@ func (dc *DeploymentController) processDeployments(ctx context.Context, watchCh
 		case event, ok := <-watchCh:
 			if !ok {
 				dc.logger.Warn("Deployment watch 通道已关闭")
+				dc.metrics.watchClosed("deployments")
 				return
 			}
 
@@ -113,13 +161,17 @@ func (dc *DeploymentController) processDeployments(ctx context.Context, watchCh
 			case storage.EventAdded, storage.EventModified:
 				if deployment, ok := event.Object.(*appsv1.Deployment); ok {
 					dc.logger.Infof("处理 Deployment 事件: %s/%s (%s)", deployment.Namespace, deployment.Name, event.Type)
-					if err := dc.syncDeployment(ctx, deployment); err != nil {
+					start := time.Now()
+					err := dc.syncDeployment(ctx, deployment)
+					if err != nil {
 						dc.logger.Error("同步 Deployment 失败: ", deployment.Name, " error: ", err.Error())
 					}
+					dc.metrics.observe(start, err)
 				}
 			case storage.EventDeleted:
 				if deployment, ok := event.Object.(*appsv1.Deployment); ok {
 					dc.logger.Infof("删除 Deployment: %s/%s", deployment.Namespace, deployment.Name)
+					dc.scheduleProgressCheck(deployment.Namespace, deployment.Name, 0)
 					// 可以在这里清理相关的 Pod
 				}
 			}
@@ -127,105 +179,297 @@ func (dc *DeploymentController) processDeployments(ctx context.Context, watchCh
 	}
 }
 
-// syncDeployment 同步 Deployment（确保 Pod 数量正确）
+// syncDeployment 同步 Deployment：按 spec.replicas 维持副本数，并把 Pod 模板的变更发布到副本（暂停时不发布）
 func (dc *DeploymentController) syncDeployment(ctx context.Context, deployment *appsv1.Deployment) error {
+	// import 模式镜像的对象只读，不在本地扩缩容
+	if mirror.IsImported(deployment) {
+		return nil
+	}
+
 	replicas := int32(1)
 	if deployment.Spec.Replicas != nil {
 		replicas = *deployment.Spec.Replicas
 	}
 
-	// 获取当前 Deployment 的 Pod
-	podGVK := schema.GroupVersionKind{
-		Group:   "",
-		Version: "v1",
-		Kind:    "Pod",
-	}
-
 	// 查找属于该 Deployment 的 Pod
-	allPods, err := dc.store.List(podGVK, deployment.Namespace)
+	deploymentPods, err := dc.listPods(deployment)
 	if err != nil {
 		return err
 	}
 
-	selector := deploymentSelectorLabels(deployment)
+	hash := podTemplateHash(deployment.Spec.Template)
+	updated, old := splitPodsByTemplate(deploymentPods, hash)
+	dc.logger.Infof("Deployment %s/%s: 期望副本数=%d, 当前副本数=%d（已更新 %d）, paused=%v",
+		deployment.Namespace, deployment.Name, replicas, len(deploymentPods), len(updated), deployment.Spec.Paused)
 
-	var deploymentPods []*corev1.Pod
-	for _, obj := range allPods {
-		if pod, ok := obj.(*corev1.Pod); ok {
-			// MySQLStore 目前只持久化 labels/annotations，OwnerReferences 可能不会被完整恢复。
-			// 因此这里优先用 selector.matchLabels 关联 Pod，避免重复创建。
-			if labelsMatchAll(pod.Labels, selector) || hasDeploymentOwnerRef(pod, deployment.Name) {
-				deploymentPods = append(deploymentPods, pod)
-			}
+	if deployment.Spec.Paused {
+		dc.scalePaused(deployment, replicas, hash, updated, old)
+	} else {
+		dc.rollout(deployment, replicas, hash, updated, old)
+	}
+
+	// 记录已处理的 generation
+	return dc.updateStatus(deployment.Namespace, deployment.Name, deployment.Generation)
+}
+
+// rollout 发布当前模板：先补齐新版本的副本，旧副本只在新版本的副本就绪之后按同样数量删除，发布期间可用副本数不低于期望副本数。
+// 发布已超过 progressDeadlineSeconds 时不再创建新版本的副本，直到 spec 变化
+func (dc *DeploymentController) rollout(deployment *appsv1.Deployment, replicas int32, hash string, updated, old []*corev1.Pod) {
+	if needed := replicas - int32(len(updated)); needed > 0 {
+		if deploymentTimedOut(deployment) {
+			dc.logger.Warnf("Deployment %s/%s 发布超过期限，不再创建新副本（缺少 %d 个）", deployment.Namespace, deployment.Name, needed)
+		} else {
+			dc.createPods(deployment, deployment.Spec.Template, hash, needed)
 		}
 	}
+	if excess := int32(len(updated)) - replicas; excess > 0 {
+		dc.deletePods(updated, excess)
+	}
 
-	currentReplicas := int32(len(deploymentPods))
-	dc.logger.Infof("Deployment %s/%s: 期望副本数=%d, 当前副本数=%d",
-		deployment.Namespace, deployment.Name, replicas, currentReplicas)
+	readyUpdated := int32(0)
+	for _, pod := range updated {
+		if podReady(pod) {
+			readyUpdated++
+		}
+	}
+	if readyUpdated > replicas {
+		readyUpdated = replicas
+	}
+	if excess := int32(len(old)) + readyUpdated - replicas; excess > 0 {
+		dc.deletePods(old, excess)
+	}
+}
 
-	// 如果 Pod 数量不足，创建新的 Pod
-	if currentReplicas < replicas {
-		needed := replicas - currentReplicas
-		dc.logger.Infof("需要创建 %d 个 Pod", needed)
+// scalePaused 暂停时只维持副本数，不发布模板的变更：还没有新版本的副本时按现有副本的模板补齐，
+// 缩容时先删除旧版本的副本
+func (dc *DeploymentController) scalePaused(deployment *appsv1.Deployment, replicas int32, hash string, updated, old []*corev1.Pod) {
+	current := int32(len(updated) + len(old))
+	if current < replicas {
+		template, templateHash := deployment.Spec.Template, hash
+		if len(updated) == 0 && len(old) > 0 {
+			template, templateHash = templateFromPod(old[len(old)-1])
+		}
+		dc.createPods(deployment, template, templateHash, replicas-current)
+	}
+	if excess := current - replicas; excess > 0 {
+		dc.deletePods(append(append([]*corev1.Pod{}, old...), updated...), excess)
+	}
+}
 
-		for i := int32(0); i < needed; i++ {
-			pod := dc.createPodForDeployment(deployment, currentReplicas+i)
-			if err := dc.store.Create(podGVK, pod); err != nil {
-				dc.logger.Error("创建 Pod 失败: ", err.Error())
-				continue
-			}
-			dc.logger.Infof("创建 Pod: %s/%s", pod.Namespace, pod.Name)
+// createPods 按模板创建 n 个 Pod
+func (dc *DeploymentController) createPods(deployment *appsv1.Deployment, template corev1.PodTemplateSpec, hash string, n int32) {
+	dc.logger.Infof("需要创建 %d 个 Pod", n)
+	for i := int32(0); i < n; i++ {
+		pod := dc.createPodForDeployment(deployment, template, hash)
+		if err := dc.store.Create(podGVK, pod); err != nil {
+			dc.logger.Error("创建 Pod 失败: ", err.Error())
+			continue
 		}
+		dc.logger.Infof("创建 Pod: %s/%s", pod.Namespace, pod.Name)
 	}
+}
 
-	// 如果 Pod 数量过多，删除多余的 Pod（简化实现，实际应该更智能）
-	if currentReplicas > replicas {
-		excess := currentReplicas - replicas
-		dc.logger.Infof("需要删除 %d 个 Pod", excess)
+// deletePods 从 pods 中删除 n 个 Pod，未就绪的优先
+func (dc *DeploymentController) deletePods(pods []*corev1.Pod, n int32) {
+	dc.logger.Infof("需要删除 %d 个 Pod", n)
+	candidates := append([]*corev1.Pod{}, pods...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return !podReady(candidates[i]) && podReady(candidates[j])
+	})
+	for i := int32(0); i < n && i < int32(len(candidates)); i++ {
+		pod := candidates[i]
+		if err := dc.store.Delete(podGVK, pod.Namespace, pod.Name); err != nil {
+			dc.logger.Error("删除 Pod 失败: ", err.Error())
+			continue
+		}
+		dc.logger.Infof("删除 Pod: %s/%s", pod.Namespace, pod.Name)
+	}
+}
 
-		for i := int32(0); i < excess && i < int32(len(deploymentPods)); i++ {
-			pod := deploymentPods[i]
-			if err := dc.store.Delete(podGVK, pod.Namespace, pod.Name); err != nil {
-				dc.logger.Error("删除 Pod 失败: ", err.Error())
+// processPods 处理 Pod 事件：刷新所属 Deployment 的副本统计
+func (dc *DeploymentController) processPods(ctx context.Context, watchCh <-chan storage.ResourceEvent) {
+	deployGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-dc.stopCh:
+			return
+		case event, ok := <-watchCh:
+			if !ok {
+				dc.metrics.watchClosed("pods")
+				return
+			}
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			start := time.Now()
+			deployments, err := dc.store.List(deployGVK, pod.Namespace)
+			if err != nil {
+				dc.metrics.observe(start, err)
 				continue
 			}
-			dc.logger.Infof("删除 Pod: %s/%s", pod.Namespace, pod.Name)
+			for _, obj := range deployments {
+				deployment, ok := obj.(*appsv1.Deployment)
+				if !ok || mirror.IsImported(deployment) {
+					continue
+				}
+				if len(podsForDeployment(deployment, []runtime.Object{pod})) == 0 {
+					continue
+				}
+				if err = dc.updateStatus(deployment.Namespace, deployment.Name, 0); err != nil {
+					dc.logger.Warnf("更新 Deployment %s/%s 状态失败: %v", deployment.Namespace, deployment.Name, err)
+				}
+			}
+			dc.metrics.observe(start, err)
 		}
 	}
-
-	return nil
 }
 
-func deploymentSelectorLabels(deploy *appsv1.Deployment) map[string]string {
-	if deploy == nil {
-		return map[string]string{}
+// updateStatus 按当前 Pod 汇总 Deployment 的副本状态；observedGeneration 非 0 时记录为已处理的 generation。
+// 状态没有变化时不写入，避免自身的 MODIFIED 事件导致循环。
+func (dc *DeploymentController) updateStatus(namespace, name string, observedGeneration int64) error {
+	deployGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	obj, err := dc.store.Get(deployGVK, namespace, name)
+	if err != nil {
+		return err
 	}
-	if deploy.Spec.Selector != nil && len(deploy.Spec.Selector.MatchLabels) > 0 {
-		return deploy.Spec.Selector.MatchLabels
+	current, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return nil
 	}
-	if len(deploy.Spec.Template.Labels) > 0 {
-		return deploy.Spec.Template.Labels
+	pods, err := dc.listPods(current)
+	if err != nil {
+		return err
 	}
-	if len(deploy.Labels) > 0 {
-		return deploy.Labels
+
+	status := *current.Status.DeepCopy()
+	newGeneration := observedGeneration > current.Status.ObservedGeneration
+	if observedGeneration > 0 {
+		status.ObservedGeneration = observedGeneration
 	}
-	return map[string]string{}
+	status.Replicas = int32(len(pods))
+	updatedPods, _ := splitPodsByTemplate(pods, podTemplateHash(current.Spec.Template))
+	status.UpdatedReplicas = int32(len(updatedPods))
+	status.ReadyReplicas = 0
+	for _, pod := range pods {
+		if podReady(pod) {
+			status.ReadyReplicas++
+		}
+	}
+	status.AvailableReplicas = status.ReadyReplicas
+	status.UnavailableReplicas = status.Replicas - status.ReadyReplicas
+	conditionChanged := setPausedCondition(&status, current.Spec.Paused)
+	if !current.Spec.Paused {
+		changed, recheck := setProgressCondition(&status, &current.Status, current, newGeneration, time.Now())
+		conditionChanged = conditionChanged || changed
+		dc.scheduleProgressCheck(namespace, name, recheck)
+	}
+
+	if !conditionChanged &&
+		status.ObservedGeneration == current.Status.ObservedGeneration &&
+		status.Replicas == current.Status.Replicas &&
+		status.UpdatedReplicas == current.Status.UpdatedReplicas &&
+		status.ReadyReplicas == current.Status.ReadyReplicas &&
+		status.AvailableReplicas == current.Status.AvailableReplicas &&
+		status.UnavailableReplicas == current.Status.UnavailableReplicas {
+		return nil
+	}
+
+	updated := current.DeepCopy()
+	updated.Status = status
+	return dc.store.Update(deployGVK, updated)
 }
 
-func labelsMatchAll(labels map[string]string, selector map[string]string) bool {
-	if len(selector) == 0 {
-		return false
+// scheduleProgressCheck 在 after 之后重新检查 Deployment 的状态（发布期限到期），替换之前的定时器；after 为 0 时只取消定时器
+func (dc *DeploymentController) scheduleProgressCheck(namespace, name string, after time.Duration) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	dc.timersMu.Lock()
+	defer dc.timersMu.Unlock()
+	if timer, ok := dc.progressTimers[key]; ok {
+		timer.Stop()
+		delete(dc.progressTimers, key)
+	}
+	if after <= 0 {
+		return
 	}
-	for k, v := range selector {
-		if labels == nil {
-			return false
+	select {
+	case <-dc.stopCh:
+		return
+	default:
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(after, func() {
+		dc.timersMu.Lock()
+		if dc.progressTimers[key] == timer {
+			delete(dc.progressTimers, key)
 		}
-		if labels[k] != v {
-			return false
+		dc.timersMu.Unlock()
+		if err := dc.updateStatus(namespace, name, 0); err != nil {
+			dc.logger.Warnf("检查 Deployment %s/%s 的发布期限失败: %v", namespace, name, err)
 		}
+	})
+	dc.progressTimers[key] = timer
+}
+
+// listPods 返回属于 Deployment 的 Pod：按 selector 走存储的标签索引查询，
+// selector 为空或不合法（只能按 ownerReference 关联）时列出 namespace 下所有 Pod
+func (dc *DeploymentController) listPods(deployment *appsv1.Deployment) ([]*corev1.Pod, error) {
+	podGVK := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	selector := deploymentSelector(deployment)
+
+	var objects []runtime.Object
+	var err error
+	if selector.Empty() {
+		objects, err = dc.store.List(podGVK, deployment.Namespace)
+	} else {
+		objects, err = dc.store.ListBySelector(podGVK, deployment.Namespace, selector)
 	}
-	return true
+	if err != nil {
+		return nil, err
+	}
+	return podsForDeployment(deployment, objects), nil
+}
+
+// podsForDeployment 返回属于 Deployment 的 Pod
+func podsForDeployment(deployment *appsv1.Deployment, objects []runtime.Object) []*corev1.Pod {
+	selector := deploymentSelector(deployment)
+
+	var pods []*corev1.Pod
+	for _, obj := range objects {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			// MySQLStore 目前只持久化 labels/annotations，OwnerReferences 可能不会被完整恢复。
+			// 因此这里优先用 selector 关联 Pod，避免重复创建。
+			if (!selector.Empty() && selector.Matches(labels.Set(pod.Labels))) || hasDeploymentOwnerRef(pod, deployment.Name) {
+				pods = append(pods, pod)
+			}
+		}
+	}
+	return pods
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// deploymentSelector 返回 Deployment 的 spec.selector（apiserver 在准入时已按 Pod 模板的 labels 补齐并校验）；
+// 没有 selector 或不合法时返回空 selector，只按 ownerReference 关联 Pod
+func deploymentSelector(deploy *appsv1.Deployment) labels.Selector {
+	if deploy == nil || deploy.Spec.Selector == nil {
+		return labels.Everything()
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return labels.Everything()
+	}
+	return selector
 }
 
 func hasDeploymentOwnerRef(pod *corev1.Pod, deployName string) bool {
@@ -240,10 +484,16 @@ func hasDeploymentOwnerRef(pod *corev1.Pod, deployName string) bool {
 	return false
 }
 
-// createPodForDeployment 为 Deployment 创建 Pod
-func (dc *DeploymentController) createPodForDeployment(deployment *appsv1.Deployment, index int32) *corev1.Pod {
+// createPodForDeployment 按模板为 Deployment 创建 Pod，hash 写入 pod-template-hash 标签
+func (dc *DeploymentController) createPodForDeployment(deployment *appsv1.Deployment, template corev1.PodTemplateSpec, hash string) *corev1.Pod {
 	podName := fmt.Sprintf("%s-%d", deployment.Name, time.Now().UnixNano())
 
+	podLabels := make(map[string]string, len(template.Labels)+1)
+	for k, v := range template.Labels {
+		podLabels[k] = v
+	}
+	podLabels[appsv1.DefaultDeploymentUniqueLabelKey] = hash
+
 	pod := &corev1.Pod{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "v1",
@@ -252,7 +502,7 @@ func (dc *DeploymentController) createPodForDeployment(deployment *appsv1.Deploy
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      podName,
 			Namespace: deployment.Namespace,
-			Labels:    deployment.Spec.Template.Labels,
+			Labels:    podLabels,
 			OwnerReferences: []metav1.OwnerReference{
 				{
 					APIVersion: deployment.APIVersion,
@@ -264,7 +514,7 @@ func (dc *DeploymentController) createPodForDeployment(deployment *appsv1.Deploy
 			},
 			CreationTimestamp: metav1.Now(),
 		},
-		Spec: deployment.Spec.Template.Spec,
+		Spec: *template.Spec.DeepCopy(),
 		Status: corev1.PodStatus{
 			Phase: corev1.PodPending,
 		},
@@ -277,3 +527,148 @@ func (dc *DeploymentController) createPodForDeployment(deployment *appsv1.Deploy
 
 	return pod
 }
+
+// podTemplateHash 返回 Pod 模板的哈希，写入 Pod 的 pod-template-hash 标签，用于区分副本属于哪个版本的模板
+func podTemplateHash(template corev1.PodTemplateSpec) string {
+	data, _ := json.Marshal(template)
+	hasher := fnv.New32a()
+	hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// splitPodsByTemplate 按 pod-template-hash 把 Pod 分为当前模板的副本与旧版本的副本。
+// 没有该标签的 Pod（升级前创建的）视为当前模板的副本，升级后不会被整体替换
+func splitPodsByTemplate(pods []*corev1.Pod, hash string) (updated, old []*corev1.Pod) {
+	for _, pod := range pods {
+		if h, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok && h != hash {
+			old = append(old, pod)
+		} else {
+			updated = append(updated, pod)
+		}
+	}
+	return updated, old
+}
+
+// templateFromPod 由现有副本还原其模板（去掉调度结果），暂停期间扩容时沿用旧版本
+func templateFromPod(pod *corev1.Pod) (corev1.PodTemplateSpec, string) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: make(map[string]string, len(pod.Labels))},
+		Spec:       *pod.Spec.DeepCopy(),
+	}
+	for k, v := range pod.Labels {
+		if k != appsv1.DefaultDeploymentUniqueLabelKey {
+			template.Labels[k] = v
+		}
+	}
+	template.Spec.NodeName = ""
+	return template, pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+}
+
+// setPausedCondition 按 spec.paused 维护 Progressing 条件（与 Kubernetes 相同）：暂停时为 Unknown/DeploymentPaused，
+// 恢复后为 True/DeploymentResumed；返回条件是否变化
+func setPausedCondition(status *appsv1.DeploymentStatus, paused bool) bool {
+	var existing *appsv1.DeploymentCondition
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == appsv1.DeploymentProgressing {
+			existing = &status.Conditions[i]
+		}
+	}
+	var cond appsv1.DeploymentCondition
+	switch {
+	case paused && (existing == nil || existing.Reason != deploymentPausedReason):
+		cond = appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionUnknown,
+			Reason: deploymentPausedReason, Message: "Deployment is paused"}
+	case !paused && existing != nil && existing.Reason == deploymentPausedReason:
+		cond = appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue,
+			Reason: deploymentResumedReason, Message: "Deployment is resumed"}
+	default:
+		return false
+	}
+	now := metav1.Now()
+	cond.LastUpdateTime, cond.LastTransitionTime = now, now
+	if existing != nil {
+		if existing.Status == cond.Status {
+			cond.LastTransitionTime = existing.LastTransitionTime
+		}
+		*existing = cond
+	} else {
+		status.Conditions = append(status.Conditions, cond)
+	}
+	return true
+}
+
+// setProgressCondition 按发布进展维护 Progressing 条件（与 Kubernetes 相同）：发布完成时为 True/NewReplicaSetAvailable；
+// spec 变化或有进展（更新的副本、就绪的副本增加，旧副本减少）时为 True/ReplicaSetUpdated 并刷新 lastUpdateTime；
+// 距上次进展超过 spec.progressDeadlineSeconds 时为 False/ProgressDeadlineExceeded。
+// 返回条件是否变化，以及距期限到期还有多久（不需要再检查时为 0）
+func setProgressCondition(status, previous *appsv1.DeploymentStatus, deployment *appsv1.Deployment, newGeneration bool, now time.Time) (bool, time.Duration) {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	existing := progressingCondition(status)
+	complete := status.UpdatedReplicas == replicas && status.Replicas == replicas && status.AvailableReplicas >= replicas
+	progressed := status.UpdatedReplicas > previous.UpdatedReplicas ||
+		status.ReadyReplicas > previous.ReadyReplicas ||
+		status.Replicas-status.UpdatedReplicas < previous.Replicas-previous.UpdatedReplicas
+
+	var cond appsv1.DeploymentCondition
+	switch {
+	case complete:
+		if existing != nil && existing.Reason == deploymentAvailableReason {
+			return false, 0
+		}
+		cond = appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue,
+			Reason: deploymentAvailableReason, Message: fmt.Sprintf("Deployment %q has successfully progressed.", deployment.Name)}
+	case newGeneration || progressed || existing == nil:
+		cond = appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue,
+			Reason: deploymentUpdatedReason, Message: fmt.Sprintf("Deployment %q is progressing.", deployment.Name)}
+	case existing.Reason == deploymentTimedOutReason || existing.Reason == deploymentAvailableReason:
+		// 已超时的发布等待 spec 变化或新的进展；已完成的发布不再计算期限
+		return false, 0
+	default:
+		if deployment.Spec.ProgressDeadlineSeconds == nil {
+			return false, 0
+		}
+		deadline := time.Duration(*deployment.Spec.ProgressDeadlineSeconds) * time.Second
+		if remaining := existing.LastUpdateTime.Add(deadline).Sub(now); remaining > 0 {
+			return false, remaining
+		}
+		cond = appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse,
+			Reason: deploymentTimedOutReason, Message: fmt.Sprintf("Deployment %q has timed out progressing.", deployment.Name)}
+	}
+
+	t := metav1.NewTime(now)
+	cond.LastUpdateTime, cond.LastTransitionTime = t, t
+	if existing != nil {
+		if existing.Status == cond.Status {
+			cond.LastTransitionTime = existing.LastTransitionTime
+		}
+		*existing = cond
+	} else {
+		status.Conditions = append(status.Conditions, cond)
+	}
+	if cond.Reason == deploymentUpdatedReason && deployment.Spec.ProgressDeadlineSeconds != nil {
+		return true, time.Duration(*deployment.Spec.ProgressDeadlineSeconds) * time.Second
+	}
+	return true, 0
+}
+
+// progressingCondition 返回 status 中的 Progressing 条件，没有时返回 nil
+func progressingCondition(status *appsv1.DeploymentStatus) *appsv1.DeploymentCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == appsv1.DeploymentProgressing {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// deploymentTimedOut 判断 Deployment 当前 spec 的发布是否已超过 progressDeadlineSeconds（spec 变化后控制器处理之前不算）
+func deploymentTimedOut(deployment *appsv1.Deployment) bool {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+	cond := progressingCondition(&deployment.Status)
+	return cond != nil && cond.Reason == deploymentTimedOutReason
+}
@@ -0,0 +1,327 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// defaultDeschedulerInterval 未配置 descheduler.interval 时的检查周期
+	defaultDeschedulerInterval = 5 * time.Minute
+	// defaultDeschedulerMaxEvictions 未配置 descheduler.max_evictions 时每轮最多驱逐的 Pod 数
+	defaultDeschedulerMaxEvictions = 1
+	// deschedulerComponent descheduler 记录 Event 时使用的组件名
+	deschedulerComponent = "k3-descheduler"
+)
+
+// DeschedulerController 重新均衡 Pod：节点加入或恢复后，已调度的 Pod 不会自动迁移。descheduler 周期性检查本节点上的 Pod，
+// 驱逐分布不均的 Deployment Pod（删除后由 Deployment 控制器重建，调度器优先选择同一控制器 Pod 少、占用低的节点）：
+//   - RemoveDuplicates：同一 Deployment 的多个 Pod 在本节点上，且其他节点放得下、同一 Deployment 的 Pod 更少
+//   - 高占用：本节点 requests 占比超过 highThreshold，且有所有项都低于 lowThreshold 的节点放得下
+//
+// 只驱逐本节点上的 Pod，多个节点同时开启时不会重复驱逐同一个 Pod
+type DeschedulerController struct {
+	store            storage.Store
+	logger           logprovider.Logger
+	nodeName         string
+	interval         time.Duration
+	dryRun           bool
+	maxEvictions     int
+	removeDuplicates bool
+	highThreshold    float64
+	lowThreshold     float64
+	stopCh           chan struct{}
+	metrics          *controllerMetrics
+}
+
+// eviction 一次计划中的驱逐
+type eviction struct {
+	pod    *corev1.Pod
+	target string
+	reason string
+}
+
+// NewDeschedulerController 创建 descheduler；cfg.Enabled 为 false 时返回 nil（未开启）
+func NewDeschedulerController(store storage.Store, logger logprovider.Logger, nodeName string, cfg config.DeschedulerConfig) (*DeschedulerController, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	interval, err := parseOptionalDuration("descheduler.interval", cfg.Interval, defaultDeschedulerInterval)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxEvictions < 0 {
+		return nil, fmt.Errorf("descheduler.max_evictions 无效: %d", cfg.MaxEvictions)
+	}
+	maxEvictions := cfg.MaxEvictions
+	if maxEvictions == 0 {
+		maxEvictions = defaultDeschedulerMaxEvictions
+	}
+	if cfg.HighThresholdPercent < 0 || cfg.HighThresholdPercent > 100 {
+		return nil, fmt.Errorf("descheduler.high_threshold_percent 需要在 0-100 之间: %d", cfg.HighThresholdPercent)
+	}
+	if cfg.HighThresholdPercent > 0 && (cfg.LowThresholdPercent <= 0 || cfg.LowThresholdPercent >= cfg.HighThresholdPercent) {
+		return nil, fmt.Errorf("descheduler.low_threshold_percent 需要大于 0 且小于 high_threshold_percent: %d", cfg.LowThresholdPercent)
+	}
+	if !cfg.RemoveDuplicates && cfg.HighThresholdPercent == 0 {
+		return nil, fmt.Errorf("descheduler 没有开启任何策略（remove_duplicates 或 high_threshold_percent）")
+	}
+	return &DeschedulerController{
+		store:            store,
+		logger:           logger,
+		nodeName:         nodeName,
+		interval:         interval,
+		dryRun:           cfg.DryRun,
+		maxEvictions:     maxEvictions,
+		removeDuplicates: cfg.RemoveDuplicates,
+		highThreshold:    float64(cfg.HighThresholdPercent),
+		lowThreshold:     float64(cfg.LowThresholdPercent),
+		stopCh:           make(chan struct{}),
+	}, nil
+}
+
+// Name 返回控制器名称
+func (dc *DeschedulerController) Name() string {
+	return "DeschedulerController"
+}
+
+func (dc *DeschedulerController) setMetrics(m *controllerMetrics) {
+	dc.metrics = m
+}
+
+// Start 周期检查；第一次检查在一个周期之后，等其他节点启动并上报
+func (dc *DeschedulerController) Start(ctx context.Context) error {
+	dc.logger.Infof("启动 descheduler（节点: %s，周期 %s，dry_run=%v）", dc.nodeName, dc.interval, dc.dryRun)
+	go func() {
+		ticker := time.NewTicker(dc.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-dc.stopCh:
+				return
+			case <-ticker.C:
+				start := time.Now()
+				err := dc.deschedule()
+				if err != nil {
+					dc.logger.Warnf("descheduler 检查失败: %v", err)
+				}
+				dc.metrics.observe(start, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止周期检查
+func (dc *DeschedulerController) Stop(ctx context.Context) error {
+	close(dc.stopCh)
+	return nil
+}
+
+// deschedule 按策略计划本节点上要驱逐的 Pod 并执行（dry_run 时只记录）
+func (dc *DeschedulerController) deschedule() error {
+	nodeObjs, err := dc.store.List(nodeGVK, "")
+	if err != nil {
+		return fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	var self *corev1.Node
+	var others []*corev1.Node
+	for _, obj := range nodeObjs {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !isNodeReady(node) {
+			continue
+		}
+		if node.Name == dc.nodeName {
+			self = node
+		} else if !node.Spec.Unschedulable && !nodeUnderPressure(node) {
+			others = append(others, node)
+		}
+	}
+	if self == nil || len(others) == 0 {
+		return nil
+	}
+
+	podObjs, err := dc.store.List(podGVK, "")
+	if err != nil {
+		return fmt.Errorf("获取 Pod 列表失败: %w", err)
+	}
+	budgets, err := listDisruptionBudgets(dc.store, dc.logger, podObjs)
+	if err != nil {
+		return err
+	}
+	allowed := make(map[*disruptionBudget]int, len(budgets))
+	for _, b := range budgets {
+		allowed[b] = b.allowed
+	}
+
+	// 计划驱逐时按目标节点模拟迁移，后面的判断基于迁移后的分布
+	podsByNode := activePodsByNode(podObjs, nil)
+	var plan []eviction
+	move := func(p *corev1.Pod, target, reason string) {
+		plan = append(plan, eviction{pod: p, target: target, reason: reason})
+		podsByNode[dc.nodeName] = removePod(podsByNode[dc.nodeName], p)
+		podsByNode[target] = append(podsByNode[target], p)
+	}
+	if dc.removeDuplicates {
+		dc.planDuplicates(self, others, podsByNode, budgets, allowed, len(plan), move)
+	}
+	if dc.highThreshold > 0 {
+		dc.planHighUtilization(self, others, podsByNode, budgets, allowed, len(plan), move)
+	}
+
+	for _, e := range plan {
+		if dc.dryRun {
+			dc.logger.Infof("[dry-run] descheduler 将驱逐 Pod %s/%s（%s，可调度到 %s）", e.pod.Namespace, e.pod.Name, e.reason, e.target)
+			continue
+		}
+		dc.logger.Infof("descheduler 驱逐 Pod %s/%s（%s，可调度到 %s）", e.pod.Namespace, e.pod.Name, e.reason, e.target)
+		if err := dc.store.Delete(podGVK, e.pod.Namespace, e.pod.Name); err != nil {
+			dc.logger.Warnf("驱逐 Pod %s/%s 失败: %v", e.pod.Namespace, e.pod.Name, err)
+			continue
+		}
+		message := fmt.Sprintf("Evicted by descheduler from node %s: %s", dc.nodeName, e.reason)
+		if err := RecordEvent(dc.store, e.pod, corev1.EventTypeNormal, "Descheduled", message, deschedulerComponent); err != nil {
+			dc.logger.Warnf("记录驱逐事件失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// planDuplicates 同一 Deployment 在本节点有多个 Pod 时，把多余的 Pod 计划迁移到放得下、同一 Deployment 的 Pod 更少的节点
+func (dc *DeschedulerController) planDuplicates(self *corev1.Node, others []*corev1.Node, podsByNode map[string][]*corev1.Pod,
+	budgets []*disruptionBudget, allowed map[*disruptionBudget]int, planned int, move func(*corev1.Pod, string, string)) {
+	groups := make(map[string][]*corev1.Pod)
+	var owners []string
+	for _, p := range dc.evictablePods(podsByNode[self.Name]) {
+		owner := podOwnerKey(p)
+		if groups[owner] == nil {
+			owners = append(owners, owner)
+		}
+		groups[owner] = append(groups[owner], p)
+	}
+	sort.Strings(owners)
+
+	for _, owner := range owners {
+		for _, p := range groups[owner] {
+			if planned >= dc.maxEvictions {
+				return
+			}
+			local := ownedPods(podsByNode[self.Name], owner)
+			if local < 2 {
+				break
+			}
+			// 迁移后目标节点上同一 Deployment 的 Pod 仍然比本节点少，避免来回迁移
+			target := bestTarget(p, others, podsByNode, func(node *corev1.Node) (float64, bool) {
+				n := ownedPods(podsByNode[node.Name], owner)
+				return float64(n), n+1 < local
+			})
+			if target == "" || !consumeBudgets(p, budgets, allowed) {
+				continue
+			}
+			move(p, target, fmt.Sprintf("同一 Deployment 有 %d 个 Pod 在本节点", local))
+			planned++
+		}
+	}
+}
+
+// planHighUtilization 本节点 requests 占比超过 highThreshold 时，把 Pod 计划迁移到占比低于 lowThreshold 的节点，
+// 迁移后目标节点不超过 highThreshold；直到本节点不超过 highThreshold
+func (dc *DeschedulerController) planHighUtilization(self *corev1.Node, others []*corev1.Node, podsByNode map[string][]*corev1.Pod,
+	budgets []*disruptionBudget, allowed map[*disruptionBudget]int, planned int, move func(*corev1.Pod, string, string)) {
+	usage := requestedPercent(self, podsByNode[self.Name])
+	if usage <= dc.highThreshold {
+		return
+	}
+	var underutilized []*corev1.Node
+	for _, node := range others {
+		if requestedPercent(node, podsByNode[node.Name]) < dc.lowThreshold {
+			underutilized = append(underutilized, node)
+		}
+	}
+	if len(underutilized) == 0 {
+		dc.logger.Debugf("节点 %s 占用 %.0f%% 超过 %.0f%%，但没有占用低于 %.0f%% 的节点", self.Name, usage, dc.highThreshold, dc.lowThreshold)
+		return
+	}
+
+	for _, p := range dc.evictablePods(podsByNode[self.Name]) {
+		if planned >= dc.maxEvictions || requestedPercent(self, podsByNode[self.Name]) <= dc.highThreshold {
+			return
+		}
+		target := bestTarget(p, underutilized, podsByNode, func(node *corev1.Node) (float64, bool) {
+			after := requestedPercent(node, append(append([]*corev1.Pod(nil), podsByNode[node.Name]...), p))
+			return after, after <= dc.highThreshold
+		})
+		if target == "" || !consumeBudgets(p, budgets, allowed) {
+			continue
+		}
+		move(p, target, fmt.Sprintf("节点占用 %.0f%% 超过 %.0f%%", requestedPercent(self, podsByNode[self.Name]), dc.highThreshold))
+		planned++
+	}
+}
+
+// evictablePods 返回可以驱逐的 Pod，按优先级从低到高、相同优先级先驱逐新创建的：
+// 只驱逐 Running 的 Deployment Pod（删除后会被重建），不驱逐 static Pod、import 镜像的 Pod 与系统优先级的 Pod
+func (dc *DeschedulerController) evictablePods(pods []*corev1.Pod) []*corev1.Pod {
+	var evictable []*corev1.Pod
+	for _, p := range pods {
+		ref := metav1.GetControllerOf(p)
+		if ref == nil || ref.Kind != "Deployment" || p.Status.Phase != corev1.PodRunning || p.DeletionTimestamp != nil {
+			continue
+		}
+		if IsMirrorPod(p) || mirror.IsImported(p) || apiserver.PodPriority(p) >= apiserver.SystemCriticalPriority {
+			continue
+		}
+		evictable = append(evictable, p)
+	}
+	sort.SliceStable(evictable, func(i, j int) bool {
+		pi, pj := apiserver.PodPriority(evictable[i]), apiserver.PodPriority(evictable[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return evictable[j].CreationTimestamp.Before(&evictable[i].CreationTimestamp)
+	})
+	return evictable
+}
+
+// bestTarget 在 nodes 中选择满足 nodeSelector、放得下 p 且 score 返回可用的节点中得分最低的，没有时返回空
+func bestTarget(p *corev1.Pod, nodes []*corev1.Node, podsByNode map[string][]*corev1.Pod,
+	score func(*corev1.Node) (float64, bool)) string {
+	requests := podRequests(p)
+	best, bestScore := "", 0.0
+	for _, node := range nodes {
+		if !nodeMatchesSelector(p, node) {
+			continue
+		}
+		if _, fits := nodeFits(requests, node, podsByNode[node.Name]); !fits {
+			continue
+		}
+		s, ok := score(node)
+		if ok && (best == "" || s < bestScore) {
+			best, bestScore = node.Name, s
+		}
+	}
+	return best
+}
+
+// removePod 返回去掉 p 之后的 pods
+func removePod(pods []*corev1.Pod, p *corev1.Pod) []*corev1.Pod {
+	out := make([]*corev1.Pod, 0, len(pods))
+	for _, q := range pods {
+		if q != p {
+			out = append(out, q)
+		}
+	}
+	return out
+}
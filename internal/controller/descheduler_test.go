@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ownedBy 设置 Pod 所属的 Deployment 与对应的 app 标签
+func ownedBy(deployment string) func(*corev1.Pod) {
+	return func(p *corev1.Pod) {
+		controller := true
+		p.Labels = map[string]string{"app": deployment}
+		p.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       deployment,
+			UID:        types.UID("uid-" + deployment),
+			Controller: &controller,
+		}}
+	}
+}
+
+// webPods 返回 node-1 上同一 Deployment（web）的 n 个 Pod
+func webPods(n int) []*corev1.Pod {
+	var pods []*corev1.Pod
+	for i := 1; i <= n; i++ {
+		pods = append(pods, testPod("web-"+string(rune('0'+i)), 0, "500m", i, onNode("node-1"), ownedBy("web"), ready))
+	}
+	return pods
+}
+
+func TestDeschedule(t *testing.T) {
+	duplicates := config.DeschedulerConfig{Enabled: true, RemoveDuplicates: true, MaxEvictions: 5}
+	for _, tc := range []struct {
+		name  string
+		cfg   config.DeschedulerConfig
+		nodes int
+		pods  []*corev1.Pod
+		// minAvailable 不为 0 时创建匹配 app=web 的 PodDisruptionBudget
+		minAvailable int
+		want         []string
+	}{
+		{
+			name:  "duplicates move until balanced",
+			cfg:   duplicates,
+			nodes: 3,
+			pods:  webPods(4),
+			want:  []string{"web-3", "web-4"},
+		},
+		{
+			name:  "duplicates respect max evictions",
+			cfg:   config.DeschedulerConfig{Enabled: true, RemoveDuplicates: true},
+			nodes: 3,
+			pods:  webPods(4),
+			want:  []string{"web-4"},
+		},
+		{
+			name:  "single pod per deployment stays",
+			cfg:   duplicates,
+			nodes: 2,
+			pods: []*corev1.Pod{
+				testPod("a", 0, "500m", 1, onNode("node-1"), ownedBy("a"), ready),
+				testPod("b", 0, "500m", 2, onNode("node-1"), ownedBy("b"), ready),
+			},
+		},
+		{
+			name:  "unowned and static pods stay",
+			cfg:   duplicates,
+			nodes: 2,
+			pods: []*corev1.Pod{
+				testPod("bare-1", 0, "500m", 1, onNode("node-1"), ready),
+				testPod("bare-2", 0, "500m", 2, onNode("node-1"), ready),
+				testPod("static-1", 0, "500m", 3, onNode("node-1"), ownedBy("static"), staticPod, ready),
+				testPod("static-2", 0, "500m", 4, onNode("node-1"), ownedBy("static"), staticPod, ready),
+			},
+		},
+		{
+			name:         "budget blocks evictions",
+			cfg:          duplicates,
+			nodes:        3,
+			pods:         webPods(4),
+			minAvailable: 4,
+		},
+		{
+			name:         "budget limits evictions",
+			cfg:          duplicates,
+			nodes:        3,
+			pods:         webPods(4),
+			minAvailable: 3,
+			want:         []string{"web-4"},
+		},
+		{
+			name:  "high utilization evicts lowest priority first",
+			cfg:   config.DeschedulerConfig{Enabled: true, HighThresholdPercent: 80, LowThresholdPercent: 30, MaxEvictions: 5},
+			nodes: 2,
+			pods: []*corev1.Pod{
+				testPod("a", 0, "1", 1, onNode("node-1"), ownedBy("a"), ready),
+				testPod("b", 0, "1", 2, onNode("node-1"), ownedBy("b"), ready),
+				testPod("c", 0, "1", 3, onNode("node-1"), ownedBy("c"), ready),
+				testPod("d", 5, "1", 4, onNode("node-1"), ownedBy("d"), ready),
+			},
+			want: []string{"c"},
+		},
+		{
+			name:  "high utilization needs an underutilized node",
+			cfg:   config.DeschedulerConfig{Enabled: true, HighThresholdPercent: 80, LowThresholdPercent: 30, MaxEvictions: 5},
+			nodes: 2,
+			pods: []*corev1.Pod{
+				testPod("a", 0, "2", 1, onNode("node-1"), ownedBy("a"), ready),
+				testPod("b", 0, "2", 2, onNode("node-1"), ownedBy("b"), ready),
+				testPod("c", 0, "2", 3, onNode("node-2"), ownedBy("c"), ready),
+			},
+		},
+		{
+			name: "dry run does not evict",
+			cfg: config.DeschedulerConfig{
+				Enabled: true, DryRun: true, RemoveDuplicates: true, HighThresholdPercent: 80, LowThresholdPercent: 30, MaxEvictions: 5,
+			},
+			nodes: 3,
+			pods:  webPods(4),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := storage.NewMemoryStore()
+			for i := 1; i <= tc.nodes; i++ {
+				if err := store.Create(nodeGVK, testNode("node-"+string(rune('0'+i)), "4")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, p := range tc.pods {
+				if err := store.Create(podGVK, p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.minAvailable > 0 {
+				minAvailable := intstr.FromInt32(int32(tc.minAvailable))
+				pdb := &policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+					Spec: policyv1.PodDisruptionBudgetSpec{
+						MinAvailable: &minAvailable,
+						Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					},
+				}
+				if err := store.Create(apiserver.PodDisruptionBudgetGVK, pdb); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dc, err := NewDeschedulerController(store, testLogger, "node-1", tc.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := dc.deschedule(); err != nil {
+				t.Fatal(err)
+			}
+
+			var evicted []string
+			for _, p := range tc.pods {
+				if _, err := store.Get(podGVK, p.Namespace, p.Name); err != nil {
+					evicted = append(evicted, p.Name)
+				}
+			}
+			sort.Strings(evicted)
+			if strings.Join(evicted, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("evicted %v, want %v", evicted, tc.want)
+			}
+			events, err := store.List(EventGVK, "default")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != len(tc.want) {
+				t.Fatalf("%d events, want %d", len(events), len(tc.want))
+			}
+			for _, obj := range events {
+				if e := obj.(*corev1.Event); e.Reason != "Descheduled" {
+					t.Fatalf("event reason %q", e.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDeschedulerControllerValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  config.DeschedulerConfig
+		err  string
+	}{
+		{"no strategy", config.DeschedulerConfig{Enabled: true}, "没有开启任何策略"},
+		{"negative max evictions", config.DeschedulerConfig{Enabled: true, RemoveDuplicates: true, MaxEvictions: -1}, "max_evictions"},
+		{"high threshold above 100", config.DeschedulerConfig{Enabled: true, HighThresholdPercent: 120, LowThresholdPercent: 10}, "high_threshold_percent"},
+		{"low threshold not below high", config.DeschedulerConfig{Enabled: true, HighThresholdPercent: 50, LowThresholdPercent: 50}, "low_threshold_percent"},
+		{"bad interval", config.DeschedulerConfig{Enabled: true, RemoveDuplicates: true, Interval: "soon"}, "descheduler.interval"},
+	} {
+		if _, err := NewDeschedulerController(storage.NewMemoryStore(), testLogger, "node-1", tc.cfg); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.err)
+		}
+	}
+	if dc, err := NewDeschedulerController(storage.NewMemoryStore(), testLogger, "node-1", config.DeschedulerConfig{}); dc != nil || err != nil {
+		t.Errorf("disabled: %v, %v", dc, err)
+	}
+}
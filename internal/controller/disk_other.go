@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package controller
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// diskUsage 在当前平台上不可用，镜像回收会跳过
+func diskUsage(path string) (capacity, used uint64, err error) {
+	return 0, 0, fmt.Errorf("%s 平台不支持统计磁盘使用率", runtime.GOOS)
+}
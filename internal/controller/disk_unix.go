@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package controller
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// diskUsage 返回 path 所在文件系统的总容量和已用字节数
+func diskUsage(path string) (capacity, used uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	capacity = st.Blocks * uint64(st.Bsize)
+	used = capacity - st.Bfree*uint64(st.Bsize)
+	return capacity, used, nil
+}
@@ -0,0 +1,293 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Downward API：env 的 valueFrom.fieldRef 与 downwardAPI 卷
+
+// DownwardAPIRefresher 由支持 downwardAPI 卷的运行时实现（目前为 Docker）。
+// Pod 的标签、注解等变化后由运行时控制器调用，重新生成卷中的文件
+type DownwardAPIRefresher interface {
+	// RefreshDownwardAPI 按 Pod 当前的元数据更新其 downwardAPI 卷中的文件（内容没有变化的文件不改写）
+	RefreshDownwardAPI(ctx context.Context, pod *corev1.Pod) error
+}
+
+// podFieldValue 返回 fieldRef 引用的 Pod 字段的值。podIP 为运行时得到的 Pod IP（为空时使用 status.podIP）。
+// 支持 metadata.name/namespace/uid、metadata.labels['<key>']、metadata.annotations['<key>']、
+// spec.nodeName、spec.serviceAccountName、status.podIP；metadata.labels 与 metadata.annotations 只能用于卷
+func podFieldValue(pod *corev1.Pod, fieldPath, podIP string) (string, error) {
+	if podIP == "" {
+		podIP = pod.Status.PodIP
+	}
+	switch fieldPath {
+	case "metadata.name":
+		return pod.Name, nil
+	case "metadata.namespace":
+		return pod.Namespace, nil
+	case "metadata.uid":
+		return string(pod.UID), nil
+	case "metadata.labels":
+		return formatDownwardMap(pod.Labels), nil
+	case "metadata.annotations":
+		return formatDownwardMap(pod.Annotations), nil
+	case "spec.nodeName":
+		return pod.Spec.NodeName, nil
+	case "spec.serviceAccountName":
+		return pod.Spec.ServiceAccountName, nil
+	case "status.podIP":
+		return podIP, nil
+	}
+	if key, ok := subscriptKey(fieldPath, "metadata.labels"); ok {
+		return pod.Labels[key], nil
+	}
+	if key, ok := subscriptKey(fieldPath, "metadata.annotations"); ok {
+		return pod.Annotations[key], nil
+	}
+	return "", fmt.Errorf("不支持的 fieldRef 字段: %s", fieldPath)
+}
+
+// subscriptKey 解析 prefix['key'] 形式的字段路径
+func subscriptKey(fieldPath, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(fieldPath, prefix+"['")
+	if !ok {
+		return "", false
+	}
+	return strings.CutSuffix(rest, "']")
+}
+
+// formatDownwardMap 按 kubelet 的格式输出标签/注解：每行 key="value"，按 key 排序
+func formatDownwardMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s=%q", k, m[k]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// containerEnv 返回容器的环境变量（NAME=value），解析 valueFrom.fieldRef 与 valueFrom.resourceFieldRef；
+// 其他 valueFrom（configMapKeyRef、secretKeyRef）暂不支持，值为空
+func containerEnv(pod *corev1.Pod, container *corev1.Container, podIP string) ([]string, error) {
+	env := make([]string, 0, len(container.Env))
+	for _, e := range container.Env {
+		value := e.Value
+		switch {
+		case e.ValueFrom != nil && e.ValueFrom.FieldRef != nil:
+			path := e.ValueFrom.FieldRef.FieldPath
+			if path == "metadata.labels" || path == "metadata.annotations" {
+				return nil, fmt.Errorf("环境变量 %s: %s 只能用于 downwardAPI 卷", e.Name, path)
+			}
+			v, err := podFieldValue(pod, path, podIP)
+			if err != nil {
+				return nil, fmt.Errorf("环境变量 %s: %w", e.Name, err)
+			}
+			value = v
+		case e.ValueFrom != nil && e.ValueFrom.ResourceFieldRef != nil:
+			// 环境变量中省略 containerName 时引用容器自身
+			ref := *e.ValueFrom.ResourceFieldRef
+			if ref.ContainerName == "" {
+				ref.ContainerName = container.Name
+			}
+			v, err := containerResourceValue(pod, &ref)
+			if err != nil {
+				return nil, fmt.Errorf("环境变量 %s: %w", e.Name, err)
+			}
+			value = v
+		}
+		env = append(env, e.Name+"="+value)
+	}
+	return env, nil
+}
+
+// containerResourceValue 返回 resourceFieldRef 引用的容器资源，按 divisor 向上取整（与 kubelet 一致）。
+// 支持 limits/requests 的 cpu、memory、ephemeral-storage；未设置 limits 时取 requests，都未设置时为 0
+// （Kubernetes 此时使用节点的 allocatable，运行时拿不到节点信息）
+func containerResourceValue(pod *corev1.Pod, ref *corev1.ResourceFieldSelector) (string, error) {
+	var container *corev1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == ref.ContainerName {
+			container = &pod.Spec.Containers[i]
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		if pod.Spec.InitContainers[i].Name == ref.ContainerName {
+			container = &pod.Spec.InitContainers[i]
+		}
+	}
+	if container == nil {
+		return "", fmt.Errorf("resourceFieldRef 引用的容器不存在: %q", ref.ContainerName)
+	}
+
+	kind, name, ok := strings.Cut(ref.Resource, ".")
+	resourceName := corev1.ResourceName(name)
+	if !ok || (kind != "limits" && kind != "requests") ||
+		(resourceName != corev1.ResourceCPU && resourceName != corev1.ResourceMemory && resourceName != corev1.ResourceEphemeralStorage) {
+		return "", fmt.Errorf("不支持的 resourceFieldRef 资源: %s", ref.Resource)
+	}
+	q, ok := container.Resources.Requests[resourceName]
+	if kind == "limits" {
+		if limit, set := container.Resources.Limits[resourceName]; set {
+			q, ok = limit, true
+		}
+	}
+	if !ok {
+		q = resource.Quantity{}
+	}
+
+	divisor := ref.Divisor
+	if divisor.IsZero() {
+		divisor = resource.MustParse("1")
+	}
+	if resourceName == corev1.ResourceCPU {
+		return strconv.FormatInt(divideCeil(q.MilliValue(), divisor.MilliValue()), 10), nil
+	}
+	return strconv.FormatInt(divideCeil(q.Value(), divisor.Value()), 10), nil
+}
+
+func divideCeil(a, b int64) int64 {
+	if b <= 0 {
+		return 0
+	}
+	return (a + b - 1) / b
+}
+
+// downwardAPIFile downwardAPI 卷中的一个文件
+type downwardAPIFile struct {
+	path    string
+	content []byte
+	mode    os.FileMode
+}
+
+// downwardAPIFiles 按卷的 items 生成文件内容（fieldRef 或 resourceFieldRef，卷中的 resourceFieldRef 必须指定 containerName）
+func downwardAPIFiles(pod *corev1.Pod, source *corev1.DownwardAPIVolumeSource, podIP string) ([]downwardAPIFile, error) {
+	defaultMode := os.FileMode(corev1.DownwardAPIVolumeSourceDefaultMode)
+	if source.DefaultMode != nil {
+		defaultMode = os.FileMode(*source.DefaultMode)
+	}
+	files := make([]downwardAPIFile, 0, len(source.Items))
+	for _, item := range source.Items {
+		if item.Path == "" || filepath.IsAbs(item.Path) || strings.HasPrefix(filepath.Clean(item.Path), "..") {
+			return nil, fmt.Errorf("downwardAPI 文件路径无效: %q", item.Path)
+		}
+		var value string
+		var err error
+		switch {
+		case item.FieldRef != nil:
+			value, err = podFieldValue(pod, item.FieldRef.FieldPath, podIP)
+		case item.ResourceFieldRef != nil:
+			value, err = containerResourceValue(pod, item.ResourceFieldRef)
+		default:
+			err = fmt.Errorf("需要 fieldRef 或 resourceFieldRef")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("downwardAPI 文件 %s: %w", item.Path, err)
+		}
+		mode := defaultMode
+		if item.Mode != nil {
+			mode = os.FileMode(*item.Mode)
+		}
+		files = append(files, downwardAPIFile{path: filepath.Clean(item.Path), content: []byte(value), mode: mode})
+	}
+	return files, nil
+}
+
+// downwardAPIMu 串行化 downwardAPI 卷的写入（启动容器与元数据变化后的刷新可能同时发生）
+var downwardAPIMu sync.Mutex
+
+// writeDownwardAPIVolume 把文件写入 dir：内容变化的文件先写临时文件再 rename，容器内读到的总是完整内容；
+// 不再属于卷的文件被删除。返回是否有文件发生变化
+func writeDownwardAPIVolume(dir string, files []downwardAPIFile) (bool, error) {
+	downwardAPIMu.Lock()
+	defer downwardAPIMu.Unlock()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, err
+	}
+	changed := false
+	wanted := make(map[string]bool, len(files))
+	for _, f := range files {
+		wanted[f.path] = true
+		target := filepath.Join(dir, f.path)
+		if old, err := os.ReadFile(target); err == nil && bytes.Equal(old, f.content) {
+			if err := os.Chmod(target, f.mode); err != nil {
+				return changed, err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return changed, err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-")
+		if err != nil {
+			return changed, err
+		}
+		_, werr := tmp.Write(f.content)
+		cerr := tmp.Close()
+		if werr == nil {
+			werr = cerr
+		}
+		if werr == nil {
+			werr = os.Chmod(tmp.Name(), f.mode)
+		}
+		if werr == nil {
+			werr = os.Rename(tmp.Name(), target)
+		}
+		if werr != nil {
+			_ = os.Remove(tmp.Name())
+			return changed, werr
+		}
+		changed = true
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || wanted[rel] {
+			return err
+		}
+		changed = true
+		return os.Remove(path)
+	})
+	return changed, err
+}
+
+// podDir 返回 Pod 在宿主机上存放卷文件（downwardAPI 卷）的目录，StopContainer 时删除
+func podDir(pod *corev1.Pod) string {
+	key := string(pod.UID)
+	if key == "" {
+		key = pod.Namespace + "_" + pod.Name
+	}
+	return filepath.Join(os.TempDir(), "k3-pods", key)
+}
+
+// downwardAPIVolumeDir 返回 downwardAPI 卷在宿主机上的目录
+func downwardAPIVolumeDir(pod *corev1.Pod, volume string) string {
+	return filepath.Join(podDir(pod), "volumes", "downward-api", volume)
+}
+
+// hasDownwardAPIVolumes Pod 是否有 downwardAPI 卷
+func hasDownwardAPIVolumes(pod *corev1.Pod) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.DownwardAPI != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// downwardPod 返回带标签、注解与资源的 Pod
+func downwardPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web-0",
+			Namespace:   "prod",
+			UID:         "uid-1",
+			Labels:      map[string]string{"app": "web", "tier": "frontend"},
+			Annotations: map[string]string{"build": `v1 "rc"`},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:           "node-1",
+			ServiceAccountName: "web",
+			Containers: []corev1.Container{
+				{Name: "app", Resources: corev1.ResourceRequirements{
+					Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1500m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m"), corev1.ResourceMemory: resource.MustParse("100M")},
+				}},
+				{Name: "sidecar", Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+				}},
+			},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.5"},
+	}
+}
+
+func TestPodFieldValue(t *testing.T) {
+	pod := downwardPod()
+	for _, tc := range []struct {
+		path  string
+		podIP string
+		want  string
+		err   bool
+	}{
+		{path: "metadata.name", want: "web-0"},
+		{path: "metadata.namespace", want: "prod"},
+		{path: "metadata.uid", want: "uid-1"},
+		{path: "spec.nodeName", want: "node-1"},
+		{path: "spec.serviceAccountName", want: "web"},
+		{path: "status.podIP", want: "10.0.0.5"},
+		{path: "status.podIP", podIP: "172.17.0.3", want: "172.17.0.3"},
+		{path: "metadata.labels", want: "app=\"web\"\ntier=\"frontend\""},
+		{path: "metadata.annotations", want: `build="v1 \"rc\""`},
+		{path: "metadata.labels['app']", want: "web"},
+		{path: "metadata.labels['missing']", want: ""},
+		{path: "metadata.annotations['build']", want: `v1 "rc"`},
+		{path: "metadata.labels['app'", err: true},
+		{path: "metadata.labels[app]", err: true},
+		{path: "spec.hostname", err: true},
+	} {
+		got, err := podFieldValue(pod, tc.path, tc.podIP)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: value %q, want error", tc.path, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s (podIP %q) = %q, %v; want %q", tc.path, tc.podIP, got, err, tc.want)
+		}
+	}
+}
+
+func TestContainerEnv(t *testing.T) {
+	fieldEnv := func(name, path string) corev1.EnvVar {
+		return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: path}}}
+	}
+	resourceEnv := func(name, container, res, divisor string) corev1.EnvVar {
+		ref := &corev1.ResourceFieldSelector{ContainerName: container, Resource: res}
+		if divisor != "" {
+			ref.Divisor = resource.MustParse(divisor)
+		}
+		return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{ResourceFieldRef: ref}}
+	}
+
+	for _, tc := range []struct {
+		name string
+		env  corev1.EnvVar
+		want string
+		err  string
+	}{
+		{name: "plain value", env: corev1.EnvVar{Name: "MODE", Value: "prod"}, want: "MODE=prod"},
+		{name: "fieldRef", env: fieldEnv("POD_IP", "status.podIP"), want: "POD_IP=172.17.0.3"},
+		{name: "label subscript", env: fieldEnv("APP", "metadata.labels['app']"), want: "APP=web"},
+		{name: "whole labels only in volumes", env: fieldEnv("LABELS", "metadata.labels"), err: "只能用于 downwardAPI 卷"},
+		{name: "unknown field", env: fieldEnv("X", "spec.hostname"), err: "环境变量 X"},
+		{name: "configMapKeyRef is left empty", env: corev1.EnvVar{Name: "CM", ValueFrom: &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "k"},
+		}}, want: "CM="},
+		{name: "cpu limit rounds up to whole cores", env: resourceEnv("CPU", "", "limits.cpu", ""), want: "CPU=2"},
+		{name: "cpu limit in millicores", env: resourceEnv("CPU", "", "limits.cpu", "1m"), want: "CPU=1500"},
+		{name: "cpu request", env: resourceEnv("CPU", "", "requests.cpu", "100m"), want: "CPU=3"},
+		{name: "memory limit in bytes", env: resourceEnv("MEM", "", "limits.memory", ""), want: "MEM=268435456"},
+		{name: "memory limit in Mi", env: resourceEnv("MEM", "", "limits.memory", "1Mi"), want: "MEM=256"},
+		{name: "memory request rounds up", env: resourceEnv("MEM", "", "requests.memory", "1Mi"), want: "MEM=96"},
+		{name: "other container, limit falls back to request", env: resourceEnv("MEM", "sidecar", "limits.memory", "1Mi"), want: "MEM=64"},
+		{name: "unset resource is zero", env: resourceEnv("CPU", "sidecar", "limits.cpu", ""), want: "CPU=0"},
+		{name: "unknown container", env: resourceEnv("CPU", "db", "limits.cpu", ""), err: "容器不存在"},
+		{name: "unsupported resource", env: resourceEnv("GPU", "", "limits.nvidia.com/gpu", ""), err: "不支持的 resourceFieldRef"},
+	} {
+		pod := downwardPod()
+		container := &pod.Spec.Containers[0]
+		container.Env = []corev1.EnvVar{tc.env}
+		got, err := containerEnv(pod, container, "172.17.0.3")
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: err = %v, want %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || len(got) != 1 || got[0] != tc.want {
+			t.Errorf("%s: env = %v, %v; want %s", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestDownwardAPIFiles(t *testing.T) {
+	mode := int32(0o600)
+	item := func(path, fieldPath string) corev1.DownwardAPIVolumeFile {
+		return corev1.DownwardAPIVolumeFile{Path: path, FieldRef: &corev1.ObjectFieldSelector{FieldPath: fieldPath}}
+	}
+
+	files, err := downwardAPIFiles(downwardPod(), &corev1.DownwardAPIVolumeSource{Items: []corev1.DownwardAPIVolumeFile{
+		item("./name", "metadata.name"),
+		{Path: "meta/labels", FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels"}, Mode: &mode},
+		{Path: "cpu", ResourceFieldRef: &corev1.ResourceFieldSelector{ContainerName: "app", Resource: "limits.cpu", Divisor: resource.MustParse("1m")}},
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []downwardAPIFile{
+		{path: "name", content: []byte("web-0"), mode: os.FileMode(corev1.DownwardAPIVolumeSourceDefaultMode)},
+		{path: filepath.Join("meta", "labels"), content: []byte("app=\"web\"\ntier=\"frontend\""), mode: 0o600},
+		{path: "cpu", content: []byte("1500"), mode: os.FileMode(corev1.DownwardAPIVolumeSourceDefaultMode)},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("files = %+v", files)
+	}
+	for i := range want {
+		if files[i].path != want[i].path || string(files[i].content) != string(want[i].content) || files[i].mode != want[i].mode {
+			t.Errorf("file %d = %+v, want %+v", i, files[i], want[i])
+		}
+	}
+
+	// defaultMode 作用于没有 mode 的文件
+	defaultMode := int32(0o640)
+	files, err = downwardAPIFiles(downwardPod(), &corev1.DownwardAPIVolumeSource{DefaultMode: &defaultMode, Items: []corev1.DownwardAPIVolumeFile{item("name", "metadata.name")}}, "")
+	if err != nil || files[0].mode != 0o640 {
+		t.Errorf("defaultMode: %+v, %v", files, err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		item corev1.DownwardAPIVolumeFile
+		err  string
+	}{
+		{"empty path", item("", "metadata.name"), "路径无效"},
+		{"absolute path", item("/etc/passwd", "metadata.name"), "路径无效"},
+		{"parent directory", item("../name", "metadata.name"), "路径无效"},
+		{"escapes after cleaning", item("meta/../../name", "metadata.name"), "路径无效"},
+		{"no reference", corev1.DownwardAPIVolumeFile{Path: "name"}, "fieldRef 或 resourceFieldRef"},
+		{"unsupported field", item("name", "spec.hostname"), "不支持的 fieldRef"},
+		{"resourceFieldRef without container", corev1.DownwardAPIVolumeFile{Path: "cpu", ResourceFieldRef: &corev1.ResourceFieldSelector{Resource: "limits.cpu"}}, "容器不存在"},
+	} {
+		_, err := downwardAPIFiles(downwardPod(), &corev1.DownwardAPIVolumeSource{Items: []corev1.DownwardAPIVolumeFile{tc.item}}, "")
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestWriteDownwardAPIVolume(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "volume")
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	for _, step := range []struct {
+		name    string
+		files   []downwardAPIFile
+		changed bool
+		want    map[string]string
+	}{
+		{
+			name: "initial write",
+			files: []downwardAPIFile{
+				{path: "name", content: []byte("web-0"), mode: 0o644},
+				{path: filepath.Join("meta", "labels"), content: []byte(`app="web"`), mode: 0o644},
+			},
+			changed: true,
+			want:    map[string]string{"name": "web-0", filepath.Join("meta", "labels"): `app="web"`},
+		},
+		{
+			name: "same content is not rewritten",
+			files: []downwardAPIFile{
+				{path: "name", content: []byte("web-0"), mode: 0o644},
+				{path: filepath.Join("meta", "labels"), content: []byte(`app="web"`), mode: 0o644},
+			},
+			want: map[string]string{"name": "web-0", filepath.Join("meta", "labels"): `app="web"`},
+		},
+		{
+			name: "changed labels",
+			files: []downwardAPIFile{
+				{path: "name", content: []byte("web-0"), mode: 0o644},
+				{path: filepath.Join("meta", "labels"), content: []byte(`app="api"`), mode: 0o644},
+			},
+			changed: true,
+			want:    map[string]string{"name": "web-0", filepath.Join("meta", "labels"): `app="api"`},
+		},
+		{
+			name:    "stale files are removed",
+			files:   []downwardAPIFile{{path: "name", content: []byte("web-0"), mode: 0o644}},
+			changed: true,
+			want:    map[string]string{"name": "web-0"},
+		},
+	} {
+		changed, err := writeDownwardAPIVolume(dir, step.files)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if changed != step.changed {
+			t.Errorf("%s: changed = %v, want %v", step.name, changed, step.changed)
+		}
+		var present []string
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				rel, _ := filepath.Rel(dir, path)
+				present = append(present, rel)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(present) != len(step.want) {
+			t.Errorf("%s: files %v, want %v (no temporary files left)", step.name, present, step.want)
+		}
+		for path, content := range step.want {
+			if got := read(path); got != content {
+				t.Errorf("%s: %s = %q, want %q", step.name, path, got, content)
+			}
+		}
+	}
+}
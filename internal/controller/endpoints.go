@@ -0,0 +1,344 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// endpointsFieldManager Endpoints 控制器写入时记录的写入者
+const endpointsFieldManager = "k3-endpoints-controller"
+
+var (
+	serviceGVK   = schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	endpointsGVK = schema.GroupVersionKind{Version: "v1", Kind: "Endpoints"}
+)
+
+// EndpointsController 为带有 selector 的 Service 维护同名的 Endpoints：
+//   - 选中的 Pod 中，Ready 的 Pod 写入 addresses，其余写入 notReadyAddresses，只有 addresses 应当接收流量；
+//     spec.publishNotReadyAddresses 为 true 时所有 Pod 都写入 addresses
+//   - 没有 Pod IP、正在删除或已结束（Succeeded/Failed）的 Pod 不发布
+//   - targetPort 为名称时按 Pod 的容器端口解析，解析结果不同的 Pod 分在不同的 subset
+//
+// 没有 selector 的 Service（例如 kube-system/k3-apiserver）的 Endpoints 由写入者自行维护，控制器不修改；
+// import 模式的镜像 Service 同样跳过。Service 删除后同时删除其 Endpoints
+type EndpointsController struct {
+	store   storage.Store
+	logger  logprovider.Logger
+	stopCh  chan struct{}
+	metrics *controllerMetrics
+}
+
+// NewEndpointsController 创建 Endpoints 控制器
+func NewEndpointsController(store storage.Store, logger logprovider.Logger) *EndpointsController {
+	return &EndpointsController{
+		store:  store,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Name 返回控制器名称
+func (ec *EndpointsController) Name() string {
+	return "EndpointsController"
+}
+
+func (ec *EndpointsController) setMetrics(m *controllerMetrics) {
+	ec.metrics = m
+}
+
+// Start 监听 Service 与 Pod 的变化并同步现有的 Service
+func (ec *EndpointsController) Start(ctx context.Context) error {
+	ec.logger.Info("启动 Endpoints 控制器...")
+
+	serviceCh, err := ec.store.Watch(serviceGVK, "", "")
+	if err != nil {
+		return fmt.Errorf("无法监听 Service 资源: %w", err)
+	}
+	ec.metrics.watch("services", serviceCh)
+	podCh, err := ec.store.Watch(podGVK, "", "")
+	if err != nil {
+		return fmt.Errorf("无法监听 Pod 资源: %w", err)
+	}
+	ec.metrics.watch("pods", podCh)
+	go ec.process(ctx, serviceCh, podCh)
+
+	services, err := ec.store.List(serviceGVK, "")
+	if err != nil {
+		ec.logger.Warnf("同步现有 Service 的 Endpoints 失败: %v", err)
+		return nil
+	}
+	for _, obj := range services {
+		if svc, ok := obj.(*corev1.Service); ok {
+			if err := ec.syncService(svc); err != nil {
+				ec.logger.Warnf("同步 Service %s/%s 的 Endpoints 失败: %v", svc.Namespace, svc.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Stop 停止 Endpoints 控制器
+func (ec *EndpointsController) Stop(ctx context.Context) error {
+	ec.logger.Info("停止 Endpoints 控制器...")
+	close(ec.stopCh)
+	return nil
+}
+
+func (ec *EndpointsController) process(ctx context.Context, serviceCh, podCh <-chan storage.ResourceEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ec.stopCh:
+			return
+		case event, ok := <-serviceCh:
+			if !ok {
+				ec.metrics.watchClosed("services")
+				serviceCh = nil
+				continue
+			}
+			svc, ok := event.Object.(*corev1.Service)
+			if !ok {
+				continue
+			}
+			start := time.Now()
+			var err error
+			if event.Type == storage.EventDeleted {
+				err = ec.deleteEndpoints(svc)
+			} else {
+				err = ec.syncService(svc)
+			}
+			if err != nil {
+				ec.logger.Warnf("同步 Service %s/%s 的 Endpoints 失败: %v", svc.Namespace, svc.Name, err)
+			}
+			ec.metrics.observe(start, err)
+		case event, ok := <-podCh:
+			if !ok {
+				ec.metrics.watchClosed("pods")
+				podCh = nil
+				continue
+			}
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			start := time.Now()
+			old, _ := event.OldObj.(*corev1.Pod)
+			err := ec.syncPodServices(pod, old)
+			if err != nil {
+				ec.logger.Warnf("同步 Pod %s/%s 所属 Service 的 Endpoints 失败: %v", pod.Namespace, pod.Name, err)
+			}
+			ec.metrics.observe(start, err)
+		}
+		if serviceCh == nil && podCh == nil {
+			return
+		}
+	}
+}
+
+// syncPodServices 同步选中 pod（变化前或变化后的标签）的所有 Service
+func (ec *EndpointsController) syncPodServices(pod, old *corev1.Pod) error {
+	services, err := ec.store.List(serviceGVK, pod.Namespace)
+	if err != nil {
+		return fmt.Errorf("列出 Service 失败: %w", err)
+	}
+	var firstErr error
+	for _, obj := range services {
+		svc, ok := obj.(*corev1.Service)
+		if !ok || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		if !selector.Matches(labels.Set(pod.Labels)) && (old == nil || !selector.Matches(labels.Set(old.Labels))) {
+			continue
+		}
+		if err := ec.syncService(svc); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// syncService 按当前选中的 Pod 计算 Service 的 Endpoints，有变化时写入
+func (ec *EndpointsController) syncService(svc *corev1.Service) error {
+	if len(svc.Spec.Selector) == 0 || mirror.IsImported(svc) {
+		return nil
+	}
+	pods, err := ec.store.ListBySelector(podGVK, svc.Namespace, labels.SelectorFromSet(svc.Spec.Selector))
+	if err != nil {
+		return fmt.Errorf("列出 Pod 失败: %w", err)
+	}
+	var selected []*corev1.Pod
+	for _, obj := range pods {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			selected = append(selected, pod)
+		}
+	}
+	subsets := endpointSubsets(svc, selected)
+
+	obj, err := ec.store.Get(endpointsGVK, svc.Namespace, svc.Name)
+	if err != nil {
+		if storage.IsBackendError(err) {
+			return err
+		}
+		ep := &corev1.Endpoints{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Endpoints"},
+			ObjectMeta: metav1.ObjectMeta{Name: svc.Name, Namespace: svc.Namespace, Labels: maps.Clone(svc.Labels)},
+			Subsets:    subsets,
+		}
+		storage.RecordManager(ep, endpointsFieldManager)
+		return ec.store.Create(endpointsGVK, ep)
+	}
+	current, ok := obj.(*corev1.Endpoints)
+	if !ok {
+		return fmt.Errorf("Endpoints %s/%s 的类型不是 Endpoints", svc.Namespace, svc.Name)
+	}
+	if mirror.IsImported(current) || equality.Semantic.DeepEqual(current.Subsets, subsets) {
+		return nil
+	}
+	ep := current.DeepCopy()
+	ep.Subsets = subsets
+	_, err = storage.UpdateAs(ec.store, endpointsGVK, ep, endpointsFieldManager, true)
+	return err
+}
+
+// deleteEndpoints Service 删除后删除控制器维护的同名 Endpoints
+func (ec *EndpointsController) deleteEndpoints(svc *corev1.Service) error {
+	if len(svc.Spec.Selector) == 0 || mirror.IsImported(svc) {
+		return nil
+	}
+	obj, err := ec.store.Get(endpointsGVK, svc.Namespace, svc.Name)
+	if err != nil {
+		if storage.IsBackendError(err) {
+			return err
+		}
+		return nil
+	}
+	if ep, ok := obj.(*corev1.Endpoints); !ok || mirror.IsImported(ep) {
+		return nil
+	}
+	return storage.DeleteAs(ec.store, endpointsGVK, svc.Namespace, svc.Name, endpointsFieldManager)
+}
+
+// endpointSubsets 计算 Service 选中 pods 的 subsets：按解析后的端口分组，组内的地址按 IP 排序，
+// Ready 的 Pod（或 publishNotReadyAddresses）写入 addresses，其余写入 notReadyAddresses
+func endpointSubsets(svc *corev1.Service, pods []*corev1.Pod) []corev1.EndpointSubset {
+	groups := make(map[string]*corev1.EndpointSubset)
+	var keys []string
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" || pod.DeletionTimestamp != nil ||
+			pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		ports, ok := endpointPorts(svc, pod)
+		if !ok {
+			continue
+		}
+		key := endpointPortsKey(ports)
+		subset, exists := groups[key]
+		if !exists {
+			subset = &corev1.EndpointSubset{Ports: ports}
+			groups[key] = subset
+			keys = append(keys, key)
+		}
+		addr := corev1.EndpointAddress{
+			IP: pod.Status.PodIP,
+			TargetRef: &corev1.ObjectReference{
+				Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name, UID: pod.UID,
+			},
+		}
+		if pod.Spec.NodeName != "" {
+			nodeName := pod.Spec.NodeName
+			addr.NodeName = &nodeName
+		}
+		if podReady(pod) || svc.Spec.PublishNotReadyAddresses {
+			subset.Addresses = append(subset.Addresses, addr)
+		} else {
+			subset.NotReadyAddresses = append(subset.NotReadyAddresses, addr)
+		}
+	}
+
+	sort.Strings(keys)
+	subsets := make([]corev1.EndpointSubset, 0, len(keys))
+	for _, key := range keys {
+		subset := groups[key]
+		sortEndpointAddresses(subset.Addresses)
+		sortEndpointAddresses(subset.NotReadyAddresses)
+		subsets = append(subsets, *subset)
+	}
+	if len(subsets) == 0 {
+		return nil
+	}
+	return subsets
+}
+
+// endpointPorts 把 Service 的端口解析为 pod 上的端口；名称形式的 targetPort 在 pod 中找不到时该端口不发布，
+// 所有端口都无法解析时返回 false
+func endpointPorts(svc *corev1.Service, pod *corev1.Pod) ([]corev1.EndpointPort, bool) {
+	var ports []corev1.EndpointPort
+	for _, sp := range svc.Spec.Ports {
+		port, ok := resolveTargetPort(sp, pod)
+		if !ok {
+			continue
+		}
+		ports = append(ports, corev1.EndpointPort{Name: sp.Name, Port: port, Protocol: defaultProtocol(sp.Protocol), AppProtocol: sp.AppProtocol})
+	}
+	return ports, len(ports) > 0 || len(svc.Spec.Ports) == 0
+}
+
+// resolveTargetPort 返回 Service 端口在 pod 上的目标端口：targetPort 为空时等于 port，为名称时查找同名的容器端口
+func resolveTargetPort(sp corev1.ServicePort, pod *corev1.Pod) (int32, bool) {
+	switch {
+	case sp.TargetPort.Type == intstr.String && sp.TargetPort.StrVal != "":
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.Name == sp.TargetPort.StrVal && defaultProtocol(p.Protocol) == defaultProtocol(sp.Protocol) {
+					return p.ContainerPort, true
+				}
+			}
+		}
+		return 0, false
+	case sp.TargetPort.Type == intstr.Int && sp.TargetPort.IntVal != 0:
+		return sp.TargetPort.IntVal, true
+	}
+	return sp.Port, true
+}
+
+// defaultProtocol 未设置的协议视为 TCP（与 apiserver 的默认值一致）
+func defaultProtocol(protocol corev1.Protocol) corev1.Protocol {
+	if protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return protocol
+}
+
+func endpointPortsKey(ports []corev1.EndpointPort) string {
+	parts := make([]string, 0, len(ports))
+	for _, p := range ports {
+		parts = append(parts, fmt.Sprintf("%s/%d/%s", p.Name, p.Port, p.Protocol))
+	}
+	return strings.Join(parts, ",")
+}
+
+func sortEndpointAddresses(addrs []corev1.EndpointAddress) {
+	sort.Slice(addrs, func(i, j int) bool {
+		if addrs[i].IP != addrs[j].IP {
+			return addrs[i].IP < addrs[j].IP
+		}
+		return addrs[i].TargetRef.Name < addrs[j].TargetRef.Name
+	})
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// endpointsPod 返回带有 app=web 标签的 Pod；ready 决定 PodReady 条件
+func endpointsPod(name, ip string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{NodeName: "node-1", Containers: []corev1.Container{{
+			Name:  "app",
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			PodIP:      ip,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func endpointIPs(addrs []corev1.EndpointAddress) []string {
+	var ips []string
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+	return ips
+}
+
+func TestEndpointSubsets(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "web"},
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromString("http")}},
+		},
+	}
+	terminating := endpointsPod("terminating", "10.0.0.5", true)
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	succeeded := endpointsPod("done", "10.0.0.6", true)
+	succeeded.Status.Phase = corev1.PodSucceeded
+	otherPort := endpointsPod("other-port", "10.0.0.7", true)
+	otherPort.Spec.Containers[0].Ports[0].ContainerPort = 9090
+	noNamedPort := endpointsPod("no-port", "10.0.0.8", true)
+	noNamedPort.Spec.Containers[0].Ports = nil
+	pods := []*corev1.Pod{
+		endpointsPod("web-b", "10.0.0.3", true),
+		endpointsPod("web-a", "10.0.0.2", true),
+		endpointsPod("starting", "10.0.0.4", false),
+		endpointsPod("pending", "", false),
+		terminating, succeeded, otherPort, noNamedPort,
+	}
+
+	subsets := endpointSubsets(svc, pods)
+	if len(subsets) != 2 {
+		t.Fatalf("expected 2 subsets (8080 and 9090), got %+v", subsets)
+	}
+	// 按端口分组，只有 Ready 的 Pod 进入 addresses
+	first := subsets[0]
+	if first.Ports[0].Port != 8080 || first.Ports[0].Protocol != corev1.ProtocolTCP {
+		t.Errorf("first subset ports = %+v", first.Ports)
+	}
+	if got := endpointIPs(first.Addresses); len(got) != 2 || got[0] != "10.0.0.2" || got[1] != "10.0.0.3" {
+		t.Errorf("ready addresses = %v, want [10.0.0.2 10.0.0.3]", got)
+	}
+	if got := endpointIPs(first.NotReadyAddresses); len(got) != 1 || got[0] != "10.0.0.4" {
+		t.Errorf("not ready addresses = %v, want [10.0.0.4]", got)
+	}
+	if addr := first.Addresses[0]; addr.TargetRef == nil || addr.TargetRef.Name != "web-a" || addr.NodeName == nil || *addr.NodeName != "node-1" {
+		t.Errorf("address = %+v", addr)
+	}
+	if second := subsets[1]; second.Ports[0].Port != 9090 || len(second.Addresses) != 1 || second.Addresses[0].IP != "10.0.0.7" {
+		t.Errorf("second subset = %+v", second)
+	}
+
+	// publishNotReadyAddresses 时未就绪的 Pod 同样发布在 addresses
+	svc.Spec.PublishNotReadyAddresses = true
+	subsets = endpointSubsets(svc, pods[:3])
+	if len(subsets) != 1 || len(subsets[0].Addresses) != 3 || len(subsets[0].NotReadyAddresses) != 0 {
+		t.Errorf("publishNotReadyAddresses: %+v", subsets)
+	}
+
+	// 没有可发布的 Pod 时没有 subset
+	if subsets := endpointSubsets(svc, pods[3:4]); subsets != nil {
+		t.Errorf("no endpoints: %+v", subsets)
+	}
+}
+
+func TestResolveTargetPort(t *testing.T) {
+	pod := endpointsPod("web", "10.0.0.2", true)
+	for _, tc := range []struct {
+		port corev1.ServicePort
+		want int32
+		ok   bool
+	}{
+		{port: corev1.ServicePort{Port: 80}, want: 80, ok: true},
+		{port: corev1.ServicePort{Port: 80, TargetPort: intstr.FromInt32(8081)}, want: 8081, ok: true},
+		{port: corev1.ServicePort{Port: 80, TargetPort: intstr.FromString("http")}, want: 8080, ok: true},
+		{port: corev1.ServicePort{Port: 80, TargetPort: intstr.FromString("metrics")}, ok: false},
+		{port: corev1.ServicePort{Port: 80, Protocol: corev1.ProtocolUDP, TargetPort: intstr.FromString("http")}, ok: false},
+	} {
+		got, ok := resolveTargetPort(tc.port, pod)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("resolveTargetPort(%+v) = %d, %v; want %d, %v", tc.port, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestEndpointsControllerFollowsReadiness(t *testing.T) {
+	store := storage.NewMemoryStore()
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "web"},
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)}},
+		},
+	}
+	// 没有 selector 的 Service 的 Endpoints 由写入者自行维护
+	manual := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "default"}}
+	manualEndpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "default"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "192.168.1.10"}}}},
+	}
+	if err := store.Create(serviceGVK, svc); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(serviceGVK, manual); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(endpointsGVK, manualEndpoints); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(podGVK, endpointsPod("web-0", "10.0.0.2", false)); err != nil {
+		t.Fatal(err)
+	}
+
+	ec := NewEndpointsController(store, testLogger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := ec.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer ec.Stop(ctx)
+
+	endpoints := func() *corev1.Endpoints {
+		obj, err := store.Get(endpointsGVK, "default", "web")
+		if err != nil {
+			return nil
+		}
+		return obj.(*corev1.Endpoints)
+	}
+	waitFor := func(what string, cond func(ep *corev1.Endpoints) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if cond(endpoints()) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("%s: endpoints = %+v", what, endpoints())
+	}
+
+	// 启动时同步：未就绪的 Pod 只在 notReadyAddresses 中
+	waitFor("not ready pod", func(ep *corev1.Endpoints) bool {
+		return ep != nil && len(ep.Subsets) == 1 && len(ep.Subsets[0].Addresses) == 0 && len(ep.Subsets[0].NotReadyAddresses) == 1
+	})
+
+	// Pod 变为 Ready 后进入 addresses
+	obj, _ := store.Get(podGVK, "default", "web-0")
+	pod := obj.(*corev1.Pod)
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	if err := store.Update(podGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("ready pod", func(ep *corev1.Endpoints) bool {
+		return ep != nil && len(ep.Subsets) == 1 && len(ep.Subsets[0].Addresses) == 1 && ep.Subsets[0].Addresses[0].IP == "10.0.0.2"
+	})
+
+	// 标签不再匹配后移出 Endpoints
+	obj, _ = store.Get(podGVK, "default", "web-0")
+	pod = obj.(*corev1.Pod)
+	pod.Labels = map[string]string{"app": "other"}
+	if err := store.Update(podGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("relabelled pod", func(ep *corev1.Endpoints) bool { return ep != nil && len(ep.Subsets) == 0 })
+
+	// Service 删除后 Endpoints 一并删除
+	if err := store.Delete(serviceGVK, "default", "web"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("deleted service", func(ep *corev1.Endpoints) bool { return ep == nil })
+
+	obj, err := store.Get(endpointsGVK, "default", "manual")
+	if err != nil || len(obj.(*corev1.Endpoints).Subsets) != 1 || storage.LastManager(obj) == endpointsFieldManager {
+		t.Errorf("endpoints of a service without selector were modified: %+v, %v", obj, err)
+	}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// EventGVK 是 core/v1 Event 的 GroupVersionKind
+var EventGVK = schema.GroupVersionKind{Version: "v1", Kind: "Event"}
+
+// RecordEvent 在 Store 中记录一条关联 obj 的 Event（GET /api/v1/namespaces/<ns>/events 可查询）。
+// eventType 为 corev1.EventTypeNormal / corev1.EventTypeWarning，component 为上报的组件名。
+func RecordEvent(store storage.Store, obj runtime.Object, eventType, reason, message, component string) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	namespace := accessor.GetNamespace()
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	host, _ := os.Hostname()
+	now := metav1.NewTime(time.Now())
+
+	event := &corev1.Event{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Event"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			// 与 kubelet 一致：<对象名>.<时间戳十六进制>
+			Name: fmt.Sprintf("%s.%x", accessor.GetName(), now.UnixNano()),
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  accessor.GetNamespace(),
+			Name:       accessor.GetName(),
+			UID:        accessor.GetUID(),
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: component, Host: host},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	return store.Create(EventGVK, event)
+}
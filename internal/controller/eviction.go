@@ -0,0 +1,383 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// evictionComponent 是驱逐事件的来源组件名
+const evictionComponent = "eviction-manager"
+
+// pressureSignal 一种资源压力：观测的资源、对应的 Node 条件与条件的 reason
+type pressureSignal struct {
+	resource  corev1.ResourceName
+	condition corev1.NodeConditionType
+	// pressureReason / normalReason 为条件为 True / False 时的 reason（与 kubelet 相同）
+	pressureReason, normalReason   string
+	pressureMessage, normalMessage string
+}
+
+var (
+	memorySignal = pressureSignal{
+		resource:        corev1.ResourceMemory,
+		condition:       corev1.NodeMemoryPressure,
+		pressureReason:  "KubeletHasInsufficientMemory",
+		normalReason:    "KubeletHasSufficientMemory",
+		pressureMessage: "kubelet has insufficient memory available",
+		normalMessage:   "kubelet has sufficient memory available",
+	}
+	diskSignal = pressureSignal{
+		resource:        corev1.ResourceEphemeralStorage,
+		condition:       corev1.NodeDiskPressure,
+		pressureReason:  "KubeletHasDiskPressure",
+		normalReason:    "KubeletHasNoDiskPressure",
+		pressureMessage: "kubelet has disk pressure",
+		normalMessage:   "kubelet has no disk pressure",
+	}
+)
+
+// EvictionManager 节点压力驱逐（简化的 kubelet eviction manager）：周期性检查本机可用内存与磁盘空间，
+//   - 低于阈值时在本节点的 Node 上设置 MemoryPressure/DiskPressure 为 True，调度器不再向该节点调度 Pod；
+//     回到阈值以上并持续 pressure_transition_period 后恢复为 False
+//   - 仍低于阈值时每个周期驱逐一个本节点的 Pod（删除并记录 Evicted 事件，由 Deployment 等控制器在其他节点重建），
+//     BestEffort 优先，其次 Burstable、Guaranteed；同一 QoS 中优先级低的优先，内存压力时再按内存使用超出 requests 的多少排序
+//
+// static Pod、import 镜像的 Pod 与 system-cluster-critical 及以上优先级的 Pod 不会被驱逐
+type EvictionManager struct {
+	store    storage.Store
+	logger   logprovider.Logger
+	nodeName string
+	settings config.EvictionSettings
+	// memoryInfo / diskInfo 返回内存与 settings.DiskPath 所在文件系统的总量与可用量（测试中替换）
+	memoryInfo func() (total, available uint64, err error)
+	diskInfo   func(path string) (capacity, available uint64, err error)
+	// podStats 返回本节点 Pod 的资源使用，为 nil 时内存压力下不按使用量排序
+	podStats func(ctx context.Context) ([]apiserver.PodStats, error)
+	now      func() time.Time
+
+	// lastObserved 每种压力最近一次低于阈值的时间
+	lastObserved map[corev1.NodeConditionType]time.Time
+	// unsupported 已记录过无法观测的信号（只告警一次）
+	unsupported map[corev1.NodeConditionType]bool
+	stopCh      chan struct{}
+	metrics     *controllerMetrics
+}
+
+// NewEvictionManager 创建节点压力驱逐；没有配置任何阈值时返回 nil（未开启）
+func NewEvictionManager(store storage.Store, logger logprovider.Logger, nodeName string, settings config.EvictionSettings,
+	podStats func(ctx context.Context) ([]apiserver.PodStats, error)) *EvictionManager {
+	if !settings.Enabled() {
+		return nil
+	}
+	return &EvictionManager{
+		store:        store,
+		logger:       logger,
+		nodeName:     nodeName,
+		settings:     settings,
+		memoryInfo:   availableMemory,
+		diskInfo:     diskAvailable,
+		podStats:     podStats,
+		now:          time.Now,
+		lastObserved: make(map[corev1.NodeConditionType]time.Time),
+		unsupported:  make(map[corev1.NodeConditionType]bool),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// diskAvailable 返回 path 所在文件系统的总容量与可用字节数
+func diskAvailable(path string) (capacity, available uint64, err error) {
+	capacity, used, err := diskUsage(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return capacity, capacity - used, nil
+}
+
+// Name 返回控制器名称
+func (em *EvictionManager) Name() string {
+	return "EvictionManager"
+}
+
+func (em *EvictionManager) setMetrics(m *controllerMetrics) {
+	em.metrics = m
+}
+
+// Start 启动周期检查
+func (em *EvictionManager) Start(ctx context.Context) error {
+	em.logger.Infof("启动节点压力驱逐（memory.available<%s，disk.available<%s（%s），周期 %s）",
+		thresholdString(em.settings.Memory), thresholdString(em.settings.Disk), em.settings.DiskPath, em.settings.Interval)
+	go func() {
+		ticker := time.NewTicker(em.settings.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-em.stopCh:
+				return
+			case <-ticker.C:
+				start := time.Now()
+				err := em.synchronize(ctx)
+				if err != nil {
+					em.logger.Warnf("节点压力检查失败: %v", err)
+				}
+				em.metrics.observe(start, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止周期检查
+func (em *EvictionManager) Stop(ctx context.Context) error {
+	close(em.stopCh)
+	return nil
+}
+
+// thresholdString 返回阈值的配置写法，未配置时为 -
+func thresholdString(t *config.EvictionThreshold) string {
+	if t == nil {
+		return "-"
+	}
+	return t.String()
+}
+
+// synchronize 执行一轮检查：观测各项资源、更新 Node 条件，仍低于阈值时驱逐一个 Pod
+func (em *EvictionManager) synchronize(ctx context.Context) error {
+	now := em.now()
+	pressure := make(map[corev1.NodeConditionType]bool)
+	var under []pressureSignal
+	for _, sig := range []pressureSignal{memorySignal, diskSignal} {
+		threshold := em.threshold(sig)
+		if threshold == nil {
+			continue
+		}
+		capacity, available, err := em.observe(sig)
+		if err != nil {
+			if !em.unsupported[sig.condition] {
+				em.logger.Warnf("无法检查 %s，跳过: %v", sig.condition, err)
+				em.unsupported[sig.condition] = true
+			}
+			continue
+		}
+		if limit := threshold.Value(capacity); available < limit {
+			em.logger.Warnf("节点 %s 可用 %s %d 字节，低于阈值 %s（%d 字节）", em.nodeName, sig.resource, available, threshold, limit)
+			em.lastObserved[sig.condition] = now
+			under = append(under, sig)
+		}
+		last, ok := em.lastObserved[sig.condition]
+		pressure[sig.condition] = ok && now.Sub(last) <= em.settings.PressureTransitionPeriod
+	}
+
+	if err := em.updateNodeConditions(pressure, now); err != nil {
+		return err
+	}
+	if len(under) == 0 {
+		return nil
+	}
+	return em.evictOne(ctx, under[0])
+}
+
+// threshold 返回信号对应的阈值，未配置时为 nil
+func (em *EvictionManager) threshold(sig pressureSignal) *config.EvictionThreshold {
+	if sig.condition == corev1.NodeMemoryPressure {
+		return em.settings.Memory
+	}
+	return em.settings.Disk
+}
+
+// observe 返回信号对应资源的总量与可用量
+func (em *EvictionManager) observe(sig pressureSignal) (capacity, available uint64, err error) {
+	if sig.condition == corev1.NodeMemoryPressure {
+		return em.memoryInfo()
+	}
+	return em.diskInfo(em.settings.DiskPath)
+}
+
+// updateNodeConditions 把压力状态写入本节点的 Node 条件，状态没有变化时不写入
+func (em *EvictionManager) updateNodeConditions(pressure map[corev1.NodeConditionType]bool, now time.Time) error {
+	obj, err := em.store.Get(nodeGVK, "", em.nodeName)
+	if err != nil {
+		return fmt.Errorf("获取节点 %s 失败: %w", em.nodeName, err)
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return fmt.Errorf("节点 %s 的类型无效: %T", em.nodeName, obj)
+	}
+	changed := false
+	for _, sig := range []pressureSignal{memorySignal, diskSignal} {
+		under, observed := pressure[sig.condition]
+		if !observed {
+			continue
+		}
+		cond := corev1.NodeCondition{
+			Type:              sig.condition,
+			Status:            corev1.ConditionFalse,
+			Reason:            sig.normalReason,
+			Message:           sig.normalMessage,
+			LastHeartbeatTime: metav1.NewTime(now),
+		}
+		if under {
+			cond.Status, cond.Reason, cond.Message = corev1.ConditionTrue, sig.pressureReason, sig.pressureMessage
+		}
+		if setNodeCondition(node, cond) {
+			changed = true
+			em.logger.Infof("节点 %s 的 %s 变为 %s", em.nodeName, sig.condition, cond.Status)
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if _, err := storage.UpdateAs(em.store, nodeGVK, node, nodeFieldManager, true); err != nil {
+		return fmt.Errorf("更新节点 %s 的压力条件失败: %w", em.nodeName, err)
+	}
+	return nil
+}
+
+// setNodeCondition 设置 node 的条件，状态变化时更新 lastTransitionTime；返回状态是否变化（包括新增条件）
+func setNodeCondition(node *corev1.Node, cond corev1.NodeCondition) bool {
+	for i := range node.Status.Conditions {
+		existing := &node.Status.Conditions[i]
+		if existing.Type != cond.Type {
+			continue
+		}
+		if existing.Status == cond.Status {
+			return false
+		}
+		cond.LastTransitionTime = cond.LastHeartbeatTime
+		*existing = cond
+		return true
+	}
+	cond.LastTransitionTime = cond.LastHeartbeatTime
+	node.Status.Conditions = append(node.Status.Conditions, cond)
+	return true
+}
+
+// nodeUnderPressure 节点的 MemoryPressure 或 DiskPressure 为 True（调度器不再向该节点调度 Pod）
+func nodeUnderPressure(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if (condition.Type == corev1.NodeMemoryPressure || condition.Type == corev1.NodeDiskPressure) &&
+			condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// evictOne 按 sig 驱逐本节点上排在最前的一个 Pod
+func (em *EvictionManager) evictOne(ctx context.Context, sig pressureSignal) error {
+	objs, err := em.store.List(podGVK, "")
+	if err != nil {
+		return fmt.Errorf("获取 Pod 列表失败: %w", err)
+	}
+	var candidates []*corev1.Pod
+	for _, obj := range objs {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || pod.Spec.NodeName != em.nodeName || pod.DeletionTimestamp != nil || isTerminalPod(pod) {
+			continue
+		}
+		if IsMirrorPod(pod) || mirror.IsImported(pod) || apiserver.PodPriority(pod) >= apiserver.SystemCriticalPriority {
+			continue
+		}
+		candidates = append(candidates, pod)
+	}
+	if len(candidates) == 0 {
+		em.logger.Warnf("节点 %s 处于 %s，但没有可以驱逐的 Pod", em.nodeName, sig.condition)
+		return nil
+	}
+
+	usage := make(map[string]int64)
+	if sig.condition == corev1.NodeMemoryPressure && em.podStats != nil {
+		stats, err := em.podStats(ctx)
+		if err != nil {
+			em.logger.Warnf("获取 Pod 资源使用失败，按 QoS 与优先级驱逐: %v", err)
+		}
+		for _, s := range stats {
+			usage[s.Namespace+"/"+s.Name] = s.MemoryBytes
+		}
+	}
+	rankForEviction(candidates, usage)
+
+	victim := candidates[0]
+	message := fmt.Sprintf("The node was low on resource: %s.", sig.resource)
+	if used, ok := usage[victim.Namespace+"/"+victim.Name]; ok {
+		requested := podRequests(victim)[corev1.ResourceMemory]
+		message += fmt.Sprintf(" Pod was using %d bytes, request is %s.", used, requested.String())
+	}
+	em.logger.Infof("节点 %s 处于 %s，驱逐 Pod %s/%s（%s）", em.nodeName, sig.condition, victim.Namespace, victim.Name, podQOSClass(victim))
+	if err := em.store.Delete(podGVK, victim.Namespace, victim.Name); err != nil {
+		return fmt.Errorf("驱逐 Pod %s/%s 失败: %w", victim.Namespace, victim.Name, err)
+	}
+	if err := RecordEvent(em.store, victim, corev1.EventTypeWarning, "Evicted", message, evictionComponent); err != nil {
+		em.logger.Warnf("记录驱逐事件失败: %v", err)
+	}
+	return nil
+}
+
+// rankForEviction 按驱逐顺序排序：QoS（BestEffort、Burstable、Guaranteed），其次优先级从低到高，
+// 再次内存使用超出 requests 的多少（usage 为空时相同），最后新创建的优先
+func rankForEviction(pods []*corev1.Pod, usage map[string]int64) {
+	qosRank := map[corev1.PodQOSClass]int{corev1.PodQOSBestEffort: 0, corev1.PodQOSBurstable: 1, corev1.PodQOSGuaranteed: 2}
+	overRequest := func(p *corev1.Pod) int64 {
+		used, ok := usage[p.Namespace+"/"+p.Name]
+		if !ok {
+			return 0
+		}
+		requested := podRequests(p)[corev1.ResourceMemory]
+		return used - requested.Value()
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		qi, qj := qosRank[podQOSClass(pods[i])], qosRank[podQOSClass(pods[j])]
+		if qi != qj {
+			return qi < qj
+		}
+		pi, pj := apiserver.PodPriority(pods[i]), apiserver.PodPriority(pods[j])
+		if pi != pj {
+			return pi < pj
+		}
+		oi, oj := overRequest(pods[i]), overRequest(pods[j])
+		if oi != oj {
+			return oi > oj
+		}
+		return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+	})
+}
+
+// podQOSClass 返回 Pod 的 QoS 类别（status.qosClass 为空时按容器的 requests/limits 计算，规则与 Kubernetes 相同）
+func podQOSClass(pod *corev1.Pod) corev1.PodQOSClass {
+	if pod.Status.QOSClass != "" {
+		return pod.Status.QOSClass
+	}
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	bestEffort, guaranteed := true, true
+	for _, c := range containers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			req, hasReq := c.Resources.Requests[name]
+			limit, hasLimit := c.Resources.Limits[name]
+			if (hasReq && !req.IsZero()) || (hasLimit && !limit.IsZero()) {
+				bestEffort = false
+			}
+			if !hasLimit || (hasReq && req.Cmp(limit) != 0) {
+				guaranteed = false
+			}
+		}
+	}
+	switch {
+	case bestEffort:
+		return corev1.PodQOSBestEffort
+	case guaranteed:
+		return corev1.PodQOSGuaranteed
+	default:
+		return corev1.PodQOSBurstable
+	}
+}
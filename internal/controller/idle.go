@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+// idlePausedControllers 空闲模式下暂停的可选控制器：它们只做周期性的清理与巡检，暂停不影响已有的工作负载。
+// 调度器不暂停（由 Pod 事件驱动，空闲时没有工作）
+var idlePausedControllers = map[string]bool{
+	"ContainerGC":           true,
+	"ImageGC":               true,
+	"DeschedulerController": true,
+	"InventoryController":   true,
+	"TTLController":         true,
+}
+
+// idlePaused 空闲模式下被暂停的可选控制器的生效配置，退出空闲模式时与原配置不同，控制器按原配置重建
+type idlePaused struct{}
+
+// SetIdle 进入或退出空闲模式（见 internal/idle）：空闲时暂停周期性清理与巡检的可选控制器，
+// 按 idle.heartbeat_multiplier 延长节点心跳周期；idle.stop_db 开启时还暂停节点心跳与静态 Pod 同步（存储后端容器已停止）。
+// 退出空闲模式时恢复这些控制器并立即上报一次节点状态
+func (cm *ControllerManager) SetIdle(idle bool) {
+	cm.mu.Lock()
+	if cm.idle == idle {
+		cm.mu.Unlock()
+		return
+	}
+	cm.idle = idle
+	if cm.started {
+		spec := cm.clusterConfig.Current()
+		for _, oc := range cm.optional {
+			cm.reconcileOptional(oc, spec)
+		}
+	}
+	cm.mu.Unlock()
+
+	if cm.runtimeController != nil {
+		cm.runtimeController.staticPodsPaused.Store(idle && cm.config.Idle.StopDB)
+	}
+	select {
+	case cm.heartbeatReset <- struct{}{}:
+	default:
+	}
+}
+
+// isIdle 是否处于空闲模式
+func (cm *ControllerManager) isIdle() bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.idle
+}
+
+// heartbeatInterval 返回当前的节点心跳周期
+func (cm *ControllerManager) heartbeatInterval() time.Duration {
+	if !cm.isIdle() {
+		return cm.intervals.NodeHeartbeat
+	}
+	multiplier := config.DefaultIdleHeartbeatMultiplier
+	if settings, err := cm.config.Idle.Settings(); err == nil {
+		multiplier = settings.HeartbeatMultiplier
+	}
+	return cm.intervals.NodeHeartbeat * time.Duration(multiplier)
+}
+
+// heartbeatPaused 空闲模式停止了存储后端容器时暂停节点心跳（写入会失败）
+func (cm *ControllerManager) heartbeatPaused() bool {
+	return cm.config.Idle.StopDB && cm.isIdle()
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+)
+
+// defaultImageGCInterval 未配置 image_gc.interval 时的检查周期
+const defaultImageGCInterval = 5 * time.Minute
+
+// ImageGC 节点镜像回收：镜像所在磁盘使用率超过高水位时，按创建时间从旧到新删除未被使用的镜像，
+// 直到使用率降到低水位以下（策略与 kubelet 的 imageGCHighThresholdPercent/imageGCLowThresholdPercent 一致）
+type ImageGC struct {
+	logger   logprovider.Logger
+	runtime  ContainerRuntime
+	policy   config.ImageGCConfig
+	interval time.Duration
+	stopCh   chan struct{}
+	metrics  *controllerMetrics
+}
+
+// NewImageGC 创建镜像回收器；policy.HighThresholdPercent <= 0 时返回 nil（未开启）
+func NewImageGC(logger logprovider.Logger, runtime ContainerRuntime, policy config.ImageGCConfig) (*ImageGC, error) {
+	if policy.HighThresholdPercent <= 0 {
+		return nil, nil
+	}
+	if policy.HighThresholdPercent > 100 || policy.LowThresholdPercent < 0 || policy.LowThresholdPercent > policy.HighThresholdPercent {
+		return nil, fmt.Errorf("image_gc 阈值无效: high=%d low=%d（要求 0 <= low <= high <= 100）", policy.HighThresholdPercent, policy.LowThresholdPercent)
+	}
+	interval := defaultImageGCInterval
+	if policy.Interval != "" {
+		d, err := time.ParseDuration(policy.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("image_gc.interval 无效: %q", policy.Interval)
+		}
+		interval = d
+	}
+	return &ImageGC{
+		logger:   logger,
+		runtime:  runtime,
+		policy:   policy,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}, nil
+}
+
+// Name 返回控制器名称
+func (gc *ImageGC) Name() string {
+	return "ImageGC"
+}
+
+func (gc *ImageGC) setMetrics(m *controllerMetrics) {
+	gc.metrics = m
+}
+
+// Start 启动周期回收
+func (gc *ImageGC) Start(ctx context.Context) error {
+	gc.logger.Infof("启动镜像回收（高水位 %d%%，低水位 %d%%，周期 %s）", gc.policy.HighThresholdPercent, gc.policy.LowThresholdPercent, gc.interval)
+	go func() {
+		ticker := time.NewTicker(gc.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-gc.stopCh:
+				return
+			case <-ticker.C:
+				start := time.Now()
+				err := gc.collect(ctx)
+				if err != nil {
+					gc.logger.Warnf("镜像回收失败: %v", err)
+				}
+				gc.metrics.observe(start, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止周期回收
+func (gc *ImageGC) Stop(ctx context.Context) error {
+	close(gc.stopCh)
+	return nil
+}
+
+// collect 执行一轮回收
+func (gc *ImageGC) collect(ctx context.Context) error {
+	root, err := gc.runtime.ImageFilesystem(ctx)
+	if err != nil {
+		return err
+	}
+	capacity, used, err := diskUsage(root)
+	if err != nil {
+		return err
+	}
+	if capacity == 0 || used*100 < capacity*uint64(gc.policy.HighThresholdPercent) {
+		return nil
+	}
+
+	target := used - capacity*uint64(gc.policy.LowThresholdPercent)/100
+	gc.logger.Infof("镜像磁盘使用率 %d%% 超过高水位 %d%%，需要释放 %d 字节", used*100/capacity, gc.policy.HighThresholdPercent, target)
+
+	images, err := gc.runtime.ListImages(ctx)
+	if err != nil {
+		return err
+	}
+	var freed uint64
+	for _, image := range imagesToCollect(images, target) {
+		if err := gc.runtime.RemoveImage(ctx, image.ID); err != nil {
+			gc.logger.Warnf("%v", err)
+			continue
+		}
+		gc.logger.Infof("已删除未使用镜像 %s %v（%d 字节）", image.ID, image.RepoTags, image.SizeBytes)
+		freed += uint64(image.SizeBytes)
+	}
+	if freed < target {
+		gc.logger.Warnf("镜像回收只释放了 %d/%d 字节，没有更多未使用的镜像", freed, target)
+	}
+	return nil
+}
+
+// imagesToCollect 按创建时间从旧到新挑选未被使用的镜像，直到累计大小达到 target
+func imagesToCollect(images []ImageInfo, target uint64) []ImageInfo {
+	var unused []ImageInfo
+	for _, image := range images {
+		if !image.InUse {
+			unused = append(unused, image)
+		}
+	}
+	sort.SliceStable(unused, func(i, j int) bool { return unused[i].Created.Before(unused[j].Created) })
+
+	var picked []ImageInfo
+	var total uint64
+	for _, image := range unused {
+		if total >= target {
+			break
+		}
+		picked = append(picked, image)
+		total += uint64(image.SizeBytes)
+	}
+	return picked
+}
+
+// DiskUsage 返回 path 所在文件系统的总容量和已用字节数（只支持 Linux 与 macOS），k3 check 使用
+func DiskUsage(path string) (capacity, used uint64, err error) {
+	return diskUsage(path)
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+func TestImagesToCollect(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2026, 1, n, 0, 0, 0, 0, time.UTC) }
+	images := []ImageInfo{
+		{ID: "newest", SizeBytes: 100, Created: day(5)},
+		{ID: "used", SizeBytes: 500, Created: day(1), InUse: true},
+		{ID: "oldest", SizeBytes: 100, Created: day(2)},
+		{ID: "middle", SizeBytes: 300, Created: day(3)},
+	}
+	for _, tc := range []struct {
+		name   string
+		target uint64
+		want   []string
+	}{
+		{"nothing to free", 0, nil},
+		{"oldest image covers the target", 50, []string{"oldest"}},
+		{"exactly the oldest image", 100, []string{"oldest"}},
+		{"oldest first until the target", 101, []string{"oldest", "middle"}},
+		{"in-use images are never collected", 10000, []string{"oldest", "middle", "newest"}},
+	} {
+		var got []string
+		for _, image := range imagesToCollect(images, tc.target) {
+			got = append(got, image.ID)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: imagesToCollect(%d) = %v, want %v", tc.name, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestNewImageGC(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		policy   config.ImageGCConfig
+		err      string
+		interval time.Duration
+	}{
+		{name: "default interval", policy: config.ImageGCConfig{HighThresholdPercent: 85, LowThresholdPercent: 80}, interval: defaultImageGCInterval},
+		{name: "custom interval", policy: config.ImageGCConfig{HighThresholdPercent: 85, LowThresholdPercent: 80, Interval: "30s"}, interval: 30 * time.Second},
+		{name: "equal thresholds", policy: config.ImageGCConfig{HighThresholdPercent: 90, LowThresholdPercent: 90}, interval: defaultImageGCInterval},
+		{name: "high above 100", policy: config.ImageGCConfig{HighThresholdPercent: 101}, err: "阈值无效"},
+		{name: "low above high", policy: config.ImageGCConfig{HighThresholdPercent: 80, LowThresholdPercent: 85}, err: "阈值无效"},
+		{name: "negative low", policy: config.ImageGCConfig{HighThresholdPercent: 80, LowThresholdPercent: -1}, err: "阈值无效"},
+		{name: "invalid interval", policy: config.ImageGCConfig{HighThresholdPercent: 80, Interval: "often"}, err: "image_gc.interval"},
+		{name: "zero interval", policy: config.ImageGCConfig{HighThresholdPercent: 80, Interval: "0s"}, err: "image_gc.interval"},
+	} {
+		gc, err := NewImageGC(testLogger, &fakeRuntime{}, tc.policy)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: err = %v, want %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || gc == nil || gc.interval != tc.interval {
+			t.Errorf("%s: NewImageGC = %+v, %v; want interval %s", tc.name, gc, err, tc.interval)
+		}
+	}
+
+	// high_threshold_percent 为 0 时不开启
+	if gc, err := NewImageGC(testLogger, &fakeRuntime{}, config.ImageGCConfig{LowThresholdPercent: 80}); gc != nil || err != nil {
+		t.Errorf("disabled: %v, %v", gc, err)
+	}
+}
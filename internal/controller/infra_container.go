@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// infraDataVolume 是基础设施容器数据目录卷的名称
+const infraDataVolume = "data"
+
+// ApplyContainerConfig 把用户配置（镜像、拉取策略、追加参数/环境变量、数据目录）应用到自动拉起的
+// 基础设施 Pod（etcd/MySQL/Consul）的第一个容器上；未设置的字段保留 Pod 中的内置默认值。
+//
+// dataMountPath 为容器内的数据目录：配置了 data_dir 时以 hostPath（DirectoryOrCreate）挂载，容器重建后数据不丢失。
+func ApplyContainerConfig(pod *corev1.Pod, c config.ContainerConfig, dataMountPath string) {
+	if pod == nil || len(pod.Spec.Containers) == 0 {
+		return
+	}
+	container := &pod.Spec.Containers[0]
+
+	if image := strings.TrimSpace(c.Image); image != "" {
+		container.Image = image
+	}
+	switch corev1.PullPolicy(strings.TrimSpace(c.ImagePullPolicy)) {
+	case corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+		container.ImagePullPolicy = corev1.PullPolicy(strings.TrimSpace(c.ImagePullPolicy))
+	}
+	container.Args = append(container.Args, c.ExtraArgs...)
+
+	for _, kv := range c.ExtraEnv {
+		name, value, _ := strings.Cut(kv, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		replaced := false
+		for i := range container.Env {
+			if container.Env[i].Name == name {
+				container.Env[i].Value = value
+				replaced = true
+			}
+		}
+		if !replaced {
+			container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+
+	if dir := strings.TrimSpace(c.DataDir); dir != "" && dataMountPath != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		hostPathType := corev1.HostPathDirectoryOrCreate
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: infraDataVolume,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: dir, Type: &hostPathType},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      infraDataVolume,
+			MountPath: dataMountPath,
+		})
+	}
+}
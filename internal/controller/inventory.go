@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/network"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// defaultInventoryInterval 未配置 inventory.interval 时读取邻居表的周期
+	defaultInventoryInterval = time.Minute
+	// defaultDeviceOfflineAfter 未配置 inventory.offline_after 时判定设备离线的时长
+	defaultDeviceOfflineAfter = 10 * time.Minute
+	// deviceLastSeenRefresh 设备信息没有变化时刷新 lastSeen 的最小间隔，避免每轮都写 Store
+	deviceLastSeenRefresh = 5 * time.Minute
+	// inventoryFieldManager 是 inventory 控制器写入 Device 时使用的写入者名称
+	inventoryFieldManager = "k3-inventory"
+)
+
+// InventoryController 局域网设备清单：周期性读取本机 ARP/neighbor 表（与 `network export` 相同的数据源），
+// 把本机网段内的邻居维护为 k3.io/v1 Device 资源；多个节点同时运行时各自补充自己看到的设备，
+// 超过 offlineAfter 没有被任何节点看到的设备标记为离线（不删除，保留清单）
+type InventoryController struct {
+	store        storage.Store
+	logger       logprovider.Logger
+	nodeName     string
+	cidrs        []string
+	resolveDNS   bool
+	interval     time.Duration
+	offlineAfter time.Duration
+	stopCh       chan struct{}
+	metrics      *controllerMetrics
+}
+
+// NewInventoryController 创建设备清单控制器；cfg.Enabled 为 false 时返回 nil（未开启）
+func NewInventoryController(store storage.Store, logger logprovider.Logger, nodeName string, cfg config.InventoryConfig) (*InventoryController, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	interval, err := parseOptionalDuration("inventory.interval", cfg.Interval, defaultInventoryInterval)
+	if err != nil {
+		return nil, err
+	}
+	offlineAfter, err := parseOptionalDuration("inventory.offline_after", cfg.OfflineAfter, defaultDeviceOfflineAfter)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.CIDRs) > 0 {
+		if _, err := network.TargetSubnets(cfg.CIDRs); err != nil {
+			return nil, fmt.Errorf("inventory.cidrs 无效: %w", err)
+		}
+	}
+	return &InventoryController{
+		store:        store,
+		logger:       logger,
+		nodeName:     nodeName,
+		cidrs:        cfg.CIDRs,
+		resolveDNS:   cfg.ResolveDNS,
+		interval:     interval,
+		offlineAfter: offlineAfter,
+		stopCh:       make(chan struct{}),
+	}, nil
+}
+
+// parseOptionalDuration 解析可选的时长配置，为空时返回默认值
+func parseOptionalDuration(key, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s 无效: %q", key, value)
+	}
+	return d, nil
+}
+
+// Name 返回控制器名称
+func (ic *InventoryController) Name() string {
+	return "InventoryController"
+}
+
+func (ic *InventoryController) setMetrics(m *controllerMetrics) {
+	ic.metrics = m
+}
+
+// Start 立即同步一次，之后周期同步
+func (ic *InventoryController) Start(ctx context.Context) error {
+	ic.logger.Infof("启动局域网设备清单（节点: %s，周期 %s，离线判定 %s）", ic.nodeName, ic.interval, ic.offlineAfter)
+	go func() {
+		start := time.Now()
+		err := ic.sync(ctx)
+		if err != nil {
+			ic.logger.Warnf("同步局域网设备失败: %v", err)
+		}
+		ic.metrics.observe(start, err)
+		ticker := time.NewTicker(ic.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ic.stopCh:
+				return
+			case <-ticker.C:
+				start := time.Now()
+				err := ic.sync(ctx)
+				if err != nil {
+					ic.logger.Warnf("同步局域网设备失败: %v", err)
+				}
+				ic.metrics.observe(start, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止周期同步
+func (ic *InventoryController) Stop(ctx context.Context) error {
+	close(ic.stopCh)
+	return nil
+}
+
+// sync 读取一次邻居表并更新 Device
+func (ic *InventoryController) sync(ctx context.Context) error {
+	subnets, err := network.TargetSubnets(ic.cidrs)
+	if err != nil {
+		return fmt.Errorf("计算网段失败: %w", err)
+	}
+	readCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	neighbors, err := network.ReadNeighborTable(readCtx)
+	if err != nil {
+		return fmt.Errorf("读取邻居表失败: %w", err)
+	}
+
+	observed := observedDevices(neighbors, subnets)
+	if ic.resolveDNS {
+		for name, status := range observed {
+			if status.Hostname == "" {
+				status.Hostname = network.ReverseLookup(readCtx, status.IP, 250*time.Millisecond)
+				observed[name] = status
+			}
+		}
+	}
+
+	return ic.reconcile(observed, time.Now())
+}
+
+// reconcile 把本轮观察到的设备写入 Store，并把长时间未出现的设备标记为离线
+func (ic *InventoryController) reconcile(observed map[string]k3v1.DeviceStatus, now time.Time) error {
+	objs, err := ic.store.List(k3v1.DeviceGVK, "")
+	if err != nil {
+		return fmt.Errorf("列出 Device 失败: %w", err)
+	}
+	existing := make(map[string]*k3v1.Device, len(objs))
+	for _, obj := range objs {
+		if dev, ok := obj.(*k3v1.Device); ok {
+			existing[dev.Name] = dev
+		}
+	}
+
+	for name, status := range observed {
+		status.Online = true
+		status.LastSeen = metav1.NewTime(now)
+		status.ObservedBy = ic.nodeName
+
+		dev, ok := existing[name]
+		if !ok {
+			dev = &k3v1.Device{
+				TypeMeta:   metav1.TypeMeta{APIVersion: k3v1.SchemeGroupVersion.String(), Kind: "Device"},
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status:     status,
+			}
+			storage.RecordManager(dev, inventoryFieldManager)
+			if err := ic.store.Create(k3v1.DeviceGVK, dev); err != nil {
+				// 其他节点可能同时创建了同一个设备，下一轮按更新处理
+				ic.logger.Debugf("创建 Device %s 失败: %v", name, err)
+				continue
+			}
+			ic.logger.Infof("发现局域网设备: %s (%s %s)", name, status.IP, status.Hostname)
+			continue
+		}
+
+		if status.Hostname == "" {
+			status.Hostname = dev.Status.Hostname
+		}
+		if !deviceStatusChanged(dev.Status, status) && now.Sub(dev.Status.LastSeen.Time) < deviceLastSeenRefresh {
+			continue
+		}
+		updated := dev.DeepCopy()
+		updated.Status = status
+		ic.updateDevice(updated)
+	}
+
+	// 超过 offlineAfter 没有出现的设备标记为离线（任何节点看到过都会刷新 lastSeen）
+	for name, dev := range existing {
+		if _, seen := observed[name]; seen || !dev.Status.Online {
+			continue
+		}
+		if now.Sub(dev.Status.LastSeen.Time) < ic.offlineAfter {
+			continue
+		}
+		updated := dev.DeepCopy()
+		updated.Status.Online = false
+		ic.logger.Infof("局域网设备离线: %s (%s)，最后出现于 %s", name, dev.Status.IP, dev.Status.LastSeen.Format(time.RFC3339))
+		ic.updateDevice(updated)
+	}
+	return nil
+}
+
+// updateDevice 写回 Device（只修改 status，保留用户维护的 spec/labels）
+func (ic *InventoryController) updateDevice(dev *k3v1.Device) {
+	dev.TypeMeta = metav1.TypeMeta{APIVersion: k3v1.SchemeGroupVersion.String(), Kind: "Device"}
+	if _, err := storage.UpdateAs(ic.store, k3v1.DeviceGVK, dev, inventoryFieldManager, true); err != nil {
+		ic.logger.Warnf("更新 Device %s 失败: %v", dev.Name, err)
+	}
+}
+
+// deviceStatusChanged 比较除 lastSeen/observedBy 以外的字段
+func deviceStatusChanged(old, cur k3v1.DeviceStatus) bool {
+	return old.IP != cur.IP || old.MAC != cur.MAC || old.Hostname != cur.Hostname || old.Online != cur.Online
+}
+
+// observedDevices 把邻居表中落在 subnets 内的 IPv4 邻居按 Device 名称去重
+func observedDevices(neighbors []network.Neighbor, subnets []*net.IPNet) map[string]k3v1.DeviceStatus {
+	out := make(map[string]k3v1.DeviceStatus)
+	for _, n := range neighbors {
+		ip4 := net.ParseIP(strings.TrimSpace(n.IP)).To4()
+		if ip4 == nil || !network.InSubnets(ip4, subnets) {
+			continue
+		}
+		mac := strings.ToLower(strings.TrimSpace(n.MAC))
+		status := k3v1.DeviceStatus{IP: ip4.String(), MAC: mac, Hostname: network.NormalizeHostToken(n.Name)}
+		name := deviceName(mac, status.IP)
+		if cur, ok := out[name]; ok && status.Hostname == "" {
+			status.Hostname = cur.Hostname
+		}
+		out[name] = status
+	}
+	return out
+}
+
+// deviceName 由 MAC 生成 Device 名称（aa-bb-cc-dd-ee-ff），没有 MAC 时使用 ip-192-168-1-10
+func deviceName(mac, ip string) string {
+	if mac != "" {
+		return strings.ReplaceAll(mac, ":", "-")
+	}
+	return "ip-" + strings.ReplaceAll(ip, ".", "-")
+}
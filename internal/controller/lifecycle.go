@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// 容器生命周期钩子（lifecycle.postStart / lifecycle.preStop）
+
+const (
+	// defaultTerminationGracePeriod Pod 没有设置 terminationGracePeriodSeconds 时的宽限期（与 Kubernetes 默认值相同）
+	defaultTerminationGracePeriod = 30 * time.Second
+	// minStopGracePeriod preStop 用完宽限期后，停止容器时仍给进程的最短时间（与 kubelet 额外的 2 秒宽限期一致）
+	minStopGracePeriod = 2 * time.Second
+	// hookHTTPTimeout httpGet 钩子单次请求的超时
+	hookHTTPTimeout = 30 * time.Second
+)
+
+// 钩子名称，用于 Event 的 reason 与容器状态的 reason
+const (
+	HookPostStart = "PostStart"
+	HookPreStop   = "PreStop"
+)
+
+// LifecycleHookError 容器的生命周期钩子执行失败
+type LifecycleHookError struct {
+	// Hook 为 PostStart 或 PreStop
+	Hook      string
+	Container string
+	Err       error
+}
+
+func (e *LifecycleHookError) Error() string {
+	return fmt.Sprintf("容器 %s 的 %s 钩子执行失败: %v", e.Container, e.Hook, e.Err)
+}
+
+func (e *LifecycleHookError) Unwrap() error {
+	return e.Err
+}
+
+// EventReason 返回记录钩子失败时 Event 的 reason（与 kubelet 相同：FailedPostStartHook / FailedPreStopHook）
+func (e *LifecycleHookError) EventReason() string {
+	return "Failed" + e.Hook + "Hook"
+}
+
+// StateReason 返回容器状态中的 reason（与 kubelet 相同：PostStartHookError / PreStopHookError）
+func (e *LifecycleHookError) StateReason() string {
+	return e.Hook + "HookError"
+}
+
+// lifecycleHookErrors 取出 err（可以是 errors.Join 的结果）中所有的钩子错误
+func lifecycleHookErrors(err error) []*LifecycleHookError {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var result []*LifecycleHookError
+		for _, e := range joined.Unwrap() {
+			result = append(result, lifecycleHookErrors(e)...)
+		}
+		return result
+	}
+	var hookErr *LifecycleHookError
+	if errors.As(err, &hookErr) {
+		return []*LifecycleHookError{hookErr}
+	}
+	return nil
+}
+
+// ContainerExecer 由可以在 Pod 的容器中执行命令的运行时实现（目前为 Docker），exec 类型的钩子需要它
+type ContainerExecer interface {
+	// ExecInContainer 在 Pod 的指定容器中执行命令，返回合并的输出；退出码非 0 时返回错误
+	ExecInContainer(ctx context.Context, pod *corev1.Pod, container string, command []string) ([]byte, error)
+}
+
+// terminationGracePeriod 返回 Pod 的删除宽限期：preStop 与停止容器共用这段时间
+func terminationGracePeriod(pod *corev1.Pod) time.Duration {
+	if s := pod.Spec.TerminationGracePeriodSeconds; s != nil && *s >= 0 {
+		return time.Duration(*s) * time.Second
+	}
+	return defaultTerminationGracePeriod
+}
+
+// terminatePod 优雅删除 Pod：写入 deletionTimestamp 与 deletionGracePeriodSeconds，由所在节点的 RuntimeController
+// 执行 preStop、在宽限期内停止容器后再从 Store 删除。还没有调度到节点的 Pod 没有容器，直接删除；已在退出的 Pod 不重复标记
+func terminatePod(store storage.Store, pod *corev1.Pod) error {
+	if pod.Spec.NodeName == "" {
+		return store.Delete(podGVK, pod.Namespace, pod.Name)
+	}
+	obj, err := store.Get(podGVK, pod.Namespace, pod.Name)
+	if err != nil {
+		return err
+	}
+	current, ok := obj.(*corev1.Pod)
+	if !ok || current.UID != pod.UID {
+		return fmt.Errorf("Pod %s/%s 已被替换", pod.Namespace, pod.Name)
+	}
+	if current.DeletionTimestamp != nil {
+		return nil
+	}
+	current = current.DeepCopy()
+	now := metav1.Now()
+	grace := int64(terminationGracePeriod(current) / time.Second)
+	current.DeletionTimestamp = &now
+	current.DeletionGracePeriodSeconds = &grace
+	return store.Update(podGVK, current)
+}
+
+// runLifecycleHandler 执行一个生命周期钩子：exec 在容器中执行命令（退出码非 0 视为失败），
+// httpGet 请求容器端口（host 为空时使用 podIP，host 网络没有 Pod IP 时使用 127.0.0.1；返回 2xx/3xx 视为成功），
+// sleep 等待指定秒数。ctx 取消时钩子中止
+func runLifecycleHandler(ctx context.Context, execer ContainerExecer, pod *corev1.Pod, container *corev1.Container, handler *corev1.LifecycleHandler, podIP string) error {
+	switch {
+	case handler.Exec != nil:
+		if execer == nil {
+			return fmt.Errorf("容器运行时不支持 exec 钩子")
+		}
+		if len(handler.Exec.Command) == 0 {
+			return fmt.Errorf("exec 钩子没有命令")
+		}
+		output, err := execer.ExecInContainer(ctx, pod, container.Name, handler.Exec.Command)
+		if err != nil {
+			return fmt.Errorf("%w, 输出: %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	case handler.HTTPGet != nil:
+		return runHTTPGetHook(ctx, container, handler.HTTPGet, podIP)
+	case handler.Sleep != nil:
+		select {
+		case <-time.After(time.Duration(handler.Sleep.Seconds) * time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	default:
+		return fmt.Errorf("不支持的钩子类型（支持 exec、httpGet、sleep）")
+	}
+}
+
+// runHTTPGetHook 执行 httpGet 钩子
+func runHTTPGetHook(ctx context.Context, container *corev1.Container, action *corev1.HTTPGetAction, podIP string) error {
+	port, err := resolveContainerPort(action.Port, container)
+	if err != nil {
+		return err
+	}
+	host := action.Host
+	if host == "" {
+		host = podIP
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	scheme := strings.ToLower(string(action.Scheme))
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := action.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)), path)
+
+	ctx, cancel := context.WithTimeout(ctx, hookHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for _, h := range action.HTTPHeaders {
+		if strings.EqualFold(h.Name, "Host") {
+			req.Host = h.Value
+			continue
+		}
+		req.Header.Add(h.Name, h.Value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 %s 失败: %w", url, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("请求 %s 返回 HTTP %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// resolveContainerPort 把数字或容器端口名解析为端口号
+func resolveContainerPort(port intstr.IntOrString, container *corev1.Container) (int, error) {
+	if port.Type == intstr.Int {
+		if port.IntVal <= 0 || port.IntVal > 65535 {
+			return 0, fmt.Errorf("端口无效: %d", port.IntVal)
+		}
+		return int(port.IntVal), nil
+	}
+	for _, p := range container.Ports {
+		if p.Name == port.StrVal {
+			return int(p.ContainerPort), nil
+		}
+	}
+	if n, err := strconv.Atoi(port.StrVal); err == nil && n > 0 && n <= 65535 {
+		return n, nil
+	}
+	return 0, fmt.Errorf("容器 %s 没有名为 %q 的端口", container.Name, port.StrVal)
+}
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// fakeExecer 记录 exec 钩子的调用，按 err 返回结果
+type fakeExecer struct {
+	container string
+	command   []string
+	output    string
+	err       error
+}
+
+func (f *fakeExecer) ExecInContainer(ctx context.Context, pod *corev1.Pod, container string, command []string) ([]byte, error) {
+	f.container, f.command = container, command
+	return []byte(f.output), f.err
+}
+
+func TestTerminationGracePeriod(t *testing.T) {
+	seconds := func(n int64) *int64 { return &n }
+	for _, tc := range []struct {
+		name  string
+		grace *int64
+		want  time.Duration
+	}{
+		{"unset uses the default", nil, defaultTerminationGracePeriod},
+		{"explicit", seconds(5), 5 * time.Second},
+		{"zero stops immediately", seconds(0), 0},
+		{"negative uses the default", seconds(-1), defaultTerminationGracePeriod},
+	} {
+		pod := &corev1.Pod{Spec: corev1.PodSpec{TerminationGracePeriodSeconds: tc.grace}}
+		if got := terminationGracePeriod(pod); got != tc.want {
+			t.Errorf("%s: terminationGracePeriod = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestResolveContainerPort(t *testing.T) {
+	container := &corev1.Container{Name: "app", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9090}}}
+	for _, tc := range []struct {
+		port intstr.IntOrString
+		want int
+		err  bool
+	}{
+		{port: intstr.FromInt32(80), want: 80},
+		{port: intstr.FromString("http"), want: 8080},
+		{port: intstr.FromString("metrics"), want: 9090},
+		{port: intstr.FromString("8081"), want: 8081},
+		{port: intstr.FromString("grpc"), err: true},
+		{port: intstr.FromInt32(0), err: true},
+		{port: intstr.FromInt32(70000), err: true},
+		{port: intstr.FromString("70000"), err: true},
+	} {
+		got, err := resolveContainerPort(tc.port, container)
+		if tc.err {
+			if err == nil {
+				t.Errorf("port %s: got %d, want error", tc.port.String(), got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("port %s = %d, %v; want %d", tc.port.String(), got, err, tc.want)
+		}
+	}
+}
+
+func TestLifecycleHookErrors(t *testing.T) {
+	postStart := &LifecycleHookError{Hook: HookPostStart, Container: "app", Err: errors.New("exit 1")}
+	preStop := &LifecycleHookError{Hook: HookPreStop, Container: "sidecar", Err: errors.New("timeout")}
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want []*LifecycleHookError
+	}{
+		{"nil", nil, nil},
+		{"unrelated error", errors.New("pull failed"), nil},
+		{"single", postStart, []*LifecycleHookError{postStart}},
+		{"wrapped", fmt.Errorf("start pod: %w", postStart), []*LifecycleHookError{postStart}},
+		{"joined", errors.Join(postStart, errors.New("pull failed"), fmt.Errorf("stop: %w", preStop)), []*LifecycleHookError{postStart, preStop}},
+		{"nested join", errors.Join(errors.Join(preStop), postStart), []*LifecycleHookError{preStop, postStart}},
+	} {
+		got := lifecycleHookErrors(tc.err)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: %v, want %v", tc.name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: [%d] = %v, want %v", tc.name, i, got[i], tc.want[i])
+			}
+		}
+	}
+
+	if postStart.EventReason() != "FailedPostStartHook" || postStart.StateReason() != "PostStartHookError" {
+		t.Errorf("reasons = %s, %s", postStart.EventReason(), postStart.StateReason())
+	}
+	if !errors.Is(fmt.Errorf("x: %w", preStop), preStop.Err) {
+		t.Error("LifecycleHookError does not unwrap to its cause")
+	}
+}
+
+func TestRunLifecycleHandlerExec(t *testing.T) {
+	pod := &corev1.Pod{}
+	container := &corev1.Container{Name: "app"}
+	handler := &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: []string{"sh", "-c", "echo hi"}}}
+
+	execer := &fakeExecer{}
+	if err := runLifecycleHandler(context.Background(), execer, pod, container, handler, ""); err != nil {
+		t.Fatal(err)
+	}
+	if execer.container != "app" || strings.Join(execer.command, " ") != "sh -c echo hi" {
+		t.Errorf("exec called with %s %v", execer.container, execer.command)
+	}
+
+	execer = &fakeExecer{output: "boom\n", err: errors.New("exit status 2")}
+	err := runLifecycleHandler(context.Background(), execer, pod, container, handler, "")
+	if err == nil || !strings.Contains(err.Error(), "exit status 2") || !strings.Contains(err.Error(), "输出: boom") {
+		t.Errorf("failed exec: %v", err)
+	}
+
+	if err := runLifecycleHandler(context.Background(), nil, pod, container, handler, ""); err == nil {
+		t.Error("exec hook without an execer succeeded")
+	}
+	empty := &corev1.LifecycleHandler{Exec: &corev1.ExecAction{}}
+	if err := runLifecycleHandler(context.Background(), &fakeExecer{}, pod, container, empty, ""); err == nil {
+		t.Error("exec hook without a command succeeded")
+	}
+	if err := runLifecycleHandler(context.Background(), &fakeExecer{}, pod, container, &corev1.LifecycleHandler{}, ""); err == nil {
+		t.Error("empty handler succeeded")
+	}
+}
+
+func TestRunLifecycleHandlerSleep(t *testing.T) {
+	handler := &corev1.LifecycleHandler{Sleep: &corev1.SleepAction{Seconds: 60}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := runLifecycleHandler(ctx, nil, &corev1.Pod{}, &corev1.Container{}, handler, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("sleep hook with a cancelled context: %v", err)
+	}
+}
+
+func TestRunHTTPGetHook(t *testing.T) {
+	type request struct {
+		path, host, token string
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- request{r.URL.Path, r.Host, r.Header.Get("X-Token")}
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/redirect":
+			w.WriteHeader(http.StatusNotModified)
+		}
+	}))
+	defer server.Close()
+	host, portStr, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	container := &corev1.Container{Name: "app", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: int32(port)}}}
+
+	for _, tc := range []struct {
+		name   string
+		action corev1.HTTPGetAction
+		podIP  string
+		want   request
+		err    string
+	}{
+		{
+			name:   "named port on the pod IP",
+			action: corev1.HTTPGetAction{Path: "/shutdown", Port: intstr.FromString("http")},
+			podIP:  host,
+			want:   request{path: "/shutdown", host: server.Listener.Addr().String()},
+		},
+		{
+			name:   "host network without pod IP uses localhost, path gets a leading slash",
+			action: corev1.HTTPGetAction{Path: "drain", Port: intstr.FromInt32(int32(port))},
+			want:   request{path: "/drain", host: server.Listener.Addr().String()},
+		},
+		{
+			name: "explicit host and headers",
+			action: corev1.HTTPGetAction{Host: host, Path: "/", Port: intstr.FromInt32(int32(port)), HTTPHeaders: []corev1.HTTPHeader{
+				{Name: "Host", Value: "web.local"},
+				{Name: "X-Token", Value: "secret"},
+			}},
+			podIP: "192.0.2.1",
+			want:  request{path: "/", host: "web.local", token: "secret"},
+		},
+		{
+			name:   "3xx counts as success",
+			action: corev1.HTTPGetAction{Path: "/redirect", Port: intstr.FromInt32(int32(port))},
+			podIP:  host,
+			want:   request{path: "/redirect", host: server.Listener.Addr().String()},
+		},
+		{
+			name:   "5xx fails",
+			action: corev1.HTTPGetAction{Path: "/fail", Port: intstr.FromInt32(int32(port))},
+			podIP:  host,
+			want:   request{path: "/fail", host: server.Listener.Addr().String()},
+			err:    "HTTP 500",
+		},
+		{
+			name:   "unknown named port",
+			action: corev1.HTTPGetAction{Path: "/", Port: intstr.FromString("admin")},
+			err:    `没有名为 "admin" 的端口`,
+		},
+	} {
+		err := runLifecycleHandler(context.Background(), nil, &corev1.Pod{}, container, &corev1.LifecycleHandler{HTTPGet: &tc.action}, tc.podIP)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: err = %v, want %q", tc.name, err, tc.err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if tc.want == (request{}) {
+			continue
+		}
+		select {
+		case got := <-requests:
+			if got != tc.want {
+				t.Errorf("%s: request %+v, want %+v", tc.name, got, tc.want)
+			}
+		default:
+			t.Errorf("%s: no request received", tc.name)
+		}
+	}
+}
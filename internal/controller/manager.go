@@ -2,18 +2,33 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"runtime"
+	"sync"
 	"time"
 
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterconfig"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/network"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/registry"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// nodeFieldManager 是 controller manager 上报 Node 时使用的写入者名称
+const nodeFieldManager = "k3-controller-manager"
+
+// maxPodsPerNode 节点上报的 pods 容量（与 kubelet 默认的 maxPods 相同）
+const maxPodsPerNode = 110
+
 // ControllerManager 管理所有控制器
 type ControllerManager struct {
 	store       storage.Store
@@ -21,6 +36,38 @@ type ControllerManager struct {
 	config      config.Config
 	nodeName    string
 	controllers []Controller
+	// intervals 节点心跳、调度重试与容器回收的周期（controller.*）
+	intervals config.ControllerIntervals
+	// resources 本节点预留的资源与 Pod 的默认额外开销（resources.*）
+	resources config.ResourceSettings
+	// podCIDRs 本节点 Pod 使用的网段（network.pod_cidrs），写入 Node 的 spec.podCIDRs
+	podCIDRs []string
+	// runtime 为本节点检测到的容器运行时（不可用时为 nil）
+	runtime ContainerRuntime
+
+	// clusterConfig 提供 ClusterConfiguration，可选控制器按它开关
+	clusterConfig *clusterconfig.Watcher
+	// mu 保护可选控制器的状态
+	mu       sync.Mutex
+	optional []*optionalController
+	// scheduler 当前运行的调度器（已被 ClusterConfiguration 关闭时为 nil）
+	scheduler *SchedulerController
+	started   bool
+	// runCtx 可选控制器的运行 context，Stop 时取消
+	runCtx    context.Context
+	cancelRun context.CancelFunc
+
+	// idle 是否处于空闲模式（见 SetIdle），由 mu 保护
+	idle bool
+	// heartbeatReset 进入或退出空闲模式时通知节点心跳按新的周期重置
+	heartbeatReset chan struct{}
+	// runtimeController 容器运行时控制器（运行时不可用时为 nil）
+	runtimeController *RuntimeController
+
+	// metrics 各控制器的处理统计（按名称，/debug/controllers 与 /metrics 使用）
+	metricsMu    sync.Mutex
+	metrics      map[string]*controllerMetrics
+	metricsOrder []string
 }
 
 // Controller 是控制器的接口
@@ -30,14 +77,29 @@ type Controller interface {
 	Name() string
 }
 
-// NewControllerManager 创建控制器管理器
+// NewControllerManager 创建控制器管理器（自动检测本机的容器运行时）
 func NewControllerManager(
 	store storage.Store,
 	logger logprovider.Logger,
 	config config.Config,
+	clusterConfig *clusterconfig.Watcher,
+) *ControllerManager {
+	return NewControllerManagerWithRuntime(store, logger, config, clusterConfig, nil)
+}
+
+// NewControllerManagerWithRuntime 创建使用指定容器运行时的控制器管理器（例如测试中的假运行时）；runtime 为 nil 时自动检测
+func NewControllerManagerWithRuntime(
+	store storage.Store,
+	logger logprovider.Logger,
+	config config.Config,
+	clusterConfig *clusterconfig.Watcher,
+	runtime ContainerRuntime,
 ) *ControllerManager {
-	// 获取节点名称（优先使用环境变量，否则使用主机名）
+	// 获取节点名称（优先使用环境变量，其次是配置 node_name，否则使用主机名）
 	nodeName := os.Getenv("NODE_NAME")
+	if nodeName == "" {
+		nodeName = config.NodeName
+	}
 	if nodeName == "" {
 		hostname, err := os.Hostname()
 		if err != nil {
@@ -48,19 +110,57 @@ func NewControllerManager(
 		}
 	}
 
+	intervals, err := config.Controller.Intervals()
+	if err != nil {
+		// Module 在创建前已校验；直接调用时配置无效则使用默认周期
+		logger.Warnf("%v，使用默认周期", err)
+		intervals = defaultControllerIntervals()
+	}
+	resources, err := config.Resources.Settings()
+	if err != nil {
+		// 同上，配置无效时不预留资源
+		logger.Warnf("%v，不预留资源", err)
+		resources.Reserved, resources.PodOverhead = nil, nil
+	}
+	cidrs, err := config.Network.CIDRs()
+	if err != nil {
+		logger.Warnf("%v，不上报 Pod 网段", err)
+	}
+	var podCIDRs []string
+	for _, n := range cidrs.Pod {
+		podCIDRs = append(podCIDRs, n.String())
+	}
+
 	cm := &ControllerManager{
-		store:    store,
-		logger:   logger,
-		config:   config,
-		nodeName: nodeName,
+		store:          store,
+		logger:         logger,
+		config:         config,
+		nodeName:       nodeName,
+		intervals:      intervals,
+		resources:      resources,
+		podCIDRs:       podCIDRs,
+		clusterConfig:  clusterConfig,
+		runtime:        runtime,
+		heartbeatReset: make(chan struct{}, 1),
 	}
+	cm.runCtx, cm.cancelRun = context.WithCancel(context.Background())
 
 	// 注册所有控制器
 	cm.registerControllers()
+	clusterConfig.OnChange(cm.applyClusterConfiguration)
 
 	return cm
 }
 
+// defaultControllerIntervals 返回未配置 controller.* 时的默认周期
+func defaultControllerIntervals() config.ControllerIntervals {
+	return config.ControllerIntervals{
+		NodeHeartbeat:       config.DefaultNodeHeartbeat,
+		ResyncPeriod:        config.DefaultResyncPeriod,
+		ContainerGCInterval: config.DefaultContainerGCInterval,
+	}
+}
+
 // registerControllers 注册所有控制器
 func (cm *ControllerManager) registerControllers() {
 	// 注册 Pod 控制器（优先注册，负责 Pod 生命周期管理）
@@ -71,19 +171,53 @@ func (cm *ControllerManager) registerControllers() {
 	deploymentController := NewDeploymentController(cm.store, cm.logger)
 	cm.controllers = append(cm.controllers, deploymentController)
 
-	// 注册 Scheduler 控制器
-	schedulerController := NewSchedulerController(cm.store, cm.logger)
-	cm.controllers = append(cm.controllers, schedulerController)
+	// 注册 Endpoints 控制器（按 Pod readiness 发布 Service 的端点）
+	endpointsController := NewEndpointsController(cm.store, cm.logger)
+	cm.controllers = append(cm.controllers, endpointsController)
 
-	// 注册容器运行时控制器
-	runtimeController, err := NewRuntimeController(cm.store, cm.logger)
+	// 注册容器运行时控制器（指定了运行时时不再检测）
+	var runtimeController *RuntimeController
+	var err error
+	if cm.runtime != nil {
+		runtimeController = newRuntimeController(cm.store, cm.logger, cm.runtime, cm.nodeName, cm.config.Storage.StaticPodPath)
+	} else {
+		runtimeController, err = NewRuntimeController(cm.store, cm.logger, cm.nodeName, cm.config.Storage.StaticPodPath, cm.config.Cluster.ID,
+			registry.PullEndpoint(cm.config.RegistryMirror))
+	}
 	if err != nil {
 		cm.logger.Warnf("无法创建容器运行时控制器: %v", err)
 		cm.logger.Warn("容器运行时功能将不可用")
 	} else {
+		if settings, err := cm.config.Controller.ContainerLogs.Settings(); err != nil {
+			cm.logger.Warnf("%v，不写容器日志文件", err)
+		} else if settings.Enabled() {
+			runtimeController.logs = newContainerLogs(runtimeController.runtime, cm.logger, settings)
+		}
 		cm.controllers = append(cm.controllers, runtimeController)
+		cm.runtime = runtimeController.runtime
+		cm.runtimeController = runtimeController
 		cm.logger.Infof("容器运行时控制器已注册: %s", runtimeController.Name())
 	}
+
+	// 注册 Scheduler、孤儿容器回收、镜像回收、descheduler 与局域网设备清单（可以由 ClusterConfiguration 开关）
+	cm.registerOptionalControllers()
+}
+
+// Register 注册额外的控制器（例如 pkg/ctrlruntime 适配的 controller-runtime Reconciler），随控制器管理器启停；
+// 必须在 Start 之前调用
+func (cm *ControllerManager) Register(c Controller) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.started {
+		return fmt.Errorf("控制器 %s 必须在控制器管理器启动前注册", c.Name())
+	}
+	for _, existing := range cm.controllers {
+		if existing.Name() == c.Name() {
+			return fmt.Errorf("控制器 %s 已注册", c.Name())
+		}
+	}
+	cm.controllers = append(cm.controllers, c)
+	return nil
 }
 
 // Start 启动控制器管理器
@@ -99,6 +233,7 @@ func (cm *ControllerManager) Start(ctx context.Context) error {
 	// 启动所有控制器
 	for _, controller := range cm.controllers {
 		cm.logger.Infof("启动控制器: %s", controller.Name())
+		cm.instrument(controller.Name(), controller)
 		go func(c Controller) {
 			if err := c.Start(ctx); err != nil {
 				cm.logger.Error("控制器启动失败: ", c.Name(), " error: ", err.Error())
@@ -106,6 +241,15 @@ func (cm *ControllerManager) Start(ctx context.Context) error {
 		}(controller)
 	}
 
+	// 按当前的 ClusterConfiguration 启动可选控制器
+	cm.mu.Lock()
+	cm.started = true
+	spec := cm.clusterConfig.Current()
+	for _, oc := range cm.optional {
+		cm.reconcileOptional(oc, spec)
+	}
+	cm.mu.Unlock()
+
 	cm.logger.Info("控制器管理器启动完成")
 	return nil
 }
@@ -117,10 +261,118 @@ func (cm *ControllerManager) Stop(ctx context.Context) error {
 		if err := controller.Stop(ctx); err != nil {
 			cm.logger.Error("停止控制器失败: ", controller.Name(), " error: ", err.Error())
 		}
+		cm.metricsFor(controller.Name()).setRunning(false)
 	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.started = false
+	for _, oc := range cm.optional {
+		if oc.running == nil {
+			continue
+		}
+		if err := oc.running.Stop(ctx); err != nil {
+			cm.logger.Error("停止控制器失败: ", oc.running.Name(), " error: ", err.Error())
+		}
+		cm.metricsFor(oc.name).setRunning(false)
+		oc.running = nil
+	}
+	cm.cancelRun()
 	return nil
 }
 
+// StreamPodLogs 读取运行在本节点上的 Pod 的容器日志：配置了 controller.container_logs 时优先读取日志文件
+// （容器已被删除时同样可以读取），没有日志文件时由容器运行时读取
+func (cm *ControllerManager) StreamPodLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	if cm.runtime == nil {
+		return nil, fmt.Errorf("容器运行时不可用")
+	}
+	if pod.Spec.NodeName != "" && pod.Spec.NodeName != cm.nodeName {
+		return nil, fmt.Errorf("Pod %s/%s 运行在节点 %s，当前节点 %s 无法读取其日志", pod.Namespace, pod.Name, pod.Spec.NodeName, cm.nodeName)
+	}
+	if cm.runtimeController != nil {
+		if rc, ok := cm.runtimeController.logs.open(ctx, pod, opts); ok {
+			return rc, nil
+		}
+	}
+	return cm.runtime.ContainerLogs(ctx, pod, opts)
+}
+
+// NodeName 返回本节点名称
+func (cm *ControllerManager) NodeName() string {
+	return cm.nodeName
+}
+
+// ListNodeImages 列出本节点容器运行时中的镜像（Names 为镜像 tag 以及镜像 ID）
+func (cm *ControllerManager) ListNodeImages(ctx context.Context) ([]corev1.ContainerImage, error) {
+	if cm.runtime == nil {
+		return nil, fmt.Errorf("容器运行时不可用")
+	}
+	images, err := cm.runtime.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]corev1.ContainerImage, 0, len(images))
+	for _, image := range images {
+		result = append(result, corev1.ContainerImage{
+			Names:     append(append([]string{}, image.RepoTags...), image.ID),
+			SizeBytes: image.SizeBytes,
+		})
+	}
+	return result, nil
+}
+
+// PullImages 在本节点依次拉取镜像
+func (cm *ControllerManager) PullImages(ctx context.Context, images []string) []apiserver.ImagePullResult {
+	results := make([]apiserver.ImagePullResult, 0, len(images))
+	for _, image := range images {
+		result := apiserver.ImagePullResult{Image: image}
+		if cm.runtime == nil {
+			result.Error = "容器运行时不可用"
+		} else if err := cm.runtime.PullImage(ctx, image); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Pulled = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// ListPodStats 采样本节点上运行中的 Pod 的资源使用（按 Pod UID 汇总各业务容器）
+func (cm *ControllerManager) ListPodStats(ctx context.Context) ([]apiserver.PodStats, error) {
+	if cm.runtime == nil {
+		return nil, fmt.Errorf("容器运行时不可用")
+	}
+	provider, ok := cm.runtime.(ContainerStatsProvider)
+	if !ok {
+		return nil, fmt.Errorf("容器运行时 %s 不支持采集资源使用", cm.runtime.Name())
+	}
+	containers, err := provider.ContainerStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byPod := make(map[types.UID]*apiserver.PodStats)
+	var order []types.UID
+	for _, c := range containers {
+		p, ok := byPod[c.PodUID]
+		if !ok {
+			p = &apiserver.PodStats{Namespace: c.PodNamespace, Name: c.PodName, Node: cm.nodeName}
+			byPod[c.PodUID] = p
+			order = append(order, c.PodUID)
+		}
+		p.CPUMillicores += c.CPUMillicores
+		p.MemoryBytes += c.MemoryBytes
+		p.Containers = append(p.Containers, apiserver.ContainerUsage{Name: c.ContainerName, CPUMillicores: c.CPUMillicores, MemoryBytes: c.MemoryBytes})
+	}
+	stats := make([]apiserver.PodStats, 0, len(order))
+	for _, uid := range order {
+		stats = append(stats, *byPod[uid])
+	}
+	return stats, nil
+}
+
 // reportNode 上报当前节点信息
 func (cm *ControllerManager) reportNode(ctx context.Context) error {
 	cm.logger.Infof("上报节点: %s", cm.nodeName)
@@ -159,6 +411,35 @@ func (cm *ControllerManager) reportNode(ctx context.Context) error {
 		},
 	}
 
+	// 上报本节点平台，调度器据此匹配 nodeSelector 中的 kubernetes.io/os、kubernetes.io/arch
+	osName, arch := cm.nodePlatform(ctx)
+	node.Labels[corev1.LabelOSStable] = osName
+	node.Labels[corev1.LabelArchStable] = arch
+	node.Status.NodeInfo.OperatingSystem = osName
+	node.Status.NodeInfo.Architecture = arch
+
+	// 上报本节点的地址（双栈时 IPv4 与 IPv6 都有）与 Pod 网段
+	for _, ip := range network.LocalIPs() {
+		node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: ip.String()})
+	}
+	if len(cm.podCIDRs) > 0 {
+		node.Spec.PodCIDR = cm.podCIDRs[0]
+		node.Spec.PodCIDRs = cm.podCIDRs
+	}
+
+	// 上报本节点的资源容量，调度器按 allocatable（扣除 resources.system_reserved/kube_reserved）判断 Pod 的 requests 是否放得下
+	node.Status.Capacity = cm.nodeCapacity()
+	node.Status.Allocatable = allocatable(node.Status.Capacity, cm.resources.Reserved)
+
+	// 上报本节点的镜像（供其他节点通过 nodes/:name/images 查询）
+	if cm.runtime != nil {
+		if images, err := cm.ListNodeImages(ctx); err == nil {
+			node.Status.Images = images
+		} else {
+			cm.logger.Warnf("获取节点镜像失败: %v", err)
+		}
+	}
+
 	// 设置 UID
 	if node.UID == "" {
 		node.UID = types.UID("node-" + cm.nodeName)
@@ -176,6 +457,7 @@ func (cm *ControllerManager) reportNode(ctx context.Context) error {
 	if err != nil {
 		// 节点不存在，创建新节点
 		cm.logger.Infof("创建新节点: %s", cm.nodeName)
+		storage.RecordManager(node, nodeFieldManager)
 		if err := cm.store.Create(gvk, node); err != nil {
 			return err
 		}
@@ -184,6 +466,8 @@ func (cm *ControllerManager) reportNode(ctx context.Context) error {
 		cm.logger.Infof("更新节点: %s", cm.nodeName)
 		// 更新心跳时间
 		if existingNodeNode, ok := existingNode.(*corev1.Node); ok {
+			// 保留其他写入者（network、外部 agent 等）设置的 labels/annotations
+			storage.MergeNodeMetadata(existingNodeNode, node)
 			node.Status.Conditions = existingNodeNode.Status.Conditions
 			// 更新心跳时间
 			for i := range node.Status.Conditions {
@@ -192,7 +476,12 @@ func (cm *ControllerManager) reportNode(ctx context.Context) error {
 				}
 			}
 		}
-		if err := cm.store.Update(gvk, node); err != nil {
+		// 与 network/discovery 等其他写入者冲突时记录告警（仍然写入，保持原有行为）
+		conflict, err := storage.UpdateAs(cm.store, gvk, node, nodeFieldManager, true)
+		if conflict != nil {
+			cm.logger.Warnf("节点 %s 写入冲突: %v", cm.nodeName, conflict)
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -201,19 +490,71 @@ func (cm *ControllerManager) reportNode(ctx context.Context) error {
 	return nil
 }
 
-// StartNodeHeartbeat 启动节点心跳上报
+// nodeCapacity 返回本节点的 cpu、memory 与 pods 容量；读取不到内存总量时不上报 memory（调度时不限制内存）
+func (cm *ControllerManager) nodeCapacity() corev1.ResourceList {
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:  *resource.NewQuantity(int64(runtime.NumCPU()), resource.DecimalSI),
+		corev1.ResourcePods: *resource.NewQuantity(maxPodsPerNode, resource.DecimalSI),
+	}
+	if memory, err := totalMemory(); err == nil {
+		capacity[corev1.ResourceMemory] = *resource.NewQuantity(int64(memory), resource.BinarySI)
+	} else {
+		cm.logger.Debugf("读取内存总量失败，不上报 memory: %v", err)
+	}
+	return capacity
+}
+
+// allocatable 返回容量扣除预留资源后可以分配给 Pod 的资源，预留超过容量时为 0
+func allocatable(capacity, reserved corev1.ResourceList) corev1.ResourceList {
+	out := capacity.DeepCopy()
+	for name, q := range reserved {
+		total, ok := out[name]
+		if !ok {
+			continue
+		}
+		total.Sub(q)
+		if total.Sign() < 0 {
+			total = *resource.NewQuantity(0, total.Format)
+		}
+		out[name] = total
+	}
+	return out
+}
+
+// nodePlatform 返回本节点的 os/arch：优先取容器运行时的平台（容器实际运行的平台），
+// 没有运行时或查询失败时使用 k3 进程自身的 GOOS/GOARCH
+func (cm *ControllerManager) nodePlatform(ctx context.Context) (string, string) {
+	if cm.runtime != nil {
+		osName, arch, err := cm.runtime.Platform(ctx)
+		if err == nil {
+			return osName, arch
+		}
+		cm.logger.Warnf("获取容器运行时平台失败，使用本机平台: %v", err)
+	}
+	return runtime.GOOS, runtime.GOARCH
+}
+
+// StartNodeHeartbeat 启动节点心跳上报；空闲模式下按 idle.heartbeat_multiplier 延长周期，退出空闲模式时立即上报一次
 func (cm *ControllerManager) StartNodeHeartbeat(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(cm.heartbeatInterval())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-cm.heartbeatReset:
+			ticker.Reset(cm.heartbeatInterval())
+			if cm.isIdle() {
+				continue
+			}
 		case <-ticker.C:
-			if err := cm.reportNode(ctx); err != nil {
-				cm.logger.Error("节点心跳上报失败: ", err.Error())
+			if cm.heartbeatPaused() {
+				continue
 			}
 		}
+		if err := cm.reportNode(ctx); err != nil {
+			cm.logger.Error("节点心跳上报失败: ", err.Error())
+		}
 	}
 }
@@ -0,0 +1,70 @@
+//go:build linux
+
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// totalMemory 从 /proc/meminfo 读取本机内存总量（字节）
+func totalMemory() (uint64, error) {
+	values, err := readMeminfo("MemTotal")
+	if err != nil {
+		return 0, err
+	}
+	return values[0], nil
+}
+
+// availableMemory 从 /proc/meminfo 读取本机内存总量与可用内存（MemAvailable，字节）
+func availableMemory() (total, available uint64, err error) {
+	values, err := readMeminfo("MemTotal", "MemAvailable")
+	if err != nil {
+		return 0, 0, err
+	}
+	return values[0], values[1], nil
+}
+
+// readMeminfo 按 keys 的顺序返回 /proc/meminfo 中对应项的字节数
+func readMeminfo(keys ...string) ([]uint64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	found := make(map[string]uint64, len(keys))
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// MemTotal:       16303788 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		key := strings.TrimSuffix(fields[0], ":")
+		for _, want := range keys {
+			if key != want {
+				continue
+			}
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("解析 %s 失败: %w", key, err)
+			}
+			found[key] = kb * 1024
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	values := make([]uint64, len(keys))
+	for i, key := range keys {
+		v, ok := found[key]
+		if !ok {
+			return nil, fmt.Errorf("/proc/meminfo 中没有 %s", key)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package controller
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// totalMemory 在当前平台上不可用，节点不上报内存容量（调度时不限制内存）
+func totalMemory() (uint64, error) {
+	return 0, fmt.Errorf("%s 平台不支持读取内存总量", runtime.GOOS)
+}
+
+// availableMemory 在当前平台上不可用，节点压力驱逐不检查内存
+func availableMemory() (total, available uint64, err error) {
+	return 0, 0, fmt.Errorf("%s 平台不支持读取可用内存", runtime.GOOS)
+}
@@ -0,0 +1,297 @@
+package controller
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+)
+
+const (
+	// stalledAfter 队列中有事件、且超过该时长没有完成任何一次处理时视为卡住
+	stalledAfter = 2 * time.Minute
+	// recentReconciles 计算最近错误率的处理次数
+	recentReconciles = 100
+	// failingErrorRate 最近的错误率超过该值时视为失败
+	failingErrorRate = 0.5
+)
+
+// reconcileBuckets 处理耗时直方图的上界（秒）
+var reconcileBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// controllerMetrics 一个控制器的处理统计；方法对 nil 接收者无操作（未接入指标的控制器不需要判断）
+type controllerMetrics struct {
+	mu      sync.Mutex
+	running bool
+	// startedAt 最近一次启动的时间（还没有完成过处理时按它判断是否卡住）
+	startedAt time.Time
+	// queues 为控制器消费的 watch 通道（队列深度为其中未读的事件数）
+	queues map[string]<-chan storage.ResourceEvent
+	// closed 已关闭的 watch 通道（处理循环已退出）
+	closed []string
+
+	reconciles, errors uint64
+	buckets            []uint64
+	sum                float64
+	// recent 为最近的处理结果（true 为失败）的环形缓冲
+	recent      [recentReconciles]bool
+	recentCount int
+	recentNext  int
+
+	lastReconcile, lastSuccess, lastErrorTime time.Time
+	lastError                                 string
+
+	// batches、placements 为调度器的批次数与从 Pod 创建到绑定的耗时（其他控制器为 0）
+	batches          uint64
+	placements       uint64
+	placementBuckets []uint64
+	placementSum     float64
+}
+
+// newControllerMetrics 创建处理统计
+func newControllerMetrics() *controllerMetrics {
+	return &controllerMetrics{
+		buckets:          make([]uint64, len(reconcileBuckets)),
+		placementBuckets: make([]uint64, len(reconcileBuckets)),
+	}
+}
+
+// instrumentedController 接入处理统计的控制器，ControllerManager 在启动前注入
+type instrumentedController interface {
+	setMetrics(m *controllerMetrics)
+}
+
+// ReconcileObserver 由不在本包中的控制器（如 pkg/ctrlruntime.Controller）实现，
+// ControllerManager 启动前传入 observe，控制器每次处理结束后调用它记录处理统计
+type ReconcileObserver interface {
+	ObserveReconciles(observe func(start time.Time, err error))
+}
+
+// watch 登记控制器消费的 watch 通道
+func (m *controllerMetrics) watch(name string, ch <-chan storage.ResourceEvent) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queues == nil {
+		m.queues = make(map[string]<-chan storage.ResourceEvent)
+	}
+	m.queues[name] = ch
+}
+
+// watchClosed 记录 watch 通道被关闭（处理循环随之退出）
+func (m *controllerMetrics) watchClosed(name string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.queues, name)
+	m.closed = append(m.closed, name)
+}
+
+// observe 记录一次处理的耗时与结果
+func (m *controllerMetrics) observe(start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	now := time.Now()
+	seconds := now.Sub(start).Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconciles++
+	m.sum += seconds
+	for i, le := range reconcileBuckets {
+		if seconds <= le {
+			m.buckets[i]++
+		}
+	}
+	m.lastReconcile = now
+	if err != nil {
+		m.errors++
+		m.lastError = err.Error()
+		m.lastErrorTime = now
+	} else {
+		m.lastSuccess = now
+	}
+	m.recent[m.recentNext] = err != nil
+	m.recentNext = (m.recentNext + 1) % recentReconciles
+	if m.recentCount < recentReconciles {
+		m.recentCount++
+	}
+}
+
+// observeBatch 记录调度器开始调度一批 Pod
+func (m *controllerMetrics) observeBatch() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches++
+}
+
+// observePlacement 记录一个 Pod 从创建到绑定节点的耗时
+func (m *controllerMetrics) observePlacement(latency time.Duration) {
+	if m == nil {
+		return
+	}
+	seconds := latency.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.placements++
+	m.placementSum += seconds
+	for i, le := range reconcileBuckets {
+		if seconds <= le {
+			m.placementBuckets[i]++
+		}
+	}
+}
+
+// setRunning 记录控制器是否在运行；重新启动时清空上一次登记的 watch 通道
+func (m *controllerMetrics) setRunning(running bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = running
+	m.startedAt = time.Now()
+	m.queues = nil
+	m.closed = nil
+}
+
+// status 汇总控制器的状态，now 用于判断是否卡住
+func (m *controllerMetrics) status(name string, now time.Time) apiserver.ControllerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := apiserver.ControllerStatus{
+		Name:       name,
+		Running:    m.running,
+		Reconciles: m.reconciles,
+		Errors:     m.errors,
+		LastError:  m.lastError,
+		ReconcileDuration: apiserver.DurationHistogram{
+			Buckets: reconcileBuckets,
+			Counts:  append([]uint64(nil), m.buckets...),
+			Sum:     m.sum,
+			Count:   m.reconciles,
+		},
+	}
+	if m.batches > 0 {
+		st.Placement = &apiserver.PlacementStats{
+			Batches: m.batches,
+			Latency: apiserver.DurationHistogram{
+				Buckets: reconcileBuckets,
+				Counts:  append([]uint64(nil), m.placementBuckets...),
+				Sum:     m.placementSum,
+				Count:   m.placements,
+			},
+		}
+	}
+	for _, ch := range m.queues {
+		st.QueueDepth += len(ch)
+	}
+	if m.recentCount > 0 {
+		failed := 0
+		for i := 0; i < m.recentCount; i++ {
+			if m.recent[i] {
+				failed++
+			}
+		}
+		st.RecentErrorRate = float64(failed) / float64(m.recentCount)
+	}
+	st.LastReconcile = optionalTime(m.lastReconcile)
+	st.LastSuccessfulSync = optionalTime(m.lastSuccess)
+	st.LastErrorTime = optionalTime(m.lastErrorTime)
+
+	switch {
+	case !m.running:
+		st.Health = apiserver.ControllerDisabled
+	case len(m.closed) > 0:
+		st.Health = apiserver.ControllerStopped
+		st.Message = "watch 通道已关闭: " + strings.Join(m.closed, ", ")
+	case st.QueueDepth > 0 && now.Sub(latest(m.lastReconcile, m.startedAt)) > stalledAfter:
+		st.Health = apiserver.ControllerStalled
+		st.Message = "队列中有待处理的事件，但超过 " + stalledAfter.String() + " 没有完成处理"
+	case m.recentCount > 0 && st.RecentErrorRate > failingErrorRate:
+		st.Health = apiserver.ControllerFailing
+		st.Message = "最近的处理大部分失败: " + m.lastError
+	default:
+		st.Health = apiserver.ControllerHealthy
+	}
+	return st
+}
+
+// latest 返回较晚的时间
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// optionalTime 零值返回 nil（JSON 中省略）
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// metricsFor 返回控制器的处理统计（按名称复用，可选控制器重建后统计不清零）
+func (cm *ControllerManager) metricsFor(name string) *controllerMetrics {
+	cm.metricsMu.Lock()
+	defer cm.metricsMu.Unlock()
+	if cm.metrics == nil {
+		cm.metrics = make(map[string]*controllerMetrics)
+	}
+	m, ok := cm.metrics[name]
+	if !ok {
+		m = newControllerMetrics()
+		cm.metrics[name] = m
+		cm.metricsOrder = append(cm.metricsOrder, name)
+	}
+	return m
+}
+
+// instrument 为控制器注入处理统计并标记为运行中（name 为登记的名称）
+func (cm *ControllerManager) instrument(name string, c Controller) {
+	m := cm.metricsFor(name)
+	m.setRunning(true)
+	if ic, ok := c.(instrumentedController); ok {
+		ic.setMetrics(m)
+	}
+	if ro, ok := c.(ReconcileObserver); ok {
+		ro.ObserveReconciles(m.observe)
+	}
+}
+
+// ControllerStatuses 返回每个已登记控制器（包括未开启的可选控制器）的状态
+func (cm *ControllerManager) ControllerStatuses() []apiserver.ControllerStatus {
+	cm.mu.Lock()
+	for _, oc := range cm.optional {
+		cm.metricsFor(oc.name)
+	}
+	cm.mu.Unlock()
+
+	cm.metricsMu.Lock()
+	names := append([]string(nil), cm.metricsOrder...)
+	metrics := make([]*controllerMetrics, len(names))
+	for i, name := range names {
+		metrics[i] = cm.metrics[name]
+	}
+	cm.metricsMu.Unlock()
+
+	now := time.Now()
+	statuses := make([]apiserver.ControllerStatus, 0, len(names))
+	for i, name := range names {
+		statuses = append(statuses, metrics[i].status(name, now))
+	}
+	return statuses
+}
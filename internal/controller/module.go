@@ -1,12 +1,65 @@
 package controller
 
 import (
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterconfig"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	"go.uber.org/fx"
 )
 
+// managerParams 是创建 ControllerManager 所需的依赖；Runtime 可选（测试中提供假的容器运行时，未提供时检测本机运行时）
+type managerParams struct {
+	fx.In
+
+	Store         storage.Store
+	Logger        logprovider.Logger
+	Config        config.Config
+	ClusterConfig *clusterconfig.Watcher
+	Runtime       ContainerRuntime `optional:"true"`
+	// Controllers 为以 fx 组 "controllers" 提供的额外控制器（如 pkg/ctrlruntime 适配的 Reconciler）
+	Controllers []Controller `group:"controllers"`
+}
+
 // Module 提供控制器模块
 var Module = fx.Module("controller",
 	fx.Provide(
-		NewControllerManager,
+		func(p managerParams) (*ControllerManager, error) {
+			// controller.* 周期超出允许范围、controller.container_logs、resources.*、network.pod_cidrs 或 eviction.* 无效时启动失败，
+			// 而不是静默使用默认值
+			if _, err := p.Config.Controller.Intervals(); err != nil {
+				return nil, err
+			}
+			if _, err := p.Config.Controller.ContainerLogs.Settings(); err != nil {
+				return nil, err
+			}
+			if _, err := p.Config.Resources.Settings(); err != nil {
+				return nil, err
+			}
+			if _, err := p.Config.Network.CIDRs(); err != nil {
+				return nil, err
+			}
+			if _, err := p.Config.Eviction.Settings(); err != nil {
+				return nil, err
+			}
+			cm := NewControllerManagerWithRuntime(p.Store, p.Logger, p.Config, p.ClusterConfig, p.Runtime)
+			for _, c := range p.Controllers {
+				if err := cm.Register(c); err != nil {
+					return nil, err
+				}
+			}
+			return cm, nil
+		},
+		// ControllerManager 持有本节点的容器运行时，同进程的 apiserver/dashboard 通过它读取 Pod 日志
+		func(cm *ControllerManager) apiserver.PodLogStreamer { return cm },
+		// 同理，nodes/images 子资源通过它查询和预拉取本节点镜像
+		func(cm *ControllerManager) apiserver.NodeImageManager { return cm },
+		// 以及 /debug/runtime/exec 在本节点运行时上执行 ps/inspect/images
+		func(cm *ControllerManager) apiserver.NodeRuntimeDebugger { return cm },
+		// 以及 k3.io/v1 podstats 采样本节点 Pod 的资源使用
+		func(cm *ControllerManager) apiserver.PodStatsProvider { return cm },
+		// 以及 /debug/controllers 与 /metrics 中的控制器状态
+		func(cm *ControllerManager) apiserver.ControllerStatusProvider { return cm },
 	),
 )
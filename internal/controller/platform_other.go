@@ -0,0 +1,23 @@
+//go:build !windows
+
+package controller
+
+import (
+	"os/exec"
+	"path/filepath"
+)
+
+// lookupDocker 查找 docker 命令
+func lookupDocker() (string, error) {
+	return exec.LookPath("docker")
+}
+
+// lookupContainerd 查找 containerd 的 ctr 命令
+func lookupContainerd() (string, error) {
+	return exec.LookPath("ctr")
+}
+
+// hostPathForDocker 把 hostPath 卷的路径转换为 docker --mount 可接受的宿主机路径
+func hostPathForDocker(path string) string {
+	return filepath.Clean(path)
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package controller
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// lookupDocker 查找 docker 命令：PATH 中没有时尝试 Docker Desktop 的默认安装目录
+func lookupDocker() (string, error) {
+	if path, err := exec.LookPath("docker"); err == nil {
+		return path, nil
+	}
+	return lookupProgramFiles(`Docker\Docker\resources\bin\docker.exe`)
+}
+
+// lookupContainerd 查找 containerd 的 ctr 命令：PATH 中没有时尝试 containerd 的默认安装目录
+func lookupContainerd() (string, error) {
+	if path, err := exec.LookPath("ctr"); err == nil {
+		return path, nil
+	}
+	return lookupProgramFiles(`containerd\ctr.exe`)
+}
+
+// lookupProgramFiles 在 %ProgramFiles% 下查找可执行文件
+func lookupProgramFiles(rel string) (string, error) {
+	dir := os.Getenv("ProgramFiles")
+	if dir == "" {
+		dir = `C:\Program Files`
+	}
+	path := filepath.Join(dir, rel)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%s 未找到", path)
+	}
+	return path, nil
+}
+
+// hostPathForDocker 把 hostPath 卷的路径转换为 Windows 路径：
+// 支持 C:\data、C:/data 以及 Git Bash/WSL 风格的 /c/data
+func hostPathForDocker(path string) string {
+	if len(path) >= 3 && path[0] == '/' && path[2] == '/' && isDriveLetter(path[1]) {
+		path = strings.ToUpper(path[1:2]) + ":" + path[2:]
+	} else if len(path) == 2 && path[0] == '/' && isDriveLetter(path[1]) {
+		path = strings.ToUpper(path[1:2]) + `:\`
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
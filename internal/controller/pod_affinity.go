@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// namespaceGVK 是 core/v1 Namespace（解析 namespaceSelector）
+var namespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+
+// antiAffinityTerm 解析后的 PodAffinityTerm
+type antiAffinityTerm struct {
+	topologyKey string
+	selector    labels.Selector
+	// namespaces 匹配的 namespace，nil 表示全部
+	namespaces map[string]bool
+	// weight preferred 项的权重
+	weight int32
+}
+
+// matches 判断 Pod 是否被反亲和项选中
+func (t *antiAffinityTerm) matches(p *corev1.Pod) bool {
+	if t.namespaces != nil && !t.namespaces[p.Namespace] {
+		return false
+	}
+	return t.selector.Matches(labels.Set(p.Labels))
+}
+
+// existingAntiAffinity 已调度 Pod 的 required 反亲和项：待调度 Pod 被选中时，不能放入该 Pod 所在的拓扑域
+type existingAntiAffinity struct {
+	pod  *corev1.Pod
+	node *corev1.Node
+	term antiAffinityTerm
+}
+
+// podAntiAffinity 调度一个 Pod 时的反亲和性：待调度 Pod 的 required 项与已有 Pod 的 required 项（对称）作为过滤条件，
+// 待调度 Pod 的 preferred 项参与节点打分
+type podAntiAffinity struct {
+	required  []antiAffinityTerm
+	preferred []antiAffinityTerm
+	existing  []existingAntiAffinity
+	// nodes 与 podsByNode 用于按拓扑域查找已有 Pod
+	nodes      []*corev1.Node
+	podsByNode map[string][]*corev1.Pod
+}
+
+// newPodAntiAffinity 解析待调度 Pod 与已调度 Pod 的 podAntiAffinity。nodes 为就绪节点；
+// 没有任何反亲和项时返回 nil
+func newPodAntiAffinity(store storage.Store, pod *corev1.Pod, nodes []*corev1.Node, podsByNode map[string][]*corev1.Pod) (*podAntiAffinity, error) {
+	resolver := &namespaceResolver{store: store}
+	pa := &podAntiAffinity{nodes: nodes, podsByNode: podsByNode}
+
+	if aa := podAntiAffinitySpec(pod); aa != nil {
+		if err := apiserver.ValidatePodAntiAffinity("spec.affinity.podAntiAffinity", aa); err != nil {
+			return nil, err
+		}
+		for _, t := range aa.RequiredDuringSchedulingIgnoredDuringExecution {
+			term, err := resolver.term(pod, t)
+			if err != nil {
+				return nil, err
+			}
+			pa.required = append(pa.required, term)
+		}
+		for _, wt := range aa.PreferredDuringSchedulingIgnoredDuringExecution {
+			term, err := resolver.term(pod, wt.PodAffinityTerm)
+			if err != nil {
+				return nil, err
+			}
+			term.weight = wt.Weight
+			pa.preferred = append(pa.preferred, term)
+		}
+	}
+
+	for _, node := range nodes {
+		for _, p := range podsByNode[node.Name] {
+			aa := podAntiAffinitySpec(p)
+			if aa == nil {
+				continue
+			}
+			for _, t := range aa.RequiredDuringSchedulingIgnoredDuringExecution {
+				term, err := resolver.term(p, t)
+				if err != nil {
+					// 已有 Pod 的无效项不影响调度
+					continue
+				}
+				pa.existing = append(pa.existing, existingAntiAffinity{pod: p, node: node, term: term})
+			}
+		}
+	}
+
+	if len(pa.required) == 0 && len(pa.preferred) == 0 && len(pa.existing) == 0 {
+		return nil, nil
+	}
+	return pa, nil
+}
+
+// podAntiAffinitySpec 返回 Pod 的 spec.affinity.podAntiAffinity
+func podAntiAffinitySpec(pod *corev1.Pod) *corev1.PodAntiAffinity {
+	if pod.Spec.Affinity == nil {
+		return nil
+	}
+	return pod.Spec.Affinity.PodAntiAffinity
+}
+
+// fits 检查 required 反亲和：node 所在拓扑域中不能有被待调度 Pod 的 required 项选中的 Pod，
+// 也不能有 required 项选中待调度 Pod 的 Pod。节点没有 topologyKey 标签时该项不生效。不满足时返回说明
+func (pa *podAntiAffinity) fits(pod *corev1.Pod, node *corev1.Node) (string, bool) {
+	if pa == nil {
+		return "", true
+	}
+	for i := range pa.required {
+		term := &pa.required[i]
+		if p := pa.firstInDomain(node, term.topologyKey, term.matches); p != nil {
+			return fmt.Sprintf("与 %s=%s 中的 Pod %s/%s 反亲和", term.topologyKey, node.Labels[term.topologyKey], p.Namespace, p.Name), false
+		}
+	}
+	for _, e := range pa.existing {
+		key := e.term.topologyKey
+		value, ok := node.Labels[key]
+		if existing, has := e.node.Labels[key]; !ok || !has || existing != value {
+			continue
+		}
+		if e.term.matches(pod) {
+			return fmt.Sprintf("%s=%s 中的 Pod %s/%s 与其反亲和", e.term.topologyKey, value, e.pod.Namespace, e.pod.Name), false
+		}
+	}
+	return "", true
+}
+
+// score 返回 preferred 反亲和项的罚分：node 所在拓扑域中每个被选中的 Pod 计一次该项的权重（越小越好）
+func (pa *podAntiAffinity) score(node *corev1.Node) int {
+	if pa == nil {
+		return 0
+	}
+	total := 0
+	for i := range pa.preferred {
+		term := &pa.preferred[i]
+		value, ok := node.Labels[term.topologyKey]
+		if !ok {
+			continue
+		}
+		for _, n := range pa.nodes {
+			if v, ok := n.Labels[term.topologyKey]; !ok || v != value {
+				continue
+			}
+			for _, p := range pa.podsByNode[n.Name] {
+				if term.matches(p) {
+					total += int(term.weight)
+				}
+			}
+		}
+	}
+	return total
+}
+
+// firstInDomain 返回 node 所在拓扑域（topologyKey 标签值相同的节点）中第一个满足 match 的 Pod；节点没有该标签时返回 nil
+func (pa *podAntiAffinity) firstInDomain(node *corev1.Node, topologyKey string, match func(*corev1.Pod) bool) *corev1.Pod {
+	value, ok := node.Labels[topologyKey]
+	if !ok {
+		return nil
+	}
+	for _, n := range pa.nodes {
+		if v, ok := n.Labels[topologyKey]; !ok || v != value {
+			continue
+		}
+		for _, p := range pa.podsByNode[n.Name] {
+			if match(p) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// namespaceResolver 解析 PodAffinityTerm 的 namespaces 与 namespaceSelector，Namespace 列表按需读取一次
+type namespaceResolver struct {
+	store      storage.Store
+	namespaces []*corev1.Namespace
+	loaded     bool
+}
+
+// term 把 owner（反亲和项所在的 Pod）的 PodAffinityTerm 解析为 antiAffinityTerm：
+// labelSelector 为空时不选中任何 Pod，matchLabelKeys/mismatchLabelKeys 按 owner 的标签值追加条件；
+// namespaces 与 namespaceSelector 都没有设置时只匹配 owner 所在的 namespace，namespaceSelector 为 {} 时匹配全部
+func (r *namespaceResolver) term(owner *corev1.Pod, t corev1.PodAffinityTerm) (antiAffinityTerm, error) {
+	term := antiAffinityTerm{topologyKey: t.TopologyKey, selector: labels.Nothing()}
+	if t.LabelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(t.LabelSelector)
+		if err != nil {
+			return term, err
+		}
+		for _, keys := range []struct {
+			keys []string
+			op   selection.Operator
+		}{{t.MatchLabelKeys, selection.In}, {t.MismatchLabelKeys, selection.NotIn}} {
+			for _, key := range keys.keys {
+				value, ok := owner.Labels[key]
+				if !ok {
+					continue
+				}
+				req, err := labels.NewRequirement(key, keys.op, []string{value})
+				if err != nil {
+					return term, err
+				}
+				selector = selector.Add(*req)
+			}
+		}
+		term.selector = selector
+	}
+
+	if t.NamespaceSelector != nil && len(t.NamespaceSelector.MatchLabels) == 0 && len(t.NamespaceSelector.MatchExpressions) == 0 {
+		return term, nil
+	}
+	term.namespaces = make(map[string]bool)
+	for _, ns := range t.Namespaces {
+		term.namespaces[ns] = true
+	}
+	if t.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(t.NamespaceSelector)
+		if err != nil {
+			return term, err
+		}
+		if err := r.load(); err != nil {
+			return term, err
+		}
+		for _, ns := range r.namespaces {
+			if selector.Matches(labels.Set(ns.Labels)) {
+				term.namespaces[ns.Name] = true
+			}
+		}
+	} else if len(t.Namespaces) == 0 {
+		term.namespaces[owner.Namespace] = true
+	}
+	return term, nil
+}
+
+// load 读取 Namespace 列表（只读取一次）
+func (r *namespaceResolver) load() error {
+	if r.loaded {
+		return nil
+	}
+	objs, err := r.store.List(namespaceGVK, "")
+	if err != nil {
+		return fmt.Errorf("获取 namespace 列表失败: %w", err)
+	}
+	for _, obj := range objs {
+		if ns, ok := obj.(*corev1.Namespace); ok {
+			r.namespaces = append(r.namespaces, ns)
+		}
+	}
+	r.loaded = true
+	return nil
+}
@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"testing"
+
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// webAffinityTerm 返回选中 app=web 的 PodAffinityTerm
+func webAffinityTerm(topologyKey string) corev1.PodAffinityTerm {
+	return corev1.PodAffinityTerm{
+		TopologyKey:   topologyKey,
+		LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+	}
+}
+
+func requiredAntiAffinity(terms ...corev1.PodAffinityTerm) func(*corev1.Pod) {
+	return func(p *corev1.Pod) {
+		p.Spec.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{RequiredDuringSchedulingIgnoredDuringExecution: terms}}
+	}
+}
+
+func preferredAntiAffinity(weight int32, term corev1.PodAffinityTerm) func(*corev1.Pod) {
+	return func(p *corev1.Pod) {
+		p.Spec.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{Weight: weight, PodAffinityTerm: term}},
+		}}
+	}
+}
+
+func inNamespace(ns string) func(*corev1.Pod) {
+	return func(p *corev1.Pod) { p.Namespace = ns }
+}
+
+func TestPodAntiAffinityFits(t *testing.T) {
+	nodes := []*corev1.Node{
+		zonedNode("a-1", "a", nil),
+		zonedNode("a-2", "a", nil),
+		zonedNode("b-1", "b", nil),
+		zonedNode("bare", "", nil),
+	}
+	store := storage.NewMemoryStore()
+	for _, ns := range []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "staging", Labels: map[string]string{"team": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	} {
+		if err := store.Create(namespaceGVK, ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		pod      *corev1.Pod
+		existing []*corev1.Pod
+		// want 各节点是否满足 required 反亲和
+		want map[string]bool
+	}{
+		{
+			name:     "hostname key excludes only the node",
+			pod:      spreadPod("new", "", requiredAntiAffinity(webAffinityTerm(corev1.LabelHostname))),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1")},
+			want:     map[string]bool{"a-1": false, "a-2": true, "b-1": true},
+		},
+		{
+			name:     "zone key excludes the whole zone",
+			pod:      spreadPod("new", "", requiredAntiAffinity(webAffinityTerm(corev1.LabelTopologyZone))),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1")},
+			want:     map[string]bool{"a-1": false, "a-2": false, "b-1": true, "bare": true},
+		},
+		{
+			name:     "every required term must hold",
+			pod:      spreadPod("new", "", requiredAntiAffinity(webAffinityTerm(corev1.LabelHostname), webAffinityTerm(corev1.LabelTopologyZone))),
+			existing: []*corev1.Pod{spreadPod("web-1", "b-1")},
+			want:     map[string]bool{"a-1": true, "a-2": true, "b-1": false},
+		},
+		{
+			name:     "existing pod's required term is symmetric",
+			pod:      spreadPod("new", ""),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1", requiredAntiAffinity(webAffinityTerm(corev1.LabelTopologyZone)))},
+			want:     map[string]bool{"a-1": false, "a-2": false, "b-1": true},
+		},
+		{
+			name:     "existing term ignores pods it does not select",
+			pod:      spreadPod("new", "", withLabels(map[string]string{"app": "db"})),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1", requiredAntiAffinity(webAffinityTerm(corev1.LabelTopologyZone)))},
+			want:     map[string]bool{"a-1": true, "a-2": true},
+		},
+		{
+			name:     "preferred terms never filter",
+			pod:      spreadPod("new", "", preferredAntiAffinity(100, webAffinityTerm(corev1.LabelHostname))),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1")},
+			want:     map[string]bool{"a-1": true, "a-2": true},
+		},
+		{
+			name:     "terms default to the pod's namespace",
+			pod:      spreadPod("new", "", requiredAntiAffinity(webAffinityTerm(corev1.LabelHostname))),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1", inNamespace("other"))},
+			want:     map[string]bool{"a-1": true},
+		},
+		{
+			name: "namespaceSelector selects labelled namespaces",
+			pod: spreadPod("new", "", requiredAntiAffinity(func() corev1.PodAffinityTerm {
+				term := webAffinityTerm(corev1.LabelHostname)
+				term.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}}
+				return term
+			}())),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1", inNamespace("staging")), spreadPod("web-2", "a-2", inNamespace("other"))},
+			want:     map[string]bool{"a-1": false, "a-2": true},
+		},
+		{
+			name: "empty namespaceSelector selects every namespace",
+			pod: spreadPod("new", "", requiredAntiAffinity(func() corev1.PodAffinityTerm {
+				term := webAffinityTerm(corev1.LabelHostname)
+				term.NamespaceSelector = &metav1.LabelSelector{}
+				return term
+			}())),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1", inNamespace("other"))},
+			want:     map[string]bool{"a-1": false},
+		},
+		{
+			name: "mismatchLabelKeys ignores the same tenant",
+			pod: spreadPod("new", "", withLabels(map[string]string{"app": "web", "tenant": "x"}), requiredAntiAffinity(func() corev1.PodAffinityTerm {
+				term := webAffinityTerm(corev1.LabelHostname)
+				term.MismatchLabelKeys = []string{"tenant"}
+				return term
+			}())),
+			existing: []*corev1.Pod{
+				spreadPod("same", "a-1", withLabels(map[string]string{"app": "web", "tenant": "x"})),
+				spreadPod("other", "a-2", withLabels(map[string]string{"app": "web", "tenant": "y"})),
+			},
+			want: map[string]bool{"a-1": true, "a-2": false},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			podsByNode := make(map[string][]*corev1.Pod)
+			for _, p := range tc.existing {
+				podsByNode[p.Spec.NodeName] = append(podsByNode[p.Spec.NodeName], p)
+			}
+			pa, err := newPodAntiAffinity(store, tc.pod, nodes, podsByNode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, node := range nodes {
+				want, ok := tc.want[node.Name]
+				if !ok {
+					continue
+				}
+				if reason, got := pa.fits(tc.pod, node); got != want {
+					t.Errorf("fits(%s) = %v (%s), want %v", node.Name, got, reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPodAntiAffinityScore(t *testing.T) {
+	nodes := []*corev1.Node{zonedNode("a-1", "a", nil), zonedNode("a-2", "a", nil), zonedNode("b-1", "b", nil)}
+	podsByNode := map[string][]*corev1.Pod{
+		"a-1": {spreadPod("web-1", "a-1"), spreadPod("web-2", "a-1")},
+		"b-1": {spreadPod("web-3", "b-1")},
+	}
+	store := storage.NewMemoryStore()
+
+	// hostname：只计算同一节点上的 Pod
+	pa, err := newPodAntiAffinity(store, spreadPod("new", "", preferredAntiAffinity(10, webAffinityTerm(corev1.LabelHostname))), nodes, podsByNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for node, want := range map[int]int{0: 20, 1: 0, 2: 10} {
+		if got := pa.score(nodes[node]); got != want {
+			t.Errorf("hostname score(%s) = %d, want %d", nodes[node].Name, got, want)
+		}
+	}
+
+	// zone：同一 zone 中其他节点上的 Pod 也计入
+	pa, err = newPodAntiAffinity(store, spreadPod("new", "", preferredAntiAffinity(10, webAffinityTerm(corev1.LabelTopologyZone))), nodes, podsByNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for node, want := range map[int]int{0: 20, 1: 20, 2: 10} {
+		if got := pa.score(nodes[node]); got != want {
+			t.Errorf("zone score(%s) = %d, want %d", nodes[node].Name, got, want)
+		}
+	}
+
+	if none, err := newPodAntiAffinity(store, spreadPod("new", ""), nodes, podsByNode); none != nil || err != nil {
+		t.Fatalf("no anti-affinity: %v, %v", none, err)
+	}
+}
+
+func TestPlaceAntiAffinity(t *testing.T) {
+	newSnapshot := func() *schedulingSnapshot {
+		snap := &schedulingSnapshot{}
+		for _, node := range []*corev1.Node{zonedNode("a-1", "a", nil), zonedNode("b-1", "b", nil)} {
+			snap.nodes = append(snap.nodes, node)
+		}
+		return snap
+	}
+	place := func(sc *SchedulerController, snap *schedulingSnapshot, pod *corev1.Pod) (string, error) {
+		node, _, err := sc.place(pod, snap)
+		if err != nil {
+			return "", err
+		}
+		bound := pod.DeepCopy()
+		assignNode(bound, node.Name)
+		snap.assume(bound)
+		return node.Name, nil
+	}
+
+	// required：两个副本放到不同节点，第三个没有节点可放
+	sc := NewSchedulerController(storage.NewMemoryStore(), testLogger)
+	snap := newSnapshot()
+	used := make(map[string]bool)
+	for _, name := range []string{"web-1", "web-2"} {
+		node, err := place(sc, snap, spreadPod(name, "", requiredAntiAffinity(webAffinityTerm(corev1.LabelHostname))))
+		if err != nil {
+			t.Fatalf("place %s: %v", name, err)
+		}
+		used[node] = true
+	}
+	if len(used) != 2 {
+		t.Fatalf("required anti-affinity placed replicas on %v", used)
+	}
+	if node, err := place(sc, snap, spreadPod("web-3", "", requiredAntiAffinity(webAffinityTerm(corev1.LabelHostname)))); err == nil {
+		t.Fatalf("web-3 placed on %s despite required anti-affinity", node)
+	}
+
+	// preferred：BinPack 策略下也先分散，节点用完后仍然可以放置
+	sc = NewSchedulerController(storage.NewMemoryStore(), testLogger)
+	sc.SetPolicy(&k3v1.SchedulerPolicy{Strategy: k3v1.SchedulingStrategyBinPack})
+	snap = newSnapshot()
+	used = make(map[string]bool)
+	for _, name := range []string{"web-1", "web-2", "web-3"} {
+		node, err := place(sc, snap, spreadPod(name, "", preferredAntiAffinity(100, webAffinityTerm(corev1.LabelHostname))))
+		if err != nil {
+			t.Fatalf("place %s with preferred anti-affinity: %v", name, err)
+		}
+		used[node] = true
+	}
+	if len(used) != 2 {
+		t.Fatalf("preferred anti-affinity placed replicas on %v", used)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -15,9 +17,10 @@ import (
 
 // PodController 管理 Pod 资源的生命周期
 type PodController struct {
-	store  storage.Store
-	logger logprovider.Logger
-	stopCh chan struct{}
+	store   storage.Store
+	logger  logprovider.Logger
+	stopCh  chan struct{}
+	metrics *controllerMetrics
 }
 
 // NewPodController 创建 Pod 控制器
@@ -34,6 +37,10 @@ func (pc *PodController) Name() string {
 	return "PodController"
 }
 
+func (pc *PodController) setMetrics(m *controllerMetrics) {
+	pc.metrics = m
+}
+
 // Start 启动 Pod 控制器
 func (pc *PodController) Start(ctx context.Context) error {
 	pc.logger.Info("启动 Pod 控制器...")
@@ -51,6 +58,7 @@ func (pc *PodController) Start(ctx context.Context) error {
 	}
 
 	// 启动处理循环
+	pc.metrics.watch("pods", watchCh)
 	go pc.processPods(ctx, watchCh)
 
 	// 处理现有的 Pod
@@ -105,38 +113,48 @@ func (pc *PodController) processPods(ctx context.Context, watchCh <-chan storage
 		case event, ok := <-watchCh:
 			if !ok {
 				pc.logger.Warn("Pod watch 通道已关闭")
+				pc.metrics.watchClosed("pods")
 				return
 			}
 
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			start := time.Now()
+			var err error
 			switch event.Type {
 			case storage.EventAdded:
-				if pod, ok := event.Object.(*corev1.Pod); ok {
-					pc.logger.Infof("处理 Pod 创建事件: %s/%s", pod.Namespace, pod.Name)
-					if err := pc.handlePodCreated(ctx, pod); err != nil {
-						pc.logger.Error("处理 Pod 创建失败: ", pod.Name, " error: ", err.Error())
-					}
+				pc.logger.Infof("处理 Pod 创建事件: %s/%s", pod.Namespace, pod.Name)
+				if err = pc.handlePodCreated(ctx, pod); err != nil {
+					pc.logger.Error("处理 Pod 创建失败: ", pod.Name, " error: ", err.Error())
 				}
 			case storage.EventModified:
-				if pod, ok := event.Object.(*corev1.Pod); ok {
-					pc.logger.Debugf("处理 Pod 更新事件: %s/%s", pod.Namespace, pod.Name)
-					if err := pc.syncPod(ctx, pod); err != nil {
-						pc.logger.Error("同步 Pod 失败: ", pod.Name, " error: ", err.Error())
-					}
+				pc.logger.Debugf("处理 Pod 更新事件: %s/%s", pod.Namespace, pod.Name)
+				if err = pc.syncPod(ctx, pod); err != nil {
+					pc.logger.Error("同步 Pod 失败: ", pod.Name, " error: ", err.Error())
 				}
 			case storage.EventDeleted:
-				if pod, ok := event.Object.(*corev1.Pod); ok {
-					pc.logger.Infof("处理 Pod 删除事件: %s/%s", pod.Namespace, pod.Name)
-					if err := pc.handlePodDeleted(ctx, pod); err != nil {
-						pc.logger.Error("处理 Pod 删除失败: ", pod.Name, " error: ", err.Error())
-					}
+				pc.logger.Infof("处理 Pod 删除事件: %s/%s", pod.Namespace, pod.Name)
+				if err = pc.handlePodDeleted(ctx, pod); err != nil {
+					pc.logger.Error("处理 Pod 删除失败: ", pod.Name, " error: ", err.Error())
 				}
 			}
+			pc.metrics.observe(start, err)
 		}
 	}
 }
 
 // handlePodCreated 处理新创建的 Pod
 func (pc *PodController) handlePodCreated(ctx context.Context, pod *corev1.Pod) error {
+	// import 模式镜像的对象只读，状态以远端集群为准
+	if mirror.IsImported(pod) {
+		return nil
+	}
+	if isStalePod(pc.store, pod) {
+		return nil
+	}
+
 	// 初始化 Pod 状态
 	if pod.Status.Phase == "" {
 		pod.Status.Phase = corev1.PodPending
@@ -191,6 +209,8 @@ func (pc *PodController) handlePodCreated(ctx context.Context, pod *corev1.Pod)
 		}
 	}
 
+	pod.Status.ObservedGeneration = pod.Generation
+
 	// 更新 Pod
 	podGVK := schema.GroupVersionKind{
 		Group:   "",
@@ -208,12 +228,27 @@ func (pc *PodController) handlePodCreated(ctx context.Context, pod *corev1.Pod)
 
 // syncPod 同步 Pod 状态
 func (pc *PodController) syncPod(ctx context.Context, pod *corev1.Pod) error {
+	if mirror.IsImported(pod) {
+		return nil
+	}
+	if isStalePod(pc.store, pod) {
+		return nil
+	}
+	before := pod.Status.DeepCopy()
+
 	// 根据 Pod 的当前状态更新条件
 	pc.updatePodConditions(pod)
 
 	// 更新 Pod 阶段
 	pc.updatePodPhase(pod)
 
+	pod.Status.ObservedGeneration = pod.Generation
+
+	// 状态没有变化时不写回，否则每次写回产生的 MODIFIED 事件又会触发同步
+	if equality.Semantic.DeepEqual(before, &pod.Status) {
+		return nil
+	}
+
 	// 更新 Pod
 	podGVK := schema.GroupVersionKind{
 		Group:   "",
@@ -228,6 +263,17 @@ func (pc *PodController) syncPod(ctx context.Context, pod *corev1.Pod) error {
 	return nil
 }
 
+// isStalePod 判断事件中的 Pod 是否已过时（Store 中已有更新的版本或已被删除）：
+// 按过时的副本写回会覆盖其他控制器（调度、运行时）刚写入的状态，更新的版本会产生自己的事件
+func isStalePod(store storage.Store, pod *corev1.Pod) bool {
+	current, err := store.Get(podGVK, pod.Namespace, pod.Name)
+	if err != nil {
+		return true
+	}
+	latest, ok := current.(*corev1.Pod)
+	return !ok || latest.ResourceVersion != pod.ResourceVersion
+}
+
 // updatePodConditions 更新 Pod 条件
 func (pc *PodController) updatePodConditions(pod *corev1.Pod) {
 	now := metav1.Now()
@@ -292,7 +338,12 @@ func (pc *PodController) updatePodConditions(pod *corev1.Pod) {
 			pod.Status.Conditions = append(pod.Status.Conditions, *cond)
 			conditions[corev1.PodReady] = cond
 		}
-		if allContainersReady && pod.Status.Phase == corev1.PodRunning {
+		previous := cond.Status
+		if allContainersReady && pod.Status.Phase == corev1.PodRunning && !readinessGatesReady(pod) {
+			cond.Status = corev1.ConditionFalse
+			cond.Reason = "ReadinessGatesNotReady"
+			cond.Message = "Not all readiness gates are satisfied"
+		} else if allContainersReady && pod.Status.Phase == corev1.PodRunning {
 			cond.Status = corev1.ConditionTrue
 			cond.Reason = "ContainersReady"
 			cond.Message = "All containers are ready"
@@ -301,8 +352,28 @@ func (pc *PodController) updatePodConditions(pod *corev1.Pod) {
 			cond.Reason = "ContainersNotReady"
 			cond.Message = "Not all containers are ready"
 		}
-		cond.LastTransitionTime = now
+		// 只在状态变化时更新转换时间，否则每次同步都会产生新的写入
+		if cond.Status != previous {
+			cond.LastTransitionTime = now
+		}
+	}
+}
+
+// readinessGatesReady 判断 spec.readinessGates 声明的条件是否都存在且为 True（例如 discovery 写回的 consul.k3.io/healthy）
+func readinessGatesReady(pod *corev1.Pod) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		ready := false
+		for _, c := range pod.Status.Conditions {
+			if c.Type == gate.ConditionType {
+				ready = c.Status == corev1.ConditionTrue
+				break
+			}
+		}
+		if !ready {
+			return false
+		}
 	}
+	return true
 }
 
 // updatePodPhase 更新 Pod 阶段
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// preemptionCandidate 一个节点上的抢占方案
+type preemptionCandidate struct {
+	node    *corev1.Node
+	victims []*corev1.Pod
+}
+
+// disruptionBudget 一个 PodDisruptionBudget 还允许驱逐的 Pod 数
+type disruptionBudget struct {
+	namespace string
+	selector  labels.Selector
+	allowed   int
+}
+
+// preempt 在 candidates（满足 nodeSelector 但资源不足的就绪节点）中寻找可以通过驱逐低优先级 Pod 放下 pod 的节点：
+// 驱逐后会违反 PodDisruptionBudget 的 Pod、static Pod 与 import 镜像的 Pod 不会被驱逐。
+// 有多个节点可选时，优先选择正在退出的 Pod 退出后就能放下 pod（不需要再驱逐）的节点，其次是被驱逐的 Pod 中
+// 最高优先级最低、优先级之和最小、被驱逐数最少的节点。
+// 被驱逐的 Pod 经 terminatePod 优雅删除（所在节点执行 preStop、等待宽限期后再从 Store 删除）并记录 Preempted 事件，
+// pod 在它们退出、释放资源后的下一次调度中放到节点上。返回选中的方案；没有可抢占的节点时返回 nil
+func (sc *SchedulerController) preempt(pod *corev1.Pod, requests corev1.ResourceList, candidates []*corev1.Node,
+	podsByNode map[string][]*corev1.Pod, allPods []runtime.Object) (*preemptionCandidate, error) {
+	if pod.Spec.PreemptionPolicy != nil && *pod.Spec.PreemptionPolicy == corev1.PreemptNever {
+		return nil, nil
+	}
+
+	budgets, err := listDisruptionBudgets(sc.store, sc.logger, allPods)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *preemptionCandidate
+	for _, node := range candidates {
+		c := selectVictims(pod, requests, node, podsByNode[node.Name], budgets)
+		if c != nil && (best == nil || betterCandidate(c, best)) {
+			best = c
+		}
+	}
+	if best == nil || len(best.victims) == 0 {
+		return best, nil
+	}
+
+	for _, victim := range best.victims {
+		sc.logger.Infof("Pod %s/%s 抢占节点 %s 上的 Pod %s/%s（优先级 %d < %d）", pod.Namespace, pod.Name, best.node.Name,
+			victim.Namespace, victim.Name, apiserver.PodPriority(victim), apiserver.PodPriority(pod))
+		if err := terminatePod(sc.store, victim); err != nil {
+			return nil, fmt.Errorf("驱逐 Pod %s/%s 失败: %w", victim.Namespace, victim.Name, err)
+		}
+		message := fmt.Sprintf("Preempted by %s/%s on node %s", pod.Namespace, pod.Name, best.node.Name)
+		if err := RecordEvent(sc.store, victim, corev1.EventTypeWarning, "Preempted", message, schedulerComponent); err != nil {
+			sc.logger.Warnf("记录抢占事件失败: %v", err)
+		}
+	}
+	return best, nil
+}
+
+// selectVictims 计算在 node 上放下 pod 需要驱逐的最少的低优先级 Pod：先假设驱逐全部低优先级 Pod，
+// 再逐个尽量保留（放回后仍然放得下就保留）。正在退出的 Pod 视为已经离开：它们退出后就能放下时返回不驱逐任何 Pod 的方案。
+// 放不下又不能驱逐（违反 PodDisruptionBudget）时返回 nil
+func selectVictims(pod *corev1.Pod, requests corev1.ResourceList, node *corev1.Node, pods []*corev1.Pod,
+	budgets []*disruptionBudget) *preemptionCandidate {
+	priority := apiserver.PodPriority(pod)
+	var active, remaining, potential []*corev1.Pod
+	for _, p := range pods {
+		if p.DeletionTimestamp != nil {
+			continue
+		}
+		active = append(active, p)
+		if apiserver.PodPriority(p) < priority && !IsMirrorPod(p) && !mirror.IsImported(p) {
+			potential = append(potential, p)
+		} else {
+			remaining = append(remaining, p)
+		}
+	}
+	if _, fits := nodeFits(requests, node, active); fits {
+		return &preemptionCandidate{node: node}
+	}
+	if len(potential) == 0 {
+		return nil
+	}
+	if _, fits := nodeFits(requests, node, remaining); !fits {
+		return nil
+	}
+
+	// 各节点独立计算，使用预算的副本
+	allowed := make(map[*disruptionBudget]int, len(budgets))
+	for _, b := range budgets {
+		allowed[b] = b.allowed
+	}
+
+	// 驱逐后会违反 PodDisruptionBudget 的先尝试保留，其次优先级高的、相同优先级时先创建的先尝试保留
+	protected := make(map[*corev1.Pod]bool, len(potential))
+	for _, p := range potential {
+		protected[p] = !budgetsAllow(p, budgets, allowed)
+	}
+	sort.SliceStable(potential, func(i, j int) bool {
+		if protected[potential[i]] != protected[potential[j]] {
+			return protected[potential[i]]
+		}
+		pi, pj := apiserver.PodPriority(potential[i]), apiserver.PodPriority(potential[j])
+		if pi != pj {
+			return pi > pj
+		}
+		return potential[i].CreationTimestamp.Before(&potential[j].CreationTimestamp)
+	})
+	var victims []*corev1.Pod
+	for _, p := range potential {
+		if _, fits := nodeFits(requests, node, append(remaining, p)); fits {
+			remaining = append(remaining, p)
+			continue
+		}
+		if !consumeBudgets(p, budgets, allowed) {
+			return nil
+		}
+		victims = append(victims, p)
+	}
+	return &preemptionCandidate{node: node, victims: victims}
+}
+
+// budgetsAllow 判断按剩余预算驱逐 p 是否符合所有匹配的 PodDisruptionBudget。
+// 未就绪的 Pod 不计入 PodDisruptionBudget 的健康数，驱逐它总是允许且不消耗预算
+func budgetsAllow(p *corev1.Pod, budgets []*disruptionBudget, allowed map[*disruptionBudget]int) bool {
+	if !apiserver.PodHealthy(p) {
+		return true
+	}
+	for _, b := range budgets {
+		if b.matches(p) && allowed[b] <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// consumeBudgets 驱逐 p 符合所有匹配的 PodDisruptionBudget 时扣减预算并返回 true
+func consumeBudgets(p *corev1.Pod, budgets []*disruptionBudget, allowed map[*disruptionBudget]int) bool {
+	if !budgetsAllow(p, budgets, allowed) {
+		return false
+	}
+	if !apiserver.PodHealthy(p) {
+		return true
+	}
+	for _, b := range budgets {
+		if b.matches(p) {
+			allowed[b]--
+		}
+	}
+	return true
+}
+
+// matches 判断 PodDisruptionBudget 是否匹配 p
+func (b *disruptionBudget) matches(p *corev1.Pod) bool {
+	return b.namespace == p.Namespace && b.selector.Matches(labels.Set(p.Labels))
+}
+
+// betterCandidate 比较两个抢占方案：不需要驱逐的最好，其次被驱逐的最高优先级更低、优先级之和更小、被驱逐数更少的更好
+func betterCandidate(a, b *preemptionCandidate) bool {
+	if len(a.victims) == 0 || len(b.victims) == 0 {
+		return len(a.victims) < len(b.victims)
+	}
+	aMax, aSum := victimPriorities(a.victims)
+	bMax, bSum := victimPriorities(b.victims)
+	if aMax != bMax {
+		return aMax < bMax
+	}
+	if aSum != bSum {
+		return aSum < bSum
+	}
+	return len(a.victims) < len(b.victims)
+}
+
+// victimPriorities 返回被驱逐 Pod 的最高优先级与优先级之和
+func victimPriorities(victims []*corev1.Pod) (int32, int64) {
+	var highest int32
+	var sum int64
+	for i, p := range victims {
+		priority := apiserver.PodPriority(p)
+		if i == 0 || priority > highest {
+			highest = priority
+		}
+		sum += int64(priority)
+	}
+	return highest, sum
+}
+
+// listDisruptionBudgets 读取所有 PodDisruptionBudget 并按当前 Pod 计算允许驱逐的数量（抢占与 descheduler 共用，
+// 计算方式见 apiserver.DisruptionsAllowed）
+func listDisruptionBudgets(store storage.Store, logger logprovider.Logger, allPods []runtime.Object) ([]*disruptionBudget, error) {
+	objs, err := store.List(apiserver.PodDisruptionBudgetGVK, "")
+	if err != nil {
+		return nil, fmt.Errorf("获取 PodDisruptionBudget 列表失败: %w", err)
+	}
+	var budgets []*disruptionBudget
+	for _, obj := range objs {
+		pdb, ok := obj.(*policyv1.PodDisruptionBudget)
+		if !ok {
+			continue
+		}
+		selector, allowed, err := apiserver.DisruptionsAllowed(pdb, allPods)
+		if err != nil {
+			logger.Warnf("PodDisruptionBudget %s/%s 无效: %v", pdb.Namespace, pdb.Name, err)
+			continue
+		}
+		if selector == nil {
+			// policy/v1 中 selector 为空的 PodDisruptionBudget 不匹配任何 Pod
+			continue
+		}
+		budgets = append(budgets, &disruptionBudget{
+			namespace: pdb.Namespace,
+			selector:  selector,
+			allowed:   allowed,
+		})
+	}
+	return budgets, nil
+}
@@ -0,0 +1,396 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var testLogger = logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}
+
+// testNode 创建 Ready 的节点，cpu 为空时不限制 CPU
+func testNode(name, cpu string) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{corev1.LabelHostname: name}},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("110")},
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if cpu != "" {
+		node.Status.Allocatable[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	return node
+}
+
+// testPod 创建 default 中请求 cpu、优先级为 priority 的 Pod；created 为创建时间的偏移（秒），决定相同优先级时的顺序
+func testPod(name string, priority int32, cpu string, created int, opts ...func(*corev1.Pod)) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            map[string]string{"app": name},
+			CreationTimestamp: metav1.NewTime(time.Unix(1700000000+int64(created), 0)),
+		},
+		Spec: corev1.PodSpec{
+			Priority: &priority,
+			Containers: []corev1.Container{{
+				Name:      "app",
+				Image:     "nginx",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	for _, opt := range opts {
+		opt(pod)
+	}
+	return pod
+}
+
+func onNode(node string) func(*corev1.Pod) {
+	return func(p *corev1.Pod) { p.Spec.NodeName = node }
+}
+
+func withLabels(set map[string]string) func(*corev1.Pod) {
+	return func(p *corev1.Pod) { p.Labels = set }
+}
+
+// ready 设置 Ready 条件（PodDisruptionBudget 只计算 Running 且 Ready 的 Pod）
+func ready(p *corev1.Pod) {
+	p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+}
+
+func terminating(p *corev1.Pod) {
+	now := metav1.Now()
+	p.DeletionTimestamp = &now
+}
+
+func staticPod(p *corev1.Pod) {
+	p.Annotations = map[string]string{AnnotationConfigMirror: "hash"}
+}
+
+func importedPod(p *corev1.Pod) {
+	p.Annotations = map[string]string{mirror.AnnotationImportedFrom: "remote"}
+}
+
+func preemptNever(p *corev1.Pod) {
+	policy := corev1.PreemptNever
+	p.Spec.PreemptionPolicy = &policy
+}
+
+// testBudget 创建匹配 default 中 selector 的 PodDisruptionBudget
+func testBudget(selector string, allowed int) *disruptionBudget {
+	return &disruptionBudget{namespace: "default", selector: labels.SelectorFromSet(labels.Set{"pdb": selector}), allowed: allowed}
+}
+
+func podNames(pods []*corev1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, p := range pods {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestSelectVictims(t *testing.T) {
+	protected := withLabels(map[string]string{"pdb": "web"})
+	for _, tc := range []struct {
+		name    string
+		cpu     string
+		pods    []*corev1.Pod
+		budgets []*disruptionBudget
+		request string
+		// want 被驱逐的 Pod（按驱逐顺序）；nil 表示不能抢占该节点
+		want []string
+	}{
+		{
+			name: "minimal victim set",
+			cpu:  "4",
+			pods: []*corev1.Pod{
+				testPod("low-1", 0, "1", 1),
+				testPod("low-2", 0, "2", 2),
+				testPod("mid", 5, "1", 3),
+			},
+			request: "2",
+			want:    []string{"low-2"},
+		},
+		{
+			name: "higher priority pods are kept first",
+			cpu:  "2",
+			pods: []*corev1.Pod{
+				testPod("low", 0, "1", 1),
+				testPod("mid", 5, "1", 2),
+			},
+			request: "1",
+			want:    []string{"low"},
+		},
+		{
+			name: "equal priority is not preempted",
+			cpu:  "2",
+			pods: []*corev1.Pod{
+				testPod("equal", 10, "1", 1),
+				testPod("low", 0, "1", 2),
+			},
+			request: "2",
+		},
+		{
+			name: "static and imported pods are never victims",
+			cpu:  "2",
+			pods: []*corev1.Pod{
+				testPod("static", 0, "1", 1, staticPod),
+				testPod("imported", 0, "1", 2, importedPod),
+			},
+			request: "1",
+		},
+		{
+			name: "budget-protected pod is kept when possible",
+			cpu:  "2",
+			pods: []*corev1.Pod{
+				testPod("protected", 0, "1", 1, protected, ready),
+				testPod("free", 0, "1", 2, ready),
+			},
+			budgets: []*disruptionBudget{testBudget("web", 0)},
+			request: "1",
+			want:    []string{"free"},
+		},
+		{
+			name: "exhausted budget blocks the node",
+			cpu:  "2",
+			pods: []*corev1.Pod{
+				testPod("a", 0, "1", 1, protected, ready),
+				testPod("b", 0, "1", 2, protected, ready),
+			},
+			budgets: []*disruptionBudget{testBudget("web", 0)},
+			request: "1",
+		},
+		{
+			name: "budget covers one of two required victims",
+			cpu:  "3",
+			pods: []*corev1.Pod{
+				testPod("a", 0, "1", 1, protected, ready),
+				testPod("b", 0, "1", 2, protected, ready),
+				testPod("c", 0, "1", 3, protected, ready),
+			},
+			budgets: []*disruptionBudget{testBudget("web", 1)},
+			request: "2",
+		},
+		{
+			name: "budget covers both victims",
+			cpu:  "3",
+			pods: []*corev1.Pod{
+				testPod("a", 0, "1", 1, protected, ready),
+				testPod("b", 0, "1", 2, protected, ready),
+				testPod("c", 0, "1", 3, protected, ready),
+			},
+			budgets: []*disruptionBudget{testBudget("web", 2)},
+			request: "2",
+			want:    []string{"b", "c"},
+		},
+		{
+			name: "unready pods do not count against the budget",
+			cpu:  "2",
+			pods: []*corev1.Pod{
+				testPod("a", 0, "1", 1, protected),
+				testPod("b", 0, "1", 2, protected),
+			},
+			budgets: []*disruptionBudget{testBudget("web", 0)},
+			request: "2",
+			want:    []string{"a", "b"},
+		},
+		{
+			name: "wait for terminating pods instead of preempting",
+			cpu:  "2",
+			pods: []*corev1.Pod{
+				testPod("leaving", 0, "1", 1, terminating),
+				testPod("low", 0, "1", 2),
+			},
+			request: "1",
+			want:    []string{},
+		},
+		{
+			name: "terminating pods are not victims again",
+			cpu:  "3",
+			pods: []*corev1.Pod{
+				testPod("leaving", 0, "1", 1, terminating),
+				testPod("low-1", 0, "1", 2),
+				testPod("low-2", 0, "1", 3),
+			},
+			request: "3",
+			want:    []string{"low-1", "low-2"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node := testNode("node-1", tc.cpu)
+			preemptor := testPod("preemptor", 10, tc.request, 0)
+			before := make([]int, len(tc.budgets))
+			for i, b := range tc.budgets {
+				before[i] = b.allowed
+			}
+
+			got := selectVictims(preemptor, podRequests(preemptor), node, tc.pods, tc.budgets)
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("victims = %v, want node rejected", podNames(got.victims))
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("node rejected, want victims %v", tc.want)
+			}
+			if names := podNames(got.victims); strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("victims = %v, want %v", names, tc.want)
+			}
+			// 每个节点使用预算的副本，不影响其他节点的计算
+			for i, b := range tc.budgets {
+				if b.allowed != before[i] {
+					t.Fatalf("budget %d changed from %d to %d", i, before[i], b.allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestBetterCandidate(t *testing.T) {
+	candidate := func(priorities ...int32) *preemptionCandidate {
+		c := &preemptionCandidate{}
+		for i, p := range priorities {
+			c.victims = append(c.victims, testPod("victim", p, "1", i))
+		}
+		return c
+	}
+	for _, tc := range []struct {
+		name string
+		a, b *preemptionCandidate
+		want bool
+	}{
+		{"lower highest priority", candidate(1, 1, 1), candidate(2), true},
+		{"higher highest priority", candidate(2), candidate(1, 1, 1), false},
+		{"lower priority sum", candidate(2, 1), candidate(2, 2), true},
+		{"fewer victims", candidate(2, 0), candidate(2, 0, 0), true},
+		{"no victims beats any", candidate(), candidate(-5), true},
+		{"any loses to no victims", candidate(-5), candidate(), false},
+		{"equal", candidate(3, 1), candidate(1, 3), false},
+	} {
+		if got := betterCandidate(tc.a, tc.b); got != tc.want {
+			t.Errorf("%s: betterCandidate = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestConsumeBudgets(t *testing.T) {
+	web, all := testBudget("web", 1), testBudget("web", 2)
+	all.selector = labels.Everything()
+	other := &disruptionBudget{namespace: "other", selector: labels.Everything(), allowed: 0}
+	budgets := []*disruptionBudget{web, all, other}
+	allowed := map[*disruptionBudget]int{web: web.allowed, all: all.allowed, other: other.allowed}
+	protected := withLabels(map[string]string{"pdb": "web"})
+
+	// 依次驱逐，后面的用例看到前面扣减后的预算
+	for _, tc := range []struct {
+		name     string
+		pod      *corev1.Pod
+		want     bool
+		web, all int
+	}{
+		{name: "consumes every matching budget", pod: testPod("a", 0, "1", 1, protected, ready), want: true, web: 0, all: 1},
+		{name: "blocked by an exhausted budget", pod: testPod("b", 0, "1", 2, protected, ready), want: false, web: 0, all: 1},
+		{name: "unready pod is free", pod: testPod("c", 0, "1", 3, protected), want: true, web: 0, all: 1},
+		{name: "consumes only matching budgets", pod: testPod("d", 0, "1", 4, ready), want: true, web: 0, all: 0},
+		{name: "blocked once the catch-all budget is used", pod: testPod("e", 0, "1", 5, ready), want: false, web: 0, all: 0},
+	} {
+		if got := consumeBudgets(tc.pod, budgets, allowed); got != tc.want {
+			t.Errorf("%s: consumeBudgets = %v, want %v", tc.name, got, tc.want)
+		}
+		if allowed[web] != tc.web || allowed[all] != tc.all || allowed[other] != 0 {
+			t.Errorf("%s: allowed = web %d, all %d, other %d; want %d, %d, 0", tc.name, allowed[web], allowed[all], allowed[other], tc.web, tc.all)
+		}
+	}
+}
+
+func TestPreemptTerminatesVictims(t *testing.T) {
+	store := storage.NewMemoryStore()
+	if err := store.Create(nodeGVK, testNode("node-1", "2")); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*corev1.Pod{
+		testPod("low", 0, "1", 1, onNode("node-1")),
+		testPod("mid", 5, "1", 2, onNode("node-1")),
+	} {
+		if err := store.Create(podGVK, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sc := NewSchedulerController(store, testLogger)
+	snapshot := func() *schedulingSnapshot {
+		t.Helper()
+		snap, err := sc.takeSnapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return snap
+	}
+
+	// preemptionPolicy: Never 的 Pod 不抢占
+	node, preempted, err := sc.place(testPod("never", 10, "1", 3, preemptNever), snapshot())
+	if node != nil || preempted || err == nil {
+		t.Fatalf("PreemptNever: node %v, preempted %v, err %v", node, preempted, err)
+	}
+	getPod := func(name string) *corev1.Pod {
+		t.Helper()
+		obj, err := store.Get(podGVK, "default", name)
+		if err != nil {
+			t.Fatalf("get %s: %v", name, err)
+		}
+		return obj.(*corev1.Pod)
+	}
+	if getPod("low").DeletionTimestamp != nil {
+		t.Fatal("PreemptNever pod preempted low")
+	}
+
+	// 被驱逐的 Pod 只标记为正在退出，抢占者等待它退出后再绑定
+	preemptor := testPod("high", 10, "1", 4)
+	node, preempted, err = sc.place(preemptor, snapshot())
+	if node != nil || !preempted || err == nil {
+		t.Fatalf("preempt: node %v, preempted %v, err %v", node, preempted, err)
+	}
+	low := getPod("low")
+	if low.DeletionTimestamp == nil || low.DeletionGracePeriodSeconds == nil || *low.DeletionGracePeriodSeconds != 30 {
+		t.Fatalf("victim not terminating gracefully: %v %v", low.DeletionTimestamp, low.DeletionGracePeriodSeconds)
+	}
+	if getPod("mid").DeletionTimestamp != nil {
+		t.Fatal("mid preempted")
+	}
+	events, err := store.List(EventGVK, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].(*corev1.Event).Reason != "Preempted" || events[0].(*corev1.Event).InvolvedObject.Name != "low" {
+		t.Fatalf("events = %v", events)
+	}
+
+	// 正在退出时不再驱逐其他 Pod
+	if _, preempted, err = sc.place(preemptor, snapshot()); preempted || err == nil || !strings.Contains(err.Error(), "正在退出") {
+		t.Fatalf("while victim terminating: preempted %v, err %v", preempted, err)
+	}
+	if getPod("mid").DeletionTimestamp != nil {
+		t.Fatal("mid preempted while low is terminating")
+	}
+
+	// 运行时控制器停止并删除被驱逐的 Pod 后调度到节点上
+	if err := store.Delete(podGVK, "default", "low"); err != nil {
+		t.Fatal(err)
+	}
+	node, preempted, err = sc.place(preemptor, snapshot())
+	if err != nil || preempted || node == nil || node.Name != "node-1" {
+		t.Fatalf("after victim exited: node %v, preempted %v, err %v", node, preempted, err)
+	}
+}
@@ -2,12 +2,22 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/registry"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // ContainerRuntime 是容器运行时的接口
@@ -22,6 +32,23 @@ type ContainerRuntime interface {
 	StopContainer(ctx context.Context, pod *corev1.Pod) error
 	// GetContainerStatus 获取容器状态
 	GetContainerStatus(ctx context.Context, pod *corev1.Pod) (ContainerStatus, error)
+	// ContainerLogs 读取容器日志（opts.Follow 时持续输出，直到 ctx 取消或调用方 Close）
+	ContainerLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error)
+	// ListContainers 列出本机上由 k3 创建（带 io.k3.pod.uid 标签）的所有容器，包括已停止的
+	ListContainers(ctx context.Context) ([]ManagedContainer, error)
+	// RemoveContainer 停止并删除指定 ID 的容器
+	RemoveContainer(ctx context.Context, id string) error
+	// ListImages 列出本机镜像，并标记是否被容器（包括已停止的）使用
+	ListImages(ctx context.Context) ([]ImageInfo, error)
+	// PullImage 拉取镜像
+	PullImage(ctx context.Context, image string) error
+	// RemoveImage 删除指定 ID 的镜像
+	RemoveImage(ctx context.Context, id string) error
+	// ImageFilesystem 返回镜像所在的本地目录（用于计算磁盘使用率）
+	ImageFilesystem(ctx context.Context) (string, error)
+	// Platform 返回容器实际运行的平台（os 与 arch，取值同 GOOS/GOARCH）。
+	// 与 k3 进程所在主机不一定相同，例如 macOS 上的 Docker Desktop 返回 linux
+	Platform(ctx context.Context) (osName, arch string, err error)
 }
 
 // ContainerStatus 容器状态
@@ -29,17 +56,61 @@ type ContainerStatus struct {
 	Running bool
 	Status  string
 	Message string
+	// PodIP Pod sandbox 的 IP（没有 sandbox 或 host 网络时为空）
+	PodIP string
+	// PodIPs Pod sandbox 的所有 IP（双栈时 IPv4 与 IPv6 各一个，第一个与 PodIP 相同）；为空时只有 PodIP
+	PodIPs []string
+}
+
+// StatusPodIPs 把 PodIP/PodIPs 转换为 Pod 的 status.podIPs（没有 IP 时为空）
+func (s ContainerStatus) StatusPodIPs() []corev1.PodIP {
+	ips := s.PodIPs
+	if len(ips) == 0 && s.PodIP != "" {
+		ips = []string{s.PodIP}
+	}
+	var out []corev1.PodIP
+	for _, ip := range ips {
+		out = append(out, corev1.PodIP{IP: ip})
+	}
+	return out
+}
+
+// ImageInfo 运行时中的一个镜像
+type ImageInfo struct {
+	ID        string
+	RepoTags  []string
+	SizeBytes int64
+	Created   time.Time
+	// InUse 是否有容器（包括已停止的、不是 k3 创建的）引用该镜像
+	InUse bool
+}
+
+// ManagedContainer 运行时中由 k3 创建的一个容器及其所属 Pod
+type ManagedContainer struct {
+	ID           string
+	Name         string
+	Status       string
+	PodUID       types.UID
+	PodNamespace string
+	PodName      string
+	// ContainerName Pod 中的容器名（io.k3.container.name 标签，sandbox 为 POD）
+	ContainerName string
 }
 
 // RuntimeDetector 检测可用的容器运行时
 type RuntimeDetector struct {
-	logger logprovider.Logger
+	logger         logprovider.Logger
+	clusterID      string
+	registryMirror string
 }
 
-// NewRuntimeDetector 创建运行时检测器
-func NewRuntimeDetector(logger logprovider.Logger) *RuntimeDetector {
+// NewRuntimeDetector 创建运行时检测器；clusterID 非空时创建的容器带有 io.k3.cluster.id 标签，
+// registryMirror 非空时（host:port）Docker 通过该镜像缓存拉取镜像
+func NewRuntimeDetector(logger logprovider.Logger, clusterID, registryMirror string) *RuntimeDetector {
 	return &RuntimeDetector{
-		logger: logger,
+		logger:         logger,
+		clusterID:      clusterID,
+		registryMirror: registryMirror,
 	}
 }
 
@@ -68,20 +139,59 @@ func (rd *RuntimeDetector) DetectRuntime() (ContainerRuntime, error) {
 	return nil, fmt.Errorf("未找到可用的容器运行时")
 }
 
-// detectDocker 检测 Docker
+// RuntimeVersion 返回容器运行时的版本（Docker/Podman 为服务端版本），k3 check 使用
+func RuntimeVersion(ctx context.Context, rt ContainerRuntime) (string, error) {
+	var cmd *exec.Cmd
+	switch rt.Name() {
+	case "Docker":
+		cmd = exec.CommandContext(ctx, dockerBin, "version", "--format", "{{.Server.Version}}")
+	case "Podman":
+		cmd = exec.CommandContext(ctx, "podman", "version", "--format", "{{.Version}}")
+	case "Containerd":
+		path, err := lookupContainerd()
+		if err != nil {
+			return "", err
+		}
+		cmd = exec.CommandContext(ctx, path, "version")
+	case "CRI-O":
+		cmd = exec.CommandContext(ctx, "crictl", "version")
+	default:
+		return "", fmt.Errorf("未知的容器运行时: %s", rt.Name())
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("获取 %s 版本失败: %w", rt.Name(), err)
+	}
+	// ctr/crictl 输出多行，取最后一个 Version/RuntimeVersion 行（ctr 的服务端段在客户端之后，crictl 的 RuntimeVersion 在 Version 之后）
+	version := strings.TrimSpace(string(out))
+	for _, line := range strings.Split(version, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && (key == "Version" || key == "RuntimeVersion") {
+			version = strings.TrimSpace(value)
+		}
+	}
+	return version, nil
+}
+
+// dockerBin 是 docker 命令的路径（Windows 上可能是 Docker Desktop 安装目录下的 docker.exe）
+var dockerBin = "docker"
+
+// detectDocker 检测 Docker（Linux/macOS 的 Docker Engine，以及 Windows 的 Docker Desktop）
 func (rd *RuntimeDetector) detectDocker() (ContainerRuntime, error) {
 	// 检查 docker 命令是否存在
-	if _, err := exec.LookPath("docker"); err != nil {
+	path, err := lookupDocker()
+	if err != nil {
 		return nil, fmt.Errorf("docker 命令未找到")
 	}
+	dockerBin = path
 
 	// 检查 docker daemon 是否运行
-	cmd := exec.Command("docker", "info")
+	cmd := exec.Command(dockerBin, "info")
 	if err := cmd.Run(); err != nil {
 		return nil, fmt.Errorf("docker daemon 未运行: %w", err)
 	}
 
-	return NewDockerRuntime(rd.logger), nil
+	return NewDockerRuntime(rd.logger, rd.clusterID, rd.registryMirror), nil
 }
 
 // detectPodman 检测 Podman
@@ -100,7 +210,7 @@ func (rd *RuntimeDetector) detectPodman() (ContainerRuntime, error) {
 
 // detectContainerd 检测 Containerd
 func (rd *RuntimeDetector) detectContainerd() (ContainerRuntime, error) {
-	if _, err := exec.LookPath("ctr"); err != nil {
+	if _, err := lookupContainerd(); err != nil {
 		return nil, fmt.Errorf("ctr 命令未找到")
 	}
 
@@ -119,12 +229,18 @@ func (rd *RuntimeDetector) detectCRIO() (ContainerRuntime, error) {
 // DockerRuntime Docker 容器运行时实现
 type DockerRuntime struct {
 	logger logprovider.Logger
+	// clusterID 所属集群的 ID（配置 cluster.id），创建的容器与卷带有 io.k3.cluster.id 标签
+	clusterID string
+	// registryMirror 镜像拉取缓存地址（host:port，配置 registry_mirror），为空时直接从镜像仓库拉取
+	registryMirror string
 }
 
 // NewDockerRuntime 创建 Docker 运行时
-func NewDockerRuntime(logger logprovider.Logger) *DockerRuntime {
+func NewDockerRuntime(logger logprovider.Logger, clusterID, registryMirror string) *DockerRuntime {
 	return &DockerRuntime{
-		logger: logger,
+		logger:         logger,
+		clusterID:      clusterID,
+		registryMirror: registryMirror,
 	}
 }
 
@@ -135,34 +251,211 @@ func (dr *DockerRuntime) Name() string {
 
 // IsAvailable 检查 Docker 是否可用
 func (dr *DockerRuntime) IsAvailable() bool {
-	cmd := exec.Command("docker", "info")
+	cmd := exec.Command(dockerBin, "info")
 	return cmd.Run() == nil
 }
 
-// StartContainer 启动容器
+// StartContainer 启动 Pod 的容器（已在运行的容器会被跳过，重复调用是幂等的）。
+// 有 UID 的 Pod 先启动一个 pause 容器作为 sandbox 持有网络命名空间，业务容器通过 --network container:<sandbox>
+// 加入，共享 localhost 与 Pod IP，端口映射也发布在 sandbox 上；业务容器重启不影响 Pod IP。
+// 没有 UID 的 Pod（旧版本 bootstrap 的存储容器描述）只启动第一个容器，不创建 sandbox；存储静态 Pod 有 UID，与普通 Pod 相同。
 func (dr *DockerRuntime) StartContainer(ctx context.Context, pod *corev1.Pod) error {
 	if len(pod.Spec.Containers) == 0 {
 		return fmt.Errorf("Pod %s/%s 没有容器定义", pod.Namespace, pod.Name)
 	}
+	if pod.UID == "" {
+		if err := dr.runContainer(ctx, pod, &pod.Spec.Containers[0], ""); err != nil {
+			return err
+		}
+		return dr.postStart(ctx, pod, &pod.Spec.Containers[0], "")
+	}
 
-	container := pod.Spec.Containers[0]
-	containerName := fmt.Sprintf("k8s_%s_%s_%s", pod.Namespace, pod.Name, container.Name)
+	// 同名 Pod 被删除后重建时 UID 不同，上一个实例遗留的容器（进程崩溃时没有停止）不能复用，先删除
+	if err := dr.removeStaleContainers(ctx, pod); err != nil {
+		return err
+	}
+	sandboxID, err := dr.ensureSandbox(ctx, pod)
+	if err != nil {
+		return err
+	}
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		existing, err := dr.findContainers(ctx, pod, container.Name)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 && isDockerRunning(existing[0].Status) {
+			continue
+		}
+		// 已退出的容器先删除再重建，避免 --name 冲突
+		for _, c := range existing {
+			_ = dr.RemoveContainer(ctx, c.ID)
+		}
+		if err := dr.runContainer(ctx, pod, container, sandboxID); err != nil {
+			return err
+		}
+		if err := dr.postStart(ctx, pod, container, sandboxID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postStart 执行容器的 lifecycle.postStart 钩子。钩子失败时与 kubelet 一样结束并删除容器，
+// 返回 LifecycleHookError，由运行时控制器记录事件并在退避后重新创建
+func (dr *DockerRuntime) postStart(ctx context.Context, pod *corev1.Pod, container *corev1.Container, sandboxID string) error {
+	if container.Lifecycle == nil || container.Lifecycle.PostStart == nil {
+		return nil
+	}
+	podIP := ""
+	if sandboxID != "" {
+		podIP = dr.containerIP(ctx, sandboxID)
+	}
+	dr.logger.Infof("执行 Pod %s/%s 容器 %s 的 postStart 钩子", pod.Namespace, pod.Name, container.Name)
+	err := runLifecycleHandler(ctx, dr, pod, container, container.Lifecycle.PostStart, podIP)
+	if err == nil {
+		return nil
+	}
+	if existing, findErr := dr.findContainers(ctx, pod, container.Name); findErr == nil {
+		for _, c := range existing {
+			_ = dr.removeContainer(ctx, c.ID, 0)
+		}
+	}
+	return &LifecycleHookError{Hook: HookPostStart, Container: container.Name, Err: err}
+}
+
+// ExecInContainer 在 Pod 的指定容器中执行命令（docker exec），容器未运行时返回错误
+func (dr *DockerRuntime) ExecInContainer(ctx context.Context, pod *corev1.Pod, container string, command []string) ([]byte, error) {
+	existing, err := dr.findContainers(ctx, pod, container)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 || !isDockerRunning(existing[0].Status) {
+		return nil, fmt.Errorf("容器 %s 未运行", container)
+	}
+	args := append([]string{"exec", existing[0].ID}, command...)
+	return exec.CommandContext(ctx, dockerBin, args...).CombinedOutput()
+}
+
+// sandboxAction 是 ensureSandbox 对 Pod 现有 sandbox 的处理方式
+type sandboxAction int
+
+const (
+	// sandboxReuse 复用运行中的 sandbox
+	sandboxReuse sandboxAction = iota
+	// sandboxCreate 没有 sandbox，直接创建
+	sandboxCreate
+	// sandboxRecreate sandbox 已退出，网络命名空间已失效：删除 Pod 的全部容器后重新创建
+	sandboxRecreate
+)
+
+// planSandbox 根据 Pod 现有的 sandbox 容器决定处理方式，复用时同时返回运行中的 sandbox 的 ID
+func planSandbox(existing []ManagedContainer) (sandboxAction, string) {
+	for _, c := range existing {
+		if isDockerRunning(c.Status) {
+			return sandboxReuse, c.ID
+		}
+	}
+	if len(existing) > 0 {
+		return sandboxRecreate, ""
+	}
+	return sandboxCreate, ""
+}
+
+// sandboxRunArgs 返回创建 sandbox 的 docker run 参数（名称、集群标签、镜像拉取参数与镜像除外）：
+// Pod 标签与 --restart always；hostNetwork 时使用 host 网络，否则设置主机名并把所有容器的端口发布在 sandbox 上
+func sandboxRunArgs(pod *corev1.Pod) []string {
+	args := []string{
+		"--label", LabelPodUID + "=" + string(pod.UID),
+		"--label", LabelPodNamespace + "=" + pod.Namespace,
+		"--label", LabelPodName + "=" + pod.Name,
+		"--label", LabelContainerName + "=" + sandboxContainerName,
+		"--restart", "always",
+	}
+	if pod.Spec.HostNetwork {
+		return append(args, "--network", "host")
+	}
+	args = append(args, "--hostname", pod.Name)
+	for _, container := range pod.Spec.Containers {
+		args = append(args, portMappings(container.Ports)...)
+	}
+	return args
+}
+
+// ensureSandbox 确保 Pod 的 pause 容器在运行并返回其 ID（处理方式见 planSandbox）。
+// sandbox 不在运行时，网络命名空间已失效，会删除 Pod 的全部容器后重新创建 sandbox。
+func (dr *DockerRuntime) ensureSandbox(ctx context.Context, pod *corev1.Pod) (string, error) {
+	existing, err := dr.findContainers(ctx, pod, sandboxContainerName)
+	if err != nil {
+		return "", err
+	}
+	switch action, id := planSandbox(existing); action {
+	case sandboxReuse:
+		return id, nil
+	case sandboxRecreate:
+		dr.logger.Infof("Pod %s/%s 的 sandbox 已退出，重建 Pod 的全部容器", pod.Namespace, pod.Name)
+		all, err := dr.findContainers(ctx, pod, "")
+		if err != nil {
+			return "", err
+		}
+		for _, c := range all {
+			_ = dr.RemoveContainer(ctx, c.ID)
+		}
+	}
+
+	name := dockerContainerName(pod, sandboxContainerName)
+	args := append([]string{"run", "-d", "--name", name}, sandboxRunArgs(pod)...)
+	args = append(args, dr.clusterLabelArgs()...)
+	args = append(args, dr.pullArgs(ctx, PauseImage, corev1.PullIfNotPresent, "")...)
+	args = append(args, PauseImage)
+
+	dr.logger.Infof("启动 Pod sandbox: %s, 命令: docker %s", name, strings.Join(args, " "))
+	output, err := exec.CommandContext(ctx, dockerBin, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("启动 sandbox 失败: %w, 输出: %s", err, string(output))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// runContainer 用 docker run 启动一个容器；sandboxID 非空时加入该 sandbox 的网络命名空间
+func (dr *DockerRuntime) runContainer(ctx context.Context, pod *corev1.Pod, container *corev1.Container, sandboxID string) error {
+	containerName := dockerContainerName(pod, container.Name)
 
 	// 构建 docker run 命令
+	options, err := dockerRunOptions(pod, container)
+	if err != nil {
+		return err
+	}
 	args := []string{"run", "-d", "--name", containerName}
+	args = append(args, options...)
+	args = append(args, dr.clusterLabelArgs()...)
 
-	// 添加环境变量
-	for _, env := range container.Env {
-		args = append(args, "-e", fmt.Sprintf("%s=%s", env.Name, env.Value))
+	// Downward API 需要 Pod IP（由 sandbox 持有）
+	podIP := ""
+	if sandboxID != "" {
+		podIP = dr.containerIP(ctx, sandboxID)
 	}
 
-	// 添加端口映射
-	for _, port := range container.Ports {
-		if port.HostPort != 0 {
-			args = append(args, "-p", fmt.Sprintf("%d:%d", port.HostPort, port.ContainerPort))
-		} else {
-			args = append(args, "-p", fmt.Sprintf("%d", port.ContainerPort))
-		}
+	// 添加环境变量（解析 valueFrom.fieldRef）
+	env, err := containerEnv(pod, container, podIP)
+	if err != nil {
+		return err
+	}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+
+	mounts, err := dr.volumeMountArgs(ctx, pod, container, podIP)
+	if err != nil {
+		return err
+	}
+	args = append(args, mounts...)
+
+	// 加入 sandbox 时网络与端口映射由 sandbox 提供
+	if sandboxID != "" {
+		args = append(args, "--network", "container:"+sandboxID)
+	} else {
+		args = append(args, portMappings(container.Ports)...)
 	}
 
 	// 添加镜像
@@ -171,6 +464,13 @@ func (dr *DockerRuntime) StartContainer(ctx context.Context, pod *corev1.Pod) er
 		return fmt.Errorf("容器镜像未指定")
 	}
 
+	// 镜像与节点平台不一致时显式指定 --platform（例如在 arm64 节点上通过 binfmt 运行只有 amd64 版本的镜像）
+	platform := dr.containerPlatform(ctx, pod, image)
+	if platform != "" {
+		args = append(args, "--platform", platform)
+	}
+	args = append(args, dr.pullArgs(ctx, image, container.ImagePullPolicy, platform)...)
+
 	args = append(args, image)
 
 	// 添加命令和参数
@@ -183,9 +483,13 @@ func (dr *DockerRuntime) StartContainer(ctx context.Context, pod *corev1.Pod) er
 
 	dr.logger.Infof("启动 Docker 容器: %s, 命令: docker %s", containerName, strings.Join(args, " "))
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd := exec.CommandContext(ctx, dockerBin, args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		if strings.Contains(string(output), "no matching manifest") {
+			return fmt.Errorf("镜像 %s 没有适用于本节点平台的版本，可通过 nodeSelector %s 把 Pod 调度到匹配的节点: %w, 输出: %s",
+				image, corev1.LabelArchStable, err, string(output))
+		}
 		return fmt.Errorf("启动容器失败: %w, 输出: %s", err, string(output))
 	}
 
@@ -193,56 +497,866 @@ func (dr *DockerRuntime) StartContainer(ctx context.Context, pod *corev1.Pod) er
 	return nil
 }
 
-// StopContainer 停止容器
+// volumeMountArgs 把 volumeMounts 转换为 --mount 参数（不用 -v，避免 Windows 盘符中的冒号被误解析）。
+// 支持 hostPath（bind 挂载，路径按平台转换）、emptyDir（Pod 级的 docker 卷，Pod 内容器共享，StopContainer 时删除）
+// 与 downwardAPI（文件写入宿主机上 Pod 的卷目录后只读 bind 挂载，元数据变化时由 RefreshDownwardAPI 更新），
+// 其他卷类型忽略并告警。
+func (dr *DockerRuntime) volumeMountArgs(ctx context.Context, pod *corev1.Pod, container *corev1.Container, podIP string) ([]string, error) {
+	volumes := make(map[string]*corev1.Volume, len(pod.Spec.Volumes))
+	for i := range pod.Spec.Volumes {
+		volumes[pod.Spec.Volumes[i].Name] = &pod.Spec.Volumes[i]
+	}
+
+	var args []string
+	for _, m := range container.VolumeMounts {
+		v, ok := volumes[m.Name]
+		if !ok {
+			return nil, fmt.Errorf("容器 %s 挂载的卷 %s 不存在", container.Name, m.Name)
+		}
+
+		var mount string
+		switch {
+		case v.HostPath != nil:
+			source := hostPathForDocker(v.HostPath.Path)
+			if m.SubPath != "" {
+				source = filepath.Join(source, m.SubPath)
+			}
+			if v.HostPath.Type != nil && *v.HostPath.Type == corev1.HostPathDirectoryOrCreate {
+				if err := os.MkdirAll(source, 0o755); err != nil {
+					return nil, fmt.Errorf("创建 hostPath 目录 %s 失败: %w", source, err)
+				}
+			}
+			mount = "type=bind,source=" + source
+		case v.EmptyDir != nil:
+			if m.SubPath != "" {
+				dr.logger.Warnf("emptyDir 卷 %s 不支持 subPath，挂载整个卷", m.Name)
+			}
+			name, err := dr.ensurePodVolume(ctx, pod, v.Name)
+			if err != nil {
+				return nil, err
+			}
+			mount = "type=volume,source=" + name
+		case v.DownwardAPI != nil:
+			dir, err := writePodDownwardAPIVolume(pod, v, podIP)
+			if err != nil {
+				return nil, err
+			}
+			source := dir
+			if m.SubPath != "" {
+				source = filepath.Join(source, m.SubPath)
+			}
+			// 与 kubelet 一样，downwardAPI 卷总是只读
+			mount = "type=bind,source=" + hostPathForDocker(source) + ",target=" + m.MountPath + ",readonly"
+			args = append(args, "--mount", mount)
+			continue
+		default:
+			dr.logger.Warnf("Docker 运行时暂不支持卷 %s 的类型，跳过挂载", m.Name)
+			continue
+		}
+		mount += ",target=" + m.MountPath
+		if m.ReadOnly {
+			mount += ",readonly"
+		}
+		args = append(args, "--mount", mount)
+	}
+	return args, nil
+}
+
+// writePodDownwardAPIVolume 生成 downwardAPI 卷的文件，返回宿主机上的卷目录
+func writePodDownwardAPIVolume(pod *corev1.Pod, v *corev1.Volume, podIP string) (string, error) {
+	files, err := downwardAPIFiles(pod, v.DownwardAPI, podIP)
+	if err != nil {
+		return "", fmt.Errorf("卷 %s: %w", v.Name, err)
+	}
+	dir := downwardAPIVolumeDir(pod, v.Name)
+	if _, err := writeDownwardAPIVolume(dir, files); err != nil {
+		return "", fmt.Errorf("写入 downwardAPI 卷 %s 失败: %w", v.Name, err)
+	}
+	return dir, nil
+}
+
+// RefreshDownwardAPI 按 Pod 当前的标签、注解与 Pod IP 更新已挂载的 downwardAPI 卷（容器还没有挂载过的卷跳过）
+func (dr *DockerRuntime) RefreshDownwardAPI(ctx context.Context, pod *corev1.Pod) error {
+	podIP := pod.Status.PodIP
+	if sandbox, err := dr.findContainers(ctx, pod, sandboxContainerName); err == nil && len(sandbox) > 0 {
+		if ip := dr.containerIP(ctx, sandbox[0].ID); ip != "" {
+			podIP = ip
+		}
+	}
+	for i := range pod.Spec.Volumes {
+		v := &pod.Spec.Volumes[i]
+		if v.DownwardAPI == nil {
+			continue
+		}
+		dir := downwardAPIVolumeDir(pod, v.Name)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		files, err := downwardAPIFiles(pod, v.DownwardAPI, podIP)
+		if err != nil {
+			return fmt.Errorf("卷 %s: %w", v.Name, err)
+		}
+		changed, err := writeDownwardAPIVolume(dir, files)
+		if err != nil {
+			return fmt.Errorf("更新 downwardAPI 卷 %s 失败: %w", v.Name, err)
+		}
+		if changed {
+			dr.logger.Infof("已更新 Pod %s/%s 的 downwardAPI 卷 %s", pod.Namespace, pod.Name, v.Name)
+		}
+	}
+	return nil
+}
+
+// ensurePodVolume 创建（已存在时复用）Pod 的 emptyDir 对应的 docker 卷，卷带有与容器相同的 Pod 标签
+func (dr *DockerRuntime) ensurePodVolume(ctx context.Context, pod *corev1.Pod, volume string) (string, error) {
+	name := dockerContainerName(pod, volume)
+	args := []string{"volume", "create",
+		"--label", LabelPodUID + "=" + string(pod.UID),
+		"--label", LabelPodNamespace + "=" + pod.Namespace,
+		"--label", LabelPodName + "=" + pod.Name,
+	}
+	args = append(args, dr.clusterLabelArgs()...)
+	output, err := exec.CommandContext(ctx, dockerBin, append(args, name)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("创建卷 %s 失败: %w, 输出: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return name, nil
+}
+
+// removePodVolumes 删除 Pod 的 emptyDir 卷
+func (dr *DockerRuntime) removePodVolumes(ctx context.Context, pod *corev1.Pod) {
+	args := []string{"volume", "ls", "-q"}
+	for _, f := range podLabelFilters(pod) {
+		args = append(args, "--filter", f)
+	}
+	output, err := exec.CommandContext(ctx, dockerBin, args...).Output()
+	if err != nil {
+		dr.logger.Warnf("查询 Pod %s/%s 的卷失败: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+	for _, name := range uniqueLines(string(output)) {
+		if err := exec.CommandContext(ctx, dockerBin, "volume", "rm", name).Run(); err != nil {
+			dr.logger.Warnf("删除卷 %s 失败: %v", name, err)
+		}
+	}
+}
+
+// portMappings 把容器端口转换为 -p 参数
+func portMappings(ports []corev1.ContainerPort) []string {
+	var args []string
+	for _, port := range ports {
+		if port.HostPort != 0 {
+			args = append(args, "-p", fmt.Sprintf("%d:%d", port.HostPort, port.ContainerPort))
+		} else {
+			args = append(args, "-p", fmt.Sprintf("%d", port.ContainerPort))
+		}
+	}
+	return args
+}
+
+// isDockerRunning 根据 docker ps 的 Status 列判断容器是否在运行（如 "Up 3 minutes"）
+func isDockerRunning(status string) bool {
+	return strings.HasPrefix(status, "Up")
+}
+
+// 容器上的 Pod 标签，用于按 Pod 查找容器（不依赖容器名）
+const (
+	LabelPodUID        = "io.k3.pod.uid"
+	LabelPodNamespace  = "io.k3.pod.namespace"
+	LabelPodName       = "io.k3.pod.name"
+	LabelContainerName = "io.k3.container.name"
+	// LabelClusterID 容器所属集群（配置 cluster.id），k3 cluster clear 按它只清理指定集群的容器
+	LabelClusterID = "io.k3.cluster.id"
+)
+
+// clusterLabelArgs 返回 io.k3.cluster.id 标签参数（没有配置集群 ID 时为空）
+func (dr *DockerRuntime) clusterLabelArgs() []string {
+	if dr.clusterID == "" {
+		return nil
+	}
+	return []string{"--label", LabelClusterID + "=" + dr.clusterID}
+}
+
+// sandboxContainerName 是 pause 容器在 io.k3.container.name 标签中的名字
+const sandboxContainerName = "POD"
+
+// PauseImage 是 Pod sandbox 使用的镜像（k3 bundle create 会把它打包进离线包）
+const PauseImage = "registry.k8s.io/pause:3.10"
+
+// dockerContainerName 返回容器名：k8s_{namespace}_{pod}_{container}，Pod 有 UID 时追加 UID 前 8 位，
+// 避免 namespace/pod 名中含下划线时拼出相同的名字。容器名只用于展示，查找一律按标签进行。
+func dockerContainerName(pod *corev1.Pod, container string) string {
+	name := fmt.Sprintf("k8s_%s_%s_%s", pod.Namespace, pod.Name, container)
+	if uid := string(pod.UID); uid != "" {
+		name += "_" + uid[:min(8, len(uid))]
+	}
+	return name
+}
+
+// findContainers 按 io.k3.* 标签查找 Pod 的容器（container 为空时返回 Pod 的所有容器）。
+// Pod 有 UID 时只按 UID 匹配：同名 Pod 重建后 UID 不同，不会关联到上一个实例的容器。
+// 没有 UID 时按 namespace/name 匹配（如旧版本 bootstrap 拉起的存储容器），找不到带标签的容器时
+// 回退到旧的 k8s_{namespace}_{pod}_{container} 名称精确匹配。
+func (dr *DockerRuntime) findContainers(ctx context.Context, pod *corev1.Pod, container string) ([]ManagedContainer, error) {
+	filters := podLabelFilters(pod)
+	if container != "" {
+		filters = append(filters, "label="+LabelContainerName+"="+container)
+	}
+	found, err := dockerPS(ctx, filters...)
+	if err != nil || len(found) > 0 || pod.UID != "" {
+		return found, err
+	}
+
+	legacy, err := dr.legacyContainers(ctx, pod, container)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range legacy {
+		dr.logger.Debugf("容器 %s 没有 io.k3.* 标签，按旧的名称规则匹配", m.Name)
+	}
+	return legacy, nil
+}
+
+// legacyContainers 按旧的 k8s_{namespace}_{pod}_{container} 名称精确匹配没有 io.k3.* 标签的容器（container 为空时匹配 Pod 的所有容器）
+func (dr *DockerRuntime) legacyContainers(ctx context.Context, pod *corev1.Pod, container string) ([]ManagedContainer, error) {
+	var found []ManagedContainer
+	for _, c := range pod.Spec.Containers {
+		if container != "" && c.Name != container {
+			continue
+		}
+		legacy := fmt.Sprintf("k8s_%s_%s_%s", pod.Namespace, pod.Name, c.Name)
+		matched, err := dockerPS(ctx, fmt.Sprintf("name=^%s$", legacy))
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, matched...)
+	}
+	return found, nil
+}
+
+// removeStaleContainers 删除与 Pod 同名（namespace/name）但不属于当前实例的容器：
+// UID 标签不同的是同名 Pod 上一个实例的容器；没有标签、按旧名称规则命名的容器无法确认属于哪个实例，同样删除后重新创建
+func (dr *DockerRuntime) removeStaleContainers(ctx context.Context, pod *corev1.Pod) error {
+	sameName, err := dockerPS(ctx, "label="+LabelPodNamespace+"="+pod.Namespace, "label="+LabelPodName+"="+pod.Name)
+	if err != nil {
+		return err
+	}
+	legacy, err := dr.legacyContainers(ctx, pod, "")
+	if err != nil {
+		return err
+	}
+	for _, c := range append(sameName, legacy...) {
+		if c.PodUID == pod.UID {
+			continue
+		}
+		dr.logger.Infof("删除 Pod %s/%s 上一个实例的容器 %s（UID %q，当前 UID %s）", pod.Namespace, pod.Name, c.Name, c.PodUID, pod.UID)
+		// 删除失败（例如已被孤儿容器回收删除）不影响启动新实例，遗留的容器由 ContainerGC 继续回收
+		if err := dr.RemoveContainer(ctx, c.ID); err != nil {
+			dr.logger.Warnf("删除上一个实例的容器 %s 失败: %v", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// podLabelFilters 返回按 Pod 过滤容器/卷的 docker --filter 条件：有 UID 时按 UID，否则按 namespace/name
+func podLabelFilters(pod *corev1.Pod) []string {
+	if pod.UID != "" {
+		return []string{"label=" + LabelPodUID + "=" + string(pod.UID)}
+	}
+	return []string{"label=" + LabelPodNamespace + "=" + pod.Namespace, "label=" + LabelPodName + "=" + pod.Name}
+}
+
+// dockerPSFormat 是 dockerPS 使用的输出格式：ID、名称、状态以及 Pod 与容器名标签，以 tab 分隔
+const dockerPSFormat = `{{.ID}}\t{{.Names}}\t{{.Status}}\t{{.Label "` + LabelPodUID + `"}}\t{{.Label "` + LabelPodNamespace + `"}}\t{{.Label "` + LabelPodName + `"}}\t{{.Label "` + LabelContainerName + `"}}`
+
+// dockerPS 执行 docker ps -a 并按 filters 过滤（多个 label 过滤条件之间为“与”）
+func dockerPS(ctx context.Context, filters ...string) ([]ManagedContainer, error) {
+	args := []string{"ps", "-a", "--no-trunc", "--format", dockerPSFormat}
+	for _, f := range filters {
+		args = append(args, "--filter", f)
+	}
+	output, err := exec.CommandContext(ctx, dockerBin, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("查询容器失败: %w", err)
+	}
+
+	var containers []ManagedContainer
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\t", 7)
+		if len(fields) != 7 {
+			continue
+		}
+		containers = append(containers, ManagedContainer{
+			ID:            fields[0],
+			Name:          fields[1],
+			Status:        fields[2],
+			PodUID:        types.UID(fields[3]),
+			PodNamespace:  fields[4],
+			PodName:       fields[5],
+			ContainerName: fields[6],
+		})
+	}
+	return containers, nil
+}
+
+// dockerRunOptions 把 Pod/容器的 spec 转换为 docker run 参数：
+// 标签、重启策略、资源限制、工作目录以及 securityContext 中的 runAsUser/runAsGroup/readOnlyRootFilesystem（镜像拉取策略见 pullArgs）。
+// docker 的 --user 不能只指定组，只设置 runAsGroup 时返回错误（与 kubelet 的 dockershim 相同）
+func dockerRunOptions(pod *corev1.Pod, container *corev1.Container) ([]string, error) {
+	args := []string{
+		"--label", LabelPodUID + "=" + string(pod.UID),
+		"--label", LabelPodNamespace + "=" + pod.Namespace,
+		"--label", LabelPodName + "=" + pod.Name,
+		"--label", LabelContainerName + "=" + container.Name,
+		"--restart", dockerRestartPolicy(pod.Spec.RestartPolicy),
+	}
+
+	// 资源限制：memory 按字节，cpu 按核数（支持 500m 这类小数核）
+	if mem, ok := container.Resources.Limits[corev1.ResourceMemory]; ok && !mem.IsZero() {
+		args = append(args, "--memory", strconv.FormatInt(mem.Value(), 10))
+	}
+	if cpu, ok := container.Resources.Limits[corev1.ResourceCPU]; ok && !cpu.IsZero() {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(cpu.MilliValue())/1000, 'f', -1, 64))
+	}
+
+	if container.WorkingDir != "" {
+		args = append(args, "--workdir", container.WorkingDir)
+	}
+
+	// 容器级 securityContext 优先于 Pod 级（与 Kubernetes 一致）
+	var runAsUser, runAsGroup *int64
+	if psc := pod.Spec.SecurityContext; psc != nil {
+		runAsUser, runAsGroup = psc.RunAsUser, psc.RunAsGroup
+	}
+	if sc := container.SecurityContext; sc != nil {
+		if sc.RunAsUser != nil {
+			runAsUser = sc.RunAsUser
+		}
+		if sc.RunAsGroup != nil {
+			runAsGroup = sc.RunAsGroup
+		}
+		if sc.ReadOnlyRootFilesystem != nil && *sc.ReadOnlyRootFilesystem {
+			args = append(args, "--read-only")
+		}
+	}
+	if runAsGroup != nil && runAsUser == nil {
+		return nil, fmt.Errorf("容器 %s: securityContext.runAsGroup 需要同时设置 runAsUser", container.Name)
+	}
+	if runAsUser != nil {
+		user := strconv.FormatInt(*runAsUser, 10)
+		if runAsGroup != nil {
+			user += ":" + strconv.FormatInt(*runAsGroup, 10)
+		}
+		args = append(args, "--user", user)
+	}
+
+	return args, nil
+}
+
+// dockerRestartPolicy 将 Pod 的 restartPolicy 映射为 docker 的 --restart 取值（未设置时按 Always 处理）
+func dockerRestartPolicy(policy corev1.RestartPolicy) string {
+	switch policy {
+	case corev1.RestartPolicyNever:
+		return "no"
+	case corev1.RestartPolicyOnFailure:
+		return "on-failure"
+	default:
+		return "always"
+	}
+}
+
+// StopContainer 停止并删除 Pod 的所有容器（按标签查找，兼容旧的按名称创建的容器）。
+// 业务容器并发停止：先执行 lifecycle.preStop，再在 terminationGracePeriodSeconds 剩余的时间内等待进程退出
+// （docker stop -t，剩余不足 2 秒时按 2 秒），超时后强制结束；最后删除 sandbox 与 Pod 的卷。
+// preStop 失败不影响停止，以 LifecycleHookError（多个时为 errors.Join）返回
 func (dr *DockerRuntime) StopContainer(ctx context.Context, pod *corev1.Pod) error {
 	if len(pod.Spec.Containers) == 0 {
 		return fmt.Errorf("Pod %s/%s 没有容器定义", pod.Namespace, pod.Name)
 	}
 
-	container := pod.Spec.Containers[0]
-	containerName := fmt.Sprintf("k8s_%s_%s_%s", pod.Namespace, pod.Name, container.Name)
+	deadline := time.Now().Add(terminationGracePeriod(pod))
+	podIP := ""
+	if sandbox, err := dr.findContainers(ctx, pod, sandboxContainerName); err == nil && len(sandbox) > 0 {
+		podIP = dr.containerIP(ctx, sandbox[0].ID)
+	}
 
-	dr.logger.Infof("停止 Docker 容器: %s", containerName)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		hookErrs []error
+	)
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		existing, err := dr.findContainers(ctx, pod, container.Name)
+		if err != nil {
+			return err
+		}
+		for _, c := range existing {
+			wg.Add(1)
+			go func(c ManagedContainer) {
+				defer wg.Done()
+				if isDockerRunning(c.Status) && container.Lifecycle != nil && container.Lifecycle.PreStop != nil {
+					dr.logger.Infof("执行 Pod %s/%s 容器 %s 的 preStop 钩子", pod.Namespace, pod.Name, container.Name)
+					hookCtx, cancel := context.WithDeadline(ctx, deadline)
+					err := runLifecycleHandler(hookCtx, dr, pod, container, container.Lifecycle.PreStop, podIP)
+					cancel()
+					if err != nil {
+						mu.Lock()
+						hookErrs = append(hookErrs, &LifecycleHookError{Hook: HookPreStop, Container: container.Name, Err: err})
+						mu.Unlock()
+					}
+				}
+				dr.logger.Infof("停止 Docker 容器: %s (%s)", c.Name, c.ID)
+				_ = dr.removeContainer(ctx, c.ID, max(time.Until(deadline), minStopGracePeriod))
+			}(c)
+		}
+	}
+	wg.Wait()
+
+	// sandbox 以及不在 spec 中的容器
+	rest, err := dr.findContainers(ctx, pod, "")
+	if err != nil {
+		return err
+	}
+	for _, c := range rest {
+		dr.logger.Infof("停止 Docker 容器: %s (%s)", c.Name, c.ID)
+		_ = dr.RemoveContainer(ctx, c.ID)
+	}
+	dr.removePodVolumes(ctx, pod)
+	if err := os.RemoveAll(podDir(pod)); err != nil {
+		dr.logger.Warnf("删除 Pod %s/%s 的卷目录失败: %v", pod.Namespace, pod.Name, err)
+	}
+
+	return errors.Join(hookErrs...)
+}
+
+// ListContainers 列出带 io.k3.pod.uid 标签的容器（旧版本 bootstrap 拉起的存储容器 UID 为空，不在其中）
+func (dr *DockerRuntime) ListContainers(ctx context.Context) ([]ManagedContainer, error) {
+	containers, err := dockerPS(ctx, "label="+LabelPodUID)
+	if err != nil {
+		return nil, err
+	}
+	managed := containers[:0]
+	for _, c := range containers {
+		if c.PodUID != "" {
+			managed = append(managed, c)
+		}
+	}
+	return managed, nil
+}
+
+// RemoveContainer 停止并删除容器
+func (dr *DockerRuntime) RemoveContainer(ctx context.Context, id string) error {
+	return dr.removeContainer(ctx, id, -1)
+}
 
+// removeContainer 停止并删除容器：先发送 SIGTERM，timeout 后强制结束（timeout < 0 时使用 docker 默认的 10 秒）
+func (dr *DockerRuntime) removeContainer(ctx context.Context, id string, timeout time.Duration) error {
 	// 先停止容器
-	cmd := exec.CommandContext(ctx, "docker", "stop", containerName)
+	args := []string{"stop"}
+	if timeout >= 0 {
+		args = append(args, "-t", strconv.Itoa(int(timeout.Round(time.Second)/time.Second)))
+	}
+	cmd := exec.CommandContext(ctx, dockerBin, append(args, id)...)
 	if err := cmd.Run(); err != nil {
 		dr.logger.Warnf("停止容器失败（可能已停止）: %v", err)
 	}
 
 	// 删除容器
-	cmd = exec.CommandContext(ctx, "docker", "rm", containerName)
-	if err := cmd.Run(); err != nil {
+	cmd = exec.CommandContext(ctx, dockerBin, "rm", id)
+	if output, err := cmd.CombinedOutput(); err != nil {
 		dr.logger.Warnf("删除容器失败（可能已删除）: %v", err)
+		return fmt.Errorf("删除容器 %s 失败: %w, 输出: %s", id, err, strings.TrimSpace(string(output)))
 	}
-
 	return nil
 }
 
-// GetContainerStatus 获取容器状态
+// GetContainerStatus 获取容器状态：Pod 的所有容器（以及 sandbox）都在运行时才视为 Running，
+// Status 为第一个未运行容器（都在运行时为第一个容器）的状态，PodIP 取自 sandbox（没有 sandbox 时取自容器）
 func (dr *DockerRuntime) GetContainerStatus(ctx context.Context, pod *corev1.Pod) (ContainerStatus, error) {
 	if len(pod.Spec.Containers) == 0 {
 		return ContainerStatus{}, fmt.Errorf("Pod %s/%s 没有容器定义", pod.Namespace, pod.Name)
 	}
 
-	container := pod.Spec.Containers[0]
-	containerName := fmt.Sprintf("k8s_%s_%s_%s", pod.Namespace, pod.Name, container.Name)
+	names := []string{pod.Spec.Containers[0].Name}
+	if pod.UID != "" {
+		names = []string{sandboxContainerName}
+		for _, c := range pod.Spec.Containers {
+			names = append(names, c.Name)
+		}
+	}
 
-	// 检查容器是否存在
-	cmd := exec.CommandContext(ctx, "docker", "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", containerName), "--format", "{{.Status}}")
-	output, err := cmd.Output()
+	status := ContainerStatus{Running: true}
+	for _, name := range names {
+		// 检查容器是否存在
+		containers, err := dr.findContainers(ctx, pod, name)
+		if err != nil || len(containers) == 0 {
+			return ContainerStatus{Running: false, Status: "Unknown"}, nil
+		}
+		c := containers[0]
+		// 没有 sandbox（Pod 没有 UID）时 Pod IP 取自唯一的容器
+		if name == sandboxContainerName || (pod.UID == "" && !pod.Spec.HostNetwork) {
+			status.PodIPs = dr.containerIPs(ctx, c.ID)
+			if len(status.PodIPs) > 0 {
+				status.PodIP = status.PodIPs[0]
+			}
+		}
+		if name == sandboxContainerName {
+			continue
+		}
+		if status.Status == "" {
+			status.Status = c.Status
+		}
+		if status.Running && !isDockerRunning(c.Status) {
+			status.Running = false
+			status.Status = fmt.Sprintf("%s: %s", name, c.Status)
+		}
+	}
+	status.Message = status.Status
+	return status, nil
+}
+
+// containerIP 返回容器在其网络中的主 IP（host 网络或查询失败时为空）
+func (dr *DockerRuntime) containerIP(ctx context.Context, id string) string {
+	if ips := dr.containerIPs(ctx, id); len(ips) > 0 {
+		return ips[0]
+	}
+	return ""
+}
+
+// containerIPs 返回容器在其网络中的 IP：启用了 IPv6 的 Docker 网络（双栈）同时有 IPv4 与全局 IPv6 地址，
+// 与 Kubernetes 的 status.podIPs 相同，每个地址族只取第一个，IPv4 在前
+func (dr *DockerRuntime) containerIPs(ctx context.Context, id string) []string {
+	output, err := exec.CommandContext(ctx, dockerBin, "inspect", "--format", "{{range .NetworkSettings.Networks}}{{.IPAddress}} {{.GlobalIPv6Address}} {{end}}", id).Output()
 	if err != nil {
-		return ContainerStatus{Running: false, Status: "Unknown"}, nil
+		return nil
 	}
+	return dualStackIPs(strings.Fields(string(output)))
+}
 
-	statusStr := strings.TrimSpace(string(output))
-	running := strings.Contains(statusStr, "Up")
+// dualStackIPs 从 ips 中按顺序取每个地址族的第一个合法地址，IPv4 在前
+func dualStackIPs(ips []string) []string {
+	var v4, v6 string
+	for _, s := range ips {
+		ip := net.ParseIP(s)
+		switch {
+		case ip == nil:
+		case ip.To4() != nil:
+			if v4 == "" {
+				v4 = s
+			}
+		default:
+			if v6 == "" {
+				v6 = s
+			}
+		}
+	}
+	var out []string
+	for _, s := range []string{v4, v6} {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
 
-	return ContainerStatus{
-		Running: running,
-		Status:  statusStr,
-		Message: statusStr,
-	}, nil
+// ListImages 通过 docker image inspect 列出镜像，并通过 docker inspect 所有容器得到正在使用的镜像
+func (dr *DockerRuntime) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	output, err := exec.CommandContext(ctx, dockerBin, "image", "ls", "-q", "--no-trunc").Output()
+	if err != nil {
+		return nil, fmt.Errorf("列出镜像失败: %w", err)
+	}
+	ids := uniqueLines(string(output))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := append([]string{"image", "inspect", "--format", `{{.Id}}\t{{.Size}}\t{{.Created}}\t{{join .RepoTags ","}}`}, ids...)
+	output, err = exec.CommandContext(ctx, dockerBin, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("查询镜像信息失败: %w", err)
+	}
+
+	inUse, err := dr.imagesInUse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []ImageInfo
+	for _, line := range uniqueLines(string(output)) {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		created, _ := time.Parse(time.RFC3339Nano, fields[2])
+		image := ImageInfo{ID: fields[0], SizeBytes: size, Created: created, InUse: inUse[fields[0]]}
+		if fields[3] != "" {
+			image.RepoTags = strings.Split(fields[3], ",")
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// imagesInUse 返回被容器（包括已停止的）引用的镜像 ID
+func (dr *DockerRuntime) imagesInUse(ctx context.Context) (map[string]bool, error) {
+	output, err := exec.CommandContext(ctx, dockerBin, "ps", "-aq", "--no-trunc").Output()
+	if err != nil {
+		return nil, fmt.Errorf("列出容器失败: %w", err)
+	}
+	ids := uniqueLines(string(output))
+	inUse := make(map[string]bool)
+	if len(ids) == 0 {
+		return inUse, nil
+	}
+	args := append([]string{"inspect", "--format", "{{.Image}}"}, ids...)
+	output, err = exec.CommandContext(ctx, dockerBin, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("查询容器镜像失败: %w", err)
+	}
+	for _, id := range uniqueLines(string(output)) {
+		inUse[id] = true
+	}
+	return inUse, nil
+}
+
+// pullArgs 按 imagePullPolicy 返回 docker run 的拉取参数：Always 每次启动都拉取，Never 只使用本地镜像，
+// IfNotPresent/未设置为 docker 默认行为（本地没有时拉取）。配置了镜像缓存时需要拉取的镜像先通过缓存拉取，
+// 成功后 docker run 不再拉取；缓存不可用时回退为直接从镜像仓库拉取
+func (dr *DockerRuntime) pullArgs(ctx context.Context, image string, policy corev1.PullPolicy, platform string) []string {
+	if policy == corev1.PullNever {
+		return []string{"--pull", "never"}
+	}
+	if ref := dr.mirrorReference(image); ref != "" && (policy == corev1.PullAlways || !dr.imagePresent(ctx, image)) {
+		err := dr.pullViaMirror(ctx, ref, image, platform)
+		if err == nil {
+			return nil
+		}
+		dr.logger.Warnf("通过镜像缓存拉取 %s 失败，直接从镜像仓库拉取: %v", image, err)
+	}
+	if policy == corev1.PullAlways {
+		return []string{"--pull", "always"}
+	}
+	return nil
+}
+
+// mirrorReference 返回通过镜像缓存拉取 image 时使用的镜像名；没有配置缓存或 image 按摘要引用时为空
+// （按摘要引用的镜像无法重新打上原来的名称，docker run 仍会直接拉取）
+func (dr *DockerRuntime) mirrorReference(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	return registry.MirrorReference(dr.registryMirror, image)
+}
+
+// imagePresent 本地是否已有镜像
+func (dr *DockerRuntime) imagePresent(ctx context.Context, image string) bool {
+	return exec.CommandContext(ctx, dockerBin, "image", "inspect", "--format", "{{.Id}}", image).Run() == nil
+}
+
+// pullViaMirror 通过镜像缓存拉取 ref，再打上原镜像名并去掉缓存地址的名称，
+// docker images、镜像回收与 docker run 看到的都与直接拉取时相同
+func (dr *DockerRuntime) pullViaMirror(ctx context.Context, ref, image, platform string) error {
+	dr.logger.Infof("通过镜像缓存拉取镜像: %s (%s)", image, ref)
+	args := []string{"pull"}
+	if platform != "" {
+		args = append(args, "--platform", platform)
+	}
+	if output, err := exec.CommandContext(ctx, dockerBin, append(args, ref)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+	if output, err := exec.CommandContext(ctx, dockerBin, "tag", ref, image).CombinedOutput(); err != nil {
+		return fmt.Errorf("docker tag 失败: %w, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+	_ = exec.CommandContext(ctx, dockerBin, "rmi", ref).Run()
+	return nil
+}
+
+// PullImage 通过 docker pull 拉取镜像（配置了镜像缓存时优先通过缓存拉取）
+func (dr *DockerRuntime) PullImage(ctx context.Context, image string) error {
+	if ref := dr.mirrorReference(image); ref != "" {
+		err := dr.pullViaMirror(ctx, ref, image, "")
+		if err == nil {
+			return nil
+		}
+		dr.logger.Warnf("通过镜像缓存拉取 %s 失败，直接从镜像仓库拉取: %v", image, err)
+	}
+	dr.logger.Infof("拉取镜像: %s", image)
+	output, err := exec.CommandContext(ctx, dockerBin, "pull", image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("拉取镜像 %s 失败: %w, 输出: %s", image, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// RemoveImage 通过 docker rmi 删除镜像（不加 --force，被容器使用的镜像会删除失败）
+func (dr *DockerRuntime) RemoveImage(ctx context.Context, id string) error {
+	output, err := exec.CommandContext(ctx, dockerBin, "rmi", id).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("删除镜像 %s 失败: %w, 输出: %s", id, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ImageFilesystem 返回 Docker 的数据目录（Docker Desktop 等远端 daemon 的目录在本机不可访问）
+func (dr *DockerRuntime) ImageFilesystem(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, dockerBin, "info", "--format", "{{.DockerRootDir}}").Output()
+	if err != nil {
+		return "", fmt.Errorf("查询 Docker 数据目录失败: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Platform 返回 Docker daemon 的平台（docker version 中的 Server.Os/Server.Arch，取值同 GOOS/GOARCH）
+func (dr *DockerRuntime) Platform(ctx context.Context) (string, string, error) {
+	output, err := exec.CommandContext(ctx, dockerBin, "version", "--format", "{{.Server.Os}}/{{.Server.Arch}}").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("查询 Docker 平台失败: %w", err)
+	}
+	osName, arch, ok := strings.Cut(strings.TrimSpace(string(output)), "/")
+	if !ok || osName == "" || arch == "" {
+		return "", "", fmt.Errorf("无法解析 Docker 平台: %q", strings.TrimSpace(string(output)))
+	}
+	return osName, arch, nil
+}
+
+// containerPlatform 返回 docker run 需要的 --platform（不需要时为空）：
+// Pod 的 nodeSelector 指定了 kubernetes.io/arch 时按其指定；否则本地已有镜像且其平台与 daemon 不一致时，按镜像的平台指定，
+// 避免 docker 因平台不匹配重新拉取或拒绝运行。本地没有镜像时由 docker pull 按 manifest list 选择 daemon 的平台。
+func (dr *DockerRuntime) containerPlatform(ctx context.Context, pod *corev1.Pod, image string) string {
+	osName, arch, err := dr.Platform(ctx)
+	if err != nil {
+		dr.logger.Warnf("%v", err)
+		return ""
+	}
+	native := osName + "/" + arch
+
+	if want := pod.Spec.NodeSelector[corev1.LabelArchStable]; want != "" {
+		if wantOS := pod.Spec.NodeSelector[corev1.LabelOSStable]; wantOS != "" {
+			osName = wantOS
+		}
+		if platform := osName + "/" + want; !samePlatform(platform, native) {
+			return platform
+		}
+		return ""
+	}
+
+	output, err := exec.CommandContext(ctx, dockerBin, "image", "inspect", "--format",
+		"{{.Os}}/{{.Architecture}}{{with .Variant}}/{{.}}{{end}}", image).Output()
+	if err != nil {
+		return ""
+	}
+	if platform := strings.TrimSpace(string(output)); platform != "" && !samePlatform(platform, native) {
+		return platform
+	}
+	return ""
+}
+
+// samePlatform 比较两个 os/arch[/variant] 的 os 与 arch（忽略 variant）
+func samePlatform(a, b string) bool {
+	aParts := strings.SplitN(a, "/", 3)
+	bParts := strings.SplitN(b, "/", 3)
+	if len(aParts) < 2 || len(bParts) < 2 {
+		return a == b
+	}
+	return aParts[0] == bParts[0] && aParts[1] == bParts[1]
+}
+
+// uniqueLines 按行拆分命令输出，去掉空行和重复行（保持原有顺序）
+func uniqueLines(output string) []string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ContainerLogs 通过 docker logs 读取容器日志（stdout/stderr 合并输出）
+func (dr *DockerRuntime) ContainerLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	if opts == nil {
+		opts = &corev1.PodLogOptions{}
+	}
+	container, err := logContainerName(pod, opts.Container)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := dr.findContainers(ctx, pod, container)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("容器 %s 不存在（Pod %s/%s）", container, pod.Namespace, pod.Name)
+	}
+	containerName := containers[0].Name
+
+	args := []string{"logs"}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	if opts.TailLines != nil {
+		args = append(args, "--tail", strconv.FormatInt(*opts.TailLines, 10))
+	}
+	if opts.SinceSeconds != nil {
+		args = append(args, "--since", fmt.Sprintf("%ds", *opts.SinceSeconds))
+	}
+	args = append(args, containers[0].ID)
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	cmd := exec.CommandContext(ctx, dockerBin, args...)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("读取容器日志失败: %w", err)
+	}
+	go func() {
+		err := cmd.Wait()
+		if err != nil && ctx.Err() == nil {
+			_ = pw.CloseWithError(fmt.Errorf("docker logs %s: %w", containerName, err))
+			return
+		}
+		_ = pw.Close()
+	}()
+
+	return &logReader{PipeReader: pr, cancel: cancel}, nil
+}
+
+// logReader 在 Close 时终止日志进程
+type logReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *logReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// logContainerName 返回要读取日志的容器名；未指定时使用第一个容器
+func logContainerName(pod *corev1.Pod, container string) (string, error) {
+	if len(pod.Spec.Containers) == 0 {
+		return "", fmt.Errorf("Pod %s/%s 没有容器定义", pod.Namespace, pod.Name)
+	}
+	if container == "" {
+		return pod.Spec.Containers[0].Name, nil
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			return container, nil
+		}
+	}
+	return "", fmt.Errorf("容器 %s 不在 Pod %s/%s 中", container, pod.Namespace, pod.Name)
 }
 
 // PodmanRuntime Podman 容器运行时实现（占位符）
@@ -276,6 +1390,38 @@ func (pr *PodmanRuntime) GetContainerStatus(ctx context.Context, pod *corev1.Pod
 	return ContainerStatus{}, fmt.Errorf("Podman 运行时尚未实现")
 }
 
+func (pr *PodmanRuntime) ContainerLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("Podman 运行时尚未实现")
+}
+
+func (pr *PodmanRuntime) ListContainers(ctx context.Context) ([]ManagedContainer, error) {
+	return nil, fmt.Errorf("Podman 运行时尚未实现")
+}
+
+func (pr *PodmanRuntime) RemoveContainer(ctx context.Context, id string) error {
+	return fmt.Errorf("Podman 运行时尚未实现")
+}
+
+func (pr *PodmanRuntime) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	return nil, fmt.Errorf("Podman 运行时尚未实现")
+}
+
+func (pr *PodmanRuntime) PullImage(ctx context.Context, image string) error {
+	return fmt.Errorf("Podman 运行时尚未实现")
+}
+
+func (pr *PodmanRuntime) RemoveImage(ctx context.Context, id string) error {
+	return fmt.Errorf("Podman 运行时尚未实现")
+}
+
+func (pr *PodmanRuntime) ImageFilesystem(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("Podman 运行时尚未实现")
+}
+
+func (pr *PodmanRuntime) Platform(ctx context.Context) (string, string, error) {
+	return "", "", fmt.Errorf("Podman 运行时尚未实现")
+}
+
 // ContainerdRuntime Containerd 容器运行时实现（占位符）
 type ContainerdRuntime struct {
 	logger logprovider.Logger
@@ -306,6 +1452,38 @@ func (cr *ContainerdRuntime) GetContainerStatus(ctx context.Context, pod *corev1
 	return ContainerStatus{}, fmt.Errorf("Containerd 运行时尚未实现")
 }
 
+func (cr *ContainerdRuntime) ContainerLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("Containerd 运行时尚未实现")
+}
+
+func (cr *ContainerdRuntime) ListContainers(ctx context.Context) ([]ManagedContainer, error) {
+	return nil, fmt.Errorf("Containerd 运行时尚未实现")
+}
+
+func (cr *ContainerdRuntime) RemoveContainer(ctx context.Context, id string) error {
+	return fmt.Errorf("Containerd 运行时尚未实现")
+}
+
+func (cr *ContainerdRuntime) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	return nil, fmt.Errorf("Containerd 运行时尚未实现")
+}
+
+func (cr *ContainerdRuntime) PullImage(ctx context.Context, image string) error {
+	return fmt.Errorf("Containerd 运行时尚未实现")
+}
+
+func (cr *ContainerdRuntime) RemoveImage(ctx context.Context, id string) error {
+	return fmt.Errorf("Containerd 运行时尚未实现")
+}
+
+func (cr *ContainerdRuntime) ImageFilesystem(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("Containerd 运行时尚未实现")
+}
+
+func (cr *ContainerdRuntime) Platform(ctx context.Context) (string, string, error) {
+	return "", "", fmt.Errorf("Containerd 运行时尚未实现")
+}
+
 // CRIORuntime CRI-O 容器运行时实现（占位符）
 type CRIORuntime struct {
 	logger logprovider.Logger
@@ -335,3 +1513,35 @@ func (crio *CRIORuntime) StopContainer(ctx context.Context, pod *corev1.Pod) err
 func (crio *CRIORuntime) GetContainerStatus(ctx context.Context, pod *corev1.Pod) (ContainerStatus, error) {
 	return ContainerStatus{}, fmt.Errorf("CRI-O 运行时尚未实现")
 }
+
+func (crio *CRIORuntime) ContainerLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("CRI-O 运行时尚未实现")
+}
+
+func (crio *CRIORuntime) ListContainers(ctx context.Context) ([]ManagedContainer, error) {
+	return nil, fmt.Errorf("CRI-O 运行时尚未实现")
+}
+
+func (crio *CRIORuntime) RemoveContainer(ctx context.Context, id string) error {
+	return fmt.Errorf("CRI-O 运行时尚未实现")
+}
+
+func (crio *CRIORuntime) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	return nil, fmt.Errorf("CRI-O 运行时尚未实现")
+}
+
+func (crio *CRIORuntime) PullImage(ctx context.Context, image string) error {
+	return fmt.Errorf("CRI-O 运行时尚未实现")
+}
+
+func (crio *CRIORuntime) RemoveImage(ctx context.Context, id string) error {
+	return fmt.Errorf("CRI-O 运行时尚未实现")
+}
+
+func (crio *CRIORuntime) ImageFilesystem(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("CRI-O 运行时尚未实现")
+}
+
+func (crio *CRIORuntime) Platform(ctx context.Context) (string, string, error) {
+	return "", "", fmt.Errorf("CRI-O 运行时尚未实现")
+}
@@ -3,38 +3,86 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 )
 
+// runtimeComponent 运行时控制器记录 Event 时使用的组件名（对应 kubelet）
+const runtimeComponent = "k3-runtime"
+
+const (
+	// hookBackoffInitial postStart 钩子失败后第一次重试前的等待时间，之后每次翻倍
+	hookBackoffInitial = 10 * time.Second
+	// hookBackoffMax 钩子失败重试等待时间的上限（与 kubelet 的 CrashLoopBackOff 相同）
+	hookBackoffMax = 5 * time.Minute
+	// stopPodExtraTimeout 停止 Pod 时在宽限期之外额外等待删除容器与卷的时间
+	stopPodExtraTimeout = 30 * time.Second
+)
+
+// hookBackoff 记录 Pod 的 postStart 钩子失败后的重试退避
+type hookBackoff struct {
+	delay time.Duration
+	until time.Time
+}
+
 // RuntimeController 容器运行时控制器，负责启动和管理容器
 type RuntimeController struct {
-	store   storage.Store
-	logger  logprovider.Logger
-	runtime ContainerRuntime
-	stopCh  chan struct{}
+	store    storage.Store
+	logger   logprovider.Logger
+	runtime  ContainerRuntime
+	nodeName string
+	// staticPodPath 静态 Pod manifest 目录（为空时不管理静态 Pod）
+	staticPodPath string
+	stopCh        chan struct{}
+	metrics       *controllerMetrics
+	// staticPodsPaused 空闲模式停止了存储后端容器时暂停静态 Pod 同步，避免容器被再次拉起
+	staticPodsPaused atomic.Bool
+
+	// backoffMu 保护 backoff（按 Pod UID 记录钩子失败后的重试退避）
+	backoffMu sync.Mutex
+	backoff   map[types.UID]*hookBackoff
+
+	// terminatingMu 保护 terminating（正在优雅停止、停止后从 Store 删除的 Pod UID，见 stopTerminatingPod）
+	terminatingMu sync.Mutex
+	terminating   map[types.UID]bool
+
+	// logs 把容器输出写入本节点的日志文件（controller.container_logs，未配置时为 nil）
+	logs *containerLogs
 }
 
-// NewRuntimeController 创建容器运行时控制器
-func NewRuntimeController(store storage.Store, logger logprovider.Logger) (*RuntimeController, error) {
+// NewRuntimeController 创建容器运行时控制器；staticPodPath 非空时同时管理该目录下的静态 Pod，
+// clusterID 为配置的 cluster.id（写入容器标签），registryMirror 为拉取镜像使用的镜像缓存地址（为空时直接拉取）
+func NewRuntimeController(store storage.Store, logger logprovider.Logger, nodeName, staticPodPath, clusterID, registryMirror string) (*RuntimeController, error) {
 	// 检测可用的容器运行时
-	detector := NewRuntimeDetector(logger)
+	detector := NewRuntimeDetector(logger, clusterID, registryMirror)
 	runtime, err := detector.DetectRuntime()
 	if err != nil {
 		return nil, fmt.Errorf("无法检测容器运行时: %w", err)
 	}
+	return newRuntimeController(store, logger, runtime, nodeName, staticPodPath), nil
+}
 
+// newRuntimeController 使用给定的容器运行时创建控制器
+func newRuntimeController(store storage.Store, logger logprovider.Logger, runtime ContainerRuntime, nodeName, staticPodPath string) *RuntimeController {
 	return &RuntimeController{
-		store:   store,
-		logger:  logger,
-		runtime: runtime,
-		stopCh:  make(chan struct{}),
-	}, nil
+		store:         store,
+		logger:        logger,
+		runtime:       runtime,
+		nodeName:      nodeName,
+		staticPodPath: staticPodPath,
+		stopCh:        make(chan struct{}),
+		backoff:       make(map[types.UID]*hookBackoff),
+		terminating:   make(map[types.UID]bool),
+	}
 }
 
 // Name 返回控制器名称
@@ -42,6 +90,10 @@ func (rc *RuntimeController) Name() string {
 	return fmt.Sprintf("RuntimeController(%s)", rc.runtime.Name())
 }
 
+func (rc *RuntimeController) setMetrics(m *controllerMetrics) {
+	rc.metrics = m
+}
+
 // Start 启动容器运行时控制器
 func (rc *RuntimeController) Start(ctx context.Context) error {
 	rc.logger.Infof("启动容器运行时控制器: %s", rc.runtime.Name())
@@ -59,6 +111,7 @@ func (rc *RuntimeController) Start(ctx context.Context) error {
 	}
 
 	// 启动处理循环
+	rc.metrics.watch("pods", watchCh)
 	go rc.processPods(ctx, watchCh)
 
 	// 处理现有的已调度但未运行的 Pod
@@ -66,6 +119,11 @@ func (rc *RuntimeController) Start(ctx context.Context) error {
 		rc.logger.Error("同步待运行 Pod 失败: ", err.Error())
 	}
 
+	// 静态 Pod（自托管的存储后端等）与对应的 mirror Pod
+	if rc.staticPodPath != "" {
+		go rc.runStaticPods(ctx)
+	}
+
 	return nil
 }
 
@@ -73,6 +131,7 @@ func (rc *RuntimeController) Start(ctx context.Context) error {
 func (rc *RuntimeController) Stop(ctx context.Context) error {
 	rc.logger.Info("停止容器运行时控制器...")
 	close(rc.stopCh)
+	rc.logs.stop()
 	return nil
 }
 
@@ -91,10 +150,27 @@ func (rc *RuntimeController) syncPendingPods(ctx context.Context) error {
 
 	rc.logger.Infof("发现 %d 个 Pod，检查待运行状态...", len(pods))
 
+	// 删除 k3 停止期间被删除的 Pod 的日志目录，继续写入运行中的 Pod 的日志
+	keep := make(map[types.UID]bool, len(pods))
+	for _, obj := range pods {
+		if pod, ok := obj.(*corev1.Pod); ok && pod.Spec.NodeName == rc.nodeName {
+			keep[pod.UID] = true
+			if pod.Status.Phase == corev1.PodRunning {
+				rc.logs.capture(ctx, pod)
+			}
+		}
+	}
+	rc.logs.prune(keep)
+
 	for _, obj := range pods {
 		if pod, ok := obj.(*corev1.Pod); ok {
+			// k3 停止前没有停止完的正在退出的 Pod
+			if rc.isTerminating(pod) {
+				rc.stopTerminatingPod(ctx, pod)
+				continue
+			}
 			// 只处理已调度到当前节点且未运行的 Pod
-			if pod.Spec.NodeName != "" && pod.Status.Phase != corev1.PodRunning {
+			if pod.Spec.NodeName == rc.nodeName && pod.Status.Phase != corev1.PodRunning {
 				rc.logger.Infof("发现待运行 Pod: %s/%s (节点: %s)", pod.Namespace, pod.Name, pod.Spec.NodeName)
 				if err := rc.handlePod(ctx, pod); err != nil {
 					rc.logger.Error("处理 Pod 失败: ", pod.Name, " error: ", err.Error())
@@ -117,28 +193,51 @@ func (rc *RuntimeController) processPods(ctx context.Context, watchCh <-chan sto
 		case event, ok := <-watchCh:
 			if !ok {
 				rc.logger.Warn("Pod watch 通道已关闭")
+				rc.metrics.watchClosed("pods")
 				return
 			}
 
 			switch event.Type {
 			case storage.EventAdded, storage.EventModified:
 				if pod, ok := event.Object.(*corev1.Pod); ok {
+					// 被优雅删除（如被抢占）的 Pod 不再拉起或刷新，停止后从 Store 删除
+					if rc.isTerminating(pod) {
+						rc.stopTerminatingPod(ctx, pod)
+						continue
+					}
+					// 运行中的 Pod 元数据变化后更新 downwardAPI 卷；容器重启后重新开始写入日志文件
+					if pod.Spec.NodeName == rc.nodeName && pod.Status.Phase == corev1.PodRunning {
+						rc.refreshDownwardAPI(ctx, pod)
+						rc.logs.capture(ctx, pod)
+					}
 					// 只处理已调度到当前节点且未运行的 Pod
-					if pod.Spec.NodeName != "" && pod.Status.Phase != corev1.PodRunning {
+					if pod.Spec.NodeName == rc.nodeName && pod.Status.Phase != corev1.PodRunning {
 						rc.logger.Infof("处理 Pod 事件: %s/%s (%s)", pod.Namespace, pod.Name, event.Type)
-						if err := rc.handlePod(ctx, pod); err != nil {
+						start := time.Now()
+						err := rc.handlePod(ctx, pod)
+						if err != nil {
 							rc.logger.Error("处理 Pod 失败: ", pod.Name, " error: ", err.Error())
 						}
+						rc.metrics.observe(start, err)
 					}
 				}
 			case storage.EventDeleted:
 				if pod, ok := event.Object.(*corev1.Pod); ok {
-					rc.logger.Infof("删除 Pod: %s/%s", pod.Namespace, pod.Name)
-					stopCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-					defer cancel()
-					if err := rc.runtime.StopContainer(stopCtx, pod); err != nil {
-						rc.logger.Error("停止容器失败: ", err.Error())
+					// 删除 mirror Pod 不影响静态 Pod 的容器，mirror 会在下一轮同步时重建
+					if IsMirrorPod(pod) {
+						continue
+					}
+					// 优雅删除的 Pod 已经（或正在）由 stopTerminatingPod 停止
+					if rc.endTermination(pod.UID) {
+						continue
 					}
+					rc.logger.Infof("删除 Pod: %s/%s", pod.Namespace, pod.Name)
+					rc.clearBackoff(pod.UID)
+					// preStop 与宽限期可能持续较长时间，异步停止，不阻塞其他 Pod 的事件；停止后删除日志文件
+					go func() {
+						rc.stopPod(ctx, pod)
+						rc.logs.remove(pod)
+					}()
 				}
 			}
 		}
@@ -147,6 +246,25 @@ func (rc *RuntimeController) processPods(ctx context.Context, watchCh <-chan sto
 
 // handlePod 处理 Pod（启动或更新容器）
 func (rc *RuntimeController) handlePod(ctx context.Context, pod *corev1.Pod) error {
+	// import 模式镜像的 Pod 运行在远端集群，本地不拉起容器
+	if mirror.IsImported(pod) {
+		return nil
+	}
+	// 静态 Pod 的容器与 mirror Pod 的状态由 syncStaticPods 维护
+	if IsMirrorPod(pod) {
+		return nil
+	}
+
+	// 已结束的 Pod 不再拉起容器
+	if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+		return nil
+	}
+	// 钩子失败后的退避期内不重试，到期后由 retryPod 重新处理
+	if rc.inBackoff(pod.UID) {
+		rc.logger.Debugf("Pod %s/%s 处于钩子失败退避期，暂不启动", pod.Namespace, pod.Name)
+		return nil
+	}
+
 	// 检查容器状态
 	status, err := rc.runtime.GetContainerStatus(ctx, pod)
 	if err != nil {
@@ -159,43 +277,294 @@ func (rc *RuntimeController) handlePod(ctx context.Context, pod *corev1.Pod) err
 		startCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 		defer cancel()
 		if err := rc.runtime.StartContainer(startCtx, pod); err != nil {
+			if hookErrs := lifecycleHookErrors(err); len(hookErrs) > 0 {
+				return rc.handleHookFailure(ctx, pod, hookErrs[0])
+			}
 			return fmt.Errorf("启动容器失败: %w", err)
 		}
+		rc.clearBackoff(pod.UID)
+	} else if isStalePod(rc.store, pod) {
+		rc.logger.Debugf("Pod %s/%s 容器已在运行", pod.Namespace, pod.Name)
+		return nil
+	} else {
+		// 容器在运行，但 Store 中最新的 Pod 不是运行状态（运行状态被并发的写入覆盖），重新写回
+		rc.logger.Infof("Pod %s/%s 容器已在运行，恢复运行状态", pod.Namespace, pod.Name)
+	}
 
-		// 更新 Pod 状态
-		pod.Status.Phase = corev1.PodRunning
-		pod.Status.ContainerStatuses = []corev1.ContainerStatus{
-			{
-				Name:  pod.Spec.Containers[0].Name,
-				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.Now()}},
-				Ready: true,
-			},
-		}
-		pod.Status.Conditions = []corev1.PodCondition{
-			{
-				Type:               corev1.PodReady,
-				Status:             corev1.ConditionTrue,
-				LastTransitionTime: metav1.Now(),
-				Reason:             "ContainersReady",
-				Message:            "All containers are ready",
-			},
+	// 更新 Pod 状态
+	pod.Status.Phase = corev1.PodRunning
+	pod.Status.ContainerStatuses = nil
+	for _, c := range pod.Spec.Containers {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name:  c.Name,
+			Image: c.Image,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.Now()}},
+			Ready: true,
+		})
+	}
+	// Pod IP 由 sandbox 持有，容器重启不会变化；hostIP 为本节点的 InternalIP
+	started, err := rc.runtime.GetContainerStatus(ctx, pod)
+	if err != nil {
+		rc.logger.Warnf("获取 Pod %s/%s 的 IP 失败: %v", pod.Namespace, pod.Name, err)
+	}
+	setPodIPs(&pod.Status, pod, rc.nodeHostIPs(), started)
+	ready := corev1.PodCondition{
+		Type:               corev1.PodReady,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "ContainersReady",
+		Message:            "All containers are ready",
+	}
+	// readinessGates 的条件由外部（例如 discovery）写入，容器重新启动后需要重新满足
+	if !readinessGatesReady(pod) {
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = "ReadinessGatesNotReady"
+		ready.Message = "Not all readiness gates are satisfied"
+	}
+	pod.Status.Conditions = []corev1.PodCondition{ready}
+
+	pod.Status.ObservedGeneration = pod.Generation
+
+	// 更新 Pod 资源
+	podGVK := schema.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "Pod",
+	}
+
+	if err := rc.store.Update(podGVK, pod); err != nil {
+		return fmt.Errorf("更新 Pod 状态失败: %w", err)
+	}
+
+	rc.logger.Infof("Pod %s/%s 已成功启动", pod.Namespace, pod.Name)
+	rc.logs.capture(ctx, pod)
+
+	return nil
+}
+
+// nodeHostIPs 返回本节点 Node 上报的 InternalIP，读取失败时为空
+func (rc *RuntimeController) nodeHostIPs() []corev1.HostIP {
+	obj, err := rc.store.Get(nodeGVK, "", rc.nodeName)
+	if err != nil {
+		return nil
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return nil
+	}
+	return nodeHostIPs(node)
+}
+
+// nodeHostIPs 返回 Node 的 InternalIP 作为 Pod 的 status.hostIPs（双栈时每个地址族取第一个，IPv4 在前）
+func nodeHostIPs(node *corev1.Node) []corev1.HostIP {
+	var ips []string
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			ips = append(ips, addr.Address)
 		}
+	}
+	var out []corev1.HostIP
+	for _, ip := range dualStackIPs(ips) {
+		out = append(out, corev1.HostIP{IP: ip})
+	}
+	return out
+}
 
-		// 更新 Pod 资源
-		podGVK := schema.GroupVersionKind{
-			Group:   "",
-			Version: "v1",
-			Kind:    "Pod",
+// setPodIPs 写入 Pod 的 hostIP/hostIPs 与 podIP/podIPs：podIP 取自运行时（sandbox 的 IP），
+// hostNetwork 的 Pod 没有自己的地址，podIP 与 hostIP 相同（与 kubelet 一致）；没有得到的地址保留原值
+func setPodIPs(status *corev1.PodStatus, pod *corev1.Pod, hostIPs []corev1.HostIP, started ContainerStatus) {
+	if len(hostIPs) > 0 {
+		status.HostIP = hostIPs[0].IP
+		status.HostIPs = hostIPs
+	}
+	switch {
+	case pod.Spec.HostNetwork && len(hostIPs) > 0:
+		status.PodIP = hostIPs[0].IP
+		status.PodIPs = nil
+		for _, ip := range hostIPs {
+			status.PodIPs = append(status.PodIPs, corev1.PodIP{IP: ip.IP})
 		}
+	case started.PodIP != "":
+		status.PodIP = started.PodIP
+		status.PodIPs = started.StatusPodIPs()
+	}
+}
+
+// refreshDownwardAPI 按 Pod 当前的标签、注解更新其 downwardAPI 卷（运行时不支持时忽略）
+func (rc *RuntimeController) refreshDownwardAPI(ctx context.Context, pod *corev1.Pod) {
+	refresher, ok := rc.runtime.(DownwardAPIRefresher)
+	if !ok || !hasDownwardAPIVolumes(pod) || mirror.IsImported(pod) {
+		return
+	}
+	if err := refresher.RefreshDownwardAPI(ctx, pod); err != nil {
+		rc.logger.Warnf("更新 Pod %s/%s 的 downwardAPI 卷失败: %v", pod.Namespace, pod.Name, err)
+	}
+}
+
+// handleHookFailure 处理 postStart 钩子失败：记录 Warning Event，把容器状态标记为 Waiting（reason 为 PostStartHookError），
+// restartPolicy 为 Never 时 Pod 进入 Failed，否则保持 Pending 并在退避后重试
+func (rc *RuntimeController) handleHookFailure(ctx context.Context, pod *corev1.Pod, hookErr *LifecycleHookError) error {
+	rc.logger.Warnf("Pod %s/%s: %v", pod.Namespace, pod.Name, hookErr)
+	if err := RecordEvent(rc.store, pod, corev1.EventTypeWarning, hookErr.EventReason(), hookErr.Error(), runtimeComponent); err != nil {
+		rc.logger.Warnf("记录 Pod %s/%s 的事件失败: %v", pod.Namespace, pod.Name, err)
+	}
 
-		if err := rc.store.Update(podGVK, pod); err != nil {
-			return fmt.Errorf("更新 Pod 状态失败: %w", err)
+	pod.Status.ContainerStatuses = nil
+	for _, c := range pod.Spec.Containers {
+		cs := corev1.ContainerStatus{Name: c.Name, Image: c.Image}
+		if c.Name == hookErr.Container {
+			cs.State.Waiting = &corev1.ContainerStateWaiting{Reason: hookErr.StateReason(), Message: hookErr.Error()}
+		} else {
+			cs.State.Waiting = &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}
 		}
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, cs)
+	}
 
-		rc.logger.Infof("Pod %s/%s 已成功启动", pod.Namespace, pod.Name)
+	retry := pod.Spec.RestartPolicy != corev1.RestartPolicyNever
+	if retry {
+		pod.Status.Phase = corev1.PodPending
 	} else {
-		rc.logger.Debugf("Pod %s/%s 容器已在运行", pod.Namespace, pod.Name)
+		pod.Status.Phase = corev1.PodFailed
+		pod.Status.Reason = hookErr.StateReason()
+		pod.Status.Message = hookErr.Error()
+		// 不再重试，删除已经启动的其他容器与 sandbox
+		go rc.stopPod(ctx, pod.DeepCopy())
 	}
+	pod.Status.ObservedGeneration = pod.Generation
 
+	// 先进入退避，避免状态更新产生的事件立即再次启动
+	if retry {
+		delay := rc.startBackoff(pod.UID)
+		rc.logger.Infof("Pod %s/%s 将在 %s 后重试", pod.Namespace, pod.Name, delay)
+		namespace, name, uid := pod.Namespace, pod.Name, pod.UID
+		time.AfterFunc(delay, func() { rc.retryPod(ctx, namespace, name, uid) })
+	}
+
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	if err := rc.store.Update(podGVK, pod); err != nil {
+		return fmt.Errorf("更新 Pod 状态失败: %w", err)
+	}
 	return nil
 }
+
+// retryPod 退避到期后重新读取 Pod 并处理（Pod 已删除、重建或不再属于本节点时忽略）
+func (rc *RuntimeController) retryPod(ctx context.Context, namespace, name string, uid types.UID) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-rc.stopCh:
+		return
+	default:
+	}
+	obj, err := rc.store.Get(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, namespace, name)
+	if err != nil {
+		return
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.UID != uid || pod.Spec.NodeName != rc.nodeName || pod.Status.Phase == corev1.PodRunning {
+		return
+	}
+	if err := rc.handlePod(ctx, pod); err != nil {
+		rc.logger.Error("处理 Pod 失败: ", pod.Name, " error: ", err.Error())
+	}
+}
+
+// stopPod 停止 Pod 的容器：preStop 与停止容器共用 terminationGracePeriodSeconds，preStop 失败时记录 Warning Event
+func (rc *RuntimeController) stopPod(ctx context.Context, pod *corev1.Pod) {
+	stopCtx, cancel := context.WithTimeout(ctx, terminationGracePeriod(pod)+stopPodExtraTimeout)
+	defer cancel()
+	err := rc.runtime.StopContainer(stopCtx, pod)
+	if err == nil {
+		return
+	}
+	hookErrs := lifecycleHookErrors(err)
+	if len(hookErrs) == 0 {
+		rc.logger.Error("停止容器失败: ", err.Error())
+		return
+	}
+	for _, hookErr := range hookErrs {
+		rc.logger.Warnf("Pod %s/%s: %v", pod.Namespace, pod.Name, hookErr)
+		if err := RecordEvent(rc.store, pod, corev1.EventTypeWarning, hookErr.EventReason(), hookErr.Error(), runtimeComponent); err != nil {
+			rc.logger.Warnf("记录 Pod %s/%s 的事件失败: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+}
+
+// isTerminating 判断 Pod 是否是当前节点上被优雅删除（设置了 deletionTimestamp，见 terminatePod）的 Pod
+func (rc *RuntimeController) isTerminating(pod *corev1.Pod) bool {
+	return pod.Spec.NodeName == rc.nodeName && pod.DeletionTimestamp != nil && !IsMirrorPod(pod) && !mirror.IsImported(pod)
+}
+
+// stopTerminatingPod 异步停止被优雅删除的 Pod（执行 preStop、等待宽限期，见 stopPod），停止后从 Store 删除。
+// 同一个 Pod 只处理一次
+func (rc *RuntimeController) stopTerminatingPod(ctx context.Context, pod *corev1.Pod) {
+	rc.terminatingMu.Lock()
+	if rc.terminating[pod.UID] {
+		rc.terminatingMu.Unlock()
+		return
+	}
+	rc.terminating[pod.UID] = true
+	rc.terminatingMu.Unlock()
+
+	rc.logger.Infof("停止正在退出的 Pod: %s/%s", pod.Namespace, pod.Name)
+	rc.clearBackoff(pod.UID)
+	go func() {
+		rc.stopPod(ctx, pod)
+		rc.logs.remove(pod)
+		// 只删除同一个 Pod，不删除停止期间重建的同名 Pod
+		obj, err := rc.store.Get(podGVK, pod.Namespace, pod.Name)
+		if err != nil {
+			return
+		}
+		if current, ok := obj.(*corev1.Pod); !ok || current.UID != pod.UID {
+			return
+		}
+		if err := rc.store.Delete(podGVK, pod.Namespace, pod.Name); err != nil {
+			rc.logger.Warnf("删除已停止的 Pod %s/%s 失败: %v", pod.Namespace, pod.Name, err)
+		}
+	}()
+}
+
+// endTermination 在 Pod 从 Store 删除后清除 stopTerminatingPod 的记录，返回 Pod 是否由 stopTerminatingPod 停止
+func (rc *RuntimeController) endTermination(uid types.UID) bool {
+	rc.terminatingMu.Lock()
+	defer rc.terminatingMu.Unlock()
+	if !rc.terminating[uid] {
+		return false
+	}
+	delete(rc.terminating, uid)
+	return true
+}
+
+// inBackoff 返回 Pod 是否处于钩子失败后的退避期
+func (rc *RuntimeController) inBackoff(uid types.UID) bool {
+	rc.backoffMu.Lock()
+	defer rc.backoffMu.Unlock()
+	b, ok := rc.backoff[uid]
+	return ok && time.Now().Before(b.until)
+}
+
+// startBackoff 开始新一轮退避并返回等待时间（从 hookBackoffInitial 开始翻倍，最多 hookBackoffMax）
+func (rc *RuntimeController) startBackoff(uid types.UID) time.Duration {
+	rc.backoffMu.Lock()
+	defer rc.backoffMu.Unlock()
+	b, ok := rc.backoff[uid]
+	if !ok {
+		b = &hookBackoff{}
+		rc.backoff[uid] = b
+	}
+	if b.delay == 0 {
+		b.delay = hookBackoffInitial
+	} else {
+		b.delay = min(b.delay*2, hookBackoffMax)
+	}
+	b.until = time.Now().Add(b.delay)
+	return b.delay
+}
+
+// clearBackoff Pod 启动成功或删除后清除退避记录
+func (rc *RuntimeController) clearBackoff(uid types.UID) {
+	rc.backoffMu.Lock()
+	defer rc.backoffMu.Unlock()
+	delete(rc.backoff, uid)
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+)
+
+// ContainerInspector 由可以查看容器详情的运行时实现（目前为 Docker）
+type ContainerInspector interface {
+	// InspectContainer 返回指定 ID 容器的详情（运行时原生的 JSON）
+	InspectContainer(ctx context.Context, id string) (json.RawMessage, error)
+}
+
+// InspectContainer 返回 docker inspect 的输出（单个容器对象）
+func (dr *DockerRuntime) InspectContainer(ctx context.Context, id string) (json.RawMessage, error) {
+	output, err := exec.CommandContext(ctx, dockerBin, "inspect", "--type", "container", id).Output()
+	if err != nil {
+		return nil, fmt.Errorf("查看容器 %s 失败: %w", id, err)
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil, fmt.Errorf("解析 docker inspect 输出失败: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("容器 %s 不存在", id)
+	}
+	return items[0], nil
+}
+
+// RuntimeExec 在本节点的容器运行时上执行只读的调试命令（ps、inspect、images）。
+// ps 与 inspect 只涉及 k3 创建的容器，inspect 的容器按 ID、ID 前缀或容器名匹配
+func (cm *ControllerManager) RuntimeExec(ctx context.Context, req apiserver.RuntimeExecRequest) (*apiserver.RuntimeExecResult, error) {
+	if cm.runtime == nil {
+		return nil, fmt.Errorf("容器运行时不可用")
+	}
+	result := &apiserver.RuntimeExecResult{
+		Kind:       "RuntimeExecResult",
+		APIVersion: "v1",
+		Node:       cm.nodeName,
+		Runtime:    cm.runtime.Name(),
+		Command:    req.Command,
+	}
+	switch req.Command {
+	case apiserver.RuntimeCommandPS:
+		containers, err := cm.runtime.ListContainers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range containers {
+			result.Containers = append(result.Containers, apiserver.RuntimeContainer{
+				ID:            c.ID,
+				Name:          c.Name,
+				Status:        c.Status,
+				PodNamespace:  c.PodNamespace,
+				PodName:       c.PodName,
+				PodUID:        c.PodUID,
+				ContainerName: c.ContainerName,
+			})
+		}
+	case apiserver.RuntimeCommandImages:
+		images, err := cm.runtime.ListImages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			result.Images = append(result.Images, apiserver.RuntimeImage{
+				ID:        image.ID,
+				RepoTags:  image.RepoTags,
+				SizeBytes: image.SizeBytes,
+				Created:   image.Created,
+				InUse:     image.InUse,
+			})
+		}
+	case apiserver.RuntimeCommandInspect:
+		inspector, ok := cm.runtime.(ContainerInspector)
+		if !ok {
+			return nil, fmt.Errorf("容器运行时 %s 不支持查看容器详情", cm.runtime.Name())
+		}
+		id, err := cm.findManagedContainer(ctx, req.Container)
+		if err != nil {
+			return nil, err
+		}
+		if result.Inspect, err = inspector.InspectContainer(ctx, id); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的命令: %s", req.Command)
+	}
+	return result, nil
+}
+
+// findManagedContainer 在 k3 创建的容器中按 ID、ID 前缀或容器名查找，返回完整 ID；
+// 不存在时返回 apiserver.ErrRuntimeContainerNotFound，前缀匹配到多个容器时报错
+func (cm *ControllerManager) findManagedContainer(ctx context.Context, ref string) (string, error) {
+	containers, err := cm.runtime.ListContainers(ctx)
+	if err != nil {
+		return "", err
+	}
+	var matched []string
+	for _, c := range containers {
+		if c.ID == ref || c.Name == ref {
+			return c.ID, nil
+		}
+		if strings.HasPrefix(c.ID, ref) {
+			matched = append(matched, c.ID)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return "", fmt.Errorf("%w: %s（只能查看 k3 创建的容器）", apiserver.ErrRuntimeContainerNotFound, ref)
+	case 1:
+		return matched[0], nil
+	default:
+		return "", fmt.Errorf("容器 ID 前缀 %s 匹配到 %d 个容器", ref, len(matched))
+	}
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDockerRestartPolicy(t *testing.T) {
+	for policy, want := range map[corev1.RestartPolicy]string{
+		"":                            "always",
+		corev1.RestartPolicyAlways:    "always",
+		corev1.RestartPolicyOnFailure: "on-failure",
+		corev1.RestartPolicyNever:     "no",
+	} {
+		if got := dockerRestartPolicy(policy); got != want {
+			t.Errorf("dockerRestartPolicy(%q) = %q, want %q", policy, got, want)
+		}
+	}
+}
+
+func TestDockerRunOptions(t *testing.T) {
+	id := func(n int64) *int64 { return &n }
+	readOnly := true
+	labels := "--label " + LabelPodUID + "=uid-1 --label " + LabelPodNamespace + "=default --label " + LabelPodName + "=web --label " + LabelContainerName + "=app"
+
+	for _, tc := range []struct {
+		name      string
+		pod       corev1.PodSpec
+		container corev1.Container
+		want      string
+		err       string
+	}{
+		{
+			name: "labels and default restart policy",
+			want: labels + " --restart always",
+		},
+		{
+			name: "restart policy and resource limits",
+			pod:  corev1.PodSpec{RestartPolicy: corev1.RestartPolicyOnFailure},
+			container: corev1.Container{Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+			}}},
+			want: labels + " --restart on-failure --memory 134217728 --cpus 0.5",
+		},
+		{
+			name: "zero limits are ignored",
+			container: corev1.Container{Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("0"),
+			}}},
+			want: labels + " --restart always",
+		},
+		{
+			name:      "working directory and read-only root filesystem",
+			container: corev1.Container{WorkingDir: "/srv", SecurityContext: &corev1.SecurityContext{ReadOnlyRootFilesystem: &readOnly}},
+			want:      labels + " --restart always --workdir /srv --read-only",
+		},
+		{
+			name: "pod-level user and group",
+			pod:  corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{RunAsUser: id(1000), RunAsGroup: id(3000)}},
+			want: labels + " --restart always --user 1000:3000",
+		},
+		{
+			name:      "container-level user overrides the pod, group inherited",
+			pod:       corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{RunAsUser: id(1000), RunAsGroup: id(3000)}},
+			container: corev1.Container{SecurityContext: &corev1.SecurityContext{RunAsUser: id(0)}},
+			want:      labels + " --restart always --user 0:3000",
+		},
+		{
+			name:      "user only",
+			container: corev1.Container{SecurityContext: &corev1.SecurityContext{RunAsUser: id(65534)}},
+			want:      labels + " --restart always --user 65534",
+		},
+		{
+			name:      "group without user is rejected",
+			container: corev1.Container{SecurityContext: &corev1.SecurityContext{RunAsGroup: id(3000)}},
+			err:       "runAsGroup 需要同时设置 runAsUser",
+		},
+		{
+			name: "pod-level group without any user is rejected",
+			pod:  corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{RunAsGroup: id(3000)}},
+			err:  "runAsGroup 需要同时设置 runAsUser",
+		},
+	} {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "uid-1"}, Spec: tc.pod}
+		container := tc.container
+		container.Name = "app"
+		got, err := dockerRunOptions(pod, &container)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: options %v, err %v; want error %q", tc.name, got, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, " ") != tc.want {
+			t.Errorf("%s:\n got %s (%v)\nwant %s", tc.name, strings.Join(got, " "), err, tc.want)
+		}
+	}
+}
+
+func TestPlanSandbox(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		existing []ManagedContainer
+		action   sandboxAction
+		id       string
+	}{
+		{name: "no sandbox", action: sandboxCreate},
+		{name: "running sandbox is reused", existing: []ManagedContainer{{ID: "s1", Status: "Up 3 minutes"}}, action: sandboxReuse, id: "s1"},
+		{name: "exited sandbox is recreated", existing: []ManagedContainer{{ID: "s1", Status: "Exited (137) 5 seconds ago"}}, action: sandboxRecreate},
+		{name: "created but never started", existing: []ManagedContainer{{ID: "s1", Status: "Created"}}, action: sandboxRecreate},
+		{
+			name:     "a running sandbox wins over a stale one",
+			existing: []ManagedContainer{{ID: "old", Status: "Exited (0) 1 hour ago"}, {ID: "s2", Status: "Up 2 seconds"}},
+			action:   sandboxReuse,
+			id:       "s2",
+		},
+	} {
+		action, id := planSandbox(tc.existing)
+		if action != tc.action || id != tc.id {
+			t.Errorf("%s: planSandbox = %v, %q; want %v, %q", tc.name, action, id, tc.action, tc.id)
+		}
+	}
+}
+
+func TestSandboxRunArgs(t *testing.T) {
+	labels := "--label " + LabelPodUID + "=uid-1 --label " + LabelPodNamespace + "=default --label " + LabelPodName + "=web --label " + LabelContainerName + "=" + sandboxContainerName + " --restart always"
+	containers := []corev1.Container{
+		{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: 80, HostPort: 8080}}},
+		{Name: "metrics", Ports: []corev1.ContainerPort{{ContainerPort: 9090}}},
+	}
+	for _, tc := range []struct {
+		name string
+		spec corev1.PodSpec
+		want string
+	}{
+		{
+			name: "pod network publishes every container's ports on the sandbox",
+			spec: corev1.PodSpec{Containers: containers},
+			want: labels + " --hostname web -p 8080:80 -p 9090",
+		},
+		{
+			name: "host network has no hostname or port mappings",
+			spec: corev1.PodSpec{HostNetwork: true, Containers: containers},
+			want: labels + " --network host",
+		},
+	} {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "uid-1"}, Spec: tc.spec}
+		if got := strings.Join(sandboxRunArgs(pod), " "); got != tc.want {
+			t.Errorf("%s:\n got %s\nwant %s", tc.name, got, tc.want)
+		}
+	}
+}
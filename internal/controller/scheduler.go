@@ -2,28 +2,87 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-// SchedulerController 实现 Pod 调度功能
+// schedulerResyncInterval 定期重试待调度 Pod 的默认间隔（节点上报容量、其他 Pod 结束后可能放得下），
+// 由 controller.resync_period 覆盖
+const schedulerResyncInterval = 30 * time.Second
+
+// schedulerComponent 调度器记录 Event 时使用的组件名
+const schedulerComponent = "k3-scheduler"
+
+var (
+	podGVK  = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	nodeGVK = schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+)
+
+// SchedulerController 实现 Pod 调度功能：按优先级从高到低调度待调度 Pod，在满足 nodeSelector、
+// topologySpreadConstraints（见 topology_spread.go）、podAntiAffinity（见 pod_affinity.go）且 allocatable
+// 放得下 Pod requests 的就绪节点中，选择同一控制器的 Pod 最少、资源占用比例最低的节点（让副本分散；
+// ClusterConfiguration 的 scheduler.strategy 为 BinPack 时选择资源占用比例最高的节点）；
+// 没有节点放得下时尝试抢占低优先级 Pod（见 preemption.go，scheduler.disablePreemption 关闭）
 type SchedulerController struct {
 	store  storage.Store
 	logger logprovider.Logger
 	stopCh chan struct{}
+	// resyncInterval 定期重试待调度 Pod 的间隔
+	resyncInterval time.Duration
+	// mu 保证同一时间只调度一批 Pod，避免 watch 与定期同步同时把 Pod 放到同一个剩余空间；同时保护 policy
+	mu      sync.Mutex
+	policy  k3v1.SchedulerPolicy
+	metrics *controllerMetrics
+	// podOverhead 没有设置 spec.overhead 的 Pod 的默认额外开销（resources.pod_overhead），为空时不填充
+	podOverhead corev1.ResourceList
 }
 
 // NewSchedulerController 创建 Scheduler 控制器
 func NewSchedulerController(store storage.Store, logger logprovider.Logger) *SchedulerController {
 	return &SchedulerController{
-		store:  store,
-		logger: logger,
-		stopCh: make(chan struct{}),
+		store:          store,
+		logger:         logger,
+		stopCh:         make(chan struct{}),
+		resyncInterval: schedulerResyncInterval,
+		policy:         k3v1.SchedulerPolicy{Strategy: k3v1.SchedulingStrategySpread},
+	}
+}
+
+// SetPolicy 更新调度策略，从下一个待调度 Pod 开始生效；policy 为 nil 时恢复默认策略
+func (sc *SchedulerController) SetPolicy(policy *k3v1.SchedulerPolicy) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	next := k3v1.SchedulerPolicy{Strategy: k3v1.SchedulingStrategySpread}
+	if policy != nil {
+		next = *policy
+		if next.Strategy == "" {
+			next.Strategy = k3v1.SchedulingStrategySpread
+		}
+	}
+	if next != sc.policy {
+		sc.logger.Infof("调度策略: %s，抢占: %v", next.Strategy, !next.DisablePreemption)
+	}
+	sc.policy = next
+}
+
+// applyPodOverhead 为没有设置 spec.overhead 的 Pod 填充默认额外开销，随调度结果写入 Store，
+// 之后调度器与 descheduler 计算节点占用时都会算上它
+func (sc *SchedulerController) applyPodOverhead(pod *corev1.Pod) {
+	if pod.Spec.Overhead == nil && len(sc.podOverhead) > 0 {
+		pod.Spec.Overhead = sc.podOverhead.DeepCopy()
 	}
 }
 
@@ -32,24 +91,30 @@ func (sc *SchedulerController) Name() string {
 	return "SchedulerController"
 }
 
+func (sc *SchedulerController) setMetrics(m *controllerMetrics) {
+	sc.metrics = m
+}
+
 // Start 启动 Scheduler 控制器
 func (sc *SchedulerController) Start(ctx context.Context) error {
 	sc.logger.Info("启动 Scheduler 控制器...")
 
-	podGVK := schema.GroupVersionKind{
-		Group:   "",
-		Version: "v1",
-		Kind:    "Pod",
-	}
-
 	// 监听 Pod 资源变化
 	watchCh, err := sc.store.Watch(podGVK, "", "")
 	if err != nil {
 		return fmt.Errorf("无法监听 Pod 资源: %w", err)
 	}
 
+	// 节点变为可调度（就绪、解除封锁）时立即重试待调度 Pod，不必等到定期同步
+	nodeCh, err := sc.store.Watch(nodeGVK, "", "")
+	if err != nil {
+		return fmt.Errorf("无法监听 Node 资源: %w", err)
+	}
+
 	// 启动处理循环
-	go sc.processPods(ctx, watchCh)
+	sc.metrics.watch("pods", watchCh)
+	sc.metrics.watch("nodes", nodeCh)
+	go sc.processPods(ctx, watchCh, nodeCh)
 
 	// 处理现有的未调度 Pod
 	if err := sc.syncPendingPods(ctx); err != nil {
@@ -66,104 +131,234 @@ func (sc *SchedulerController) Stop(ctx context.Context) error {
 	return nil
 }
 
-// syncPendingPods 同步待调度的 Pod
+// syncPendingPods 一次调度所有待调度的 Pod（见 scheduleBatch）；单个 Pod 调度失败只记录日志
 func (sc *SchedulerController) syncPendingPods(ctx context.Context) error {
-	podGVK := schema.GroupVersionKind{
-		Group:   "",
-		Version: "v1",
-		Kind:    "Pod",
-	}
-
-	pods, err := sc.store.List(podGVK, "")
+	objs, err := sc.store.List(podGVK, "")
 	if err != nil {
 		return err
 	}
 
-	sc.logger.Infof("发现 %d 个 Pod，检查待调度状态...", len(pods))
-
-	for _, obj := range pods {
-		if pod, ok := obj.(*corev1.Pod); ok {
-			if pod.Spec.NodeName == "" && pod.Status.Phase == corev1.PodPending {
-				sc.logger.Infof("发现待调度 Pod: %s/%s", pod.Namespace, pod.Name)
-				if err := sc.schedulePod(ctx, pod); err != nil {
-					sc.logger.Error("调度 Pod 失败: ", pod.Name, " error: ", err.Error())
-				}
-			}
+	var pending []*corev1.Pod
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok && isPendingPod(pod) {
+			pending = append(pending, pod)
 		}
 	}
-
+	if len(pending) == 0 {
+		return nil
+	}
+	sc.logger.Infof("发现 %d 个待调度 Pod", len(pending))
+	sc.scheduleBatch(ctx, pending)
 	return nil
 }
 
-// processPods 处理 Pod 事件
-func (sc *SchedulerController) processPods(ctx context.Context, watchCh <-chan storage.ResourceEvent) {
+// processPods 处理 Pod 事件：新的待调度 Pod 与通道中已经到达的其他待调度 Pod 一起批量调度（见 collectPending）；
+// 已调度的 Pod 被删除（释放了节点资源）、节点变为可调度时与定时器一起触发重新调度
+func (sc *SchedulerController) processPods(ctx context.Context, watchCh, nodeCh <-chan storage.ResourceEvent) {
+	ticker := time.NewTicker(sc.resyncInterval)
+	defer ticker.Stop()
+	// schedulable 各节点上一次事件时是否可调度，只在变为可调度时重新调度（心跳不触发）
+	schedulable := map[string]bool{}
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-sc.stopCh:
 			return
+		case <-ticker.C:
+			sc.resync(ctx)
+		case event, ok := <-nodeCh:
+			if !ok {
+				sc.logger.Warn("Node watch 通道已关闭")
+				sc.metrics.watchClosed("nodes")
+				nodeCh = nil
+				continue
+			}
+			node, isNode := event.Object.(*corev1.Node)
+			if !isNode {
+				continue
+			}
+			if event.Type == storage.EventDeleted {
+				delete(schedulable, node.Name)
+				continue
+			}
+			now := isNodeReady(node) && !node.Spec.Unschedulable && !nodeUnderPressure(node)
+			if now && !schedulable[node.Name] {
+				sc.resync(ctx)
+			}
+			schedulable[node.Name] = now
 		case event, ok := <-watchCh:
 			if !ok {
 				sc.logger.Warn("Pod watch 通道已关闭")
+				sc.metrics.watchClosed("pods")
 				return
 			}
-
-			switch event.Type {
-			case storage.EventAdded, storage.EventModified:
-				if pod, ok := event.Object.(*corev1.Pod); ok {
-					// 只处理未调度的 Pod
-					if pod.Spec.NodeName == "" && pod.Status.Phase == corev1.PodPending {
-						sc.logger.Infof("发现待调度 Pod: %s/%s", pod.Namespace, pod.Name)
-						if err := sc.schedulePod(ctx, pod); err != nil {
-							sc.logger.Error("调度 Pod 失败: ", pod.Name, " error: ", err.Error())
-						}
-					}
-				}
+			batch := newPendingBatch()
+			batch.add(sc.store, event)
+			open := sc.collectPending(watchCh, batch)
+			if pods := batch.list(); len(pods) > 0 {
+				sc.logger.Infof("发现 %d 个待调度 Pod", len(pods))
+				start := time.Now()
+				sc.metrics.observe(start, sc.scheduleBatch(ctx, pods))
+			}
+			if batch.released {
+				sc.resync(ctx)
+			}
+			if !open {
+				sc.logger.Warn("Pod watch 通道已关闭")
+				sc.metrics.watchClosed("pods")
+				return
 			}
 		}
 	}
 }
 
-// schedulePod 调度 Pod 到节点
-func (sc *SchedulerController) schedulePod(ctx context.Context, pod *corev1.Pod) error {
-	// 获取所有可用节点
-	nodeGVK := schema.GroupVersionKind{
-		Group:   "",
-		Version: "v1",
-		Kind:    "Node",
+// resync 重新调度所有待调度 Pod 并记录处理统计
+func (sc *SchedulerController) resync(ctx context.Context) {
+	start := time.Now()
+	err := sc.syncPendingPods(ctx)
+	if err != nil {
+		sc.logger.Error("同步待调度 Pod 失败: ", err.Error())
 	}
+	sc.metrics.observe(start, err)
+}
 
-	nodes, err := sc.store.List(nodeGVK, "")
+// isPendingPod 判断 Pod 是否在等待调度
+func isPendingPod(pod *corev1.Pod) bool {
+	return pod.Spec.NodeName == "" && pod.Status.Phase == corev1.PodPending
+}
+
+// place 在快照上为 Pod 选择节点（调用方持有 sc.mu）：返回选中的节点，以及是否为此抢占了其他 Pod
+// （被驱逐的 Pod 已标记为正在退出，快照随之过时）。抢占后 Pod 仍返回错误保持待调度，被驱逐的 Pod 退出后重新调度
+func (sc *SchedulerController) place(pod *corev1.Pod, snap *schedulingSnapshot) (*corev1.Node, bool, error) {
+	if len(snap.nodes) == 0 {
+		return nil, false, fmt.Errorf("没有可用的节点")
+	}
+	podObjs := snap.pods
+	podsByNode := activePodsByNode(podObjs, pod)
+
+	// 已封锁（spec.unschedulable，如 k3 cluster upgrade-nodes 升级期间）或处于内存/磁盘压力的节点不再接收新的 Pod
+	var ready []*corev1.Node
+	for _, obj := range snap.nodes {
+		if node, ok := obj.(*corev1.Node); ok && isNodeReady(node) && !node.Spec.Unschedulable && !nodeUnderPressure(node) {
+			ready = append(ready, node)
+		}
+	}
+	spread, err := newTopologySpread(pod, ready, podsByNode)
 	if err != nil {
-		return fmt.Errorf("获取节点列表失败: %w", err)
+		return nil, false, fmt.Errorf("topologySpreadConstraints 无效: %w", err)
+	}
+	antiAffinity, err := newPodAntiAffinity(sc.store, pod, ready, podsByNode)
+	if err != nil {
+		return nil, false, fmt.Errorf("podAntiAffinity 无效: %w", err)
 	}
 
-	if len(nodes) == 0 {
-		return fmt.Errorf("没有可用的节点")
+	// 在满足 nodeSelector、拓扑分布约束与 Pod 反亲和、资源放得下的就绪节点中按调度策略选择节点
+	var candidates []*corev1.Node
+	var selected *corev1.Node
+	var selectedScore nodeScore
+	var spreadRejected, affinityRejected int
+	var spreadReason, affinityReason string
+	insufficient := make(map[corev1.ResourceName]int)
+	requests := podRequests(pod)
+	owner := podOwnerKey(pod)
+	for _, node := range ready {
+		if !nodeMatchesSelector(pod, node) {
+			continue
+		}
+		if reason, ok := spread.fits(node); !ok {
+			spreadRejected++
+			spreadReason = reason
+			continue
+		}
+		if reason, ok := antiAffinity.fits(pod, node); !ok {
+			affinityRejected++
+			affinityReason = reason
+			continue
+		}
+		if name, fits := nodeFits(requests, node, podsByNode[node.Name]); !fits {
+			insufficient[name]++
+			candidates = append(candidates, node)
+			continue
+		}
+		score := nodeScore{
+			skew:         spread.score(node),
+			antiAffinity: antiAffinity.score(node),
+			owned:        ownedPods(podsByNode[node.Name], owner),
+			usage:        requestedPercent(node, podsByNode[node.Name]),
+		}
+		if selected == nil || preferNode(sc.policy.Strategy, score, selectedScore) {
+			selected, selectedScore = node, score
+		}
+	}
+	if selected != nil {
+		return selected, false, nil
 	}
 
-	// 简单的调度策略：选择第一个可用节点
-	// 实际应该实现更复杂的调度算法（资源检查、亲和性等）
-	var selectedNode *corev1.Node
-	for _, obj := range nodes {
-		if node, ok := obj.(*corev1.Node); ok {
-			// 检查节点是否就绪
-			if sc.isNodeReady(node) {
-				selectedNode = node
-				break
-			}
+	if len(candidates) == 0 {
+		var reasons []string
+		if spreadRejected > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d 个节点不满足 topologySpreadConstraints（%s）", spreadRejected, spreadReason))
+		}
+		if affinityRejected > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d 个节点不满足 podAntiAffinity（%s）", affinityRejected, affinityReason))
+		}
+		if len(reasons) > 0 {
+			return nil, false, errors.New(strings.Join(reasons, "，"))
 		}
+		if len(ready) > 0 {
+			return nil, false, fmt.Errorf("%d 个就绪节点都不满足 nodeSelector %v", len(ready), pod.Spec.NodeSelector)
+		}
+		return nil, false, fmt.Errorf("没有可用的就绪节点")
 	}
 
-	if selectedNode == nil {
-		return fmt.Errorf("没有可用的就绪节点")
+	// 满足 nodeSelector、拓扑分布约束与 Pod 反亲和的节点资源都不足：尝试抢占低优先级 Pod
+	if sc.policy.DisablePreemption {
+		return nil, false, fmt.Errorf("%d 个节点资源不足 %v（已关闭抢占）", len(candidates), insufficient)
+	}
+	preempted, err := sc.preempt(pod, requests, candidates, podsByNode, podObjs)
+	if err != nil {
+		return nil, false, err
 	}
+	if preempted != nil && len(preempted.victims) == 0 {
+		return nil, false, fmt.Errorf("等待节点 %s 上正在退出的 Pod 释放资源", preempted.node.Name)
+	}
+	if preempted != nil {
+		return nil, true, fmt.Errorf("已抢占节点 %s 上的 %d 个 Pod，等待其退出后调度", preempted.node.Name, len(preempted.victims))
+	}
+	return nil, false, fmt.Errorf("%d 个节点资源不足 %v", len(candidates), insufficient)
+}
 
-	sc.logger.Infof("将 Pod %s/%s 调度到节点 %s", pod.Namespace, pod.Name, selectedNode.Name)
+// nodeScore 调度时比较节点用到的指标
+type nodeScore struct {
+	// skew ScheduleAnyway 拓扑分布约束下的偏差（见 topologySpread.score）
+	skew float64
+	// antiAffinity preferred Pod 反亲和的罚分（见 podAntiAffinity.score）
+	antiAffinity int
+	// owned 节点上同一控制器的 Pod 数
+	owned int
+	// usage 节点资源占用比例
+	usage float64
+}
 
-	// 更新 Pod，设置节点名称
-	pod.Spec.NodeName = selectedNode.Name
+// preferNode 判断节点是否优于当前选中的节点：先比较 ScheduleAnyway 拓扑分布约束的偏差与 preferred 反亲和的罚分（越小越好），
+// 再按调度策略比较：Spread 优先同一控制器的 Pod 少、其次占用低；BinPack 优先占用高、其次同一控制器的 Pod 少
+func preferNode(strategy k3v1.SchedulingStrategy, score, selected nodeScore) bool {
+	if score.skew != selected.skew {
+		return score.skew < selected.skew
+	}
+	if score.antiAffinity != selected.antiAffinity {
+		return score.antiAffinity < selected.antiAffinity
+	}
+	if strategy == k3v1.SchedulingStrategyBinPack {
+		return score.usage > selected.usage || (score.usage == selected.usage && score.owned < selected.owned)
+	}
+	return score.owned < selected.owned || (score.owned == selected.owned && score.usage < selected.usage)
+}
+
+// assignNode 把 Pod 标记为已调度到节点（只修改对象，由 bindPod 写入 Store）
+func assignNode(pod *corev1.Pod, nodeName string) {
+	pod.Spec.NodeName = nodeName
 	pod.Status.Phase = corev1.PodPending // Pod 已调度但还未运行
 	pod.Status.Conditions = []corev1.PodCondition{
 		{
@@ -171,27 +366,22 @@ func (sc *SchedulerController) schedulePod(ctx context.Context, pod *corev1.Pod)
 			Status:             corev1.ConditionTrue,
 			LastTransitionTime: metav1.Now(),
 			Reason:             "Scheduled",
-			Message:            fmt.Sprintf("Successfully assigned %s/%s to %s", pod.Namespace, pod.Name, selectedNode.Name),
+			Message:            fmt.Sprintf("Successfully assigned %s/%s to %s", pod.Namespace, pod.Name, nodeName),
 		},
 	}
+}
 
-	// 更新 Pod
-	podGVK := schema.GroupVersionKind{
-		Group:   "",
-		Version: "v1",
-		Kind:    "Pod",
-	}
-
+// bindPod 写入已标记节点的 Pod（见 assignNode）
+func (sc *SchedulerController) bindPod(pod *corev1.Pod) error {
 	if err := sc.store.Update(podGVK, pod); err != nil {
 		return fmt.Errorf("更新 Pod 失败: %w", err)
 	}
-
-	sc.logger.Infof("Pod %s/%s 已成功调度到节点 %s", pod.Namespace, pod.Name, selectedNode.Name)
+	sc.logger.Infof("Pod %s/%s 已成功调度到节点 %s", pod.Namespace, pod.Name, pod.Spec.NodeName)
 	return nil
 }
 
 // isNodeReady 检查节点是否就绪
-func (sc *SchedulerController) isNodeReady(node *corev1.Node) bool {
+func isNodeReady(node *corev1.Node) bool {
 	for _, condition := range node.Status.Conditions {
 		if condition.Type == corev1.NodeReady {
 			return condition.Status == corev1.ConditionTrue
@@ -199,3 +389,150 @@ func (sc *SchedulerController) isNodeReady(node *corev1.Node) bool {
 	}
 	return false
 }
+
+// nodeMatchesSelector 检查节点标签是否满足 Pod 的 nodeSelector（全部键值相等；
+// 例如 kubernetes.io/arch: arm64 只会调度到容器运行时为 arm64 的节点）
+func nodeMatchesSelector(pod *corev1.Pod, node *corev1.Node) bool {
+	if len(pod.Spec.NodeSelector) == 0 {
+		return true
+	}
+	return labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(labels.Set(node.Labels))
+}
+
+// activePodsByNode 按节点分组已调度且未结束的 Pod（占用节点资源），不包括正在调度的 pod 自身（pod 为 nil 时不排除）
+func activePodsByNode(objs []runtime.Object, pod *corev1.Pod) map[string][]*corev1.Pod {
+	byNode := make(map[string][]*corev1.Pod)
+	for _, obj := range objs {
+		p, ok := obj.(*corev1.Pod)
+		if !ok || p.Spec.NodeName == "" || isTerminalPod(p) {
+			continue
+		}
+		if pod != nil && p.Namespace == pod.Namespace && p.Name == pod.Name {
+			continue
+		}
+		byNode[p.Spec.NodeName] = append(byNode[p.Spec.NodeName], p)
+	}
+	return byNode
+}
+
+// isTerminalPod 判断 Pod 是否已结束（不再占用节点资源）
+func isTerminalPod(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// podRequests 返回 Pod 需要的资源（与 Kubernetes 相同）：容器 requests 之和与各 init 容器 requests 的最大值取大，
+// 没有写 requests 时使用 limits，再加上 spec.overhead；另外每个 Pod 占用 1 个 pods 容量
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	requests := corev1.ResourceList{corev1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI)}
+	for _, c := range pod.Spec.Containers {
+		for name, q := range containerRequests(c) {
+			sum := requests[name]
+			sum.Add(q)
+			requests[name] = sum
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range containerRequests(c) {
+			if current, ok := requests[name]; !ok || q.Cmp(current) > 0 {
+				requests[name] = q
+			}
+		}
+	}
+	for name, q := range pod.Spec.Overhead {
+		sum := requests[name]
+		sum.Add(q)
+		requests[name] = sum
+	}
+	return requests
+}
+
+// containerRequests 返回容器的 cpu/memory requests，没有写 requests 的资源使用 limits
+func containerRequests(c corev1.Container) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if q, ok := c.Resources.Requests[name]; ok {
+			requests[name] = q
+		} else if q, ok := c.Resources.Limits[name]; ok {
+			requests[name] = q
+		}
+	}
+	return requests
+}
+
+// sumRequests 返回 pods 的 requests 之和
+func sumRequests(pods []*corev1.Pod) corev1.ResourceList {
+	used := corev1.ResourceList{}
+	for _, p := range pods {
+		for name, q := range podRequests(p) {
+			sum := used[name]
+			sum.Add(q)
+			used[name] = sum
+		}
+	}
+	return used
+}
+
+// nodeFits 判断节点 allocatable 是否放得下 requests（节点上已有 pods 的 requests 之外）；
+// 放不下时返回不足的资源名。节点没有上报的资源不做限制
+func nodeFits(requests corev1.ResourceList, node *corev1.Node, pods []*corev1.Pod) (corev1.ResourceName, bool) {
+	if len(node.Status.Allocatable) == 0 {
+		return "", true
+	}
+	used := sumRequests(pods)
+	for _, name := range []corev1.ResourceName{corev1.ResourcePods, corev1.ResourceCPU, corev1.ResourceMemory} {
+		req, ok := requests[name]
+		if !ok {
+			continue
+		}
+		allocatable, ok := node.Status.Allocatable[name]
+		if !ok {
+			continue
+		}
+		total := used[name]
+		total.Add(req)
+		if total.Cmp(allocatable) > 0 {
+			return name, false
+		}
+	}
+	return "", true
+}
+
+// requestedPercent 返回节点上 Pod requests 占 allocatable 的最大百分比（cpu/memory/pods 中最高的一项）；
+// 节点没有上报 allocatable 时为 0
+func requestedPercent(node *corev1.Node, pods []*corev1.Pod) float64 {
+	used := sumRequests(pods)
+	var highest float64
+	for _, name := range []corev1.ResourceName{corev1.ResourcePods, corev1.ResourceCPU, corev1.ResourceMemory} {
+		allocatable, ok := node.Status.Allocatable[name]
+		if !ok || allocatable.IsZero() {
+			continue
+		}
+		q := used[name]
+		if percent := float64(q.MilliValue()) * 100 / float64(allocatable.MilliValue()); percent > highest {
+			highest = percent
+		}
+	}
+	return highest
+}
+
+// podOwnerKey 返回 Pod 所属控制器的标识（namespace/Kind/name），没有控制器时为空
+func podOwnerKey(pod *corev1.Pod) string {
+	if ref := metav1.GetControllerOf(pod); ref != nil {
+		return pod.Namespace + "/" + ref.Kind + "/" + ref.Name
+	}
+	return ""
+}
+
+// ownedPods 统计 pods 中属于 owner 控制器的数量（owner 为空时为 0）
+func ownedPods(pods []*corev1.Pod, owner string) int {
+	if owner == "" {
+		return 0
+	}
+	n := 0
+	for _, p := range pods {
+		if podOwnerKey(p) == owner {
+			n++
+		}
+	}
+	return n
+}
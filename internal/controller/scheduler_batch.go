@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// maxSchedulingBatch 从 watch 通道中为一批调度收集的事件数上限
+const maxSchedulingBatch = 256
+
+// schedulingSnapshot 一批 Pod 调度时共用的集群状态：节点与 Pod 每批只读取一次，
+// 每决定一个 Pod 的节点就把它计入快照，同一批后面的 Pod 据此计算节点占用、拓扑分布与反亲和
+type schedulingSnapshot struct {
+	nodes []runtime.Object
+	pods  []runtime.Object
+}
+
+// takeSnapshot 读取节点与 Pod 列表
+func (sc *SchedulerController) takeSnapshot() (*schedulingSnapshot, error) {
+	nodes, err := sc.store.List(nodeGVK, "")
+	if err != nil {
+		return nil, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+	pods, err := sc.store.List(podGVK, "")
+	if err != nil {
+		return nil, fmt.Errorf("获取 Pod 列表失败: %w", err)
+	}
+	return &schedulingSnapshot{nodes: nodes, pods: pods}, nil
+}
+
+// assume 把已决定节点的 Pod 计入快照（替换快照中的同名 Pod）
+func (s *schedulingSnapshot) assume(pod *corev1.Pod) {
+	for i, obj := range s.pods {
+		if p, ok := obj.(*corev1.Pod); ok && p.Namespace == pod.Namespace && p.Name == pod.Name {
+			s.pods[i] = pod
+			return
+		}
+	}
+	s.pods = append(s.pods, pod)
+}
+
+// scheduleBatch 一次调度一批 Pod：按优先级从高到低（相同优先级先创建的优先）在同一个快照上依次决定节点，
+// 全部决定后再依次写入绑定。抢占了 Pod 时先写入已决定的绑定，再重新读取快照。
+// 单个 Pod 调度失败只记录日志（Pod 保持待调度，等待下一次重新调度），返回第一个失败
+func (sc *SchedulerController) scheduleBatch(ctx context.Context, pods []*corev1.Pod) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var firstErr error
+	fail := func(pod *corev1.Pod, err error) {
+		sc.logger.Error("调度 Pod 失败: ", pod.Name, " error: ", err.Error())
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	var queue []*corev1.Pod
+	for _, pod := range pods {
+		// import 模式镜像的对象只读，不参与本地调度
+		if mirror.IsImported(pod) {
+			continue
+		}
+		// 控制器直接写入 Store 的 Pod 没有经过 apiserver，在这里补上优先级
+		if err := apiserver.ResolvePodPriority(sc.store, pod); err != nil {
+			fail(pod, err)
+			continue
+		}
+		sc.applyPodOverhead(pod)
+		queue = append(queue, pod)
+	}
+	if len(queue) == 0 {
+		return firstErr
+	}
+	sortByPriority(queue)
+	sc.metrics.observeBatch()
+
+	snap, err := sc.takeSnapshot()
+	if err != nil {
+		return err
+	}
+	var bindings []*corev1.Pod
+	for _, pod := range queue {
+		node, preempted, err := sc.place(pod, snap)
+		if preempted {
+			// 被驱逐的 Pod 已标记为正在退出，快照过时
+			sc.writeBindings(bindings, fail)
+			bindings = nil
+			var snapErr error
+			if snap, snapErr = sc.takeSnapshot(); snapErr != nil {
+				return snapErr
+			}
+		}
+		if err != nil {
+			fail(pod, err)
+			continue
+		}
+		sc.logger.Infof("将 Pod %s/%s 调度到节点 %s", pod.Namespace, pod.Name, node.Name)
+		bound := pod.DeepCopy()
+		assignNode(bound, node.Name)
+		bindings = append(bindings, bound)
+		snap.assume(bound)
+	}
+	sc.writeBindings(bindings, fail)
+	return firstErr
+}
+
+// writeBindings 依次写入已决定节点的 Pod，并记录从 Pod 创建到绑定的耗时
+func (sc *SchedulerController) writeBindings(bindings []*corev1.Pod, fail func(*corev1.Pod, error)) {
+	for _, pod := range bindings {
+		if err := sc.bindPod(pod); err != nil {
+			fail(pod, err)
+			continue
+		}
+		if !pod.CreationTimestamp.IsZero() {
+			sc.metrics.observePlacement(time.Since(pod.CreationTimestamp.Time))
+		}
+	}
+}
+
+// sortByPriority 按优先级从高到低排序，相同优先级先创建的在前
+func sortByPriority(pods []*corev1.Pod) {
+	sort.SliceStable(pods, func(i, j int) bool {
+		pi, pj := apiserver.PodPriority(pods[i]), apiserver.PodPriority(pods[j])
+		if pi != pj {
+			return pi > pj
+		}
+		return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+	})
+}
+
+// pendingBatch 从 watch 事件中收集的待调度 Pod，同一个 Pod 只保留最新的状态
+type pendingBatch struct {
+	pods  map[string]*corev1.Pod
+	order []string
+	// released 有已调度的 Pod 被删除（释放了节点资源），需要重新调度所有待调度 Pod
+	released bool
+}
+
+// newPendingBatch 创建空的待调度批次
+func newPendingBatch() *pendingBatch {
+	return &pendingBatch{pods: make(map[string]*corev1.Pod)}
+}
+
+// add 按事件更新批次：待调度的 Pod 加入（过时的事件中 Pod 可能已被调度，跳过），已调度或删除的 Pod 移出
+func (b *pendingBatch) add(store storage.Store, event storage.ResourceEvent) {
+	pod, ok := event.Object.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	key := pod.Namespace + "/" + pod.Name
+	switch event.Type {
+	case storage.EventAdded, storage.EventModified:
+		if !isPendingPod(pod) || isStalePod(store, pod) {
+			delete(b.pods, key)
+			return
+		}
+		if _, seen := b.pods[key]; !seen {
+			b.order = append(b.order, key)
+		}
+		b.pods[key] = pod
+	case storage.EventDeleted:
+		delete(b.pods, key)
+		if pod.Spec.NodeName != "" {
+			b.released = true
+		}
+	}
+}
+
+// list 按加入顺序返回批次中的 Pod
+func (b *pendingBatch) list() []*corev1.Pod {
+	pods := make([]*corev1.Pod, 0, len(b.pods))
+	for _, key := range b.order {
+		if pod, ok := b.pods[key]; ok {
+			pods = append(pods, pod)
+			delete(b.pods, key)
+		}
+	}
+	return pods
+}
+
+// collectPending 把 watch 通道中已经到达的事件（最多 maxSchedulingBatch 个）加入批次，不等待新事件；
+// 通道已关闭时返回 false
+func (sc *SchedulerController) collectPending(watchCh <-chan storage.ResourceEvent, batch *pendingBatch) bool {
+	for i := 0; i < maxSchedulingBatch; i++ {
+		select {
+		case event, ok := <-watchCh:
+			if !ok {
+				return false
+			}
+			batch.add(sc.store, event)
+		default:
+			return true
+		}
+	}
+	return true
+}
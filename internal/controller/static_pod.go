@@ -0,0 +1,325 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// 静态 Pod：由节点本地 manifest 文件定义、不经过 apiserver 和调度的 Pod（与 kubelet 的 staticPodPath 一致）。
+// 存储后端（etcd/MySQL）以静态 Pod 的方式自托管：bootstrap 生成 manifest 并在 Store 可用之前拉起容器，
+// Store 就绪后由 RuntimeController 接管，并在 Store 中维护对应的 mirror Pod，让存储容器像普通 Pod 一样可见。
+
+const (
+	// AnnotationConfigSource 标记 Pod 的来源，静态 Pod 为 "file"
+	AnnotationConfigSource = "kubernetes.io/config.source"
+	// AnnotationConfigHash 是静态 Pod 内容的哈希（同时作为 UID），manifest 变化时随之变化
+	AnnotationConfigHash = "kubernetes.io/config.hash"
+	// AnnotationConfigMirror 标记 mirror Pod，值为对应静态 Pod 的哈希
+	AnnotationConfigMirror = "kubernetes.io/config.mirror"
+
+	// staticPodSource 是静态 Pod 的 config.source 取值
+	staticPodSource = "file"
+	// staticPodSyncInterval 重新读取 manifest 目录并同步静态 Pod 与 mirror Pod 的周期
+	staticPodSyncInterval = 20 * time.Second
+)
+
+// PrepareStaticPod 补齐静态 Pod 的 TypeMeta、来源注解和 UID。
+// UID 由 namespace/name/labels/spec 的哈希生成，同一份 manifest 在重启后得到同一个 UID，
+// 运行时据此（io.k3.pod.uid 标签）找回已经在运行的容器。
+func PrepareStaticPod(pod *corev1.Pod) {
+	pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	if pod.Namespace == "" {
+		pod.Namespace = metav1.NamespaceDefault
+	}
+
+	content, _ := json.Marshal(struct {
+		Namespace string            `json:"namespace"`
+		Name      string            `json:"name"`
+		Labels    map[string]string `json:"labels,omitempty"`
+		Spec      corev1.PodSpec    `json:"spec"`
+	}{pod.Namespace, pod.Name, pod.Labels, pod.Spec})
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:16])
+
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[AnnotationConfigSource] = staticPodSource
+	pod.Annotations[AnnotationConfigHash] = hash
+	pod.UID = types.UID(hash)
+}
+
+// IsMirrorPod 判断 Pod 是否是静态 Pod 在 Store 中的 mirror
+func IsMirrorPod(pod *corev1.Pod) bool {
+	_, ok := pod.Annotations[AnnotationConfigMirror]
+	return ok
+}
+
+// StaticPodManifestPath 返回静态 Pod 在 dir 中的 manifest 路径（<namespace>-<name>.yaml）
+func StaticPodManifestPath(dir, namespace, name string) string {
+	return filepath.Join(dir, namespace+"-"+name+".yaml")
+}
+
+// WriteStaticPodManifest 把静态 Pod 写入 dir（内容没有变化时不重写），返回 manifest 路径
+func WriteStaticPodManifest(dir string, pod *corev1.Pod) (string, error) {
+	out := pod.DeepCopy()
+	// UID 和哈希在读取时重新计算，不写入文件，避免手工修改 manifest 后与内容不一致
+	out.UID = ""
+	delete(out.Annotations, AnnotationConfigHash)
+	if len(out.Annotations) == 0 {
+		out.Annotations = nil
+	}
+	out.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+
+	data, err := parser.ToYAML(out)
+	if err != nil {
+		return "", fmt.Errorf("序列化静态 Pod %s/%s 失败: %w", pod.Namespace, pod.Name, err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建静态 Pod 目录失败: %w", err)
+	}
+	path := StaticPodManifestPath(dir, pod.Namespace, pod.Name)
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == string(data) {
+		return path, nil
+	}
+	// manifest 中可能包含数据库密码等环境变量，只允许当前用户读写
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("写入静态 Pod manifest 失败: %w", err)
+	}
+	return path, nil
+}
+
+// LoadStaticPods 读取 dir 下所有 .yaml/.yml 文件中的 Pod（目录不存在时返回空），按 namespace/name 排序。
+// 非 Pod 资源和解析失败的文件会被跳过并通过 errs 返回，不影响其他 manifest。
+func LoadStaticPods(dir string) (pods []*corev1.Pod, errs []error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{fmt.Errorf("读取静态 Pod 目录失败: %w", err)}
+	}
+
+	p := parser.NewParser()
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		objs, _, err := p.ParseYAMLFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("解析 %s 失败: %w", path, err))
+			continue
+		}
+		for _, obj := range objs {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s 中包含非 Pod 资源 %T，已忽略", path, obj))
+				continue
+			}
+			PrepareStaticPod(pod)
+			pods = append(pods, pod)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Namespace+"/"+pods[i].Name < pods[j].Namespace+"/"+pods[j].Name
+	})
+	return pods, errs
+}
+
+// EnsureStaticPod 确保静态 Pod 的容器在运行（已在运行时不做任何事），返回是否新启动了容器。
+// 本 UID 还没有任何容器时，先按 namespace/name 清理同名的旧容器：没有 UID 的旧式存储容器，
+// 以及 manifest 变化前（UID 不同）的容器，它们通常占用着相同的端口。
+func EnsureStaticPod(ctx context.Context, runtime ContainerRuntime, pod *corev1.Pod) (bool, error) {
+	status, _ := runtime.GetContainerStatus(ctx, pod)
+	if status.Running {
+		return false, nil
+	}
+	if !hasContainers(ctx, runtime, pod.UID) {
+		stale := pod.DeepCopy()
+		stale.UID = ""
+		_ = runtime.StopContainer(ctx, stale)
+	}
+	if err := runtime.StartContainer(ctx, pod); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// hasContainers 判断运行时中是否有属于 uid 的容器（包括已停止的）
+func hasContainers(ctx context.Context, runtime ContainerRuntime, uid types.UID) bool {
+	containers, err := runtime.ListContainers(ctx)
+	if err != nil {
+		return false
+	}
+	for _, c := range containers {
+		if c.PodUID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// mirrorPodName 返回 mirror Pod 的名称（<name>-<node>，与 kubelet 一致）
+func mirrorPodName(staticName, nodeName string) string {
+	return staticName + "-" + nodeName
+}
+
+// buildMirrorPod 根据静态 Pod 与运行时状态构造 mirror Pod（UID 与静态 Pod 相同，运行时据此找到容器），
+// hostIPs 为本节点的 InternalIP
+func buildMirrorPod(static *corev1.Pod, nodeName string, status ContainerStatus, hostIPs []corev1.HostIP) *corev1.Pod {
+	mirror := static.DeepCopy()
+	mirror.Name = mirrorPodName(static.Name, nodeName)
+	mirror.Annotations[AnnotationConfigMirror] = static.Annotations[AnnotationConfigHash]
+	mirror.Spec.NodeName = nodeName
+	mirror.Status = mirrorPodStatus(static, status, hostIPs)
+	return mirror
+}
+
+// mirrorPodStatus 把运行时状态转换为 Pod 状态
+func mirrorPodStatus(pod *corev1.Pod, status ContainerStatus, hostIPs []corev1.HostIP) corev1.PodStatus {
+	now := metav1.Now()
+	out := corev1.PodStatus{Phase: corev1.PodPending}
+	ready := corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionFalse, LastTransitionTime: now, Reason: "ContainersNotReady", Message: status.Message}
+	if status.Running {
+		out.Phase = corev1.PodRunning
+		ready = corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: now, Reason: "ContainersReady", Message: "All containers are ready"}
+	}
+	out.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: now, Reason: "StaticPod"},
+		ready,
+	}
+	for _, c := range pod.Spec.Containers {
+		cs := corev1.ContainerStatus{Name: c.Name, Image: c.Image, Ready: status.Running}
+		if status.Running {
+			cs.State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: now}}
+		} else {
+			cs.State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerNotRunning", Message: status.Message}}
+		}
+		out.ContainerStatuses = append(out.ContainerStatuses, cs)
+	}
+	setPodIPs(&out, pod, hostIPs, status)
+	return out
+}
+
+// mirrorPodUpToDate 判断 Store 中的 mirror Pod 是否已对应当前的静态 Pod 与运行状态（忽略时间戳）
+func mirrorPodUpToDate(existing, desired *corev1.Pod) bool {
+	if existing.UID != desired.UID || existing.Spec.NodeName != desired.Spec.NodeName ||
+		existing.Annotations[AnnotationConfigMirror] != desired.Annotations[AnnotationConfigMirror] {
+		return false
+	}
+	if existing.Status.Phase != desired.Status.Phase || existing.Status.PodIP != desired.Status.PodIP ||
+		existing.Status.HostIP != desired.Status.HostIP {
+		return false
+	}
+	return podReady(existing) == podReady(desired)
+}
+
+// syncStaticPods 读取静态 Pod 目录：确保容器在运行，并在 Store 中创建/更新 mirror Pod；
+// manifest 已删除的静态 Pod 会停止容器并删除 mirror Pod。mirror Pod 被手工删除时会在下一轮重新创建。
+func (rc *RuntimeController) syncStaticPods(ctx context.Context) {
+	pods, errs := LoadStaticPods(rc.staticPodPath)
+	for _, err := range errs {
+		rc.logger.Warnf("静态 Pod: %v", err)
+	}
+
+	podGVK := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	hostIPs := rc.nodeHostIPs()
+	wanted := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		started, err := EnsureStaticPod(ctx, rc.runtime, pod)
+		if err != nil {
+			rc.logger.Warnf("启动静态 Pod %s/%s 失败: %v", pod.Namespace, pod.Name, err)
+		} else if started {
+			rc.logger.Infof("静态 Pod %s/%s 已启动", pod.Namespace, pod.Name)
+		}
+
+		status, _ := rc.runtime.GetContainerStatus(ctx, pod)
+		desired := buildMirrorPod(pod, rc.nodeName, status, hostIPs)
+		wanted[desired.Namespace+"/"+desired.Name] = true
+
+		obj, err := rc.store.Get(podGVK, desired.Namespace, desired.Name)
+		if err != nil {
+			if err := rc.store.Create(podGVK, desired); err != nil {
+				rc.logger.Warnf("创建 mirror Pod %s/%s 失败: %v", desired.Namespace, desired.Name, err)
+			} else {
+				rc.logger.Infof("已创建 mirror Pod %s/%s", desired.Namespace, desired.Name)
+			}
+			continue
+		}
+		existing, ok := obj.(*corev1.Pod)
+		if !ok || mirrorPodUpToDate(existing, desired) {
+			continue
+		}
+		if existing.UID != desired.UID {
+			// manifest 变化后 UID 随之变化，重新创建 mirror Pod
+			_ = rc.store.Delete(podGVK, existing.Namespace, existing.Name)
+			if err := rc.store.Create(podGVK, desired); err != nil {
+				rc.logger.Warnf("重建 mirror Pod %s/%s 失败: %v", desired.Namespace, desired.Name, err)
+			}
+			continue
+		}
+		desired.ResourceVersion = existing.ResourceVersion
+		desired.CreationTimestamp = existing.CreationTimestamp
+		if err := rc.store.Update(podGVK, desired); err != nil {
+			rc.logger.Warnf("更新 mirror Pod %s/%s 失败: %v", desired.Namespace, desired.Name, err)
+		}
+	}
+
+	// manifest 被删除：停止容器并清理本节点多余的 mirror Pod
+	objs, err := rc.store.List(podGVK, "")
+	if err != nil {
+		return
+	}
+	for _, obj := range objs {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || !IsMirrorPod(pod) || pod.Spec.NodeName != rc.nodeName || wanted[pod.Namespace+"/"+pod.Name] {
+			continue
+		}
+		rc.logger.Infof("静态 Pod manifest 已删除，停止 %s/%s", pod.Namespace, pod.Name)
+		if err := rc.runtime.StopContainer(ctx, pod); err != nil {
+			rc.logger.Warnf("停止静态 Pod 容器失败: %v", err)
+		}
+		if err := rc.store.Delete(podGVK, pod.Namespace, pod.Name); err != nil {
+			rc.logger.Warnf("删除 mirror Pod %s/%s 失败: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+}
+
+// runStaticPods 周期性同步静态 Pod
+func (rc *RuntimeController) runStaticPods(ctx context.Context) {
+	rc.syncStaticPods(ctx)
+	ticker := time.NewTicker(staticPodSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rc.stopCh:
+			return
+		case <-ticker.C:
+			if rc.staticPodsPaused.Load() {
+				continue
+			}
+			rc.syncStaticPods(ctx)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ContainerStats 一个容器当前的资源使用
+type ContainerStats struct {
+	ManagedContainer
+	// CPUMillicores CPU 使用（1000 为一个核）
+	CPUMillicores int64
+	// MemoryBytes 内存使用
+	MemoryBytes int64
+}
+
+// ContainerStatsProvider 由可以采集容器资源使用的运行时实现（目前为 Docker）
+type ContainerStatsProvider interface {
+	// ContainerStats 采样本机上由 k3 创建、正在运行的业务容器（不包括 sandbox）的 CPU 与内存使用
+	ContainerStats(ctx context.Context) ([]ContainerStats, error)
+}
+
+// dockerStatsFormat 是 docker stats 的输出格式：ID、CPU 百分比（100% 为一个核）与内存使用，以 tab 分隔
+const dockerStatsFormat = `{{.ID}}\t{{.CPUPerc}}\t{{.MemUsage}}`
+
+// ContainerStats 通过 docker stats --no-stream 采样（docker 从容器的 cgroup 读取，cgroup v1/v2 均支持），
+// CPU 为两次采样之间的平均使用率，一次调用约需 2 秒
+func (dr *DockerRuntime) ContainerStats(ctx context.Context) ([]ContainerStats, error) {
+	containers, err := dockerPS(ctx, "label="+LabelPodUID, "status=running")
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]ManagedContainer, len(containers))
+	args := []string{"stats", "--no-stream", "--no-trunc", "--format", dockerStatsFormat}
+	for _, c := range containers {
+		if c.PodUID == "" || c.ContainerName == sandboxContainerName {
+			continue
+		}
+		byID[c.ID] = c
+		args = append(args, c.ID)
+	}
+	if len(byID) == 0 {
+		return nil, nil
+	}
+
+	output, err := exec.CommandContext(ctx, dockerBin, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("采集容器资源使用失败: %w", err)
+	}
+	var stats []ContainerStats
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		c, ok := byID[fields[0]]
+		if !ok {
+			continue
+		}
+		cpu, err := parseDockerCPUPercent(fields[1])
+		if err != nil {
+			dr.logger.Debugf("解析容器 %s 的 CPU 使用失败: %v", c.Name, err)
+		}
+		memory, err := parseDockerMemUsage(fields[2])
+		if err != nil {
+			dr.logger.Debugf("解析容器 %s 的内存使用失败: %v", c.Name, err)
+		}
+		stats = append(stats, ContainerStats{ManagedContainer: c, CPUMillicores: cpu, MemoryBytes: memory})
+	}
+	return stats, nil
+}
+
+// parseDockerCPUPercent 把 docker stats 的 CPU 百分比（如 "12.50%"，100% 为一个核）转换为 millicores
+func parseDockerCPUPercent(s string) (int64, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if s == "" || s == "--" {
+		return 0, nil
+	}
+	percent, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(percent * 10)), nil
+}
+
+// dockerMemoryUnits docker stats 内存使用的单位（二进制与十进制两种写法）
+var dockerMemoryUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseDockerMemUsage 解析 docker stats 的内存使用（如 "10.5MiB / 1.944GiB"），返回已使用的字节数
+func parseDockerMemUsage(s string) (int64, error) {
+	used, _, _ := strings.Cut(s, "/")
+	used = strings.TrimSpace(used)
+	if used == "" || used == "--" {
+		return 0, nil
+	}
+	for _, unit := range dockerMemoryUnits {
+		if number, ok := strings.CutSuffix(used, unit.suffix); ok {
+			value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil {
+				return 0, err
+			}
+			return int64(math.Round(value * unit.factor)), nil
+		}
+	}
+	return 0, fmt.Errorf("无法识别的内存使用: %q", s)
+}
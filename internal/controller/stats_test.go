@@ -0,0 +1,74 @@
+package controller
+
+import "testing"
+
+func TestParseDockerCPUPercent(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want int64
+		err  bool
+	}{
+		{in: "12.50%", want: 125},
+		{in: "0.00%", want: 0},
+		{in: "100%", want: 1000},
+		{in: "250.04%", want: 2500},
+		{in: "0.05%", want: 1},
+		{in: " 3.2% ", want: 32},
+		{in: "7.5", want: 75},
+		{in: "--", want: 0},
+		{in: "--%", want: 0},
+		{in: "", want: 0},
+		{in: "abc%", err: true},
+		{in: "12,5%", err: true},
+	} {
+		got, err := parseDockerCPUPercent(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("parseDockerCPUPercent(%q) = %d, want error", tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseDockerCPUPercent(%q) = %d, %v; want %d", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseDockerMemUsage(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want int64
+		err  bool
+	}{
+		{in: "1.2GiB / 2GiB", want: 1288490189},
+		{in: "10.5MiB / 1.944GiB", want: 11010048},
+		{in: "512KiB / 1GiB", want: 512 << 10},
+		{in: "1TiB / 2TiB", want: 1 << 40},
+		{in: "100kB / 1GB", want: 100000},
+		{in: "100KB / 1GB", want: 100000},
+		{in: "1.5MB / 1GB", want: 1500000},
+		{in: "2GB / 4GB", want: 2000000000},
+		{in: "1TB / 2TB", want: 1000000000000},
+		{in: "0B / 0B", want: 0},
+		{in: "2.5 MiB / 1 GiB", want: 2621440},
+		{in: "7MiB", want: 7 << 20},
+		{in: "-- / --", want: 0},
+		{in: "--", want: 0},
+		{in: "", want: 0},
+		{in: "10 / 20", err: true},
+		{in: "MiB / 1GiB", err: true},
+		{in: "lotsMiB / 1GiB", err: true},
+		{in: "10XB / 1GB", err: true},
+	} {
+		got, err := parseDockerMemUsage(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("parseDockerMemUsage(%q) = %d, want error", tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("parseDockerMemUsage(%q) = %d, %v; want %d", tc.in, got, err, tc.want)
+		}
+	}
+}
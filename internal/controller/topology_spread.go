@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// spreadConstraint 一条 topologySpreadConstraint 及各拓扑域中匹配的 Pod 数
+type spreadConstraint struct {
+	key        string
+	maxSkew    int
+	minDomains int
+	hard       bool
+	selector   labels.Selector
+	// counts 拓扑域（节点上 key 标签的值）→ 域内匹配 selector 的 Pod 数；只包含参与计算的节点所在的域
+	counts map[string]int
+	// self 待调度 Pod 自身匹配 selector 时为 1
+	self int
+}
+
+// topologySpread 待调度 Pod 的拓扑分布约束（spec.topologySpreadConstraints）：
+// DoNotSchedule 的约束作为过滤条件，ScheduleAnyway 的约束参与节点打分
+type topologySpread struct {
+	constraints []*spreadConstraint
+}
+
+// newTopologySpread 按 Pod 的 topologySpreadConstraints 统计各拓扑域中已有的 Pod。
+// nodes 为就绪节点；nodeAffinityPolicy 为 Honor（默认）时只统计满足 Pod nodeSelector 的节点，
+// 没有 key 标签的节点不属于任何域。Pod 没有约束时返回 nil
+func newTopologySpread(pod *corev1.Pod, nodes []*corev1.Node, podsByNode map[string][]*corev1.Pod) (*topologySpread, error) {
+	if len(pod.Spec.TopologySpreadConstraints) == 0 {
+		return nil, nil
+	}
+	if err := apiserver.ValidateTopologySpreadConstraints("spec.topologySpreadConstraints", pod.Spec.TopologySpreadConstraints); err != nil {
+		return nil, err
+	}
+
+	ts := &topologySpread{}
+	for _, c := range pod.Spec.TopologySpreadConstraints {
+		selector, err := spreadSelector(pod, c)
+		if err != nil {
+			return nil, err
+		}
+		sc := &spreadConstraint{
+			key:      c.TopologyKey,
+			maxSkew:  int(c.MaxSkew),
+			hard:     c.WhenUnsatisfiable == corev1.DoNotSchedule,
+			selector: selector,
+			counts:   make(map[string]int),
+		}
+		if c.MinDomains != nil {
+			sc.minDomains = int(*c.MinDomains)
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			sc.self = 1
+		}
+		honorSelector := c.NodeAffinityPolicy == nil || *c.NodeAffinityPolicy == corev1.NodeInclusionPolicyHonor
+		for _, node := range nodes {
+			domain, ok := node.Labels[c.TopologyKey]
+			if !ok || (honorSelector && !nodeMatchesSelector(pod, node)) {
+				continue
+			}
+			// 没有匹配 Pod 的域也参与计算最小值
+			if _, ok := sc.counts[domain]; !ok {
+				sc.counts[domain] = 0
+			}
+			for _, p := range podsByNode[node.Name] {
+				if p.Namespace == pod.Namespace && p.DeletionTimestamp == nil && selector.Matches(labels.Set(p.Labels)) {
+					sc.counts[domain]++
+				}
+			}
+		}
+		ts.constraints = append(ts.constraints, sc)
+	}
+	return ts, nil
+}
+
+// spreadSelector 返回约束的 labelSelector，并按 matchLabelKeys 追加 Pod 自身的标签值
+// （例如 pod-template-hash，只统计同一版本的副本）；没有 labelSelector 时不匹配任何 Pod
+func spreadSelector(pod *corev1.Pod, c corev1.TopologySpreadConstraint) (labels.Selector, error) {
+	if c.LabelSelector == nil {
+		return labels.Nothing(), nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(c.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range c.MatchLabelKeys {
+		value, ok := pod.Labels[key]
+		if !ok {
+			continue
+		}
+		req, err := labels.NewRequirement(key, selection.Equals, []string{value})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*req)
+	}
+	return selector, nil
+}
+
+// minCount 返回各域中匹配 Pod 数的最小值；域的数量少于 minDomains 时为 0（与 Kubernetes 相同）
+func (c *spreadConstraint) minCount() int {
+	if len(c.counts) < c.minDomains {
+		return 0
+	}
+	first := true
+	lowest := 0
+	for _, n := range c.counts {
+		if first || n < lowest {
+			lowest, first = n, false
+		}
+	}
+	return lowest
+}
+
+// fits 检查 DoNotSchedule 的约束：Pod 放到 node 后，node 所在域与最少的域之差不超过 maxSkew。
+// 不满足时返回说明；没有 topologyKey 标签的节点不满足
+func (ts *topologySpread) fits(node *corev1.Node) (string, bool) {
+	if ts == nil {
+		return "", true
+	}
+	for _, c := range ts.constraints {
+		if !c.hard {
+			continue
+		}
+		domain, ok := node.Labels[c.key]
+		if !ok {
+			return fmt.Sprintf("节点没有标签 %s", c.key), false
+		}
+		if skew := c.counts[domain] + c.self - c.minCount(); skew > c.maxSkew {
+			return fmt.Sprintf("%s=%s 的偏差 %d 超过 maxSkew %d", c.key, domain, skew, c.maxSkew), false
+		}
+	}
+	return "", true
+}
+
+// score 返回 ScheduleAnyway 约束下 Pod 放到 node 后的偏差之和（越小越好，每条约束按 maxSkew 折算）；
+// 没有 topologyKey 标签的节点按最差处理
+func (ts *topologySpread) score(node *corev1.Node) float64 {
+	if ts == nil {
+		return 0
+	}
+	var total float64
+	for _, c := range ts.constraints {
+		if c.hard {
+			continue
+		}
+		count := 0
+		if domain, ok := node.Labels[c.key]; ok {
+			count = c.counts[domain]
+		} else {
+			for _, n := range c.counts {
+				count = max(count, n+1)
+			}
+		}
+		total += float64(count+c.self-c.minCount()) / float64(c.maxSkew)
+	}
+	return total
+}
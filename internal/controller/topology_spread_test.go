@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// zonedNode 创建带 zone 与额外标签的节点；zone 为空时没有 zone 标签
+func zonedNode(name, zone string, extra map[string]string) *corev1.Node {
+	node := testNode(name, "")
+	if zone != "" {
+		node.Labels[corev1.LabelTopologyZone] = zone
+	}
+	for k, v := range extra {
+		node.Labels[k] = v
+	}
+	return node
+}
+
+// spreadPod 创建带 app=web 标签、调度到 node 的 Pod
+func spreadPod(name, node string, opts ...func(*corev1.Pod)) *corev1.Pod {
+	return testPod(name, 0, "100m", 0, append([]func(*corev1.Pod){onNode(node), withLabels(map[string]string{"app": "web"})}, opts...)...)
+}
+
+func spreadConstraints(constraints ...corev1.TopologySpreadConstraint) func(*corev1.Pod) {
+	return func(p *corev1.Pod) { p.Spec.TopologySpreadConstraints = constraints }
+}
+
+// zoneSpread 返回按 zone 分布 app=web 的约束
+func zoneSpread(maxSkew int32, when corev1.UnsatisfiableConstraintAction) corev1.TopologySpreadConstraint {
+	return corev1.TopologySpreadConstraint{
+		MaxSkew:           maxSkew,
+		TopologyKey:       corev1.LabelTopologyZone,
+		WhenUnsatisfiable: when,
+		LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+	}
+}
+
+func TestTopologySpreadFits(t *testing.T) {
+	nodes := []*corev1.Node{
+		zonedNode("a-1", "a", map[string]string{"disk": "ssd"}),
+		zonedNode("a-2", "a", map[string]string{"disk": "ssd"}),
+		zonedNode("b-1", "b", nil),
+		zonedNode("bare", "", nil),
+	}
+	twoInA := []*corev1.Pod{spreadPod("web-1", "a-1"), spreadPod("web-2", "a-2")}
+	ignore := corev1.NodeInclusionPolicyIgnore
+	three := int32(3)
+
+	for _, tc := range []struct {
+		name     string
+		pod      *corev1.Pod
+		existing []*corev1.Pod
+		// want 各节点是否满足约束
+		want map[string]bool
+	}{
+		{
+			name:     "maxSkew 1 keeps zones balanced",
+			pod:      spreadPod("new", "", spreadConstraints(zoneSpread(1, corev1.DoNotSchedule))),
+			existing: twoInA,
+			want:     map[string]bool{"a-1": false, "a-2": false, "b-1": true, "bare": false},
+		},
+		{
+			name:     "larger maxSkew tolerates imbalance",
+			pod:      spreadPod("new", "", spreadConstraints(zoneSpread(3, corev1.DoNotSchedule))),
+			existing: twoInA,
+			want:     map[string]bool{"a-1": true, "a-2": true, "b-1": true, "bare": false},
+		},
+		{
+			name:     "ScheduleAnyway never filters",
+			pod:      spreadPod("new", "", spreadConstraints(zoneSpread(1, corev1.ScheduleAnyway))),
+			existing: twoInA,
+			want:     map[string]bool{"a-1": true, "a-2": true, "b-1": true, "bare": true},
+		},
+		{
+			name:     "pod outside the selector does not add to skew",
+			pod:      spreadPod("new", "", withLabels(map[string]string{"app": "other"}), spreadConstraints(zoneSpread(1, corev1.DoNotSchedule))),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1")},
+			want:     map[string]bool{"a-1": true, "b-1": true},
+		},
+		{
+			name:     "pods in other namespaces and terminating pods are not counted",
+			pod:      spreadPod("new", "", spreadConstraints(zoneSpread(1, corev1.DoNotSchedule))),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1", terminating), spreadPod("web-2", "a-2", func(p *corev1.Pod) { p.Namespace = "other" })},
+			want:     map[string]bool{"a-1": true, "b-1": true},
+		},
+		{
+			name: "minDomains above the domain count treats the minimum as zero",
+			pod: spreadPod("new", "", spreadConstraints(func() corev1.TopologySpreadConstraint {
+				c := zoneSpread(1, corev1.DoNotSchedule)
+				c.MinDomains = &three
+				return c
+			}())),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1"), spreadPod("web-2", "b-1")},
+			want:     map[string]bool{"a-1": false, "b-1": false},
+		},
+		{
+			name:     "without minDomains the same layout fits",
+			pod:      spreadPod("new", "", spreadConstraints(zoneSpread(1, corev1.DoNotSchedule))),
+			existing: []*corev1.Pod{spreadPod("web-1", "a-1"), spreadPod("web-2", "b-1")},
+			want:     map[string]bool{"a-1": true, "b-1": true},
+		},
+		{
+			name: "nodeSelector limits the domains by default",
+			pod: spreadPod("new", "", spreadConstraints(zoneSpread(1, corev1.DoNotSchedule)), func(p *corev1.Pod) {
+				p.Spec.NodeSelector = map[string]string{"disk": "ssd"}
+			}),
+			existing: twoInA,
+			want:     map[string]bool{"a-1": true, "a-2": true},
+		},
+		{
+			name: "nodeAffinityPolicy Ignore counts every domain",
+			pod: spreadPod("new", "", spreadConstraints(func() corev1.TopologySpreadConstraint {
+				c := zoneSpread(1, corev1.DoNotSchedule)
+				c.NodeAffinityPolicy = &ignore
+				return c
+			}()), func(p *corev1.Pod) {
+				p.Spec.NodeSelector = map[string]string{"disk": "ssd"}
+			}),
+			existing: twoInA,
+			want:     map[string]bool{"a-1": false, "a-2": false},
+		},
+		{
+			name: "matchLabelKeys only counts the same revision",
+			pod: spreadPod("new", "", withLabels(map[string]string{"app": "web", "pod-template-hash": "v2"}), spreadConstraints(func() corev1.TopologySpreadConstraint {
+				c := zoneSpread(1, corev1.DoNotSchedule)
+				c.MatchLabelKeys = []string{"pod-template-hash"}
+				return c
+			}())),
+			existing: []*corev1.Pod{
+				spreadPod("old-1", "a-1", withLabels(map[string]string{"app": "web", "pod-template-hash": "v1"})),
+				spreadPod("old-2", "a-2", withLabels(map[string]string{"app": "web", "pod-template-hash": "v1"})),
+			},
+			want: map[string]bool{"a-1": true, "b-1": true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			podsByNode := make(map[string][]*corev1.Pod)
+			for _, p := range tc.existing {
+				podsByNode[p.Spec.NodeName] = append(podsByNode[p.Spec.NodeName], p)
+			}
+			ts, err := newTopologySpread(tc.pod, nodes, podsByNode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, node := range nodes {
+				want, ok := tc.want[node.Name]
+				if !ok {
+					continue
+				}
+				if reason, got := ts.fits(node); got != want {
+					t.Errorf("fits(%s) = %v (%s), want %v", node.Name, got, reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTopologySpreadScore(t *testing.T) {
+	nodes := []*corev1.Node{zonedNode("a-1", "a", nil), zonedNode("b-1", "b", nil), zonedNode("bare", "", nil)}
+	podsByNode := map[string][]*corev1.Pod{"a-1": {spreadPod("web-1", "a-1"), spreadPod("web-2", "a-1")}}
+	pod := spreadPod("new", "", spreadConstraints(zoneSpread(1, corev1.ScheduleAnyway)))
+	ts, err := newTopologySpread(pod, nodes, podsByNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, bare := ts.score(nodes[0]), ts.score(nodes[1]), ts.score(nodes[2])
+	if !(b < a && a < bare) {
+		t.Fatalf("scores a-1 %v, b-1 %v, bare %v; want b-1 < a-1 < bare", a, b, bare)
+	}
+
+	// DoNotSchedule 的约束不参与打分，没有约束时为 nil
+	hard, err := newTopologySpread(spreadPod("new", "", spreadConstraints(zoneSpread(1, corev1.DoNotSchedule))), nodes, podsByNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := hard.score(nodes[0]); s != 0 {
+		t.Fatalf("DoNotSchedule score = %v", s)
+	}
+	if none, err := newTopologySpread(spreadPod("new", ""), nodes, podsByNode); none != nil || err != nil {
+		t.Fatalf("no constraints: %v, %v", none, err)
+	}
+	if _, err := newTopologySpread(spreadPod("new", "", spreadConstraints(zoneSpread(0, corev1.DoNotSchedule))), nodes, podsByNode); err == nil {
+		t.Fatal("maxSkew 0 accepted")
+	}
+}
+
+func TestPlaceSpreadsAcrossZones(t *testing.T) {
+	store := storage.NewMemoryStore()
+	sc := NewSchedulerController(store, testLogger)
+	snap := &schedulingSnapshot{}
+	for _, node := range []*corev1.Node{zonedNode("a-1", "a", nil), zonedNode("a-2", "a", nil), zonedNode("b-1", "b", nil)} {
+		snap.nodes = append(snap.nodes, runtime.Object(node))
+	}
+
+	// 依次放置 4 个副本：每个决定计入快照，两个 zone 各 2 个
+	zones := make(map[string]int)
+	for _, name := range []string{"web-1", "web-2", "web-3", "web-4"} {
+		pod := spreadPod(name, "", spreadConstraints(zoneSpread(1, corev1.DoNotSchedule)))
+		node, _, err := sc.place(pod, snap)
+		if err != nil {
+			t.Fatalf("place %s: %v", name, err)
+		}
+		zones[node.Labels[corev1.LabelTopologyZone]]++
+		bound := pod.DeepCopy()
+		assignNode(bound, node.Name)
+		snap.assume(bound)
+	}
+	if zones["a"] != 2 || zones["b"] != 2 {
+		t.Fatalf("zones = %v, want 2 per zone", zones)
+	}
+}
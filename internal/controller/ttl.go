@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// defaultTTLInterval 未配置 retention.interval 时的检查周期
+const defaultTTLInterval = time.Minute
+
+// jobGVK 是 batch/v1 Job 的 GroupVersionKind
+var jobGVK = schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+
+// TTLController 按保留时长从 Store 删除短期对象，避免它们一直累积：
+//   - Event：最后一次发生之后超过 retention.events
+//   - Pod：本节点上 Succeeded / Failed 的 Pod 结束之后超过 retention.succeeded_pods / failed_pods
+//   - Job：完成或失败之后超过 spec.ttlSecondsAfterFinished（没有设置时为 retention.finished_jobs），同时删除其 Pod
+//
+// Pod 只由其所在节点删除；Event 与 Job 由每个节点检查，已被其他节点删除的对象直接跳过
+type TTLController struct {
+	store         storage.Store
+	logger        logprovider.Logger
+	nodeName      string
+	interval      time.Duration
+	events        time.Duration
+	succeededPods time.Duration
+	failedPods    time.Duration
+	finishedJobs  time.Duration
+	now           func() time.Time
+	stopCh        chan struct{}
+	metrics       *controllerMetrics
+}
+
+// NewTTLController 创建 TTL 控制器；保留时长为空表示不删除该类对象
+func NewTTLController(store storage.Store, logger logprovider.Logger, nodeName string, cfg config.RetentionConfig) (*TTLController, error) {
+	tc := &TTLController{
+		store:    store,
+		logger:   logger,
+		nodeName: nodeName,
+		now:      time.Now,
+		stopCh:   make(chan struct{}),
+	}
+	var err error
+	if tc.interval, err = parseOptionalDuration("retention.interval", cfg.Interval, defaultTTLInterval); err != nil {
+		return nil, err
+	}
+	for _, r := range []struct {
+		key, value string
+		target     *time.Duration
+	}{
+		{"retention.events", cfg.Events, &tc.events},
+		{"retention.succeeded_pods", cfg.SucceededPods, &tc.succeededPods},
+		{"retention.failed_pods", cfg.FailedPods, &tc.failedPods},
+		{"retention.finished_jobs", cfg.FinishedJobs, &tc.finishedJobs},
+	} {
+		if *r.target, err = parseOptionalDuration(r.key, r.value, 0); err != nil {
+			return nil, err
+		}
+	}
+	return tc, nil
+}
+
+// Name 返回控制器名称
+func (tc *TTLController) Name() string {
+	return "TTLController"
+}
+
+func (tc *TTLController) setMetrics(m *controllerMetrics) {
+	tc.metrics = m
+}
+
+// Start 启动周期检查
+func (tc *TTLController) Start(ctx context.Context) error {
+	tc.logger.Infof("启动 TTL 控制器（周期 %s，events=%s，succeededPods=%s，failedPods=%s，finishedJobs=%s）",
+		tc.interval, tc.events, tc.succeededPods, tc.failedPods, tc.finishedJobs)
+	go func() {
+		ticker := time.NewTicker(tc.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tc.stopCh:
+				return
+			case <-ticker.C:
+				start := time.Now()
+				err := tc.prune()
+				if err != nil {
+					tc.logger.Warnf("TTL 清理失败: %v", err)
+				}
+				tc.metrics.observe(start, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止周期检查
+func (tc *TTLController) Stop(ctx context.Context) error {
+	close(tc.stopCh)
+	return nil
+}
+
+// prune 执行一轮清理，各类对象互不影响，返回第一个错误
+func (tc *TTLController) prune() error {
+	var firstErr error
+	for _, step := range []func(time.Time) error{tc.pruneEvents, tc.prunePods, tc.pruneJobs} {
+		if err := step(tc.now()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (tc *TTLController) pruneEvents(now time.Time) error {
+	if tc.events <= 0 {
+		return nil
+	}
+	objects, err := tc.store.List(EventGVK, "")
+	if err != nil {
+		return fmt.Errorf("列出 Event 失败: %w", err)
+	}
+	pruned := 0
+	for _, obj := range objects {
+		event, ok := obj.(*corev1.Event)
+		if !ok || now.Sub(eventLastSeen(event)) < tc.events {
+			continue
+		}
+		if tc.delete(EventGVK, event.Namespace, event.Name) {
+			pruned++
+		}
+	}
+	if pruned > 0 {
+		tc.logger.Infof("TTL 清理了 %d 个超过 %s 的 Event", pruned, tc.events)
+	}
+	return nil
+}
+
+func (tc *TTLController) prunePods(now time.Time) error {
+	if tc.succeededPods <= 0 && tc.failedPods <= 0 {
+		return nil
+	}
+	objects, err := tc.store.List(podGVK, "")
+	if err != nil {
+		return fmt.Errorf("列出 Pod 失败: %w", err)
+	}
+	for _, obj := range objects {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || pod.Spec.NodeName != tc.nodeName || pod.DeletionTimestamp != nil || IsMirrorPod(pod) {
+			continue
+		}
+		var retention time.Duration
+		switch pod.Status.Phase {
+		case corev1.PodSucceeded:
+			retention = tc.succeededPods
+		case corev1.PodFailed:
+			retention = tc.failedPods
+		}
+		if retention <= 0 || now.Sub(podFinishedAt(pod)) < retention {
+			continue
+		}
+		if tc.delete(podGVK, pod.Namespace, pod.Name) {
+			tc.logger.Infof("TTL 删除 %s 的 Pod %s/%s", pod.Status.Phase, pod.Namespace, pod.Name)
+		}
+	}
+	return nil
+}
+
+func (tc *TTLController) pruneJobs(now time.Time) error {
+	objects, err := tc.store.List(jobGVK, "")
+	if err != nil {
+		return fmt.Errorf("列出 Job 失败: %w", err)
+	}
+	for _, obj := range objects {
+		job, ok := obj.(*batchv1.Job)
+		if !ok || job.DeletionTimestamp != nil {
+			continue
+		}
+		finishedAt, finished := jobFinishedAt(job)
+		if !finished {
+			continue
+		}
+		ttl := tc.finishedJobs
+		if job.Spec.TTLSecondsAfterFinished != nil {
+			ttl = time.Duration(*job.Spec.TTLSecondsAfterFinished) * time.Second
+		} else if ttl <= 0 {
+			continue
+		}
+		if now.Sub(finishedAt) < ttl {
+			continue
+		}
+		if !tc.delete(jobGVK, job.Namespace, job.Name) {
+			continue
+		}
+		tc.logger.Infof("TTL 删除已结束的 Job %s/%s", job.Namespace, job.Name)
+		tc.deleteJobPods(job)
+	}
+	return nil
+}
+
+// deleteJobPods 删除属于 job 的 Pod（与 Kubernetes 删除 Job 时的级联删除一致）
+func (tc *TTLController) deleteJobPods(job *batchv1.Job) {
+	pods, err := tc.store.List(podGVK, job.Namespace)
+	if err != nil {
+		tc.logger.Warnf("列出 Job %s/%s 的 Pod 失败: %v", job.Namespace, job.Name, err)
+		return
+	}
+	for _, obj := range pods {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			continue
+		}
+		if ref := metav1.GetControllerOf(pod); ref != nil && ref.UID == job.UID {
+			tc.delete(podGVK, pod.Namespace, pod.Name)
+		}
+	}
+}
+
+// delete 删除对象，返回是否由本次删除；对象已被其他节点删除时返回 false，不记为失败
+func (tc *TTLController) delete(gvk schema.GroupVersionKind, namespace, name string) bool {
+	if err := tc.store.Delete(gvk, namespace, name); err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			tc.logger.Warnf("TTL 删除 %s %s/%s 失败: %v", gvk.Kind, namespace, name, err)
+		}
+		return false
+	}
+	return true
+}
+
+// eventLastSeen 返回 Event 最后一次发生的时间：lastTimestamp，其次 eventTime、创建时间
+func eventLastSeen(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// podFinishedAt 返回 Pod 结束的时间：各容器结束时间中最晚的一个，没有时为 Ready 条件变化的时间，再其次为创建时间
+func podFinishedAt(pod *corev1.Pod) time.Time {
+	var finished time.Time
+	for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if t := status.State.Terminated; t != nil && t.FinishedAt.After(finished) {
+			finished = t.FinishedAt.Time
+		}
+	}
+	if !finished.IsZero() {
+		return finished
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && !cond.LastTransitionTime.IsZero() {
+			return cond.LastTransitionTime.Time
+		}
+	}
+	return pod.CreationTimestamp.Time
+}
+
+// jobFinishedAt 返回 Job 完成或失败的时间（Complete/Failed 条件变为 True 的时间，其次 completionTime）
+func jobFinishedAt(job *batchv1.Job) (time.Time, bool) {
+	for _, cond := range job.Status.Conditions {
+		if (cond.Type == batchv1.JobComplete || cond.Type == batchv1.JobFailed) && cond.Status == corev1.ConditionTrue {
+			if !cond.LastTransitionTime.IsZero() {
+				return cond.LastTransitionTime.Time, true
+			}
+			if job.Status.CompletionTime != nil {
+				return job.Status.CompletionTime.Time, true
+			}
+			return job.CreationTimestamp.Time, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// NetworkCIDRs 解析后的 Pod 与 Service 网段，保持配置中的顺序（第一个网段的地址族为主地址族）
+type NetworkCIDRs struct {
+	Pod     []*net.IPNet
+	Service []*net.IPNet
+}
+
+// CIDRs 解析并校验 network.pod_cidrs 与 network.service_cidrs：每项必须是网段（不能是单个地址），
+// 与 Kubernetes 相同，最多一个 IPv4 与一个 IPv6 网段（双栈）
+func (c NetworkConfig) CIDRs() (NetworkCIDRs, error) {
+	var out NetworkCIDRs
+	var err error
+	if out.Pod, err = parseDualStackCIDRs("network.pod_cidrs", c.PodCIDRs); err != nil {
+		return out, err
+	}
+	if out.Service, err = parseDualStackCIDRs("network.service_cidrs", c.ServiceCIDRs); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// parseDualStackCIDRs 解析网段列表，每个地址族最多一个
+func parseDualStackCIDRs(key string, values []string) ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		ip, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s 无效: %q", key, v)
+		}
+		if !ip.Equal(n.IP) {
+			return nil, fmt.Errorf("%s: %q 不是网段地址（应为 %s）", key, v, n.String())
+		}
+		for _, prev := range out {
+			if (prev.IP.To4() == nil) == (n.IP.To4() == nil) {
+				return nil, fmt.Errorf("%s 每个地址族最多只能有一个网段: %s, %s", key, prev, n)
+			}
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/function/web/translate/model"
 	"github.com/spf13/viper"
@@ -26,24 +27,351 @@ func init() {
 }
 
 type Config struct {
-	Debug                    bool          `mapstructure:"debug"`
-	Role                     string        `mapstructure:"role"` // master/node/one
-	Gin                      GinConfig     `mapstructure:"web"`
-	Log                      LogConfig     `mapstructure:"log"`
-	JWT                      JWT           `mapstructure:"jwt"`
-	Storage                  StorageConfig `mapstructure:"storage"`
-	Cities                   []model.City  `yaml:"cities"`
-	MinimumDeviationDistance float64       `mapstructure:"minimum_deviation_distance"` // 最小偏差距离
-	OutputFormat             string        `mapstructure:"output"`                     // 输出形式
+	Debug                    bool                 `mapstructure:"debug"`
+	Role                     string               `mapstructure:"role"`      // master/node/one
+	NodeName                 string               `mapstructure:"node_name"` // 节点名（环境变量 NODE_NAME 优先，都为空时使用主机名）
+	Cluster                  ClusterConfig        `mapstructure:"cluster"`
+	Gin                      GinConfig            `mapstructure:"web"`
+	Log                      LogConfig            `mapstructure:"log"`
+	JWT                      JWT                  `mapstructure:"jwt"`
+	Auth                     AuthConfig           `mapstructure:"auth"`
+	APIServer                APIServerConfig      `mapstructure:"apiserver"`
+	Controller               ControllerConfig     `mapstructure:"controller"`
+	Idle                     IdleConfig           `mapstructure:"idle"`
+	Resources                ResourcesConfig      `mapstructure:"resources"`
+	Network                  NetworkConfig        `mapstructure:"network"`
+	Storage                  StorageConfig        `mapstructure:"storage"`
+	Bootstrap                BootstrapConfig      `mapstructure:"bootstrap"`
+	ImageGC                  ImageGCConfig        `mapstructure:"image_gc"`
+	Eviction                 EvictionConfig       `mapstructure:"eviction"`
+	Inventory                InventoryConfig      `mapstructure:"inventory"`
+	Descheduler              DeschedulerConfig    `mapstructure:"descheduler"`
+	Retention                RetentionConfig      `mapstructure:"retention"`
+	Discovery                DiscoveryConfig      `mapstructure:"discovery"`
+	Notifications            NotificationsConfig  `mapstructure:"notifications"`
+	GitOps                   GitOpsConfig         `mapstructure:"gitops"`
+	Tenancy                  TenancyConfig        `mapstructure:"tenancy"`
+	RegistryMirror           RegistryMirrorConfig `mapstructure:"registry_mirror"`
+	APIProxy                 APIProxyConfig       `mapstructure:"api_proxy"`
+	Cities                   []model.City         `yaml:"cities"`
+	MinimumDeviationDistance float64              `mapstructure:"minimum_deviation_distance"` // 最小偏差距离
+	OutputFormat             string               `mapstructure:"output"`                     // 输出形式
+}
+
+// ClusterConfig 集群标识：cluster create 生成 ID 写入同一集群目录下的所有节点配置，
+// 本机拉起的容器带有 io.k3.cluster.id 标签，cluster clear 只清理该集群的容器
+type ClusterConfig struct {
+	ID string `mapstructure:"id"`
+	// Server 集群 apiserver 地址（如 http://127.0.0.1:8080），k3 apply / rollout 未指定 --server 时使用；
+	// 为空时使用本机 web.port
+	Server string `mapstructure:"server"`
 }
 
 type JWT struct {
-	SigningKey []byte
+	SigningKey []byte `mapstructure:"signing_key"`
+}
+
+// AuthConfig web 层认证与 namespace 隔离配置（默认关闭，仅适合 localhost 使用）
+type AuthConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Tokens  []TokenConfig `mapstructure:"tokens"`
+}
+
+// TokenConfig 静态 Bearer Token，对应一个身份
+type TokenConfig struct {
+	Token string `mapstructure:"token"`
+	User  string `mapstructure:"user"`
+	// Role 为 cluster-admin 时可以访问所有 namespace 与集群级资源
+	Role string `mapstructure:"role"`
+	// Namespaces 允许访问的 namespace（"*" 表示全部）
+	Namespaces []string `mapstructure:"namespaces"`
+	// Kinds 允许访问的资源种类（例如 Pod、Deployment，不区分大小写），为空表示全部
+	Kinds []string `mapstructure:"kinds"`
+}
+
+// APIServerConfig apiserver 配置
+type APIServerConfig struct {
+	// UsageInterval 按客户端身份统计的请求数据写入 ClientUsage 的间隔（默认 1m，off 关闭统计）
+	UsageInterval string `mapstructure:"usage_interval"`
+	// SelfRegisterInterval 把本节点 apiserver/dashboard 的地址刷新到 kube-system/k3-apiserver Endpoints 的间隔（默认 30s，off 关闭自注册）
+	SelfRegisterInterval string `mapstructure:"self_register_interval"`
+	// AdvertiseAddress 写入 Endpoints 的本节点地址，为空时使用第一个非回环 IPv4 地址
+	AdvertiseAddress string `mapstructure:"advertise_address"`
+	// WatchKeepalive watch 流（SSE）没有事件时发送心跳注释的间隔（默认 15s，off 关闭），
+	// 用于穿过有空闲超时的反向代理，并尽快发现断开的客户端、释放服务端的 watcher
+	WatchKeepalive string `mapstructure:"watch_keepalive"`
+}
+
+// ControllerConfig 控制器的心跳与同步周期（如 30s、2m），为空时使用默认值，超出允许范围时启动失败（见 intervals.go）。
+// 电池供电的边缘节点可以调长以减少唤醒，演示环境可以调短让状态变化更快可见
+type ControllerConfig struct {
+	// NodeHeartbeat 节点状态（容量、条件、镜像）上报周期，默认 30s，允许 1s~1h
+	NodeHeartbeat string `mapstructure:"node_heartbeat"`
+	// ResyncPeriod 调度器定期重试待调度 Pod 的周期，默认 30s，允许 1s~1h
+	ResyncPeriod string `mapstructure:"resync_period"`
+	// ContainerGCInterval 孤儿容器回收周期，默认 1m，允许 10s~24h
+	ContainerGCInterval string `mapstructure:"container_gc_interval"`
+	// ContainerLogs 把容器的 stdout/stderr 写入本节点的日志文件
+	ContainerLogs ContainerLogsConfig `mapstructure:"container_logs"`
+}
+
+// ContainerLogsConfig 容器日志文件：设置 Dir 后运行时控制器把每个容器的输出写入 Dir/<namespace>_<pod>_<uid>/<container>.log
+// 并按大小轮转，容器被删除或 k3 重启后 Pod 日志 API 仍能读取；Pod 删除时删除其日志目录
+type ContainerLogsConfig struct {
+	// Dir 日志目录（相对路径以配置文件所在目录为基准），为空时不写日志文件
+	Dir string `mapstructure:"dir"`
+	// MaxSize 单个日志文件的大小上限（如 10Mi，默认 10Mi，不小于 1Ki）
+	MaxSize string `mapstructure:"max_size"`
+	// MaxFiles 每个容器保留的日志文件数（包括正在写入的文件），默认 5，允许 1~100
+	MaxFiles int `mapstructure:"max_files"`
+}
+
+// ResourcesConfig 节点预留资源与 Pod 额外开销（取值为 Kubernetes 的数量格式，如 cpu: 250m、memory: 512Mi，只支持 cpu 与 memory）。
+// 资源很少的边缘节点上，操作系统、容器运行时与 k3 进程本身也要占用资源，按容量调度容易把节点挤到 OOM
+type ResourcesConfig struct {
+	// SystemReserved 为操作系统与系统守护进程预留的资源，从本节点上报的 allocatable 中扣除
+	SystemReserved map[string]string `mapstructure:"system_reserved"`
+	// KubeReserved 为 k3 进程与容器运行时预留的资源，同样从 allocatable 中扣除
+	KubeReserved map[string]string `mapstructure:"kube_reserved"`
+	// PodOverhead 没有设置 spec.overhead 的 Pod 在调度时额外占用的资源（沙箱、pause 容器等），调度时写入 spec.overhead
+	PodOverhead map[string]string `mapstructure:"pod_overhead"`
+}
+
+// IdleConfig 空闲模式（见 internal/idle）：超过 After 没有 API 请求、Pod 变化与 dashboard 会话时降低后台活动，
+// 下一个请求到来时立即恢复。用于在笔记本上运行的家庭实验环境，减少不必要的周期性唤醒
+type IdleConfig struct {
+	// After 进入空闲模式前的无活动时长（如 10m），允许 1s~24h，为空或 off 时关闭空闲模式
+	After string `mapstructure:"after"`
+	// HeartbeatMultiplier 空闲时节点心跳周期的倍数，默认 10，允许 1~1000
+	HeartbeatMultiplier int `mapstructure:"heartbeat_multiplier"`
+	// StopDB 空闲时停止本进程自动拉起的存储后端容器，下一个请求到来时重新拉起并等待就绪（期间暂停节点心跳与静态 Pod 同步）
+	StopDB bool `mapstructure:"stop_db"`
+}
+
+// NetworkConfig 局域网节点发现（cmd/network）的心跳与过期时间，以及 Pod/Service 网段（支持 IPv6 与双栈，见 cidrs.go）
+type NetworkConfig struct {
+	// PeerTTL 节点超过该时长没有被发现或探测到时标记为 NotReady，默认 90s，允许 3s~24h，且不小于 2 倍的 ProbeInterval
+	PeerTTL string `mapstructure:"peer_ttl"`
+	// Heartbeat 刷新本节点信息的周期，默认 30s，允许 1s~1h
+	Heartbeat string `mapstructure:"heartbeat"`
+	// ProbeInterval 探测已知节点的周期，默认 15s，允许 1s~1h
+	ProbeInterval string `mapstructure:"probe_interval"`
+	// PodCIDRs 本节点 Pod 使用的网段（写入 Node 的 spec.podCIDRs），双栈时配置一个 IPv4 与一个 IPv6 网段，如 [10.42.0.0/24, fd00:42::/64]
+	PodCIDRs []string `mapstructure:"pod_cidrs"`
+	// ServiceCIDRs Service 的 clusterIP 所在网段，规则同 PodCIDRs；配置后 apiserver 拒绝网段之外的 clusterIP 与没有对应网段的 ipFamilies
+	ServiceCIDRs []string `mapstructure:"service_cidrs"`
+}
+
+// ImageGCConfig 节点镜像回收策略：镜像所在磁盘使用率超过 HighThresholdPercent 时按创建时间从旧到新
+// 删除没有被任何容器使用的镜像，直到使用率降到 LowThresholdPercent 以下。HighThresholdPercent 为 0 时关闭。
+type ImageGCConfig struct {
+	HighThresholdPercent int `mapstructure:"high_threshold_percent"`
+	LowThresholdPercent  int `mapstructure:"low_threshold_percent"`
+	// Interval 检查周期（如 5m，默认 5m）
+	Interval string `mapstructure:"interval"`
+}
+
+// EvictionConfig 节点压力驱逐（简化的 kubelet eviction manager）：可用内存或磁盘低于阈值时在 Node 上设置 MemoryPressure/DiskPressure 条件，
+// 调度器不再向该节点调度 Pod，并每个周期驱逐一个本节点的 Pod（BestEffort 优先）。阈值都为空时关闭
+type EvictionConfig struct {
+	// MemoryAvailable 可用内存（/proc/meminfo 的 MemAvailable）低于该值时处于 MemoryPressure，数量（如 100Mi）或占总量的百分比（如 5%）；只支持 Linux
+	MemoryAvailable string `mapstructure:"memory_available"`
+	// DiskAvailable DiskPath 所在文件系统的可用空间低于该值时处于 DiskPressure，数量（如 1Gi）或百分比（如 10%）
+	DiskAvailable string `mapstructure:"disk_available"`
+	// DiskPath 检查磁盘空间的路径，默认 /（容器运行时的数据目录在其他分区时改为该目录）
+	DiskPath string `mapstructure:"disk_path"`
+	// Interval 检查周期（如 10s，默认 10s）
+	Interval string `mapstructure:"interval"`
+	// PressureTransitionPeriod 低于阈值的情况消失后，条件保持为 True 的时长（默认 5m），避免在阈值附近来回切换
+	PressureTransitionPeriod string `mapstructure:"pressure_transition_period"`
+}
+
+// InventoryConfig 局域网设备清单：周期性读取本机 ARP/neighbor 表，把设备维护为 k3.io/v1 Device 资源。Enabled 为 false 时关闭。
+type InventoryConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval 读取邻居表的周期（如 1m，默认 1m）
+	Interval string `mapstructure:"interval"`
+	// OfflineAfter 设备超过该时长没有出现在任何节点的邻居表中时标记为离线（默认 10m）
+	OfflineAfter string `mapstructure:"offline_after"`
+	// CIDRs 只记录这些网段内的设备；为空时使用本机网卡所在网段
+	CIDRs []string `mapstructure:"cidrs"`
+	// ResolveDNS 邻居表中没有主机名时是否反向解析
+	ResolveDNS bool `mapstructure:"resolve_dns"`
+}
+
+// RetentionConfig 短期对象的保留时长（如 1h、24h），超过后由 TTLController 从 Store 删除；为空表示一直保留。
+// 设置了 spec.ttlSecondsAfterFinished 的 Job 总是按该字段删除
+type RetentionConfig struct {
+	// Events Event 在最后一次发生（lastTimestamp）之后的保留时长
+	Events string `mapstructure:"events"`
+	// SucceededPods / FailedPods 本节点上 Succeeded / Failed 的 Pod 在结束之后的保留时长
+	SucceededPods string `mapstructure:"succeeded_pods"`
+	FailedPods    string `mapstructure:"failed_pods"`
+	// FinishedJobs 没有设置 spec.ttlSecondsAfterFinished 的 Job 在完成或失败之后的保留时长
+	FinishedJobs string `mapstructure:"finished_jobs"`
+	// Interval 检查周期（如 1m，默认 1m）
+	Interval string `mapstructure:"interval"`
+}
+
+// DeschedulerConfig 重新均衡 Pod：周期性找出分布不均的 Pod 并驱逐，由 Deployment 控制器重建后重新调度。
+// 每个节点只驱逐本节点上的 Pod，可以在多个节点同时开启。Enabled 为 false 时关闭
+type DeschedulerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval 检查周期（如 5m，默认 5m）
+	Interval string `mapstructure:"interval"`
+	// DryRun 只记录将要驱逐的 Pod，不实际驱逐
+	DryRun bool `mapstructure:"dry_run"`
+	// MaxEvictions 每轮最多驱逐的 Pod 数（默认 1），避免大量 Pod 同时重建
+	MaxEvictions int `mapstructure:"max_evictions"`
+	// RemoveDuplicates 同一 Deployment 的多个 Pod 集中在本节点、其他节点放得下时驱逐多余的 Pod
+	RemoveDuplicates bool `mapstructure:"remove_duplicates"`
+	// HighThresholdPercent 本节点 requests 占 allocatable 的比例（cpu/memory/pods 任一项）超过该值、
+	// 且有所有项都低于 LowThresholdPercent 的节点时，驱逐本节点的 Pod 直到不超过该值。为 0 时关闭
+	HighThresholdPercent int `mapstructure:"high_threshold_percent"`
+	LowThresholdPercent  int `mapstructure:"low_threshold_percent"`
+}
+
+// NotificationsConfig 资源事件通知：watch Store 中的资源变更，按 sink 配置推送到外部系统（webhook/MQTT/NATS）。
+// 没有配置 sink 时关闭。只在带 apiserver 的进程（master/one/start）中运行，避免多个节点重复推送
+type NotificationsConfig struct {
+	Sinks []NotificationSinkConfig `mapstructure:"sinks"`
+}
+
+// NotificationSinkConfig 一个通知目标及其关注的资源与事件
+type NotificationSinkConfig struct {
+	// Name sink 名称（日志中使用，需唯一）
+	Name string `mapstructure:"name"`
+	// Type webhook / mqtt / nats
+	Type string `mapstructure:"type"`
+	// URL webhook 为 http(s)://...；mqtt 为 tcp://host:1883（ssl:// 或 mqtts:// 使用 TLS）；nats 为 nats://host:4222（tls:// 使用 TLS）
+	URL string `mapstructure:"url"`
+	// Topic MQTT topic / NATS subject，可使用模板（如 k3/{{.Kind}}/{{.Name}}）
+	Topic string `mapstructure:"topic"`
+	// Headers webhook 附加的请求头
+	Headers map[string]string `mapstructure:"headers"`
+	// Username / Password MQTT/NATS 认证（也可以写在 URL 中）
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// Resources 关注的资源（复数小写，如 pods、nodes、deployments）
+	Resources []string `mapstructure:"resources"`
+	// Events 关注的事件类型（ADDED / MODIFIED / DELETED），为空表示全部
+	Events []string `mapstructure:"events"`
+	// Namespaces 只推送这些 namespace 中的资源，为空表示全部（集群级资源不受限制）
+	Namespaces []string `mapstructure:"namespaces"`
+	// Template 消息体模板（Go text/template），为空时推送 JSON 格式的事件
+	Template string `mapstructure:"template"`
+	// Timeout 单次推送超时，默认 5s
+	Timeout string `mapstructure:"timeout"`
+	// MaxRetries 推送失败后的重试次数，默认 3；小于 0 表示不重试
+	MaxRetries int `mapstructure:"max_retries"`
+	// RetryInterval 首次重试的等待时间，默认 1s，之后指数退避
+	RetryInterval string `mapstructure:"retry_interval"`
+	// MaxRetryInterval 重试等待时间上限，默认 30s
+	MaxRetryInterval string `mapstructure:"max_retry_interval"`
+}
+
+// GitOpsConfig gitops 控制器（同步 k3.io/v1 GitRepository）配置。控制器只在带 apiserver 的进程（master/one/start）中运行
+type GitOpsConfig struct {
+	// WorkDir 仓库检出目录（相对路径以配置文件所在目录为基准），默认为配置文件所在目录下的 gitops
+	WorkDir string `mapstructure:"work_dir"`
+}
+
+// TenancyConfig 多租户：Namespace 创建后自动注入默认的 ResourceQuota、LimitRange、NetworkPolicy 与 ServiceAccount，
+// 自助创建的 namespace 一开始就带有限制。只在带 apiserver 的进程（master/one/start）中运行。Enabled 为 false 时关闭
+type TenancyConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Templates 默认资源模板：YAML 文件或目录（相对路径以配置文件所在目录为基准），支持 {{ .Namespace }} 等模板变量；
+	// 为空时使用内置模板
+	Templates string `mapstructure:"templates"`
+	// ExcludeNamespaces 不注入的 namespace，默认 default、kube-system、kube-public、kube-node-lease
+	ExcludeNamespaces []string `mapstructure:"exclude_namespaces"`
+}
+
+// RegistryMirrorConfig 节点内置的镜像拉取缓存（只读的 Docker Registry v2 服务，按需从上游拉取并缓存到磁盘），
+// 以及本节点 Docker 拉取镜像时使用的缓存地址。局域网中的多个节点通过同一个缓存拉取，相同的镜像层只从公网下载一次
+type RegistryMirrorConfig struct {
+	// Enabled 在本节点（node/one/start）启动缓存服务
+	Enabled bool `mapstructure:"enabled"`
+	// Listen 缓存服务监听地址，默认 :5000
+	Listen string `mapstructure:"listen"`
+	// CacheDir 缓存目录（相对路径以配置文件所在目录为基准），默认为配置文件所在目录下的 registry-cache
+	CacheDir string `mapstructure:"cache_dir"`
+	// MaxSize 缓存上限（如 20Gi），超过后按最近访问时间删除镜像层；为空表示不限制
+	MaxSize string `mapstructure:"max_size"`
+	// ManifestTTL tag 对应的 manifest 缓存多久后向上游重新确认（上游不可用时继续使用缓存），默认 10m
+	ManifestTTL string `mapstructure:"manifest_ttl"`
+	// Upstreams 上游仓库的地址与认证；没有配置的仓库使用 https://<仓库域名>（docker.io 为 https://registry-1.docker.io）
+	Upstreams []RegistryUpstreamConfig `mapstructure:"upstreams"`
+	// Endpoint 本节点 Docker 通过该缓存拉取镜像（host:port，如 192.168.1.10:5000）；
+	// 为空时：本节点开启了缓存则使用 127.0.0.1:<端口>，否则直接从上游拉取。
+	// 缓存服务为 http，非 127.0.0.1 的地址需要加入 Docker daemon 的 insecure-registries
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// RegistryUpstreamConfig 一个上游仓库
+type RegistryUpstreamConfig struct {
+	// Host 镜像名中的仓库域名（如 docker.io、ghcr.io、192.168.1.20:5000）
+	Host string `mapstructure:"host"`
+	// URL 上游地址（如 https://mirror.gcr.io），为空时为 https://<host>
+	URL string `mapstructure:"url"`
+	// Username / Password 上游认证（私有仓库或提高 Docker Hub 的拉取限额），为空时匿名拉取
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// APIProxyConfig 节点本地的 apiserver 缓存代理：GET/LIST 由本地 informer 缓存提供，
+// 与 apiserver 短暂断开时写请求进入队列，恢复后按顺序重放（对象在此期间被修改时记为冲突，不覆盖）
+type APIProxyConfig struct {
+	// Enabled 在本节点（node/one/start）启动代理
+	Enabled bool `mapstructure:"enabled"`
+	// Listen 代理监听地址，默认 127.0.0.1:8081。缓存读取以 token 的身份进行，不建议监听在非本机地址
+	Listen string `mapstructure:"listen"`
+	// Upstream apiserver 地址，为空时使用 cluster.server
+	Upstream string `mapstructure:"upstream"`
+	// Token 访问 apiserver 使用的 Bearer Token（开启认证时必填）；请求自带 Authorization 时写请求使用请求自己的
+	Token string `mapstructure:"token"`
+	// MaxQueue 断开期间最多排队的写请求数，默认 100，队列满时写请求返回 503
+	MaxQueue int `mapstructure:"max_queue"`
+	// QueueTTL 排队的写请求最长保留时间，超过后丢弃并记为冲突，默认 5m
+	QueueTTL string `mapstructure:"queue_ttl"`
+	// HealthInterval 检查 apiserver 是否恢复（GET /api/readyz）的间隔，默认 5s
+	HealthInterval string `mapstructure:"health_interval"`
 }
 
 type GinConfig struct {
-	Port int  `mapstructure:"port"`
-	CORS bool `mapstructure:"cors"`
+	Port int `mapstructure:"port"`
+	// CORS 允许浏览器跨域访问，策略见 CORSPolicy
+	CORS       bool       `mapstructure:"cors"`
+	CORSPolicy CORSConfig `mapstructure:"cors_policy"`
+	// MethodOverride 允许 POST 请求通过 X-HTTP-Method-Override 头改为 PUT/PATCH/DELETE（用于只放行 GET/POST 的代理）
+	MethodOverride bool `mapstructure:"method_override"`
+	// SnapshotMaxObjects Dashboard 快照中 Pod 数的上限，超过后改为分页拉取；0 使用默认值 2000，小于 0 不限制
+	SnapshotMaxObjects int `mapstructure:"snapshot_max_objects"`
+	// AdminPort 调试端口（/debug/pprof、/debug/vars、/debug/goroutines），0 表示不开启；
+	// 开启认证时只对 cluster-admin 开放，未开启认证时只监听 127.0.0.1
+	AdminPort int `mapstructure:"admin_port"`
+	// BasePath 经反向代理以子路径对外提供服务时的路径前缀（如 /k3）：路由、SSE/WebSocket 与看板中的链接都在该前缀下，
+	// 代理去掉前缀后转发的请求同样可以处理。为空表示挂在根路径
+	BasePath string `mapstructure:"base_path"`
+	// TrustedProxies 信任的反向代理地址或网段（如 10.0.0.1、172.16.0.0/12）：只有来自这些地址的请求才按
+	// X-Forwarded-For/X-Forwarded-Proto/X-Forwarded-Host 取客户端 IP、协议与主机名；为空时忽略这些请求头
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// CORSConfig 跨域策略（web.cors 为 true 时生效），未设置的字段使用默认值
+type CORSConfig struct {
+	// AllowOrigins 允许的来源（如 https://dash.example.com、https://*.example.com），默认 ["*"]
+	AllowOrigins []string `mapstructure:"allow_origins"`
+	// AllowMethods 允许的方法，默认 GET、POST、PUT、PATCH、DELETE、HEAD、OPTIONS
+	AllowMethods []string `mapstructure:"allow_methods"`
+	// AllowHeaders 允许的请求头，为空时允许预检请求中声明的所有请求头
+	AllowHeaders []string `mapstructure:"allow_headers"`
+	// ExposeHeaders 浏览器脚本可以读取的响应头
+	ExposeHeaders []string `mapstructure:"expose_headers"`
+	// AllowCredentials 允许携带 Cookie 等凭据；开启时 AllowOrigins 不能为 *
+	AllowCredentials bool `mapstructure:"allow_credentials"`
+	// MaxAge 浏览器缓存预检结果的时长（如 10m，默认 10m），0s 表示不缓存
+	MaxAge string `mapstructure:"max_age"`
 }
 
 // WebConfig is an alias for GinConfig for backward compatibility
@@ -58,6 +386,36 @@ type StorageConfig struct {
 	Type  string      `mapstructure:"type"` // memory / mysql / etcd
 	MySQL MySQLConfig `mapstructure:"mysql"`
 	Etcd  EtcdConfig  `mapstructure:"etcd"`
+	// StaticPodPath 静态 Pod manifest 目录：本机自动拉起的 etcd/MySQL 以静态 Pod 方式写入这里，
+	// 由 controller 管理并在 storage namespace 中维护 mirror Pod。默认为配置文件所在目录下的 manifests
+	StaticPodPath string `mapstructure:"static_pod_path"`
+	// CircuitBreaker MySQL/etcd 后端不可用时的熔断与重连配置
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	// HistoryRevisions 每个对象保留的历史版本数（GET ...?revision=N、k3 history），默认 10；小于 0 表示不记录历史
+	HistoryRevisions int `mapstructure:"history_revisions"`
+}
+
+// BootstrapConfig 启动时按需拉起的基础设施容器
+type BootstrapConfig struct {
+	// AutoStartDB 存储指向本机时是否自动拉起 MySQL/etcd 容器，默认 true；
+	// 自行管理数据库时设为 false（等价于 --no-bootstrap-containers 或环境变量 AUTO_START_DB=false）
+	AutoStartDB *bool `mapstructure:"auto_start_db"`
+}
+
+// AutoStartDBEnabled 是否自动拉起本机的存储容器（未配置时为 true）
+func (c BootstrapConfig) AutoStartDBEnabled() bool {
+	return c.AutoStartDB == nil || *c.AutoStartDB
+}
+
+// CircuitBreakerConfig 外部存储（MySQL/etcd）熔断配置：连续 FailureThreshold 次连接类错误后熔断，
+// 熔断期间请求直接失败（apiserver 返回 503），后台按 RetryInterval 起、MaxRetryInterval 封顶的指数退避重连
+type CircuitBreakerConfig struct {
+	// FailureThreshold 连续多少次后端错误后熔断，默认 3；小于 0 表示关闭熔断
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	// RetryInterval 熔断后首次重连的等待时间，默认 1s
+	RetryInterval string `mapstructure:"retry_interval"`
+	// MaxRetryInterval 重连等待时间上限，默认 30s
+	MaxRetryInterval string `mapstructure:"max_retry_interval"`
 }
 
 type MySQLConfig struct {
@@ -68,6 +426,21 @@ type MySQLConfig struct {
 	Database     string `mapstructure:"database"`
 	MaxOpenConns int    `mapstructure:"max_open_conns"`
 	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+	// Replicas 只读副本：Get/List 轮流发往副本，写入只发往主库
+	Replicas []MySQLReplicaConfig `mapstructure:"replicas"`
+	// ReplicaMaxLag 副本的最大复制延迟，默认 5s：本实例在这段时间内写入的对象从副本读到的还是旧版本时改读主库
+	ReplicaMaxLag string `mapstructure:"replica_max_lag"`
+	// 本机自动拉起 MySQL 容器时使用（默认镜像 mysql:8.0）
+	Container ContainerConfig `mapstructure:",squash"`
+}
+
+// MySQLReplicaConfig MySQL 只读副本的连接信息，未设置的 port/user/password/database 与主库相同
+type MySQLReplicaConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	Database string `mapstructure:"database"`
 }
 
 type EtcdConfig struct {
@@ -75,6 +448,43 @@ type EtcdConfig struct {
 	DialTimeout string   `mapstructure:"dial_timeout"`
 	Username    string   `mapstructure:"username"`
 	Password    string   `mapstructure:"password"`
+	// RequestTimeout 单次读写请求超时，默认 5s（etcd 不可用时请求不会一直阻塞）
+	RequestTimeout string `mapstructure:"request_timeout"`
+	// Prefix 所有键（资源、历史、索引、schema 记录）与 watch 的前缀，默认 /k3。
+	// 多个集群共用一个 etcd 时各自设置不同的前缀（如 /k3/<cluster-name>）
+	Prefix string `mapstructure:"prefix"`
+	// MigrateFrom 修改 Prefix 时填写原来的前缀：新前缀下还没有数据时，启动时把原前缀下的数据移过来
+	MigrateFrom string `mapstructure:"migrate_from"`
+	// 本机自动拉起 etcd 容器时使用（默认镜像 quay.io/coreos/etcd:v3.5.0）
+	Container ContainerConfig `mapstructure:",squash"`
+}
+
+// DiscoveryConfig 服务发现配置
+type DiscoveryConfig struct {
+	Consul ConsulConfig `mapstructure:"consul"`
+}
+
+// ConsulConfig Consul 配置
+type ConsulConfig struct {
+	// 本机自动拉起 Consul 容器时使用（默认镜像 consul:1.17）
+	Container ContainerConfig `mapstructure:",squash"`
+	// PodHealthChecks 把被 Service 选中的 Pod 注册到 Consul（检查取自 readinessProbe），并把检查结果写回 Pod 的 consul.k3.io/healthy 条件
+	PodHealthChecks bool `mapstructure:"pod_health_checks"`
+}
+
+// ContainerConfig 自动拉起的基础设施容器（etcd/MySQL/Consul）的镜像与运行参数，与所在配置块平级展开
+// （如 storage.mysql.image、discovery.consul.data_dir）。未设置的字段使用内置默认值。
+type ContainerConfig struct {
+	// Image 镜像（可指向私有仓库/镜像站，便于离线环境使用）
+	Image string `mapstructure:"image"`
+	// ImagePullPolicy 镜像拉取策略：Always / IfNotPresent（默认）/ Never
+	ImagePullPolicy string `mapstructure:"image_pull_policy"`
+	// ExtraArgs 追加到容器启动参数末尾
+	ExtraArgs []string `mapstructure:"extra_args"`
+	// ExtraEnv 追加的环境变量，格式 KEY=VALUE（同名时覆盖内置值）
+	ExtraEnv []string `mapstructure:"extra_env"`
+	// DataDir 宿主机数据目录（相对路径以配置文件所在目录为基准），设置后挂载到容器的数据目录，容器重建后数据不丢失；为空时数据随容器删除
+	DataDir string `mapstructure:"data_dir"`
 }
 
 func NewFileConfig() Config {
@@ -102,5 +512,41 @@ func NewFileConfig() Config {
 		log.Fatalln("无法解析配置文件:", err.Error())
 	}
 
+	// 环境变量 AUTO_START_DB 覆盖 bootstrap.auto_start_db（k3 --no-bootstrap-containers 通过它传入）
+	if v := os.Getenv("AUTO_START_DB"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalln("无法解析环境变量 AUTO_START_DB:", err.Error())
+		}
+		config.Bootstrap.AutoStartDB = &enabled
+	}
+	if config.Storage.StaticPodPath == "" {
+		config.Storage.StaticPodPath = filepath.Join(filepath.Dir(configPath), "manifests")
+	}
+	if config.GitOps.WorkDir == "" {
+		config.GitOps.WorkDir = "gitops"
+	}
+	if !filepath.IsAbs(config.GitOps.WorkDir) {
+		config.GitOps.WorkDir = filepath.Join(filepath.Dir(configPath), config.GitOps.WorkDir)
+	}
+	if config.Tenancy.Templates != "" && !filepath.IsAbs(config.Tenancy.Templates) {
+		config.Tenancy.Templates = filepath.Join(filepath.Dir(configPath), config.Tenancy.Templates)
+	}
+	if config.RegistryMirror.CacheDir == "" {
+		config.RegistryMirror.CacheDir = "registry-cache"
+	}
+	if !filepath.IsAbs(config.RegistryMirror.CacheDir) {
+		config.RegistryMirror.CacheDir = filepath.Join(filepath.Dir(configPath), config.RegistryMirror.CacheDir)
+	}
+	if config.Controller.ContainerLogs.Dir != "" && !filepath.IsAbs(config.Controller.ContainerLogs.Dir) {
+		config.Controller.ContainerLogs.Dir = filepath.Join(filepath.Dir(configPath), config.Controller.ContainerLogs.Dir)
+	}
+	// 基础设施容器的相对 data_dir 以配置文件所在目录为基准
+	for _, c := range []*ContainerConfig{&config.Storage.MySQL.Container, &config.Storage.Etcd.Container, &config.Discovery.Consul.Container} {
+		if c.DataDir != "" && !filepath.IsAbs(c.DataDir) {
+			c.DataDir = filepath.Join(filepath.Dir(configPath), c.DataDir)
+		}
+	}
+
 	return config
 }
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// 容器日志文件的默认值
+const (
+	DefaultContainerLogMaxSize  = 10 << 20
+	DefaultContainerLogMaxFiles = 5
+)
+
+// ContainerLogSettings 解析后的容器日志文件配置
+type ContainerLogSettings struct {
+	// Dir 日志目录，为空表示不写日志文件（日志 API 直接读取容器运行时）
+	Dir string
+	// MaxSize 单个日志文件的大小上限（字节），超过后轮转
+	MaxSize int64
+	// MaxFiles 每个容器保留的日志文件数（包括正在写入的文件）
+	MaxFiles int
+}
+
+// Enabled 是否把容器日志写入本地文件
+func (s ContainerLogSettings) Enabled() bool {
+	return s.Dir != ""
+}
+
+// Settings 解析并校验容器日志文件配置，未配置的项使用默认值
+func (c ContainerLogsConfig) Settings() (ContainerLogSettings, error) {
+	out := ContainerLogSettings{Dir: c.Dir, MaxSize: DefaultContainerLogMaxSize, MaxFiles: DefaultContainerLogMaxFiles}
+	if c.MaxSize != "" {
+		q, err := resource.ParseQuantity(c.MaxSize)
+		if err != nil {
+			return out, fmt.Errorf("controller.container_logs.max_size 无效: %q: %w", c.MaxSize, err)
+		}
+		if q.Value() < 1024 {
+			return out, fmt.Errorf("controller.container_logs.max_size 不能小于 1Ki: %q", c.MaxSize)
+		}
+		out.MaxSize = q.Value()
+	}
+	if c.MaxFiles != 0 {
+		if c.MaxFiles < 1 || c.MaxFiles > 100 {
+			return out, fmt.Errorf("controller.container_logs.max_files 需要在 1~100 之间: %d", c.MaxFiles)
+		}
+		out.MaxFiles = c.MaxFiles
+	}
+	return out, nil
+}
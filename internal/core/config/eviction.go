@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// 节点压力驱逐的默认值
+const (
+	DefaultEvictionInterval                 = 10 * time.Second
+	DefaultEvictionPressureTransitionPeriod = 5 * time.Minute
+	DefaultEvictionDiskPath                 = "/"
+)
+
+// EvictionThreshold 一个驱逐阈值：固定数量（字节）或占总量的百分比
+type EvictionThreshold struct {
+	Bytes   int64
+	Percent float64
+}
+
+// Value 返回总量为 capacity 时的阈值（字节）
+func (t EvictionThreshold) Value(capacity uint64) uint64 {
+	if t.Percent > 0 {
+		return uint64(float64(capacity) * t.Percent / 100)
+	}
+	return uint64(t.Bytes)
+}
+
+// String 返回阈值的配置写法
+func (t EvictionThreshold) String() string {
+	if t.Percent > 0 {
+		return strconv.FormatFloat(t.Percent, 'f', -1, 64) + "%"
+	}
+	return resource.NewQuantity(t.Bytes, resource.BinarySI).String()
+}
+
+// EvictionSettings 解析后的节点压力驱逐配置
+type EvictionSettings struct {
+	// Memory / Disk 为 nil 表示不检查该项
+	Memory *EvictionThreshold
+	Disk   *EvictionThreshold
+
+	DiskPath                 string
+	Interval                 time.Duration
+	PressureTransitionPeriod time.Duration
+}
+
+// Enabled 是否配置了任一阈值
+func (s EvictionSettings) Enabled() bool {
+	return s.Memory != nil || s.Disk != nil
+}
+
+// Settings 解析并校验节点压力驱逐配置，未配置的周期使用默认值
+func (c EvictionConfig) Settings() (EvictionSettings, error) {
+	out := EvictionSettings{DiskPath: c.DiskPath}
+	if out.DiskPath == "" {
+		out.DiskPath = DefaultEvictionDiskPath
+	}
+	var err error
+	if out.Memory, err = parseEvictionThreshold("eviction.memory_available", c.MemoryAvailable); err != nil {
+		return out, err
+	}
+	if out.Disk, err = parseEvictionThreshold("eviction.disk_available", c.DiskAvailable); err != nil {
+		return out, err
+	}
+	if out.Interval, err = parseBoundedDuration("eviction.interval", c.Interval, DefaultEvictionInterval, time.Second, time.Hour); err != nil {
+		return out, err
+	}
+	if out.PressureTransitionPeriod, err = parseBoundedDuration("eviction.pressure_transition_period", c.PressureTransitionPeriod,
+		DefaultEvictionPressureTransitionPeriod, 0, 24*time.Hour); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// parseEvictionThreshold 解析数量（如 100Mi）或百分比（如 10%，0 到 100 之间），为空时返回 nil
+func parseEvictionThreshold(key, value string) (*EvictionThreshold, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if p, ok := strings.CutSuffix(value, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("%s 无效: %q（百分比应在 0 到 100 之间）", key, value)
+		}
+		return &EvictionThreshold{Percent: percent}, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil || q.Sign() <= 0 {
+		return nil, fmt.Errorf("%s 无效: %q", key, value)
+	}
+	return &EvictionThreshold{Bytes: q.Value()}, nil
+}
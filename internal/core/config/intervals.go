@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// 心跳与同步周期的默认值
+const (
+	DefaultNodeHeartbeat       = 30 * time.Second
+	DefaultResyncPeriod        = 30 * time.Second
+	DefaultContainerGCInterval = time.Minute
+	DefaultPeerTTL             = 90 * time.Second
+	DefaultNetworkHeartbeat    = 30 * time.Second
+	DefaultProbeInterval       = 15 * time.Second
+)
+
+// DefaultIdleHeartbeatMultiplier 未配置 idle.heartbeat_multiplier 时空闲模式下节点心跳周期的倍数
+const DefaultIdleHeartbeatMultiplier = 10
+
+// ControllerIntervals 解析后的控制器周期
+type ControllerIntervals struct {
+	NodeHeartbeat       time.Duration
+	ResyncPeriod        time.Duration
+	ContainerGCInterval time.Duration
+}
+
+// Intervals 解析并校验控制器周期，未配置的项使用默认值
+func (c ControllerConfig) Intervals() (ControllerIntervals, error) {
+	var out ControllerIntervals
+	var err error
+	if out.NodeHeartbeat, err = parseBoundedDuration("controller.node_heartbeat", c.NodeHeartbeat, DefaultNodeHeartbeat, time.Second, time.Hour); err != nil {
+		return out, err
+	}
+	if out.ResyncPeriod, err = parseBoundedDuration("controller.resync_period", c.ResyncPeriod, DefaultResyncPeriod, time.Second, time.Hour); err != nil {
+		return out, err
+	}
+	if out.ContainerGCInterval, err = parseBoundedDuration("controller.container_gc_interval", c.ContainerGCInterval, DefaultContainerGCInterval, 10*time.Second, 24*time.Hour); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// NetworkIntervals 解析后的节点发现周期
+type NetworkIntervals struct {
+	PeerTTL       time.Duration
+	Heartbeat     time.Duration
+	ProbeInterval time.Duration
+}
+
+// Intervals 解析并校验节点发现周期，未配置的项使用默认值。
+// PeerTTL 小于 2 倍的 ProbeInterval 时，一次探测稍有延迟节点就会在 Ready/NotReady 之间来回切换，因此拒绝
+func (c NetworkConfig) Intervals() (NetworkIntervals, error) {
+	var out NetworkIntervals
+	var err error
+	if out.PeerTTL, err = parseBoundedDuration("network.peer_ttl", c.PeerTTL, DefaultPeerTTL, 3*time.Second, 24*time.Hour); err != nil {
+		return out, err
+	}
+	if out.Heartbeat, err = parseBoundedDuration("network.heartbeat", c.Heartbeat, DefaultNetworkHeartbeat, time.Second, time.Hour); err != nil {
+		return out, err
+	}
+	if out.ProbeInterval, err = parseBoundedDuration("network.probe_interval", c.ProbeInterval, DefaultProbeInterval, time.Second, time.Hour); err != nil {
+		return out, err
+	}
+	if out.PeerTTL < 2*out.ProbeInterval {
+		return out, fmt.Errorf("network.peer_ttl（%s）不能小于 2 倍的 network.probe_interval（%s）", out.PeerTTL, out.ProbeInterval)
+	}
+	return out, nil
+}
+
+// IdleSettings 解析后的空闲模式配置
+type IdleSettings struct {
+	// After 进入空闲模式前的无活动时长，0 表示关闭空闲模式
+	After               time.Duration
+	HeartbeatMultiplier int
+	StopDB              bool
+}
+
+// Settings 解析并校验空闲模式配置，未配置的项使用默认值
+func (c IdleConfig) Settings() (IdleSettings, error) {
+	out := IdleSettings{HeartbeatMultiplier: DefaultIdleHeartbeatMultiplier, StopDB: c.StopDB}
+	if c.After != "off" {
+		var err error
+		if out.After, err = parseBoundedDuration("idle.after", c.After, 0, time.Second, 24*time.Hour); err != nil {
+			return out, err
+		}
+	}
+	switch m := c.HeartbeatMultiplier; {
+	case m == 0:
+	case m < 1 || m > 1000:
+		return out, fmt.Errorf("idle.heartbeat_multiplier 超出允许范围 1~1000: %d", m)
+	default:
+		out.HeartbeatMultiplier = m
+	}
+	return out, nil
+}
+
+// parseBoundedDuration 解析时长配置，为空时返回默认值，超出 [min, max] 时返回错误
+func parseBoundedDuration(key, value string, def, min, max time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s 无效: %q", key, value)
+	}
+	if d < min || d > max {
+		return 0, fmt.Errorf("%s 超出允许范围 %s~%s: %q", key, min, max, value)
+	}
+	return d, nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResourceSettings 解析后的节点预留资源与 Pod 额外开销
+type ResourceSettings struct {
+	// Reserved system_reserved 与 kube_reserved 之和，从节点容量中扣除得到 allocatable
+	Reserved corev1.ResourceList
+	// PodOverhead 没有设置 spec.overhead 的 Pod 的默认额外开销，为空表示没有
+	PodOverhead corev1.ResourceList
+}
+
+// Settings 解析并校验预留资源与 Pod 额外开销：只允许 cpu 与 memory，数量不能为负
+func (c ResourcesConfig) Settings() (ResourceSettings, error) {
+	var out ResourceSettings
+	system, err := parseResourceList("resources.system_reserved", c.SystemReserved)
+	if err != nil {
+		return out, err
+	}
+	kube, err := parseResourceList("resources.kube_reserved", c.KubeReserved)
+	if err != nil {
+		return out, err
+	}
+	out.Reserved = system
+	for name, q := range kube {
+		sum := out.Reserved[name]
+		sum.Add(q)
+		out.Reserved[name] = sum
+	}
+	if out.PodOverhead, err = parseResourceList("resources.pod_overhead", c.PodOverhead); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// parseResourceList 解析 cpu/memory 的数量配置
+func parseResourceList(key string, values map[string]string) (corev1.ResourceList, error) {
+	out := corev1.ResourceList{}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch corev1.ResourceName(name) {
+		case corev1.ResourceCPU, corev1.ResourceMemory:
+		default:
+			return nil, fmt.Errorf("%s 只支持 cpu 与 memory: %q", key, name)
+		}
+		q, err := resource.ParseQuantity(values[name])
+		if err != nil || q.Sign() < 0 {
+			return nil, fmt.Errorf("%s.%s 无效: %q", key, name, values[name])
+		}
+		out[corev1.ResourceName(name)] = q
+	}
+	return out, nil
+}
@@ -35,6 +35,8 @@ type GormLogger struct {
 var (
 	globalLogger *Logger
 	zapLogger    *zap.Logger
+	// atomicLevel 是 zapLogger 的日志级别，SetLevel 在运行时修改它
+	atomicLevel zap.AtomicLevel
 )
 
 // 仅用于极少数场景，请勿随意使用
@@ -175,21 +177,12 @@ func newLogger(config config.Config) Logger {
 		}
 	}
 	zapConfig.Encoding = "console"
-	logLevel := config.Log.Level
-	level := zap.PanicLevel
-	switch logLevel {
-	case "debug":
-		level = zapcore.DebugLevel
-	case "info":
-		level = zapcore.InfoLevel
-	case "warn":
-		level = zapcore.WarnLevel
-	case "error":
-		level = zapcore.ErrorLevel
-	case "fatal":
-		level = zapcore.FatalLevel
+	level, ok := ParseLevel(config.Log.Level)
+	if !ok {
+		level = zap.PanicLevel
 	}
 	zapConfig.Level.SetLevel(level)
+	atomicLevel = zapConfig.Level
 
 	var err error
 	zapLogger, err = zapConfig.Build()
@@ -202,6 +195,54 @@ func newLogger(config config.Config) Logger {
 	return *logger
 }
 
+// ParseLevel 解析配置中的日志级别（debug/info/warn/error/fatal）
+func ParseLevel(name string) (zapcore.Level, bool) {
+	switch name {
+	case "debug":
+		return zapcore.DebugLevel, true
+	case "info":
+		return zapcore.InfoLevel, true
+	case "warn":
+		return zapcore.WarnLevel, true
+	case "error":
+		return zapcore.ErrorLevel, true
+	case "fatal":
+		return zapcore.FatalLevel, true
+	}
+	return zap.PanicLevel, false
+}
+
+// SetLevel 在运行时修改日志级别（例如 ClusterConfiguration 的 logLevel），对所有派生的 logger 生效
+func SetLevel(name string) error {
+	level, ok := ParseLevel(name)
+	if !ok {
+		return fmt.Errorf("unknown log level: %q", name)
+	}
+	if zapLogger == nil {
+		return fmt.Errorf("logger is not initialized")
+	}
+	atomicLevel.SetLevel(level)
+	return nil
+}
+
+// CurrentLevel 返回当前的日志级别名称（debug/info/warn/error...），logger 未初始化时为空
+func CurrentLevel() string {
+	if zapLogger == nil {
+		return ""
+	}
+	return atomicLevel.Level().String()
+}
+
+// SwapLevel 把日志级别设为 level 并返回之前的级别（可以恢复 log.level 未设置时的 panic），logger 未初始化时返回 false
+func SwapLevel(level zapcore.Level) (zapcore.Level, bool) {
+	if zapLogger == nil {
+		return level, false
+	}
+	previous := atomicLevel.Level()
+	atomicLevel.SetLevel(level)
+	return previous, true
+}
+
 // Write interface implementation for gin-framework
 func (l *GinLogger) Write(p []byte) (n int, err error) {
 	l.Info(string(p))
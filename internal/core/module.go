@@ -13,4 +13,6 @@ var CoreModule = fx.Options(
 	//todo 集成数据库
 	// fx.Provide(NewDatabase),
 	fx.Provide(webprovider.NewFiberEngine),
+	// web.admin_port 上的调试端口（pprof、expvar）
+	fx.Invoke(webprovider.StartAdminServer),
 )
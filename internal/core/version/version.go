@@ -0,0 +1,4 @@
+package version
+
+// Version k3 版本号，构建时通过 -ldflags "-X github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/version.Version=v0.3.0" 注入
+var Version = "dev"
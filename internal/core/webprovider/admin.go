@@ -0,0 +1,93 @@
+package webprovider
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"fmt"
+	"net"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/gofiber/fiber/v2"
+	fiberexpvar "github.com/gofiber/fiber/v2/middleware/expvar"
+	fiberpprof "github.com/gofiber/fiber/v2/middleware/pprof"
+	"go.uber.org/fx"
+)
+
+// publishVarsOnce expvar 的变量只能发布一次
+var publishVarsOnce sync.Once
+
+// NewAdminApp 创建调试端口上的应用：/debug/pprof/*（net/http/pprof）、/debug/vars（expvar）与
+// /debug/goroutines（所有 goroutine 的调用栈）。经过与 API 相同的认证，且只对 cluster-admin 开放
+func NewAdminApp(cfg config.Config) *fiber.App {
+	publishVarsOnce.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+	})
+
+	app := fiber.New(fiber.Config{
+		AppName:               "Hermes admin",
+		DisableStartupMessage: true,
+		ErrorHandler:          ErrorHandler,
+	})
+	app.Use(NewAuthMiddleware(cfg))
+	app.Use(func(c *fiber.Ctx) error {
+		if !IdentityFromCtx(c).IsClusterAdmin() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: requires cluster-admin"})
+		}
+		return c.Next()
+	})
+	app.Use(fiberpprof.New())
+	app.Use(fiberexpvar.New())
+	app.Get("/debug/goroutines", handleGoroutines)
+	return app
+}
+
+// handleGoroutines 以文本输出所有 goroutine 的完整调用栈（与 /debug/pprof/goroutine?debug=2 相同）
+func handleGoroutines(c *fiber.Ctx) error {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	return c.Send(buf.Bytes())
+}
+
+// adminListenAddress 返回调试端口的监听地址：未开启认证时只监听本机，避免调试端点暴露到网络
+func adminListenAddress(cfg config.Config) string {
+	if !cfg.Auth.Enabled {
+		return fmt.Sprintf("127.0.0.1:%d", cfg.Gin.AdminPort)
+	}
+	return fmt.Sprintf(":%d", cfg.Gin.AdminPort)
+}
+
+// StartAdminServer 在 web.admin_port 上启动调试端口（未配置时不启动），随进程生命周期启停
+func StartAdminServer(lc fx.Lifecycle, cfg config.Config, l logprovider.Logger) {
+	if cfg.Gin.AdminPort <= 0 {
+		return
+	}
+	app := NewAdminApp(cfg)
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			addr := adminListenAddress(cfg)
+			// 同步监听，端口冲突时启动失败
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("无法监听调试端口 %s: %w", addr, err)
+			}
+			l.Infof("调试端口已启动 http://%s/debug/pprof/", addr)
+			go func() {
+				if err := app.Listener(ln); err != nil {
+					l.Errorf("调试端口退出: %v", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return app.Shutdown()
+		},
+	})
+}
@@ -0,0 +1,152 @@
+package webprovider
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RoleClusterAdmin 可以访问所有 namespace 与集群级资源
+const RoleClusterAdmin = "cluster-admin"
+
+// identityKey 是 Identity 在 fiber.Ctx Locals 中的键
+const identityKey = "identity"
+
+// Identity 是请求方身份
+type Identity struct {
+	User string
+	Role string
+	// Namespaces 允许访问的 namespace（"*" 表示全部）
+	Namespaces []string
+	// Kinds 允许访问的资源种类（Kind，不区分大小写；为空或 "*" 表示全部）
+	Kinds []string
+}
+
+// IsClusterAdmin 是否为集群管理员
+func (id *Identity) IsClusterAdmin() bool {
+	return id == nil || id.Role == RoleClusterAdmin
+}
+
+// AllowsNamespace 是否可以访问 namespace；集群级资源（namespace 为空）只读可见，由调用方判断写权限
+func (id *Identity) AllowsNamespace(namespace string) bool {
+	if id.IsClusterAdmin() || namespace == "" {
+		return true
+	}
+	for _, ns := range id.Namespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// NamespaceFilter 返回按 namespace 过滤的函数；不受限时返回 nil
+func (id *Identity) NamespaceFilter() func(namespace string) bool {
+	if id.IsClusterAdmin() {
+		return nil
+	}
+	for _, ns := range id.Namespaces {
+		if ns == "*" {
+			return nil
+		}
+	}
+	return id.AllowsNamespace
+}
+
+// AllowsKind 是否可以访问该种类的资源
+func (id *Identity) AllowsKind(kind string) bool {
+	if id.IsClusterAdmin() || len(id.Kinds) == 0 {
+		return true
+	}
+	for _, k := range id.Kinds {
+		if k == "*" || strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// KindFilter 返回按资源种类过滤的函数；不受限时返回 nil
+func (id *Identity) KindFilter() func(kind string) bool {
+	if id.IsClusterAdmin() || len(id.Kinds) == 0 {
+		return nil
+	}
+	for _, k := range id.Kinds {
+		if k == "*" {
+			return nil
+		}
+	}
+	return id.AllowsKind
+}
+
+// IdentityFromCtx 返回当前请求的身份；未开启认证时返回 nil（视为不受限）
+func IdentityFromCtx(c *fiber.Ctx) *Identity {
+	id, _ := c.Locals(identityKey).(*Identity)
+	return id
+}
+
+// IdentityFromLocals 用于 websocket 等只暴露 Locals 的场景
+func IdentityFromLocals(locals func(key string) interface{}) *Identity {
+	id, _ := locals(identityKey).(*Identity)
+	return id
+}
+
+// authExemptPaths 不需要认证的路径（探活与看板静态页面）
+var authExemptPaths = map[string]bool{
+	"/":            true,
+	"/dashboard":   true,
+	"/api/health":  true,
+	"/api/healthz": true,
+	"/api/readyz":  true,
+	"/index":       true,
+}
+
+// NewAuthMiddleware 根据 auth 配置解析 Bearer Token（静态 token 或 JWT）并写入 Identity。
+// 未开启时直接放行；开启后无有效凭证返回 401。
+// 浏览器的 WebSocket 无法设置 Authorization 头，因此也接受 ?access_token= 查询参数。
+func NewAuthMiddleware(cfg config.Config) fiber.Handler {
+	if !cfg.Auth.Enabled {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	return func(c *fiber.Ctx) error {
+		if authExemptPaths[c.Path()] {
+			return c.Next()
+		}
+		token := strings.TrimSpace(strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "))
+		if token == "" {
+			token = c.Query("access_token")
+		}
+		if token == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized: bearer token required"})
+		}
+		id := identityForToken(cfg, token)
+		if id == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized: invalid token"})
+		}
+		c.Locals(identityKey, id)
+		return c.Next()
+	}
+}
+
+func identityForToken(cfg config.Config, token string) *Identity {
+	for _, t := range cfg.Auth.Tokens {
+		if t.Token != "" && subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return &Identity{User: t.User, Role: t.Role, Namespaces: t.Namespaces, Kinds: t.Kinds}
+		}
+	}
+	if len(cfg.JWT.SigningKey) == 0 {
+		return nil
+	}
+	j := JWT{SigningKey: cfg.JWT.SigningKey}
+	claims, err := j.ParseToken(token)
+	if err != nil {
+		return nil
+	}
+	user := claims.Email
+	if user == "" {
+		user = claims.Subject
+	}
+	return &Identity{User: user, Role: claims.Role, Namespaces: claims.Namespaces, Kinds: claims.Kinds}
+}
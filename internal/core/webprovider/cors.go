@@ -1,11 +1,81 @@
 package webprovider
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
+// defaultCORSMaxAge 未配置 web.cors_policy.max_age 时浏览器缓存预检结果的时长
+const defaultCORSMaxAge = 10 * time.Minute
+
+// defaultCORSMethods 未配置 web.cors_policy.allow_methods 时允许的方法
+var defaultCORSMethods = []string{
+	fiber.MethodGet, fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch,
+	fiber.MethodDelete, fiber.MethodHead, fiber.MethodOptions,
+}
+
+// NewCORSHandler 按 web.cors_policy 创建跨域中间件：预检请求（OPTIONS）直接返回 204，不经过认证与路由
+func NewCORSHandler(policy config.CORSConfig) (handler fiber.Handler, err error) {
+	origins := policy.AllowOrigins
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	methods := append([]string(nil), policy.AllowMethods...)
+	if len(methods) == 0 {
+		methods = append(methods, defaultCORSMethods...)
+	}
+	for i, m := range methods {
+		methods[i] = strings.ToUpper(strings.TrimSpace(m))
+		if !isHTTPMethod(methods[i]) {
+			return nil, fmt.Errorf("web.cors_policy.allow_methods 无效: %q", m)
+		}
+	}
+	maxAge := defaultCORSMaxAge
+	if policy.MaxAge != "" {
+		d, err := time.ParseDuration(policy.MaxAge)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("web.cors_policy.max_age 无效: %q", policy.MaxAge)
+		}
+		maxAge = d
+	}
+	for _, o := range origins {
+		if o == "*" && policy.AllowCredentials {
+			return nil, fmt.Errorf("web.cors_policy.allow_credentials 开启时 allow_origins 不能为 *")
+		}
+	}
+
+	// cors.New 对无效的来源格式直接 panic，转为配置错误
+	defer func() {
+		if r := recover(); r != nil {
+			handler, err = nil, fmt.Errorf("web.cors_policy 无效: %v", r)
+		}
+	}()
+	return cors.New(cors.Config{
+		AllowOrigins:     strings.Join(origins, ","),
+		AllowMethods:     strings.Join(methods, ","),
+		AllowHeaders:     strings.Join(policy.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(policy.ExposeHeaders, ","),
+		AllowCredentials: policy.AllowCredentials,
+		MaxAge:           int(maxAge / time.Second),
+	}), nil
+}
+
+// isHTTPMethod 判断是否为 Fiber 支持的 HTTP 方法
+func isHTTPMethod(method string) bool {
+	for _, m := range fiber.DefaultMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 type CorsMiddleware struct {
 	fiber  FiberEngine
 	logger logprovider.Logger
@@ -28,12 +98,12 @@ func (m CorsMiddleware) SetUp() {
 		return
 	}
 
-	m.fiber.App.Use(cors.New(cors.Config{
-		AllowCredentials: true,
-		AllowOrigins:     "*",
-		AllowHeaders:     "*",
-		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS",
-	}))
+	handler, err := NewCORSHandler(m.config.Gin.CORSPolicy)
+	if err != nil {
+		m.logger.Errorf("配置CORS失败: %v", err)
+		return
+	}
+	m.fiber.App.Use(handler)
 
 	m.logger.Info("已配置CORS")
 }
@@ -0,0 +1,114 @@
+package webprovider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func newCORSApp(t *testing.T, cfg config.Config) *fiber.App {
+	t.Helper()
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	if err := UseRequestMiddlewares(app, cfg); err != nil {
+		t.Fatalf("UseRequestMiddlewares: %v", err)
+	}
+	app.Get("/api/v1/pods", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	return app
+}
+
+func preflight(t *testing.T, app *fiber.App, origin, method string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodOptions, "/api/v1/pods", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	req.Header.Set("Access-Control-Request-Headers", "Authorization, X-HTTP-Method-Override")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("preflight: %v", err)
+	}
+	return resp
+}
+
+func TestCORSDefaults(t *testing.T) {
+	app := newCORSApp(t, config.Config{Gin: config.GinConfig{CORS: true}})
+	resp := preflight(t, app, "https://dash.example.com", "DELETE")
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("preflight status = %d, want 204", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q, want *", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Methods"); !strings.Contains(got, "DELETE") {
+		t.Errorf("Allow-Methods = %q, want DELETE included", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Headers"); !strings.Contains(got, "X-HTTP-Method-Override") {
+		t.Errorf("Allow-Headers = %q, want request headers reflected", got)
+	}
+	if got := resp.Header.Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Max-Age = %q, want 600", got)
+	}
+}
+
+func TestCORSPolicy(t *testing.T) {
+	cfg := config.Config{Gin: config.GinConfig{CORS: true, CORSPolicy: config.CORSConfig{
+		AllowOrigins:     []string{"https://dash.example.com"},
+		AllowMethods:     []string{"get", "post"},
+		AllowHeaders:     []string{"Authorization"},
+		AllowCredentials: true,
+		MaxAge:           "1h",
+	}}}
+	// 开启认证时预检请求不带 token 也不能被拒绝
+	cfg.Auth = config.AuthConfig{Enabled: true, Tokens: []config.TokenConfig{{Token: "t", User: "admin", Role: RoleClusterAdmin}}}
+	app := newCORSApp(t, cfg)
+
+	resp := preflight(t, app, "https://dash.example.com", "GET")
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("preflight status = %d, want 204", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://dash.example.com" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Methods"); got != "GET,POST" {
+		t.Errorf("Allow-Methods = %q, want GET,POST", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Headers"); got != "Authorization" {
+		t.Errorf("Allow-Headers = %q, want Authorization", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Allow-Credentials = %q, want true", got)
+	}
+	if got := resp.Header.Get("Access-Control-Max-Age"); got != "3600" {
+		t.Errorf("Max-Age = %q, want 3600", got)
+	}
+
+	resp = preflight(t, app, "https://evil.example.com", "GET")
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("disallowed origin got Allow-Origin %q", got)
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+	app := newCORSApp(t, config.Config{})
+	resp := preflight(t, app, "https://dash.example.com", "GET")
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("CORS disabled but got Allow-Origin %q", got)
+	}
+}
+
+func TestCORSInvalidPolicy(t *testing.T) {
+	cases := []config.CORSConfig{
+		{AllowCredentials: true},
+		{AllowMethods: []string{"FETCH"}},
+		{MaxAge: "soon"},
+		{AllowOrigins: []string{"not a url"}},
+	}
+	for _, policy := range cases {
+		if _, err := NewCORSHandler(policy); err == nil {
+			t.Errorf("%+v: expected error", policy)
+		}
+	}
+}
@@ -22,6 +22,10 @@ var (
 type YourUserClaims struct {
 	UID   uint
 	Email string
+	// Role / Namespaces / Kinds 用于 namespace 与资源种类隔离（见 Identity）
+	Role       string   `json:"role,omitempty"`
+	Namespaces []string `json:"namespaces,omitempty"`
+	Kinds      []string `json:"kinds,omitempty"`
 	jwt.RegisteredClaims
 }
 
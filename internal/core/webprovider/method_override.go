@@ -0,0 +1,32 @@
+package webprovider
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MethodOverrideHeader 请求经过只放行 GET/POST 的代理时，客户端以 POST 发送并用该头指定实际方法
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// NewMethodOverrideHandler 创建方法覆盖中间件：POST 请求带有 X-HTTP-Method-Override 时按其中的 PUT/PATCH/DELETE 路由，
+// 之后的认证、审计与处理函数看到的都是覆盖后的方法。只接受 POST，避免 GET 链接触发写操作
+func NewMethodOverrideHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		override := strings.TrimSpace(c.Get(MethodOverrideHeader))
+		if override == "" {
+			return c.Next()
+		}
+		if c.Method() != fiber.MethodPost {
+			return fiber.NewError(fiber.StatusBadRequest, MethodOverrideHeader+" 只能用于 POST 请求")
+		}
+		method := strings.ToUpper(override)
+		switch method {
+		case fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		default:
+			return fiber.NewError(fiber.StatusBadRequest, MethodOverrideHeader+" 只能为 PUT、PATCH 或 DELETE: "+override)
+		}
+		c.Method(method)
+		return c.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package webprovider
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newOverrideApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(NewMethodOverrideHandler())
+	for _, m := range []string{fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete} {
+		app.Add(m, "/api/v1/pods/:name", func(c *fiber.Ctx) error {
+			return c.SendString(c.Method() + " " + c.Params("name"))
+		})
+	}
+	return app
+}
+
+func TestMethodOverrideRoutesToOverriddenMethod(t *testing.T) {
+	app := newOverrideApp()
+	for _, method := range []string{"PUT", "patch", "DELETE"} {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/pods/web", nil)
+		req.Header.Set(MethodOverrideHeader, method)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		want := map[string]string{"PUT": "PUT web", "patch": "PATCH web", "DELETE": "DELETE web"}[method]
+		if resp.StatusCode != fiber.StatusOK || string(body) != want {
+			t.Errorf("override %s: got %d %q, want %q", method, resp.StatusCode, body, want)
+		}
+	}
+}
+
+func TestMethodOverrideWithoutHeader(t *testing.T) {
+	resp, err := newOverrideApp().Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/pods/web", nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "POST web" {
+		t.Fatalf("got %q, want POST web", body)
+	}
+}
+
+func TestMethodOverrideRejectsInvalidRequests(t *testing.T) {
+	cases := []struct {
+		method, override string
+	}{
+		// GET 不能被覆盖成写操作
+		{fiber.MethodGet, "DELETE"},
+		{fiber.MethodPost, "GET"},
+		{fiber.MethodPost, "CONNECT"},
+		{fiber.MethodPost, "bogus"},
+	}
+	app := newOverrideApp()
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/api/v1/pods/web", nil)
+		req.Header.Set(MethodOverrideHeader, tc.override)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s with override %s: got %d, want 400", tc.method, tc.override, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package webprovider
+
+import (
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// NormalizeBasePath 规范化 web.base_path：以 / 开头、不以 / 结尾，根路径返回空字符串
+func NormalizeBasePath(basePath string) string {
+	p := strings.Trim(strings.TrimSpace(basePath), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// NewBasePathHandler 创建路径前缀中间件：请求路径在 basePath 之下时去掉前缀后再路由，
+// 之后的认证、授权与处理函数看到的都是不带前缀的路径；不带前缀的请求（代理已去掉前缀）原样处理
+func NewBasePathHandler(basePath string) fiber.Handler {
+	basePath = NormalizeBasePath(basePath)
+	return func(c *fiber.Ctx) error {
+		if basePath == "" {
+			return c.Next()
+		}
+		path := c.Path()
+		switch {
+		case path == basePath:
+			c.Path("/")
+		case strings.HasPrefix(path, basePath+"/"):
+			c.Path(path[len(basePath):])
+		}
+		return c.Next()
+	}
+}
+
+// WithTrustedProxies 按 web.trusted_proxies 设置 Fiber 的代理配置：来自信任代理的请求按 X-Forwarded-For 取客户端 IP
+// （c.IP()），按 X-Forwarded-Proto/X-Forwarded-Host 取协议与主机名；其他来源的这些请求头被忽略
+func WithTrustedProxies(fc fiber.Config, web config.GinConfig) fiber.Config {
+	fc.EnableTrustedProxyCheck = true
+	fc.TrustedProxies = web.TrustedProxies
+	if len(web.TrustedProxies) > 0 {
+		fc.ProxyHeader = fiber.HeaderXForwardedFor
+		// X-Forwarded-For 经过多级代理时为逗号分隔的列表，取其中第一个合法 IP
+		fc.EnableIPValidation = true
+	}
+	return fc
+}
@@ -0,0 +1,104 @@
+package webprovider
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	for in, want := range map[string]string{"": "", "/": "", "k3": "/k3", "/k3/": "/k3", " /a/b/ ": "/a/b"} {
+		if got := NormalizeBasePath(in); got != want {
+			t.Errorf("NormalizeBasePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func newBasePathApp(t *testing.T, cfg config.Config) *fiber.App {
+	t.Helper()
+	app := fiber.New(WithTrustedProxies(fiber.Config{ErrorHandler: ErrorHandler}, cfg.Gin))
+	if err := UseRequestMiddlewares(app, cfg); err != nil {
+		t.Fatalf("UseRequestMiddlewares: %v", err)
+	}
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("index") })
+	app.Get("/api/healthz", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/api/v1/pods", func(c *fiber.Ctx) error { return c.SendString("pods " + c.Path()) })
+	app.Get("/ip", func(c *fiber.Ctx) error { return c.SendString(c.IP()) })
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, path string, header map[string]string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestBasePathRouting(t *testing.T) {
+	app := newBasePathApp(t, config.Config{Gin: config.GinConfig{BasePath: "/k3/"}})
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/k3", fiber.StatusOK, "index"},
+		{"/k3/", fiber.StatusOK, "index"},
+		{"/k3/api/v1/pods", fiber.StatusOK, "pods /api/v1/pods"},
+		// 代理已去掉前缀
+		{"/api/v1/pods", fiber.StatusOK, "pods /api/v1/pods"},
+		// 只是名字以前缀开头的路径不去掉前缀
+		{"/k3api/v1/pods", fiber.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		code, body := get(t, app, tt.path, nil)
+		if code != tt.code || tt.body != "" && body != tt.body {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.path, code, body, tt.code, tt.body)
+		}
+	}
+}
+
+func TestBasePathAuthExemptPaths(t *testing.T) {
+	app := newBasePathApp(t, config.Config{
+		Gin:  config.GinConfig{BasePath: "/k3"},
+		Auth: config.AuthConfig{Enabled: true, Tokens: []config.TokenConfig{{Token: "secret", User: "admin", Role: "cluster-admin"}}},
+	})
+	if code, _ := get(t, app, "/k3/api/healthz", nil); code != fiber.StatusOK {
+		t.Errorf("exempt path under base path: HTTP %d, want 200", code)
+	}
+	if code, _ := get(t, app, "/k3/api/v1/pods", nil); code != fiber.StatusUnauthorized {
+		t.Errorf("protected path without token: HTTP %d, want 401", code)
+	}
+	if code, _ := get(t, app, "/k3/api/v1/pods", map[string]string{"Authorization": "Bearer secret"}); code != fiber.StatusOK {
+		t.Errorf("protected path with token: HTTP %d, want 200", code)
+	}
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	forwarded := map[string]string{fiber.HeaderXForwardedFor: "203.0.113.7, 10.0.0.1"}
+
+	// httptest 请求的来源地址为 0.0.0.0
+	app := newBasePathApp(t, config.Config{Gin: config.GinConfig{TrustedProxies: []string{"0.0.0.0"}}})
+	if _, ip := get(t, app, "/ip", forwarded); ip != "203.0.113.7" {
+		t.Errorf("client IP via trusted proxy = %q, want 203.0.113.7", ip)
+	}
+
+	app = newBasePathApp(t, config.Config{Gin: config.GinConfig{TrustedProxies: []string{"10.0.0.0/8"}}})
+	if _, ip := get(t, app, "/ip", forwarded); ip == "203.0.113.7" {
+		t.Errorf("X-Forwarded-For from an untrusted address was honored")
+	}
+
+	app = newBasePathApp(t, config.Config{})
+	if _, ip := get(t, app, "/ip", forwarded); ip == "203.0.113.7" {
+		t.Errorf("X-Forwarded-For honored without trusted proxies")
+	}
+}
@@ -16,15 +16,17 @@ import (
 type FiberEngine struct {
 	App *fiber.App
 	Api fiber.Router
+	// BasePath 对外的路径前缀（web.base_path，已规范化），生成给浏览器的链接时加在路径前面
+	BasePath string
 }
 
 // NewFiberEngine creates a new Fiber engine with middleware
-func NewFiberEngine(cfg config.Config) FiberEngine {
-	app := fiber.New(fiber.Config{
+func NewFiberEngine(cfg config.Config) (FiberEngine, error) {
+	app := fiber.New(WithTrustedProxies(fiber.Config{
 		AppName:      "Hermes",
 		ServerHeader: "Hermes",
 		ErrorHandler: ErrorHandler,
-	})
+	}, cfg.Gin))
 
 	zapLogger := logprovider.GetZapLogger()
 
@@ -45,14 +47,40 @@ func NewFiberEngine(cfg config.Config) FiberEngine {
 		},
 	}))
 
+	if err := UseRequestMiddlewares(app, cfg); err != nil {
+		return FiberEngine{}, err
+	}
+
 	return FiberEngine{
 		App: app,
 		// Api router group is for mounting API-like routes.
 		// Keep it at root so Kubernetes-style paths stay canonical:
 		// - /api/v1/...
 		// - /apis/<group>/<version>/...
-		Api: app,
+		Api:      app,
+		BasePath: NormalizeBasePath(cfg.Gin.BasePath),
+	}, nil
+}
+
+// UseRequestMiddlewares 按配置注册路径前缀（web.base_path）、跨域（web.cors）、方法覆盖（web.method_override）与认证中间件。
+// 需要在注册任何路由之前调用：预检请求不经过认证，路径前缀与方法覆盖在路由匹配前生效
+func UseRequestMiddlewares(app *fiber.App, cfg config.Config) error {
+	if NormalizeBasePath(cfg.Gin.BasePath) != "" {
+		app.Use(NewBasePathHandler(cfg.Gin.BasePath))
+	}
+	if cfg.Gin.CORS {
+		handler, err := NewCORSHandler(cfg.Gin.CORSPolicy)
+		if err != nil {
+			return err
+		}
+		app.Use(handler)
+	}
+	if cfg.Gin.MethodOverride {
+		app.Use(NewMethodOverrideHandler())
 	}
+	// 认证：写入请求身份（auth.enabled=false 时直接放行）
+	app.Use(NewAuthMiddleware(cfg))
+	return nil
 }
 
 // ErrorHandler is the custom error handler for Fiber
@@ -0,0 +1,210 @@
+// Package diag 处理现场排查用的信号：SIGUSR1 把诊断快照写入日志，SIGUSR2 在 debug 与原来的日志级别之间切换。
+// 不需要重启进程，也不需要开启 web.admin_port 或 cluster-admin 权限
+package diag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/fx"
+	"go.uber.org/zap/zapcore"
+)
+
+// params Store 与控制器只在对应的进程中存在
+type params struct {
+	fx.In
+
+	Lifecycle   fx.Lifecycle
+	Config      config.Config
+	Logger      logprovider.Logger
+	Store       storage.Store                      `optional:"true"`
+	Controllers apiserver.ControllerStatusProvider `optional:"true"`
+}
+
+// Module 在应用运行期间处理 SIGUSR1/SIGUSR2（Windows 没有这两个信号，不做任何事）
+var Module = fx.Invoke(func(p params) {
+	d := New(p.Logger, p.Config.Storage.Type, p.Store, p.Controllers)
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error { d.Start(); return nil },
+		OnStop:  func(context.Context) error { d.Stop(); return nil },
+	})
+})
+
+// Diagnostics 收集诊断快照并切换日志级别
+type Diagnostics struct {
+	logger      logprovider.Logger
+	backend     string
+	store       storage.Store
+	controllers apiserver.ControllerStatusProvider
+
+	mu sync.Mutex
+	// previous 切换到 debug 之前的日志级别
+	previous zapcore.Level
+
+	signals chan os.Signal
+	done    chan struct{}
+}
+
+// New 创建 Diagnostics；backend 为存储类型（Store 不报告连接状态时使用），store 与 controllers 可以为 nil
+func New(logger logprovider.Logger, backend string, store storage.Store, controllers apiserver.ControllerStatusProvider) *Diagnostics {
+	return &Diagnostics{logger: logger, backend: backend, store: store, controllers: controllers, previous: zapcore.InfoLevel}
+}
+
+// Start 开始处理信号
+func (d *Diagnostics) Start() {
+	if dumpSignal == nil {
+		return
+	}
+	d.signals = make(chan os.Signal, 1)
+	d.done = make(chan struct{})
+	signal.Notify(d.signals, dumpSignal, toggleSignal)
+	go func() {
+		defer close(d.done)
+		for sig := range d.signals {
+			switch sig {
+			case dumpSignal:
+				d.Dump()
+			case toggleSignal:
+				d.ToggleDebug()
+			}
+		}
+	}()
+	d.logger.Infof("诊断信号: kill -USR1 %d 输出诊断快照，kill -USR2 %d 切换 debug 日志", os.Getpid(), os.Getpid())
+}
+
+// Stop 停止处理信号（之后收到的 SIGUSR1/SIGUSR2 按默认行为处理）
+func (d *Diagnostics) Stop() {
+	if d.signals == nil {
+		return
+	}
+	signal.Stop(d.signals)
+	close(d.signals)
+	<-d.done
+	d.signals = nil
+}
+
+// Dump 把诊断快照写入日志（info 级别）；info 日志未开启（log.level 为 warn 及以上或未设置）时写到 stderr
+func (d *Diagnostics) Dump() {
+	lines := d.Collect(time.Now()).Lines()
+	if !d.logger.Desugar().Core().Enabled(zapcore.InfoLevel) {
+		fmt.Fprintln(os.Stderr, strings.Join(lines, "\n"))
+		return
+	}
+	for _, line := range lines {
+		d.logger.Info(line)
+	}
+}
+
+// ToggleDebug 当前不是 debug 时切换到 debug，否则恢复切换之前的级别（没有记录时为 info）
+func (d *Diagnostics) ToggleDebug() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	current := logprovider.CurrentLevel()
+	if current == zapcore.DebugLevel.String() {
+		// 先写日志再切换：恢复到更高的级别后这条日志仍然可见
+		d.logger.Warnf("收到 SIGUSR2，日志级别 %s -> %s", current, d.previous)
+		logprovider.SwapLevel(d.previous)
+		return
+	}
+	previous, ok := logprovider.SwapLevel(zapcore.DebugLevel)
+	if !ok {
+		return
+	}
+	d.previous = previous
+	d.logger.Warnf("收到 SIGUSR2，日志级别 %s -> %s", current, zapcore.DebugLevel)
+}
+
+// Snapshot 一次诊断快照
+type Snapshot struct {
+	Time       time.Time
+	Goroutines int
+	// HeapAlloc 堆上在用的字节数，NumGC 为已完成的 GC 次数
+	HeapAlloc uint64
+	NumGC     uint32
+	LogLevel  string
+	// Controllers 为 nil 表示本进程没有控制器
+	Controllers []apiserver.ControllerStatus
+	// Storage 为 nil 表示本进程没有 Store
+	Storage *storage.BackendHealth
+	Watches []storage.WatchStats
+}
+
+// Collect 收集诊断快照
+func (d *Diagnostics) Collect(now time.Time) Snapshot {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	snap := Snapshot{
+		Time:       now,
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  mem.HeapAlloc,
+		NumGC:      mem.NumGC,
+		LogLevel:   logprovider.CurrentLevel(),
+	}
+	if d.controllers != nil {
+		snap.Controllers = d.controllers.ControllerStatuses()
+		sort.Slice(snap.Controllers, func(i, j int) bool { return snap.Controllers[i].Name < snap.Controllers[j].Name })
+	}
+	if d.store != nil {
+		health := storage.BackendHealth{Backend: d.backend, Ready: true, State: storage.CircuitClosed}
+		if reporter, ok := d.store.(storage.HealthReporter); ok {
+			health = reporter.Health()
+		}
+		snap.Storage = &health
+		if provider, ok := d.store.(storage.WatchStatsProvider); ok {
+			snap.Watches = provider.WatchStats()
+		}
+	}
+	return snap
+}
+
+// Lines 把快照格式化为日志行：概要、每个控制器、存储、每组 watcher
+func (s Snapshot) Lines() []string {
+	lines := []string{fmt.Sprintf("诊断快照 %s: goroutines=%d heap=%dMiB gc=%d log_level=%s",
+		s.Time.Format(time.RFC3339), s.Goroutines, s.HeapAlloc>>20, s.NumGC, s.LogLevel)}
+	if s.Controllers != nil {
+		depth := 0
+		for _, st := range s.Controllers {
+			depth += st.QueueDepth
+		}
+		lines = append(lines, fmt.Sprintf("  控制器: %d 个，队列中共 %d 个事件", len(s.Controllers), depth))
+		for _, st := range s.Controllers {
+			line := fmt.Sprintf("    %s: health=%s queue=%d reconciles=%d errors=%d", st.Name, st.Health, st.QueueDepth, st.Reconciles, st.Errors)
+			if st.Message != "" {
+				line += " (" + st.Message + ")"
+			}
+			lines = append(lines, line)
+		}
+	}
+	if s.Storage != nil {
+		line := fmt.Sprintf("  存储: backend=%s ready=%t state=%s failures=%d", s.Storage.Backend, s.Storage.Ready, s.Storage.State, s.Storage.ConsecutiveFailures)
+		if s.Storage.LastError != "" {
+			line += " last_error=" + s.Storage.LastError
+		}
+		lines = append(lines, line)
+		watchers, pending := 0, 0
+		for _, w := range s.Watches {
+			watchers += w.Watchers
+			pending += w.Pending
+		}
+		lines = append(lines, fmt.Sprintf("  watch: %d 个 watcher，积压 %d 个事件", watchers, pending))
+		for _, w := range s.Watches {
+			namespace := w.Namespace
+			if namespace == "" {
+				namespace = "*"
+			}
+			lines = append(lines, fmt.Sprintf("    %s %s: watchers=%d pending=%d", w.GVK.GroupVersion().String()+"/"+w.GVK.Kind, namespace, w.Watchers, w.Pending))
+		}
+	}
+	return lines
+}
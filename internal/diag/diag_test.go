@@ -0,0 +1,59 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeControllers []apiserver.ControllerStatus
+
+func (f fakeControllers) NodeName() string { return "node-1" }
+func (f fakeControllers) ControllerStatuses() []apiserver.ControllerStatus {
+	return append([]apiserver.ControllerStatus(nil), f...)
+}
+
+func TestSnapshot(t *testing.T) {
+	store := storage.NewMemoryStore()
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	if _, err := store.Watch(podGVK, "", ""); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if _, err := store.Watch(podGVK, "prod", ""); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	controllers := fakeControllers{
+		{Name: "scheduler", Health: apiserver.ControllerHealthy, QueueDepth: 2, Reconciles: 10},
+		{Name: "deployment", Health: apiserver.ControllerStalled, Message: "no progress", QueueDepth: 5, Reconciles: 3, Errors: 1},
+	}
+	d := New(logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}, "memory", store, controllers)
+
+	snap := d.Collect(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	if snap.Goroutines == 0 || snap.Storage == nil || snap.Storage.Backend != "memory" || len(snap.Watches) != 2 {
+		t.Fatalf("snapshot = %+v", snap)
+	}
+	got := strings.Join(snap.Lines(), "\n")
+	for _, want := range []string{
+		"诊断快照 2026-10-17T08:00:00Z: goroutines=",
+		"控制器: 2 个，队列中共 7 个事件",
+		"    deployment: health=stalled queue=5 reconciles=3 errors=1 (no progress)\n    scheduler:",
+		"存储: backend=memory ready=true state=closed failures=0",
+		"watch: 2 个 watcher，积压 0 个事件",
+		"    v1/Pod *: watchers=1 pending=0\n    v1/Pod prod: watchers=1 pending=0",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("snapshot lines missing %q:\n%s", want, got)
+		}
+	}
+
+	// 没有 Store 与控制器的进程只输出概要
+	if lines := New(d.logger, "", nil, nil).Collect(time.Now()).Lines(); len(lines) != 1 {
+		t.Fatalf("lines without store and controllers = %q", lines)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package diag
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignal 输出诊断快照，toggleSignal 切换 debug 日志
+var (
+	dumpSignal   os.Signal = syscall.SIGUSR1
+	toggleSignal os.Signal = syscall.SIGUSR2
+)
@@ -0,0 +1,11 @@
+//go:build windows
+
+package diag
+
+import "os"
+
+// Windows 没有 SIGUSR1/SIGUSR2，Start 不做任何事
+var (
+	dumpSignal   os.Signal
+	toggleSignal os.Signal
+)
@@ -0,0 +1,105 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/hashicorp/consul/api"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// externalNameTag 标记由 discovery 按 ExternalName Service 注册的 Consul 服务
+const externalNameTag = "k3-external-name"
+
+// externalNameServiceID Consul 中 ExternalName Service 的 ID
+func externalNameServiceID(namespace, service string) string {
+	return fmt.Sprintf("k3-svc-%s-%s", namespace, service)
+}
+
+// desiredExternalNames 为 ExternalName Service 生成 Consul 注册：服务名为 <namespace>-<service>，地址为 spec.externalName。
+// 服务地址是主机名时 Consul DNS 对 <namespace>-<service>.service.consul 返回 CNAME，与 Kubernetes 的 ExternalName 相同；
+// 端口为 Service 第一个端口（没有时为 0），只出现在 SRV 记录中
+func desiredExternalNames(store storage.Store) ([]*api.AgentServiceRegistration, error) {
+	objs, err := store.List(serviceGVK, "")
+	if err != nil {
+		return nil, fmt.Errorf("获取 Service 列表失败: %w", err)
+	}
+	var result []*api.AgentServiceRegistration
+	for _, obj := range objs {
+		svc, ok := obj.(*corev1.Service)
+		if !ok || svc.Spec.Type != corev1.ServiceTypeExternalName || svc.Spec.ExternalName == "" {
+			continue
+		}
+		port := 0
+		if len(svc.Spec.Ports) > 0 {
+			port = int(svc.Spec.Ports[0].Port)
+		}
+		result = append(result, &api.AgentServiceRegistration{
+			ID:      externalNameServiceID(svc.Namespace, svc.Name),
+			Name:    svc.Namespace + "-" + svc.Name,
+			Tags:    []string{externalNameTag},
+			Port:    port,
+			Address: svc.Spec.ExternalName,
+			Meta: map[string]string{
+				"namespace": svc.Namespace,
+				"service":   svc.Name,
+			},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// syncExternalNames 注册新增或修改了 externalName 的 ExternalName Service，注销已经删除（或不再是 ExternalName）的 Service。
+// 这些服务没有健康检查，Consul 总是返回它们
+func (s *Service) syncExternalNames() error {
+	desired, err := desiredExternalNames(s.store)
+	if err != nil {
+		return err
+	}
+	agent := s.consulClient.Agent()
+	existing, err := agent.ServicesWithFilter(fmt.Sprintf("%q in Tags", externalNameTag))
+	if err != nil {
+		return fmt.Errorf("获取 Consul 中的 ExternalName 服务失败: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	for _, reg := range desired {
+		wanted[reg.ID] = true
+		if old, ok := existing[reg.ID]; ok && old.Address == reg.Address && old.Port == reg.Port {
+			continue
+		}
+		if err := agent.ServiceRegister(reg); err != nil {
+			s.logger.Warnf("注册 ExternalName Service %s 到 Consul 失败: %v", reg.Name, err)
+			continue
+		}
+		s.logger.Debugf("已注册 ExternalName Service 到 Consul: %s -> %s", reg.Name, reg.Address)
+	}
+	for id := range existing {
+		if wanted[id] {
+			continue
+		}
+		if err := agent.ServiceDeregister(id); err != nil {
+			s.logger.Warnf("从 Consul 注销 ExternalName 服务 %s 失败: %v", id, err)
+			continue
+		}
+		s.logger.Debugf("已从 Consul 注销 ExternalName 服务: %s", id)
+	}
+	return nil
+}
+
+// deregisterExternalNames 注销 discovery 按 ExternalName Service 注册的所有 Consul 服务（停止时调用）
+func (s *Service) deregisterExternalNames() {
+	agent := s.consulClient.Agent()
+	existing, err := agent.ServicesWithFilter(fmt.Sprintf("%q in Tags", externalNameTag))
+	if err != nil {
+		s.logger.Warnf("获取 Consul 中的 ExternalName 服务失败: %v", err)
+		return
+	}
+	for id := range existing {
+		if err := agent.ServiceDeregister(id); err != nil {
+			s.logger.Warnf("从 Consul 注销 ExternalName 服务 %s 失败: %v", id, err)
+		}
+	}
+}
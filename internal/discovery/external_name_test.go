@@ -0,0 +1,60 @@
+package discovery
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestServiceRegistrations(t *testing.T) {
+	store := storage.NewMemoryStore()
+	create := func(gvk schema.GroupVersionKind, obj runtime.Object) {
+		t.Helper()
+		if err := store.Create(gvk, obj); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	create(serviceGVK, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "db.example.com", Ports: []corev1.ServicePort{{Port: 5432}}},
+	})
+	create(serviceGVK, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "peers"},
+		Spec: corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone, PublishNotReadyAddresses: true,
+			Selector: map[string]string{"app": "db"}, Ports: []corev1.ServicePort{{Port: 7000}}},
+	})
+	create(podGVK, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db-0", Labels: map[string]string{"app": "db"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.7"},
+	})
+
+	names, err := desiredExternalNames(store)
+	if err != nil {
+		t.Fatalf("desiredExternalNames: %v", err)
+	}
+	if len(names) != 1 || names[0].Name != "default-db" || names[0].Address != "db.example.com" || names[0].Port != 5432 {
+		t.Fatalf("unexpected external name registrations: %+v", names)
+	}
+
+	pods, err := desiredPodRegistrations(store, Settings{NodeName: "node-1", WatchInterval: time.Second})
+	if err != nil {
+		t.Fatalf("desiredPodRegistrations: %v", err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected 1 pod registration, got %d", len(pods))
+	}
+	reg := pods[0]
+	if reg.registration.Name != "default-peers" || !slices.Contains(reg.registration.Tags, "db-0") {
+		t.Fatalf("headless pod should be tagged with its hostname: %+v", reg.registration)
+	}
+	// publishNotReadyAddresses：未就绪的 Pod 也注册为健康
+	if !reg.ready || reg.fromProbe {
+		t.Fatalf("expected a passing TTL check, got ready=%v fromProbe=%v", reg.ready, reg.fromProbe)
+	}
+}
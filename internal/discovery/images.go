@@ -0,0 +1,9 @@
+package discovery
+
+import "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+
+// ConsulImage 返回本机自动拉起 Consul 容器时使用的镜像（consul:1.17，或配置中的 discovery.consul.image），
+// k3 bundle create 用它把 Consul 镜像打包进离线包
+func ConsulImage(c config.ContainerConfig) string {
+	return buildConsulPod("", c).Spec.Containers[0].Image
+}
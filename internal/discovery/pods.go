@@ -0,0 +1,433 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/hashicorp/consul/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// PodReadinessGate 是 discovery 写回 Pod 的 condition 类型：Pod 在 Consul 中的健康检查全部通过时为 True。
+// Pod 在 spec.readinessGates 中声明该类型后，检查失败会让 Pod 变为未就绪（不再计入 Service 的就绪端点）
+const PodReadinessGate corev1.PodConditionType = "consul.k3.io/healthy"
+
+// podServiceTag 标记由 discovery 按 Pod 注册的 Consul 服务（与节点服务区分，清理时只处理带该标签的服务）
+const podServiceTag = "k3-pod"
+
+var (
+	podGVK     = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	serviceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+)
+
+// podRegistration 一个 Pod 在某个 Service 下的 Consul 注册
+type podRegistration struct {
+	registration *api.AgentServiceRegistration
+	namespace    string
+	pod          string
+	// fromProbe 检查来自 readinessProbe（HTTP/TCP/gRPC）；否则为 TTL 检查，由 discovery 按 Pod 的 Ready 条件更新，不写回 Pod
+	fromProbe bool
+	ready     bool
+}
+
+// podServiceID Consul 中 Pod 服务的 ID
+func podServiceID(namespace, service, pod string) string {
+	return fmt.Sprintf("k3-pod-%s-%s-%s", namespace, service, pod)
+}
+
+// podCheckID Pod 服务健康检查的 ID
+func podCheckID(serviceID string) string {
+	return serviceID + ":readiness"
+}
+
+// desiredPodRegistrations 按 Service 的 selector 为正在运行且已分配 IP 的 Pod 生成 Consul 注册：
+// 服务名为 <namespace>-<service>，端口为 Service 第一个端口的 targetPort。ExternalName Service 不选择 Pod（见 desiredExternalNames）
+func desiredPodRegistrations(store storage.Store, settings Settings) ([]podRegistration, error) {
+	objs, err := store.List(serviceGVK, "")
+	if err != nil {
+		return nil, fmt.Errorf("获取 Service 列表失败: %w", err)
+	}
+	var result []podRegistration
+	for _, obj := range objs {
+		svc, ok := obj.(*corev1.Service)
+		if !ok || svc.Spec.Type == corev1.ServiceTypeExternalName || len(svc.Spec.Selector) == 0 || len(svc.Spec.Ports) == 0 {
+			continue
+		}
+		pods, err := store.ListBySelector(podGVK, svc.Namespace, labels.SelectorFromSet(svc.Spec.Selector))
+		if err != nil {
+			return nil, fmt.Errorf("获取 Service %s/%s 的 Pod 失败: %w", svc.Namespace, svc.Name, err)
+		}
+		for _, o := range pods {
+			pod, ok := o.(*corev1.Pod)
+			if !ok || pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+				continue
+			}
+			result = append(result, buildPodRegistration(svc, pod, settings))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].registration.ID < result[j].registration.ID })
+	return result, nil
+}
+
+// buildPodRegistration 生成 Pod 的 Consul 注册。检查取自 Pod 中第一个定义了 readinessProbe 的容器（优先选择暴露服务端口的容器）：
+// httpGet、tcpSocket、grpc 分别转换为 Consul 的 HTTP、TCP、gRPC 检查，间隔与超时取 periodSeconds、timeoutSeconds；
+// 没有可转换的探针（例如 exec）时使用 TTL 检查，由 discovery 按 Pod 的 Ready 条件更新。
+// headless Service（clusterIP: None）的 Pod 额外带有主机名标签，可以按 <hostname>.<namespace>-<service>.service.consul 解析单个 Pod；
+// 设置了 publishNotReadyAddresses 时 TTL 检查总是通过（未就绪的 Pod 也能被解析，StatefulSet 的成员互相发现时常用）
+func buildPodRegistration(svc *corev1.Service, pod *corev1.Pod, settings Settings) podRegistration {
+	port := int(svc.Spec.Ports[0].Port)
+	if target := resolvePort(pod, svc.Spec.Ports[0].TargetPort); target > 0 {
+		port = target
+	}
+	id := podServiceID(pod.Namespace, svc.Name, pod.Name)
+	tags := []string{podServiceTag}
+	if svc.Spec.ClusterIP == corev1.ClusterIPNone {
+		tags = append(tags, podHostname(pod))
+	}
+	reg := &api.AgentServiceRegistration{
+		ID:      id,
+		Name:    svc.Namespace + "-" + svc.Name,
+		Tags:    tags,
+		Port:    port,
+		Address: pod.Status.PodIP,
+		Meta: map[string]string{
+			"namespace":  pod.Namespace,
+			"pod":        pod.Name,
+			"service":    svc.Name,
+			"node":       pod.Spec.NodeName,
+			"discoverer": settings.NodeName,
+		},
+	}
+
+	// 不设置 DeregisterCriticalServiceAfter：未就绪的 Pod 仍保留在 Consul 中（不健康），由 syncPodChecks 按 Pod 的存在与否注销
+	check := &api.AgentServiceCheck{
+		CheckID: podCheckID(id),
+		Name:    fmt.Sprintf("readiness %s/%s", pod.Namespace, pod.Name),
+	}
+	fromProbe := false
+	ready := podReadyCondition(pod)
+	if svc.Spec.PublishNotReadyAddresses {
+		ready = true
+	} else if probe := readinessProbeFor(pod, port); probe != nil {
+		fromProbe = probeToCheck(pod, probe, check)
+	}
+	if !fromProbe {
+		check.TTL = (3 * settings.WatchInterval).String()
+	}
+	reg.Check = check
+	return podRegistration{registration: reg, namespace: pod.Namespace, pod: pod.Name, fromProbe: fromProbe, ready: ready}
+}
+
+// podHostname 返回 Pod 在 headless Service 中的主机名：spec.hostname，没有设置时为 Pod 名（与 Kubernetes 的 DNS 记录相同）
+func podHostname(pod *corev1.Pod) string {
+	if pod.Spec.Hostname != "" {
+		return pod.Spec.Hostname
+	}
+	return pod.Name
+}
+
+// readinessProbeFor 返回 Pod 的 readinessProbe：优先取暴露 port 的容器，其次取第一个定义了探针的容器
+func readinessProbeFor(pod *corev1.Pod, port int) *corev1.Probe {
+	var first *corev1.Probe
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.ReadinessProbe == nil {
+			continue
+		}
+		for _, p := range c.Ports {
+			if int(p.ContainerPort) == port {
+				return c.ReadinessProbe
+			}
+		}
+		if first == nil {
+			first = c.ReadinessProbe
+		}
+	}
+	return first
+}
+
+// probeToCheck 把 readinessProbe 转换为 Consul 检查，探针类型不支持或端口无法解析时返回 false
+func probeToCheck(pod *corev1.Pod, probe *corev1.Probe, check *api.AgentServiceCheck) bool {
+	period := time.Duration(probe.PeriodSeconds) * time.Second
+	if period <= 0 {
+		period = 10 * time.Second
+	}
+	timeout := time.Duration(probe.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	check.Interval = period.String()
+	check.Timeout = timeout.String()
+
+	switch {
+	case probe.HTTPGet != nil:
+		port := resolvePort(pod, probe.HTTPGet.Port)
+		if port <= 0 {
+			return false
+		}
+		host := probe.HTTPGet.Host
+		if host == "" {
+			host = pod.Status.PodIP
+		}
+		scheme := strings.ToLower(string(probe.HTTPGet.Scheme))
+		if scheme == "" {
+			scheme = "http"
+		}
+		path := probe.HTTPGet.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		check.HTTP = fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)), path)
+		// 与 kubelet 相同，HTTPS 探针不校验证书
+		check.TLSSkipVerify = scheme == "https"
+		if len(probe.HTTPGet.HTTPHeaders) > 0 {
+			check.Header = make(map[string][]string)
+			for _, h := range probe.HTTPGet.HTTPHeaders {
+				check.Header[h.Name] = append(check.Header[h.Name], h.Value)
+			}
+		}
+		return true
+	case probe.TCPSocket != nil:
+		port := resolvePort(pod, probe.TCPSocket.Port)
+		if port <= 0 {
+			return false
+		}
+		host := probe.TCPSocket.Host
+		if host == "" {
+			host = pod.Status.PodIP
+		}
+		check.TCP = net.JoinHostPort(host, strconv.Itoa(port))
+		return true
+	case probe.GRPC != nil:
+		check.GRPC = net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(probe.GRPC.Port)))
+		if probe.GRPC.Service != nil && *probe.GRPC.Service != "" {
+			check.GRPC += "/" + *probe.GRPC.Service
+		}
+		return true
+	}
+	return false
+}
+
+// resolvePort 解析端口：数字直接返回，名称在 Pod 的容器端口中查找；无法解析时返回 0
+func resolvePort(pod *corev1.Pod, port intstr.IntOrString) int {
+	if port.Type == intstr.Int {
+		return port.IntValue()
+	}
+	if port.StrVal == "" {
+		return 0
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == port.StrVal {
+				return int(p.ContainerPort)
+			}
+		}
+	}
+	return 0
+}
+
+// podReadyCondition 判断 Pod 的 Ready condition 是否为 True
+func podReadyCondition(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podChecksLoop 定期把 Pod 与 ExternalName Service 注册到 Consul，并把检查结果写回 Pod
+func (s *Service) podChecksLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.settings.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.syncPodChecks(); err != nil {
+			s.logger.Warnf("同步 Pod 健康检查失败: %v", err)
+		}
+		if err := s.syncExternalNames(); err != nil {
+			s.logger.Warnf("同步 ExternalName Service 失败: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncPodChecks 注册新增的 Pod、注销已经不存在（或不再被 Service 选中）的 Pod，更新 TTL 检查，
+// 并把 readinessProbe 检查的结果写回 Pod 的 PodReadinessGate 条件
+func (s *Service) syncPodChecks() error {
+	desired, err := desiredPodRegistrations(s.store, s.settings)
+	if err != nil {
+		return err
+	}
+	agent := s.consulClient.Agent()
+	existing, err := agent.ServicesWithFilter(fmt.Sprintf("%q in Tags", podServiceTag))
+	if err != nil {
+		return fmt.Errorf("获取 Consul 中的 Pod 服务失败: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	for _, d := range desired {
+		reg := d.registration
+		wanted[reg.ID] = true
+		if old, ok := existing[reg.ID]; !ok || old.Address != reg.Address || old.Port != reg.Port || !slices.Equal(old.Tags, reg.Tags) {
+			if err := agent.ServiceRegister(reg); err != nil {
+				s.logger.Warnf("注册 Pod %s/%s 到 Consul 失败: %v", d.namespace, d.pod, err)
+				continue
+			}
+			s.logger.Debugf("已注册 Pod 到 Consul: %s (%s:%d)", reg.ID, reg.Address, reg.Port)
+		}
+		if !d.fromProbe {
+			status, output := api.HealthCritical, "pod is not ready"
+			if d.ready {
+				status, output = api.HealthPassing, "pod is ready"
+			}
+			if err := agent.UpdateTTL(reg.Check.CheckID, output, status); err != nil {
+				s.logger.Debugf("更新 Pod %s/%s 的 TTL 检查失败: %v", d.namespace, d.pod, err)
+			}
+		}
+	}
+	for id := range existing {
+		if wanted[id] {
+			continue
+		}
+		if err := agent.ServiceDeregister(id); err != nil {
+			s.logger.Warnf("从 Consul 注销 Pod 服务 %s 失败: %v", id, err)
+			continue
+		}
+		s.logger.Debugf("已从 Consul 注销 Pod 服务: %s", id)
+	}
+
+	checks, err := agent.ChecksWithFilter(fmt.Sprintf("ServiceTags contains %q", podServiceTag))
+	if err != nil {
+		return fmt.Errorf("获取 Pod 健康检查状态失败: %w", err)
+	}
+	return s.writeBackPodHealth(desired, checks)
+}
+
+// writeBackPodHealth 按 Pod 汇总 readinessProbe 检查（Pod 被多个 Service 选中时全部通过才算健康），
+// 状态变化时更新 Pod 的 PodReadinessGate 条件；检查尚未执行过（Consul 初始状态）的 Pod 暂不写回
+func (s *Service) writeBackPodHealth(desired []podRegistration, checks map[string]*api.AgentCheck) error {
+	type podHealth struct {
+		namespace, name string
+		healthy         bool
+		output          string
+	}
+	byPod := make(map[string]*podHealth)
+	var order []string
+	for _, d := range desired {
+		if !d.fromProbe {
+			continue
+		}
+		check, ok := checks[d.registration.Check.CheckID]
+		if !ok || check.Output == "" {
+			continue
+		}
+		key := d.namespace + "/" + d.pod
+		h, ok := byPod[key]
+		if !ok {
+			h = &podHealth{namespace: d.namespace, name: d.pod, healthy: true}
+			byPod[key] = h
+			order = append(order, key)
+		}
+		if check.Status != api.HealthPassing {
+			h.healthy = false
+			h.output = strings.TrimSpace(check.Output)
+		}
+	}
+	for _, key := range order {
+		h := byPod[key]
+		if err := s.setPodHealth(h.namespace, h.name, h.healthy, h.output); err != nil {
+			s.logger.Warnf("写回 Pod %s 的健康状态失败: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// setPodHealth 更新 Pod 的 PodReadinessGate 条件（状态不变时不写入）。Pod 声明了该 readinessGate 且检查失败时同时把 Ready 置为 False，
+// 检查恢复后由 PodController 按容器状态与 readinessGates 重新计算 Ready
+func (s *Service) setPodHealth(namespace, name string, healthy bool, output string) error {
+	obj, err := s.store.Get(podGVK, namespace, name)
+	if err != nil {
+		return err
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("unexpected object %T", obj)
+	}
+	status, reason, message := corev1.ConditionTrue, "ConsulCheckPassing", "Consul health checks are passing"
+	if !healthy {
+		status, reason, message = corev1.ConditionFalse, "ConsulCheckFailing", output
+	}
+
+	pod = pod.DeepCopy()
+	now := metav1.Now()
+	changed := setPodCondition(pod, corev1.PodCondition{Type: PodReadinessGate, Status: status, Reason: reason, Message: message, LastTransitionTime: now})
+	if !healthy && hasReadinessGate(pod, PodReadinessGate) {
+		changed = setPodCondition(pod, corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionFalse, Reason: "ReadinessGatesNotReady",
+			Message: fmt.Sprintf("corresponding condition of pod readiness gate %q does not exist or is False", PodReadinessGate), LastTransitionTime: now}) || changed
+	}
+	if !changed {
+		return nil
+	}
+	conflict, err := storage.UpdateAs(s.store, podGVK, pod, fieldManager, true)
+	if conflict != nil {
+		s.logger.Warnf("discovery: Pod %s/%s 写入冲突: %v", namespace, name, conflict)
+	}
+	return err
+}
+
+// setPodCondition 设置 Pod 条件，status 不变时保留原有条件并返回 false
+func setPodCondition(pod *corev1.Pod, cond corev1.PodCondition) bool {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type != cond.Type {
+			continue
+		}
+		if pod.Status.Conditions[i].Status == cond.Status {
+			return false
+		}
+		pod.Status.Conditions[i] = cond
+		return true
+	}
+	pod.Status.Conditions = append(pod.Status.Conditions, cond)
+	return true
+}
+
+// hasReadinessGate 判断 Pod 是否在 spec.readinessGates 中声明了 conditionType
+func hasReadinessGate(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, g := range pod.Spec.ReadinessGates {
+		if g.ConditionType == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// deregisterPodServices 注销 discovery 按 Pod 注册的所有 Consul 服务（停止时调用）
+func (s *Service) deregisterPodServices() {
+	agent := s.consulClient.Agent()
+	existing, err := agent.ServicesWithFilter(fmt.Sprintf("%q in Tags", podServiceTag))
+	if err != nil {
+		s.logger.Warnf("获取 Consul 中的 Pod 服务失败: %v", err)
+		return
+	}
+	for id := range existing {
+		if err := agent.ServiceDeregister(id); err != nil {
+			s.logger.Warnf("从 Consul 注销 Pod 服务 %s 失败: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/hashicorp/consul/api"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// fakeAgent 是只实现 discovery 用到的 agent 接口的 Consul：服务注册/注销、TTL 更新与检查列表。
+// 新注册的检查与 Consul 一样处于 critical 且没有输出（尚未执行）
+type fakeAgent struct {
+	mu       sync.Mutex
+	services map[string]*api.AgentService
+	checks   map[string]*api.AgentCheck
+	// registrations 每个服务的注册次数
+	registrations map[string]int
+}
+
+func newFakeAgent(t *testing.T) (*fakeAgent, string) {
+	f := &fakeAgent{
+		services:      make(map[string]*api.AgentService),
+		checks:        make(map[string]*api.AgentCheck),
+		registrations: make(map[string]int),
+	}
+	server := httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(server.Close)
+	return f, strings.TrimPrefix(server.URL, "http://")
+}
+
+func (f *fakeAgent) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch path := r.URL.Path; {
+	case r.Method == http.MethodGet && path == "/v1/agent/services":
+		// 只支持 `"<tag>" in Tags` 形式的过滤
+		tag, _, _ := strings.Cut(r.URL.Query().Get("filter"), " in Tags")
+		tag = strings.Trim(tag, `"`)
+		result := make(map[string]*api.AgentService)
+		for id, svc := range f.services {
+			if tag == "" || slices.Contains(svc.Tags, tag) {
+				result[id] = svc
+			}
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodGet && path == "/v1/agent/checks":
+		_ = json.NewEncoder(w).Encode(f.checks)
+	case r.Method == http.MethodPut && path == "/v1/agent/service/register":
+		var reg api.AgentServiceRegistration
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.services[reg.ID] = &api.AgentService{ID: reg.ID, Service: reg.Name, Tags: reg.Tags, Port: reg.Port, Address: reg.Address, Meta: reg.Meta}
+		f.registrations[reg.ID]++
+		if reg.Check != nil {
+			check := &api.AgentCheck{CheckID: reg.Check.CheckID, Name: reg.Check.Name, ServiceID: reg.ID, Status: api.HealthCritical,
+				Definition: api.HealthCheckDefinition{HTTP: reg.Check.HTTP, TCP: reg.Check.TCP}}
+			f.checks[check.CheckID] = check
+		}
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/v1/agent/service/deregister/"):
+		id := strings.TrimPrefix(path, "/v1/agent/service/deregister/")
+		delete(f.services, id)
+		for checkID, check := range f.checks {
+			if check.ServiceID == id {
+				delete(f.checks, checkID)
+			}
+		}
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/v1/agent/check/update/"):
+		check, ok := f.checks[strings.TrimPrefix(path, "/v1/agent/check/update/")]
+		if !ok {
+			http.Error(w, "unknown check", http.StatusNotFound)
+			return
+		}
+		var update struct{ Status, Output string }
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		check.Status, check.Output = update.Status, update.Output
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// setCheck 模拟 Consul 执行检查后的结果
+func (f *fakeAgent) setCheck(checkID, status, output string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checks[checkID].Status = status
+	f.checks[checkID].Output = output
+}
+
+func (f *fakeAgent) check(checkID string) *api.AgentCheck {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.checks[checkID]; ok {
+		copied := *c
+		return &copied
+	}
+	return nil
+}
+
+func (f *fakeAgent) service(id string) *api.AgentService {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.services[id]
+}
+
+func (f *fakeAgent) registered(id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.registrations[id]
+}
+
+// newPodCheckService 创建连接 fake agent 的 discovery 服务，Store 中有选中 app=web 的 Service web
+func newPodCheckService(t *testing.T) (*Service, *fakeAgent, storage.Store) {
+	agent, addr := newFakeAgent(t)
+	store := storage.NewMemoryStore()
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "web"},
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromString("http")}}},
+	}
+	if err := store.Create(serviceGVK, svc); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(store, logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()},
+		Settings{ConsulAddress: addr, NodeName: "node-1", WatchInterval: time.Second, PodHealthChecks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, agent, store
+}
+
+// webPod 返回被 Service web 选中、正在运行的 Pod
+func webPod(name, ip string, ready bool, probe *corev1.Probe) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}, ReadinessProbe: probe,
+		}}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func getPod(t *testing.T, store storage.Store, name string) *corev1.Pod {
+	t.Helper()
+	obj, err := store.Get(podGVK, "default", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*corev1.Pod)
+}
+
+func podCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) corev1.ConditionStatus {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == conditionType {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+func TestSyncPodChecksTTL(t *testing.T) {
+	s, agent, store := newPodCheckService(t)
+	id := podServiceID("default", "web", "web-1")
+
+	if err := store.Create(podGVK, webPod("web-1", "10.0.0.5", true, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	svc := agent.service(id)
+	if svc == nil || svc.Service != "default-web" || svc.Address != "10.0.0.5" || svc.Port != 8080 {
+		t.Fatalf("registered service = %+v", svc)
+	}
+	if c := agent.check(podCheckID(id)); c == nil || c.Status != api.HealthPassing {
+		t.Fatalf("TTL check of a ready pod = %+v", c)
+	}
+
+	// Ready 变为 False 后 TTL 检查变为 critical，不重新注册
+	pod := getPod(t, store, "web-1").DeepCopy()
+	pod.Status.Conditions[0].Status = corev1.ConditionFalse
+	if err := store.Update(podGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	if c := agent.check(podCheckID(id)); c == nil || c.Status != api.HealthCritical || c.Output != "pod is not ready" {
+		t.Fatalf("TTL check of an unready pod = %+v", c)
+	}
+	if n := agent.registered(id); n != 1 {
+		t.Errorf("service registered %d times, want 1", n)
+	}
+
+	// 恢复就绪
+	pod = getPod(t, store, "web-1").DeepCopy()
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	if err := store.Update(podGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	if c := agent.check(podCheckID(id)); c == nil || c.Status != api.HealthPassing {
+		t.Fatalf("TTL check after the pod became ready again = %+v", c)
+	}
+
+	// TTL 检查不写回 Pod
+	if status := podCondition(getPod(t, store, "web-1"), PodReadinessGate); status != "" {
+		t.Errorf("TTL check wrote %s back to the pod", status)
+	}
+
+	// 删除 Pod 后注销服务与检查
+	if err := store.Delete(podGVK, "default", "web-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	if svc := agent.service(id); svc != nil {
+		t.Fatalf("service of a deleted pod is still registered: %+v", svc)
+	}
+	if c := agent.check(podCheckID(id)); c != nil {
+		t.Fatalf("check of a deleted pod is still registered: %+v", c)
+	}
+}
+
+func TestSyncPodChecksReregistersAndDeregisters(t *testing.T) {
+	s, agent, store := newPodCheckService(t)
+	id := podServiceID("default", "web", "web-1")
+	if err := store.Create(podGVK, webPod("web-1", "10.0.0.5", true, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pod IP 变化后重新注册
+	pod := getPod(t, store, "web-1").DeepCopy()
+	pod.Status.PodIP = "10.0.0.9"
+	if err := store.Update(podGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	if svc := agent.service(id); svc == nil || svc.Address != "10.0.0.9" || agent.registered(id) != 2 {
+		t.Fatalf("service after the pod IP changed = %+v (%d registrations)", svc, agent.registered(id))
+	}
+
+	// 不再被 Service 选中（或开始退出）的 Pod 被注销
+	pod = getPod(t, store, "web-1").DeepCopy()
+	pod.Labels = map[string]string{"app": "api"}
+	if err := store.Update(podGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	if svc := agent.service(id); svc != nil {
+		t.Fatalf("service of an unselected pod is still registered: %+v", svc)
+	}
+
+	// 停止时注销所有 Pod 服务
+	if err := store.Create(podGVK, webPod("web-2", "10.0.0.6", true, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	s.deregisterPodServices()
+	if svc := agent.service(podServiceID("default", "web", "web-2")); svc != nil {
+		t.Fatalf("deregisterPodServices left %+v", svc)
+	}
+}
+
+func TestSyncPodChecksProbeWriteBack(t *testing.T) {
+	s, agent, store := newPodCheckService(t)
+	id := podServiceID("default", "web", "web-1")
+	probe := &corev1.Probe{PeriodSeconds: 5, ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "healthz", Port: intstr.FromString("http")}}}
+	pod := webPod("web-1", "10.0.0.5", true, probe)
+	pod.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: PodReadinessGate}}
+	if err := store.Create(podGVK, pod); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	c := agent.check(podCheckID(id))
+	if c == nil || c.Definition.HTTP != "http://10.0.0.5:8080/healthz" {
+		t.Fatalf("HTTP check = %+v", c)
+	}
+	// 检查尚未执行时不写回
+	if status := podCondition(getPod(t, store, "web-1"), PodReadinessGate); status != "" {
+		t.Fatalf("unexecuted check wrote %s back to the pod", status)
+	}
+
+	// 检查失败：readiness gate 为 False，Pod 变为未就绪
+	agent.setCheck(podCheckID(id), api.HealthCritical, "connection refused")
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	got := getPod(t, store, "web-1")
+	if podCondition(got, PodReadinessGate) != corev1.ConditionFalse || podCondition(got, corev1.PodReady) != corev1.ConditionFalse {
+		t.Fatalf("conditions after a failing check = %+v", got.Status.Conditions)
+	}
+
+	// 检查恢复：readiness gate 为 True（Ready 由 PodController 重新计算）
+	agent.setCheck(podCheckID(id), api.HealthPassing, "HTTP GET: 200 OK")
+	if err := s.syncPodChecks(); err != nil {
+		t.Fatal(err)
+	}
+	if status := podCondition(getPod(t, store, "web-1"), PodReadinessGate); status != corev1.ConditionTrue {
+		t.Fatalf("readiness gate after the check recovered = %s", status)
+	}
+}
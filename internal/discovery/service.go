@@ -13,8 +13,10 @@ import (
 	"sync"
 	"time"
 
-	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/network"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	"github.com/hashicorp/consul/api"
 	corev1 "k8s.io/api/core/v1"
@@ -51,6 +53,13 @@ type Settings struct {
 	WatchInterval time.Duration
 	// AutoStartConsul 如果 Consul 不可用，是否自动启动 Consul 容器（仅当 ConsulAddress 指向 localhost 时生效）
 	AutoStartConsul bool
+	// ConsulContainer 自动启动的 Consul 容器的镜像、拉取策略、追加参数/环境变量与数据目录（对应 discovery.consul 配置）
+	ConsulContainer config.ContainerConfig
+	// ClusterID 所属集群 ID（对应 cluster.id），自动启动的 Consul 容器带有 io.k3.cluster.id 标签
+	ClusterID string
+	// PodHealthChecks 是否把被 Service 选中的 Pod 注册到 Consul（检查取自 readinessProbe），并把检查结果写回 Pod 的 PodReadinessGate 条件；
+	// 同时把 ExternalName Service 注册为 CNAME（对应 discovery.consul.pod_health_checks）
+	PodHealthChecks bool
 }
 
 // ConsulContainerHandle 记录由本进程"自动拉起"的 Consul 容器信息
@@ -163,6 +172,11 @@ func (s *Service) Start(ctx context.Context) error {
 	// 启动服务发现循环
 	go s.discoveryLoop(bgCtx)
 
+	// 按 Pod 注册健康检查
+	if s.settings.PodHealthChecks {
+		go s.podChecksLoop(bgCtx)
+	}
+
 	s.logger.Infof("discovery: 已启动 (consul=%s, service=%s, node=%s, health=%s:%d)",
 		s.settings.ConsulAddress, s.settings.ServiceName, s.settings.NodeName,
 		"0.0.0.0", s.settings.ServicePort)
@@ -183,6 +197,10 @@ func (s *Service) Stop(ctx context.Context) error {
 	}
 
 	// 从 Consul 注销服务
+	if s.settings.PodHealthChecks {
+		s.deregisterPodServices()
+		s.deregisterExternalNames()
+	}
 	if err := s.deregisterService(ctx); err != nil {
 		s.logger.Warnf("从 Consul 注销服务失败: %v", err)
 	}
@@ -231,7 +249,7 @@ func (s *Service) healthMux() http.Handler {
 		_, _ = w.Write([]byte("ok"))
 	})
 	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
-		localIPs := localIPv4s()
+		localIPs := network.LocalIPs()
 		info := map[string]interface{}{
 			"node":      s.settings.NodeName,
 			"service":   s.settings.ServiceName,
@@ -248,8 +266,8 @@ func (s *Service) healthMux() http.Handler {
 
 // registerService 注册服务到 Consul
 func (s *Service) registerService(ctx context.Context) error {
-	// 获取本地 IP 地址
-	localIPs := localIPv4s()
+	// 获取本地 IP 地址：主地址取实际连得通 Consul 的地址族，双栈时另一个地址族的地址写入 TaggedAddresses
+	localIPs := s.advertiseIPs()
 	if len(localIPs) == 0 {
 		return fmt.Errorf("无法获取本地 IP 地址")
 	}
@@ -260,17 +278,18 @@ func (s *Service) registerService(ctx context.Context) error {
 		Interval:                       s.settings.HealthCheckInterval.String(),
 		Timeout:                        s.settings.HealthCheckTimeout.String(),
 		DeregisterCriticalServiceAfter: s.settings.DeregisterCriticalServiceAfter.String(),
-		HTTP:                           fmt.Sprintf("http://%s:%d/healthz", serviceAddress, s.settings.ServicePort),
+		HTTP:                           fmt.Sprintf("http://%s/healthz", net.JoinHostPort(serviceAddress, strconv.Itoa(s.settings.ServicePort))),
 	}
 
 	// 构建服务注册信息
 	registration := &api.AgentServiceRegistration{
-		ID:      s.serviceID,
-		Name:    s.settings.ServiceName,
-		Tags:    s.settings.ServiceTags,
-		Port:    s.settings.ServicePort,
-		Address: serviceAddress,
-		Check:   healthCheck,
+		ID:              s.serviceID,
+		Name:            s.settings.ServiceName,
+		Tags:            s.settings.ServiceTags,
+		Port:            s.settings.ServicePort,
+		Address:         serviceAddress,
+		TaggedAddresses: taggedAddresses(localIPs, s.settings.ServicePort),
+		Check:           healthCheck,
 		Meta: map[string]string{
 			"node": s.settings.NodeName,
 			"pid":  strconv.Itoa(os.Getpid()),
@@ -347,7 +366,7 @@ func (s *Service) syncServiceToNode(svc *api.CatalogService) error {
 		}
 	}
 
-	// 解析服务地址
+	// 解析服务地址（双栈节点的另一个地址族在 TaggedAddresses 中）
 	var addresses []corev1.NodeAddress
 	if svc.ServiceAddress != "" {
 		addresses = append(addresses, corev1.NodeAddress{
@@ -355,6 +374,14 @@ func (s *Service) syncServiceToNode(svc *api.CatalogService) error {
 			Address: svc.ServiceAddress,
 		})
 	}
+	for _, tag := range []string{taggedLANIPv4, taggedLANIPv6} {
+		if a, ok := svc.ServiceTaggedAddresses[tag]; ok && a.Address != "" && a.Address != svc.ServiceAddress {
+			addresses = append(addresses, corev1.NodeAddress{
+				Type:    corev1.NodeInternalIP,
+				Address: a.Address,
+			})
+		}
+	}
 	if svc.Address != "" && svc.Address != svc.ServiceAddress {
 		addresses = append(addresses, corev1.NodeAddress{
 			Type:    corev1.NodeHostName,
@@ -389,8 +416,14 @@ func (s *Service) syncServiceToNode(svc *api.CatalogService) error {
 			Addresses: addresses,
 			Conditions: []corev1.NodeCondition{
 				{
-					Type:               corev1.NodeReady,
-					Status:             func() corev1.ConditionStatus { if isReady { return corev1.ConditionTrue } else { return corev1.ConditionFalse } }(),
+					Type: corev1.NodeReady,
+					Status: func() corev1.ConditionStatus {
+						if isReady {
+							return corev1.ConditionTrue
+						} else {
+							return corev1.ConditionFalse
+						}
+					}(),
 					LastHeartbeatTime:  metav1.Now(),
 					LastTransitionTime: metav1.Now(),
 					Reason:             "ConsulHealthCheck",
@@ -416,6 +449,7 @@ func (s *Service) syncServiceToNode(svc *api.CatalogService) error {
 	existingNode, err := s.store.Get(gvk, "", nodeName)
 	if err != nil {
 		// 节点不存在，创建新节点
+		storage.RecordManager(node, fieldManager)
 		if err := s.store.Create(gvk, node); err != nil {
 			return fmt.Errorf("创建节点失败: %w", err)
 		}
@@ -423,6 +457,8 @@ func (s *Service) syncServiceToNode(svc *api.CatalogService) error {
 	} else {
 		// 节点已存在，更新节点信息
 		if existingNodeNode, ok := existingNode.(*corev1.Node); ok {
+			// 保留其他写入者设置的 labels/annotations
+			storage.MergeNodeMetadata(existingNodeNode, node)
 			// 保留原有的条件，更新心跳时间
 			node.Status.Conditions = existingNodeNode.Status.Conditions
 			for i := range node.Status.Conditions {
@@ -436,7 +472,7 @@ func (s *Service) syncServiceToNode(svc *api.CatalogService) error {
 				}
 			}
 		}
-		if err := s.store.Update(gvk, node); err != nil {
+		if err := s.updateNode(gvk, node); err != nil {
 			return fmt.Errorf("更新节点失败: %w", err)
 		}
 		s.logger.Debugf("已更新节点: %s (来自 Consul 服务: %s)", nodeName, svc.ServiceID)
@@ -447,10 +483,14 @@ func (s *Service) syncServiceToNode(svc *api.CatalogService) error {
 
 // registerSelfNode 注册当前节点到 store
 func (s *Service) registerSelfNode() error {
-	localIPs := localIPv4s()
+	localIPs := s.advertiseIPs()
 	if len(localIPs) == 0 {
 		return fmt.Errorf("无法获取本地 IP 地址")
 	}
+	addresses := make([]corev1.NodeAddress, 0, len(localIPs))
+	for _, ip := range localIPs {
+		addresses = append(addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: ip.String()})
+	}
 
 	node := &corev1.Node{
 		TypeMeta: metav1.TypeMeta{
@@ -466,12 +506,7 @@ func (s *Service) registerSelfNode() error {
 			CreationTimestamp: metav1.Now(),
 		},
 		Status: corev1.NodeStatus{
-			Addresses: []corev1.NodeAddress{
-				{
-					Type:    corev1.NodeInternalIP,
-					Address: localIPs[0].String(),
-				},
-			},
+			Addresses: addresses,
 			Conditions: []corev1.NodeCondition{
 				{
 					Type:               corev1.NodeReady,
@@ -500,6 +535,7 @@ func (s *Service) registerSelfNode() error {
 	existingNode, err := s.store.Get(gvk, "", s.settings.NodeName)
 	if err != nil {
 		// 节点不存在，创建新节点
+		storage.RecordManager(node, fieldManager)
 		if err := s.store.Create(gvk, node); err != nil {
 			return fmt.Errorf("创建节点失败: %w", err)
 		}
@@ -507,6 +543,7 @@ func (s *Service) registerSelfNode() error {
 	} else {
 		// 节点已存在，更新节点信息
 		if existingNodeNode, ok := existingNode.(*corev1.Node); ok {
+			storage.MergeNodeMetadata(existingNodeNode, node)
 			node.Status.Conditions = existingNodeNode.Status.Conditions
 			for i := range node.Status.Conditions {
 				if node.Status.Conditions[i].Type == corev1.NodeReady {
@@ -514,7 +551,7 @@ func (s *Service) registerSelfNode() error {
 				}
 			}
 		}
-		if err := s.store.Update(gvk, node); err != nil {
+		if err := s.updateNode(gvk, node); err != nil {
 			return fmt.Errorf("更新节点失败: %w", err)
 		}
 		s.logger.Debugf("已更新当前节点: %s", s.settings.NodeName)
@@ -540,22 +577,49 @@ func (s *Service) heartbeatLoop(ctx context.Context) {
 	}
 }
 
-// localIPv4s 获取本地 IPv4 地址
-func localIPv4s() []net.IP {
-	var ips []net.IP
-	addrs, err := net.InterfaceAddrs()
+// Consul 中双栈地址的标准 TaggedAddresses 键
+const (
+	taggedLANIPv4 = "lan_ipv4"
+	taggedLANIPv6 = "lan_ipv6"
+)
+
+// advertiseIPs 返回本机可公布的地址（见 network.LocalIPs），第一个为主地址：
+// 先向 Consul 建立一次 TCP 连接，按路由选出的本地地址就是 Consul（以及通过它发现本节点的其他节点）实际连得通的地址，把它排在最前；
+// Consul 在本机（回环地址）或连接失败时按 IPv4 在前
+func (s *Service) advertiseIPs() []net.IP {
+	ips := network.LocalIPs()
+	host, port := parseConsulAddress(s.settings.ConsulAddress)
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), 2*time.Second)
 	if err != nil {
 		return ips
 	}
+	local, _ := conn.LocalAddr().(*net.TCPAddr)
+	_ = conn.Close()
+	if local == nil || !network.UsableIP(local.IP) {
+		return ips
+	}
+	out := []net.IP{local.IP}
+	for _, ip := range network.PreferFamily(ips, local.IP) {
+		if !ip.Equal(local.IP) {
+			out = append(out, ip)
+		}
+	}
+	return out
+}
 
-	for _, addr := range addrs {
-		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
-			if ipNet.IP.To4() != nil {
-				ips = append(ips, ipNet.IP)
-			}
+// taggedAddresses 为每个地址族的第一个地址生成 Consul 的 lan_ipv4/lan_ipv6 TaggedAddresses
+func taggedAddresses(ips []net.IP, port int) map[string]api.ServiceAddress {
+	out := map[string]api.ServiceAddress{}
+	for _, ip := range ips {
+		key := taggedLANIPv4
+		if ip.To4() == nil {
+			key = taggedLANIPv6
+		}
+		if _, ok := out[key]; !ok {
+			out[key] = api.ServiceAddress{Address: ip.String(), Port: port}
 		}
 	}
-	return ips
+	return out
 }
 
 // defaultNodeName 获取默认节点名称
@@ -584,17 +648,16 @@ func parseConsulAddress(addr string) (host string, port int) {
 		return "localhost", 8500
 	}
 
-	// 处理格式：host:port
-	parts := strings.Split(addr, ":")
-	if len(parts) == 2 {
-		host = parts[0]
-		if p, err := strconv.Atoi(parts[1]); err == nil {
-			port = p
+	// 处理格式：host:port、[IPv6]:port，以及不带端口的主机名或 IPv6 地址
+	if h, p, err := net.SplitHostPort(addr); err == nil {
+		host = h
+		if v, err := strconv.Atoi(p); err == nil {
+			port = v
 		} else {
 			port = 8500
 		}
 	} else {
-		host = addr
+		host = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
 		port = 8500
 	}
 
@@ -609,10 +672,18 @@ func parseConsulAddress(addr string) (host string, port int) {
 }
 
 // buildConsulPod 构造用于拉起 Consul 容器的 Pod 描述
-func buildConsulPod(consulAddress string) *corev1.Pod {
+//
+// 默认以 -dev 模式运行 consul:1.17（数据只在内存中）；配置了 data_dir 时改为单节点 server 模式，
+// 数据写入挂载到 /consul/data 的宿主机目录，重启后服务注册信息不丢失。
+func buildConsulPod(consulAddress string, c config.ContainerConfig) *corev1.Pod {
 	_, port := parseConsulAddress(consulAddress)
 
-	return &corev1.Pod{
+	mode := []string{"-dev"}
+	if strings.TrimSpace(c.DataDir) != "" {
+		mode = []string{"-server", "-bootstrap-expect", "1", "-data-dir", "/consul/data"}
+	}
+
+	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: "discovery",
 			Name:      "consul",
@@ -622,12 +693,10 @@ func buildConsulPod(consulAddress string) *corev1.Pod {
 				{
 					Name:  "consul",
 					Image: "consul:1.17",
-					Args: []string{
-						"agent",
-						"-dev",
+					Args: append(append([]string{"agent"}, mode...),
 						"-client", "0.0.0.0",
 						"-ui",
-					},
+					),
 					Ports: []corev1.ContainerPort{
 						{
 							ContainerPort: 8500,
@@ -646,6 +715,8 @@ func buildConsulPod(consulAddress string) *corev1.Pod {
 			},
 		},
 	}
+	controller.ApplyContainerConfig(pod, c, "/consul/data")
+	return pod
 }
 
 // ensureConsulRunning 确保 Consul 容器运行
@@ -666,15 +737,15 @@ func (s *Service) ensureConsulRunning(ctx context.Context) error {
 		return nil
 	}
 
-	// 检测容器运行时
-	detector := controller.NewRuntimeDetector(s.logger)
+	// 检测容器运行时（Consul 在镜像缓存启动之前拉起，直接从镜像仓库拉取）
+	detector := controller.NewRuntimeDetector(s.logger, s.settings.ClusterID, "")
 	runtime, err := detector.DetectRuntime()
 	if err != nil {
 		return fmt.Errorf("未检测到可用容器运行时: %w", err)
 	}
 
 	// 构建 Consul Pod
-	pod := buildConsulPod(s.settings.ConsulAddress)
+	pod := buildConsulPod(s.settings.ConsulAddress, s.settings.ConsulContainer)
 
 	// 检查容器是否已在运行
 	status, _ := runtime.GetContainerStatus(ctx, pod)
@@ -735,3 +806,15 @@ func (s *Service) waitForConsul(addr string, timeout time.Duration) error {
 	}
 	return fmt.Errorf("等待 Consul 就绪超时: %s", addr)
 }
+
+// fieldManager 是 discovery 写入 Node 时使用的写入者名称
+const fieldManager = "k3-discovery"
+
+// updateNode 更新节点并记录写入者；覆盖其他写入者的 labels/annotations 时记录告警（仍然写入，保持原有行为）
+func (s *Service) updateNode(gvk schema.GroupVersionKind, node *corev1.Node) error {
+	conflict, err := storage.UpdateAs(s.store, gvk, node, fieldManager, true)
+	if conflict != nil {
+		s.logger.Warnf("discovery: 节点 %s 写入冲突: %v", node.Name, conflict)
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+func TestNamespaceIsolation(t *testing.T) {
+	c := Start(t, WithAuth(
+		config.TokenConfig{Token: "team-a", User: "alice", Namespaces: []string{"team-a"}},
+		config.TokenConfig{Token: "viewer", User: "bob", Namespaces: []string{"*"}},
+	))
+	c.Apply(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+  namespace: team-a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+  namespace: default
+`)
+
+	for _, tc := range []struct {
+		name, token, method, path string
+		want                      int
+	}{
+		{"own namespace", "team-a", http.MethodGet, "/api/v1/namespaces/team-a/configmaps", http.StatusOK},
+		{"other namespace", "team-a", http.MethodGet, "/api/v1/namespaces/default/configmaps", http.StatusForbidden},
+		// 跨 namespace 的 list/watch 不再静默过滤结果
+		{"cross-namespace list", "team-a", http.MethodGet, "/api/v1/configmaps", http.StatusForbidden},
+		{"cross-namespace watch", "team-a", http.MethodGet, "/api/v1/watch/configmaps", http.StatusForbidden},
+		{"cross-namespace list ?watch", "team-a", http.MethodGet, "/api/v1/configmaps?watch=true", http.StatusForbidden},
+		// 不带 namespace 的 /<resource>/<name> 是 default 中的对象
+		{"default object without namespace", "team-a", http.MethodGet, "/api/v1/configmaps/cfg", http.StatusForbidden},
+		{"cross-namespace write", "team-a", http.MethodDelete, "/api/v1/configmaps", http.StatusForbidden},
+		// 可以访问所有 namespace 的身份可以跨 namespace 列出
+		{"wildcard cross-namespace list", "viewer", http.MethodGet, "/api/v1/configmaps", http.StatusOK},
+		{"wildcard default object", "viewer", http.MethodGet, "/api/v1/configmaps/cfg", http.StatusOK},
+		// 集群级资源对所有身份只读
+		{"cluster-scoped list", "team-a", http.MethodGet, "/api/v1/nodes", http.StatusOK},
+		{"cluster-scoped get", "team-a", http.MethodGet, "/api/v1/namespaces/team-a", http.StatusOK},
+		{"cluster-scoped write", "team-a", http.MethodDelete, "/api/v1/namespaces/team-a", http.StatusForbidden},
+	} {
+		if code, body := c.DoAs(tc.token, tc.method, tc.path, nil); code != tc.want {
+			t.Errorf("%s: %s %s: HTTP %d, want %d: %s", tc.name, tc.method, tc.path, code, tc.want, body)
+		}
+	}
+}
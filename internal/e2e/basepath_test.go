@@ -0,0 +1,27 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+func TestBasePath(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Gin.BasePath = "/k3/"
+	}))
+
+	code, body := c.DoWithContentType(http.MethodPost, "/k3"+configMapsPath, "application/json",
+		[]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"behind-proxy"}}`))
+	if code != http.StatusCreated {
+		t.Fatalf("create under base path: HTTP %d: %s", code, body)
+	}
+	// 代理去掉前缀后转发的请求
+	if code, body := c.Do(http.MethodGet, configMapsPath+"/behind-proxy", nil); code != http.StatusOK {
+		t.Fatalf("get without base path: HTTP %d: %s", code, body)
+	}
+	if code, body := c.Do(http.MethodGet, "/k3/api/v1/namespaces/default/configmaps/behind-proxy", nil); code != http.StatusOK {
+		t.Fatalf("get under base path: HTTP %d: %s", code, body)
+	}
+}
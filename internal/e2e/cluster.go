@@ -0,0 +1,382 @@
+// Package e2e 在测试进程内启动完整的 fx 依赖图（memory Store、控制器、随机端口上的 apiserver），
+// 用真实的 HTTP 请求提交 manifest，并等待控制器把对象推进到期望的状态。
+//
+// 容器运行时使用内存中的 FakeRuntime，测试不会拉起任何容器：
+//
+//	c := e2e.Start(t)
+//	c.Apply(`apiVersion: apps/v1
+//	kind: Deployment
+//	...`)
+//	c.WaitForDeploymentReady("default", "web")
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterconfig"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/client"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// DefaultNodeName 是测试集群中唯一节点的名称
+	DefaultNodeName = "e2e-node"
+	// DefaultTimeout 是 WaitFor 系列方法的默认超时
+	DefaultTimeout = 10 * time.Second
+	// pollInterval 是 WaitFor 轮询 Store 的间隔
+	pollInterval = 20 * time.Millisecond
+	// AdminToken 是 WithAuth 开启认证时 Do、Apply 与 Client 使用的 cluster-admin token
+	AdminToken = "e2e-admin"
+)
+
+// Cluster 测试进程内运行的 k3：Store 可以直接断言状态，Server 为 apiserver 地址
+type Cluster struct {
+	t       testing.TB
+	Config  config.Config
+	Store   storage.Store
+	Manager *controller.ControllerManager
+	Runtime *FakeRuntime
+	// Server 为 apiserver 地址，例如 http://127.0.0.1:40123
+	Server string
+	Client *client.Clientset
+	http   *http.Client
+}
+
+// options 是 Start 的可选项
+type options struct {
+	configure []func(*config.Config)
+	logs      bool
+	extra     []fx.Option
+}
+
+// Option 修改测试集群的配置
+type Option func(*options)
+
+// WithConfig 在启动前修改配置（默认：memory 存储、节点名 e2e-node、关闭认证、请求统计与自注册）
+func WithConfig(fn func(cfg *config.Config)) Option {
+	return func(o *options) {
+		o.configure = append(o.configure, fn)
+	}
+}
+
+// WithLogs 把组件日志输出到 t.Log（默认丢弃）
+func WithLogs() Option {
+	return func(o *options) {
+		o.logs = true
+	}
+}
+
+// WithAuth 开启认证，tokens 之外追加 cluster-admin 的 AdminToken（Do、Apply 与 Client 使用）；
+// 以其他身份发送请求使用 DoAs
+func WithAuth(tokens ...config.TokenConfig) Option {
+	return func(o *options) {
+		o.configure = append(o.configure, func(cfg *config.Config) {
+			cfg.Auth.Enabled = true
+			cfg.Auth.Tokens = append(append(cfg.Auth.Tokens, tokens...), config.TokenConfig{Token: AdminToken, User: "e2e-admin", Role: webprovider.RoleClusterAdmin})
+		})
+	}
+}
+
+// WithFxOptions 向依赖图中追加模块，例如 tenancy.Module
+func WithFxOptions(opts ...fx.Option) Option {
+	return func(o *options) {
+		o.extra = append(o.extra, opts...)
+	}
+}
+
+// Start 启动测试集群，测试结束时自动停止
+func Start(t testing.TB, opts ...Option) *Cluster {
+	t.Helper()
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg := config.Config{NodeName: DefaultNodeName}
+	cfg.Storage.Type = "memory"
+	cfg.APIServer.UsageInterval = "off"
+	cfg.APIServer.SelfRegisterInterval = "off"
+	for _, fn := range o.configure {
+		fn(&cfg)
+	}
+	// NewControllerManager 优先使用环境变量中的节点名
+	t.Setenv("NODE_NAME", cfg.NodeName)
+
+	zapLogger := zap.NewNop()
+	if o.logs {
+		zapLogger = zaptest.NewLogger(t)
+	}
+	logger := logprovider.Logger{SugaredLogger: zapLogger.Sugar()}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("e2e: 监听随机端口失败: %v", err)
+	}
+
+	// Do 与 Client 共用连接池；Client 的 watch 请求不能有整体超时
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	var roundTripper http.RoundTripper = transport
+	if cfg.Auth.Enabled {
+		roundTripper = bearerTransport{token: AdminToken, next: transport}
+	}
+	c := &Cluster{
+		t:       t,
+		Config:  cfg,
+		Runtime: NewFakeRuntime(),
+		Server:  "http://" + ln.Addr().String(),
+		http:    &http.Client{Transport: roundTripper, Timeout: 15 * time.Second},
+	}
+
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(cfg, logger),
+		fx.Supply(fx.Annotate(c.Runtime, fx.As(new(controller.ContainerRuntime)))),
+		fx.Provide(
+			newFiberEngine,
+			bootstrap.ProvideDBContainerHandle,
+			bootstrap.ProvideStore,
+		),
+		clusterconfig.Module,
+		controller.Module,
+		apiserver.Module,
+		fx.Options(o.extra...),
+		fx.Populate(&c.Store, &c.Manager),
+		fx.Invoke(func(lc fx.Lifecycle, cm *controller.ControllerManager, engine webprovider.FiberEngine) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					if err := cm.Start(ctx); err != nil {
+						return err
+					}
+					go func() { _ = engine.App.Listener(ln) }()
+					return nil
+				},
+				OnStop: func(ctx context.Context) error {
+					_ = engine.App.ShutdownWithContext(ctx)
+					return cm.Stop(ctx)
+				},
+			})
+		}),
+	)
+	if err := app.Err(); err != nil {
+		_ = ln.Close()
+		t.Fatalf("e2e: 构建依赖图失败: %v", err)
+	}
+	// 与 cmd/k3 相同，使用不会超时的 context 启动：控制器的处理循环在 ctx 取消时退出
+	if err := app.Start(context.Background()); err != nil {
+		_ = ln.Close()
+		t.Fatalf("e2e: 启动失败: %v", err)
+	}
+	t.Cleanup(func() {
+		// fasthttp 关闭时会等待保持中的连接，先关闭测试客户端的空闲连接
+		transport.CloseIdleConnections()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := app.Stop(ctx); err != nil {
+			t.Logf("e2e: 停止失败: %v", err)
+		}
+	})
+
+	c.Client, err = client.NewForConfig(&client.Config{Host: c.Server, HTTPClient: &http.Client{Transport: roundTripper}})
+	if err != nil {
+		t.Fatalf("e2e: 创建客户端失败: %v", err)
+	}
+	c.waitForServer()
+	return c
+}
+
+// newFiberEngine 创建与 webprovider.NewFiberEngine 相同路由结构的 Fiber 应用（不依赖全局 zap logger）
+func newFiberEngine(cfg config.Config) (webprovider.FiberEngine, error) {
+	app := fiber.New(webprovider.WithTrustedProxies(fiber.Config{
+		DisableStartupMessage: true,
+		ErrorHandler:          webprovider.ErrorHandler,
+	}, cfg.Gin))
+	if err := webprovider.UseRequestMiddlewares(app, cfg); err != nil {
+		return webprovider.FiberEngine{}, err
+	}
+	return webprovider.FiberEngine{App: app, Api: app, BasePath: webprovider.NormalizeBasePath(cfg.Gin.BasePath)}, nil
+}
+
+// bearerTransport 为没有设置 Authorization 的请求加上 token
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+// waitForServer 等待 apiserver 开始接受请求
+func (c *Cluster) waitForServer() {
+	c.t.Helper()
+	c.WaitFor("apiserver 就绪", func() (bool, error) {
+		resp, err := c.http.Get(c.Server + "/api/v1/nodes")
+		if err != nil {
+			return false, nil
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusOK, nil
+	})
+}
+
+// Do 向 apiserver 发送请求，返回状态码与响应体；body 为 YAML 或 JSON
+func (c *Cluster) Do(method, path string, body []byte) (int, []byte) {
+	c.t.Helper()
+	contentType := ""
+	if body != nil {
+		contentType = "application/yaml"
+	}
+	return c.DoWithContentType(method, path, contentType, body)
+}
+
+// DoAs 以 token 对应的身份发送请求（需要 WithAuth），返回状态码与响应体
+func (c *Cluster) DoAs(token, method, path string, body []byte) (int, []byte) {
+	c.t.Helper()
+	contentType := ""
+	if body != nil {
+		contentType = "application/yaml"
+	}
+	return c.do(method, path, contentType, body, token)
+}
+
+// DoWithContentType 与 Do 相同，使用指定的 Content-Type（为空时不设置），例如 PATCH 的 application/json-patch+json
+func (c *Cluster) DoWithContentType(method, path, contentType string, body []byte) (int, []byte) {
+	c.t.Helper()
+	return c.do(method, path, contentType, body, "")
+}
+
+// do 发送请求；token 不为空时替换默认的 Authorization
+func (c *Cluster) do(method, path, contentType string, body []byte, token string) (int, []byte) {
+	c.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.Server+path, reader)
+	if err != nil {
+		c.t.Fatalf("e2e: 构造请求失败: %v", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("e2e: %s %s 失败: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("e2e: 读取 %s %s 的响应失败: %v", method, path, err)
+	}
+	return resp.StatusCode, data
+}
+
+// Apply 通过 apiserver 提交 manifest（支持 --- 分隔的多个文档）：不存在时创建，已存在时更新。
+// 返回解析出的对象，任何一个对象提交失败时测试失败
+func (c *Cluster) Apply(manifest string) []runtime.Object {
+	c.t.Helper()
+	objects, gvks, err := parser.NewParser().ParseYAMLManifest([]byte(manifest))
+	if err != nil {
+		c.t.Fatalf("e2e: 解析 manifest 失败: %v", err)
+	}
+	for i, obj := range objects {
+		if gvks[i] == nil {
+			c.t.Fatalf("e2e: 第 %d 个对象缺少 apiVersion/kind", i+1)
+		}
+		meta, ok := obj.(metav1.Object)
+		if !ok {
+			c.t.Fatalf("e2e: 第 %d 个对象没有 metadata", i+1)
+		}
+		namespace := meta.GetNamespace()
+		if namespace == "" && !apiserver.IsClusterScoped(gvks[i].Kind) {
+			namespace = "default"
+			meta.SetNamespace(namespace)
+		}
+		path, err := CollectionPath(*gvks[i], namespace)
+		if err != nil {
+			c.t.Fatalf("e2e: %v", err)
+		}
+		body, err := parser.ToYAML(obj)
+		if err != nil {
+			c.t.Fatalf("e2e: 序列化 %s/%s 失败: %v", gvks[i].Kind, meta.GetName(), err)
+		}
+		code, resp := c.Do(http.MethodPost, path, body)
+		if code == http.StatusConflict {
+			code, resp = c.Do(http.MethodPut, path+"/"+meta.GetName(), body)
+		}
+		if code < 200 || code >= 300 {
+			c.t.Fatalf("e2e: 提交 %s %s/%s 失败: HTTP %d: %s", gvks[i].Kind, namespace, meta.GetName(), code, strings.TrimSpace(string(resp)))
+		}
+	}
+	return objects
+}
+
+// Delete 通过 apiserver 删除对象，对象不存在时测试失败
+func (c *Cluster) Delete(gvk schema.GroupVersionKind, namespace, name string) {
+	c.t.Helper()
+	path, err := CollectionPath(gvk, namespace)
+	if err != nil {
+		c.t.Fatalf("e2e: %v", err)
+	}
+	if code, resp := c.Do(http.MethodDelete, path+"/"+name, nil); code < 200 || code >= 300 {
+		c.t.Fatalf("e2e: 删除 %s %s/%s 失败: HTTP %d: %s", gvk.Kind, namespace, name, code, strings.TrimSpace(string(resp)))
+	}
+}
+
+// CollectionPath 返回资源集合的 API 路径，例如 /apis/apps/v1/namespaces/default/deployments
+func CollectionPath(gvk schema.GroupVersionKind, namespace string) (string, error) {
+	resource, err := resourceFor(gvk)
+	if err != nil {
+		return "", err
+	}
+	prefix := "/api/" + gvk.Version
+	if gvk.Group != "" {
+		prefix = "/apis/" + gvk.Group + "/" + gvk.Version
+	}
+	if namespace == "" || apiserver.IsClusterScoped(gvk.Kind) {
+		return prefix + "/" + resource, nil
+	}
+	return prefix + "/namespaces/" + namespace + "/" + resource, nil
+}
+
+// resourceFor 返回 Kind 对应的资源复数名（以 apiserver 的路由为准）
+func resourceFor(gvk schema.GroupVersionKind) (string, error) {
+	lower := strings.ToLower(gvk.Kind)
+	for _, candidate := range []string{lower + "s", lower + "es", strings.TrimSuffix(lower, "y") + "ies"} {
+		if served, err := apiserver.GVKForResource(candidate); err == nil && served.Kind == gvk.Kind {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("apiserver 不支持 %s", gvk.Kind)
+}
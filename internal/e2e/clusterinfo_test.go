@@ -0,0 +1,88 @@
+package e2e
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterinfo"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClusterInfoCreatedAtBootstrap(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Cluster.ID = "c-test"
+	}))
+
+	code, body := c.Do(http.MethodGet, "/version", nil)
+	if code != http.StatusOK {
+		t.Fatalf("GET /version: %d %s", code, body)
+	}
+	var v apiserver.VersionInfo
+	if err := json.Unmarshal(body, &v); err != nil {
+		t.Fatalf("decode version: %v", err)
+	}
+	if v.GitVersion == "" || v.Cluster == nil {
+		t.Fatalf("unexpected version info: %s", body)
+	}
+	if v.Cluster.ClusterUID == "" || v.Cluster.ClusterID != "c-test" || v.Cluster.StorageBackend != "memory" {
+		t.Fatalf("unexpected cluster identity: %+v", v.Cluster)
+	}
+	if len(v.Nodes) != 1 || v.Nodes[0].Name != DefaultNodeName {
+		t.Fatalf("unexpected nodes: %+v", v.Nodes)
+	}
+
+	code, body = c.Do(http.MethodGet, "/apis/k3.io/v1/clusterinfos/"+k3v1.ClusterInfoName, nil)
+	if code != http.StatusOK {
+		t.Fatalf("GET clusterinfo: %d %s", code, body)
+	}
+	var info k3v1.ClusterInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		t.Fatalf("decode clusterinfo: %v", err)
+	}
+	if info.Spec.ClusterUID != v.Cluster.ClusterUID {
+		t.Fatalf("clusterUID mismatch: %q vs %q", info.Spec.ClusterUID, v.Cluster.ClusterUID)
+	}
+	// API 只读
+	if code, _ := c.Do(http.MethodDelete, "/apis/k3.io/v1/clusterinfos/"+k3v1.ClusterInfoName, nil); code == http.StatusOK {
+		t.Fatalf("expected clusterinfo to be read-only")
+	}
+
+	// 另一个集群的节点、或版本相差过大的节点不能加入
+	now := time.Now()
+	if err := clusterinfo.Check(c.Store, clusterinfo.Identity{NodeName: "other", ClusterID: "c-other"}, now); !errors.Is(err, clusterinfo.ErrIncompatible) {
+		t.Fatalf("expected cluster id mismatch, got %v", err)
+	}
+	stored := info.DeepCopy()
+	stored.Status.Nodes[0].K3Version = "v0.4.0"
+	if err := c.Store.Update(k3v1.ClusterInfoGVK, stored); err != nil {
+		t.Fatalf("update clusterinfo: %v", err)
+	}
+	if err := clusterinfo.Check(c.Store, clusterinfo.Identity{NodeName: "node-new", K3Version: "v0.6.0"}, now); !errors.Is(err, clusterinfo.ErrIncompatible) {
+		t.Fatalf("expected version skew error, got %v", err)
+	}
+	joined, err := clusterinfo.Join(c.Store, clusterinfo.Identity{NodeName: "node-new", ClusterID: "c-test", K3Version: "v0.5.1"}, now)
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if len(joined.Status.Nodes) != 2 || joined.Spec.ClusterUID != info.Spec.ClusterUID {
+		t.Fatalf("unexpected clusterinfo after join: %+v", joined)
+	}
+
+	// 早已离开（Node 对象不存在）的 node-new 不再参与版本检查
+	stale := joined.DeepCopy()
+	for i := range stale.Status.Nodes {
+		stale.Status.Nodes[i].JoinedAt = metav1.NewTime(now.Add(-time.Hour))
+	}
+	if err := c.Store.Update(k3v1.ClusterInfoGVK, stale); err != nil {
+		t.Fatalf("update clusterinfo: %v", err)
+	}
+	if err := clusterinfo.Check(c.Store, clusterinfo.Identity{NodeName: "node-next", K3Version: "v0.3.0"}, now); err != nil {
+		t.Fatalf("stale members should be ignored: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const configMapsPath = "/api/v1/namespaces/default/configmaps"
+
+// postConfigMap 以 JSON 请求体创建 ConfigMap，返回状态码与响应体
+func postConfigMap(t *testing.T, c *Cluster, cm *corev1.ConfigMap) (int, []byte) {
+	t.Helper()
+	cm.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+	body, err := json.Marshal(cm)
+	if err != nil {
+		t.Fatalf("encode configmap: %v", err)
+	}
+	return c.DoWithContentType(http.MethodPost, configMapsPath, "application/json", body)
+}
+
+func TestConfigMapBinaryDataRoundTrip(t *testing.T) {
+	c := Start(t)
+
+	blob := make([]byte, 64*1024)
+	for i := range blob {
+		blob[i] = byte(i * 7)
+	}
+	code, body := postConfigMap(t, c, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "assets"},
+		Data:       map[string]string{"index.html": "<h1>k3</h1>"},
+		BinaryData: map[string][]byte{"logo.png": blob},
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("create: HTTP %d: %s", code, body)
+	}
+
+	code, body = c.Do(http.MethodGet, configMapsPath+"/assets", nil)
+	if code != http.StatusOK {
+		t.Fatalf("get: HTTP %d: %s", code, body)
+	}
+	var got corev1.ConfigMap
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decode configmap: %v", err)
+	}
+	if !bytes.Equal(got.BinaryData["logo.png"], blob) || got.Data["index.html"] != "<h1>k3</h1>" {
+		t.Fatalf("configmap payload changed after round trip: data=%v binaryData keys=%d", got.Data, len(got.BinaryData))
+	}
+}
+
+func TestConfigMapAndSecretSizeLimits(t *testing.T) {
+	c := Start(t)
+
+	// 恰好 1MiB（键与值）可以创建
+	key := "data.txt"
+	code, body := postConfigMap(t, c, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "at-limit"},
+		Data:       map[string]string{key: strings.Repeat("a", apiserver.MaxConfigMapSize-len(key))},
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("create configmap at the limit: HTTP %d: %s", code, body)
+	}
+
+	// data 与 binaryData 合计超过 1MiB
+	code, body = postConfigMap(t, c, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "too-large"},
+		Data:       map[string]string{"a": strings.Repeat("a", apiserver.MaxConfigMapSize/2)},
+		BinaryData: map[string][]byte{"b": make([]byte, apiserver.MaxConfigMapSize/2)},
+	})
+	if code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("create oversized configmap: HTTP %d: %s", code, body)
+	}
+
+	// data 与 binaryData 中的键重复
+	code, body = postConfigMap(t, c, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "duplicate"},
+		Data:       map[string]string{"k": "v"},
+		BinaryData: map[string][]byte{"k": {1}},
+	})
+	if code != http.StatusBadRequest {
+		t.Fatalf("create configmap with duplicate key: HTTP %d: %s", code, body)
+	}
+
+	// 更新也受限制
+	code, body = c.Do(http.MethodGet, configMapsPath+"/at-limit", nil)
+	if code != http.StatusOK {
+		t.Fatalf("get: HTTP %d: %s", code, body)
+	}
+	var cm corev1.ConfigMap
+	if err := json.Unmarshal(body, &cm); err != nil {
+		t.Fatalf("decode configmap: %v", err)
+	}
+	cm.Data["more"] = "x"
+	update, _ := json.Marshal(&cm)
+	code, body = c.DoWithContentType(http.MethodPut, configMapsPath+"/at-limit", "application/json", update)
+	if code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("update configmap over the limit: HTTP %d: %s", code, body)
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "too-large"},
+		Data:       map[string][]byte{"cert": make([]byte, apiserver.MaxSecretSize)},
+	}
+	data, _ := json.Marshal(secret)
+	code, body = c.DoWithContentType(http.MethodPost, "/api/v1/namespaces/default/secrets", "application/json", data)
+	if code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("create oversized secret: HTTP %d: %s", code, body)
+	}
+}
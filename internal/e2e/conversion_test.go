@@ -0,0 +1,50 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDeploymentVersions(t *testing.T) {
+	c := Start(t)
+
+	// 以 apps/v1beta1 创建（省略 selector），Store 中保存 apps/v1
+	deploy := []byte(`{"apiVersion":"apps/v1beta1","kind":"Deployment","metadata":{"name":"web"},"spec":{"replicas":1,` +
+		`"template":{"metadata":{"labels":{"app":"web"}},"spec":{"containers":[{"name":"nginx","image":"nginx:1.25"}]}}}}`)
+	code, body := c.DoWithContentType(http.MethodPost, "/apis/apps/v1beta1/namespaces/default/deployments", "application/json", deploy)
+	if code != http.StatusCreated {
+		t.Fatalf("create apps/v1beta1 deployment: HTTP %d: %s", code, body)
+	}
+	stored, ok := c.Get(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "default", "web").(*appsv1.Deployment)
+	if !ok || stored.Spec.Selector == nil || stored.Spec.Selector.MatchLabels["app"] != "web" {
+		t.Fatalf("stored deployment = %+v", stored)
+	}
+
+	// 每个登记的版本都可以读取，返回请求的版本
+	for _, version := range []string{"v1", "v1beta1", "v1beta2"} {
+		code, body := c.Do(http.MethodGet, "/apis/apps/"+version+"/namespaces/default/deployments/web", nil)
+		if code != http.StatusOK {
+			t.Fatalf("get apps/%s deployment: HTTP %d: %s", version, code, body)
+		}
+		var meta struct {
+			APIVersion string `json:"apiVersion"`
+		}
+		if err := json.Unmarshal(body, &meta); err != nil || meta.APIVersion != "apps/"+version {
+			t.Fatalf("get apps/%s deployment: apiVersion %q (%v)", version, meta.APIVersion, err)
+		}
+	}
+
+	// 没有提供的版本返回 404
+	for _, path := range []string{
+		"/apis/apps/v2/namespaces/default/deployments",
+		"/apis/apps/v1beta1/namespaces/default/daemonsets",
+	} {
+		if code, body := c.Do(http.MethodGet, path, nil); code != http.StatusNotFound {
+			t.Errorf("GET %s: HTTP %d: %s, want 404", path, code, body)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/ctrlruntime"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/fx"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const mirrorFinalizer = "example.k3.io/mirror"
+
+// mirrorReconciler 是按 controller-runtime 写法实现的示例 operator：为每个 ConfigMap 维护一个同名、数据相同的 Secret，
+// ConfigMap 删除时由 finalizer 删除 Secret
+type mirrorReconciler struct {
+	client ctrlclient.Client
+}
+
+func (r *mirrorReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	var cm corev1.ConfigMap
+	if err := r.client.Get(ctx, req.NamespacedName, &cm); err != nil {
+		return reconcile.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	if cm.Labels["mirror"] != "true" {
+		return reconcile.Result{}, nil
+	}
+	if !cm.DeletionTimestamp.IsZero() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: cm.Namespace, Name: cm.Name}}
+		if err := r.client.Delete(ctx, secret); ctrlclient.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(&cm, mirrorFinalizer)
+		return reconcile.Result{}, r.client.Update(ctx, &cm)
+	}
+	if controllerutil.AddFinalizer(&cm, mirrorFinalizer) {
+		if err := r.client.Update(ctx, &cm); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: cm.Namespace, Name: cm.Name}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.client, secret, func() error {
+		secret.StringData = nil
+		secret.Data = map[string][]byte{}
+		for k, v := range cm.Data {
+			secret.Data[k] = []byte(v)
+		}
+		return controllerutil.SetControllerReference(&cm, secret, r.client.Scheme())
+	})
+	return reconcile.Result{}, err
+}
+
+func TestControllerRuntimeReconciler(t *testing.T) {
+	c := Start(t, WithFxOptions(fx.Provide(
+		fx.Annotate(func(store storage.Store) (controller.Controller, error) {
+			return ctrlruntime.NewControllerManagedBy(store).
+				Named("configmap-mirror").
+				For(&corev1.ConfigMap{}).
+				Owns(&corev1.Secret{}).
+				Complete(&mirrorReconciler{client: ctrlruntime.NewClient(store)})
+		}, fx.ResultTags(`group:"controllers"`)),
+	)))
+	ctx := context.Background()
+	secretGVK := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+	c.Apply(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  labels:
+    mirror: "true"
+data:
+  key: v1
+`)
+	secretData := func() string {
+		obj, err := c.Store.Get(secretGVK, "default", "app")
+		if err != nil {
+			return ""
+		}
+		return string(obj.(*corev1.Secret).Data["key"])
+	}
+	c.WaitFor("secret mirrored", func() (bool, error) { return secretData() == "v1", nil })
+
+	cm, err := c.Client.CoreV1().ConfigMaps("default").Get(ctx, "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get configmap: %v", err)
+	}
+	cm.Data["key"] = "v2"
+	if _, err := c.Client.CoreV1().ConfigMaps("default").Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update configmap: %v", err)
+	}
+	c.WaitFor("secret updated", func() (bool, error) { return secretData() == "v2", nil })
+
+	// Owns：删除子资源后 Reconcile 重新创建
+	if err := c.Store.Delete(secretGVK, "default", "app"); err != nil {
+		t.Fatalf("delete secret: %v", err)
+	}
+	c.WaitFor("secret recreated", func() (bool, error) { return secretData() == "v2", nil })
+
+	// 控制器的处理统计出现在 /debug/controllers 中
+	code, body := c.Do(http.MethodGet, "/debug/controllers", nil)
+	if code != http.StatusOK {
+		t.Fatalf("GET /debug/controllers: %d %s", code, body)
+	}
+	var statuses apiserver.ControllerStatusList
+	if err := json.Unmarshal(body, &statuses); err != nil {
+		t.Fatalf("decode controller statuses: %s", body)
+	}
+	found := false
+	for _, st := range statuses.Controllers {
+		if st.Name == "configmap-mirror" {
+			found = st.Running && st.Reconciles > 0
+		}
+	}
+	if !found {
+		t.Fatalf("configmap-mirror not reported as running: %s", body)
+	}
+
+	// 通过适配器 client 删除：先由 finalizer 清理 Secret，再删除 ConfigMap
+	cl := ctrlruntime.NewClient(c.Store)
+	if err := cl.Delete(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"}}); err != nil {
+		t.Fatalf("delete configmap: %v", err)
+	}
+	c.WaitForDeleted(configMapGVK, "default", "app")
+	if _, err := c.Client.CoreV1().Secrets("default").Get(ctx, "app", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("secret should be deleted by the finalizer, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDualStackCIDRs(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Network.PodCIDRs = []string{"fd00:42::/64", "10.42.0.0/24"}
+		cfg.Network.ServiceCIDRs = []string{"10.43.0.0/16", "fd00:43::/112"}
+	}))
+
+	node, err := c.Client.CoreV1().Nodes().Get(context.Background(), DefaultNodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	// 保持配置中的顺序，第一个网段的地址族为主地址族
+	if node.Spec.PodCIDR != "fd00:42::/64" || !slices.Equal(node.Spec.PodCIDRs, []string{"fd00:42::/64", "10.42.0.0/24"}) {
+		t.Fatalf("unexpected pod cidrs: %q %v", node.Spec.PodCIDR, node.Spec.PodCIDRs)
+	}
+
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"dual-stack", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"ds"},"spec":{"clusterIP":"10.43.0.10","clusterIPs":["10.43.0.10","fd00:43::a"],"ipFamilies":["IPv4","IPv6"],"ipFamilyPolicy":"RequireDualStack","ports":[{"port":80}]}}`, http.StatusCreated},
+		{"ipv6-only", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"v6"},"spec":{"clusterIP":"fd00:43::b","ipFamilies":["IPv6"],"ipFamilyPolicy":"SingleStack","ports":[{"port":80}]}}`, http.StatusCreated},
+		{"outside-cidr", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"out"},"spec":{"clusterIP":"10.96.0.10","ports":[{"port":80}]}}`, http.StatusBadRequest},
+		{"same-family", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"twice"},"spec":{"clusterIP":"10.43.0.11","clusterIPs":["10.43.0.11","10.43.0.12"],"ports":[{"port":80}]}}`, http.StatusBadRequest},
+		{"family-mismatch", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"mismatch"},"spec":{"clusterIP":"10.43.0.13","clusterIPs":["10.43.0.13"],"ipFamilies":["IPv6"],"ports":[{"port":80}]}}`, http.StatusBadRequest},
+		{"single-stack-two-ips", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"single"},"spec":{"clusterIP":"10.43.0.14","clusterIPs":["10.43.0.14","fd00:43::e"],"ipFamilyPolicy":"SingleStack","ports":[{"port":80}]}}`, http.StatusBadRequest},
+	} {
+		code, body := c.DoWithContentType(http.MethodPost, servicesPath, "application/json", []byte(tc.body))
+		if code != tc.want {
+			t.Errorf("%s: HTTP %d, want %d: %s", tc.name, code, tc.want, body)
+		}
+	}
+}
+
+func TestRequireDualStackWithSingleStackCIDRs(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Network.ServiceCIDRs = []string{"fd00:43::/112"}
+	}))
+	body := `{"apiVersion":"v1","kind":"Service","metadata":{"name":"ds"},"spec":{"ipFamilyPolicy":"RequireDualStack","ports":[{"port":80}]}}`
+	if code, resp := c.DoWithContentType(http.MethodPost, servicesPath, "application/json", []byte(body)); code != http.StatusBadRequest {
+		t.Fatalf("RequireDualStack without an IPv4 service cidr: HTTP %d: %s", code, resp)
+	}
+	body = `{"apiVersion":"v1","kind":"Service","metadata":{"name":"v4"},"spec":{"ipFamilies":["IPv4"],"ports":[{"port":80}]}}`
+	if code, resp := c.DoWithContentType(http.MethodPost, servicesPath, "application/json", []byte(body)); code != http.StatusBadRequest {
+		t.Fatalf("IPv4 family without an IPv4 service cidr: HTTP %d: %s", code, resp)
+	}
+}
@@ -0,0 +1,212 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const webDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.25
+`
+
+func TestDeploymentRollsOutPods(t *testing.T) {
+	c := Start(t)
+	c.Apply(webDeployment)
+
+	c.WaitForDeploymentReady("default", "web")
+	pods := c.Pods("default", "app=web")
+	if len(pods) != 2 {
+		t.Fatalf("expected 2 pods, got %d", len(pods))
+	}
+	for _, pod := range pods {
+		if !PodReady(pod) {
+			t.Errorf("pod %s is not ready: %s", pod.Name, pod.Status.Phase)
+		}
+		if pod.Spec.NodeName != DefaultNodeName {
+			t.Errorf("pod %s scheduled to %q, want %q", pod.Name, pod.Spec.NodeName, DefaultNodeName)
+		}
+		if pod.Status.PodIP == "" {
+			t.Errorf("pod %s has no podIP", pod.Name)
+		}
+		if !c.Runtime.Running(pod.Namespace, pod.Name) {
+			t.Errorf("pod %s is not running in the runtime", pod.Name)
+		}
+	}
+}
+
+func TestDeletedPodIsReplaced(t *testing.T) {
+	c := Start(t)
+	c.Apply(webDeployment)
+	c.WaitForDeploymentReady("default", "web")
+
+	victim := c.Pods("default", "app=web")[0]
+	c.Delete(PodGVK, "default", victim.Name)
+	c.WaitForDeleted(PodGVK, "default", victim.Name)
+	c.WaitFor("容器停止", func() (bool, error) {
+		return !c.Runtime.Running("default", victim.Name), nil
+	})
+
+	c.WaitFor("Deployment 补齐副本", func() (bool, error) {
+		ready := 0
+		for _, pod := range c.Pods("default", "app=web") {
+			if pod.Name == victim.Name {
+				return false, nil
+			}
+			if PodReady(pod) {
+				ready++
+			}
+		}
+		return ready == 2, nil
+	})
+}
+
+func TestRecreatedPodGetsFreshContainer(t *testing.T) {
+	c := Start(t)
+	const manifest = `apiVersion: v1
+kind: Pod
+metadata:
+  name: single
+spec:
+  containers:
+  - name: app
+    image: busybox:1.36
+`
+	c.Apply(manifest)
+	first := c.WaitForPodReady("default", "single")
+
+	c.Delete(PodGVK, "default", "single")
+	c.WaitForDeleted(PodGVK, "default", "single")
+	c.Apply(manifest)
+	second := c.WaitForPod("default", "single", "以新的 UID 就绪", func(pod *corev1.Pod) bool {
+		return pod.UID != first.UID && PodReady(pod)
+	})
+
+	if got := c.Runtime.RunningUID("default", "single"); got != second.UID {
+		t.Fatalf("runtime is running UID %q, want the new instance %q", got, second.UID)
+	}
+}
+
+func TestScaleThroughClient(t *testing.T) {
+	c := Start(t)
+	c.Apply(webDeployment)
+	c.WaitForDeploymentReady("default", "web")
+
+	ctx := context.Background()
+	d, err := c.Client.AppsV1().Deployments("default").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get deployment: %v", err)
+	}
+	replicas := int32(3)
+	d.Spec.Replicas = &replicas
+	if _, err := c.Client.AppsV1().Deployments("default").Update(ctx, d, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update deployment: %v", err)
+	}
+
+	d = c.WaitForDeploymentReady("default", "web")
+	if d.Status.ReadyReplicas != 3 {
+		t.Fatalf("expected 3 ready replicas, got %d", d.Status.ReadyReplicas)
+	}
+	if got := len(c.Runtime.RunningPods()); got != 3 {
+		t.Fatalf("expected 3 running pods in the runtime, got %d", got)
+	}
+}
+
+func TestFailedImageKeepsPodFromRunning(t *testing.T) {
+	c := Start(t)
+	c.Runtime.FailImage("broken:latest", errors.New("pull access denied"))
+	c.Apply(`apiVersion: v1
+kind: Pod
+metadata:
+  name: broken
+spec:
+  containers:
+  - name: app
+    image: broken:latest
+`)
+
+	pod := c.WaitForPod("default", "broken", "已调度", func(pod *corev1.Pod) bool {
+		return pod.Spec.NodeName != ""
+	})
+	if c.Runtime.Running("default", "broken") {
+		t.Fatalf("pod with failing image should not be running")
+	}
+	if PodReady(pod) {
+		t.Fatalf("pod with failing image should not be ready")
+	}
+}
+
+func TestPodAddressesFromNodeAndRuntime(t *testing.T) {
+	c := Start(t)
+	node, _ := c.Get(NodeGVK, "", DefaultNodeName).(*corev1.Node)
+	var internalIPs []string
+	if node != nil {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP {
+				internalIPs = append(internalIPs, addr.Address)
+			}
+		}
+	}
+	if len(internalIPs) == 0 {
+		t.Skip("node has no InternalIP on this host")
+	}
+
+	c.Apply(`apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-net
+spec:
+  containers:
+  - name: app
+    image: busybox:1.36
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: host-net
+spec:
+  hostNetwork: true
+  containers:
+  - name: app
+    image: busybox:1.36
+`)
+	isInternalIP := func(ip string) bool {
+		for _, internal := range internalIPs {
+			if ip == internal {
+				return true
+			}
+		}
+		return false
+	}
+
+	pod := c.WaitForPodReady("default", "pod-net")
+	if !isInternalIP(pod.Status.HostIP) || len(pod.Status.HostIPs) == 0 || pod.Status.HostIPs[0].IP != pod.Status.HostIP {
+		t.Errorf("pod-net hostIP = %q, hostIPs = %v, want one of %v", pod.Status.HostIP, pod.Status.HostIPs, internalIPs)
+	}
+	if pod.Status.PodIP == "" || pod.Status.PodIP == pod.Status.HostIP {
+		t.Errorf("pod-net podIP = %q, want the runtime's IP", pod.Status.PodIP)
+	}
+
+	hostPod := c.WaitForPodReady("default", "host-net")
+	if !isInternalIP(hostPod.Status.HostIP) || hostPod.Status.PodIP != hostPod.Status.HostIP {
+		t.Errorf("host-net podIP = %q, hostIP = %q, want both to be the node's InternalIP", hostPod.Status.PodIP, hostPod.Status.HostIP)
+	}
+}
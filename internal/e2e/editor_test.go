@@ -0,0 +1,158 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/api"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"go.uber.org/fx"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// editYAML 调用 dashboard 的 YAML 编辑器接口，返回状态码与响应
+func editYAML(t *testing.T, c *Cluster, method, path, body string) (int, api.YAMLEditResponse) {
+	t.Helper()
+	code, data := c.DoWithContentType(method, path, "application/yaml", []byte(body))
+	var resp api.YAMLEditResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("%s %s: decode response %q: %v", method, path, data, err)
+	}
+	return code, resp
+}
+
+func TestEditorUsesAPIServerAdmission(t *testing.T) {
+	c := Start(t, WithFxOptions(
+		api.DashboardModule,
+		fx.Invoke(func(r api.DashboardRoutes) { r.SetUp() }),
+	))
+
+	if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cfg"}, Data: map[string]string{"k": "v"}}); code != http.StatusCreated {
+		t.Fatalf("create configmap: HTTP %d: %s", code, body)
+	}
+
+	// annotations 超过 256KiB：校验与提交都返回与 apiserver 相同的错误，对象不被修改
+	oversized := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+  annotations:
+    note: "` + strings.Repeat("a", apiserver.MaxAnnotationsSize) + `"
+data:
+  k: changed
+`
+	code, resp := editYAML(t, c, http.MethodPost, "/dashboard/api/yaml/configmaps/cfg/validate", oversized)
+	if code != http.StatusOK || resp.Valid || len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0], "metadata.annotations") {
+		t.Fatalf("validate oversized annotations: HTTP %d: %+v", code, resp.Errors)
+	}
+	code, resp = editYAML(t, c, http.MethodPut, "/dashboard/api/yaml/configmaps/cfg", oversized)
+	if code != http.StatusUnprocessableEntity || resp.Valid {
+		t.Fatalf("submit oversized annotations: HTTP %d: %+v", code, resp.Errors)
+	}
+	if cm := c.Get(configMapGVK, "default", "cfg").(*corev1.ConfigMap); cm.Data["k"] != "v" {
+		t.Fatalf("configmap updated despite failed admission: %v", cm.Data)
+	}
+
+	// Deployment 的 selector 不可修改
+	c.Apply(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.25
+`)
+	code, resp = editYAML(t, c, http.MethodPut, "/dashboard/api/yaml/deployments/web", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+      tier: frontend
+  template:
+    metadata:
+      labels:
+        app: web
+        tier: frontend
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.25
+`)
+	if code != http.StatusUnprocessableEntity || len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0], "spec.selector") {
+		t.Fatalf("submit selector change: HTTP %d: %+v", code, resp.Errors)
+	}
+
+	// 合法的修改照常提交
+	code, resp = editYAML(t, c, http.MethodPut, "/dashboard/api/yaml/configmaps/cfg", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+data:
+  k: changed
+`)
+	if code != http.StatusOK || !resp.Changed {
+		t.Fatalf("submit valid edit: HTTP %d: %+v", code, resp)
+	}
+}
+
+func TestEditorResourceVersionPrecondition(t *testing.T) {
+	c := Start(t, WithFxOptions(
+		api.DashboardModule,
+		fx.Invoke(func(r api.DashboardRoutes) { r.SetUp() }),
+	))
+
+	if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cfg"}, Data: map[string]string{"k": "v"}}); code != http.StatusCreated {
+		t.Fatalf("create configmap: HTTP %d: %s", code, body)
+	}
+	read := c.Get(configMapGVK, "default", "cfg").(*corev1.ConfigMap).ResourceVersion
+	edit := func(value string) string {
+		return `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+  resourceVersion: "` + read + `"
+data:
+  k: ` + value + `
+`
+	}
+
+	// 两个编辑者基于同一版本提交：第一个成功，第二个返回 409，不覆盖第一个的修改
+	code, resp := editYAML(t, c, http.MethodPut, "/dashboard/api/yaml/configmaps/cfg", edit("first"))
+	if code != http.StatusOK || !resp.Changed {
+		t.Fatalf("first editor: HTTP %d: %+v", code, resp)
+	}
+	code, data := c.DoWithContentType(http.MethodPut, "/dashboard/api/yaml/configmaps/cfg", "application/yaml", []byte(edit("second")))
+	if code != http.StatusConflict || !strings.Contains(string(data), read) {
+		t.Fatalf("second editor: HTTP %d: %s", code, data)
+	}
+	if cm := c.Get(configMapGVK, "default", "cfg").(*corev1.ConfigMap); cm.Data["k"] != "first" {
+		t.Fatalf("stale edit overwrote the object: %v", cm.Data)
+	}
+
+	// apiserver 的 PUT 同样以 resourceVersion 为前置条件
+	code, data = c.DoWithContentType(http.MethodPut, configMapsPath+"/cfg", "application/yaml", []byte(edit("third")))
+	if code != http.StatusConflict {
+		t.Fatalf("PUT with a stale resourceVersion: HTTP %d: %s", code, data)
+	}
+	read = c.Get(configMapGVK, "default", "cfg").(*corev1.ConfigMap).ResourceVersion
+	if code, data = c.DoWithContentType(http.MethodPut, configMapsPath+"/cfg", "application/yaml", []byte(edit("third"))); code != http.StatusOK {
+		t.Fatalf("PUT with the latest resourceVersion: HTTP %d: %s", code, data)
+	}
+}
@@ -0,0 +1,104 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const webDisruptionBudget = `
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+  namespace: default
+spec:
+  minAvailable: 2
+  selector:
+    matchLabels:
+      app: web
+`
+
+// evict 通过 eviction 子资源驱逐 Pod，返回状态码与响应
+func (c *Cluster) evict(namespace, name string) (int, []byte) {
+	body := fmt.Sprintf(`{"apiVersion":"policy/v1","kind":"Eviction","metadata":{"name":%q,"namespace":%q}}`, name, namespace)
+	return c.Do(http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/eviction", namespace, name), []byte(body))
+}
+
+func TestEvictionRespectsDisruptionBudget(t *testing.T) {
+	c := Start(t)
+	c.Apply(webDeployment)
+	c.Apply(webDisruptionBudget)
+	c.WaitForDeploymentReady("default", "web")
+
+	pod := c.Pods("default", "app=web")[0]
+	code, resp := c.evict("default", pod.Name)
+	if code != http.StatusTooManyRequests || !strings.Contains(string(resp), "DisruptionBudget") {
+		t.Fatalf("eviction with minAvailable=2 = HTTP %d: %s, want 429", code, resp)
+	}
+	if c.Pod("default", pod.Name) == nil {
+		t.Fatal("pod deleted although eviction was rejected")
+	}
+
+	c.Apply(strings.Replace(webDisruptionBudget, "minAvailable: 2", "minAvailable: 1", 1))
+	if code, resp := c.evict("default", pod.Name); code != http.StatusCreated {
+		t.Fatalf("eviction with minAvailable=1 = HTTP %d: %s, want 201", code, resp)
+	}
+	c.WaitFor("被驱逐的 Pod 删除", func() (bool, error) {
+		return c.Pod("default", pod.Name) == nil, nil
+	})
+	if code, _ := c.evict("default", "missing"); code != http.StatusNotFound {
+		t.Fatalf("eviction of missing pod = HTTP %d, want 404", code)
+	}
+	c.WaitForDeploymentReady("default", "web")
+}
+
+func TestCordonedNodeIsNotScheduled(t *testing.T) {
+	c := Start(t)
+	c.Apply(webDeployment)
+	c.WaitForDeploymentReady("default", "web")
+
+	ctx := context.Background()
+	nodes := c.Client.CoreV1().Nodes()
+	if _, err := nodes.Patch(ctx, DefaultNodeName, types.MergePatchType, []byte(`{"spec":{"unschedulable":true}}`), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("cordon: %v", err)
+	}
+	deployments := c.Client.AppsV1().Deployments("default")
+	if _, err := deployments.Patch(ctx, "web", types.MergePatchType, []byte(`{"spec":{"replicas":3}}`), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("scale: %v", err)
+	}
+	c.WaitFor("新副本创建", func() (bool, error) {
+		return len(c.Pods("default", "app=web")) == 3, nil
+	})
+	// 等待几轮调度与节点心跳：新副本不调度到封锁的节点，心跳也不解除封锁
+	time.Sleep(3 * time.Second)
+	scheduled := 0
+	for _, pod := range c.Pods("default", "app=web") {
+		if pod.Spec.NodeName != "" {
+			scheduled++
+		}
+	}
+	if scheduled != 2 {
+		t.Fatalf("%d pods scheduled while the only node is cordoned, want 2", scheduled)
+	}
+	node, err := nodes.Get(ctx, DefaultNodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	if !node.Spec.Unschedulable {
+		t.Fatal("node heartbeat cleared spec.unschedulable")
+	}
+
+	if _, err := nodes.Patch(ctx, DefaultNodeName, types.MergePatchType, []byte(`{"spec":{"unschedulable":false}}`), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("uncordon: %v", err)
+	}
+	if d := c.WaitForDeploymentReady("default", "web"); d.Status.ReadyReplicas != 3 {
+		t.Fatalf("ready replicas = %d after uncordon, want 3", d.Status.ReadyReplicas)
+	}
+}
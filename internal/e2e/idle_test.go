@@ -0,0 +1,46 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/idle"
+)
+
+// controllerRunning 返回控制器是否在运行
+func controllerRunning(c *Cluster, name string) bool {
+	for _, st := range c.Manager.ControllerStatuses() {
+		if st.Name == name {
+			return st.Running
+		}
+	}
+	return false
+}
+
+func TestIdleModePausesControllersAndWakesOnRequest(t *testing.T) {
+	c := Start(t,
+		WithConfig(func(cfg *config.Config) {
+			cfg.Idle.After = "1s"
+		}),
+		WithFxOptions(idle.Module),
+	)
+
+	c.WaitFor("ContainerGC 启动", func() (bool, error) {
+		return controllerRunning(c, "ContainerGC"), nil
+	})
+	c.WaitFor("空闲后暂停 ContainerGC", func() (bool, error) {
+		return !controllerRunning(c, "ContainerGC"), nil
+	})
+	if !controllerRunning(c, "SchedulerController") {
+		t.Fatalf("scheduler should keep running in idle mode")
+	}
+
+	// 下一个请求在恢复后才被处理
+	if code, body := c.Do(http.MethodGet, "/api/v1/pods", nil); code != http.StatusOK {
+		t.Fatalf("list pods: %d %s", code, body)
+	}
+	if !controllerRunning(c, "ContainerGC") {
+		t.Fatalf("ContainerGC not resumed after an API request")
+	}
+}
@@ -0,0 +1,49 @@
+package e2e
+
+import (
+	"bufio"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+func TestWatchKeepalive(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.APIServer.WatchKeepalive = "100ms"
+	}))
+
+	resp, err := http.Get(c.Server + configMapsPath + "?watch=true&timeoutSeconds=3")
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("watch: HTTP %d", resp.StatusCode)
+	}
+
+	// 没有事件时也会收到心跳注释
+	lines := make(chan string, 16)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("watch stream closed before a keepalive")
+			}
+			if line == ": keepalive" {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("no keepalive comment within 2s")
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+const loggerPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: logger
+spec:
+  containers:
+  - name: app
+    image: busybox
+`
+
+// podLogs 通过日志 API 读取 Pod 日志
+func (c *Cluster) podLogs(namespace, name, query string) string {
+	c.t.Helper()
+	code, body := c.Do(http.MethodGet, fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log?%s", namespace, name, query), nil)
+	if code != http.StatusOK {
+		c.t.Fatalf("GET logs: HTTP %d: %s", code, body)
+	}
+	return string(body)
+}
+
+func TestContainerLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Controller.ContainerLogs = config.ContainerLogsConfig{Dir: dir, MaxSize: "1Ki", MaxFiles: 3}
+	}))
+
+	var logs strings.Builder
+	for i := range 200 {
+		fmt.Fprintf(&logs, "line-%03d\n", i)
+	}
+	c.Runtime.SetLogs("default", "logger", "app", logs.String())
+	c.Apply(loggerPod)
+	pod := c.WaitForPodReady("default", "logger")
+
+	// 日志写入 <dir>/<namespace>_<pod>_<uid>/<container>.log，按大小轮转，只保留 max_files 个文件
+	podDir := filepath.Join(dir, fmt.Sprintf("default_logger_%s", pod.UID))
+	c.WaitFor("日志写入文件", func() (bool, error) {
+		data, err := os.ReadFile(filepath.Join(podDir, "app.log"))
+		return err == nil && strings.HasSuffix(string(data), " line-199\n"), nil
+	})
+	files, _ := filepath.Glob(filepath.Join(podDir, "app.log*"))
+	if len(files) != 3 {
+		t.Fatalf("log files = %v, want app.log with 2 rotated files", files)
+	}
+
+	// 容器被删除后日志 API 仍从文件读取
+	if err := c.Runtime.StopContainer(context.Background(), pod); err != nil {
+		t.Fatalf("stop container: %v", err)
+	}
+	all := c.podLogs("default", "logger", "")
+	if strings.Contains(all, "line-000\n") || !strings.HasSuffix(all, "line-198\nline-199\n") {
+		t.Fatalf("logs after container removal:\n%s", all)
+	}
+	if got := c.podLogs("default", "logger", "tailLines=2"); got != "line-198\nline-199\n" {
+		t.Fatalf("tailLines=2: %q", got)
+	}
+	withTimestamps := c.podLogs("default", "logger", "tailLines=1&timestamps=true")
+	ts, line, _ := strings.Cut(withTimestamps, " ")
+	if _, err := time.Parse(time.RFC3339Nano, ts); err != nil || line != "line-199\n" {
+		t.Fatalf("timestamps=true: %q", withTimestamps)
+	}
+
+	// Pod 删除后删除日志目录
+	c.Delete(PodGVK, "default", "logger")
+	c.WaitFor("日志目录被删除", func() (bool, error) {
+		_, err := os.Stat(podDir)
+		return os.IsNotExist(err), nil
+	})
+}
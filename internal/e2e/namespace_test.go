@@ -0,0 +1,75 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEmptyNamespaceDefaultsToDefault(t *testing.T) {
+	c := Start(t)
+
+	// URL 与请求体都没有 namespace：写入 default
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "no-namespace"},
+		Data:       map[string]string{"k": "v"},
+	}
+	body, _ := json.Marshal(cm)
+	code, resp := c.DoWithContentType(http.MethodPost, "/api/v1/configmaps", "application/json", body)
+	if code != http.StatusCreated {
+		t.Fatalf("create: HTTP %d: %s", code, resp)
+	}
+	code, resp = c.Do(http.MethodGet, configMapsPath+"/no-namespace", nil)
+	if code != http.StatusOK {
+		t.Fatalf("get from default: HTTP %d: %s", code, resp)
+	}
+	var got corev1.ConfigMap
+	if err := json.Unmarshal(resp, &got); err != nil {
+		t.Fatalf("decode configmap: %v", err)
+	}
+	if got.Namespace != "default" {
+		t.Fatalf("stored namespace = %q, want default", got.Namespace)
+	}
+
+	// 不带 namespace 的 URL 上的 PATCH 作用于 default 中的对象
+	code, resp = c.DoWithContentType(http.MethodPatch, "/api/v1/configmaps/no-namespace",
+		"application/merge-patch+json", []byte(`{"data":{"k":"patched"}}`))
+	if code != http.StatusOK {
+		t.Fatalf("patch without namespace: HTTP %d: %s", code, resp)
+	}
+
+	// URL 与请求体的 namespace 不一致
+	cm.Name = "mismatch"
+	cm.Namespace = "other"
+	body, _ = json.Marshal(cm)
+	code, resp = c.DoWithContentType(http.MethodPost, configMapsPath, "application/json", body)
+	if code != http.StatusBadRequest {
+		t.Fatalf("create with mismatched namespace: HTTP %d: %s", code, resp)
+	}
+
+	// 集群级资源忽略 namespace
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "namespaced-node", Namespace: "default"},
+	}
+	body, _ = json.Marshal(node)
+	code, resp = c.DoWithContentType(http.MethodPost, "/api/v1/nodes", "application/json", body)
+	if code != http.StatusCreated {
+		t.Fatalf("create node: HTTP %d: %s", code, resp)
+	}
+	code, resp = c.Do(http.MethodGet, "/api/v1/nodes/namespaced-node", nil)
+	if code != http.StatusOK {
+		t.Fatalf("get node: HTTP %d: %s", code, resp)
+	}
+	var gotNode corev1.Node
+	if err := json.Unmarshal(resp, &gotNode); err != nil {
+		t.Fatalf("decode node: %v", err)
+	}
+	if gotNode.Namespace != "" {
+		t.Fatalf("node namespace = %q, want empty", gotNode.Namespace)
+	}
+}
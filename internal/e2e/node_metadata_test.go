@@ -0,0 +1,139 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const nodeLabelsPath = "/api/v1/nodes/" + DefaultNodeName + "/labels"
+
+// waitForNode 等待 controller manager 上报本节点
+func waitForNode(c *Cluster) *corev1.Node {
+	var node *corev1.Node
+	c.WaitFor("节点上报", func() (bool, error) {
+		node, _ = c.Get(NodeGVK, "", DefaultNodeName).(*corev1.Node)
+		return node != nil, nil
+	})
+	return node
+}
+
+// patchNodeMetadata 以 manager 的身份合并 labels 或 annotations，返回状态码与更新后的 Node
+func patchNodeMetadata(t *testing.T, c *Cluster, path, manager, body string) (int, *corev1.Node) {
+	t.Helper()
+	code, data := c.DoWithContentType(http.MethodPatch, path+"?fieldManager="+manager, "application/json", []byte(body))
+	if code != http.StatusOK {
+		return code, nil
+	}
+	var node corev1.Node
+	if err := json.Unmarshal(data, &node); err != nil {
+		t.Fatalf("decode node: %v: %s", err, data)
+	}
+	return code, &node
+}
+
+func TestNodeLabelsSurviveHeartbeat(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Controller.NodeHeartbeat = "1s"
+	}))
+	waitForNode(c)
+
+	code, node := patchNodeMetadata(t, c, nodeLabelsPath, "rack-agent", `{"topology.example.com/rack":"r1"}`)
+	if code != http.StatusOK {
+		t.Fatalf("patch labels: HTTP %d", code)
+	}
+	if node.Labels["topology.example.com/rack"] != "r1" || node.Labels["kubernetes.io/hostname"] != DefaultNodeName {
+		t.Fatalf("labels should be merged, got %v", node.Labels)
+	}
+
+	// controller manager 的心跳整体上报 Node，但保留其他写入者的键（e2e 依赖图不启动心跳，这里手动启动）
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	since := time.Now()
+	go c.Manager.StartNodeHeartbeat(ctx)
+	c.WaitFor("心跳上报", func() (bool, error) {
+		n := c.Get(NodeGVK, "", DefaultNodeName).(*corev1.Node)
+		for _, cond := range n.Status.Conditions {
+			if cond.Type == corev1.NodeReady && cond.LastHeartbeatTime.After(since) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if got := c.Get(NodeGVK, "", DefaultNodeName).(*corev1.Node).Labels["topology.example.com/rack"]; got != "r1" {
+		t.Fatalf("label lost after heartbeat, got %q", got)
+	}
+
+	// null 删除键
+	if code, node = patchNodeMetadata(t, c, nodeLabelsPath, "rack-agent", `{"topology.example.com/rack":null}`); code != http.StatusOK {
+		t.Fatalf("delete label: HTTP %d", code)
+	}
+	if _, ok := node.Labels["topology.example.com/rack"]; ok {
+		t.Fatalf("label should be deleted, got %v", node.Labels)
+	}
+}
+
+func TestNodeMetadataOwnership(t *testing.T) {
+	c := Start(t)
+	waitForNode(c)
+	annotationsPath := "/api/v1/nodes/" + DefaultNodeName + "/annotations"
+
+	if code, _ := patchNodeMetadata(t, c, annotationsPath, "rack-agent", `{"k3.network/port":"1"}`); code != http.StatusForbidden {
+		t.Fatalf("foreign writer on k3.network/*: expected 403, got %d", code)
+	}
+	code, node := patchNodeMetadata(t, c, annotationsPath, "k3-network", `{"k3.network/port":"7946"}`)
+	if code != http.StatusOK || node.Annotations["k3.network/port"] != "7946" {
+		t.Fatalf("owner write: HTTP %d, annotations %v", code, node)
+	}
+
+	if code, _ := patchNodeMetadata(t, c, nodeLabelsPath, "rack-agent", `{"bad key":"v"}`); code != http.StatusBadRequest {
+		t.Fatalf("invalid key: expected 400, got %d", code)
+	}
+	if code, _ := patchNodeMetadata(t, c, nodeLabelsPath, "rack-agent", `{"rack":"has spaces"}`); code != http.StatusBadRequest {
+		t.Fatalf("invalid label value: expected 400, got %d", code)
+	}
+	if code, _ := patchNodeMetadata(t, c, nodeLabelsPath, "rack-agent", `["rack"]`); code != http.StatusBadRequest {
+		t.Fatalf("non-object body: expected 400, got %d", code)
+	}
+	if code, _ := patchNodeMetadata(t, c, "/api/v1/nodes/missing/labels", "rack-agent", `{"rack":"r1"}`); code != http.StatusNotFound {
+		t.Fatalf("missing node: expected 404, got %d", code)
+	}
+}
+
+func TestNodeMetadataOwnershipUsesIdentity(t *testing.T) {
+	c := Start(t, WithAuth(
+		config.TokenConfig{Token: "network", User: "k3-network", Role: webprovider.RoleClusterAdmin},
+		config.TokenConfig{Token: "ops", User: "ops", Role: webprovider.RoleClusterAdmin},
+	))
+	waitForNode(c)
+	annotationsPath := "/api/v1/nodes/" + DefaultNodeName + "/annotations"
+	patch := func(token, query, body string) (int, []byte) {
+		t.Helper()
+		return c.do(http.MethodPatch, annotationsPath+query, "application/json", []byte(body), token)
+	}
+
+	// 开启认证后 fieldManager 与 User-Agent 不能冒充所属写入者
+	if code, body := patch("ops", "?fieldManager=k3-network", `{"k3.network/port":"1"}`); code != http.StatusForbidden {
+		t.Fatalf("ops claiming k3-network: HTTP %d: %s", code, body)
+	}
+	code, body := patch("network", "?fieldManager=rack-agent", `{"k3.network/port":"7946"}`)
+	var node corev1.Node
+	if code != http.StatusOK || json.Unmarshal(body, &node) != nil || node.Annotations["k3.network/port"] != "7946" {
+		t.Fatalf("k3-network token: HTTP %d: %s", code, body)
+	}
+	// 记录的写入者同样是认证身份
+	if got := node.ManagedFields; len(got) == 0 || got[len(got)-1].Manager != "k3-network" {
+		t.Errorf("managedFields = %v, want k3-network", got)
+	}
+
+	// 不受保护的键任何 cluster-admin 都可以修改
+	if code, body := patch("ops", "", `{"example.com/owner":"ops"}`); code != http.StatusOK {
+		t.Fatalf("unprotected key: HTTP %d: %s", code, body)
+	}
+}
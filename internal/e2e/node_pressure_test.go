@@ -0,0 +1,94 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const pendingPod = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pending
+  namespace: default
+spec:
+  containers:
+  - name: main
+    image: busybox
+`
+
+func TestNodePressureEvictsBestEffortFirst(t *testing.T) {
+	// disk_available 为 100% 时节点总是处于磁盘压力
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Eviction = config.EvictionConfig{DiskAvailable: "100%", DiskPath: t.TempDir(), Interval: "1s"}
+	}))
+
+	guaranteed := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("64Mi"),
+	}
+	for name, resources := range map[string]corev1.ResourceRequirements{
+		"best-effort": {},
+		"guaranteed":  {Requests: guaranteed, Limits: guaranteed},
+	} {
+		pod := &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{NodeName: DefaultNodeName, Containers: []corev1.Container{
+				{Name: "main", Image: "busybox", Resources: resources},
+			}},
+		}
+		if err := c.Store.Create(PodGVK, pod); err != nil {
+			t.Fatalf("create pod %s: %v", name, err)
+		}
+	}
+
+	// 每个周期只驱逐一个 Pod：BestEffort 先于 Guaranteed
+	c.WaitFor("BestEffort Pod 被驱逐", func() (bool, error) {
+		if c.Pod("default", "guaranteed") == nil {
+			t.Fatal("guaranteed pod evicted before best-effort pod")
+		}
+		return c.Pod("default", "best-effort") == nil, nil
+	})
+	c.WaitForDeleted(PodGVK, "default", "guaranteed")
+
+	events, err := c.Store.List(controller.EventGVK, "default")
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	evicted := 0
+	for _, obj := range events {
+		if event, ok := obj.(*corev1.Event); ok && event.Reason == "Evicted" {
+			evicted++
+		}
+	}
+	if evicted != 2 {
+		t.Fatalf("Evicted events = %d, want 2", evicted)
+	}
+
+	c.WaitFor("节点 DiskPressure 为 True", func() (bool, error) {
+		node, ok := c.Get(NodeGVK, "", DefaultNodeName).(*corev1.Node)
+		if !ok {
+			return false, nil
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeDiskPressure {
+				return condition.Status == corev1.ConditionTrue && condition.Reason == "KubeletHasDiskPressure", nil
+			}
+		}
+		return false, nil
+	})
+
+	// 处于压力的节点不再接收新的 Pod
+	c.Apply(pendingPod)
+	time.Sleep(2 * time.Second)
+	if pod := c.Pod("default", "pending"); pod == nil || pod.Spec.NodeName != "" {
+		t.Fatalf("pod scheduled to node under disk pressure: %+v", pod)
+	}
+}
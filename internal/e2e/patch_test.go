@@ -0,0 +1,152 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const patchPodPath = "/api/v1/namespaces/default/pods/patched"
+
+// createPatchPod 以 YAML 请求体创建 Pod，返回创建结果
+func createPatchPod(t *testing.T, c *Cluster) *corev1.Pod {
+	t.Helper()
+	code, body := c.Do(http.MethodPost, "/api/v1/namespaces/default/pods", []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: patched
+  labels:
+    app: web
+    tier: fe
+spec:
+  containers:
+  - name: app
+    image: busybox:1.36
+    args: ["sleep", "3600"]
+`))
+	if code != http.StatusCreated {
+		t.Fatalf("create from YAML: HTTP %d: %s", code, body)
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(body, &pod); err != nil {
+		t.Fatalf("decode created pod: %v", err)
+	}
+	return &pod
+}
+
+func patchPod(t *testing.T, c *Cluster, contentType, patch string) *corev1.Pod {
+	t.Helper()
+	code, body := c.DoWithContentType(http.MethodPatch, patchPodPath, contentType, []byte(patch))
+	if code != http.StatusOK {
+		t.Fatalf("PATCH %s: HTTP %d: %s", contentType, code, body)
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(body, &pod); err != nil {
+		t.Fatalf("decode patched pod: %v", err)
+	}
+	return &pod
+}
+
+func TestCreateFromYAMLBody(t *testing.T) {
+	c := Start(t)
+	pod := createPatchPod(t, c)
+	if pod.Labels["app"] != "web" || len(pod.Spec.Containers) != 1 || pod.Spec.Containers[0].Image != "busybox:1.36" {
+		t.Fatalf("unexpected pod from YAML body: %+v", pod)
+	}
+	if stored := c.Pod("default", "patched"); stored == nil || stored.UID != pod.UID {
+		t.Fatalf("pod not stored as returned")
+	}
+}
+
+func TestMergePatchRoundTrip(t *testing.T) {
+	c := Start(t)
+	createPatchPod(t, c)
+
+	pod := patchPod(t, c, "application/merge-patch+json",
+		`{"metadata":{"labels":{"tier":null,"track":"canary"},"annotations":{"note":"hi"}}}`)
+	if _, ok := pod.Labels["tier"]; ok || pod.Labels["app"] != "web" || pod.Labels["track"] != "canary" {
+		t.Fatalf("unexpected labels after merge patch: %v", pod.Labels)
+	}
+	if pod.Annotations["note"] != "hi" {
+		t.Fatalf("annotation not added: %v", pod.Annotations)
+	}
+	// 数组整体替换
+	if args := pod.Spec.Containers[0].Args; len(args) != 2 {
+		t.Fatalf("merge patch touched args: %v", args)
+	}
+	stored := c.Pod("default", "patched")
+	if stored.Labels["track"] != "canary" {
+		t.Fatalf("merge patch not persisted: %v", stored.Labels)
+	}
+}
+
+func TestJSONPatchRoundTrip(t *testing.T) {
+	c := Start(t)
+	createPatchPod(t, c)
+
+	pod := patchPod(t, c, "application/json-patch+json", `[
+  {"op": "test", "path": "/metadata/labels/app", "value": "web"},
+  {"op": "replace", "path": "/spec/containers/0/args/1", "value": "60"},
+  {"op": "add", "path": "/spec/containers/0/args/-", "value": "--verbose"},
+  {"op": "add", "path": "/spec/containers/0/args/0", "value": "exec"},
+  {"op": "move", "from": "/metadata/labels/tier", "path": "/metadata/labels/layer"},
+  {"op": "copy", "from": "/metadata/labels/app", "path": "/metadata/labels/origin"}
+]`)
+	if got := pod.Spec.Containers[0].Args; len(got) != 4 || got[0] != "exec" || got[1] != "sleep" || got[2] != "60" || got[3] != "--verbose" {
+		t.Fatalf("unexpected args after JSON patch: %v", got)
+	}
+	if _, ok := pod.Labels["tier"]; ok || pod.Labels["layer"] != "fe" || pod.Labels["origin"] != "web" {
+		t.Fatalf("unexpected labels after JSON patch: %v", pod.Labels)
+	}
+	if stored := c.Pod("default", "patched"); len(stored.Spec.Containers[0].Args) != 4 {
+		t.Fatalf("JSON patch not persisted: %v", stored.Spec.Containers[0].Args)
+	}
+}
+
+func TestFailedJSONPatchLeavesObjectUnchanged(t *testing.T) {
+	c := Start(t)
+	createPatchPod(t, c)
+
+	// 第一个操作可以执行，第二个 test 不满足：整个 patch 不生效
+	code, body := c.DoWithContentType(http.MethodPatch, patchPodPath, "application/json-patch+json", []byte(`[
+  {"op": "add", "path": "/metadata/labels/release", "value": "v2"},
+  {"op": "test", "path": "/metadata/labels/app", "value": "api"}
+]`))
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for failed test op, got %d: %s", code, body)
+	}
+	if _, ok := c.Pod("default", "patched").Labels["release"]; ok {
+		t.Fatalf("failed JSON patch was partially applied")
+	}
+
+	code, body = c.DoWithContentType(http.MethodPatch, patchPodPath, "application/json-patch+json", []byte(`{"op": "remove"}`))
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for non-array JSON patch, got %d: %s", code, body)
+	}
+}
+
+func TestUnsupportedContentType(t *testing.T) {
+	c := Start(t)
+	createPatchPod(t, c)
+
+	if code, body := c.DoWithContentType(http.MethodPatch, patchPodPath, "application/apply-patch+yaml", []byte("metadata: {}")); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 for unsupported patch type, got %d: %s", code, body)
+	}
+	if code, body := c.DoWithContentType(http.MethodPost, "/api/v1/namespaces/default/pods", "application/x-www-form-urlencoded", []byte("name=x")); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 for form body, got %d: %s", code, body)
+	}
+}
+
+func TestStrategicMergePatchMergesListsByKey(t *testing.T) {
+	c := Start(t)
+	createPatchPod(t, c)
+
+	// containers 按 name 合并：只修改 app 的镜像，不替换整个列表
+	pod := patchPod(t, c, "application/strategic-merge-patch+json",
+		`{"spec":{"containers":[{"name":"app","image":"busybox:1.37"}]}}`)
+	if len(pod.Spec.Containers) != 1 || pod.Spec.Containers[0].Image != "busybox:1.37" || len(pod.Spec.Containers[0].Args) != 2 {
+		t.Fatalf("unexpected containers after strategic merge patch: %+v", pod.Spec.Containers)
+	}
+}
@@ -0,0 +1,66 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReservedResourcesAndPodOverhead(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Resources.SystemReserved = map[string]string{"memory": "128Mi"}
+		cfg.Resources.KubeReserved = map[string]string{"cpu": "500m", "memory": "128Mi"}
+		cfg.Resources.PodOverhead = map[string]string{"cpu": "10m", "memory": "32Mi"}
+	}))
+
+	node, err := c.Client.CoreV1().Nodes().Get(context.Background(), DefaultNodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	for name, reserved := range map[corev1.ResourceName]string{corev1.ResourceCPU: "500m", corev1.ResourceMemory: "256Mi"} {
+		want := node.Status.Capacity[name]
+		want.Sub(resource.MustParse(reserved))
+		if got := node.Status.Allocatable[name]; got.Cmp(want) != 0 {
+			t.Fatalf("allocatable %s = %s, want %s", name, got.String(), want.String())
+		}
+	}
+
+	c.Apply(`apiVersion: v1
+kind: Pod
+metadata:
+  name: small
+spec:
+  containers:
+  - name: app
+    image: busybox:1.36
+`)
+	pod := c.WaitForPodReady("default", "small")
+	if cpu := pod.Spec.Overhead[corev1.ResourceCPU]; cpu.Cmp(resource.MustParse("10m")) != 0 {
+		t.Fatalf("unexpected pod overhead: %v", pod.Spec.Overhead)
+	}
+
+	// 放得下容量、放不下 allocatable 的 Pod 不调度
+	capacity := node.Status.Capacity[corev1.ResourceCPU]
+	c.Apply(fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: big
+spec:
+  containers:
+  - name: app
+    image: busybox:1.36
+    resources:
+      requests:
+        cpu: "%s"
+`, capacity.String()))
+	time.Sleep(2 * time.Second)
+	if big := c.Pod("default", "big"); big == nil || big.Spec.NodeName != "" {
+		t.Fatalf("pod requesting the whole node capacity should stay pending: %+v", big)
+	}
+}
@@ -0,0 +1,106 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var configMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+// listConfigMaps 按 query LIST default 下的 ConfigMap，返回状态码、列表的 resourceVersion 与对象名
+func listConfigMaps(t *testing.T, c *Cluster, query string) (int, string, []string) {
+	t.Helper()
+	code, body := c.Do(http.MethodGet, configMapsPath+query, nil)
+	if code != http.StatusOK {
+		return code, "", nil
+	}
+	var list struct {
+		Metadata metav1.ListMeta `json:"metadata"`
+		Items    []struct {
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("decode list: %v\n%s", err, body)
+	}
+	var names []string
+	for _, item := range list.Items {
+		names = append(names, item.Metadata.Name)
+	}
+	return code, list.Metadata.ResourceVersion, names
+}
+
+func TestListResourceVersionMatch(t *testing.T) {
+	c := Start(t)
+
+	if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a"}}); code != http.StatusCreated {
+		t.Fatalf("create a: HTTP %d: %s", code, body)
+	}
+	// 不带 resourceVersion 时直接读 Store，列表带 resourceVersion
+	_, listed, names := listConfigMaps(t, c, "")
+	if listed == "" || len(names) != 1 {
+		t.Fatalf("list: resourceVersion=%q names=%v", listed, names)
+	}
+
+	// resourceVersion=0 读取缓存的任意版本
+	if code, rv, names := listConfigMaps(t, c, "?resourceVersion=0"); code != http.StatusOK || rv == "" || len(names) != 1 {
+		t.Fatalf("resourceVersion=0: HTTP %d rv=%q names=%v", code, rv, names)
+	}
+
+	// NotOlderThan：缓存追上新写入后返回
+	if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "b"}}); code != http.StatusCreated {
+		t.Fatalf("create b: HTTP %d: %s", code, body)
+	}
+	b := c.Get(configMapGVK, "default", "b").(metav1.Object)
+	code, current, names := listConfigMaps(t, c, "?resourceVersionMatch=NotOlderThan&resourceVersion="+b.GetResourceVersion())
+	if code != http.StatusOK || len(names) != 2 || newerThan(b.GetResourceVersion(), current) {
+		t.Fatalf("NotOlderThan %s: HTTP %d rv=%q names=%v", b.GetResourceVersion(), code, current, names)
+	}
+	if code, body := c.Do(http.MethodGet, configMapsPath+"/b?resourceVersion="+b.GetResourceVersion(), nil); code != http.StatusOK {
+		t.Fatalf("GET b with resourceVersion: HTTP %d: %s", code, body)
+	}
+
+	// Exact：缓存版本恰好相同时返回，之后有新的写入则 410
+	if code, rv, _ := listConfigMaps(t, c, "?resourceVersionMatch=Exact&resourceVersion="+current); code != http.StatusOK || rv != current {
+		t.Fatalf("Exact %s: HTTP %d rv=%q", current, code, rv)
+	}
+	if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "c"}}); code != http.StatusCreated {
+		t.Fatalf("create c: HTTP %d: %s", code, body)
+	}
+	cm := c.Get(configMapGVK, "default", "c").(metav1.Object)
+	listConfigMaps(t, c, "?resourceVersion="+cm.GetResourceVersion())
+	if code, _, _ := listConfigMaps(t, c, "?resourceVersionMatch=Exact&resourceVersion="+current); code != http.StatusGone {
+		t.Fatalf("Exact with an overwritten resourceVersion: HTTP %d, want 410", code)
+	}
+
+	// 缓存在等待时间内追不上时返回 504
+	future, _ := strconv.ParseUint(cm.GetResourceVersion(), 10, 64)
+	if code, _, _ := listConfigMaps(t, c, "?resourceVersion="+strconv.FormatUint(future*2, 10)); code != http.StatusGatewayTimeout {
+		t.Fatalf("too large resourceVersion: HTTP %d, want 504", code)
+	}
+
+	for _, path := range []string{
+		configMapsPath + "?resourceVersionMatch=NotOlderThan",
+		configMapsPath + "?resourceVersionMatch=Exact&resourceVersion=0",
+		configMapsPath + "?resourceVersionMatch=Latest&resourceVersion=1",
+		configMapsPath + "?resourceVersion=abc",
+		configMapsPath + "/a?resourceVersionMatch=NotOlderThan&resourceVersion=0",
+	} {
+		if code, body := c.Do(http.MethodGet, path, nil); code != http.StatusBadRequest {
+			t.Errorf("GET %s: HTTP %d, want 400: %s", path, code, body)
+		}
+	}
+}
+
+// newerThan 判断数字 resourceVersion a 是否比 b 新
+func newerThan(a, b string) bool {
+	x, _ := strconv.ParseUint(a, 10, 64)
+	y, _ := strconv.ParseUint(b, 10, 64)
+	return x > y
+}
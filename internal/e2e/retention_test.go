@@ -0,0 +1,115 @@
+package e2e
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	podGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	jobGVK = schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+)
+
+// exists 返回对象是否还在 Store 中
+func exists(c *Cluster, gvk schema.GroupVersionKind, namespace, name string) (bool, error) {
+	_, err := c.Store.Get(gvk, namespace, name)
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func TestRetentionPrunesExpiredObjects(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Retention = config.RetentionConfig{Events: "1h", SucceededPods: "1h", Interval: "100ms"}
+	}))
+
+	old := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	recent := metav1.Now()
+	for name, last := range map[string]metav1.Time{"old": old, "recent": recent} {
+		event := &corev1.Event{
+			TypeMeta:      metav1.TypeMeta{APIVersion: "v1", Kind: "Event"},
+			ObjectMeta:    metav1.ObjectMeta{Name: name, Namespace: "default"},
+			LastTimestamp: last,
+		}
+		if err := c.Store.Create(controller.EventGVK, event); err != nil {
+			t.Fatalf("create event %s: %v", name, err)
+		}
+	}
+
+	finishedPod := func(name string, finishedAt metav1.Time, owner *metav1.OwnerReference) *corev1.Pod {
+		pod := &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: DefaultNodeName, RestartPolicy: corev1.RestartPolicyNever, Containers: []corev1.Container{{Name: "main", Image: "busybox"}}},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "main", State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{FinishedAt: finishedAt},
+				}}},
+			},
+		}
+		if owner != nil {
+			pod.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		return pod
+	}
+	for _, pod := range []*corev1.Pod{finishedPod("done-old", old, nil), finishedPod("done-recent", recent, nil)} {
+		if err := c.Store.Create(podGVK, pod); err != nil {
+			t.Fatalf("create pod %s: %v", pod.Name, err)
+		}
+	}
+
+	// ttlSecondsAfterFinished 为 0 的已完成 Job 连同其 Pod 一起删除；未完成的 Job 保留
+	zero := int32(0)
+	isController := true
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default", UID: types.UID("job-uid")},
+		Spec:       batchv1.JobSpec{TTLSecondsAfterFinished: &zero},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+			Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: recent,
+		}}},
+	}
+	running := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "default"},
+		Spec:       batchv1.JobSpec{TTLSecondsAfterFinished: &zero},
+	}
+	for _, j := range []*batchv1.Job{job, running} {
+		if err := c.Store.Create(jobGVK, j); err != nil {
+			t.Fatalf("create job %s: %v", j.Name, err)
+		}
+	}
+	owner := &metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: job.Name, UID: job.UID, Controller: &isController}
+	if err := c.Store.Create(podGVK, finishedPod("migrate-abc", recent, owner)); err != nil {
+		t.Fatalf("create job pod: %v", err)
+	}
+
+	for _, o := range []struct {
+		gvk  schema.GroupVersionKind
+		name string
+	}{{controller.EventGVK, "old"}, {podGVK, "done-old"}, {jobGVK, "migrate"}, {podGVK, "migrate-abc"}} {
+		c.WaitFor(o.gvk.Kind+" "+o.name+" 被删除", func() (bool, error) {
+			found, err := exists(c, o.gvk, "default", o.name)
+			return !found, err
+		})
+	}
+	for _, o := range []struct {
+		gvk  schema.GroupVersionKind
+		name string
+	}{{controller.EventGVK, "recent"}, {podGVK, "done-recent"}, {jobGVK, "running"}} {
+		if found, err := exists(c, o.gvk, "default", o.name); err != nil || !found {
+			t.Fatalf("%s %s should be kept: found=%v err=%v", o.gvk.Kind, o.name, found, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// progressingReason 返回 Deployment 的 Progressing 条件的原因
+func progressingReason(d *appsv1.Deployment) string {
+	for _, cond := range d.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing {
+			return cond.Reason
+		}
+	}
+	return ""
+}
+
+// podImages 统计 Pod 的镜像，返回镜像 → Pod 数
+func podImages(pods []*corev1.Pod) map[string]int {
+	images := map[string]int{}
+	for _, pod := range pods {
+		images[pod.Spec.Containers[0].Image]++
+	}
+	return images
+}
+
+func TestDeploymentPauseAndResume(t *testing.T) {
+	c := Start(t)
+	c.Apply(webDeployment)
+	c.WaitForDeploymentReady("default", "web")
+
+	ctx := context.Background()
+	deployments := c.Client.AppsV1().Deployments("default")
+	if _, err := deployments.Patch(ctx, "web", types.MergePatchType, []byte(`{"spec":{"paused":true}}`), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	c.WaitFor("Deployment 标记为暂停", func() (bool, error) {
+		return progressingReason(c.Deployment("default", "web")) == "DeploymentPaused", nil
+	})
+
+	// 暂停期间修改模板并扩容：只补齐副本数，新副本沿用旧模板
+	patch := `{"spec":{"replicas":3,"template":{"spec":{"containers":[{"name":"nginx","image":"nginx:1.26"}]}}}}`
+	if _, err := deployments.Patch(ctx, "web", types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("update template: %v", err)
+	}
+	d := c.WaitForDeploymentReady("default", "web")
+	if images := podImages(c.Pods("default", "app=web")); images["nginx:1.25"] != 3 {
+		t.Fatalf("paused deployment rolled out the template: %v", images)
+	}
+	if d.Status.UpdatedReplicas != 0 {
+		t.Fatalf("updatedReplicas = %d while paused, want 0", d.Status.UpdatedReplicas)
+	}
+
+	// 恢复后发布新模板，旧副本全部被替换
+	if _, err := deployments.Patch(ctx, "web", types.MergePatchType, []byte(`{"spec":{"paused":false}}`), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	c.WaitFor("新模板发布完成", func() (bool, error) {
+		pods := c.Pods("default", "app=web")
+		ready := 0
+		for _, pod := range pods {
+			if PodReady(pod) {
+				ready++
+			}
+		}
+		return len(pods) == 3 && ready == 3 && podImages(pods)["nginx:1.26"] == 3, nil
+	})
+	c.WaitFor("Deployment 状态更新", func() (bool, error) {
+		d := c.Deployment("default", "web")
+		return d.Status.UpdatedReplicas == 3 && progressingReason(d) == "NewReplicaSetAvailable", nil
+	})
+}
+
+func TestDeploymentProgressDeadline(t *testing.T) {
+	c := Start(t)
+	c.Runtime.FailImage("nginx:broken", errors.New("pull access denied"))
+	c.Apply(webDeployment)
+	c.WaitForDeploymentReady("default", "web")
+	c.WaitFor("发布完成", func() (bool, error) {
+		return progressingReason(c.Deployment("default", "web")) == "NewReplicaSetAvailable", nil
+	})
+
+	// progressDeadlineSeconds 必须大于 0
+	ctx := context.Background()
+	deployments := c.Client.AppsV1().Deployments("default")
+	if _, err := deployments.Patch(ctx, "web", types.MergePatchType, []byte(`{"spec":{"progressDeadlineSeconds":0}}`), metav1.PatchOptions{}); err == nil {
+		t.Fatal("progressDeadlineSeconds=0 accepted")
+	}
+
+	// 新模板的副本无法就绪：超过 progressDeadlineSeconds 后 Progressing 为 False，旧副本保留
+	patch := `{"spec":{"progressDeadlineSeconds":1,"template":{"spec":{"containers":[{"name":"nginx","image":"nginx:broken"}]}}}}`
+	if _, err := deployments.Patch(ctx, "web", types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("update template: %v", err)
+	}
+	c.WaitFor("发布超过期限", func() (bool, error) {
+		return progressingReason(c.Deployment("default", "web")) == "ProgressDeadlineExceeded", nil
+	})
+	d := c.Deployment("default", "web")
+	for _, cond := range d.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status != corev1.ConditionFalse {
+			t.Fatalf("Progressing = %s after the deadline, want False", cond.Status)
+		}
+	}
+	if images := podImages(c.Pods("default", "app=web")); images["nginx:1.25"] != 2 || images["nginx:broken"] != 2 {
+		t.Fatalf("pods after the deadline: %v", images)
+	}
+
+	// 超时后不再补齐新模板的副本
+	broken := c.Pods("default", "app=web")
+	for _, pod := range broken {
+		if pod.Spec.Containers[0].Image == "nginx:broken" {
+			c.Delete(PodGVK, "default", pod.Name)
+			break
+		}
+	}
+	time.Sleep(500 * time.Millisecond)
+	if images := podImages(c.Pods("default", "app=web")); images["nginx:broken"] != 1 {
+		t.Fatalf("replacement pods created after the deadline: %v", images)
+	}
+
+	// 修改模板后重新开始发布
+	c.Runtime.FailImage("nginx:broken", nil)
+	patch = `{"spec":{"template":{"spec":{"containers":[{"name":"nginx","image":"nginx:1.26"}]}}}}`
+	if _, err := deployments.Patch(ctx, "web", types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("fix template: %v", err)
+	}
+	c.WaitFor("新模板发布完成", func() (bool, error) {
+		pods := c.Pods("default", "app=web")
+		return len(pods) == 2 && podImages(pods)["nginx:1.26"] == 2 &&
+			progressingReason(c.Deployment("default", "web")) == "NewReplicaSetAvailable", nil
+	})
+}
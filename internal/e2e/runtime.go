@@ -0,0 +1,283 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// FakeRuntime 内存中的容器运行时：StartContainer 只记录 Pod 为运行中并分配 Pod IP，不拉起任何容器。
+// 与 Docker 运行时一样按 Pod UID 识别容器：同名 Pod 重建后不会关联到上一个实例。
+// 可以用 FailImage 让指定镜像启动失败、用 SetLogs 设置容器日志
+type FakeRuntime struct {
+	mu     sync.Mutex
+	pods   map[string]*fakePod
+	failed map[string]error
+	logs   map[string]fakeLogs
+	nextIP int
+}
+
+// fakeLogs 容器的日志以及设置的时间（每行的时间戳从该时间开始，逐行递增 1ns）
+type fakeLogs struct {
+	text string
+	at   time.Time
+}
+
+// fakePod 一个“运行中”的 Pod
+type fakePod struct {
+	pod     *corev1.Pod
+	ip      string
+	running bool
+}
+
+var (
+	_ controller.ContainerRuntime   = (*FakeRuntime)(nil)
+	_ controller.ContainerInspector = (*FakeRuntime)(nil)
+)
+
+// NewFakeRuntime 创建空的假运行时
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		pods:   make(map[string]*fakePod),
+		failed: make(map[string]error),
+		logs:   make(map[string]fakeLogs),
+	}
+}
+
+// fakeKey 是 Pod 在假运行时中的标识
+func fakeKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// FailImage 使用该镜像的 Pod 启动失败（err 为 nil 时恢复）
+func (r *FakeRuntime) FailImage(image string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		delete(r.failed, image)
+		return
+	}
+	r.failed[image] = err
+}
+
+// SetLogs 设置 Pod 中容器的日志
+func (r *FakeRuntime) SetLogs(namespace, pod, container, logs string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs[fakeKey(namespace, pod)+"/"+container] = fakeLogs{text: logs, at: time.Now().UTC()}
+}
+
+// Running 判断 Pod 是否由假运行时启动且未停止
+func (r *FakeRuntime) Running(namespace, name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.pods[fakeKey(namespace, name)]
+	return ok && p.running
+}
+
+// RunningUID 返回运行中的 Pod 实例的 UID，没有运行时为空
+func (r *FakeRuntime) RunningUID(namespace, name string) types.UID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.pods[fakeKey(namespace, name)]
+	if !ok || !p.running {
+		return ""
+	}
+	return p.pod.UID
+}
+
+// RunningPods 返回运行中的 Pod（namespace/name）
+func (r *FakeRuntime) RunningPods() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []string
+	for key, p := range r.pods {
+		if p.running {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Name 返回运行时名称
+func (r *FakeRuntime) Name() string {
+	return "Fake"
+}
+
+// IsAvailable 总是可用
+func (r *FakeRuntime) IsAvailable() bool {
+	return true
+}
+
+// StartContainer 记录 Pod 为运行中；容器使用了 FailImage 设置的镜像时返回对应的错误
+func (r *FakeRuntime) StartContainer(ctx context.Context, pod *corev1.Pod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range pod.Spec.Containers {
+		if err, ok := r.failed[c.Image]; ok {
+			return err
+		}
+	}
+	key := fakeKey(pod.Namespace, pod.Name)
+	p, ok := r.pods[key]
+	// 同名 Pod 上一个实例的容器不复用，重新分配
+	if !ok || p.pod.UID != pod.UID {
+		r.nextIP++
+		p = &fakePod{ip: fmt.Sprintf("10.88.%d.%d", r.nextIP/250, r.nextIP%250+2)}
+		r.pods[key] = p
+	}
+	p.pod = pod.DeepCopy()
+	p.running = true
+	return nil
+}
+
+// StopContainer 记录 Pod 已停止（同名 Pod 的其他实例不受影响）
+func (r *FakeRuntime) StopContainer(ctx context.Context, pod *corev1.Pod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := fakeKey(pod.Namespace, pod.Name)
+	if p, ok := r.pods[key]; ok && p.pod.UID == pod.UID {
+		delete(r.pods, key)
+	}
+	return nil
+}
+
+// GetContainerStatus 返回 Pod 是否运行中及其 IP
+func (r *FakeRuntime) GetContainerStatus(ctx context.Context, pod *corev1.Pod) (controller.ContainerStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.pods[fakeKey(pod.Namespace, pod.Name)]
+	if !ok || !p.running || p.pod.UID != pod.UID {
+		return controller.ContainerStatus{Status: "not found"}, nil
+	}
+	return controller.ContainerStatus{Running: true, Status: "running", PodIP: p.ip}, nil
+}
+
+// ContainerLogs 返回 SetLogs 设置的日志（opts.Timestamps 时每行带时间戳）；与 Docker 相同，容器停止（删除）后读取失败
+func (r *FakeRuntime) ContainerLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	if opts == nil {
+		opts = &corev1.PodLogOptions{}
+	}
+	container := opts.Container
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.pods[fakeKey(pod.Namespace, pod.Name)]; !ok || p.pod.UID != pod.UID {
+		return nil, fmt.Errorf("容器 %s 不存在（Pod %s/%s）", container, pod.Namespace, pod.Name)
+	}
+	logs := r.logs[fakeKey(pod.Namespace, pod.Name)+"/"+container]
+	if !opts.Timestamps {
+		return io.NopCloser(strings.NewReader(logs.text)), nil
+	}
+	var b strings.Builder
+	for i, line := range strings.SplitAfter(logs.text, "\n") {
+		if line != "" {
+			b.WriteString(logs.at.Add(time.Duration(i)).Format(time.RFC3339Nano) + " " + line)
+		}
+	}
+	return io.NopCloser(strings.NewReader(b.String())), nil
+}
+
+// ListContainers 每个运行中 Pod 的每个容器对应一个条目
+func (r *FakeRuntime) ListContainers(ctx context.Context) ([]controller.ManagedContainer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []controller.ManagedContainer
+	for _, p := range r.pods {
+		for _, c := range p.pod.Spec.Containers {
+			list = append(list, controller.ManagedContainer{
+				ID:            string(p.pod.UID) + "-" + c.Name,
+				Name:          c.Name,
+				Status:        "running",
+				PodUID:        p.pod.UID,
+				PodNamespace:  p.pod.Namespace,
+				PodName:       p.pod.Name,
+				ContainerName: c.Name,
+			})
+		}
+	}
+	return list, nil
+}
+
+// InspectContainer 返回 ListContainers 中该容器的简要信息（字段名与 docker inspect 相同）
+func (r *FakeRuntime) InspectContainer(ctx context.Context, id string) (json.RawMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.pods {
+		for _, c := range p.pod.Spec.Containers {
+			if string(p.pod.UID)+"-"+c.Name != id {
+				continue
+			}
+			return json.Marshal(map[string]interface{}{
+				"Id":              id,
+				"Name":            "/" + c.Name,
+				"State":           map[string]interface{}{"Status": "running", "Running": p.running},
+				"Config":          map[string]interface{}{"Image": c.Image},
+				"NetworkSettings": map[string]interface{}{"IPAddress": p.ip},
+			})
+		}
+	}
+	return nil, fmt.Errorf("container %s not found", id)
+}
+
+// RemoveContainer 删除容器所属的 Pod
+func (r *FakeRuntime) RemoveContainer(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, p := range r.pods {
+		if strings.HasPrefix(id, string(p.pod.UID)+"-") {
+			delete(r.pods, key)
+		}
+	}
+	return nil
+}
+
+// ListImages 返回运行中 Pod 使用的镜像
+func (r *FakeRuntime) ListImages(ctx context.Context) ([]controller.ImageInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool)
+	var images []controller.ImageInfo
+	for _, p := range r.pods {
+		for _, c := range p.pod.Spec.Containers {
+			if seen[c.Image] {
+				continue
+			}
+			seen[c.Image] = true
+			images = append(images, controller.ImageInfo{ID: c.Image, RepoTags: []string{c.Image}, Created: time.Now(), InUse: true})
+		}
+	}
+	return images, nil
+}
+
+// PullImage 总是成功（FailImage 设置的镜像除外）
+func (r *FakeRuntime) PullImage(ctx context.Context, image string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failed[image]
+}
+
+// RemoveImage 不做任何事
+func (r *FakeRuntime) RemoveImage(ctx context.Context, id string) error {
+	return nil
+}
+
+// ImageFilesystem 假运行时没有镜像目录
+func (r *FakeRuntime) ImageFilesystem(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("fake runtime has no image filesystem")
+}
+
+// Platform 固定为 linux/amd64
+func (r *FakeRuntime) Platform(ctx context.Context) (string, string, error) {
+	return "linux", "amd64", nil
+}
@@ -0,0 +1,122 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runtimeExec 调用 POST /debug/runtime/exec，返回状态码与响应
+func (c *Cluster) runtimeExec(req apiserver.RuntimeExecRequest) (int, []byte) {
+	c.t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		c.t.Fatalf("marshal request: %v", err)
+	}
+	return c.DoWithContentType(http.MethodPost, "/debug/runtime/exec", "application/json", body)
+}
+
+func TestRuntimeExecOnLocalNode(t *testing.T) {
+	c := Start(t)
+	c.Apply(webDeployment)
+	c.WaitForDeploymentReady("default", "web")
+
+	code, resp := c.runtimeExec(apiserver.RuntimeExecRequest{Command: apiserver.RuntimeCommandPS})
+	if code != http.StatusOK {
+		t.Fatalf("ps = HTTP %d: %s", code, resp)
+	}
+	var ps apiserver.RuntimeExecResult
+	if err := json.Unmarshal(resp, &ps); err != nil {
+		t.Fatalf("decode ps: %v", err)
+	}
+	if ps.Node != DefaultNodeName || len(ps.Containers) == 0 {
+		t.Fatalf("ps = %+v, want containers on %s", ps, DefaultNodeName)
+	}
+	container := ps.Containers[0]
+	if container.PodNamespace != "default" || !strings.HasPrefix(container.PodName, "web-") {
+		t.Fatalf("ps container = %+v, want a web pod", container)
+	}
+
+	code, resp = c.runtimeExec(apiserver.RuntimeExecRequest{Node: DefaultNodeName, Command: apiserver.RuntimeCommandInspect, Container: container.ID})
+	if code != http.StatusOK {
+		t.Fatalf("inspect = HTTP %d: %s", code, resp)
+	}
+	var inspect apiserver.RuntimeExecResult
+	if err := json.Unmarshal(resp, &inspect); err != nil {
+		t.Fatalf("decode inspect: %v", err)
+	}
+	var details struct{ Id string }
+	if err := json.Unmarshal(inspect.Inspect, &details); err != nil || details.Id != container.ID {
+		t.Fatalf("inspect = %s (%v), want container %s", inspect.Inspect, err, container.ID)
+	}
+
+	code, resp = c.runtimeExec(apiserver.RuntimeExecRequest{Command: apiserver.RuntimeCommandImages})
+	if code != http.StatusOK || !strings.Contains(string(resp), "nginx") {
+		t.Fatalf("images = HTTP %d: %s, want nginx", code, resp)
+	}
+
+	for _, tc := range []struct {
+		req  apiserver.RuntimeExecRequest
+		want int
+	}{
+		{apiserver.RuntimeExecRequest{Command: "rm"}, http.StatusBadRequest},
+		{apiserver.RuntimeExecRequest{Command: apiserver.RuntimeCommandInspect}, http.StatusBadRequest},
+		{apiserver.RuntimeExecRequest{Command: apiserver.RuntimeCommandInspect, Container: "missing"}, http.StatusNotFound},
+		{apiserver.RuntimeExecRequest{Node: "missing", Command: apiserver.RuntimeCommandPS}, http.StatusNotFound},
+	} {
+		if code, resp := c.runtimeExec(tc.req); code != tc.want {
+			t.Errorf("%+v = HTTP %d: %s, want %d", tc.req, code, resp, tc.want)
+		}
+	}
+}
+
+func TestRuntimeExecForwardsToPublishedNode(t *testing.T) {
+	c := Start(t)
+	remote := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "remote"},
+	}
+	if err := c.Store.Create(NodeGVK, remote); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	// 没有发布地址的节点无法转发
+	if code, resp := c.runtimeExec(apiserver.RuntimeExecRequest{Node: "remote", Command: apiserver.RuntimeCommandPS}); code != http.StatusServiceUnavailable {
+		t.Fatalf("ps on unpublished node = HTTP %d: %s, want 503", code, resp)
+	}
+
+	// remote 发布的地址指向本测试集群：请求被转发一次，收到转发的 apiserver 不再继续转发
+	u, err := url.Parse(c.Server)
+	if err != nil {
+		t.Fatalf("parse server address: %v", err)
+	}
+	host, portStr, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatalf("split server address: %v", err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	nodeName := "remote"
+	ep := &corev1.Endpoints{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Endpoints"},
+		ObjectMeta: metav1.ObjectMeta{Name: apiserver.SelfRegisterService, Namespace: apiserver.SelfRegisterNamespace},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: host, NodeName: &nodeName}},
+			Ports:     []corev1.EndpointPort{{Name: "http", Port: int32(port)}},
+		}},
+	}
+	if err := c.Store.Create(endpointsGVK, ep); err != nil {
+		t.Fatalf("create endpoints: %v", err)
+	}
+	code, resp := c.runtimeExec(apiserver.RuntimeExecRequest{Node: "remote", Command: apiserver.RuntimeCommandPS})
+	if code != http.StatusBadRequest || !strings.Contains(string(resp), "forwarded by "+DefaultNodeName) {
+		t.Fatalf("forwarded ps = HTTP %d: %s, want 400 from the forwarded request", code, resp)
+	}
+}
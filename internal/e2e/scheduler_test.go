@@ -0,0 +1,38 @@
+package e2e
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScaleUpSchedulesInBatches(t *testing.T) {
+	c := Start(t)
+	c.Apply(strings.Replace(webDeployment, "replicas: 2", "replicas: 30", 1))
+
+	d := c.WaitForDeploymentReady("default", "web")
+	if d.Status.ReadyReplicas != 30 {
+		t.Fatalf("expected 30 ready replicas, got %d", d.Status.ReadyReplicas)
+	}
+	for _, pod := range c.Pods("default", "app=web") {
+		if pod.Spec.NodeName != DefaultNodeName {
+			t.Fatalf("pod %s scheduled to %q", pod.Name, pod.Spec.NodeName)
+		}
+	}
+
+	for _, st := range c.Manager.ControllerStatuses() {
+		if st.Name != "SchedulerController" {
+			continue
+		}
+		if st.Placement == nil {
+			t.Fatal("scheduler should report placement stats")
+		}
+		if st.Placement.Latency.Count < 30 {
+			t.Fatalf("expected at least 30 placements, got %d", st.Placement.Latency.Count)
+		}
+		if st.Placement.Batches == 0 || st.Placement.Batches > st.Placement.Latency.Count {
+			t.Fatalf("unexpected batch count %d for %d placements", st.Placement.Batches, st.Placement.Latency.Count)
+		}
+		return
+	}
+	t.Fatal("SchedulerController status not found")
+}
@@ -0,0 +1,90 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	serviceGVK   = schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	namespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	endpointsGVK = schema.GroupVersionKind{Version: "v1", Kind: "Endpoints"}
+)
+
+const selfEndpointsPath = "/api/v1/namespaces/" + apiserver.SelfRegisterNamespace + "/endpoints/" + apiserver.SelfRegisterService
+
+// endpointNodes 返回 Endpoints 中各地址的节点名与 IP
+func endpointNodes(ep *corev1.Endpoints) map[string]string {
+	nodes := make(map[string]string)
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			if addr.NodeName != nil {
+				nodes[*addr.NodeName] = addr.IP
+			}
+		}
+	}
+	return nodes
+}
+
+func TestSelfRegisterPublishesServiceAndEndpoints(t *testing.T) {
+	c := Start(t, WithConfig(func(cfg *config.Config) {
+		cfg.Gin.Port = 18080
+		cfg.APIServer.SelfRegisterInterval = "1s"
+		cfg.APIServer.AdvertiseAddress = "10.0.0.5"
+	}))
+
+	var ep corev1.Endpoints
+	c.WaitFor("本节点写入 Endpoints", func() (bool, error) {
+		code, body := c.Do(http.MethodGet, selfEndpointsPath, nil)
+		if code != http.StatusOK {
+			return false, nil
+		}
+		if err := json.Unmarshal(body, &ep); err != nil {
+			return false, err
+		}
+		return endpointNodes(&ep)[DefaultNodeName] == "10.0.0.5", nil
+	})
+	if len(ep.Subsets) != 1 || len(ep.Subsets[0].Ports) != 1 || ep.Subsets[0].Ports[0].Port != 18080 {
+		t.Fatalf("unexpected endpoint subsets: %+v", ep.Subsets)
+	}
+
+	svc, ok := c.Get(serviceGVK, apiserver.SelfRegisterNamespace, apiserver.SelfRegisterService).(*corev1.Service)
+	if !ok {
+		t.Fatalf("service %s/%s not created", apiserver.SelfRegisterNamespace, apiserver.SelfRegisterService)
+	}
+	if len(svc.Spec.Selector) != 0 || len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port != 18080 {
+		t.Fatalf("unexpected service spec: %+v", svc.Spec)
+	}
+	if c.Get(namespaceGVK, "", apiserver.SelfRegisterNamespace) == nil {
+		t.Fatalf("namespace %s not created", apiserver.SelfRegisterNamespace)
+	}
+
+	// 模拟一个已经停止刷新的节点：它的地址在超过 TTL 后被移除
+	stored, ok := c.Get(endpointsGVK, apiserver.SelfRegisterNamespace, apiserver.SelfRegisterService).(*corev1.Endpoints)
+	if !ok {
+		t.Fatalf("endpoints not found in store")
+	}
+	stale := stored.DeepCopy()
+	peer := "stale-node"
+	stale.Subsets[0].Addresses = append(stale.Subsets[0].Addresses, corev1.EndpointAddress{IP: "10.0.0.9", NodeName: &peer})
+	stale.Annotations["k3.apiserver/renew-times"] = `{"stale-node":"` + time.Now().Add(-time.Minute).UTC().Format(time.RFC3339) + `"}`
+	if err := c.Store.Update(endpointsGVK, stale); err != nil {
+		t.Fatalf("update endpoints: %v", err)
+	}
+	c.WaitFor("移除过期节点", func() (bool, error) {
+		ep, ok := c.Get(endpointsGVK, apiserver.SelfRegisterNamespace, apiserver.SelfRegisterService).(*corev1.Endpoints)
+		if !ok {
+			return false, nil
+		}
+		nodes := endpointNodes(ep)
+		_, hasPeer := nodes[peer]
+		return !hasPeer && nodes[DefaultNodeName] == "10.0.0.5", nil
+	})
+}
@@ -0,0 +1,32 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+)
+
+const servicesPath = "/api/v1/namespaces/default/services"
+
+func TestServiceTypeValidation(t *testing.T) {
+	c := Start(t)
+
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"externalname", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"db"},"spec":{"type":"ExternalName","externalName":"db.example.com"}}`, http.StatusCreated},
+		{"externalname-missing", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"db2"},"spec":{"type":"ExternalName"}}`, http.StatusBadRequest},
+		{"externalname-invalid", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"db3"},"spec":{"type":"ExternalName","externalName":"Not_A_Host"}}`, http.StatusBadRequest},
+		{"externalname-clusterip", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"db4"},"spec":{"type":"ExternalName","externalName":"db.example.com","clusterIP":"None"}}`, http.StatusBadRequest},
+		{"headless", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"peers"},"spec":{"clusterIP":"None","selector":{"app":"db"}}}`, http.StatusCreated},
+		{"headless-nodeport", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"peers2"},"spec":{"type":"NodePort","clusterIP":"None","ports":[{"port":80}]}}`, http.StatusBadRequest},
+		{"selector-hostile-key", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"web2"},"spec":{"selector":{"x\"')) OR 1=1 -- ":"v"},"ports":[{"port":80}]}}`, http.StatusBadRequest},
+		{"selector-bad-value", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"web3"},"spec":{"selector":{"app":"a b"},"ports":[{"port":80}]}}`, http.StatusBadRequest},
+		{"bad-clusterip", `{"apiVersion":"v1","kind":"Service","metadata":{"name":"web"},"spec":{"clusterIP":"not-an-ip","ports":[{"port":80}]}}`, http.StatusBadRequest},
+	} {
+		code, body := c.DoWithContentType(http.MethodPost, servicesPath, "application/json", []byte(tc.body))
+		if code != tc.want {
+			t.Errorf("%s: HTTP %d, want %d: %s", tc.name, code, tc.want, body)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// invalidResponse 422 响应体
+type invalidResponse struct {
+	Error  string `json:"error"`
+	Causes []struct {
+		Field   string `json:"field"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	} `json:"causes"`
+}
+
+func TestMetadataValidation(t *testing.T) {
+	c := Start(t)
+
+	// 名称、label 键与值同时不合法：一次返回所有字段
+	code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:   "Bad/Name",
+		Labels: map[string]string{"app": strings.Repeat("x", 64), "bad key!": "v"},
+	}})
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("create invalid configmap: HTTP %d: %s", code, body)
+	}
+	var resp invalidResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	fields := map[string]bool{}
+	for _, cause := range resp.Causes {
+		fields[cause.Field] = true
+	}
+	for _, want := range []string{"metadata.name", "metadata.labels[app]", "metadata.labels"} {
+		if !fields[want] {
+			t.Errorf("causes %v do not include %s", fields, want)
+		}
+	}
+
+	// annotations 超过 256KiB
+	code, body = postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        "large-annotations",
+		Annotations: map[string]string{"note": strings.Repeat("a", apiserver.MaxAnnotationsSize)},
+	}})
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("create configmap with oversized annotations: HTTP %d: %s", code, body)
+	}
+
+	// Service 名称为 DNS-1035 label（不能以数字开头）
+	svc := []byte(`{"apiVersion":"v1","kind":"Service","metadata":{"name":"1web"},"spec":{"ports":[{"port":80}]}}`)
+	if code, body := c.DoWithContentType(http.MethodPost, "/api/v1/namespaces/default/services", "application/json", svc); code != http.StatusUnprocessableEntity {
+		t.Fatalf("create service with invalid name: HTTP %d: %s", code, body)
+	}
+
+	// 合法对象可以创建；更新时同样校验 labels
+	if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app.config-1", Labels: map[string]string{"example.com/tier": "web"}}}); code != http.StatusCreated {
+		t.Fatalf("create valid configmap: HTTP %d: %s", code, body)
+	}
+	code, body = c.DoWithContentType(http.MethodPatch, configMapsPath+"/app.config-1", "application/merge-patch+json",
+		[]byte(`{"metadata":{"labels":{"tier":"-bad-"}}}`))
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("patch invalid label: HTTP %d: %s", code, body)
+	}
+}
+
+const deploymentsPath = "/apis/apps/v1/namespaces/default/deployments"
+
+func TestDeploymentSelectorDefaultingAndImmutability(t *testing.T) {
+	c := Start(t)
+
+	// 未指定 selector 时由模板 labels 生成
+	c.Apply(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.25
+`)
+	deploy := c.WaitForDeploymentReady("default", "web")
+	if deploy.Spec.Selector == nil || deploy.Spec.Selector.MatchLabels["app"] != "web" {
+		t.Fatalf("selector = %+v, want defaulted from template labels", deploy.Spec.Selector)
+	}
+	if pods := c.Pods("default", "app=web"); len(pods) != 1 {
+		t.Fatalf("pods = %d, want 1", len(pods))
+	}
+
+	// selector 创建后不可修改
+	code, body := c.DoWithContentType(http.MethodPatch, deploymentsPath+"/web", "application/merge-patch+json",
+		[]byte(`{"spec":{"selector":{"matchLabels":{"app":"web","tier":"frontend"}},"template":{"metadata":{"labels":{"tier":"frontend"}}}}}`))
+	if code != http.StatusUnprocessableEntity || !strings.Contains(string(body), "spec.selector") {
+		t.Fatalf("patch selector: HTTP %d: %s, want 422 on spec.selector", code, body)
+	}
+
+	// selector 必须匹配模板 labels
+	mismatched := []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"api"},"spec":{` +
+		`"selector":{"matchLabels":{"app":"api"}},"template":{"metadata":{"labels":{"app":"web"}},` +
+		`"spec":{"containers":[{"name":"app","image":"nginx"}]}}}}`)
+	if code, body := c.DoWithContentType(http.MethodPost, deploymentsPath, "application/json", mismatched); code != http.StatusUnprocessableEntity {
+		t.Fatalf("create deployment with mismatched selector: HTTP %d: %s", code, body)
+	}
+
+	// 既没有 selector 也没有模板 labels
+	empty := []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"empty"},"spec":{` +
+		`"template":{"spec":{"containers":[{"name":"app","image":"nginx"}]}}}}`)
+	if code, body := c.DoWithContentType(http.MethodPost, deploymentsPath, "application/json", empty); code != http.StatusUnprocessableEntity {
+		t.Fatalf("create deployment without selector: HTTP %d: %s", code, body)
+	}
+}
+
+func TestDaemonSetTemplateSchedulingValidation(t *testing.T) {
+	c := Start(t)
+
+	// DaemonSet 的模板与 Deployment/StatefulSet 一样校验 topologySpreadConstraints
+	ds := []byte(`{"apiVersion":"apps/v1","kind":"DaemonSet","metadata":{"name":"agent"},"spec":{` +
+		`"selector":{"matchLabels":{"app":"agent"}},"template":{"metadata":{"labels":{"app":"agent"}},` +
+		`"spec":{"topologySpreadConstraints":[{"maxSkew":0,"topologyKey":"kubernetes.io/hostname","whenUnsatisfiable":"DoNotSchedule"}],` +
+		`"containers":[{"name":"agent","image":"busybox"}]}}}}`)
+	code, body := c.DoWithContentType(http.MethodPost, "/apis/apps/v1/namespaces/default/daemonsets", "application/json", ds)
+	if code != http.StatusBadRequest || !strings.Contains(string(body), "spec.template.spec.topologySpreadConstraints[0].maxSkew") {
+		t.Fatalf("create daemonset with invalid maxSkew: HTTP %d: %s", code, body)
+	}
+}
+
+func TestImportedObjectsAreReadOnly(t *testing.T) {
+	c := Start(t)
+
+	// import 模式的镜像由 Importer 直接写入 Store
+	mirrored := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}, ObjectMeta: metav1.ObjectMeta{
+		Name: "mirrored", Namespace: "default", Annotations: map[string]string{mirror.AnnotationImportedFrom: "prod"},
+	}}
+	if err := c.Store.Create(configMapGVK, mirrored); err != nil {
+		t.Fatal(err)
+	}
+	code, body := c.DoWithContentType(http.MethodPatch, configMapsPath+"/mirrored", "application/merge-patch+json",
+		[]byte(`{"metadata":{"annotations":{"k3.io/imported-from":null}},"data":{"k":"v"}}`))
+	if code != http.StatusForbidden {
+		t.Fatalf("patch imported configmap: HTTP %d: %s", code, body)
+	}
+
+	// 也不能创建带该注解的对象
+	code, body = postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name: "fake-mirror", Annotations: map[string]string{mirror.AnnotationImportedFrom: "prod"},
+	}})
+	if code != http.StatusForbidden {
+		t.Fatalf("create configmap with imported-from annotation: HTTP %d: %s", code, body)
+	}
+
+	// 删除同样返回 403
+	if code, body := c.Do(http.MethodDelete, configMapsPath+"/mirrored", nil); code != http.StatusForbidden {
+		t.Fatalf("delete imported configmap: HTTP %d: %s", code, body)
+	}
+
+	// deletecollection 只删除本地对象，跳过镜像
+	if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "local"}}); code != http.StatusCreated {
+		t.Fatalf("create local configmap: HTTP %d: %s", code, body)
+	}
+	code, body = c.Do(http.MethodDelete, configMapsPath, nil)
+	if code != http.StatusOK || !strings.Contains(string(body), `"deleted":1`) || !strings.Contains(string(body), "default/local") {
+		t.Fatalf("deletecollection: HTTP %d: %s", code, body)
+	}
+	if _, err := c.Store.Get(configMapGVK, "default", "mirrored"); err != nil {
+		t.Fatalf("deletecollection removed the imported configmap: %v", err)
+	}
+}
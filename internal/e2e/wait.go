@@ -0,0 +1,150 @@
+package e2e
+
+import (
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// PodGVK 是 core/v1 Pod
+	PodGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	// NodeGVK 是 core/v1 Node
+	NodeGVK = schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+	// DeploymentGVK 是 apps/v1 Deployment
+	DeploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+)
+
+// WaitFor 每 20ms 检查一次 cond，DefaultTimeout 内没有满足时测试失败；cond 返回错误时立即失败
+func (c *Cluster) WaitFor(desc string, cond func() (bool, error)) {
+	c.t.Helper()
+	c.WaitForWithTimeout(desc, DefaultTimeout, cond)
+}
+
+// WaitForWithTimeout 与 WaitFor 相同，使用指定的超时
+func (c *Cluster) WaitForWithTimeout(desc string, timeout time.Duration, cond func() (bool, error)) {
+	c.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := cond()
+		if err != nil {
+			c.t.Fatalf("e2e: 等待 %s 失败: %v", desc, err)
+		}
+		if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			c.t.Fatalf("e2e: 等待 %s 超时（%s）", desc, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Get 从 Store 读取对象，不存在时返回 nil
+func (c *Cluster) Get(gvk schema.GroupVersionKind, namespace, name string) runtime.Object {
+	c.t.Helper()
+	obj, err := c.Store.Get(gvk, namespace, name)
+	// Store 没有导出 not found 错误类型，按错误信息判断
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		return nil
+	}
+	if err != nil {
+		c.t.Fatalf("e2e: 读取 %s %s/%s 失败: %v", gvk.Kind, namespace, name, err)
+	}
+	return obj
+}
+
+// Pod 从 Store 读取 Pod，不存在时返回 nil
+func (c *Cluster) Pod(namespace, name string) *corev1.Pod {
+	c.t.Helper()
+	pod, _ := c.Get(PodGVK, namespace, name).(*corev1.Pod)
+	return pod
+}
+
+// Deployment 从 Store 读取 Deployment，不存在时返回 nil
+func (c *Cluster) Deployment(namespace, name string) *appsv1.Deployment {
+	c.t.Helper()
+	d, _ := c.Get(DeploymentGVK, namespace, name).(*appsv1.Deployment)
+	return d
+}
+
+// Pods 列出 namespace 中标签满足 selector（为空表示全部）的 Pod
+func (c *Cluster) Pods(namespace, selector string) []*corev1.Pod {
+	c.t.Helper()
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		c.t.Fatalf("e2e: 无效的 selector %q: %v", selector, err)
+	}
+	objs, err := c.Store.ListBySelector(PodGVK, namespace, sel)
+	if err != nil {
+		c.t.Fatalf("e2e: 列出 Pod 失败: %v", err)
+	}
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
+
+// WaitForPod 等待 Pod 存在且满足 cond，返回满足条件时的 Pod
+func (c *Cluster) WaitForPod(namespace, name, desc string, cond func(pod *corev1.Pod) bool) *corev1.Pod {
+	c.t.Helper()
+	var pod *corev1.Pod
+	c.WaitFor("Pod "+namespace+"/"+name+" "+desc, func() (bool, error) {
+		pod = c.Pod(namespace, name)
+		return pod != nil && cond(pod), nil
+	})
+	return pod
+}
+
+// WaitForPodReady 等待 Pod 运行且 Ready 条件为 True
+func (c *Cluster) WaitForPodReady(namespace, name string) *corev1.Pod {
+	c.t.Helper()
+	return c.WaitForPod(namespace, name, "就绪", PodReady)
+}
+
+// WaitForDeploymentReady 等待 Deployment 的所有副本就绪且控制器已处理最新的 generation
+func (c *Cluster) WaitForDeploymentReady(namespace, name string) *appsv1.Deployment {
+	c.t.Helper()
+	var d *appsv1.Deployment
+	c.WaitFor("Deployment "+namespace+"/"+name+" 就绪", func() (bool, error) {
+		d = c.Deployment(namespace, name)
+		if d == nil {
+			return false, nil
+		}
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		return d.Status.ObservedGeneration >= d.Generation && d.Status.ReadyReplicas == replicas, nil
+	})
+	return d
+}
+
+// WaitForDeleted 等待对象从 Store 中消失
+func (c *Cluster) WaitForDeleted(gvk schema.GroupVersionKind, namespace, name string) {
+	c.t.Helper()
+	c.WaitFor(gvk.Kind+" "+namespace+"/"+name+" 被删除", func() (bool, error) {
+		return c.Get(gvk, namespace, name) == nil, nil
+	})
+}
+
+// PodReady 判断 Pod 是否运行且 Ready 条件为 True
+func PodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
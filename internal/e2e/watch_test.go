@@ -0,0 +1,104 @@
+package e2e
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sseEvent watch 流中的一个事件
+type sseEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// readSSE 在后台读取 SSE 流，逐个返回事件
+func readSSE(t *testing.T, resp *http.Response) <-chan sseEvent {
+	t.Helper()
+	ch := make(chan sseEvent, 16)
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		for scanner.Scan() {
+			line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event sseEvent
+			if json.Unmarshal([]byte(line), &event) == nil {
+				ch <- event
+			}
+		}
+	}()
+	return ch
+}
+
+func nextEvent(t *testing.T, ch <-chan sseEvent) (string, metav1.ObjectMeta) {
+	t.Helper()
+	select {
+	case event, ok := <-ch:
+		if !ok {
+			t.Fatalf("watch stream closed")
+		}
+		var obj struct {
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		}
+		_ = json.Unmarshal(event.Object, &obj)
+		return event.Type, obj.Metadata
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a watch event")
+	}
+	return "", metav1.ObjectMeta{}
+}
+
+func TestWatchSendInitialEvents(t *testing.T) {
+	c := Start(t)
+
+	for _, name := range []string{"a", "b"} {
+		if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}}); code != http.StatusCreated {
+			t.Fatalf("create %s: HTTP %d: %s", name, code, body)
+		}
+	}
+
+	// timeoutSeconds 让服务端及时结束流，测试集群关闭时不必等待
+	resp, err := http.Get(c.Server + configMapsPath + "?watch=true&sendInitialEvents=true&timeoutSeconds=3")
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("watch: HTTP %d", resp.StatusCode)
+	}
+	events := readSSE(t, resp)
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		typ, meta := nextEvent(t, events)
+		if typ != "ADDED" {
+			t.Fatalf("initial event %d: type %s, want ADDED", i, typ)
+		}
+		seen[meta.Name] = true
+	}
+	if !seen["a"] || !seen["b"] {
+		t.Fatalf("initial events = %v, want a and b", seen)
+	}
+	typ, meta := nextEvent(t, events)
+	if typ != "BOOKMARK" || meta.Annotations[apiserver.InitialEventsEndAnnotation] != "true" {
+		t.Fatalf("after initial events: %s %v, want BOOKMARK with %s", typ, meta.Annotations, apiserver.InitialEventsEndAnnotation)
+	}
+
+	if code, body := postConfigMap(t, c, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "c"}}); code != http.StatusCreated {
+		t.Fatalf("create c: HTTP %d: %s", code, body)
+	}
+	if typ, meta := nextEvent(t, events); typ != "ADDED" || meta.Name != "c" {
+		t.Fatalf("live event: %s %s, want ADDED c", typ, meta.Name)
+	}
+}
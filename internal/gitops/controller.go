@@ -0,0 +1,536 @@
+package gitops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/fx"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// DefaultInterval GitRepository 未设置 interval 时的同步周期
+	DefaultInterval = 5 * time.Minute
+	// checkInterval 检查哪些 GitRepository 到期需要同步的周期
+	checkInterval = 15 * time.Second
+	// syncTimeout 单次同步（拉取、渲染、写入）的超时
+	syncTimeout = 5 * time.Minute
+	// fieldManager 是 gitops 控制器写入资源时使用的写入者名称
+	fieldManager = "k3-gitops"
+	// component 记录 Event 时的组件名
+	component = "gitops-controller"
+
+	// OwnerAnnotation 记录资源由哪个 GitRepository（<namespace>/<name>）写入，prune 只删除带有该注解的资源
+	OwnerAnnotation = "k3.io/gitrepository"
+	// ChecksumAnnotation 记录写入时 manifest 的摘要，manifest 没有变化时不重复写入
+	ChecksumAnnotation = "k3.io/gitops-checksum"
+
+	// ConditionReady GitRepository 的 Ready 条件
+	ConditionReady = "Ready"
+)
+
+// Ready 条件的原因
+const (
+	ReasonSynced       = "Synced"
+	ReasonFetchFailed  = "FetchFailed"
+	ReasonBuildFailed  = "BuildFailed"
+	ReasonApplyFailed  = "ApplyFailed"
+	ReasonPruneFailed  = "PruneFailed"
+	ReasonSecretFailed = "SecretFailed"
+)
+
+var secretGVK = schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+
+// Module 启动 gitops 控制器（只应在带 apiserver 的进程中使用，避免多个节点重复同步）
+var Module = fx.Options(
+	fx.Invoke(func(lc fx.Lifecycle, cfg config.Config, logger logprovider.Logger, store storage.Store) {
+		c := NewController(store, logger, cfg.GitOps.WorkDir)
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error { return c.Start(context.Background()) },
+			OnStop:  c.Stop,
+		})
+	}),
+)
+
+// Controller 同步 k3.io/v1 GitRepository：按 interval 拉取仓库、渲染 manifest 目录，
+// 以与 apiserver 写入相同的流程（版本转换、默认值、写入者记录）创建/更新资源，并在开启 prune 时删除仓库中已移除的资源
+type Controller struct {
+	store       storage.Store
+	logger      logprovider.Logger
+	workDir     string
+	parser      *parser.Parser
+	conversions *apiserver.ConversionRegistry
+	stopCh      chan struct{}
+	done        chan struct{}
+}
+
+// NewController 创建 gitops 控制器，仓库检出到 workDir/<namespace>/<name>
+func NewController(store storage.Store, logger logprovider.Logger, workDir string) *Controller {
+	return &Controller{
+		store:       store,
+		logger:      logger,
+		workDir:     workDir,
+		parser:      parser.NewParser(),
+		conversions: apiserver.DefaultConversions(),
+		stopCh:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Name 返回控制器名称
+func (c *Controller) Name() string {
+	return "GitOpsController"
+}
+
+// Start watch GitRepository 并周期检查到期的同步
+func (c *Controller) Start(ctx context.Context) error {
+	watchCh, err := c.store.Watch(k3v1.GitRepositoryGVK, "", "")
+	if err != nil {
+		close(c.done)
+		return fmt.Errorf("watch GitRepository 失败: %w", err)
+	}
+	c.logger.Infof("启动 gitops 控制器（工作目录: %s）", c.workDir)
+	go func() {
+		defer close(c.done)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-c.stopCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		c.syncDue(ctx)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watchCh:
+				if !ok {
+					return
+				}
+				c.handleEvent(ctx, event)
+			case <-ticker.C:
+				c.syncDue(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止控制器并等待进行中的同步结束
+func (c *Controller) Stop(ctx context.Context) error {
+	close(c.stopCh)
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// handleEvent spec 变化（generation 与 observedGeneration 不一致）时立即同步；删除时按 prune 清理资源与检出目录
+func (c *Controller) handleEvent(ctx context.Context, event storage.ResourceEvent) {
+	repo, ok := event.Object.(*k3v1.GitRepository)
+	if !ok {
+		return
+	}
+	switch event.Type {
+	case storage.EventAdded, storage.EventModified:
+		if !repo.Spec.Suspend && repo.Status.ObservedGeneration != repo.Generation {
+			c.sync(ctx, repo)
+		}
+	case storage.EventDeleted:
+		if repo.Spec.Prune {
+			if err := c.prune(repo, repo.Status.Inventory, nil); err != nil {
+				c.logger.Warnf("清理 GitRepository %s 的资源失败: %v", repoKey(repo), err)
+			}
+		}
+		_ = os.RemoveAll(c.repoDir(repo))
+	}
+}
+
+// syncDue 同步所有到期（距离上次同步超过 interval）或 spec 有变化的 GitRepository
+func (c *Controller) syncDue(ctx context.Context) {
+	objs, err := c.store.List(k3v1.GitRepositoryGVK, "")
+	if err != nil {
+		c.logger.Warnf("列出 GitRepository 失败: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, obj := range objs {
+		if ctx.Err() != nil {
+			return
+		}
+		repo, ok := obj.(*k3v1.GitRepository)
+		if !ok || repo.Spec.Suspend {
+			continue
+		}
+		if repo.Status.ObservedGeneration == repo.Generation && now.Before(repo.Status.LastSyncTime.Add(interval(repo))) {
+			continue
+		}
+		c.sync(ctx, repo)
+	}
+}
+
+// interval 返回 GitRepository 的同步周期
+func interval(repo *k3v1.GitRepository) time.Duration {
+	if repo.Spec.Interval.Duration > 0 {
+		return repo.Spec.Interval.Duration
+	}
+	return DefaultInterval
+}
+
+// repoKey 返回 <namespace>/<name>
+func repoKey(repo *k3v1.GitRepository) string {
+	return repo.Namespace + "/" + repo.Name
+}
+
+// repoDir 返回仓库的检出目录
+func (c *Controller) repoDir(repo *k3v1.GitRepository) string {
+	return filepath.Join(c.workDir, repo.Namespace, repo.Name)
+}
+
+// sync 同步一次并写入 status
+func (c *Controller) sync(ctx context.Context, repo *k3v1.GitRepository) {
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	revision, inventory, reason, err := c.syncRepo(syncCtx, repo)
+	if ctx.Err() != nil {
+		// 控制器停止，不记录状态
+		return
+	}
+	c.updateStatus(repo, revision, inventory, reason, err)
+}
+
+// syncRepo 拉取、渲染并写入资源，返回 commit、写入的资源以及失败原因
+func (c *Controller) syncRepo(ctx context.Context, repo *k3v1.GitRepository) (string, []k3v1.ManagedResource, string, error) {
+	username, password, err := c.credentials(repo)
+	if err != nil {
+		return "", nil, ReasonSecretFailed, err
+	}
+	dir := c.repoDir(repo)
+	revision, err := fetch(ctx, dir, withCredentials(repo.Spec.URL, username, password), repo.Spec.Ref)
+	if err != nil {
+		return "", nil, ReasonFetchFailed, redact(err, password)
+	}
+
+	manifestPath, err := manifestDir(dir, repo.Spec.Path)
+	if err != nil {
+		return revision, nil, ReasonBuildFailed, err
+	}
+	manifests, err := render(ctx, c.parser, manifestPath)
+	if err != nil {
+		return revision, nil, ReasonBuildFailed, err
+	}
+	objects, err := c.prepare(repo, manifests)
+	if err != nil {
+		return revision, nil, ReasonBuildFailed, err
+	}
+
+	inventory := make([]k3v1.ManagedResource, 0, len(objects))
+	var errs []error
+	for _, o := range objects {
+		if err := c.apply(repo, o); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", o.gvk.Kind, qualifiedName(o.ref.Namespace, o.ref.Name), err))
+			continue
+		}
+		inventory = append(inventory, o.ref)
+	}
+	if len(errs) > 0 {
+		// 部分写入失败时不 prune，避免误删
+		return revision, inventory, ReasonApplyFailed, errors.Join(errs...)
+	}
+	if repo.Spec.Prune {
+		if err := c.prune(repo, repo.Status.Inventory, inventory); err != nil {
+			return revision, inventory, ReasonPruneFailed, err
+		}
+	}
+	return revision, inventory, ReasonSynced, nil
+}
+
+// credentials 读取 spec.secretRef 中的 username/password
+func (c *Controller) credentials(repo *k3v1.GitRepository) (string, string, error) {
+	if repo.Spec.SecretRef == nil || repo.Spec.SecretRef.Name == "" {
+		return "", "", nil
+	}
+	obj, err := c.store.Get(secretGVK, repo.Namespace, repo.Spec.SecretRef.Name)
+	if err != nil {
+		return "", "", fmt.Errorf("读取 Secret %s 失败: %w", repo.Spec.SecretRef.Name, err)
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return "", "", fmt.Errorf("unexpected object type %T for Secret %s", obj, repo.Spec.SecretRef.Name)
+	}
+	value := func(key string) string {
+		if v, ok := secret.StringData[key]; ok {
+			return v
+		}
+		return string(secret.Data[key])
+	}
+	return value("username"), value("password"), nil
+}
+
+// desiredObject 是一个准备写入的资源
+type desiredObject struct {
+	obj      runtime.Object
+	gvk      schema.GroupVersionKind // 存储版本
+	ref      k3v1.ManagedResource
+	checksum string
+}
+
+// prepare 校验并规范化渲染结果：资源必须由 apiserver 提供，补全 namespace，转换为存储版本并计算摘要
+func (c *Controller) prepare(repo *k3v1.GitRepository, manifests []manifest) ([]desiredObject, error) {
+	targetNamespace := repo.Spec.TargetNamespace
+	if targetNamespace == "" {
+		targetNamespace = repo.Namespace
+	}
+	seen := make(map[k3v1.ManagedResource]string)
+	out := make([]desiredObject, 0, len(manifests))
+	for _, m := range manifests {
+		if c.conversions.Versions(m.gvk.GroupKind()) == nil {
+			return nil, fmt.Errorf("%s: 不支持的资源 %s", m.source, m.gvk.Kind)
+		}
+		storageGVK, err := c.conversions.StorageGVK(m.gvk)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.source, err)
+		}
+		accessor, err := meta.Accessor(m.obj)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.source, err)
+		}
+		if accessor.GetName() == "" {
+			return nil, fmt.Errorf("%s: %s 缺少 metadata.name", m.source, m.gvk.Kind)
+		}
+		if storage.IsClusterScoped(storageGVK) {
+			accessor.SetNamespace("")
+		} else if accessor.GetNamespace() == "" {
+			accessor.SetNamespace(targetNamespace)
+		}
+
+		obj, err := c.conversions.ToStorage(m.obj, m.gvk)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.source, err)
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.source, err)
+		}
+		sum := sha256.Sum256(data)
+
+		ref := k3v1.ManagedResource{
+			APIVersion: storageGVK.GroupVersion().String(),
+			Kind:       storageGVK.Kind,
+			Namespace:  accessor.GetNamespace(),
+			Name:       accessor.GetName(),
+		}
+		if prev, ok := seen[ref]; ok {
+			return nil, fmt.Errorf("%s: %s %s 与 %s 重复", m.source, ref.Kind, qualifiedName(ref.Namespace, ref.Name), prev)
+		}
+		seen[ref] = m.source
+		out = append(out, desiredObject{obj: obj, gvk: storageGVK, ref: ref, checksum: hex.EncodeToString(sum[:])})
+	}
+	return out, nil
+}
+
+// apply 创建或更新一个资源：属于其他 GitRepository 的资源不覆盖；
+// 更新时保留现有的 status、uid 与创建时间（与 kubectl apply 只修改声明的字段类似）
+func (c *Controller) apply(repo *k3v1.GitRepository, o desiredObject) error {
+	accessor, err := meta.Accessor(o.obj)
+	if err != nil {
+		return err
+	}
+	annotations := accessor.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[OwnerAnnotation] = repoKey(repo)
+	annotations[ChecksumAnnotation] = o.checksum
+	accessor.SetAnnotations(annotations)
+
+	existing, err := c.store.Get(o.gvk, o.ref.Namespace, o.ref.Name)
+	if err != nil {
+		if storage.IsBackendError(err) {
+			return err
+		}
+		apiserver.SetDefaults(o.obj)
+		storage.RecordManager(o.obj, fieldManager)
+		return c.store.Create(o.gvk, o.obj)
+	}
+
+	current, err := meta.Accessor(existing)
+	if err != nil {
+		return err
+	}
+	if owner := current.GetAnnotations()[OwnerAnnotation]; owner != "" && owner != repoKey(repo) {
+		return fmt.Errorf("资源属于 GitRepository %s", owner)
+	}
+	// manifest 没有变化且之后没有其他写入者修改过时跳过；被手动修改的资源会被恢复为仓库中的内容
+	if current.GetAnnotations()[ChecksumAnnotation] == o.checksum && storage.LastManager(existing) == fieldManager {
+		return nil
+	}
+	accessor.SetResourceVersion(current.GetResourceVersion())
+	accessor.SetUID(current.GetUID())
+	accessor.SetCreationTimestamp(current.GetCreationTimestamp())
+	if err := copyStatus(existing, o.obj); err != nil {
+		return err
+	}
+	apiserver.SetDefaults(o.obj)
+	_, err = storage.UpdateAs(c.store, o.gvk, o.obj, fieldManager, true)
+	return err
+}
+
+// copyStatus 把 from 的 status 复制到 to（status 由控制器维护，不来自仓库）
+func copyStatus(from, to runtime.Object) error {
+	fromU, err := runtime.DefaultUnstructuredConverter.ToUnstructured(from)
+	if err != nil {
+		return err
+	}
+	status, ok := fromU["status"]
+	if !ok {
+		return nil
+	}
+	toU, err := runtime.DefaultUnstructuredConverter.ToUnstructured(to)
+	if err != nil {
+		return err
+	}
+	toU["status"] = status
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(toU, to)
+}
+
+// prune 删除 previous 中不在 current 里、且仍属于该 GitRepository 的资源
+func (c *Controller) prune(repo *k3v1.GitRepository, previous, current []k3v1.ManagedResource) error {
+	keep := make(map[k3v1.ManagedResource]bool, len(current))
+	for _, ref := range current {
+		keep[ref] = true
+	}
+	var errs []error
+	for _, ref := range previous {
+		if keep[ref] {
+			continue
+		}
+		gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+		obj, err := c.store.Get(gvk, ref.Namespace, ref.Name)
+		if err != nil {
+			if storage.IsBackendError(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if accessor, err := meta.Accessor(obj); err != nil || accessor.GetAnnotations()[OwnerAnnotation] != repoKey(repo) {
+			continue
+		}
+		if err := c.store.Delete(gvk, ref.Namespace, ref.Name); err != nil {
+			errs = append(errs, fmt.Errorf("删除 %s %s: %w", ref.Kind, qualifiedName(ref.Namespace, ref.Name), err))
+			continue
+		}
+		c.logger.Infof("GitRepository %s: 删除已从仓库移除的 %s %s", repoKey(repo), ref.Kind, qualifiedName(ref.Namespace, ref.Name))
+	}
+	return errors.Join(errs...)
+}
+
+// updateStatus 写入同步结果；Ready 条件变化或同步到新的 commit 时记录 Event
+func (c *Controller) updateStatus(repo *k3v1.GitRepository, revision string, inventory []k3v1.ManagedResource, reason string, syncErr error) {
+	obj, err := c.store.Get(k3v1.GitRepositoryGVK, repo.Namespace, repo.Name)
+	if err != nil {
+		// 同步期间被删除
+		return
+	}
+	latest, ok := obj.(*k3v1.GitRepository)
+	if !ok {
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		ObservedGeneration: repo.Generation,
+	}
+	if syncErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Message = syncErr.Error()
+	} else {
+		condition.Message = fmt.Sprintf("已同步 %s（%d 个资源）", shortRevision(revision), len(inventory))
+	}
+	previous := meta.FindStatusCondition(latest.Status.Conditions, ConditionReady)
+	changed := previous == nil || previous.Status != condition.Status || previous.Reason != condition.Reason || previous.Message != condition.Message
+	newRevision := syncErr == nil && revision != latest.Status.Revision
+
+	latest.Status.ObservedGeneration = repo.Generation
+	latest.Status.LastSyncTime = metav1.Now()
+	meta.SetStatusCondition(&latest.Status.Conditions, condition)
+	if syncErr == nil {
+		latest.Status.Revision = revision
+		latest.Status.Inventory = inventory
+	} else if len(inventory) > 0 {
+		// 部分写入成功（ApplyFailed）或 prune 失败（PruneFailed）：已写入的资源加入 inventory，
+		// 之前的资源也保留，下次同步成功时再 prune
+		latest.Status.Inventory = mergeInventory(inventory, latest.Status.Inventory)
+	}
+	if err := c.store.Update(k3v1.GitRepositoryGVK, latest); err != nil {
+		c.logger.Warnf("更新 GitRepository %s 状态失败: %v", repoKey(repo), err)
+		return
+	}
+
+	switch {
+	case syncErr != nil && changed:
+		c.logger.Warnf("同步 GitRepository %s 失败（%s）: %v", repoKey(repo), reason, syncErr)
+		_ = controller.RecordEvent(c.store, latest, corev1.EventTypeWarning, reason, condition.Message, component)
+	case syncErr == nil && (changed || newRevision):
+		c.logger.Infof("GitRepository %s: %s", repoKey(repo), condition.Message)
+		_ = controller.RecordEvent(c.store, latest, corev1.EventTypeNormal, reason, condition.Message, component)
+	}
+}
+
+// mergeInventory 合并两个 inventory（去重，a 在前）
+func mergeInventory(a, b []k3v1.ManagedResource) []k3v1.ManagedResource {
+	seen := make(map[k3v1.ManagedResource]bool, len(a)+len(b))
+	out := make([]k3v1.ManagedResource, 0, len(a)+len(b))
+	for _, ref := range append(append([]k3v1.ManagedResource{}, a...), b...) {
+		if !seen[ref] {
+			seen[ref] = true
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+// shortRevision 返回 commit 的前 12 位
+func shortRevision(revision string) string {
+	if len(revision) > 12 {
+		return revision[:12]
+	}
+	return revision
+}
+
+// qualifiedName 返回 namespace/name（集群级资源只有 name）
+func qualifiedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return strings.Join([]string{namespace, name}, "/")
+}
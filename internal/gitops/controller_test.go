@@ -0,0 +1,172 @@
+package gitops
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testDeployment = `apiVersion: apps/v1beta1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+`
+
+const testConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: other
+data:
+  key: value
+`
+
+// gitRepo 在临时目录中创建 git 仓库，files 为相对 deploy/ 的文件内容
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	commit(t, dir, files)
+	return dir
+}
+
+// commit 写入 files（内容为空表示删除）并提交
+func commit(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, "deploy", name)
+		if content == "" {
+			if err := os.Remove(path); err != nil {
+				t.Fatalf("remove %s: %v", name, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git(t, dir, "add", "-A")
+	git(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "update")
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := runGit(context.Background(), dir, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestControllerSyncAndPrune(t *testing.T) {
+	repoDir := gitRepo(t, map[string]string{"web.yaml": testDeployment, "config/settings.yaml": testConfigMap})
+
+	store := storage.NewMemoryStore()
+	repo := &k3v1.GitRepository{
+		TypeMeta:   metav1.TypeMeta{APIVersion: k3v1.SchemeGroupVersion.String(), Kind: "GitRepository"},
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "default"},
+		Spec:       k3v1.GitRepositorySpec{URL: repoDir, Path: "deploy", Prune: true},
+	}
+	if err := store.Create(k3v1.GitRepositoryGVK, repo); err != nil {
+		t.Fatalf("Create GitRepository: %v", err)
+	}
+	c := NewController(store, logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}, t.TempDir())
+
+	sync := func() *k3v1.GitRepository {
+		t.Helper()
+		obj, err := store.Get(k3v1.GitRepositoryGVK, "default", "apps")
+		if err != nil {
+			t.Fatalf("Get GitRepository: %v", err)
+		}
+		c.sync(context.Background(), obj.(*k3v1.GitRepository))
+		obj, _ = store.Get(k3v1.GitRepositoryGVK, "default", "apps")
+		return obj.(*k3v1.GitRepository)
+	}
+
+	synced := sync()
+	ready := meta.FindStatusCondition(synced.Status.Conditions, ConditionReady)
+	if ready == nil || ready.Status != metav1.ConditionTrue {
+		t.Fatalf("expected Ready condition, got %+v", synced.Status.Conditions)
+	}
+	if len(synced.Status.Inventory) != 2 || synced.Status.Revision == "" {
+		t.Fatalf("unexpected status %+v", synced.Status)
+	}
+
+	deploymentGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	obj, err := store.Get(deploymentGVK, "default", "web")
+	if err != nil {
+		t.Fatalf("Deployment was not created in the GitRepository namespace: %v", err)
+	}
+	deploy := obj.(*appsv1.Deployment)
+	if deploy.Spec.Selector == nil || deploy.Annotations[OwnerAnnotation] != "default/apps" {
+		t.Errorf("expected converted apps/v1 Deployment owned by default/apps, got selector=%v annotations=%v", deploy.Spec.Selector, deploy.Annotations)
+	}
+	if _, err := store.Get(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "other", "settings"); err != nil {
+		t.Fatalf("ConfigMap was not created: %v", err)
+	}
+
+	// 没有变化时不重复写入
+	rv := deploy.ResourceVersion
+	sync()
+	obj, _ = store.Get(deploymentGVK, "default", "web")
+	if obj.(*appsv1.Deployment).ResourceVersion != rv {
+		t.Errorf("unchanged Deployment should not be rewritten")
+	}
+
+	// 从仓库移除后被删除
+	commit(t, repoDir, map[string]string{"config/settings.yaml": ""})
+	synced = sync()
+	if len(synced.Status.Inventory) != 1 {
+		t.Errorf("expected 1 resource in inventory, got %+v", synced.Status.Inventory)
+	}
+	if _, err := store.Get(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "other", "settings"); err == nil {
+		t.Errorf("ConfigMap removed from the repository should be pruned")
+	}
+}
+
+func TestControllerBuildFailed(t *testing.T) {
+	repoDir := gitRepo(t, map[string]string{"ingress.yaml": "apiVersion: networking.k8s.io/v1\nkind: Ingress\nmetadata:\n  name: x\n"})
+
+	store := storage.NewMemoryStore()
+	repo := &k3v1.GitRepository{
+		TypeMeta:   metav1.TypeMeta{APIVersion: k3v1.SchemeGroupVersion.String(), Kind: "GitRepository"},
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "default"},
+		Spec:       k3v1.GitRepositorySpec{URL: repoDir, Path: "deploy"},
+	}
+	if err := store.Create(k3v1.GitRepositoryGVK, repo); err != nil {
+		t.Fatalf("Create GitRepository: %v", err)
+	}
+	c := NewController(store, logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}, t.TempDir())
+	c.sync(context.Background(), repo)
+
+	obj, _ := store.Get(k3v1.GitRepositoryGVK, "default", "apps")
+	ready := meta.FindStatusCondition(obj.(*k3v1.GitRepository).Status.Conditions, ConditionReady)
+	if ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != ReasonBuildFailed {
+		t.Fatalf("expected BuildFailed condition, got %+v", ready)
+	}
+}
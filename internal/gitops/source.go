@@ -0,0 +1,189 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// kustomizationFiles 目录中存在其中之一时使用 kustomize 渲染
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// manifest 是渲染得到的一个资源
+type manifest struct {
+	obj    runtime.Object
+	gvk    schema.GroupVersionKind
+	source string // 来源文件（kustomize 渲染时为目录），用于错误信息
+}
+
+// fetch 把 repoURL 的 ref（为空时为远端默认分支）检出到 dir，返回 commit。
+// 每次都是浅拉取 + 强制检出，本地修改与未跟踪文件会被清除；认证信息只出现在命令行参数中，不写入 .git/config
+func fetch(ctx context.Context, dir, repoURL, ref string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+		if _, err := runGit(ctx, dir, "init", "-q"); err != nil {
+			return "", err
+		}
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := runGit(ctx, dir, "fetch", "-q", "--depth", "1", "--no-tags", "--force", repoURL, ref); err != nil {
+		return "", err
+	}
+	if _, err := runGit(ctx, dir, "checkout", "-q", "--force", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	if _, err := runGit(ctx, dir, "clean", "-q", "-fdx"); err != nil {
+		return "", err
+	}
+	out, err := runGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// runGit 在 dir 中执行 git（禁止交互式输入凭据），失败时错误中包含 git 的输出
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(out.String()))
+	}
+	return out.String(), nil
+}
+
+// withCredentials 把用户名/密码写入 http(s) 地址；其他地址原样返回
+func withCredentials(repoURL, username, password string) string {
+	if username == "" && password == "" {
+		return repoURL
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return repoURL
+	}
+	if username == "" {
+		// 只有 token 时用户名任意（GitHub/GitLab/Gitea 均接受）
+		username = "git"
+	}
+	u.User = url.UserPassword(username, password)
+	return u.String()
+}
+
+// redact 从错误信息中去掉密码
+func redact(err error, password string) error {
+	if err == nil || password == "" {
+		return err
+	}
+	msg := strings.ReplaceAll(err.Error(), url.PathEscape(password), "***")
+	return errors.New(strings.ReplaceAll(msg, password, "***"))
+}
+
+// manifestDir 返回仓库中的 manifest 目录，path 不能指向仓库之外
+func manifestDir(repoDir, path string) (string, error) {
+	dir := filepath.Join(repoDir, filepath.FromSlash(path))
+	rel, err := filepath.Rel(repoDir, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q 超出仓库目录", path)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("path %q 不存在", path)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("path %q 不是目录", path)
+	}
+	return dir, nil
+}
+
+// render 渲染 manifest 目录：有 kustomization.yaml 时执行 kustomize build（或 kubectl kustomize），
+// 否则按文件名顺序读取目录（含子目录，跳过隐藏目录）中的 .yaml/.yml/.json 文件
+func render(ctx context.Context, p *parser.Parser, dir string) ([]manifest, error) {
+	for _, name := range kustomizationFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return renderKustomize(ctx, p, dir)
+		}
+	}
+
+	var out []manifest
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		objects, gvks, err := p.ParseYAMLFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		for i, obj := range objects {
+			if gvks[i] == nil {
+				return fmt.Errorf("%s: 第 %d 个对象无法解析 GVK", rel, i+1)
+			}
+			out = append(out, manifest{obj: obj, gvk: *gvks[i], source: filepath.ToSlash(rel)})
+		}
+		return nil
+	})
+	return out, err
+}
+
+// renderKustomize 执行 kustomize build（没有 kustomize 时使用 kubectl kustomize）
+func renderKustomize(ctx context.Context, p *parser.Parser, dir string) ([]manifest, error) {
+	var cmd *exec.Cmd
+	if path, err := exec.LookPath("kustomize"); err == nil {
+		cmd = exec.CommandContext(ctx, path, "build", dir)
+	} else if path, err := exec.LookPath("kubectl"); err == nil {
+		cmd = exec.CommandContext(ctx, path, "kustomize", dir)
+	} else {
+		return nil, fmt.Errorf("目录中有 kustomization.yaml，但没有找到 kustomize 或 kubectl")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("kustomize build: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	objects, gvks, err := p.ParseYAMLManifest(stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]manifest, 0, len(objects))
+	for i, obj := range objects {
+		if gvks[i] == nil {
+			return nil, fmt.Errorf("kustomize 输出的第 %d 个对象无法解析 GVK", i+1)
+		}
+		out = append(out, manifest{obj: obj, gvk: *gvks[i], source: "kustomization"})
+	}
+	return out, nil
+}
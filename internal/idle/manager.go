@@ -0,0 +1,181 @@
+package idle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+)
+
+// hookTimeout 每个进入/退出空闲模式的钩子的最长执行时间（退出时可能需要拉起存储后端容器并等待就绪）
+const hookTimeout = 2 * time.Minute
+
+// Hook 进入与退出空闲模式时执行的动作。进入时按注册顺序执行 Sleep，退出时按相反顺序执行 Wake
+type Hook struct {
+	Name  string
+	Sleep func(ctx context.Context) error
+	Wake  func(ctx context.Context) error
+}
+
+// Manager 记录集群活动（API 请求、Pod 变化、dashboard 会话），超过 after 没有活动且没有进行中的会话时进入空闲模式，
+// 下一次活动时立即退出：Touch 等待所有 Wake 钩子完成后才返回，请求因此总是在恢复后的集群上处理
+type Manager struct {
+	after  time.Duration
+	logger logprovider.Logger
+	now    func() time.Time
+
+	// transition 串行化进入与退出空闲模式
+	transition sync.Mutex
+
+	mu       sync.Mutex
+	hooks    []Hook
+	last     time.Time
+	sessions int
+	idle     bool
+	// idleSince 最近一次进入空闲模式的时间
+	idleSince time.Time
+}
+
+// NewManager 创建空闲管理器；after 为 0 时不会进入空闲模式
+func NewManager(after time.Duration, logger logprovider.Logger) *Manager {
+	m := &Manager{after: after, logger: logger, now: time.Now}
+	m.last = m.now()
+	return m
+}
+
+// Enabled 是否开启了空闲模式
+func (m *Manager) Enabled() bool {
+	return m.after > 0
+}
+
+// Register 注册进入与退出空闲模式时执行的钩子
+func (m *Manager) Register(hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Idle 是否处于空闲模式
+func (m *Manager) Idle() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.idle
+}
+
+// Touch 记录一次活动；处于空闲模式（或正在进入空闲模式）时等待恢复完成后返回
+func (m *Manager) Touch() {
+	m.mu.Lock()
+	m.last = m.now()
+	idle := m.idle
+	m.mu.Unlock()
+	if idle {
+		m.wake()
+	}
+}
+
+// Begin 开始一个长连接会话，会话期间不会进入空闲模式；返回结束会话的函数（只生效一次）
+func (m *Manager) Begin() func() {
+	m.Touch()
+	m.mu.Lock()
+	m.sessions++
+	m.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			m.sessions--
+			m.last = m.now()
+			m.mu.Unlock()
+		})
+	}
+}
+
+// Run 定期检查是否应进入空闲模式，直到 ctx 取消；没有开启空闲模式时直接返回
+func (m *Manager) Run(ctx context.Context) {
+	if !m.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(checkInterval(m.after))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+// checkInterval 检查空闲的周期：after 的 1/10，限制在 1s~30s
+func checkInterval(after time.Duration) time.Duration {
+	return min(max(after/10, time.Second), 30*time.Second)
+}
+
+// check 超过 after 没有活动且没有会话时进入空闲模式
+func (m *Manager) check() {
+	m.transition.Lock()
+	defer m.transition.Unlock()
+
+	m.mu.Lock()
+	now := m.now()
+	if m.idle || m.sessions > 0 || now.Sub(m.last) < m.after {
+		m.mu.Unlock()
+		return
+	}
+	// 先标记为空闲：执行 Sleep 钩子期间到达的请求在 wake 中等待本次进入完成，再恢复
+	m.idle = true
+	m.idleSince = now
+	hooks := append([]Hook(nil), m.hooks...)
+	inactive := now.Sub(m.last).Round(time.Second)
+	m.mu.Unlock()
+
+	m.logger.Infof("%s 没有活动，进入空闲模式", inactive)
+	for _, hook := range hooks {
+		if hook.Sleep == nil {
+			continue
+		}
+		if err := runHook(hook.Sleep); err != nil {
+			m.logger.Warnf("进入空闲模式: %s 失败: %v", hook.Name, err)
+		}
+	}
+}
+
+// wake 按注册的相反顺序执行 Wake 钩子，退出空闲模式
+func (m *Manager) wake() {
+	m.transition.Lock()
+	defer m.transition.Unlock()
+
+	m.mu.Lock()
+	if !m.idle {
+		m.mu.Unlock()
+		return
+	}
+	hooks := append([]Hook(nil), m.hooks...)
+	since := m.idleSince
+	m.mu.Unlock()
+
+	start := m.now()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if hooks[i].Wake == nil {
+			continue
+		}
+		if err := runHook(hooks[i].Wake); err != nil {
+			m.logger.Warnf("退出空闲模式: %s 失败: %v", hooks[i].Name, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.idle = false
+	m.last = m.now()
+	m.mu.Unlock()
+	m.logger.Infof("退出空闲模式（空闲 %s，恢复耗时 %s）", start.Sub(since).Round(time.Second), m.now().Sub(start).Round(time.Millisecond))
+}
+
+// runHook 在 hookTimeout 内执行钩子
+func runHook(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	return fn(ctx)
+}
@@ -0,0 +1,158 @@
+package idle
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestManager 创建使用 fakeClock 的管理器，并注册两个记录调用顺序的钩子
+func newTestManager(after time.Duration) (*Manager, *fakeClock, *[]string) {
+	clock := &fakeClock{now: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)}
+	m := NewManager(after, logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()})
+	m.now = clock.Now
+	m.last = clock.Now()
+
+	var calls []string
+	for _, name := range []string{"a", "b"} {
+		name := name
+		m.Register(Hook{
+			Name:  name,
+			Sleep: func(context.Context) error { calls = append(calls, "sleep-"+name); return nil },
+			Wake:  func(context.Context) error { calls = append(calls, "wake-"+name); return nil },
+		})
+	}
+	return m, clock, &calls
+}
+
+func TestManagerSleepsAfterInactivityAndWakesOnTouch(t *testing.T) {
+	m, clock, calls := newTestManager(time.Minute)
+
+	clock.Advance(30 * time.Second)
+	m.check()
+	if m.Idle() {
+		t.Fatalf("idle before the inactivity period elapsed")
+	}
+
+	clock.Advance(31 * time.Second)
+	m.check()
+	if !m.Idle() {
+		t.Fatalf("not idle after the inactivity period elapsed")
+	}
+	m.check()
+
+	m.Touch()
+	if m.Idle() {
+		t.Fatalf("still idle after Touch")
+	}
+	want := []string{"sleep-a", "sleep-b", "wake-b", "wake-a"}
+	if !reflect.DeepEqual(*calls, want) {
+		t.Fatalf("hook calls = %v, want %v", *calls, want)
+	}
+
+	// Touch 重新开始计时
+	clock.Advance(59 * time.Second)
+	m.check()
+	if m.Idle() {
+		t.Fatalf("idle again before the inactivity period elapsed")
+	}
+}
+
+func TestManagerSessionPreventsIdle(t *testing.T) {
+	m, clock, calls := newTestManager(time.Minute)
+
+	end := m.Begin()
+	clock.Advance(time.Hour)
+	m.check()
+	if m.Idle() {
+		t.Fatalf("idle while a session is open")
+	}
+
+	end()
+	end() // 重复调用只生效一次
+	clock.Advance(59 * time.Second)
+	m.check()
+	if m.Idle() {
+		t.Fatalf("idle right after the session ended")
+	}
+	clock.Advance(2 * time.Second)
+	m.check()
+	if !m.Idle() {
+		t.Fatalf("not idle after the session ended and the inactivity period elapsed")
+	}
+	if len(*calls) != 2 {
+		t.Fatalf("hook calls = %v", *calls)
+	}
+}
+
+func TestManagerDisabled(t *testing.T) {
+	m, clock, _ := newTestManager(0)
+	if m.Enabled() {
+		t.Fatalf("manager with after=0 reports enabled")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.Run(ctx)
+	clock.Advance(24 * time.Hour)
+	m.Touch()
+	if m.Idle() {
+		t.Fatalf("disabled manager went idle")
+	}
+}
+
+func TestPodActivity(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: ""}}
+	bound := pod.DeepCopy()
+	bound.Spec.NodeName = "node-1"
+	running := bound.DeepCopy()
+	running.Status.Phase = corev1.PodRunning
+	deleting := running.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	tests := []struct {
+		name  string
+		event storage.ResourceEvent
+		want  bool
+	}{
+		{"added", storage.ResourceEvent{Type: storage.EventAdded, Object: pod}, true},
+		{"deleted", storage.ResourceEvent{Type: storage.EventDeleted, Object: pod}, true},
+		{"spec changed", storage.ResourceEvent{Type: storage.EventModified, Object: bound, OldObj: pod}, true},
+		{"status only", storage.ResourceEvent{Type: storage.EventModified, Object: running, OldObj: bound}, false},
+		{"deletion started", storage.ResourceEvent{Type: storage.EventModified, Object: deleting, OldObj: running}, true},
+		{"no old object", storage.ResourceEvent{Type: storage.EventModified, Object: running}, true},
+		{"bookmark", storage.ResourceEvent{Type: storage.EventBookmark, Object: pod}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podActivity(tt.event); got != tt.want {
+				t.Fatalf("podActivity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package idle
+
+import (
+	"context"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/bootstrap"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/fx"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var podGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+// hookParams 是注册空闲钩子所需的依赖；ControllerManager 与 DBContainerHandle 仅在对应的进程中存在
+type hookParams struct {
+	fx.In
+
+	Lifecycle   fx.Lifecycle
+	Config      config.Config
+	Logger      logprovider.Logger
+	Store       storage.Store
+	Manager     *Manager
+	Controllers *controller.ControllerManager `optional:"true"`
+	DB          *bootstrap.DBContainerHandle  `optional:"true"`
+}
+
+// Module 提供空闲管理器：apiserver 与 dashboard 通过 apiserver.ActivityTracker 记录请求与会话，
+// Pod 的创建、删除与 spec 变化也算作活动；空闲时降低控制器的活动，idle.stop_db 开启时停止自动拉起的存储后端容器。
+// idle.after 为空或 off 时不会进入空闲模式
+var Module = fx.Module("idle",
+	fx.Provide(
+		func(cfg config.Config, logger logprovider.Logger) (*Manager, error) {
+			settings, err := cfg.Idle.Settings()
+			if err != nil {
+				return nil, err
+			}
+			return NewManager(settings.After, logger), nil
+		},
+		func(m *Manager) apiserver.ActivityTracker { return m },
+	),
+	fx.Invoke(registerHooks),
+)
+
+// registerHooks 注册控制器与存储后端容器的钩子，并在应用运行期间检查空闲、watch Pod 变化
+func registerHooks(p hookParams) {
+	m := p.Manager
+	if !m.Enabled() {
+		return
+	}
+	if p.Controllers != nil {
+		cm := p.Controllers
+		m.Register(Hook{
+			Name:  "controllers",
+			Sleep: func(context.Context) error { cm.SetIdle(true); return nil },
+			Wake:  func(context.Context) error { cm.SetIdle(false); return nil },
+		})
+	}
+	// 存储后端容器最后停止、最先恢复，控制器恢复时存储已经可用
+	if p.Config.Idle.StopDB && p.DB.Suspendable() {
+		db := p.DB
+		m.Register(Hook{
+			Name:  "storage",
+			Sleep: func(ctx context.Context) error { return db.Suspend(ctx, p.Logger) },
+			Wake:  func(ctx context.Context) error { return db.Resume(ctx, p.Logger, p.Store) },
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				go watchPods(ctx, p.Store, m, p.Logger)
+				m.Run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+			case <-stopCtx.Done():
+			}
+			return nil
+		},
+	})
+}
+
+// watchPods 把 Pod 的创建、删除与 spec 变化记录为活动；只有 status 变化（运行时上报状态）不算
+func watchPods(ctx context.Context, store storage.Store, m *Manager, logger logprovider.Logger) {
+	watchCh, err := store.Watch(podGVK, "", "")
+	if err != nil {
+		logger.Warnf("空闲模式: watch Pod 失败，Pod 变化不计为活动: %v", err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watchCh:
+			if !ok {
+				return
+			}
+			if podActivity(event) {
+				m.Touch()
+			}
+		}
+	}
+}
+
+// podActivity 事件是否为 Pod 的创建、删除或 spec 变化
+func podActivity(event storage.ResourceEvent) bool {
+	switch event.Type {
+	case storage.EventAdded, storage.EventDeleted:
+		return true
+	case storage.EventModified:
+		pod, ok := event.Object.(*corev1.Pod)
+		old, hasOld := event.OldObj.(*corev1.Pod)
+		if !ok || !hasOld {
+			return ok
+		}
+		return !equality.Semantic.DeepEqual(pod.Spec, old.Spec) || pod.DeletionTimestamp != nil && old.DeletionTimestamp == nil
+	}
+	return false
+}
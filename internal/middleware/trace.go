@@ -32,6 +32,7 @@ func TraceIDMiddlewareFiber(logger *zap.Logger) fiber.Handler {
 		loggerWithTrace.Info("Incoming request",
 			zap.String("method", c.Method()),
 			zap.String("url", c.Path()),
+			zap.String("ip", c.IP()),
 		)
 
 		// 处理请求
@@ -0,0 +1,239 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// DryRun 模式
+const (
+	DryRunNone   = "none"
+	DryRunClient = "client"
+	DryRunServer = "server"
+)
+
+// ExportKinds 默认导出的资源，按依赖顺序排列（配置先于工作负载）
+var ExportKinds = []string{"configmaps", "secrets", "services", "deployments", "statefulsets", "daemonsets", "pods"}
+
+// ExportOptions 是 export 的选项
+type ExportOptions struct {
+	// Kinds 需要导出的资源（复数小写；为空使用 ExportKinds，Node 不支持导出）
+	Kinds []string
+	// Namespaces 只导出这些本地 namespace（为空表示全部）
+	Namespaces []string
+	// NamespaceMap 本地 namespace -> 目标 namespace 的映射
+	NamespaceMap map[string]string
+	// DryRun none/client/server
+	DryRun string
+	// FieldManager server-side apply 使用的 field manager
+	FieldManager string
+	// IncludeImported 是否导出 import 模式镜像的对象（默认跳过，避免回写来源集群）
+	IncludeImported bool
+}
+
+// ExportResult 记录单个对象的导出结果
+type ExportResult struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Action applied / skipped / dry-run
+	Action string
+	Reason string
+	Err    error
+}
+
+// Export 遍历本地 Store，把支持的资源通过 server-side apply 提交到目标集群
+func Export(ctx context.Context, store storage.Store, target dynamic.Interface, opts ExportOptions, report func(ExportResult)) error {
+	kinds := append([]string(nil), opts.Kinds...)
+	if len(kinds) == 0 {
+		kinds = append(kinds, ExportKinds...)
+	}
+	for _, k := range kinds {
+		spec, ok := supportedKinds[k]
+		if !ok || spec.clusterScoped {
+			return fmt.Errorf("不支持导出的资源类型: %s", k)
+		}
+	}
+	if opts.DryRun == "" {
+		opts.DryRun = DryRunNone
+	}
+	switch opts.DryRun {
+	case DryRunNone, DryRunClient, DryRunServer:
+	default:
+		return fmt.Errorf("无效的 dry-run 模式: %s（支持 none/client/server）", opts.DryRun)
+	}
+	if opts.FieldManager == "" {
+		opts.FieldManager = "k3-export"
+	}
+
+	allowedNS := map[string]bool{}
+	for _, ns := range opts.Namespaces {
+		allowedNS[ns] = true
+	}
+
+	// 按依赖顺序导出
+	sort.SliceStable(kinds, func(i, j int) bool { return exportOrder(kinds[i]) < exportOrder(kinds[j]) })
+
+	for _, kind := range kinds {
+		spec := supportedKinds[kind]
+		objects, err := store.List(spec.gvk, "")
+		if err != nil {
+			return fmt.Errorf("列出本地 %s 失败: %w", spec.gvk.Kind, err)
+		}
+		sort.Slice(objects, func(i, j int) bool { return objectSortKey(objects[i]) < objectSortKey(objects[j]) })
+
+		resource := spec.gvk.GroupVersion().WithResource(kind)
+		for _, obj := range objects {
+			meta, ok := obj.(metav1.Object)
+			if !ok {
+				continue
+			}
+			result := ExportResult{Kind: spec.gvk.Kind, Namespace: meta.GetNamespace(), Name: meta.GetName()}
+
+			if len(allowedNS) > 0 && !allowedNS[meta.GetNamespace()] {
+				continue
+			}
+			if reason := skipReason(meta, opts); reason != "" {
+				result.Action, result.Reason = "skipped", reason
+				report(result)
+				continue
+			}
+
+			u, err := toExportUnstructured(obj, spec.gvk, opts.NamespaceMap)
+			if err != nil {
+				result.Err = err
+				report(result)
+				continue
+			}
+			result.Namespace = u.GetNamespace()
+
+			if opts.DryRun == DryRunClient {
+				result.Action = "dry-run"
+				report(result)
+				continue
+			}
+
+			data, err := json.Marshal(u.Object)
+			if err != nil {
+				result.Err = err
+				report(result)
+				continue
+			}
+			force := true
+			patchOpts := metav1.PatchOptions{FieldManager: opts.FieldManager, Force: &force}
+			if opts.DryRun == DryRunServer {
+				patchOpts.DryRun = []string{metav1.DryRunAll}
+			}
+			if _, err := target.Resource(resource).Namespace(u.GetNamespace()).Patch(ctx, u.GetName(), types.ApplyPatchType, data, patchOpts); err != nil {
+				result.Err = err
+			} else if opts.DryRun == DryRunServer {
+				result.Action = "dry-run"
+			} else {
+				result.Action = "applied"
+			}
+			report(result)
+		}
+	}
+	return nil
+}
+
+// skipReason 返回对象不应导出的原因（空字符串表示可以导出）
+func skipReason(meta metav1.Object, opts ExportOptions) string {
+	if !opts.IncludeImported && IsImported(meta) {
+		return "imported from " + meta.GetAnnotations()[AnnotationImportedFrom]
+	}
+	// 由 controller 创建的对象（例如 Deployment 的 Pod）由目标集群自行生成
+	if len(meta.GetOwnerReferences()) > 0 {
+		return "owned by " + meta.GetOwnerReferences()[0].Kind
+	}
+	return ""
+}
+
+// toExportUnstructured 转换为 unstructured，并去掉只在本地有意义的字段
+func toExportUnstructured(obj runtime.Object, gvk schema.GroupVersionKind, nsMap map[string]string) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("转换对象失败: %w", err)
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+
+	for _, field := range [][]string{
+		{"metadata", "uid"},
+		{"metadata", "resourceVersion"},
+		{"metadata", "generation"},
+		{"metadata", "creationTimestamp"},
+		{"metadata", "managedFields"},
+		{"metadata", "ownerReferences"},
+		{"status"},
+	} {
+		unstructured.RemoveNestedField(u.Object, field...)
+	}
+
+	// Service 的 clusterIP 由目标集群分配（headless 的 None 需要保留）
+	if u.GetKind() == "Service" {
+		if ip, _, _ := unstructured.NestedString(u.Object, "spec", "clusterIP"); ip != "None" {
+			unstructured.RemoveNestedField(u.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(u.Object, "spec", "clusterIPs")
+		}
+	}
+	// Pod 的 nodeName 指向本地节点，交给目标集群重新调度
+	if u.GetKind() == "Pod" {
+		unstructured.RemoveNestedField(u.Object, "spec", "nodeName")
+	}
+
+	ns := u.GetNamespace()
+	if ns == "" {
+		ns = metav1.NamespaceDefault
+	}
+	if mapped, ok := nsMap[ns]; ok && mapped != "" {
+		ns = mapped
+	}
+	u.SetNamespace(ns)
+	return u, nil
+}
+
+// ParseNamespaceMap 解析 "src=dst,src2=dst2" 形式的 namespace 映射
+func ParseNamespaceMap(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		src, dst, ok := strings.Cut(pair, "=")
+		src, dst = strings.TrimSpace(src), strings.TrimSpace(dst)
+		if !ok || src == "" || dst == "" {
+			return nil, fmt.Errorf("无效的 namespace 映射: %q（格式 src=dst）", pair)
+		}
+		out[src] = dst
+	}
+	return out, nil
+}
+
+func exportOrder(kind string) int {
+	for i, k := range ExportKinds {
+		if k == kind {
+			return i
+		}
+	}
+	return len(ExportKinds)
+}
+
+func objectSortKey(obj runtime.Object) string {
+	if meta, ok := obj.(metav1.Object); ok {
+		return meta.GetNamespace() + "/" + meta.GetName()
+	}
+	return ""
+}
@@ -0,0 +1,365 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// AnnotationImportedFrom 标记由 import 模式从真实集群镜像而来的对象，值为来源集群（kubeconfig context 或 server 地址）。
+// 带有该注解的对象是只读镜像：本地 controller 不会对其做调度/拉起容器/扩缩容。
+const AnnotationImportedFrom = "k3.io/imported-from"
+
+// IsImported 判断对象是否为 import 模式镜像的只读对象
+func IsImported(obj metav1.Object) bool {
+	if obj == nil {
+		return false
+	}
+	_, ok := obj.GetAnnotations()[AnnotationImportedFrom]
+	return ok
+}
+
+// Settings 是 import 模式的配置
+type Settings struct {
+	// Kubeconfig kubeconfig 路径（为空时依次使用 $KUBECONFIG、~/.kube/config）
+	Kubeconfig string
+	// Context 使用的 kubeconfig context（为空使用 current-context）
+	Context string
+	// Namespaces 需要镜像的 namespace（为空表示全部）
+	Namespaces []string
+	// Kinds 需要镜像的资源（复数小写，例如 pods/deployments；为空使用 DefaultKinds）
+	Kinds []string
+	// Resync informer 的周期性全量同步间隔
+	Resync time.Duration
+}
+
+// DefaultKinds 默认镜像的资源（Secret/Node 需显式指定）
+var DefaultKinds = []string{"deployments", "statefulsets", "daemonsets", "pods", "services", "configmaps"}
+
+// kindSpec 描述一种可镜像的资源
+type kindSpec struct {
+	gvk           schema.GroupVersionKind
+	clusterScoped bool
+	informer      func(f informers.SharedInformerFactory) cache.SharedIndexInformer
+}
+
+var supportedKinds = map[string]kindSpec{
+	"pods": {
+		gvk: schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Pods().Informer()
+		},
+	},
+	"services": {
+		gvk: schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Services().Informer()
+		},
+	},
+	"configmaps": {
+		gvk: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().ConfigMaps().Informer()
+		},
+	},
+	"secrets": {
+		gvk: schema.GroupVersionKind{Version: "v1", Kind: "Secret"},
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Secrets().Informer()
+		},
+	},
+	"nodes": {
+		gvk:           schema.GroupVersionKind{Version: "v1", Kind: "Node"},
+		clusterScoped: true,
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Core().V1().Nodes().Informer()
+		},
+	},
+	"deployments": {
+		gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Apps().V1().Deployments().Informer()
+		},
+	},
+	"statefulsets": {
+		gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Apps().V1().StatefulSets().Informer()
+		},
+	},
+	"daemonsets": {
+		gvk: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		informer: func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return f.Apps().V1().DaemonSets().Informer()
+		},
+	},
+}
+
+// Importer 通过 client-go watch 真实集群，把选定资源最终一致地镜像到本地 Store（单向、只读）
+type Importer struct {
+	store     storage.Store
+	logger    logprovider.Logger
+	settings  Settings
+	clientset kubernetes.Interface
+	source    string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// LoadRESTConfig 按 kubeconfig/context 加载 client-go 配置，并返回用于标识来源集群的名称
+func LoadRESTConfig(kubeconfig, context string) (*rest.Config, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path := expandHome(kubeconfig); path != "" {
+		rules.ExplicitPath = path
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	restCfg, err := loader.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("加载 kubeconfig 失败: %w", err)
+	}
+
+	source := context
+	if source == "" {
+		if raw, err := loader.RawConfig(); err == nil {
+			source = raw.CurrentContext
+		}
+	}
+	if source == "" {
+		source = restCfg.Host
+	}
+	return restCfg, source, nil
+}
+
+// NewImporter 创建 Importer
+func NewImporter(store storage.Store, logger logprovider.Logger, settings Settings) (*Importer, error) {
+	if len(settings.Kinds) == 0 {
+		settings.Kinds = DefaultKinds
+	}
+	for _, k := range settings.Kinds {
+		if _, ok := supportedKinds[k]; !ok {
+			return nil, fmt.Errorf("不支持的资源类型: %s", k)
+		}
+	}
+	if settings.Resync <= 0 {
+		settings.Resync = 10 * time.Minute
+	}
+
+	restCfg, source, err := LoadRESTConfig(settings.Kubeconfig, settings.Context)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Kubernetes 客户端失败: %w", err)
+	}
+
+	return &Importer{
+		store:     store,
+		logger:    logger,
+		settings:  settings,
+		clientset: clientset,
+		source:    source,
+	}, nil
+}
+
+// Start 启动 informer 并注册事件回调（首次同步在后台完成）
+func (im *Importer) Start(ctx context.Context) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	bgCtx, cancel := context.WithCancel(context.Background())
+	im.cancel = cancel
+
+	namespaces := im.settings.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	type syncTarget struct {
+		spec      kindSpec
+		namespace string
+		informer  cache.SharedIndexInformer
+	}
+	var targets []syncTarget
+
+	for i, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(im.clientset, im.settings.Resync, informers.WithNamespace(ns))
+		for _, kind := range im.settings.Kinds {
+			spec := supportedKinds[kind]
+			// 集群级资源只需要注册一次
+			if spec.clusterScoped && i > 0 {
+				continue
+			}
+			informer := spec.informer(factory)
+			if _, err := informer.AddEventHandler(im.handlerFor(spec.gvk)); err != nil {
+				cancel()
+				return fmt.Errorf("注册 %s 事件回调失败: %w", kind, err)
+			}
+			targetNS := ns
+			if spec.clusterScoped {
+				targetNS = ""
+			}
+			targets = append(targets, syncTarget{spec: spec, namespace: targetNS, informer: informer})
+		}
+		factory.Start(bgCtx.Done())
+	}
+
+	go func() {
+		for _, t := range targets {
+			if !cache.WaitForCacheSync(bgCtx.Done(), t.informer.HasSynced) {
+				return
+			}
+			im.prune(t.spec.gvk, t.namespace, t.informer.GetStore())
+		}
+		im.logger.Infof("import: 首次同步完成 (source=%s)", im.source)
+	}()
+
+	im.logger.Infof("import: 已启动 (source=%s, namespaces=%s, kinds=%s)",
+		im.source, strings.Join(im.settings.Namespaces, ","), strings.Join(im.settings.Kinds, ","))
+	return nil
+}
+
+// Stop 停止所有 informer
+func (im *Importer) Stop(ctx context.Context) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	if im.cancel != nil {
+		im.cancel()
+		im.cancel = nil
+	}
+	im.logger.Info("import: 已停止")
+	return nil
+}
+
+func (im *Importer) handlerFor(gvk schema.GroupVersionKind) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			im.upsert(gvk, obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			im.upsert(gvk, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			meta, ok := obj.(metav1.Object)
+			if !ok {
+				return
+			}
+			im.delete(gvk, meta.GetNamespace(), meta.GetName())
+		},
+	}
+}
+
+// delete 删除远端已删除对象的本地镜像；本地同名对象不是镜像（没有 AnnotationImportedFrom）时保留
+func (im *Importer) delete(gvk schema.GroupVersionKind, namespace, name string) {
+	existing, err := im.store.Get(gvk, namespace, name)
+	if err != nil {
+		return
+	}
+	if meta, ok := existing.(metav1.Object); !ok || !IsImported(meta) {
+		im.logger.Debugf("import: 本地 %s %s/%s 不是镜像，不删除", gvk.Kind, namespace, name)
+		return
+	}
+	if err := im.store.Delete(gvk, namespace, name); err != nil {
+		im.logger.Debugf("import: 删除 %s %s/%s 失败: %v", gvk.Kind, namespace, name, err)
+	}
+}
+
+// upsert 把远端对象写入本地 Store（已存在则更新）；本地已有同名的非镜像对象时跳过，不覆盖本地对象
+func (im *Importer) upsert(gvk schema.GroupVersionKind, obj interface{}) {
+	rObj, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+	local := im.sanitize(gvk, rObj)
+	meta, ok := local.(metav1.Object)
+	if !ok {
+		return
+	}
+
+	if existing, err := im.store.Get(gvk, meta.GetNamespace(), meta.GetName()); err == nil {
+		if existingMeta, ok := existing.(metav1.Object); !ok || !IsImported(existingMeta) {
+			im.logger.Warnf("import: 本地已有同名的 %s %s/%s（不是镜像），跳过", gvk.Kind, meta.GetNamespace(), meta.GetName())
+			return
+		}
+		if err := im.store.Update(gvk, local); err != nil {
+			im.logger.Warnf("import: 更新 %s %s/%s 失败: %v", gvk.Kind, meta.GetNamespace(), meta.GetName(), err)
+		}
+		return
+	}
+	if err := im.store.Create(gvk, local); err != nil {
+		im.logger.Warnf("import: 写入 %s %s/%s 失败: %v", gvk.Kind, meta.GetNamespace(), meta.GetName(), err)
+	}
+}
+
+// sanitize 复制远端对象并去掉只对远端有意义的字段，同时打上来源注解
+func (im *Importer) sanitize(gvk schema.GroupVersionKind, obj runtime.Object) runtime.Object {
+	local := obj.DeepCopyObject()
+	local.GetObjectKind().SetGroupVersionKind(gvk)
+	if meta, ok := local.(metav1.Object); ok {
+		meta.SetResourceVersion("")
+		meta.SetManagedFields(nil)
+		annotations := meta.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[AnnotationImportedFrom] = im.source
+		meta.SetAnnotations(annotations)
+	}
+	return local
+}
+
+// prune 删除本地已不存在于远端的镜像对象（例如上次运行之后在远端被删除的对象）
+func (im *Importer) prune(gvk schema.GroupVersionKind, namespace string, remote cache.Store) {
+	objects, err := im.store.List(gvk, namespace)
+	if err != nil {
+		im.logger.Warnf("import: 列出本地 %s 失败: %v", gvk.Kind, err)
+		return
+	}
+	for _, obj := range objects {
+		meta, ok := obj.(metav1.Object)
+		if !ok || meta.GetAnnotations()[AnnotationImportedFrom] != im.source {
+			continue
+		}
+		key := meta.GetName()
+		if meta.GetNamespace() != "" {
+			key = meta.GetNamespace() + "/" + key
+		}
+		if _, exists, _ := remote.GetByKey(key); exists {
+			continue
+		}
+		if err := im.store.Delete(gvk, meta.GetNamespace(), meta.GetName()); err == nil {
+			im.logger.Infof("import: 清理远端已删除的 %s %s", gvk.Kind, key)
+		}
+	}
+}
+
+func expandHome(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
@@ -0,0 +1,155 @@
+package mirror
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var (
+	configMapGVK  = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+)
+
+// newTestImporter 创建使用 fake clientset 的 Importer（来源集群为 remote）
+func newTestImporter(store storage.Store, clientset *fake.Clientset, kinds ...string) *Importer {
+	return &Importer{
+		store:     store,
+		logger:    logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()},
+		settings:  Settings{Namespaces: []string{"default"}, Kinds: kinds, Resync: time.Hour},
+		clientset: clientset,
+		source:    "remote",
+	}
+}
+
+// waitFor 每 10ms 检查一次，5s 内不满足时测试失败
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", desc)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func configMap(name string, annotations map[string]string, data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations},
+		Data:       map[string]string{"k": data},
+	}
+}
+
+func TestImporterMirrorsAndPrunes(t *testing.T) {
+	store := storage.NewMemoryStore()
+	imported := map[string]string{AnnotationImportedFrom: "remote"}
+	// 上次运行留下、远端已删除的镜像会被清理；本地对象保留
+	for _, cm := range []*corev1.ConfigMap{configMap("stale", imported, "old"), configMap("local", nil, "local")} {
+		if err := store.Create(configMapGVK, cm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replicas := int32(2)
+	clientset := fake.NewSimpleClientset(
+		configMap("app", nil, "remote"),
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: appsv1.DeploymentSpec{Replicas: &replicas}},
+	)
+	im := newTestImporter(store, clientset, "configmaps", "deployments")
+	if err := im.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer im.Stop(context.Background())
+
+	waitFor(t, "mirrored deployment", func() bool {
+		obj, err := store.Get(deploymentGVK, "default", "web")
+		return err == nil && IsImported(obj.(metav1.Object))
+	})
+	waitFor(t, "stale mirror pruned", func() bool {
+		_, err := store.Get(configMapGVK, "default", "stale")
+		return err != nil
+	})
+	obj, err := store.Get(configMapGVK, "default", "app")
+	if err != nil {
+		t.Fatalf("mirrored configmap: %v", err)
+	}
+	if got := obj.(*corev1.ConfigMap); got.Annotations[AnnotationImportedFrom] != "remote" || got.Data["k"] != "remote" {
+		t.Fatalf("mirrored configmap = %+v", got.ObjectMeta)
+	}
+
+	// 远端更新同步到镜像
+	ctx := context.Background()
+	if _, err := clientset.CoreV1().ConfigMaps("default").Update(ctx, configMap("app", nil, "updated"), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "mirror updated", func() bool {
+		obj, err := store.Get(configMapGVK, "default", "app")
+		return err == nil && obj.(*corev1.ConfigMap).Data["k"] == "updated"
+	})
+
+	// 远端删除后镜像被删除
+	if err := clientset.AppsV1().Deployments("default").Delete(ctx, "web", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "mirror deleted", func() bool {
+		_, err := store.Get(deploymentGVK, "default", "web")
+		return err != nil
+	})
+	if _, err := store.Get(configMapGVK, "default", "local"); err != nil {
+		t.Fatalf("local configmap removed: %v", err)
+	}
+}
+
+func TestImporterKeepsLocalObjects(t *testing.T) {
+	store := storage.NewMemoryStore()
+	if err := store.Create(configMapGVK, configMap("shared", nil, "local")); err != nil {
+		t.Fatal(err)
+	}
+	clientset := fake.NewSimpleClientset(configMap("shared", nil, "remote"), configMap("marker", nil, "remote"))
+	im := newTestImporter(store, clientset, "configmaps")
+	if err := im.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer im.Stop(context.Background())
+
+	// marker 出现时 shared 的事件已经处理过
+	waitFor(t, "marker mirrored", func() bool {
+		_, err := store.Get(configMapGVK, "default", "marker")
+		return err == nil
+	})
+	assertLocal := func(when string) {
+		t.Helper()
+		obj, err := store.Get(configMapGVK, "default", "shared")
+		if err != nil {
+			t.Fatalf("%s: local configmap removed: %v", when, err)
+		}
+		if cm := obj.(*corev1.ConfigMap); IsImported(cm) || cm.Data["k"] != "local" {
+			t.Fatalf("%s: local configmap overwritten: %+v %v", when, cm.Annotations, cm.Data)
+		}
+	}
+	assertLocal("after sync")
+
+	// 远端删除同名对象不删除本地对象
+	ctx := context.Background()
+	if err := clientset.CoreV1().ConfigMaps("default").Delete(ctx, "shared", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientset.CoreV1().ConfigMaps("default").Delete(ctx, "marker", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "marker deleted", func() bool {
+		_, err := store.Get(configMapGVK, "default", "marker")
+		return err != nil
+	})
+	assertLocal("after remote delete")
+}
@@ -0,0 +1,68 @@
+package network
+
+import (
+	"net"
+)
+
+// UsableIP 判断 ip 能否作为节点地址对外公布：排除回环、未指定、组播与链路本地地址
+// （IPv6 链路本地地址必须带网卡名才能连接，其他节点拿到也用不了）
+func UsableIP(ip net.IP) bool {
+	if ip == nil || ip.IsUnspecified() || ip.IsLoopback() || ip.IsMulticast() {
+		return false
+	}
+	return !ip.IsLinkLocalUnicast()
+}
+
+// LocalIPs 返回本机已启用的非回环网卡上可以对外公布的地址（双栈时 IPv4 与 IPv6 都有），IPv4 在前
+func LocalIPs() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var v4, v6 []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			var ip net.IP
+			switch v := a.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+			if !UsableIP(ip) {
+				continue
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				v4 = append(v4, ip4)
+			} else {
+				v6 = append(v6, ip)
+			}
+		}
+	}
+	return append(v4, v6...)
+}
+
+// PreferFamily 把与 preferred 同一地址族的地址排到前面（各自保持原有顺序），preferred 为空时按 IPv4 在前排列
+func PreferFamily(addrs []net.IP, preferred net.IP) []net.IP {
+	wantV6 := preferred != nil && preferred.To4() == nil
+	first := make([]net.IP, 0, len(addrs))
+	var rest []net.IP
+	for _, ip := range addrs {
+		if ip == nil {
+			continue
+		}
+		if (ip.To4() == nil) == wantV6 {
+			first = append(first, ip)
+		} else {
+			rest = append(rest, ip)
+		}
+	}
+	return append(first, rest...)
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package network
+
+import (
+	"errors"
+
+	"github.com/grandcat/zeroconf"
+)
+
+// errMDNSUnsupported 表示当前平台不支持 mDNS（服务会降级为只做健康检查和自身节点上报）
+var errMDNSUnsupported = errors.New("mdns is not supported on this platform")
+
+// registerMDNS 通过 mDNS 广播本节点
+func registerMDNS(instance, service, domain string, port int, txt []string) (*zeroconf.Server, error) {
+	return zeroconf.Register(instance, service, domain, port, txt, nil)
+}
+
+// newMDNSResolver 创建 mDNS 发现用的 resolver
+func newMDNSResolver() (*zeroconf.Resolver, error) {
+	return zeroconf.NewResolver(nil)
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package network
+
+import (
+	"errors"
+
+	"github.com/grandcat/zeroconf"
+)
+
+// errMDNSUnsupported 表示当前平台不支持 mDNS（服务会降级为只做健康检查和自身节点上报）。
+// Windows 上系统自带的 mDNS 响应器会占用 5353 端口，多网卡的组播也不可靠，因此不启用。
+var errMDNSUnsupported = errors.New("mdns is not supported on windows")
+
+func registerMDNS(instance, service, domain string, port int, txt []string) (*zeroconf.Server, error) {
+	return nil, errMDNSUnsupported
+}
+
+func newMDNSResolver() (*zeroconf.Resolver, error) {
+	return nil, errMDNSUnsupported
+}
@@ -0,0 +1,270 @@
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 系统 ARP/neighbor 表读取：供 `network export` 一次性导出，以及 controller 的 LAN inventory 周期性同步 Device 资源。
+// 只读取系统已经“学到”的邻居，不做端口扫描和全网段探测。
+
+// Neighbor 是邻居表中的一条记录
+type Neighbor struct {
+	IP   string
+	Name string // best-effort, may be "?"
+	MAC  string // best-effort
+}
+
+var (
+	arpDarwinRe = regexp.MustCompile(`^(\S+)\s+\((\d+\.\d+\.\d+\.\d+)\)\s+at\s+(.+?)\s+on\s+(\S+)`)
+	ipNeighRe   = regexp.MustCompile(`^(\d+\.\d+\.\d+\.\d+)\s+dev\s+(\S+)\b`)
+	macRe       = regexp.MustCompile(`(?i)([0-9a-f]{2}:){5}[0-9a-f]{2}`)
+	// Windows arp -a: "  192.168.1.1           aa-bb-cc-dd-ee-ff     dynamic"
+	arpWindowsRe = regexp.MustCompile(`(?i)^(\d+\.\d+\.\d+\.\d+)\s+((?:[0-9a-f]{2}-){5}[0-9a-f]{2})\s+(\S+)`)
+)
+
+// ReadNeighborTable 读取系统邻居表（Linux: ip neigh / arp -a，macOS/Windows: arp -a）
+func ReadNeighborTable(ctx context.Context) ([]Neighbor, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.CommandContext(ctx, "arp", "-a").Output()
+		if err != nil {
+			return nil, err
+		}
+		return parseDarwinARP(string(out)), nil
+	case "linux":
+		out, err := exec.CommandContext(ctx, "sh", "-c", "ip neigh show 2>/dev/null || arp -a 2>/dev/null").Output()
+		if err != nil {
+			return nil, err
+		}
+		return parseLinuxNeighbors(string(out)), nil
+	case "windows":
+		out, err := exec.CommandContext(ctx, "arp", "-a").Output()
+		if err != nil {
+			return nil, err
+		}
+		return parseWindowsARP(string(out)), nil
+	default:
+		out, err := exec.CommandContext(ctx, "arp", "-a").Output()
+		if err != nil {
+			return nil, err
+		}
+		return parseDarwinARP(string(out)), nil
+	}
+}
+
+func parseDarwinARP(out string) []Neighbor {
+	lines := strings.Split(out, "\n")
+	entries := make([]Neighbor, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		m := arpDarwinRe.FindStringSubmatch(line)
+		if len(m) < 4 {
+			continue
+		}
+		host := strings.TrimSpace(m[1])
+		ip := strings.TrimSpace(m[2])
+		hw := strings.ToLower(strings.TrimSpace(m[3]))
+		// Skip unresolved neighbors like: "at (incomplete)".
+		if strings.Contains(hw, "incomplete") {
+			continue
+		}
+		mac := strings.ToLower(macRe.FindString(hw))
+		if net.ParseIP(ip).To4() == nil {
+			continue
+		}
+		entries = append(entries, Neighbor{IP: ip, Name: host, MAC: mac})
+	}
+	return entries
+}
+
+// parseWindowsARP 解析 Windows 的 arp -a 输出（没有主机名，MAC 以 "-" 分隔）。
+// 类型列（dynamic/static）会随系统语言变化，因此按地址跳过广播和组播条目。
+func parseWindowsARP(out string) []Neighbor {
+	lines := strings.Split(out, "\n")
+	entries := make([]Neighbor, 0, len(lines))
+	for _, line := range lines {
+		m := arpWindowsRe.FindStringSubmatch(strings.TrimSpace(line))
+		if len(m) < 4 {
+			continue
+		}
+		ip := net.ParseIP(m[1]).To4()
+		if ip == nil || ip.IsMulticast() || ip.Equal(net.IPv4bcast) {
+			continue
+		}
+		mac := strings.ToLower(strings.ReplaceAll(m[2], "-", ":"))
+		if mac == "ff:ff:ff:ff:ff:ff" {
+			continue
+		}
+		entries = append(entries, Neighbor{IP: m[1], Name: "", MAC: mac})
+	}
+	return entries
+}
+
+func parseLinuxNeighbors(out string) []Neighbor {
+	lines := strings.Split(out, "\n")
+	entries := make([]Neighbor, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		upper := strings.ToUpper(line)
+		if strings.Contains(upper, " INCOMPLETE") || strings.Contains(upper, " FAILED") {
+			continue
+		}
+		mac := strings.ToLower(macRe.FindString(line))
+		if m := ipNeighRe.FindStringSubmatch(line); len(m) >= 2 {
+			ip := strings.TrimSpace(m[1])
+			if net.ParseIP(ip).To4() == nil {
+				continue
+			}
+			entries = append(entries, Neighbor{IP: ip, Name: "", MAC: mac})
+			continue
+		}
+		if m := arpDarwinRe.FindStringSubmatch(line); len(m) >= 3 {
+			ip := strings.TrimSpace(m[2])
+			host := strings.TrimSpace(m[1])
+			if net.ParseIP(ip).To4() == nil {
+				continue
+			}
+			entries = append(entries, Neighbor{IP: ip, Name: host, MAC: mac})
+			continue
+		}
+	}
+	return entries
+}
+
+// NormalizeHostToken 去掉邻居表中的占位主机名（"?"）和末尾的 "."
+func NormalizeHostToken(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "?" {
+		return ""
+	}
+	return strings.TrimSuffix(s, ".")
+}
+
+// ReverseLookup 反向解析 IP 的主机名，失败或超时返回空字符串
+func ReverseLookup(ctx context.Context, ip string, timeout time.Duration) string {
+	if strings.TrimSpace(ip) == "" {
+		return ""
+	}
+	if timeout <= 0 {
+		timeout = 250 * time.Millisecond
+	}
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	r := net.Resolver{}
+	names, err := r.LookupAddr(cctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimSpace(names[0]), ".")
+}
+
+// TargetSubnets 返回要导出的 IPv4 网段：指定了 cidrs 时解析它们，否则取本机已启用的非 loopback 网卡所在网段（去重）
+func TargetSubnets(cidrs []string) ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	if len(cidrs) > 0 {
+		for _, s := range cidrs {
+			_, n, err := net.ParseCIDR(strings.TrimSpace(s))
+			if err != nil {
+				return nil, err
+			}
+			if n == nil || n.IP == nil || n.IP.To4() == nil {
+				continue
+			}
+			out = append(out, n)
+		}
+		return dedupeSubnets(out), nil
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok || ipnet == nil || ipnet.IP == nil {
+				continue
+			}
+			ip4 := ipnet.IP.To4()
+			if ip4 == nil || ip4.IsLoopback() {
+				continue
+			}
+			out = append(out, &net.IPNet{IP: ip4.Mask(ipnet.Mask), Mask: ipnet.Mask})
+		}
+	}
+	return dedupeSubnets(out), nil
+}
+
+func dedupeSubnets(in []*net.IPNet) []*net.IPNet {
+	seen := map[string]struct{}{}
+	out := make([]*net.IPNet, 0, len(in))
+	for _, n := range in {
+		if n == nil {
+			continue
+		}
+		k := n.String()
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		out = append(out, n)
+	}
+	return out
+}
+
+// InSubnets 判断 ip 是否是某个网段中的主机地址（排除网络地址和广播地址）
+func InSubnets(ip net.IP, subnets []*net.IPNet) bool {
+	for _, n := range subnets {
+		if n != nil && n.Contains(ip) && !isNetworkOrBroadcastIPv4(ip, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNetworkOrBroadcastIPv4(ip net.IP, n *net.IPNet) bool {
+	if n == nil || n.IP == nil || n.Mask == nil {
+		return false
+	}
+	ip4 := ip.To4()
+	net4 := n.IP.To4()
+	if ip4 == nil || net4 == nil {
+		return false
+	}
+	ones, bits := n.Mask.Size()
+	if bits != 32 {
+		return false
+	}
+	// /31,/32 don't have meaningful broadcast semantics for our filtering.
+	if ones >= 31 {
+		return false
+	}
+	netU := binary.BigEndian.Uint32(net4.Mask(n.Mask))
+	maskU := binary.BigEndian.Uint32(n.Mask)
+	bcastU := netU | ^maskU
+	u := binary.BigEndian.Uint32(ip4)
+	return u == netU || u == bcastU
+}
@@ -0,0 +1,62 @@
+package network
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseNeighborTables(t *testing.T) {
+	darwin := `router.lan (192.168.1.1) at aa:bb:cc:dd:ee:01 on en0 ifscope [ethernet]
+? (192.168.1.23) at 11:22:33:44:55:66 on en0 ifscope [ethernet]
+? (192.168.1.99) at (incomplete) on en0 ifscope [ethernet]`
+	if got := parseDarwinARP(darwin); !reflect.DeepEqual(got, []Neighbor{
+		{IP: "192.168.1.1", Name: "router.lan", MAC: "aa:bb:cc:dd:ee:01"},
+		{IP: "192.168.1.23", Name: "?", MAC: "11:22:33:44:55:66"},
+	}) {
+		t.Fatalf("darwin: %+v", got)
+	}
+
+	linux := `192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE
+192.168.1.50 dev eth0  INCOMPLETE
+fe80::1 dev eth0 lladdr aa:bb:cc:dd:ee:ff router STALE`
+	if got := parseLinuxNeighbors(linux); !reflect.DeepEqual(got, []Neighbor{
+		{IP: "192.168.1.1", MAC: "aa:bb:cc:dd:ee:ff"},
+	}) {
+		t.Fatalf("linux: %+v", got)
+	}
+
+	windows := `
+Interface: 192.168.1.10 --- 0x4
+  Internet Address      Physical Address      Type
+  192.168.1.1           AA-BB-CC-DD-EE-FF     dynamic
+  192.168.1.255         ff-ff-ff-ff-ff-ff     static
+  224.0.0.22            01-00-5e-00-00-16     static`
+	if got := parseWindowsARP(windows); !reflect.DeepEqual(got, []Neighbor{
+		{IP: "192.168.1.1", MAC: "aa:bb:cc:dd:ee:ff"},
+	}) {
+		t.Fatalf("windows: %+v", got)
+	}
+}
+
+func TestInSubnets(t *testing.T) {
+	subnets, err := TargetSubnets([]string{"192.168.1.0/24", "192.168.1.7/24", "10.0.0.0/31"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subnets) != 2 {
+		t.Fatalf("expected deduped subnets, got %v", subnets)
+	}
+	cases := map[string]bool{
+		"192.168.1.20":  true,
+		"192.168.1.0":   false, // 网络地址
+		"192.168.1.255": false, // 广播地址
+		"192.168.2.1":   false,
+		"10.0.0.1":      true, // /31 不排除
+	}
+	for ip, want := range cases {
+		if got := InSubnets(net.ParseIP(ip), subnets); got != want {
+			t.Errorf("InSubnets(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
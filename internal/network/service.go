@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -61,6 +62,8 @@ type peerState struct {
 	addrs    []net.IP
 	port     int
 	txt      map[string]string
+	// preferred 最近一次探测连通的地址，下次探测时优先尝试它所在的地址族
+	preferred net.IP
 }
 
 func NewService(store storage.Store, logger logprovider.Logger, s Settings) *Service {
@@ -119,24 +122,34 @@ func (svc *Service) Start(ctx context.Context) error {
 		"node=" + svc.s.NodeName,
 		"port=" + strconv.Itoa(port),
 		"pid=" + strconv.Itoa(os.Getpid()),
-	}
-	mdns, err := zeroconf.Register(svc.s.NodeName, svc.s.Service, svc.s.Domain, port, txt, nil)
-	if err != nil {
+		// 节点平台，供调度器按 nodeSelector 的 kubernetes.io/arch 选择节点
+		"os=" + runtime.GOOS,
+		"arch=" + runtime.GOARCH,
+	}
+	mdns, err := registerMDNS(svc.s.NodeName, svc.s.Service, svc.s.Domain, port, txt)
+	switch {
+	case errors.Is(err, errMDNSUnsupported):
+		// 降级：不做 mDNS 广播/发现，健康检查与自身节点上报照常工作
+		svc.logger.Warnf("network: %v, peer discovery disabled", err)
+	case err != nil:
 		_ = svc.httpServer.Shutdown(context.Background())
 		return fmt.Errorf("network: mdns register failed: %w", err)
+	default:
+		svc.mdnsServer = mdns
 	}
-	svc.mdnsServer = mdns
 
 	if svc.s.RegisterSelf {
-		addrs := localIPv4s()
+		addrs := LocalIPs()
 		_ = svc.upsertManagedNode(svc.s.NodeName, addrs, port, map[string]string{
 			"source": "self",
 		}, true)
 		go svc.selfHeartbeatLoop(bgCtx, port)
 	}
 
-	if err := svc.startBrowse(bgCtx); err != nil {
-		svc.logger.Warnf("network: browse start failed: %v", err)
+	if svc.mdnsServer != nil {
+		if err := svc.startBrowse(bgCtx); err != nil {
+			svc.logger.Warnf("network: browse start failed: %v", err)
+		}
 	}
 
 	go svc.reapLoop(bgCtx)
@@ -172,7 +185,7 @@ func (svc *Service) healthMux(port int) http.Handler {
 			"node":   svc.s.NodeName,
 			"port":   port,
 			"pid":    os.Getpid(),
-			"addrs":  localIPv4sAsStrings(),
+			"addrs":  localIPStrings(),
 			"ts":     time.Now().Format(time.RFC3339Nano),
 			"mdns":   map[string]string{"service": svc.s.Service, "domain": svc.s.Domain},
 			"selfUp": svc.s.RegisterSelf,
@@ -182,7 +195,7 @@ func (svc *Service) healthMux(port int) http.Handler {
 }
 
 func (svc *Service) startBrowse(ctx context.Context) error {
-	resolver, err := zeroconf.NewResolver(nil)
+	resolver, err := newMDNSResolver()
 	if err != nil {
 		return err
 	}
@@ -234,12 +247,16 @@ func (svc *Service) onEntry(e *zeroconf.ServiceEntry) {
 		return
 	}
 
+	// 双栈节点同时广播 IPv4 与 IPv6 地址，按上次探测连通的地址族排序，Node 的第一个 InternalIP 就是实际可达的地址
 	svc.mu.Lock()
+	preferred := svc.peers[name].preferred
+	addrs = PreferFamily(addrs, preferred)
 	svc.peers[name] = peerState{
-		lastSeen: time.Now(),
-		addrs:    addrs,
-		port:     port,
-		txt:      txt,
+		lastSeen:  time.Now(),
+		addrs:     addrs,
+		port:      port,
+		txt:       txt,
+		preferred: preferred,
 	}
 	svc.mu.Unlock()
 
@@ -269,15 +286,21 @@ func (svc *Service) reapLoop(ctx context.Context) {
 			svc.mu.Unlock()
 
 			for name, st := range peers {
-				alive := probePeer(st.addrs, st.port, svc.s.ProbeTimeout)
-				if alive {
+				if ip := probePeer(PreferFamily(st.addrs, st.preferred), st.port, svc.s.ProbeTimeout); ip != nil {
 					svc.mu.Lock()
 					cur := svc.peers[name]
 					cur.lastSeen = now
+					cur.preferred = ip
+					cur.addrs = PreferFamily(cur.addrs, ip)
 					svc.peers[name] = cur
 					svc.mu.Unlock()
 
-					_ = svc.markManagedNodeReady(name, true, "PeerAlive", "tcp probe ok")
+					// 连通的地址族变化时（例如对端只有 IPv6 可达）重新排列 Node 的地址
+					if sameFamily(ip, st.preferred) {
+						_ = svc.markManagedNodeReady(name, true, "PeerAlive", "tcp probe ok")
+					} else if err := svc.upsertManagedNode(name, cur.addrs, cur.port, cur.txt, true); err != nil {
+						svc.logger.Debugf("network: upsert peer node failed: %s: %v", name, err)
+					}
 					continue
 				}
 
@@ -300,7 +323,7 @@ func (svc *Service) selfHeartbeatLoop(ctx context.Context, port int) {
 		case <-ctx.Done():
 			return
 		case <-t.C:
-			addrs := localIPv4s()
+			addrs := LocalIPs()
 			_ = svc.upsertManagedNode(svc.s.NodeName, addrs, port, map[string]string{
 				"source": "self",
 			}, true)
@@ -319,7 +342,7 @@ func (svc *Service) upsertManagedNode(name string, addrs []net.IP, port int, txt
 				return nil
 			}
 			updated := buildNodeFromExisting(existing, name, addrs, port, txt, ready)
-			return svc.store.Update(nodeGVK, updated)
+			return svc.updateNode(nodeGVK, updated)
 		}
 		// Unknown type in store; avoid overwriting.
 		return nil
@@ -327,6 +350,7 @@ func (svc *Service) upsertManagedNode(name string, addrs []net.IP, port int, txt
 
 	// Create new managed node.
 	node := buildNode(name, addrs, port, txt, ready)
+	storage.RecordManager(node, fieldManager)
 	return svc.store.Create(nodeGVK, node)
 }
 
@@ -351,7 +375,19 @@ func (svc *Service) markManagedNodeReady(name string, ready bool, reason, messag
 		n.Annotations = map[string]string{}
 	}
 	n.Annotations["k3.network/lastSeen"] = time.Now().Format(time.RFC3339Nano)
-	return svc.store.Update(nodeGVK, n)
+	return svc.updateNode(nodeGVK, n)
+}
+
+// fieldManager 是 network 写入 Node 时使用的写入者名称
+const fieldManager = "k3-network"
+
+// updateNode 更新节点并记录写入者；覆盖其他写入者的 labels/annotations 时记录告警（仍然写入，保持原有行为）
+func (svc *Service) updateNode(gvk schema.GroupVersionKind, node *corev1.Node) error {
+	conflict, err := storage.UpdateAs(svc.store, gvk, node, fieldManager, true)
+	if conflict != nil {
+		svc.logger.Warnf("network: node %s write conflict: %v", node.Name, conflict)
+	}
+	return err
 }
 
 func buildNode(name string, addrs []net.IP, port int, txt map[string]string, ready bool) *corev1.Node {
@@ -379,6 +415,7 @@ func buildNode(name string, addrs []net.IP, port int, txt map[string]string, rea
 	if v := strings.TrimSpace(txt["pid"]); v != "" {
 		n.Annotations["k3.network/pid"] = v
 	}
+	setPlatformLabels(n.Labels, txt, true)
 
 	if n.UID == "" {
 		n.UID = types.UID("node-" + name)
@@ -408,27 +445,37 @@ func buildNodeFromExisting(existing *corev1.Node, name string, addrs []net.IP, p
 	if v := strings.TrimSpace(txt["pid"]); v != "" {
 		n.Annotations["k3.network/pid"] = v
 	}
+	// 节点自己的 controller manager 按容器运行时上报的平台标签优先
+	setPlatformLabels(n.Labels, txt, false)
 
 	// Keep UID/CreationTimestamp from existing.
 	return n
 }
 
+// setPlatformLabels 把 TXT 中的 os/arch 写入 kubernetes.io/os、kubernetes.io/arch 标签；
+// overwrite 为 false 时不覆盖已有的标签
+func setPlatformLabels(labels map[string]string, txt map[string]string, overwrite bool) {
+	for key, label := range map[string]string{"os": corev1.LabelOSStable, "arch": corev1.LabelArchStable} {
+		v := strings.TrimSpace(txt[key])
+		if v == "" {
+			continue
+		}
+		if _, ok := labels[label]; ok && !overwrite {
+			continue
+		}
+		labels[label] = v
+	}
+}
+
 func buildAddresses(name string, addrs []net.IP) []corev1.NodeAddress {
 	out := []corev1.NodeAddress{
 		{Type: corev1.NodeHostName, Address: name},
 	}
 	for _, ip := range addrs {
-		if ip == nil {
+		if !UsableIP(ip) {
 			continue
 		}
-		if v4 := ip.To4(); v4 != nil {
-			out = append(out, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: v4.String()})
-			continue
-		}
-		// Only keep global IPv6.
-		if ip.To16() != nil && !ip.IsLinkLocalUnicast() && !ip.IsLoopback() {
-			out = append(out, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: ip.String()})
-		}
+		out = append(out, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: ip.String()})
 	}
 	return dedupeNodeAddresses(out)
 }
@@ -482,36 +529,32 @@ func dedupeNodeAddresses(in []corev1.NodeAddress) []corev1.NodeAddress {
 	return out
 }
 
-func probePeer(addrs []net.IP, port int, timeout time.Duration) bool {
+// probePeer 依次尝试 addrs 中的地址（调用方把上次连通的地址族排在前面），返回第一个能建立 TCP 连接的地址，都不通时返回 nil。
+// 回环地址也会尝试（同一台机器上的多个实例），链路本地地址没有网卡名无法连接，跳过
+func probePeer(addrs []net.IP, port int, timeout time.Duration) net.IP {
 	if port <= 0 {
-		return false
-	}
-	var candidates []net.IP
-	for _, ip := range addrs {
-		if ip == nil {
-			continue
-		}
-		if v4 := ip.To4(); v4 != nil {
-			candidates = append(candidates, v4)
-		}
+		return nil
 	}
 	for _, ip := range addrs {
-		if ip == nil || ip.To4() != nil {
+		if ip == nil || ip.IsUnspecified() || ip.IsMulticast() || ip.IsLinkLocalUnicast() {
 			continue
 		}
-		if ip.To16() != nil && !ip.IsLinkLocalUnicast() && !ip.IsLoopback() {
-			candidates = append(candidates, ip)
-		}
-	}
-	for _, ip := range candidates {
 		addr := net.JoinHostPort(ip.String(), strconv.Itoa(port))
 		conn, err := net.DialTimeout("tcp", addr, timeout)
 		if err == nil {
 			_ = conn.Close()
-			return true
+			return ip
 		}
 	}
-	return false
+	return nil
+}
+
+// sameFamily 判断两个地址是否属于同一地址族（b 为空时返回 false）
+func sameFamily(a, b net.IP) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return (a.To4() == nil) == (b.To4() == nil)
 }
 
 func parseTXT(lines []string) map[string]string {
@@ -560,50 +603,11 @@ func listenTCP(addr string) (net.Listener, int, error) {
 	return ln, tcpAddr.Port, nil
 }
 
-func localIPv4s() []net.IP {
-	ifaces, err := net.Interfaces()
-	if err != nil {
-		return nil
-	}
-	var ips []net.IP
-	for _, iface := range ifaces {
-		if iface.Flags&net.FlagUp == 0 {
-			continue
-		}
-		if iface.Flags&net.FlagLoopback != 0 {
-			continue
-		}
-		addrs, err := iface.Addrs()
-		if err != nil {
-			continue
-		}
-		for _, a := range addrs {
-			var ip net.IP
-			switch v := a.(type) {
-			case *net.IPNet:
-				ip = v.IP
-			case *net.IPAddr:
-				ip = v.IP
-			}
-			if ip == nil {
-				continue
-			}
-			ip = ip.To4()
-			if ip == nil || ip.IsLoopback() {
-				continue
-			}
-			ips = append(ips, ip)
-		}
-	}
-	return ips
-}
-
-func localIPv4sAsStrings() []string {
-	ips := localIPv4s()
+func localIPStrings() []string {
+	ips := LocalIPs()
 	out := make([]string, 0, len(ips))
 	for _, ip := range ips {
 		out = append(out, ip.String())
 	}
 	return out
 }
-
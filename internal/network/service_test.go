@@ -141,6 +141,29 @@ func TestSetNodeReadyCondition_StatusChangeUpdatesTransition(t *testing.T) {
 	}
 }
 
+func TestBuildNode_PlatformLabels(t *testing.T) {
+	txt := map[string]string{"os": "linux", "arch": "arm64"}
+
+	n := buildNode("pi-1", nil, 7946, txt, true)
+	if n.Labels[corev1.LabelOSStable] != "linux" || n.Labels[corev1.LabelArchStable] != "arm64" {
+		t.Fatalf("unexpected platform labels: %v", n.Labels)
+	}
+
+	// Labels reported by the node's own controller manager win over TXT.
+	existing := n.DeepCopy()
+	existing.Labels[corev1.LabelArchStable] = "arm"
+	updated := buildNodeFromExisting(existing, "pi-1", nil, 7946, txt, true)
+	if updated.Labels[corev1.LabelArchStable] != "arm" {
+		t.Fatalf("expected existing arch label to be kept, got %v", updated.Labels)
+	}
+
+	// Peers running an older version publish no platform.
+	old := buildNode("old-1", nil, 7946, map[string]string{"pid": "1"}, true)
+	if _, ok := old.Labels[corev1.LabelArchStable]; ok {
+		t.Fatalf("did not expect arch label without TXT, got %v", old.Labels)
+	}
+}
+
 func TestHealthMux(t *testing.T) {
 	svc := &Service{
 		s: Settings{
@@ -209,14 +232,48 @@ func TestProbePeer(t *testing.T) {
 	}()
 
 	port := ln.Addr().(*net.TCPAddr).Port
-	if ok := probePeer([]net.IP{net.ParseIP("127.0.0.1")}, port, 500*time.Millisecond); !ok {
+	if ip := probePeer([]net.IP{net.ParseIP("127.0.0.1")}, port, 500*time.Millisecond); ip == nil {
 		t.Fatalf("expected probePeer to succeed")
 	}
 
 	<-done
 
-	if ok := probePeer([]net.IP{net.ParseIP("127.0.0.1")}, 0, 100*time.Millisecond); ok {
+	if ip := probePeer([]net.IP{net.ParseIP("127.0.0.1")}, 0, 100*time.Millisecond); ip != nil {
 		t.Fatalf("expected probePeer to fail when port<=0")
 	}
 }
 
+func TestProbePeerPrefersFamilyThatConnects(t *testing.T) {
+	// 只在 IPv6 上监听：IPv4 地址连接被拒绝，应返回 IPv6 地址
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("ipv6 loopback not available: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = c.Close()
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	addrs := []net.IP{net.ParseIP("fe80::1"), net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+	ip := probePeer(PreferFamily(addrs, nil), port, 500*time.Millisecond)
+	if ip == nil || !ip.Equal(net.ParseIP("::1")) {
+		t.Fatalf("expected ::1 to connect, got %v", ip)
+	}
+
+	// 下一次按连通的地址族排序，IPv6 排在前面
+	ordered := PreferFamily(addrs, ip)
+	if !ordered[0].Equal(net.ParseIP("fe80::1")) || !ordered[2].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("unexpected order: %v", ordered)
+	}
+	if !sameFamily(ip, ordered[1]) || sameFamily(ip, ordered[2]) || sameFamily(ip, nil) {
+		t.Fatalf("unexpected family comparison")
+	}
+}
+
@@ -0,0 +1,19 @@
+package network
+
+import (
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+// SettingsFromConfig 按配置文件的 network.* 生成 Settings 中的心跳与过期时间；
+// 周期超出允许范围或 peer_ttl 小于 2 倍 probe_interval 时返回错误
+func SettingsFromConfig(cfg config.NetworkConfig) (Settings, error) {
+	intervals, err := cfg.Intervals()
+	if err != nil {
+		return Settings{}, err
+	}
+	return Settings{
+		PeerTTL:               intervals.PeerTTL,
+		ProbeInterval:         intervals.ProbeInterval,
+		SelfHeartbeatInterval: intervals.Heartbeat,
+	}, nil
+}
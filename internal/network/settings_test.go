@@ -0,0 +1,49 @@
+package network
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+func TestSettingsFromConfigDefaults(t *testing.T) {
+	s, err := SettingsFromConfig(config.NetworkConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.PeerTTL != 90*time.Second || s.ProbeInterval != 15*time.Second || s.SelfHeartbeatInterval != 30*time.Second {
+		t.Fatalf("unexpected defaults: %+v", s)
+	}
+}
+
+func TestSettingsFromConfigCustom(t *testing.T) {
+	s, err := SettingsFromConfig(config.NetworkConfig{PeerTTL: "10m", Heartbeat: "5m", ProbeInterval: "2m"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.PeerTTL != 10*time.Minute || s.ProbeInterval != 2*time.Minute || s.SelfHeartbeatInterval != 5*time.Minute {
+		t.Fatalf("unexpected settings: %+v", s)
+	}
+}
+
+func TestSettingsFromConfigRejectsInvalid(t *testing.T) {
+	cases := []struct {
+		cfg  config.NetworkConfig
+		want string
+	}{
+		{config.NetworkConfig{PeerTTL: "abc"}, "network.peer_ttl"},
+		{config.NetworkConfig{PeerTTL: "1s"}, "network.peer_ttl"},
+		{config.NetworkConfig{Heartbeat: "2h"}, "network.heartbeat"},
+		{config.NetworkConfig{ProbeInterval: "0s"}, "network.probe_interval"},
+		// 15s 的 probe_interval 要求 peer_ttl 至少 30s
+		{config.NetworkConfig{PeerTTL: "20s"}, "2 倍"},
+	}
+	for _, tc := range cases {
+		_, err := SettingsFromConfig(tc.cfg)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: expected error containing %q, got %v", tc.cfg, tc.want, err)
+		}
+	}
+}
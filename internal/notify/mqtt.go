@@ -0,0 +1,275 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+)
+
+// MQTT 3.1.1 控制报文类型（高 4 位）
+const (
+	mqttConnect = 1
+	mqttConnAck = 2
+	mqttPublish = 3
+	mqttPubAck  = 4
+)
+
+// mqttSink 以 QoS 1 向 MQTT broker（MQTT 3.1.1）发布消息，收到 PUBACK 才算成功。
+// 只实现发布需要的报文（CONNECT/PUBLISH/PUBACK），连接在多次发布之间复用
+type mqttSink struct {
+	address  string
+	useTLS   bool
+	host     string
+	username string
+	password string
+	clientID string
+
+	mu       sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	packetID uint16
+}
+
+// newMQTTSink 解析 tcp:// / mqtt://（默认端口 1883）或 ssl:// / mqtts:// / tls://（默认端口 8883）地址
+func newMQTTSink(u *url.URL, username, password string) (*mqttSink, error) {
+	s := &mqttSink{host: u.Hostname(), username: username, password: password}
+	port := u.Port()
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		if port == "" {
+			port = "1883"
+		}
+	case "ssl", "mqtts", "tls":
+		s.useTLS = true
+		if port == "" {
+			port = "8883"
+		}
+	default:
+		return nil, fmt.Errorf("mqtt url 需要 tcp:// 或 ssl://: %q", u.String())
+	}
+	s.address = net.JoinHostPort(s.host, port)
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	// MQTT 3.1.1 只保证 broker 接受 23 字节以内的 client id
+	s.clientID = "k3-notify-" + hex.EncodeToString(id)
+	return s, nil
+}
+
+// Publish 发布消息；复用的连接可能已被 broker 关闭，失败时重连后再试一次
+func (s *mqttSink) Publish(ctx context.Context, topic string, payload []byte) error {
+	if topic == "" {
+		return &permanentError{err: errors.New("mqtt topic 为空")}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reused := s.conn != nil
+	err := s.publish(ctx, topic, payload)
+	if err != nil && reused && !isPermanent(err) {
+		err = s.publish(ctx, topic, payload)
+	}
+	return err
+}
+
+// publish 在当前连接（没有时先连接）上发布一条 QoS 1 消息并等待 PUBACK
+func (s *mqttSink) publish(ctx context.Context, topic string, payload []byte) error {
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	setDeadline(ctx, s.conn)
+
+	s.packetID++
+	if s.packetID == 0 {
+		s.packetID = 1
+	}
+	var body bytes.Buffer
+	writeMQTTString(&body, topic)
+	_ = binary.Write(&body, binary.BigEndian, s.packetID)
+	body.Write(payload)
+	if err := writeMQTTPacket(s.conn, mqttPublish<<4|0x02, body.Bytes()); err != nil {
+		s.closeConn()
+		return err
+	}
+
+	typ, ack, err := readMQTTPacket(s.reader)
+	if err != nil {
+		s.closeConn()
+		return err
+	}
+	if typ>>4 != mqttPubAck || len(ack) < 2 || binary.BigEndian.Uint16(ack) != s.packetID {
+		s.closeConn()
+		return fmt.Errorf("mqtt: 期望 PUBACK(%d)，收到报文类型 %d", s.packetID, typ>>4)
+	}
+	return nil
+}
+
+// connect 建立连接并完成 CONNECT/CONNACK（clean session，关闭 keep alive）
+func (s *mqttSink) connect(ctx context.Context) error {
+	conn, err := dial(ctx, s.address, s.useTLS, s.host)
+	if err != nil {
+		return err
+	}
+	setDeadline(ctx, conn)
+
+	var flags byte = 0x02 // clean session
+	if s.username != "" {
+		flags |= 0x80
+		if s.password != "" {
+			flags |= 0x40
+		}
+	}
+	var body bytes.Buffer
+	writeMQTTString(&body, "MQTT")
+	body.WriteByte(4) // 协议级别 3.1.1
+	body.WriteByte(flags)
+	body.Write([]byte{0, 0}) // keep alive 0：broker 不因空闲断开连接
+	writeMQTTString(&body, s.clientID)
+	if s.username != "" {
+		writeMQTTString(&body, s.username)
+		if s.password != "" {
+			writeMQTTString(&body, s.password)
+		}
+	}
+	if err := writeMQTTPacket(conn, mqttConnect<<4, body.Bytes()); err != nil {
+		conn.Close()
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	typ, ack, err := readMQTTPacket(reader)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if typ>>4 != mqttConnAck || len(ack) < 2 {
+		conn.Close()
+		return fmt.Errorf("mqtt: 期望 CONNACK，收到报文类型 %d", typ>>4)
+	}
+	switch code := ack[1]; code {
+	case 0:
+	case 3:
+		// 服务暂不可用，可以重试
+		conn.Close()
+		return errors.New("mqtt: broker 暂不可用")
+	default:
+		conn.Close()
+		return &permanentError{err: fmt.Errorf("mqtt: broker 拒绝连接（返回码 %d）", code)}
+	}
+	s.conn, s.reader = conn, reader
+	return nil
+}
+
+// closeConn 关闭当前连接，下次发布时重连
+func (s *mqttSink) closeConn() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.reader = nil, nil
+	}
+}
+
+// Close 关闭连接
+func (s *mqttSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeConn()
+	return nil
+}
+
+// writeMQTTString 写入 2 字节长度前缀的 UTF-8 字符串
+func writeMQTTString(buf *bytes.Buffer, s string) {
+	_ = binary.Write(buf, binary.BigEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
+
+// writeMQTTPacket 写入固定报头（类型与标志、剩余长度）和报文内容
+func writeMQTTPacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+// readMQTTPacket 读取一个报文，返回固定报头的第一个字节与报文内容
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: 剩余长度无效")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// dial 建立 TCP（useTLS 时为 TLS）连接
+func dial(ctx context.Context, address string, useTLS bool, serverName string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if !useTLS {
+		return conn, nil
+	}
+	tlsConn, err := upgradeTLS(ctx, conn, serverName)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// upgradeTLS 在已建立的连接上完成 TLS 握手
+func upgradeTLS(ctx context.Context, conn net.Conn, serverName string) (net.Conn, error) {
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// setDeadline 把 ctx 的截止时间设置到连接上（没有截止时间时清除）
+func setDeadline(ctx context.Context, conn net.Conn) {
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+}
@@ -0,0 +1,197 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// natsSink 向 NATS subject 发布消息（NATS 客户端文本协议）。每次发布后发送 PING 并等待 PONG，
+// 服务器按顺序处理，收到 PONG 即说明消息已被服务器接受（出错时会先收到 -ERR）
+type natsSink struct {
+	address  string
+	useTLS   bool
+	host     string
+	username string
+	password string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// natsInfo 服务器 INFO 中用到的字段
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// natsConnect CONNECT 参数；只有用户名时作为 token 认证
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// newNATSSink 解析 nats://（默认端口 4222）或 tls:// 地址
+func newNATSSink(u *url.URL, username, password string) (*natsSink, error) {
+	s := &natsSink{host: u.Hostname(), username: username, password: password}
+	switch u.Scheme {
+	case "nats":
+	case "tls":
+		s.useTLS = true
+	default:
+		return nil, fmt.Errorf("nats url 需要 nats:// 或 tls://: %q", u.String())
+	}
+	port := u.Port()
+	if port == "" {
+		port = "4222"
+	}
+	s.address = net.JoinHostPort(s.host, port)
+	return s, nil
+}
+
+// Publish 发布消息；复用的连接可能已被服务器关闭，失败时重连后再试一次
+func (s *natsSink) Publish(ctx context.Context, subject string, payload []byte) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return &permanentError{err: fmt.Errorf("nats subject 无效: %q", subject)}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reused := s.conn != nil
+	err := s.publish(ctx, subject, payload)
+	if err != nil && reused && !isPermanent(err) {
+		err = s.publish(ctx, subject, payload)
+	}
+	return err
+}
+
+// publish 在当前连接（没有时先连接）上发布一条消息并等待 PONG
+func (s *natsSink) publish(ctx context.Context, subject string, payload []byte) error {
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	setDeadline(ctx, s.conn)
+
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload)
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		s.closeConn()
+		return err
+	}
+	if err := s.waitPong(); err != nil {
+		s.closeConn()
+		return err
+	}
+	return nil
+}
+
+// connect 建立连接：读取 INFO，按需升级 TLS，发送 CONNECT 并用 PING/PONG 确认（认证失败时服务器返回 -ERR）
+func (s *natsSink) connect(ctx context.Context) error {
+	conn, err := dial(ctx, s.address, false, s.host)
+	if err != nil {
+		return err
+	}
+	setDeadline(ctx, conn)
+
+	reader := bufio.NewReader(conn)
+	line, err := readNATSLine(reader)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("nats: 期望 INFO，收到 %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		conn.Close()
+		return fmt.Errorf("nats: INFO 无效: %w", err)
+	}
+	if s.useTLS || info.TLSRequired {
+		tlsConn, err := upgradeTLS(ctx, conn, s.host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		conn, reader = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	params := natsConnect{Name: "k3-notify", Lang: "go", Version: "k3"}
+	switch {
+	case s.username != "" && s.password != "":
+		params.User, params.Pass = s.username, s.password
+	case s.username != "":
+		params.AuthToken = s.username
+	}
+	data, _ := json.Marshal(params)
+	if _, err := conn.Write([]byte("CONNECT " + string(data) + "\r\nPING\r\n")); err != nil {
+		conn.Close()
+		return err
+	}
+	s.conn, s.reader = conn, reader
+	if err := s.waitPong(); err != nil {
+		s.closeConn()
+		if strings.Contains(strings.ToLower(err.Error()), "authorization") {
+			return &permanentError{err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// waitPong 读取到 PONG 为止：回应服务器的 PING，忽略 +OK/INFO，-ERR 作为错误返回
+func (s *natsSink) waitPong() error {
+	for {
+		line, err := readNATSLine(s.reader)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// closeConn 关闭当前连接，下次发布时重连
+func (s *natsSink) closeConn() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.reader = nil, nil
+	}
+}
+
+// Close 关闭连接
+func (s *natsSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeConn()
+	return nil
+}
+
+// readNATSLine 读取一行协议消息（去掉 \r\n）
+func readNATSLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,358 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/fx"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// defaultTimeout 未配置 timeout 时单次推送的超时
+	defaultTimeout = 5 * time.Second
+	// defaultMaxRetries 未配置 max_retries 时的重试次数
+	defaultMaxRetries = 3
+	// defaultRetryInterval / defaultMaxRetryInterval 未配置时的重试退避
+	defaultRetryInterval    = time.Second
+	defaultMaxRetryInterval = 30 * time.Second
+	// queueSize 每个 sink 待推送消息的上限，sink 持续不可用时超出的消息被丢弃（不阻塞 Store watch）
+	queueSize = 256
+)
+
+// Module 按 notifications 配置启动资源事件通知（没有配置 sink 时什么也不做）
+var Module = fx.Options(
+	fx.Invoke(func(lc fx.Lifecycle, cfg config.Config, logger logprovider.Logger, store storage.Store) error {
+		f, err := NewForwarder(store, logger, cfg.Notifications)
+		if err != nil || f == nil {
+			return err
+		}
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error { return f.Start(context.Background()) },
+			OnStop:  f.Stop,
+		})
+		return nil
+	}),
+)
+
+// delivery 一条待推送的消息
+type delivery struct {
+	desc    string // 日志中使用：事件类型与资源
+	topic   string
+	payload []byte
+}
+
+// sinkRunner 一个 sink 的过滤条件、模板与推送队列
+type sinkRunner struct {
+	name       string
+	sink       Sink
+	gvks       map[schema.GroupVersionKind]bool
+	events     map[string]bool // 为空表示全部
+	namespaces map[string]bool // 为空表示全部
+
+	topic   *template.Template
+	payload *template.Template
+
+	timeout          time.Duration
+	maxRetries       int
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+
+	queue chan delivery
+}
+
+// Forwarder watch Store 中配置的资源，把匹配的事件渲染后推送到各个 sink；
+// 每个 sink 有独立的队列并按顺序推送，失败时按指数退避重试
+type Forwarder struct {
+	store  storage.Store
+	logger logprovider.Logger
+	sinks  []*sinkRunner
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewForwarder 按配置创建事件通知；没有配置 sink 时返回 nil（未开启）
+func NewForwarder(store storage.Store, logger logprovider.Logger, cfg config.NotificationsConfig) (*Forwarder, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, nil
+	}
+	f := &Forwarder{store: store, logger: logger}
+	names := make(map[string]bool)
+	for i, sc := range cfg.Sinks {
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("%s-%d", strings.ToLower(sc.Type), i)
+		}
+		if names[sc.Name] {
+			return nil, fmt.Errorf("notifications.sinks: sink 名称重复: %q", sc.Name)
+		}
+		names[sc.Name] = true
+		r, err := newSinkRunner(sc)
+		if err != nil {
+			return nil, fmt.Errorf("notifications.sinks[%s]: %w", sc.Name, err)
+		}
+		f.sinks = append(f.sinks, r)
+	}
+	return f, nil
+}
+
+// newSinkRunner 校验 sink 配置并创建 sink
+func newSinkRunner(cfg config.NotificationSinkConfig) (*sinkRunner, error) {
+	r := &sinkRunner{
+		name:       cfg.Name,
+		gvks:       make(map[schema.GroupVersionKind]bool),
+		events:     make(map[string]bool),
+		namespaces: make(map[string]bool),
+		maxRetries: cfg.MaxRetries,
+		queue:      make(chan delivery, queueSize),
+	}
+	if len(cfg.Resources) == 0 {
+		return nil, fmt.Errorf("resources 不能为空")
+	}
+	for _, res := range cfg.Resources {
+		gvk, err := apiserver.GVKForResource(res)
+		if err != nil {
+			return nil, err
+		}
+		r.gvks[gvk] = true
+	}
+	for _, ev := range cfg.Events {
+		ev = strings.ToUpper(strings.TrimSpace(ev))
+		switch storage.EventType(ev) {
+		case storage.EventAdded, storage.EventModified, storage.EventDeleted:
+			r.events[ev] = true
+		default:
+			return nil, fmt.Errorf("不支持的事件类型: %q（支持 ADDED/MODIFIED/DELETED）", ev)
+		}
+	}
+	for _, ns := range cfg.Namespaces {
+		r.namespaces[ns] = true
+	}
+
+	var err error
+	if r.payload, err = parseTemplate("template", cfg.Template); err != nil {
+		return nil, err
+	}
+	topic := cfg.Topic
+	if topic == "" {
+		switch strings.ToLower(cfg.Type) {
+		case "mqtt":
+			topic = "k3/events/{{.Kind}}"
+		case "nats":
+			topic = "k3.events.{{.Kind}}"
+		}
+	}
+	if r.topic, err = parseTemplate("topic", topic); err != nil {
+		return nil, err
+	}
+
+	if r.timeout, err = parseOptionalDuration("timeout", cfg.Timeout, defaultTimeout); err != nil {
+		return nil, err
+	}
+	if r.retryInterval, err = parseOptionalDuration("retry_interval", cfg.RetryInterval, defaultRetryInterval); err != nil {
+		return nil, err
+	}
+	if r.maxRetryInterval, err = parseOptionalDuration("max_retry_interval", cfg.MaxRetryInterval, defaultMaxRetryInterval); err != nil {
+		return nil, err
+	}
+	switch {
+	case r.maxRetries == 0:
+		r.maxRetries = defaultMaxRetries
+	case r.maxRetries < 0:
+		r.maxRetries = 0
+	}
+
+	if r.sink, err = newSink(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// parseOptionalDuration 解析可选的时长配置，为空时返回默认值
+func parseOptionalDuration(key, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s 无效: %q", key, value)
+	}
+	return d, nil
+}
+
+// matches 判断事件是否需要推送到该 sink
+func (r *sinkRunner) matches(gvk schema.GroupVersionKind, eventType storage.EventType, namespace string) bool {
+	if !r.gvks[gvk] {
+		return false
+	}
+	if len(r.events) > 0 && !r.events[string(eventType)] {
+		return false
+	}
+	// 集群级资源没有 namespace，不受 namespaces 限制
+	if len(r.namespaces) > 0 && namespace != "" && !r.namespaces[namespace] {
+		return false
+	}
+	return true
+}
+
+// Start 为配置中的每种资源建立 watch，并启动各 sink 的推送
+func (f *Forwarder) Start(ctx context.Context) error {
+	ctx, f.cancel = context.WithCancel(ctx)
+
+	gvks := make(map[schema.GroupVersionKind]bool)
+	for _, r := range f.sinks {
+		for gvk := range r.gvks {
+			gvks[gvk] = true
+		}
+	}
+	for gvk := range gvks {
+		ch, err := f.store.Watch(gvk, "", "")
+		if err != nil {
+			f.cancel()
+			return fmt.Errorf("watch %s 失败: %w", gvk.Kind, err)
+		}
+		f.wg.Add(1)
+		go func(gvk schema.GroupVersionKind) {
+			defer f.wg.Done()
+			f.dispatch(ctx, gvk, ch)
+		}(gvk)
+	}
+	for _, r := range f.sinks {
+		f.wg.Add(1)
+		go func(r *sinkRunner) {
+			defer f.wg.Done()
+			f.run(ctx, r)
+		}(r)
+	}
+	f.logger.Infof("启动资源事件通知（%d 个 sink）", len(f.sinks))
+	return nil
+}
+
+// Stop 停止 watch 与推送（未推送的消息被丢弃），关闭 sink 连接
+func (f *Forwarder) Stop(ctx context.Context) error {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	for _, r := range f.sinks {
+		_ = r.sink.Close()
+	}
+	return nil
+}
+
+// dispatch 把一种资源的事件渲染后放入匹配的 sink 队列；队列满时丢弃，不阻塞 watch
+func (f *Forwarder) dispatch(ctx context.Context, gvk schema.GroupVersionKind, ch <-chan storage.ResourceEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if ev.Type == storage.EventBookmark {
+				continue
+			}
+			event, err := newEvent(gvk, ev, time.Now())
+			if err != nil {
+				f.logger.Warnf("转换 %s 事件失败: %v", gvk.Kind, err)
+				continue
+			}
+			desc := fmt.Sprintf("%s %s %s", event.Type, event.Kind, qualifiedName(event.Namespace, event.Name))
+			for _, r := range f.sinks {
+				if !r.matches(gvk, ev.Type, event.Namespace) {
+					continue
+				}
+				d, err := r.render(event)
+				if err != nil {
+					f.logger.Warnf("通知 %s 渲染 %s 失败: %v", r.name, desc, err)
+					continue
+				}
+				d.desc = desc
+				select {
+				case r.queue <- d:
+				default:
+					f.logger.Warnf("通知 %s 队列已满，丢弃 %s", r.name, desc)
+				}
+			}
+		}
+	}
+}
+
+// render 渲染 topic 与消息体
+func (r *sinkRunner) render(event *Event) (delivery, error) {
+	var d delivery
+	if r.topic != nil {
+		topic, err := render(r.topic, event)
+		if err != nil {
+			return d, err
+		}
+		d.topic = strings.TrimSpace(string(topic))
+	}
+	payload, err := render(r.payload, event)
+	if err != nil {
+		return d, err
+	}
+	d.payload = payload
+	return d, nil
+}
+
+// run 按顺序推送 sink 队列中的消息
+func (f *Forwarder) run(ctx context.Context, r *sinkRunner) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-r.queue:
+			if err := r.deliver(ctx, d); err != nil && ctx.Err() == nil {
+				f.logger.Warnf("通知 %s 推送 %s 失败: %v", r.name, d.desc, err)
+			}
+		}
+	}
+}
+
+// deliver 推送一条消息，失败时按指数退避重试（不可重试的错误直接返回）
+func (r *sinkRunner) deliver(ctx context.Context, d delivery) error {
+	backoff := r.retryInterval
+	for attempt := 0; ; attempt++ {
+		pubCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		err := r.sink.Publish(pubCtx, d.topic, d.payload)
+		cancel()
+		if err == nil || isPermanent(err) || attempt >= r.maxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > r.maxRetryInterval {
+			backoff = r.maxRetryInterval
+		}
+	}
+}
+
+// qualifiedName 返回 namespace/name（集群级资源只有 name）
+func qualifiedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
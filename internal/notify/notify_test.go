@@ -0,0 +1,274 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var podGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+func testPodEvent() storage.ResourceEvent {
+	return storage.ResourceEvent{
+		Type: storage.EventAdded,
+		Object: &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	ev, err := newEvent(podGVK, testPodEvent(), time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("newEvent: %v", err)
+	}
+	tmpl, err := parseTemplate("template", `{{.Type}} {{.Namespace}}/{{.Name}} {{.Object.status.phase}} {{json .Object.metadata.name}}`)
+	if err != nil {
+		t.Fatalf("parseTemplate: %v", err)
+	}
+	out, err := render(tmpl, ev)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got, want := string(out), `ADDED default/web Running "web"`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// 没有模板时推送事件 JSON
+	out, err = render(nil, ev)
+	if err != nil || !strings.Contains(string(out), `"type":"ADDED"`) || !strings.Contains(string(out), `"name":"web"`) {
+		t.Errorf("unexpected default payload %s (err=%v)", out, err)
+	}
+}
+
+func TestSinkRunnerMatches(t *testing.T) {
+	r, err := newSinkRunner(config.NotificationSinkConfig{
+		Name:       "hook",
+		Type:       "webhook",
+		URL:        "http://127.0.0.1:1/hook",
+		Resources:  []string{"pods", "nodes"},
+		Events:     []string{"added", "DELETED"},
+		Namespaces: []string{"default"},
+	})
+	if err != nil {
+		t.Fatalf("newSinkRunner: %v", err)
+	}
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+	cases := []struct {
+		gvk       schema.GroupVersionKind
+		eventType storage.EventType
+		namespace string
+		want      bool
+	}{
+		{podGVK, storage.EventAdded, "default", true},
+		{podGVK, storage.EventModified, "default", false},
+		{podGVK, storage.EventAdded, "other", false},
+		{nodeGVK, storage.EventDeleted, "", true},
+		{schema.GroupVersionKind{Version: "v1", Kind: "Service"}, storage.EventAdded, "default", false},
+	}
+	for _, c := range cases {
+		if got := r.matches(c.gvk, c.eventType, c.namespace); got != c.want {
+			t.Errorf("matches(%s, %s, %q) = %v, want %v", c.gvk.Kind, c.eventType, c.namespace, got, c.want)
+		}
+	}
+
+	if _, err := newSinkRunner(config.NotificationSinkConfig{Type: "smtp", URL: "smtp://x", Resources: []string{"pods"}}); err == nil {
+		t.Errorf("expected unsupported sink type to be rejected")
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	r, err := newSinkRunner(config.NotificationSinkConfig{
+		Type:          "webhook",
+		URL:           srv.URL,
+		Resources:     []string{"pods"},
+		RetryInterval: "1ms",
+	})
+	if err != nil {
+		t.Fatalf("newSinkRunner: %v", err)
+	}
+	if err := r.deliver(context.Background(), delivery{payload: []byte("{}")}); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls.Load())
+	}
+
+	// 4xx 不重试
+	calls.Store(0)
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer bad.Close()
+	r.sink = &webhookSink{url: bad.URL, client: http.DefaultClient}
+	if err := r.deliver(context.Background(), delivery{payload: []byte("{}")}); err == nil || calls.Load() != 1 {
+		t.Errorf("expected single failed attempt, got %d (err=%v)", calls.Load(), err)
+	}
+}
+
+// serveOnce 在本地端口上运行 handle 处理一个连接，返回地址
+func serveOnce(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}()
+	return ln.Addr().String()
+}
+
+func TestMQTTPublish(t *testing.T) {
+	got := make(chan string, 1)
+	addr := serveOnce(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		typ, body, err := readMQTTPacket(r)
+		if err != nil || typ>>4 != mqttConnect || !strings.Contains(string(body), "user") {
+			got <- fmt.Sprintf("bad CONNECT: %v", err)
+			return
+		}
+		_ = writeMQTTPacket(conn, mqttConnAck<<4, []byte{0, 0})
+		typ, body, err = readMQTTPacket(r)
+		if err != nil || typ != mqttPublish<<4|0x02 {
+			got <- fmt.Sprintf("bad PUBLISH: %v", err)
+			return
+		}
+		topicLen := int(binary.BigEndian.Uint16(body))
+		topic := string(body[2 : 2+topicLen])
+		id := body[2+topicLen : 4+topicLen]
+		_ = writeMQTTPacket(conn, mqttPubAck<<4, id)
+		got <- topic + " " + string(body[4+topicLen:])
+	})
+
+	u, _ := url.Parse("tcp://" + addr)
+	s, err := newMQTTSink(u, "user", "pass")
+	if err != nil {
+		t.Fatalf("newMQTTSink: %v", err)
+	}
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Publish(ctx, "k3/events/Pod", []byte("hello")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if msg := <-got; msg != "k3/events/Pod hello" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestNATSPublish(t *testing.T) {
+	got := make(chan string, 1)
+	addr := serveOnce(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		_, _ = conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		var lines []string
+		for {
+			line, err := readNATSLine(r)
+			if err != nil {
+				got <- fmt.Sprintf("read: %v", err)
+				return
+			}
+			if line == "PING" {
+				_, _ = conn.Write([]byte("PONG\r\n"))
+				if len(lines) > 1 {
+					got <- strings.Join(lines[1:], "|")
+					return
+				}
+				continue
+			}
+			lines = append(lines, line)
+		}
+	})
+
+	u, _ := url.Parse("nats://" + addr)
+	s, err := newNATSSink(u, "", "")
+	if err != nil {
+		t.Fatalf("newNATSSink: %v", err)
+	}
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Publish(ctx, "k3.events.Pod", []byte("hello")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if msg := <-got; msg != "PUB k3.events.Pod 5|hello" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestForwarderWebhook(t *testing.T) {
+	got := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(body)
+		got <- r.Header.Get("X-Token") + " " + string(body)
+	}))
+	defer srv.Close()
+
+	store := storage.NewMemoryStore()
+	f, err := NewForwarder(store, logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}, config.NotificationsConfig{
+		Sinks: []config.NotificationSinkConfig{{
+			Type:      "webhook",
+			URL:       srv.URL,
+			Headers:   map[string]string{"X-Token": "secret"},
+			Resources: []string{"pods"},
+			Template:  `{{.Type}} {{.Name}}`,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewForwarder: %v", err)
+	}
+	if err := f.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer f.Stop(context.Background())
+
+	if err := store.Create(podGVK, testPodEvent().Object); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	select {
+	case msg := <-got:
+		if msg != "secret ADDED web" {
+			t.Errorf("unexpected webhook request %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Event 推送给 sink 的事件，也是消息体/topic 模板的数据
+type Event struct {
+	Type       string                 `json:"type"`
+	APIVersion string                 `json:"apiVersion"`
+	Kind       string                 `json:"kind"`
+	Namespace  string                 `json:"namespace,omitempty"`
+	Name       string                 `json:"name"`
+	Time       time.Time              `json:"time"`
+	Object     map[string]interface{} `json:"object,omitempty"`
+	OldObject  map[string]interface{} `json:"oldObject,omitempty"`
+}
+
+// newEvent 把 Store 事件转换为通知事件（对象转换为通用 map，便于模板按字段路径取值）
+func newEvent(gvk schema.GroupVersionKind, ev storage.ResourceEvent, at time.Time) (*Event, error) {
+	out := &Event{
+		Type:       string(ev.Type),
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Time:       at,
+	}
+	if ev.Object != nil {
+		if m, err := meta.Accessor(ev.Object); err == nil {
+			out.Namespace = m.GetNamespace()
+			out.Name = m.GetName()
+		}
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ev.Object)
+		if err != nil {
+			return nil, err
+		}
+		out.Object = obj
+	}
+	if ev.OldObj != nil {
+		old, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ev.OldObj)
+		if err != nil {
+			return nil, err
+		}
+		out.OldObject = old
+	}
+	return out, nil
+}
+
+// templateFuncs 模板中可用的函数
+var templateFuncs = template.FuncMap{
+	// json 把值编码为 JSON（如 {{json .Object.status}}）
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// parseTemplate 解析模板；text 为空时返回 nil
+func parseTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s 模板无效: %w", name, err)
+	}
+	return t, nil
+}
+
+// render 用模板渲染事件；t 为 nil 时返回事件的 JSON
+func render(t *template.Template, ev *Event) ([]byte, error) {
+	if t == nil {
+		return json.Marshal(ev)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, ev); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+// Sink 是通知目标
+type Sink interface {
+	// Publish 推送一条消息；topic 为 MQTT topic / NATS subject（webhook 忽略）
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Close 关闭连接
+	Close() error
+}
+
+// permanentError 重试也不会成功的错误（如 webhook 返回 4xx、认证失败），不再重试
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// isPermanent 判断错误是否不需要重试
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// newSink 按配置创建 sink
+func newSink(cfg config.NotificationSinkConfig) (Sink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("url 无效: %q", cfg.URL)
+	}
+	username, password := cfg.Username, cfg.Password
+	if u.User != nil && username == "" {
+		username = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	switch strings.ToLower(cfg.Type) {
+	case "webhook":
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("webhook url 需要 http:// 或 https://: %q", cfg.URL)
+		}
+		return &webhookSink{url: cfg.URL, headers: cfg.Headers, client: &http.Client{}}, nil
+	case "mqtt":
+		return newMQTTSink(u, username, password)
+	case "nats":
+		return newNATSSink(u, username, password)
+	default:
+		return nil, fmt.Errorf("不支持的 sink 类型: %q（支持 webhook/mqtt/nats）", cfg.Type)
+	}
+}
+
+// webhookSink 以 HTTP POST 推送消息
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// Publish POST 消息体；2xx 为成功，408/429/5xx 可重试，其他状态码不再重试
+func (s *webhookSink) Publish(ctx context.Context, _ string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "k3-notify")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("webhook 返回 %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	if resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return &permanentError{err: err}
+}
+
+// Close webhook 没有长连接
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
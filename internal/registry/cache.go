@@ -0,0 +1,191 @@
+package registry
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cache 磁盘缓存，目录结构：
+//
+//	blobs/sha256/<hex>              镜像层与配置
+//	manifests/sha256/<hex>          manifest 内容
+//	manifests/sha256/<hex>.type     manifest 的 Content-Type
+//	tags/<仓库域名>/<路径>/_tags/<tag>  tag 对应的 manifest 摘要（文件修改时间为上次向上游确认的时间）
+//	tmp/                            下载中的文件
+//
+// 内容按摘要寻址，写入时先写到 tmp 再重命名，多个请求并发写入同一摘要是安全的
+type cache struct {
+	dir string
+}
+
+// cachedManifest 缓存的 manifest
+type cachedManifest struct {
+	digest    string
+	mediaType string
+	body      []byte
+}
+
+func newCache(dir string) (*cache, error) {
+	for _, sub := range []string{"blobs/sha256", "manifests/sha256", "tags", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(sub)), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	// 上次退出时未完成的下载
+	entries, _ := os.ReadDir(filepath.Join(dir, "tmp"))
+	for _, e := range entries {
+		_ = os.Remove(filepath.Join(dir, "tmp", e.Name()))
+	}
+	return &cache{dir: dir}, nil
+}
+
+// blobPath 返回摘要对应的 blob 路径（digest 已校验为 sha256:<hex>）
+func (c *cache) blobPath(digest string) string {
+	return filepath.Join(c.dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
+
+func (c *cache) manifestPath(digest string) string {
+	return filepath.Join(c.dir, "manifests", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
+
+func (c *cache) tagPath(host, repo, tag string) string {
+	// 仓库路径的各段不能以 _ 开头，_tags 不会与下一级路径冲突
+	return filepath.Join(c.dir, "tags", host, filepath.FromSlash(repo), "_tags", tag)
+}
+
+// openBlob 打开缓存的 blob 并更新其访问时间（淘汰按访问时间进行），不存在时返回 nil
+func (c *cache) openBlob(digest string) *os.File {
+	path := c.blobPath(digest)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return f
+}
+
+// tempFile 在 tmp 目录中创建下载用的临时文件
+func (c *cache) tempFile() (*os.File, error) {
+	return os.CreateTemp(filepath.Join(c.dir, "tmp"), "blob-")
+}
+
+// commitBlob 把下载完成并校验过的临时文件放入缓存
+func (c *cache) commitBlob(tmp, digest string) error {
+	return os.Rename(tmp, c.blobPath(digest))
+}
+
+// manifest 读取缓存的 manifest，不存在时返回 nil
+func (c *cache) manifest(digest string) *cachedManifest {
+	body, err := os.ReadFile(c.manifestPath(digest))
+	if err != nil {
+		return nil
+	}
+	mediaType, _ := os.ReadFile(c.manifestPath(digest) + ".type")
+	return &cachedManifest{digest: digest, mediaType: string(mediaType), body: body}
+}
+
+// putManifest 写入 manifest
+func (c *cache) putManifest(m *cachedManifest) error {
+	if err := c.writeFile(c.manifestPath(m.digest)+".type", []byte(m.mediaType)); err != nil {
+		return err
+	}
+	return c.writeFile(c.manifestPath(m.digest), m.body)
+}
+
+// tagEntry tag 文件的内容
+type tagEntry struct {
+	Digest string `json:"digest"`
+}
+
+// tag 返回 tag 缓存的 manifest 摘要以及上次向上游确认的时间，没有缓存时摘要为空
+func (c *cache) tag(host, repo, tag string) (string, time.Time) {
+	path := c.tagPath(host, repo, tag)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", time.Time{}
+	}
+	var entry tagEntry
+	if err := json.Unmarshal(data, &entry); err != nil || !digestPattern.MatchString(entry.Digest) {
+		return "", time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}
+	}
+	return entry.Digest, info.ModTime()
+}
+
+// putTag 记录 tag 对应的摘要，确认时间为当前时间
+func (c *cache) putTag(host, repo, tag, digest string) error {
+	path := c.tagPath(host, repo, tag)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(tagEntry{Digest: digest})
+	return c.writeFile(path, data)
+}
+
+// writeFile 通过 tmp 中的临时文件写入 path
+func (c *cache) writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Join(c.dir, "tmp"), "file-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// evict 在 blob 总大小超过 limit 时按访问时间从旧到新删除，直到不超过 limit；返回删除的数量与字节数
+func (c *cache) evict(limit int64) (int, int64, error) {
+	type blob struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var blobs []blob
+	var total int64
+	err := filepath.WalkDir(filepath.Join(c.dir, "blobs"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		blobs = append(blobs, blob{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil || total <= limit {
+		return 0, 0, err
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].modTime.Before(blobs[j].modTime) })
+	var removed int
+	var freed int64
+	for _, b := range blobs {
+		if total <= limit {
+			break
+		}
+		if err := os.Remove(b.path); err != nil {
+			continue
+		}
+		total -= b.size
+		freed += b.size
+		removed++
+	}
+	return removed, freed, nil
+}
@@ -0,0 +1,89 @@
+package registry
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+const (
+	// DefaultHost 镜像名中没有仓库域名时所在的仓库
+	DefaultHost = "docker.io"
+	// defaultListen 缓存服务默认监听地址
+	defaultListen = ":5000"
+)
+
+var (
+	// hostPattern 仓库域名（可带端口）
+	hostPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?(:[0-9]+)?$`)
+	// repoPattern 仓库路径（与 distribution 的命名规则一致，各段由 / 分隔）
+	repoPattern = regexp.MustCompile(`^[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*(/[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*)*$`)
+	// tagPattern tag
+	tagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	// digestPattern 只支持 sha256 摘要
+	digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// PullEndpoint 返回本节点 Docker 拉取镜像时使用的缓存地址（host:port）：
+// 配置了 endpoint 时使用它，否则本节点开启了缓存时为 127.0.0.1:<端口>，都没有时为空（直接从上游拉取）
+func PullEndpoint(cfg config.RegistryMirrorConfig) string {
+	if cfg.Endpoint != "" {
+		return cfg.Endpoint
+	}
+	if !cfg.Enabled {
+		return ""
+	}
+	listen := cfg.Listen
+	if listen == "" {
+		listen = defaultListen
+	}
+	_, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// MirrorReference 返回通过缓存 endpoint 拉取 image 时使用的镜像名：<endpoint>/<仓库域名>/<路径>[:tag][@digest]，
+// 如 nginx:1.25 -> 127.0.0.1:5000/docker.io/library/nginx:1.25。endpoint 为空或镜像本身就在 endpoint 上时返回空
+func MirrorReference(endpoint, image string) string {
+	if endpoint == "" || image == "" {
+		return ""
+	}
+	host, repo, suffix := splitImage(image)
+	if host == endpoint {
+		return ""
+	}
+	return endpoint + "/" + host + "/" + repo + suffix
+}
+
+// splitImage 把镜像引用拆分为仓库域名、仓库路径以及 tag/digest 后缀（包含开头的 ":" 或 "@"）
+func splitImage(image string) (host, repo, suffix string) {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, suffix = name[:i], name[i:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, suffix = name[:i], name[i:]+suffix
+	}
+	host, repo = splitName(name)
+	return host, repo, suffix
+}
+
+// splitName 把仓库名拆分为域名与路径，规则与 docker 一致：第一段包含 "." 或 ":"、或为 localhost 时是域名，
+// 否则为 docker.io；docker.io 上只有一段的名称补全为 library/<名称>
+func splitName(name string) (string, string) {
+	host, repo := DefaultHost, name
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		host, repo = first, rest
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		host = DefaultHost
+	}
+	if host == DefaultHost && !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	return host, repo
+}
@@ -0,0 +1,477 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"go.uber.org/fx"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// defaultManifestTTL tag 对应的 manifest 默认缓存时间
+	defaultManifestTTL = 10 * time.Minute
+	// maxManifestSize manifest 的大小上限
+	maxManifestSize = 4 << 20
+)
+
+// manifestMediaTypes 客户端没有指定 Accept 时向上游请求的 manifest 类型
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Module 在本节点启动镜像拉取缓存（配置 registry_mirror.enabled 时）
+var Module = fx.Options(
+	fx.Invoke(func(lc fx.Lifecycle, cfg config.Config, logger logprovider.Logger) error {
+		s, err := NewServer(logger, cfg.RegistryMirror)
+		if err != nil || s == nil {
+			return err
+		}
+		lc.Append(fx.Hook{
+			OnStart: s.Start,
+			OnStop:  s.Stop,
+		})
+		return nil
+	}),
+)
+
+// Server 只读的 Docker Registry v2 服务：manifest 与镜像层按需从上游拉取并缓存到磁盘，之后的请求直接由缓存提供。
+// 仓库名的第一段为上游仓库域名（如 /v2/docker.io/library/nginx/manifests/latest），没有域名时为 docker.io，
+// 因此也可以作为 Docker daemon 的 registry-mirrors 使用
+type Server struct {
+	logger      logprovider.Logger
+	cache       *cache
+	listen      string
+	manifestTTL time.Duration
+	maxSize     int64
+	client      *http.Client
+	httpServer  *http.Server
+
+	// ctx 在 Stop 时取消；镜像层下载不跟随客户端请求取消，客户端断开后继续写入缓存
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	upstreams map[string]*upstream
+	inflight  map[string]chan struct{} // 正在下载的镜像层，下载结束时关闭
+}
+
+// NewServer 创建缓存服务，cfg.Enabled 为 false 时返回 nil
+func NewServer(logger logprovider.Logger, cfg config.RegistryMirrorConfig) (*Server, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	s := &Server{
+		logger:      logger,
+		listen:      cfg.Listen,
+		manifestTTL: defaultManifestTTL,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 30 * time.Second,
+				MaxIdleConnsPerHost:   8,
+			},
+		},
+		upstreams: make(map[string]*upstream),
+		inflight:  make(map[string]chan struct{}),
+	}
+	if s.listen == "" {
+		s.listen = defaultListen
+	}
+	if cfg.ManifestTTL != "" {
+		ttl, err := time.ParseDuration(cfg.ManifestTTL)
+		if err != nil {
+			return nil, fmt.Errorf("registry_mirror.manifest_ttl 无效: %w", err)
+		}
+		s.manifestTTL = ttl
+	}
+	if cfg.MaxSize != "" {
+		q, err := resource.ParseQuantity(cfg.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("registry_mirror.max_size 无效: %w", err)
+		}
+		s.maxSize = q.Value()
+	}
+	for _, u := range cfg.Upstreams {
+		if !hostPattern.MatchString(u.Host) {
+			return nil, fmt.Errorf("registry_mirror.upstreams 中的 host %q 无效", u.Host)
+		}
+		s.upstreams[u.Host] = newUpstream(u, s.client)
+	}
+
+	var err error
+	if s.cache, err = newCache(cfg.CacheDir); err != nil {
+		return nil, fmt.Errorf("创建镜像缓存目录失败: %w", err)
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.httpServer = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	return s, nil
+}
+
+// Start 开始监听
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return fmt.Errorf("镜像缓存监听 %s 失败: %w", s.listen, err)
+	}
+	s.logger.Infof("镜像拉取缓存已启动: %s（缓存目录 %s）", ln.Addr(), s.cache.dir)
+	go func() {
+		if err := s.httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Errorf("镜像缓存服务退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop 停止服务并中止进行中的下载
+func (s *Server) Stop(ctx context.Context) error {
+	s.cancel()
+	return s.httpServer.Shutdown(ctx)
+}
+
+// ServeHTTP 处理 /v2/ 下的拉取请求（GET/HEAD），不支持推送
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "镜像缓存只支持拉取")
+		return
+	}
+	if r.URL.Path == "/v2/" || r.URL.Path == "/v2" {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v2/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "NAME_UNKNOWN", "not found")
+		return
+	}
+
+	var kind, name, ref string
+	if i := strings.LastIndex(rest, "/manifests/"); i > 0 {
+		kind, name, ref = "manifests", rest[:i], rest[i+len("/manifests/"):]
+	} else if i := strings.LastIndex(rest, "/blobs/"); i > 0 {
+		kind, name, ref = "blobs", rest[:i], rest[i+len("/blobs/"):]
+	} else {
+		writeError(w, http.StatusNotFound, "NAME_UNKNOWN", "not found")
+		return
+	}
+	host, repo := splitName(name)
+	if !hostPattern.MatchString(host) || !repoPattern.MatchString(repo) {
+		writeError(w, http.StatusBadRequest, "NAME_INVALID", fmt.Sprintf("无效的仓库名 %q", name))
+		return
+	}
+
+	if kind == "manifests" {
+		s.serveManifest(w, r, host, repo, ref)
+		return
+	}
+	if !digestPattern.MatchString(ref) {
+		writeError(w, http.StatusBadRequest, "DIGEST_INVALID", fmt.Sprintf("不支持的摘要 %q", ref))
+		return
+	}
+	s.serveBlob(w, r, host, repo, ref)
+}
+
+// upstream 返回仓库域名对应的上游（没有配置时为 https://<域名>）
+func (s *Server) upstream(host string) *upstream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.upstreams[host]
+	if !ok {
+		u = newUpstream(config.RegistryUpstreamConfig{Host: host}, s.client)
+		s.upstreams[host] = u
+	}
+	return u
+}
+
+// serveManifest 按摘要请求时优先使用缓存；按 tag 请求时缓存在 manifest_ttl 内直接使用，
+// 过期后向上游确认（摘要没变只刷新确认时间），上游不可用时继续使用过期的缓存
+func (s *Server) serveManifest(w http.ResponseWriter, r *http.Request, host, repo, ref string) {
+	isDigest := digestPattern.MatchString(ref)
+	if !isDigest && !tagPattern.MatchString(ref) {
+		writeError(w, http.StatusBadRequest, "MANIFEST_INVALID", fmt.Sprintf("无效的 tag %q", ref))
+		return
+	}
+	up := s.upstream(host)
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		accept = manifestMediaTypes
+	}
+
+	if isDigest {
+		m := s.cache.manifest(ref)
+		if m == nil {
+			var err error
+			if m, err = s.fetchManifest(r.Context(), up, repo, ref, accept); err != nil {
+				s.writeUpstreamError(w, err, "MANIFEST_UNKNOWN", host, repo, ref)
+				return
+			}
+		}
+		writeManifest(w, r, m)
+		return
+	}
+
+	digest, checked := s.cache.tag(host, repo, ref)
+	if digest != "" {
+		if m := s.cache.manifest(digest); m != nil {
+			if time.Since(checked) < s.manifestTTL || s.revalidate(r.Context(), up, host, repo, ref, digest, accept) {
+				writeManifest(w, r, m)
+				return
+			}
+		}
+	}
+	m, err := s.fetchManifest(r.Context(), up, repo, ref, accept)
+	if err != nil {
+		var upErr *upstreamError
+		var stale *cachedManifest
+		if digest != "" {
+			stale = s.cache.manifest(digest)
+		}
+		if stale != nil && !(errors.As(err, &upErr) && upErr.status == http.StatusNotFound) {
+			s.logger.Warnf("镜像缓存: 向上游确认 %s/%s:%s 失败，使用缓存: %v", host, repo, ref, err)
+			writeManifest(w, r, stale)
+			return
+		}
+		s.writeUpstreamError(w, err, "MANIFEST_UNKNOWN", host, repo, ref)
+		return
+	}
+	if err := s.cache.putTag(host, repo, ref, m.digest); err != nil {
+		s.logger.Warnf("镜像缓存: 记录 %s/%s:%s 失败: %v", host, repo, ref, err)
+	}
+	writeManifest(w, r, m)
+}
+
+// revalidate 用 HEAD 向上游确认 tag 是否仍指向 digest，是则刷新确认时间（HEAD 不计入 Docker Hub 的拉取次数）
+func (s *Server) revalidate(ctx context.Context, up *upstream, host, repo, tag, digest string, accept []string) bool {
+	resp, err := up.do(ctx, http.MethodHead, repo, "manifests/"+tag, http.Header{"Accept": accept})
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Docker-Content-Digest") != digest {
+		return false
+	}
+	_ = s.cache.putTag(host, repo, tag, digest)
+	return true
+}
+
+// fetchManifest 从上游获取 manifest，校验摘要后写入缓存
+func (s *Server) fetchManifest(ctx context.Context, up *upstream, repo, ref string, accept []string) (*cachedManifest, error) {
+	resp, err := up.do(ctx, http.MethodGet, repo, "manifests/"+ref, http.Header{"Accept": accept})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &upstreamError{status: resp.StatusCode, body: strings.TrimSpace(string(body))}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxManifestSize {
+		return nil, fmt.Errorf("manifest 超过 %d 字节", maxManifestSize)
+	}
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if digestPattern.MatchString(ref) && digest != ref {
+		return nil, fmt.Errorf("manifest 摘要不一致: 期望 %s，实际 %s", ref, digest)
+	}
+	m := &cachedManifest{digest: digest, mediaType: resp.Header.Get("Content-Type"), body: body}
+	if err := s.cache.putManifest(m); err != nil {
+		s.logger.Warnf("镜像缓存: 写入 manifest %s 失败: %v", digest, err)
+	}
+	return m, nil
+}
+
+// serveBlob 提供镜像层：已缓存时直接读取（支持 Range），否则从上游下载，边写入缓存边返回给客户端。
+// 同一镜像层同时只下载一次，其他请求等待下载完成后读取缓存
+func (s *Server) serveBlob(w http.ResponseWriter, r *http.Request, host, repo, digest string) {
+	for {
+		if f := s.cache.openBlob(digest); f != nil {
+			defer f.Close()
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Docker-Content-Digest", digest)
+			http.ServeContent(w, r, "", time.Time{}, f)
+			return
+		}
+		if r.Method == http.MethodHead {
+			s.proxyBlobHead(w, r, host, repo, digest)
+			return
+		}
+
+		s.mu.Lock()
+		done, downloading := s.inflight[digest]
+		if !downloading {
+			done = make(chan struct{})
+			s.inflight[digest] = done
+		}
+		s.mu.Unlock()
+		if !downloading {
+			s.downloadBlob(w, r, host, repo, digest, done)
+			return
+		}
+		select {
+		case <-done:
+			// 下载失败时缓存中仍然没有，由本请求重新下载
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// downloadBlob 从上游下载镜像层，同时写入临时文件与客户端，校验摘要后放入缓存
+func (s *Server) downloadBlob(w http.ResponseWriter, r *http.Request, host, repo, digest string, done chan struct{}) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, digest)
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	resp, err := s.upstream(host).do(s.ctx, http.MethodGet, repo, "blobs/"+digest, nil)
+	if err != nil {
+		s.writeUpstreamError(w, err, "BLOB_UNKNOWN", host, repo, digest)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		s.writeUpstreamError(w, &upstreamError{status: resp.StatusCode, body: strings.TrimSpace(string(body))}, "BLOB_UNKNOWN", host, repo, digest)
+		return
+	}
+
+	tmp, err := s.cache.tempFile()
+	if err != nil {
+		s.logger.Warnf("镜像缓存: 创建临时文件失败: %v", err)
+		writeError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest)
+	if resp.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	hash := sha256.New()
+	client := &clientWriter{w: w}
+	_, copyErr := io.Copy(io.MultiWriter(tmp, hash, client), resp.Body)
+	closeErr := tmp.Close()
+	if copyErr != nil || closeErr != nil {
+		s.logger.Warnf("镜像缓存: 下载 %s/%s@%s 失败: %v", host, repo, digest, errors.Join(copyErr, closeErr))
+		return
+	}
+	if got := "sha256:" + hex.EncodeToString(hash.Sum(nil)); got != digest {
+		s.logger.Warnf("镜像缓存: %s/%s 的镜像层摘要不一致: 期望 %s，实际 %s", host, repo, digest, got)
+		return
+	}
+	if err := s.cache.commitBlob(tmp.Name(), digest); err != nil {
+		s.logger.Warnf("镜像缓存: 写入镜像层 %s 失败: %v", digest, err)
+		return
+	}
+	s.logger.Debugf("镜像缓存: 已缓存 %s/%s@%s", host, repo, digest)
+
+	if s.maxSize > 0 {
+		if removed, freed, err := s.cache.evict(s.maxSize); err != nil {
+			s.logger.Warnf("镜像缓存: 清理失败: %v", err)
+		} else if removed > 0 {
+			s.logger.Infof("镜像缓存: 超过上限，删除了 %d 个最久未使用的镜像层（%d 字节）", removed, freed)
+		}
+	}
+}
+
+// proxyBlobHead 把未缓存镜像层的 HEAD 请求转发给上游
+func (s *Server) proxyBlobHead(w http.ResponseWriter, r *http.Request, host, repo, digest string) {
+	resp, err := s.upstream(host).do(r.Context(), http.MethodHead, repo, "blobs/"+digest, nil)
+	if err != nil {
+		s.writeUpstreamError(w, err, "BLOB_UNKNOWN", host, repo, digest)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Docker-Content-Digest", digest)
+		if resp.ContentLength >= 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+}
+
+// writeUpstreamError 把上游错误转换为 Registry 错误响应：上游的 404/401/403 原样返回，其他错误返回 502
+func (s *Server) writeUpstreamError(w http.ResponseWriter, err error, code, host, repo, ref string) {
+	var upErr *upstreamError
+	if errors.As(err, &upErr) {
+		switch upErr.status {
+		case http.StatusNotFound:
+			writeError(w, http.StatusNotFound, code, fmt.Sprintf("%s/%s %s 不存在", host, repo, ref))
+			return
+		case http.StatusUnauthorized, http.StatusForbidden:
+			writeError(w, upErr.status, "DENIED", err.Error())
+			return
+		}
+	}
+	s.logger.Warnf("镜像缓存: 从上游获取 %s/%s %s 失败: %v", host, repo, ref, err)
+	writeError(w, http.StatusBadGateway, "UNKNOWN", err.Error())
+}
+
+// writeManifest 返回 manifest（HEAD 请求只返回头部）
+func writeManifest(w http.ResponseWriter, r *http.Request, m *cachedManifest) {
+	if m.mediaType != "" {
+		w.Header().Set("Content-Type", m.mediaType)
+	}
+	w.Header().Set("Docker-Content-Digest", m.digest)
+	w.Header().Set("Content-Length", strconv.Itoa(len(m.body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(m.body)
+	}
+}
+
+// writeError 返回 Registry API 格式的错误
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
+
+// clientWriter 向客户端写入；客户端断开后丢弃后续数据，让下载继续写入缓存
+type clientWriter struct {
+	w      io.Writer
+	failed bool
+}
+
+func (c *clientWriter) Write(p []byte) (int, error) {
+	if !c.failed {
+		if _, err := c.w.Write(p); err != nil {
+			c.failed = true
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,259 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"go.uber.org/zap"
+)
+
+func TestMirrorReference(t *testing.T) {
+	cases := []struct {
+		image string
+		want  string
+	}{
+		{"nginx", "127.0.0.1:5000/docker.io/library/nginx"},
+		{"nginx:1.25", "127.0.0.1:5000/docker.io/library/nginx:1.25"},
+		{"grafana/grafana:10.0.0", "127.0.0.1:5000/docker.io/grafana/grafana:10.0.0"},
+		{"docker.io/library/redis:7", "127.0.0.1:5000/docker.io/library/redis:7"},
+		{"ghcr.io/home-assistant/home-assistant:stable", "127.0.0.1:5000/ghcr.io/home-assistant/home-assistant:stable"},
+		{"registry.k8s.io/pause:3.10", "127.0.0.1:5000/registry.k8s.io/pause:3.10"},
+		{"192.168.1.20:5000/app:v1", "127.0.0.1:5000/192.168.1.20:5000/app:v1"},
+		{"127.0.0.1:5000/docker.io/library/nginx:latest", ""},
+	}
+	for _, c := range cases {
+		if got := MirrorReference("127.0.0.1:5000", c.image); got != c.want {
+			t.Errorf("MirrorReference(%q) = %q, want %q", c.image, got, c.want)
+		}
+	}
+	if got := MirrorReference("", "nginx"); got != "" {
+		t.Errorf("expected empty reference without endpoint, got %q", got)
+	}
+}
+
+func TestPullEndpoint(t *testing.T) {
+	cases := []struct {
+		cfg  config.RegistryMirrorConfig
+		want string
+	}{
+		{config.RegistryMirrorConfig{}, ""},
+		{config.RegistryMirrorConfig{Enabled: true}, "127.0.0.1:5000"},
+		{config.RegistryMirrorConfig{Enabled: true, Listen: "0.0.0.0:5050"}, "127.0.0.1:5050"},
+		{config.RegistryMirrorConfig{Endpoint: "192.168.1.10:5000"}, "192.168.1.10:5000"},
+	}
+	for _, c := range cases {
+		if got := PullEndpoint(c.cfg); got != c.want {
+			t.Errorf("PullEndpoint(%+v) = %q, want %q", c.cfg, got, c.want)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" ||
+		params["scope"] != "repository:library/nginx:pull" {
+		t.Errorf("unexpected challenge %q %v", scheme, params)
+	}
+}
+
+// fakeRegistry 需要 Bearer token 的上游仓库，提供 library/nginx:latest 的 manifest 与一个镜像层
+type fakeRegistry struct {
+	*httptest.Server
+	manifest     []byte
+	blob         []byte
+	blobDigest   string
+	blobRequests atomic.Int32
+	tagRequests  atomic.Int32
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	t.Helper()
+	f := &fakeRegistry{blob: []byte("layer data")}
+	sum := sha256.Sum256(f.blob)
+	f.blobDigest = "sha256:" + hex.EncodeToString(sum[:])
+	f.manifest = []byte(fmt.Sprintf(`{"schemaVersion":2,"layers":[{"digest":%q}]}`, f.blobDigest))
+	manifestSum := sha256.Sum256(f.manifest)
+	manifestDigest := "sha256:" + hex.EncodeToString(manifestSum[:])
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:library/nginx:pull" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"token":"secret","expires_in":300}`))
+	})
+	mux.HandleFunc("/v2/library/nginx/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, f.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/library/nginx/manifests/latest", "/v2/library/nginx/manifests/" + manifestDigest:
+			if r.URL.Path == "/v2/library/nginx/manifests/latest" {
+				f.tagRequests.Add(1)
+			}
+			w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+			w.Header().Set("Docker-Content-Digest", manifestDigest)
+			if r.Method == http.MethodHead {
+				return
+			}
+			_, _ = w.Write(f.manifest)
+		case "/v2/library/nginx/blobs/" + f.blobDigest:
+			f.blobRequests.Add(1)
+			_, _ = w.Write(f.blob)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func newTestServer(t *testing.T, upstreamURL, manifestTTL string) (*Server, string) {
+	t.Helper()
+	s, err := NewServer(logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}, config.RegistryMirrorConfig{
+		Enabled:     true,
+		CacheDir:    t.TempDir(),
+		ManifestTTL: manifestTTL,
+		Upstreams:   []config.RegistryUpstreamConfig{{Host: "docker.io", URL: upstreamURL}},
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	t.Cleanup(s.cancel)
+	return s, srv.URL
+}
+
+func get(t *testing.T, url string) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, body
+}
+
+func TestServerPullThrough(t *testing.T) {
+	upstream := newFakeRegistry(t)
+	_, mirror := newTestServer(t, upstream.URL, "")
+
+	resp, _ := get(t, mirror+"/v2/")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Docker-Distribution-API-Version") != "registry/2.0" {
+		t.Fatalf("unexpected /v2/ response %d", resp.StatusCode)
+	}
+
+	// 带域名与不带域名（作为 docker.io 的 registry-mirrors）的仓库名都可以拉取
+	for _, name := range []string{"docker.io/library/nginx", "library/nginx"} {
+		resp, body := get(t, mirror+"/v2/"+name+"/manifests/latest")
+		if resp.StatusCode != http.StatusOK || string(body) != string(upstream.manifest) {
+			t.Fatalf("GET manifest %s: %d %s", name, resp.StatusCode, body)
+		}
+		if resp.Header.Get("Content-Type") != "application/vnd.docker.distribution.manifest.v2+json" {
+			t.Errorf("unexpected manifest content type %q", resp.Header.Get("Content-Type"))
+		}
+	}
+	if n := upstream.tagRequests.Load(); n != 1 {
+		t.Errorf("expected tag to be fetched from upstream once, got %d", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, body := get(t, mirror+"/v2/docker.io/library/nginx/blobs/"+upstream.blobDigest)
+		if resp.StatusCode != http.StatusOK || string(body) != string(upstream.blob) {
+			t.Fatalf("GET blob: %d %s", resp.StatusCode, body)
+		}
+		if resp.Header.Get("Docker-Content-Digest") != upstream.blobDigest {
+			t.Errorf("unexpected digest header %q", resp.Header.Get("Docker-Content-Digest"))
+		}
+	}
+	if n := upstream.blobRequests.Load(); n != 1 {
+		t.Errorf("expected blob to be downloaded from upstream once, got %d", n)
+	}
+
+	resp, _ = get(t, mirror+"/v2/docker.io/library/nginx/manifests/missing")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown tag, got %d", resp.StatusCode)
+	}
+	req, _ := http.NewRequest(http.MethodPut, mirror+"/v2/docker.io/library/nginx/manifests/latest", nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected push to be rejected, got %v (err=%v)", resp, err)
+	}
+}
+
+func TestServerServesStaleManifest(t *testing.T) {
+	upstream := newFakeRegistry(t)
+	_, mirror := newTestServer(t, upstream.URL, "1ns")
+
+	if resp, _ := get(t, mirror+"/v2/docker.io/library/nginx/manifests/latest"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET manifest: %d", resp.StatusCode)
+	}
+	// 过期后用 HEAD 向上游确认，不重新下载
+	if resp, _ := get(t, mirror+"/v2/docker.io/library/nginx/manifests/latest"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET manifest: %d", resp.StatusCode)
+	}
+	if n := upstream.tagRequests.Load(); n != 2 {
+		t.Errorf("expected one GET and one HEAD for the tag, got %d requests", n)
+	}
+
+	// 上游不可用时使用过期的缓存
+	upstream.Close()
+	resp, body := get(t, mirror+"/v2/docker.io/library/nginx/manifests/latest")
+	if resp.StatusCode != http.StatusOK || string(body) != string(upstream.manifest) {
+		t.Errorf("expected stale manifest, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestCacheEvict(t *testing.T) {
+	c, err := newCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("newCache: %v", err)
+	}
+	base := time.Now().Add(-time.Hour)
+	var digests []string
+	for i := 0; i < 3; i++ {
+		digest := fmt.Sprintf("sha256:%064d", i)
+		if err := os.WriteFile(c.blobPath(digest), make([]byte, 100), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		accessed := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(c.blobPath(digest), accessed, accessed); err != nil {
+			t.Fatal(err)
+		}
+		digests = append(digests, digest)
+	}
+	// 最早的镜像层刚被读取过，不应被删除
+	if f := c.openBlob(digests[0]); f != nil {
+		f.Close()
+	}
+
+	removed, freed, err := c.evict(150)
+	if err != nil || removed != 2 || freed != 200 {
+		t.Fatalf("evict = %d, %d, %v", removed, freed, err)
+	}
+	for i, digest := range digests {
+		_, err := os.Stat(c.blobPath(digest))
+		if exists := err == nil; exists != (i == 0) {
+			t.Errorf("blob %d exists=%v", i, exists)
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Join(c.dir, "tmp")); len(entries) != 0 {
+		t.Errorf("tmp should be empty, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,199 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+// dockerHubURL 是 docker.io 的 Registry API 地址
+const dockerHubURL = "https://registry-1.docker.io"
+
+// upstream 一个上游仓库的客户端，按 WWW-Authenticate 完成匿名/账号认证并缓存 token
+type upstream struct {
+	host     string
+	base     string
+	username string
+	password string
+	client   *http.Client
+
+	mu     sync.Mutex
+	basic  bool                   // 上游使用 Basic 认证
+	tokens map[string]bearerToken // scope -> token
+}
+
+// bearerToken 上游签发的 token 及其过期时间
+type bearerToken struct {
+	value   string
+	expires time.Time
+}
+
+// upstreamError 上游返回的非成功状态
+type upstreamError struct {
+	status int
+	body   string
+}
+
+func (e *upstreamError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("上游返回 %d", e.status)
+	}
+	return fmt.Sprintf("上游返回 %d: %s", e.status, e.body)
+}
+
+// newUpstream 创建上游客户端，cfg.URL 为空时为 https://<host>（docker.io 为 https://registry-1.docker.io）
+func newUpstream(cfg config.RegistryUpstreamConfig, client *http.Client) *upstream {
+	base := cfg.URL
+	if base == "" {
+		base = "https://" + cfg.Host
+		if cfg.Host == DefaultHost {
+			base = dockerHubURL
+		}
+	}
+	return &upstream{
+		host:     cfg.Host,
+		base:     strings.TrimSuffix(base, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		client:   client,
+		tokens:   make(map[string]bearerToken),
+	}
+}
+
+// do 向上游发送 /v2/<repo>/<path> 请求；返回 401 时按 WWW-Authenticate 认证后重试一次
+func (u *upstream) do(ctx context.Context, method, repo, path string, header http.Header) (*http.Response, error) {
+	scope := "repository:" + repo + ":pull"
+	resp, err := u.send(ctx, method, repo, path, header, scope)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if err := u.authenticate(ctx, challenge, scope); err != nil {
+		return nil, err
+	}
+	return u.send(ctx, method, repo, path, header, scope)
+}
+
+func (u *upstream) send(ctx context.Context, method, repo, path string, header http.Header, scope string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.base+"/v2/"+repo+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	u.mu.Lock()
+	if token, ok := u.tokens[scope]; ok && time.Now().Before(token.expires) {
+		req.Header.Set("Authorization", "Bearer "+token.value)
+	} else if u.basic {
+		req.SetBasicAuth(u.username, u.password)
+	}
+	u.mu.Unlock()
+	return u.client.Do(req)
+}
+
+// authenticate 处理上游的认证要求：Basic 认证使用配置的账号；Bearer 认证向 realm 申请 scope 的 token（配置了账号时带上账号）
+func (u *upstream) authenticate(ctx context.Context, challenge, scope string) error {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if u.username == "" {
+			return fmt.Errorf("上游 %s 需要认证，请在 registry_mirror.upstreams 中配置账号", u.host)
+		}
+		u.mu.Lock()
+		u.basic = true
+		u.mu.Unlock()
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("上游 %s 返回 401，不支持的认证方式 %q", u.host, challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return fmt.Errorf("上游 %s 的认证地址无效: %q", u.host, params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if u.username != "" {
+		req.SetBasicAuth(u.username, u.password)
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("向 %s 申请 token 失败: %w", realm.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("向 %s 申请 token 失败: %w", realm.Host, &upstreamError{status: resp.StatusCode, body: strings.TrimSpace(string(body))})
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("解析 %s 签发的 token 失败: %w", realm.Host, err)
+	}
+	value := token.Token
+	if value == "" {
+		value = token.AccessToken
+	}
+	if value == "" {
+		return fmt.Errorf("%s 没有返回 token", realm.Host)
+	}
+	// 规范默认有效期 60s；提前 10s 过期，避免请求途中失效
+	expiresIn := token.ExpiresIn
+	if expiresIn < 60 {
+		expiresIn = 60
+	}
+	u.mu.Lock()
+	u.tokens[scope] = bearerToken{value: value, expires: time.Now().Add(time.Duration(expiresIn-10) * time.Second)}
+	u.mu.Unlock()
+	return nil
+}
+
+// parseChallenge 解析 WWW-Authenticate，如 Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(value)
+		}
+	}
+	return scheme, params
+}
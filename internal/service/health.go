@@ -4,15 +4,17 @@ import (
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	"github.com/gofiber/fiber/v2"
 )
 
 func NewHealthService(fiber webprovider.FiberEngine, l logprovider.Logger,
-	config config.Config) HealthService {
+	config config.Config, store storage.Store) HealthService {
 	return HealthService{
 		fiber:  fiber,
 		l:      l,
 		config: config,
+		store:  store,
 	}
 }
 
@@ -20,6 +22,7 @@ type HealthService struct {
 	fiber  webprovider.FiberEngine
 	l      logprovider.Logger
 	config config.Config
+	store  storage.Store
 }
 
 func (s HealthService) Check(ctx *fiber.Ctx) error {
@@ -32,3 +35,20 @@ func (s HealthService) CheckFiber(c *fiber.Ctx) error {
 		"code": 200,
 	})
 }
+
+// Ready 返回存储后端状态：熔断打开（MySQL/etcd 不可用）时返回 503，便于负载均衡/探针摘除本实例
+func (s HealthService) Ready(c *fiber.Ctx) error {
+	health := storage.BackendHealth{Backend: s.config.Storage.Type, Ready: true, State: storage.CircuitClosed}
+	if reporter, ok := s.store.(storage.HealthReporter); ok {
+		health = reporter.Health()
+	}
+	code := fiber.StatusOK
+	if !health.Ready {
+		code = fiber.StatusServiceUnavailable
+	}
+	return c.Status(code).JSON(fiber.Map{
+		"code":    code,
+		"ready":   health.Ready,
+		"storage": health,
+	})
+}
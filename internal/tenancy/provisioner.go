@@ -0,0 +1,420 @@
+package tenancy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/controller"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/fx"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// fieldManager 是注入默认资源时使用的写入者名称
+	fieldManager = "k3-namespace-provisioner"
+	// component 记录 Event 时的组件名
+	component = "namespace-provisioner"
+
+	// SkipAnnotation 为 "true" 的 namespace 不注入默认资源
+	SkipAnnotation = "k3.io/skip-defaults"
+	// ProvisionedAnnotation 记录已注入的默认资源（Kind/name，逗号分隔）。带有该注解的 namespace 不再注入，
+	// 管理员删除或修改默认资源后不会被恢复
+	ProvisionedAnnotation = "k3.io/defaults-provisioned"
+)
+
+// NamespaceGVK 是 core/v1 Namespace（集群级）
+var NamespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+
+// defaultExcludeNamespaces 未配置 tenancy.exclude_namespaces 时不注入的 namespace
+var defaultExcludeNamespaces = []string{"default", "kube-system", "kube-public", "kube-node-lease"}
+
+// DefaultTemplates 内置的默认资源模板：限制 Pod 数与 requests/limits 总量的配额、容器默认的 requests/limits、
+// 只允许同一 namespace 内访问的网络策略，以及 default ServiceAccount
+const DefaultTemplates = `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: default-quota
+spec:
+  hard:
+    pods: "20"
+    services: "10"
+    configmaps: "50"
+    secrets: "50"
+    requests.cpu: "4"
+    requests.memory: 8Gi
+    limits.cpu: "8"
+    limits.memory: 16Gi
+---
+apiVersion: v1
+kind: LimitRange
+metadata:
+  name: default-limits
+spec:
+  limits:
+  - type: Container
+    default:
+      cpu: 500m
+      memory: 512Mi
+    defaultRequest:
+      cpu: 100m
+      memory: 128Mi
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: default-isolation
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+    - podSelector: {}
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: default
+`
+
+// Module 开启 tenancy.enabled 时启动 namespace 初始化控制器（只应在带 apiserver 的进程中使用，避免多个节点重复注入）
+var Module = fx.Options(
+	fx.Invoke(func(lc fx.Lifecycle, cfg config.Config, logger logprovider.Logger, store storage.Store) error {
+		if !cfg.Tenancy.Enabled {
+			return nil
+		}
+		p, err := NewProvisioner(store, logger, cfg.Tenancy)
+		if err != nil {
+			return err
+		}
+		lc.Append(fx.Hook{
+			OnStart: func(context.Context) error { return p.Start(context.Background()) },
+			OnStop:  p.Stop,
+		})
+		return nil
+	}),
+)
+
+// TemplateData 是渲染模板时可以使用的变量
+type TemplateData struct {
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// namespacedTemplate 一个模板文档
+type namespacedTemplate struct {
+	source string
+	tmpl   *template.Template
+}
+
+// Provisioner 在 Namespace 创建后按模板注入默认资源。已存在的同名资源不覆盖；
+// 注入完成后在 Namespace 上记录 ProvisionedAnnotation，之后不再处理该 namespace
+type Provisioner struct {
+	store       storage.Store
+	logger      logprovider.Logger
+	templates   []namespacedTemplate
+	exclude     map[string]bool
+	parser      *parser.Parser
+	conversions *apiserver.ConversionRegistry
+	stopCh      chan struct{}
+	done        chan struct{}
+}
+
+// NewProvisioner 按配置加载模板（cfg.Templates 为空时使用 DefaultTemplates），并用示例 namespace 渲染一次校验模板
+func NewProvisioner(store storage.Store, logger logprovider.Logger, cfg config.TenancyConfig) (*Provisioner, error) {
+	p := &Provisioner{
+		store:       store,
+		logger:      logger,
+		exclude:     make(map[string]bool),
+		parser:      parser.NewParser(),
+		conversions: apiserver.DefaultConversions(),
+		stopCh:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	exclude := cfg.ExcludeNamespaces
+	if exclude == nil {
+		exclude = defaultExcludeNamespaces
+	}
+	for _, ns := range exclude {
+		p.exclude[ns] = true
+	}
+
+	sources := map[string]string{"内置模板": DefaultTemplates}
+	if cfg.Templates != "" {
+		var err error
+		if sources, err = readTemplates(cfg.Templates); err != nil {
+			return nil, fmt.Errorf("读取 tenancy.templates 失败: %w", err)
+		}
+	}
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for i, doc := range splitDocuments(sources[name]) {
+			source := fmt.Sprintf("%s#%d", name, i+1)
+			tmpl, err := template.New(source).Option("missingkey=zero").Parse(doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			p.templates = append(p.templates, namespacedTemplate{source: source, tmpl: tmpl})
+		}
+	}
+	if _, err := p.render(&corev1.Namespace{}, "example"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// readTemplates 读取模板文件，或目录（含子目录）中的 .yaml/.yml/.json 文件
+func readTemplates(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[string]string)
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources[filepath.Base(path)] = string(data)
+		return sources, nil
+	}
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(path, file)
+		sources[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	return sources, err
+}
+
+// splitDocuments 按 "---" 行拆分多文档 YAML，跳过空文档（渲染前拆分，模板语法不跨文档）
+func splitDocuments(data string) []string {
+	var docs []string
+	var cur []string
+	flush := func() {
+		doc := strings.Join(cur, "\n")
+		if strings.TrimSpace(doc) != "" {
+			docs = append(docs, doc)
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimRight(line, " \t\r") == "---" {
+			flush()
+			continue
+		}
+		cur = append(cur, line)
+	}
+	flush()
+	return docs
+}
+
+// desiredObject 渲染后的默认资源
+type desiredObject struct {
+	obj  runtime.Object
+	gvk  schema.GroupVersionKind // 存储版本
+	name string
+}
+
+// key 返回资源在 ProvisionedAnnotation 中的名称
+func (o desiredObject) key() string {
+	return o.gvk.Kind + "/" + o.name
+}
+
+// render 为 namespace 渲染所有模板，转换为存储版本；只允许 apiserver 提供的 namespace 级资源，模板中的 namespace 被忽略
+func (p *Provisioner) render(ns *corev1.Namespace, name string) ([]desiredObject, error) {
+	data := TemplateData{Namespace: name, Labels: ns.Labels, Annotations: ns.Annotations}
+	out := make([]desiredObject, 0, len(p.templates))
+	seen := make(map[string]string)
+	for _, t := range p.templates {
+		var buf bytes.Buffer
+		if err := t.tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.source, err)
+		}
+		if strings.TrimSpace(buf.String()) == "" {
+			// 条件模板（{{ if ... }}）可以不产生资源
+			continue
+		}
+		obj, gvk, err := p.parser.ParseYAML(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.source, err)
+		}
+		if gvk == nil || p.conversions.Versions(gvk.GroupKind()) == nil {
+			return nil, fmt.Errorf("%s: 不支持的资源", t.source)
+		}
+		storageGVK, err := p.conversions.StorageGVK(*gvk)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.source, err)
+		}
+		if storage.IsClusterScoped(storageGVK) {
+			return nil, fmt.Errorf("%s: %s 是集群级资源，不能作为 namespace 的默认资源", t.source, gvk.Kind)
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.source, err)
+		}
+		if accessor.GetName() == "" {
+			return nil, fmt.Errorf("%s: %s 缺少 metadata.name", t.source, gvk.Kind)
+		}
+		accessor.SetNamespace(name)
+		obj, err = p.conversions.ToStorage(obj, *gvk)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.source, err)
+		}
+		o := desiredObject{obj: obj, gvk: storageGVK, name: accessor.GetName()}
+		if prev, ok := seen[o.key()]; ok {
+			return nil, fmt.Errorf("%s: %s 与 %s 重复", t.source, o.key(), prev)
+		}
+		seen[o.key()] = t.source
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+// Name 返回控制器名称
+func (p *Provisioner) Name() string {
+	return "NamespaceProvisioner"
+}
+
+// Start watch Namespace：启动时处理还没有注入过的 namespace（控制器未运行期间创建的），之后处理新创建的 namespace
+func (p *Provisioner) Start(ctx context.Context) error {
+	watchCh, err := p.store.Watch(NamespaceGVK, "", "")
+	if err != nil {
+		close(p.done)
+		return fmt.Errorf("watch Namespace 失败: %w", err)
+	}
+	p.logger.Infof("启动 namespace 初始化控制器（%d 个默认资源模板）", len(p.templates))
+	go func() {
+		defer close(p.done)
+		if objs, err := p.store.List(NamespaceGVK, ""); err != nil {
+			p.logger.Warnf("列出 Namespace 失败: %v", err)
+		} else {
+			for _, obj := range objs {
+				if ns, ok := obj.(*corev1.Namespace); ok {
+					p.provision(ns)
+				}
+			}
+		}
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			case event, ok := <-watchCh:
+				if !ok {
+					return
+				}
+				if ns, ok := event.Object.(*corev1.Namespace); ok && event.Type == storage.EventAdded {
+					p.provision(ns)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 停止控制器
+func (p *Provisioner) Stop(ctx context.Context) error {
+	close(p.stopCh)
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// provision 为一个 namespace 注入默认资源并记录结果
+func (p *Provisioner) provision(ns *corev1.Namespace) {
+	if p.exclude[ns.Name] || ns.DeletionTimestamp != nil || ns.Status.Phase == corev1.NamespaceTerminating ||
+		ns.Annotations[SkipAnnotation] == "true" || ns.Annotations[ProvisionedAnnotation] != "" {
+		return
+	}
+	created, err := p.Provision(ns)
+	if err != nil {
+		p.logger.Warnf("为 namespace %s 注入默认资源失败: %v", ns.Name, err)
+		_ = controller.RecordEvent(p.store, ns, corev1.EventTypeWarning, "ProvisionFailed", fmt.Sprintf("注入默认资源失败: %v", err), component)
+		return
+	}
+	p.logger.Infof("已为 namespace %s 注入默认资源: %s", ns.Name, strings.Join(created, ", "))
+	_ = controller.RecordEvent(p.store, ns, corev1.EventTypeNormal, "DefaultsProvisioned", fmt.Sprintf("已注入默认资源: %s", strings.Join(created, ", ")), component)
+}
+
+// Provision 渲染模板并创建 namespace 中还不存在的默认资源（已存在的不覆盖），然后在 Namespace 上记录 ProvisionedAnnotation。
+// 返回新创建的资源（Kind/name）；部分资源创建失败时不记录注解，下次启动时重试
+func (p *Provisioner) Provision(ns *corev1.Namespace) ([]string, error) {
+	objs, err := p.render(ns, ns.Name)
+	if err != nil {
+		return nil, err
+	}
+	created := make([]string, 0, len(objs))
+	provisioned := make([]string, 0, len(objs))
+	for _, o := range objs {
+		provisioned = append(provisioned, o.key())
+		if _, err := p.store.Get(o.gvk, ns.Name, o.name); err == nil {
+			continue
+		} else if storage.IsBackendError(err) {
+			return created, err
+		}
+		apiserver.SetDefaults(o.obj)
+		storage.RecordManager(o.obj, fieldManager)
+		if err := p.store.Create(o.gvk, o.obj); err != nil {
+			return created, fmt.Errorf("创建 %s 失败: %w", o.key(), err)
+		}
+		created = append(created, o.key())
+	}
+
+	current, err := p.store.Get(NamespaceGVK, "", ns.Name)
+	if err != nil {
+		return created, err
+	}
+	latest, ok := current.(*corev1.Namespace)
+	if !ok {
+		return created, fmt.Errorf("unexpected object type %T for Namespace %s", current, ns.Name)
+	}
+	latest = latest.DeepCopy()
+	if latest.Annotations == nil {
+		latest.Annotations = make(map[string]string)
+	}
+	value := strings.Join(provisioned, ",")
+	if value == "" {
+		// 没有任何模板时同样记录，避免每次启动重复处理
+		value = "none"
+	}
+	latest.Annotations[ProvisionedAnnotation] = value
+	if _, err := storage.UpdateAs(p.store, NamespaceGVK, latest, fieldManager, true); err != nil {
+		return created, fmt.Errorf("更新 Namespace 注解失败: %w", err)
+	}
+	return created, nil
+}
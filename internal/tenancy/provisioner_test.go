@@ -0,0 +1,252 @@
+package tenancy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	resourceQuotaGVK  = schema.GroupVersionKind{Version: "v1", Kind: "ResourceQuota"}
+	limitRangeGVK     = schema.GroupVersionKind{Version: "v1", Kind: "LimitRange"}
+	serviceAccountGVK = schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}
+)
+
+func testLogger() logprovider.Logger {
+	return logprovider.Logger{SugaredLogger: zap.NewNop().Sugar()}
+}
+
+func newNamespace(name string, annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations, Labels: map[string]string{"team": "a"}},
+	}
+}
+
+func getNamespace(t *testing.T, store storage.Store, name string) *corev1.Namespace {
+	t.Helper()
+	obj, err := store.Get(NamespaceGVK, "", name)
+	if err != nil {
+		t.Fatalf("get namespace %s: %v", name, err)
+	}
+	return obj.(*corev1.Namespace)
+}
+
+func TestProvisionDefaults(t *testing.T) {
+	store := storage.NewMemoryStore()
+	p, err := NewProvisioner(store, testLogger(), config.TenancyConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("NewProvisioner: %v", err)
+	}
+
+	// 已存在的同名资源不覆盖
+	existing := &corev1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "team-a", Labels: map[string]string{"keep": "true"}},
+	}
+	if err := store.Create(serviceAccountGVK, existing); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(NamespaceGVK, newNamespace("team-a", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := p.Provision(getNamespace(t, store, "team-a"))
+	if err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+	want := "ResourceQuota/default-quota,LimitRange/default-limits,NetworkPolicy/default-isolation"
+	if strings.Join(created, ",") != want {
+		t.Fatalf("created = %v, want %s", created, want)
+	}
+
+	obj, err := store.Get(resourceQuotaGVK, "team-a", "default-quota")
+	if err != nil {
+		t.Fatalf("get quota: %v", err)
+	}
+	if quota := obj.(*corev1.ResourceQuota); quota.Spec.Hard.Pods().Value() != 20 {
+		t.Fatalf("quota pods = %v", quota.Spec.Hard.Pods())
+	}
+	obj, err = store.Get(limitRangeGVK, "team-a", "default-limits")
+	if err != nil {
+		t.Fatalf("get limitrange: %v", err)
+	}
+	if lr := obj.(*corev1.LimitRange); lr.Spec.Limits[0].Default.Memory().String() != "512Mi" {
+		t.Fatalf("limitrange = %+v", lr.Spec)
+	}
+	obj, err = store.Get(apiserver.NetworkPolicyGVK, "team-a", "default-isolation")
+	if err != nil {
+		t.Fatalf("get networkpolicy: %v", err)
+	}
+	if np := obj.(*networkingv1.NetworkPolicy); len(np.Spec.PolicyTypes) != 1 || np.Spec.PolicyTypes[0] != networkingv1.PolicyTypeIngress {
+		t.Fatalf("networkpolicy = %+v", np.Spec)
+	}
+	obj, err = store.Get(serviceAccountGVK, "team-a", "default")
+	if err != nil {
+		t.Fatalf("get serviceaccount: %v", err)
+	}
+	if obj.(*corev1.ServiceAccount).Labels["keep"] != "true" {
+		t.Fatal("existing ServiceAccount was overwritten")
+	}
+
+	ns := getNamespace(t, store, "team-a")
+	if got := ns.Annotations[ProvisionedAnnotation]; got != want+",ServiceAccount/default" {
+		t.Fatalf("%s = %q", ProvisionedAnnotation, got)
+	}
+}
+
+func TestProvisionTemplatesFromDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"quota.yaml": `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: quota
+  namespace: ignored
+spec:
+  hard:
+    pods: "{{ if eq (index .Labels "tier") "large" }}50{{ else }}5{{ end }}"
+`,
+		"sub/cm.yml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: tenant-info
+data:
+  namespace: "{{ .Namespace }}"
+---
+{{ if .Annotations.owner }}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: owner
+data:
+  owner: "{{ .Annotations.owner }}"
+{{ end }}
+`,
+		"README.md": "not a template",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := storage.NewMemoryStore()
+	p, err := NewProvisioner(store, testLogger(), config.TenancyConfig{Enabled: true, Templates: dir})
+	if err != nil {
+		t.Fatalf("NewProvisioner: %v", err)
+	}
+	ns := newNamespace("team-b", nil)
+	ns.Labels["tier"] = "large"
+	if err := store.Create(NamespaceGVK, ns); err != nil {
+		t.Fatal(err)
+	}
+	created, err := p.Provision(getNamespace(t, store, "team-b"))
+	if err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+	if strings.Join(created, ",") != "ResourceQuota/quota,ConfigMap/tenant-info" {
+		t.Fatalf("created = %v", created)
+	}
+	obj, err := store.Get(resourceQuotaGVK, "team-b", "quota")
+	if err != nil {
+		t.Fatalf("get quota: %v", err)
+	}
+	if pods := obj.(*corev1.ResourceQuota).Spec.Hard.Pods().Value(); pods != 50 {
+		t.Fatalf("pods = %d", pods)
+	}
+	obj, err = store.Get(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "team-b", "tenant-info")
+	if err != nil {
+		t.Fatalf("get configmap: %v", err)
+	}
+	if got := obj.(*corev1.ConfigMap).Data["namespace"]; got != "team-b" {
+		t.Fatalf("namespace = %q", got)
+	}
+}
+
+func TestNewProvisionerRejectsInvalidTemplates(t *testing.T) {
+	for name, content := range map[string]string{
+		"cluster-scoped": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: other\n",
+		"no name":        "apiVersion: v1\nkind: ServiceAccount\nmetadata: {}\n",
+		"syntax":         "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: {{ .Namespace\n",
+		"duplicate":      "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: a\n",
+	} {
+		path := filepath.Join(t.TempDir(), "templates.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewProvisioner(storage.NewMemoryStore(), testLogger(), config.TenancyConfig{Templates: path}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestProvisionerWatch(t *testing.T) {
+	store := storage.NewMemoryStore()
+	// 启动前创建、还没有注入过的 namespace 在启动时处理
+	if err := store.Create(NamespaceGVK, newNamespace("before", nil)); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProvisioner(store, testLogger(), config.TenancyConfig{Enabled: true, ExcludeNamespaces: []string{"excluded"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop(context.Background())
+
+	for _, ns := range []*corev1.Namespace{
+		newNamespace("after", nil),
+		newNamespace("excluded", nil),
+		newNamespace("skipped", map[string]string{SkipAnnotation: "true"}),
+	} {
+		if err := store.Create(NamespaceGVK, ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitQuota := func(ns string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if _, err := store.Get(resourceQuotaGVK, ns, "default-quota"); err == nil {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("namespace %s was not provisioned", ns)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitQuota("before")
+	waitQuota("after")
+
+	// after 之后创建的 namespace 处理完时，被排除与跳过的 namespace 也已经处理过
+	if err := store.Create(NamespaceGVK, newNamespace("last", nil)); err != nil {
+		t.Fatal(err)
+	}
+	waitQuota("last")
+	for _, ns := range []string{"excluded", "skipped"} {
+		if _, err := store.Get(resourceQuotaGVK, ns, "default-quota"); err == nil {
+			t.Errorf("namespace %s should not be provisioned", ns)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package
+// +groupName=k3.io
+
+// Package v1 定义 k3.io/v1 组下的 k3 自有资源（目前只有 Device）
+package v1
@@ -0,0 +1,51 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupName 是 k3 自有资源的 API 组
+const GroupName = "k3.io"
+
+// SchemeGroupVersion 是本包资源的 GroupVersion
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1"}
+
+// DeviceGVK 是 Device 的 GroupVersionKind
+var DeviceGVK = SchemeGroupVersion.WithKind("Device")
+
+// ClientUsageGVK 是 ClientUsage 的 GroupVersionKind
+var ClientUsageGVK = SchemeGroupVersion.WithKind("ClientUsage")
+
+// GitRepositoryGVK 是 GitRepository 的 GroupVersionKind
+var GitRepositoryGVK = SchemeGroupVersion.WithKind("GitRepository")
+
+// ClusterConfigurationGVK 是 ClusterConfiguration 的 GroupVersionKind
+var ClusterConfigurationGVK = SchemeGroupVersion.WithKind("ClusterConfiguration")
+
+// ClusterInfoGVK 是 ClusterInfo 的 GroupVersionKind
+var ClusterInfoGVK = SchemeGroupVersion.WithKind("ClusterInfo")
+
+var (
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	// AddToScheme 把 k3.io/v1 的类型注册到 scheme（parser 会注册到 client-go 的全局 scheme）
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&Device{},
+		&DeviceList{},
+		&ClientUsage{},
+		&ClientUsageList{},
+		&GitRepository{},
+		&GitRepositoryList{},
+		&ClusterConfiguration{},
+		&ClusterConfigurationList{},
+		&ClusterInfo{},
+		&ClusterInfoList{},
+	)
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+	return nil
+}
@@ -0,0 +1,290 @@
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Device 是局域网中的一台设备（集群级资源），由节点的 inventory 控制器根据 ARP/neighbor 表维护。
+// 名称由 MAC 生成（例如 aa-bb-cc-dd-ee-ff），IP 变化时仍是同一个 Device；没有 MAC 时按 IP 生成。
+type Device struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DeviceSpec   `json:"spec,omitempty"`
+	Status DeviceStatus `json:"status,omitempty"`
+}
+
+// DeviceSpec 是用户维护的设备信息，控制器不会修改
+type DeviceSpec struct {
+	// Description 设备备注
+	Description string `json:"description,omitempty"`
+}
+
+// DeviceStatus 是控制器观察到的设备状态
+type DeviceStatus struct {
+	// IP 最近一次观察到的 IPv4 地址
+	IP string `json:"ip,omitempty"`
+	// MAC 硬件地址（小写，冒号分隔）
+	MAC string `json:"mac,omitempty"`
+	// Hostname 邻居表中的主机名或反向解析结果（best-effort）
+	Hostname string `json:"hostname,omitempty"`
+	// Online 最近 offlineAfter 内是否在邻居表中出现过
+	Online bool `json:"online"`
+	// LastSeen 最近一次出现在邻居表中的时间
+	LastSeen metav1.Time `json:"lastSeen,omitempty"`
+	// ObservedBy 最近一次观察到该设备的节点
+	ObservedBy string `json:"observedBy,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DeviceList 是 Device 的列表
+type DeviceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Device `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClientUsage 是一个客户端（身份 + User-Agent）对 apiserver 的累计请求统计（集群级资源），
+// 由 apiserver 定期写入，用于找出共享集群中请求过多的控制器或看板。名称由身份与 User-Agent 生成
+type ClientUsage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ClientUsageStatus `json:"status,omitempty"`
+}
+
+// ClientUsageStatus 是累计的请求统计（多个 apiserver 各自累加到同一个对象）
+type ClientUsageStatus struct {
+	// User 请求身份；未开启认证时为 anonymous
+	User string `json:"user"`
+	// UserAgent User-Agent 的产品名（例如 kubectl、k3）
+	UserAgent string `json:"userAgent,omitempty"`
+	// Requests 请求数
+	Requests int64 `json:"requests"`
+	// Errors 状态码 >= 400 的请求数
+	Errors int64 `json:"errors"`
+	// RequestBytes 请求体字节数
+	RequestBytes int64 `json:"requestBytes"`
+	// ResponseBytes 响应体字节数（watch 等流式响应不计）
+	ResponseBytes int64 `json:"responseBytes"`
+	// Verbs 按请求方法统计的请求数（GET/POST/PUT/PATCH/DELETE）
+	Verbs map[string]int64 `json:"verbs,omitempty"`
+	// FirstSeen 开始统计的时间
+	FirstSeen metav1.Time `json:"firstSeen,omitempty"`
+	// LastSeen 最近一次请求的时间
+	LastSeen metav1.Time `json:"lastSeen,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClientUsageList 是 ClientUsage 的列表
+type ClientUsageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClientUsage `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GitRepository 声明一个 git 仓库中的 manifest 目录：gitops 控制器按 interval 拉取仓库，
+// 把目录中的资源（有 kustomization.yaml 时先执行 kustomize build）写入集群，并在开启 prune 时删除仓库中已移除的资源
+type GitRepository struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GitRepositorySpec   `json:"spec,omitempty"`
+	Status GitRepositoryStatus `json:"status,omitempty"`
+}
+
+// GitRepositorySpec 是仓库地址与同步策略
+type GitRepositorySpec struct {
+	// URL 仓库地址（https://、ssh:// 或 git@host:path；ssh 使用运行 k3 的用户的 ssh 配置）
+	URL string `json:"url"`
+	// Ref 分支、tag 或 commit，为空时使用远端默认分支
+	Ref string `json:"ref,omitempty"`
+	// Path manifest 目录（相对仓库根目录），为空时为仓库根目录
+	Path string `json:"path,omitempty"`
+	// Interval 同步周期，默认 5m
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// Prune 删除之前由本仓库创建、但已从仓库中移除的资源
+	Prune bool `json:"prune,omitempty"`
+	// Suspend 暂停同步
+	Suspend bool `json:"suspend,omitempty"`
+	// TargetNamespace 没有指定 namespace 的资源写入的 namespace，为空时使用 GitRepository 所在的 namespace
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// SecretRef 同 namespace 中保存 https 认证信息的 Secret（username/password，password 可以是 token）
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// GitRepositoryStatus 是最近一次同步的结果
+type GitRepositoryStatus struct {
+	// ObservedGeneration 最近一次同步时 spec 的 generation
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Revision 最近一次成功同步的 commit
+	Revision string `json:"revision,omitempty"`
+	// LastSyncTime 最近一次同步（无论成功与否）的时间
+	LastSyncTime metav1.Time `json:"lastSyncTime,omitempty"`
+	// Inventory 最近一次成功同步写入的资源，用于 prune
+	Inventory []ManagedResource `json:"inventory,omitempty"`
+	// Conditions 同步状态：Ready 为 True 表示最近一次同步成功
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ManagedResource 是 GitRepository 写入的一个资源
+type ManagedResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GitRepositoryList 是 GitRepository 的列表
+type GitRepositoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []GitRepository `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterConfiguration 是可以在运行时修改的集群配置（集群级资源，名称固定为 cluster）。
+// 各组件 watch 该对象：spec 中设置的字段覆盖配置文件中对应的配置，修改后无需重启即可生效；
+// 未设置的字段以及删除对象后恢复使用配置文件
+type ClusterConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterConfigurationSpec `json:"spec,omitempty"`
+}
+
+// ClusterConfigurationName 是生效的 ClusterConfiguration 的名称，其他名称的对象不会被创建
+const ClusterConfigurationName = "cluster"
+
+// ClusterConfigurationSpec 是覆盖配置文件的运行时配置
+type ClusterConfigurationSpec struct {
+	// LogLevel 日志级别（debug/info/warn/error/fatal），覆盖 log.level
+	LogLevel string `json:"logLevel,omitempty"`
+	// Controllers 按名称开关节点上的可选控制器（SchedulerController、ContainerGC、ImageGC、
+	// DeschedulerController、InventoryController、TTLController）：true 开启配置文件中没有开启的控制器，false 停止控制器
+	Controllers map[string]bool `json:"controllers,omitempty"`
+	// Scheduler 调度策略
+	Scheduler *SchedulerPolicy `json:"scheduler,omitempty"`
+	// ImageGC 镜像回收阈值，覆盖 image_gc
+	ImageGC *ImageGCPolicy `json:"imageGC,omitempty"`
+}
+
+// SchedulingStrategy 调度器在放得下 Pod 的节点中选择节点的策略
+type SchedulingStrategy string
+
+const (
+	// SchedulingStrategySpread 选择同一控制器的 Pod 最少、资源占用比例最低的节点，让副本分散（默认）
+	SchedulingStrategySpread SchedulingStrategy = "Spread"
+	// SchedulingStrategyBinPack 选择资源占用比例最高的节点，把 Pod 集中到少数节点
+	SchedulingStrategyBinPack SchedulingStrategy = "BinPack"
+)
+
+// SchedulerPolicy 是调度器的策略
+type SchedulerPolicy struct {
+	// Strategy 选择节点的策略，为空时为 Spread
+	Strategy SchedulingStrategy `json:"strategy,omitempty"`
+	// DisablePreemption 没有节点放得下 Pod 时不抢占低优先级 Pod
+	DisablePreemption bool `json:"disablePreemption,omitempty"`
+}
+
+// ImageGCPolicy 是镜像回收的阈值，含义与配置文件 image_gc 相同
+type ImageGCPolicy struct {
+	// HighThresholdPercent 镜像所在磁盘使用率超过该值时开始回收，0 表示关闭镜像回收
+	HighThresholdPercent int `json:"highThresholdPercent"`
+	// LowThresholdPercent 回收到使用率低于该值为止
+	LowThresholdPercent int `json:"lowThresholdPercent,omitempty"`
+	// Interval 检查周期（如 5m），为空时使用配置文件 image_gc.interval
+	Interval string `json:"interval,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterConfigurationList 是 ClusterConfiguration 的列表
+type ClusterConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterConfiguration `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterInfo 记录集群身份与各节点的 k3 版本（集群级资源，名称固定为 cluster）。
+// 第一个连接到共享存储的节点创建它；之后加入的节点在写入存储之前检查集群 ID 与版本差异，
+// 不兼容时拒绝启动。只能由节点写入，API 只读（GET /version 也会返回其中的集群身份）
+type ClusterInfo struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterInfoSpec   `json:"spec"`
+	Status ClusterInfoStatus `json:"status,omitempty"`
+}
+
+// ClusterInfoName 是 ClusterInfo 的名称
+const ClusterInfoName = "cluster"
+
+// ClusterInfoSpec 是集群创建时确定、之后不再改变的身份信息
+type ClusterInfoSpec struct {
+	// ClusterUID 集群的唯一标识（UUID）
+	ClusterUID string `json:"clusterUID"`
+	// ClusterID 创建集群的节点配置的 cluster.id（k3 cluster create 生成），没有配置时为空
+	ClusterID string `json:"clusterID,omitempty"`
+	// CreationTime 集群创建时间
+	CreationTime metav1.Time `json:"creationTime"`
+	// K3Version 创建集群的 k3 版本
+	K3Version string `json:"k3Version"`
+	// StorageBackend 共享存储类型（memory/mysql/etcd）
+	StorageBackend string `json:"storageBackend"`
+}
+
+// ClusterInfoStatus 是各节点加入时上报的版本
+type ClusterInfoStatus struct {
+	// Nodes 各节点最近一次加入集群时的版本，按名称排序
+	Nodes []ClusterMember `json:"nodes,omitempty"`
+}
+
+// ClusterMember 是一个节点最近一次加入集群时的版本
+type ClusterMember struct {
+	// Name 节点名
+	Name string `json:"name"`
+	// K3Version 节点运行的 k3 版本
+	K3Version string `json:"k3Version"`
+	// SchemaVersion 节点支持的存储 schema 版本
+	SchemaVersion int `json:"schemaVersion"`
+	// JoinedAt 最近一次加入（启动）的时间
+	JoinedAt metav1.Time `json:"joinedAt"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterInfoList 是 ClusterInfo 的列表
+type ClusterInfoList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterInfo `json:"items"`
+}
@@ -0,0 +1,201 @@
+package v1
+
+// 本文件为 k3.io 资源提供 SwaggerDoc（与 k8s.io/api 的 types_swagger_doc_generated.go 相同的约定），
+// k3 explain 据此输出字段说明。内容与 types.go 中的注释保持一致，修改字段时两处一起修改
+
+var map_Device = map[string]string{
+	"":         "Device 是局域网中的一台设备（集群级资源），由节点的 inventory 控制器根据 ARP/neighbor 表维护。名称由 MAC 生成（例如 aa-bb-cc-dd-ee-ff），IP 变化时仍是同一个 Device；没有 MAC 时按 IP 生成。",
+	"metadata": "标准的对象元数据。",
+	"spec":     "用户维护的设备信息，控制器不会修改。",
+	"status":   "控制器观察到的设备状态。",
+}
+
+func (Device) SwaggerDoc() map[string]string {
+	return map_Device
+}
+
+var map_DeviceSpec = map[string]string{
+	"":            "DeviceSpec 是用户维护的设备信息，控制器不会修改。",
+	"description": "设备备注。",
+}
+
+func (DeviceSpec) SwaggerDoc() map[string]string {
+	return map_DeviceSpec
+}
+
+var map_DeviceStatus = map[string]string{
+	"":           "DeviceStatus 是控制器观察到的设备状态。",
+	"ip":         "最近一次观察到的 IPv4 地址。",
+	"mac":        "硬件地址（小写，冒号分隔）。",
+	"hostname":   "邻居表中的主机名或反向解析结果（best-effort）。",
+	"online":     "最近 offlineAfter 内是否在邻居表中出现过。",
+	"lastSeen":   "最近一次出现在邻居表中的时间。",
+	"observedBy": "最近一次观察到该设备的节点。",
+}
+
+func (DeviceStatus) SwaggerDoc() map[string]string {
+	return map_DeviceStatus
+}
+
+var map_ClientUsage = map[string]string{
+	"":         "ClientUsage 是一个客户端（身份 + User-Agent）对 apiserver 的累计请求统计（集群级资源），由 apiserver 定期写入，用于找出共享集群中请求过多的控制器或看板。名称由身份与 User-Agent 生成。",
+	"metadata": "标准的对象元数据。",
+	"status":   "累计的请求统计。",
+}
+
+func (ClientUsage) SwaggerDoc() map[string]string {
+	return map_ClientUsage
+}
+
+var map_ClientUsageStatus = map[string]string{
+	"":              "ClientUsageStatus 是累计的请求统计（多个 apiserver 各自累加到同一个对象）。",
+	"user":          "请求身份；未开启认证时为 anonymous。",
+	"userAgent":     "User-Agent 的产品名（例如 kubectl、k3）。",
+	"requests":      "请求数。",
+	"errors":        "状态码 >= 400 的请求数。",
+	"requestBytes":  "请求体字节数。",
+	"responseBytes": "响应体字节数（watch 等流式响应不计）。",
+	"verbs":         "按请求方法统计的请求数（GET/POST/PUT/PATCH/DELETE）。",
+	"firstSeen":     "开始统计的时间。",
+	"lastSeen":      "最近一次请求的时间。",
+}
+
+func (ClientUsageStatus) SwaggerDoc() map[string]string {
+	return map_ClientUsageStatus
+}
+
+var map_GitRepository = map[string]string{
+	"":         "GitRepository 声明一个 git 仓库中的 manifest 目录：gitops 控制器按 interval 拉取仓库，把目录中的资源（有 kustomization.yaml 时先执行 kustomize build）写入集群，并在开启 prune 时删除仓库中已移除的资源。",
+	"metadata": "标准的对象元数据。",
+	"spec":     "仓库地址与同步策略。",
+	"status":   "最近一次同步的结果。",
+}
+
+func (GitRepository) SwaggerDoc() map[string]string {
+	return map_GitRepository
+}
+
+var map_GitRepositorySpec = map[string]string{
+	"":                "GitRepositorySpec 是仓库地址与同步策略。",
+	"url":             "仓库地址（https://、ssh:// 或 git@host:path；ssh 使用运行 k3 的用户的 ssh 配置）。",
+	"ref":             "分支、tag 或 commit，为空时使用远端默认分支。",
+	"path":            "manifest 目录（相对仓库根目录），为空时为仓库根目录。",
+	"interval":        "同步周期，默认 5m。",
+	"prune":           "删除之前由本仓库创建、但已从仓库中移除的资源。",
+	"suspend":         "暂停同步。",
+	"targetNamespace": "没有指定 namespace 的资源写入的 namespace，为空时使用 GitRepository 所在的 namespace。",
+	"secretRef":       "同 namespace 中保存 https 认证信息的 Secret（username/password，password 可以是 token）。",
+}
+
+func (GitRepositorySpec) SwaggerDoc() map[string]string {
+	return map_GitRepositorySpec
+}
+
+var map_GitRepositoryStatus = map[string]string{
+	"":                   "GitRepositoryStatus 是最近一次同步的结果。",
+	"observedGeneration": "最近一次同步时 spec 的 generation。",
+	"revision":           "最近一次成功同步的 commit。",
+	"lastSyncTime":       "最近一次同步（无论成功与否）的时间。",
+	"inventory":          "最近一次成功同步写入的资源，用于 prune。",
+	"conditions":         "同步状态：Ready 为 True 表示最近一次同步成功。",
+}
+
+func (GitRepositoryStatus) SwaggerDoc() map[string]string {
+	return map_GitRepositoryStatus
+}
+
+var map_ManagedResource = map[string]string{
+	"": "ManagedResource 是 GitRepository 写入的一个资源。",
+}
+
+func (ManagedResource) SwaggerDoc() map[string]string {
+	return map_ManagedResource
+}
+
+var map_ClusterConfiguration = map[string]string{
+	"":         "ClusterConfiguration 是可以在运行时修改的集群配置（集群级资源，名称固定为 cluster）。各组件 watch 该对象：spec 中设置的字段覆盖配置文件中对应的配置，修改后无需重启即可生效；未设置的字段以及删除对象后恢复使用配置文件。",
+	"metadata": "标准的对象元数据。",
+	"spec":     "覆盖配置文件的运行时配置。",
+}
+
+func (ClusterConfiguration) SwaggerDoc() map[string]string {
+	return map_ClusterConfiguration
+}
+
+var map_ClusterConfigurationSpec = map[string]string{
+	"":            "ClusterConfigurationSpec 是覆盖配置文件的运行时配置。",
+	"logLevel":    "日志级别（debug/info/warn/error/fatal），覆盖 log.level。",
+	"controllers": "按名称开关节点上的可选控制器（SchedulerController、ContainerGC、ImageGC、DeschedulerController、InventoryController、TTLController）：true 开启配置文件中没有开启的控制器，false 停止控制器。",
+	"scheduler":   "调度策略。",
+	"imageGC":     "镜像回收阈值，覆盖 image_gc。",
+}
+
+func (ClusterConfigurationSpec) SwaggerDoc() map[string]string {
+	return map_ClusterConfigurationSpec
+}
+
+var map_SchedulerPolicy = map[string]string{
+	"":                  "SchedulerPolicy 是调度器的策略。",
+	"strategy":          "选择节点的策略：Spread 选择同一控制器的 Pod 最少、资源占用比例最低的节点（默认），BinPack 选择资源占用比例最高的节点。",
+	"disablePreemption": "没有节点放得下 Pod 时不抢占低优先级 Pod。",
+}
+
+func (SchedulerPolicy) SwaggerDoc() map[string]string {
+	return map_SchedulerPolicy
+}
+
+var map_ImageGCPolicy = map[string]string{
+	"":                     "ImageGCPolicy 是镜像回收的阈值，含义与配置文件 image_gc 相同。",
+	"highThresholdPercent": "镜像所在磁盘使用率超过该值时开始回收，0 表示关闭镜像回收。",
+	"lowThresholdPercent":  "回收到使用率低于该值为止。",
+	"interval":             "检查周期（如 5m），为空时使用配置文件 image_gc.interval。",
+}
+
+func (ImageGCPolicy) SwaggerDoc() map[string]string {
+	return map_ImageGCPolicy
+}
+
+var map_ClusterInfo = map[string]string{
+	"":         "ClusterInfo 记录集群身份与各节点的 k3 版本（集群级资源，名称固定为 cluster）。第一个连接到共享存储的节点创建它；之后加入的节点在写入存储之前检查集群 ID 与版本差异，不兼容时拒绝启动。只能由节点写入，API 只读。",
+	"metadata": "标准的对象元数据。",
+	"spec":     "集群创建时确定、之后不再改变的身份信息。",
+	"status":   "各节点加入时上报的版本。",
+}
+
+func (ClusterInfo) SwaggerDoc() map[string]string {
+	return map_ClusterInfo
+}
+
+var map_ClusterInfoSpec = map[string]string{
+	"":               "ClusterInfoSpec 是集群创建时确定、之后不再改变的身份信息。",
+	"clusterUID":     "集群的唯一标识（UUID）。",
+	"clusterID":      "创建集群的节点配置的 cluster.id（k3 cluster create 生成），没有配置时为空。",
+	"creationTime":   "集群创建时间。",
+	"k3Version":      "创建集群的 k3 版本。",
+	"storageBackend": "共享存储类型（memory/mysql/etcd）。",
+}
+
+func (ClusterInfoSpec) SwaggerDoc() map[string]string {
+	return map_ClusterInfoSpec
+}
+
+var map_ClusterInfoStatus = map[string]string{
+	"":      "ClusterInfoStatus 是各节点加入时上报的版本。",
+	"nodes": "各节点最近一次加入集群时的版本，按名称排序。",
+}
+
+func (ClusterInfoStatus) SwaggerDoc() map[string]string {
+	return map_ClusterInfoStatus
+}
+
+var map_ClusterMember = map[string]string{
+	"":              "ClusterMember 是一个节点最近一次加入集群时的版本。",
+	"name":          "节点名。",
+	"k3Version":     "节点运行的 k3 版本。",
+	"schemaVersion": "节点支持的存储 schema 版本。",
+	"joinedAt":      "最近一次加入（启动）的时间。",
+}
+
+func (ClusterMember) SwaggerDoc() map[string]string {
+	return map_ClusterMember
+}
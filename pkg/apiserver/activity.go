@@ -0,0 +1,26 @@
+package apiserver
+
+import "github.com/gofiber/fiber/v2"
+
+// ActivityTracker 记录 API 请求与长连接会话，用于空闲模式（见 internal/idle）判断集群是否有人在用
+type ActivityTracker interface {
+	// Touch 记录一次活动；处于空闲模式时先恢复（可能需要等待存储后端重新就绪）再返回
+	Touch()
+	// Begin 开始一个长连接会话（如 dashboard 的 WebSocket），会话期间不会进入空闲模式；返回结束会话的函数
+	Begin() (end func())
+}
+
+// WithActivityTracker 每个 API 请求处理前记录一次活动
+func WithActivityTracker(tracker ActivityTracker) Option {
+	return func(s *APIServer) {
+		s.activity = tracker
+	}
+}
+
+// trackActivity 记录请求活动：空闲模式下先恢复，再继续处理请求
+func (s *APIServer) trackActivity(c *fiber.Ctx) error {
+	if s.activity != nil {
+		s.activity.Touch()
+	}
+	return c.Next()
+}
@@ -0,0 +1,106 @@
+package apiserver
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/mirror"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Admission 是对象写入 Store 前的准入校验。apiserver 的 create/update/patch 与 dashboard 的 YAML 编辑器
+// 都通过 Admit 校验，不经过 apiserver 的写入方不能绕过 metadata、按 kind 的校验与不可修改字段的限制
+type Admission struct {
+	store storage.Store
+	// serviceCIDRs Service 的 clusterIP 所在网段（为空时不限制）
+	serviceCIDRs []*net.IPNet
+}
+
+// NewAdmission 创建准入校验；serviceCIDRs 为 Service 的 clusterIP 所在网段（network.service_cidrs，为空时不限制）
+func NewAdmission(store storage.Store, serviceCIDRs []*net.IPNet) *Admission {
+	return &Admission{store: store, serviceCIDRs: serviceCIDRs}
+}
+
+// Admit 校验将要写入的对象 obj（存储版本，已经 SetDefaults）：metadata（validateMetadata）、按 kind 的校验（admit），
+// 以及相对 Store 中当前对象 old 不能修改的字段（admitUpdate）。old 为 nil 表示创建，此时同时校验名称。
+// metadata 不合法时返回 *InvalidError，写入 import 模式的镜像时返回 403；Pod 会在校验时解析优先级
+func (a *Admission) Admit(gvk schema.GroupVersionKind, obj, old runtime.Object) error {
+	for _, o := range []runtime.Object{old, obj} {
+		if err := a.AdmitWrite(o); err != nil {
+			return err
+		}
+	}
+	if err := validateMetadata(gvk, obj, old == nil); err != nil {
+		return err
+	}
+	if err := a.admit(obj); err != nil {
+		return err
+	}
+	if old == nil {
+		return nil
+	}
+	return a.admitUpdate(old, obj)
+}
+
+// AdmitWrite 是所有写入路径共用的检查：import 模式的镜像（带 k3.io/imported-from）是只读的，返回 403。
+// 镜像由 Importer 直接写入 Store，不经过准入，修改会在下一次同步时被覆盖。
+// Admit 对新旧对象都执行该检查（因此也不能给对象加上该注解），删除、驱逐与节点 labels/annotations 的写入在写入前单独调用
+func (a *Admission) AdmitWrite(obj runtime.Object) error {
+	if meta, ok := obj.(metav1.Object); ok && mirror.IsImported(meta) {
+		return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("forbidden: %s is imported from %s and read-only (annotation %s)",
+			meta.GetName(), meta.GetAnnotations()[mirror.AnnotationImportedFrom], mirror.AnnotationImportedFrom))
+	}
+	return nil
+}
+
+// admit 在写入前按 kind 做准入处理：Pod 解析优先级，PriorityClass 校验取值与 globalDefault，
+// ClusterConfiguration 校验名称与取值，Pod 与 Deployment/StatefulSet/DaemonSet 的模板校验 topologySpreadConstraints 与 podAntiAffinity，
+// ConfigMap/Secret 校验键与总大小，Service 校验类型与 clusterIP
+func (a *Admission) admit(obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		if err := validatePodScheduling("spec", &o.Spec); err != nil {
+			return err
+		}
+		return ResolvePodPriority(a.store, o)
+	case *appsv1.Deployment:
+		if err := validateDeployment(o); err != nil {
+			return err
+		}
+		return validatePodScheduling("spec.template.spec", &o.Spec.Template.Spec)
+	case *appsv1.StatefulSet:
+		return validatePodScheduling("spec.template.spec", &o.Spec.Template.Spec)
+	case *appsv1.DaemonSet:
+		return validatePodScheduling("spec.template.spec", &o.Spec.Template.Spec)
+	case *schedulingv1.PriorityClass:
+		return validatePriorityClass(a.store, o)
+	case *k3v1.ClusterConfiguration:
+		return validateClusterConfiguration(o)
+	case *corev1.ConfigMap:
+		return validateConfigMap(o)
+	case *corev1.Secret:
+		return validateSecret(o)
+	case *corev1.Service:
+		return validateService(o, a.serviceCIDRs)
+	}
+	return nil
+}
+
+// admitUpdate 在 admit 之外校验更新相对 Store 中当前对象 old 的限制（不能修改的字段）
+func (a *Admission) admitUpdate(old, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		if prev, ok := old.(*appsv1.Deployment); ok {
+			return validateDeploymentUpdate(prev, o)
+		}
+	}
+	return nil
+}
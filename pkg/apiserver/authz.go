@@ -0,0 +1,93 @@
+package apiserver
+
+import (
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/gofiber/fiber/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// authorize 按请求身份做 namespace 隔离（未开启认证或 cluster-admin 时不受限）：
+// - 身份限定了资源种类（kinds）时，其他种类的资源一律不可访问
+// - 带 namespace 的请求：namespace 必须在身份允许的列表中
+// - 集群级资源（Node、Namespace、PriorityClass、ClusterConfiguration 等）：所有身份只读（其中没有 namespace 中的对象），写操作需要 cluster-admin
+// - 不带 namespace 的 /<resource>/<name>：是 default 中的对象，按 default 判断
+// - 跨 namespace 的 list/watch：只对可以访问所有 namespace（"*"）的身份开放，否则返回 403 而不是过滤结果；写操作需要 cluster-admin
+// - ClientUsage（各客户端的请求统计）：只有 cluster-admin 可以访问
+// - GitRepository：gitops 控制器会把仓库中的资源写入任意 namespace，写操作需要 cluster-admin
+func (s *APIServer) authorize(c *fiber.Ctx) error {
+	id := webprovider.IdentityFromCtx(c)
+	if id.IsClusterAdmin() {
+		return c.Next()
+	}
+	gvk, err := parseGVKFromContext(c)
+	if err != nil {
+		// 交给路由返回 404/400
+		return c.Next()
+	}
+
+	namespace := namespaceFromPath(c.Path())
+	readOnly := c.Method() == fiber.MethodGet
+	switch {
+	case !id.AllowsKind(gvk.Kind):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "forbidden: user " + id.User + " cannot access " + gvk.Kind,
+		})
+	case gvk.Kind == k3v1.ClientUsageGVK.Kind:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: client usage requires cluster-admin"})
+	case gvk.Kind == k3v1.GitRepositoryGVK.Kind && !readOnly:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: gitrepository writes require cluster-admin"})
+	case namespace != "":
+		if !id.AllowsNamespace(namespace) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "forbidden: user " + id.User + " cannot access namespace " + namespace,
+			})
+		}
+	case IsClusterScoped(gvk.Kind):
+		if !readOnly {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: cluster-scoped writes require cluster-admin"})
+		}
+	case !readOnly:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: cross-namespace writes require cluster-admin"})
+	case !isCollectionPath(c.Path()):
+		if !id.AllowsNamespace(metav1.NamespaceDefault) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "forbidden: user " + id.User + " cannot access namespace " + metav1.NamespaceDefault,
+			})
+		}
+	case id.NamespaceFilter() != nil:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "forbidden: user " + id.User + " cannot list or watch " + gvk.Kind + " across namespaces",
+		})
+	}
+	return c.Next()
+}
+
+// isCollectionPath 判断不带 namespace 的请求是否针对整个资源集合（/<resource>、/watch/<resource>），
+// 而不是 /<resource>/<name>[/<subresource>] 的单个对象
+func isCollectionPath(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	// /api/v1/<resource>...，/apis/<group>/<version>/<resource>...
+	rest := parts
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		rest = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		rest = parts[3:]
+	}
+	return len(rest) <= 1 || rest[0] == "watch"
+}
+
+// namespaceFromPath 从请求路径中解析 namespaces/<ns> 段；/api/v1/namespaces/<name> 是 Namespace 资源本身（集群级），返回空
+func namespaceFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	// /api/v1/[watch/]namespaces/<ns>/<resource>...，/apis/<group>/<version>/[watch/]namespaces/<ns>/<resource>...
+	for i := 2; i < len(parts)-2 && i <= 4; i++ {
+		if parts[i] == "namespaces" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package apiserver
+
+import (
+	"fmt"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+)
+
+// validateClusterConfiguration 校验 ClusterConfiguration：名称必须为 cluster（组件只读取这一个对象），
+// 日志级别、调度策略与镜像回收阈值必须有效。控制器名称由各节点的 controller manager 检查
+func validateClusterConfiguration(cc *k3v1.ClusterConfiguration) error {
+	if cc.Name != k3v1.ClusterConfigurationName {
+		return fmt.Errorf("ClusterConfiguration 的名称必须为 %q", k3v1.ClusterConfigurationName)
+	}
+	spec := cc.Spec
+	if spec.LogLevel != "" {
+		if _, ok := logprovider.ParseLevel(spec.LogLevel); !ok {
+			return fmt.Errorf("spec.logLevel 无效: %q（可选 debug/info/warn/error/fatal）", spec.LogLevel)
+		}
+	}
+	if p := spec.Scheduler; p != nil {
+		switch p.Strategy {
+		case "", k3v1.SchedulingStrategySpread, k3v1.SchedulingStrategyBinPack:
+		default:
+			return fmt.Errorf("spec.scheduler.strategy 无效: %q（可选 %s/%s）", p.Strategy, k3v1.SchedulingStrategySpread, k3v1.SchedulingStrategyBinPack)
+		}
+	}
+	if gc := spec.ImageGC; gc != nil {
+		if gc.HighThresholdPercent < 0 || gc.HighThresholdPercent > 100 || gc.LowThresholdPercent < 0 || gc.LowThresholdPercent > gc.HighThresholdPercent {
+			return fmt.Errorf("spec.imageGC 阈值无效: high=%d low=%d（要求 0 <= low <= high <= 100）", gc.HighThresholdPercent, gc.LowThresholdPercent)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package apiserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// MaxConfigMapSize ConfigMap 的 data 与 binaryData（键与值）的总大小上限，与 Kubernetes 相同为 1MiB
+	MaxConfigMapSize = 1 << 20
+	// MaxSecretSize Secret 的 data 与 stringData（键与值）的总大小上限，与 Kubernetes 相同为 1MiB
+	MaxSecretSize = 1 << 20
+)
+
+// validateConfigMap 校验 ConfigMap 的键（合法的文件名，data 与 binaryData 中不能重复）与总大小；超过上限时返回 413
+func validateConfigMap(cm *corev1.ConfigMap) error {
+	size := 0
+	for key, value := range cm.Data {
+		if err := validateDataKey("data", key); err != nil {
+			return err
+		}
+		size += len(key) + len(value)
+	}
+	for key, value := range cm.BinaryData {
+		if err := validateDataKey("binaryData", key); err != nil {
+			return err
+		}
+		if _, ok := cm.Data[key]; ok {
+			return fmt.Errorf("binaryData[%s]: 键已经出现在 data 中", key)
+		}
+		size += len(key) + len(value)
+	}
+	if size > MaxConfigMapSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("ConfigMap %s 的 data 与 binaryData 共 %d 字节，超过上限 %d 字节", cm.Name, size, MaxConfigMapSize))
+	}
+	return nil
+}
+
+// validateSecret 校验 Secret 的键与总大小（stringData 写入时合并到 data，一起计算）；超过上限时返回 413
+func validateSecret(secret *corev1.Secret) error {
+	size := 0
+	for key, value := range secret.Data {
+		if err := validateDataKey("data", key); err != nil {
+			return err
+		}
+		size += len(key) + len(value)
+	}
+	for key, value := range secret.StringData {
+		if err := validateDataKey("stringData", key); err != nil {
+			return err
+		}
+		if _, ok := secret.Data[key]; !ok {
+			size += len(key)
+		} else {
+			size -= len(secret.Data[key])
+		}
+		size += len(value)
+	}
+	if size > MaxSecretSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Secret %s 的 data 与 stringData 共 %d 字节，超过上限 %d 字节", secret.Name, size, MaxSecretSize))
+	}
+	return nil
+}
+
+// validateDataKey 校验 ConfigMap/Secret 的键：非空、不超过 253 个字符，只能包含字母、数字、'-'、'_'、'.'
+func validateDataKey(field, key string) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return fmt.Errorf("%s[%s]: 无效的键: %s", field, key, strings.Join(errs, "; "))
+	}
+	return nil
+}
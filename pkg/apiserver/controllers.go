@@ -0,0 +1,200 @@
+package apiserver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/gofiber/fiber/v2"
+)
+
+// 控制器的健康状态（ControllerStatus.Health）
+const (
+	// ControllerHealthy 正常处理事件
+	ControllerHealthy = "ok"
+	// ControllerStalled 队列中有待处理的事件，但超过一段时间没有完成任何一次处理（处理循环卡住）
+	ControllerStalled = "stalled"
+	// ControllerFailing 最近的处理大部分失败
+	ControllerFailing = "failing"
+	// ControllerStopped watch 通道已关闭，处理循环已退出（需要重启进程）
+	ControllerStopped = "stopped"
+	// ControllerDisabled 可选控制器未开启
+	ControllerDisabled = "disabled"
+)
+
+// ControllerStatusProvider 提供本进程中控制器的运行状态（由 ControllerManager 实现）
+type ControllerStatusProvider interface {
+	// NodeName 返回本节点名称
+	NodeName() string
+	// ControllerStatuses 返回每个已登记控制器的状态
+	ControllerStatuses() []ControllerStatus
+}
+
+// WithControllerStatusProvider 启用 /debug/controllers 与 /metrics 中的控制器指标
+func WithControllerStatusProvider(p ControllerStatusProvider) Option {
+	return func(s *APIServer) {
+		s.controllers = p
+	}
+}
+
+// DurationHistogram 处理耗时的直方图：Counts[i] 为耗时不超过 Buckets[i] 秒的次数（累计，与 Prometheus 相同）
+type DurationHistogram struct {
+	Buckets []float64 `json:"buckets"`
+	Counts  []uint64  `json:"counts"`
+	Sum     float64   `json:"sum"`
+	Count   uint64    `json:"count"`
+}
+
+// ControllerStatus 一个控制器的状态与指标
+type ControllerStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	// Health 为 ok、stalled、failing、stopped 或 disabled，Message 说明原因
+	Health  string `json:"health"`
+	Message string `json:"message,omitempty"`
+	// QueueDepth 为 watch 通道中尚未处理的事件数
+	QueueDepth int    `json:"queueDepth"`
+	Reconciles uint64 `json:"reconciles"`
+	Errors     uint64 `json:"errors"`
+	// RecentErrorRate 为最近（最多 100 次）处理中失败的比例
+	RecentErrorRate    float64           `json:"recentErrorRate"`
+	LastReconcile      *time.Time        `json:"lastReconcile,omitempty"`
+	LastSuccessfulSync *time.Time        `json:"lastSuccessfulSync,omitempty"`
+	LastError          string            `json:"lastError,omitempty"`
+	LastErrorTime      *time.Time        `json:"lastErrorTime,omitempty"`
+	ReconcileDuration  DurationHistogram `json:"reconcileDuration"`
+	// Placement 为调度器的批量放置统计（其他控制器省略）
+	Placement *PlacementStats `json:"placement,omitempty"`
+}
+
+// PlacementStats 调度器的批量放置统计
+type PlacementStats struct {
+	// Batches 为调度过的批次数（每批共用一次节点与 Pod 列表）
+	Batches uint64 `json:"batches"`
+	// Latency 为 Pod 从创建到绑定节点的耗时
+	Latency DurationHistogram `json:"latency"`
+}
+
+// ControllerStatusList 是 GET /debug/controllers 的响应
+type ControllerStatusList struct {
+	Node        string             `json:"node"`
+	Timestamp   time.Time          `json:"timestamp"`
+	Controllers []ControllerStatus `json:"controllers"`
+}
+
+// requireClusterAdmin 调试与指标端点只对 cluster-admin 开放（未开启认证时不受限）
+func requireClusterAdmin(c *fiber.Ctx) error {
+	if !webprovider.IdentityFromCtx(c).IsClusterAdmin() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: requires cluster-admin"})
+	}
+	return c.Next()
+}
+
+// HandleControllers 处理 GET /debug/controllers：列出本进程中每个控制器的健康状态与指标
+func (s *APIServer) HandleControllers(c *fiber.Ctx) error {
+	if s.controllers == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "controllers are not running in this process"})
+	}
+	statuses := s.controllers.ControllerStatuses()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return c.JSON(ControllerStatusList{Node: s.controllers.NodeName(), Timestamp: time.Now(), Controllers: statuses})
+}
+
+// HandleMetrics 处理 GET /metrics：以 Prometheus 文本格式输出控制器指标（进程中没有控制器时输出为空）
+func (s *APIServer) HandleMetrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	if s.controllers == nil {
+		return c.SendString("")
+	}
+	statuses := s.controllers.ControllerStatuses()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return c.SendString(FormatControllerMetrics(statuses))
+}
+
+// FormatControllerMetrics 把控制器状态转换为 Prometheus 文本格式
+func FormatControllerMetrics(statuses []ControllerStatus) string {
+	var b strings.Builder
+	family := func(name, typ, help string, each func(st ControllerStatus, label string)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, st := range statuses {
+			each(st, fmt.Sprintf("controller=%q", st.Name))
+		}
+	}
+	boolValue := func(v bool) int {
+		if v {
+			return 1
+		}
+		return 0
+	}
+
+	family("k3_controller_running", "gauge", "Whether the controller is running (1) or disabled/stopped (0).", func(st ControllerStatus, l string) {
+		fmt.Fprintf(&b, "k3_controller_running{%s} %d\n", l, boolValue(st.Running))
+	})
+	family("k3_controller_healthy", "gauge", "Whether the controller health is ok (1) or stalled/failing/stopped (0).", func(st ControllerStatus, l string) {
+		fmt.Fprintf(&b, "k3_controller_healthy{%s} %d\n", l, boolValue(st.Health == ControllerHealthy))
+	})
+	family("k3_controller_queue_depth", "gauge", "Number of watch events waiting to be processed.", func(st ControllerStatus, l string) {
+		fmt.Fprintf(&b, "k3_controller_queue_depth{%s} %d\n", l, st.QueueDepth)
+	})
+	family("k3_controller_reconcile_total", "counter", "Total number of reconciles.", func(st ControllerStatus, l string) {
+		fmt.Fprintf(&b, "k3_controller_reconcile_total{%s} %d\n", l, st.Reconciles)
+	})
+	family("k3_controller_reconcile_errors_total", "counter", "Total number of failed reconciles.", func(st ControllerStatus, l string) {
+		fmt.Fprintf(&b, "k3_controller_reconcile_errors_total{%s} %d\n", l, st.Errors)
+	})
+	family("k3_controller_recent_error_rate", "gauge", "Fraction of failed reconciles among the most recent ones.", func(st ControllerStatus, l string) {
+		fmt.Fprintf(&b, "k3_controller_recent_error_rate{%s} %s\n", l, formatFloat(st.RecentErrorRate))
+	})
+	family("k3_controller_last_successful_sync_timestamp_seconds", "gauge", "Unix time of the last successful reconcile (0 if none).", func(st ControllerStatus, l string) {
+		var ts float64
+		if st.LastSuccessfulSync != nil {
+			ts = float64(st.LastSuccessfulSync.UnixNano()) / 1e9
+		}
+		fmt.Fprintf(&b, "k3_controller_last_successful_sync_timestamp_seconds{%s} %s\n", l, formatFloat(ts))
+	})
+	family("k3_controller_reconcile_duration_seconds", "histogram", "Duration of reconciles.", func(st ControllerStatus, l string) {
+		h := st.ReconcileDuration
+		for i, le := range h.Buckets {
+			if i < len(h.Counts) {
+				fmt.Fprintf(&b, "k3_controller_reconcile_duration_seconds_bucket{%s,le=%q} %d\n", l, formatFloat(le), h.Counts[i])
+			}
+		}
+		fmt.Fprintf(&b, "k3_controller_reconcile_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l, h.Count)
+		fmt.Fprintf(&b, "k3_controller_reconcile_duration_seconds_sum{%s} %s\n", l, formatFloat(h.Sum))
+		fmt.Fprintf(&b, "k3_controller_reconcile_duration_seconds_count{%s} %d\n", l, h.Count)
+	})
+
+	// 调度器的放置统计只输出给有该统计的控制器
+	var placed []ControllerStatus
+	for _, st := range statuses {
+		if st.Placement != nil {
+			placed = append(placed, st)
+		}
+	}
+	if len(placed) > 0 {
+		statuses = placed
+		family("k3_scheduler_batches_total", "counter", "Total number of scheduling batches.", func(st ControllerStatus, l string) {
+			fmt.Fprintf(&b, "k3_scheduler_batches_total{%s} %d\n", l, st.Placement.Batches)
+		})
+		family("k3_scheduler_placement_latency_seconds", "histogram", "Time from pod creation to node binding.", func(st ControllerStatus, l string) {
+			h := st.Placement.Latency
+			for i, le := range h.Buckets {
+				if i < len(h.Counts) {
+					fmt.Fprintf(&b, "k3_scheduler_placement_latency_seconds_bucket{%s,le=%q} %d\n", l, formatFloat(le), h.Counts[i])
+				}
+			}
+			fmt.Fprintf(&b, "k3_scheduler_placement_latency_seconds_bucket{%s,le=\"+Inf\"} %d\n", l, h.Count)
+			fmt.Fprintf(&b, "k3_scheduler_placement_latency_seconds_sum{%s} %s\n", l, formatFloat(h.Sum))
+			fmt.Fprintf(&b, "k3_scheduler_placement_latency_seconds_count{%s} %d\n", l, h.Count)
+		})
+	}
+	return b.String()
+}
+
+// formatFloat 以最短形式输出浮点数
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,182 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ErrVersionNotServed 请求的版本没有在资源登记中（资源已登记，但没有该版本）
+var ErrVersionNotServed = errors.New("version not served")
+
+// ConvertFunc 把对象转换为 to 版本
+type ConvertFunc func(obj runtime.Object, to schema.GroupVersionKind) (runtime.Object, error)
+
+// spokeConversion 一个对外版本（spoke）与存储版本（hub）之间的转换
+type spokeConversion struct {
+	toHub   ConvertFunc
+	fromHub ConvertFunc
+}
+
+// kindVersions 一种资源的存储版本与其他对外版本
+type kindVersions struct {
+	storage string
+	spokes  map[string]spokeConversion
+}
+
+// ConversionRegistry 按资源登记存储版本与各版本的转换函数（hub-and-spoke，与 Kubernetes apiserver 一致）：
+// Store 中每种资源只保存存储版本，其他版本的请求在读写时与存储版本互相转换
+type ConversionRegistry struct {
+	kinds map[schema.GroupKind]*kindVersions
+}
+
+// NewConversionRegistry 创建空的转换登记
+func NewConversionRegistry() *ConversionRegistry {
+	return &ConversionRegistry{kinds: make(map[schema.GroupKind]*kindVersions)}
+}
+
+// DefaultConversions 返回内置资源的登记：所有内置资源以当前版本存储，apps 资源额外提供 v1beta1/v1beta2
+func DefaultConversions() *ConversionRegistry {
+	r := NewConversionRegistry()
+	for _, kind := range []string{"Pod", "Service", "Endpoints", "ConfigMap", "Secret", "Event", "Node", "Namespace", "ServiceAccount", "ResourceQuota", "LimitRange"} {
+		r.RegisterKind(schema.GroupVersionKind{Version: "v1", Kind: kind})
+	}
+	for _, kind := range []string{"Deployment", "StatefulSet", "DaemonSet"} {
+		r.RegisterKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind})
+		r.RegisterVersion(schema.GroupVersionKind{Group: "apps", Version: "v1beta2", Kind: kind}, nil, nil)
+	}
+	// apps/v1beta1 没有 DaemonSet，selector 可以省略
+	for _, kind := range []string{"Deployment", "StatefulSet"} {
+		r.RegisterVersion(schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: kind}, appsV1beta1ToV1, nil)
+	}
+	r.RegisterKind(k3v1.DeviceGVK)
+	r.RegisterKind(k3v1.ClientUsageGVK)
+	r.RegisterKind(k3v1.GitRepositoryGVK)
+	r.RegisterKind(k3v1.ClusterConfigurationGVK)
+	r.RegisterKind(k3v1.ClusterInfoGVK)
+	r.RegisterKind(PriorityClassGVK)
+	r.RegisterKind(PodDisruptionBudgetGVK)
+	r.RegisterKind(NetworkPolicyGVK)
+	return r
+}
+
+// RegisterKind 登记资源的存储版本
+func (r *ConversionRegistry) RegisterKind(storage schema.GroupVersionKind) {
+	r.kinds[storage.GroupKind()] = &kindVersions{
+		storage: storage.Version,
+		spokes:  make(map[string]spokeConversion),
+	}
+}
+
+// RegisterVersion 登记资源的其他对外版本及其与存储版本之间的转换；toHub/fromHub 为 nil 时使用 ConvertViaJSON
+// （字段名一致、只是版本不同的类型）。资源需要先用 RegisterKind 登记存储版本
+func (r *ConversionRegistry) RegisterVersion(spoke schema.GroupVersionKind, toHub, fromHub ConvertFunc) {
+	kv, ok := r.kinds[spoke.GroupKind()]
+	if !ok {
+		panic(fmt.Sprintf("conversion: storage version of %s is not registered", spoke.GroupKind()))
+	}
+	if toHub == nil {
+		toHub = ConvertViaJSON
+	}
+	if fromHub == nil {
+		fromHub = ConvertViaJSON
+	}
+	kv.spokes[spoke.Version] = spokeConversion{toHub: toHub, fromHub: fromHub}
+}
+
+// StorageGVK 返回请求版本对应的存储版本；没有登记的资源原样返回，登记了但没有该版本时返回 ErrVersionNotServed
+func (r *ConversionRegistry) StorageGVK(gvk schema.GroupVersionKind) (schema.GroupVersionKind, error) {
+	kv, ok := r.kinds[gvk.GroupKind()]
+	if !ok || gvk.Version == kv.storage {
+		return gvk, nil
+	}
+	if _, ok := kv.spokes[gvk.Version]; !ok {
+		return schema.GroupVersionKind{}, fmt.Errorf("%w: %s/%s %s", ErrVersionNotServed, gvk.Group, gvk.Version, gvk.Kind)
+	}
+	return gvk.GroupKind().WithVersion(kv.storage), nil
+}
+
+// Versions 返回资源对外提供的所有版本（存储版本在前）
+func (r *ConversionRegistry) Versions(gk schema.GroupKind) []string {
+	kv, ok := r.kinds[gk]
+	if !ok {
+		return nil
+	}
+	spokes := make([]string, 0, len(kv.spokes))
+	for v := range kv.spokes {
+		spokes = append(spokes, v)
+	}
+	sort.Strings(spokes)
+	return append([]string{kv.storage}, spokes...)
+}
+
+// ToStorage 把 from 版本的对象转换为存储版本；已是存储版本时原样返回
+func (r *ConversionRegistry) ToStorage(obj runtime.Object, from schema.GroupVersionKind) (runtime.Object, error) {
+	storage, err := r.StorageGVK(from)
+	if err != nil || storage == from {
+		return obj, err
+	}
+	out, err := r.kinds[from.GroupKind()].spokes[from.Version].toHub(obj, storage)
+	if err != nil {
+		return nil, fmt.Errorf("convert %s to %s: %w", from, storage, err)
+	}
+	return out, nil
+}
+
+// FromStorage 把存储版本的对象转换为 to 版本；to 就是存储版本时原样返回
+func (r *ConversionRegistry) FromStorage(obj runtime.Object, to schema.GroupVersionKind) (runtime.Object, error) {
+	storage, err := r.StorageGVK(to)
+	if err != nil || storage == to {
+		return obj, err
+	}
+	out, err := r.kinds[to.GroupKind()].spokes[to.Version].fromHub(obj, to)
+	if err != nil {
+		return nil, fmt.Errorf("convert %s to %s: %w", storage, to, err)
+	}
+	return out, nil
+}
+
+// ConvertViaJSON 通过 JSON 在同名类型的不同版本之间转换：目标版本没有的字段被丢弃，
+// 需要改名或改变结构的字段应注册自定义的 ConvertFunc
+func ConvertViaJSON(obj runtime.Object, to schema.GroupVersionKind) (runtime.Object, error) {
+	out, err := scheme.Scheme.New(to)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, err
+	}
+	out.GetObjectKind().SetGroupVersionKind(to)
+	return out, nil
+}
+
+// appsV1beta1ToV1 转换 apps/v1beta1 的 Deployment/StatefulSet：v1beta1 省略 selector 时取 Pod 模板的 labels（apps/v1 中 selector 必填）
+func appsV1beta1ToV1(obj runtime.Object, to schema.GroupVersionKind) (runtime.Object, error) {
+	out, err := ConvertViaJSON(obj, to)
+	if err != nil {
+		return nil, err
+	}
+	switch o := out.(type) {
+	case *appsv1.Deployment:
+		if o.Spec.Selector == nil && len(o.Spec.Template.Labels) > 0 {
+			o.Spec.Selector = &metav1.LabelSelector{MatchLabels: o.Spec.Template.Labels}
+		}
+	case *appsv1.StatefulSet:
+		if o.Spec.Selector == nil && len(o.Spec.Template.Labels) > 0 {
+			o.Spec.Selector = &metav1.LabelSelector{MatchLabels: o.Spec.Template.Labels}
+		}
+	}
+	return out, nil
+}
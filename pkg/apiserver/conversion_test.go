@@ -0,0 +1,213 @@
+package apiserver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	appsv1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var (
+	deploymentV1      = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deploymentV1beta1 = schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"}
+	deploymentV1beta2 = schema.GroupVersionKind{Group: "apps", Version: "v1beta2", Kind: "Deployment"}
+)
+
+// storedDeployment 返回存储版本（apps/v1）的 Deployment
+func storedDeployment() *appsv1.Deployment {
+	replicas := int32(3)
+	maxSurge := intstr.FromString("25%")
+	labels := map[string]string{"app": "web"}
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: labels, ResourceVersion: "7"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Strategy: appsv1.DeploymentStrategy{
+				Type:          appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.25"}}},
+			},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 3, ReadyReplicas: 2},
+	}
+}
+
+func TestDeploymentRoundTrip(t *testing.T) {
+	r := DefaultConversions()
+	for _, tc := range []struct {
+		gvk  schema.GroupVersionKind
+		want reflect.Type
+	}{
+		{deploymentV1beta1, reflect.TypeOf(&appsv1beta1.Deployment{})},
+		{deploymentV1beta2, reflect.TypeOf(&appsv1beta2.Deployment{})},
+	} {
+		t.Run(tc.gvk.Version, func(t *testing.T) {
+			stored := storedDeployment()
+			spoke, err := r.FromStorage(stored.DeepCopy(), tc.gvk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.TypeOf(spoke) != tc.want {
+				t.Fatalf("FromStorage returned %T", spoke)
+			}
+			if got := spoke.GetObjectKind().GroupVersionKind(); got != tc.gvk {
+				t.Fatalf("FromStorage kind = %v", got)
+			}
+
+			back, err := r.ToStorage(spoke, tc.gvk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equality.Semantic.DeepEqual(back, stored) {
+				t.Fatalf("round trip changed the object:\n got %+v\nwant %+v", back, stored)
+			}
+		})
+	}
+}
+
+func TestAppsV1beta1SelectorDefaulting(t *testing.T) {
+	r := DefaultConversions()
+	template := corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web", "tier": "frontend"}}}
+	explicit := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	for _, tc := range []struct {
+		name string
+		obj  runtime.Object
+		from schema.GroupVersionKind
+		want *metav1.LabelSelector
+	}{
+		{
+			name: "v1beta1 Deployment without selector",
+			obj:  &appsv1beta1.Deployment{Spec: appsv1beta1.DeploymentSpec{Template: template}},
+			from: deploymentV1beta1,
+			want: &metav1.LabelSelector{MatchLabels: template.Labels},
+		},
+		{
+			name: "v1beta1 Deployment keeps its selector",
+			obj:  &appsv1beta1.Deployment{Spec: appsv1beta1.DeploymentSpec{Selector: explicit, Template: template}},
+			from: deploymentV1beta1,
+			want: explicit,
+		},
+		{
+			name: "v1beta1 StatefulSet without selector",
+			obj:  &appsv1beta1.StatefulSet{Spec: appsv1beta1.StatefulSetSpec{Template: template}},
+			from: schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "StatefulSet"},
+			want: &metav1.LabelSelector{MatchLabels: template.Labels},
+		},
+		{
+			name: "v1beta1 Deployment without template labels",
+			obj:  &appsv1beta1.Deployment{},
+			from: deploymentV1beta1,
+		},
+		{
+			// v1beta2 与 apps/v1 一样 selector 必填，不补默认值
+			name: "v1beta2 is converted as is",
+			obj:  &appsv1beta2.Deployment{Spec: appsv1beta2.DeploymentSpec{Template: template}},
+			from: deploymentV1beta2,
+		},
+	} {
+		out, err := r.ToStorage(tc.obj, tc.from)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var got *metav1.LabelSelector
+		switch o := out.(type) {
+		case *appsv1.Deployment:
+			got = o.Spec.Selector
+		case *appsv1.StatefulSet:
+			got = o.Spec.Selector
+		default:
+			t.Fatalf("%s: ToStorage returned %T", tc.name, out)
+		}
+		if !equality.Semantic.DeepEqual(got, tc.want) {
+			t.Errorf("%s: selector = %v, want %v", tc.name, got, tc.want)
+		}
+		if gvk := out.GetObjectKind().GroupVersionKind(); gvk.Version != "v1" {
+			t.Errorf("%s: converted kind = %v", tc.name, gvk)
+		}
+	}
+}
+
+func TestConvertViaJSON(t *testing.T) {
+	// apps/v1beta1 Deployment 的 rollbackTo 在 apps/v1 中不存在，转换时丢弃
+	obj := &appsv1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec:       appsv1beta1.DeploymentSpec{RollbackTo: &appsv1beta1.RollbackConfig{Revision: 2}, Paused: true},
+	}
+	out, err := ConvertViaJSON(obj, deploymentV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := out.(*appsv1.Deployment)
+	if !ok || d.Name != "web" || !d.Spec.Paused || d.Kind != "Deployment" || d.APIVersion != "apps/v1" {
+		t.Fatalf("ConvertViaJSON = %#v", out)
+	}
+
+	if _, err := ConvertViaJSON(obj, schema.GroupVersionKind{Group: "apps", Version: "v9", Kind: "Deployment"}); err == nil {
+		t.Fatal("conversion to an unknown type succeeded")
+	}
+}
+
+func TestConversionRegistry(t *testing.T) {
+	r := DefaultConversions()
+
+	for _, tc := range []struct {
+		name string
+		gvk  schema.GroupVersionKind
+		want schema.GroupVersionKind
+		err  error
+	}{
+		{"storage version", deploymentV1, deploymentV1, nil},
+		{"spoke version", deploymentV1beta1, deploymentV1, nil},
+		{"unknown version of a registered kind", schema.GroupVersionKind{Group: "apps", Version: "v2", Kind: "Deployment"}, schema.GroupVersionKind{}, ErrVersionNotServed},
+		{"DaemonSet has no v1beta1", schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "DaemonSet"}, schema.GroupVersionKind{}, ErrVersionNotServed},
+		{"unregistered kind is returned as is", schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Widget"}, schema.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Widget"}, nil},
+	} {
+		got, err := r.StorageGVK(tc.gvk)
+		if !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("%s: StorageGVK(%v) = %v, %v; want %v, %v", tc.name, tc.gvk, got, err, tc.want, tc.err)
+		}
+	}
+
+	unserved := schema.GroupVersionKind{Group: "apps", Version: "v2", Kind: "Deployment"}
+	if _, err := r.ToStorage(&appsv1.Deployment{}, unserved); !errors.Is(err, ErrVersionNotServed) {
+		t.Errorf("ToStorage(unserved) err = %v", err)
+	}
+	if _, err := r.FromStorage(storedDeployment(), unserved); !errors.Is(err, ErrVersionNotServed) {
+		t.Errorf("FromStorage(unserved) err = %v", err)
+	}
+
+	// 已是存储版本时原样返回
+	stored := storedDeployment()
+	if out, err := r.FromStorage(stored, deploymentV1); err != nil || out != runtime.Object(stored) {
+		t.Errorf("FromStorage(storage version) = %p, %v; want the same object", out, err)
+	}
+
+	if got := r.Versions(schema.GroupKind{Group: "apps", Kind: "Deployment"}); !reflect.DeepEqual(got, []string{"v1", "v1beta1", "v1beta2"}) {
+		t.Errorf("Versions(Deployment) = %v", got)
+	}
+	if got := r.Versions(schema.GroupKind{Group: "apps", Kind: "DaemonSet"}); !reflect.DeepEqual(got, []string{"v1", "v1beta2"}) {
+		t.Errorf("Versions(DaemonSet) = %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterVersion without a storage version did not panic")
+		}
+	}()
+	NewConversionRegistry().RegisterVersion(deploymentV1beta1, nil, nil)
+}
@@ -0,0 +1,277 @@
+package apiserver
+
+import (
+	"maps"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// SetDefaults 在持久化之前为对象填充默认值：先执行 scheme 中注册的 defaulting 函数，
+// 再按 kind 补齐 Kubernetes 的常用默认值（取值与 k8s.io/kubernetes/pkg/apis/*/v1/defaults.go 一致）。
+// client-go 的 scheme 没有注册 defaulter，因此实际生效的主要是下面的显式 defaulter。
+// 只填充未设置的字段，重复调用是幂等的。
+func SetDefaults(obj runtime.Object) {
+	scheme.Scheme.Default(obj)
+
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		setDefaultsPodSpec(&o.Spec)
+	case *corev1.Service:
+		setDefaultsService(o)
+	case *corev1.Secret:
+		if o.Type == "" {
+			o.Type = corev1.SecretTypeOpaque
+		}
+	case *appsv1.Deployment:
+		setDefaultsDeployment(o)
+	case *appsv1.StatefulSet:
+		setDefaultsStatefulSet(o)
+	case *appsv1.DaemonSet:
+		setDefaultsDaemonSet(o)
+	}
+}
+
+func setDefaultsPodSpec(spec *corev1.PodSpec) {
+	if spec.RestartPolicy == "" {
+		spec.RestartPolicy = corev1.RestartPolicyAlways
+	}
+	if spec.DNSPolicy == "" {
+		spec.DNSPolicy = corev1.DNSClusterFirst
+	}
+	if spec.SchedulerName == "" {
+		spec.SchedulerName = corev1.DefaultSchedulerName
+	}
+	if spec.TerminationGracePeriodSeconds == nil {
+		spec.TerminationGracePeriodSeconds = ptrTo(int64(corev1.DefaultTerminationGracePeriodSeconds))
+	}
+	if spec.SecurityContext == nil {
+		spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if spec.EnableServiceLinks == nil {
+		spec.EnableServiceLinks = ptrTo(corev1.DefaultEnableServiceLinks)
+	}
+	for i := range spec.InitContainers {
+		setDefaultsContainer(&spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		setDefaultsContainer(&spec.Containers[i])
+	}
+	for i := range spec.Volumes {
+		setDefaultsVolume(&spec.Volumes[i])
+	}
+}
+
+func setDefaultsContainer(c *corev1.Container) {
+	if c.ImagePullPolicy == "" {
+		// 未指定 tag 或 tag 为 latest 时总是拉取
+		c.ImagePullPolicy = corev1.PullIfNotPresent
+		if imageTag(c.Image) == "latest" {
+			c.ImagePullPolicy = corev1.PullAlways
+		}
+	}
+	if c.TerminationMessagePath == "" {
+		c.TerminationMessagePath = corev1.TerminationMessagePathDefault
+	}
+	if c.TerminationMessagePolicy == "" {
+		c.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+	}
+	for i := range c.Ports {
+		if c.Ports[i].Protocol == "" {
+			c.Ports[i].Protocol = corev1.ProtocolTCP
+		}
+	}
+	for i := range c.Env {
+		if ref := c.Env[i].ValueFrom; ref != nil && ref.FieldRef != nil && ref.FieldRef.APIVersion == "" {
+			ref.FieldRef.APIVersion = "v1"
+		}
+	}
+	setDefaultsProbe(c.LivenessProbe)
+	setDefaultsProbe(c.ReadinessProbe)
+	setDefaultsProbe(c.StartupProbe)
+
+	// 只设置了 limits 时，requests 默认等于 limits
+	if len(c.Resources.Limits) > 0 {
+		if c.Resources.Requests == nil {
+			c.Resources.Requests = corev1.ResourceList{}
+		}
+		for name, q := range c.Resources.Limits {
+			if _, ok := c.Resources.Requests[name]; !ok {
+				c.Resources.Requests[name] = q.DeepCopy()
+			}
+		}
+	}
+}
+
+func setDefaultsProbe(p *corev1.Probe) {
+	if p == nil {
+		return
+	}
+	if p.TimeoutSeconds == 0 {
+		p.TimeoutSeconds = 1
+	}
+	if p.PeriodSeconds == 0 {
+		p.PeriodSeconds = 10
+	}
+	if p.SuccessThreshold == 0 {
+		p.SuccessThreshold = 1
+	}
+	if p.FailureThreshold == 0 {
+		p.FailureThreshold = 3
+	}
+	if p.HTTPGet != nil && p.HTTPGet.Scheme == "" {
+		p.HTTPGet.Scheme = corev1.URISchemeHTTP
+	}
+}
+
+func setDefaultsVolume(v *corev1.Volume) {
+	// 没有指定任何来源时默认为 emptyDir
+	if v.VolumeSource == (corev1.VolumeSource{}) {
+		v.EmptyDir = &corev1.EmptyDirVolumeSource{}
+	}
+	if v.ConfigMap != nil && v.ConfigMap.DefaultMode == nil {
+		v.ConfigMap.DefaultMode = ptrTo(corev1.ConfigMapVolumeSourceDefaultMode)
+	}
+	if v.Secret != nil && v.Secret.DefaultMode == nil {
+		v.Secret.DefaultMode = ptrTo(corev1.SecretVolumeSourceDefaultMode)
+	}
+	if v.DownwardAPI != nil {
+		if v.DownwardAPI.DefaultMode == nil {
+			v.DownwardAPI.DefaultMode = ptrTo(corev1.DownwardAPIVolumeSourceDefaultMode)
+		}
+		for i := range v.DownwardAPI.Items {
+			if ref := v.DownwardAPI.Items[i].FieldRef; ref != nil && ref.APIVersion == "" {
+				ref.APIVersion = "v1"
+			}
+		}
+	}
+}
+
+func setDefaultsService(svc *corev1.Service) {
+	if svc.Spec.Type == "" {
+		svc.Spec.Type = corev1.ServiceTypeClusterIP
+	}
+	if svc.Spec.SessionAffinity == "" {
+		svc.Spec.SessionAffinity = corev1.ServiceAffinityNone
+	}
+	for i := range svc.Spec.Ports {
+		p := &svc.Spec.Ports[i]
+		if p.Protocol == "" {
+			p.Protocol = corev1.ProtocolTCP
+		}
+		if p.TargetPort == (intstr.IntOrString{}) {
+			p.TargetPort = intstr.FromInt32(p.Port)
+		}
+	}
+	if svc.Spec.Type != corev1.ServiceTypeExternalName && svc.Spec.InternalTrafficPolicy == nil {
+		policy := corev1.ServiceInternalTrafficPolicyCluster
+		svc.Spec.InternalTrafficPolicy = &policy
+	}
+	if (svc.Spec.Type == corev1.ServiceTypeNodePort || svc.Spec.Type == corev1.ServiceTypeLoadBalancer) && svc.Spec.ExternalTrafficPolicy == "" {
+		svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyCluster
+	}
+}
+
+func setDefaultsDeployment(d *appsv1.Deployment) {
+	// apps/v1 要求 selector，省略时与 extensions/v1beta1 一样取 Pod 模板的 labels
+	if d.Spec.Selector == nil && len(d.Spec.Template.Labels) > 0 {
+		d.Spec.Selector = &metav1.LabelSelector{MatchLabels: maps.Clone(d.Spec.Template.Labels)}
+	}
+	if d.Spec.Replicas == nil {
+		d.Spec.Replicas = ptrTo(int32(1))
+	}
+	if d.Spec.Strategy.Type == "" {
+		d.Spec.Strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
+	}
+	if d.Spec.Strategy.Type == appsv1.RollingUpdateDeploymentStrategyType {
+		if d.Spec.Strategy.RollingUpdate == nil {
+			d.Spec.Strategy.RollingUpdate = &appsv1.RollingUpdateDeployment{}
+		}
+		if d.Spec.Strategy.RollingUpdate.MaxUnavailable == nil {
+			d.Spec.Strategy.RollingUpdate.MaxUnavailable = ptrTo(intstr.FromString("25%"))
+		}
+		if d.Spec.Strategy.RollingUpdate.MaxSurge == nil {
+			d.Spec.Strategy.RollingUpdate.MaxSurge = ptrTo(intstr.FromString("25%"))
+		}
+	}
+	if d.Spec.RevisionHistoryLimit == nil {
+		d.Spec.RevisionHistoryLimit = ptrTo(int32(10))
+	}
+	if d.Spec.ProgressDeadlineSeconds == nil {
+		d.Spec.ProgressDeadlineSeconds = ptrTo(int32(600))
+	}
+	setDefaultsPodSpec(&d.Spec.Template.Spec)
+}
+
+func setDefaultsStatefulSet(s *appsv1.StatefulSet) {
+	if s.Spec.Replicas == nil {
+		s.Spec.Replicas = ptrTo(int32(1))
+	}
+	if s.Spec.PodManagementPolicy == "" {
+		s.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+	}
+	if s.Spec.UpdateStrategy.Type == "" {
+		s.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+	}
+	if s.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType {
+		if s.Spec.UpdateStrategy.RollingUpdate == nil {
+			s.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{}
+		}
+		if s.Spec.UpdateStrategy.RollingUpdate.Partition == nil {
+			s.Spec.UpdateStrategy.RollingUpdate.Partition = ptrTo(int32(0))
+		}
+	}
+	if s.Spec.RevisionHistoryLimit == nil {
+		s.Spec.RevisionHistoryLimit = ptrTo(int32(10))
+	}
+	if s.Spec.PersistentVolumeClaimRetentionPolicy == nil {
+		s.Spec.PersistentVolumeClaimRetentionPolicy = &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+			WhenDeleted: appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+			WhenScaled:  appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+		}
+	}
+	setDefaultsPodSpec(&s.Spec.Template.Spec)
+}
+
+func setDefaultsDaemonSet(ds *appsv1.DaemonSet) {
+	if ds.Spec.UpdateStrategy.Type == "" {
+		ds.Spec.UpdateStrategy.Type = appsv1.RollingUpdateDaemonSetStrategyType
+	}
+	if ds.Spec.UpdateStrategy.Type == appsv1.RollingUpdateDaemonSetStrategyType {
+		if ds.Spec.UpdateStrategy.RollingUpdate == nil {
+			ds.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateDaemonSet{}
+		}
+		if ds.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable == nil {
+			ds.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable = ptrTo(intstr.FromInt32(1))
+		}
+		if ds.Spec.UpdateStrategy.RollingUpdate.MaxSurge == nil {
+			ds.Spec.UpdateStrategy.RollingUpdate.MaxSurge = ptrTo(intstr.FromInt32(0))
+		}
+	}
+	if ds.Spec.RevisionHistoryLimit == nil {
+		ds.Spec.RevisionHistoryLimit = ptrTo(int32(10))
+	}
+	setDefaultsPodSpec(&ds.Spec.Template.Spec)
+}
+
+// imageTag 返回镜像 tag（没有 tag 时视为 latest；按 digest 引用时返回空）
+func imageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	// 最后一个 "/" 之后的 ":" 才是 tag 分隔符（前面的可能是 registry 端口）
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return "latest"
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,272 @@
+package apiserver
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func testPodSpec() corev1.PodSpec {
+	return corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.25"}}}
+}
+
+func TestSetDefaultsPod(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+		Containers: []corev1.Container{{
+			Name:  "app",
+			Image: "nginx:1.25",
+			Ports: []corev1.ContainerPort{{ContainerPort: 80}},
+			Env: []corev1.EnvVar{{Name: "NODE", ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+			}}},
+			ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromInt32(80)}}},
+			Resources: corev1.ResourceRequirements{
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			},
+		}},
+		Volumes: []corev1.Volume{
+			{Name: "scratch"},
+			{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}},
+			{Name: "secret", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{}}},
+		},
+	}}
+	SetDefaults(pod)
+
+	spec := pod.Spec
+	if spec.RestartPolicy != corev1.RestartPolicyAlways || spec.DNSPolicy != corev1.DNSClusterFirst ||
+		spec.SchedulerName != corev1.DefaultSchedulerName || spec.SecurityContext == nil {
+		t.Errorf("pod spec defaults = %+v", spec)
+	}
+	if spec.TerminationGracePeriodSeconds == nil || *spec.TerminationGracePeriodSeconds != 30 {
+		t.Errorf("terminationGracePeriodSeconds = %v", spec.TerminationGracePeriodSeconds)
+	}
+	if spec.EnableServiceLinks == nil || !*spec.EnableServiceLinks {
+		t.Errorf("enableServiceLinks = %v", spec.EnableServiceLinks)
+	}
+
+	if c := spec.InitContainers[0]; c.ImagePullPolicy != corev1.PullAlways || c.TerminationMessagePath != corev1.TerminationMessagePathDefault {
+		t.Errorf("init container defaults = %+v", c)
+	}
+	c := spec.Containers[0]
+	if c.ImagePullPolicy != corev1.PullIfNotPresent || c.TerminationMessagePolicy != corev1.TerminationMessageReadFile {
+		t.Errorf("container defaults = %+v", c)
+	}
+	if c.Ports[0].Protocol != corev1.ProtocolTCP {
+		t.Errorf("port protocol = %q", c.Ports[0].Protocol)
+	}
+	if c.Env[0].ValueFrom.FieldRef.APIVersion != "v1" {
+		t.Errorf("fieldRef apiVersion = %q", c.Env[0].ValueFrom.FieldRef.APIVersion)
+	}
+	if p := c.ReadinessProbe; p.TimeoutSeconds != 1 || p.PeriodSeconds != 10 || p.SuccessThreshold != 1 ||
+		p.FailureThreshold != 3 || p.HTTPGet.Scheme != corev1.URISchemeHTTP {
+		t.Errorf("probe defaults = %+v", p)
+	}
+	// 已设置的 requests 保留，缺少的从 limits 补齐
+	if cpu := c.Resources.Requests[corev1.ResourceCPU]; cpu.String() != "100m" {
+		t.Errorf("cpu request = %s, want 100m", cpu.String())
+	}
+	if mem := c.Resources.Requests[corev1.ResourceMemory]; mem.String() != "128Mi" {
+		t.Errorf("memory request = %s, want 128Mi", mem.String())
+	}
+
+	if spec.Volumes[0].EmptyDir == nil {
+		t.Errorf("volume without a source = %+v, want emptyDir", spec.Volumes[0])
+	}
+	if mode := spec.Volumes[1].ConfigMap.DefaultMode; mode == nil || *mode != corev1.ConfigMapVolumeSourceDefaultMode {
+		t.Errorf("configMap defaultMode = %v", mode)
+	}
+	if mode := spec.Volumes[2].Secret.DefaultMode; mode == nil || *mode != corev1.SecretVolumeSourceDefaultMode {
+		t.Errorf("secret defaultMode = %v", mode)
+	}
+}
+
+func TestSetDefaultsImagePullPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		image string
+		want  corev1.PullPolicy
+	}{
+		{"nginx", corev1.PullAlways},
+		{"nginx:latest", corev1.PullAlways},
+		{"nginx:1.25", corev1.PullIfNotPresent},
+		{"registry.local:5000/nginx", corev1.PullAlways},
+		{"registry.local:5000/nginx:1.25", corev1.PullIfNotPresent},
+		{"nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000", corev1.PullIfNotPresent},
+	} {
+		pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: tc.image}}}}
+		SetDefaults(pod)
+		if got := pod.Spec.Containers[0].ImagePullPolicy; got != tc.want {
+			t.Errorf("%s: imagePullPolicy = %s, want %s", tc.image, got, tc.want)
+		}
+	}
+
+	// 显式设置的策略不覆盖
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx", ImagePullPolicy: corev1.PullNever}}}}
+	SetDefaults(pod)
+	if got := pod.Spec.Containers[0].ImagePullPolicy; got != corev1.PullNever {
+		t.Errorf("explicit imagePullPolicy = %s, want Never", got)
+	}
+}
+
+func TestSetDefaultsService(t *testing.T) {
+	svc := &corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+		{Port: 80},
+		{Port: 443, TargetPort: intstr.FromString("https"), Protocol: corev1.ProtocolUDP},
+	}}}
+	SetDefaults(svc)
+	if svc.Spec.Type != corev1.ServiceTypeClusterIP || svc.Spec.SessionAffinity != corev1.ServiceAffinityNone {
+		t.Errorf("service defaults = %+v", svc.Spec)
+	}
+	if p := svc.Spec.Ports[0]; p.Protocol != corev1.ProtocolTCP || p.TargetPort != intstr.FromInt32(80) {
+		t.Errorf("port defaults = %+v", p)
+	}
+	if p := svc.Spec.Ports[1]; p.Protocol != corev1.ProtocolUDP || p.TargetPort != intstr.FromString("https") {
+		t.Errorf("explicit port = %+v", p)
+	}
+	if p := svc.Spec.InternalTrafficPolicy; p == nil || *p != corev1.ServiceInternalTrafficPolicyCluster {
+		t.Errorf("internalTrafficPolicy = %v", p)
+	}
+	if svc.Spec.ExternalTrafficPolicy != "" {
+		t.Errorf("ClusterIP externalTrafficPolicy = %q, want empty", svc.Spec.ExternalTrafficPolicy)
+	}
+
+	nodePort := &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort}}
+	SetDefaults(nodePort)
+	if nodePort.Spec.ExternalTrafficPolicy != corev1.ServiceExternalTrafficPolicyCluster {
+		t.Errorf("NodePort externalTrafficPolicy = %q", nodePort.Spec.ExternalTrafficPolicy)
+	}
+
+	external := &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "example.com"}}
+	SetDefaults(external)
+	if external.Spec.InternalTrafficPolicy != nil {
+		t.Errorf("ExternalName internalTrafficPolicy = %v, want nil", *external.Spec.InternalTrafficPolicy)
+	}
+}
+
+func TestSetDefaultsSecret(t *testing.T) {
+	secret := &corev1.Secret{}
+	SetDefaults(secret)
+	if secret.Type != corev1.SecretTypeOpaque {
+		t.Errorf("secret type = %q, want Opaque", secret.Type)
+	}
+	tls := &corev1.Secret{Type: corev1.SecretTypeTLS}
+	SetDefaults(tls)
+	if tls.Type != corev1.SecretTypeTLS {
+		t.Errorf("explicit secret type = %q", tls.Type)
+	}
+}
+
+func TestSetDefaultsDeployment(t *testing.T) {
+	labels := map[string]string{"app": "web"}
+	d := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec:       testPodSpec(),
+	}}}
+	SetDefaults(d)
+	if d.Spec.Selector == nil || !equality.Semantic.DeepEqual(d.Spec.Selector.MatchLabels, labels) {
+		t.Errorf("selector = %v", d.Spec.Selector)
+	}
+	// selector 是模板 labels 的副本
+	d.Spec.Template.Labels["tier"] = "frontend"
+	if _, ok := d.Spec.Selector.MatchLabels["tier"]; ok {
+		t.Error("selector shares the template labels map")
+	}
+	if *d.Spec.Replicas != 1 || *d.Spec.RevisionHistoryLimit != 10 || *d.Spec.ProgressDeadlineSeconds != 600 {
+		t.Errorf("deployment defaults = %+v", d.Spec)
+	}
+	ru := d.Spec.Strategy.RollingUpdate
+	if d.Spec.Strategy.Type != appsv1.RollingUpdateDeploymentStrategyType || ru == nil ||
+		*ru.MaxUnavailable != intstr.FromString("25%") || *ru.MaxSurge != intstr.FromString("25%") {
+		t.Errorf("strategy = %+v", d.Spec.Strategy)
+	}
+	if d.Spec.Template.Spec.RestartPolicy != corev1.RestartPolicyAlways {
+		t.Errorf("template restartPolicy = %q", d.Spec.Template.Spec.RestartPolicy)
+	}
+
+	recreate := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}}}
+	SetDefaults(recreate)
+	if recreate.Spec.Strategy.RollingUpdate != nil {
+		t.Errorf("Recreate strategy got rollingUpdate %+v", recreate.Spec.Strategy.RollingUpdate)
+	}
+	if recreate.Spec.Selector != nil {
+		t.Errorf("selector without template labels = %v", recreate.Spec.Selector)
+	}
+}
+
+func TestSetDefaultsStatefulSet(t *testing.T) {
+	s := &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: testPodSpec()}}}
+	SetDefaults(s)
+	if *s.Spec.Replicas != 1 || *s.Spec.RevisionHistoryLimit != 10 || s.Spec.PodManagementPolicy != appsv1.OrderedReadyPodManagement {
+		t.Errorf("statefulset defaults = %+v", s.Spec)
+	}
+	if st := s.Spec.UpdateStrategy; st.Type != appsv1.RollingUpdateStatefulSetStrategyType || st.RollingUpdate == nil || *st.RollingUpdate.Partition != 0 {
+		t.Errorf("updateStrategy = %+v", st)
+	}
+	if p := s.Spec.PersistentVolumeClaimRetentionPolicy; p == nil ||
+		p.WhenDeleted != appsv1.RetainPersistentVolumeClaimRetentionPolicyType || p.WhenScaled != appsv1.RetainPersistentVolumeClaimRetentionPolicyType {
+		t.Errorf("pvc retention policy = %+v", p)
+	}
+	if s.Spec.Template.Spec.Containers[0].ImagePullPolicy != corev1.PullIfNotPresent {
+		t.Errorf("template container = %+v", s.Spec.Template.Spec.Containers[0])
+	}
+
+	onDelete := &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}}}
+	SetDefaults(onDelete)
+	if onDelete.Spec.UpdateStrategy.RollingUpdate != nil {
+		t.Errorf("OnDelete strategy got rollingUpdate %+v", onDelete.Spec.UpdateStrategy.RollingUpdate)
+	}
+}
+
+func TestSetDefaultsDaemonSet(t *testing.T) {
+	ds := &appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: testPodSpec()}}}
+	SetDefaults(ds)
+	ru := ds.Spec.UpdateStrategy.RollingUpdate
+	if ds.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType || ru == nil ||
+		*ru.MaxUnavailable != intstr.FromInt32(1) || *ru.MaxSurge != intstr.FromInt32(0) {
+		t.Errorf("updateStrategy = %+v", ds.Spec.UpdateStrategy)
+	}
+	if *ds.Spec.RevisionHistoryLimit != 10 {
+		t.Errorf("revisionHistoryLimit = %d", *ds.Spec.RevisionHistoryLimit)
+	}
+	if ds.Spec.Template.Spec.DNSPolicy != corev1.DNSClusterFirst {
+		t.Errorf("template dnsPolicy = %q", ds.Spec.Template.Spec.DNSPolicy)
+	}
+}
+
+func TestSetDefaultsIdempotent(t *testing.T) {
+	labels := map[string]string{"app": "web"}
+	template := corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}, Spec: corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:           "app",
+			Image:          "nginx",
+			LivenessProbe:  &corev1.Probe{},
+			Resources:      corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			Ports:          []corev1.ContainerPort{{ContainerPort: 80}},
+			ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromInt32(80)}}},
+		}},
+		Volumes: []corev1.Volume{{Name: "scratch"}},
+	}}
+	for _, obj := range []runtime.Object{
+		&corev1.Pod{Spec: template.Spec},
+		&corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 80}}}},
+		&corev1.Secret{},
+		&appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: template}},
+		&appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: template}},
+		&appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{Template: template}},
+	} {
+		obj = obj.DeepCopyObject()
+		SetDefaults(obj)
+		once := obj.DeepCopyObject()
+		SetDefaults(obj)
+		if !equality.Semantic.DeepEqual(obj, once) {
+			t.Errorf("%T: second SetDefaults changed the object:\n got %+v\nwant %+v", obj, obj, once)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package apiserver
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateDeployment 校验 Deployment 的 selector：必须设置（省略时 SetDefaults 已取 Pod 模板的 labels）、
+// 语法合法且匹配 Pod 模板的 labels；progressDeadlineSeconds 必须大于 0 且大于 minReadySeconds（与 apps/v1 相同）。
+// 不合法时返回 *InvalidError
+func validateDeployment(d *appsv1.Deployment) error {
+	path := field.NewPath("spec", "selector")
+	var errs field.ErrorList
+	switch selector := d.Spec.Selector; {
+	case selector == nil || (len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0):
+		errs = append(errs, field.Required(path, "selector is required (or set spec.template.metadata.labels to default it)"))
+	default:
+		parsed, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			errs = append(errs, field.Invalid(path, selector, err.Error()))
+		} else if !parsed.Matches(labels.Set(d.Spec.Template.Labels)) {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "template", "metadata", "labels"), d.Spec.Template.Labels,
+				"`selector` does not match template `labels`"))
+		}
+	}
+	if deadline := d.Spec.ProgressDeadlineSeconds; deadline != nil {
+		path := field.NewPath("spec", "progressDeadlineSeconds")
+		switch {
+		case *deadline <= 0:
+			errs = append(errs, field.Invalid(path, *deadline, "must be greater than 0"))
+		case *deadline <= d.Spec.MinReadySeconds:
+			errs = append(errs, field.Invalid(path, *deadline, "must be greater than minReadySeconds"))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &InvalidError{Kind: "Deployment", Name: d.Name, Causes: errs}
+}
+
+// validateDeploymentUpdate selector 创建后不能修改（与 apps/v1 相同）；
+// 旧对象没有 selector（在默认 selector 之前写入）时允许补上
+func validateDeploymentUpdate(old, d *appsv1.Deployment) error {
+	if old.Spec.Selector == nil || apiequality.Semantic.DeepEqual(old.Spec.Selector, d.Spec.Selector) {
+		return nil
+	}
+	return &InvalidError{Kind: "Deployment", Name: d.Name, Causes: field.ErrorList{
+		field.Invalid(field.NewPath("spec", "selector"), d.Spec.Selector, "field is immutable"),
+	}}
+}
@@ -0,0 +1,148 @@
+package apiserver
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// podGVK 是 core/v1 Pod
+var podGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+// DisruptionsAllowed 按 pods 计算 PodDisruptionBudget 还允许驱逐的 Pod 数（eviction 子资源、抢占与 descheduler 共用）：
+// 健康数（Running 且 Ready）减去需要保持的健康数（minAvailable，或匹配的 Pod 总数减 maxUnavailable），百分比向上取整。
+// selector 为空的 PodDisruptionBudget 不匹配任何 Pod（与 policy/v1 一致），返回 nil selector
+func DisruptionsAllowed(pdb *policyv1.PodDisruptionBudget, pods []runtime.Object) (labels.Selector, int, error) {
+	if pdb.Spec.Selector == nil {
+		return nil, 0, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return nil, 0, fmt.Errorf("selector 无效: %w", err)
+	}
+
+	expected, healthy := 0, 0
+	for _, o := range pods {
+		p, ok := o.(*corev1.Pod)
+		if !ok || p.Namespace != pdb.Namespace || podTerminal(p) || !selector.Matches(labels.Set(p.Labels)) {
+			continue
+		}
+		expected++
+		if PodHealthy(p) {
+			healthy++
+		}
+	}
+
+	desired := 0
+	switch {
+	case pdb.Spec.MinAvailable != nil:
+		desired, err = intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, expected, true)
+	case pdb.Spec.MaxUnavailable != nil:
+		var maxUnavailable int
+		maxUnavailable, err = intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, expected, true)
+		desired = max(expected-maxUnavailable, 0)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return selector, max(healthy-desired, 0), nil
+}
+
+// PodHealthy 判断 Pod 是否计入 PodDisruptionBudget 的健康数（Running 且 Ready）
+func PodHealthy(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func podTerminal(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// HandleEviction 处理 POST .../pods/:name/eviction：驱逐后仍满足所有匹配的 PodDisruptionBudget 时删除 Pod 并返回 201，
+// 否则返回 429（与 Kubernetes 相同，客户端稍后重试）。未就绪或已结束的 Pod 不计入健康数，总是可以驱逐。
+// 驱逐串行处理，并发的驱逐请求不会同时消耗同一份预算
+func (s *APIServer) HandleEviction(c *fiber.Ctx) error {
+	namespace, name := c.Params("namespace"), c.Params("name")
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+
+	obj, err := s.store.Get(podGVK, namespace, name)
+	if err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusNotFound)).JSON(fiber.Map{"error": err.Error()})
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "unexpected object type"})
+	}
+	if err := s.admission.AdmitWrite(pod); err != nil {
+		return admissionError(c, err)
+	}
+
+	if PodHealthy(pod) {
+		if blocking, err := s.blockingDisruptionBudget(pod); err != nil {
+			return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+		} else if blocking != "" {
+			c.Set(fiber.HeaderRetryAfter, "5")
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":  "Cannot evict pod as it would violate the pod's disruption budget.",
+				"reason": "DisruptionBudget",
+				"budget": blocking,
+			})
+		}
+	}
+
+	if err := s.store.Delete(podGVK, namespace, name); err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(&metav1.Status{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+		Status:   metav1.StatusSuccess,
+	})
+}
+
+// blockingDisruptionBudget 返回驱逐 pod 会违反的 PodDisruptionBudget 名称，都允许时返回空
+func (s *APIServer) blockingDisruptionBudget(pod *corev1.Pod) (string, error) {
+	budgets, err := s.store.List(PodDisruptionBudgetGVK, pod.Namespace)
+	if err != nil {
+		return "", err
+	}
+	if len(budgets) == 0 {
+		return "", nil
+	}
+	pods, err := s.store.List(podGVK, pod.Namespace)
+	if err != nil {
+		return "", err
+	}
+	for _, obj := range budgets {
+		pdb, ok := obj.(*policyv1.PodDisruptionBudget)
+		if !ok {
+			continue
+		}
+		selector, allowed, err := DisruptionsAllowed(pdb, pods)
+		if err != nil || selector == nil || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if allowed <= 0 {
+			return pdb.Name, nil
+		}
+	}
+	return "", nil
+}
@@ -1,17 +1,24 @@
 package apiserver
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/resources"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	"github.com/gofiber/fiber/v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
@@ -19,15 +26,48 @@ import (
 
 // APIServer 是 Kubernetes API server 的实现
 type APIServer struct {
-	store  storage.Store
-	parser *parser.Parser
+	store       storage.Store
+	parser      *parser.Parser
+	logs        PodLogStreamer
+	images      NodeImageManager
+	runtime     NodeRuntimeDebugger
+	stats       PodStatsProvider
+	conversions *ConversionRegistry
+	usage       *UsageRecorder
+	controllers ControllerStatusProvider
+	activity    ActivityTracker
+	// keepalive watch 流的心跳间隔，<= 0 时不发送
+	keepalive time.Duration
+	// admission 写入前的准入校验（见 admission.go）
+	admission *Admission
+	// evictMu 串行处理驱逐请求（见 HandleEviction）
+	evictMu sync.Mutex
+	// readCaches 带 resourceVersion 的 GET/LIST 使用的读缓存（见 readcache.go）
+	readCaches readCaches
 }
 
 // NewAPIServer 创建新的 API server
 func NewAPIServer(store storage.Store) *APIServer {
 	return &APIServer{
-		store:  store,
-		parser: parser.NewParser(),
+		store:       store,
+		parser:      parser.NewParser(),
+		conversions: DefaultConversions(),
+		admission:   NewAdmission(store, nil),
+		keepalive:   DefaultWatchKeepalive,
+	}
+}
+
+// WithAdmission 使用与其他写入方（dashboard 的 YAML 编辑器）共用的准入校验
+func WithAdmission(admission *Admission) Option {
+	return func(s *APIServer) {
+		s.admission = admission
+	}
+}
+
+// WithConversions 替换资源的存储版本与转换登记（默认 DefaultConversions）
+func WithConversions(conversions *ConversionRegistry) Option {
+	return func(s *APIServer) {
+		s.conversions = conversions
 	}
 }
 
@@ -61,6 +101,7 @@ func parseGVKFromContext(c *fiber.Ctx) (schema.GroupVersionKind, error) {
 	//   - /api/v1/namespaces/<ns>/<resource>
 	//   - /api/v1/watch/<resource>
 	//   - /api/v1/watch/namespaces/<ns>/<resource>
+	//   - /api/v1/namespaces[/<name>]（Namespace）
 	// - Grouped:
 	//   - /apis/<group>/<version>/<resource>
 	//   - /apis/<group>/<version>/namespaces/<ns>/<resource>
@@ -90,8 +131,8 @@ func parseGVKFromContext(c *fiber.Ctx) (schema.GroupVersionKind, error) {
 	if len(rest) > 0 && rest[0] == "watch" {
 		rest = rest[1:]
 	}
-	// optional "namespaces/<ns>"
-	if len(rest) >= 2 && rest[0] == "namespaces" {
+	// optional "namespaces/<ns>"（/namespaces 与 /namespaces/<name> 是 Namespace 资源本身）
+	if len(rest) >= 3 && rest[0] == "namespaces" {
 		rest = rest[2:]
 	}
 	if len(rest) < 1 {
@@ -107,34 +148,54 @@ func parseGVKFromContext(c *fiber.Ctx) (schema.GroupVersionKind, error) {
 	return schema.GroupVersionKind{Group: group, Version: version, Kind: kind}, nil
 }
 
+// requestGVK 解析请求路径的 GVK，并返回该资源在 Store 中的存储版本
+func (s *APIServer) requestGVK(c *fiber.Ctx) (gvk, storageGVK schema.GroupVersionKind, err error) {
+	gvk, err = parseGVKFromContext(c)
+	if err != nil {
+		return gvk, gvk, err
+	}
+	storageGVK, err = s.conversions.StorageGVK(gvk)
+	return gvk, storageGVK, err
+}
+
+// gvkErrorStatus 返回 requestGVK 错误对应的状态码：版本未提供时 404，路径无法解析时 400
+func gvkErrorStatus(err error) int {
+	if errors.Is(err, ErrVersionNotServed) {
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusBadRequest
+}
+
 func kindFromResource(resource string) (string, error) {
-	switch strings.ToLower(strings.TrimSpace(resource)) {
-	case "pods":
-		return "Pod", nil
-	case "services":
-		return "Service", nil
-	case "configmaps":
-		return "ConfigMap", nil
-	case "secrets":
-		return "Secret", nil
-	case "nodes":
-		return "Node", nil
-	case "deployments":
-		return "Deployment", nil
-	case "statefulsets":
-		return "StatefulSet", nil
-	case "daemonsets":
-		return "DaemonSet", nil
-	default:
-		return "", fmt.Errorf("unsupported resource: %s", resource)
+	gvk, err := resources.ForResource(resource)
+	return gvk.Kind, err
+}
+
+// storeErrorStatus 返回 Store 错误对应的状态码：存储后端不可用（熔断打开、连接失败、超时）时返回 503 并设置 Retry-After，
+// 客户端稍后重试即可；其他错误返回 status
+func storeErrorStatus(c *fiber.Ctx, err error, status int) int {
+	if storage.IsBackendError(err) {
+		c.Set(fiber.HeaderRetryAfter, "5")
+		return fiber.StatusServiceUnavailable
 	}
+	return status
 }
 
-// HandleGet 处理 GET 请求（获取单个资源）
+// GVKForResource 根据资源复数名（如 pods、deployments）返回对应的 GroupVersionKind
+func GVKForResource(resource string) (schema.GroupVersionKind, error) {
+	return resources.ForResource(resource)
+}
+
+// IsClusterScoped 判断资源是否是集群级资源（没有 namespace），与存储使用同一份登记
+func IsClusterScoped(kind string) bool {
+	return storage.IsClusterScopedKind(kind)
+}
+
+// HandleGet 处理 GET 请求（获取单个资源）；revision=N 或 history=true 时读取对象的版本历史
 func (s *APIServer) HandleGet(c *fiber.Ctx) error {
-	gvk, err := parseGVKFromContext(c)
+	gvk, storageGVK, err := s.requestGVK(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	namespace := c.Params("namespace")
@@ -143,40 +204,104 @@ func (s *APIServer) HandleGet(c *fiber.Ctx) error {
 	if name == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "resource name is required"})
 	}
+	if c.Query("revision") != "" || c.QueryBool("history") {
+		return s.handleHistory(c, gvk, storageGVK, namespace, name)
+	}
+	opts, err := parseReadOptions(c, false)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	obj, err := s.store.Get(gvk, namespace, name)
+	// 带 resourceVersion 时从读缓存读取（resourceVersion=0 为任意版本，否则不比它旧）
+	cache, err := s.cachedRead(storageGVK, opts)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(readErrorStatus(c, err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	var obj runtime.Object
+	if cache != nil {
+		cached, _, ok := cache.get(namespace, name)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": fmt.Sprintf("resource not found: %s", name)})
+		}
+		obj = cached
+	} else if obj, err = s.store.Get(storageGVK, namespace, name); err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusNotFound)).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(obj)
+	out, err := s.conversions.FromStorage(obj, gvk)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(out)
 }
 
 // HandleList 处理 GET 请求（列出资源）
 func (s *APIServer) HandleList(c *fiber.Ctx) error {
-	gvk, err := parseGVKFromContext(c)
+	gvk, storageGVK, err := s.requestGVK(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// ?watch=true 与 /watch/ 路径相同
+	if c.QueryBool("watch") {
+		return s.HandleWatch(c)
 	}
 
 	namespace := c.Params("namespace")
 
-	objects, err := s.store.List(gvk, namespace)
+	// labelSelector 交给存储按标签索引查询
+	selector, err := labels.Parse(c.Query("labelSelector"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("invalid labelSelector: %v", err)})
+	}
+	opts, err := parseReadOptions(c, true)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// 构建 List 响应
+	// 带 resourceVersion 时从读缓存读取，不扫描后端（informer 定期 relist 时使用）；否则直接读 Store
+	cache, err := s.cachedRead(storageGVK, opts)
+	if err != nil {
+		return c.Status(readErrorStatus(c, err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	var objects []runtime.Object
+	var resourceVersion string
+	if cache != nil {
+		objects, resourceVersion = cache.list(namespace, selector)
+		if err := checkExact(opts, resourceVersion); err != nil {
+			return c.Status(readErrorStatus(c, err)).JSON(fiber.Map{"error": err.Error()})
+		}
+	} else {
+		objects, err = s.store.ListBySelector(storageGVK, namespace, selector)
+		if err != nil {
+			return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+		}
+		resourceVersion = listResourceVersion(objects)
+	}
+
+	// Accept 含 as=Table 时返回服务端计算好的列（READY、STATUS、AGE 等）
+	if wantsTable(c.Get(fiber.HeaderAccept)) {
+		table := buildTable(storageGVK.Kind, objects, namespace == "" && !IsClusterScoped(gvk.Kind), time.Now())
+		table.ResourceVersion = resourceVersion
+		return c.Status(fiber.StatusOK).JSON(table)
+	}
+
+	// 构建 List 响应（metadata.resourceVersion 可用于之后带 resourceVersion 的 LIST）
 	list := &metav1.List{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "v1",
 			Kind:       "List",
 		},
-		Items: make([]runtime.RawExtension, 0, len(objects)),
+		ListMeta: metav1.ListMeta{ResourceVersion: resourceVersion},
+		Items:    make([]runtime.RawExtension, 0, len(objects)),
 	}
 
 	for _, obj := range objects {
-		data, err := json.Marshal(obj)
+		out, err := s.conversions.FromStorage(obj, gvk)
+		if err != nil {
+			continue
+		}
+		data, err := json.Marshal(out)
 		if err != nil {
 			continue
 		}
@@ -188,11 +313,14 @@ func (s *APIServer) HandleList(c *fiber.Ctx) error {
 
 // HandleCreate 处理 POST 请求（创建资源）
 func (s *APIServer) HandleCreate(c *fiber.Ctx) error {
-	gvk, err := parseGVKFromContext(c)
+	gvk, storageGVK, err := s.requestGVK(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if err := checkObjectContentType(c); err != nil {
+		return c.Status(errorStatus(err, fiber.StatusBadRequest)).JSON(fiber.Map{"error": err.Error()})
+	}
 	bodyBytes := c.Body()
 	if len(bodyBytes) == 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "empty request body"})
@@ -211,28 +339,37 @@ func (s *APIServer) HandleCreate(c *fiber.Ctx) error {
 		})
 	}
 
-	// 若是 namespaced 资源，允许从 URL 中补全 namespace（body 未填时）
-	if ns := c.Params("namespace"); ns != "" {
-		if meta, ok := obj.(metav1.Object); ok && meta.GetNamespace() == "" {
-			meta.SetNamespace(ns)
-		}
+	if err := admitNamespace(c, gvk, obj); err != nil {
+		return c.Status(errorStatus(err, fiber.StatusBadRequest)).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// 创建资源
-	if err := s.store.Create(gvk, obj); err != nil {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	// 转换为存储版本、填充默认值后创建资源
+	obj, err = s.conversions.ToStorage(obj, gvk)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	SetDefaults(obj)
+	if err := s.admission.Admit(gvk, obj, nil); err != nil {
+		return admissionError(c, err)
+	}
+	storage.RecordManager(obj, fieldManager(c))
+	if err := s.store.Create(storageGVK, obj); err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusConflict)).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(obj)
+	return s.respondConverted(c, fiber.StatusCreated, obj, gvk)
 }
 
 // HandleUpdate 处理 PUT 请求（更新资源）
 func (s *APIServer) HandleUpdate(c *fiber.Ctx) error {
-	gvk, err := parseGVKFromContext(c)
+	gvk, storageGVK, err := s.requestGVK(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if err := checkObjectContentType(c); err != nil {
+		return c.Status(errorStatus(err, fiber.StatusBadRequest)).JSON(fiber.Map{"error": err.Error()})
+	}
 	bodyBytes := c.Body()
 	if len(bodyBytes) == 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "empty request body"})
@@ -249,102 +386,128 @@ func (s *APIServer) HandleUpdate(c *fiber.Ctx) error {
 		})
 	}
 
-	// 若是 namespaced 资源，允许从 URL 中补全 namespace（body 未填时）
-	if ns := c.Params("namespace"); ns != "" {
-		if meta, ok := obj.(metav1.Object); ok && meta.GetNamespace() == "" {
-			meta.SetNamespace(ns)
-		}
+	if err := admitNamespace(c, gvk, obj); err != nil {
+		return c.Status(errorStatus(err, fiber.StatusBadRequest)).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// 更新资源
-	if err := s.store.Update(gvk, obj); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	// 更新资源：覆盖其他写入者的 labels/annotations 时返回 409，force=true 时强制写入
+	obj, err = s.conversions.ToStorage(obj, gvk)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	SetDefaults(obj)
+	var current runtime.Object
+	if meta, ok := obj.(metav1.Object); ok {
+		if stored, err := s.store.Get(storageGVK, meta.GetNamespace(), meta.GetName()); err == nil {
+			current = stored
+		}
+	}
+	if err := s.admission.Admit(gvk, obj, current); err != nil {
+		return admissionError(c, err)
+	}
+	// 与 kube-apiserver 一致，body 中的 metadata.resourceVersion 是写入的前置条件，对象已被修改时返回 409
+	conflict, err := storage.UpdateAsIf(s.store, storageGVK, obj, fieldManager(c), c.QueryBool("force"))
+	if err != nil {
+		if errors.As(err, &conflict) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error(), "conflict": conflict})
+		}
+		if storage.IsResourceVersionConflict(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(storeErrorStatus(c, err, fiber.StatusNotFound)).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(obj)
+	return s.respondConverted(c, fiber.StatusOK, obj, gvk)
 }
 
 // HandlePatch 处理 PATCH 请求（部分更新资源）
 func (s *APIServer) HandlePatch(c *fiber.Ctx) error {
-	gvk, err := parseGVKFromContext(c)
+	gvk, storageGVK, err := s.requestGVK(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	namespace := c.Params("namespace")
+	namespace := requestNamespace(c, gvk)
 	name := c.Params("name")
 
 	if name == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "resource name is required"})
 	}
 
-	// 获取现有资源
-	obj, err := s.store.Get(gvk, namespace, name)
+	// 获取现有资源，patch 作用在请求的版本上
+	stored, err := s.store.Get(storageGVK, namespace, name)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(storeErrorStatus(c, err, fiber.StatusNotFound)).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	// 解析 patch 数据
-	var patchData map[string]interface{}
-	if err := c.BodyParser(&patchData); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	obj, err := s.conversions.FromStorage(stored, gvk)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// 简单的 merge patch 实现
+	// 按 Content-Type 应用 patch（merge / strategic merge / JSON patch）
 	objBytes, err := json.Marshal(obj)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	var objMap map[string]interface{}
-	if err := json.Unmarshal(objBytes, &objMap); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	mergedBytes, err := applyPatch(mediaType(c), objBytes, c.Body(), obj)
+	if err != nil {
+		return c.Status(errorStatus(err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// 合并 patch
-	mergePatch(objMap, patchData)
-
-	// 转换回对象
-	mergedBytes, err := json.Marshal(objMap)
+	patchedObj, _, err := s.parser.ParseYAML(mergedBytes)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// patch 不能把请求改写到其他对象上
+	if meta, ok := patchedObj.(metav1.Object); ok && (meta.GetName() != name || meta.GetNamespace() != namespace) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "patch must not change metadata.name or metadata.namespace"})
 	}
 
-	patchedObj, _, err := s.parser.ParseYAML(mergedBytes)
+	// 转换为存储版本、填充默认值后更新资源（patch 删除的字段会重新取默认值）
+	patchedObj, err = s.conversions.ToStorage(patchedObj, gvk)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	SetDefaults(patchedObj)
+	if err := s.admission.Admit(gvk, patchedObj, stored); err != nil {
+		return admissionError(c, err)
+	}
+	conflict, err := storage.UpdateAs(s.store, storageGVK, patchedObj, fieldManager(c), c.QueryBool("force"))
+	if err != nil {
+		if errors.As(err, &conflict) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error(), "conflict": conflict})
+		}
+		return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	// 更新资源
-	if err := s.store.Update(gvk, patchedObj); err != nil {
+	return s.respondConverted(c, fiber.StatusOK, patchedObj, gvk)
+}
+
+// respondConverted 把存储版本的对象转换为请求的版本后返回
+func (s *APIServer) respondConverted(c *fiber.Ctx, status int, obj runtime.Object, gvk schema.GroupVersionKind) error {
+	out, err := s.conversions.FromStorage(obj, gvk)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-
-	return c.Status(fiber.StatusOK).JSON(patchedObj)
+	return c.Status(status).JSON(out)
 }
 
-// mergePatch 合并 patch 数据
-func mergePatch(dst, src map[string]interface{}) {
-	for k, v := range src {
-		if v == nil {
-			delete(dst, k)
-		} else if srcMap, ok := v.(map[string]interface{}); ok {
-			if dstMap, ok := dst[k].(map[string]interface{}); ok {
-				mergePatch(dstMap, srcMap)
-			} else {
-				dst[k] = v
-			}
-		} else {
-			dst[k] = v
-		}
+// fieldManager 返回本次写入者：优先使用 fieldManager 参数，其次取 User-Agent 的产品名（与 Kubernetes 一致）
+func fieldManager(c *fiber.Ctx) string {
+	if m := c.Query("fieldManager"); m != "" {
+		return m
+	}
+	if ua := c.Get(fiber.HeaderUserAgent); ua != "" {
+		return strings.SplitN(ua, "/", 2)[0]
 	}
+	return "unknown"
 }
 
 // HandleDelete 处理 DELETE 请求（删除资源）
 func (s *APIServer) HandleDelete(c *fiber.Ctx) error {
-	gvk, err := parseGVKFromContext(c)
+	gvk, storageGVK, err := s.requestGVK(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	namespace := c.Params("namespace")
@@ -355,30 +518,146 @@ func (s *APIServer) HandleDelete(c *fiber.Ctx) error {
 	}
 
 	// 获取资源（用于返回）
-	obj, err := s.store.Get(gvk, namespace, name)
+	obj, err := s.store.Get(storageGVK, namespace, name)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(storeErrorStatus(c, err, fiber.StatusNotFound)).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := s.admission.AdmitWrite(obj); err != nil {
+		return admissionError(c, err)
 	}
 
-	// 删除资源
-	if err := s.store.Delete(gvk, namespace, name); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	// 删除资源（版本历史中记录删除者）
+	if err := storage.DeleteAs(s.store, storageGVK, namespace, name, fieldManager(c)); err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	// 返回删除的对象
-	return c.Status(fiber.StatusOK).JSON(obj)
+	return s.respondConverted(c, fiber.StatusOK, obj, gvk)
+}
+
+// DeleteCollectionSummary 是 deletecollection 的响应
+type DeleteCollectionSummary struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	// DryRun 为 true 时只列出会被删除的对象
+	DryRun  bool `json:"dryRun,omitempty"`
+	Deleted int  `json:"deleted"`
+	// Items 被删除对象的 namespace/name（集群级资源只有 name）
+	Items []string `json:"items"`
+}
+
+// HandleDeleteCollection 处理集合上的 DELETE 请求：按 labelSelector/fieldSelector 批量删除（跳过 import 模式的只读镜像），
+// dryRun=All 时只返回匹配结果
+func (s *APIServer) HandleDeleteCollection(c *fiber.Ctx) error {
+	_, storageGVK, err := s.requestGVK(c)
+	if err != nil {
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	namespace := c.Params("namespace")
+	match, err := selectorMatcher(c.Query("labelSelector"), c.Query("fieldSelector"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// import 模式的只读镜像不删除
+	matchObject := func(obj runtime.Object) bool {
+		meta, ok := obj.(metav1.Object)
+		return ok && match(meta) && s.admission.AdmitWrite(obj) == nil
+	}
+
+	summary := DeleteCollectionSummary{
+		Kind:       "DeleteCollectionSummary",
+		APIVersion: "v1",
+		DryRun:     c.Query("dryRun") == metav1.DryRunAll,
+		Items:      []string{},
+	}
+
+	var objects []runtime.Object
+	if summary.DryRun {
+		all, err := s.store.List(storageGVK, namespace)
+		if err != nil {
+			return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+		}
+		for _, obj := range all {
+			if matchObject(obj) {
+				objects = append(objects, obj)
+			}
+		}
+	} else {
+		objects, err = s.store.DeleteCollection(storageGVK, namespace, matchObject)
+	}
+
+	summary.Items = objectKeys(objects)
+	summary.Deleted = len(objects)
+	if err != nil {
+		// 部分删除成功时同样返回已删除的对象，便于脚本重试
+		return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error(), "summary": summary})
+	}
+	return c.Status(fiber.StatusOK).JSON(summary)
+}
+
+// selectorMatcher 解析 labelSelector 与 fieldSelector（支持 metadata.name / metadata.namespace）
+func selectorMatcher(labelSelector, fieldSelector string) (func(metav1.Object) bool, error) {
+	ls, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid labelSelector: %w", err)
+	}
+	fs, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fieldSelector: %w", err)
+	}
+	for _, r := range fs.Requirements() {
+		if r.Field != "metadata.name" && r.Field != "metadata.namespace" {
+			return nil, fmt.Errorf("unsupported fieldSelector field: %s", r.Field)
+		}
+	}
+	return func(meta metav1.Object) bool {
+		return ls.Matches(labels.Set(meta.GetLabels())) && fs.Matches(fields.Set{
+			"metadata.name":      meta.GetName(),
+			"metadata.namespace": meta.GetNamespace(),
+		})
+	}, nil
+}
+
+// objectKeys 返回对象的 namespace/name 列表（排序，便于阅读）
+func objectKeys(objects []runtime.Object) []string {
+	keys := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		meta, ok := obj.(metav1.Object)
+		if !ok {
+			continue
+		}
+		if meta.GetNamespace() == "" {
+			keys = append(keys, meta.GetName())
+		} else {
+			keys = append(keys, meta.GetNamespace()+"/"+meta.GetName())
+		}
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // HandleWatch 处理 WATCH 请求（监听资源变更）
 func (s *APIServer) HandleWatch(c *fiber.Ctx) error {
-	gvk, err := parseGVKFromContext(c)
+	gvk, storageGVK, err := s.requestGVK(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	namespace := c.Params("namespace")
 	resourceVersion := c.Query("resourceVersion")
 
+	// labelSelector/fieldSelector 在服务端过滤事件：对象进入或离开选择范围时推送 ADDED/DELETED
+	match, err := selectorMatcher(c.Query("labelSelector"), c.Query("fieldSelector"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	// import 模式的只读镜像不删除
+	matchObject := func(obj runtime.Object) bool {
+		meta, ok := obj.(metav1.Object)
+		return ok && match(meta) && s.admission.AdmitWrite(obj) == nil
+	}
+
 	// 设置 Server-Sent Events 响应头
 	c.Set("Content-Type", "text/event-stream")
 	c.Set("Cache-Control", "no-cache")
@@ -386,11 +665,29 @@ func (s *APIServer) HandleWatch(c *fiber.Ctx) error {
 	c.Set("X-Accel-Buffering", "no") // 禁用 nginx 缓冲
 
 	// 创建 watch channel
-	eventCh, err := s.store.Watch(gvk, namespace, resourceVersion)
+	eventCh, err := s.store.Watch(storageGVK, namespace, resourceVersion)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	// sendInitialEvents=true：订阅之后列出当前对象，先以 ADDED 推送，再推送带 k8s.io/initial-events-end 注解的 BOOKMARK，
+	// 客户端一次请求完成 list+watch，不会漏掉两次请求之间的变更
+	var initial []runtime.Object
+	var tracker *initialEvents
+	if c.QueryBool("sendInitialEvents") {
+		objects, err := s.store.List(storageGVK, namespace)
+		if err != nil {
+			s.stopWatch(storageGVK, namespace, eventCh)
+			return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+		}
+		for _, obj := range objects {
+			if matchObject(obj) {
+				initial = append(initial, obj)
+			}
+		}
+		tracker = newInitialEvents(initial)
+	}
+
 	// 设置超时（可选）
 	timeoutSeconds := c.Query("timeoutSeconds")
 	var timeout time.Duration = 30 * time.Minute // 默认 30 分钟
@@ -400,75 +697,111 @@ func (s *APIServer) HandleWatch(c *fiber.Ctx) error {
 		}
 	}
 
-	// 创建 context with timeout
-	reqCtx := c.UserContext()
-	if timeout > 0 {
-		var cancel context.CancelFunc
-		reqCtx, cancel = context.WithTimeout(reqCtx, timeout)
-		defer cancel()
-	}
+	// 使用流式响应：fasthttp 默认会缓冲整个响应体，直到 handler 返回才发送，
+	// 因此事件需要在 body stream writer 中逐条写出并 flush。
+	// 客户端断开后只有写入才会失败，没有事件时由心跳注释尽快发现断开；流结束时注销 watcher
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer s.stopWatch(storageGVK, namespace, eventCh)
+		keepalive, stopKeepalive := s.keepaliveTicker()
+		defer stopKeepalive()
 
-	// 发送初始事件（BOOKMARK）
-	initialEvent := watch.Event{
-		Type:   watch.Bookmark,
-		Object: &metav1.Status{},
-	}
-	if err := sendSSEFiber(c, initialEvent); err != nil {
-		return err
-	}
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
 
-	// 流式发送事件
-	for {
-		select {
-		case event, ok := <-eventCh:
-			if !ok {
-				return nil
+		if tracker != nil {
+			for _, obj := range initial {
+				out, err := s.conversions.FromStorage(obj, gvk)
+				if err != nil {
+					continue
+				}
+				if err := writeSSE(w, watch.Event{Type: watch.Added, Object: out}); err != nil {
+					return
+				}
 			}
-
-			// 转换事件类型
-			var watchType watch.EventType
-			switch event.Type {
-			case storage.EventAdded:
-				watchType = watch.Added
-			case storage.EventModified:
-				watchType = watch.Modified
-			case storage.EventDeleted:
-				watchType = watch.Deleted
-			case storage.EventBookmark:
-				watchType = watch.Bookmark
-			default:
-				watchType = watch.Added
+			if err := writeSSE(w, watch.Event{Type: watch.Bookmark, Object: tracker.endBookmark(gvk)}); err != nil {
+				return
 			}
-
-			// 发送事件
-			watchEvent := watch.Event{
-				Type:   watchType,
-				Object: event.Object,
+		} else {
+			// 发送初始事件（BOOKMARK）
+			initialEvent := watch.Event{
+				Type:   watch.Bookmark,
+				Object: &metav1.Status{},
 			}
-
-			if err := sendSSEFiber(c, watchEvent); err != nil {
-				return err
+			if err := writeSSE(w, initialEvent); err != nil {
+				return
 			}
+		}
 
-		case <-reqCtx.Done():
-			return nil
+		// 流式发送事件
+		for {
+			select {
+			case event, ok := <-eventCh:
+				if !ok {
+					return
+				}
+				if event, ok = storage.FilterEvent(event, matchObject); !ok {
+					continue
+				}
+				if tracker != nil && tracker.skip(event) {
+					continue
+				}
+
+				// 转换事件类型
+				var watchType watch.EventType
+				switch event.Type {
+				case storage.EventAdded:
+					watchType = watch.Added
+				case storage.EventModified:
+					watchType = watch.Modified
+				case storage.EventDeleted:
+					watchType = watch.Deleted
+				case storage.EventBookmark:
+					watchType = watch.Bookmark
+				default:
+					watchType = watch.Added
+				}
+
+				// 发送事件（客户端断开时写入/flush 会失败）
+				out, err := s.conversions.FromStorage(event.Object, gvk)
+				if err != nil {
+					continue
+				}
+				watchEvent := watch.Event{
+					Type:   watchType,
+					Object: out,
+				}
+				if err := writeSSE(w, watchEvent); err != nil {
+					return
+				}
+
+			case <-keepalive:
+				if err := writeKeepalive(w); err != nil {
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
 		}
-	}
+	})
+
+	return nil
 }
 
-// sendSSEFiber 发送 Server-Sent Event (Fiber版本)
-func sendSSEFiber(c *fiber.Ctx, event watch.Event) error {
+// writeSSE 发送 Server-Sent Event 并立即 flush
+func writeSSE(w *bufio.Writer, event watch.Event) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
 
 	// SSE 格式: data: {json}\n\n
-	_, err = fmt.Fprintf(c, "data: %s\n\n", string(data))
-	if err != nil {
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", string(data)); err != nil {
 		return err
 	}
-
-	// Fiber 会自动处理刷新
-	return nil
+	return w.Flush()
 }
@@ -0,0 +1,78 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// RevisionList 是 GET ...?history=true 的响应：对象保留的版本，按版本号从旧到新，
+// 每个版本的 object 已转换为请求的版本
+type RevisionList struct {
+	Kind       string             `json:"kind"`
+	APIVersion string             `json:"apiVersion"`
+	Items      []storage.Revision `json:"items"`
+}
+
+// handleHistory 处理带 revision 或 history 参数的 GET：revision=N 返回第 N 个版本的对象，
+// history=true 返回所有保留的版本。对象删除后历史仍可读取
+func (s *APIServer) handleHistory(c *fiber.Ctx, gvk, storageGVK schema.GroupVersionKind, namespace, name string) error {
+	notFound := fiber.Map{"error": fmt.Sprintf("resource not found: %s", name)}
+	revisions, err := storage.History(s.store, storageGVK, namespace, name)
+	if errors.Is(err, storage.ErrHistoryUnsupported) {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(revisions) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(notFound)
+	}
+
+	if v := c.Query("revision"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("invalid revision: %s", v)})
+		}
+		rev, ok := storage.FindRevision(revisions, n)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": fmt.Sprintf("revision %d of %s not found (kept revisions %d-%d)", n, name, revisions[0].Revision, revisions[len(revisions)-1].Revision),
+			})
+		}
+		out, err := s.revisionObject(rev, gvk)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusOK).JSON(json.RawMessage(out))
+	}
+
+	list := RevisionList{Kind: "RevisionList", APIVersion: "v1", Items: make([]storage.Revision, 0, len(revisions))}
+	for _, rev := range revisions {
+		out, err := s.revisionObject(rev, gvk)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		rev.Object = out
+		list.Items = append(list.Items, rev)
+	}
+	return c.Status(fiber.StatusOK).JSON(list)
+}
+
+// revisionObject 把版本中保存的存储版本对象转换为请求的版本
+func (s *APIServer) revisionObject(rev storage.Revision, gvk schema.GroupVersionKind) ([]byte, error) {
+	obj, _, err := s.parser.ParseYAML(rev.Object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d: %w", rev.Revision, err)
+	}
+	out, err := s.conversions.FromStorage(obj, gvk)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,117 @@
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// NodeImageManager 管理本节点的镜像（由持有容器运行时的进程提供，例如 one 模式下的 ControllerManager）
+type NodeImageManager interface {
+	// NodeName 返回本节点名称，只有发往本节点的请求才会直接访问容器运行时
+	NodeName() string
+	// ListNodeImages 列出本节点上的镜像
+	ListNodeImages(ctx context.Context) ([]corev1.ContainerImage, error)
+	// PullImages 依次拉取镜像，返回每个镜像的结果
+	PullImages(ctx context.Context, images []string) []ImagePullResult
+}
+
+// WithNodeImageManager 启用 nodes/images 子资源的实时查询和预拉取
+func WithNodeImageManager(images NodeImageManager) Option {
+	return func(s *APIServer) {
+		s.images = images
+	}
+}
+
+// NodeImageList 是 GET /api/v1/nodes/:name/images 的响应
+type NodeImageList struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Node       string `json:"node"`
+	// Source 为 runtime（实时查询本节点运行时）或 nodeStatus（节点最近一次上报的 status.images）
+	Source string                  `json:"source"`
+	Images []corev1.ContainerImage `json:"images"`
+}
+
+// ImagePullRequest 是 POST /api/v1/nodes/:name/images 的请求体
+type ImagePullRequest struct {
+	Images []string `json:"images"`
+}
+
+// ImagePullResult 单个镜像的预拉取结果
+type ImagePullResult struct {
+	Image  string `json:"image"`
+	Pulled bool   `json:"pulled"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HandleListNodeImages 处理 GET /api/v1/nodes/:name/images：
+// 本节点实时查询容器运行时，其他节点返回其上报在 status.images 中的镜像
+func (s *APIServer) HandleListNodeImages(c *fiber.Ctx) error {
+	name := c.Params("name")
+	list := NodeImageList{Kind: "NodeImageList", APIVersion: "v1", Node: name}
+
+	if s.images != nil && s.images.NodeName() == name {
+		images, err := s.images.ListNodeImages(c.UserContext())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		list.Source = "runtime"
+		list.Images = images
+		return c.JSON(list)
+	}
+
+	obj, err := s.store.Get(schema.GroupVersionKind{Version: "v1", Kind: "Node"}, "", name)
+	if err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusNotFound)).JSON(fiber.Map{"error": err.Error()})
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "stored object is not a Node"})
+	}
+	list.Source = "nodeStatus"
+	list.Images = node.Status.Images
+	return c.JSON(list)
+}
+
+// HandlePullNodeImages 处理 POST /api/v1/nodes/:name/images：在本节点上预拉取镜像。
+// 部分镜像拉取失败时返回 207 Multi-Status，结果中带每个镜像的错误。
+func (s *APIServer) HandlePullNodeImages(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if s.images == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "image management is not available on this server (no container runtime)"})
+	}
+	if s.images.NodeName() != name {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("node %s is not served by this server (local node: %s)", name, s.images.NodeName()),
+		})
+	}
+
+	var req ImagePullRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	var images []string
+	for _, image := range req.Images {
+		if image = strings.TrimSpace(image); image != "" {
+			images = append(images, image)
+		}
+	}
+	if len(images) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "images must not be empty"})
+	}
+
+	results := s.images.PullImages(c.UserContext(), images)
+	status := fiber.StatusOK
+	for _, r := range results {
+		if !r.Pulled {
+			status = fiber.StatusMultiStatus
+			break
+		}
+	}
+	return c.Status(status).JSON(fiber.Map{"node": name, "results": results})
+}
@@ -0,0 +1,94 @@
+package apiserver
+
+import (
+	"strconv"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// InitialEventsEndAnnotation 标记初始事件结束的 BOOKMARK（与 Kubernetes 的 sendInitialEvents 相同）
+const InitialEventsEndAnnotation = "k8s.io/initial-events-end"
+
+// initialEvents 记录 sendInitialEvents 时以 ADDED 推送过的对象，用于丢弃先订阅、后列出期间重复到达的事件。
+// 订阅在列出之前建立，列出之后发生的变更不会丢失；列出之前已经发生的变更会在 watch 通道中再出现一次，
+// 按 resourceVersion 判断不比列出的版本新的事件直接丢弃
+type initialEvents struct {
+	// listed 列出时各对象（namespace/name）的 resourceVersion，对象收到更新的事件后移除
+	listed map[string]string
+	// known 客户端已知存在的对象：DELETED 只对这些对象推送
+	known map[string]bool
+	// resourceVersion 列出的对象中最新的 resourceVersion，写入结束 BOOKMARK
+	resourceVersion string
+}
+
+// newInitialEvents 记录列出的对象
+func newInitialEvents(objects []runtime.Object) *initialEvents {
+	ie := &initialEvents{listed: make(map[string]string, len(objects)), known: make(map[string]bool, len(objects))}
+	for _, obj := range objects {
+		meta, ok := obj.(metav1.Object)
+		if !ok {
+			continue
+		}
+		key := objectKey(meta)
+		ie.listed[key] = meta.GetResourceVersion()
+		ie.known[key] = true
+		if newerResourceVersion(meta.GetResourceVersion(), ie.resourceVersion) {
+			ie.resourceVersion = meta.GetResourceVersion()
+		}
+	}
+	return ie
+}
+
+// skip 判断 watch 通道中的事件是否已经包含在初始事件中
+func (ie *initialEvents) skip(event storage.ResourceEvent) bool {
+	meta, ok := event.Object.(metav1.Object)
+	if !ok || event.Type == storage.EventBookmark {
+		return false
+	}
+	key := objectKey(meta)
+	if event.Type == storage.EventDeleted {
+		if !ie.known[key] {
+			return true
+		}
+		delete(ie.known, key)
+		delete(ie.listed, key)
+		return false
+	}
+	if rv, ok := ie.listed[key]; ok {
+		if !newerResourceVersion(meta.GetResourceVersion(), rv) {
+			return true
+		}
+		delete(ie.listed, key)
+	}
+	ie.known[key] = true
+	return false
+}
+
+// endBookmark 返回初始事件结束的 BOOKMARK 对象
+func (ie *initialEvents) endBookmark(gvk schema.GroupVersionKind) runtime.Object {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: gvk.GroupVersion().String(), Kind: gvk.Kind},
+		ObjectMeta: metav1.ObjectMeta{
+			ResourceVersion: ie.resourceVersion,
+			Annotations:     map[string]string{InitialEventsEndAnnotation: "true"},
+		},
+	}
+}
+
+// objectKey 返回对象的 namespace/name
+func objectKey(meta metav1.Object) string {
+	return meta.GetNamespace() + "/" + meta.GetName()
+}
+
+// newerResourceVersion 判断 a 是否比 b 新：都是数字时按数值比较，否则只要不同就视为更新
+func newerResourceVersion(a, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil {
+		return x > y
+	}
+	return a != b
+}
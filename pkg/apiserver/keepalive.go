@@ -0,0 +1,61 @@
+package apiserver
+
+import (
+	"bufio"
+	"fmt"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultWatchKeepalive 未配置 apiserver.watch_keepalive 时 watch 流发送心跳注释的间隔。
+// 反向代理（nginx 默认 proxy_read_timeout 60s）与负载均衡会关闭长时间没有数据的连接，间隔需小于它们的空闲超时
+const DefaultWatchKeepalive = 15 * time.Second
+
+// WithWatchKeepalive 设置 watch 流的心跳间隔：没有事件时每隔 interval 写一行 SSE 注释（": keepalive"），
+// 既让代理保持连接，也能尽快发现已经断开的客户端；interval <= 0 时不发送心跳
+func WithWatchKeepalive(interval time.Duration) Option {
+	return func(s *APIServer) {
+		s.keepalive = interval
+	}
+}
+
+// parseWatchKeepalive 解析 apiserver.watch_keepalive：为空时使用默认值，off 时关闭心跳
+func parseWatchKeepalive(v string) (time.Duration, error) {
+	switch v {
+	case "":
+		return DefaultWatchKeepalive, nil
+	case "off":
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("apiserver.watch_keepalive 无效: %q", v)
+	}
+	return d, nil
+}
+
+// keepaliveTicker 返回心跳 ticker 的通道与停止函数；未启用心跳时返回 nil 通道（select 中永远不会就绪）
+func (s *APIServer) keepaliveTicker() (<-chan time.Time, func()) {
+	if s.keepalive <= 0 {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(s.keepalive)
+	return ticker.C, ticker.Stop
+}
+
+// writeKeepalive 写入一行 SSE 注释并 flush；客户端已断开时返回错误
+func writeKeepalive(w *bufio.Writer) error {
+	if _, err := w.WriteString(": keepalive\n\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// stopWatch 注销 watch 通道（store 实现了 storage.WatchStopper 时），客户端断开或流结束后立即释放 watcher
+func (s *APIServer) stopWatch(gvk schema.GroupVersionKind, namespace string, ch <-chan storage.ResourceEvent) {
+	if stopper, ok := s.store.(storage.WatchStopper); ok {
+		stopper.StopWatcher(gvk, namespace, ch)
+	}
+}
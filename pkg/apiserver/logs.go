@@ -0,0 +1,122 @@
+package apiserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PodLogStreamer 读取 Pod 的容器日志（由持有容器运行时的进程提供，例如 one 模式下的 ControllerManager）
+type PodLogStreamer interface {
+	StreamPodLogs(ctx context.Context, pod *corev1.Pod, opts *corev1.PodLogOptions) (io.ReadCloser, error)
+}
+
+// Option 是 RegisterRoutes 的可选配置
+type Option func(*APIServer)
+
+// WithPodLogStreamer 启用 pods/log 子资源
+func WithPodLogStreamer(logs PodLogStreamer) Option {
+	return func(s *APIServer) {
+		s.logs = logs
+	}
+}
+
+// ParsePodLogOptions 从查询参数解析 PodLogOptions（container/follow/timestamps/tailLines/sinceSeconds）
+func ParsePodLogOptions(c *fiber.Ctx) (*corev1.PodLogOptions, error) {
+	opts := &corev1.PodLogOptions{
+		Container:  c.Query("container"),
+		Follow:     c.QueryBool("follow"),
+		Timestamps: c.QueryBool("timestamps"),
+	}
+	if v := c.Query("tailLines"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid tailLines: %s", v)
+		}
+		opts.TailLines = &n
+	}
+	if v := c.Query("sinceSeconds"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid sinceSeconds: %s", v)
+		}
+		opts.SinceSeconds = &n
+	}
+	return opts, nil
+}
+
+// OpenPodLogs 查找 Pod 并打开日志流；出错时同时返回建议的 HTTP 状态码
+func OpenPodLogs(ctx context.Context, store storage.Store, logs PodLogStreamer, namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, int, error) {
+	if logs == nil {
+		return nil, fiber.StatusNotImplemented, fmt.Errorf("pod logs are not available on this server (no container runtime)")
+	}
+	obj, err := store.Get(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, namespace, name)
+	if err != nil {
+		return nil, fiber.StatusNotFound, err
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fiber.StatusInternalServerError, fmt.Errorf("stored object is not a Pod")
+	}
+	rc, err := logs.StreamPodLogs(ctx, pod, opts)
+	if err != nil {
+		return nil, fiber.StatusBadRequest, err
+	}
+	return rc, fiber.StatusOK, nil
+}
+
+// HandlePodLog 处理 GET /api/v1/namespaces/:namespace/pods/:name/log
+func (s *APIServer) HandlePodLog(c *fiber.Ctx) error {
+	opts, err := ParsePodLogOptions(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rc, status, err := OpenPodLogs(ctx, s.store, s.logs, c.Params("namespace"), c.Params("name"), opts)
+	if err != nil {
+		cancel()
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set("Content-Type", "text/plain; charset=utf-8")
+	c.Set("X-Accel-Buffering", "no")
+	if !opts.Follow {
+		defer cancel()
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusOK).Send(data)
+	}
+
+	// follow：逐块写出并 flush，客户端断开时写入失败即结束
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer rc.Close()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := rc.Read(buf)
+			if n > 0 {
+				if _, werr := w.Write(buf[:n]); werr != nil {
+					return
+				}
+				if werr := w.Flush(); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
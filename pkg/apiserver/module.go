@@ -1,14 +1,174 @@
 package apiserver
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/logprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
 	"go.uber.org/fx"
 )
 
+// routeParams 是注册路由所需的依赖（PodLogStreamer、NodeImageManager、NodeRuntimeDebugger、PodStatsProvider、ControllerStatusProvider 仅在带控制器的进程中存在，
+// ActivityTracker 仅在引入 idle.Module 的进程中存在）
+type routeParams struct {
+	fx.In
+
+	Lifecycle   fx.Lifecycle
+	Config      config.Config
+	Logger      logprovider.Logger
+	FiberEngine webprovider.FiberEngine
+	Store       storage.Store
+	Admission   *Admission
+	Logs        PodLogStreamer           `optional:"true"`
+	Images      NodeImageManager         `optional:"true"`
+	Runtime     NodeRuntimeDebugger      `optional:"true"`
+	Stats       PodStatsProvider         `optional:"true"`
+	Controllers ControllerStatusProvider `optional:"true"`
+	Activity    ActivityTracker          `optional:"true"`
+}
+
 // Module 提供 API server 模块
 var Module = fx.Options(
-	fx.Invoke(func(fiberEngine webprovider.FiberEngine, store storage.Store) {
-		RegisterRoutes(fiberEngine, store)
+	fx.Provide(newConfiguredAdmission),
+	fx.Invoke(func(p routeParams) error {
+		keepalive, err := parseWatchKeepalive(p.Config.APIServer.WatchKeepalive)
+		if err != nil {
+			return err
+		}
+		opts := []Option{WithWatchKeepalive(keepalive), WithAdmission(p.Admission)}
+		if p.Logs != nil {
+			opts = append(opts, WithPodLogStreamer(p.Logs))
+		}
+		if p.Images != nil {
+			opts = append(opts, WithNodeImageManager(p.Images))
+		}
+		if p.Runtime != nil {
+			opts = append(opts, WithNodeRuntimeDebugger(p.Runtime))
+		}
+		if p.Stats != nil {
+			opts = append(opts, WithPodStatsProvider(p.Stats))
+		}
+		if p.Controllers != nil {
+			opts = append(opts, WithControllerStatusProvider(p.Controllers))
+		}
+		if p.Activity != nil {
+			opts = append(opts, WithActivityTracker(p.Activity))
+		}
+		usage, err := startUsageRecorder(p)
+		if err != nil {
+			return err
+		}
+		if usage != nil {
+			opts = append(opts, WithUsageRecorder(usage))
+		}
+		if err := startSelfRegistrar(p); err != nil {
+			return err
+		}
+		RegisterRoutes(p.FiberEngine, p.Store, opts...)
+		return nil
 	}),
 )
+
+// newConfiguredAdmission 按 network.service_cidrs 创建准入校验，apiserver 与 dashboard 的 YAML 编辑器共用
+func newConfiguredAdmission(cfg config.Config, store storage.Store) (*Admission, error) {
+	cidrs, err := cfg.Network.CIDRs()
+	if err != nil {
+		return nil, err
+	}
+	return NewAdmission(store, cidrs.Service), nil
+}
+
+// startUsageRecorder 按 apiserver.usage_interval 创建请求统计，并在应用运行期间定期写入 ClientUsage；
+// 配置为 off 时返回 nil
+func startUsageRecorder(p routeParams) (*UsageRecorder, error) {
+	interval := DefaultUsageInterval
+	switch v := p.Config.APIServer.UsageInterval; v {
+	case "":
+	case "off":
+		return nil, nil
+	default:
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("apiserver.usage_interval 无效: %q", v)
+		}
+		interval = d
+	}
+
+	usage := NewUsageRecorder(p.Store)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				usage.Run(ctx, interval, func(err error) {
+					p.Logger.Warnf("写入 ClientUsage 失败: %v", err)
+				})
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+			case <-stopCtx.Done():
+			}
+			return nil
+		},
+	})
+	return usage, nil
+}
+
+// startSelfRegistrar 按 apiserver.self_register_interval 在应用运行期间把本节点发布到 kube-system/k3-apiserver，
+// 退出时移除本节点的地址；配置为 off 时不启动
+func startSelfRegistrar(p routeParams) error {
+	interval := DefaultSelfRegisterInterval
+	switch v := p.Config.APIServer.SelfRegisterInterval; v {
+	case "":
+	case "off":
+		return nil
+	default:
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("apiserver.self_register_interval 无效: %q", v)
+		}
+		interval = d
+	}
+	address, err := AdvertiseAddress(p.Config.APIServer.AdvertiseAddress)
+	if err != nil {
+		return err
+	}
+	port := p.Config.Gin.Port
+	if port <= 0 || port > 65535 {
+		p.Logger.Warnf("web.port 无效（%d），不发布 %s/%s", port, SelfRegisterNamespace, SelfRegisterService)
+		return nil
+	}
+
+	registrar := NewSelfRegistrar(p.Store, SelfNodeName(p.Config.NodeName), address, int32(port), interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				registrar.Run(ctx, func(err error) {
+					p.Logger.Warnf("发布 %s/%s 失败: %v", SelfRegisterNamespace, SelfRegisterService, err)
+				})
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+			case <-stopCtx.Done():
+			}
+			return nil
+		},
+	})
+	return nil
+}
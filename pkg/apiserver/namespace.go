@@ -0,0 +1,45 @@
+package apiserver
+
+import (
+	"fmt"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// requestNamespace 返回请求操作的对象所在的 namespace：集群级资源为空，
+// namespace 级资源取 URL 中的 namespace，没有时为 default（与 Store 定位单个对象的规则一致）
+func requestNamespace(c *fiber.Ctx, gvk schema.GroupVersionKind) string {
+	if storage.IsClusterScoped(gvk) {
+		return ""
+	}
+	if ns := c.Params("namespace"); ns != "" {
+		return ns
+	}
+	return metav1.NamespaceDefault
+}
+
+// admitNamespace 是写入前决定对象 namespace 的唯一一步（POST/PUT）：集群级资源清空 namespace；
+// namespace 级资源在请求体未填写时依次取 URL 中的 namespace 与 default，
+// 两者都填写且不一致时拒绝，避免借助 URL 中有权限的 namespace 写入其他 namespace
+func admitNamespace(c *fiber.Ctx, gvk schema.GroupVersionKind, obj runtime.Object) error {
+	meta, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+	if storage.IsClusterScoped(gvk) {
+		meta.SetNamespace("")
+		return nil
+	}
+	urlNamespace := c.Params("namespace")
+	switch {
+	case meta.GetNamespace() == "":
+		meta.SetNamespace(requestNamespace(c, gvk))
+	case urlNamespace != "" && meta.GetNamespace() != urlNamespace:
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("namespace mismatch: path=%s, body=%s", urlNamespace, meta.GetNamespace()))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// HandlePatchNodeLabels 处理 PATCH /api/v1/nodes/:name/labels
+func (s *APIServer) HandlePatchNodeLabels(c *fiber.Ctx) error {
+	return s.patchNodeMetadata(c, storage.NodeLabels)
+}
+
+// HandlePatchNodeAnnotations 处理 PATCH /api/v1/nodes/:name/annotations
+func (s *APIServer) HandlePatchNodeAnnotations(c *fiber.Ctx) error {
+	return s.patchNodeMetadata(c, storage.NodeAnnotations)
+}
+
+// patchNodeMetadata 把请求体（键到值的 JSON 对象，值为 null 表示删除）合并到 Node 的 labels 或 annotations，返回更新后的 Node。
+// 写入者见 nodeMetadataManager；受保护前缀（storage.NodeMetadataOwners）下的键只能由其所属写入者修改，否则返回 403；
+// import 模式的镜像 Node 是只读的，同样返回 403
+func (s *APIServer) patchNodeMetadata(c *fiber.Ctx, field storage.NodeMetadataField) error {
+	gvk, storageGVK, err := s.requestGVK(c)
+	if err != nil {
+		return c.Status(gvkErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	switch mt := mediaType(c); mt {
+	case "", fiber.MIMEApplicationJSON, ContentTypeMergePatch:
+	default:
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": fmt.Sprintf("unsupported media type %q, expected %s or %s", mt, fiber.MIMEApplicationJSON, ContentTypeMergePatch),
+		})
+	}
+	var patch map[string]*string
+	if err := json.Unmarshal(c.Body(), &patch); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body must be an object of string or null values: " + err.Error()})
+	}
+
+	name := c.Params("name")
+	obj, err := s.store.Get(storageGVK, "", name)
+	if err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusNotFound)).JSON(fiber.Map{"error": err.Error()})
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "stored object is not a Node"})
+	}
+	if err := s.admission.AdmitWrite(node); err != nil {
+		return admissionError(c, err)
+	}
+
+	changed, err := storage.PatchNodeMetadata(node, field, patch, nodeMetadataManager(c))
+	if err != nil {
+		var owner *storage.MetadataOwnerError
+		switch {
+		case errors.As(err, &owner):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error(), "owner": owner})
+		case errors.Is(err, storage.ErrInvalidNodeMetadata):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if changed {
+		if err := s.store.Update(storageGVK, node); err != nil {
+			return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	return s.respondConverted(c, fiber.StatusOK, node, gvk)
+}
+
+// nodeMetadataManager 返回修改 Node labels/annotations 的写入者。开启认证时为认证身份的用户名：fieldManager 与 User-Agent
+// 由客户端任意填写，不能用来决定受保护前缀的归属。未开启认证时没有可信的身份，取自 fieldManager 或 User-Agent，
+// 此时受保护前缀只防止外部 agent 误写，不是授权边界
+func nodeMetadataManager(c *fiber.Ctx) string {
+	if id := webprovider.IdentityFromCtx(c); id != nil {
+		return id.User
+	}
+	return fieldManager(c)
+}
@@ -0,0 +1,389 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// PATCH 请求体支持的 Content-Type
+const (
+	// ContentTypeMergePatch JSON merge patch（RFC 7386）
+	ContentTypeMergePatch = "application/merge-patch+json"
+	// ContentTypeStrategicMergePatch strategic merge patch（kubectl patch 的默认类型），列表按 patchMergeKey 合并
+	ContentTypeStrategicMergePatch = "application/strategic-merge-patch+json"
+	// ContentTypeJSONPatch JSON patch（RFC 6902），请求体为操作数组
+	ContentTypeJSONPatch = "application/json-patch+json"
+)
+
+// mediaType 返回请求的 Content-Type（小写、去掉 charset 等参数），未设置时返回空字符串
+func mediaType(c *fiber.Ctx) string {
+	value := c.Get(fiber.HeaderContentType)
+	if value == "" {
+		return ""
+	}
+	mt, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+	return mt
+}
+
+// errorStatus 返回 *fiber.Error 中的状态码，其他错误返回 status
+func errorStatus(err error, status int) int {
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return status
+}
+
+// checkObjectContentType 检查 POST/PUT 请求体的 Content-Type：对象只能以 JSON 或 YAML 提交（未设置时按内容识别），
+// 其他类型（表单、protobuf 等）返回 415
+func checkObjectContentType(c *fiber.Ctx) error {
+	switch mt := mediaType(c); mt {
+	case "", fiber.MIMEApplicationJSON, "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml", fiber.MIMETextPlain:
+		return nil
+	default:
+		return fiber.NewError(fiber.StatusUnsupportedMediaType,
+			fmt.Sprintf("unsupported media type %q, expected application/json or application/yaml", mt))
+	}
+}
+
+// applyPatch 按 Content-Type 把 patch 作用在 original（对象的 JSON）上，返回 patch 后的 JSON。
+// application/json 与未设置 Content-Type 时按 merge patch 处理（兼容旧客户端）；schema 为对象的 Go 类型，strategic merge patch 据此合并列表。
+// 返回的错误为 *fiber.Error：不支持的类型为 415，patch 格式错误为 400，无法应用（路径不存在、test 不满足）为 422
+func applyPatch(contentType string, original, patch []byte, schema runtime.Object) ([]byte, error) {
+	switch contentType {
+	case "", fiber.MIMEApplicationJSON, ContentTypeMergePatch:
+		return applyMergePatch(original, patch)
+	case ContentTypeStrategicMergePatch:
+		out, err := strategicpatch.StrategicMergePatch(original, patch, schema)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		}
+		return out, nil
+	case ContentTypeJSONPatch:
+		return applyJSONPatch(original, patch)
+	default:
+		return nil, fiber.NewError(fiber.StatusUnsupportedMediaType, fmt.Sprintf("unsupported patch type %q, expected %s, %s or %s",
+			contentType, ContentTypeMergePatch, ContentTypeStrategicMergePatch, ContentTypeJSONPatch))
+	}
+}
+
+// decodeJSON 解码 JSON，数字保留为 json.Number（避免大整数经 float64 丢失精度）
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// applyMergePatch 按 RFC 7386 合并：patch 中为 null 的字段删除，对象递归合并，其他值（包括数组）整体替换
+func applyMergePatch(original, patch []byte) ([]byte, error) {
+	var patchMap map[string]interface{}
+	if err := decodeJSON(patch, &patchMap); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid merge patch, expected a JSON object: "+err.Error())
+	}
+	var objMap map[string]interface{}
+	if err := decodeJSON(original, &objMap); err != nil {
+		return nil, err
+	}
+	mergePatch(objMap, patchMap)
+	return json.Marshal(objMap)
+}
+
+// mergePatch 合并 patch 数据
+func mergePatch(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+		} else if srcMap, ok := v.(map[string]interface{}); ok {
+			dstMap, ok := dst[k].(map[string]interface{})
+			if !ok {
+				// 原来不是对象时整体替换，同样去掉其中为 null 的字段
+				dstMap = map[string]interface{}{}
+				dst[k] = dstMap
+			}
+			mergePatch(dstMap, srcMap)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+// jsonPatchOperation JSON patch 中的一个操作
+type jsonPatchOperation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from"`
+	// Value 为原始 JSON，区分没有 value 与 value 为 null
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch 按 RFC 6902 依次执行 add/remove/replace/move/copy/test，任一操作失败时整个 patch 不生效
+func applyJSONPatch(original, patch []byte) ([]byte, error) {
+	var ops []jsonPatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid JSON patch, expected an array of operations: "+err.Error())
+	}
+	var doc interface{}
+	if err := decodeJSON(original, &doc); err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		var err error
+		doc, err = applyJSONPatchOperation(doc, op)
+		if err != nil {
+			return nil, fiber.NewError(statusOf(err), fmt.Sprintf("JSON patch operation %d (%s %s): %v", i, op.Op, op.Path, err))
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// errMalformedPatch 标记 patch 本身的格式错误（400），其余错误为无法应用（422）
+type errMalformedPatch struct{ msg string }
+
+func (e errMalformedPatch) Error() string { return e.msg }
+
+func statusOf(err error) int {
+	if _, ok := err.(errMalformedPatch); ok {
+		return fiber.StatusBadRequest
+	}
+	return fiber.StatusUnprocessableEntity
+}
+
+func applyJSONPatchOperation(doc interface{}, op jsonPatchOperation) (interface{}, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	value := func() (interface{}, error) {
+		if len(op.Value) == 0 {
+			return nil, errMalformedPatch{"missing value"}
+		}
+		var v interface{}
+		if err := decodeJSON(op.Value, &v); err != nil {
+			return nil, errMalformedPatch{"invalid value: " + err.Error()}
+		}
+		return v, nil
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, v)
+	case "remove":
+		doc, _, err := removeValue(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		// replace 等价于先 remove（路径必须存在）再 add
+		doc, _, err := removeValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, v)
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if op.From != op.Path && strings.HasPrefix(op.Path+"/", op.From+"/") {
+				return nil, errMalformedPatch{"cannot move a value into one of its children"}
+			}
+			doc, v, err := removeValue(doc, from)
+			if err != nil {
+				return nil, err
+			}
+			return addValue(doc, path, v)
+		}
+		v, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		// 复制一份，之后对其中一处的修改不影响另一处
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var clone interface{}
+		if err := decodeJSON(raw, &clone); err != nil {
+			return nil, err
+		}
+		return addValue(doc, path, clone)
+	case "test":
+		want, err := value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(got, want) {
+			return nil, fmt.Errorf("test failed: value is %s", mustJSON(got))
+		}
+		return doc, nil
+	default:
+		return nil, errMalformedPatch{fmt.Sprintf("unsupported op %q", op.Op)}
+	}
+}
+
+func mustJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// parseJSONPointer 解析 RFC 6901 JSON pointer（"" 表示整个文档）
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errMalformedPatch{fmt.Sprintf("invalid path %q, must start with /", pointer)}
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex 解析数组下标；allowEnd 为 true 时接受 "-" 与 len（追加到末尾）
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > length || (!allowEnd && i == length) {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func getValue(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("path not found: %q", token)
+			}
+			node = v
+		case []interface{}:
+			i, err := arrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("path not found: %q", token)
+		}
+	}
+	return node, nil
+}
+
+// addValue 在 path 处加入 value，返回修改后的节点（path 为空时替换整个文档；数组中为插入）
+func addValue(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			n[token] = value
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("path not found: %q", token)
+		}
+		v, err := addValue(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = v
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(n), len(rest) == 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		v, err := addValue(n[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = v
+		return n, nil
+	default:
+		return nil, fmt.Errorf("path not found: %q", token)
+	}
+}
+
+// removeValue 删除 path 处的值，返回修改后的节点与被删除的值；path 必须存在
+func removeValue(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, node, nil
+	}
+	token, rest := path[0], path[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path not found: %q", token)
+		}
+		if len(rest) == 0 {
+			delete(n, token)
+			return n, child, nil
+		}
+		v, removed, err := removeValue(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[token] = v
+		return n, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(n), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		v, removed, err := removeValue(n[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = v
+		return n, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("path not found: %q", token)
+	}
+}
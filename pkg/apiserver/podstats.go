@@ -0,0 +1,118 @@
+package apiserver
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PodStatsProvider 采样本节点上 Pod 的资源使用（由持有容器运行时的进程提供，例如 one 模式下的 ControllerManager）
+type PodStatsProvider interface {
+	// NodeName 返回本节点名称
+	NodeName() string
+	// ListPodStats 采样本节点上运行中的 Pod 的 CPU 与内存使用
+	ListPodStats(ctx context.Context) ([]PodStats, error)
+}
+
+// WithPodStatsProvider 启用 k3.io/v1 podstats（k3 top pods）
+func WithPodStatsProvider(stats PodStatsProvider) Option {
+	return func(s *APIServer) {
+		s.stats = stats
+	}
+}
+
+// ContainerUsage 一个容器的资源使用
+type ContainerUsage struct {
+	Name string `json:"name"`
+	// CPUMillicores CPU 使用（1000 为一个核）
+	CPUMillicores int64 `json:"cpuMillicores"`
+	MemoryBytes   int64 `json:"memoryBytes"`
+}
+
+// PodStats 一个 Pod 的资源使用（各容器之和）
+type PodStats struct {
+	Namespace     string           `json:"namespace"`
+	Name          string           `json:"name"`
+	Node          string           `json:"node"`
+	CPUMillicores int64            `json:"cpuMillicores"`
+	MemoryBytes   int64            `json:"memoryBytes"`
+	Containers    []ContainerUsage `json:"containers"`
+}
+
+// PodStatsList 是 GET /apis/k3.io/v1/[namespaces/:namespace/]podstats 的响应
+type PodStatsList struct {
+	Kind       string      `json:"kind"`
+	APIVersion string      `json:"apiVersion"`
+	Timestamp  metav1.Time `json:"timestamp"`
+	// Node 采样的节点（apiserver 所在进程的节点）
+	Node string `json:"node"`
+	// UnavailableNodes 有运行中的 Pod、但不由本 apiserver 采样的节点（这些 Pod 不在 items 中）
+	UnavailableNodes []string   `json:"unavailableNodes,omitempty"`
+	Items            []PodStats `json:"items"`
+}
+
+// HandlePodStats 处理 GET /apis/k3.io/v1/podstats 与 /apis/k3.io/v1/namespaces/:namespace/podstats：
+// 实时采样 apiserver 所在节点上 Pod 的 CPU/内存使用（与 pods/log 一样只覆盖本节点），按 namespace/name 排序；
+// 受限用户只能看到允许访问的 namespace（身份限定了资源种类且不包括 Pod 时返回 403）
+func (s *APIServer) HandlePodStats(c *fiber.Ctx) error {
+	if s.stats == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "pod stats are not available on this server (no container runtime)"})
+	}
+	namespace := c.Params("namespace")
+	id := webprovider.IdentityFromCtx(c)
+	if !id.AllowsKind("Pod") {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden: user " + id.User + " cannot access Pod"})
+	}
+	if namespace != "" && !id.AllowsNamespace(namespace) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "forbidden: user " + id.User + " cannot access namespace " + namespace,
+		})
+	}
+	visible := func(ns string) bool {
+		return (namespace == "" || ns == namespace) && id.AllowsNamespace(ns)
+	}
+
+	stats, err := s.stats.ListPodStats(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	list := PodStatsList{
+		Kind:       "PodStatsList",
+		APIVersion: "k3.io/v1",
+		Timestamp:  metav1.Now(),
+		Node:       s.stats.NodeName(),
+		Items:      []PodStats{},
+	}
+	for _, p := range stats {
+		if visible(p.Namespace) {
+			list.Items = append(list.Items, p)
+		}
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		if list.Items[i].Namespace != list.Items[j].Namespace {
+			return list.Items[i].Namespace < list.Items[j].Namespace
+		}
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	// 其他节点上运行中的 Pod 无法在这里采样，返回这些节点供客户端提示
+	if objs, err := s.store.List(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, namespace); err == nil {
+		unavailable := make(map[string]bool)
+		for _, obj := range objs {
+			pod, ok := obj.(*corev1.Pod)
+			if ok && pod.Status.Phase == corev1.PodRunning && pod.Spec.NodeName != "" && pod.Spec.NodeName != list.Node && visible(pod.Namespace) {
+				unavailable[pod.Spec.NodeName] = true
+			}
+		}
+		for node := range unavailable {
+			list.UnavailableNodes = append(list.UnavailableNodes, node)
+		}
+		sort.Strings(list.UnavailableNodes)
+	}
+	return c.JSON(list)
+}
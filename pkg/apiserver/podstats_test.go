@@ -0,0 +1,107 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakePodStats 返回固定采样结果的 PodStatsProvider
+type fakePodStats []PodStats
+
+func (f fakePodStats) NodeName() string { return "node-1" }
+
+func (f fakePodStats) ListPodStats(ctx context.Context) ([]PodStats, error) {
+	return f, nil
+}
+
+func TestHandlePodStats(t *testing.T) {
+	store := storage.NewMemoryStore()
+	for _, pod := range []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "dev"}, Spec: corev1.PodSpec{NodeName: "node-1"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "dev"}, Spec: corev1.PodSpec{NodeName: "node-2"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"}, Spec: corev1.PodSpec{NodeName: "node-3"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "dev"}, Spec: corev1.PodSpec{NodeName: "node-4"}, Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+	} {
+		if err := store.Create(podGVK, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewAPIServer(store)
+	WithPodStatsProvider(fakePodStats{
+		{Namespace: "prod", Name: "cache", Node: "node-1", CPUMillicores: 5},
+		{Namespace: "dev", Name: "web", Node: "node-1", CPUMillicores: 120, MemoryBytes: 64 << 20},
+		{Namespace: "dev", Name: "cron", Node: "node-1"},
+	})(s)
+
+	app := fiber.New()
+	app.Use(webprovider.NewAuthMiddleware(config.Config{Auth: config.AuthConfig{Enabled: true, Tokens: []config.TokenConfig{
+		{Token: "admin", User: "admin", Role: webprovider.RoleClusterAdmin},
+		{Token: "dev", User: "alice", Namespaces: []string{"dev"}},
+		{Token: "deployer", User: "ci", Namespaces: []string{"dev"}, Kinds: []string{"Deployment"}},
+	}}}))
+	app.Get("/apis/k3.io/v1/podstats", s.HandlePodStats)
+	app.Get("/apis/k3.io/v1/namespaces/:namespace/podstats", s.HandlePodStats)
+
+	for _, tc := range []struct {
+		name        string
+		token, path string
+		code        int
+		items       []string
+		unavailable []string
+	}{
+		{name: "all namespaces, sorted", token: "admin", path: "/apis/k3.io/v1/podstats", code: 200,
+			items: []string{"dev/cron", "dev/web", "prod/cache"}, unavailable: []string{"node-2", "node-3"}},
+		{name: "one namespace", token: "admin", path: "/apis/k3.io/v1/namespaces/prod/podstats", code: 200,
+			items: []string{"prod/cache"}, unavailable: []string{"node-3"}},
+		{name: "restricted user sees only its namespaces", token: "dev", path: "/apis/k3.io/v1/podstats", code: 200,
+			items: []string{"dev/cron", "dev/web"}, unavailable: []string{"node-2"}},
+		{name: "restricted user, other namespace", token: "dev", path: "/apis/k3.io/v1/namespaces/prod/podstats", code: 403},
+		{name: "identity without Pod access", token: "deployer", path: "/apis/k3.io/v1/podstats", code: 403},
+	} {
+		req := httptest.NewRequest(fiber.MethodGet, tc.path, nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tc.token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tc.code {
+			t.Errorf("%s: HTTP %d: %s", tc.name, resp.StatusCode, body)
+			continue
+		}
+		if tc.code != 200 {
+			continue
+		}
+		var list PodStatsList
+		if err := json.Unmarshal(body, &list); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var items []string
+		for _, p := range list.Items {
+			items = append(items, p.Namespace+"/"+p.Name)
+		}
+		if list.Kind != "PodStatsList" || list.Node != "node-1" || strings.Join(items, ",") != strings.Join(tc.items, ",") ||
+			strings.Join(list.UnavailableNodes, ",") != strings.Join(tc.unavailable, ",") {
+			t.Errorf("%s: %s", tc.name, body)
+		}
+	}
+
+	// 没有容器运行时的 apiserver 返回 501
+	app = fiber.New()
+	app.Get("/apis/k3.io/v1/podstats", NewAPIServer(store).HandlePodStats)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/apis/k3.io/v1/podstats", nil))
+	if err != nil || resp.StatusCode != fiber.StatusNotImplemented {
+		t.Errorf("without a stats provider: %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,130 @@
+package apiserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// PriorityClassGVK 是 scheduling.k8s.io/v1 PriorityClass（集群级）
+	PriorityClassGVK = schema.GroupVersionKind{Group: "scheduling.k8s.io", Version: "v1", Kind: "PriorityClass"}
+	// PodDisruptionBudgetGVK 是 policy/v1 PodDisruptionBudget
+	PodDisruptionBudgetGVK = schema.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}
+	// NetworkPolicyGVK 是 networking.k8s.io/v1 NetworkPolicy
+	NetworkPolicyGVK = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"}
+)
+
+const (
+	// HighestUserDefinablePriority 用户创建的 PriorityClass 的最大值，更高的值保留给系统优先级
+	HighestUserDefinablePriority = int32(1000000000)
+	// SystemCriticalPriority 是 system-cluster-critical 的优先级
+	SystemCriticalPriority = 2 * HighestUserDefinablePriority
+	// systemPriorityClassPrefix 系统优先级的名称前缀，用户不能创建以它开头的 PriorityClass
+	systemPriorityClassPrefix = "system-"
+)
+
+// systemPriorityClasses 内置的系统优先级（与 Kubernetes 相同），不需要创建即可在 priorityClassName 中使用
+var systemPriorityClasses = map[string]int32{
+	"system-cluster-critical": SystemCriticalPriority,
+	"system-node-critical":    SystemCriticalPriority + 1000,
+}
+
+// ResolvePodPriority 按 spec.priorityClassName 设置 Pod 的 spec.priority 与 spec.preemptionPolicy（已设置 priority 时不修改）：
+// 没有指定 priorityClassName 时使用 globalDefault 的 PriorityClass，都没有时优先级为 0。
+// apiserver 创建 Pod 时调用；控制器直接写入 Store 的 Pod 由调度器在调度前调用。PriorityClass 不存在时返回错误
+func ResolvePodPriority(store storage.Store, pod *corev1.Pod) error {
+	if pod.Spec.Priority != nil {
+		return nil
+	}
+	name := pod.Spec.PriorityClassName
+	if value, ok := systemPriorityClasses[name]; ok {
+		setPodPriority(pod, value, nil)
+		return nil
+	}
+	if name != "" {
+		obj, err := store.Get(PriorityClassGVK, "", name)
+		if err != nil {
+			if storage.IsBackendError(err) {
+				return err
+			}
+			return fmt.Errorf("PriorityClass %q 不存在", name)
+		}
+		pc, ok := obj.(*schedulingv1.PriorityClass)
+		if !ok {
+			return fmt.Errorf("unexpected object type %T for PriorityClass %s", obj, name)
+		}
+		setPodPriority(pod, pc.Value, pc.PreemptionPolicy)
+		return nil
+	}
+
+	def, err := globalDefaultPriorityClass(store)
+	if err != nil {
+		return err
+	}
+	if def == nil {
+		setPodPriority(pod, 0, nil)
+		return nil
+	}
+	pod.Spec.PriorityClassName = def.Name
+	setPodPriority(pod, def.Value, def.PreemptionPolicy)
+	return nil
+}
+
+// PodPriority 返回 Pod 的优先级（没有设置时为 0）
+func PodPriority(pod *corev1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+func setPodPriority(pod *corev1.Pod, value int32, policy *corev1.PreemptionPolicy) {
+	pod.Spec.Priority = &value
+	if pod.Spec.PreemptionPolicy == nil {
+		if policy == nil {
+			policy = ptrTo(corev1.PreemptLowerPriority)
+		}
+		pod.Spec.PreemptionPolicy = ptrTo(*policy)
+	}
+}
+
+// globalDefaultPriorityClass 返回 globalDefault 的 PriorityClass（有多个时取值最大的），没有时返回 nil
+func globalDefaultPriorityClass(store storage.Store) (*schedulingv1.PriorityClass, error) {
+	objs, err := store.List(PriorityClassGVK, "")
+	if err != nil {
+		return nil, err
+	}
+	var def *schedulingv1.PriorityClass
+	for _, obj := range objs {
+		if pc, ok := obj.(*schedulingv1.PriorityClass); ok && pc.GlobalDefault && (def == nil || pc.Value > def.Value) {
+			def = pc
+		}
+	}
+	return def, nil
+}
+
+// validatePriorityClass 校验 PriorityClass：不能使用 system- 前缀与系统保留的优先级，只能有一个 globalDefault
+func validatePriorityClass(store storage.Store, pc *schedulingv1.PriorityClass) error {
+	if strings.HasPrefix(pc.Name, systemPriorityClassPrefix) {
+		return fmt.Errorf("PriorityClass 名称不能以 %q 开头（保留给系统优先级）", systemPriorityClassPrefix)
+	}
+	if pc.Value > HighestUserDefinablePriority {
+		return fmt.Errorf("PriorityClass 的 value 不能大于 %d", HighestUserDefinablePriority)
+	}
+	if !pc.GlobalDefault {
+		return nil
+	}
+	def, err := globalDefaultPriorityClass(store)
+	if err != nil {
+		return err
+	}
+	if def != nil && def.Name != pc.Name {
+		return fmt.Errorf("PriorityClass %s 已经是 globalDefault", def.Name)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// readCacheWait resourceVersion 比读缓存新时等待缓存追上的最长时间（与 Kubernetes 相同）
+	readCacheWait = 3 * time.Second
+	// readCacheTTL 读缓存的有效期：watch 通道满时事件会被丢弃，定期重建缓存，丢失的事件最多影响这么久
+	readCacheTTL = 5 * time.Minute
+)
+
+var (
+	// errTooLargeResourceVersion 读缓存在 readCacheWait 内没有追上请求的 resourceVersion（504）
+	errTooLargeResourceVersion = errors.New("too large resource version")
+	// errResourceVersionExpired Exact 请求的版本已被更新的写入覆盖，读缓存不保留旧快照（410）
+	errResourceVersionExpired = errors.New("resource version expired")
+	// errReadCacheClosed 等待期间读缓存失效（到期或 watch 通道关闭），重新获取缓存后继续等待
+	errReadCacheClosed = errors.New("read cache closed")
+)
+
+// readOptions GET/LIST 的 resourceVersion 与 resourceVersionMatch
+type readOptions struct {
+	resourceVersion string
+	match           metav1.ResourceVersionMatch
+}
+
+// parseReadOptions 按 Kubernetes 的规则校验读请求的版本参数：
+// resourceVersionMatch 只用于 LIST 且必须同时提供 resourceVersion，Exact 不能与 resourceVersion=0 一起使用
+func parseReadOptions(c *fiber.Ctx, list bool) (readOptions, error) {
+	opts := readOptions{
+		resourceVersion: c.Query("resourceVersion"),
+		match:           metav1.ResourceVersionMatch(c.Query("resourceVersionMatch")),
+	}
+	if opts.resourceVersion != "" {
+		if _, err := strconv.ParseUint(opts.resourceVersion, 10, 64); err != nil {
+			return opts, fmt.Errorf("invalid resourceVersion: %q", opts.resourceVersion)
+		}
+	}
+	switch opts.match {
+	case "":
+		return opts, nil
+	case metav1.ResourceVersionMatchExact, metav1.ResourceVersionMatchNotOlderThan:
+	default:
+		return opts, fmt.Errorf("unsupported resourceVersionMatch: %q（支持 Exact、NotOlderThan）", opts.match)
+	}
+	switch {
+	case !list:
+		return opts, fmt.Errorf("resourceVersionMatch is forbidden for get")
+	case opts.resourceVersion == "":
+		return opts, fmt.Errorf("resourceVersionMatch is forbidden unless resourceVersion is provided")
+	case opts.match == metav1.ResourceVersionMatchExact && opts.resourceVersion == "0":
+		return opts, fmt.Errorf("resourceVersionMatch %q is forbidden for resourceVersion \"0\"", opts.match)
+	}
+	return opts, nil
+}
+
+// readErrorStatus 返回读缓存错误对应的状态码
+func readErrorStatus(c *fiber.Ctx, err error) int {
+	switch {
+	case errors.Is(err, errTooLargeResourceVersion):
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return fiber.StatusGatewayTimeout
+	case errors.Is(err, errResourceVersionExpired):
+		return fiber.StatusGone
+	}
+	return storeErrorStatus(c, err, fiber.StatusInternalServerError)
+}
+
+// readCaches 按存储 GVK 维护的读缓存，第一次带 resourceVersion 的读请求时创建
+type readCaches struct {
+	mu     sync.Mutex
+	caches map[schema.GroupVersionKind]*readCache
+}
+
+// readCache 一种资源（所有 namespace）的全部对象：订阅 watch 之后 List 一次，之后由 watch 事件维护；
+// resourceVersion 为缓存中见过的最新版本。watch 通道关闭（后端重连、Store 关闭）或超过 readCacheTTL 后缓存失效，下次读取时重建
+type readCache struct {
+	gvk   schema.GroupVersionKind
+	ready chan struct{}
+	err   error
+
+	mu              sync.RWMutex
+	objects         map[string]runtime.Object // key: namespace/name
+	resourceVersion string
+	// changed 每次更新后关闭并替换，等待版本的请求据此唤醒
+	changed chan struct{}
+	closed  bool
+}
+
+// readCacheFor 返回 gvk 的读缓存（必要时创建并等待首次 List 完成）
+func (s *APIServer) readCacheFor(gvk schema.GroupVersionKind) (*readCache, error) {
+	s.readCaches.mu.Lock()
+	if s.readCaches.caches == nil {
+		s.readCaches.caches = make(map[schema.GroupVersionKind]*readCache)
+	}
+	rc, ok := s.readCaches.caches[gvk]
+	if !ok {
+		rc = &readCache{gvk: gvk, ready: make(chan struct{}), objects: make(map[string]runtime.Object), changed: make(chan struct{})}
+		s.readCaches.caches[gvk] = rc
+		go s.runReadCache(gvk, rc)
+	}
+	s.readCaches.mu.Unlock()
+
+	<-rc.ready
+	if rc.err != nil {
+		return nil, rc.err
+	}
+	return rc, nil
+}
+
+// runReadCache 填充读缓存并应用 watch 事件，直到 watch 通道关闭或缓存到期
+func (s *APIServer) runReadCache(gvk schema.GroupVersionKind, rc *readCache) {
+	// 先从登记中移除再标记关闭：等待中的请求被唤醒后取到的是新缓存
+	defer func() {
+		s.readCaches.mu.Lock()
+		if s.readCaches.caches[gvk] == rc {
+			delete(s.readCaches.caches, gvk)
+		}
+		s.readCaches.mu.Unlock()
+		rc.mu.Lock()
+		rc.closed = true
+		close(rc.changed)
+		rc.mu.Unlock()
+	}()
+
+	// 先订阅再 List：List 之后的变更不会丢失，List 之前的变更按 resourceVersion 丢弃（见 apply）
+	ch, err := s.store.Watch(gvk, "", "")
+	if err != nil {
+		rc.err = err
+		close(rc.ready)
+		return
+	}
+	objects, err := s.store.List(gvk, "")
+	if err != nil {
+		s.stopWatch(gvk, "", ch)
+		rc.err = err
+		close(rc.ready)
+		return
+	}
+	for _, obj := range objects {
+		rc.apply(storage.ResourceEvent{Type: storage.EventAdded, Object: obj})
+	}
+	close(rc.ready)
+
+	expire := time.NewTimer(readCacheTTL)
+	defer expire.Stop()
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			rc.apply(event)
+		case <-expire.C:
+			s.stopWatch(gvk, "", ch)
+			return
+		}
+	}
+}
+
+// apply 把一个事件写入缓存：不比缓存中的对象新的事件（订阅与 List 之间重复到达）直接丢弃
+func (rc *readCache) apply(event storage.ResourceEvent) {
+	meta, ok := event.Object.(metav1.Object)
+	if !ok {
+		return
+	}
+	rv := meta.GetResourceVersion()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if event.Type != storage.EventBookmark {
+		key := objectKey(meta)
+		current, exists := rc.objects[key]
+		if exists && newerResourceVersion(current.(metav1.Object).GetResourceVersion(), rv) {
+			return
+		}
+		if event.Type == storage.EventDeleted {
+			delete(rc.objects, key)
+		} else {
+			rc.objects[key] = event.Object.DeepCopyObject()
+		}
+	}
+	if newerResourceVersion(rv, rc.resourceVersion) {
+		rc.resourceVersion = rv
+	}
+	close(rc.changed)
+	rc.changed = make(chan struct{})
+}
+
+// waitFor 等待缓存的版本不比 rv 旧，最多等待到 deadline
+func (rc *readCache) waitFor(rv string, deadline *time.Timer) error {
+	for {
+		rc.mu.RLock()
+		current, changed, closed := rc.resourceVersion, rc.changed, rc.closed
+		rc.mu.RUnlock()
+		if !newerResourceVersion(rv, current) {
+			return nil
+		}
+		if closed {
+			return errReadCacheClosed
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return fmt.Errorf("%w: 等待 resourceVersion %s 超时（缓存为 %s）", errTooLargeResourceVersion, rv, current)
+		}
+	}
+}
+
+// get 返回缓存中对象的副本与缓存的版本（namespace 与 Store 的 Get 相同：集群级资源忽略，为空时为 default）
+func (rc *readCache) get(namespace, name string) (runtime.Object, string, bool) {
+	if storage.IsClusterScoped(rc.gvk) {
+		namespace = ""
+	} else if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	obj, ok := rc.objects[namespace+"/"+name]
+	if !ok {
+		return nil, rc.resourceVersion, false
+	}
+	return obj.DeepCopyObject(), rc.resourceVersion, true
+}
+
+// list 返回 namespace（为空表示所有 namespace）下满足 selector 的对象副本（与 Store 的 List 顺序相同）与缓存的版本
+func (rc *readCache) list(namespace string, selector labels.Selector) ([]runtime.Object, string) {
+	if storage.IsClusterScoped(rc.gvk) {
+		namespace = ""
+	}
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	var objects []runtime.Object
+	for _, obj := range rc.objects {
+		meta := obj.(metav1.Object)
+		if namespace != "" && meta.GetNamespace() != namespace {
+			continue
+		}
+		if selector != nil && !selector.Matches(labels.Set(meta.GetLabels())) {
+			continue
+		}
+		objects = append(objects, obj.DeepCopyObject())
+	}
+	storage.SortObjects(objects)
+	return objects, rc.resourceVersion
+}
+
+// cachedRead 按 resourceVersion 语义决定是否从读缓存读取：未指定 resourceVersion 时返回 nil（直接读 Store，最新数据）；
+// resourceVersion=0 时读取缓存的任意版本；否则等待缓存不比请求的版本旧（NotOlderThan，默认）。
+// Exact 由调用方在读取后用 checkExact 确认缓存版本恰好等于请求的版本
+func (s *APIServer) cachedRead(gvk schema.GroupVersionKind, opts readOptions) (*readCache, error) {
+	if opts.resourceVersion == "" {
+		return nil, nil
+	}
+	deadline := time.NewTimer(readCacheWait)
+	defer deadline.Stop()
+	for {
+		rc, err := s.readCacheFor(gvk)
+		if err != nil {
+			return nil, err
+		}
+		if opts.resourceVersion == "0" {
+			return rc, nil
+		}
+		if err := rc.waitFor(opts.resourceVersion, deadline); !errors.Is(err, errReadCacheClosed) {
+			if err != nil {
+				return nil, err
+			}
+			return rc, nil
+		}
+	}
+}
+
+// checkExact Exact 请求时确认读取的快照版本恰好等于请求的版本：缓存只保留最新状态，已被更新的写入覆盖时返回 410
+func checkExact(opts readOptions, snapshot string) error {
+	if opts.match != metav1.ResourceVersionMatchExact || snapshot == opts.resourceVersion {
+		return nil
+	}
+	return fmt.Errorf("%w: resourceVersion %s 已被更新（当前为 %s），请不带 resourceVersion 重新 List", errResourceVersionExpired, opts.resourceVersion, snapshot)
+}
+
+// listResourceVersion 直接读取 Store 时列表的 resourceVersion：取列出对象中最新的版本
+func listResourceVersion(objects []runtime.Object) string {
+	rv := ""
+	for _, obj := range objects {
+		if meta, ok := obj.(metav1.Object); ok && newerResourceVersion(meta.GetResourceVersion(), rv) {
+			rv = meta.GetResourceVersion()
+		}
+	}
+	return rv
+}
@@ -3,14 +3,27 @@ package apiserver
 import (
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
 )
 
 // RegisterRoutes 注册 Kubernetes API server 路由
-func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
+func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store, opts ...Option) {
 	apiServer := NewAPIServer(store)
+	for _, opt := range opts {
+		opt(apiServer)
+	}
+
+	// 控制器状态与指标（只对 cluster-admin 开放）
+	fiberEngine.Api.Get("/debug/controllers", requireClusterAdmin, apiServer.HandleControllers)
+	fiberEngine.Api.Get("/metrics", requireClusterAdmin, apiServer.HandleMetrics)
+	// 容器运行时调试（ps/inspect/images，只对 cluster-admin 开放）
+	fiberEngine.Api.Post("/debug/runtime/exec", requireClusterAdmin, apiServer.HandleRuntimeExec)
+
+	// 版本与集群身份（与 Kubernetes 的 /version 兼容）
+	fiberEngine.Api.Get("/version", apiServer.HandleVersion)
 
 	// Core API v1
-	coreV1 := fiberEngine.Api.Group("/api/v1")
+	coreV1 := fiberEngine.Api.Group("/api/v1", apiServer.trackActivity, apiServer.recordUsage, apiServer.authorize)
 	{
 		// Pods
 		coreV1.Get("/pods", apiServer.HandleList)
@@ -19,6 +32,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		coreV1.Put("/pods/:name", apiServer.HandleUpdate)
 		coreV1.Patch("/pods/:name", apiServer.HandlePatch)
 		coreV1.Delete("/pods/:name", apiServer.HandleDelete)
+		coreV1.Delete("/pods", apiServer.HandleDeleteCollection)
 		coreV1.Get("/watch/pods", apiServer.HandleWatch)
 
 		// Namespaced Pods
@@ -28,7 +42,10 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		coreV1.Put("/namespaces/:namespace/pods/:name", apiServer.HandleUpdate)
 		coreV1.Patch("/namespaces/:namespace/pods/:name", apiServer.HandlePatch)
 		coreV1.Delete("/namespaces/:namespace/pods/:name", apiServer.HandleDelete)
+		coreV1.Delete("/namespaces/:namespace/pods", apiServer.HandleDeleteCollection)
 		coreV1.Get("/watch/namespaces/:namespace/pods", apiServer.HandleWatch)
+		coreV1.Get("/namespaces/:namespace/pods/:name/log", apiServer.HandlePodLog)
+		coreV1.Post("/namespaces/:namespace/pods/:name/eviction", apiServer.HandleEviction)
 
 		// Services
 		coreV1.Get("/services", apiServer.HandleList)
@@ -37,6 +54,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		coreV1.Put("/services/:name", apiServer.HandleUpdate)
 		coreV1.Patch("/services/:name", apiServer.HandlePatch)
 		coreV1.Delete("/services/:name", apiServer.HandleDelete)
+		coreV1.Delete("/services", apiServer.HandleDeleteCollection)
 		coreV1.Get("/watch/services", apiServer.HandleWatch)
 
 		// Namespaced Services
@@ -46,6 +64,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		coreV1.Put("/namespaces/:namespace/services/:name", apiServer.HandleUpdate)
 		coreV1.Patch("/namespaces/:namespace/services/:name", apiServer.HandlePatch)
 		coreV1.Delete("/namespaces/:namespace/services/:name", apiServer.HandleDelete)
+		coreV1.Delete("/namespaces/:namespace/services", apiServer.HandleDeleteCollection)
 		coreV1.Get("/watch/namespaces/:namespace/services", apiServer.HandleWatch)
 
 		// ConfigMaps
@@ -55,6 +74,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		coreV1.Put("/configmaps/:name", apiServer.HandleUpdate)
 		coreV1.Patch("/configmaps/:name", apiServer.HandlePatch)
 		coreV1.Delete("/configmaps/:name", apiServer.HandleDelete)
+		coreV1.Delete("/configmaps", apiServer.HandleDeleteCollection)
 		coreV1.Get("/watch/configmaps", apiServer.HandleWatch)
 
 		// Namespaced ConfigMaps
@@ -64,6 +84,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		coreV1.Put("/namespaces/:namespace/configmaps/:name", apiServer.HandleUpdate)
 		coreV1.Patch("/namespaces/:namespace/configmaps/:name", apiServer.HandlePatch)
 		coreV1.Delete("/namespaces/:namespace/configmaps/:name", apiServer.HandleDelete)
+		coreV1.Delete("/namespaces/:namespace/configmaps", apiServer.HandleDeleteCollection)
 		coreV1.Get("/watch/namespaces/:namespace/configmaps", apiServer.HandleWatch)
 
 		// Secrets
@@ -73,6 +94,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		coreV1.Put("/secrets/:name", apiServer.HandleUpdate)
 		coreV1.Patch("/secrets/:name", apiServer.HandlePatch)
 		coreV1.Delete("/secrets/:name", apiServer.HandleDelete)
+		coreV1.Delete("/secrets", apiServer.HandleDeleteCollection)
 		coreV1.Get("/watch/secrets", apiServer.HandleWatch)
 
 		// Namespaced Secrets
@@ -82,11 +104,57 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		coreV1.Put("/namespaces/:namespace/secrets/:name", apiServer.HandleUpdate)
 		coreV1.Patch("/namespaces/:namespace/secrets/:name", apiServer.HandlePatch)
 		coreV1.Delete("/namespaces/:namespace/secrets/:name", apiServer.HandleDelete)
+		coreV1.Delete("/namespaces/:namespace/secrets", apiServer.HandleDeleteCollection)
 		coreV1.Get("/watch/namespaces/:namespace/secrets", apiServer.HandleWatch)
+
+		// Events（由 k3 组件记录，例如存储后端容器被重启）
+		coreV1.Get("/events", apiServer.HandleList)
+		coreV1.Get("/events/:name", apiServer.HandleGet)
+		coreV1.Post("/events", apiServer.HandleCreate)
+		coreV1.Delete("/events/:name", apiServer.HandleDelete)
+		coreV1.Delete("/events", apiServer.HandleDeleteCollection)
+		coreV1.Get("/watch/events", apiServer.HandleWatch)
+
+		// Namespaced Events
+		coreV1.Get("/namespaces/:namespace/events", apiServer.HandleList)
+		coreV1.Get("/namespaces/:namespace/events/:name", apiServer.HandleGet)
+		coreV1.Post("/namespaces/:namespace/events", apiServer.HandleCreate)
+		coreV1.Delete("/namespaces/:namespace/events/:name", apiServer.HandleDelete)
+		coreV1.Delete("/namespaces/:namespace/events", apiServer.HandleDeleteCollection)
+		coreV1.Get("/watch/namespaces/:namespace/events", apiServer.HandleWatch)
+
+		// Nodes（集群级资源）
+		coreV1.Get("/nodes", apiServer.HandleList)
+		coreV1.Get("/nodes/:name", apiServer.HandleGet)
+		coreV1.Post("/nodes", apiServer.HandleCreate)
+		coreV1.Put("/nodes/:name", apiServer.HandleUpdate)
+		coreV1.Patch("/nodes/:name", apiServer.HandlePatch)
+		coreV1.Delete("/nodes/:name", apiServer.HandleDelete)
+		coreV1.Delete("/nodes", apiServer.HandleDeleteCollection)
+		coreV1.Get("/watch/nodes", apiServer.HandleWatch)
+		coreV1.Get("/nodes/:name/images", apiServer.HandleListNodeImages)
+		coreV1.Post("/nodes/:name/images", apiServer.HandlePullNodeImages)
+		coreV1.Patch("/nodes/:name/labels", apiServer.HandlePatchNodeLabels)
+		coreV1.Patch("/nodes/:name/annotations", apiServer.HandlePatchNodeAnnotations)
+
+		// Namespaces（集群级资源；开启 tenancy 时创建后自动注入默认的配额、限制、网络策略与 ServiceAccount）
+		coreV1.Get("/namespaces", apiServer.HandleList)
+		coreV1.Get("/namespaces/:name", apiServer.HandleGet)
+		coreV1.Post("/namespaces", apiServer.HandleCreate)
+		coreV1.Put("/namespaces/:name", apiServer.HandleUpdate)
+		coreV1.Patch("/namespaces/:name", apiServer.HandlePatch)
+		coreV1.Delete("/namespaces/:name", apiServer.HandleDelete)
+		coreV1.Get("/watch/namespaces", apiServer.HandleWatch)
+
+		// ServiceAccounts、ResourceQuotas、LimitRanges（namespace 级）
+		registerResourceRoutes(coreV1, "serviceaccounts", apiServer)
+		registerResourceRoutes(coreV1, "endpoints", apiServer)
+		registerResourceRoutes(coreV1, "resourcequotas", apiServer)
+		registerResourceRoutes(coreV1, "limitranges", apiServer)
 	}
 
 	// Apps API v1
-	appsV1 := fiberEngine.Api.Group("/apis/apps/v1")
+	appsV1 := fiberEngine.Api.Group("/apis/apps/v1", apiServer.trackActivity, apiServer.recordUsage, apiServer.authorize)
 	{
 		// Deployments
 		appsV1.Get("/deployments", apiServer.HandleList)
@@ -95,6 +163,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		appsV1.Put("/deployments/:name", apiServer.HandleUpdate)
 		appsV1.Patch("/deployments/:name", apiServer.HandlePatch)
 		appsV1.Delete("/deployments/:name", apiServer.HandleDelete)
+		appsV1.Delete("/deployments", apiServer.HandleDeleteCollection)
 		appsV1.Get("/watch/deployments", apiServer.HandleWatch)
 
 		// Namespaced Deployments
@@ -104,6 +173,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		appsV1.Put("/namespaces/:namespace/deployments/:name", apiServer.HandleUpdate)
 		appsV1.Patch("/namespaces/:namespace/deployments/:name", apiServer.HandlePatch)
 		appsV1.Delete("/namespaces/:namespace/deployments/:name", apiServer.HandleDelete)
+		appsV1.Delete("/namespaces/:namespace/deployments", apiServer.HandleDeleteCollection)
 		appsV1.Get("/watch/namespaces/:namespace/deployments", apiServer.HandleWatch)
 
 		// StatefulSets
@@ -113,6 +183,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		appsV1.Put("/statefulsets/:name", apiServer.HandleUpdate)
 		appsV1.Patch("/statefulsets/:name", apiServer.HandlePatch)
 		appsV1.Delete("/statefulsets/:name", apiServer.HandleDelete)
+		appsV1.Delete("/statefulsets", apiServer.HandleDeleteCollection)
 		appsV1.Get("/watch/statefulsets", apiServer.HandleWatch)
 
 		// Namespaced StatefulSets
@@ -122,6 +193,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		appsV1.Put("/namespaces/:namespace/statefulsets/:name", apiServer.HandleUpdate)
 		appsV1.Patch("/namespaces/:namespace/statefulsets/:name", apiServer.HandlePatch)
 		appsV1.Delete("/namespaces/:namespace/statefulsets/:name", apiServer.HandleDelete)
+		appsV1.Delete("/namespaces/:namespace/statefulsets", apiServer.HandleDeleteCollection)
 		appsV1.Get("/watch/namespaces/:namespace/statefulsets", apiServer.HandleWatch)
 
 		// DaemonSets
@@ -131,6 +203,7 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		appsV1.Put("/daemonsets/:name", apiServer.HandleUpdate)
 		appsV1.Patch("/daemonsets/:name", apiServer.HandlePatch)
 		appsV1.Delete("/daemonsets/:name", apiServer.HandleDelete)
+		appsV1.Delete("/daemonsets", apiServer.HandleDeleteCollection)
 		appsV1.Get("/watch/daemonsets", apiServer.HandleWatch)
 
 		// Namespaced DaemonSets
@@ -140,6 +213,114 @@ func RegisterRoutes(fiberEngine webprovider.FiberEngine, store storage.Store) {
 		appsV1.Put("/namespaces/:namespace/daemonsets/:name", apiServer.HandleUpdate)
 		appsV1.Patch("/namespaces/:namespace/daemonsets/:name", apiServer.HandlePatch)
 		appsV1.Delete("/namespaces/:namespace/daemonsets/:name", apiServer.HandleDelete)
+		appsV1.Delete("/namespaces/:namespace/daemonsets", apiServer.HandleDeleteCollection)
 		appsV1.Get("/watch/namespaces/:namespace/daemonsets", apiServer.HandleWatch)
 	}
+
+	// apps 资源的其他版本（见 DefaultConversions）：以 apps/v1 存储，读写时转换
+	served := make(map[string][]string)
+	var versions []string
+	for _, resource := range []string{"deployments", "statefulsets", "daemonsets"} {
+		gvk, _ := GVKForResource(resource)
+		for _, version := range apiServer.conversions.Versions(gvk.GroupKind()) {
+			if version == gvk.Version {
+				continue
+			}
+			if served[version] == nil {
+				versions = append(versions, version)
+			}
+			served[version] = append(served[version], resource)
+		}
+	}
+	for _, version := range versions {
+		group := fiberEngine.Api.Group("/apis/apps/"+version, apiServer.trackActivity, apiServer.recordUsage, apiServer.authorize)
+		for _, resource := range served[version] {
+			registerResourceRoutes(group, resource, apiServer)
+		}
+	}
+
+	// k3 自有资源 k3.io/v1
+	k3V1 := fiberEngine.Api.Group("/apis/k3.io/v1", apiServer.trackActivity, apiServer.recordUsage, apiServer.authorize)
+	{
+		// Devices（集群级，由 inventory 控制器维护）
+		k3V1.Get("/devices", apiServer.HandleList)
+		k3V1.Get("/devices/:name", apiServer.HandleGet)
+		k3V1.Post("/devices", apiServer.HandleCreate)
+		k3V1.Put("/devices/:name", apiServer.HandleUpdate)
+		k3V1.Patch("/devices/:name", apiServer.HandlePatch)
+		k3V1.Delete("/devices/:name", apiServer.HandleDelete)
+		k3V1.Delete("/devices", apiServer.HandleDeleteCollection)
+		k3V1.Get("/watch/devices", apiServer.HandleWatch)
+
+		// ClientUsages（集群级，由 apiserver 按客户端身份定期累加请求统计，只有 cluster-admin 可以访问；删除即清零）
+		k3V1.Get("/clientusages", apiServer.HandleList)
+		k3V1.Get("/clientusages/:name", apiServer.HandleGet)
+		k3V1.Delete("/clientusages/:name", apiServer.HandleDelete)
+		k3V1.Delete("/clientusages", apiServer.HandleDeleteCollection)
+		k3V1.Get("/watch/clientusages", apiServer.HandleWatch)
+
+		// GitRepositories（namespace 级，由 gitops 控制器同步）
+		registerResourceRoutes(k3V1, "gitrepositories", apiServer)
+
+		// ClusterConfigurations（集群级，名称固定为 cluster，各组件 watch 后在运行时生效）
+		k3V1.Get("/clusterconfigurations", apiServer.HandleList)
+		k3V1.Get("/clusterconfigurations/:name", apiServer.HandleGet)
+		k3V1.Post("/clusterconfigurations", apiServer.HandleCreate)
+		k3V1.Put("/clusterconfigurations/:name", apiServer.HandleUpdate)
+		k3V1.Patch("/clusterconfigurations/:name", apiServer.HandlePatch)
+		k3V1.Delete("/clusterconfigurations/:name", apiServer.HandleDelete)
+		k3V1.Delete("/clusterconfigurations", apiServer.HandleDeleteCollection)
+		k3V1.Get("/watch/clusterconfigurations", apiServer.HandleWatch)
+
+		// ClusterInfos（集群级，名称固定为 cluster，由节点加入集群时写入；只读）
+		k3V1.Get("/clusterinfos", apiServer.HandleList)
+		k3V1.Get("/clusterinfos/:name", apiServer.HandleGet)
+		k3V1.Get("/watch/clusterinfos", apiServer.HandleWatch)
+
+		// PodStats（不存储，实时采样 apiserver 所在节点上 Pod 的 CPU/内存使用，k3 top pods 使用）
+		k3V1.Get("/podstats", apiServer.HandlePodStats)
+		k3V1.Get("/namespaces/:namespace/podstats", apiServer.HandlePodStats)
+	}
+
+	// scheduling.k8s.io/v1
+	schedulingV1 := fiberEngine.Api.Group("/apis/scheduling.k8s.io/v1", apiServer.trackActivity, apiServer.recordUsage, apiServer.authorize)
+	{
+		// PriorityClasses（集群级，Pod 通过 priorityClassName 引用，调度器按优先级排队与抢占）
+		schedulingV1.Get("/priorityclasses", apiServer.HandleList)
+		schedulingV1.Get("/priorityclasses/:name", apiServer.HandleGet)
+		schedulingV1.Post("/priorityclasses", apiServer.HandleCreate)
+		schedulingV1.Put("/priorityclasses/:name", apiServer.HandleUpdate)
+		schedulingV1.Patch("/priorityclasses/:name", apiServer.HandlePatch)
+		schedulingV1.Delete("/priorityclasses/:name", apiServer.HandleDelete)
+		schedulingV1.Delete("/priorityclasses", apiServer.HandleDeleteCollection)
+		schedulingV1.Get("/watch/priorityclasses", apiServer.HandleWatch)
+	}
+
+	// policy/v1
+	policyV1 := fiberEngine.Api.Group("/apis/policy/v1", apiServer.trackActivity, apiServer.recordUsage, apiServer.authorize)
+	{
+		// PodDisruptionBudgets（namespace 级，抢占选择被驱逐的 Pod 时遵守）
+		registerResourceRoutes(policyV1, "poddisruptionbudgets", apiServer)
+	}
+
+	// networking.k8s.io/v1
+	networkingV1 := fiberEngine.Api.Group("/apis/networking.k8s.io/v1", apiServer.trackActivity, apiServer.recordUsage, apiServer.authorize)
+	{
+		// NetworkPolicies（namespace 级）
+		registerResourceRoutes(networkingV1, "networkpolicies", apiServer)
+	}
+}
+
+// registerResourceRoutes 为 namespace 级资源注册与 apps/v1 相同的一组路由（list/get/create/update/patch/delete/deletecollection/watch）
+func registerResourceRoutes(group fiber.Router, resource string, apiServer *APIServer) {
+	for _, prefix := range []string{"", "/namespaces/:namespace"} {
+		group.Get(prefix+"/"+resource, apiServer.HandleList)
+		group.Get(prefix+"/"+resource+"/:name", apiServer.HandleGet)
+		group.Post(prefix+"/"+resource, apiServer.HandleCreate)
+		group.Put(prefix+"/"+resource+"/:name", apiServer.HandleUpdate)
+		group.Patch(prefix+"/"+resource+"/:name", apiServer.HandlePatch)
+		group.Delete(prefix+"/"+resource+"/:name", apiServer.HandleDelete)
+		group.Delete(prefix+"/"+resource, apiServer.HandleDeleteCollection)
+		group.Get("/watch"+prefix+"/"+resource, apiServer.HandleWatch)
+	}
 }
@@ -0,0 +1,202 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// 允许通过 POST /debug/runtime/exec 执行的运行时命令（只读）
+const (
+	RuntimeCommandPS      = "ps"
+	RuntimeCommandInspect = "inspect"
+	RuntimeCommandImages  = "images"
+)
+
+const (
+	// runtimeForwardedHeader 转发到其他节点的请求带有该请求头（值为转发的节点名），收到的节点不再继续转发
+	runtimeForwardedHeader = "X-K3-Runtime-Forwarded-By"
+	// runtimeForwardTimeout 转发到其他节点的超时
+	runtimeForwardTimeout = 30 * time.Second
+)
+
+// ErrRuntimeContainerNotFound inspect 的容器不存在（或不是 k3 创建的容器）
+var ErrRuntimeContainerNotFound = errors.New("container not found")
+
+// NodeRuntimeDebugger 在本节点的容器运行时上执行只读的调试命令（由持有容器运行时的进程提供，例如 one 模式下的 ControllerManager）
+type NodeRuntimeDebugger interface {
+	// NodeName 返回本节点名称，只有发往本节点的请求才会直接访问容器运行时
+	NodeName() string
+	// RuntimeExec 执行 req.Command（已校验在允许的命令之中）
+	RuntimeExec(ctx context.Context, req RuntimeExecRequest) (*RuntimeExecResult, error)
+}
+
+// WithNodeRuntimeDebugger 启用 POST /debug/runtime/exec
+func WithNodeRuntimeDebugger(runtime NodeRuntimeDebugger) Option {
+	return func(s *APIServer) {
+		s.runtime = runtime
+	}
+}
+
+// RuntimeExecRequest 是 POST /debug/runtime/exec 的请求体
+type RuntimeExecRequest struct {
+	// Node 执行命令的节点，为空时为 apiserver 所在节点
+	Node string `json:"node"`
+	// Command 为 ps、inspect 或 images
+	Command string `json:"command"`
+	// Container inspect 的容器：容器 ID（或其前缀）或容器名，只能是 k3 创建的容器
+	Container string `json:"container,omitempty"`
+}
+
+// RuntimeContainer ps 输出的一个容器（只包含 k3 创建的容器）
+type RuntimeContainer struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Status        string    `json:"status"`
+	PodNamespace  string    `json:"podNamespace"`
+	PodName       string    `json:"podName"`
+	PodUID        types.UID `json:"podUID"`
+	ContainerName string    `json:"containerName"`
+}
+
+// RuntimeImage images 输出的一个镜像
+type RuntimeImage struct {
+	ID        string    `json:"id"`
+	RepoTags  []string  `json:"repoTags,omitempty"`
+	SizeBytes int64     `json:"sizeBytes"`
+	Created   time.Time `json:"created"`
+	InUse     bool      `json:"inUse"`
+}
+
+// RuntimeExecResult 是 POST /debug/runtime/exec 的响应，按命令填写 containers、images 或 inspect 之一
+type RuntimeExecResult struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Node       string `json:"node"`
+	// Runtime 容器运行时名称（如 Docker）
+	Runtime    string             `json:"runtime"`
+	Command    string             `json:"command"`
+	Containers []RuntimeContainer `json:"containers,omitempty"`
+	Images     []RuntimeImage     `json:"images,omitempty"`
+	// Inspect 运行时返回的容器详情（原样输出）
+	Inspect json.RawMessage `json:"inspect,omitempty"`
+}
+
+// HandleRuntimeExec 处理 POST /debug/runtime/exec：在指定节点的容器运行时上执行只读的调试命令（ps、inspect、images）。
+// 本节点直接执行；其他节点通过 kube-system/k3-apiserver 中该节点发布的地址转发给其 apiserver（带上原请求的 Authorization），
+// 只转发一次
+func (s *APIServer) HandleRuntimeExec(c *fiber.Ctx) error {
+	var req RuntimeExecRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	switch req.Command {
+	case RuntimeCommandPS, RuntimeCommandImages:
+	case RuntimeCommandInspect:
+		if req.Container == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "container is required for inspect"})
+		}
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("command %q is not allowed (allowed: %s, %s, %s)", req.Command, RuntimeCommandPS, RuntimeCommandInspect, RuntimeCommandImages),
+		})
+	}
+
+	if s.runtime != nil && (req.Node == "" || req.Node == s.runtime.NodeName()) {
+		req.Node = s.runtime.NodeName()
+		result, err := s.runtime.RuntimeExec(c.UserContext(), req)
+		if errors.Is(err, ErrRuntimeContainerNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(result)
+	}
+	if req.Node == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "node is required (no container runtime on this server)"})
+	}
+	if from := c.Get(runtimeForwardedHeader); from != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("node %s is not served by this server (request forwarded by %s)", req.Node, from),
+		})
+	}
+	if _, err := s.store.Get(schema.GroupVersionKind{Version: "v1", Kind: "Node"}, "", req.Node); err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusNotFound)).JSON(fiber.Map{"error": err.Error()})
+	}
+	addr, err := s.agentAddress(req.Node)
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+	}
+	return s.forwardRuntimeExec(c, addr, req)
+}
+
+// agentAddress 返回节点发布在 kube-system/k3-apiserver Endpoints 中的 apiserver 地址（host:port）
+func (s *APIServer) agentAddress(node string) (string, error) {
+	obj, err := s.store.Get(endpointsGVK, SelfRegisterNamespace, SelfRegisterService)
+	if err != nil {
+		return "", fmt.Errorf("node %s has not published its apiserver address: %w", node, err)
+	}
+	ep, ok := obj.(*corev1.Endpoints)
+	if !ok {
+		return "", fmt.Errorf("stored object %s/%s is not Endpoints", SelfRegisterNamespace, SelfRegisterService)
+	}
+	for _, subset := range ep.Subsets {
+		if len(subset.Ports) == 0 {
+			continue
+		}
+		for _, addr := range subset.Addresses {
+			if addr.NodeName != nil && *addr.NodeName == node {
+				return net.JoinHostPort(addr.IP, strconv.Itoa(int(subset.Ports[0].Port))), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("node %s has not published its apiserver address in %s/%s", node, SelfRegisterNamespace, SelfRegisterService)
+}
+
+// forwardRuntimeExec 把请求转发给 addr 上的 apiserver，原样返回其状态码与响应
+func (s *APIServer) forwardRuntimeExec(c *fiber.Ctx, addr string, req RuntimeExecRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(c.UserContext(), runtimeForwardTimeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+addr+"/debug/runtime/exec", bytes.NewReader(body))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	httpReq.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if auth := c.Get(fiber.HeaderAuthorization); auth != "" {
+		httpReq.Header.Set(fiber.HeaderAuthorization, auth)
+	}
+	from := "unknown"
+	if s.runtime != nil {
+		from = s.runtime.NodeName()
+	}
+	httpReq.Header.Set(runtimeForwardedHeader, from)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": fmt.Sprintf("forward to node %s (%s): %v", req.Node, addr, err)})
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": fmt.Sprintf("read response from node %s (%s): %v", req.Node, addr, err)})
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Status(resp.StatusCode).Send(data)
+}
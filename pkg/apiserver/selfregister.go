@@ -0,0 +1,355 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// DefaultSelfRegisterInterval 未配置 apiserver.self_register_interval 时刷新本节点 Endpoints 地址的间隔
+	DefaultSelfRegisterInterval = 30 * time.Second
+	// SelfRegisterNamespace 发布 apiserver/dashboard 的 Service 与 Endpoints 所在的 namespace
+	SelfRegisterNamespace = "kube-system"
+	// SelfRegisterService 发布 apiserver/dashboard 的 Service 与 Endpoints 名称（两者共用同一个端口）
+	SelfRegisterService = "k3-apiserver"
+	// SelfRegisterManager 自注册写入的 fieldManager
+	SelfRegisterManager = "k3-apiserver"
+	// selfRegisterRenewAnnotation Endpoints 上记录各节点最近一次刷新时间的注解（JSON：节点名 -> RFC3339 时间）
+	selfRegisterRenewAnnotation = "k3.apiserver/renew-times"
+	// selfRegisterTTLFactor 节点超过 selfRegisterTTLFactor 个刷新间隔没有刷新时，其地址从 Endpoints 中移除
+	selfRegisterTTLFactor = 3
+)
+
+var (
+	namespaceGVK = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	serviceGVK   = schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	endpointsGVK = schema.GroupVersionKind{Version: "v1", Kind: "Endpoints"}
+)
+
+// SelfRegistrar 把本节点的 apiserver/dashboard 发布为 kube-system/k3-apiserver 的 Service 与 Endpoints：
+// 每个节点定期把自己的地址写入共用的 Endpoints，并移除超过 TTL 没有刷新的节点（已停止或失联），
+// 代理与 CLI 通过 Endpoints 找到任意一个存活的控制面节点，不再写死 localhost。
+// 多个节点同时写入时后写入者可能覆盖其他节点的地址，被覆盖的节点在下一个刷新间隔重新加入
+type SelfRegistrar struct {
+	store    storage.Store
+	nodeName string
+	address  string
+	port     int32
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewSelfRegistrar 创建自注册：nodeName 为本节点名，address:port 为其他节点访问本节点 apiserver 的地址
+func NewSelfRegistrar(store storage.Store, nodeName, address string, port int32, interval time.Duration) *SelfRegistrar {
+	return &SelfRegistrar{
+		store:    store,
+		nodeName: nodeName,
+		address:  address,
+		port:     port,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run 立即注册一次，之后每个刷新间隔刷新一次；ctx 取消时从 Endpoints 中移除本节点后返回
+func (r *SelfRegistrar) Run(ctx context.Context, onError func(error)) {
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	report(r.Register())
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			report(r.Deregister())
+			return
+		case <-ticker.C:
+			report(r.Register())
+		}
+	}
+}
+
+// Register 确保 namespace 与 Service 存在，并把本节点的地址写入 Endpoints
+func (r *SelfRegistrar) Register() error {
+	if err := r.ensureNamespace(); err != nil {
+		return err
+	}
+	if err := r.ensureService(); err != nil {
+		return err
+	}
+	return r.updateEndpoints(true)
+}
+
+// Deregister 从 Endpoints 中移除本节点的地址（Service 保留，其他节点仍在使用）
+func (r *SelfRegistrar) Deregister() error {
+	return r.updateEndpoints(false)
+}
+
+// ensureNamespace 创建 kube-system namespace（已存在时不修改）
+func (r *SelfRegistrar) ensureNamespace() error {
+	if _, err := r.store.Get(namespaceGVK, "", SelfRegisterNamespace); err == nil {
+		return nil
+	} else if storage.IsBackendError(err) {
+		return fmt.Errorf("读取 namespace %s 失败: %w", SelfRegisterNamespace, err)
+	}
+	ns := &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: SelfRegisterNamespace},
+	}
+	storage.RecordManager(ns, SelfRegisterManager)
+	if err := r.store.Create(namespaceGVK, ns); err != nil {
+		return fmt.Errorf("创建 namespace %s 失败: %w", SelfRegisterNamespace, err)
+	}
+	return nil
+}
+
+// ensureService 创建没有 selector 的 Service（地址由 Endpoints 提供）；端口与本节点不同时改为本节点的端口
+func (r *SelfRegistrar) ensureService() error {
+	obj, err := r.store.Get(serviceGVK, SelfRegisterNamespace, SelfRegisterService)
+	if err != nil {
+		if storage.IsBackendError(err) {
+			return fmt.Errorf("读取 Service %s/%s 失败: %w", SelfRegisterNamespace, SelfRegisterService, err)
+		}
+		svc := &corev1.Service{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      SelfRegisterService,
+				Namespace: SelfRegisterNamespace,
+				Labels:    map[string]string{"app.kubernetes.io/name": SelfRegisterService},
+			},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeClusterIP,
+				Ports: []corev1.ServicePort{r.servicePort()},
+			},
+		}
+		storage.RecordManager(svc, SelfRegisterManager)
+		if err := r.store.Create(serviceGVK, svc); err != nil {
+			return fmt.Errorf("创建 Service %s/%s 失败: %w", SelfRegisterNamespace, SelfRegisterService, err)
+		}
+		return nil
+	}
+	svc, ok := obj.(*corev1.Service)
+	if !ok {
+		return fmt.Errorf("Service %s/%s 的类型不是 Service", SelfRegisterNamespace, SelfRegisterService)
+	}
+	want := r.servicePort()
+	if len(svc.Spec.Ports) == 1 && svc.Spec.Ports[0].Port == want.Port && svc.Spec.Ports[0].TargetPort == want.TargetPort {
+		return nil
+	}
+	svc = svc.DeepCopy()
+	svc.Spec.Ports = []corev1.ServicePort{want}
+	storage.RecordManager(svc, SelfRegisterManager)
+	if err := r.store.Update(serviceGVK, svc); err != nil {
+		return fmt.Errorf("更新 Service %s/%s 失败: %w", SelfRegisterNamespace, SelfRegisterService, err)
+	}
+	return nil
+}
+
+// servicePort 返回 apiserver/dashboard 的端口
+func (r *SelfRegistrar) servicePort() corev1.ServicePort {
+	return corev1.ServicePort{
+		Name:       "http",
+		Protocol:   corev1.ProtocolTCP,
+		Port:       r.port,
+		TargetPort: intstr.FromInt32(r.port),
+	}
+}
+
+// updateEndpoints 读取 Endpoints，加入（present 为 true）或移除本节点的地址，同时移除超过 TTL 没有刷新的节点，
+// 有变化时写回
+func (r *SelfRegistrar) updateEndpoints(present bool) error {
+	var ep *corev1.Endpoints
+	exists := false
+	obj, err := r.store.Get(endpointsGVK, SelfRegisterNamespace, SelfRegisterService)
+	switch {
+	case err == nil:
+		cur, ok := obj.(*corev1.Endpoints)
+		if !ok {
+			return fmt.Errorf("Endpoints %s/%s 的类型不是 Endpoints", SelfRegisterNamespace, SelfRegisterService)
+		}
+		ep, exists = cur.DeepCopy(), true
+	case storage.IsBackendError(err):
+		return fmt.Errorf("读取 Endpoints %s/%s 失败: %w", SelfRegisterNamespace, SelfRegisterService, err)
+	case !present:
+		return nil
+	default:
+		ep = &corev1.Endpoints{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Endpoints"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      SelfRegisterService,
+				Namespace: SelfRegisterNamespace,
+				Labels:    map[string]string{"app.kubernetes.io/name": SelfRegisterService},
+			},
+		}
+	}
+
+	now := r.now()
+	renewed := renewTimes(ep)
+	addresses := make(map[string]corev1.EndpointAddress)
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			if addr.NodeName != nil {
+				addresses[*addr.NodeName] = addr
+			}
+		}
+	}
+	ttl := selfRegisterTTLFactor * r.interval
+	for node, at := range renewed {
+		if node != r.nodeName && now.Sub(at) > ttl {
+			delete(renewed, node)
+		}
+	}
+	for node := range addresses {
+		if _, ok := renewed[node]; !ok && node != r.nodeName {
+			delete(addresses, node)
+		}
+	}
+	if present {
+		nodeName := r.nodeName
+		addresses[nodeName] = corev1.EndpointAddress{IP: r.address, Hostname: hostnameLabel(nodeName), NodeName: &nodeName}
+		renewed[nodeName] = now
+	} else {
+		delete(addresses, r.nodeName)
+		delete(renewed, r.nodeName)
+	}
+
+	before, _ := json.Marshal(ep.Subsets)
+	ep.Subsets = nil
+	if len(addresses) > 0 {
+		nodes := make([]string, 0, len(addresses))
+		for node := range addresses {
+			nodes = append(nodes, node)
+		}
+		sort.Strings(nodes)
+		subset := corev1.EndpointSubset{Ports: []corev1.EndpointPort{{Name: "http", Port: r.port, Protocol: corev1.ProtocolTCP}}}
+		for _, node := range nodes {
+			subset.Addresses = append(subset.Addresses, addresses[node])
+		}
+		ep.Subsets = []corev1.EndpointSubset{subset}
+	}
+	after, _ := json.Marshal(ep.Subsets)
+	setRenewTimes(ep, renewed)
+
+	storage.RecordManager(ep, SelfRegisterManager)
+	if !exists {
+		if err := r.store.Create(endpointsGVK, ep); err != nil {
+			return fmt.Errorf("创建 Endpoints %s/%s 失败: %w", SelfRegisterNamespace, SelfRegisterService, err)
+		}
+		return nil
+	}
+	// 注册时即使地址没有变化也写回刷新时间，其他节点据此判断本节点仍然存活
+	if !present && string(before) == string(after) {
+		return nil
+	}
+	if err := r.store.Update(endpointsGVK, ep); err != nil {
+		return fmt.Errorf("更新 Endpoints %s/%s 失败: %w", SelfRegisterNamespace, SelfRegisterService, err)
+	}
+	return nil
+}
+
+// renewTimes 读取 Endpoints 上各节点最近一次刷新的时间；注解缺失或无法解析时返回空表
+func renewTimes(ep *corev1.Endpoints) map[string]time.Time {
+	times := make(map[string]time.Time)
+	raw, ok := ep.Annotations[selfRegisterRenewAnnotation]
+	if !ok {
+		return times
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return times
+	}
+	for node, v := range values {
+		if at, err := time.Parse(time.RFC3339, v); err == nil {
+			times[node] = at
+		}
+	}
+	return times
+}
+
+// setRenewTimes 把各节点最近一次刷新的时间写入 Endpoints 的注解
+func setRenewTimes(ep *corev1.Endpoints, times map[string]time.Time) {
+	values := make(map[string]string, len(times))
+	for node, at := range times {
+		values[node] = at.UTC().Format(time.RFC3339)
+	}
+	raw, _ := json.Marshal(values)
+	if ep.Annotations == nil {
+		ep.Annotations = make(map[string]string)
+	}
+	ep.Annotations[selfRegisterRenewAnnotation] = string(raw)
+}
+
+// hostnameLabel 节点名是合法的 DNS label 时作为地址的 hostname，否则返回空
+func hostnameLabel(name string) string {
+	if len(name) > 63 {
+		return ""
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && i > 0 && i < len(name)-1:
+		default:
+			return ""
+		}
+	}
+	return name
+}
+
+// SelfNodeName 返回本节点名：环境变量 NODE_NAME 优先，其次是配置 node_name，都为空时使用主机名
+func SelfNodeName(configured string) string {
+	if name := os.Getenv("NODE_NAME"); name != "" {
+		return name
+	}
+	if configured != "" {
+		return configured
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return "node-1"
+}
+
+// AdvertiseAddress 返回其他节点访问本节点的地址：配置了 apiserver.advertise_address 时使用它，
+// 否则使用第一个非回环 IPv4 地址，只有 IPv6 时使用第一个全局 IPv6 地址，都没有时使用 127.0.0.1
+func AdvertiseAddress(configured string) (string, error) {
+	if configured != "" {
+		if net.ParseIP(configured) == nil {
+			return "", fmt.Errorf("apiserver.advertise_address 不是合法的 IP 地址: %q", configured)
+		}
+		return configured, nil
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		var v6 string
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				return ipNet.IP.String(), nil
+			}
+			if v6 == "" && ipNet.IP.IsGlobalUnicast() {
+				v6 = ipNet.IP.String()
+			}
+		}
+		if v6 != "" {
+			return v6, nil
+		}
+	}
+	return "127.0.0.1", nil
+}
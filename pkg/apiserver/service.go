@@ -0,0 +1,161 @@
+package apiserver
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// WithServiceCIDRs 设置 Service 的 clusterIP 所在网段（network.service_cidrs，双栈时 IPv4 与 IPv6 各一个）；
+// 设置后拒绝网段之外的 clusterIP 以及没有对应网段的 ipFamilies
+func WithServiceCIDRs(cidrs []*net.IPNet) Option {
+	return func(s *APIServer) {
+		s.admission = NewAdmission(s.store, cidrs)
+	}
+}
+
+// validateService 校验 Service 的类型与 clusterIP（与 Kubernetes 相同）：
+// ExternalName 必须设置小写的 DNS-1123 subdomain 形式的 spec.externalName，且不能设置 clusterIP；
+// clusterIP 为 None（headless，DNS 直接解析到 Pod IP）只允许 ClusterIP 类型，其他取值必须是 IP 地址。
+// 双栈时 clusterIPs 每个地址族最多一个，第一个与 clusterIP 相同，并与 ipFamilies 一一对应；cidrs 不为空时还要求地址落在其中。
+// spec.selector 的键与值必须是合法的标签（存储按标签查询 Pod 时使用）
+func validateService(svc *corev1.Service, cidrs []*net.IPNet) error {
+	spec := &svc.Spec
+	if err := validateServiceSelector(spec.Selector); err != nil {
+		return err
+	}
+	if spec.Type == corev1.ServiceTypeExternalName {
+		name := strings.TrimSuffix(spec.ExternalName, ".")
+		if name == "" {
+			return fmt.Errorf("spec.externalName: ExternalName 类型的 Service 必须设置")
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("spec.externalName: %q 不是合法的 DNS 名称: %s", spec.ExternalName, strings.Join(errs, "; "))
+		}
+		if spec.ClusterIP != "" {
+			return fmt.Errorf("spec.clusterIP: ExternalName 类型的 Service 不能设置")
+		}
+		return nil
+	}
+	switch spec.ClusterIP {
+	case "":
+	case corev1.ClusterIPNone:
+		if spec.Type != corev1.ServiceTypeClusterIP {
+			return fmt.Errorf("spec.clusterIP: %s 类型的 Service 不能为 None", spec.Type)
+		}
+		return nil
+	default:
+		if net.ParseIP(spec.ClusterIP) == nil {
+			return fmt.Errorf("spec.clusterIP: %q 必须是 IP 地址或 None", spec.ClusterIP)
+		}
+	}
+	return validateServiceIPFamilies(spec, cidrs)
+}
+
+// validateServiceSelector 校验 spec.selector 的键与值（与 metadata.labels 的规则相同）
+func validateServiceSelector(selector map[string]string) error {
+	for k, v := range selector {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("spec.selector: 键 %q 不合法: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("spec.selector[%s]: 值 %q 不合法: %s", k, v, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// validateServiceIPFamilies 校验 clusterIPs、ipFamilies 与 ipFamilyPolicy 是否一致，以及是否落在 Service 网段内
+func validateServiceIPFamilies(spec *corev1.ServiceSpec, cidrs []*net.IPNet) error {
+	if len(spec.ClusterIPs) > 0 && spec.ClusterIP != "" && spec.ClusterIPs[0] != spec.ClusterIP {
+		return fmt.Errorf("spec.clusterIPs[0]: 必须与 spec.clusterIP（%s）相同", spec.ClusterIP)
+	}
+	if len(spec.ClusterIPs) > 2 || len(spec.IPFamilies) > 2 {
+		return fmt.Errorf("spec.clusterIPs/spec.ipFamilies: 最多两个（IPv4 与 IPv6 各一个）")
+	}
+	var families []corev1.IPFamily
+	for i, v := range spec.ClusterIPs {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return fmt.Errorf("spec.clusterIPs[%d]: %q 必须是 IP 地址", i, v)
+		}
+		if len(cidrs) > 0 && !cidrsContain(cidrs, ip) {
+			return fmt.Errorf("spec.clusterIPs[%d]: %s 不在 Service 网段 %s 内", i, v, cidrsString(cidrs))
+		}
+		families = append(families, ipFamilyOf(ip))
+	}
+	if ip := net.ParseIP(spec.ClusterIP); ip != nil && len(cidrs) > 0 && !cidrsContain(cidrs, ip) {
+		return fmt.Errorf("spec.clusterIP: %s 不在 Service 网段 %s 内", spec.ClusterIP, cidrsString(cidrs))
+	}
+	if len(families) == 2 && families[0] == families[1] {
+		return fmt.Errorf("spec.clusterIPs: 两个地址必须分属 IPv4 与 IPv6")
+	}
+	if len(spec.IPFamilies) == 2 && spec.IPFamilies[0] == spec.IPFamilies[1] {
+		return fmt.Errorf("spec.ipFamilies: 不能重复")
+	}
+	for i, family := range spec.IPFamilies {
+		if family != corev1.IPv4Protocol && family != corev1.IPv6Protocol {
+			return fmt.Errorf("spec.ipFamilies[%d]: 只支持 IPv4 与 IPv6: %q", i, family)
+		}
+		if i < len(families) && families[i] != family {
+			return fmt.Errorf("spec.ipFamilies[%d]: %s 与 spec.clusterIPs[%d] 的地址族 %s 不一致", i, family, i, families[i])
+		}
+		if len(cidrs) > 0 && !cidrsHaveFamily(cidrs, family) {
+			return fmt.Errorf("spec.ipFamilies[%d]: 没有配置 %s 的 Service 网段（network.service_cidrs）", i, family)
+		}
+	}
+	if spec.IPFamilyPolicy == nil {
+		return nil
+	}
+	switch *spec.IPFamilyPolicy {
+	case corev1.IPFamilyPolicySingleStack:
+		if len(spec.ClusterIPs) > 1 || len(spec.IPFamilies) > 1 {
+			return fmt.Errorf("spec.ipFamilyPolicy: SingleStack 只能有一个 clusterIP 与 ipFamily")
+		}
+	case corev1.IPFamilyPolicyPreferDualStack:
+	case corev1.IPFamilyPolicyRequireDualStack:
+		if len(cidrs) > 0 && !(cidrsHaveFamily(cidrs, corev1.IPv4Protocol) && cidrsHaveFamily(cidrs, corev1.IPv6Protocol)) {
+			return fmt.Errorf("spec.ipFamilyPolicy: RequireDualStack 需要同时配置 IPv4 与 IPv6 的 Service 网段（当前为 %s）", cidrsString(cidrs))
+		}
+	default:
+		return fmt.Errorf("spec.ipFamilyPolicy: 只支持 SingleStack、PreferDualStack 与 RequireDualStack: %q", *spec.IPFamilyPolicy)
+	}
+	return nil
+}
+
+// ipFamilyOf 返回地址所属的地址族
+func ipFamilyOf(ip net.IP) corev1.IPFamily {
+	if ip.To4() != nil {
+		return corev1.IPv4Protocol
+	}
+	return corev1.IPv6Protocol
+}
+
+func cidrsContain(cidrs []*net.IPNet, ip net.IP) bool {
+	for _, n := range cidrs {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func cidrsHaveFamily(cidrs []*net.IPNet, family corev1.IPFamily) bool {
+	for _, n := range cidrs {
+		if ipFamilyOf(n.IP) == family {
+			return true
+		}
+	}
+	return false
+}
+
+func cidrsString(cidrs []*net.IPNet) string {
+	parts := make([]string, 0, len(cidrs))
+	for _, n := range cidrs {
+		parts = append(parts, n.String())
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,162 @@
+package apiserver
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/printers"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// wantsTable 判断客户端是否请求 Table 格式（与 kubectl 相同：Accept: application/json;as=Table;v=v1;g=meta.k8s.io）
+func wantsTable(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		for _, param := range strings.Split(part, ";") {
+			if strings.EqualFold(strings.TrimSpace(param), "as=Table") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tablePrinter 一种资源的列定义与取值；对象类型不匹配时 cells 返回 nil
+type tablePrinter struct {
+	columns []metav1.TableColumnDefinition
+	cells   func(obj runtime.Object, now time.Time) []interface{}
+}
+
+var (
+	nameColumn = metav1.TableColumnDefinition{Name: "Name", Type: "string", Format: "name", Description: "对象名称"}
+	ageColumn  = metav1.TableColumnDefinition{Name: "Age", Type: "string", Description: "创建至今的时长"}
+)
+
+// tablePrinters 按 Kind 登记的列，未登记的资源只输出 Name 与 Age
+var tablePrinters = map[string]tablePrinter{
+	"Pod": {
+		columns: []metav1.TableColumnDefinition{
+			nameColumn,
+			{Name: "Ready", Type: "string", Description: "就绪容器数/容器数"},
+			{Name: "Status", Type: "string", Description: "Pod 状态（与 kubectl 相同）"},
+			{Name: "Restarts", Type: "integer", Description: "容器重启次数之和"},
+			ageColumn,
+			{Name: "IP", Type: "string", Priority: 1, Description: "Pod IP"},
+			{Name: "Node", Type: "string", Priority: 1, Description: "所在节点"},
+		},
+		cells: func(obj runtime.Object, now time.Time) []interface{} {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				return nil
+			}
+			s := printers.SummarizePod(pod, now)
+			return []interface{}{pod.Name, s.Ready, s.Status, s.Restarts, s.Age, noneIfEmpty(s.IP), noneIfEmpty(s.Node)}
+		},
+	},
+	"Deployment": {
+		columns: []metav1.TableColumnDefinition{
+			nameColumn,
+			{Name: "Ready", Type: "string", Description: "就绪副本数/期望副本数"},
+			{Name: "Up-to-date", Type: "integer", Description: "已更新到最新模板的副本数"},
+			{Name: "Available", Type: "integer", Description: "可用副本数"},
+			ageColumn,
+		},
+		cells: func(obj runtime.Object, now time.Time) []interface{} {
+			d, ok := obj.(*appsv1.Deployment)
+			if !ok {
+				return nil
+			}
+			desired := int32(1)
+			if d.Spec.Replicas != nil {
+				desired = *d.Spec.Replicas
+			}
+			return []interface{}{d.Name, printers.Replicas(d.Status.ReadyReplicas, desired), d.Status.UpdatedReplicas,
+				d.Status.AvailableReplicas, printers.Age(d.CreationTimestamp.Time, now)}
+		},
+	},
+	"Node": {
+		columns: []metav1.TableColumnDefinition{
+			nameColumn,
+			{Name: "Status", Type: "string", Description: "Ready condition"},
+			ageColumn,
+		},
+		cells: func(obj runtime.Object, now time.Time) []interface{} {
+			n, ok := obj.(*corev1.Node)
+			if !ok {
+				return nil
+			}
+			status := "NotReady"
+			for _, c := range n.Status.Conditions {
+				if c.Type == corev1.NodeReady && c.Status == corev1.ConditionTrue {
+					status = "Ready"
+				}
+			}
+			if n.Spec.Unschedulable {
+				status += ",SchedulingDisabled"
+			}
+			return []interface{}{n.Name, status, printers.Age(n.CreationTimestamp.Time, now)}
+		},
+	},
+}
+
+// defaultTablePrinter 未登记资源的列
+var defaultTablePrinter = tablePrinter{
+	columns: []metav1.TableColumnDefinition{nameColumn, ageColumn},
+	cells: func(obj runtime.Object, now time.Time) []interface{} {
+		meta, ok := obj.(metav1.Object)
+		if !ok {
+			return nil
+		}
+		return []interface{}{meta.GetName(), printers.Age(meta.GetCreationTimestamp().Time, now)}
+	},
+}
+
+// noneIfEmpty 空值显示为 <none>
+func noneIfEmpty(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+// buildTable 把存储版本的对象转换为 metav1.Table；withNamespace 为 true 时（跨 namespace 列出 namespaced 资源）在最前面加 Namespace 列。
+// 每行的 object 为 PartialObjectMetadata，类型与 printer 不匹配的对象按默认列输出
+func buildTable(kind string, objects []runtime.Object, withNamespace bool, now time.Time) *metav1.Table {
+	printer, ok := tablePrinters[kind]
+	if !ok {
+		printer = defaultTablePrinter
+	}
+	table := &metav1.Table{
+		TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "Table"},
+		Rows:     make([]metav1.TableRow, 0, len(objects)),
+	}
+	if withNamespace {
+		table.ColumnDefinitions = append(table.ColumnDefinitions, metav1.TableColumnDefinition{Name: "Namespace", Type: "string", Description: "所在 namespace"})
+	}
+	table.ColumnDefinitions = append(table.ColumnDefinitions, printer.columns...)
+
+	for _, obj := range objects {
+		meta, ok := obj.(metav1.Object)
+		if !ok {
+			continue
+		}
+		cells := printer.cells(obj, now)
+		if cells == nil {
+			cells = []interface{}{meta.GetName()}
+			for len(cells) < len(printer.columns) {
+				cells = append(cells, "<unknown>")
+			}
+		}
+		if withNamespace {
+			cells = append([]interface{}{meta.GetNamespace()}, cells...)
+		}
+		partial := &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "PartialObjectMetadata"},
+			ObjectMeta: metav1.ObjectMeta{Name: meta.GetName(), Namespace: meta.GetNamespace(), UID: meta.GetUID(), ResourceVersion: meta.GetResourceVersion(), CreationTimestamp: meta.GetCreationTimestamp(), Labels: meta.GetLabels()},
+		}
+		table.Rows = append(table.Rows, metav1.TableRow{Cells: cells, Object: runtime.RawExtension{Object: partial}})
+	}
+	return table
+}
@@ -0,0 +1,95 @@
+package apiserver
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidateTopologySpreadConstraints 校验 topologySpreadConstraints：maxSkew 至少为 1，topologyKey 不能为空，
+// whenUnsatisfiable 为 DoNotSchedule/ScheduleAnyway，minDomains 只能用于 DoNotSchedule，labelSelector 必须有效。
+// field 为错误信息中的字段路径（例如 spec.template.spec.topologySpreadConstraints）
+func ValidateTopologySpreadConstraints(field string, constraints []corev1.TopologySpreadConstraint) error {
+	seen := make(map[string]bool, len(constraints))
+	for i, c := range constraints {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if c.MaxSkew < 1 {
+			return fmt.Errorf("%s.maxSkew 必须大于 0: %d", path, c.MaxSkew)
+		}
+		if c.TopologyKey == "" {
+			return fmt.Errorf("%s.topologyKey 不能为空", path)
+		}
+		switch c.WhenUnsatisfiable {
+		case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+		default:
+			return fmt.Errorf("%s.whenUnsatisfiable 无效: %q（可选 %s/%s）", path, c.WhenUnsatisfiable, corev1.DoNotSchedule, corev1.ScheduleAnyway)
+		}
+		if c.MinDomains != nil {
+			if *c.MinDomains < 1 {
+				return fmt.Errorf("%s.minDomains 必须大于 0: %d", path, *c.MinDomains)
+			}
+			if c.WhenUnsatisfiable != corev1.DoNotSchedule {
+				return fmt.Errorf("%s.minDomains 只能与 whenUnsatisfiable: %s 一起使用", path, corev1.DoNotSchedule)
+			}
+		}
+		if _, err := metav1.LabelSelectorAsSelector(c.LabelSelector); err != nil {
+			return fmt.Errorf("%s.labelSelector 无效: %w", path, err)
+		}
+		// 与 Kubernetes 相同，topologyKey 与 whenUnsatisfiable 的组合不能重复
+		key := c.TopologyKey + "/" + string(c.WhenUnsatisfiable)
+		if seen[key] {
+			return fmt.Errorf("%s: topologyKey %s 与 whenUnsatisfiable %s 的组合重复", path, c.TopologyKey, c.WhenUnsatisfiable)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// ValidatePodAntiAffinity 校验 podAntiAffinity：各项的 topologyKey 不能为空，preferred 项的 weight 在 1-100 之间，
+// labelSelector 与 namespaceSelector 必须有效
+func ValidatePodAntiAffinity(field string, aa *corev1.PodAntiAffinity) error {
+	if aa == nil {
+		return nil
+	}
+	for i, t := range aa.RequiredDuringSchedulingIgnoredDuringExecution {
+		if err := validatePodAffinityTerm(fmt.Sprintf("%s.requiredDuringSchedulingIgnoredDuringExecution[%d]", field, i), t); err != nil {
+			return err
+		}
+	}
+	for i, wt := range aa.PreferredDuringSchedulingIgnoredDuringExecution {
+		path := fmt.Sprintf("%s.preferredDuringSchedulingIgnoredDuringExecution[%d]", field, i)
+		if wt.Weight < 1 || wt.Weight > 100 {
+			return fmt.Errorf("%s.weight 必须在 1-100 之间: %d", path, wt.Weight)
+		}
+		if err := validatePodAffinityTerm(path+".podAffinityTerm", wt.PodAffinityTerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePodAffinityTerm(path string, t corev1.PodAffinityTerm) error {
+	if t.TopologyKey == "" {
+		return fmt.Errorf("%s.topologyKey 不能为空", path)
+	}
+	if _, err := metav1.LabelSelectorAsSelector(t.LabelSelector); err != nil {
+		return fmt.Errorf("%s.labelSelector 无效: %w", path, err)
+	}
+	if _, err := metav1.LabelSelectorAsSelector(t.NamespaceSelector); err != nil {
+		return fmt.Errorf("%s.namespaceSelector 无效: %w", path, err)
+	}
+	return nil
+}
+
+// validatePodScheduling 校验 Pod（或模板）spec 中与调度相关的字段：topologySpreadConstraints 与 podAntiAffinity。
+// field 为 spec 的字段路径（例如 spec.template.spec）
+func validatePodScheduling(field string, spec *corev1.PodSpec) error {
+	if err := ValidateTopologySpreadConstraints(field+".topologySpreadConstraints", spec.TopologySpreadConstraints); err != nil {
+		return err
+	}
+	if spec.Affinity != nil {
+		return ValidatePodAntiAffinity(field+".affinity.podAntiAffinity", spec.Affinity.PodAntiAffinity)
+	}
+	return nil
+}
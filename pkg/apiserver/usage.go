@@ -0,0 +1,251 @@
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultUsageInterval 未配置 apiserver.usage_interval 时写入 ClientUsage 的间隔
+const DefaultUsageInterval = time.Minute
+
+// anonymousUser 未开启认证时的请求身份
+const anonymousUser = "anonymous"
+
+// usageDelta 上次写入之后一个客户端的请求统计
+type usageDelta struct {
+	user, agent   string
+	requests      int64
+	errors        int64
+	requestBytes  int64
+	responseBytes int64
+	verbs         map[string]int64
+	first, last   time.Time
+}
+
+// merge 把 other 累加到 d
+func (d *usageDelta) merge(other *usageDelta) {
+	d.requests += other.requests
+	d.errors += other.errors
+	d.requestBytes += other.requestBytes
+	d.responseBytes += other.responseBytes
+	for verb, n := range other.verbs {
+		d.verbs[verb] += n
+	}
+	if other.first.Before(d.first) {
+		d.first = other.first
+	}
+	if other.last.After(d.last) {
+		d.last = other.last
+	}
+}
+
+// UsageRecorder 按客户端（身份 + User-Agent）统计 apiserver 请求，并定期累加到 k3.io/v1 ClientUsage
+type UsageRecorder struct {
+	store storage.Store
+
+	mu      sync.Mutex
+	pending map[string]*usageDelta // key: ClientUsage 名称
+}
+
+// NewUsageRecorder 创建请求统计
+func NewUsageRecorder(store storage.Store) *UsageRecorder {
+	return &UsageRecorder{
+		store:   store,
+		pending: make(map[string]*usageDelta),
+	}
+}
+
+// Record 记录一次请求
+func (r *UsageRecorder) Record(user, agent, verb string, status int, requestBytes, responseBytes int64, at time.Time) {
+	name := ClientUsageName(user, agent)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.pending[name]
+	if !ok {
+		d = &usageDelta{user: user, agent: agent, verbs: make(map[string]int64), first: at}
+		r.pending[name] = d
+	}
+	d.requests++
+	if status >= 400 {
+		d.errors++
+	}
+	d.requestBytes += requestBytes
+	d.responseBytes += responseBytes
+	d.verbs[verb]++
+	d.last = at
+}
+
+// Flush 把上次写入之后的统计累加到对应的 ClientUsage（不存在时创建）；写入失败的部分保留到下次
+func (r *UsageRecorder) Flush() error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[string]*usageDelta)
+	r.mu.Unlock()
+
+	var firstErr error
+	for name, d := range pending {
+		if err := r.apply(name, d); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			r.requeue(name, d)
+		}
+	}
+	return firstErr
+}
+
+// requeue 把写入失败的统计放回待写入
+func (r *UsageRecorder) requeue(name string, d *usageDelta) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cur, ok := r.pending[name]; ok {
+		d.merge(cur)
+	}
+	r.pending[name] = d
+}
+
+// apply 把 d 累加到名为 name 的 ClientUsage
+func (r *UsageRecorder) apply(name string, d *usageDelta) error {
+	obj, err := r.store.Get(k3v1.ClientUsageGVK, "", name)
+	if err != nil {
+		if storage.IsBackendError(err) {
+			return err
+		}
+		usage := &k3v1.ClientUsage{
+			TypeMeta:   metav1.TypeMeta{APIVersion: k3v1.SchemeGroupVersion.String(), Kind: k3v1.ClientUsageGVK.Kind},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: k3v1.ClientUsageStatus{
+				User:      d.user,
+				UserAgent: d.agent,
+				Verbs:     make(map[string]int64),
+				FirstSeen: metav1.NewTime(d.first),
+			},
+		}
+		addUsage(&usage.Status, d)
+		return r.store.Create(k3v1.ClientUsageGVK, usage)
+	}
+
+	usage, ok := obj.(*k3v1.ClientUsage)
+	if !ok {
+		return fmt.Errorf("unexpected object type %T for ClientUsage %s", obj, name)
+	}
+	if usage.Status.Verbs == nil {
+		usage.Status.Verbs = make(map[string]int64)
+	}
+	addUsage(&usage.Status, d)
+	return r.store.Update(k3v1.ClientUsageGVK, usage)
+}
+
+// addUsage 把 d 累加到 status
+func addUsage(status *k3v1.ClientUsageStatus, d *usageDelta) {
+	status.Requests += d.requests
+	status.Errors += d.errors
+	status.RequestBytes += d.requestBytes
+	status.ResponseBytes += d.responseBytes
+	for verb, n := range d.verbs {
+		status.Verbs[verb] += n
+	}
+	if status.FirstSeen.IsZero() {
+		status.FirstSeen = metav1.NewTime(d.first)
+	}
+	if d.last.After(status.LastSeen.Time) {
+		status.LastSeen = metav1.NewTime(d.last)
+	}
+}
+
+// Run 每 interval 写入一次统计，ctx 结束时再写入一次后返回
+func (r *UsageRecorder) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Flush(); err != nil && onError != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// ClientUsageName 返回客户端对应的 ClientUsage 名称：可读的身份与 User-Agent 加上区分大小写/特殊字符的哈希
+func ClientUsageName(user, agent string) string {
+	h := fnv.New32a()
+	h.Write([]byte(user + "/" + agent))
+	name := sanitizeName(user) + "-" + sanitizeName(agent)
+	if len(name) > 200 {
+		name = name[:200]
+	}
+	return fmt.Sprintf("%s-%08x", name, h.Sum32())
+}
+
+// sanitizeName 转换为 DNS-1123 字符（小写字母、数字、-）
+func sanitizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, s)
+	s = strings.Trim(s, "-")
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// WithUsageRecorder 启用按客户端身份的请求统计
+func WithUsageRecorder(usage *UsageRecorder) Option {
+	return func(s *APIServer) {
+		s.usage = usage
+	}
+}
+
+// recordUsage 统计请求（身份、User-Agent、状态码、字节数）；watch 等流式响应不计响应字节数
+func (s *APIServer) recordUsage(c *fiber.Ctx) error {
+	if s.usage == nil {
+		return c.Next()
+	}
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = fiber.StatusInternalServerError
+		if fe, ok := err.(*fiber.Error); ok {
+			status = fe.Code
+		}
+	}
+	var responseBytes int64
+	if !c.Response().IsBodyStream() {
+		responseBytes = int64(len(c.Response().Body()))
+	}
+	user := anonymousUser
+	if id := webprovider.IdentityFromCtx(c); id != nil && id.User != "" {
+		user = id.User
+	}
+	agent := "unknown"
+	if ua := c.Get(fiber.HeaderUserAgent); ua != "" {
+		agent = strings.SplitN(ua, "/", 2)[0]
+	}
+	s.usage.Record(user, agent, c.Method(), status, int64(len(c.Request().Body())), responseBytes, time.Now())
+	return err
+}
@@ -0,0 +1,100 @@
+package apiserver
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// MaxAnnotationsSize annotations（键与值）的总大小上限，与 Kubernetes 相同为 256KiB
+const MaxAnnotationsSize = 256 * 1024
+
+// InvalidError 对象不满足 Kubernetes 的校验规则，Causes 列出每个不合法的字段（响应 422）
+type InvalidError struct {
+	Kind   string
+	Name   string
+	Causes field.ErrorList
+}
+
+func (e *InvalidError) Error() string {
+	return apierrors.NewInvalid(schema.GroupKind{Kind: e.Kind}, e.Name, e.Causes).Error()
+}
+
+// validateMetadata 校验对象的 metadata：checkName 时按 kind 校验名称（创建时；名称不可修改，更新不再校验），
+// namespace 为 DNS-1123 label，labels 的键与值、annotations 的键与总大小。不合法时返回 *InvalidError
+func validateMetadata(gvk schema.GroupVersionKind, obj runtime.Object, checkName bool) error {
+	meta, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+	path := field.NewPath("metadata")
+	var errs field.ErrorList
+	if checkName {
+		if name := meta.GetName(); name == "" {
+			errs = append(errs, field.Required(path.Child("name"), "name is required"))
+		} else {
+			for _, msg := range nameValidator(gvk.Kind)(name) {
+				errs = append(errs, field.Invalid(path.Child("name"), name, msg))
+			}
+		}
+	}
+	if ns := meta.GetNamespace(); ns != "" {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(path.Child("namespace"), ns, msg))
+		}
+	}
+	for key, value := range meta.GetLabels() {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(path.Child("labels"), key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, field.Invalid(path.Child("labels").Key(key), value, msg))
+		}
+	}
+	size := 0
+	for key, value := range meta.GetAnnotations() {
+		for _, msg := range validation.IsQualifiedName(strings.ToLower(key)) {
+			errs = append(errs, field.Invalid(path.Child("annotations"), key, msg))
+		}
+		size += len(key) + len(value)
+	}
+	if size > MaxAnnotationsSize {
+		errs = append(errs, field.TooLong(path.Child("annotations"), "", MaxAnnotationsSize))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &InvalidError{Kind: gvk.Kind, Name: meta.GetName(), Causes: errs}
+}
+
+// nameValidator 返回 kind 的名称规则：Namespace 为 DNS-1123 label，Service 为 DNS-1035 label，其他为 DNS-1123 subdomain
+func nameValidator(kind string) func(string) []string {
+	switch kind {
+	case "Namespace":
+		return validation.IsDNS1123Label
+	case "Service":
+		return validation.IsDNS1035Label
+	default:
+		return validation.IsDNS1123Subdomain
+	}
+}
+
+// admissionError 返回准入失败的响应：*InvalidError 为 422 并列出每个不合法的字段，其他错误按 errorStatus 取状态码
+func admissionError(c *fiber.Ctx, err error) error {
+	var invalid *InvalidError
+	if errors.As(err, &invalid) {
+		causes := make([]fiber.Map, 0, len(invalid.Causes))
+		for _, cause := range invalid.Causes {
+			causes = append(causes, fiber.Map{"field": cause.Field, "reason": string(cause.Type), "message": cause.ErrorBody()})
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error(), "causes": causes})
+	}
+	return c.Status(storeErrorStatus(c, err, errorStatus(err, fiber.StatusBadRequest))).JSON(fiber.Map{"error": err.Error()})
+}
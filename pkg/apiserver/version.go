@@ -0,0 +1,52 @@
+package apiserver
+
+import (
+	"fmt"
+	goruntime "runtime"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/clusterinfo"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/version"
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+)
+
+// VersionInfo 是 GET /version 的响应：与 Kubernetes 相同的字段（gitVersion 为 k3 版本，kubectl version 可以读取），
+// 加上存储 schema 版本与共享存储中的集群身份（ClusterInfo，还没有节点加入时省略）
+type VersionInfo struct {
+	k8sversion.Info
+	// SchemaVersion 本节点支持的存储 schema 版本
+	SchemaVersion int `json:"schemaVersion"`
+	// Cluster 集群身份：UID、cluster.id、创建时间、创建时的 k3 版本与存储类型
+	Cluster *k3v1.ClusterInfoSpec `json:"cluster,omitempty"`
+	// Nodes 各节点最近一次加入集群时的版本
+	Nodes []k3v1.ClusterMember `json:"nodes,omitempty"`
+}
+
+// HandleVersion 处理 GET /version
+func (s *APIServer) HandleVersion(c *fiber.Ctx) error {
+	resp := VersionInfo{
+		Info: k8sversion.Info{
+			GitVersion: version.Version,
+			GoVersion:  goruntime.Version(),
+			Compiler:   goruntime.Compiler,
+			Platform:   fmt.Sprintf("%s/%s", goruntime.GOOS, goruntime.GOARCH),
+		},
+		SchemaVersion: storage.SchemaVersion,
+	}
+	if v, err := utilversion.ParseGeneric(version.Version); err == nil {
+		resp.Major = fmt.Sprint(v.Major())
+		resp.Minor = fmt.Sprint(v.Minor())
+	}
+	info, err := clusterinfo.Get(s.store)
+	if err != nil {
+		return c.Status(storeErrorStatus(c, err, fiber.StatusInternalServerError)).JSON(fiber.Map{"error": err.Error()})
+	}
+	if info != nil {
+		resp.Cluster = &info.Spec
+		resp.Nodes = info.Status.Nodes
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// LastAppliedConfigAnnotation 记录上一次 apply 提交的配置（与 kubectl 相同），下一次 apply 据此判断哪些字段已从 manifest 中删除
+const LastAppliedConfigAnnotation = corev1.LastAppliedConfigAnnotation
+
+// ApplyResult 是一次 apply 的结果
+type ApplyResult string
+
+const (
+	// ApplyCreated 对象不存在，已创建
+	ApplyCreated ApplyResult = "created"
+	// ApplyConfigured 对象已存在，已按三方合并更新
+	ApplyConfigured ApplyResult = "configured"
+	// ApplyUnchanged 对象已存在且与 manifest 一致，没有发出更新
+	ApplyUnchanged ApplyResult = "unchanged"
+)
+
+// Apply 以 kubectl apply 的语义提交 obj，collectionPath 为对象所在的资源集合路径（如 /api/v1/namespaces/default/pods）：
+//   - 提交的对象带有 last-applied-configuration 注解，内容为本次 manifest（不含该注解）
+//   - 对象不存在时创建；已存在时以（上次 apply 的配置、本次 manifest、当前对象）计算三方 strategic merge patch：
+//     manifest 中修改的字段更新，上次 apply 有、本次删除的字段从对象中删除，其他写入者设置的字段（status、控制器写入的注解等）保留
+//
+// 当前对象没有注解（不是通过 apply 创建的）时没有可删除的字段，只更新 manifest 中的字段
+func (c *Clientset) Apply(ctx context.Context, collectionPath string, obj runtime.Object) (ApplyResult, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", fmt.Errorf("apply: %w", err)
+	}
+	name := accessor.GetName()
+	if name == "" {
+		return "", fmt.Errorf("apply: metadata.name is required")
+	}
+	modified, err := ApplyConfiguration(obj)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.rest.do(ctx, http.MethodPost, collectionPath, nil, "application/json", modified, nil)
+	if err == nil {
+		return ApplyCreated, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+
+	itemPath := strings.TrimRight(collectionPath, "/") + "/" + name
+	var current json.RawMessage
+	if err := c.rest.do(ctx, http.MethodGet, itemPath, nil, "", nil, &current); err != nil {
+		return "", err
+	}
+	patch, err := ThreeWayApplyPatch(obj, current, modified)
+	if err != nil {
+		return "", err
+	}
+	if string(patch) == "{}" {
+		return ApplyUnchanged, nil
+	}
+	if err := c.rest.do(ctx, http.MethodPatch, itemPath, nil, string(types.StrategicMergePatchType), patch, nil); err != nil {
+		return "", err
+	}
+	return ApplyConfigured, nil
+}
+
+// ApplyConfiguration 返回 apply 提交的对象 JSON：去掉序列化类型化对象时产生的 null 与空对象（如 creationTimestamp: null、status: {}），
+// 并把同样内容（不含注解本身）写入 last-applied-configuration 注解
+func ApplyConfiguration(obj runtime.Object) ([]byte, error) {
+	config, err := lastAppliedConfig(obj)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(config, &m); err != nil {
+		return nil, fmt.Errorf("apply: %w", err)
+	}
+	metadata, _ := m["metadata"].(map[string]any)
+	if metadata == nil {
+		metadata = map[string]any{}
+		m["metadata"] = metadata
+	}
+	annotations, _ := metadata["annotations"].(map[string]any)
+	if annotations == nil {
+		annotations = map[string]any{}
+		metadata["annotations"] = annotations
+	}
+	annotations[LastAppliedConfigAnnotation] = string(config)
+	return json.Marshal(m)
+}
+
+// lastAppliedConfig 返回写入注解的配置：manifest 去掉 null、空对象与已有的 last-applied-configuration 注解
+func lastAppliedConfig(obj runtime.Object) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("apply: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("apply: %w", err)
+	}
+	if metadata, ok := m["metadata"].(map[string]any); ok {
+		if annotations, ok := metadata["annotations"].(map[string]any); ok {
+			delete(annotations, LastAppliedConfigAnnotation)
+		}
+	}
+	pruneEmpty(m)
+	return json.Marshal(m)
+}
+
+// pruneEmpty 递归删除值为 null 或空对象的字段；它们来自类型化对象的零值，不是 manifest 中写的内容，
+// 留在配置里会在三方合并时被当作“删除该字段”
+func pruneEmpty(m map[string]any) {
+	for k, v := range m {
+		switch val := v.(type) {
+		case nil:
+			delete(m, k)
+		case map[string]any:
+			pruneEmpty(val)
+			if len(val) == 0 {
+				delete(m, k)
+			}
+		case []any:
+			for _, item := range val {
+				if im, ok := item.(map[string]any); ok {
+					pruneEmpty(im)
+				}
+			}
+		}
+	}
+}
+
+// ThreeWayApplyPatch 计算把 current（当前对象的 JSON）更新为 modified（ApplyConfiguration 的结果）的 strategic merge patch，
+// original 取自 current 的 last-applied-configuration 注解；obj 为对象的 Go 类型，决定列表按哪个字段合并（如容器按 name）。
+// 没有变化时返回 {}
+func ThreeWayApplyPatch(obj runtime.Object, current, modified []byte) ([]byte, error) {
+	var live struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(current, &live); err != nil {
+		return nil, fmt.Errorf("apply: decode current object: %w", err)
+	}
+	var original []byte
+	if v := live.Metadata.Annotations[LastAppliedConfigAnnotation]; v != "" {
+		original = []byte(v)
+	}
+	schema, err := strategicpatch.NewPatchMetaFromStruct(obj)
+	if err != nil {
+		return nil, fmt.Errorf("apply: %w", err)
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, schema, true)
+	if err != nil {
+		return nil, fmt.Errorf("apply: compute patch: %w", err)
+	}
+	return patch, nil
+}
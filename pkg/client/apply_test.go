@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func parseOne(t *testing.T, manifest string) runtime.Object {
+	t.Helper()
+	objects, _, err := parser.NewParser().ParseYAMLManifest([]byte(manifest))
+	if err != nil || len(objects) != 1 {
+		t.Fatalf("parse manifest: %v (%d objects)", err, len(objects))
+	}
+	return objects[0]
+}
+
+func TestClient_ApplyThreeWayMerge(t *testing.T) {
+	cs := newTestClient(t)
+	ctx := context.Background()
+	const path = "/api/v1/namespaces/default/configmaps"
+
+	v1 := parseOne(t, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  namespace: default
+  labels:
+    app: web
+    tier: frontend
+data:
+  a: "1"
+  b: "2"
+`)
+	if res, err := cs.Apply(ctx, path, v1); err != nil || res != ApplyCreated {
+		t.Fatalf("first apply: %v %v", res, err)
+	}
+	if res, err := cs.Apply(ctx, path, v1); err != nil || res != ApplyUnchanged {
+		t.Fatalf("re-apply: %v %v", res, err)
+	}
+
+	// 其他写入者加的标签不在 manifest 中，apply 时保留
+	cm, err := cs.CoreV1().ConfigMaps("default").Get(ctx, "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if cm.Annotations[LastAppliedConfigAnnotation] == "" {
+		t.Fatalf("expected last-applied annotation, got %v", cm.Annotations)
+	}
+	cm.Labels["owner"] = "controller"
+	if _, err := cs.CoreV1().ConfigMaps("default").Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// 从 manifest 中删除 b 与 tier，修改 a
+	v2 := parseOne(t, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  namespace: default
+  labels:
+    app: web
+data:
+  a: "10"
+`)
+	if res, err := cs.Apply(ctx, path, v2); err != nil || res != ApplyConfigured {
+		t.Fatalf("second apply: %v %v", res, err)
+	}
+	cm, err = cs.CoreV1().ConfigMaps("default").Get(ctx, "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(cm.Data) != 1 || cm.Data["a"] != "10" {
+		t.Fatalf("removed key should be gone: %v", cm.Data)
+	}
+	if _, ok := cm.Labels["tier"]; ok || cm.Labels["owner"] != "controller" || cm.Labels["app"] != "web" {
+		t.Fatalf("unexpected labels: %v", cm.Labels)
+	}
+}
+
+func TestClient_ApplyRemovesContainerFields(t *testing.T) {
+	cs := newTestClient(t)
+	ctx := context.Background()
+	const path = "/api/v1/namespaces/default/pods"
+
+	if _, err := cs.Apply(ctx, path, parseOne(t, `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: default
+spec:
+  containers:
+  - name: app
+    image: nginx:1.25
+    env:
+    - name: A
+      value: "1"
+    - name: B
+      value: "2"
+  - name: sidecar
+    image: busybox:1.36
+`)); err != nil {
+		t.Fatalf("first apply: %v", err)
+	}
+	if _, err := cs.Apply(ctx, path, parseOne(t, `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: default
+spec:
+  containers:
+  - name: app
+    image: nginx:1.25
+    env:
+    - name: A
+      value: "1"
+`)); err != nil {
+		t.Fatalf("second apply: %v", err)
+	}
+
+	pod, err := cs.CoreV1().Pods("default").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(pod.Spec.Containers) != 1 || pod.Spec.Containers[0].Name != "app" {
+		t.Fatalf("sidecar should be removed: %+v", pod.Spec.Containers)
+	}
+	if env := pod.Spec.Containers[0].Env; len(env) != 1 || env[0] != (corev1.EnvVar{Name: "A", Value: "1"}) {
+		t.Fatalf("env B should be removed: %+v", env)
+	}
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Config 是访问 k3 API server 的客户端配置
+type Config struct {
+	// Host 是 API server 地址，例如 http://127.0.0.1:8080
+	Host string
+	// Timeout 是普通请求（非 watch）的超时时间，0 表示使用默认值
+	Timeout time.Duration
+	// HTTPClient 可选，自定义底层 http.Client（watch 请求同样使用它）
+	HTTPClient *http.Client
+}
+
+// Interface 是 Clientset 暴露的接口，便于在测试中替换
+type Interface interface {
+	CoreV1() CoreV1Interface
+	AppsV1() AppsV1Interface
+}
+
+// Clientset 是 k3 的类型化客户端集合（对齐 client-go 的 kubernetes.Clientset 用法）
+type Clientset struct {
+	rest *restClient
+}
+
+var _ Interface = (*Clientset)(nil)
+
+// NewForConfig 根据配置创建 Clientset
+func NewForConfig(cfg *Config) (*Clientset, error) {
+	if cfg == nil || strings.TrimSpace(cfg.Host) == "" {
+		return nil, fmt.Errorf("client config host is required")
+	}
+	base, err := url.Parse(strings.TrimRight(strings.TrimSpace(cfg.Host), "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host %q: %w", cfg.Host, err)
+	}
+	if base.Scheme == "" {
+		return nil, fmt.Errorf("invalid host %q: scheme is required", cfg.Host)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	return &Clientset{rest: &restClient{base: base, http: httpClient, timeout: timeout}}, nil
+}
+
+// CoreV1 返回 core/v1 资源客户端
+func (c *Clientset) CoreV1() CoreV1Interface {
+	return &coreV1Client{rest: c.rest}
+}
+
+// AppsV1 返回 apps/v1 资源客户端
+func (c *Clientset) AppsV1() AppsV1Interface {
+	return &appsV1Client{rest: c.rest}
+}
+
+// restClient 负责拼接路径、发送 JSON 请求并把错误转换成 apierrors.StatusError
+type restClient struct {
+	base    *url.URL
+	http    *http.Client
+	timeout time.Duration
+}
+
+// resourcePath 生成资源路径：
+// - core:    /api/v1[/namespaces/<ns>]/<resource>[/<name>]
+// - grouped: /apis/<group>/<version>[/namespaces/<ns>]/<resource>[/<name>]
+func resourcePath(gv schema.GroupVersion, resource, namespace, name string, watch bool) string {
+	var b strings.Builder
+	if gv.Group == "" {
+		b.WriteString("/api/" + gv.Version)
+	} else {
+		b.WriteString("/apis/" + gv.Group + "/" + gv.Version)
+	}
+	if watch {
+		b.WriteString("/watch")
+	}
+	if namespace != "" {
+		b.WriteString("/namespaces/" + url.PathEscape(namespace))
+	}
+	b.WriteString("/" + resource)
+	if name != "" {
+		b.WriteString("/" + url.PathEscape(name))
+	}
+	return b.String()
+}
+
+func (r *restClient) url(path string, query url.Values) string {
+	u := *r.base
+	u.Path = strings.TrimRight(u.Path, "/") + path
+	if len(query) > 0 {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// do 发送请求并把响应体解码到 out（out 为 nil 时丢弃响应体）
+func (r *restClient) do(ctx context.Context, method, path string, query url.Values, contentType string, body any, out any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		switch v := body.(type) {
+		case []byte:
+			reader = bytes.NewReader(v)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("failed to marshal request body: %w", err)
+			}
+			reader = bytes.NewReader(data)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.url(path, query), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newStatusError(method, resp.StatusCode, data)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// newStatusError 把 k3 的 {"error": "..."} 响应转换成 apierrors.StatusError，
+// 以便调用方继续使用 apierrors.IsNotFound / IsAlreadyExists 等判断
+func newStatusError(method string, code int, body []byte) error {
+	message := strings.TrimSpace(string(body))
+
+	// 优先识别标准的 metav1.Status
+	var status metav1.Status
+	if err := json.Unmarshal(body, &status); err == nil && status.Kind == "Status" && status.Reason != "" {
+		if status.Code == 0 {
+			status.Code = int32(code)
+		}
+		return &apierrors.StatusError{ErrStatus: status}
+	}
+
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != "" {
+		message = payload.Error
+	}
+
+	reason := metav1.StatusReasonUnknown
+	switch code {
+	case http.StatusBadRequest:
+		reason = metav1.StatusReasonBadRequest
+	case http.StatusUnauthorized:
+		reason = metav1.StatusReasonUnauthorized
+	case http.StatusForbidden:
+		reason = metav1.StatusReasonForbidden
+	case http.StatusNotFound:
+		reason = metav1.StatusReasonNotFound
+	case http.StatusConflict:
+		if method == http.MethodPost {
+			reason = metav1.StatusReasonAlreadyExists
+		} else {
+			reason = metav1.StatusReasonConflict
+		}
+	case http.StatusUnsupportedMediaType:
+		reason = metav1.StatusReasonUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		reason = metav1.StatusReasonInvalid
+	case http.StatusTooManyRequests:
+		reason = metav1.StatusReasonTooManyRequests
+	case http.StatusServiceUnavailable:
+		reason = metav1.StatusReasonServiceUnavailable
+	default:
+		if code >= 500 {
+			reason = metav1.StatusReasonInternalError
+		}
+	}
+
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    int32(code),
+		Reason:  reason,
+		Message: message,
+	}}
+}
@@ -0,0 +1,316 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/webprovider"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apiserver"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	"github.com/gofiber/fiber/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// newTestClient 启动一个基于 MemoryStore 的 k3 API server，并返回指向它的 Clientset
+func newTestClient(t *testing.T) *Clientset {
+	t.Helper()
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	apiserver.RegisterRoutes(webprovider.FiberEngine{App: app, Api: app}, storage.NewMemoryStore())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.ShutdownWithTimeout(time.Second) })
+
+	cs, err := NewForConfig(&Config{Host: "http://" + ln.Addr().String(), Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("NewForConfig: %v", err)
+	}
+	return cs
+}
+
+func TestClient_PodCRUD(t *testing.T) {
+	cs := newTestClient(t)
+	ctx := context.Background()
+	pods := cs.CoreV1().Pods("default")
+
+	created, err := pods.Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p1"}}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Namespace != "default" || created.UID == "" {
+		t.Fatalf("unexpected created pod: ns=%q uid=%q", created.Namespace, created.UID)
+	}
+
+	if _, err := pods.Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p1"}}, metav1.CreateOptions{}); !apierrors.IsAlreadyExists(err) {
+		t.Fatalf("expected AlreadyExists, got %v", err)
+	}
+
+	got, err := pods.Get(ctx, "p1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got.Labels = map[string]string{"app": "web"}
+	if _, err := pods.Update(ctx, got, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	patched, err := pods.Patch(ctx, "p1", types.MergePatchType, []byte(`{"metadata":{"labels":{"tier":"fe"}}}`), metav1.PatchOptions{})
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if patched.Labels["app"] != "web" || patched.Labels["tier"] != "fe" {
+		t.Fatalf("unexpected labels after patch: %v", patched.Labels)
+	}
+
+	patched, err = pods.Patch(ctx, "p1", types.JSONPatchType,
+		[]byte(`[{"op":"test","path":"/metadata/labels/tier","value":"fe"},{"op":"remove","path":"/metadata/labels/app"}]`), metav1.PatchOptions{})
+	if err != nil {
+		t.Fatalf("json patch: %v", err)
+	}
+	if _, ok := patched.Labels["app"]; ok || patched.Labels["tier"] != "fe" {
+		t.Fatalf("unexpected labels after json patch: %v", patched.Labels)
+	}
+
+	list, err := pods.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "p1" {
+		t.Fatalf("unexpected list: %+v", list.Items)
+	}
+
+	if err := pods.Delete(ctx, "p1", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := pods.Get(ctx, "p1", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
+
+func TestClient_DeleteCollection(t *testing.T) {
+	cs := newTestClient(t)
+	ctx := context.Background()
+	pods := cs.CoreV1().Pods("default")
+
+	for name, app := range map[string]string{"web-1": "web", "web-2": "web", "db-1": "db"} {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": app}}}
+		if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+
+	// dryRun 不删除任何对象
+	if err := pods.DeleteCollection(ctx, metav1.DeleteOptions{DryRun: []string{metav1.DryRunAll}}, metav1.ListOptions{LabelSelector: "app=web"}); err != nil {
+		t.Fatalf("deletecollection dryRun: %v", err)
+	}
+	if list, _ := pods.List(ctx, metav1.ListOptions{}); len(list.Items) != 3 {
+		t.Fatalf("dryRun should keep all pods, got %d", len(list.Items))
+	}
+
+	if err := pods.DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: "app=web"}); err != nil {
+		t.Fatalf("deletecollection: %v", err)
+	}
+	list, err := pods.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "db-1" {
+		t.Fatalf("unexpected pods after deletecollection: %+v", list.Items)
+	}
+}
+
+func TestClient_Defaulting(t *testing.T) {
+	cs := newTestClient(t)
+	ctx := context.Background()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p1"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "app",
+			Image: "nginx",
+			Ports: []corev1.ContainerPort{{ContainerPort: 80}},
+		}}},
+	}
+	created, err := cs.CoreV1().Pods("default").Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create pod: %v", err)
+	}
+	c := created.Spec.Containers[0]
+	if created.Spec.RestartPolicy != corev1.RestartPolicyAlways ||
+		created.Spec.TerminationGracePeriodSeconds == nil || *created.Spec.TerminationGracePeriodSeconds != 30 ||
+		c.Ports[0].Protocol != corev1.ProtocolTCP || c.ImagePullPolicy != corev1.PullAlways {
+		t.Fatalf("pod not defaulted: %+v", created.Spec)
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}},
+			},
+		}},
+	}
+	createdDeployment, err := cs.AppsV1().Deployments("default").Create(ctx, deployment, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create deployment: %v", err)
+	}
+	spec := createdDeployment.Spec
+	if spec.Replicas == nil || *spec.Replicas != 1 || spec.Strategy.RollingUpdate == nil ||
+		spec.Template.Spec.Containers[0].ImagePullPolicy != corev1.PullIfNotPresent ||
+		spec.Selector == nil || spec.Selector.MatchLabels["app"] != "web" {
+		t.Fatalf("deployment not defaulted: %+v", spec)
+	}
+
+	// 更新时去掉的默认字段会重新填充
+	createdDeployment.Spec.Replicas = nil
+	updated, err := cs.AppsV1().Deployments("default").Update(ctx, createdDeployment, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("update deployment: %v", err)
+	}
+	if updated.Spec.Replicas == nil || *updated.Spec.Replicas != 1 {
+		t.Fatalf("replicas not defaulted on update: %v", updated.Spec.Replicas)
+	}
+}
+
+func TestClient_Watch(t *testing.T) {
+	cs := newTestClient(t)
+	ctx := context.Background()
+	deployments := cs.AppsV1().Deployments("default")
+
+	w, err := deployments.Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer w.Stop()
+
+	d1 := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "d1"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "d1"}},
+		}},
+	}
+	if _, err := deployments.Create(ctx, d1, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	select {
+	case ev, ok := <-w.ResultChan():
+		if !ok {
+			t.Fatalf("watch closed unexpectedly")
+		}
+		d, isDeployment := ev.Object.(*appsv1.Deployment)
+		if ev.Type != watch.Added || !isDeployment || d.Name != "d1" {
+			t.Fatalf("unexpected event: type=%s object=%T", ev.Type, ev.Object)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for watch event")
+	}
+}
+
+func TestClient_WatchLabelSelector(t *testing.T) {
+	cs := newTestClient(t)
+	ctx := context.Background()
+	configMaps := cs.CoreV1().ConfigMaps("default")
+
+	w, err := configMaps.Watch(ctx, metav1.ListOptions{LabelSelector: "app=web"})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer w.Stop()
+
+	next := func() watch.Event {
+		t.Helper()
+		select {
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				t.Fatalf("watch closed unexpectedly")
+			}
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for watch event")
+		}
+		return watch.Event{}
+	}
+	name := func(ev watch.Event) string { return ev.Object.(*corev1.ConfigMap).Name }
+
+	// 不满足 selector 的对象不推送
+	if _, err := configMaps.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "db", Labels: map[string]string{"app": "db"}}}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create db: %v", err)
+	}
+	web, err := configMaps.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: map[string]string{"app": "web"}}}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create web: %v", err)
+	}
+	if ev := next(); ev.Type != watch.Added || name(ev) != "web" {
+		t.Fatalf("expected ADDED web, got %s %s", ev.Type, name(ev))
+	}
+
+	// 离开选择范围时推送 DELETED
+	web.Labels["app"] = "other"
+	if _, err := configMaps.Update(ctx, web, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update web: %v", err)
+	}
+	if ev := next(); ev.Type != watch.Deleted || name(ev) != "web" {
+		t.Fatalf("expected DELETED web, got %s %s", ev.Type, name(ev))
+	}
+}
+
+func TestInformer_SyncAndEvents(t *testing.T) {
+	cs := newTestClient(t)
+	ctx := context.Background()
+	nodes := cs.CoreV1().Nodes()
+
+	if _, err := nodes.Create(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create n1: %v", err)
+	}
+
+	factory := NewInformerFactory(cs, 0)
+	informer := factory.Nodes()
+	added := make(chan string, 10)
+	informer.AddEventHandler(ResourceEventHandlerFuncs[*corev1.Node]{
+		AddFunc: func(n *corev1.Node) { added <- n.Name },
+	})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	for name, ok := range factory.WaitForCacheSync(stopCh) {
+		if !ok {
+			t.Fatalf("informer %s did not sync", name)
+		}
+	}
+
+	if _, ok := informer.Get("", "n1"); !ok {
+		t.Fatalf("expected n1 in cache")
+	}
+
+	if _, err := nodes.Create(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n2"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create n2: %v", err)
+	}
+
+	seen := map[string]bool{}
+	deadline := time.After(5 * time.Second)
+	for !seen["n1"] || !seen["n2"] {
+		select {
+		case name := <-added:
+			seen[name] = true
+		case <-deadline:
+			t.Fatalf("timed out waiting for add events, seen=%v", seen)
+		}
+	}
+	if got := len(informer.List()); got != 2 {
+		t.Fatalf("expected 2 cached nodes, got %d", got)
+	}
+}
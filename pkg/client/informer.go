@@ -0,0 +1,410 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ResourceEventHandlerFuncs 是 informer 的事件回调（对齐 client-go 的 cache.ResourceEventHandlerFuncs，
+// 但使用具体类型，免去类型断言）
+type ResourceEventHandlerFuncs[T Object] struct {
+	AddFunc    func(obj T)
+	UpdateFunc func(oldObj, newObj T)
+	DeleteFunc func(obj T)
+}
+
+// Informer 维护某一资源类型的本地缓存：建立 Watch 后 List 全量，再消费增量事件；
+// watch 断开后会重新 List 并对比缓存补发事件
+type Informer[T Object, L any] struct {
+	client ResourceInterface[T, L]
+	resync time.Duration
+
+	mu       sync.RWMutex
+	items    map[string]T
+	handlers []ResourceEventHandlerFuncs[T]
+
+	synced  atomic.Bool
+	started atomic.Bool
+}
+
+// NewInformer 基于单个资源客户端创建 informer；resync 为 0 表示不做周期性重新 List
+func NewInformer[T Object, L any](client ResourceInterface[T, L], resync time.Duration) *Informer[T, L] {
+	return &Informer[T, L]{
+		client: client,
+		resync: resync,
+		items:  make(map[string]T),
+	}
+}
+
+// AddEventHandler 注册事件回调；若缓存已同步，会先为现有对象补发 Add 事件
+func (i *Informer[T, L]) AddEventHandler(h ResourceEventHandlerFuncs[T]) {
+	i.mu.Lock()
+	i.handlers = append(i.handlers, h)
+	var existing []T
+	if i.synced.Load() {
+		existing = i.sortedLocked()
+	}
+	i.mu.Unlock()
+
+	if h.AddFunc != nil {
+		for _, obj := range existing {
+			h.AddFunc(obj)
+		}
+	}
+}
+
+// HasSynced 返回首次 List 是否已经完成
+func (i *Informer[T, L]) HasSynced() bool {
+	return i.synced.Load()
+}
+
+// List 返回缓存中的全部对象（按 namespace/name 排序）
+func (i *Informer[T, L]) List() []T {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.sortedLocked()
+}
+
+// Get 从缓存中获取对象；集群级资源 namespace 传空字符串
+func (i *Informer[T, L]) Get(namespace, name string) (T, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	obj, ok := i.items[cacheKey(namespace, name)]
+	return obj, ok
+}
+
+// Run 启动 list/watch 循环，直到 stopCh 关闭
+func (i *Informer[T, L]) Run(stopCh <-chan struct{}) {
+	if !i.started.CompareAndSwap(false, true) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	backoff := time.Second
+	for {
+		if err := i.listAndWatch(ctx); err == nil {
+			backoff = time.Second
+		} else if backoff < 30*time.Second {
+			backoff *= 2
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (i *Informer[T, L]) listAndWatch(ctx context.Context) error {
+	opts := metav1.ListOptions{}
+	if i.resync > 0 {
+		seconds := int64(i.resync / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		opts.TimeoutSeconds = &seconds
+	}
+	// 先建立 watch 再 List：k3 的 watch 不支持按 resourceVersion 续传，
+	// 这样 List 期间发生的变更不会丢失（最多表现为一次多余的 Update）
+	w, err := i.client.Watch(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	list, err := i.client.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	items, err := extractItems[T](list)
+	if err != nil {
+		return err
+	}
+	i.replace(items)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return nil
+			}
+			obj, ok := event.Object.(T)
+			if !ok {
+				continue
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				i.upsert(obj)
+			case watch.Deleted:
+				i.remove(obj)
+			}
+		}
+	}
+}
+
+// replace 用一次全量 List 的结果替换缓存，并按差异分发事件
+func (i *Informer[T, L]) replace(items []T) {
+	next := make(map[string]T, len(items))
+	for _, obj := range items {
+		next[objectKey(obj)] = obj
+	}
+
+	i.mu.Lock()
+	prev := i.items
+	i.items = next
+	handlers := append([]ResourceEventHandlerFuncs[T](nil), i.handlers...)
+	i.mu.Unlock()
+	i.synced.Store(true)
+
+	for _, key := range sortedKeys(next) {
+		obj := next[key]
+		if old, ok := prev[key]; ok {
+			dispatchUpdate(handlers, old, obj)
+		} else {
+			dispatchAdd(handlers, obj)
+		}
+	}
+	for _, key := range sortedKeys(prev) {
+		if _, ok := next[key]; !ok {
+			dispatchDelete(handlers, prev[key])
+		}
+	}
+}
+
+func (i *Informer[T, L]) upsert(obj T) {
+	key := objectKey(obj)
+	i.mu.Lock()
+	old, exists := i.items[key]
+	i.items[key] = obj
+	handlers := append([]ResourceEventHandlerFuncs[T](nil), i.handlers...)
+	i.mu.Unlock()
+
+	if exists {
+		dispatchUpdate(handlers, old, obj)
+	} else {
+		dispatchAdd(handlers, obj)
+	}
+}
+
+func (i *Informer[T, L]) remove(obj T) {
+	key := objectKey(obj)
+	i.mu.Lock()
+	old, exists := i.items[key]
+	delete(i.items, key)
+	handlers := append([]ResourceEventHandlerFuncs[T](nil), i.handlers...)
+	i.mu.Unlock()
+
+	if exists {
+		obj = old
+	}
+	dispatchDelete(handlers, obj)
+}
+
+func (i *Informer[T, L]) sortedLocked() []T {
+	out := make([]T, 0, len(i.items))
+	for _, key := range sortedKeys(i.items) {
+		out = append(out, i.items[key])
+	}
+	return out
+}
+
+func dispatchAdd[T Object](handlers []ResourceEventHandlerFuncs[T], obj T) {
+	for _, h := range handlers {
+		if h.AddFunc != nil {
+			h.AddFunc(obj)
+		}
+	}
+}
+
+func dispatchUpdate[T Object](handlers []ResourceEventHandlerFuncs[T], oldObj, newObj T) {
+	for _, h := range handlers {
+		if h.UpdateFunc != nil {
+			h.UpdateFunc(oldObj, newObj)
+		}
+	}
+}
+
+func dispatchDelete[T Object](handlers []ResourceEventHandlerFuncs[T], obj T) {
+	for _, h := range handlers {
+		if h.DeleteFunc != nil {
+			h.DeleteFunc(obj)
+		}
+	}
+}
+
+func extractItems[T Object](list any) ([]T, error) {
+	listObj, ok := list.(runtime.Object)
+	if !ok {
+		return nil, nil
+	}
+	objs, err := meta.ExtractList(listObj)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]T, 0, len(objs))
+	for _, o := range objs {
+		if obj, ok := o.(T); ok {
+			out = append(out, obj)
+		}
+	}
+	return out, nil
+}
+
+func objectKey(obj metav1.Object) string {
+	return cacheKey(obj.GetNamespace(), obj.GetName())
+}
+
+func cacheKey(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// informerRunner 是 factory 管理 informer 所需的最小接口
+type informerRunner interface {
+	Run(stopCh <-chan struct{})
+	HasSynced() bool
+}
+
+// InformerFactory 按资源类型复用 informer（对齐 client-go 的 SharedInformerFactory 用法）
+type InformerFactory struct {
+	client    Interface
+	namespace string
+	resync    time.Duration
+
+	mu        sync.Mutex
+	informers map[string]informerRunner
+	started   map[string]bool
+}
+
+// NewInformerFactory 创建覆盖所有 namespace 的 informer factory
+func NewInformerFactory(client Interface, resync time.Duration) *InformerFactory {
+	return NewFilteredInformerFactory(client, "", resync)
+}
+
+// NewFilteredInformerFactory 创建只关注指定 namespace 的 informer factory（集群级资源不受影响）
+func NewFilteredInformerFactory(client Interface, namespace string, resync time.Duration) *InformerFactory {
+	return &InformerFactory{
+		client:    client,
+		namespace: namespace,
+		resync:    resync,
+		informers: make(map[string]informerRunner),
+		started:   make(map[string]bool),
+	}
+}
+
+// Start 启动所有已创建但尚未运行的 informer
+func (f *InformerFactory) Start(stopCh <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, inf := range f.informers {
+		if f.started[key] {
+			continue
+		}
+		f.started[key] = true
+		go inf.Run(stopCh)
+	}
+}
+
+// WaitForCacheSync 等待所有已启动的 informer 完成首次同步，返回每个 informer 的同步结果
+func (f *InformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[string]bool {
+	f.mu.Lock()
+	pending := make(map[string]informerRunner, len(f.started))
+	for key := range f.started {
+		pending[key] = f.informers[key]
+	}
+	f.mu.Unlock()
+
+	result := make(map[string]bool, len(pending))
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		done := true
+		for key, inf := range pending {
+			result[key] = inf.HasSynced()
+			if !result[key] {
+				done = false
+			}
+		}
+		if done {
+			return result
+		}
+		select {
+		case <-stopCh:
+			return result
+		case <-ticker.C:
+		}
+	}
+}
+
+func informerFor[T Object, L any](f *InformerFactory, key string, client ResourceInterface[T, L]) *Informer[T, L] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if inf, ok := f.informers[key]; ok {
+		return inf.(*Informer[T, L])
+	}
+	inf := NewInformer(client, f.resync)
+	f.informers[key] = inf
+	return inf
+}
+
+func (f *InformerFactory) Pods() *Informer[*corev1.Pod, *corev1.PodList] {
+	return informerFor(f, "pods", f.client.CoreV1().Pods(f.namespace))
+}
+
+func (f *InformerFactory) Services() *Informer[*corev1.Service, *corev1.ServiceList] {
+	return informerFor(f, "services", f.client.CoreV1().Services(f.namespace))
+}
+
+func (f *InformerFactory) ConfigMaps() *Informer[*corev1.ConfigMap, *corev1.ConfigMapList] {
+	return informerFor(f, "configmaps", f.client.CoreV1().ConfigMaps(f.namespace))
+}
+
+func (f *InformerFactory) Secrets() *Informer[*corev1.Secret, *corev1.SecretList] {
+	return informerFor(f, "secrets", f.client.CoreV1().Secrets(f.namespace))
+}
+
+func (f *InformerFactory) Nodes() *Informer[*corev1.Node, *corev1.NodeList] {
+	return informerFor(f, "nodes", f.client.CoreV1().Nodes())
+}
+
+func (f *InformerFactory) Deployments() *Informer[*appsv1.Deployment, *appsv1.DeploymentList] {
+	return informerFor(f, "deployments", f.client.AppsV1().Deployments(f.namespace))
+}
+
+func (f *InformerFactory) StatefulSets() *Informer[*appsv1.StatefulSet, *appsv1.StatefulSetList] {
+	return informerFor(f, "statefulsets", f.client.AppsV1().StatefulSets(f.namespace))
+}
+
+func (f *InformerFactory) DaemonSets() *Informer[*appsv1.DaemonSet, *appsv1.DaemonSetList] {
+	return informerFor(f, "daemonsets", f.client.AppsV1().DaemonSets(f.namespace))
+}
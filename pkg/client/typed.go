@@ -0,0 +1,471 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Object 是类型化客户端可处理的资源对象（例如 *corev1.Pod）
+type Object interface {
+	runtime.Object
+	metav1.Object
+}
+
+// ResourceInterface 是单个资源类型的客户端接口，方法签名与 client-go 的 typed client 保持一致
+type ResourceInterface[T Object, L any] interface {
+	Create(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error)
+	Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	List(ctx context.Context, opts metav1.ListOptions) (L, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (T, error)
+}
+
+type (
+	PodInterface         = ResourceInterface[*corev1.Pod, *corev1.PodList]
+	ServiceInterface     = ResourceInterface[*corev1.Service, *corev1.ServiceList]
+	ConfigMapInterface   = ResourceInterface[*corev1.ConfigMap, *corev1.ConfigMapList]
+	SecretInterface      = ResourceInterface[*corev1.Secret, *corev1.SecretList]
+	NodeInterface        = ResourceInterface[*corev1.Node, *corev1.NodeList]
+	DeploymentInterface  = ResourceInterface[*appsv1.Deployment, *appsv1.DeploymentList]
+	StatefulSetInterface = ResourceInterface[*appsv1.StatefulSet, *appsv1.StatefulSetList]
+	DaemonSetInterface   = ResourceInterface[*appsv1.DaemonSet, *appsv1.DaemonSetList]
+)
+
+// CoreV1Interface 提供 core/v1 资源的客户端
+type CoreV1Interface interface {
+	Pods(namespace string) PodInterface
+	Services(namespace string) ServiceInterface
+	ConfigMaps(namespace string) ConfigMapInterface
+	Secrets(namespace string) SecretInterface
+	Nodes() NodeInterface
+}
+
+// AppsV1Interface 提供 apps/v1 资源的客户端
+type AppsV1Interface interface {
+	Deployments(namespace string) DeploymentInterface
+	StatefulSets(namespace string) StatefulSetInterface
+	DaemonSets(namespace string) DaemonSetInterface
+}
+
+var (
+	coreV1GV = schema.GroupVersion{Group: "", Version: "v1"}
+	appsV1GV = schema.GroupVersion{Group: "apps", Version: "v1"}
+)
+
+type coreV1Client struct {
+	rest *restClient
+}
+
+func (c *coreV1Client) Pods(namespace string) PodInterface {
+	return newResourceClient(c.rest, coreV1GV.WithKind("Pod"), "pods", namespace,
+		func() *corev1.Pod { return &corev1.Pod{} },
+		func(items []*corev1.Pod) *corev1.PodList {
+			list := &corev1.PodList{Items: make([]corev1.Pod, 0, len(items))}
+			for _, it := range items {
+				list.Items = append(list.Items, *it)
+			}
+			return list
+		})
+}
+
+func (c *coreV1Client) Services(namespace string) ServiceInterface {
+	return newResourceClient(c.rest, coreV1GV.WithKind("Service"), "services", namespace,
+		func() *corev1.Service { return &corev1.Service{} },
+		func(items []*corev1.Service) *corev1.ServiceList {
+			list := &corev1.ServiceList{Items: make([]corev1.Service, 0, len(items))}
+			for _, it := range items {
+				list.Items = append(list.Items, *it)
+			}
+			return list
+		})
+}
+
+func (c *coreV1Client) ConfigMaps(namespace string) ConfigMapInterface {
+	return newResourceClient(c.rest, coreV1GV.WithKind("ConfigMap"), "configmaps", namespace,
+		func() *corev1.ConfigMap { return &corev1.ConfigMap{} },
+		func(items []*corev1.ConfigMap) *corev1.ConfigMapList {
+			list := &corev1.ConfigMapList{Items: make([]corev1.ConfigMap, 0, len(items))}
+			for _, it := range items {
+				list.Items = append(list.Items, *it)
+			}
+			return list
+		})
+}
+
+func (c *coreV1Client) Secrets(namespace string) SecretInterface {
+	return newResourceClient(c.rest, coreV1GV.WithKind("Secret"), "secrets", namespace,
+		func() *corev1.Secret { return &corev1.Secret{} },
+		func(items []*corev1.Secret) *corev1.SecretList {
+			list := &corev1.SecretList{Items: make([]corev1.Secret, 0, len(items))}
+			for _, it := range items {
+				list.Items = append(list.Items, *it)
+			}
+			return list
+		})
+}
+
+func (c *coreV1Client) Nodes() NodeInterface {
+	return newResourceClient(c.rest, coreV1GV.WithKind("Node"), "nodes", "",
+		func() *corev1.Node { return &corev1.Node{} },
+		func(items []*corev1.Node) *corev1.NodeList {
+			list := &corev1.NodeList{Items: make([]corev1.Node, 0, len(items))}
+			for _, it := range items {
+				list.Items = append(list.Items, *it)
+			}
+			return list
+		})
+}
+
+type appsV1Client struct {
+	rest *restClient
+}
+
+func (c *appsV1Client) Deployments(namespace string) DeploymentInterface {
+	return newResourceClient(c.rest, appsV1GV.WithKind("Deployment"), "deployments", namespace,
+		func() *appsv1.Deployment { return &appsv1.Deployment{} },
+		func(items []*appsv1.Deployment) *appsv1.DeploymentList {
+			list := &appsv1.DeploymentList{Items: make([]appsv1.Deployment, 0, len(items))}
+			for _, it := range items {
+				list.Items = append(list.Items, *it)
+			}
+			return list
+		})
+}
+
+func (c *appsV1Client) StatefulSets(namespace string) StatefulSetInterface {
+	return newResourceClient(c.rest, appsV1GV.WithKind("StatefulSet"), "statefulsets", namespace,
+		func() *appsv1.StatefulSet { return &appsv1.StatefulSet{} },
+		func(items []*appsv1.StatefulSet) *appsv1.StatefulSetList {
+			list := &appsv1.StatefulSetList{Items: make([]appsv1.StatefulSet, 0, len(items))}
+			for _, it := range items {
+				list.Items = append(list.Items, *it)
+			}
+			return list
+		})
+}
+
+func (c *appsV1Client) DaemonSets(namespace string) DaemonSetInterface {
+	return newResourceClient(c.rest, appsV1GV.WithKind("DaemonSet"), "daemonsets", namespace,
+		func() *appsv1.DaemonSet { return &appsv1.DaemonSet{} },
+		func(items []*appsv1.DaemonSet) *appsv1.DaemonSetList {
+			list := &appsv1.DaemonSetList{Items: make([]appsv1.DaemonSet, 0, len(items))}
+			for _, it := range items {
+				list.Items = append(list.Items, *it)
+			}
+			return list
+		})
+}
+
+// resourceClient 是 ResourceInterface 的通用实现
+type resourceClient[T Object, L any] struct {
+	rest      *restClient
+	gvk       schema.GroupVersionKind
+	resource  string
+	namespace string
+	newObj    func() T
+	newList   func(items []T) L
+}
+
+func newResourceClient[T Object, L any](rest *restClient, gvk schema.GroupVersionKind, resource, namespace string, newObj func() T, newList func([]T) L) *resourceClient[T, L] {
+	return &resourceClient[T, L]{
+		rest:      rest,
+		gvk:       gvk,
+		resource:  resource,
+		namespace: namespace,
+		newObj:    newObj,
+		newList:   newList,
+	}
+}
+
+func (c *resourceClient[T, L]) path(namespace, name string, watch bool) string {
+	return resourcePath(c.gvk.GroupVersion(), c.resource, namespace, name, watch)
+}
+
+// namespaceFor 优先使用客户端绑定的 namespace，其次使用对象自身的 namespace
+func (c *resourceClient[T, L]) namespaceFor(obj T) string {
+	if c.namespace != "" {
+		return c.namespace
+	}
+	return obj.GetNamespace()
+}
+
+// prepare 补全 apiVersion/kind（k3 的 decoder 依赖它们识别类型）
+func (c *resourceClient[T, L]) prepare(obj T) {
+	obj.GetObjectKind().SetGroupVersionKind(c.gvk)
+	if c.namespace != "" && obj.GetNamespace() == "" {
+		obj.SetNamespace(c.namespace)
+	}
+}
+
+func (c *resourceClient[T, L]) Create(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error) {
+	c.prepare(obj)
+	out := c.newObj()
+	query := url.Values{}
+	if len(opts.DryRun) > 0 {
+		query["dryRun"] = opts.DryRun
+	}
+	if opts.FieldManager != "" {
+		query.Set("fieldManager", opts.FieldManager)
+	}
+	if err := c.rest.do(ctx, http.MethodPost, c.path(c.namespaceFor(obj), "", false), query, "", obj, out); err != nil {
+		var zero T
+		return zero, err
+	}
+	return out, nil
+}
+
+func (c *resourceClient[T, L]) Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error) {
+	c.prepare(obj)
+	out := c.newObj()
+	query := url.Values{}
+	if len(opts.DryRun) > 0 {
+		query["dryRun"] = opts.DryRun
+	}
+	if opts.FieldManager != "" {
+		query.Set("fieldManager", opts.FieldManager)
+	}
+	if err := c.rest.do(ctx, http.MethodPut, c.path(c.namespaceFor(obj), obj.GetName(), false), query, "", obj, out); err != nil {
+		var zero T
+		return zero, err
+	}
+	return out, nil
+}
+
+func (c *resourceClient[T, L]) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	query := url.Values{}
+	if len(opts.DryRun) > 0 {
+		query["dryRun"] = opts.DryRun
+	}
+	return c.rest.do(ctx, http.MethodDelete, c.path(c.namespace, name, false), query, "", nil, nil)
+}
+
+func (c *resourceClient[T, L]) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	query := url.Values{}
+	if listOpts.LabelSelector != "" {
+		query.Set("labelSelector", listOpts.LabelSelector)
+	}
+	if listOpts.FieldSelector != "" {
+		query.Set("fieldSelector", listOpts.FieldSelector)
+	}
+	if len(opts.DryRun) > 0 {
+		query["dryRun"] = opts.DryRun
+	}
+	return c.rest.do(ctx, http.MethodDelete, c.path(c.namespace, "", false), query, "", nil, nil)
+}
+
+func (c *resourceClient[T, L]) Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error) {
+	out := c.newObj()
+	query := url.Values{}
+	if opts.ResourceVersion != "" {
+		query.Set("resourceVersion", opts.ResourceVersion)
+	}
+	if err := c.rest.do(ctx, http.MethodGet, c.path(c.namespace, name, false), query, "", nil, out); err != nil {
+		var zero T
+		return zero, err
+	}
+	return out, nil
+}
+
+func (c *resourceClient[T, L]) List(ctx context.Context, opts metav1.ListOptions) (L, error) {
+	var raw struct {
+		Metadata metav1.ListMeta   `json:"metadata"`
+		Items    []json.RawMessage `json:"items"`
+	}
+	if err := c.rest.do(ctx, http.MethodGet, c.path(c.namespace, "", false), listQuery(opts), "", nil, &raw); err != nil {
+		var zero L
+		return zero, err
+	}
+
+	items := make([]T, 0, len(raw.Items))
+	for _, data := range raw.Items {
+		obj := c.newObj()
+		if err := json.Unmarshal(data, obj); err != nil {
+			var zero L
+			return zero, fmt.Errorf("failed to decode %s item: %w", c.gvk.Kind, err)
+		}
+		items = append(items, obj)
+	}
+
+	list := c.newList(items)
+	if lm, ok := any(list).(metav1.ListInterface); ok {
+		lm.SetResourceVersion(raw.Metadata.ResourceVersion)
+		lm.SetContinue(raw.Metadata.Continue)
+	}
+	return list, nil
+}
+
+func (c *resourceClient[T, L]) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (T, error) {
+	var zero T
+	switch pt {
+	case types.MergePatchType, types.StrategicMergePatchType, types.JSONPatchType:
+	default:
+		return zero, fmt.Errorf("unsupported patch type: %s", pt)
+	}
+
+	out := c.newObj()
+	query := url.Values{}
+	if len(opts.DryRun) > 0 {
+		query["dryRun"] = opts.DryRun
+	}
+	if opts.FieldManager != "" {
+		query.Set("fieldManager", opts.FieldManager)
+	}
+	if opts.Force != nil {
+		query.Set("force", strconv.FormatBool(*opts.Force))
+	}
+	// patch 类型即请求的 Content-Type，apiserver 据此选择合并方式
+	if err := c.rest.do(ctx, http.MethodPatch, c.path(c.namespace, name, false), query, string(pt), data, out); err != nil {
+		return zero, err
+	}
+	return out, nil
+}
+
+func (c *resourceClient[T, L]) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.rest.url(c.path(c.namespace, "", true), listQuery(opts)), nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.rest.http.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		defer cancel()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, newStatusError(http.MethodGet, resp.StatusCode, body)
+	}
+
+	w := &streamWatcher{
+		result:    make(chan watch.Event, 100),
+		done:      ctx.Done(),
+		cancel:    cancel,
+		bookmarks: opts.AllowWatchBookmarks,
+	}
+	go w.receive(resp, func() runtime.Object { return c.newObj() })
+	return w, nil
+}
+
+// listQuery 把 ListOptions 编码成查询参数
+func listQuery(opts metav1.ListOptions) url.Values {
+	query := url.Values{}
+	if opts.LabelSelector != "" {
+		query.Set("labelSelector", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		query.Set("fieldSelector", opts.FieldSelector)
+	}
+	if opts.ResourceVersion != "" {
+		query.Set("resourceVersion", opts.ResourceVersion)
+	}
+	if opts.TimeoutSeconds != nil {
+		query.Set("timeoutSeconds", strconv.FormatInt(*opts.TimeoutSeconds, 10))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(opts.Limit, 10))
+	}
+	if opts.Continue != "" {
+		query.Set("continue", opts.Continue)
+	}
+	if opts.AllowWatchBookmarks {
+		query.Set("allowWatchBookmarks", "true")
+	}
+	return query
+}
+
+// streamWatcher 把 k3 的 SSE watch 流（data: {"type":..., "object":...}）转换成 watch.Interface
+type streamWatcher struct {
+	result    chan watch.Event
+	done      <-chan struct{}
+	cancel    context.CancelFunc
+	bookmarks bool
+	stopOnce  sync.Once
+}
+
+func (w *streamWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *streamWatcher) Stop() {
+	w.stopOnce.Do(w.cancel)
+}
+
+func (w *streamWatcher) receive(resp *http.Response, newObj func() runtime.Object) {
+	defer close(w.result)
+	defer resp.Body.Close()
+	defer w.Stop()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			// 空行（事件分隔）与 ": keepalive" 之类的注释行直接忽略
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "" {
+			continue
+		}
+
+		var raw struct {
+			Type   watch.EventType `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+		if err := json.Unmarshal([]byte(payload), &raw); err != nil {
+			continue
+		}
+
+		var obj runtime.Object
+		switch raw.Type {
+		case watch.Bookmark:
+			if !w.bookmarks {
+				continue
+			}
+			obj = newObj()
+		case watch.Error:
+			obj = &metav1.Status{}
+		default:
+			obj = newObj()
+		}
+		if len(raw.Object) > 0 {
+			if err := json.Unmarshal(raw.Object, obj); err != nil {
+				continue
+			}
+		}
+
+		select {
+		case w.result <- watch.Event{Type: raw.Type, Object: obj}:
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package ctrlruntime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	// 导入 pkg/parser 把 k3.io/v1 注册到全局 scheme（与 Store、apiserver 使用同一个 scheme）
+	_ "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// storeClient 是直接读写 Store 的 controller-runtime client.Client：
+//   - 只支持类型化对象（scheme.Scheme 中注册的 Go 类型），不支持 unstructured 与 PartialObjectMetadata
+//   - Update 按 resourceVersion 做乐观并发检查（对象的 resourceVersion 与 Store 中不一致时返回 Conflict），
+//     并保留 Store 中的 status；status 只能通过 Status() 更新（与带 status 子资源的 Kubernetes 资源相同）
+//   - Delete 遵循 finalizers：对象还有 finalizer 时只设置 deletionTimestamp，最后一个 finalizer 被移除时才从 Store 删除
+//   - 写入不经过 apiserver 的准入与默认值（与 k3 内置控制器相同）
+type storeClient struct {
+	store storage.Store
+
+	mapperOnce sync.Once
+	mapper     meta.RESTMapper
+}
+
+// NewClient 创建读写 store 的 controller-runtime client.Client
+func NewClient(store storage.Store) client.Client {
+	return &storeClient{store: store}
+}
+
+// Scheme 返回 client 使用的 scheme（client-go 的全局 scheme，已注册 k3.io/v1）
+func (c *storeClient) Scheme() *runtime.Scheme {
+	return scheme.Scheme
+}
+
+// RESTMapper 返回按 scheme 中已注册类型生成的 RESTMapper，作用域与 Store 的集群级资源判断一致
+func (c *storeClient) RESTMapper() meta.RESTMapper {
+	c.mapperOnce.Do(func() {
+		m := meta.NewDefaultRESTMapper(scheme.Scheme.PreferredVersionAllGroups())
+		for gvk := range scheme.Scheme.AllKnownTypes() {
+			if gvk.Version == runtime.APIVersionInternal || strings.HasSuffix(gvk.Kind, "List") {
+				continue
+			}
+			scope := meta.RESTScopeNamespace
+			if storage.IsClusterScoped(gvk) {
+				scope = meta.RESTScopeRoot
+			}
+			m.Add(gvk, scope)
+		}
+		c.mapper = m
+	})
+	return c.mapper
+}
+
+// GroupVersionKindFor 返回对象的 GroupVersionKind
+func (c *storeClient) GroupVersionKindFor(obj runtime.Object) (schema.GroupVersionKind, error) {
+	return apiutil.GVKForObject(obj, scheme.Scheme)
+}
+
+// IsObjectNamespaced 判断对象是否为 namespace 级资源
+func (c *storeClient) IsObjectNamespaced(obj runtime.Object) (bool, error) {
+	gvk, err := c.GroupVersionKindFor(obj)
+	if err != nil {
+		return false, err
+	}
+	return !storage.IsClusterScoped(gvk), nil
+}
+
+// objectGVK 返回类型化对象的 GroupVersionKind 与 GroupResource（错误信息使用）
+func (c *storeClient) objectGVK(obj runtime.Object) (schema.GroupVersionKind, schema.GroupResource, error) {
+	switch obj.(type) {
+	case *unstructured.Unstructured, *unstructured.UnstructuredList, *metav1.PartialObjectMetadata, *metav1.PartialObjectMetadataList:
+		return schema.GroupVersionKind{}, schema.GroupResource{}, fmt.Errorf("ctrlruntime: %T is not supported, use typed objects", obj)
+	}
+	gvk, err := c.GroupVersionKindFor(obj)
+	if err != nil {
+		return schema.GroupVersionKind{}, schema.GroupResource{}, err
+	}
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	return gvk, groupResource(gvk), nil
+}
+
+// groupResource 按 kind 推导资源名（小写复数），只用于错误信息
+func groupResource(gvk schema.GroupVersionKind) schema.GroupResource {
+	plural, _ := meta.UnsafeGuessKindToResource(gvk)
+	return plural.GroupResource()
+}
+
+// namespaceFor 集群级资源不带 namespace，namespace 级资源未指定时为 default（与 Store 相同）
+func namespaceFor(gvk schema.GroupVersionKind, namespace string) string {
+	if storage.IsClusterScoped(gvk) {
+		return ""
+	}
+	if namespace == "" {
+		return metav1.NamespaceDefault
+	}
+	return namespace
+}
+
+// storeError 把 Store 返回的错误转换为 apierrors（IsNotFound、IsAlreadyExists 可以直接使用）
+func storeError(err error, gr schema.GroupResource, name string) error {
+	if err == nil {
+		return nil
+	}
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		return apierrors.NewNotFound(gr, name)
+	case strings.Contains(msg, "already exists"):
+		return apierrors.NewAlreadyExists(gr, name)
+	}
+	return err
+}
+
+// copyInto 把 src 的内容复制到 dst（同一 Go 类型），dst 的 TypeMeta 设置为 gvk
+func copyInto(dst, src runtime.Object, gvk schema.GroupVersionKind) error {
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Ptr || sv.Type() != dv.Type() {
+		if err := scheme.Scheme.Convert(src, dst, nil); err != nil {
+			return fmt.Errorf("ctrlruntime: cannot copy %T into %T: %w", src, dst, err)
+		}
+	} else {
+		dv.Elem().Set(reflect.ValueOf(src.DeepCopyObject()).Elem())
+	}
+	dst.GetObjectKind().SetGroupVersionKind(gvk)
+	return nil
+}
+
+// Get 读取 key 对应的对象到 obj
+func (c *storeClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	gvk, gr, err := c.objectGVK(obj)
+	if err != nil {
+		return err
+	}
+	stored, err := c.store.Get(gvk, namespaceFor(gvk, key.Namespace), key.Name)
+	if err != nil {
+		return storeError(err, gr, key.Name)
+	}
+	return copyInto(obj, stored, gvk)
+}
+
+// List 列出对象到 list，支持 namespace、标签选择器、metadata.name/metadata.namespace 字段选择器与 limit（不支持 continue）
+func (c *storeClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	gvk, _, err := c.objectGVK(list)
+	if err != nil {
+		return err
+	}
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+	namespace := options.Namespace
+	if storage.IsClusterScoped(gvk) {
+		namespace = ""
+	}
+	objects, err := c.store.ListBySelector(gvk, namespace, options.LabelSelector)
+	if err != nil {
+		return err
+	}
+	storage.SortObjects(objects)
+
+	items := make([]runtime.Object, 0, len(objects))
+	for _, obj := range objects {
+		if options.FieldSelector != nil && !options.FieldSelector.Empty() {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+			if !options.FieldSelector.Matches(fields.Set{"metadata.name": accessor.GetName(), "metadata.namespace": accessor.GetNamespace()}) {
+				continue
+			}
+		}
+		items = append(items, obj)
+		if options.Limit > 0 && int64(len(items)) >= options.Limit {
+			break
+		}
+	}
+	if err := meta.SetList(list, items); err != nil {
+		return err
+	}
+	list.GetObjectKind().SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	return nil
+}
+
+// Create 创建对象，obj 带回 Store 设置的 resourceVersion、uid 与 creationTimestamp
+func (c *storeClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	gvk, gr, err := c.objectGVK(obj)
+	if err != nil {
+		return err
+	}
+	options := (&client.CreateOptions{}).ApplyOptions(opts)
+	if obj.GetName() == "" && obj.GetGenerateName() != "" {
+		obj.SetName(obj.GetGenerateName() + utilrand.String(5))
+	}
+	if obj.GetName() == "" {
+		return apierrors.NewBadRequest("metadata.name is required")
+	}
+	if obj.GetResourceVersion() != "" {
+		return apierrors.NewBadRequest("resourceVersion should not be set on objects to be created")
+	}
+	if len(options.DryRun) > 0 {
+		return nil
+	}
+	obj.SetNamespace(namespaceFor(gvk, obj.GetNamespace()))
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	return storeError(c.store.Create(gvk, obj), gr, obj.GetName())
+}
+
+// current 读取 Store 中 obj 对应的对象
+func (c *storeClient) current(gvk schema.GroupVersionKind, gr schema.GroupResource, obj client.Object) (client.Object, error) {
+	stored, err := c.store.Get(gvk, namespaceFor(gvk, obj.GetNamespace()), obj.GetName())
+	if err != nil {
+		return nil, storeError(err, gr, obj.GetName())
+	}
+	cur, ok := stored.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("ctrlruntime: stored %T is not a client.Object", stored)
+	}
+	return cur, nil
+}
+
+// write 把 obj 写入 Store：resourceVersion 与 Store 中不一致时返回 Conflict；
+// 对象已被标记删除且 finalizers 已清空时从 Store 删除
+func (c *storeClient) write(gvk schema.GroupVersionKind, gr schema.GroupResource, cur, obj client.Object) error {
+	if rv := obj.GetResourceVersion(); rv != "" && rv != cur.GetResourceVersion() {
+		return apierrors.NewConflict(gr, obj.GetName(),
+			fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again"))
+	}
+	obj.SetNamespace(cur.GetNamespace())
+	obj.SetUID(cur.GetUID())
+	obj.SetCreationTimestamp(cur.GetCreationTimestamp())
+	if cur.GetDeletionTimestamp() != nil {
+		obj.SetDeletionTimestamp(cur.GetDeletionTimestamp())
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	if obj.GetDeletionTimestamp() != nil && len(obj.GetFinalizers()) == 0 {
+		return storeError(c.store.Delete(gvk, obj.GetNamespace(), obj.GetName()), gr, obj.GetName())
+	}
+	return storeError(c.store.Update(gvk, obj), gr, obj.GetName())
+}
+
+// Update 更新对象（保留 Store 中的 status）
+func (c *storeClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	gvk, gr, err := c.objectGVK(obj)
+	if err != nil {
+		return err
+	}
+	if len((&client.UpdateOptions{}).ApplyOptions(opts).DryRun) > 0 {
+		return nil
+	}
+	cur, err := c.current(gvk, gr, obj)
+	if err != nil {
+		return err
+	}
+	copyStatus(obj, cur)
+	return c.write(gvk, gr, cur, obj)
+}
+
+// Patch 按 merge、strategic merge 或 JSON patch 更新对象（不支持 server-side apply）
+func (c *storeClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.patch(ctx, obj, patch, false, (&client.PatchOptions{}).ApplyOptions(opts).DryRun)
+}
+
+// patch 读取当前对象，应用 patch 后写回；status 为 true 时只更新 status，否则保留 Store 中的 status
+func (c *storeClient) patch(ctx context.Context, obj client.Object, patch client.Patch, status bool, dryRun []string) error {
+	gvk, gr, err := c.objectGVK(obj)
+	if err != nil {
+		return err
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	cur, err := c.current(gvk, gr, obj)
+	if err != nil {
+		return err
+	}
+	original, err := json.Marshal(cur)
+	if err != nil {
+		return err
+	}
+	var patched []byte
+	switch patch.Type() {
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, data)
+	case types.StrategicMergePatchType:
+		patched, err = strategicpatch.StrategicMergePatch(original, data, cur)
+	case types.JSONPatchType:
+		var p jsonpatch.Patch
+		if p, err = jsonpatch.DecodePatch(data); err == nil {
+			patched, err = p.Apply(original)
+		}
+	default:
+		return apierrors.NewBadRequest(fmt.Sprintf("patch type %q is not supported", patch.Type()))
+	}
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	updated, ok := cur.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("ctrlruntime: %T is not a client.Object", cur)
+	}
+	reflect.ValueOf(updated).Elem().Set(reflect.Zero(reflect.TypeOf(updated).Elem()))
+	if err := json.Unmarshal(patched, updated); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	if status {
+		statusOnly := cur.DeepCopyObject().(client.Object)
+		copyStatus(statusOnly, updated)
+		updated = statusOnly
+	} else {
+		copyStatus(updated, cur)
+	}
+	// patch 不做乐观并发检查，除非 patch 中带有 resourceVersion（如 MergeFromWithOptimisticLock）
+	if updated.GetResourceVersion() == "" {
+		updated.SetResourceVersion(cur.GetResourceVersion())
+	}
+	if len(dryRun) == 0 {
+		if err := c.write(gvk, gr, cur, updated); err != nil {
+			return err
+		}
+	}
+	return copyInto(obj, updated, gvk)
+}
+
+// Apply 不支持 server-side apply
+func (c *storeClient) Apply(ctx context.Context, obj runtime.ApplyConfiguration, opts ...client.ApplyOption) error {
+	return apierrors.NewMethodNotSupported(schema.GroupResource{}, "apply")
+}
+
+// Delete 删除对象；对象有 finalizer 时只设置 deletionTimestamp
+func (c *storeClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	gvk, gr, err := c.objectGVK(obj)
+	if err != nil {
+		return err
+	}
+	options := (&client.DeleteOptions{}).ApplyOptions(opts)
+	cur, err := c.current(gvk, gr, obj)
+	if err != nil {
+		return err
+	}
+	if pre := options.Preconditions; pre != nil {
+		if pre.UID != nil && *pre.UID != cur.GetUID() {
+			return apierrors.NewConflict(gr, obj.GetName(), fmt.Errorf("precondition failed: UID in precondition: %v, UID in object meta: %v", *pre.UID, cur.GetUID()))
+		}
+		if pre.ResourceVersion != nil && *pre.ResourceVersion != cur.GetResourceVersion() {
+			return apierrors.NewConflict(gr, obj.GetName(), fmt.Errorf("precondition failed: ResourceVersion in precondition: %v, ResourceVersion in object meta: %v", *pre.ResourceVersion, cur.GetResourceVersion()))
+		}
+	}
+	if len(options.DryRun) > 0 {
+		return nil
+	}
+	if len(cur.GetFinalizers()) == 0 {
+		return storeError(c.store.Delete(gvk, cur.GetNamespace(), cur.GetName()), gr, obj.GetName())
+	}
+	if cur.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	now := metav1.NewTime(time.Now())
+	cur.SetDeletionTimestamp(&now)
+	return storeError(c.store.Update(gvk, cur), gr, obj.GetName())
+}
+
+// DeleteAllOf 删除 namespace 下满足标签选择器的所有对象（逐个按 Delete 处理 finalizer）
+func (c *storeClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	gvk, _, err := c.objectGVK(obj)
+	if err != nil {
+		return err
+	}
+	options := (&client.DeleteAllOfOptions{}).ApplyOptions(opts)
+	namespace := options.Namespace
+	if storage.IsClusterScoped(gvk) {
+		namespace = ""
+	}
+	selector := options.LabelSelector
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	objects, err := c.store.ListBySelector(gvk, namespace, selector)
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		target, ok := o.(client.Object)
+		if !ok {
+			continue
+		}
+		if err := c.Delete(ctx, target, &options.DeleteOptions); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status 返回更新 status 子资源的 writer
+func (c *storeClient) Status() client.SubResourceWriter {
+	return &subResourceClient{client: c, name: "status"}
+}
+
+// SubResource 返回子资源 client，只支持 status
+func (c *storeClient) SubResource(subResource string) client.SubResourceClient {
+	return &subResourceClient{client: c, name: subResource}
+}
+
+// subResourceClient 是 status 子资源的 client；其他子资源（scale、eviction 等）返回不支持
+type subResourceClient struct {
+	client *storeClient
+	name   string
+}
+
+func (s *subResourceClient) unsupported() error {
+	return apierrors.NewMethodNotSupported(schema.GroupResource{}, "subresource "+s.name)
+}
+
+// Get 读取子资源；status 子资源即对象本身
+func (s *subResourceClient) Get(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceGetOption) error {
+	if s.name != "status" {
+		return s.unsupported()
+	}
+	return s.client.Get(ctx, client.ObjectKeyFromObject(obj), subResource)
+}
+
+// Create 不支持
+func (s *subResourceClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	return s.unsupported()
+}
+
+// Update 只更新对象的 status，其他字段保持 Store 中的值
+func (s *subResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	if s.name != "status" {
+		return s.unsupported()
+	}
+	gvk, gr, err := s.client.objectGVK(obj)
+	if err != nil {
+		return err
+	}
+	options := (&client.SubResourceUpdateOptions{}).ApplyOptions(opts)
+	if len(options.DryRun) > 0 {
+		return nil
+	}
+	cur, err := s.client.current(gvk, gr, obj)
+	if err != nil {
+		return err
+	}
+	updated := cur.DeepCopyObject().(client.Object)
+	copyStatus(updated, obj)
+	updated.SetResourceVersion(obj.GetResourceVersion())
+	if err := s.client.write(gvk, gr, cur, updated); err != nil {
+		return err
+	}
+	return copyInto(obj, updated, gvk)
+}
+
+// Patch 按 patch 更新对象的 status
+func (s *subResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if s.name != "status" {
+		return s.unsupported()
+	}
+	options := (&client.SubResourcePatchOptions{}).ApplyOptions(opts)
+	return s.client.patch(ctx, obj, patch, true, options.DryRun)
+}
+
+// Apply 不支持 server-side apply
+func (s *subResourceClient) Apply(ctx context.Context, obj runtime.ApplyConfiguration, opts ...client.SubResourceApplyOption) error {
+	return s.unsupported()
+}
+
+// copyStatus 把 src 的 Status 字段复制到 dst（同一 Go 类型且都有 Status 字段时）
+func copyStatus(dst, src runtime.Object) {
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Ptr || dv.Type() != sv.Type() {
+		return
+	}
+	df, sf := dv.Elem().FieldByName("Status"), sv.Elem().FieldByName("Status")
+	if !df.IsValid() || !sf.IsValid() || !df.CanSet() {
+		return
+	}
+	df.Set(reflect.ValueOf(src.DeepCopyObject()).Elem().FieldByName("Status"))
+}
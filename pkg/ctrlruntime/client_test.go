@@ -0,0 +1,142 @@
+package ctrlruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestStoreClient_CRUD(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient(storage.NewMemoryStore())
+
+	var missing corev1.ConfigMap
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nope"}, &missing); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": name}}}
+		if err := c.Create(ctx, cm); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if cm.Namespace != "default" || cm.ResourceVersion == "" || cm.UID == "" {
+			t.Fatalf("create should fill namespace, resourceVersion and uid: %+v", cm.ObjectMeta)
+		}
+	}
+	if err := c.Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a"}}); !apierrors.IsAlreadyExists(err) {
+		t.Fatalf("expected AlreadyExists, got %v", err)
+	}
+
+	var list corev1.ConfigMapList
+	if err := c.List(ctx, &list, client.InNamespace("default"), client.MatchingLabels{"app": "b"}); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "b" {
+		t.Fatalf("unexpected list: %+v", list.Items)
+	}
+
+	// 过时的 resourceVersion 返回 Conflict
+	var first, second corev1.ConfigMap
+	key := types.NamespacedName{Namespace: "default", Name: "a"}
+	if err := c.Get(ctx, key, &first); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	second = *first.DeepCopy()
+	first.Data = map[string]string{"k": "1"}
+	if err := c.Update(ctx, &first); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	second.Data = map[string]string{"k": "2"}
+	if err := c.Update(ctx, &second); !apierrors.IsConflict(err) {
+		t.Fatalf("expected Conflict, got %v", err)
+	}
+
+	patch := client.MergeFrom(first.DeepCopy())
+	first.Data["k2"] = "x"
+	if err := c.Patch(ctx, &first, patch); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	var patched corev1.ConfigMap
+	if err := c.Get(ctx, key, &patched); err != nil || patched.Data["k"] != "1" || patched.Data["k2"] != "x" {
+		t.Fatalf("unexpected patched object: %+v %v", patched.Data, err)
+	}
+
+	if err := c.DeleteAllOf(ctx, &corev1.ConfigMap{}, client.InNamespace("default")); err != nil {
+		t.Fatalf("delete all: %v", err)
+	}
+	if err := c.List(ctx, &list); err != nil || len(list.Items) != 0 {
+		t.Fatalf("expected no configmaps, got %d %v", len(list.Items), err)
+	}
+}
+
+func TestStoreClient_StatusSubresource(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient(storage.NewMemoryStore())
+
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	if err := c.Create(ctx, dep); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// Update 不修改 status，Status().Update 只修改 status
+	dep.Status.Replicas = 3
+	dep.Labels = map[string]string{"tier": "web"}
+	if err := c.Update(ctx, dep); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	var got appsv1.Deployment
+	if err := c.Get(ctx, client.ObjectKeyFromObject(dep), &got); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Status.Replicas != 0 || got.Labels["tier"] != "web" {
+		t.Fatalf("update should keep stored status: %+v", got)
+	}
+
+	got.Status.Replicas = 3
+	got.Labels = nil
+	if err := c.Status().Update(ctx, &got); err != nil {
+		t.Fatalf("status update: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(dep), &got); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Status.Replicas != 3 || got.Labels["tier"] != "web" {
+		t.Fatalf("status update should only change status: %+v", got)
+	}
+}
+
+func TestStoreClient_Finalizers(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient(storage.NewMemoryStore())
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cfg", Finalizers: []string{"example.k3.io/cleanup"}}}
+	if err := c.Create(ctx, cm); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := c.Delete(ctx, cm); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var got corev1.ConfigMap
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cm), &got); err != nil {
+		t.Fatalf("object with finalizer should still exist: %v", err)
+	}
+	if got.DeletionTimestamp == nil {
+		t.Fatalf("expected deletionTimestamp to be set")
+	}
+
+	got.Finalizers = nil
+	if err := c.Update(ctx, &got); err != nil {
+		t.Fatalf("remove finalizer: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cm), &got); !apierrors.IsNotFound(err) {
+		t.Fatalf("object should be deleted after its last finalizer is removed, got %v", err)
+	}
+}
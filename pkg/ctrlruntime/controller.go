@@ -0,0 +1,367 @@
+package ctrlruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/storage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// watchSource 是控制器监听的一种资源，mapFn 把该资源的事件映射为要处理的请求
+type watchSource struct {
+	gvk   schema.GroupVersionKind
+	mapFn handler.MapFunc
+}
+
+// Builder 以 controller-runtime builder 的写法组装控制器：
+//
+//	ctl, err := ctrlruntime.NewControllerManagedBy(store).
+//		For(&appsv1.Deployment{}).
+//		Owns(&corev1.ConfigMap{}).
+//		Complete(reconciler)
+type Builder struct {
+	store   storage.Store
+	client  *storeClient
+	name    string
+	forObj  client.Object
+	owns    []client.Object
+	watches []struct {
+		obj   client.Object
+		mapFn handler.MapFunc
+	}
+	workers int
+}
+
+// NewControllerManagedBy 创建监听 store 的控制器 Builder
+func NewControllerManagedBy(store storage.Store) *Builder {
+	return &Builder{store: store, client: &storeClient{store: store}}
+}
+
+// For 指定控制器处理的资源：该资源的每次变更以其 namespace/name 调用 Reconcile
+func (b *Builder) For(obj client.Object) *Builder {
+	b.forObj = obj
+	return b
+}
+
+// Owns 监听 For 资源创建的子资源：子资源变更时以其 controller ownerReference 指向的 For 对象调用 Reconcile
+func (b *Builder) Owns(obj client.Object) *Builder {
+	b.owns = append(b.owns, obj)
+	return b
+}
+
+// Watches 监听其他资源，mapFn 决定该资源的变更触发哪些 Reconcile
+func (b *Builder) Watches(obj client.Object, mapFn handler.MapFunc) *Builder {
+	b.watches = append(b.watches, struct {
+		obj   client.Object
+		mapFn handler.MapFunc
+	}{obj, mapFn})
+	return b
+}
+
+// Named 指定控制器名称（/debug/controllers 与日志中使用），默认为 For 资源的 kind 小写
+func (b *Builder) Named(name string) *Builder {
+	b.name = name
+	return b
+}
+
+// WithMaxConcurrentReconciles 指定同时执行 Reconcile 的数量，默认为 1（同一个对象不会被并发处理）
+func (b *Builder) WithMaxConcurrentReconciles(n int) *Builder {
+	b.workers = n
+	return b
+}
+
+// Complete 创建执行 r 的控制器，用 ControllerManager.Register 注册后随 controller manager 启停
+func (b *Builder) Complete(r reconcile.Reconciler) (*Controller, error) {
+	if r == nil {
+		return nil, fmt.Errorf("ctrlruntime: reconciler is required")
+	}
+	if b.forObj == nil {
+		return nil, fmt.Errorf("ctrlruntime: For() is required")
+	}
+	forGVK, _, err := b.client.objectGVK(b.forObj)
+	if err != nil {
+		return nil, err
+	}
+	c := &Controller{
+		name:       b.name,
+		store:      b.store,
+		reconciler: r,
+		workers:    b.workers,
+		forGVK:     forGVK,
+	}
+	if c.name == "" {
+		c.name = strings.ToLower(forGVK.Kind)
+	}
+	if c.workers <= 0 {
+		c.workers = 1
+	}
+	c.sources = append(c.sources, watchSource{gvk: forGVK, mapFn: enqueueSelf})
+	for _, obj := range b.owns {
+		gvk, _, err := b.client.objectGVK(obj)
+		if err != nil {
+			return nil, err
+		}
+		c.sources = append(c.sources, watchSource{gvk: gvk, mapFn: enqueueOwner(forGVK)})
+	}
+	for _, w := range b.watches {
+		gvk, _, err := b.client.objectGVK(w.obj)
+		if err != nil {
+			return nil, err
+		}
+		if w.mapFn == nil {
+			return nil, fmt.Errorf("ctrlruntime: Watches(%s) requires a map function", gvk.Kind)
+		}
+		c.sources = append(c.sources, watchSource{gvk: gvk, mapFn: w.mapFn})
+	}
+	return c, nil
+}
+
+// enqueueSelf 以对象自身调用 Reconcile
+func enqueueSelf(_ context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}}}
+}
+
+// enqueueOwner 以对象的 controller ownerReference（kind 为 owner 时）调用 Reconcile
+func enqueueOwner(owner schema.GroupVersionKind) handler.MapFunc {
+	return func(_ context.Context, obj client.Object) []reconcile.Request {
+		ref := metav1.GetControllerOf(obj)
+		if ref == nil || ref.Kind != owner.Kind {
+			return nil
+		}
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil || gv.Group != owner.Group {
+			return nil
+		}
+		namespace := obj.GetNamespace()
+		if storage.IsClusterScoped(owner) {
+			namespace = ""
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: ref.Name}}}
+	}
+}
+
+// Controller 在 k3 的 ControllerManager 中运行 controller-runtime 的 reconcile.Reconciler：
+// 监听 Store 中的资源变更，把请求放入限速队列，按 Result 与错误重新入队（与 controller-runtime 相同）：
+//   - 返回错误时按指数退避重试（reconcile.TerminalError 除外）
+//   - Result.RequeueAfter > 0 时在该时长后重试，Result.Requeue 为 true 时按退避重试
+//   - Reconcile panic 时视为返回错误
+//
+// 启动时把 For 资源的所有对象入队一次；传给 Reconcile 的 context 带有 controller-runtime 的 logger（log.FromContext）
+type Controller struct {
+	name       string
+	store      storage.Store
+	reconciler reconcile.Reconciler
+	workers    int
+	forGVK     schema.GroupVersionKind
+	sources    []watchSource
+
+	mu       sync.Mutex
+	queue    workqueue.TypedRateLimitingInterface[reconcile.Request]
+	cancel   context.CancelFunc
+	watchers []watcher
+	wg       sync.WaitGroup
+	observe  func(start time.Time, err error)
+}
+
+// watcher 是一个已打开的 Store watch
+type watcher struct {
+	gvk schema.GroupVersionKind
+	ch  <-chan storage.ResourceEvent
+}
+
+// Name 返回控制器名称
+func (c *Controller) Name() string {
+	return c.name
+}
+
+// ObserveReconciles 由 ControllerManager 调用，每次 Reconcile 结束后以开始时间与结果调用 observe（/debug/controllers 与 /metrics 中的处理统计）
+func (c *Controller) ObserveReconciles(observe func(start time.Time, err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observe = observe
+}
+
+// Start 打开 watch、把现有对象入队并启动 worker；ctx 只用于传递值，控制器运行到 Stop 为止
+func (c *Controller) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.queue != nil {
+		return fmt.Errorf("controller %s already started", c.name)
+	}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	queue := workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.DefaultTypedControllerRateLimiter[reconcile.Request](),
+		workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{Name: c.name},
+	)
+
+	watchers := make([]watcher, 0, len(c.sources))
+	for _, src := range c.sources {
+		ch, err := c.store.Watch(src.gvk, "", "")
+		if err != nil {
+			cancel()
+			queue.ShutDown()
+			c.stopWatchers(watchers)
+			return fmt.Errorf("无法监听 %s: %w", src.gvk.Kind, err)
+		}
+		watchers = append(watchers, watcher{gvk: src.gvk, ch: ch})
+		c.wg.Add(1)
+		go c.forward(runCtx, queue, ch, src.mapFn)
+	}
+
+	existing, err := c.store.List(c.forGVK, "")
+	if err != nil {
+		cancel()
+		queue.ShutDown()
+		c.stopWatchers(watchers)
+		return fmt.Errorf("列出 %s 失败: %w", c.forGVK.Kind, err)
+	}
+	for _, obj := range existing {
+		if o, ok := obj.(client.Object); ok {
+			for _, req := range enqueueSelf(runCtx, o) {
+				queue.Add(req)
+			}
+		}
+	}
+
+	for i := 0; i < c.workers; i++ {
+		c.wg.Add(1)
+		go c.work(runCtx, queue)
+	}
+	c.queue, c.cancel, c.watchers = queue, cancel, watchers
+	return nil
+}
+
+// Stop 停止 watch 与 worker，等待正在执行的 Reconcile 结束
+func (c *Controller) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	queue, cancel, watchers := c.queue, c.cancel, c.watchers
+	c.queue, c.cancel, c.watchers = nil, nil, nil
+	c.mu.Unlock()
+	if queue == nil {
+		return nil
+	}
+	cancel()
+	queue.ShutDown()
+	c.stopWatchers(watchers)
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopWatchers 注销 watch（Store 支持时），forward 随通道关闭或 context 取消退出
+func (c *Controller) stopWatchers(watchers []watcher) {
+	stopper, ok := c.store.(storage.WatchStopper)
+	if !ok {
+		return
+	}
+	for _, w := range watchers {
+		stopper.StopWatcher(w.gvk, "", w.ch)
+	}
+}
+
+// forward 把 watch 事件经 mapFn 转换为请求放入队列
+func (c *Controller) forward(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request], ch <-chan storage.ResourceEvent, mapFn handler.MapFunc) {
+	defer c.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if event.Type == storage.EventBookmark {
+				continue
+			}
+			objs := []client.Object{}
+			if o, ok := event.Object.(client.Object); ok {
+				objs = append(objs, o)
+			}
+			// 子资源的 owner 变化时，原来的 owner 也需要处理
+			if o, ok := event.OldObj.(client.Object); ok {
+				objs = append(objs, o)
+			}
+			for _, o := range objs {
+				for _, req := range mapFn(ctx, o) {
+					queue.Add(req)
+				}
+			}
+		}
+	}
+}
+
+// work 从队列取出请求执行 Reconcile，直到队列关闭
+func (c *Controller) work(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	defer c.wg.Done()
+	for {
+		req, shutdown := queue.Get()
+		if shutdown {
+			return
+		}
+		c.process(ctx, queue, req)
+		queue.Done(req)
+	}
+}
+
+// process 执行一次 Reconcile 并按结果重新入队
+func (c *Controller) process(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request], req reconcile.Request) {
+	logger := log.Log.WithValues("controller", c.name, "namespace", req.Namespace, "name", req.Name)
+	start := time.Now()
+	result, err := c.reconcile(log.IntoContext(ctx, logger), req)
+
+	c.mu.Lock()
+	observe := c.observe
+	c.mu.Unlock()
+	if observe != nil {
+		observe(start, err)
+	}
+
+	switch {
+	case err != nil:
+		if errors.Is(err, reconcile.TerminalError(nil)) {
+			logger.Error(err, "Reconcile 返回终止错误，不再重试")
+			queue.Forget(req)
+			return
+		}
+		logger.Error(err, "Reconcile 失败")
+		queue.AddRateLimited(req)
+	case result.RequeueAfter > 0:
+		queue.Forget(req)
+		queue.AddAfter(req, result.RequeueAfter)
+	case result.Requeue: //nolint:staticcheck // 与 controller-runtime 相同，仍然支持已弃用的 Requeue
+		queue.AddRateLimited(req)
+	default:
+		queue.Forget(req)
+	}
+}
+
+// reconcile 调用 Reconciler，panic 转换为错误
+func (c *Controller) reconcile(ctx context.Context, req reconcile.Request) (result reconcile.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v [recovered]", r)
+		}
+	}()
+	return c.reconciler.Reconcile(ctx, req)
+}
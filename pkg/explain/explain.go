@@ -0,0 +1,255 @@
+// Package explain 按资源的 Go 类型（反射）列出字段、类型与说明（类型的 SwaggerDoc），并标注 k3 对每个字段的支持程度（见 support.go）。
+// k3 只实现了 Kubernetes 的一个子集，很多字段可以写入但不会生效；k3 explain 据此告诉用户哪些字段真正起作用。
+// 本包只依赖 k8s.io/api 与 pkg/resources，客户端（k3ctl）可以直接引用
+package explain
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// kindTypes 资源的 Go 类型，与 pkg/resources 登记的资源一一对应（见 TestKindTypesMatchResources）
+var kindTypes = map[string]any{
+	"Pod":                  corev1.Pod{},
+	"Service":              corev1.Service{},
+	"Endpoints":            corev1.Endpoints{},
+	"ConfigMap":            corev1.ConfigMap{},
+	"Secret":               corev1.Secret{},
+	"Event":                corev1.Event{},
+	"Node":                 corev1.Node{},
+	"Namespace":            corev1.Namespace{},
+	"ServiceAccount":       corev1.ServiceAccount{},
+	"ResourceQuota":        corev1.ResourceQuota{},
+	"LimitRange":           corev1.LimitRange{},
+	"NetworkPolicy":        networkingv1.NetworkPolicy{},
+	"Deployment":           appsv1.Deployment{},
+	"StatefulSet":          appsv1.StatefulSet{},
+	"DaemonSet":            appsv1.DaemonSet{},
+	"Device":               k3v1.Device{},
+	"ClientUsage":          k3v1.ClientUsage{},
+	"GitRepository":        k3v1.GitRepository{},
+	"ClusterConfiguration": k3v1.ClusterConfiguration{},
+	"ClusterInfo":          k3v1.ClusterInfo{},
+	"PriorityClass":        schedulingv1.PriorityClass{},
+	"PodDisruptionBudget":  policyv1.PodDisruptionBudget{},
+}
+
+// leafTypes 序列化为标量的结构体类型：不展开字段，按 JSON 中的形式显示类型
+var leafTypes = map[reflect.Type]string{
+	reflect.TypeOf(metav1.Time{}):          "string",
+	reflect.TypeOf(metav1.MicroTime{}):     "string",
+	reflect.TypeOf(metav1.Duration{}):      "string",
+	reflect.TypeOf(metav1.FieldsV1{}):      "Object",
+	reflect.TypeOf(resource.Quantity{}):    "Quantity",
+	reflect.TypeOf(intstr.IntOrString{}):   "IntOrString",
+	reflect.TypeOf(runtime.RawExtension{}): "Object",
+}
+
+// Field 一个字段的说明
+type Field struct {
+	// Name JSON 字段名（资源本身为 Kind）
+	Name string
+	// Type 与 kubectl explain 相同的类型写法，例如 string、[]Container、map[string]string
+	Type        string
+	Description string
+	Support     Support
+	// Note 支持程度的补充说明，例如部分生效时哪些子字段生效
+	Note string
+	// Fields 子字段；递归解释时子字段也带有自己的子字段
+	Fields []Field
+}
+
+// Explanation 一次解释的结果
+type Explanation struct {
+	GVK schema.GroupVersionKind
+	// Path 规范化后的字段路径（不含资源名），为空表示资源本身
+	Path  []string
+	Field Field
+}
+
+// Explain 解释 gvk 资源中 path 指向的字段（例如 spec、containers）：返回字段的类型、说明、支持程度与直接子字段；
+// recursive 为 true 时子字段递归展开。字段名不区分大小写
+func Explain(gvk schema.GroupVersionKind, path []string, recursive bool) (*Explanation, error) {
+	obj, ok := kindTypes[gvk.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind: %s", gvk.Kind)
+	}
+	t := reflect.TypeOf(obj)
+	field := Field{Name: gvk.Kind, Type: t.Name(), Description: swaggerDoc(t)[""]}
+
+	normalized := make([]string, 0, len(path))
+	for _, segment := range path {
+		st := structType(t)
+		if st == nil {
+			return nil, fmt.Errorf("%s 是 %s 类型的字段，没有子字段", fieldPath(gvk.Kind, normalized), typeName(t))
+		}
+		f, ok := lookupField(st, segment)
+		if !ok {
+			return nil, fmt.Errorf("%s 没有字段 %s", fieldPath(gvk.Kind, normalized), segment)
+		}
+		normalized = append(normalized, f.name)
+		t = f.typ
+		field = Field{Name: f.name, Type: typeName(f.typ), Description: f.description()}
+	}
+	field.Support, field.Note = Lookup(gvk, normalized)
+	field.Fields = children(gvk, normalized, t, recursive, map[reflect.Type]bool{})
+	return &Explanation{GVK: gvk, Path: normalized, Field: field}, nil
+}
+
+// fieldPath 把资源名与字段路径拼成 pod.spec.containers 的形式（用于错误信息）
+func fieldPath(kind string, path []string) string {
+	return strings.Join(append([]string{strings.ToLower(kind)}, path...), ".")
+}
+
+// children 返回 t 的子字段；seen 防止递归类型无限展开
+func children(gvk schema.GroupVersionKind, path []string, t reflect.Type, recursive bool, seen map[reflect.Type]bool) []Field {
+	st := structType(t)
+	if st == nil || seen[st] {
+		return nil
+	}
+	seen[st] = true
+	defer delete(seen, st)
+
+	var fields []Field
+	for _, f := range structFields(st) {
+		childPath := append(path[:len(path):len(path)], f.name)
+		field := Field{Name: f.name, Type: typeName(f.typ), Description: f.description()}
+		field.Support, field.Note = Lookup(gvk, childPath)
+		if recursive {
+			field.Fields = children(gvk, childPath, f.typ, true, seen)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// structField 结构体的一个 JSON 字段（inline 的嵌入结构体已展开）
+type structField struct {
+	name string
+	typ  reflect.Type
+	// doc 所在结构体的 SwaggerDoc 中该字段的说明
+	doc string
+}
+
+// description 字段的说明，所在结构体没有说明时使用字段类型的说明
+func (f structField) description() string {
+	if f.doc != "" {
+		return f.doc
+	}
+	if st := structType(f.typ); st != nil {
+		return swaggerDoc(st)[""]
+	}
+	return ""
+}
+
+// structFields 按声明顺序返回 st 的 JSON 字段：跳过未导出与 json:"-" 的字段，展开没有字段名的嵌入结构体（TypeMeta、ProbeHandler、VolumeSource 等）
+func structFields(st reflect.Type) []structField {
+	doc := swaggerDoc(st)
+	var fields []structField
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && f.Anonymous {
+			if embedded := structType(f.Type); embedded != nil {
+				fields = append(fields, structFields(embedded)...)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, structField{name: name, typ: f.Type, doc: doc[name]})
+	}
+	return fields
+}
+
+// lookupField 按字段名查找 st 的字段：先精确匹配，再忽略大小写
+func lookupField(st reflect.Type, name string) (structField, bool) {
+	fields := structFields(st)
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return structField{}, false
+}
+
+// structType 去掉指针、切片与 map 后的结构体类型；标量与 leafTypes 返回 nil
+func structType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		case reflect.Struct:
+			if _, leaf := leafTypes[t]; leaf {
+				return nil
+			}
+			return t
+		}
+		return nil
+	}
+}
+
+// typeName 返回 kubectl explain 风格的类型名
+func typeName(t reflect.Type) string {
+	if name, ok := leafTypes[t]; ok {
+		return name
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeName(t.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte 序列化为 base64 字符串
+			return "string"
+		}
+		return "[]" + typeName(t.Elem())
+	case reflect.Map:
+		return "map[string]" + typeName(t.Elem())
+	case reflect.Struct:
+		return t.Name()
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "Object"
+}
+
+// swaggerDoc 返回类型的 SwaggerDoc（k8s.io/api 与 k3.io 的类型都有），"" 键为类型本身的说明
+func swaggerDoc(t reflect.Type) map[string]string {
+	if d, ok := reflect.Zero(t).Interface().(interface{ SwaggerDoc() map[string]string }); ok {
+		return d.SwaggerDoc()
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package explain
+
+import (
+	"strings"
+	"testing"
+
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/resources"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	podGVK        = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+)
+
+// 每个登记的资源都要能解释，新增资源时同时登记 kindTypes
+func TestKindTypesMatchResources(t *testing.T) {
+	for _, resource := range resources.Resources() {
+		gvk, err := resources.ForResource(resource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Explain(gvk, nil, false); err != nil {
+			t.Errorf("%s: %v", resource, err)
+		}
+	}
+	if len(kindTypes) != len(resources.Resources()) {
+		t.Errorf("kindTypes has %d kinds, resources has %d", len(kindTypes), len(resources.Resources()))
+	}
+}
+
+func childNames(fields []Field) []string {
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func child(t *testing.T, field Field, name string) Field {
+	t.Helper()
+	for _, f := range field.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	t.Fatalf("%s has no field %s: %v", field.Name, name, childNames(field.Fields))
+	return Field{}
+}
+
+func TestExplainPath(t *testing.T) {
+	exp, err := Explain(podGVK, []string{"Spec", "containers"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(exp.Path, ".") != "spec.containers" {
+		t.Errorf("path = %v", exp.Path)
+	}
+	if exp.Field.Type != "[]Container" || exp.Field.Support != SupportPartial || exp.Field.Description == "" {
+		t.Errorf("containers = %+v", exp.Field)
+	}
+	if f := child(t, exp.Field, "image"); f.Type != "string" || f.Support != SupportEffective {
+		t.Errorf("image = %+v", f)
+	}
+	if f := child(t, exp.Field, "livenessProbe"); f.Type != "Probe" || f.Support != SupportStored || f.Note == "" {
+		t.Errorf("livenessProbe = %+v", f)
+	}
+	if f := child(t, exp.Field, "resources"); f.Support != SupportPartial {
+		t.Errorf("resources = %+v", f)
+	}
+
+	// 嵌入的 ProbeHandler 展开为 readinessProbe 的字段
+	exp, err = Explain(podGVK, []string{"spec", "containers", "readinessProbe"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := child(t, exp.Field, "httpGet"); f.Support != SupportEffective {
+		t.Errorf("readinessProbe.httpGet = %+v", f)
+	}
+	if f := child(t, exp.Field, "failureThreshold"); f.Support != SupportStored {
+		t.Errorf("readinessProbe.failureThreshold = %+v", f)
+	}
+
+	for _, path := range [][]string{{"spec", "nope"}, {"metadata", "name", "x"}} {
+		if _, err := Explain(podGVK, path, false); err == nil {
+			t.Errorf("%v: expected error", path)
+		}
+	}
+}
+
+func TestExplainTypes(t *testing.T) {
+	exp, err := Explain(podGVK, []string{"spec"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"nodeSelector":                  "map[string]string",
+		"overhead":                      "map[string]Quantity",
+		"terminationGracePeriodSeconds": "integer",
+		"hostNetwork":                   "boolean",
+		"volumes":                       "[]Volume",
+	} {
+		if f := child(t, exp.Field, name); f.Type != want {
+			t.Errorf("%s: type %s, want %s", name, f.Type, want)
+		}
+	}
+
+	exp, err = Explain(podGVK, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// TypeMeta 展开为 apiVersion 与 kind
+	if got := strings.Join(childNames(exp.Field.Fields), ","); got != "kind,apiVersion,metadata,spec,status" {
+		t.Errorf("pod fields = %s", got)
+	}
+	exp, err = Explain(podGVK, []string{"metadata"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := child(t, exp.Field, "creationTimestamp"); f.Type != "string" {
+		t.Errorf("creationTimestamp = %+v", f)
+	}
+}
+
+func TestExplainRecursive(t *testing.T) {
+	exp, err := Explain(deploymentGVK, []string{"spec", "template"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := child(t, child(t, exp.Field, "spec"), "containers")
+	if f := child(t, containers, "image"); f.Support != SupportEffective {
+		t.Errorf("template image = %+v", f)
+	}
+	if f := child(t, child(t, containers, "lifecycle"), "postStart"); f.Support != SupportPartial || len(f.Fields) == 0 {
+		t.Errorf("postStart = %+v", f)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	device := schema.GroupVersionKind{Group: k3v1.GroupName, Version: "v1", Kind: "Device"}
+	statefulSet := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	service := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	for _, tc := range []struct {
+		gvk  schema.GroupVersionKind
+		path string
+		want Support
+	}{
+		{podGVK, "", SupportPartial},
+		{podGVK, "spec", SupportPartial},
+		{podGVK, "spec.dnsPolicy", SupportStored},
+		{podGVK, "spec.containers.image", SupportEffective},
+		{podGVK, "spec.containers.lifecycle.preStop.exec.command", SupportEffective},
+		{podGVK, "spec.containers.lifecycle.preStop.tcpSocket", SupportStored},
+		{podGVK, "spec.volumes.configMap.name", SupportStored},
+		{podGVK, "spec.volumes.emptyDir", SupportEffective},
+		{podGVK, "spec.affinity", SupportPartial},
+		{podGVK, "status.phase", SupportManaged},
+		{podGVK, "metadata.labels", SupportEffective},
+		{podGVK, "metadata.uid", SupportManaged},
+		{podGVK, "metadata.finalizers", SupportStored},
+		{deploymentGVK, "spec.strategy.rollingUpdate.maxSurge", SupportStored},
+		{deploymentGVK, "spec.template", SupportPartial},
+		{deploymentGVK, "spec.template.metadata.labels", SupportEffective},
+		{deploymentGVK, "spec.template.metadata.annotations", SupportStored},
+		{deploymentGVK, "spec.template.spec.nodeSelector", SupportEffective},
+		{deploymentGVK, "spec.template.spec.tolerations", SupportStored},
+		{statefulSet, "spec.template.spec.containers.image", SupportStored},
+		{service, "spec.ports.protocol", SupportStored},
+		{service, "spec.ports.targetPort", SupportEffective},
+		{device, "spec.description", SupportEffective},
+		{device, "status.ip", SupportManaged},
+	} {
+		var path []string
+		if tc.path != "" {
+			path = strings.Split(tc.path, ".")
+		}
+		if got, _ := Lookup(tc.gvk, path); got != tc.want {
+			t.Errorf("%s %s: %s, want %s", tc.gvk.Kind, tc.path, got, tc.want)
+		}
+	}
+}
+
+// 支持表中登记的路径都要是真实存在的字段，避免字段改名后静默失效
+func TestRulesReferExistingFields(t *testing.T) {
+	for kind, rules := range kindRules {
+		gvk, err := resources.ForResource(resourceFor(t, kind))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key := range rules {
+			if key == "" {
+				continue
+			}
+			if _, err := Explain(gvk, strings.Split(key, "."), false); err != nil {
+				t.Errorf("%s %s: %v", kind, key, err)
+			}
+		}
+	}
+	for key := range metadataRules {
+		if _, err := Explain(podGVK, strings.Split(key, "."), false); err != nil {
+			t.Errorf("metadata %s: %v", key, err)
+		}
+	}
+}
+
+func resourceFor(t *testing.T, kind string) string {
+	t.Helper()
+	resource, ok := resources.ResourceForKind(kind)
+	if !ok {
+		t.Fatalf("kind %s is not registered", kind)
+	}
+	return resource
+}
@@ -0,0 +1,270 @@
+package explain
+
+import (
+	"strings"
+
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Support k3 对一个字段的支持程度
+type Support string
+
+const (
+	// SupportEffective 字段（及其所有子字段）会被 k3 的组件读取并产生效果
+	SupportEffective Support = "生效"
+	// SupportPartial 只有部分子字段或部分取值生效，见 Note 或子字段
+	SupportPartial Support = "部分生效"
+	// SupportStored 字段可以写入并原样保存（apiserver 可能设置默认值），但没有组件读取它
+	SupportStored Support = "仅保存"
+	// SupportManaged 字段由 k3 写入（status、uid 等），用户写入的值会被覆盖
+	SupportManaged Support = "由 k3 维护"
+)
+
+// rule 支持表中的一项：登记在一个字段路径上（不含资源名，以 . 分隔，切片与 map 不加下标）
+type rule struct {
+	support Support
+	note    string
+}
+
+func effective(note string) rule { return rule{support: SupportEffective, note: note} }
+func partial(note string) rule   { return rule{support: SupportPartial, note: note} }
+func stored(note string) rule    { return rule{support: SupportStored, note: note} }
+func managed(note string) rule   { return rule{support: SupportManaged, note: note} }
+
+// metadataRules 所有资源共用的 metadata 支持表
+var metadataRules = map[string]rule{
+	"metadata.name":                       effective(""),
+	"metadata.namespace":                  effective(""),
+	"metadata.labels":                     effective("用于标签选择器：Deployment、Service、PDB、Pod 反亲和与 k3 get -l"),
+	"metadata.annotations":                effective("原样保存，可由 downwardAPI 读取；k3 自己的注解见各模块文档"),
+	"metadata.resourceVersion":            effective("更新时用于乐观并发检查，读请求见 resourceVersion 参数"),
+	"metadata.ownerReferences":            partial("Deployment 创建的 Pod 由 k3 设置；删除所有者时不会级联删除"),
+	"metadata.generateName":               stored("apiserver 不会按前缀生成名称，必须指定 name"),
+	"metadata.finalizers":                 stored("删除对象时不等待 finalizer"),
+	"metadata.uid":                        managed(""),
+	"metadata.creationTimestamp":          managed(""),
+	"metadata.generation":                 managed(""),
+	"metadata.deletionTimestamp":          managed(""),
+	"metadata.deletionGracePeriodSeconds": managed(""),
+	"metadata.managedFields":              managed(""),
+	"metadata.selfLink":                   managed(""),
+}
+
+// templateMetadataRules Deployment 的 spec.template.metadata（相对 template）：只有 labels 会复制到 Pod
+var templateMetadataRules = map[string]rule{
+	"metadata":        partial("只有 labels 会复制到 Pod"),
+	"metadata.labels": effective("必须匹配 spec.selector，复制到创建的 Pod"),
+}
+
+// kindRules 按 Kind 登记的支持表，"" 为资源本身。查找规则见 lookup；没有登记的字段按 defaultRule 处理
+var kindRules = map[string]map[string]rule{
+	"Pod": {
+		"": partial("k3 用 docker 运行 Pod：只有标注为生效的字段会影响调度与容器"),
+
+		"spec.containers.name":                                   effective(""),
+		"spec.containers.image":                                  effective(""),
+		"spec.containers.imagePullPolicy":                        effective(""),
+		"spec.containers.command":                                effective(""),
+		"spec.containers.args":                                   effective(""),
+		"spec.containers.workingDir":                             effective(""),
+		"spec.containers.ports.containerPort":                    effective("发布在 Pod 的 sandbox 容器上"),
+		"spec.containers.ports.hostPort":                         effective("转换为 docker -p <hostPort>:<containerPort>"),
+		"spec.containers.ports.name":                             effective("httpGet 钩子与 Service 的 targetPort 可以引用端口名"),
+		"spec.containers.env.name":                               effective(""),
+		"spec.containers.env.value":                              effective(""),
+		"spec.containers.env.valueFrom.fieldRef":                 partial("metadata.labels 与 metadata.annotations 只能用于 downwardAPI 卷"),
+		"spec.containers.envFrom":                                stored("不读取 ConfigMap/Secret"),
+		"spec.containers.resources.limits":                       effective("cpu、memory 转换为 docker 的 --cpus、--memory，并参与调度"),
+		"spec.containers.resources.requests":                     effective("参与调度与驱逐时的资源计算"),
+		"spec.containers.volumeMounts.name":                      effective(""),
+		"spec.containers.volumeMounts.mountPath":                 effective(""),
+		"spec.containers.volumeMounts.subPath":                   effective(""),
+		"spec.containers.volumeMounts.readOnly":                  effective(""),
+		"spec.containers.lifecycle":                              effective("postStart 在容器启动后执行，preStop 在停止容器前执行，受 terminationGracePeriodSeconds 限制"),
+		"spec.containers.lifecycle.postStart.tcpSocket":          stored("钩子只支持 exec、httpGet 与 sleep"),
+		"spec.containers.lifecycle.preStop.tcpSocket":            stored("钩子只支持 exec、httpGet 与 sleep"),
+		"spec.containers.lifecycle.stopSignal":                   stored(""),
+		"spec.containers.readinessProbe":                         partial("只用于 discovery 模块注册到 Consul 的健康检查"),
+		"spec.containers.readinessProbe.exec":                    effective(""),
+		"spec.containers.readinessProbe.httpGet":                 effective(""),
+		"spec.containers.readinessProbe.tcpSocket":               effective(""),
+		"spec.containers.readinessProbe.grpc":                    effective(""),
+		"spec.containers.readinessProbe.periodSeconds":           effective(""),
+		"spec.containers.readinessProbe.timeoutSeconds":          effective(""),
+		"spec.containers.livenessProbe":                          stored("k3 不执行存活探针"),
+		"spec.containers.startupProbe":                           stored("k3 不执行启动探针"),
+		"spec.containers.securityContext.runAsUser":              effective("转换为 docker --user，优先于 Pod 级的设置"),
+		"spec.containers.securityContext.runAsGroup":             effective("转换为 docker --user，优先于 Pod 级的设置"),
+		"spec.containers.securityContext.readOnlyRootFilesystem": effective("转换为 docker --read-only"),
+		"spec.initContainers":                                    partial("init 容器不会运行，只参与调度与驱逐时的资源计算"),
+
+		"spec.volumes.name":               effective(""),
+		"spec.volumes.hostPath":           effective("type 只区分 DirectoryOrCreate：目录不存在时创建"),
+		"spec.volumes.emptyDir":           effective("Pod 级的 docker 卷，Pod 内的容器共享，Pod 停止时删除"),
+		"spec.volumes.emptyDir.medium":    stored(""),
+		"spec.volumes.emptyDir.sizeLimit": stored(""),
+		"spec.volumes.downwardAPI":        effective("元数据变化时更新卷中的文件"),
+		"spec.volumes.configMap":          stored("ConfigMap 卷不会挂载"),
+		"spec.volumes.secret":             stored("Secret 卷不会挂载"),
+
+		"spec.restartPolicy":                 effective(""),
+		"spec.terminationGracePeriodSeconds": effective("preStop 钩子与停止容器的总时限"),
+		"spec.nodeName":                      effective("指定后跳过调度"),
+		"spec.nodeSelector":                  effective(""),
+		"spec.affinity.podAntiAffinity":      effective(""),
+		"spec.affinity.nodeAffinity":         stored("不支持节点亲和，请使用 nodeSelector"),
+		"spec.topologySpreadConstraints":     effective(""),
+		"spec.tolerations":                   stored("k3 的节点没有污点，容忍不影响调度"),
+		"spec.priority":                      effective("由 apiserver 按 priorityClassName 填写"),
+		"spec.priorityClassName":             effective(""),
+		"spec.preemptionPolicy":              effective(""),
+		"spec.overhead":                      effective("计入调度时的资源需求"),
+		"spec.hostNetwork":                   effective("转换为 docker --network host"),
+		"spec.readinessGates":                effective("条件都为 True 时 Pod 才就绪"),
+		"spec.securityContext.runAsUser":     effective("转换为 docker --user"),
+		"spec.securityContext.runAsGroup":    effective("转换为 docker --user"),
+		"spec.serviceAccountName":            partial("只能由 downward API 读取，不挂载 ServiceAccount token"),
+		"spec.hostname":                      partial("只用于 headless Service 注册到 Consul 的主机名"),
+	},
+	"Deployment": {
+		"":                             partial("由 k3 的 Deployment 控制器创建 Pod"),
+		"spec.replicas":                effective(""),
+		"spec.selector":                effective(""),
+		"spec.paused":                  effective("暂停期间模板的变更不发布，仍维持副本数"),
+		"spec.progressDeadlineSeconds": effective("超时后 Progressing 条件为 False（ProgressDeadlineExceeded）"),
+		"spec.strategy":                stored("发布时先创建全部新副本，新副本就绪后删除旧副本；maxSurge 与 maxUnavailable 不生效"),
+		"spec.minReadySeconds":         stored("只做校验"),
+		"spec.revisionHistoryLimit":    stored(""),
+	},
+	"StatefulSet": {
+		"": stored("没有 StatefulSet 控制器：对象只保存，不会创建 Pod"),
+	},
+	"DaemonSet": {
+		"": stored("没有 DaemonSet 控制器：对象只保存，不会创建 Pod"),
+	},
+	"Service": {
+		"":                              partial("由 discovery 模块把选中的 Pod 注册到 Consul；没有 ClusterIP 转发"),
+		"spec.selector":                 effective(""),
+		"spec.ports":                    partial("只使用第一个端口"),
+		"spec.ports.port":               effective(""),
+		"spec.ports.targetPort":         effective("可以是容器端口名"),
+		"spec.type":                     partial("只区分 ExternalName，其他类型都按选择 Pod 处理"),
+		"spec.externalName":             effective(""),
+		"spec.clusterIP":                partial("只区分 None：headless Service 注册时带上 Pod 的主机名"),
+		"spec.publishNotReadyAddresses": effective(""),
+	},
+	"Node": {
+		"spec.unschedulable": effective("k3 cluster upgrade-nodes 与驱逐使用"),
+		"spec.podCIDR":       managed("由节点分配"),
+		"spec.podCIDRs":      managed("由节点分配"),
+		"spec.taints":        stored("调度器不检查污点"),
+	},
+	"PodDisruptionBudget": {
+		"spec.minAvailable":   effective("驱逐时检查，包括 k3 cluster upgrade-nodes"),
+		"spec.maxUnavailable": effective("驱逐时检查，包括 k3 cluster upgrade-nodes"),
+		"spec.selector":       effective(""),
+	},
+	"PriorityClass": {
+		"value":            effective(""),
+		"globalDefault":    effective(""),
+		"preemptionPolicy": effective(""),
+	},
+	"ConfigMap": {
+		"": stored("不会挂载为卷，也不能用于环境变量"),
+	},
+	"Secret": {
+		"":     stored("不会挂载为卷，也不能用于环境变量"),
+		"data": partial("GitRepository 的 spec.secretRef 读取 username 与 password"),
+	},
+	"ResourceQuota": {
+		"": stored("没有配额准入，不限制资源使用"),
+	},
+	"LimitRange": {
+		"": stored("不设置默认值，也不限制资源"),
+	},
+	"NetworkPolicy": {
+		"": stored("不限制网络访问"),
+	},
+	"ClientUsage": {
+		"": managed("由 apiserver 定期写入"),
+	},
+	"ClusterInfo": {
+		"": managed("只能由节点写入，API 只读"),
+	},
+}
+
+// Lookup 返回 gvk 资源中 path 字段的支持程度与说明：
+// metadata 使用共用的支持表，status 由 k3 维护，Deployment 的 spec.template.spec 与 Pod 的 spec 相同，其余按 kindRules 查找
+func Lookup(gvk schema.GroupVersionKind, path []string) (Support, string) {
+	r := lookupRule(gvk, path)
+	return r.support, r.note
+}
+
+func lookupRule(gvk schema.GroupVersionKind, path []string) rule {
+	if len(path) > 0 {
+		switch path[0] {
+		case "metadata":
+			return lookup(metadataRules, path, stored(""))
+		case "status":
+			return managed("由 k3 的组件写入")
+		}
+	}
+	if gvk.Kind == "Deployment" && len(path) >= 2 && path[0] == "spec" && path[1] == "template" {
+		template := path[2:]
+		switch {
+		case len(template) == 0:
+			return partial("与 Pod 的支持程度相同")
+		case template[0] == "metadata":
+			return lookup(templateMetadataRules, template, stored(""))
+		default:
+			return lookupRule(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, template)
+		}
+	}
+	return lookup(kindRules[gvk.Kind], path, defaultRule(gvk))
+}
+
+// defaultRule 没有登记的字段：k3.io 的资源由 k3 自己实现，字段都生效；其他资源的字段只保存
+func defaultRule(gvk schema.GroupVersionKind) rule {
+	if gvk.Group == k3v1.GroupName {
+		return effective("")
+	}
+	return stored("")
+}
+
+// lookup 在支持表中查找 path：
+//  1. 登记了 path 本身时使用登记的结果；
+//  2. 只登记了 path 的子字段时为部分生效；
+//  3. 否则继承最近的登记了的上级字段的支持程度（不继承说明）；上级字段是部分生效且登记了子字段时，未登记的字段使用 fallback；
+//  4. 都没有时使用 fallback
+func lookup(rules map[string]rule, path []string, fallback rule) rule {
+	key := strings.Join(path, ".")
+	if r, ok := rules[key]; ok {
+		return r
+	}
+	if hasDescendants(rules, key) {
+		return partial("")
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		ancestor := strings.Join(path[:i], ".")
+		r, ok := rules[ancestor]
+		if !ok {
+			continue
+		}
+		if r.support == SupportPartial && hasDescendants(rules, ancestor) {
+			return fallback
+		}
+		return rule{support: r.support}
+	}
+	return fallback
+}
+
+// hasDescendants 判断支持表中是否登记了 key 的子字段（key 为空表示资源本身）
+func hasDescendants(rules map[string]rule, key string) bool {
+	for k := range rules {
+		if k != key && (key == "" || strings.HasPrefix(k, key+".")) {
+			return true
+		}
+	}
+	return false
+}
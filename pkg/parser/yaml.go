@@ -7,12 +7,19 @@ import (
 	"os"
 	"strings"
 
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
+func init() {
+	// k3 自有资源（k3.io/v1）注册到全局 scheme，Store 与 apiserver 才能解码
+	utilruntime.Must(k3v1.AddToScheme(scheme.Scheme))
+}
+
 // Parser 是 Kubernetes YAML 解析器
 type Parser struct {
 	decoder runtime.Decoder
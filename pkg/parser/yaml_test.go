@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -407,3 +408,34 @@ spec:
 		})
 	}
 }
+
+func TestParseYAML_Device(t *testing.T) {
+	deviceYAML := `
+apiVersion: k3.io/v1
+kind: Device
+metadata:
+  name: aa-bb-cc-dd-ee-ff
+spec:
+  description: 客厅打印机
+status:
+  ip: 192.168.1.20
+  mac: aa:bb:cc:dd:ee:ff
+  online: true
+`
+
+	obj, gvk, err := NewParser().ParseYAML([]byte(deviceYAML))
+	if err != nil {
+		t.Fatalf("Failed to parse Device YAML: %v", err)
+	}
+	if gvk.Group != "k3.io" || gvk.Kind != "Device" {
+		t.Errorf("Expected k3.io Device, got %s", gvk.String())
+	}
+
+	device, ok := obj.(*k3v1.Device)
+	if !ok {
+		t.Fatalf("Expected *k3v1.Device, got %T", obj)
+	}
+	if device.Spec.Description != "客厅打印机" || device.Status.IP != "192.168.1.20" || !device.Status.Online {
+		t.Errorf("Unexpected device: %+v", device)
+	}
+}
@@ -0,0 +1,183 @@
+// Package printers 计算资源的展示字段（READY、STATUS、RESTARTS、AGE 等），
+// apiserver 的 Table 响应、Dashboard 与 CLI 共用，规则与 kubectl 一致
+package printers
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// PodSummary 是 Pod 的展示字段
+type PodSummary struct {
+	// Ready 为 "就绪容器数/容器数"，例如 "1/2"
+	Ready           string
+	ReadyContainers int
+	TotalContainers int
+	// Status 与 kubectl get pods 的 STATUS 列相同，例如 Running、Init:0/1、CrashLoopBackOff、Terminating
+	Status   string
+	Restarts int32
+	Age      string
+	Node     string
+	IP       string
+}
+
+// SummarizePod 计算 Pod 的展示字段，now 用于计算 Age
+func SummarizePod(pod *corev1.Pod, now time.Time) PodSummary {
+	ready, total := PodReadyContainers(pod)
+	return PodSummary{
+		Ready:           fmt.Sprintf("%d/%d", ready, total),
+		ReadyContainers: ready,
+		TotalContainers: total,
+		Status:          PodStatus(pod),
+		Restarts:        PodRestarts(pod),
+		Age:             Age(pod.CreationTimestamp.Time, now),
+		Node:            pod.Spec.NodeName,
+		IP:              pod.Status.PodIP,
+	}
+}
+
+// PodReadyContainers 返回就绪的容器数与容器总数。总数为 spec.containers 加上 restartPolicy: Always 的 init 容器（sidecar），
+// 普通 init 容器不计入；没有状态的容器视为未就绪
+func PodReadyContainers(pod *corev1.Pod) (ready, total int) {
+	statuses := make(map[string]bool, len(pod.Status.ContainerStatuses))
+	for _, cs := range pod.Status.ContainerStatuses {
+		statuses[cs.Name] = cs.Ready
+	}
+	for _, c := range pod.Spec.Containers {
+		total++
+		if statuses[c.Name] {
+			ready++
+		}
+	}
+
+	initStatuses := make(map[string]bool, len(pod.Status.InitContainerStatuses))
+	for _, cs := range pod.Status.InitContainerStatuses {
+		initStatuses[cs.Name] = cs.Ready
+	}
+	for _, c := range pod.Spec.InitContainers {
+		if !isSidecar(c) {
+			continue
+		}
+		total++
+		if initStatuses[c.Name] {
+			ready++
+		}
+	}
+	return ready, total
+}
+
+// isSidecar 判断 init 容器是否为 sidecar（restartPolicy: Always，与主容器一起运行）
+func isSidecar(c corev1.Container) bool {
+	return c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
+
+// PodRestarts 返回 init 容器与容器的重启次数之和
+func PodRestarts(pod *corev1.Pod) int32 {
+	var restarts int32
+	for _, cs := range pod.Status.InitContainerStatuses {
+		restarts += cs.RestartCount
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		restarts += cs.RestartCount
+	}
+	return restarts
+}
+
+// PodStatus 返回 kubectl get pods 的 STATUS 列：默认为 phase（或 status.reason），
+// init 容器未完成时为 Init:<已完成数>/<总数> 或 Init:<原因>，否则取最后一个等待/退出容器的原因，正在删除时为 Terminating
+func PodStatus(pod *corev1.Pod) string {
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+	if reason == "" {
+		reason = string(corev1.PodPending)
+	}
+
+	initializing := false
+	for i, cs := range pod.Status.InitContainerStatuses {
+		if i < len(pod.Spec.InitContainers) && isSidecar(pod.Spec.InitContainers[i]) && cs.Started != nil && *cs.Started {
+			continue
+		}
+		switch {
+		case cs.State.Terminated != nil && cs.State.Terminated.ExitCode == 0:
+			continue
+		case cs.State.Terminated != nil:
+			reason = "Init:" + terminatedReason(cs.State.Terminated)
+		case cs.State.Waiting != nil && cs.State.Waiting.Reason != "" && cs.State.Waiting.Reason != "PodInitializing":
+			reason = "Init:" + cs.State.Waiting.Reason
+		default:
+			reason = fmt.Sprintf("Init:%d/%d", i, len(pod.Spec.InitContainers))
+		}
+		initializing = true
+		break
+	}
+
+	if !initializing {
+		hasRunning := false
+		for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+			cs := pod.Status.ContainerStatuses[i]
+			switch {
+			case cs.State.Waiting != nil && cs.State.Waiting.Reason != "":
+				reason = cs.State.Waiting.Reason
+			case cs.State.Terminated != nil:
+				reason = terminatedReason(cs.State.Terminated)
+			case cs.Ready && cs.State.Running != nil:
+				hasRunning = true
+			}
+		}
+		// 部分容器已经正常退出、其余仍在运行
+		if reason == "Completed" && hasRunning {
+			if podReadyCondition(pod) {
+				reason = string(corev1.PodRunning)
+			} else {
+				reason = "NotReady"
+			}
+		}
+	}
+
+	if pod.DeletionTimestamp != nil {
+		if pod.Status.Reason == "NodeLost" {
+			return "Unknown"
+		}
+		return "Terminating"
+	}
+	return reason
+}
+
+// terminatedReason 返回退出容器的原因，没有原因时为 Signal:<n> 或 ExitCode:<n>
+func terminatedReason(t *corev1.ContainerStateTerminated) string {
+	if t.Reason != "" {
+		return t.Reason
+	}
+	if t.Signal != 0 {
+		return fmt.Sprintf("Signal:%d", t.Signal)
+	}
+	return fmt.Sprintf("ExitCode:%d", t.ExitCode)
+}
+
+// podReadyCondition 判断 Pod 的 Ready condition 是否为 True
+func podReadyCondition(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Age 返回 created 到 now 的时长（kubectl 的格式，例如 45s、3m20s、5h、12d）；created 为零值时为 <unknown>
+func Age(created, now time.Time) string {
+	if created.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(now.Sub(created))
+}
+
+// Replicas 返回 "就绪数/期望数"（Deployment、StatefulSet 等的 READY 列）
+func Replicas(ready, desired int32) string {
+	return fmt.Sprintf("%d/%d", ready, desired)
+}
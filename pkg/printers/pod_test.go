@@ -0,0 +1,191 @@
+package printers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func running(name string, ready bool, restarts int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:         name,
+		Ready:        ready,
+		RestartCount: restarts,
+		State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+}
+
+func TestPodReadyContainers(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	tests := []struct {
+		name string
+		pod  corev1.Pod
+		want string
+	}{
+		{
+			name: "没有状态的容器视为未就绪",
+			pod:  corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "a"}, {Name: "b"}}}},
+			want: "0/2",
+		},
+		{
+			name: "部分就绪",
+			pod: corev1.Pod{
+				Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "a"}, {Name: "b"}}},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{running("a", true, 0)}},
+			},
+			want: "1/2",
+		},
+		{
+			name: "普通 init 容器不计入",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "init"}},
+					Containers:     []corev1.Container{{Name: "a"}},
+				},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{running("a", true, 0)}},
+			},
+			want: "1/1",
+		},
+		{
+			name: "sidecar 计入",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "proxy", RestartPolicy: &always}},
+					Containers:     []corev1.Container{{Name: "a"}},
+				},
+				Status: corev1.PodStatus{
+					InitContainerStatuses: []corev1.ContainerStatus{running("proxy", true, 0)},
+					ContainerStatuses:     []corev1.ContainerStatus{running("a", false, 0)},
+				},
+			},
+			want: "1/2",
+		},
+	}
+	for _, tt := range tests {
+		if got := SummarizePod(&tt.pod, time.Now()).Ready; got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPodStatus(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name string
+		pod  corev1.Pod
+		want string
+	}{
+		{
+			name: "没有 phase",
+			pod:  corev1.Pod{},
+			want: "Pending",
+		},
+		{
+			name: "运行中",
+			pod: corev1.Pod{Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{running("a", true, 0)},
+			}},
+			want: "Running",
+		},
+		{
+			name: "init 容器进行中",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{InitContainers: []corev1.Container{{Name: "i1"}, {Name: "i2"}}},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodPending,
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{Name: "i1", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+						{Name: "i2", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+					},
+				},
+			},
+			want: "Init:1/2",
+		},
+		{
+			name: "init 容器失败",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{InitContainers: []corev1.Container{{Name: "i1"}}},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodPending,
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{Name: "i1", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2}}},
+					},
+				},
+			},
+			want: "Init:ExitCode:2",
+		},
+		{
+			name: "init 容器 CrashLoopBackOff",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{InitContainers: []corev1.Container{{Name: "i1"}}},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodPending,
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{Name: "i1", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+					},
+				},
+			},
+			want: "Init:CrashLoopBackOff",
+		},
+		{
+			name: "容器等待原因",
+			pod: corev1.Pod{Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{
+					running("a", true, 0),
+					{Name: "b", RestartCount: 3, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+				},
+			}},
+			want: "CrashLoopBackOff",
+		},
+		{
+			name: "部分容器正常退出",
+			pod: corev1.Pod{Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "job", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}},
+					running("a", true, 0),
+				},
+			}},
+			want: "Running",
+		},
+		{
+			name: "正在删除",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			want: "Terminating",
+		},
+	}
+	for _, tt := range tests {
+		if got := PodStatus(&tt.pod); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPodRestartsAndAge(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{{Name: "i1", RestartCount: 1}},
+			ContainerStatuses:     []corev1.ContainerStatus{running("a", true, 2), running("b", true, 4)},
+		},
+	}
+	s := SummarizePod(&pod, created.Add(90*time.Minute))
+	if s.Restarts != 7 {
+		t.Errorf("restarts: got %d, want 7", s.Restarts)
+	}
+	if s.Age != "90m" {
+		t.Errorf("age: got %s, want 90m", s.Age)
+	}
+	if got := Age(time.Time{}, time.Now()); got != "<unknown>" {
+		t.Errorf("zero age: got %s", got)
+	}
+}
@@ -0,0 +1,103 @@
+// Package resources 登记 k3 提供的资源：资源名（复数，如 pods）对应的 GroupVersionKind，以及哪些资源是集群级资源。
+// apiserver、存储与命令行客户端（k3ctl）共用这份登记；本包只依赖 apimachinery，客户端引用它不会带入服务端的依赖
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// byResource 资源名 -> 默认（存储）版本的 GVK
+var byResource = map[string]schema.GroupVersionKind{
+	"pods":                  {Version: "v1", Kind: "Pod"},
+	"services":              {Version: "v1", Kind: "Service"},
+	"endpoints":             {Version: "v1", Kind: "Endpoints"},
+	"configmaps":            {Version: "v1", Kind: "ConfigMap"},
+	"secrets":               {Version: "v1", Kind: "Secret"},
+	"events":                {Version: "v1", Kind: "Event"},
+	"nodes":                 {Version: "v1", Kind: "Node"},
+	"namespaces":            {Version: "v1", Kind: "Namespace"},
+	"serviceaccounts":       {Version: "v1", Kind: "ServiceAccount"},
+	"resourcequotas":        {Version: "v1", Kind: "ResourceQuota"},
+	"limitranges":           {Version: "v1", Kind: "LimitRange"},
+	"networkpolicies":       {Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
+	"deployments":           {Group: "apps", Version: "v1", Kind: "Deployment"},
+	"statefulsets":          {Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	"daemonsets":            {Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	"devices":               k3v1.DeviceGVK,
+	"clientusages":          k3v1.ClientUsageGVK,
+	"gitrepositories":       k3v1.GitRepositoryGVK,
+	"clusterconfigurations": k3v1.ClusterConfigurationGVK,
+	"clusterinfos":          k3v1.ClusterInfoGVK,
+	"priorityclasses":       {Group: "scheduling.k8s.io", Version: "v1", Kind: "PriorityClass"},
+	"poddisruptionbudgets":  {Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"},
+}
+
+// clusterScopedKinds 登记集群级资源（没有 namespace）。存储的三个后端都按它决定资源的存储位置：
+// 集群级资源读写时忽略 namespace 参数，与同名的 namespace 级资源互不冲突
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Group: "", Kind: "Node"}:                             true,
+	{Group: "", Kind: "Namespace"}:                        true,
+	{Group: k3v1.GroupName, Kind: "Device"}:               true,
+	{Group: k3v1.GroupName, Kind: "ClientUsage"}:          true,
+	{Group: k3v1.GroupName, Kind: "ClusterConfiguration"}: true,
+	{Group: k3v1.GroupName, Kind: "ClusterInfo"}:          true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:   true,
+}
+
+// ForResource 根据资源名（复数，如 pods、deployments，不区分大小写）返回对应的 GroupVersionKind
+func ForResource(resource string) (schema.GroupVersionKind, error) {
+	gvk, ok := byResource[strings.ToLower(strings.TrimSpace(resource))]
+	if !ok {
+		return schema.GroupVersionKind{}, fmt.Errorf("unsupported resource: %s", resource)
+	}
+	return gvk, nil
+}
+
+// ResourceForKind 返回 Kind 对应的资源名（复数）
+func ResourceForKind(kind string) (string, bool) {
+	for resource, gvk := range byResource {
+		if gvk.Kind == kind {
+			return resource, true
+		}
+	}
+	return "", false
+}
+
+// IsClusterScoped 判断 gvk 是否是集群级资源
+func IsClusterScoped(gvk schema.GroupVersionKind) bool {
+	return clusterScopedKinds[gvk.GroupKind()]
+}
+
+// ClusterScopedKinds 返回登记的所有集群级资源
+func ClusterScopedKinds() []schema.GroupKind {
+	kinds := make([]schema.GroupKind, 0, len(clusterScopedKinds))
+	for gk := range clusterScopedKinds {
+		kinds = append(kinds, gk)
+	}
+	return kinds
+}
+
+// IsClusterScopedKind 只按 Kind 判断是否是集群级资源（apiserver 路由与命令行只知道 Kind 时使用）
+func IsClusterScopedKind(kind string) bool {
+	for gk := range clusterScopedKinds {
+		if gk.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Resources 返回登记的所有资源名（复数），按名称排序
+func Resources() []string {
+	names := make([]string, 0, len(byResource))
+	for resource := range byResource {
+		names = append(names, resource)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -4,24 +4,63 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/version"
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// defaultEtcdRequestTimeout 是 etcd 单次读写请求的默认超时
+const defaultEtcdRequestTimeout = 5 * time.Second
+
+const (
+	// etcdLegacyPrefix schema v3 之前的资源键前缀（/kubernetes/<group>/<version>/<kind>/[<namespace>/]<name>），
+	// 只属于使用默认前缀的集群
+	etcdLegacyPrefix = "/kubernetes/"
+	// etcdIndexBatch ListBySelector 按索引读取资源时每个事务读取的键数（低于 etcd 默认的 128 个操作上限）
+	etcdIndexBatch = 64
+)
+
 // EtcdStore 是基于 etcd 的存储实现
 type EtcdStore struct {
-	client   *clientv3.Client
-	parser   *parser.Parser
-	watchers map[string][]chan ResourceEvent
-	ctx      context.Context
-	cancel   context.CancelFunc
+	client  *clientv3.Client
+	parser  *parser.Parser
+	watches *watchHub
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// requestTimeout 单次读写请求超时
+	requestTimeout time.Duration
+
+	// watchMu 保护 watchCancel（Reconnect 时重建 watch）
+	watchMu     sync.Mutex
+	watchCancel context.CancelFunc
+
+	// legacyKeys 为 true 时读操作同时查找旧布局的键（确认 schema 已迁移到 v3 之前保持开启）
+	legacyKeys atomic.Bool
+
+	// labelIndexReady 为 true 时 ListBySelector 使用标签索引键（确认 schema 已迁移到 v4 之后开启）
+	labelIndexReady atomic.Bool
+
+	// historyLimit 每个对象保留的历史版本数，0 表示不记录
+	historyLimit int
+
+	// keys 本集群的键（storage.etcd.prefix）
+	keys etcdKeyspace
+	// migrateFrom 为 storage.etcd.migrate_from：本前缀下还没有数据时，Migrate 把旧前缀下的数据移过来；未配置时为 nil
+	migrateFrom *etcdKeyspace
+	// ownsLegacy 本集群（或迁移来源）使用默认前缀时，旧布局（/kubernetes/...）的数据属于本集群
+	ownsLegacy bool
 }
 
 // NewEtcdStore 创建新的 etcd 存储
@@ -35,6 +74,30 @@ func NewEtcdStore(cfg config.EtcdConfig) (*EtcdStore, error) {
 		}
 	}
 
+	requestTimeout := defaultEtcdRequestTimeout
+	if cfg.RequestTimeout != "" {
+		var err error
+		requestTimeout, err = time.ParseDuration(cfg.RequestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request_timeout: %w", err)
+		}
+	}
+
+	prefix, err := normalizeEtcdPrefix(cfg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	var migrateFrom *etcdKeyspace
+	if strings.TrimSpace(cfg.MigrateFrom) != "" {
+		from, err := normalizeEtcdPrefix(cfg.MigrateFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migrate_from: %w", err)
+		}
+		if from != prefix {
+			migrateFrom = &etcdKeyspace{prefix: from}
+		}
+	}
+
 	client, err := clientv3.New(clientv3.Config{
 		Endpoints:   cfg.Endpoints,
 		DialTimeout: dialTimeout,
@@ -48,50 +111,88 @@ func NewEtcdStore(cfg config.EtcdConfig) (*EtcdStore, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	store := &EtcdStore{
-		client:   client,
-		parser:   parser.NewParser(),
-		watchers: make(map[string][]chan ResourceEvent),
-		ctx:      ctx,
-		cancel:   cancel,
-	}
+		client:         client,
+		parser:         parser.NewParser(),
+		watches:        newWatchHub(false),
+		ctx:            ctx,
+		cancel:         cancel,
+		requestTimeout: requestTimeout,
+		historyLimit:   DefaultHistoryRevisions,
+		keys:           etcdKeyspace{prefix: prefix},
+		migrateFrom:    migrateFrom,
+		ownsLegacy:     prefix == DefaultEtcdPrefix || (migrateFrom != nil && migrateFrom.prefix == DefaultEtcdPrefix),
+	}
+	store.legacyKeys.Store(store.ownsLegacy)
 
 	// 启动 watch 监听器
-	go store.startWatcher()
+	store.startWatcher()
 
 	return store, nil
 }
 
+// requestContext 返回单次读写请求使用的 context（带超时，Close 后立即取消）
+func (s *EtcdStore) requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(s.ctx, s.requestTimeout)
+}
+
 // resourceKey 生成 etcd 中的资源键
 func (s *EtcdStore) resourceKey(gvk schema.GroupVersionKind, namespace, name string) string {
+	return s.keys.resources() + resourcePath(gvk, namespace, name)
+}
+
+// collectionKey 生成一类资源（指定 namespace 或所有 namespace）在 etcd 中的键前缀，List 按它读取
+func (s *EtcdStore) collectionKey(gvk schema.GroupVersionKind, namespace string) string {
+	return s.keys.resources() + collectionPath(gvk, scopedNamespace(gvk, namespace))
+}
+
+// legacyKey 生成旧布局中的资源键（旧版本写入集群级资源时可能带 namespace，兼容模式下两种都查）
+func legacyKey(gvk schema.GroupVersionKind, namespace, name string) string {
 	if namespace == "" {
-		return fmt.Sprintf("/kubernetes/%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, name)
+		return fmt.Sprintf("%s%s/%s/%s/%s", etcdLegacyPrefix, gvk.Group, gvk.Version, gvk.Kind, name)
 	}
-	return fmt.Sprintf("/kubernetes/%s/%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, namespace, name)
+	return fmt.Sprintf("%s%s/%s/%s/%s/%s", etcdLegacyPrefix, gvk.Group, gvk.Version, gvk.Kind, namespace, name)
 }
 
-// watchKey 生成 watch 的键前缀
-func (s *EtcdStore) watchKey(gvk schema.GroupVersionKind, namespace string) string {
-	if namespace == "" {
-		return fmt.Sprintf("/kubernetes/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind)
+// getValue 读取资源的原始数据：先查新布局，兼容模式下再查旧布局；资源不存在时 value 为 nil，
+// legacy 返回数据所在的旧键（在新布局中时为空）
+func (s *EtcdStore) getValue(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (value []byte, legacy string, err error) {
+	keys := []string{s.resourceKey(gvk, namespace, name)}
+	if s.legacyKeys.Load() {
+		keys = append(keys, legacyKey(gvk, namespace, name))
+		if IsClusterScoped(gvk) && namespace != "" {
+			keys = append(keys, legacyKey(gvk, "", name))
+		}
+	}
+	for i, key := range keys {
+		resp, err := s.client.Get(ctx, key)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get from etcd: %w", err)
+		}
+		if len(resp.Kvs) > 0 {
+			if i > 0 {
+				legacy = key
+			}
+			return resp.Kvs[0].Value, legacy, nil
+		}
 	}
-	return fmt.Sprintf("/kubernetes/%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, namespace)
+	return nil, "", nil
 }
 
 // Get 获取指定资源
 func (s *EtcdStore) Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
-	key := s.resourceKey(gvk, namespace, name)
+	ctx, cancel := s.requestContext()
+	defer cancel()
 
-	resp, err := s.client.Get(context.Background(), key)
+	value, _, err := s.getValue(ctx, gvk, objectNamespace(gvk, namespace), name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get from etcd: %w", err)
+		return nil, err
 	}
-
-	if len(resp.Kvs) == 0 {
+	if value == nil {
 		return nil, fmt.Errorf("resource not found: %s/%s", namespace, name)
 	}
 
 	// 解析数据
-	obj, _, err := s.parser.ParseYAML(resp.Kvs[0].Value)
+	obj, _, err := s.parser.ParseYAML(value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse resource data: %w", err)
 	}
@@ -99,57 +200,218 @@ func (s *EtcdStore) Get(gvk schema.GroupVersionKind, namespace, name string) (ru
 	return obj, nil
 }
 
-// List 列出所有资源
+// List 列出所有资源：只读取该 GVK（与 namespace）的前缀
 func (s *EtcdStore) List(gvk schema.GroupVersionKind, namespace string) ([]runtime.Object, error) {
-	prefix := s.watchKey(gvk, namespace)
+	ctx, cancel := s.requestContext()
+	defer cancel()
 
-	resp, err := s.client.Get(context.Background(), prefix, clientv3.WithPrefix())
+	namespace = scopedNamespace(gvk, namespace)
+	resp, err := s.client.Get(ctx, s.collectionKey(gvk, namespace), clientv3.WithPrefix())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list from etcd: %w", err)
 	}
 
 	var objects []runtime.Object
+	seen := make(map[string]bool, len(resp.Kvs))
 	for _, kv := range resp.Kvs {
 		obj, _, err := s.parser.ParseYAML(kv.Value)
 		if err != nil {
 			continue
 		}
+		seen[strings.TrimPrefix(string(kv.Key), s.keys.resources())] = true
+		objects = append(objects, obj)
+	}
+
+	if s.legacyKeys.Load() {
+		legacy, err := s.listLegacy(ctx, gvk, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range legacy {
+			meta, err := getObjectMeta(obj)
+			if err != nil || seen[resourcePath(gvk, meta.GetNamespace(), meta.GetName())] {
+				continue
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	SortObjects(objects)
+	return objects, nil
+}
 
-		// 如果指定了 namespace，过滤
+// listLegacy 列出旧布局中该 GVK（与 namespace）的资源
+func (s *EtcdStore) listLegacy(ctx context.Context, gvk schema.GroupVersionKind, namespace string) ([]runtime.Object, error) {
+	prefix := fmt.Sprintf("%s%s/%s/%s/", etcdLegacyPrefix, gvk.Group, gvk.Version, gvk.Kind)
+	resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legacy keys from etcd: %w", err)
+	}
+	var objects []runtime.Object
+	for _, kv := range resp.Kvs {
+		obj, _, err := s.parser.ParseYAML(kv.Value)
+		if err != nil {
+			continue
+		}
 		if namespace != "" {
 			meta, err := getObjectMeta(obj)
 			if err == nil && meta.GetNamespace() != namespace {
 				continue
 			}
 		}
-
 		objects = append(objects, obj)
 	}
+	return objects, nil
+}
+
+// ListBySelector 用 selector 中第一个 = 或 in 条件的标签索引键找到候选资源，再按完整的 selector 过滤；
+// 没有这类条件或索引尚未建立（schema 迁移到 v4 之前）时等同于 List 后过滤
+func (s *EtcdStore) ListBySelector(gvk schema.GroupVersionKind, namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	if selector == nil || selector.Empty() {
+		return s.List(gvk, namespace)
+	}
+	reqs, selectable := indexableRequirements(selector)
+	if !selectable {
+		return nil, nil
+	}
+	var lookup *labels.Requirement
+	for i := range reqs {
+		if reqs[i].Operator() != selection.Exists {
+			lookup = &reqs[i]
+			break
+		}
+	}
+	if lookup == nil || !s.labelIndexReady.Load() {
+		objects, err := s.List(gvk, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return filterBySelector(objects, selector), nil
+	}
+
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
+	namespace = scopedNamespace(gvk, namespace)
+	var keys []string
+	for _, value := range lookup.Values().List() {
+		prefix := s.labelIndexPrefix(gvk, lookup.Key(), value)
+		if namespace != "" {
+			prefix += namespace + "/"
+		}
+		resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read label index from etcd: %w", err)
+		}
+		for _, kv := range resp.Kvs {
+			keys = append(keys, string(kv.Value))
+		}
+	}
 
+	var objects []runtime.Object
+	for start := 0; start < len(keys); start += etcdIndexBatch {
+		end := min(start+etcdIndexBatch, len(keys))
+		ops := make([]clientv3.Op, 0, end-start)
+		for _, key := range keys[start:end] {
+			ops = append(ops, clientv3.OpGet(key))
+		}
+		resp, err := s.client.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get indexed resources from etcd: %w", err)
+		}
+		for _, r := range resp.Responses {
+			// 索引键指向的资源已被删除时跳过
+			for _, kv := range r.GetResponseRange().Kvs {
+				obj, _, err := s.parser.ParseYAML(kv.Value)
+				if err != nil {
+					continue
+				}
+				if matchesSelector(obj, selector) {
+					objects = append(objects, obj)
+				}
+			}
+		}
+	}
+	SortObjects(objects)
 	return objects, nil
 }
 
+// labelIndexPrefix 返回某个标签取值的索引键前缀（以 / 结尾）
+func (s *EtcdStore) labelIndexPrefix(gvk schema.GroupVersionKind, key, value string) string {
+	group := gvk.Group
+	if group == "" {
+		group = "core"
+	}
+	return s.keys.index() + group + "/" + gvk.Version + "/" + gvk.Kind + "/" + key + "=" + value + "/"
+}
+
+// labelIndexKeys 返回对象的标签索引键 → 资源键
+func (s *EtcdStore) labelIndexKeys(gvk schema.GroupVersionKind, obj runtime.Object) map[string]string {
+	meta, err := getObjectMeta(obj)
+	if err != nil {
+		return nil
+	}
+	namespace := objectNamespace(gvk, meta.GetNamespace())
+	resourceKey := s.resourceKey(gvk, namespace, meta.GetName())
+	keys := make(map[string]string, len(meta.GetLabels()))
+	for k, v := range meta.GetLabels() {
+		keys[s.labelIndexPrefix(gvk, k, v)+namespace+"/"+meta.GetName()] = resourceKey
+	}
+	return keys
+}
+
+// labelIndexOps 返回对象从 oldObj 变为 obj 时维护标签索引的操作：删除不再存在的标签的索引键，写入新的索引键。
+// oldObj 或 obj 为 nil 分别表示创建与删除
+func (s *EtcdStore) labelIndexOps(gvk schema.GroupVersionKind, oldObj, obj runtime.Object) []clientv3.Op {
+	var oldKeys, newKeys map[string]string
+	if oldObj != nil {
+		oldKeys = s.labelIndexKeys(gvk, oldObj)
+	}
+	if obj != nil {
+		newKeys = s.labelIndexKeys(gvk, obj)
+	}
+	var ops []clientv3.Op
+	for key := range oldKeys {
+		if _, ok := newKeys[key]; !ok {
+			ops = append(ops, clientv3.OpDelete(key))
+		}
+	}
+	for key, value := range newKeys {
+		if _, ok := oldKeys[key]; !ok {
+			ops = append(ops, clientv3.OpPut(key, value))
+		}
+	}
+	return ops
+}
+
 // Create 创建资源
 func (s *EtcdStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
 	meta, err := getObjectMeta(obj)
 	if err != nil {
 		return err
 	}
 
-	namespace := meta.GetNamespace()
+	// 集群级资源不带 namespace，namespace 级资源默认为 default
+	namespace := objectNamespace(gvk, meta.GetNamespace())
+	meta.SetNamespace(namespace)
 	name := meta.GetName()
 	key := s.resourceKey(gvk, namespace, name)
 
-	// 检查资源是否已存在
-	resp, err := s.client.Get(context.Background(), key)
+	// 检查资源是否已存在（包括旧布局中的键）
+	existing, _, err := s.getValue(ctx, gvk, namespace, name)
 	if err != nil {
 		return fmt.Errorf("failed to check resource existence: %w", err)
 	}
 
-	if len(resp.Kvs) > 0 {
+	if existing != nil {
 		return fmt.Errorf("resource already exists: %s/%s", namespace, name)
 	}
 
+	initGeneration(obj)
+
 	// 序列化对象
 	data, err := json.Marshal(obj)
 	if err != nil {
@@ -174,14 +436,15 @@ func (s *EtcdStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) erro
 		meta.SetUID(types.UID(fmt.Sprintf("uid-%d", time.Now().UnixNano())))
 	}
 
-	// 保存到 etcd
-	_, err = s.client.Put(context.Background(), key, string(data))
-	if err != nil {
+	// 保存到 etcd（与标签索引键在同一个事务中写入）
+	ops := append([]clientv3.Op{clientv3.OpPut(key, string(data))}, s.labelIndexOps(gvk, nil, obj)...)
+	if _, err := s.client.Txn(ctx).Then(ops...).Commit(); err != nil {
 		return fmt.Errorf("failed to put to etcd: %w", err)
 	}
+	s.recordRevision(ctx, gvk, namespace, name, RevisionCreate, nil, obj, "")
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventAdded,
 		Object: obj,
 	})
@@ -191,29 +454,47 @@ func (s *EtcdStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) erro
 
 // Update 更新资源
 func (s *EtcdStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	return s.UpdateIf(gvk, obj, "")
+}
+
+// UpdateIf 仅当对象仍是 resourceVersion 时更新（见 ConditionalUpdater）：事务比较读取时的旧值，
+// 读取之后有其他写入时事务不生效并返回冲突
+func (s *EtcdStore) UpdateIf(gvk schema.GroupVersionKind, obj runtime.Object, resourceVersion string) error {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
 	meta, err := getObjectMeta(obj)
 	if err != nil {
 		return err
 	}
 
-	namespace := meta.GetNamespace()
+	namespace := objectNamespace(gvk, meta.GetNamespace())
+	meta.SetNamespace(namespace)
 	name := meta.GetName()
 	key := s.resourceKey(gvk, namespace, name)
 
 	// 获取旧资源
-	resp, err := s.client.Get(context.Background(), key)
+	value, legacy, err := s.getValue(ctx, gvk, namespace, name)
 	if err != nil {
 		return fmt.Errorf("failed to get old resource: %w", err)
 	}
 
-	if len(resp.Kvs) == 0 {
+	if value == nil {
 		return fmt.Errorf("resource not found: %s/%s", namespace, name)
 	}
 
-	oldObj, _, err := s.parser.ParseYAML(resp.Kvs[0].Value)
+	oldObj, _, err := s.parser.ParseYAML(value)
 	if err != nil {
 		return fmt.Errorf("failed to parse old resource: %w", err)
 	}
+	if err := checkResourceVersion(gvk, oldObj, resourceVersion); err != nil {
+		return err
+	}
+
+	updateGeneration(oldObj, obj)
+
+	// 更新 resourceVersion（在序列化之前，保存的内容带有新版本，前置条件才能比较出之后的修改）
+	meta.SetResourceVersion(fmt.Sprintf("%d", time.Now().UnixNano()))
 
 	// 序列化新对象
 	data, err := json.Marshal(obj)
@@ -221,18 +502,29 @@ func (s *EtcdStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) erro
 		return fmt.Errorf("failed to marshal object: %w", err)
 	}
 
-	// 更新 resourceVersion
-	resourceVersion := fmt.Sprintf("%d", time.Now().UnixNano())
-	meta.SetResourceVersion(resourceVersion)
-
-	// 更新 etcd
-	_, err = s.client.Put(context.Background(), key, string(data))
+	// 更新 etcd（旧布局中的资源写入新键并删除旧键）
+	ops := []clientv3.Op{clientv3.OpPut(key, string(data))}
+	readKey := key
+	if legacy != "" {
+		ops = append(ops, clientv3.OpDelete(legacy))
+		readKey = legacy
+	}
+	ops = append(ops, s.labelIndexOps(gvk, oldObj, obj)...)
+	txn := s.client.Txn(ctx)
+	if resourceVersion != "" {
+		txn = txn.If(clientv3.Compare(clientv3.Value(readKey), "=", string(value)))
+	}
+	resp, err := txn.Then(ops...).Commit()
 	if err != nil {
 		return fmt.Errorf("failed to update etcd: %w", err)
 	}
+	if !resp.Succeeded {
+		return &ResourceVersionConflict{Kind: gvk.Kind, Namespace: namespace, Name: name, ResourceVersion: resourceVersion}
+	}
+	s.recordRevision(ctx, gvk, namespace, name, RevisionUpdate, oldObj, obj, "")
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventModified,
 		Object: obj,
 		OldObj: oldObj,
@@ -243,31 +535,44 @@ func (s *EtcdStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) erro
 
 // Delete 删除资源
 func (s *EtcdStore) Delete(gvk schema.GroupVersionKind, namespace, name string) error {
+	return s.DeleteAs(gvk, namespace, name, "")
+}
+
+// DeleteAs 以 manager 的身份删除资源
+func (s *EtcdStore) DeleteAs(gvk schema.GroupVersionKind, namespace, name, manager string) error {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
+	namespace = objectNamespace(gvk, namespace)
 	key := s.resourceKey(gvk, namespace, name)
 
 	// 获取资源（用于返回和通知）
-	resp, err := s.client.Get(context.Background(), key)
+	value, legacy, err := s.getValue(ctx, gvk, namespace, name)
 	if err != nil {
 		return fmt.Errorf("failed to get resource: %w", err)
 	}
 
-	if len(resp.Kvs) == 0 {
+	if value == nil {
 		return fmt.Errorf("resource not found: %s/%s", namespace, name)
 	}
 
-	obj, _, err := s.parser.ParseYAML(resp.Kvs[0].Value)
+	obj, _, err := s.parser.ParseYAML(value)
 	if err != nil {
 		return fmt.Errorf("failed to parse resource: %w", err)
 	}
 
 	// 删除资源
-	_, err = s.client.Delete(context.Background(), key)
-	if err != nil {
+	if legacy != "" {
+		key = legacy
+	}
+	ops := append([]clientv3.Op{clientv3.OpDelete(key)}, s.labelIndexOps(gvk, obj, nil)...)
+	if _, err := s.client.Txn(ctx).Then(ops...).Commit(); err != nil {
 		return fmt.Errorf("failed to delete from etcd: %w", err)
 	}
+	s.recordRevision(ctx, gvk, namespace, name, RevisionDelete, nil, obj, manager)
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventDeleted,
 		Object: obj,
 	})
@@ -275,24 +580,96 @@ func (s *EtcdStore) Delete(gvk schema.GroupVersionKind, namespace, name string)
 	return nil
 }
 
+// DeleteCollection 删除满足 match 的资源（逐个删除，非原子）
+func (s *EtcdStore) DeleteCollection(gvk schema.GroupVersionKind, namespace string, match func(runtime.Object) bool) ([]runtime.Object, error) {
+	return deleteMatching(s, gvk, namespace, match)
+}
+
+// History 返回对象保留的版本
+func (s *EtcdStore) History(gvk schema.GroupVersionKind, namespace, name string) ([]Revision, error) {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
+	revisions, _, err := s.getHistory(ctx, s.keys.history()+resourcePath(gvk, namespace, name))
+	return revisions, err
+}
+
+// getHistory 读取历史键，返回版本列表与键的 ModRevision（键不存在时为 0）
+func (s *EtcdStore) getHistory(ctx context.Context, key string) ([]Revision, int64, error) {
+	resp, err := s.client.Get(ctx, key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get history: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, 0, nil
+	}
+	var revisions []Revision
+	if err := json.Unmarshal(resp.Kvs[0].Value, &revisions); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse history: %w", err)
+	}
+	return revisions, resp.Kvs[0].ModRevision, nil
+}
+
+// recordRevision 把对象的一个版本追加到历史键；并发写入同一对象时按 ModRevision 比较后重试，
+// 失败时不影响本次写入
+func (s *EtcdStore) recordRevision(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string, op RevisionOperation, old, obj runtime.Object, manager string) {
+	if s.historyLimit <= 0 {
+		return
+	}
+	rev, err := newRevision(op, old, obj, manager)
+	if err != nil {
+		return
+	}
+	key := s.keys.history() + resourcePath(gvk, namespace, name)
+	for attempt := 0; attempt < 3; attempt++ {
+		revisions, modRevision, err := s.getHistory(ctx, key)
+		if err != nil {
+			return
+		}
+		data, err := json.Marshal(appendRevision(revisions, rev, s.historyLimit))
+		if err != nil {
+			return
+		}
+		resp, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", modRevision)).
+			Then(clientv3.OpPut(key, string(data))).
+			Commit()
+		if err != nil || resp.Succeeded {
+			return
+		}
+	}
+}
+
 // Watch 监听资源变更
 func (s *EtcdStore) Watch(gvk schema.GroupVersionKind, namespace string, resourceVersion string) (<-chan ResourceEvent, error) {
-	watchKey := s.watchKey(gvk, namespace)
-	ch := make(chan ResourceEvent, 100)
+	return s.watches.add(gvk, namespace), nil
+}
 
-	// 注册 watcher
-	if s.watchers[watchKey] == nil {
-		s.watchers[watchKey] = make([]chan ResourceEvent, 0)
-	}
-	s.watchers[watchKey] = append(s.watchers[watchKey], ch)
+// StopWatcher 注销并关闭 Watch 返回的通道
+func (s *EtcdStore) StopWatcher(gvk schema.GroupVersionKind, namespace string, ch <-chan ResourceEvent) {
+	s.watches.remove(gvk, namespace, ch)
+}
 
-	return ch, nil
+// WatchStats 返回各组 watcher 的数量与积压的事件数
+func (s *EtcdStore) WatchStats() []WatchStats {
+	return s.watches.stats()
 }
 
-// startWatcher 启动 etcd watch 监听器
+// startWatcher 启动 etcd watch 监听器（已有监听器时先停止旧的）
 func (s *EtcdStore) startWatcher() {
-	// 监听所有 /kubernetes 前缀的键
-	watchChan := s.client.Watch(s.ctx, "/kubernetes", clientv3.WithPrefix())
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.watchCancel != nil {
+		s.watchCancel()
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.watchCancel = cancel
+	go s.watch(ctx)
+}
+
+// watch 监听资源前缀下的键并通知 watchers，直到 ctx 结束
+func (s *EtcdStore) watch(ctx context.Context) {
+	watchChan := s.client.Watch(ctx, s.keys.resources(), clientv3.WithPrefix())
 
 	for watchResp := range watchChan {
 		for _, event := range watchResp.Events {
@@ -302,13 +679,8 @@ func (s *EtcdStore) startWatcher() {
 				continue
 			}
 
-			// 从对象获取 GVK 和 namespace
+			// 从对象获取 GVK（namespace 由 watchHub 从对象读取）
 			gvk := obj.GetObjectKind().GroupVersionKind()
-			meta, err := getObjectMeta(obj)
-			if err != nil {
-				continue
-			}
-			namespace := meta.GetNamespace()
 
 			// 确定事件类型
 			var eventType EventType
@@ -326,7 +698,7 @@ func (s *EtcdStore) startWatcher() {
 			}
 
 			// 通知 watchers
-			s.notifyWatchers(gvk, namespace, ResourceEvent{
+			s.watches.notify(gvk, ResourceEvent{
 				Type:   eventType,
 				Object: obj,
 			})
@@ -334,24 +706,22 @@ func (s *EtcdStore) startWatcher() {
 	}
 }
 
-// notifyWatchers 通知所有 watchers
-func (s *EtcdStore) notifyWatchers(gvk schema.GroupVersionKind, namespace string, event ResourceEvent) {
-	watchKey := s.watchKey(gvk, namespace)
-	watchers := s.watchers[watchKey]
-
-	// 也通知全局 watchers
-	if namespace != "" {
-		globalKey := s.watchKey(gvk, "")
-		watchers = append(watchers, s.watchers[globalKey]...)
-	}
-
-	for _, ch := range watchers {
-		select {
-		case ch <- event:
-		default:
-			// 如果通道已满，跳过
+// Reconnect 确认 etcd 可用后重建 watch：没有持久化数据目录的 etcd 重启后 revision 从头开始，
+// 旧的 watch 会一直等待已经不存在的 revision，收不到新的事件
+func (s *EtcdStore) Reconnect(ctx context.Context) error {
+	var lastErr error
+	for _, endpoint := range s.client.Endpoints() {
+		if _, err := s.client.Status(ctx, endpoint); err != nil {
+			lastErr = err
+			continue
 		}
+		s.startWatcher()
+		return nil
 	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no etcd endpoints")
+	}
+	return lastErr
 }
 
 // Close 关闭 etcd 连接
@@ -359,3 +729,273 @@ func (s *EtcdStore) Close() error {
 	s.cancel()
 	return s.client.Close()
 }
+
+// etcdSchemaRecord schema 版本记录
+type etcdSchemaRecord struct {
+	Version       int       `json:"version"`
+	BinaryVersion string    `json:"binaryVersion"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// SchemaVersion 读取 <prefix>/schema；没有记录时按是否已有资源区分空库（0）与旧版本的数据（1）。
+// 配置了 migrate_from 且本前缀下还没有记录时，返回旧前缀下的版本（数据在 Migrate 时移过来）。
+// 确认版本不低于 v3 后关闭旧布局的兼容读取
+func (s *EtcdStore) SchemaVersion(ctx context.Context) (int, error) {
+	v, err := s.schemaVersion(ctx)
+	if err == nil {
+		s.legacyKeys.Store(s.ownsLegacy && v > 0 && v < 3)
+		s.labelIndexReady.Store(v >= 4)
+	}
+	return v, err
+}
+
+// schemaVersion 读取记录的 schema 版本
+func (s *EtcdStore) schemaVersion(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+
+	v, found, err := s.readSchemaRecord(ctx, s.keys)
+	if err != nil || found {
+		return v, err
+	}
+	if s.migrateFrom != nil {
+		v, found, err := s.readSchemaRecord(ctx, *s.migrateFrom)
+		if err != nil || found {
+			return v, err
+		}
+	}
+	if !s.ownsLegacy {
+		return 0, nil
+	}
+
+	resp, err := s.client.Get(ctx, etcdLegacyPrefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return 0, fmt.Errorf("failed to check existing resources: %w", err)
+	}
+	if resp.Count == 0 {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// readSchemaRecord 读取 keys 下的 schema 记录，没有记录时 found 为 false
+func (s *EtcdStore) readSchemaRecord(ctx context.Context, keys etcdKeyspace) (v int, found bool, err error) {
+	resp, err := s.client.Get(ctx, keys.schema())
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, false, nil
+	}
+	var record etcdSchemaRecord
+	if err := json.Unmarshal(resp.Kvs[0].Value, &record); err != nil {
+		return 0, false, fmt.Errorf("failed to parse schema version: %w", err)
+	}
+	return record.Version, true, nil
+}
+
+// Migrate 执行待执行的 schema 迁移并记录版本；v2、v5、v6 只涉及 MySQL 表结构，etcd 只记录版本。
+// 配置了 migrate_from 时先把旧前缀下的数据移到本前缀
+func (s *EtcdStore) Migrate(ctx context.Context) ([]Migration, error) {
+	if err := s.movePrefix(ctx); err != nil {
+		return nil, err
+	}
+	stored, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := runMigrations(ctx, stored, map[int]func(context.Context) error{
+		3: s.moveLegacyKeys,
+		4: s.buildLabelIndex,
+		7: s.defaultEmptyNamespaces,
+	}, s.recordSchemaVersion)
+	if err == nil {
+		s.legacyKeys.Store(false)
+		s.labelIndexReady.Store(true)
+	}
+	return applied, err
+}
+
+// movePrefix 把 migrate_from 前缀下的资源、历史与标签索引移到本前缀，最后移动 schema 记录。
+// 本前缀下已有 schema 记录时（已迁移过或本集群已有数据）什么也不做；中途失败时下次启动继续，
+// 新键已存在时保留新键，只删除旧键
+func (s *EtcdStore) movePrefix(ctx context.Context) error {
+	if s.migrateFrom == nil {
+		return nil
+	}
+	from := *s.migrateFrom
+	rctx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	_, found, err := s.readSchemaRecord(rctx, s.keys)
+	cancel()
+	if err != nil || found {
+		return err
+	}
+	for _, dir := range from.dirs() {
+		resp, err := s.client.Get(ctx, dir, clientv3.WithPrefix())
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		for _, kv := range resp.Kvs {
+			oldKey := string(kv.Key)
+			newKey := rebaseKey(oldKey, from, s.keys)
+			value := string(kv.Value)
+			if dir == from.index() {
+				// 索引键的值为资源键
+				value = rebaseKey(value, from, s.keys)
+			}
+			if err := s.moveKey(ctx, oldKey, newKey, value); err != nil {
+				return err
+			}
+		}
+	}
+	resp, err := s.client.Get(ctx, from.schema())
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", from.schema(), err)
+	}
+	if len(resp.Kvs) > 0 {
+		if err := s.moveKey(ctx, from.schema(), s.keys.schema(), string(resp.Kvs[0].Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveKey 在一个事务中写入 newKey（已存在时保留）并删除 oldKey
+func (s *EtcdStore) moveKey(ctx context.Context, oldKey, newKey, value string) error {
+	_, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(newKey), "=", 0)).
+		Then(clientv3.OpPut(newKey, value), clientv3.OpDelete(oldKey)).
+		Else(clientv3.OpDelete(oldKey)).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", oldKey, newKey, err)
+	}
+	return nil
+}
+
+// moveLegacyKeys 把旧布局（/kubernetes/...）的资源移动到新布局（v3）。
+// 新键已存在时（兼容模式下已被更新过）保留新键，只删除旧键
+func (s *EtcdStore) moveLegacyKeys(ctx context.Context) error {
+	if !s.ownsLegacy {
+		return nil
+	}
+	resp, err := s.client.Get(ctx, etcdLegacyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to list legacy keys: %w", err)
+	}
+	for _, kv := range resp.Kvs {
+		oldKey := string(kv.Key)
+		obj, _, err := s.parser.ParseYAML(kv.Value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", oldKey, err)
+		}
+		meta, err := getObjectMeta(obj)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", oldKey, err)
+		}
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		newKey := s.resourceKey(gvk, meta.GetNamespace(), meta.GetName())
+		value := kv.Value
+		if IsClusterScoped(gvk) && meta.GetNamespace() != "" {
+			meta.SetNamespace("")
+			if value, err = json.Marshal(obj); err != nil {
+				return fmt.Errorf("failed to marshal %s: %w", oldKey, err)
+			}
+		}
+		if err := s.moveKey(ctx, oldKey, newKey, string(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultEmptyNamespaces 把 namespace 为空的 namespace 级资源（键为 .../namespaces/<name>）移到 default（v7），
+// 连同版本历史；default 中已有同名对象时保留它。移动过对象时重建标签索引
+func (s *EtcdStore) defaultEmptyNamespaces(ctx context.Context) error {
+	prefix := s.keys.resources()
+	resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %w", err)
+	}
+	moved := false
+	for _, kv := range resp.Kvs {
+		oldKey := string(kv.Key)
+		// <group>/<version>/<kind>/namespaces/<name>
+		parts := strings.Split(strings.TrimPrefix(oldKey, prefix), "/")
+		if len(parts) != 5 || parts[3] != "namespaces" {
+			continue
+		}
+		gvk := schema.GroupVersionKind{Group: parts[0], Version: parts[1], Kind: parts[2]}
+		if gvk.Group == "core" {
+			gvk.Group = ""
+		}
+		obj, err := decodeStoredObject(s.parser, gvk, kv.Value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", oldKey, err)
+		}
+		meta, err := getObjectMeta(obj)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", oldKey, err)
+		}
+		meta.SetNamespace(metav1.NamespaceDefault)
+		value, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", oldKey, err)
+		}
+		if err := s.moveKey(ctx, oldKey, s.resourceKey(gvk, metav1.NamespaceDefault, meta.GetName()), string(value)); err != nil {
+			return err
+		}
+		oldHistory := s.keys.history() + strings.TrimPrefix(oldKey, prefix)
+		if history, err := s.client.Get(ctx, oldHistory); err == nil && len(history.Kvs) > 0 {
+			newHistory := s.keys.history() + resourcePath(gvk, metav1.NamespaceDefault, meta.GetName())
+			if err := s.moveKey(ctx, oldHistory, newHistory, string(history.Kvs[0].Value)); err != nil {
+				return err
+			}
+		}
+		moved = true
+	}
+	if !moved {
+		return nil
+	}
+	return s.buildLabelIndex(ctx)
+}
+
+// buildLabelIndex 为已有的资源写入标签索引键（v4）。先删除残留的索引键再重建，可以重复执行
+func (s *EtcdStore) buildLabelIndex(ctx context.Context) error {
+	if _, err := s.client.Delete(ctx, s.keys.index(), clientv3.WithPrefix()); err != nil {
+		return fmt.Errorf("failed to clear label index: %w", err)
+	}
+	resp, err := s.client.Get(ctx, s.keys.resources(), clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %w", err)
+	}
+	for _, kv := range resp.Kvs {
+		obj, _, err := s.parser.ParseYAML(kv.Value)
+		if err != nil {
+			continue
+		}
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		ops := s.labelIndexOps(gvk, nil, obj)
+		for start := 0; start < len(ops); start += etcdIndexBatch {
+			end := min(start+etcdIndexBatch, len(ops))
+			if _, err := s.client.Txn(ctx).Then(ops[start:end]...).Commit(); err != nil {
+				return fmt.Errorf("failed to index %s: %w", kv.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// recordSchemaVersion 写入 schema 版本以及写入它的 k3 版本
+func (s *EtcdStore) recordSchemaVersion(ctx context.Context, v int) error {
+	data, err := json.Marshal(etcdSchemaRecord{Version: v, BinaryVersion: version.Version, UpdatedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+	if _, err := s.client.Put(ctx, s.keys.schema(), string(data)); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return nil
+}
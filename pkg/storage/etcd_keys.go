@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultEtcdPrefix 未配置 storage.etcd.prefix 时 k3 键的前缀
+const DefaultEtcdPrefix = "/k3"
+
+// etcdReservedSegments 前缀中不能出现的路径段：它们是前缀下的目录名，出现在前缀中时
+// 一个集群的键会落在另一个集群的资源/历史/索引前缀下（例如 /k3/resources 的键都在 /k3 集群的 watch 范围内）
+var etcdReservedSegments = map[string]bool{"resources": true, "history": true, "index": true, "schema": true, "kubernetes": true}
+
+// etcdKeyspace 一个前缀下的 k3 键：资源、对象历史、标签索引与 schema 记录。
+// 多个集群共用一个 etcd 时各自使用不同的前缀，键与 watch 互不重叠
+type etcdKeyspace struct {
+	prefix string
+}
+
+// resources 资源键前缀，键为 resources() + resourcePath(gvk, namespace, name)
+func (k etcdKeyspace) resources() string { return k.prefix + "/resources/" }
+
+// history 对象版本历史的键前缀，键为 history() + resourcePath(gvk, namespace, name)，值为该对象保留的版本列表（JSON）
+func (k etcdKeyspace) history() string { return k.prefix + "/history/" }
+
+// index 标签索引键前缀，键为 index() + {group}/{version}/{kind}/{label}={value}/{namespace}/{name}
+// （集群级资源 namespace 为空），值为资源键（schema v4）
+func (k etcdKeyspace) index() string { return k.prefix + "/index/" }
+
+// schema 记录数据 schema 版本的键（不在资源前缀下）
+func (k etcdKeyspace) schema() string { return k.prefix + "/schema" }
+
+// dirs 返回前缀下需要整体迁移的目录
+func (k etcdKeyspace) dirs() []string {
+	return []string{k.resources(), k.history(), k.index()}
+}
+
+// normalizeEtcdPrefix 规范化 storage.etcd.prefix：为空时使用 DefaultEtcdPrefix，必须以 / 开头，去掉末尾的 /
+func normalizeEtcdPrefix(prefix string) (string, error) {
+	p := strings.TrimSpace(prefix)
+	if p == "" {
+		return DefaultEtcdPrefix, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return "", fmt.Errorf("etcd prefix %q must start with /", prefix)
+	}
+	p = strings.TrimRight(p, "/")
+	if p == "" {
+		return "", fmt.Errorf("etcd prefix %q must not be /", prefix)
+	}
+	for _, seg := range strings.Split(p[1:], "/") {
+		if seg == "" {
+			return "", fmt.Errorf("etcd prefix %q contains an empty path segment", prefix)
+		}
+		if etcdReservedSegments[seg] {
+			return "", fmt.Errorf("etcd prefix %q must not contain the reserved segment %q", prefix, seg)
+		}
+	}
+	return p, nil
+}
+
+// rebaseKey 把 from 前缀下的键改为 to 前缀下的同一个键
+func rebaseKey(key string, from, to etcdKeyspace) string {
+	return to.prefix + strings.TrimPrefix(key, from.prefix)
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+)
+
+func TestNormalizeEtcdPrefix(t *testing.T) {
+	valid := map[string]string{
+		"":            "/k3",
+		"/k3":         "/k3",
+		"/k3/prod/":   "/k3/prod",
+		" /k3/edge ":  "/k3/edge",
+		"/clusters/a": "/clusters/a",
+	}
+	for in, want := range valid {
+		got, err := normalizeEtcdPrefix(in)
+		if err != nil || got != want {
+			t.Errorf("normalizeEtcdPrefix(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"k3", "/", "/k3//prod", "/k3/resources", "/kubernetes", "/k3/a/index"} {
+		if got, err := normalizeEtcdPrefix(in); err == nil {
+			t.Errorf("normalizeEtcdPrefix(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+// 不同前缀的键空间互不包含：一个集群的 watch/List 前缀不会匹配另一个集群的键
+func TestEtcdKeyspacesDoNotOverlap(t *testing.T) {
+	spaces := []etcdKeyspace{{DefaultEtcdPrefix}, {"/k3/prod"}, {"/k3/prod2"}, {"/k3/prod/edge"}}
+	for _, a := range spaces {
+		for _, b := range spaces {
+			if a == b {
+				continue
+			}
+			for _, dir := range a.dirs() {
+				for _, key := range append(b.dirs(), b.schema()) {
+					if strings.HasPrefix(key+"x", dir) {
+						t.Errorf("%s key %q falls under %s dir %q", b.prefix, key, a.prefix, dir)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRebaseKey(t *testing.T) {
+	from, to := etcdKeyspace{"/k3"}, etcdKeyspace{"/k3/prod"}
+	key := from.resources() + "core/v1/Pod/namespaces/default/web"
+	if got, want := rebaseKey(key, from, to), "/k3/prod/resources/core/v1/Pod/namespaces/default/web"; got != want {
+		t.Fatalf("rebaseKey = %q, want %q", got, want)
+	}
+	if got := rebaseKey(from.schema(), from, to); got != to.schema() {
+		t.Fatalf("rebaseKey(schema) = %q, want %q", got, to.schema())
+	}
+}
+
+func TestNewEtcdStoreKeyspace(t *testing.T) {
+	s, err := NewEtcdStore(config.EtcdConfig{Endpoints: []string{"127.0.0.1:1"}, Prefix: "/k3/prod/", MigrateFrom: "/k3"})
+	if err != nil {
+		t.Fatalf("NewEtcdStore: %v", err)
+	}
+	defer s.Close()
+	if s.keys.prefix != "/k3/prod" || s.migrateFrom == nil || s.migrateFrom.prefix != DefaultEtcdPrefix {
+		t.Fatalf("unexpected keyspace: %+v from %+v", s.keys, s.migrateFrom)
+	}
+	// 迁移来源为默认前缀时，旧布局（/kubernetes/...）的数据也属于本集群
+	if !s.ownsLegacy {
+		t.Fatalf("expected legacy keys to belong to a cluster migrated from the default prefix")
+	}
+
+	other, err := NewEtcdStore(config.EtcdConfig{Endpoints: []string{"127.0.0.1:1"}, Prefix: "/k3/lab"})
+	if err != nil {
+		t.Fatalf("NewEtcdStore: %v", err)
+	}
+	defer other.Close()
+	if other.ownsLegacy || other.legacyKeys.Load() {
+		t.Fatalf("cluster with its own prefix must not read legacy keys")
+	}
+
+	if _, err := NewEtcdStore(config.EtcdConfig{Endpoints: []string{"127.0.0.1:1"}, Prefix: "k3"}); err == nil {
+		t.Fatalf("expected error for a relative prefix")
+	}
+}
@@ -6,16 +6,42 @@ import (
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
 )
 
-// NewStore 根据配置创建存储实例
+// NewStore 根据配置创建存储实例；MySQL/etcd 外面包一层 ResilientStore（熔断与重连，见 storage.circuit_breaker）。
+// 每个对象保留的历史版本数见 storage.history_revisions
 func NewStore(cfg config.StorageConfig) (Store, error) {
 	switch cfg.Type {
 	case "memory":
-		return NewMemoryStore(), nil
+		store := NewMemoryStore()
+		store.historyLimit = HistoryLimit(cfg)
+		return store, nil
 	case "mysql":
-		return NewMySQLStore(cfg.MySQL)
+		store, err := NewMySQLStore(cfg.MySQL)
+		if err != nil {
+			return nil, err
+		}
+		store.historyLimit = HistoryLimit(cfg)
+		return wrapResilient(store, cfg)
 	case "etcd":
-		return NewEtcdStore(cfg.Etcd)
+		store, err := NewEtcdStore(cfg.Etcd)
+		if err != nil {
+			return nil, err
+		}
+		store.historyLimit = HistoryLimit(cfg)
+		return wrapResilient(store, cfg)
 	default:
 		return nil, fmt.Errorf("unsupported storage type: %s", cfg.Type)
 	}
 }
+
+// wrapResilient 用熔断配置包装外部存储，配置错误时关闭已建立的连接
+func wrapResilient(backend interface {
+	Store
+	Close() error
+}, cfg config.StorageConfig) (Store, error) {
+	store, err := NewResilientStore(backend, cfg.Type, cfg.CircuitBreaker)
+	if err != nil {
+		_ = backend.Close()
+		return nil, err
+	}
+	return store, nil
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// 写入者（field manager）跟踪：在 metadata.managedFields 中只保留最近一次写入者，
+// 作为完整字段管理（server-side apply）之前的过渡方案。
+
+// ManagerConflict 表示一次写入会覆盖另一个写入者设置的 labels/annotations
+type ManagerConflict struct {
+	// Manager 本次写入者
+	Manager string `json:"manager"`
+	// LastManager 对象的上一个写入者
+	LastManager string `json:"lastManager"`
+	// Fields 会被修改或删除的字段，如 metadata.labels[app]
+	Fields []string `json:"fields"`
+}
+
+func (c *ManagerConflict) Error() string {
+	return fmt.Sprintf("conflict: %s would overwrite fields managed by %s: %s", c.Manager, c.LastManager, strings.Join(c.Fields, ", "))
+}
+
+// LastManager 返回对象最近一次的写入者（未记录时为空）
+func LastManager(obj runtime.Object) string {
+	meta, err := getObjectMeta(obj)
+	if err != nil {
+		return ""
+	}
+	entries := meta.GetManagedFields()
+	if len(entries) == 0 {
+		return ""
+	}
+	return entries[len(entries)-1].Manager
+}
+
+// RecordManager 将 manager 记录为对象的最近写入者（manager 为空时不做任何事）
+func RecordManager(obj runtime.Object, manager string) {
+	meta, err := getObjectMeta(obj)
+	if err != nil || manager == "" {
+		return
+	}
+	now := metav1.Now()
+	meta.SetManagedFields([]metav1.ManagedFieldsEntry{{
+		Manager:    manager,
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		APIVersion: obj.GetObjectKind().GroupVersionKind().GroupVersion().String(),
+		Time:       &now,
+	}})
+}
+
+// DetectManagerConflict 检查 manager 用 updated 覆盖 current 时是否与上一个写入者冲突：
+// 上一个写入者不同，且 updated 不是基于 current 的最新版本（resourceVersion 为空或不一致），
+// 同时会修改/删除 current 上已有的 labels 或 annotations。无冲突时返回 nil。
+func DetectManagerConflict(current, updated runtime.Object, manager string) *ManagerConflict {
+	last := LastManager(current)
+	if last == "" || manager == "" || last == manager {
+		return nil
+	}
+	cur, err := getObjectMeta(current)
+	if err != nil {
+		return nil
+	}
+	upd, err := getObjectMeta(updated)
+	if err != nil {
+		return nil
+	}
+	if upd.GetResourceVersion() != "" && upd.GetResourceVersion() == cur.GetResourceVersion() {
+		return nil
+	}
+
+	var fields []string
+	fields = append(fields, overwrittenKeys("metadata.labels", cur.GetLabels(), upd.GetLabels())...)
+	fields = append(fields, overwrittenKeys("metadata.annotations", cur.GetAnnotations(), upd.GetAnnotations())...)
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ManagerConflict{Manager: manager, LastManager: last, Fields: fields}
+}
+
+// UpdateAs 以 manager 的身份更新对象并记录写入者。
+// 检测到冲突时：force=false 不写入并返回冲突（err 即冲突本身）；force=true 照常写入，同时返回冲突供调用方记录。
+func UpdateAs(store Store, gvk schema.GroupVersionKind, obj runtime.Object, manager string, force bool) (*ManagerConflict, error) {
+	return updateAs(store, gvk, obj, manager, force, false)
+}
+
+// UpdateAsIf 与 UpdateAs 相同，但 obj 带有 metadata.resourceVersion 时以它为前置条件（见 UpdateIf）：
+// 对象已被修改时不写入并返回 *ResourceVersionConflict
+func UpdateAsIf(store Store, gvk schema.GroupVersionKind, obj runtime.Object, manager string, force bool) (*ManagerConflict, error) {
+	return updateAs(store, gvk, obj, manager, force, true)
+}
+
+func updateAs(store Store, gvk schema.GroupVersionKind, obj runtime.Object, manager string, force, precondition bool) (*ManagerConflict, error) {
+	meta, err := getObjectMeta(obj)
+	if err != nil {
+		return nil, err
+	}
+	var resourceVersion string
+	if precondition {
+		resourceVersion = meta.GetResourceVersion()
+	}
+	current, err := store.Get(gvk, meta.GetNamespace(), meta.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResourceVersion(gvk, current, resourceVersion); err != nil {
+		return nil, err
+	}
+
+	conflict := DetectManagerConflict(current, obj, manager)
+	if conflict != nil && !force {
+		return conflict, conflict
+	}
+	RecordManager(obj, manager)
+	if err := UpdateIf(store, gvk, obj, resourceVersion); err != nil {
+		return conflict, err
+	}
+	return conflict, nil
+}
+
+// overwrittenKeys 返回 current 中被 updated 修改或删除的 key
+func overwrittenKeys(prefix string, current, updated map[string]string) []string {
+	var keys []string
+	for k, v := range current {
+		if nv, ok := updated[k]; !ok || nv != v {
+			keys = append(keys, fmt.Sprintf("%s[%s]", prefix, k))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// metadata.generation 由存储层维护（与 Kubernetes 一致）：创建时为 1，之后仅在 spec 变化时递增；
+// 客户端提交的 generation 会被忽略。控制器处理完某个 generation 后写入 status.observedGeneration，
+// 客户端比较两者即可知道变更是否已被处理。
+
+// initGeneration 创建时设置 generation（已有值时保留，MySQL 的 Update 会复用 Create 路径）
+func initGeneration(obj runtime.Object) {
+	meta, err := getObjectMeta(obj)
+	if err != nil {
+		return
+	}
+	if meta.GetGeneration() == 0 {
+		meta.SetGeneration(1)
+	}
+}
+
+// updateGeneration 沿用旧对象的 generation，spec 变化时加一
+func updateGeneration(oldObj, newObj runtime.Object) {
+	oldMeta, err := getObjectMeta(oldObj)
+	if err != nil {
+		return
+	}
+	newMeta, err := getObjectMeta(newObj)
+	if err != nil {
+		return
+	}
+
+	generation := oldMeta.GetGeneration()
+	if generation == 0 {
+		generation = 1
+	}
+	if specChanged(oldObj, newObj) {
+		generation++
+	}
+	newMeta.SetGeneration(generation)
+}
+
+// specChanged 比较两个对象的 spec；没有 spec 的对象（ConfigMap、Secret 等）视为未变化
+func specChanged(oldObj, newObj runtime.Object) bool {
+	oldU, err := runtime.DefaultUnstructuredConverter.ToUnstructured(oldObj)
+	if err != nil {
+		return false
+	}
+	newU, err := runtime.DefaultUnstructuredConverter.ToUnstructured(newObj)
+	if err != nil {
+		return false
+	}
+	oldSpec, hasOld := oldU["spec"]
+	newSpec, hasNew := newU["spec"]
+	if !hasOld && !hasNew {
+		return false
+	}
+	return !equality.Semantic.DeepEqual(oldSpec, newSpec)
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// 对象版本历史：每次 Create/Update/Delete 后保存对象当时的完整内容与写入者，
+// 每个对象只保留最近 N 个版本（storage.history_revisions）。历史在对象删除后仍然保留，
+// 同名对象重建后版本号继续递增。记录历史失败不影响本次写入。
+
+// DefaultHistoryRevisions 未配置 storage.history_revisions 时每个对象保留的版本数
+const DefaultHistoryRevisions = 10
+
+// RevisionOperation 产生一个版本的写操作
+type RevisionOperation string
+
+const (
+	RevisionCreate RevisionOperation = "Create"
+	RevisionUpdate RevisionOperation = "Update"
+	RevisionDelete RevisionOperation = "Delete"
+)
+
+// Revision 对象的一个历史版本
+type Revision struct {
+	// Revision 对象内递增的版本号，从 1 开始
+	Revision int64 `json:"revision"`
+	// ResourceVersion 写入后对象的 resourceVersion（删除时为删除前的值）
+	ResourceVersion string            `json:"resourceVersion"`
+	Operation       RevisionOperation `json:"operation"`
+	// Manager 写入者（field manager）；没有记录写入者的写入为空
+	Manager   string    `json:"manager,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Object 写入后的对象（删除时为删除前的对象），存储版本的 JSON
+	Object json.RawMessage `json:"object"`
+}
+
+// HistoryStore 由保存版本历史的 Store 实现（三个后端以及 ResilientStore 都实现）
+type HistoryStore interface {
+	// History 返回对象保留的版本，按版本号从旧到新；没有历史时返回空
+	History(gvk schema.GroupVersionKind, namespace, name string) ([]Revision, error)
+	// DeleteAs 以 manager 的身份删除对象，删除记录的写入者为 manager
+	DeleteAs(gvk schema.GroupVersionKind, namespace, name, manager string) error
+}
+
+// ErrHistoryUnsupported Store 不保存版本历史
+var ErrHistoryUnsupported = errors.New("store does not keep revision history")
+
+// History 返回对象保留的版本；store 不保存历史时返回 ErrHistoryUnsupported
+func History(store Store, gvk schema.GroupVersionKind, namespace, name string) ([]Revision, error) {
+	h, ok := store.(HistoryStore)
+	if !ok {
+		return nil, ErrHistoryUnsupported
+	}
+	return h.History(gvk, namespace, name)
+}
+
+// DeleteAs 以 manager 的身份删除对象；store 不保存历史时等同于 Delete
+func DeleteAs(store Store, gvk schema.GroupVersionKind, namespace, name, manager string) error {
+	if h, ok := store.(HistoryStore); ok {
+		return h.DeleteAs(gvk, namespace, name, manager)
+	}
+	return store.Delete(gvk, namespace, name)
+}
+
+// FindRevision 返回版本号为 revision 的版本
+func FindRevision(revisions []Revision, revision int64) (Revision, bool) {
+	for _, r := range revisions {
+		if r.Revision == revision {
+			return r, true
+		}
+	}
+	return Revision{}, false
+}
+
+// HistoryLimit 返回配置的每个对象保留的版本数：未配置时为 DefaultHistoryRevisions，小于 0 时为 0（不记录历史）
+func HistoryLimit(cfg config.StorageConfig) int {
+	switch {
+	case cfg.HistoryRevisions < 0:
+		return 0
+	case cfg.HistoryRevisions == 0:
+		return DefaultHistoryRevisions
+	default:
+		return cfg.HistoryRevisions
+	}
+}
+
+// newRevision 为一次写入生成版本（版本号由 appendRevision 分配）。
+// manager 为空时：Create 取对象记录的写入者；Update 只在本次写入更新了 managedFields 时取写入者，
+// 否则（写入方没有调用 RecordManager）对象上的仍是上一个写入者，记为空
+func newRevision(op RevisionOperation, old, obj runtime.Object, manager string) (Revision, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return Revision{}, fmt.Errorf("failed to marshal revision: %w", err)
+	}
+	meta, err := getObjectMeta(obj)
+	if err != nil {
+		return Revision{}, err
+	}
+	if manager == "" {
+		switch op {
+		case RevisionCreate:
+			manager = LastManager(obj)
+		case RevisionUpdate:
+			if managerTime(obj) != managerTime(old) || LastManager(obj) != LastManager(old) {
+				manager = LastManager(obj)
+			}
+		}
+	}
+	return Revision{
+		ResourceVersion: meta.GetResourceVersion(),
+		Operation:       op,
+		Manager:         manager,
+		Timestamp:       time.Now(),
+		Object:          data,
+	}, nil
+}
+
+// managerTime 返回最近一次写入者记录的时间（没有记录时为零值）
+func managerTime(obj runtime.Object) time.Time {
+	if obj == nil {
+		return time.Time{}
+	}
+	meta, err := getObjectMeta(obj)
+	if err != nil {
+		return time.Time{}
+	}
+	entries := meta.GetManagedFields()
+	if len(entries) == 0 || entries[len(entries)-1].Time == nil {
+		return time.Time{}
+	}
+	return entries[len(entries)-1].Time.Time
+}
+
+// appendRevision 为 rev 分配下一个版本号并追加到 revisions，只保留最近 limit 个
+func appendRevision(revisions []Revision, rev Revision, limit int) []Revision {
+	rev.Revision = 1
+	if n := len(revisions); n > 0 {
+		rev.Revision = revisions[n-1].Revision + 1
+	}
+	revisions = append(revisions, rev)
+	if len(revisions) > limit {
+		revisions = append([]Revision(nil), revisions[len(revisions)-limit:]...)
+	}
+	return revisions
+}
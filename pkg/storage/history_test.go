@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestMemoryStore_History(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Data:       map[string]string{"k": "v1"},
+	}
+	RecordManager(cm, "kubectl")
+	if err := store.Create(gvk, cm); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// 记录了写入者的更新
+	cm.Data["k"] = "v2"
+	if _, err := UpdateAs(store, gvk, cm, "k3-gitops", true); err != nil {
+		t.Fatalf("update as gitops: %v", err)
+	}
+
+	// 没有记录写入者的更新：managedFields 仍是上一个写入者，版本的写入者为空
+	cm.Data["k"] = "v3"
+	if err := store.Update(gvk, cm); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if err := DeleteAs(store, gvk, "default", "app", "dashboard"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	revisions, err := History(store, gvk, "default", "app")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	want := []struct {
+		op      RevisionOperation
+		manager string
+		value   string
+	}{
+		{RevisionCreate, "kubectl", "v1"},
+		{RevisionUpdate, "k3-gitops", "v2"},
+		{RevisionUpdate, "", "v3"},
+		{RevisionDelete, "dashboard", "v3"},
+	}
+	if len(revisions) != len(want) {
+		t.Fatalf("expected %d revisions, got %d", len(want), len(revisions))
+	}
+	for i, w := range want {
+		r := revisions[i]
+		if r.Revision != int64(i+1) || r.Operation != w.op || r.Manager != w.manager {
+			t.Errorf("revision %d: got #%d %s by %q, want %s by %q", i+1, r.Revision, r.Operation, r.Manager, w.op, w.manager)
+		}
+		var got corev1.ConfigMap
+		if err := json.Unmarshal(r.Object, &got); err != nil {
+			t.Fatalf("revision %d: %v", i+1, err)
+		}
+		if got.Data["k"] != w.value {
+			t.Errorf("revision %d: data %q, want %q", i+1, got.Data["k"], w.value)
+		}
+	}
+
+	if r, ok := FindRevision(revisions, 2); !ok || r.Manager != "k3-gitops" {
+		t.Errorf("FindRevision(2) = %+v, %v", r, ok)
+	}
+	if _, ok := FindRevision(revisions, 9); ok {
+		t.Error("FindRevision(9) should not be found")
+	}
+}
+
+func TestMemoryStore_HistoryLimit(t *testing.T) {
+	store := NewMemoryStore()
+	store.historyLimit = 3
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+	}
+	if err := store.Create(gvk, node); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := store.Update(gvk, node); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+
+	// 集群级资源忽略 namespace 参数
+	revisions, err := store.History(gvk, "ignored", "node-1")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(revisions) != 3 || revisions[0].Revision != 3 || revisions[2].Revision != 5 {
+		t.Fatalf("expected revisions 3..5, got %+v", revisions)
+	}
+
+	store.historyLimit = 0
+	if err := store.Update(gvk, node); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if revisions, _ := store.History(gvk, "", "node-1"); len(revisions) != 3 {
+		t.Fatalf("history disabled should not record, got %d revisions", len(revisions))
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	cases := map[int]int{0: DefaultHistoryRevisions, -1: 0, 5: 5}
+	for configured, want := range cases {
+		if got := HistoryLimit(config.StorageConfig{HistoryRevisions: configured}); got != want {
+			t.Errorf("HistoryLimit(%d) = %d, want %d", configured, got, want)
+		}
+	}
+}
+
+func TestHistoryUnsupported(t *testing.T) {
+	var store Store = struct{ Store }{NewMemoryStore()}
+	if _, err := History(store, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, "default", "p"); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Fatalf("expected ErrHistoryUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// IsClusterScoped 判断 gvk 是否是集群级资源（见 pkg/resources）。三个后端都按它决定资源的存储位置：
+// 集群级资源读写时忽略 namespace 参数，与同名的 namespace 级资源互不冲突
+func IsClusterScoped(gvk schema.GroupVersionKind) bool {
+	return resources.IsClusterScoped(gvk)
+}
+
+// IsClusterScopedKind 只按 Kind 判断是否是集群级资源（apiserver 路由只知道 Kind 时使用）
+func IsClusterScopedKind(kind string) bool {
+	return resources.IsClusterScopedKind(kind)
+}
+
+// scopedNamespace 返回资源实际使用的 namespace：集群级资源固定为空
+func scopedNamespace(gvk schema.GroupVersionKind, namespace string) string {
+	if IsClusterScoped(gvk) {
+		return ""
+	}
+	return namespace
+}
+
+// objectNamespace 返回单个对象使用的 namespace：集群级资源固定为空，namespace 级资源未指定 namespace 时为 default。
+// 三个后端的 Create/Update/Get/Delete 都按它定位对象，空 namespace 只在 List/Watch/DeleteCollection 中表示所有 namespace
+func objectNamespace(gvk schema.GroupVersionKind, namespace string) string {
+	if IsClusterScoped(gvk) {
+		return ""
+	}
+	if namespace == "" {
+		return metav1.NamespaceDefault
+	}
+	return namespace
+}
+
+// collectionPath 返回一类资源在存储中的路径前缀（以 / 结尾）：
+//
+//	<group|core>/<version>/<kind>/namespaces/<namespace>/  指定 namespace 的 namespace 级资源
+//	<group|core>/<version>/<kind>/namespaces/              所有 namespace 的 namespace 级资源
+//	<group|core>/<version>/<kind>/cluster/                 集群级资源
+func collectionPath(gvk schema.GroupVersionKind, namespace string) string {
+	group := gvk.Group
+	if group == "" {
+		group = "core"
+	}
+	base := group + "/" + gvk.Version + "/" + gvk.Kind + "/"
+	if IsClusterScoped(gvk) {
+		return base + "cluster/"
+	}
+	if namespace == "" {
+		return base + "namespaces/"
+	}
+	return base + "namespaces/" + namespace + "/"
+}
+
+// resourcePath 返回单个对象在存储中的路径（collectionPath + name，namespace 按 objectNamespace）
+func resourcePath(gvk schema.GroupVersionKind, namespace, name string) string {
+	return collectionPath(gvk, objectNamespace(gvk, namespace)) + name
+}
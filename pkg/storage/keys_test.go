@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"testing"
+
+	k3v1 "github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/apis/k3/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestResourcePath(t *testing.T) {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+
+	cases := []struct {
+		got, want string
+	}{
+		{resourcePath(podGVK, "default", "web"), "core/v1/Pod/namespaces/default/web"},
+		{collectionPath(podGVK, ""), "core/v1/Pod/namespaces/"},
+		{resourcePath(nodeGVK, "", "node-1"), "core/v1/Node/cluster/node-1"},
+		// 集群级资源忽略 namespace
+		{resourcePath(nodeGVK, "default", "node-1"), "core/v1/Node/cluster/node-1"},
+		{collectionPath(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "a"), "apps/v1/Deployment/namespaces/a/"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("expected %q, got %q", c.want, c.got)
+		}
+	}
+}
+
+func TestIsClusterScopedTable(t *testing.T) {
+	cases := map[string]bool{
+		"k8s_core_v1_node":        true,
+		"k8s_core_v1_namespace":   true,
+		tableName(k3v1.DeviceGVK): true,
+		"k8s_core_v1_pod":         false,
+		"k8s_apps_v1_node":        false,
+	}
+	for table, want := range cases {
+		if got := isClusterScopedTable(table); got != want {
+			t.Errorf("isClusterScopedTable(%q) = %v, want %v", table, got, want)
+		}
+	}
+}
+
+func TestMemoryStore_ClusterScoped(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Namespace: "default"},
+	}
+	if err := store.Create(gvk, node); err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	if node.Namespace != "" {
+		t.Errorf("Expected namespace of cluster-scoped resource to be cleared, got %q", node.Namespace)
+	}
+
+	// 无论带不带 namespace 都指向同一个资源
+	if _, err := store.Get(gvk, "", "node-1"); err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if _, err := store.Get(gvk, "other", "node-1"); err != nil {
+		t.Fatalf("Failed to get node with namespace: %v", err)
+	}
+	dup := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Namespace: "other"},
+	}
+	if err := store.Create(gvk, dup); err == nil {
+		t.Errorf("Expected duplicate cluster-scoped resource to be rejected")
+	}
+
+	nodes, err := store.List(gvk, "default")
+	if err != nil || len(nodes) != 1 {
+		t.Fatalf("Expected 1 node, got %d (err=%v)", len(nodes), err)
+	}
+}
+
+func TestMemoryStore_ListScoped(t *testing.T) {
+	store := NewMemoryStore()
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	svcGVK := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+
+	for _, ns := range []string{"a", "ab", "b"} {
+		pod := &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: ns},
+		}
+		if err := store.Create(podGVK, pod); err != nil {
+			t.Fatalf("Failed to create pod in %s: %v", ns, err)
+		}
+	}
+	svc := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "a"},
+	}
+	if err := store.Create(svcGVK, svc); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	// namespace 是前缀的另一个 namespace 不应被列出
+	pods, err := store.List(podGVK, "a")
+	if err != nil || len(pods) != 1 {
+		t.Fatalf("Expected 1 pod in namespace a, got %d (err=%v)", len(pods), err)
+	}
+	pods, err = store.List(podGVK, "")
+	if err != nil || len(pods) != 3 {
+		t.Fatalf("Expected 3 pods across namespaces, got %d (err=%v)", len(pods), err)
+	}
+
+	deleted, err := store.DeleteCollection(podGVK, "", nil)
+	if err != nil || len(deleted) != 3 {
+		t.Fatalf("Expected 3 deleted pods, got %d (err=%v)", len(deleted), err)
+	}
+	if _, err := store.Get(svcGVK, "a", "web"); err != nil {
+		t.Errorf("Service should survive pod DeleteCollection: %v", err)
+	}
+}
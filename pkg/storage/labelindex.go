@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// 标签索引：各后端用索引缩小 ListBySelector 的候选集，最终结果都再按完整的 selector 过滤一次，
+// 索引只决定读取哪些对象，不影响结果的正确性
+
+// indexableRequirements 返回 selector 中可以用索引查找的条件（=、==、in、exists），
+// 其余条件（!=、notin、!）只在最终过滤时判断。selectable 为 false 表示 selector 不匹配任何对象
+func indexableRequirements(selector labels.Selector) (reqs []labels.Requirement, selectable bool) {
+	all, selectable := selector.Requirements()
+	if !selectable {
+		return nil, false
+	}
+	for _, req := range all {
+		switch req.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In, selection.Exists:
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs, true
+}
+
+// matchesSelector 判断对象的标签是否满足 selector（不是 metav1.Object 的对象不匹配）
+func matchesSelector(obj runtime.Object, selector labels.Selector) bool {
+	meta, err := getObjectMeta(obj)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(meta.GetLabels()))
+}
+
+// filterBySelector 保留满足 selector 的对象
+func filterBySelector(objects []runtime.Object, selector labels.Selector) []runtime.Object {
+	var result []runtime.Object
+	for _, obj := range objects {
+		if matchesSelector(obj, selector) {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// memoryLabelIndex MemoryStore 的倒排索引：gvk（group/version/kind）→ 标签 key → 标签 value → 对象
+type memoryLabelIndex map[string]map[string]map[string]map[objectKey]struct{}
+
+// objectKey 索引中的对象（集群级资源 namespace 为空）
+type objectKey struct {
+	namespace string
+	name      string
+}
+
+// add 把对象的标签加入索引
+func (idx memoryLabelIndex) add(gvkKey string, key objectKey, objLabels map[string]string) {
+	if len(objLabels) == 0 {
+		return
+	}
+	byKey := idx[gvkKey]
+	if byKey == nil {
+		byKey = make(map[string]map[string]map[objectKey]struct{})
+		idx[gvkKey] = byKey
+	}
+	for k, v := range objLabels {
+		byValue := byKey[k]
+		if byValue == nil {
+			byValue = make(map[string]map[objectKey]struct{})
+			byKey[k] = byValue
+		}
+		objects := byValue[v]
+		if objects == nil {
+			objects = make(map[objectKey]struct{})
+			byValue[v] = objects
+		}
+		objects[key] = struct{}{}
+	}
+}
+
+// remove 从索引中删除对象的标签，并清理空的层级
+func (idx memoryLabelIndex) remove(gvkKey string, key objectKey, objLabels map[string]string) {
+	byKey := idx[gvkKey]
+	if byKey == nil {
+		return
+	}
+	for k, v := range objLabels {
+		byValue := byKey[k]
+		if byValue == nil {
+			continue
+		}
+		delete(byValue[v], key)
+		if len(byValue[v]) == 0 {
+			delete(byValue, v)
+		}
+		if len(byValue) == 0 {
+			delete(byKey, k)
+		}
+	}
+	if len(byKey) == 0 {
+		delete(idx, gvkKey)
+	}
+}
+
+// candidates 返回同时满足所有可索引条件的对象；没有可索引条件时 ok 为 false（需要全量扫描）
+func (idx memoryLabelIndex) candidates(gvkKey string, reqs []labels.Requirement) (result map[objectKey]struct{}, ok bool) {
+	if len(reqs) == 0 {
+		return nil, false
+	}
+	byKey := idx[gvkKey]
+	for i, req := range reqs {
+		matched := make(map[objectKey]struct{})
+		byValue := byKey[req.Key()]
+		if req.Operator() == selection.Exists {
+			for _, objects := range byValue {
+				for key := range objects {
+					matched[key] = struct{}{}
+				}
+			}
+		} else {
+			for _, v := range req.Values().UnsortedList() {
+				for key := range byValue[v] {
+					matched[key] = struct{}{}
+				}
+			}
+		}
+		if i == 0 {
+			result = matched
+			continue
+		}
+		for key := range result {
+			if _, found := matched[key]; !found {
+				delete(result, key)
+			}
+		}
+	}
+	return result, true
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
@@ -11,6 +13,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -18,19 +21,50 @@ import (
 
 // MySQLStore 是基于 MySQL 的存储实现
 type MySQLStore struct {
-	db       *gorm.DB
-	parser   *parser.Parser
-	watchers map[string][]chan ResourceEvent
+	db      *gorm.DB
+	parser  *parser.Parser
+	watches *watchHub
+	// historyLimit 每个对象保留的历史版本数，0 表示不记录
+	historyLimit int
+	// revisionTableReady 历史表已确认存在
+	revisionTableReady atomic.Bool
+	// replicas 只读副本，未配置时为 nil（读写都发往主库）
+	replicas *mysqlReplicas
 }
 
-// NewMySQLStore 创建新的 MySQL 存储
+// NewMySQLStore 创建新的 MySQL 存储；配置了 replicas 时同时连接只读副本
 func NewMySQLStore(cfg config.MySQLConfig) (*MySQLStore, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
+	db, err := openMySQL(cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Database, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &MySQLStore{
+		db:      db,
+		parser:  parser.NewParser(),
+		watches: newWatchHub(false),
+
+		historyLimit: DefaultHistoryRevisions,
+	}
+	if len(cfg.Replicas) > 0 {
+		if store.replicas, err = openMySQLReplicas(cfg); err != nil {
+			_ = store.Close()
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// openMySQL 连接一个 MySQL 实例（主库或只读副本），连接池大小取自 cfg
+func openMySQL(host string, port int, user, password, database string, cfg config.MySQLConfig) (*gorm.DB, error) {
+	// timeout 为建立连接的超时，MySQL 不可达时请求尽快失败而不是长时间阻塞
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&timeout=5s",
+		user, password, host, port, database)
 
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
+		return nil, fmt.Errorf("failed to connect to MySQL %s:%d: %w", host, port, err)
 	}
 
 	sqlDB, err := db.DB()
@@ -40,105 +74,172 @@ func NewMySQLStore(cfg config.MySQLConfig) (*MySQLStore, error) {
 
 	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
-
-	store := &MySQLStore{
-		db:       db,
-		parser:   parser.NewParser(),
-		watchers: make(map[string][]chan ResourceEvent),
-	}
-
-	return store, nil
+	return db, nil
 }
 
-// resourceKey 生成资源的唯一键
-func (s *MySQLStore) resourceKey(gvk schema.GroupVersionKind, namespace, name string) string {
-	if namespace == "" {
-		return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, name)
+// whereResource 按 name 与 namespace 定位一行资源；集群级资源只按 name 查询
+// （兼容旧版本写入的带 namespace 的行）
+func whereResource(query *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) *gorm.DB {
+	if IsClusterScoped(gvk) {
+		return query.Where("name = ?", name)
 	}
-	return fmt.Sprintf("%s/%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, namespace, name)
+	return query.Where("name = ? AND namespace = ?", name, namespace)
 }
 
-// watchKey 生成 watch 的键
-func (s *MySQLStore) watchKey(gvk schema.GroupVersionKind, namespace string) string {
-	if namespace == "" {
-		return fmt.Sprintf("%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind)
+// Get 获取指定资源；配置了只读副本时优先从副本读取（见 mysql_replica.go）
+func (s *MySQLStore) Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	namespace = objectNamespace(gvk, namespace)
+	if s.replicas != nil {
+		obj, err := s.get(s.replicas.pick(), gvk, namespace, name)
+		if s.replicas.freshObject(gvk, namespace, name, obj, err) {
+			return obj, err
+		}
 	}
-	return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, namespace)
+	return s.getPrimary(gvk, namespace, name)
 }
 
-// Get 获取指定资源
-func (s *MySQLStore) Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+// getPrimary 从主库读取资源（写入前读取旧对象时使用，不经过副本）
+func (s *MySQLStore) getPrimary(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
 	// 确保表存在
 	if err := s.ensureTable(gvk); err != nil {
 		return nil, err
 	}
+	return s.get(s.db, gvk, objectNamespace(gvk, namespace), name)
+}
 
+// get 从 db（主库或副本）加载资源
+func (s *MySQLStore) get(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
 	// 根据资源类型使用不同的加载方法
 	switch gvk.Kind {
 	case "Pod":
 		if gvk.Group == "" && gvk.Version == "v1" {
-			return s.loadPod(gvk, namespace, name)
+			return s.loadPod(db, gvk, namespace, name)
 		}
 	case "Deployment":
 		if gvk.Group == "apps" && gvk.Version == "v1" {
-			return s.loadDeployment(gvk, namespace, name)
+			return s.loadDeployment(db, gvk, namespace, name)
 		}
 	case "Service":
 		if gvk.Group == "" && gvk.Version == "v1" {
-			return s.loadService(gvk, namespace, name)
+			return s.loadService(db, gvk, namespace, name)
+		}
+	case "ConfigMap":
+		if gvk.Group == "" && gvk.Version == "v1" {
+			return s.loadConfigMap(db, gvk, namespace, name)
+		}
+	case "Secret":
+		if gvk.Group == "" && gvk.Version == "v1" {
+			return s.loadSecret(db, gvk, namespace, name)
+		}
+	case "StatefulSet":
+		if gvk.Group == "apps" && gvk.Version == "v1" {
+			return s.loadStatefulSet(db, gvk, namespace, name)
+		}
+	case "DaemonSet":
+		if gvk.Group == "apps" && gvk.Version == "v1" {
+			return s.loadDaemonSet(db, gvk, namespace, name)
 		}
 	case "Node":
 		if gvk.Group == "" && gvk.Version == "v1" {
-			return s.loadNode(gvk, namespace, name)
+			return s.loadNode(db, gvk, namespace, name)
 		}
 	}
 
 	// 通用资源加载
-	return s.loadGenericResource(gvk, namespace, name)
+	return s.loadGenericResource(db, gvk, namespace, name)
 }
 
 // List 列出所有资源
 func (s *MySQLStore) List(gvk schema.GroupVersionKind, namespace string) ([]runtime.Object, error) {
+	return s.ListBySelector(gvk, namespace, nil)
+}
+
+// ListBySelector 把 selector 中的 =、in、exists、!（不存在）条件转换为 labels 列上的查询：
+// 常用标签（indexedLabels）使用带索引的生成列，其他标签使用 JSON 函数；其余条件读取后在 Go 中过滤。
+// 配置了只读副本时优先从副本读取（见 mysql_replica.go）
+func (s *MySQLStore) ListBySelector(gvk schema.GroupVersionKind, namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	if selector != nil && selector.Empty() {
+		selector = nil
+	}
+	if selector != nil {
+		if _, selectable := selector.Requirements(); !selectable {
+			return nil, nil
+		}
+	}
+	// 集群级资源没有 namespace，忽略 namespace 参数
+	namespace = scopedNamespace(gvk, namespace)
+	if s.replicas != nil {
+		objects, err := s.list(s.replicas.pick(), gvk, namespace, selector)
+		if err == nil && s.replicas.freshList(gvk, namespace, selector, objects) {
+			return objects, nil
+		}
+	}
+
 	// 确保表存在
 	if err := s.ensureTable(gvk); err != nil {
 		return nil, err
 	}
+	return s.list(s.db, gvk, namespace, selector)
+}
 
-	tableName := tableName(gvk)
-	var bases []BaseResource
-
-	query := s.db.Table(tableName)
-	// Node 资源没有 namespace，忽略 namespace 参数
-	if gvk.Kind != "Node" || gvk.Group != "" || gvk.Version != "v1" {
-		if namespace != "" {
-			query = query.Where("namespace = ?", namespace)
+// list 从 db（主库或副本）列出资源，selector 为 nil 表示全部
+func (s *MySQLStore) list(db *gorm.DB, gvk schema.GroupVersionKind, namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	query := db.Table(tableName(gvk))
+	if namespace != "" {
+		query = query.Where("namespace = ?", namespace)
+	}
+	if selector != nil {
+		all, _ := selector.Requirements()
+		for _, req := range all {
+			query = whereLabel(query, req)
 		}
 	}
 
+	var bases []BaseResource
 	if err := query.Find(&bases).Error; err != nil {
 		return nil, fmt.Errorf("failed to list resources: %w", err)
 	}
+	objects := s.loadBases(db, gvk, bases)
+	if selector != nil {
+		objects = filterBySelector(objects, selector)
+	}
+	return objects, nil
+}
 
+// loadBases 按查询到的行从同一个 db 加载完整对象（加载失败的行被跳过，例如并发删除）
+func (s *MySQLStore) loadBases(db *gorm.DB, gvk schema.GroupVersionKind, bases []BaseResource) []runtime.Object {
 	var objects []runtime.Object
 	for _, base := range bases {
-		obj, err := s.Get(gvk, base.Namespace, base.Name)
+		obj, err := s.get(db, gvk, base.Namespace, base.Name)
 		if err != nil {
 			continue
 		}
 		objects = append(objects, obj)
 	}
-
-	return objects, nil
+	SortObjects(objects)
+	return objects
 }
 
 // Create 创建资源
 func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	if err := s.create(gvk, obj); err != nil {
+		return err
+	}
+	s.recordRevision(gvk, RevisionCreate, nil, obj, "")
+	s.replicas.recordWrite(gvk, obj, false)
+	return nil
+}
+
+// create 保存资源并通知 watchers（Update 复用，不记录历史）
+func (s *MySQLStore) create(gvk schema.GroupVersionKind, obj runtime.Object) error {
 	meta, err := getObjectMeta(obj)
 	if err != nil {
 		return err
 	}
 
-	namespace := meta.GetNamespace()
+	// 集群级资源不带 namespace，namespace 级资源默认为 default
+	namespace := objectNamespace(gvk, meta.GetNamespace())
+	meta.SetNamespace(namespace)
 	name := meta.GetName()
 
 	// 确保表存在
@@ -150,7 +251,7 @@ func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) err
 	tableName := tableName(gvk)
 	if gvk.Kind != "Node" || gvk.Group != "" || gvk.Version != "v1" {
 		var count int64
-		query := s.db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace)
+		query := whereResource(s.db.Table(tableName), gvk, namespace, name)
 		if err := query.Count(&count).Error; err == nil && count > 0 {
 			return fmt.Errorf("resource already exists: %s/%s", namespace, name)
 		}
@@ -173,6 +274,7 @@ func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) err
 	if meta.GetUID() == "" {
 		meta.SetUID(types.UID(fmt.Sprintf("uid-%d", time.Now().UnixNano())))
 	}
+	initGeneration(obj)
 
 	// 根据资源类型使用不同的保存方法
 	switch gvk.Kind {
@@ -183,7 +285,7 @@ func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) err
 					return fmt.Errorf("failed to save pod: %w", err)
 				}
 				// 通知 watchers
-				s.notifyWatchers(gvk, namespace, ResourceEvent{
+				s.watches.notify(gvk, ResourceEvent{
 					Type:   EventAdded,
 					Object: obj,
 				})
@@ -197,7 +299,7 @@ func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) err
 					return fmt.Errorf("failed to save deployment: %w", err)
 				}
 				// 通知 watchers
-				s.notifyWatchers(gvk, namespace, ResourceEvent{
+				s.watches.notify(gvk, ResourceEvent{
 					Type:   EventAdded,
 					Object: obj,
 				})
@@ -211,7 +313,63 @@ func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) err
 					return fmt.Errorf("failed to save service: %w", err)
 				}
 				// 通知 watchers
-				s.notifyWatchers(gvk, namespace, ResourceEvent{
+				s.watches.notify(gvk, ResourceEvent{
+					Type:   EventAdded,
+					Object: obj,
+				})
+				return nil
+			}
+		}
+	case "ConfigMap":
+		if gvk.Group == "" && gvk.Version == "v1" {
+			if cm, ok := obj.(*corev1.ConfigMap); ok {
+				if err := s.saveConfigMap(gvk, cm); err != nil {
+					return fmt.Errorf("failed to save configmap: %w", err)
+				}
+				// 通知 watchers
+				s.watches.notify(gvk, ResourceEvent{
+					Type:   EventAdded,
+					Object: obj,
+				})
+				return nil
+			}
+		}
+	case "Secret":
+		if gvk.Group == "" && gvk.Version == "v1" {
+			if secret, ok := obj.(*corev1.Secret); ok {
+				if err := s.saveSecret(gvk, secret); err != nil {
+					return fmt.Errorf("failed to save secret: %w", err)
+				}
+				// 通知 watchers
+				s.watches.notify(gvk, ResourceEvent{
+					Type:   EventAdded,
+					Object: obj,
+				})
+				return nil
+			}
+		}
+	case "StatefulSet":
+		if gvk.Group == "apps" && gvk.Version == "v1" {
+			if sts, ok := obj.(*appsv1.StatefulSet); ok {
+				if err := s.saveStatefulSet(gvk, sts); err != nil {
+					return fmt.Errorf("failed to save statefulset: %w", err)
+				}
+				// 通知 watchers
+				s.watches.notify(gvk, ResourceEvent{
+					Type:   EventAdded,
+					Object: obj,
+				})
+				return nil
+			}
+		}
+	case "DaemonSet":
+		if gvk.Group == "apps" && gvk.Version == "v1" {
+			if ds, ok := obj.(*appsv1.DaemonSet); ok {
+				if err := s.saveDaemonSet(gvk, ds); err != nil {
+					return fmt.Errorf("failed to save daemonset: %w", err)
+				}
+				// 通知 watchers
+				s.watches.notify(gvk, ResourceEvent{
 					Type:   EventAdded,
 					Object: obj,
 				})
@@ -225,7 +383,7 @@ func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) err
 					return fmt.Errorf("failed to save node: %w", err)
 				}
 				// 通知 watchers
-				s.notifyWatchers(gvk, namespace, ResourceEvent{
+				s.watches.notify(gvk, ResourceEvent{
 					Type:   EventAdded,
 					Object: obj,
 				})
@@ -240,7 +398,7 @@ func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) err
 	}
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventAdded,
 		Object: obj,
 	})
@@ -250,12 +408,19 @@ func (s *MySQLStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) err
 
 // Update 更新资源
 func (s *MySQLStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	return s.UpdateIf(gvk, obj, "")
+}
+
+// UpdateIf 仅当对象仍是 resourceVersion 时更新（见 ConditionalUpdater）：按 resource_version 条件删除旧行，
+// 没有删除到行说明读取之后已有其他写入
+func (s *MySQLStore) UpdateIf(gvk schema.GroupVersionKind, obj runtime.Object, resourceVersion string) error {
 	meta, err := getObjectMeta(obj)
 	if err != nil {
 		return err
 	}
 
-	namespace := meta.GetNamespace()
+	namespace := objectNamespace(gvk, meta.GetNamespace())
+	meta.SetNamespace(namespace)
 	name := meta.GetName()
 
 	// 确保表存在
@@ -263,37 +428,41 @@ func (s *MySQLStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) err
 		return err
 	}
 
-	// 获取旧资源
-	oldObj, err := s.Get(gvk, namespace, name)
+	// 获取旧资源（从主库读取，副本可能还没有最新版本）
+	oldObj, err := s.get(s.db, gvk, namespace, name)
 	if err != nil {
 		return fmt.Errorf("resource not found: %w", err)
 	}
+	if err := checkResourceVersion(gvk, oldObj, resourceVersion); err != nil {
+		return err
+	}
 
-	// 更新 resourceVersion
-	resourceVersion := fmt.Sprintf("%d", time.Now().UnixNano())
-	meta.SetResourceVersion(resourceVersion)
+	// 更新 resourceVersion 与 generation
+	meta.SetResourceVersion(fmt.Sprintf("%d", time.Now().UnixNano()))
+	updateGeneration(oldObj, obj)
 
 	// 先删除旧资源，再创建新资源（简化实现）
-	tableName := tableName(gvk)
-	query := s.db.Table(tableName)
-	// Node 资源没有 namespace
-	if gvk.Kind == "Node" && gvk.Group == "" && gvk.Version == "v1" {
-		query = query.Where("name = ?", name)
-	} else {
-		query = query.Where("name = ? AND namespace = ?", name, namespace)
+	query := whereResource(s.db.Table(tableName(gvk)), gvk, namespace, name)
+	if resourceVersion != "" {
+		query = query.Where("resource_version = ?", resourceVersion)
+	}
+	result := query.Delete(&BaseResource{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete old resource: %w", result.Error)
 	}
-	// 注意：使用硬删除，避免软删除记录仍占用 UID 唯一索引导致后续 Create/Update 失败。
-	if err := query.Unscoped().Delete(&BaseResource{}).Error; err != nil {
-		return fmt.Errorf("failed to delete old resource: %w", err)
+	if resourceVersion != "" && result.RowsAffected == 0 {
+		return &ResourceVersionConflict{Kind: gvk.Kind, Namespace: namespace, Name: name, ResourceVersion: resourceVersion}
 	}
 
 	// 重新创建资源
-	if err := s.Create(gvk, obj); err != nil {
+	if err := s.create(gvk, obj); err != nil {
 		return fmt.Errorf("failed to create updated resource: %w", err)
 	}
+	s.recordRevision(gvk, RevisionUpdate, oldObj, obj, "")
+	s.replicas.recordWrite(gvk, obj, false)
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventModified,
 		Object: obj,
 		OldObj: oldObj,
@@ -304,28 +473,30 @@ func (s *MySQLStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) err
 
 // Delete 删除资源
 func (s *MySQLStore) Delete(gvk schema.GroupVersionKind, namespace, name string) error {
+	return s.DeleteAs(gvk, namespace, name, "")
+}
+
+// DeleteAs 以 manager 的身份删除资源
+func (s *MySQLStore) DeleteAs(gvk schema.GroupVersionKind, namespace, name, manager string) error {
+	namespace = objectNamespace(gvk, namespace)
+
 	// 获取资源（用于返回和通知）
-	obj, err := s.Get(gvk, namespace, name)
+	obj, err := s.getPrimary(gvk, namespace, name)
 	if err != nil {
 		return fmt.Errorf("resource not found: %w", err)
 	}
 
 	// 删除资源
-	tableName := tableName(gvk)
-	query := s.db.Table(tableName)
-	// Node 资源没有 namespace
-	if gvk.Kind == "Node" && gvk.Group == "" && gvk.Version == "v1" {
-		query = query.Where("name = ?", name)
-	} else {
-		query = query.Where("name = ? AND namespace = ?", name, namespace)
-	}
-	// 注意：使用硬删除，避免软删除记录仍占用 UID 唯一索引导致后续 Create 失败。
-	if err := query.Unscoped().Delete(&BaseResource{}).Error; err != nil {
+	query := whereResource(s.db.Table(tableName(gvk)), gvk, namespace, name)
+	// 硬删除：被删除的内容由历史中的 delete 版本保留
+	if err := query.Delete(&BaseResource{}).Error; err != nil {
 		return fmt.Errorf("failed to delete resource: %w", err)
 	}
+	s.recordRevision(gvk, RevisionDelete, nil, obj, manager)
+	s.replicas.recordWrite(gvk, obj, true)
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventDeleted,
 		Object: obj,
 	})
@@ -333,45 +504,41 @@ func (s *MySQLStore) Delete(gvk schema.GroupVersionKind, namespace, name string)
 	return nil
 }
 
+// DeleteCollection 删除满足 match 的资源（逐个删除，非原子）
+func (s *MySQLStore) DeleteCollection(gvk schema.GroupVersionKind, namespace string, match func(runtime.Object) bool) ([]runtime.Object, error) {
+	return deleteMatching(s, gvk, namespace, match)
+}
+
 // Watch 监听资源变更
 func (s *MySQLStore) Watch(gvk schema.GroupVersionKind, namespace string, resourceVersion string) (<-chan ResourceEvent, error) {
-	watchKey := s.watchKey(gvk, namespace)
-	ch := make(chan ResourceEvent, 100)
-
-	// 注册 watcher
-	if s.watchers[watchKey] == nil {
-		s.watchers[watchKey] = make([]chan ResourceEvent, 0)
-	}
-	s.watchers[watchKey] = append(s.watchers[watchKey], ch)
-
-	return ch, nil
+	return s.watches.add(gvk, namespace), nil
 }
 
-// notifyWatchers 通知所有 watchers
-func (s *MySQLStore) notifyWatchers(gvk schema.GroupVersionKind, namespace string, event ResourceEvent) {
-	watchKey := s.watchKey(gvk, namespace)
-	watchers := s.watchers[watchKey]
+// StopWatcher 注销并关闭 Watch 返回的通道
+func (s *MySQLStore) StopWatcher(gvk schema.GroupVersionKind, namespace string, ch <-chan ResourceEvent) {
+	s.watches.remove(gvk, namespace, ch)
+}
 
-	// 也通知全局 watchers
-	if namespace != "" {
-		globalKey := s.watchKey(gvk, "")
-		watchers = append(watchers, s.watchers[globalKey]...)
-	}
+// WatchStats 返回各组 watcher 的数量与积压的事件数
+func (s *MySQLStore) WatchStats() []WatchStats {
+	return s.watches.stats()
+}
 
-	for _, ch := range watchers {
-		select {
-		case ch <- event:
-		default:
-			// 如果通道已满，跳过
-		}
+// Reconnect 确认 MySQL 可以重新连接。连接池会在使用时丢弃失效的连接，这里只需要 Ping 一次
+func (s *MySQLStore) Reconnect(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
 	}
+	return sqlDB.PingContext(ctx)
 }
 
-// Close 关闭 MySQL 连接
+// Close 关闭 MySQL 连接（包括只读副本）
 func (s *MySQLStore) Close() error {
 	if s.db == nil {
 		return nil
 	}
+	s.replicas.close()
 	sqlDB, err := s.db.DB()
 	if err != nil {
 		return fmt.Errorf("failed to get database instance: %w", err)
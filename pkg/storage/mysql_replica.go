@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"gorm.io/gorm"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultReplicaMaxLag 未配置 storage.mysql.replica_max_lag 时副本的最大复制延迟
+const DefaultReplicaMaxLag = 5 * time.Second
+
+// mysqlReplicas MySQL 只读副本：Get/List 轮流发往各副本，写入只发往主库。
+// 副本异步复制主库，刚写入的对象在副本上可能还是旧版本（或者还没有删除）：本实例记录 maxLag 内写入的对象
+// （resourceVersion、标签，删除时记为已删除），从副本读到的结果没有反映这些写入时改读主库，
+// 因此控制器写入之后立即读取总能读到自己的写入。副本查询失败（不可达、表还没有复制过来）时也改读主库
+type mysqlReplicas struct {
+	dbs    []*gorm.DB
+	next   atomic.Uint64
+	maxLag time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+	// writes 表名 → namespace/name → 最近一次写入
+	writes map[string]*replicaWrites
+}
+
+// replicaWrites 一张表最近写入的对象
+type replicaWrites struct {
+	objects map[string]replicaWrite
+	// pruned 上一次清理过期记录的时间
+	pruned time.Time
+}
+
+// replicaWrite 一次写入：副本上的对象不比 resourceVersion 旧（删除时不存在）才算已经复制
+type replicaWrite struct {
+	namespace       string
+	resourceVersion string
+	labels          labels.Set
+	deleted         bool
+	at              time.Time
+}
+
+// openMySQLReplicas 连接 cfg.Replicas 中的只读副本，未设置的 port/user/password/database 使用主库的值
+func openMySQLReplicas(cfg config.MySQLConfig) (*mysqlReplicas, error) {
+	maxLag, err := parseDurationOr(cfg.ReplicaMaxLag, DefaultReplicaMaxLag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replica_max_lag: %w", err)
+	}
+	r := newMySQLReplicas(maxLag)
+	for _, replica := range cfg.Replicas {
+		port, user, password, database := replica.Port, replica.User, replica.Password, replica.Database
+		if port == 0 {
+			port = cfg.Port
+		}
+		if user == "" {
+			user, password = cfg.User, cfg.Password
+		}
+		if database == "" {
+			database = cfg.Database
+		}
+		db, err := openMySQL(replica.Host, port, user, password, database, cfg)
+		if err != nil {
+			r.close()
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+		r.dbs = append(r.dbs, db)
+	}
+	return r, nil
+}
+
+func newMySQLReplicas(maxLag time.Duration) *mysqlReplicas {
+	return &mysqlReplicas{maxLag: maxLag, now: time.Now, writes: make(map[string]*replicaWrites)}
+}
+
+// pick 轮流返回一个副本
+func (r *mysqlReplicas) pick() *gorm.DB {
+	return r.dbs[int(r.next.Add(1)-1)%len(r.dbs)]
+}
+
+// close 关闭副本连接
+func (r *mysqlReplicas) close() {
+	if r == nil {
+		return
+	}
+	for _, db := range r.dbs {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	}
+}
+
+// recordWrite 记录写入主库成功的对象（未配置副本时什么也不做）
+func (r *mysqlReplicas) recordWrite(gvk schema.GroupVersionKind, obj runtime.Object, deleted bool) {
+	if r == nil {
+		return
+	}
+	meta, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table := r.writes[tableName(gvk)]
+	if table == nil {
+		table = &replicaWrites{objects: make(map[string]replicaWrite), pruned: now}
+		r.writes[tableName(gvk)] = table
+	}
+	table.objects[meta.GetNamespace()+"/"+meta.GetName()] = replicaWrite{
+		namespace:       meta.GetNamespace(),
+		resourceVersion: meta.GetResourceVersion(),
+		labels:          labels.Set(meta.GetLabels()),
+		deleted:         deleted,
+		at:              now,
+	}
+	r.prune(table, now)
+}
+
+// recent 返回表中 maxLag 内的写入（副本应该已经复制了更早的写入）
+func (r *mysqlReplicas) recent(gvk schema.GroupVersionKind) map[string]replicaWrite {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table := r.writes[tableName(gvk)]
+	if table == nil {
+		return nil
+	}
+	now := r.now()
+	r.prune(table, now)
+	recent := make(map[string]replicaWrite, len(table.objects))
+	for key, w := range table.objects {
+		if now.Sub(w.at) < r.maxLag {
+			recent[key] = w
+		}
+	}
+	return recent
+}
+
+// lookup 返回 maxLag 内对 key（namespace/name）的写入
+func (r *mysqlReplicas) lookup(gvk schema.GroupVersionKind, key string) (replicaWrite, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	table := r.writes[tableName(gvk)]
+	if table == nil {
+		return replicaWrite{}, false
+	}
+	w, ok := table.objects[key]
+	if !ok || r.now().Sub(w.at) >= r.maxLag {
+		return replicaWrite{}, false
+	}
+	return w, true
+}
+
+// prune 每 maxLag 清理一次过期的写入记录（调用方持有 r.mu）
+func (r *mysqlReplicas) prune(table *replicaWrites, now time.Time) {
+	if now.Sub(table.pruned) < r.maxLag {
+		return
+	}
+	for key, w := range table.objects {
+		if now.Sub(w.at) >= r.maxLag {
+			delete(table.objects, key)
+		}
+	}
+	table.pruned = now
+}
+
+// freshObject 判断从副本 Get 到的结果（obj, err）是否可以直接返回：
+// 查询失败（不是 not found）、或者没有反映本实例最近对该对象的写入时返回 false，改读主库
+func (r *mysqlReplicas) freshObject(gvk schema.GroupVersionKind, namespace, name string, obj runtime.Object, err error) bool {
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+	w, ok := r.lookup(gvk, namespace+"/"+name)
+	if !ok {
+		return true
+	}
+	if w.deleted || err != nil {
+		return w.deleted && err != nil
+	}
+	meta, isMeta := obj.(metav1.Object)
+	return isMeta && resourceVersionAtLeast(meta.GetResourceVersion(), w.resourceVersion)
+}
+
+// freshList 判断从副本 List 到的 objects 是否反映了本实例最近的写入：namespace 与 selector 范围内，
+// 写入后仍然匹配的对象必须出现且不比写入的版本旧，已删除或不再匹配的对象不能出现
+func (r *mysqlReplicas) freshList(gvk schema.GroupVersionKind, namespace string, selector labels.Selector, objects []runtime.Object) bool {
+	recent := r.recent(gvk)
+	if len(recent) == 0 {
+		return true
+	}
+	listed := make(map[string]string, len(objects))
+	for _, obj := range objects {
+		if meta, ok := obj.(metav1.Object); ok {
+			listed[meta.GetNamespace()+"/"+meta.GetName()] = meta.GetResourceVersion()
+		}
+	}
+	for key, w := range recent {
+		if namespace != "" && w.namespace != namespace {
+			continue
+		}
+		rv, found := listed[key]
+		if !w.deleted && (selector == nil || selector.Matches(w.labels)) {
+			if !found || !resourceVersionAtLeast(rv, w.resourceVersion) {
+				return false
+			}
+		} else if found {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceVersionAtLeast 判断 a 是否不比 b 旧（MySQL 的 resourceVersion 为纳秒时间戳；无法解析时要求相等）
+func resourceVersionAtLeast(a, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil {
+		return x >= y
+	}
+	return a == b
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var replicaPodGVK = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+func replicaTestPod(name, resourceVersion string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: resourceVersion, Labels: podLabels}}
+}
+
+func TestMySQLReplicas_FreshObject(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := newMySQLReplicas(5 * time.Second)
+	r.now = func() time.Time { return now }
+
+	// 没有写入记录：副本的结果（包括 not found）直接返回；查询失败时改读主库
+	if !r.freshObject(replicaPodGVK, "default", "web", nil, gorm.ErrRecordNotFound) {
+		t.Error("not found without recent write should be fresh")
+	}
+	if r.freshObject(replicaPodGVK, "default", "web", nil, fmt.Errorf("table missing")) {
+		t.Error("replica error should fall back to primary")
+	}
+
+	r.recordWrite(replicaPodGVK, replicaTestPod("web", "200", nil), false)
+	if r.freshObject(replicaPodGVK, "default", "web", nil, gorm.ErrRecordNotFound) {
+		t.Error("not found right after create should fall back to primary")
+	}
+	if r.freshObject(replicaPodGVK, "default", "web", replicaTestPod("web", "100", nil), nil) {
+		t.Error("older resourceVersion should fall back to primary")
+	}
+	if !r.freshObject(replicaPodGVK, "default", "web", replicaTestPod("web", "200", nil), nil) {
+		t.Error("replicated resourceVersion should be fresh")
+	}
+
+	r.recordWrite(replicaPodGVK, replicaTestPod("web", "200", nil), true)
+	if r.freshObject(replicaPodGVK, "default", "web", replicaTestPod("web", "200", nil), nil) {
+		t.Error("deleted object still on replica should fall back to primary")
+	}
+	if !r.freshObject(replicaPodGVK, "default", "web", nil, gorm.ErrRecordNotFound) {
+		t.Error("not found after delete should be fresh")
+	}
+
+	// 超过 maxLag 的写入视为已经复制
+	now = now.Add(5 * time.Second)
+	if !r.freshObject(replicaPodGVK, "default", "web", replicaTestPod("web", "200", nil), nil) {
+		t.Error("write older than maxLag should not be checked")
+	}
+}
+
+func TestMySQLReplicas_FreshList(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := newMySQLReplicas(5 * time.Second)
+	r.now = func() time.Time { return now }
+
+	r.recordWrite(replicaPodGVK, replicaTestPod("a", "200", map[string]string{"app": "web"}), false)
+	r.recordWrite(replicaPodGVK, replicaTestPod("b", "300", map[string]string{"app": "db"}), false)
+	r.recordWrite(replicaPodGVK, replicaTestPod("c", "100", map[string]string{"app": "web"}), true)
+
+	web := labels.SelectorFromSet(labels.Set{"app": "web"})
+	cases := []struct {
+		name      string
+		namespace string
+		selector  labels.Selector
+		objects   []runtime.Object
+		fresh     bool
+	}{
+		{"all writes replicated", "", nil, []runtime.Object{replicaTestPod("a", "200", nil), replicaTestPod("b", "300", nil)}, true},
+		{"created object missing", "", nil, []runtime.Object{replicaTestPod("a", "200", nil)}, false},
+		{"older version", "", nil, []runtime.Object{replicaTestPod("a", "150", nil), replicaTestPod("b", "300", nil)}, false},
+		{"deleted object listed", "", nil, []runtime.Object{replicaTestPod("a", "200", nil), replicaTestPod("b", "300", nil), replicaTestPod("c", "100", nil)}, false},
+		{"selector skips non-matching write", "", web, []runtime.Object{replicaTestPod("a", "200", nil)}, true},
+		{"object no longer matching listed", "", web, []runtime.Object{replicaTestPod("a", "200", nil), replicaTestPod("b", "250", nil)}, false},
+		{"other namespace", "other", nil, nil, true},
+	}
+	for _, tc := range cases {
+		if got := r.freshList(replicaPodGVK, tc.namespace, tc.selector, tc.objects); got != tc.fresh {
+			t.Errorf("%s: freshList = %v, want %v", tc.name, got, tc.fresh)
+		}
+	}
+
+	now = now.Add(5 * time.Second)
+	if !r.freshList(replicaPodGVK, "", nil, nil) {
+		t.Error("writes older than maxLag should not be checked")
+	}
+}
+
+// TestMySQLStore_ReadReplicas 把同一个数据库同时作为只读副本：读写分离后 Get/List/ListBySelector 结果不变
+func TestMySQLStore_ReadReplicas(t *testing.T) {
+	primary := openTestMySQLStore(t)
+	primary.replicas = newMySQLReplicas(DefaultReplicaMaxLag)
+	primary.replicas.dbs = []*gorm.DB{primary.db.Session(&gorm.Session{})}
+	t.Cleanup(func() { primary.replicas = nil })
+
+	namespace := fmt.Sprintf("replica-%d", time.Now().UnixNano())
+	pod := replicaTestPod("web", "", map[string]string{"app": "web"})
+	pod.Namespace = namespace
+	if err := primary.Create(replicaPodGVK, pod); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	t.Cleanup(func() { _ = primary.Delete(replicaPodGVK, namespace, "web") })
+
+	obj, err := primary.Get(replicaPodGVK, namespace, "web")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got := obj.(metav1.Object).GetResourceVersion(); got != pod.ResourceVersion {
+		t.Errorf("resourceVersion = %s, want %s", got, pod.ResourceVersion)
+	}
+	objects, err := primary.ListBySelector(replicaPodGVK, namespace, labels.SelectorFromSet(labels.Set{"app": "web"}))
+	if err != nil || len(objects) != 1 {
+		t.Fatalf("list by selector = %d objects, %v; want 1", len(objects), err)
+	}
+	if err := primary.Delete(replicaPodGVK, namespace, "web"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := primary.Get(replicaPodGVK, namespace, "web"); err == nil {
+		t.Error("get after delete succeeded")
+	}
+}
@@ -1,32 +1,41 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/version"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/resources"
 	"gorm.io/gorm"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/scheme"
 )
 
-// BaseResource 是所有资源表的基础结构
+// BaseResource 是所有资源表的基础结构。
+// 资源行总是硬删除（没有 deleted_at 列）：删除后的内容由 k3_object_revisions 中的 delete 版本保留
 type BaseResource struct {
 	ID              uint      `gorm:"primaryKey"`
 	Name            string    `gorm:"index;size:255;not null"`
 	Namespace       string    `gorm:"index;size:255"`
 	UID             string    `gorm:"uniqueIndex;size:255"`
 	ResourceVersion string    `gorm:"index;size:255"`
+	Generation      int64     `gorm:"default:0"`
 	Labels          string    `gorm:"type:json"` // JSON 格式存储 labels
 	Annotations     string    `gorm:"type:json"` // JSON 格式存储 annotations
 	CreatedAt       time.Time `gorm:"index"`
 	UpdatedAt       time.Time
-	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
 
 // PodResource Pod 资源表
@@ -73,6 +82,21 @@ type SecretResource struct {
 	StringData string `gorm:"type:json"` // StringData 字段的 JSON
 }
 
+// StatefulSetResource StatefulSet 资源表
+type StatefulSetResource struct {
+	BaseResource
+	Replicas *int32 `gorm:"type:int"`
+	Spec     string `gorm:"type:json"` // StatefulSetSpec 的 JSON
+	Status   string `gorm:"type:json"` // StatefulSetStatus 的 JSON
+}
+
+// DaemonSetResource DaemonSet 资源表
+type DaemonSetResource struct {
+	BaseResource
+	Spec   string `gorm:"type:json"` // DaemonSetSpec 的 JSON
+	Status string `gorm:"type:json"` // DaemonSetStatus 的 JSON
+}
+
 // NodeResource Node 资源表
 type NodeResource struct {
 	BaseResource
@@ -117,6 +141,14 @@ func getTableModel(gvk schema.GroupVersionKind) interface{} {
 		if gvk.Group == "" && gvk.Version == "v1" {
 			return &SecretResource{}
 		}
+	case "StatefulSet":
+		if gvk.Group == "apps" && gvk.Version == "v1" {
+			return &StatefulSetResource{}
+		}
+	case "DaemonSet":
+		if gvk.Group == "apps" && gvk.Version == "v1" {
+			return &DaemonSetResource{}
+		}
 	case "Node":
 		if gvk.Group == "" && gvk.Version == "v1" {
 			return &NodeResource{}
@@ -138,10 +170,18 @@ func (s *MySQLStore) ensureTable(gvk schema.GroupVersionKind) error {
 	}
 
 	if count == 0 {
-		// 表不存在，创建表
+		// 表不存在，创建表（连同常用标签的生成列与索引）
 		if err := s.db.Table(tableName).AutoMigrate(model); err != nil {
 			return fmt.Errorf("failed to create table %s: %w", tableName, err)
 		}
+		if err := s.addLabelColumns(s.db, tableName); err != nil {
+			return err
+		}
+	} else if migrator := s.db.Table(tableName).Migrator(); !migrator.HasColumn(model, "Generation") {
+		// 旧表补齐 generation 列
+		if err := migrator.AddColumn(model, "Generation"); err != nil {
+			return fmt.Errorf("failed to add generation column to %s: %w", tableName, err)
+		}
 	}
 
 	return nil
@@ -157,6 +197,7 @@ func toBaseResource(meta metav1.Object) BaseResource {
 		Namespace:       meta.GetNamespace(),
 		UID:             string(meta.GetUID()),
 		ResourceVersion: meta.GetResourceVersion(),
+		Generation:      meta.GetGeneration(),
 		Labels:          string(labelsJSON),
 		Annotations:     string(annotationsJSON),
 		CreatedAt:       meta.GetCreationTimestamp().Time,
@@ -175,6 +216,7 @@ func fromBaseResource(base BaseResource, obj runtime.Object) error {
 	meta.SetNamespace(base.Namespace)
 	meta.SetUID(types.UID(base.UID))
 	meta.SetResourceVersion(base.ResourceVersion)
+	meta.SetGeneration(base.Generation)
 	meta.SetCreationTimestamp(metav1.NewTime(base.CreatedAt))
 
 	// 恢复 labels
@@ -214,11 +256,11 @@ func (s *MySQLStore) savePod(pod *corev1.Pod) error {
 }
 
 // loadPod 加载 Pod 资源
-func (s *MySQLStore) loadPod(gvk schema.GroupVersionKind, namespace, name string) (*corev1.Pod, error) {
+func (s *MySQLStore) loadPod(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (*corev1.Pod, error) {
 	tableName := tableName(gvk)
 	var resource PodResource
 
-	if err := s.db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
+	if err := db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
 		return nil, err
 	}
 
@@ -275,11 +317,11 @@ func (s *MySQLStore) saveDeployment(deployment *appsv1.Deployment) error {
 }
 
 // loadDeployment 加载 Deployment 资源
-func (s *MySQLStore) loadDeployment(gvk schema.GroupVersionKind, namespace, name string) (*appsv1.Deployment, error) {
+func (s *MySQLStore) loadDeployment(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (*appsv1.Deployment, error) {
 	tableName := tableName(gvk)
 	var resource DeploymentResource
 
-	if err := s.db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
+	if err := db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
 		return nil, err
 	}
 
@@ -339,11 +381,11 @@ func (s *MySQLStore) saveService(service *corev1.Service) error {
 }
 
 // loadService 加载 Service 资源
-func (s *MySQLStore) loadService(gvk schema.GroupVersionKind, namespace, name string) (*corev1.Service, error) {
+func (s *MySQLStore) loadService(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (*corev1.Service, error) {
 	tableName := tableName(gvk)
 	var resource ServiceResource
 
-	if err := s.db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
+	if err := db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
 		return nil, err
 	}
 
@@ -369,6 +411,205 @@ func (s *MySQLStore) loadService(gvk schema.GroupVersionKind, namespace, name st
 	return service, nil
 }
 
+// saveConfigMap 保存 ConfigMap 资源：data 与 binaryData 分别写入对应的列（binaryData 的值在 JSON 中为 base64）
+func (s *MySQLStore) saveConfigMap(gvk schema.GroupVersionKind, cm *corev1.ConfigMap) error {
+	tableName := tableName(gvk)
+	base := toBaseResource(cm)
+
+	dataJSON, _ := json.Marshal(cm.Data)
+	binaryJSON, _ := json.Marshal(cm.BinaryData)
+
+	resource := ConfigMapResource{
+		BaseResource: base,
+		Data:         string(dataJSON),
+		BinaryData:   string(binaryJSON),
+	}
+
+	return s.db.Table(tableName).Create(&resource).Error
+}
+
+// loadConfigMap 加载 ConfigMap 资源；data 与 binaryData 列都为空的行由旧版本按通用资源写入，按通用资源加载
+func (s *MySQLStore) loadConfigMap(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	tableName := tableName(gvk)
+	var resource ConfigMapResource
+
+	if err := db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
+		return nil, err
+	}
+	if resource.Data == "" && resource.BinaryData == "" {
+		return s.loadGenericResource(db, gvk, namespace, name)
+	}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+		},
+	}
+
+	if err := fromBaseResource(resource.BaseResource, cm); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(resource.Data), &cm.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode configmap data: %w", err)
+	}
+	if err := json.Unmarshal([]byte(resource.BinaryData), &cm.BinaryData); err != nil {
+		return nil, fmt.Errorf("failed to decode configmap binaryData: %w", err)
+	}
+
+	return cm, nil
+}
+
+// saveSecret 保存 Secret 资源：type、data 与 stringData 分别写入对应的列
+func (s *MySQLStore) saveSecret(gvk schema.GroupVersionKind, secret *corev1.Secret) error {
+	tableName := tableName(gvk)
+	base := toBaseResource(secret)
+
+	dataJSON, _ := json.Marshal(secret.Data)
+	stringDataJSON, _ := json.Marshal(secret.StringData)
+
+	resource := SecretResource{
+		BaseResource: base,
+		Type:         string(secret.Type),
+		Data:         string(dataJSON),
+		StringData:   string(stringDataJSON),
+	}
+
+	return s.db.Table(tableName).Create(&resource).Error
+}
+
+// loadSecret 加载 Secret 资源；data 与 stringData 列都为空的行由旧版本按通用资源写入，按通用资源加载
+func (s *MySQLStore) loadSecret(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	tableName := tableName(gvk)
+	var resource SecretResource
+
+	if err := db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
+		return nil, err
+	}
+	if resource.Data == "" && resource.StringData == "" {
+		return s.loadGenericResource(db, gvk, namespace, name)
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+		},
+		Type: corev1.SecretType(resource.Type),
+	}
+
+	if err := fromBaseResource(resource.BaseResource, secret); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(resource.Data), &secret.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode secret data: %w", err)
+	}
+	if err := json.Unmarshal([]byte(resource.StringData), &secret.StringData); err != nil {
+		return nil, fmt.Errorf("failed to decode secret stringData: %w", err)
+	}
+
+	return secret, nil
+}
+
+// saveStatefulSet 保存 StatefulSet 资源
+func (s *MySQLStore) saveStatefulSet(gvk schema.GroupVersionKind, sts *appsv1.StatefulSet) error {
+	tableName := tableName(gvk)
+	base := toBaseResource(sts)
+
+	specJSON, _ := json.Marshal(sts.Spec)
+	statusJSON, _ := json.Marshal(sts.Status)
+
+	resource := StatefulSetResource{
+		BaseResource: base,
+		Replicas:     sts.Spec.Replicas,
+		Spec:         string(specJSON),
+		Status:       string(statusJSON),
+	}
+
+	return s.db.Table(tableName).Create(&resource).Error
+}
+
+// loadStatefulSet 加载 StatefulSet 资源；spec 列为空的行由旧版本按通用资源写入，按通用资源加载
+func (s *MySQLStore) loadStatefulSet(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	tableName := tableName(gvk)
+	var resource StatefulSetResource
+
+	if err := db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
+		return nil, err
+	}
+	if resource.Spec == "" {
+		return s.loadGenericResource(db, gvk, namespace, name)
+	}
+
+	sts := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+		},
+	}
+
+	if err := fromBaseResource(resource.BaseResource, sts); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(resource.Spec), &sts.Spec); err != nil {
+		return nil, fmt.Errorf("failed to decode statefulset spec: %w", err)
+	}
+	if err := json.Unmarshal([]byte(resource.Status), &sts.Status); err != nil {
+		return nil, fmt.Errorf("failed to decode statefulset status: %w", err)
+	}
+
+	return sts, nil
+}
+
+// saveDaemonSet 保存 DaemonSet 资源
+func (s *MySQLStore) saveDaemonSet(gvk schema.GroupVersionKind, ds *appsv1.DaemonSet) error {
+	tableName := tableName(gvk)
+	base := toBaseResource(ds)
+
+	specJSON, _ := json.Marshal(ds.Spec)
+	statusJSON, _ := json.Marshal(ds.Status)
+
+	resource := DaemonSetResource{
+		BaseResource: base,
+		Spec:         string(specJSON),
+		Status:       string(statusJSON),
+	}
+
+	return s.db.Table(tableName).Create(&resource).Error
+}
+
+// loadDaemonSet 加载 DaemonSet 资源；spec 列为空的行由旧版本按通用资源写入，按通用资源加载
+func (s *MySQLStore) loadDaemonSet(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	tableName := tableName(gvk)
+	var resource DaemonSetResource
+
+	if err := db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
+		return nil, err
+	}
+	if resource.Spec == "" {
+		return s.loadGenericResource(db, gvk, namespace, name)
+	}
+
+	ds := &appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+		},
+	}
+
+	if err := fromBaseResource(resource.BaseResource, ds); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(resource.Spec), &ds.Spec); err != nil {
+		return nil, fmt.Errorf("failed to decode daemonset spec: %w", err)
+	}
+	if err := json.Unmarshal([]byte(resource.Status), &ds.Status); err != nil {
+		return nil, fmt.Errorf("failed to decode daemonset status: %w", err)
+	}
+
+	return ds, nil
+}
+
 // saveGenericResource 保存通用资源（使用基础表结构）
 func (s *MySQLStore) saveGenericResource(gvk schema.GroupVersionKind, obj runtime.Object) error {
 	tableName := tableName(gvk)
@@ -379,33 +620,55 @@ func (s *MySQLStore) saveGenericResource(gvk schema.GroupVersionKind, obj runtim
 
 	base := toBaseResource(meta)
 
-	// 将整个对象序列化为 JSON 存储在 annotations 中（作为备用）
-	objJSON, _ := json.Marshal(obj)
+	// 将整个对象序列化为 JSON 存储在 annotations 中（作为备用）；
+	// 控制器写入的对象常常没有设置 apiVersion/kind，按 gvk 补齐后读取时才能解码
+	stored := obj.DeepCopyObject()
+	stored.GetObjectKind().SetGroupVersionKind(gvk)
+	objJSON, _ := json.Marshal(stored)
 	base.Annotations = string(objJSON)
 
 	return s.db.Table(tableName).Create(&base).Error
 }
 
 // loadGenericResource 加载通用资源
-func (s *MySQLStore) loadGenericResource(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+func (s *MySQLStore) loadGenericResource(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
 	tableName := tableName(gvk)
 	var resource BaseResource
 
-	if err := s.db.Table(tableName).Where("name = ? AND namespace = ?", name, namespace).First(&resource).Error; err != nil {
+	if err := whereResource(db.Table(tableName), gvk, namespace, name).First(&resource).Error; err != nil {
 		return nil, err
 	}
 
 	// 从 annotations 中恢复完整对象
-	if resource.Annotations != "" {
-		// 尝试解析为完整对象
-		obj, _, err := s.parser.ParseYAML([]byte(resource.Annotations))
-		if err == nil {
-			return obj, nil
-		}
+	if resource.Annotations == "" {
+		return nil, fmt.Errorf("failed to load generic resource: empty object")
+	}
+	obj, err := decodeStoredObject(s.parser, gvk, []byte(resource.Annotations))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load generic resource: %w", err)
 	}
+	// 行的 namespace 列为准（v7 迁移改写了旧版本写入的空 namespace）
+	if meta, ok := obj.(metav1.Object); ok {
+		meta.SetNamespace(resource.Namespace)
+	}
+	return obj, nil
+}
 
-	// 如果解析失败，返回错误
-	return nil, fmt.Errorf("failed to load generic resource")
+// decodeStoredObject 解码保存的完整对象；旧版本写入的对象缺少 apiVersion/kind 时按 gvk 对应的类型解码
+func decodeStoredObject(p *parser.Parser, gvk schema.GroupVersionKind, data []byte) (runtime.Object, error) {
+	obj, _, err := p.ParseYAML(data)
+	if err == nil {
+		return obj, nil
+	}
+	typed, newErr := scheme.Scheme.New(gvk)
+	if newErr != nil {
+		return nil, err
+	}
+	if jsonErr := json.Unmarshal(data, typed); jsonErr != nil {
+		return nil, jsonErr
+	}
+	typed.GetObjectKind().SetGroupVersionKind(gvk)
+	return typed, nil
 }
 
 // saveNode 保存 Node 资源（支持创建和更新）
@@ -434,22 +697,16 @@ func (s *MySQLStore) saveNode(node *corev1.Node) error {
 	}
 
 	// 节点不存在，创建新节点
-	// 注意：历史版本曾对资源做软删除，可能导致“查询不到但唯一索引仍占用（UID）”。
-	// 这里做一次 best-effort 的硬删除清理，避免 Duplicate entry。
-	_ = s.db.Table(tableName).
-		Unscoped().
-		Where("name = ? OR uid = ?", node.Name, resource.UID).
-		Delete(&NodeResource{}).Error
 	return s.db.Table(tableName).Create(&resource).Error
 }
 
 // loadNode 加载 Node 资源
-func (s *MySQLStore) loadNode(gvk schema.GroupVersionKind, namespace, name string) (*corev1.Node, error) {
+func (s *MySQLStore) loadNode(db *gorm.DB, gvk schema.GroupVersionKind, namespace, name string) (*corev1.Node, error) {
 	tableName := tableName(gvk)
 	var resource NodeResource
 
 	// Node 资源没有 namespace，使用空字符串查询
-	if err := s.db.Table(tableName).Where("name = ?", name).First(&resource).Error; err != nil {
+	if err := db.Table(tableName).Where("name = ?", name).First(&resource).Error; err != nil {
 		return nil, err
 	}
 
@@ -474,3 +731,413 @@ func (s *MySQLStore) loadNode(gvk schema.GroupVersionKind, namespace, name strin
 
 	return node, nil
 }
+
+// schemaTable 记录数据 schema 版本的表（只有一行）
+const schemaTable = "k3_schema_version"
+
+// schemaRecord schema 版本记录
+type schemaRecord struct {
+	ID            uint `gorm:"primaryKey"`
+	Version       int
+	BinaryVersion string `gorm:"size:64"`
+	UpdatedAt     time.Time
+}
+
+// mysqlMigrations MySQL 各 schema 版本的迁移步骤
+func (s *MySQLStore) mysqlMigrations() map[int]func(context.Context) error {
+	return map[int]func(context.Context) error{
+		2: s.addGenerationColumns,
+		3: s.clearClusterScopedNamespaces,
+		4: s.addLabelIndexes,
+		5: s.purgeSoftDeletedRows,
+		6: s.convertTypedTables,
+		7: s.defaultEmptyNamespaces,
+	}
+}
+
+// resourceTables 列出已有的资源表（k8s_ 前缀）
+func (s *MySQLStore) resourceTables(ctx context.Context) ([]string, error) {
+	var tables []string
+	if err := s.db.WithContext(ctx).Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name LIKE 'k8s\\_%'").Scan(&tables).Error; err != nil {
+		return nil, fmt.Errorf("failed to list resource tables: %w", err)
+	}
+	return tables, nil
+}
+
+// addGenerationColumns 为旧的资源表补齐 generation 列（v2）
+func (s *MySQLStore) addGenerationColumns(ctx context.Context) error {
+	tables, err := s.resourceTables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		migrator := s.db.WithContext(ctx).Table(table).Migrator()
+		if migrator.HasColumn(&BaseResource{}, "Generation") {
+			continue
+		}
+		if err := migrator.AddColumn(&BaseResource{}, "Generation"); err != nil {
+			return fmt.Errorf("failed to add generation column to %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// clearClusterScopedNamespaces 清空集群级资源表中旧版本写入的 namespace（v3）
+func (s *MySQLStore) clearClusterScopedNamespaces(ctx context.Context) error {
+	tables, err := s.resourceTables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if !isClusterScopedTable(table) {
+			continue
+		}
+		if err := s.db.WithContext(ctx).Table(table).Where("namespace <> ''").Update("namespace", "").Error; err != nil {
+			return fmt.Errorf("failed to clear namespace in %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// purgeSoftDeletedRows 删除旧版本软删除后留下的行并去掉 deleted_at 列（v5）。
+// 这些行查询不到却仍占用 uid 唯一索引；去掉该列后资源表只有硬删除
+func (s *MySQLStore) purgeSoftDeletedRows(ctx context.Context) error {
+	tables, err := s.resourceTables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		db := s.db.WithContext(ctx)
+		migrator := db.Table(table).Migrator()
+		if !migrator.HasColumn(&BaseResource{}, "deleted_at") {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf("DELETE FROM `%s` WHERE `deleted_at` IS NOT NULL", table)).Error; err != nil {
+			return fmt.Errorf("failed to purge soft-deleted rows in %s: %w", table, err)
+		}
+		if err := migrator.DropColumn(&BaseResource{}, "deleted_at"); err != nil {
+			return fmt.Errorf("failed to drop deleted_at column in %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// defaultEmptyNamespaces 把 namespace 级资源表中 namespace 为空的行移到 default（v7）；
+// default 中已有同名对象时保留 default 中的对象，删除空 namespace 的行
+func (s *MySQLStore) defaultEmptyNamespaces(ctx context.Context) error {
+	tables, err := s.resourceTables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if isClusterScopedTable(table) {
+			continue
+		}
+		db := s.db.WithContext(ctx)
+		if err := db.Exec(fmt.Sprintf("DELETE FROM `%[1]s` WHERE `namespace` = '' AND `name` IN (SELECT `name` FROM (SELECT `name` FROM `%[1]s` WHERE `namespace` = ?) AS taken)", table), metav1.NamespaceDefault).Error; err != nil {
+			return fmt.Errorf("failed to drop shadowed rows in %s: %w", table, err)
+		}
+		if err := db.Table(table).Where("namespace = ''").Update("namespace", metav1.NamespaceDefault).Error; err != nil {
+			return fmt.Errorf("failed to default namespace in %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// typedTables 按列保存的资源（v6 起）以及旧版本按通用资源写入的行的条件（按列保存的字段都为 NULL）
+var typedTables = []struct {
+	gvk     schema.GroupVersionKind
+	generic string
+}{
+	{schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "`data` IS NULL AND `binary_data` IS NULL"},
+	{schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, "`data` IS NULL AND `string_data` IS NULL"},
+	{schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}, "`spec` IS NULL"},
+	{schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}, "`spec` IS NULL"},
+}
+
+// convertTypedTables 为 ConfigMap、Secret、StatefulSet、DaemonSet 表补齐按列保存的字段，
+// 并把旧版本按通用资源写入的行改写为按列保存（v6）。无法解码的行保留原样，读取时仍按通用资源加载
+func (s *MySQLStore) convertTypedTables(ctx context.Context) error {
+	tables, err := s.resourceTables(ctx)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		existing[table] = true
+	}
+	for _, typed := range typedTables {
+		table := tableName(typed.gvk)
+		if !existing[table] {
+			continue
+		}
+		db := s.db.WithContext(ctx)
+		if err := db.Table(table).AutoMigrate(getTableModel(typed.gvk)); err != nil {
+			return fmt.Errorf("failed to add typed columns to %s: %w", table, err)
+		}
+		var bases []BaseResource
+		if err := db.Table(table).Where(typed.generic).Find(&bases).Error; err != nil {
+			return fmt.Errorf("failed to list generic rows in %s: %w", table, err)
+		}
+		for _, base := range bases {
+			obj, err := decodeStoredObject(s.parser, typed.gvk, []byte(base.Annotations))
+			if err != nil {
+				continue
+			}
+			if err := db.Table(table).Where("id = ?", base.ID).Delete(&BaseResource{}).Error; err != nil {
+				return fmt.Errorf("failed to rewrite %s/%s in %s: %w", base.Namespace, base.Name, table, err)
+			}
+			if err := s.saveTyped(typed.gvk, obj); err != nil {
+				return fmt.Errorf("failed to rewrite %s/%s in %s: %w", base.Namespace, base.Name, table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// saveTyped 按列保存 typedTables 中的资源
+func (s *MySQLStore) saveTyped(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		return s.saveConfigMap(gvk, o)
+	case *corev1.Secret:
+		return s.saveSecret(gvk, o)
+	case *appsv1.StatefulSet:
+		return s.saveStatefulSet(gvk, o)
+	case *appsv1.DaemonSet:
+		return s.saveDaemonSet(gvk, o)
+	}
+	return fmt.Errorf("unexpected object type %T for %s", obj, gvk.Kind)
+}
+
+// indexedLabels 在资源表中建立生成列与索引的常用标签（控制器与 Service 选择 Pod 时使用）
+var indexedLabels = []string{
+	"app",
+	"app.kubernetes.io/name",
+	"app.kubernetes.io/instance",
+	"pod-template-hash",
+}
+
+// labelColumn 返回标签对应的生成列名，例如 app.kubernetes.io/name → label_app_kubernetes_io_name
+func labelColumn(key string) string {
+	return "label_" + strings.NewReplacer(".", "_", "/", "_", "-", "_").Replace(key)
+}
+
+// labelJSONPath 返回标签在 labels 列中的 JSON 路径
+func labelJSONPath(key string) string {
+	return `$."` + key + `"`
+}
+
+// isIndexedLabel 判断标签是否有生成列
+func isIndexedLabel(key string) bool {
+	for _, k := range indexedLabels {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// whereLabel 把一个标签条件转换为查询条件；!=、notin 不转换（NULL 的语义与 selector 不同），由调用方在 Go 中过滤。
+// JSON 路径作为参数传入；不是合法标签名的键也不转换（同样由调用方在 Go 中过滤）
+func whereLabel(query *gorm.DB, req labels.Requirement) *gorm.DB {
+	key := req.Key()
+	if len(validation.IsQualifiedName(key)) > 0 {
+		return query
+	}
+	switch req.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		if isIndexedLabel(key) {
+			return query.Where(labelColumn(key)+" IN ?", req.Values().List())
+		}
+		return query.Where("JSON_UNQUOTE(JSON_EXTRACT(labels, ?)) IN ?", labelJSONPath(key), req.Values().List())
+	case selection.Exists:
+		return query.Where("JSON_CONTAINS_PATH(labels, 'one', ?) = 1", labelJSONPath(key))
+	case selection.DoesNotExist:
+		return query.Where("(labels IS NULL OR JSON_CONTAINS_PATH(labels, 'one', ?) = 0)", labelJSONPath(key))
+	}
+	return query
+}
+
+// addLabelColumns 为表补齐 indexedLabels 的虚拟生成列与索引（已有的列跳过）
+func (s *MySQLStore) addLabelColumns(db *gorm.DB, table string) error {
+	migrator := db.Table(table).Migrator()
+	for _, key := range indexedLabels {
+		column := labelColumn(key)
+		if migrator.HasColumn(&BaseResource{}, column) {
+			continue
+		}
+		ddl := fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` VARCHAR(255) GENERATED ALWAYS AS (JSON_UNQUOTE(JSON_EXTRACT(`labels`, '%s'))) VIRTUAL, ADD INDEX `idx_%s` (`%s`)",
+			table, column, labelJSONPath(key), column, column)
+		if err := db.Exec(ddl).Error; err != nil {
+			return fmt.Errorf("failed to add label column %s to %s: %w", column, table, err)
+		}
+	}
+	return nil
+}
+
+// addLabelIndexes 为已有的资源表补齐常用标签的生成列与索引（v4）
+func (s *MySQLStore) addLabelIndexes(ctx context.Context) error {
+	tables, err := s.resourceTables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if err := s.addLabelColumns(s.db.WithContext(ctx), table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isClusterScopedTable 判断表是否存放集群级资源（任意版本的 k8s_{group}_{version}_{kind}）
+func isClusterScopedTable(table string) bool {
+	for _, gk := range resources.ClusterScopedKinds() {
+		prefix := tableName(schema.GroupVersionKind{Group: gk.Group, Kind: gk.Kind})
+		// 去掉版本得到 k8s_{group}_ 与 _{kind}
+		parts := strings.SplitN(prefix, "__", 2)
+		if len(parts) == 2 && strings.HasPrefix(table, parts[0]+"_") && strings.HasSuffix(table, "_"+parts[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaVersion 读取 k3_schema_version；没有记录时按是否已有资源表区分空库（0）与旧版本的数据（1）
+func (s *MySQLStore) SchemaVersion(ctx context.Context) (int, error) {
+	db := s.db.WithContext(ctx)
+	if db.Migrator().HasTable(schemaTable) {
+		var record schemaRecord
+		err := db.Table(schemaTable).Order("id").Limit(1).Find(&record).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to read schema version: %w", err)
+		}
+		if record.ID != 0 {
+			return record.Version, nil
+		}
+	}
+	tables, err := s.resourceTables(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(tables) == 0 {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// Migrate 执行待执行的 schema 迁移并记录版本
+func (s *MySQLStore) Migrate(ctx context.Context) ([]Migration, error) {
+	stored, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runMigrations(ctx, stored, s.mysqlMigrations(), s.recordSchemaVersion)
+}
+
+// recordSchemaVersion 写入 schema 版本以及写入它的 k3 版本
+func (s *MySQLStore) recordSchemaVersion(ctx context.Context, v int) error {
+	db := s.db.WithContext(ctx)
+	if err := db.Table(schemaTable).AutoMigrate(&schemaRecord{}); err != nil {
+		return fmt.Errorf("failed to create %s: %w", schemaTable, err)
+	}
+	record := schemaRecord{ID: 1, Version: v, BinaryVersion: version.Version, UpdatedAt: time.Now()}
+	if err := db.Table(schemaTable).Save(&record).Error; err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return nil
+}
+
+// revisionTable 保存对象版本历史的表（第一次记录历史时创建）
+const revisionTable = "k3_object_revisions"
+
+// revisionRecord 对象的一个历史版本
+type revisionRecord struct {
+	ID uint `gorm:"primaryKey"`
+	// Resource 对象的 resourcePath(gvk, namespace, name)
+	Resource        string `gorm:"index:idx_object_revision,priority:1;size:512;not null"`
+	Revision        int64  `gorm:"index:idx_object_revision,priority:2"`
+	ResourceVersion string `gorm:"size:255"`
+	Operation       string `gorm:"size:16"`
+	Manager         string `gorm:"size:255"`
+	CreatedAt       time.Time
+	Object          string `gorm:"type:longtext"`
+}
+
+// ensureRevisionTable 确保历史表存在
+func (s *MySQLStore) ensureRevisionTable() error {
+	if s.revisionTableReady.Load() {
+		return nil
+	}
+	if err := s.db.Table(revisionTable).AutoMigrate(&revisionRecord{}); err != nil {
+		return fmt.Errorf("failed to create %s: %w", revisionTable, err)
+	}
+	s.revisionTableReady.Store(true)
+	return nil
+}
+
+// recordRevision 记录对象的一个版本并删除超出 historyLimit 的旧版本；失败时不影响本次写入
+func (s *MySQLStore) recordRevision(gvk schema.GroupVersionKind, op RevisionOperation, old, obj runtime.Object, manager string) {
+	if s.historyLimit <= 0 {
+		return
+	}
+	rev, err := newRevision(op, old, obj, manager)
+	if err != nil {
+		return
+	}
+	meta, err := getObjectMeta(obj)
+	if err != nil {
+		return
+	}
+	_ = s.saveRevision(resourcePath(gvk, meta.GetNamespace(), meta.GetName()), rev)
+}
+
+// saveRevision 以 key 下一个版本号保存 rev
+func (s *MySQLStore) saveRevision(key string, rev Revision) error {
+	if err := s.ensureRevisionTable(); err != nil {
+		return err
+	}
+	var last revisionRecord
+	if err := s.db.Table(revisionTable).Where("resource = ?", key).Order("revision DESC").Limit(1).Find(&last).Error; err != nil {
+		return fmt.Errorf("failed to read last revision: %w", err)
+	}
+	record := revisionRecord{
+		Resource:        key,
+		Revision:        last.Revision + 1,
+		ResourceVersion: rev.ResourceVersion,
+		Operation:       string(rev.Operation),
+		Manager:         rev.Manager,
+		CreatedAt:       rev.Timestamp,
+		Object:          string(rev.Object),
+	}
+	if err := s.db.Table(revisionTable).Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to save revision: %w", err)
+	}
+	return s.db.Table(revisionTable).
+		Where("resource = ? AND revision <= ?", key, record.Revision-int64(s.historyLimit)).
+		Delete(&revisionRecord{}).Error
+}
+
+// History 返回对象保留的版本
+func (s *MySQLStore) History(gvk schema.GroupVersionKind, namespace, name string) ([]Revision, error) {
+	if err := s.ensureRevisionTable(); err != nil {
+		return nil, err
+	}
+	var records []revisionRecord
+	key := resourcePath(gvk, namespace, name)
+	if err := s.db.Table(revisionTable).Where("resource = ?", key).Order("revision").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	revisions := make([]Revision, 0, len(records))
+	for _, r := range records {
+		revisions = append(revisions, Revision{
+			Revision:        r.Revision,
+			ResourceVersion: r.ResourceVersion,
+			Operation:       RevisionOperation(r.Operation),
+			Manager:         r.Manager,
+			Timestamp:       r.CreatedAt,
+			Object:          json.RawMessage(r.Object),
+		})
+	}
+	return revisions, nil
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/pkg/parser"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	statefulSetGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	daemonSetGVK   = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}
+)
+
+func TestDecodeStoredObjectWithoutTypeMeta(t *testing.T) {
+	// 旧版本的 saveGenericResource 直接序列化控制器写入的对象，没有 apiVersion/kind
+	replicas := int32(3)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas, ServiceName: "db"},
+	}
+	data, err := json.Marshal(sts)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	obj, err := decodeStoredObject(parser.NewParser(), statefulSetGVK, data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	got, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		t.Fatalf("decoded %T, want *appsv1.StatefulSet", obj)
+	}
+	if got.Kind != "StatefulSet" || got.APIVersion != "apps/v1" || *got.Spec.Replicas != 3 || got.Spec.ServiceName != "db" {
+		t.Fatalf("unexpected decoded object: %+v", got)
+	}
+
+	// 带 apiVersion/kind 的对象按声明的类型解码
+	cm := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}, ObjectMeta: metav1.ObjectMeta{Name: "cfg"}}
+	data, _ = json.Marshal(cm)
+	if obj, err := decodeStoredObject(parser.NewParser(), payloadConfigMapGVK, data); err != nil {
+		t.Fatalf("decode configmap: %v", err)
+	} else if _, ok := obj.(*corev1.ConfigMap); !ok {
+		t.Fatalf("decoded %T, want *corev1.ConfigMap", obj)
+	}
+
+	if _, err := decodeStoredObject(parser.NewParser(), statefulSetGVK, []byte("not json")); err == nil {
+		t.Fatal("expected error for invalid data")
+	}
+}
+
+func TestTypedTableModels(t *testing.T) {
+	tests := []struct {
+		gvk  schema.GroupVersionKind
+		want interface{}
+	}{
+		{payloadConfigMapGVK, &ConfigMapResource{}},
+		{payloadSecretGVK, &SecretResource{}},
+		{statefulSetGVK, &StatefulSetResource{}},
+		{daemonSetGVK, &DaemonSetResource{}},
+		{schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, &BaseResource{}},
+	}
+	for _, tt := range tests {
+		if got := getTableModel(tt.gvk); reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+			t.Errorf("getTableModel(%s) = %T, want %T", tt.gvk.Kind, got, tt.want)
+		}
+	}
+	for _, typed := range typedTables {
+		if _, ok := getTableModel(typed.gvk).(*BaseResource); ok {
+			t.Errorf("%s is listed in typedTables but has no typed model", typed.gvk.Kind)
+		}
+	}
+}
+
+// TestMySQLStore_TypedRoundTrip 需要 K3_TEST_MYSQL_DSN：没有 TypeMeta 的 StatefulSet/DaemonSet 按列保存并原样读回
+func TestMySQLStore_TypedRoundTrip(t *testing.T) {
+	store := openTestMySQLStore(t)
+	namespace := fmt.Sprintf("typed-%d", time.Now().UnixNano())
+
+	replicas := int32(2)
+	labels := map[string]string{"app": "db"}
+	objects := []struct {
+		gvk schema.GroupVersionKind
+		obj runtime.Object
+	}{
+		{statefulSetGVK, &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: namespace, Labels: labels, Annotations: map[string]string{"note": "x"}},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:    &replicas,
+				ServiceName: "db",
+				Selector:    &metav1.LabelSelector{MatchLabels: labels},
+			},
+			Status: appsv1.StatefulSetStatus{ReadyReplicas: 1},
+		}},
+		{daemonSetGVK, &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: namespace, Labels: labels},
+			Spec:       appsv1.DaemonSetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3},
+		}},
+	}
+	for _, o := range objects {
+		o := o
+		meta, _ := getObjectMeta(o.obj)
+		t.Cleanup(func() { _ = store.Delete(o.gvk, namespace, meta.GetName()) })
+		if err := store.Create(o.gvk, o.obj.DeepCopyObject()); err != nil {
+			t.Fatalf("create %s: %v", o.gvk.Kind, err)
+		}
+		got, err := store.Get(o.gvk, namespace, meta.GetName())
+		if err != nil {
+			t.Fatalf("get %s: %v", o.gvk.Kind, err)
+		}
+		switch want := o.obj.(type) {
+		case *appsv1.StatefulSet:
+			sts := got.(*appsv1.StatefulSet)
+			if !reflect.DeepEqual(sts.Spec, want.Spec) || sts.Status.ReadyReplicas != 1 || sts.Annotations["note"] != "x" {
+				t.Errorf("statefulset changed after round trip: %+v", sts)
+			}
+		case *appsv1.DaemonSet:
+			ds := got.(*appsv1.DaemonSet)
+			if !reflect.DeepEqual(ds.Spec, want.Spec) || ds.Status.DesiredNumberScheduled != 3 {
+				t.Errorf("daemonset changed after round trip: %+v", ds)
+			}
+		}
+	}
+
+	listed, err := store.List(daemonSetGVK, namespace)
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("list daemonsets: %d objects, err=%v", len(listed), err)
+	}
+}
+
+func TestWhereLabelBindsPath(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:1)/k3", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	// build 返回生成的 SQL（参数为 ?）与参数
+	build := func(set labels.Set) (string, []interface{}) {
+		query := db.Table("pods")
+		reqs, _ := labels.SelectorFromSet(set).Requirements()
+		for _, req := range reqs {
+			query = whereLabel(query, req)
+		}
+		stmt := query.Find(&[]BaseResource{}).Statement
+		return stmt.SQL.String(), stmt.Vars
+	}
+
+	// 普通标签的 JSON 路径作为参数传入，常用标签使用生成列
+	sql, vars := build(labels.Set{"tier": "web"})
+	if !strings.Contains(sql, "JSON_UNQUOTE(JSON_EXTRACT(labels, ?)) IN (?)") || len(vars) != 2 || vars[0] != `$."tier"` {
+		t.Errorf("plain label: %s %v", sql, vars)
+	}
+	if sql, _ := build(labels.Set{"app": "web"}); !strings.Contains(sql, "label_app IN (?)") {
+		t.Errorf("indexed label: %s", sql)
+	}
+
+	// labels.SelectorFromSet 不校验键：不合法的键不生成 SQL 条件（由 filterBySelector 在 Go 中过滤）
+	hostile := `x"')) OR 1=1 -- `
+	if sql, vars := build(labels.Set{hostile: "v"}); strings.Contains(sql, "OR 1=1") || strings.Contains(sql, "WHERE") || len(vars) != 0 {
+		t.Errorf("hostile key reached SQL: %s %v", sql, vars)
+	}
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// testEmptyNamespaceDefaults 检查没有 namespace 的 namespace 级对象写入 default，且按 "" 与 default 都能读取、更新、删除
+func testEmptyNamespaceDefaults(t *testing.T, store Store) {
+	t.Helper()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	name := "no-namespace"
+	t.Cleanup(func() { _ = store.Delete(gvk, metav1.NamespaceDefault, name) })
+
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Data:       map[string]string{"k": "v1"},
+	}
+	if err := store.Create(gvk, cm); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if cm.Namespace != metav1.NamespaceDefault {
+		t.Fatalf("created object namespace = %q, want default", cm.Namespace)
+	}
+	for _, ns := range []string{"", metav1.NamespaceDefault} {
+		obj, err := store.Get(gvk, ns, name)
+		if err != nil {
+			t.Fatalf("get with namespace %q: %v", ns, err)
+		}
+		if got := obj.(*corev1.ConfigMap).Namespace; got != metav1.NamespaceDefault {
+			t.Fatalf("get with namespace %q returned namespace %q", ns, got)
+		}
+	}
+	objects, err := store.List(gvk, metav1.NamespaceDefault)
+	if err != nil {
+		t.Fatalf("list default: %v", err)
+	}
+	listed := false
+	for _, obj := range objects {
+		listed = listed || obj.(*corev1.ConfigMap).Name == name
+	}
+	if !listed {
+		t.Fatalf("object not listed in default")
+	}
+
+	// 更新同样按 default 定位
+	updated := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Data:       map[string]string{"k": "v2"},
+	}
+	if err := store.Update(gvk, updated); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	obj, err := store.Get(gvk, metav1.NamespaceDefault, name)
+	if err != nil || obj.(*corev1.ConfigMap).Data["k"] != "v2" {
+		t.Fatalf("get after update: %v, err=%v", obj, err)
+	}
+
+	if err := store.Delete(gvk, "", name); err != nil {
+		t.Fatalf("delete with empty namespace: %v", err)
+	}
+	if _, err := store.Get(gvk, metav1.NamespaceDefault, name); err == nil {
+		t.Fatal("object still exists after delete")
+	}
+
+	// 集群级资源不受影响
+	node := &corev1.Node{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Node"}, ObjectMeta: metav1.ObjectMeta{Name: "ns-test-node"}}
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+	t.Cleanup(func() { _ = store.Delete(nodeGVK, "", node.Name) })
+	if err := store.Create(nodeGVK, node); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+	if node.Namespace != "" {
+		t.Fatalf("cluster-scoped object got namespace %q", node.Namespace)
+	}
+}
+
+func TestMemoryStore_EmptyNamespaceDefaults(t *testing.T) {
+	testEmptyNamespaceDefaults(t, NewMemoryStore())
+}
+
+func TestMySQLStore_EmptyNamespaceDefaults(t *testing.T) {
+	testEmptyNamespaceDefaults(t, openTestMySQLStore(t))
+}
+
+func TestEtcdStore_EmptyNamespaceDefaults(t *testing.T) {
+	testEmptyNamespaceDefaults(t, openTestEtcdStore(t))
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Node 由多个写入者共同维护（controller manager 上报状态、network 的 mDNS 发现、discovery 的 consul 注册，
+// 以及通过 nodes/:name/labels、nodes/:name/annotations 写入的外部 agent）。
+// 各写入者只合并自己的 labels/annotations，受保护前缀下的键只能由其所属写入者修改。
+
+// NodeMetadataOwners 受保护的 Node label/annotation 键前缀，以及唯一允许通过 PatchNodeMetadata 修改它们的写入者。
+// 写入者由调用方认定：进程内的组件传入自己的名字；apiserver 开启认证时用认证身份的用户名，不采信客户端填写的 fieldManager
+var NodeMetadataOwners = map[string]string{
+	"k3.network/":    "k3-network",
+	"k3.discovery/":  "k3-discovery",
+	"k3.controller/": "k3-controller-manager",
+}
+
+// NodeMetadataField 是 PatchNodeMetadata 修改的字段
+type NodeMetadataField string
+
+const (
+	// NodeLabels metadata.labels
+	NodeLabels NodeMetadataField = "labels"
+	// NodeAnnotations metadata.annotations
+	NodeAnnotations NodeMetadataField = "annotations"
+)
+
+// ErrInvalidNodeMetadata patch 中的键或 label 值不合法
+var ErrInvalidNodeMetadata = errors.New("invalid node metadata")
+
+// MetadataOwnerError 写入者修改了属于其他写入者的键
+type MetadataOwnerError struct {
+	// Key 被修改的键
+	Key string `json:"key"`
+	// Owner 键所属的写入者
+	Owner string `json:"owner"`
+	// Manager 本次写入者
+	Manager string `json:"manager"`
+}
+
+func (e *MetadataOwnerError) Error() string {
+	return fmt.Sprintf("forbidden: %s is owned by %s, %s cannot modify it", e.Key, e.Owner, e.Manager)
+}
+
+// NodeMetadataOwner 返回 key 所属的写入者；不在受保护前缀下时返回空
+func NodeMetadataOwner(key string) string {
+	for prefix, owner := range NodeMetadataOwners {
+		if strings.HasPrefix(key, prefix) {
+			return owner
+		}
+	}
+	return ""
+}
+
+// PatchNodeMetadata 以 manager 的身份把 patch 合并到 node 的 labels 或 annotations：值为 nil 的键被删除，
+// 其余键被设置，patch 中没有的键保持不变。键（以及 label 的值）不合法时返回 ErrInvalidNodeMetadata，
+// 修改受保护前缀下其他写入者的键时返回 *MetadataOwnerError，两种情况下 node 都不被修改。
+// 返回 node 是否有变化；有变化时把 manager 记录为最近写入者
+func PatchNodeMetadata(node *corev1.Node, field NodeMetadataField, patch map[string]*string, manager string) (bool, error) {
+	var current map[string]string
+	switch field {
+	case NodeLabels:
+		current = node.Labels
+	case NodeAnnotations:
+		current = node.Annotations
+	default:
+		return false, fmt.Errorf("%w: unknown field %q", ErrInvalidNodeMetadata, field)
+	}
+
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return false, fmt.Errorf("%w: key %q: %s", ErrInvalidNodeMetadata, key, strings.Join(errs, "; "))
+		}
+		if value := patch[key]; value != nil && field == NodeLabels {
+			if errs := validation.IsValidLabelValue(*value); len(errs) > 0 {
+				return false, fmt.Errorf("%w: label %s=%q: %s", ErrInvalidNodeMetadata, key, *value, strings.Join(errs, "; "))
+			}
+		}
+		if owner := NodeMetadataOwner(key); owner != "" && owner != manager {
+			return false, &MetadataOwnerError{Key: key, Owner: owner, Manager: manager}
+		}
+	}
+
+	changed := false
+	for _, key := range keys {
+		old, exists := current[key]
+		value := patch[key]
+		switch {
+		case value == nil:
+			if exists {
+				delete(current, key)
+				changed = true
+			}
+		case !exists || old != *value:
+			if current == nil {
+				current = make(map[string]string)
+			}
+			current[key] = *value
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	if field == NodeLabels {
+		node.Labels = current
+	} else {
+		node.Annotations = current
+	}
+	RecordManager(node, manager)
+	return true, nil
+}
+
+// MergeNodeMetadata 把 existing 上 node 没有设置的 labels/annotations 复制到 node。
+// 上报整个 Node 的写入者用它保留其他写入者（外部 agent、network 等）设置的键，只覆盖自己设置的键。
+// 上报者不设置 spec 时保留 existing 的 spec（如封锁节点的 spec.unschedulable），心跳不会解除封锁
+func MergeNodeMetadata(existing, node *corev1.Node) {
+	node.Labels = mergeMissing(node.Labels, existing.Labels)
+	node.Annotations = mergeMissing(node.Annotations, existing.Annotations)
+	if reflect.DeepEqual(node.Spec, corev1.NodeSpec{}) {
+		node.Spec = *existing.Spec.DeepCopy()
+	}
+}
+
+// mergeMissing 把 from 中 into 没有的键复制到 into
+func mergeMissing(into, from map[string]string) map[string]string {
+	for k, v := range from {
+		if _, ok := into[k]; ok {
+			continue
+		}
+		if into == nil {
+			into = make(map[string]string, len(from))
+		}
+		into[k] = v
+	}
+	return into
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestPatchNodeMetadata(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1", Labels: map[string]string{"a": "1", "b": "2"}}}
+
+	changed, err := PatchNodeMetadata(node, NodeLabels, map[string]*string{"a": strPtr("x"), "b": nil, "c": strPtr("3")}, "agent")
+	if err != nil || !changed {
+		t.Fatalf("patch: changed=%v err=%v", changed, err)
+	}
+	want := map[string]string{"a": "x", "c": "3"}
+	if len(node.Labels) != len(want) || node.Labels["a"] != "x" || node.Labels["c"] != "3" {
+		t.Fatalf("labels = %v, want %v", node.Labels, want)
+	}
+	if LastManager(node) != "agent" {
+		t.Fatalf("manager not recorded: %q", LastManager(node))
+	}
+
+	// 没有变化时不记录写入者
+	changed, err = PatchNodeMetadata(node, NodeLabels, map[string]*string{"a": strPtr("x"), "missing": nil}, "other")
+	if err != nil || changed || LastManager(node) != "agent" {
+		t.Fatalf("no-op patch: changed=%v err=%v manager=%q", changed, err, LastManager(node))
+	}
+
+	// annotations 初始为 nil
+	if _, err := PatchNodeMetadata(node, NodeAnnotations, map[string]*string{"note": strPtr("free text ok")}, "agent"); err != nil {
+		t.Fatalf("annotations: %v", err)
+	}
+	if node.Annotations["note"] != "free text ok" {
+		t.Fatalf("annotations = %v", node.Annotations)
+	}
+}
+
+func TestPatchNodeMetadataRejects(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1", Labels: map[string]string{"a": "1"}}}
+
+	_, err := PatchNodeMetadata(node, NodeLabels, map[string]*string{"a": nil, "k3.network/managed": strPtr("true")}, "agent")
+	var owner *MetadataOwnerError
+	if !errors.As(err, &owner) || owner.Owner != "k3-network" {
+		t.Fatalf("expected ownership error, got %v", err)
+	}
+	if node.Labels["a"] != "1" {
+		t.Fatalf("rejected patch must not modify the node, got %v", node.Labels)
+	}
+	if _, err := PatchNodeMetadata(node, NodeLabels, map[string]*string{"k3.network/managed": strPtr("true")}, "k3-network"); err != nil {
+		t.Fatalf("owner should be allowed: %v", err)
+	}
+
+	for name, patch := range map[string]map[string]*string{
+		"invalid key":   {"bad key": strPtr("v")},
+		"invalid value": {"a": strPtr("not a label value")},
+	} {
+		if _, err := PatchNodeMetadata(node, NodeLabels, patch, "agent"); !errors.Is(err, ErrInvalidNodeMetadata) {
+			t.Fatalf("%s: expected ErrInvalidNodeMetadata, got %v", name, err)
+		}
+	}
+}
+
+func TestMergeNodeMetadata(t *testing.T) {
+	existing := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Labels:      map[string]string{"kubernetes.io/hostname": "old", "rack": "r1"},
+		Annotations: map[string]string{"k3.network/port": "7946"},
+	}, Spec: corev1.NodeSpec{Unschedulable: true}}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"kubernetes.io/hostname": "n1"}}}
+
+	MergeNodeMetadata(existing, node)
+	if node.Labels["kubernetes.io/hostname"] != "n1" || node.Labels["rack"] != "r1" {
+		t.Fatalf("labels = %v", node.Labels)
+	}
+	if node.Annotations["k3.network/port"] != "7946" {
+		t.Fatalf("annotations = %v", node.Annotations)
+	}
+	if !node.Spec.Unschedulable {
+		t.Fatalf("spec.unschedulable of the cordoned node was reset")
+	}
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SortObjects 按 namespace/name 排序（相同时按 creationTimestamp 从早到晚），
+// 所有存储后端的 List/ListBySelector 都按该顺序返回，分页与 Dashboard 的行顺序在多次调用间保持稳定
+func SortObjects(objects []runtime.Object) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objectLess(objects[i], objects[j])
+	})
+}
+
+// objectLess 比较两个对象的排序位置；没有 metadata 的对象排在最后
+func objectLess(a, b runtime.Object) bool {
+	ma, okA := a.(metav1.Object)
+	mb, okB := b.(metav1.Object)
+	if !okA || !okB {
+		return okA && !okB
+	}
+	if ma.GetNamespace() != mb.GetNamespace() {
+		return ma.GetNamespace() < mb.GetNamespace()
+	}
+	if ma.GetName() != mb.GetName() {
+		return ma.GetName() < mb.GetName()
+	}
+	ta, tb := ma.GetCreationTimestamp(), mb.GetCreationTimestamp()
+	return ta.Before(&tb)
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func objectKeys(objects []runtime.Object) []string {
+	keys := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		meta := obj.(metav1.Object)
+		keys = append(keys, meta.GetNamespace()+"/"+meta.GetName())
+	}
+	return keys
+}
+
+func TestMemoryStore_ListOrder(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	for _, key := range [][2]string{{"b", "web-2"}, {"a", "web-9"}, {"b", "api"}, {"a", "web-10"}, {"c", "db"}} {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: key[0],
+			Name:      key[1],
+			Labels:    map[string]string{"app": "x"},
+		}}
+		if err := store.Create(gvk, pod); err != nil {
+			t.Fatalf("create %v: %v", key, err)
+		}
+	}
+	want := []string{"a/web-10", "a/web-9", "b/api", "b/web-2", "c/db"}
+
+	// map 的遍历顺序每次不同，多次调用的结果都应一致
+	for i := 0; i < 20; i++ {
+		objects, err := store.List(gvk, "")
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if got := objectKeys(objects); !slices.Equal(got, want) {
+			t.Fatalf("List order = %v, want %v", got, want)
+		}
+		objects, err = store.ListBySelector(gvk, "", labels.SelectorFromSet(labels.Set{"app": "x"}))
+		if err != nil {
+			t.Fatalf("list by selector: %v", err)
+		}
+		if got := objectKeys(objects); !slices.Equal(got, want) {
+			t.Fatalf("ListBySelector order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSortObjects_CreationTimestampTiebreak(t *testing.T) {
+	now := time.Now()
+	newer := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "newer", CreationTimestamp: metav1.NewTime(now)}}
+	older := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "older", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}}
+	first := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "api", CreationTimestamp: metav1.NewTime(now)}}
+
+	objects := []runtime.Object{newer, first, older}
+	SortObjects(objects)
+	var uids []string
+	for _, obj := range objects {
+		uids = append(uids, obj.(*corev1.Pod).Name+":"+string(obj.(*corev1.Pod).UID))
+	}
+	want := []string{"api:", "web:older", "web:newer"}
+	if !slices.Equal(uids, want) {
+		t.Fatalf("SortObjects = %v, want %v", uids, want)
+	}
+}
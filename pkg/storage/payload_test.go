@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/go-sql-driver/mysql"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	payloadConfigMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	payloadSecretGVK    = schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+)
+
+// binaryPayload 返回 n 字节覆盖所有字节值的数据（包含 NUL 与非法 UTF-8）
+func binaryPayload(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+// testLargeAndBinaryPayloads 检查接近 1MiB 的 data、二进制 binaryData 与 Secret 的 data/stringData 能原样读回（创建、更新与列表）
+func testLargeAndBinaryPayloads(t *testing.T, store Store, namespace string) {
+	t.Helper()
+
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "payload", Namespace: namespace},
+		Data:       map[string]string{"large.txt": strings.Repeat("k3-配置", 100*1024), "empty": ""},
+		BinaryData: map[string][]byte{"blob.bin": binaryPayload(256 * 1024), "nul": {0}},
+	}
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "payload", Namespace: namespace},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"key.der": binaryPayload(512 * 1024)},
+		StringData: map[string]string{"username": "admin"},
+	}
+	t.Cleanup(func() {
+		_ = store.Delete(payloadConfigMapGVK, namespace, cm.Name)
+		_ = store.Delete(payloadSecretGVK, namespace, secret.Name)
+	})
+
+	if err := store.Create(payloadConfigMapGVK, cm.DeepCopy()); err != nil {
+		t.Fatalf("create configmap: %v", err)
+	}
+	if err := store.Create(payloadSecretGVK, secret.DeepCopy()); err != nil {
+		t.Fatalf("create secret: %v", err)
+	}
+	assertConfigMap(t, store, namespace, cm)
+	assertSecret(t, store, namespace, secret)
+
+	// 更新：binaryData 变化、data 清空
+	updated := cm.DeepCopy()
+	updated.Data = nil
+	updated.BinaryData["blob.bin"] = bytes.Repeat([]byte{0xff, 0x00}, 1024)
+	if err := store.Update(payloadConfigMapGVK, updated.DeepCopy()); err != nil {
+		t.Fatalf("update configmap: %v", err)
+	}
+	assertConfigMap(t, store, namespace, updated)
+
+	objects, err := store.List(payloadConfigMapGVK, namespace)
+	if err != nil {
+		t.Fatalf("list configmaps: %v", err)
+	}
+	found := false
+	for _, obj := range objects {
+		if listed, ok := obj.(*corev1.ConfigMap); ok && listed.Name == cm.Name {
+			found = true
+			if !reflect.DeepEqual(listed.BinaryData, updated.BinaryData) {
+				t.Errorf("listed configmap binaryData differs")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("configmap %s/%s not listed", namespace, cm.Name)
+	}
+}
+
+func assertConfigMap(t *testing.T, store Store, namespace string, want *corev1.ConfigMap) {
+	t.Helper()
+	obj, err := store.Get(payloadConfigMapGVK, namespace, want.Name)
+	if err != nil {
+		t.Fatalf("get configmap: %v", err)
+	}
+	got, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		t.Fatalf("get configmap returned %T", obj)
+	}
+	if len(got.Data) != len(want.Data) || len(want.Data) > 0 && !reflect.DeepEqual(got.Data, want.Data) {
+		t.Errorf("configmap data differs: got %d keys, want %d", len(got.Data), len(want.Data))
+	}
+	if !reflect.DeepEqual(got.BinaryData, want.BinaryData) {
+		t.Errorf("configmap binaryData differs: got %d keys, want %d", len(got.BinaryData), len(want.BinaryData))
+	}
+}
+
+func assertSecret(t *testing.T, store Store, namespace string, want *corev1.Secret) {
+	t.Helper()
+	obj, err := store.Get(payloadSecretGVK, namespace, want.Name)
+	if err != nil {
+		t.Fatalf("get secret: %v", err)
+	}
+	got, ok := obj.(*corev1.Secret)
+	if !ok {
+		t.Fatalf("get secret returned %T", obj)
+	}
+	if got.Type != want.Type {
+		t.Errorf("secret type = %q, want %q", got.Type, want.Type)
+	}
+	if !reflect.DeepEqual(got.Data, want.Data) || !reflect.DeepEqual(got.StringData, want.StringData) {
+		t.Errorf("secret data differs")
+	}
+}
+
+func TestMemoryStore_LargeAndBinaryPayloads(t *testing.T) {
+	testLargeAndBinaryPayloads(t, NewMemoryStore(), "default")
+}
+
+// openTestMySQLStore 连接 K3_TEST_MYSQL_DSN（如 root:secret@tcp(127.0.0.1:3306)/k3_test）指定的 MySQL，未设置时跳过测试
+func openTestMySQLStore(t testing.TB) *MySQLStore {
+	t.Helper()
+	dsn := os.Getenv("K3_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("未设置 K3_TEST_MYSQL_DSN，跳过 MySQL 测试")
+	}
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("parse K3_TEST_MYSQL_DSN: %v", err)
+	}
+	host, port, ok := strings.Cut(parsed.Addr, ":")
+	cfg := config.MySQLConfig{Host: host, Port: 3306, User: parsed.User, Password: parsed.Passwd, Database: parsed.DBName, MaxOpenConns: 4, MaxIdleConns: 2}
+	if ok {
+		if _, err := fmt.Sscanf(port, "%d", &cfg.Port); err != nil {
+			t.Fatalf("invalid port in K3_TEST_MYSQL_DSN: %v", err)
+		}
+	}
+	store, err := NewMySQLStore(cfg)
+	if err != nil {
+		t.Fatalf("connect mysql: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// openTestEtcdStore 连接 K3_TEST_ETCD_ENDPOINTS（逗号分隔，如 127.0.0.1:2379）指定的 etcd，使用独立的前缀；未设置时跳过测试
+func openTestEtcdStore(t testing.TB) *EtcdStore {
+	t.Helper()
+	endpoints := os.Getenv("K3_TEST_ETCD_ENDPOINTS")
+	if endpoints == "" {
+		t.Skip("未设置 K3_TEST_ETCD_ENDPOINTS，跳过 etcd 测试")
+	}
+	store, err := NewEtcdStore(config.EtcdConfig{
+		Endpoints: strings.Split(endpoints, ","),
+		Prefix:    fmt.Sprintf("/k3-test-%d", time.Now().UnixNano()),
+	})
+	if err != nil {
+		t.Fatalf("connect etcd: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestMySQLStore_LargeAndBinaryPayloads(t *testing.T) {
+	testLargeAndBinaryPayloads(t, openTestMySQLStore(t), fmt.Sprintf("payload-%d", time.Now().UnixNano()))
+}
+
+func TestEtcdStore_LargeAndBinaryPayloads(t *testing.T) {
+	testLargeAndBinaryPayloads(t, openTestEtcdStore(t), "default")
+}
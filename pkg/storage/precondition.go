@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// resourceVersion 前置条件：与 kube-apiserver 的 Update 一样，写入的对象带有 metadata.resourceVersion 时，
+// 只有存储中的对象仍是这个版本才写入。比较与写入在 Store 内原子地完成（内存存储持有锁、etcd 用事务比较、
+// MySQL 按 resource_version 条件删除旧行），两个基于同一版本的并发写入只有一个成功。
+
+// ResourceVersionConflict 表示对象在调用方读取之后已被修改，写入基于的 resourceVersion 不再是最新版本
+type ResourceVersionConflict struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// ResourceVersion 写入基于的版本
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+func (c *ResourceVersionConflict) Error() string {
+	return fmt.Sprintf("operation cannot be fulfilled on %s %s: the object has been modified (resourceVersion %s is stale); please apply your changes to the latest version and try again",
+		c.Kind, resourcePathName(c.Namespace, c.Name), c.ResourceVersion)
+}
+
+// IsResourceVersionConflict 判断 err 是否为 resourceVersion 前置条件不满足
+func IsResourceVersionConflict(err error) bool {
+	var conflict *ResourceVersionConflict
+	return errors.As(err, &conflict)
+}
+
+// ConditionalUpdater 由能原子地比较 resourceVersion 并写入的 Store 实现
+type ConditionalUpdater interface {
+	// UpdateIf 仅当存储中对象的 resourceVersion 为 resourceVersion 时更新（为空时等同于 Update），
+	// 否则不写入并返回 *ResourceVersionConflict
+	UpdateIf(gvk schema.GroupVersionKind, obj runtime.Object, resourceVersion string) error
+}
+
+// UpdateIf 以 resourceVersion 为前置条件更新对象；store 没有实现 ConditionalUpdater 时先读取比较再更新（不是原子的）
+func UpdateIf(store Store, gvk schema.GroupVersionKind, obj runtime.Object, resourceVersion string) error {
+	if u, ok := store.(ConditionalUpdater); ok {
+		return u.UpdateIf(gvk, obj, resourceVersion)
+	}
+	if resourceVersion != "" {
+		meta, err := getObjectMeta(obj)
+		if err != nil {
+			return err
+		}
+		current, err := store.Get(gvk, meta.GetNamespace(), meta.GetName())
+		if err != nil {
+			return err
+		}
+		if err := checkResourceVersion(gvk, current, resourceVersion); err != nil {
+			return err
+		}
+	}
+	return store.Update(gvk, obj)
+}
+
+// checkResourceVersion 检查存储中的 current 是否仍是 resourceVersion（为空时不检查）
+func checkResourceVersion(gvk schema.GroupVersionKind, current runtime.Object, resourceVersion string) error {
+	if resourceVersion == "" {
+		return nil
+	}
+	meta, err := getObjectMeta(current)
+	if err != nil {
+		return err
+	}
+	if meta.GetResourceVersion() == resourceVersion {
+		return nil
+	}
+	return &ResourceVersionConflict{Kind: gvk.Kind, Namespace: meta.GetNamespace(), Name: meta.GetName(), ResourceVersion: resourceVersion}
+}
+
+func resourcePathName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var configMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+// plainStore 隐藏 MemoryStore 的 UpdateIf，走 UpdateIf 的回退路径
+type plainStore struct{ Store }
+
+func TestUpdateIf(t *testing.T) {
+	for name, wrap := range map[string]func(*MemoryStore) Store{
+		"conditional updater": func(s *MemoryStore) Store { return s },
+		"fallback":            func(s *MemoryStore) Store { return plainStore{s} },
+	} {
+		memory := NewMemoryStore()
+		store := wrap(memory)
+		if err := store.Create(configMapGVK, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: "default"}}); err != nil {
+			t.Fatal(err)
+		}
+		current, _ := store.Get(configMapGVK, "default", "cfg")
+		read := current.(*corev1.ConfigMap).ResourceVersion
+
+		first := current.(*corev1.ConfigMap).DeepCopy()
+		first.Data = map[string]string{"k": "first"}
+		if err := UpdateIf(store, configMapGVK, first, read); err != nil {
+			t.Fatalf("%s: update from the latest version: %v", name, err)
+		}
+
+		// 基于同一版本的第二次写入被拒绝，对象保持第一次写入的内容
+		second := current.(*corev1.ConfigMap).DeepCopy()
+		second.Data = map[string]string{"k": "second"}
+		err := UpdateIf(store, configMapGVK, second, read)
+		if !IsResourceVersionConflict(err) {
+			t.Fatalf("%s: stale update: %v", name, err)
+		}
+		if got, _ := store.Get(configMapGVK, "default", "cfg"); got.(*corev1.ConfigMap).Data["k"] != "first" {
+			t.Errorf("%s: stale update was written: %v", name, got.(*corev1.ConfigMap).Data)
+		}
+
+		// 没有前置条件时照常写入
+		if err := UpdateIf(store, configMapGVK, second, ""); err != nil {
+			t.Errorf("%s: unconditional update: %v", name, err)
+		}
+	}
+}
+
+func TestMemoryStore_UpdateIfConcurrent(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.Create(configMapGVK, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: "default"}}); err != nil {
+		t.Fatal(err)
+	}
+	current, _ := store.Get(configMapGVK, "default", "cfg")
+
+	// 多个写入者同时基于同一版本写入，只有一个成功
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, conflicts := 0, 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			obj := current.(*corev1.ConfigMap).DeepCopy()
+			obj.Data = map[string]string{"writer": "x"}
+			_, err := UpdateAsIf(store, configMapGVK, obj, "dashboard", false)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case IsResourceVersionConflict(err):
+				conflicts++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 || conflicts != 15 {
+		t.Errorf("succeeded %d, conflicts %d; want 1 and 15", succeeded, conflicts)
+	}
+}
+
+func TestUpdateAsIf_ReportsResourceVersionConflictFirst(t *testing.T) {
+	store := NewMemoryStore()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: "default", Labels: map[string]string{"app": "web"}}}
+	RecordManager(cm, "k3-gitops")
+	if err := store.Create(configMapGVK, cm); err != nil {
+		t.Fatal(err)
+	}
+	current, _ := store.Get(configMapGVK, "default", "cfg")
+	stale := current.(*corev1.ConfigMap).DeepCopy()
+	if err := store.Update(configMapGVK, current); err != nil {
+		t.Fatal(err)
+	}
+
+	// 过期版本同时会删除其他写入者的 label：返回的是 resourceVersion 冲突，而不是写入者冲突
+	stale.Labels = nil
+	conflict, err := UpdateAsIf(store, configMapGVK, stale, "dashboard", false)
+	if conflict != nil || !IsResourceVersionConflict(err) {
+		t.Errorf("UpdateAsIf = %v, %v; want a resourceVersion conflict", conflict, err)
+	}
+
+	// UpdateAs 不把 resourceVersion 当作前置条件
+	stale.Labels = map[string]string{"app": "web"}
+	if _, err := UpdateAs(store, configMapGVK, stale, "dashboard", false); err != nil {
+		t.Errorf("UpdateAs with a stale resourceVersion: %v", err)
+	}
+}
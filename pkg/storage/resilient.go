@@ -0,0 +1,468 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	"github.com/go-sql-driver/mysql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ErrBackendUnavailable 表示存储后端不可用：熔断打开期间的请求直接返回该错误，不再访问后端
+var ErrBackendUnavailable = errors.New("storage backend unavailable")
+
+// CircuitState 熔断器状态
+type CircuitState string
+
+const (
+	// CircuitClosed 后端正常，请求直接转发
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen 后端不可用，请求直接失败，后台按退避间隔重连
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen 重连成功，放行请求试探：成功则关闭熔断，再次遇到后端错误则重新打开
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+const (
+	defaultFailureThreshold = 3
+	defaultRetryInterval    = time.Second
+	defaultMaxRetryInterval = 30 * time.Second
+	// reconnectTimeout 单次重连的超时
+	reconnectTimeout = 5 * time.Second
+)
+
+// BackendHealth 存储后端的连接状态（/api/readyz 返回）
+type BackendHealth struct {
+	// Backend 后端类型（mysql/etcd/memory）
+	Backend string `json:"backend"`
+	// Ready 为 false 表示熔断打开，请求会直接失败
+	Ready bool `json:"ready"`
+	// State 熔断器状态
+	State CircuitState `json:"state"`
+	// ConsecutiveFailures 连续的后端错误次数
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// LastError 最近一次后端错误
+	LastError string `json:"lastError,omitempty"`
+	// LastFailure / LastSuccess 最近一次后端错误 / 成功访问后端的时间
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// NextRetry 熔断打开时下一次重连的时间
+	NextRetry *time.Time `json:"nextRetry,omitempty"`
+}
+
+// HealthReporter 由能报告后端连接状态的 Store 实现
+type HealthReporter interface {
+	Health() BackendHealth
+}
+
+// IsBackendError 判断 err 是否表示后端不可达（连接失败、连接中断、超时、etcd 不可用），
+// 而不是资源不存在、已存在等业务错误。只有这类错误计入熔断
+func IsBackendError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrBackendUnavailable) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// ResilientStore 为外部存储（MySQL/etcd）增加连接状态跟踪与熔断：
+// 连续 FailureThreshold 次后端错误后熔断，熔断期间请求立即返回 ErrBackendUnavailable（控制器与 apiserver 不再逐个等待超时），
+// 后台按指数退避调用后端的 Reconnect，成功后进入半开状态试探。Watch 只注册本地通道，不受熔断影响。
+type ResilientStore struct {
+	backend Store
+	name    string
+
+	threshold        int
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+
+	mu          sync.Mutex
+	state       CircuitState
+	failures    int
+	lastErr     error
+	lastFailure time.Time
+	lastSuccess time.Time
+	nextRetry   time.Time
+	onChange    func(from, to CircuitState, err error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewResilientStore 用 cfg 中的熔断配置包装 backend；name 为后端类型（mysql/etcd），用于状态展示
+func NewResilientStore(backend Store, name string, cfg config.CircuitBreakerConfig) (*ResilientStore, error) {
+	threshold := cfg.FailureThreshold
+	if threshold == 0 {
+		threshold = defaultFailureThreshold
+	}
+	retryInterval, err := parseDurationOr(cfg.RetryInterval, defaultRetryInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid circuit_breaker.retry_interval: %w", err)
+	}
+	maxRetryInterval, err := parseDurationOr(cfg.MaxRetryInterval, defaultMaxRetryInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid circuit_breaker.max_retry_interval: %w", err)
+	}
+	if maxRetryInterval < retryInterval {
+		maxRetryInterval = retryInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ResilientStore{
+		backend:          backend,
+		name:             name,
+		threshold:        threshold,
+		retryInterval:    retryInterval,
+		maxRetryInterval: maxRetryInterval,
+		state:            CircuitClosed,
+		ctx:              ctx,
+		cancel:           cancel,
+	}, nil
+}
+
+// parseDurationOr 解析可选的时长配置，为空时返回 def
+func parseDurationOr(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return def, nil
+	}
+	return d, nil
+}
+
+// OnStateChange 注册熔断状态变化回调（用于日志），err 为触发变化的后端错误
+func (s *ResilientStore) OnStateChange(fn func(from, to CircuitState, err error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = fn
+}
+
+// Health 返回后端连接状态
+func (s *ResilientStore) Health() BackendHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := BackendHealth{
+		Backend:             s.name,
+		Ready:               s.state != CircuitOpen,
+		State:               s.state,
+		ConsecutiveFailures: s.failures,
+	}
+	if s.lastErr != nil {
+		h.LastError = s.lastErr.Error()
+	}
+	if !s.lastFailure.IsZero() {
+		t := s.lastFailure
+		h.LastFailure = &t
+	}
+	if !s.lastSuccess.IsZero() {
+		t := s.lastSuccess
+		h.LastSuccess = &t
+	}
+	if s.state == CircuitOpen {
+		t := s.nextRetry
+		h.NextRetry = &t
+	}
+	return h
+}
+
+// allow 判断请求能否访问后端
+func (s *ResilientStore) allow() error {
+	if s.threshold < 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == CircuitOpen {
+		return fmt.Errorf("%w: %s (%v)", ErrBackendUnavailable, s.name, s.lastErr)
+	}
+	return nil
+}
+
+// record 记录一次后端访问的结果并推进熔断状态
+func (s *ResilientStore) record(err error) {
+	now := time.Now()
+	s.mu.Lock()
+	from := s.state
+	if !IsBackendError(err) {
+		// 业务错误（资源不存在等）说明后端可达
+		s.failures = 0
+		s.lastSuccess = now
+		s.state = CircuitClosed
+	} else {
+		s.failures++
+		s.lastErr = err
+		s.lastFailure = now
+		if s.threshold >= 0 && (s.state == CircuitHalfOpen || s.failures >= s.threshold) && s.state != CircuitOpen {
+			s.state = CircuitOpen
+			s.nextRetry = now.Add(s.retryInterval)
+			go s.reconnectLoop()
+		}
+	}
+	to, onChange := s.state, s.onChange
+	s.mu.Unlock()
+
+	if from != to && onChange != nil {
+		onChange(from, to, err)
+	}
+}
+
+// reconnectLoop 熔断打开后按指数退避重连，成功后进入半开状态；Close 或状态不再是 open 时退出
+func (s *ResilientStore) reconnectLoop() {
+	interval := s.retryInterval
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		s.mu.Lock()
+		open := s.state == CircuitOpen
+		s.mu.Unlock()
+		if !open {
+			return
+		}
+
+		err := s.reconnect()
+		if err == nil {
+			s.transition(CircuitHalfOpen, nil)
+			return
+		}
+
+		interval = min(interval*2, s.maxRetryInterval)
+		s.mu.Lock()
+		s.lastErr = err
+		s.lastFailure = time.Now()
+		s.nextRetry = time.Now().Add(interval)
+		s.mu.Unlock()
+	}
+}
+
+// reconnect 调用后端的 Reconnect；后端没有实现 Reconnector 时直接进入半开状态，由下一个请求试探
+func (s *ResilientStore) reconnect() error {
+	r, ok := s.backend.(Reconnector)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, reconnectTimeout)
+	defer cancel()
+	return r.Reconnect(ctx)
+}
+
+// transition 切换熔断状态并通知回调
+func (s *ResilientStore) transition(to CircuitState, err error) {
+	s.mu.Lock()
+	from := s.state
+	s.state = to
+	if to == CircuitClosed {
+		s.failures = 0
+		s.lastSuccess = time.Now()
+	}
+	onChange := s.onChange
+	s.mu.Unlock()
+	if from != to && onChange != nil {
+		onChange(from, to, err)
+	}
+}
+
+// Reconnect 立即重连后端（例如自动拉起的数据库容器重启后由 bootstrap 调用），成功后关闭熔断
+func (s *ResilientStore) Reconnect(ctx context.Context) error {
+	if r, ok := s.backend.(Reconnector); ok {
+		if err := r.Reconnect(ctx); err != nil {
+			return err
+		}
+	}
+	s.transition(CircuitClosed, nil)
+	return nil
+}
+
+// SchemaVersion 读取后端记录的 schema 版本（见 SchemaMigrator）
+func (s *ResilientStore) SchemaVersion(ctx context.Context) (int, error) {
+	m, ok := s.backend.(SchemaMigrator)
+	if !ok {
+		return SchemaVersion, nil
+	}
+	if err := s.allow(); err != nil {
+		return 0, err
+	}
+	v, err := m.SchemaVersion(ctx)
+	s.record(err)
+	return v, err
+}
+
+// Migrate 在后端执行待执行的 schema 迁移（见 SchemaMigrator）
+func (s *ResilientStore) Migrate(ctx context.Context) ([]Migration, error) {
+	m, ok := s.backend.(SchemaMigrator)
+	if !ok {
+		return nil, nil
+	}
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	applied, err := m.Migrate(ctx)
+	s.record(err)
+	return applied, err
+}
+
+// Close 停止后台重连并关闭后端连接
+func (s *ResilientStore) Close() error {
+	s.cancel()
+	if closer, ok := s.backend.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Get 获取指定资源
+func (s *ResilientStore) Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	obj, err := s.backend.Get(gvk, namespace, name)
+	s.record(err)
+	return obj, err
+}
+
+// List 列出所有资源
+func (s *ResilientStore) List(gvk schema.GroupVersionKind, namespace string) ([]runtime.Object, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	objects, err := s.backend.List(gvk, namespace)
+	s.record(err)
+	return objects, err
+}
+
+// ListBySelector 按标签选择器列出资源
+func (s *ResilientStore) ListBySelector(gvk schema.GroupVersionKind, namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	objects, err := s.backend.ListBySelector(gvk, namespace, selector)
+	s.record(err)
+	return objects, err
+}
+
+// Create 创建资源
+func (s *ResilientStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	if err := s.allow(); err != nil {
+		return err
+	}
+	err := s.backend.Create(gvk, obj)
+	s.record(err)
+	return err
+}
+
+// Update 更新资源
+func (s *ResilientStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	if err := s.allow(); err != nil {
+		return err
+	}
+	err := s.backend.Update(gvk, obj)
+	s.record(err)
+	return err
+}
+
+// UpdateIf 以 resourceVersion 为前置条件更新资源（见 ConditionalUpdater）
+func (s *ResilientStore) UpdateIf(gvk schema.GroupVersionKind, obj runtime.Object, resourceVersion string) error {
+	if err := s.allow(); err != nil {
+		return err
+	}
+	err := UpdateIf(s.backend, gvk, obj, resourceVersion)
+	s.record(err)
+	return err
+}
+
+// Delete 删除资源
+func (s *ResilientStore) Delete(gvk schema.GroupVersionKind, namespace, name string) error {
+	if err := s.allow(); err != nil {
+		return err
+	}
+	err := s.backend.Delete(gvk, namespace, name)
+	s.record(err)
+	return err
+}
+
+// DeleteAs 以 manager 的身份删除资源（见 HistoryStore）
+func (s *ResilientStore) DeleteAs(gvk schema.GroupVersionKind, namespace, name, manager string) error {
+	if err := s.allow(); err != nil {
+		return err
+	}
+	err := DeleteAs(s.backend, gvk, namespace, name, manager)
+	s.record(err)
+	return err
+}
+
+// History 返回对象保留的版本（见 HistoryStore）
+func (s *ResilientStore) History(gvk schema.GroupVersionKind, namespace, name string) ([]Revision, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	revisions, err := History(s.backend, gvk, namespace, name)
+	s.record(err)
+	return revisions, err
+}
+
+// DeleteCollection 删除满足 match 的资源
+func (s *ResilientStore) DeleteCollection(gvk schema.GroupVersionKind, namespace string, match func(runtime.Object) bool) ([]runtime.Object, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	deleted, err := s.backend.DeleteCollection(gvk, namespace, match)
+	s.record(err)
+	return deleted, err
+}
+
+// Watch 监听资源变更（只注册本地通道，不访问后端，不受熔断影响）
+func (s *ResilientStore) Watch(gvk schema.GroupVersionKind, namespace string, resourceVersion string) (<-chan ResourceEvent, error) {
+	return s.backend.Watch(gvk, namespace, resourceVersion)
+}
+
+// StopWatcher 注销后端的 watcher（后端实现了 WatchStopper 时）
+func (s *ResilientStore) StopWatcher(gvk schema.GroupVersionKind, namespace string, ch <-chan ResourceEvent) {
+	if stopper, ok := s.backend.(WatchStopper); ok {
+		stopper.StopWatcher(gvk, namespace, ch)
+	}
+}
+
+// WatchStats 返回后端的 watcher 统计（后端实现了 WatchStatsProvider 时）
+func (s *ResilientStore) WatchStats() []WatchStats {
+	if provider, ok := s.backend.(WatchStatsProvider); ok {
+		return provider.WatchStats()
+	}
+	return nil
+}
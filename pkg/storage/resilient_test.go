@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Z-Nightmare/kuberneteskuberneteskubernetes/internal/core/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// flakyStore 在 down 时对所有读写返回连接错误，用于模拟后端中断
+type flakyStore struct {
+	*MemoryStore
+
+	mu         sync.Mutex
+	down       bool
+	calls      int
+	reconnects int
+}
+
+func (f *flakyStore) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *flakyStore) check() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.down {
+		return fmt.Errorf("failed to get from etcd: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+	}
+	return nil
+}
+
+func (f *flakyStore) Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	return f.MemoryStore.Get(gvk, namespace, name)
+}
+
+func (f *flakyStore) Reconnect(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reconnects++
+	if f.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestIsBackendError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("resource not found: default/foo"), false},
+		{fmt.Errorf("resource already exists: default/foo"), false},
+		{fmt.Errorf("failed to get from etcd: %w", context.DeadlineExceeded), true},
+		{fmt.Errorf("wrap: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), true},
+		{fmt.Errorf("%w: mysql", ErrBackendUnavailable), true},
+	}
+	for _, c := range cases {
+		if got := IsBackendError(c.err); got != c.want {
+			t.Errorf("IsBackendError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestResilientStore_CircuitBreaker(t *testing.T) {
+	backend := &flakyStore{MemoryStore: NewMemoryStore()}
+	store, err := NewResilientStore(backend, "etcd", config.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		RetryInterval:    "20ms",
+		MaxRetryInterval: "40ms",
+	})
+	if err != nil {
+		t.Fatalf("NewResilientStore: %v", err)
+	}
+	defer store.Close()
+
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "default"},
+	}
+	if err := store.Create(gvk, pod); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 业务错误不计入熔断
+	for i := 0; i < 3; i++ {
+		if _, err := store.Get(gvk, "default", "missing"); err == nil || IsBackendError(err) {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+	}
+	if h := store.Health(); h.State != CircuitClosed || h.ConsecutiveFailures != 0 {
+		t.Fatalf("unexpected health after not found: %+v", h)
+	}
+
+	// 连续后端错误达到阈值后熔断，请求不再到达后端
+	backend.setDown(true)
+	for i := 0; i < 2; i++ {
+		if _, err := store.Get(gvk, "default", "p"); !IsBackendError(err) {
+			t.Fatalf("expected backend error, got %v", err)
+		}
+	}
+	if h := store.Health(); h.State != CircuitOpen || h.Ready || h.NextRetry == nil {
+		t.Fatalf("expected open circuit, got %+v", h)
+	}
+	backend.mu.Lock()
+	calls := backend.calls
+	backend.mu.Unlock()
+	if _, err := store.Get(gvk, "default", "p"); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("expected ErrBackendUnavailable, got %v", err)
+	}
+	backend.mu.Lock()
+	if backend.calls != calls {
+		t.Fatalf("open circuit should not call backend")
+	}
+	backend.mu.Unlock()
+
+	// 后端恢复后后台重连进入半开，下一个成功请求关闭熔断
+	backend.setDown(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for store.Health().State == CircuitOpen {
+		if time.Now().After(deadline) {
+			t.Fatalf("circuit did not leave open state: %+v", store.Health())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if h := store.Health(); h.State != CircuitHalfOpen || !h.Ready {
+		t.Fatalf("expected half-open, got %+v", h)
+	}
+	if _, err := store.Get(gvk, "default", "p"); err != nil {
+		t.Fatalf("Get after recovery: %v", err)
+	}
+	if h := store.Health(); h.State != CircuitClosed {
+		t.Fatalf("expected closed circuit, got %+v", h)
+	}
+}
+
+func TestResilientStore_HalfOpenFailureReopens(t *testing.T) {
+	backend := &flakyStore{MemoryStore: NewMemoryStore()}
+	store, err := NewResilientStore(backend, "mysql", config.CircuitBreakerConfig{FailureThreshold: 1, RetryInterval: "10ms"})
+	if err != nil {
+		t.Fatalf("NewResilientStore: %v", err)
+	}
+	defer store.Close()
+
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	backend.setDown(true)
+	_, _ = store.Get(gvk, "default", "p")
+	if store.Health().State != CircuitOpen {
+		t.Fatalf("expected open circuit")
+	}
+
+	// 手动重连（bootstrap 在数据库容器重启后调用）直接关闭熔断
+	backend.setDown(false)
+	if err := store.Reconnect(context.Background()); err != nil {
+		t.Fatalf("Reconnect: %v", err)
+	}
+	if store.Health().State != CircuitClosed {
+		t.Fatalf("expected closed circuit after Reconnect")
+	}
+
+	// 半开状态下再次失败立即重新熔断
+	backend.setDown(true)
+	_, _ = store.Get(gvk, "default", "p")
+	store.transition(CircuitHalfOpen, nil)
+	_, _ = store.Get(gvk, "default", "p")
+	if store.Health().State != CircuitOpen {
+		t.Fatalf("expected reopen from half-open, got %+v", store.Health())
+	}
+}
+
+func TestResilientStore_StopWatcher(t *testing.T) {
+	backend := &flakyStore{MemoryStore: NewMemoryStore()}
+	store, err := NewResilientStore(backend, "etcd", config.CircuitBreakerConfig{})
+	if err != nil {
+		t.Fatalf("NewResilientStore: %v", err)
+	}
+	defer store.Close()
+
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	ch, err := store.Watch(gvk, "default", "")
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	store.StopWatcher(gvk, "default", ch)
+	if _, ok := <-ch; ok {
+		t.Fatalf("watch channel not closed after StopWatcher")
+	}
+
+	// 注销后的通道不再收到事件，也不会因向已关闭的通道发送而 panic
+	cm := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}, ObjectMeta: metav1.ObjectMeta{Name: "after-stop", Namespace: "default"}}
+	if err := store.Create(gvk, cm); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion 是当前代码使用的数据 schema 版本（MySQL 表结构、etcd 键布局）。
+// 新增列/表或改变数据布局时递增，并在 Migrations 以及各后端的迁移表中加入对应步骤。
+const SchemaVersion = 7
+
+// MinSchemaVersion 是当前代码能直接迁移的最旧 schema 版本；更旧的数据需要先用中间版本升级
+const MinSchemaVersion = 1
+
+// Migration 是一次 schema 升级
+type Migration struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+}
+
+// Migrations 按版本列出所有 schema 升级（版本 1 为引入版本记录之前的布局）
+var Migrations = []Migration{
+	{Version: 2, Description: "资源表增加 generation 列"},
+	{Version: 3, Description: "按 namespace 级/集群级区分资源键：etcd 资源移到 /k3/resources/，集群级资源清空 namespace"},
+	{Version: 4, Description: "标签索引：MySQL 资源表增加常用标签的生成列与索引，etcd 为已有资源写入标签索引键"},
+	{Version: 5, Description: "MySQL 资源表改为只硬删除：清除旧版本软删除留下的行并去掉 deleted_at 列"},
+	{Version: 6, Description: "MySQL 按列保存 ConfigMap、Secret、StatefulSet、DaemonSet：补齐列并改写按通用资源写入的行"},
+	{Version: 7, Description: "namespace 为空的 namespace 级资源移到 default"},
+}
+
+var (
+	// ErrSchemaTooNew 后端数据由更新版本写入，当前代码读写会破坏数据（禁止降级）
+	ErrSchemaTooNew = errors.New("storage schema is newer than this binary supports")
+	// ErrSchemaTooOld 后端数据过旧，当前代码无法直接迁移
+	ErrSchemaTooOld = errors.New("storage schema is too old to migrate directly")
+)
+
+// SchemaMigrator 由持久化后端（MySQL/etcd）实现：记录数据的 schema 版本并执行升级迁移
+type SchemaMigrator interface {
+	// SchemaVersion 返回后端记录的 schema 版本：没有任何数据时为 0，引入版本记录之前写入的数据为 1
+	SchemaVersion(ctx context.Context) (int, error)
+	// Migrate 执行记录版本之后的迁移，升级到 SchemaVersion 并记录版本，返回执行过的迁移
+	Migrate(ctx context.Context) ([]Migration, error)
+}
+
+// CheckSchemaVersion 检查后端记录的 schema 版本能否由支持 [minVersion, maxVersion] 的代码使用（迁移后）：
+// 比 maxVersion 新时返回 ErrSchemaTooNew，比 minVersion 旧时返回 ErrSchemaTooOld；stored 为 0（没有数据）时总是可用
+func CheckSchemaVersion(stored, minVersion, maxVersion int) error {
+	if stored > maxVersion {
+		return fmt.Errorf("%w: stored v%d, supported v%d", ErrSchemaTooNew, stored, maxVersion)
+	}
+	if stored > 0 && stored < minVersion {
+		return fmt.Errorf("%w: stored v%d, minimum v%d", ErrSchemaTooOld, stored, minVersion)
+	}
+	return nil
+}
+
+// PendingMigrations 返回从 stored 升级到 SchemaVersion 需要执行的迁移；stored 为 0（没有数据）时无需迁移
+func PendingMigrations(stored int) []Migration {
+	if stored == 0 {
+		return nil
+	}
+	var pending []Migration
+	for _, m := range Migrations {
+		if m.Version > stored && m.Version <= SchemaVersion {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// EnsureSchema 检查 s 的 schema 版本并执行待执行的迁移；s 不是持久化后端（memory）时什么也不做。
+// 返回迁移前的版本与执行过的迁移
+func EnsureSchema(ctx context.Context, s Store) (int, []Migration, error) {
+	m, ok := s.(SchemaMigrator)
+	if !ok {
+		return SchemaVersion, nil, nil
+	}
+	stored, err := m.SchemaVersion(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := CheckSchemaVersion(stored, MinSchemaVersion, SchemaVersion); err != nil {
+		return stored, nil, err
+	}
+	applied, err := m.Migrate(ctx)
+	return stored, applied, err
+}
+
+// runMigrations 依次执行 stored 之后的迁移（steps 中没有的版本表示该后端无需处理），每步成功后记录版本
+func runMigrations(ctx context.Context, stored int, steps map[int]func(context.Context) error, record func(context.Context, int) error) ([]Migration, error) {
+	if err := CheckSchemaVersion(stored, MinSchemaVersion, SchemaVersion); err != nil {
+		return nil, err
+	}
+	if stored == 0 {
+		// 空后端直接使用当前布局
+		return nil, record(ctx, SchemaVersion)
+	}
+	var applied []Migration
+	for _, m := range PendingMigrations(stored) {
+		if step, ok := steps[m.Version]; ok {
+			if err := step(ctx); err != nil {
+				return applied, fmt.Errorf("migration v%d (%s) failed: %w", m.Version, m.Description, err)
+			}
+		}
+		if err := record(ctx, m.Version); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	gormschema "gorm.io/gorm/schema"
+)
+
+func TestCheckSchemaVersion(t *testing.T) {
+	if err := CheckSchemaVersion(0, 2, 3); err != nil {
+		t.Fatalf("empty backend should be accepted, got %v", err)
+	}
+	if err := CheckSchemaVersion(3, 2, 3); err != nil {
+		t.Fatalf("current schema should be accepted, got %v", err)
+	}
+	if err := CheckSchemaVersion(4, 2, 3); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew for downgrade, got %v", err)
+	}
+	if err := CheckSchemaVersion(1, 2, 3); !errors.Is(err, ErrSchemaTooOld) {
+		t.Fatalf("expected ErrSchemaTooOld, got %v", err)
+	}
+}
+
+func TestRunMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	var recorded []int
+	record := func(_ context.Context, v int) error {
+		recorded = append(recorded, v)
+		return nil
+	}
+
+	// 空后端直接记录当前版本，不执行迁移
+	applied, err := runMigrations(ctx, 0, nil, record)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("empty backend: applied=%v err=%v", applied, err)
+	}
+	if len(recorded) != 1 || recorded[0] != SchemaVersion {
+		t.Fatalf("empty backend should record v%d, got %v", SchemaVersion, recorded)
+	}
+
+	// 旧数据依次执行迁移并逐步记录版本
+	recorded = nil
+	ran := 0
+	steps := map[int]func(context.Context) error{
+		2: func(context.Context) error { ran++; return nil },
+	}
+	applied, err = runMigrations(ctx, 1, steps, record)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if ran != 1 || len(applied) != len(PendingMigrations(1)) {
+		t.Fatalf("expected migration v2 to run, ran=%d applied=%v", ran, applied)
+	}
+	if recorded[len(recorded)-1] != SchemaVersion {
+		t.Fatalf("expected v%d recorded last, got %v", SchemaVersion, recorded)
+	}
+
+	// 已是当前版本时什么也不做
+	recorded = nil
+	applied, err = runMigrations(ctx, SchemaVersion, steps, record)
+	if err != nil || len(applied) != 0 || len(recorded) != 0 {
+		t.Fatalf("up-to-date: applied=%v recorded=%v err=%v", applied, recorded, err)
+	}
+
+	// 迁移失败时不记录该版本
+	recorded = nil
+	failing := map[int]func(context.Context) error{
+		2: func(context.Context) error { return errors.New("boom") },
+	}
+	if _, err := runMigrations(ctx, 1, failing, record); err == nil {
+		t.Fatal("expected migration error")
+	}
+	if len(recorded) != 0 {
+		t.Fatalf("failed migration should not be recorded, got %v", recorded)
+	}
+
+	// 数据比代码新时拒绝
+	if _, err := runMigrations(ctx, SchemaVersion+1, steps, record); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestEnsureSchemaMemoryStore(t *testing.T) {
+	stored, applied, err := EnsureSchema(context.Background(), NewMemoryStore())
+	if err != nil || stored != SchemaVersion || len(applied) != 0 {
+		t.Fatalf("memory store: stored=%d applied=%v err=%v", stored, applied, err)
+	}
+}
+
+func TestBaseResourceHasNoSoftDelete(t *testing.T) {
+	// 资源表只硬删除：模型中有 gorm.DeletedAt 时 Delete 会变成 UPDATE deleted_at，查询也会过滤已删除的行
+	s, err := gormschema.Parse(&BaseResource{}, &sync.Map{}, gormschema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("parse BaseResource: %v", err)
+	}
+	if len(s.DeleteClauses) != 0 || len(s.QueryClauses) != 0 {
+		t.Fatalf("BaseResource should not use soft delete, got delete=%d query=%d clauses", len(s.DeleteClauses), len(s.QueryClauses))
+	}
+	if s.LookUpField("deleted_at") != nil {
+		t.Fatal("BaseResource should not have a deleted_at column")
+	}
+}
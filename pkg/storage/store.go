@@ -1,11 +1,14 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -30,51 +33,82 @@ type ResourceEvent struct {
 
 // Store 是 Kubernetes 资源的存储接口
 type Store interface {
-	// Get 获取指定资源
+	// Get 获取指定资源（返回的对象归调用方所有，修改后需 Update 才会写入存储）
 	Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error)
-	// List 列出所有资源（可指定 namespace）
+	// List 列出所有资源（可指定 namespace），返回的对象同样归调用方所有
 	List(gvk schema.GroupVersionKind, namespace string) ([]runtime.Object, error)
+	// ListBySelector 列出 namespace 下（为空表示所有 namespace）标签满足 selector 的资源，后端用标签索引缩小读取范围；
+	// selector 为 nil 或为空时等同于 List
+	ListBySelector(gvk schema.GroupVersionKind, namespace string, selector labels.Selector) ([]runtime.Object, error)
 	// Create 创建资源
 	Create(gvk schema.GroupVersionKind, obj runtime.Object) error
 	// Update 更新资源
 	Update(gvk schema.GroupVersionKind, obj runtime.Object) error
 	// Delete 删除资源
 	Delete(gvk schema.GroupVersionKind, namespace, name string) error
+	// DeleteCollection 删除 namespace 下（为空表示所有 namespace）满足 match 的资源，返回被删除的对象；match 为 nil 时全部删除
+	DeleteCollection(gvk schema.GroupVersionKind, namespace string, match func(runtime.Object) bool) ([]runtime.Object, error)
 	// Watch 监听资源变更
 	Watch(gvk schema.GroupVersionKind, namespace string, resourceVersion string) (<-chan ResourceEvent, error)
 }
 
+// Reconnector 由连接外部后端的 Store（MySQL/etcd）实现：后端中断后恢复时（例如自动拉起的数据库容器被重启）
+// 调用 Reconnect 确认连接可用，并重建依赖长连接的状态（如 etcd watch）
+type Reconnector interface {
+	Reconnect(ctx context.Context) error
+}
+
+// WatchStopper 由能注销 watcher 的 Store 实现：Watch 的调用方不再读取通道时（如 watch 客户端断开）调用 StopWatcher，
+// 注销并关闭通道，避免后端一直向无人读取的通道投递事件
+type WatchStopper interface {
+	StopWatcher(gvk schema.GroupVersionKind, namespace string, ch <-chan ResourceEvent)
+}
+
 // MemoryStore 是基于内存的存储实现
 type MemoryStore struct {
 	mu        sync.RWMutex
-	resources map[string]map[string]runtime.Object // key: gvk-namespace-name, value: object
-	watchers  map[string][]chan ResourceEvent      // key: gvk-namespace, value: watchers
-	version   int64                                // 全局版本号，用于 resourceVersion
+	resources map[string]map[string]runtime.Object // key: collectionPath(gvk, namespace)，value: name -> object
+	watches   *watchHub
+	version   int64                 // 全局版本号，用于 resourceVersion
+	history   map[string][]Revision // key: resourcePath(gvk, namespace, name)
+	// labelIndex 标签倒排索引（key: collectionPath(gvk, "")），与 resources 同步维护
+	labelIndex memoryLabelIndex
+	// historyLimit 每个对象保留的历史版本数，0 表示不记录
+	historyLimit int
 }
 
 // NewMemoryStore 创建新的内存存储
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
 		resources: make(map[string]map[string]runtime.Object),
-		watchers:  make(map[string][]chan ResourceEvent),
+		watches:   newWatchHub(true),
 		version:   0,
-	}
-}
+		history:   make(map[string][]Revision),
 
-// key 生成资源的唯一键
-func (s *MemoryStore) key(gvk schema.GroupVersionKind, namespace, name string) string {
-	if namespace == "" {
-		return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, name)
+		labelIndex: make(memoryLabelIndex),
+
+		historyLimit: DefaultHistoryRevisions,
 	}
-	return fmt.Sprintf("%s/%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, namespace, name)
 }
 
-// watchKey 生成 watch 的键
-func (s *MemoryStore) watchKey(gvk schema.GroupVersionKind, namespace string) string {
-	if namespace == "" {
-		return fmt.Sprintf("%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind)
+// collections 返回 List 需要读取的集合：指定 namespace 或集群级资源只有一个集合，
+// 否则是该 GVK 下所有 namespace 的集合（只遍历集合，不遍历所有资源）
+func (s *MemoryStore) collections(gvk schema.GroupVersionKind, namespace string) []map[string]runtime.Object {
+	namespace = scopedNamespace(gvk, namespace)
+	if namespace != "" || IsClusterScoped(gvk) {
+		if nsMap, ok := s.resources[collectionPath(gvk, namespace)]; ok {
+			return []map[string]runtime.Object{nsMap}
+		}
+		return nil
+	}
+	prefix := collectionPath(gvk, "")
+	var result []map[string]runtime.Object
+	for key, nsMap := range s.resources {
+		if strings.HasPrefix(key, prefix) {
+			result = append(result, nsMap)
+		}
 	}
-	return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, namespace)
+	return result
 }
 
 // getObjectMeta 获取对象的元数据
@@ -91,18 +125,13 @@ func (s *MemoryStore) Get(gvk schema.GroupVersionKind, namespace, name string) (
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	key := s.key(gvk, namespace, name)
-	nsMap, exists := s.resources[key]
-	if !exists {
-		return nil, fmt.Errorf("resource not found: %s", key)
-	}
-
-	obj, exists := nsMap[name]
+	obj, exists := s.resources[collectionPath(gvk, objectNamespace(gvk, namespace))][name]
 	if !exists {
-		return nil, fmt.Errorf("resource not found: %s/%s", namespace, name)
+		return nil, fmt.Errorf("resource not found: %s", resourcePath(gvk, namespace, name))
 	}
 
-	return obj, nil
+	// 返回副本：调用方修改返回的对象不会绕过 Update 改动存储
+	return obj.DeepCopyObject(), nil
 }
 
 // List 列出所有资源
@@ -111,33 +140,71 @@ func (s *MemoryStore) List(gvk schema.GroupVersionKind, namespace string) ([]run
 	defer s.mu.RUnlock()
 
 	var results []runtime.Object
-
-	// 遍历所有资源
-	for _, nsMap := range s.resources {
+	for _, nsMap := range s.collections(gvk, namespace) {
 		for _, obj := range nsMap {
-			meta, err := getObjectMeta(obj)
-			if err != nil {
-				continue
-			}
+			results = append(results, obj.DeepCopyObject())
+		}
+	}
+	SortObjects(results)
 
-			// 检查 GVK 是否匹配
-			objGVK := obj.GetObjectKind().GroupVersionKind()
-			if objGVK.Group != gvk.Group || objGVK.Version != gvk.Version || objGVK.Kind != gvk.Kind {
-				continue
-			}
+	return results, nil
+}
 
-			// 如果指定了 namespace，只返回该 namespace 的资源
-			if namespace != "" && meta.GetNamespace() != namespace {
-				continue
-			}
+// ListBySelector 按标签倒排索引取出候选对象，再按完整的 selector 过滤；
+// selector 中没有可索引的条件（=、in、exists）时扫描所有对象
+func (s *MemoryStore) ListBySelector(gvk schema.GroupVersionKind, namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	if selector == nil || selector.Empty() {
+		return s.List(gvk, namespace)
+	}
+	reqs, selectable := indexableRequirements(selector)
+	if !selectable {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-			results = append(results, obj)
+	namespace = scopedNamespace(gvk, namespace)
+	candidates, ok := s.labelIndex.candidates(collectionPath(gvk, ""), reqs)
+	var results []runtime.Object
+	if !ok {
+		for _, nsMap := range s.collections(gvk, namespace) {
+			for _, obj := range nsMap {
+				if matchesSelector(obj, selector) {
+					results = append(results, obj.DeepCopyObject())
+				}
+			}
 		}
+		SortObjects(results)
+		return results, nil
 	}
-
+	for key := range candidates {
+		if namespace != "" && key.namespace != namespace {
+			continue
+		}
+		obj, exists := s.resources[collectionPath(gvk, key.namespace)][key.name]
+		if exists && matchesSelector(obj, selector) {
+			results = append(results, obj.DeepCopyObject())
+		}
+	}
+	SortObjects(results)
 	return results, nil
 }
 
+// indexObject 把对象加入标签索引（调用方持有写锁）
+func (s *MemoryStore) indexObject(gvk schema.GroupVersionKind, obj runtime.Object) {
+	if meta, err := getObjectMeta(obj); err == nil {
+		s.labelIndex.add(collectionPath(gvk, ""), objectKey{namespace: meta.GetNamespace(), name: meta.GetName()}, meta.GetLabels())
+	}
+}
+
+// unindexObject 从标签索引中删除对象（调用方持有写锁）
+func (s *MemoryStore) unindexObject(gvk schema.GroupVersionKind, obj runtime.Object) {
+	if meta, err := getObjectMeta(obj); err == nil {
+		s.labelIndex.remove(collectionPath(gvk, ""), objectKey{namespace: meta.GetNamespace(), name: meta.GetName()}, meta.GetLabels())
+	}
+}
+
 // Create 创建资源
 func (s *MemoryStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) error {
 	s.mu.Lock()
@@ -148,15 +215,15 @@ func (s *MemoryStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) er
 		return err
 	}
 
-	namespace := meta.GetNamespace()
+	// 集群级资源不带 namespace，namespace 级资源默认为 default
+	namespace := objectNamespace(gvk, meta.GetNamespace())
+	meta.SetNamespace(namespace)
 	name := meta.GetName()
-	key := s.key(gvk, namespace, name)
+	key := collectionPath(gvk, namespace)
 
 	// 检查资源是否已存在
-	if nsMap, exists := s.resources[key]; exists {
-		if _, exists := nsMap[name]; exists {
-			return fmt.Errorf("resource already exists: %s/%s", namespace, name)
-		}
+	if _, exists := s.resources[key][name]; exists {
+		return fmt.Errorf("resource already exists: %s/%s", namespace, name)
 	}
 
 	// 设置 resourceVersion
@@ -175,15 +242,18 @@ func (s *MemoryStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) er
 	if meta.GetUID() == "" {
 		meta.SetUID(types.UID(fmt.Sprintf("uid-%d", s.version)))
 	}
+	initGeneration(obj)
 
-	// 存储资源
+	// 存储副本（调用方的 obj 带回 resourceVersion 等字段，之后修改它不影响存储）
 	if s.resources[key] == nil {
 		s.resources[key] = make(map[string]runtime.Object)
 	}
-	s.resources[key][name] = obj
+	s.resources[key][name] = obj.DeepCopyObject()
+	s.indexObject(gvk, obj)
+	s.recordRevision(gvk, namespace, name, RevisionCreate, nil, obj, "")
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventAdded,
 		Object: obj,
 	})
@@ -193,6 +263,11 @@ func (s *MemoryStore) Create(gvk schema.GroupVersionKind, obj runtime.Object) er
 
 // Update 更新资源
 func (s *MemoryStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	return s.UpdateIf(gvk, obj, "")
+}
+
+// UpdateIf 仅当对象仍是 resourceVersion 时更新（见 ConditionalUpdater），比较与写入在同一把锁内完成
+func (s *MemoryStore) UpdateIf(gvk schema.GroupVersionKind, obj runtime.Object, resourceVersion string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -201,25 +276,33 @@ func (s *MemoryStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) er
 		return err
 	}
 
-	namespace := meta.GetNamespace()
+	namespace := objectNamespace(gvk, meta.GetNamespace())
+	meta.SetNamespace(namespace)
 	name := meta.GetName()
-	key := s.key(gvk, namespace, name)
+	key := collectionPath(gvk, namespace)
 
 	// 检查资源是否存在
 	oldObj, exists := s.resources[key][name]
 	if !exists {
 		return fmt.Errorf("resource not found: %s/%s", namespace, name)
 	}
+	if err := checkResourceVersion(gvk, oldObj, resourceVersion); err != nil {
+		return err
+	}
 
-	// 更新 resourceVersion
+	// 更新 resourceVersion 与 generation
 	s.version++
 	meta.SetResourceVersion(fmt.Sprintf("%d", s.version))
+	updateGeneration(oldObj, obj)
 
-	// 更新资源
-	s.resources[key][name] = obj
+	// 更新资源（存储副本）
+	s.resources[key][name] = obj.DeepCopyObject()
+	s.unindexObject(gvk, oldObj)
+	s.indexObject(gvk, obj)
+	s.recordRevision(gvk, namespace, name, RevisionUpdate, oldObj, obj, "")
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventModified,
 		Object: obj,
 		OldObj: oldObj,
@@ -230,17 +313,19 @@ func (s *MemoryStore) Update(gvk schema.GroupVersionKind, obj runtime.Object) er
 
 // Delete 删除资源
 func (s *MemoryStore) Delete(gvk schema.GroupVersionKind, namespace, name string) error {
+	return s.DeleteAs(gvk, namespace, name, "")
+}
+
+// DeleteAs 以 manager 的身份删除资源
+func (s *MemoryStore) DeleteAs(gvk schema.GroupVersionKind, namespace, name, manager string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	key := s.key(gvk, namespace, name)
+	namespace = objectNamespace(gvk, namespace)
+	key := collectionPath(gvk, namespace)
 
 	// 检查资源是否存在
-	nsMap, exists := s.resources[key]
-	if !exists {
-		return fmt.Errorf("resource not found: %s/%s", namespace, name)
-	}
-
+	nsMap := s.resources[key]
 	obj, exists := nsMap[name]
 	if !exists {
 		return fmt.Errorf("resource not found: %s/%s", namespace, name)
@@ -251,9 +336,11 @@ func (s *MemoryStore) Delete(gvk schema.GroupVersionKind, namespace, name string
 	if len(nsMap) == 0 {
 		delete(s.resources, key)
 	}
+	s.unindexObject(gvk, obj)
+	s.recordRevision(gvk, namespace, name, RevisionDelete, nil, obj, manager)
 
 	// 通知 watchers
-	s.notifyWatchers(gvk, namespace, ResourceEvent{
+	s.watches.notify(gvk, ResourceEvent{
 		Type:   EventDeleted,
 		Object: obj,
 	})
@@ -261,57 +348,109 @@ func (s *MemoryStore) Delete(gvk schema.GroupVersionKind, namespace, name string
 	return nil
 }
 
-// Watch 监听资源变更
-func (s *MemoryStore) Watch(gvk schema.GroupVersionKind, namespace string, resourceVersion string) (<-chan ResourceEvent, error) {
+// DeleteCollection 在同一把锁内筛选并删除资源，删除过程中不会混入并发写入
+func (s *MemoryStore) DeleteCollection(gvk schema.GroupVersionKind, namespace string, match func(runtime.Object) bool) ([]runtime.Object, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	watchKey := s.watchKey(gvk, namespace)
-	ch := make(chan ResourceEvent, 100) // 缓冲通道
+	var deleted []runtime.Object
+	for _, nsMap := range s.collections(gvk, namespace) {
+		for name, obj := range nsMap {
+			meta, err := getObjectMeta(obj)
+			if err != nil {
+				continue
+			}
+			if match != nil && !match(obj) {
+				continue
+			}
 
-	// 注册 watcher
-	if s.watchers[watchKey] == nil {
-		s.watchers[watchKey] = make([]chan ResourceEvent, 0)
+			delete(nsMap, name)
+			if len(nsMap) == 0 {
+				delete(s.resources, collectionPath(gvk, meta.GetNamespace()))
+			}
+			s.unindexObject(gvk, obj)
+			s.recordRevision(gvk, meta.GetNamespace(), name, RevisionDelete, nil, obj, "")
+			s.watches.notify(gvk, ResourceEvent{
+				Type:   EventDeleted,
+				Object: obj,
+			})
+			deleted = append(deleted, obj)
+		}
 	}
-	s.watchers[watchKey] = append(s.watchers[watchKey], ch)
 
-	return ch, nil
+	return deleted, nil
+}
+
+// History 返回对象保留的版本
+func (s *MemoryStore) History(gvk schema.GroupVersionKind, namespace, name string) ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Revision(nil), s.history[resourcePath(gvk, namespace, name)]...), nil
 }
 
-// notifyWatchers 通知所有 watchers
-func (s *MemoryStore) notifyWatchers(gvk schema.GroupVersionKind, namespace string, event ResourceEvent) {
-	watchKey := s.watchKey(gvk, namespace)
-	watchers := s.watchers[watchKey]
+// recordRevision 记录对象的一个版本（调用方持有写锁）
+func (s *MemoryStore) recordRevision(gvk schema.GroupVersionKind, namespace, name string, op RevisionOperation, old, obj runtime.Object, manager string) {
+	if s.historyLimit <= 0 {
+		return
+	}
+	rev, err := newRevision(op, old, obj, manager)
+	if err != nil {
+		return
+	}
+	key := resourcePath(gvk, namespace, name)
+	s.history[key] = appendRevision(s.history[key], rev, s.historyLimit)
+}
 
-	// 也通知全局 watchers（namespace 为空）
-	if namespace != "" {
-		globalKey := s.watchKey(gvk, "")
-		watchers = append(watchers, s.watchers[globalKey]...)
+// deleteMatching 基于 List + Delete 实现 DeleteCollection（非原子），供外部存储后端复用；
+// 列出后被并发删除的对象会被跳过
+func deleteMatching(store Store, gvk schema.GroupVersionKind, namespace string, match func(runtime.Object) bool) ([]runtime.Object, error) {
+	objects, err := store.List(gvk, namespace)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, ch := range watchers {
-		select {
-		case ch <- event:
-		default:
-			// 如果通道已满，跳过（避免阻塞）
+	var deleted []runtime.Object
+	for _, obj := range objects {
+		if match != nil && !match(obj) {
+			continue
+		}
+		meta, err := getObjectMeta(obj)
+		if err != nil {
+			continue
 		}
+		if err := store.Delete(gvk, meta.GetNamespace(), meta.GetName()); err != nil {
+			if _, getErr := store.Get(gvk, meta.GetNamespace(), meta.GetName()); getErr != nil {
+				continue
+			}
+			return deleted, fmt.Errorf("failed to delete %s/%s: %w", meta.GetNamespace(), meta.GetName(), err)
+		}
+		deleted = append(deleted, obj)
 	}
+	return deleted, nil
+}
+
+// Watch 监听资源变更
+func (s *MemoryStore) Watch(gvk schema.GroupVersionKind, namespace string, resourceVersion string) (<-chan ResourceEvent, error) {
+	return s.watches.add(gvk, namespace), nil
+}
+
+// copyEvent 复制事件中的对象
+func copyEvent(event ResourceEvent) ResourceEvent {
+	if event.Object != nil {
+		event.Object = event.Object.DeepCopyObject()
+	}
+	if event.OldObj != nil {
+		event.OldObj = event.OldObj.DeepCopyObject()
+	}
+	return event
 }
 
 // StopWatcher 停止指定的 watcher
 func (s *MemoryStore) StopWatcher(gvk schema.GroupVersionKind, namespace string, ch <-chan ResourceEvent) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	watchKey := s.watchKey(gvk, namespace)
-	watchers := s.watchers[watchKey]
+	s.watches.remove(gvk, namespace, ch)
+}
 
-	for i, w := range watchers {
-		if w == ch {
-			// 移除 watcher
-			s.watchers[watchKey] = append(watchers[:i], watchers[i+1:]...)
-			close(w)
-			break
-		}
-	}
+// WatchStats 返回各组 watcher 的数量与积压的事件数
+func (s *MemoryStore) WatchStats() []WatchStats {
+	return s.watches.stats()
 }
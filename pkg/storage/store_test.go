@@ -6,6 +6,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -135,6 +137,117 @@ func TestMemoryStore_Delete(t *testing.T) {
 	}
 }
 
+func TestMemoryStore_DeleteCollection(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+	for _, p := range []struct{ ns, name, app string }{
+		{"default", "web-1", "web"},
+		{"default", "db-1", "db"},
+		{"other", "web-2", "web"},
+	} {
+		pod := &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: p.name, Namespace: p.ns, Labels: map[string]string{"app": p.app}},
+		}
+		if err := store.Create(gvk, pod); err != nil {
+			t.Fatalf("Failed to create pod: %v", err)
+		}
+	}
+
+	deleted, err := store.DeleteCollection(gvk, "default", func(obj runtime.Object) bool {
+		return obj.(*corev1.Pod).Labels["app"] == "web"
+	})
+	if err != nil {
+		t.Fatalf("Failed to delete collection: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].(*corev1.Pod).Name != "web-1" {
+		t.Fatalf("Unexpected deleted objects: %v", deleted)
+	}
+
+	remaining, _ := store.List(gvk, "")
+	if len(remaining) != 2 {
+		t.Errorf("Expected 2 remaining pods, got %d", len(remaining))
+	}
+}
+
+func TestUpdateAs_Conflict(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"k3.network/managed": "true"}},
+	}
+	RecordManager(node, "k3-network")
+	if err := store.Create(gvk, node); err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+
+	// 另一个写入者的盲写会删除 label：不强制时拒绝
+	blind := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+	}
+	conflict, err := UpdateAs(store, gvk, blind, "k3-controller-manager", false)
+	if err == nil || conflict == nil {
+		t.Fatalf("Expected conflict, got conflict=%v err=%v", conflict, err)
+	}
+	if conflict.LastManager != "k3-network" || len(conflict.Fields) != 1 || conflict.Fields[0] != "metadata.labels[k3.network/managed]" {
+		t.Errorf("Unexpected conflict: %+v", conflict)
+	}
+
+	// 基于最新版本的写入不算冲突
+	current, _ := store.Get(gvk, "", "node-1")
+	latest := current.(*corev1.Node).DeepCopy()
+	latest.Labels = nil
+	if conflict, err := UpdateAs(store, gvk, latest, "k3-controller-manager", false); err != nil || conflict != nil {
+		t.Fatalf("Expected no conflict, got conflict=%v err=%v", conflict, err)
+	}
+	if got, _ := store.Get(gvk, "", "node-1"); LastManager(got) != "k3-controller-manager" {
+		t.Errorf("Expected last manager to be recorded, got %q", LastManager(got))
+	}
+}
+
+func TestMemoryStore_Generation(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1"}}},
+	}
+	if err := store.Create(gvk, pod); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	if pod.Generation != 1 {
+		t.Fatalf("Expected generation 1 after create, got %d", pod.Generation)
+	}
+
+	// 只修改 labels/status 不递增 generation
+	updated := pod.DeepCopy()
+	updated.Labels = map[string]string{"app": "web"}
+	updated.Status.Phase = corev1.PodRunning
+	if err := store.Update(gvk, updated); err != nil {
+		t.Fatalf("Failed to update pod: %v", err)
+	}
+	if updated.Generation != 1 {
+		t.Errorf("Expected generation 1 after metadata/status update, got %d", updated.Generation)
+	}
+
+	// 修改 spec 递增 generation（忽略客户端提交的值）
+	changed := updated.DeepCopy()
+	changed.Spec.Containers[0].Image = "nginx:2"
+	changed.Generation = 42
+	if err := store.Update(gvk, changed); err != nil {
+		t.Fatalf("Failed to update pod: %v", err)
+	}
+	if changed.Generation != 2 {
+		t.Errorf("Expected generation 2 after spec change, got %d", changed.Generation)
+	}
+}
+
 func TestMemoryStore_List(t *testing.T) {
 	store := NewMemoryStore()
 
@@ -234,3 +347,133 @@ func TestMemoryStore_Watch(t *testing.T) {
 		t.Errorf("Expected DELETED event, got %s", event.Type)
 	}
 }
+
+func TestMemoryStore_ReturnsCopies(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+	}
+	eventCh, err := store.Watch(gvk, "default", "")
+	if err != nil {
+		t.Fatalf("Failed to start watch: %v", err)
+	}
+	if err := store.Create(gvk, pod); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	rv := pod.ResourceVersion
+
+	// 修改 Create 传入的对象、Get/List 返回的对象以及 watch 事件中的对象都不影响存储
+	pod.Labels = map[string]string{"from": "create"}
+	got, _ := store.Get(gvk, "default", "test-pod")
+	got.(*corev1.Pod).Labels = map[string]string{"from": "get"}
+	list, _ := store.List(gvk, "default")
+	list[0].(*corev1.Pod).Spec.NodeName = "from-list"
+	event := <-eventCh
+	event.Object.(*corev1.Pod).Status.Phase = corev1.PodFailed
+
+	stored, err := store.Get(gvk, "default", "test-pod")
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+	storedPod := stored.(*corev1.Pod)
+	if storedPod.Labels != nil || storedPod.Spec.NodeName != "" || storedPod.Status.Phase != "" {
+		t.Errorf("Stored pod was mutated through a returned object: %+v", storedPod)
+	}
+	if storedPod.ResourceVersion != rv {
+		t.Errorf("Expected resourceVersion %s, got %s", rv, storedPod.ResourceVersion)
+	}
+
+	// 修改后 Update 才会生效，并递增 resourceVersion
+	storedPod.Labels = map[string]string{"app": "nginx"}
+	if err := store.Update(gvk, storedPod); err != nil {
+		t.Fatalf("Failed to update pod: %v", err)
+	}
+	updated, _ := store.Get(gvk, "default", "test-pod")
+	if updated.(*corev1.Pod).Labels["app"] != "nginx" || updated.(*corev1.Pod).ResourceVersion == rv {
+		t.Errorf("Expected update to be stored with a new resourceVersion, got %+v", updated)
+	}
+}
+
+func TestMemoryStore_ListBySelector(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	newPod := func(ns, name string, podLabels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: podLabels}}
+	}
+	for _, p := range []*corev1.Pod{
+		newPod("a", "web-1", map[string]string{"app": "web", "tier": "frontend"}),
+		newPod("a", "web-2", map[string]string{"app": "web", "tier": "backend"}),
+		newPod("b", "web-3", map[string]string{"app": "web"}),
+		newPod("a", "db-1", map[string]string{"app": "db"}),
+		newPod("a", "plain", nil),
+	} {
+		if err := store.Create(gvk, p); err != nil {
+			t.Fatalf("create %s: %v", p.Name, err)
+		}
+	}
+
+	names := func(ns, selector string) map[string]bool {
+		t.Helper()
+		sel, err := labels.Parse(selector)
+		if err != nil {
+			t.Fatalf("parse %q: %v", selector, err)
+		}
+		objs, err := store.ListBySelector(gvk, ns, sel)
+		if err != nil {
+			t.Fatalf("list %q: %v", selector, err)
+		}
+		result := make(map[string]bool)
+		for _, obj := range objs {
+			result[obj.(*corev1.Pod).Name] = true
+		}
+		return result
+	}
+	expect := func(ns, selector string, want ...string) {
+		t.Helper()
+		got := names(ns, selector)
+		if len(got) != len(want) {
+			t.Fatalf("%q in %q: expected %v, got %v", selector, ns, want, got)
+		}
+		for _, name := range want {
+			if !got[name] {
+				t.Fatalf("%q in %q: expected %v, got %v", selector, ns, want, got)
+			}
+		}
+	}
+
+	expect("", "app=web", "web-1", "web-2", "web-3")
+	expect("a", "app=web", "web-1", "web-2")
+	expect("a", "app in (web,db)", "web-1", "web-2", "db-1")
+	expect("a", "app=web,tier!=backend", "web-1")
+	expect("", "tier", "web-1", "web-2")
+	// 没有可索引的条件时扫描全部对象
+	expect("a", "!tier", "db-1", "plain")
+	expect("a", "", "web-1", "web-2", "db-1", "plain")
+
+	// 更新标签后索引随之变化
+	obj, _ := store.Get(gvk, "a", "web-2")
+	updated := obj.(*corev1.Pod)
+	updated.Labels = map[string]string{"app": "api"}
+	if err := store.Update(gvk, updated); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	expect("a", "app=web", "web-1")
+	expect("a", "app=api", "web-2")
+	expect("", "tier", "web-1")
+
+	// 删除后不再出现在索引中
+	if err := store.Delete(gvk, "a", "web-1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.DeleteCollection(gvk, "b", nil); err != nil {
+		t.Fatalf("delete collection: %v", err)
+	}
+	expect("", "app=web")
+	expect("", "tier")
+	if len(store.labelIndex[collectionPath(gvk, "")]["app"]["web"]) != 0 || store.labelIndex[collectionPath(gvk, "")]["tier"] != nil {
+		t.Fatalf("expected stale index entries to be removed, got %v", store.labelIndex)
+	}
+}
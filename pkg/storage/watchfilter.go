@@ -0,0 +1,32 @@
+package storage
+
+import "k8s.io/apimachinery/pkg/runtime"
+
+// FilterEvent 按 match 转换一个 watch 事件，与 Kubernetes 带 selector 的 watch 一致：
+//   - ADDED/DELETED：对象满足 match 时原样推送
+//   - MODIFIED：新旧对象都满足时推送 MODIFIED；只有新对象满足时改为 ADDED（进入选择范围）；
+//     只有旧对象满足时改为 DELETED（离开选择范围，对象为更新后的版本）；没有 OldObj 时按新对象判断
+//   - BOOKMARK 总是推送
+//
+// 第二个返回值为 false 表示丢弃该事件
+func FilterEvent(event ResourceEvent, match func(runtime.Object) bool) (ResourceEvent, bool) {
+	switch event.Type {
+	case EventBookmark:
+		return event, true
+	case EventModified:
+		now := match(event.Object)
+		if event.OldObj == nil {
+			return event, now
+		}
+		before := match(event.OldObj)
+		switch {
+		case now && !before:
+			return ResourceEvent{Type: EventAdded, Object: event.Object}, true
+		case !now && before:
+			return ResourceEvent{Type: EventDeleted, Object: event.Object}, true
+		}
+		return event, now
+	default:
+		return event, match(event.Object)
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFilterEvent(t *testing.T) {
+	selector := labels.SelectorFromSet(labels.Set{"app": "web"})
+	match := func(obj runtime.Object) bool { return matchesSelector(obj, selector) }
+	pod := func(app string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Labels: map[string]string{"app": app}}}
+	}
+
+	cases := []struct {
+		name  string
+		event ResourceEvent
+		want  EventType
+		keep  bool
+	}{
+		{"added match", ResourceEvent{Type: EventAdded, Object: pod("web")}, EventAdded, true},
+		{"added other", ResourceEvent{Type: EventAdded, Object: pod("db")}, "", false},
+		{"deleted match", ResourceEvent{Type: EventDeleted, Object: pod("web")}, EventDeleted, true},
+		{"modified both", ResourceEvent{Type: EventModified, Object: pod("web"), OldObj: pod("web")}, EventModified, true},
+		{"modified enters", ResourceEvent{Type: EventModified, Object: pod("web"), OldObj: pod("db")}, EventAdded, true},
+		{"modified leaves", ResourceEvent{Type: EventModified, Object: pod("db"), OldObj: pod("web")}, EventDeleted, true},
+		{"modified neither", ResourceEvent{Type: EventModified, Object: pod("db"), OldObj: pod("db")}, "", false},
+		{"modified without old", ResourceEvent{Type: EventModified, Object: pod("web")}, EventModified, true},
+		{"bookmark", ResourceEvent{Type: EventBookmark}, EventBookmark, true},
+	}
+	for _, tc := range cases {
+		got, keep := FilterEvent(tc.event, match)
+		if keep != tc.keep || (keep && got.Type != tc.want) {
+			t.Errorf("%s: got (%s, %v), want (%s, %v)", tc.name, got.Type, keep, tc.want, tc.keep)
+		}
+	}
+}
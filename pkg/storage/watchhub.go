@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// watchHubKey watcher 的登记键：namespace 为空表示所有 namespace（集群级资源总是为空）
+type watchHubKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+}
+
+// WatchStats 一组 watcher（同一 GVK 与 namespace）的数量与通道中积压的事件数
+type WatchStats struct {
+	GVK schema.GroupVersionKind
+	// Namespace 为空表示所有 namespace（集群级资源总是为空）
+	Namespace string
+	Watchers  int
+	// Pending 通道中尚未被读取的事件数；接近通道容量时新事件会被丢弃
+	Pending int
+}
+
+// WatchStatsProvider 由使用 watchHub 的 Store 实现，诊断快照据此输出 watcher 数量
+type WatchStatsProvider interface {
+	WatchStats() []WatchStats
+}
+
+// watchHub 按 (GVK, namespace 或所有 namespace) 登记 watcher 并分发事件，三个后端共用同一套路由：
+// 事件的 namespace 取自事件对象本身（而不是写入方传入的参数），投递给该 namespace 的 watcher 与所有 namespace 的 watcher，
+// 每个 watcher 只收到一次
+type watchHub struct {
+	mu       sync.RWMutex
+	watchers map[watchHubKey][]chan ResourceEvent
+	// copyObjects 为 true 时每个 watcher 收到对象的独立副本（Memory 的对象与存储共享，需要复制）
+	copyObjects bool
+}
+
+// newWatchHub 创建 watchHub
+func newWatchHub(copyObjects bool) *watchHub {
+	return &watchHub{
+		watchers:    make(map[watchHubKey][]chan ResourceEvent),
+		copyObjects: copyObjects,
+	}
+}
+
+// key 返回 Watch/StopWatcher 的 namespace 参数对应的登记键（集群级资源忽略 namespace）。
+// namespace 可能来自 Fiber 复用缓冲区的路由参数，作为 map 的键之前需要复制
+func (h *watchHub) key(gvk schema.GroupVersionKind, namespace string) watchHubKey {
+	return watchHubKey{gvk: gvk, namespace: strings.Clone(scopedNamespace(gvk, namespace))}
+}
+
+// add 登记一个 watcher，返回其事件通道
+func (h *watchHub) add(gvk schema.GroupVersionKind, namespace string) chan ResourceEvent {
+	ch := make(chan ResourceEvent, 100) // 缓冲通道
+	key := h.key(gvk, namespace)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchers[key] = append(h.watchers[key], ch)
+	return ch
+}
+
+// remove 注销并关闭 add 返回的通道；namespace 与 Watch 时相同
+func (h *watchHub) remove(gvk schema.GroupVersionKind, namespace string, ch <-chan ResourceEvent) {
+	key := h.key(gvk, namespace)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	watchers := h.watchers[key]
+	for i, w := range watchers {
+		if w == ch {
+			h.watchers[key] = append(watchers[:i:i], watchers[i+1:]...)
+			if len(h.watchers[key]) == 0 {
+				delete(h.watchers, key)
+			}
+			close(w)
+			return
+		}
+	}
+}
+
+// notify 把事件投递给匹配的 watcher；通道已满的 watcher 跳过（避免阻塞写入）
+func (h *watchHub) notify(gvk schema.GroupVersionKind, event ResourceEvent) {
+	namespace := eventNamespace(gvk, event)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	h.deliver(h.watchers[watchHubKey{gvk: gvk}], event)
+	if namespace != "" {
+		h.deliver(h.watchers[watchHubKey{gvk: gvk, namespace: namespace}], event)
+	}
+}
+
+// stats 返回每组 watcher 的统计，按 GVK 与 namespace 排序
+func (h *watchHub) stats() []WatchStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	stats := make([]WatchStats, 0, len(h.watchers))
+	for key, watchers := range h.watchers {
+		st := WatchStats{GVK: key.gvk, Namespace: key.namespace, Watchers: len(watchers)}
+		for _, ch := range watchers {
+			st.Pending += len(ch)
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if a, b := stats[i].GVK.String(), stats[j].GVK.String(); a != b {
+			return a < b
+		}
+		return stats[i].Namespace < stats[j].Namespace
+	})
+	return stats
+}
+
+func (h *watchHub) deliver(watchers []chan ResourceEvent, event ResourceEvent) {
+	for _, ch := range watchers {
+		e := event
+		if h.copyObjects {
+			e = copyEvent(event)
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// eventNamespace 返回事件所属的 namespace：集群级资源为空，namespace 级资源取事件对象的 namespace（未设置时为 default，
+// 与 Create/Update 写入的位置一致）
+func eventNamespace(gvk schema.GroupVersionKind, event ResourceEvent) string {
+	if IsClusterScoped(gvk) {
+		return ""
+	}
+	for _, obj := range []runtime.Object{event.Object, event.OldObj} {
+		if obj == nil {
+			continue
+		}
+		if meta, err := getObjectMeta(obj); err == nil {
+			return objectNamespace(gvk, meta.GetNamespace())
+		}
+	}
+	return ""
+}
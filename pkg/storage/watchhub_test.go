@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// drain 取出通道中已有的事件（不等待）
+func drain(ch <-chan ResourceEvent) []ResourceEvent {
+	var events []ResourceEvent
+	for {
+		select {
+		case event := <-ch:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestWatchHub_Routing(t *testing.T) {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+	pod := func(namespace, name string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+
+	hub := newWatchHub(false)
+	all := hub.add(podGVK, "")
+	prod := hub.add(podGVK, "prod")
+	defaultNS := hub.add(podGVK, "default")
+	nodes := hub.add(nodeGVK, "prod") // 集群级资源忽略 namespace
+
+	hub.notify(podGVK, ResourceEvent{Type: EventAdded, Object: pod("prod", "a")})
+	hub.notify(podGVK, ResourceEvent{Type: EventAdded, Object: pod("staging", "b")})
+	// namespace 为空的对象属于 default（与 Create 写入的位置一致）
+	hub.notify(podGVK, ResourceEvent{Type: EventDeleted, Object: pod("", "c")})
+	hub.notify(nodeGVK, ResourceEvent{Type: EventAdded, Object: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}})
+
+	names := func(ch <-chan ResourceEvent) []string {
+		var out []string
+		for _, event := range drain(ch) {
+			meta, _ := getObjectMeta(event.Object)
+			out = append(out, meta.GetName())
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name string
+		ch   <-chan ResourceEvent
+		want []string
+	}{
+		{"all namespaces", all, []string{"a", "b", "c"}},
+		{"prod", prod, []string{"a"}},
+		{"default", defaultNS, []string{"c"}},
+		{"cluster scoped", nodes, []string{"n1"}},
+	} {
+		if got := names(tc.ch); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	hub.remove(podGVK, "prod", prod)
+	if _, ok := <-prod; ok {
+		t.Fatal("removed watcher channel not closed")
+	}
+	hub.notify(podGVK, ResourceEvent{Type: EventAdded, Object: pod("prod", "d")})
+	if got := names(all); !slices.Equal(got, []string{"d"}) {
+		t.Errorf("all namespaces after remove: got %v", got)
+	}
+}
+
+// 所有 namespace 的删除（DeleteCollection 传入空 namespace）同样通知各 namespace 的 watcher；
+// namespace 的写入同样通知所有 namespace 的 watcher，且只通知一次
+func TestMemoryStore_WatchAcrossNamespaces(t *testing.T) {
+	store := NewMemoryStore()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+	all, _ := store.Watch(gvk, "", "")
+	prod, _ := store.Watch(gvk, "prod", "")
+	for _, namespace := range []string{"prod", "staging"} {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: namespace}}
+		if err := store.Create(gvk, cm); err != nil {
+			t.Fatalf("create configmap in %s: %v", namespace, err)
+		}
+	}
+	if got := len(drain(all)); got != 2 {
+		t.Fatalf("all-namespace watcher got %d ADDED events, want 2", got)
+	}
+	if got := len(drain(prod)); got != 1 {
+		t.Fatalf("prod watcher got %d ADDED events, want 1", got)
+	}
+
+	if _, err := store.DeleteCollection(gvk, "", nil); err != nil {
+		t.Fatalf("delete collection: %v", err)
+	}
+	events := drain(prod)
+	if len(events) != 1 || events[0].Type != EventDeleted || events[0].Object.(*corev1.ConfigMap).Namespace != "prod" {
+		t.Fatalf("prod watcher events after cluster-wide delete: %+v", events)
+	}
+	if got := len(drain(all)); got != 2 {
+		t.Fatalf("all-namespace watcher got %d DELETED events, want 2", got)
+	}
+}
+
+func TestWatchHub_Stats(t *testing.T) {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+	hub := newWatchHub(false)
+	hub.add(podGVK, "")
+	prod := hub.add(podGVK, "prod")
+	hub.add(podGVK, "prod")
+	hub.add(nodeGVK, "")
+
+	hub.notify(podGVK, ResourceEvent{Type: EventAdded, Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "prod"}}})
+	hub.remove(podGVK, "prod", prod)
+
+	want := []WatchStats{
+		{GVK: nodeGVK, Watchers: 1},
+		{GVK: podGVK, Watchers: 1, Pending: 1},
+		{GVK: podGVK, Namespace: "prod", Watchers: 1, Pending: 1},
+	}
+	if got := hub.stats(); !slices.Equal(got, want) {
+		t.Fatalf("stats = %+v, want %+v", got, want)
+	}
+}